@@ -2,18 +2,21 @@ package cmd
 
 import (
 	"bufio"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/depgen"
+	"github.com/aupeachmo/aigogo/pkg/depimport"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/imports"
 	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/logging"
 	"github.com/aupeachmo/aigogo/pkg/manifest"
 	"github.com/aupeachmo/aigogo/pkg/pyproject"
-	"github.com/aupeachmo/aigogo/pkg/store"
 )
 
 func addCmd() *Command {
@@ -22,7 +25,7 @@ func addCmd() *Command {
 		Description: "Add packages, files, or dependencies",
 		Run: func(args []string) error {
 			if len(args) == 0 {
-				return fmt.Errorf("usage: aigg add <package-ref|file|dep|dev> [args...]\n\nSubcommands:\n  <registry/repo:tag>         Add a package to aigogo.lock\n  file <path>...              Add files to include list\n  dep <pkg> <ver>             Add runtime dependency\n  dep --from-pyproject        Import all dependencies from pyproject.toml\n  dev <pkg> <ver>             Add development dependency\n  dev --from-pyproject        Import dev dependencies from pyproject.toml\n\nExamples:\n  aigg add docker.io/org/my-utils:1.0.0\n  aigg add file utils.py helpers.py\n  aigg add dep requests >=2.28.0")
+				return errcode.Errorf(errcode.Usage, "usage: aigg add <package-ref|file|dep|dev> [args...]\n\nSubcommands:\n  <registry/repo:tag> [--force] [--strict] Add a package to aigogo.lock\n  <registry/repo:tag> --dev|--optional Add a package that install --production skips\n  <registry/repo:tag[extra,...]> Add a package with optional dependency extras\n  <repo:tag> --registry <host[/ns]> Add a package from a registry other than the default\n  <./path/to/package>         Add a local package, linked to its working directory\n  --from-file <file>          Add every package listed in a file, one reference per line\n  -                           Add every package listed on stdin\n  file <path>...              Add files to include list\n  dep <pkg> <ver>             Add runtime dependency\n  dep --from-pyproject        Import all dependencies from pyproject.toml\n  dep --from-requirements [f] Import dependencies from requirements.txt\n  dep --from-lock             Import pyproject.toml deps at uv.lock/poetry.lock versions\n  dep --from-conda [f]        Import dependencies from a conda environment.yml\n  dep --from-cargo            Import dependencies from Cargo.toml\n  dep --from-gomod            Import dependencies from go.mod\n  dev <pkg> <ver>             Add development dependency\n  dev --from-pyproject        Import dev dependencies from pyproject.toml\n  dev --from-requirements [f] Import dev dependencies from requirements-dev.txt\n  dev --from-cargo            Import dev dependencies from Cargo.toml\n  peer <pkg> <ver>            Add peer dependency (provided by the consuming project)\n\nExamples:\n  aigg add docker.io/org/my-utils:1.0.0\n  aigg add 'docker.io/org/my-utils:1.0.0[viz]'\n  aigg add ../my-utils\n  cat packages.txt | aigg add - --dev\n  aigg add file utils.py helpers.py\n  aigg add dep requests >=2.28.0")
 			}
 
 			subcommand := args[0]
@@ -32,230 +35,140 @@ func addCmd() *Command {
 			case "file":
 				return addFiles(subArgs)
 			case "dep":
-				return addDependencyCmd(subArgs, false)
+				return addDependencyCmd(subArgs, groupRuntime)
 			case "dev":
-				return addDependencyCmd(subArgs, true)
+				return addDependencyCmd(subArgs, groupDev)
+			case "peer":
+				return addDependencyCmd(subArgs, groupPeer)
 			default:
-				// If not a known subcommand, treat as package reference
-				if looksLikePackageRef(subcommand) {
-					return addPackage(subcommand)
+				// If not a known subcommand, treat as a package directory or
+				// reference
+				if isBatchAdd(args) {
+					return addBatchCmd(args)
 				}
-				return fmt.Errorf("unknown subcommand '%s'\nValid subcommands: file, dep, dev\nOr provide a package reference like: docker.io/org/package:tag", subcommand)
+				if aigogo.LooksLikeLocalPath(subcommand) || aigogo.LooksLikePackageRef(subcommand) {
+					af := newAddFlags()
+					if err := af.flags.Parse(subArgs); err != nil {
+						return errcode.Wrap(errcode.Usage, err)
+					}
+					if af.flags.NArg() > 0 {
+						return errcode.Errorf(errcode.Usage, "unexpected argument: %s\nUsage: aigg add <registry/repo:tag> [--force] [--strict] [--dev|--optional] [--registry <host[/namespace]>] [--max-extract-size <size>]", af.flags.Arg(0))
+					}
+					opts, err := af.options()
+					if err != nil {
+						return err
+					}
+					return addPackage(subcommand, opts)
+				}
+				return errcode.Errorf(errcode.Usage, "unknown subcommand '%s'\nValid subcommands: file, dep, dev, peer\nOr provide a package reference like: docker.io/org/package:tag", subcommand)
 			}
 		},
 	}
 }
 
-// looksLikePackageRef checks if the argument looks like a package reference
-// Package refs contain "/" or ":" (e.g., docker.io/org/pkg:1.0.0 or pkg:1.0.0)
-func looksLikePackageRef(arg string) bool {
-	return strings.Contains(arg, "/") || strings.Contains(arg, ":")
+// addFlags are the flags of aigg add <package>, shared by a batch add
+type addFlags struct {
+	flags                        *flag.FlagSet
+	force, strict, dev, optional *bool
+	registry, maxExtractSize     *string
 }
 
-// addPackage adds a package to the lock file via CAS
-func addPackage(imageRef string) error {
-	fmt.Printf("Adding package: %s\n\n", imageRef)
-
-	// Check local cache first before pulling from registry
-	var srcDir string
-	var relFiles []string
-	var needCleanup bool
-
-	if cachePath := docker.GetCachePath(imageRef); cachePath != "" {
-		fmt.Println("Found in local cache...")
-		srcDir = cachePath
-
-		// Collect files from cache (skip metadata)
-		entries, err := os.ReadDir(cachePath)
-		if err != nil {
-			return fmt.Errorf("failed to read local cache: %w", err)
-		}
-		for _, entry := range entries {
-			if entry.Name() == ".aigogo-metadata.json" {
-				continue
-			}
-			if entry.IsDir() {
-				subFiles, err := collectFiles(filepath.Join(cachePath, entry.Name()), entry.Name())
-				if err != nil {
-					return err
-				}
-				relFiles = append(relFiles, subFiles...)
-			} else {
-				relFiles = append(relFiles, entry.Name())
-			}
-		}
-	} else {
-		// Pull from registry
-		fmt.Println("Pulling from registry...")
-		puller := docker.NewPuller()
-		if err := puller.Pull(imageRef); err != nil {
-			return fmt.Errorf("failed to pull package: %w", err)
-		}
-
-		// Extract to temp directory
-		tmpDir, err := os.MkdirTemp("", "aigogo-add-*")
-		if err != nil {
-			return fmt.Errorf("failed to create temp directory: %w", err)
-		}
-		srcDir = tmpDir
-		needCleanup = true
-
-		fmt.Println("Extracting package...")
-		extractor := docker.NewExtractor()
-		extractedFiles, err := extractor.Extract(imageRef, tmpDir, true)
-		if err != nil {
-			_ = os.RemoveAll(tmpDir)
-			return fmt.Errorf("failed to extract package: %w", err)
-		}
-
-		// Convert to relative paths
-		for _, f := range extractedFiles {
-			relPath, err := filepath.Rel(tmpDir, f)
-			if err != nil {
-				_ = os.RemoveAll(tmpDir)
-				return err
-			}
-			relFiles = append(relFiles, relPath)
-		}
-	}
-
-	if needCleanup {
-		defer func() { _ = os.RemoveAll(srcDir) }()
+func newAddFlags() *addFlags {
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	return &addFlags{
+		flags:          fs,
+		force:          fs.Bool("force", false, "Add the package even if its environment constraints don't match this machine"),
+		strict:         fs.Bool("strict", false, "Refuse the package if it is deprecated"),
+		dev:            fs.Bool("dev", false, "Add the package to the dev group, which install --production skips"),
+		optional:       fs.Bool("optional", false, "Add the package to the optional group, which install --production skips"),
+		registry:       registryFlag(fs),
+		maxExtractSize: maxExtractSizeFlag(fs),
 	}
+}
 
-	// Read manifest to get metadata
-	manifestPath := filepath.Join(srcDir, "aigogo.json")
-	var pkgManifest *manifest.Manifest
-	var manifestData []byte
-
-	if data, err := os.ReadFile(manifestPath); err == nil {
-		manifestData = data
-		var m manifest.Manifest
-		if err := json.Unmarshal(data, &m); err == nil {
-			pkgManifest = &m
+// options returns the parsed flags as aigogo.AddOptions
+func (f *addFlags) options() (aigogo.AddOptions, error) {
+	opts := aigogo.AddOptions{Force: *f.force, Strict: *f.strict, Registry: *f.registry}
+	if opts.Registry != "" {
+		if err := checkRegistryFlag(opts.Registry); err != nil {
+			return opts, err
 		}
 	}
-
-	// Determine package info
-	pkgName := lockfile.GetPackageName(imageRef)
-	pkgVersion := "unknown"
-	pkgLanguage := "python" // default
-
-	if pkgManifest != nil {
-		if pkgManifest.Name != "" {
-			pkgName = pkgManifest.Name
-		}
-		if pkgManifest.Version != "" {
-			pkgVersion = pkgManifest.Version
-		}
-		if pkgManifest.Language.Name != "" {
-			pkgLanguage = strings.ToLower(pkgManifest.Language.Name)
-		}
-	} else {
-		// Extract version from tag if no manifest
-		if idx := strings.LastIndex(imageRef, ":"); idx != -1 {
-			pkgVersion = imageRef[idx+1:]
-		}
-		// Create minimal manifest
-		manifestData, _ = json.MarshalIndent(map[string]interface{}{
-			"name":     pkgName,
-			"version":  pkgVersion,
-			"language": map[string]string{"name": pkgLanguage},
-		}, "", "  ")
+	if err := setMaxExtractSize(*f.maxExtractSize); err != nil {
+		return opts, err
 	}
-
-	// Store in CAS
-	fmt.Println("Storing in content-addressable store...")
-	cas, err := store.NewStore()
-	if err != nil {
-		return fmt.Errorf("failed to initialize store: %w", err)
+	switch {
+	case *f.dev && *f.optional:
+		return opts, fmt.Errorf("--dev and --optional can't be combined")
+	case *f.dev:
+		opts.Group = lockfile.GroupDev
+	case *f.optional:
+		opts.Group = lockfile.GroupOptional
 	}
+	return opts, nil
+}
 
-	hash, err := cas.Store(srcDir, relFiles, manifestData)
+// addPackage adds a package reference or directory to the lock file (see
+// aigogo.Client.AddPackage) and prints what was added
+func addPackage(ref string, opts aigogo.AddOptions) error {
+	client, err := newClient()
 	if err != nil {
-		return fmt.Errorf("failed to store package: %w", err)
-	}
-
-	// Make read-only
-	if err := cas.MakeReadOnly(hash); err != nil {
-		fmt.Printf("⚠ Warning: failed to make files read-only: %v\n", err)
+		return err
 	}
-
-	// Find or create lock file
-	cwd, err := os.Getwd()
+	result, err := client.AddPackage(commandContext(), ref, opts)
 	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
+		return err
 	}
 
-	lockPath, lock, err := lockfile.FindLockFileFrom(cwd)
-	if err != nil {
-		// Create new lock file in current directory
-		lockPath = filepath.Join(cwd, lockfile.LockFileName)
-		lock = lockfile.New()
+	added := result.Added[0]
+	locked := added.Locked
+	noteAdded(result)
+	if locked.IsLocal() {
+		fmt.Printf("✓ Added %s@%s to %s\n", added.Name, added.Version, result.LockPath)
+		fmt.Printf("  Path: %s\n", locked.Path)
+	} else {
+		fmt.Printf("\n✓ Added %s@%s to %s\n", added.Name, added.Version, result.LockPath)
+		fmt.Printf("  Hash: sha256:%s\n", added.Hash[:16]+"...")
 	}
-
-	// Add package to lock file
-	// Normalize name for Python (hyphens → underscores); keep original for JS
-	lockName := pkgName
-	if pkgLanguage == "python" {
-		lockName = lockfile.NormalizeName(pkgName)
+	fmt.Printf("  Files: %d\n", added.Files)
+	fmt.Printf("  Language: %s\n", strings.Join(locked.LanguageNames(), ", "))
+	if len(locked.Extras) > 0 {
+		fmt.Printf("  Extras: %s\n", strings.Join(locked.Extras, ", "))
 	}
-	lock.Add(lockName, lockfile.LockedPackage{
-		Version:   pkgVersion,
-		Integrity: "sha256:" + hash,
-		Source:    imageRef,
-		Language:  pkgLanguage,
-		Files:     relFiles,
-	})
-
-	// Save lock file
-	if err := lockfile.Save(lockPath, lock); err != nil {
-		return fmt.Errorf("failed to save lock file: %w", err)
+	if locked.Group != "" {
+		fmt.Printf("  Group: %s (skipped by 'aigg install --production')\n", locked.Group)
 	}
 
-	fmt.Printf("\n✓ Added %s@%s to %s\n", pkgName, pkgVersion, lockPath)
-	fmt.Printf("  Hash: sha256:%s\n", hash[:16]+"...")
-	fmt.Printf("  Files: %d\n", len(relFiles))
-	fmt.Printf("  Language: %s\n", pkgLanguage)
-
-	fmt.Println("\nNext steps:")
-	fmt.Println("  1. Run 'aigg install' to create import links")
-	fmt.Println("  2. Commit aigogo.lock to version control")
+	logging.Println("\nNext steps:")
+	if locked.IsLocal() {
+		// Local path packages are linked to the package directory
+		logging.Println("  1. Run 'aigg install' to link the package to its directory")
+		logging.Println("  2. Edit the package in place; changes show up without reinstalling")
+		logging.Println("     (run 'aigg install' again after adding files, exports or languages)")
+		return nil
+	}
+	logging.Println("  1. Run 'aigg install' to create import links")
+	logging.Println("  2. Commit aigogo.lock to version control")
 
 	// Show import hint
-	switch pkgLanguage {
-	case "python":
-		fmt.Printf("\nImport with: from aigogo.%s import ...\n", lockName)
-	case "javascript", "typescript":
-		fmt.Printf("\nImport with: import ... from '@aigogo/%s'\n", pkgName)
+	ns := imports.NamespaceFor(loadProjectSettings(filepath.Dir(result.LockPath)).Namespace)
+	logging.Println()
+	for _, lang := range locked.LanguageNames() {
+		switch lang {
+		case "python":
+			logging.Printf("Import with: from %s.%s import ...\n", ns.Python, lockfile.NormalizeName(added.LockName))
+		case "javascript", "typescript":
+			logging.Printf("Import with: import ... from '%s/%s'\n", ns.JavaScript, added.LockName)
+		case "ruby":
+			logging.Printf("Require with: require 'aigogo/%s/<file>'\n", lockfile.NormalizeName(added.LockName))
+		case "java":
+			logging.Printf("Source root: .aigogo/imports/java/%s\n", added.LockName)
+		}
 	}
 
 	return nil
 }
 
-// collectFiles recursively collects file paths from a directory, returning
-// paths relative to the given prefix.
-func collectFiles(dir string, prefix string) ([]string, error) {
-	var files []string
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return nil, err
-	}
-	for _, entry := range entries {
-		relPath := prefix + "/" + entry.Name()
-		if entry.IsDir() {
-			subFiles, err := collectFiles(filepath.Join(dir, entry.Name()), relPath)
-			if err != nil {
-				return nil, err
-			}
-			files = append(files, subFiles...)
-		} else {
-			files = append(files, relPath)
-		}
-	}
-	return files, nil
-}
-
 func addFiles(args []string) error {
 	// Parse flags
 	fs := flag.NewFlagSet("add file", flag.ContinueOnError)
@@ -279,7 +192,7 @@ func addFiles(args []string) error {
 	}
 
 	if err := fs.Parse(flagArgs); err != nil {
-		return err
+		return errcode.Wrap(errcode.Usage, err)
 	}
 	posArgs = append(posArgs, fs.Args()...)
 	args = posArgs
@@ -300,7 +213,7 @@ func addFiles(args []string) error {
 
 	// Check if files.include is "auto"
 	if str, ok := m.Files.Include.(string); ok && str == "auto" {
-		return fmt.Errorf("files.include is set to 'auto'\nPlease edit aigogo.json to change it to an array before adding files")
+		return fmt.Errorf("files.include is set to 'auto'\nRun 'aigg files freeze' to turn it into an explicit list before adding files")
 	}
 
 	// Get file paths
@@ -318,7 +231,7 @@ func addFiles(args []string) error {
 	}
 
 	if len(filePaths) == 0 {
-		return fmt.Errorf("at least one file path is required")
+		return errcode.Errorf(errcode.Usage, "at least one file path is required")
 	}
 
 	// Get existing patterns
@@ -401,7 +314,7 @@ func addFiles(args []string) error {
 	m.Files.Include = existingPatterns
 
 	// Save updated manifest
-	if err := manifest.Save(manifestPath, m); err != nil {
+	if err := saveManifest(manifestPath, m); err != nil {
 		return fmt.Errorf("failed to save aigogo.json: %w", err)
 	}
 
@@ -413,10 +326,24 @@ func addFiles(args []string) error {
 	return nil
 }
 
-func addDependencyCmd(args []string, isDev bool) error {
+// Dependency groups that aigg add dep, dev and peer add to
+const (
+	groupRuntime = "runtime"
+	groupDev     = "development"
+	groupPeer    = "peer"
+)
+
+func addDependencyCmd(args []string, group string) error {
 	// Parse flags
 	fs := flag.NewFlagSet("add dep", flag.ContinueOnError)
 	fromPyproject := fs.Bool("from-pyproject", false, "Import dependencies from pyproject.toml")
+	fromRequirements := fs.Bool("from-requirements", false, "Import dependencies from a pip requirements file")
+	fromConda := fs.Bool("from-conda", false, "Import dependencies from a conda environment.yml")
+	fromLock := fs.Bool("from-lock", false, "Import dependencies from pyproject.toml pinned to uv.lock/poetry.lock versions")
+	compatible := fs.Bool("compatible", false, "With --from-lock, record compatible ranges instead of exact versions")
+	fromCargo := fs.Bool("from-cargo", false, "Import dependencies from Cargo.toml")
+	fromGoMod := fs.Bool("from-gomod", false, "Import dependencies from go.mod")
+	language := fs.String("language", "", "Language the dependency is for, in a multi-language package")
 
 	// Separate flags from positional args so flags can appear anywhere
 	var flagArgs []string
@@ -434,24 +361,51 @@ func addDependencyCmd(args []string, isDev bool) error {
 	}
 
 	if err := fs.Parse(flagArgs); err != nil {
-		return err
+		return errcode.Wrap(errcode.Usage, err)
 	}
 	posArgs = append(posArgs, fs.Args()...)
 
+	if group == groupPeer && (*fromPyproject || *fromRequirements || *fromConda || *fromLock || *fromCargo || *fromGoMod) {
+		return fmt.Errorf("peer dependencies can't be imported\nDeclare each one with: aigg add peer <pkg> <version>")
+	}
+	isDev := group == groupDev
+
 	// Check if --from-pyproject flag is set
-	if *fromPyproject {
-		return addDependenciesFromPyproject(isDev)
+	if *compatible && !*fromLock {
+		return fmt.Errorf("--compatible can only be used with --from-lock")
+	}
+	if *fromPyproject || *fromLock {
+		return addDependenciesFromPyproject(isDev, *fromLock, *compatible)
+	}
+	if *fromRequirements {
+		path := ""
+		if len(posArgs) > 0 {
+			path = posArgs[0]
+		}
+		return addDependenciesFromRequirements(path, isDev)
+	}
+	if *fromConda {
+		path := ""
+		if len(posArgs) > 0 {
+			path = posArgs[0]
+		}
+		return addDependenciesFromConda(path, isDev)
+	}
+	if *fromCargo {
+		return addDependenciesFromForeignManifest("cargo", isDev)
+	}
+	if *fromGoMod {
+		return addDependenciesFromForeignManifest("gomod", isDev)
 	}
 
 	// Otherwise, use manual dependency addition
-	return addDependency(posArgs, isDev)
+	return addDependency(posArgs, group, *language)
 }
 
-func addDependency(args []string, isDev bool) error {
-	depType := "runtime"
-	if isDev {
-		depType = "development"
-	}
+// addDependency declares a dependency in group. language picks which of a
+// multi-language package's languages it is for; "" means the primary one.
+func addDependency(args []string, group string, language string) error {
+	depType := group
 
 	// Find and load manifest (supports subdirectories)
 	m, manifestDir, err := manifest.FindManifest()
@@ -461,6 +415,13 @@ func addDependency(args []string, isDev bool) error {
 
 	manifestPath := filepath.Join(manifestDir, "aigogo.json")
 
+	if language == "" {
+		language = m.Language.Name
+	}
+	if !m.HasLanguage(language) {
+		return fmt.Errorf("%s is not one of the package's languages; add it to \"languages\" in aigogo.json first", language)
+	}
+
 	reader := bufio.NewReader(os.Stdin)
 
 	// Get package name
@@ -477,7 +438,7 @@ func addDependency(args []string, isDev bool) error {
 	}
 
 	if pkgName == "" {
-		return fmt.Errorf("package name is required")
+		return errcode.Errorf(errcode.Usage, "package name is required")
 	}
 
 	// Initialize dependencies if nil
@@ -488,24 +449,49 @@ func addDependency(args []string, isDev bool) error {
 		}
 	}
 
+	// Python names may carry extras: requests[socks]
+	isPython := strings.ToLower(language) == "python"
+	baseName := pkgName
+	if idx := strings.Index(pkgName, "["); isPython && idx != -1 {
+		baseName = strings.TrimSpace(pkgName[:idx])
+	}
+
 	// Check if already exists
 	targetList := m.Dependencies.Runtime
-	if isDev {
+	switch group {
+	case groupDev:
 		targetList = m.Dependencies.Dev
+	case groupPeer:
+		targetList = m.Dependencies.Peer
 	}
 	for _, dep := range targetList {
-		if dep.Package == pkgName {
+		if dep.Package == baseName && m.DependencyLanguage(dep) == language {
 			return fmt.Errorf("package '%s' is already declared as a %s dependency with version '%s'", pkgName, depType, dep.Version)
 		}
 	}
 
+	// A package either ships a library or expects the host to provide it
+	var other []manifest.Dependency
+	var otherType, otherCmd string
+	switch group {
+	case groupRuntime:
+		other, otherType, otherCmd = m.Dependencies.Peer, groupPeer, "peer"
+	case groupPeer:
+		other, otherType, otherCmd = m.Dependencies.Runtime, groupRuntime, "dep"
+	}
+	for _, dep := range other {
+		if dep.Package == baseName && m.DependencyLanguage(dep) == language {
+			return fmt.Errorf("package '%s' is already declared as a %s dependency\nRemove it first with: aigg rm %s %s", pkgName, otherType, otherCmd, baseName)
+		}
+	}
+
 	// Get version
 	var version string
 	if len(args) > 1 {
 		version = args[1]
 	} else {
 		// Show suggested version format based on language
-		suggestion := suggestVersionFormat(m.Language.Name, pkgName)
+		suggestion := suggestVersionFormat(language, pkgName)
 		fmt.Printf("Version constraint %s: ", suggestion)
 
 		input, err := reader.ReadString('\n')
@@ -516,7 +502,7 @@ func addDependency(args []string, isDev bool) error {
 	}
 
 	if version == "" {
-		return fmt.Errorf("version is required")
+		return errcode.Errorf(errcode.Usage, "version is required")
 	}
 
 	// Add the dependency
@@ -525,14 +511,35 @@ func addDependency(args []string, isDev bool) error {
 		Version: version,
 	}
 
-	if isDev {
+	// Python extras and environment markers use PEP 508 syntax:
+	// aigg add dep "requests[socks]" ">=2.31; python_version < '3.11'"
+	if isPython && (baseName != pkgName || strings.Contains(version, ";")) {
+		spec := pkgName + version
+		if strings.HasPrefix(strings.TrimSpace(version), "*") {
+			spec = pkgName + strings.TrimPrefix(strings.TrimSpace(version), "*")
+		}
+		if parsed := pyproject.ParseRequirement(spec); parsed != nil {
+			newDep = *parsed
+			pkgName = newDep.Package
+			version = newDep.Version
+		}
+	}
+
+	if language != m.Language.Name {
+		newDep.Language = language
+	}
+
+	switch group {
+	case groupDev:
 		m.Dependencies.Dev = append(m.Dependencies.Dev, newDep)
-	} else {
+	case groupPeer:
+		m.Dependencies.Peer = append(m.Dependencies.Peer, newDep)
+	default:
 		m.Dependencies.Runtime = append(m.Dependencies.Runtime, newDep)
 	}
 
 	// Save updated manifest
-	if err := manifest.Save(manifestPath, m); err != nil {
+	if err := saveManifest(manifestPath, m); err != nil {
 		return fmt.Errorf("failed to save aigogo.json: %w", err)
 	}
 
@@ -555,13 +562,29 @@ func suggestVersionFormat(language, pkgName string) string {
 		return "(e.g., v1.2.3)"
 	case "rust":
 		return "(e.g., 1.0 or ^1.0)"
+	case "ruby":
+		return "(e.g., ~> 2.0 or >= 2.0, < 3.0)"
+	case "java":
+		return "(e.g., 2.10.1 or [2.0,3.0))"
+	case "csharp":
+		return "(e.g., 13.0.3 or [13.0,14.0))"
+	case "php":
+		return "(e.g., ^7.8 or ~7.8.0)"
 	default:
 		return ""
 	}
 }
 
-// addDependenciesFromPyproject imports dependencies from pyproject.toml
-func addDependenciesFromPyproject(isDev bool) error {
+// addDependenciesFromPyproject imports dependencies from pyproject.toml.
+// With fromLock, each dependency is pinned to the version resolved in
+// uv.lock or poetry.lock, or to a compatible range from it when compatible
+// is set.
+func addDependenciesFromPyproject(isDev, fromLock, compatible bool) error {
+	flagName := "--from-pyproject"
+	if fromLock {
+		flagName = "--from-lock"
+	}
+
 	// Find and load manifest (supports subdirectories)
 	m, manifestDir, err := manifest.FindManifest()
 	if err != nil {
@@ -572,7 +595,7 @@ func addDependenciesFromPyproject(isDev bool) error {
 
 	// Check that this is a Python project
 	if strings.ToLower(m.Language.Name) != "python" {
-		return fmt.Errorf("--from-pyproject is only supported for Python projects (current language: %s)", m.Language.Name)
+		return fmt.Errorf("%s is only supported for Python projects (current language: %s)", flagName, m.Language.Name)
 	}
 
 	// Find pyproject.toml
@@ -611,53 +634,257 @@ func addDependenciesFromPyproject(isDev bool) error {
 		fmt.Printf("✓ Set Python version requirement: %s\n", deps.PythonVersion)
 	}
 
-	var added int
-	var skipped int
+	imported := deps.Runtime
+	if isDev {
+		imported = deps.Dev
+	}
+
+	if fromLock {
+		imported, err = pinToLockFile(manifestDir, imported, compatible)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := mergeImportedDependencies(m, manifestPath, imported, isDev); err != nil {
+		return err
+	}
+
+	if deps.PythonVersion != "" && m.Language.Version != deps.PythonVersion {
+		fmt.Printf("\n💡 Note: Your aigogo.json Python version (%s) differs from pyproject.toml (%s)\n",
+			m.Language.Version, deps.PythonVersion)
+	}
+
+	return nil
+}
+
+// pinToLockFile replaces declared pyproject specifiers with the versions
+// resolved in uv.lock or poetry.lock
+func pinToLockFile(dir string, deps []manifest.Dependency, compatible bool) ([]manifest.Dependency, error) {
+	lockPath, err := pyproject.FindLockFile(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find lock file: %w", err)
+	}
+
+	fmt.Printf("🔒 Using resolved versions from: %s\n", lockPath)
+
+	locked, err := pyproject.ParseLockVersions(lockPath)
+	if err != nil {
+		return nil, err
+	}
+
+	constraint := func(version string) string { return "==" + version }
+	if compatible {
+		constraint = func(version string) string { return depgen.SuggestConstraint(version, "python") }
+	}
+
+	pinned, missing := pyproject.PinToLock(deps, locked, constraint)
+	for _, pkg := range missing {
+		fmt.Printf("⚠ '%s' is not in %s; keeping the pyproject.toml constraint\n", pkg, filepath.Base(lockPath))
+	}
+	fmt.Println()
+	return pinned, nil
+}
 
+// addDependenciesFromRequirements imports dependencies from a pip
+// requirements file. Without a path, requirements.txt (or a dev requirements
+// file for dev dependencies) is looked up next to aigogo.json.
+func addDependenciesFromRequirements(path string, isDev bool) error {
+	m, manifestDir, err := manifest.FindManifest()
+	if err != nil {
+		return fmt.Errorf("failed to find aigogo.json: %w\nRun 'aigg init' first", err)
+	}
+
+	manifestPath := filepath.Join(manifestDir, "aigogo.json")
+
+	if strings.ToLower(m.Language.Name) != "python" {
+		return fmt.Errorf("--from-requirements is only supported for Python projects (current language: %s)", m.Language.Name)
+	}
+
+	if path == "" {
+		path, err = pyproject.FindRequirements(manifestDir, isDev)
+		if err != nil {
+			return fmt.Errorf("failed to find requirements file: %w", err)
+		}
+	}
+
+	fmt.Printf("📦 Reading dependencies from: %s\n", path)
+
+	reqs, err := pyproject.ParseRequirements(path)
+	if err != nil {
+		return err
+	}
+	for _, f := range reqs.Files[1:] {
+		fmt.Printf("  including %s\n", f)
+	}
+	for _, w := range reqs.Warnings {
+		fmt.Printf("⚠ %s\n", w)
+	}
+	fmt.Println()
+
+	if m.Dependencies == nil {
+		m.Dependencies = &manifest.Dependencies{
+			Runtime: []manifest.Dependency{},
+			Dev:     []manifest.Dependency{},
+		}
+	}
+
+	return mergeImportedDependencies(m, manifestPath, reqs.Dependencies, isDev)
+}
+
+// addDependenciesFromConda imports dependencies from a conda
+// environment.yml, translating conda package names to PyPI names
+func addDependenciesFromConda(path string, isDev bool) error {
+	m, manifestDir, err := manifest.FindManifest()
+	if err != nil {
+		return fmt.Errorf("failed to find aigogo.json: %w\nRun 'aigg init' first", err)
+	}
+
+	manifestPath := filepath.Join(manifestDir, "aigogo.json")
+
+	if strings.ToLower(m.Language.Name) != "python" {
+		return fmt.Errorf("--from-conda is only supported for Python projects (current language: %s)", m.Language.Name)
+	}
+
+	if path == "" {
+		path, err = pyproject.FindCondaEnvironment(manifestDir)
+		if err != nil {
+			return fmt.Errorf("failed to find conda environment: %w", err)
+		}
+	}
+
+	fmt.Printf("📦 Reading dependencies from: %s\n", path)
+
+	env, err := pyproject.ParseCondaEnvironment(path)
+	if err != nil {
+		return err
+	}
+	for _, w := range env.Warnings {
+		fmt.Printf("⚠ %s\n", w)
+	}
+	fmt.Println()
+
+	if m.Dependencies == nil {
+		m.Dependencies = &manifest.Dependencies{
+			Runtime: []manifest.Dependency{},
+			Dev:     []manifest.Dependency{},
+		}
+	}
+
+	if env.PythonVersion != "" && m.Language.Version == "" {
+		m.Language.Version = env.PythonVersion
+		fmt.Printf("✓ Set Python version requirement: %s\n", env.PythonVersion)
+	}
+
+	if err := mergeImportedDependencies(m, manifestPath, env.Dependencies, isDev); err != nil {
+		return err
+	}
+
+	if env.PythonVersion != "" && m.Language.Version != env.PythonVersion {
+		fmt.Printf("\n💡 Note: Your aigogo.json Python version (%s) differs from %s (%s)\n",
+			m.Language.Version, filepath.Base(path), env.PythonVersion)
+	}
+	return nil
+}
+
+// foreignManifests describes the non-Python manifests dependencies can be
+// imported from
+var foreignManifests = map[string]struct {
+	flag     string
+	language string
+	find     func(dir string) (string, error)
+	parse    func(path string) (*depimport.ParsedDependencies, error)
+}{
+	"cargo": {"--from-cargo", "rust", depimport.FindCargoToml, depimport.ParseCargoToml},
+	"gomod": {"--from-gomod", "go", depimport.FindGoMod, depimport.ParseGoMod},
+}
+
+// addDependenciesFromForeignManifest imports dependencies from a Cargo.toml
+// or go.mod next to aigogo.json
+func addDependenciesFromForeignManifest(kind string, isDev bool) error {
+	source := foreignManifests[kind]
+
+	m, manifestDir, err := manifest.FindManifest()
+	if err != nil {
+		return fmt.Errorf("failed to find aigogo.json: %w\nRun 'aigg init' first", err)
+	}
+
+	manifestPath := filepath.Join(manifestDir, "aigogo.json")
+
+	if strings.ToLower(m.Language.Name) != source.language {
+		return fmt.Errorf("%s is only supported for %s projects (current language: %s)", source.flag, source.language, m.Language.Name)
+	}
+	if isDev && kind == "gomod" {
+		return fmt.Errorf("go.mod has no development dependencies; use 'aigg add dep --from-gomod'")
+	}
+
+	path, err := source.find(manifestDir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("📦 Reading dependencies from: %s\n", path)
+
+	deps, err := source.parse(path)
+	if err != nil {
+		return err
+	}
+	for _, w := range deps.Warnings {
+		fmt.Printf("⚠ Skipping %s\n", w)
+	}
+	fmt.Println()
+
+	if m.Dependencies == nil {
+		m.Dependencies = &manifest.Dependencies{
+			Runtime: []manifest.Dependency{},
+			Dev:     []manifest.Dependency{},
+		}
+	}
+
+	if deps.LanguageVersion != "" && m.Language.Version == "" {
+		m.Language.Version = deps.LanguageVersion
+		fmt.Printf("✓ Set %s version requirement: %s\n", source.language, deps.LanguageVersion)
+	}
+
+	imported := deps.Runtime
 	if isDev {
-		// Add only dev dependencies
-		fmt.Printf("Adding %d development dependencies...\n\n", len(deps.Dev))
-		for _, dep := range deps.Dev {
-			// Check if already exists
-			exists := false
-			for _, existing := range m.Dependencies.Dev {
-				if existing.Package == dep.Package {
-					fmt.Printf("⚠ Skipping '%s' (already exists)\n", dep.Package)
-					exists = true
-					skipped++
-					break
-				}
-			}
-			if !exists {
-				m.Dependencies.Dev = append(m.Dependencies.Dev, dep)
-				fmt.Printf("✓ Added %s %s\n", dep.Package, dep.Version)
-				added++
+		imported = deps.Dev
+	}
+	return mergeImportedDependencies(m, manifestPath, imported, isDev)
+}
+
+// mergeImportedDependencies adds imported dependencies to the runtime or dev
+// list, skipping packages that are already declared, and saves the manifest
+func mergeImportedDependencies(m *manifest.Manifest, manifestPath string, deps []manifest.Dependency, isDev bool) error {
+	target := &m.Dependencies.Runtime
+	depType := "runtime"
+	if isDev {
+		target = &m.Dependencies.Dev
+		depType = "development"
+	}
+
+	fmt.Printf("Adding %d %s dependencies...\n\n", len(deps), depType)
+
+	var added, skipped int
+	for _, dep := range deps {
+		exists := false
+		for _, existing := range *target {
+			// A package may be listed once per environment marker
+			if existing.Package == dep.Package && existing.Marker == dep.Marker {
+				fmt.Printf("⚠ Skipping '%s' (already exists)\n", dep.Package)
+				exists = true
+				skipped++
+				break
 			}
 		}
-	} else {
-		// Add only runtime dependencies
-		fmt.Printf("Adding %d runtime dependencies...\n\n", len(deps.Runtime))
-		for _, dep := range deps.Runtime {
-			// Check if already exists
-			exists := false
-			for _, existing := range m.Dependencies.Runtime {
-				if existing.Package == dep.Package {
-					fmt.Printf("⚠ Skipping '%s' (already exists)\n", dep.Package)
-					exists = true
-					skipped++
-					break
-				}
-			}
-			if !exists {
-				m.Dependencies.Runtime = append(m.Dependencies.Runtime, dep)
-				fmt.Printf("✓ Added %s %s\n", dep.Package, dep.Version)
-				added++
-			}
+		if !exists {
+			*target = append(*target, dep)
+			fmt.Printf("✓ Added %s\n", textDependency(dep))
+			added++
 		}
 	}
 
-	// Save updated manifest
-	if err := manifest.Save(manifestPath, m); err != nil {
+	if err := saveManifest(manifestPath, m); err != nil {
 		return fmt.Errorf("failed to save aigogo.json: %w", err)
 	}
 
@@ -666,11 +893,5 @@ func addDependenciesFromPyproject(isDev bool) error {
 		fmt.Printf(" (%d skipped)", skipped)
 	}
 	fmt.Println()
-
-	if deps.PythonVersion != "" && m.Language.Version != deps.PythonVersion {
-		fmt.Printf("\n💡 Note: Your aigogo.json Python version (%s) differs from pyproject.toml (%s)\n",
-			m.Language.Version, deps.PythonVersion)
-	}
-
 	return nil
 }
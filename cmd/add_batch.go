@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+)
+
+// isBatchAdd reports whether aigg add's arguments list the packages to add
+// elsewhere: on stdin with -, or in a file with --from-file
+func isBatchAdd(args []string) bool {
+	for _, arg := range args {
+		name := strings.TrimLeft(arg, "-")
+		if arg == "-" || (arg != name && (name == "from-file" || strings.HasPrefix(name, "from-file="))) {
+			return true
+		}
+	}
+	return false
+}
+
+// addBatchCmd runs aigg add - and aigg add --from-file <file>
+func addBatchCmd(args []string) error {
+	af := newAddFlags()
+	fromFile := af.flags.String("from-file", "", "Add every package listed in this file")
+
+	// parseFlags would take - for a flag
+	stdin := false
+	var rest []string
+	for _, arg := range args {
+		if arg == "-" {
+			stdin = true
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	pos, err := parseFlags(af.flags, rest)
+	if err != nil {
+		return err
+	}
+	if len(pos) > 0 {
+		return errcode.Errorf(errcode.Usage, "unexpected argument: %s\nUsage: aigg add --from-file <file> | aigg add - [--force] [--strict] [--dev|--optional] [--registry <host[/namespace]>]", pos[0])
+	}
+	if stdin == (*fromFile != "") {
+		return errcode.Errorf(errcode.Usage, "give either - (read stdin) or --from-file <file>, not both")
+	}
+	opts, err := af.options()
+	if err != nil {
+		return err
+	}
+
+	source, in := "stdin", io.Reader(os.Stdin)
+	if !stdin {
+		f, err := os.Open(*fromFile)
+		if err != nil {
+			return errcode.Errorf(errcode.NotFound, "failed to open package list: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		source, in = *fromFile, f
+	}
+	refs, err := readPackageList(in)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	if len(refs) == 0 {
+		return errcode.Errorf(errcode.Usage, "%s lists no packages", source)
+	}
+	return addPackages(refs, opts)
+}
+
+// readPackageList reads package references and directories, separated by
+// whitespace or newlines. # starts a comment, and a repeated entry is
+// read once.
+func readPackageList(r io.Reader) ([]string, error) {
+	var refs []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		for _, ref := range strings.Fields(line) {
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs, scanner.Err()
+}
+
+// addPackages adds every package in refs at once (see
+// aigogo.Client.AddPackages) and prints what was added
+func addPackages(refs []string, opts aigogo.AddOptions) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	result, err := client.AddPackages(commandContext(), refs, opts)
+	if err != nil {
+		return err
+	}
+
+	noteAdded(result)
+	fmt.Printf("\n✓ Added %d package(s) to %s\n", len(result.Added), result.LockPath)
+	for _, a := range result.Added {
+		source := a.Locked.Source
+		if a.Locked.IsLocal() {
+			source = a.Locked.Path
+		}
+		line := fmt.Sprintf("  %s@%s (%s)", a.Name, a.Version, source)
+		if len(a.Locked.Extras) > 0 {
+			line += " [extras: " + strings.Join(a.Locked.Extras, ", ") + "]"
+		}
+		fmt.Println(line)
+	}
+	if opts.Group != "" {
+		fmt.Printf("  Group: %s (skipped by 'aigg install --production')\n", opts.Group)
+	}
+
+	logging.Println("\nNext steps:")
+	logging.Println("  1. Run 'aigg install' to create import links")
+	logging.Println("  2. Commit aigogo.lock to version control")
+	return nil
+}
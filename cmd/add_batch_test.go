@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadPackageList(t *testing.T) {
+	list := `# Curated packages
+docker.io/org/utils:1.0.0
+  plots:2.0.0[viz]   # with extras
+../libs/local  docker.io/org/utils:1.0.0
+
+`
+	got, err := readPackageList(strings.NewReader(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"docker.io/org/utils:1.0.0", "plots:2.0.0[viz]", "../libs/local"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readPackageList() = %q, want %q", got, want)
+	}
+}
+
+func TestIsBatchAdd(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"-"}, true},
+		{[]string{"--dev", "-"}, true},
+		{[]string{"--from-file", "packages.txt"}, true},
+		{[]string{"-from-file=packages.txt", "--strict"}, true},
+		{[]string{"docker.io/org/utils:1.0.0", "--dev"}, false},
+		{[]string{"from-file"}, false},
+	}
+	for _, tt := range tests {
+		if got := isBatchAdd(tt.args); got != tt.want {
+			t.Errorf("isBatchAdd(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
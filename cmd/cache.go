@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+)
+
+const cacheUsage = `usage: aigg cache <ls|rm|prune|path> [args...]
+
+Subcommands:
+  ls [list flags]             List cached packages, as aigg list does
+  rm <ref>...                 Remove cached packages
+  rm --all [--force]          Remove every cached package
+  prune [--older-than <age>] [--max-size <size>] [--dry-run]
+                              Remove packages built or pulled more than <age> ago (e.g. 30d, 12h),
+                              then the oldest until the cache fits in <size> (e.g. 500MB)
+  path [ref]                  Print the cache directory, or a cached package's directory`
+
+func cacheCmd() *Command {
+	return &Command{
+		Name:        "cache",
+		Description: "List, remove and prune cached packages",
+		Run: func(args []string) error {
+			if len(args) == 0 {
+				return errcode.Errorf(errcode.Usage, "%s", cacheUsage)
+			}
+
+			switch args[0] {
+			case "ls":
+				return runCommand(listCmd(), args[1:])
+			case "rm":
+				return cacheRm(args[1:])
+			case "prune":
+				return cachePrune(args[1:])
+			case "path":
+				return cachePath(args[1:])
+			default:
+				return errcode.Errorf(errcode.Usage, "unknown subcommand '%s'\nValid subcommands: ls, rm, prune, path", args[0])
+			}
+		},
+	}
+}
+
+// cacheRm removes the cached packages named in args, or with --all every
+// cached package
+func cacheRm(args []string) error {
+	flags := flag.NewFlagSet("cache rm", flag.ContinueOnError)
+	all := flags.Bool("all", false, "Remove every cached package")
+	force := flags.Bool("force", false, "Skip the confirmation prompt of --all")
+	args, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if *all {
+		if len(args) > 0 {
+			return errcode.Errorf(errcode.Usage, "--all removes every cached package; it can't be combined with references")
+		}
+		return removeAllCached(*force)
+	}
+	if len(args) == 0 {
+		return errcode.Errorf(errcode.Usage, "usage: aigg cache rm <ref>... | aigg cache rm --all [--force]")
+	}
+
+	remover := docker.NewRemover()
+	for _, imageRef := range args {
+		if err := remover.Remove(imageRef); err != nil {
+			return fmt.Errorf("failed to remove image: %w", err)
+		}
+		fmt.Printf("Successfully removed %s\n", imageRef)
+	}
+	return nil
+}
+
+// removeAllCached removes every cached package, after asking unless force
+// is set
+func removeAllCached(force bool) error {
+	lister := docker.NewLister()
+	packages, err := lister.List()
+	if err != nil {
+		return fmt.Errorf("failed to list packages: %w", err)
+	}
+
+	if len(packages) == 0 {
+		fmt.Println("No cached packages to remove.")
+		return nil
+	}
+
+	// Show what will be removed
+	fmt.Printf("This will remove %d cached package(s):\n\n", len(packages))
+	for _, pkg := range packages {
+		fmt.Printf("  • %s\n", pkg)
+	}
+	fmt.Println()
+
+	// Prompt for confirmation unless --force
+	if !force {
+		fmt.Print("Are you sure you want to remove ALL cached packages? (yes/no): ")
+		reader := bufio.NewReader(os.Stdin)
+		response, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read confirmation: %w", err)
+		}
+
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "yes" {
+			fmt.Println("Operation cancelled.")
+			return nil
+		}
+	}
+
+	if err := docker.NewRemover().RemoveAll(); err != nil {
+		return fmt.Errorf("failed to remove all packages: %w", err)
+	}
+
+	fmt.Printf("Successfully removed %d package(s).\n", len(packages))
+	return nil
+}
+
+// cachePrune removes the cached packages a prune policy selects
+func cachePrune(args []string) error {
+	flags := flag.NewFlagSet("cache prune", flag.ContinueOnError)
+	olderThan := flags.String("older-than", "", "Remove packages built or pulled longer ago than this, e.g. 30d, 2w or 12h")
+	maxSize := flags.String("max-size", "", "Then remove the oldest packages until the cache fits in this size, e.g. 500MB or 2GB")
+	dryRun := flags.Bool("dry-run", false, "Print what would be removed without removing it")
+	args, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return errcode.Errorf(errcode.Usage, "unexpected argument: %s", args[0])
+	}
+	if *olderThan == "" && *maxSize == "" {
+		return errcode.Errorf(errcode.Usage, "usage: aigg cache prune [--older-than <age>] [--max-size <size>] [--dry-run]\nGive --older-than, --max-size or both")
+	}
+
+	var policy prunePolicy
+	if *olderThan != "" {
+		if policy.olderThan, err = parseAge(*olderThan); err != nil {
+			return errcode.Errorf(errcode.Usage, "invalid --older-than: %v", err)
+		}
+	}
+	if *maxSize != "" {
+		if policy.maxSize, err = parseByteSize(*maxSize); err != nil {
+			return errcode.Errorf(errcode.Usage, "invalid --max-size: %v", err)
+		}
+	}
+
+	if !*dryRun {
+		fl, err := docker.LockCache()
+		if err != nil {
+			return err
+		}
+		defer fl.Release()
+	}
+
+	images, err := docker.NewLister().ListDetailed()
+	if err != nil {
+		return fmt.Errorf("failed to list images: %w", err)
+	}
+
+	prune := policy.selectImages(images, time.Now())
+	if len(prune) == 0 {
+		fmt.Printf("Nothing to prune (%d cached)\n", len(images))
+		return nil
+	}
+
+	verb := "Removed"
+	if *dryRun {
+		verb = "Would remove"
+	}
+	var freed int64
+	for _, img := range prune {
+		if !*dryRun {
+			if err := os.RemoveAll(img.Path); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", img.Name, err)
+			}
+		}
+		freed += img.Size
+		fmt.Printf("%s %s (%s)\n", verb, img.Name, formatSize(img.Size))
+	}
+	if *dryRun {
+		fmt.Printf("\n%d package(s) would be pruned, freeing %s\n", len(prune), formatSize(freed))
+	} else {
+		fmt.Printf("\n✓ Pruned %d package(s), freed %s\n", len(prune), formatSize(freed))
+	}
+	return nil
+}
+
+// cachePath prints the cache directory, or the directory of a cached
+// package
+func cachePath(args []string) error {
+	switch len(args) {
+	case 0:
+		dir, err := docker.CacheDir()
+		if err != nil {
+			return err
+		}
+		fmt.Println(dir)
+		return nil
+	case 1:
+		dir := docker.GetCachePath(args[0])
+		if dir == "" {
+			return errcode.Errorf(errcode.NotFound, "%s is not in the local cache", args[0])
+		}
+		fmt.Println(dir)
+		return nil
+	default:
+		return errcode.Errorf(errcode.Usage, "usage: aigg cache path [ref]")
+	}
+}
+
+// prunePolicy is what aigg cache prune removes: packages built or pulled
+// longer than olderThan ago, then the oldest until the rest take up no more
+// than maxSize. A zero field isn't applied.
+type prunePolicy struct {
+	olderThan time.Duration
+	maxSize   int64
+}
+
+// selectImages returns the images the policy removes, oldest first
+func (p prunePolicy) selectImages(images []docker.CachedImage, now time.Time) []docker.CachedImage {
+	sorted := append([]docker.CachedImage(nil), images...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].BuildTime.Before(sorted[j].BuildTime)
+	})
+
+	var total int64
+	for _, img := range sorted {
+		total += img.Size
+	}
+
+	var prune []docker.CachedImage
+	for _, img := range sorted {
+		expired := p.olderThan > 0 && now.Sub(img.BuildTime) > p.olderThan
+		oversized := p.maxSize > 0 && total > p.maxSize
+		if !expired && !oversized {
+			break
+		}
+		prune = append(prune, img)
+		total -= img.Size
+	}
+	return prune
+}
+
+// parseAge parses a duration such as 12h, or a number of days or weeks such
+// as 30d or 2w
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			days, err := strconv.Atoi(n)
+			if err != nil || days <= 0 {
+				return 0, fmt.Errorf("%s (expected a positive number of days or weeks, e.g. 30d or 2w)", s)
+			}
+			return time.Duration(days) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s (expected an age such as 30d, 2w or 12h)", s)
+	}
+	return d, nil
+}
+
+// parseByteSize parses a size such as 500MB or 2GB, in units of 1024 as
+// formatSize prints them
+func parseByteSize(s string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	number := strings.TrimRight(upper, "KMGTB")
+	unit := strings.TrimSpace(upper[len(number):])
+	multipliers := map[string]int64{"": 1, "B": 1, "K": 1 << 10, "KB": 1 << 10, "M": 1 << 20, "MB": 1 << 20, "G": 1 << 30, "GB": 1 << 30, "T": 1 << 40, "TB": 1 << 40}
+	multiplier, ok := multipliers[unit]
+	n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if !ok || err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s (expected a size such as 500MB or 2GB)", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+)
+
+func TestPrunePolicySelectImages(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	images := []docker.CachedImage{
+		{Name: "new:1.0.0", BuildTime: now.Add(-time.Hour), Size: 300},
+		{Name: "old:1.0.0", BuildTime: now.Add(-40 * 24 * time.Hour), Size: 100},
+		{Name: "mid:1.0.0", BuildTime: now.Add(-10 * 24 * time.Hour), Size: 200},
+	}
+
+	names := func(images []docker.CachedImage) []string {
+		var names []string
+		for _, img := range images {
+			names = append(names, img.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name   string
+		policy prunePolicy
+		want   []string
+	}{
+		{"older than", prunePolicy{olderThan: 30 * 24 * time.Hour}, []string{"old:1.0.0"}},
+		{"max size", prunePolicy{maxSize: 300}, []string{"old:1.0.0", "mid:1.0.0"}},
+		{"both", prunePolicy{olderThan: 5 * 24 * time.Hour, maxSize: 1000}, []string{"old:1.0.0", "mid:1.0.0"}},
+		{"nothing", prunePolicy{olderThan: 90 * 24 * time.Hour, maxSize: 600}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(tt.policy.selectImages(images, now))
+			if len(got) != len(tt.want) {
+				t.Fatalf("selectImages() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("selectImages() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+	}
+	for in, want := range tests {
+		if got, err := parseAge(in); err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "d", "-1d", "0h", "soon"} {
+		if _, err := parseAge(in); err == nil {
+			t.Errorf("parseAge(%q) succeeded", in)
+		}
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]int64{
+		"500":    500,
+		"10KB":   10 << 10,
+		"500MB":  500 << 20,
+		"1.5 GB": 3 << 29,
+		"2g":     2 << 30,
+	}
+	for in, want := range tests {
+		if got, err := parseByteSize(in); err != nil || got != want {
+			t.Errorf("parseByteSize(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "MB", "0", "-5MB", "5XB"} {
+		if _, err := parseByteSize(in); err == nil {
+			t.Errorf("parseByteSize(%q) succeeded", in)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func checkIgnoreCmd() *Command {
+	return &Command{
+		Name:        "check-ignore",
+		Description: "Explain whether paths are packaged or ignored",
+		Run: func(args []string) error {
+			if len(args) == 0 {
+				return errcode.Errorf(errcode.Usage, "usage: aigg check-ignore <path>...")
+			}
+			return runCheckIgnore(args)
+		},
+	}
+}
+
+// runCheckIgnore prints, for each path, whether builds package it and
+// which pattern decided that
+func runCheckIgnore(paths []string) error {
+	m, manifestDir, err := manifest.FindManifest()
+	if err != nil {
+		return fmt.Errorf("failed to find aigogo.json: %w\nRun 'aigg init' first", err)
+	}
+
+	lines, err := explainPaths(manifestDir, m, paths)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// explainPaths returns one line per path explaining whether it is packaged
+func explainPaths(manifestDir string, m *manifest.Manifest, paths []string) ([]string, error) {
+	im, err := manifest.NewIgnoreManager(manifestDir, m.Files.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ignore patterns: %w", err)
+	}
+
+	// The files.include list is only resolved when a file isn't ignored
+	var included map[string]bool
+	isIncluded := func(rel string) (bool, error) {
+		if included == nil {
+			files, err := aigogo.IncludedFiles(manifestDir, m)
+			if err != nil {
+				return false, err
+			}
+			included = make(map[string]bool, len(files))
+			for _, f := range files {
+				included[f] = true
+			}
+		}
+		return included[rel], nil
+	}
+
+	var lines []string
+	for _, path := range paths {
+		rel, isDir, err := packagePath(manifestDir, path)
+		if err != nil {
+			return nil, err
+		}
+
+		match := im.Explain(rel, isDir)
+		if match != nil && match.Ignored {
+			lines = append(lines, fmt.Sprintf("%s: %s", path, describeIgnoreMatch(match)))
+			continue
+		}
+
+		if !isDir {
+			ok, err := isIncluded(rel)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				lines = append(lines, fmt.Sprintf("%s: not ignored, but files.include (%s) doesn't select it", path, describeInclude(&m.Files)))
+				continue
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", path, describeIgnoreMatch(match)))
+	}
+	return lines, nil
+}
+
+// packagePath returns path relative to the package directory, with forward
+// slashes, and whether it is a directory. Paths that don't exist are
+// files unless they end with a slash.
+func packagePath(manifestDir, path string) (string, bool, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid path %s: %w", path, err)
+	}
+	rel, err := filepath.Rel(manifestDir, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false, fmt.Errorf("%s is outside the package directory %s", path, manifestDir)
+	}
+	if rel == "." {
+		return "", false, fmt.Errorf("%s is the package directory itself", path)
+	}
+
+	isDir := strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator))
+	if info, err := os.Stat(abs); err == nil {
+		isDir = info.IsDir()
+	}
+	return filepath.ToSlash(rel), isDir, nil
+}
+
+// describeIgnoreMatch explains the outcome of an ignore check: the
+// deciding pattern and where it comes from. match may be nil.
+func describeIgnoreMatch(match *manifest.IgnoreMatch) string {
+	if match == nil {
+		return "included (no exclude pattern matches)"
+	}
+
+	var source string
+	switch match.Source {
+	case manifest.IgnoreSourceDefaults:
+		source = "built-in default " + match.Pattern
+	case manifest.IgnoreSourceManifest:
+		source = "files.exclude in aigogo.json: " + match.Pattern
+	default:
+		source = fmt.Sprintf("%s:%d: %s", match.Source, match.Line, match.Pattern)
+	}
+
+	if !match.Ignored {
+		return "included, re-included by " + source
+	}
+	if match.Dir != "" {
+		return fmt.Sprintf("excluded, inside directory %s/ excluded by %s", match.Dir, source)
+	}
+	return "excluded by " + source
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func TestExplainPaths(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	for _, f := range []string{"utils.py", "notes.txt", "app.log", "keep.log", "tests/test_utils.py", "sub/x.tmp"} {
+		if err := os.MkdirAll(filepath.Dir(f), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(f, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(".aigogoignore", []byte("*.log\n!keep.log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("sub/.aigogoignore", []byte("*.tmp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &manifest.Manifest{
+		Name:     "utils",
+		Version:  "1.0.0",
+		Language: manifest.Language{Name: "python"},
+		Files:    manifest.FileSpec{Include: []interface{}{"*.py", "*.log"}, Exclude: []string{"tests/"}},
+	}
+
+	got, err := explainPaths(dir, m, []string{"utils.py", "notes.txt", "app.log", "keep.log", "tests/test_utils.py", "tests/", "sub/x.tmp", "node_modules/x.js"})
+	if err != nil {
+		t.Fatalf("explainPaths failed: %v", err)
+	}
+	want := []string{
+		"utils.py: included (no exclude pattern matches)",
+		`notes.txt: not ignored, but files.include (*.py, *.log) doesn't select it`,
+		"app.log: excluded by .aigogoignore:1: *.log",
+		"keep.log: included, re-included by .aigogoignore:2: !keep.log",
+		"tests/test_utils.py: excluded, inside directory tests/ excluded by files.exclude in aigogo.json: tests/",
+		"tests/: excluded by files.exclude in aigogo.json: tests/",
+		"sub/x.tmp: excluded by sub/.aigogoignore:1: *.tmp",
+		"node_modules/x.js: excluded, inside directory node_modules/ excluded by built-in default node_modules/",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("explainPaths() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := explainPaths(dir, m, []string{"../elsewhere.py"}); err == nil || !strings.Contains(err.Error(), "outside the package directory") {
+		t.Errorf("runCheckIgnore outside the package = %v, want an error", err)
+	}
+}
@@ -5,13 +5,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/store"
 )
 
 func cleanCmd() *Command {
 	flags := flag.NewFlagSet("clean", flag.ContinueOnError)
-	cleanEnvs := flags.Bool("envs", false, "Remove all exec environments (~/.aigogo/envs/)")
-	cleanCache := flags.Bool("cache", false, "Remove build/pull cache (~/.aigogo/cache/)")
-	cleanStore := flags.Bool("store", false, "Remove content-addressable store (~/.aigogo/store/)")
+	cleanEnvs := flags.Bool("envs", false, "Remove all exec environments (envs/ in the data directory)")
+	cleanCache := flags.Bool("cache", false, "Remove build/pull cache")
+	cleanStore := flags.Bool("store", false, "Remove content-addressable store (store/ in the data directory)")
 	cleanAll := flags.Bool("all", false, "Remove envs, cache, and store")
 
 	return &Command{
@@ -30,30 +33,48 @@ func cleanCmd() *Command {
 				*cleanStore = true
 			}
 
-			home, err := os.UserHomeDir()
-			if err != nil {
-				return fmt.Errorf("failed to get home directory: %w", err)
-			}
-
-			baseDir := filepath.Join(home, ".aigogo")
-
 			if *cleanEnvs {
-				dir := filepath.Join(baseDir, "envs")
+				dir, err := envsDir()
+				if err != nil {
+					return err
+				}
 				if err := cleanDirectory(dir, "exec environments"); err != nil {
 					return err
 				}
 			}
 
 			if *cleanCache {
-				dir := filepath.Join(baseDir, "cache")
-				if err := cleanDirectory(dir, "build/pull cache"); err != nil {
+				dir, err := docker.CacheDir()
+				if err != nil {
+					return err
+				}
+				fl, err := docker.LockCache()
+				if err != nil {
+					return err
+				}
+				err = cleanDirectory(dir, "build/pull cache")
+				fl.Release()
+				if err != nil {
 					return err
 				}
 			}
 
 			if *cleanStore {
-				dir := filepath.Join(baseDir, "store")
-				if err := cleanDirectory(dir, "content-addressable store"); err != nil {
+				dir, err := store.DefaultDir()
+				if err != nil {
+					return err
+				}
+				cas, err := store.NewStoreAt(dir)
+				if err != nil {
+					return err
+				}
+				fl, err := cas.Lock()
+				if err != nil {
+					return err
+				}
+				err = cleanDirectory(dir, "content-addressable store")
+				fl.Release()
+				if err != nil {
 					return err
 				}
 			}
@@ -65,12 +86,18 @@ func cleanCmd() *Command {
 
 // showDiskUsage displays the size of each aigogo directory
 func showDiskUsage() error {
-	home, err := os.UserHomeDir()
+	envs, err := envsDir()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return err
+	}
+	cacheDir, err := docker.CacheDir()
+	if err != nil {
+		return err
+	}
+	storeDir, err := store.DefaultDir()
+	if err != nil {
+		return err
 	}
-
-	baseDir := filepath.Join(home, ".aigogo")
 
 	type dirInfo struct {
 		name string
@@ -79,9 +106,9 @@ func showDiskUsage() error {
 	}
 
 	dirs := []dirInfo{
-		{"Exec environments", filepath.Join(baseDir, "envs"), "aigg clean --envs"},
-		{"Build/pull cache", filepath.Join(baseDir, "cache"), "aigg clean --cache"},
-		{"Package store", filepath.Join(baseDir, "store"), "aigg clean --store"},
+		{"Exec environments", envs, "aigg clean --envs"},
+		{"Build/pull cache", cacheDir, "aigg clean --cache"},
+		{"Package store", storeDir, "aigg clean --store"},
 	}
 
 	fmt.Println("aigogo disk usage:")
@@ -96,6 +123,7 @@ func showDiskUsage() error {
 		} else {
 			fmt.Printf("  %-22s %8s\n", d.name+":", "empty")
 		}
+		fmt.Printf("  %-22s %s\n", "", d.path)
 	}
 
 	fmt.Println()
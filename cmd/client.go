@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+)
+
+// newClient returns a pkg/aigogo client for the working directory that
+// reports as aigg does: progress through the logging package, so --quiet
+// and --verbose apply, and warnings and script output to stdout and
+// stderr. It's built per command, as --plain swaps os.Stdout.
+func newClient() (*aigogo.Client, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current directory: %w", err)
+	}
+	c := aigogo.NewClient(cwd)
+	c.SetInput(os.Stdin)
+	c.SetOutput(os.Stdout, os.Stderr)
+	c.SetLogger(progressLogger{})
+	c.SetConcurrency(maxParallelFetches)
+	c.SetSettingsLoader(loadProjectSettings)
+	return c, nil
+}
+
+// progressLogger reports a client's progress through the logging package
+type progressLogger struct{}
+
+func (progressLogger) Printf(format string, args ...interface{}) {
+	logging.Printf(format, args...)
+}
+
+func (progressLogger) Verbosef(format string, args ...interface{}) {
+	logging.Verbosef(format, args...)
+}
+
+// stderrLogger reports a client's progress on stderr, for commands whose
+// stdout is the content they print
+type stderrLogger struct{}
+
+func (stderrLogger) Printf(format string, args ...interface{}) {
+	writeProgress(os.Stderr, format, args...)
+}
+
+func (stderrLogger) Verbosef(format string, args ...interface{}) {
+	if logging.Enabled(logging.LevelVerbose) {
+		writeProgress(os.Stderr, format, args...)
+	}
+}
+
+// writeProgress writes a progress line unless --quiet is set
+func writeProgress(w io.Writer, format string, args ...interface{}) {
+	if logging.Enabled(logging.LevelNormal) {
+		_, _ = fmt.Fprintf(w, format, args...)
+	}
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/scaffold"
+	"github.com/aupeachmo/aigogo/pkg/userdirs"
+)
+
+// completeCacheTTL is how long registry tags are reused for completion, so
+// pressing tab repeatedly doesn't query the registry each time
+const completeCacheTTL = 2 * time.Minute
+
+// completeCacheFile is the completion cache in the cache directory
+const completeCacheFile = "completion-cache.json"
+
+// completeKinds are the kinds of suggestions aigg __complete prints
+var completeKinds = map[string]func(word string) []string{
+	"packages":  func(string) []string { return lockPackageNames() },
+	"deps":      func(string) []string { return manifestDependencies(groupRuntime) },
+	"dev":       func(string) []string { return manifestDependencies(groupDev) },
+	"peer":      func(string) []string { return manifestDependencies(groupPeer) },
+	"files":     func(string) []string { return manifestFiles() },
+	"images":    func(string) []string { return cachedImages() },
+	"refs":      completeRefs,
+	"templates": func(string) []string { return templateNames() },
+}
+
+// completeCmd is the hidden command the shell completion scripts call for
+// suggestions that depend on the project, the cache or a registry. It
+// prints the suggestions starting with word, one per line, and nothing when
+// there are none or they can't be found.
+func completeCmd() *Command {
+	return &Command{
+		Name:        "__complete",
+		Description: "Print completion suggestions for the shell completion scripts",
+		Run: func(args []string) error {
+			if len(args) == 0 || len(args) > 2 {
+				return errcode.Errorf(errcode.Usage, "usage: aigg __complete <kind> [word]")
+			}
+			suggest, ok := completeKinds[args[0]]
+			if !ok {
+				return errcode.Errorf(errcode.Usage, "unknown completion kind: %s", args[0])
+			}
+			word := ""
+			if len(args) == 2 {
+				word = args[1]
+			}
+
+			for _, s := range suggest(word) {
+				if strings.HasPrefix(s, word) {
+					fmt.Println(s)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// lockPackageNames returns the names of the packages in the project's
+// aigogo.lock
+func lockPackageNames() []string {
+	_, lock, err := lockfile.FindLockFile()
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for name := range lock.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// manifestDependencies returns the names of the dependencies in a group of
+// the package's aigogo.json
+func manifestDependencies(group string) []string {
+	m, _, err := manifest.FindManifest()
+	if err != nil || m.Dependencies == nil {
+		return nil
+	}
+	deps := m.Dependencies.Runtime
+	switch group {
+	case groupDev:
+		deps = m.Dependencies.Dev
+	case groupPeer:
+		deps = m.Dependencies.Peer
+	}
+	var names []string
+	for _, dep := range deps {
+		names = append(names, dep.Package)
+	}
+	return names
+}
+
+// manifestFiles returns the include patterns of the package's aigogo.json
+func manifestFiles() []string {
+	m, _, err := manifest.FindManifest()
+	if err != nil {
+		return nil
+	}
+	patterns, _ := m.Files.GetIncludePatterns()
+	return patterns
+}
+
+// cachedImages returns the references of the locally built and pulled
+// packages
+func cachedImages() []string {
+	images, err := docker.NewLister().List()
+	if err != nil {
+		return nil
+	}
+	sort.Strings(images)
+	return images
+}
+
+// templateNames returns the names of the built-in and user templates
+func templateNames() []string {
+	userDir, _ := scaffold.UserTemplatesDir()
+	names, err := scaffold.List(userDir)
+	if err != nil {
+		return nil
+	}
+	return names
+}
+
+// completeRefs suggests package references: once word names a registry
+// repository and a colon, the repository's tags from the registry,
+// otherwise the cached packages
+func completeRefs(word string) []string {
+	i := strings.LastIndex(word, ":")
+	if i < 0 || !strings.Contains(word[:i], "/") {
+		return cachedImages()
+	}
+	repo := word[:i]
+	tags := registryTags(repo)
+	refs := make([]string, len(tags))
+	for j, tag := range tags {
+		refs[j] = repo + ":" + tag
+	}
+	return refs
+}
+
+// completeCacheEntry is one cached list of registry tags
+type completeCacheEntry struct {
+	Time time.Time `json:"time"`
+	Tags []string  `json:"tags"`
+}
+
+// registryTags returns the tags of a registry repository, from the
+// completion cache when they were fetched within completeCacheTTL
+func registryTags(repo string) []string {
+	dir, err := userdirs.CacheDir()
+	if err != nil {
+		return nil
+	}
+	cachePath := filepath.Join(dir, completeCacheFile)
+
+	cache := map[string]completeCacheEntry{}
+	if data, err := os.ReadFile(cachePath); err == nil {
+		_ = json.Unmarshal(data, &cache)
+	}
+	if entry, ok := cache[repo]; ok && time.Since(entry.Time) < completeCacheTTL {
+		return entry.Tags
+	}
+
+	tags, err := docker.NewPuller().Tags(commandContext(), repo)
+	if err != nil {
+		return nil
+	}
+	sort.Strings(tags)
+
+	// Drop expired entries so the cache doesn't grow with every repository
+	for r, entry := range cache {
+		if time.Since(entry.Time) >= completeCacheTTL {
+			delete(cache, r)
+		}
+	}
+	cache[repo] = completeCacheEntry{Time: time.Now(), Tags: tags}
+	if data, err := json.Marshal(cache); err == nil {
+		_ = os.MkdirAll(filepath.Dir(cachePath), 0755)
+		_ = os.WriteFile(cachePath, data, 0644)
+	}
+	return tags
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func TestCompleteKinds(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	m := &manifest.Manifest{
+		Name:     "utils",
+		Version:  "1.0.0",
+		Language: manifest.Language{Name: "python", Version: ">=3.8"},
+		Files:    manifest.FileSpec{Include: []string{"utils.py", "lib/helpers.py"}},
+		Dependencies: &manifest.Dependencies{
+			Runtime: []manifest.Dependency{{Package: "requests", Version: ">=2.0"}},
+			Dev:     []manifest.Dependency{{Package: "pytest", Version: ">=7.0"}},
+		},
+	}
+	if err := manifest.Save(filepath.Join(dir, "aigogo.json"), m); err != nil {
+		t.Fatal(err)
+	}
+	lock := lockfile.New()
+	lock.Add("utils", lockfile.LockedPackage{Version: "1.0.0", Source: "docker.io/org/utils:1.0.0"})
+	lock.Add("agents", lockfile.LockedPackage{Version: "2.0.0", Source: "docker.io/org/agents:2.0.0"})
+	if err := lockfile.Save(filepath.Join(dir, lockfile.LockFileName), lock); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]string{
+		"packages": {"agents", "utils"},
+		"deps":     {"requests"},
+		"dev":      {"pytest"},
+		"peer":     nil,
+		"files":    {"utils.py", "lib/helpers.py"},
+	}
+	for kind, want := range tests {
+		if got := completeKinds[kind](""); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %q, want %q", kind, got, want)
+		}
+	}
+}
+
+func TestCompleteRefsFromCache(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+
+	// Tags fetched within completeCacheTTL are reused without a request
+	cache := map[string]completeCacheEntry{
+		"ghcr.io/org/utils": {Time: time.Now(), Tags: []string{"1.0.0", "1.1.0"}},
+	}
+	data, _ := json.Marshal(cache)
+	if err := os.MkdirAll(filepath.Join(home, ".aigogo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".aigogo", completeCacheFile), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"ghcr.io/org/utils:1.0.0", "ghcr.io/org/utils:1.1.0"}
+	if got := completeRefs("ghcr.io/org/utils:1"); !reflect.DeepEqual(got, want) {
+		t.Errorf("completeRefs() = %q, want %q", got, want)
+	}
+}
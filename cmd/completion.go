@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
 )
 
 func completionCmd() *Command {
@@ -10,7 +12,7 @@ func completionCmd() *Command {
 		Description: "Generate shell completion scripts",
 		Run: func(args []string) error {
 			if len(args) < 1 {
-				return fmt.Errorf("usage: aigg completion <bash|zsh|fish>\n\nExamples:\n  # Bash\n  aigg completion bash | sudo tee /etc/bash_completion.d/aigg && sudo chmod 755 /etc/bash_completion.d/aigg\n  # or add to ~/.bashrc:\n  source <(aigg completion bash)\n\n  # Zsh\n  aigg completion zsh > ~/.zsh/completions/_aigg\n  # or add to ~/.zshrc:\n  source <(aigg completion zsh)\n\n  # Fish\n  aigg completion fish > ~/.config/fish/completions/aigg.fish")
+				return errcode.Errorf(errcode.Usage, "usage: aigg completion <bash|zsh|fish>\n\nExamples:\n  # Bash\n  aigg completion bash | sudo tee /etc/bash_completion.d/aigg && sudo chmod 755 /etc/bash_completion.d/aigg\n  # or add to ~/.bashrc:\n  source <(aigg completion bash)\n\n  # Zsh\n  aigg completion zsh > ~/.zsh/completions/_aigg\n  # or add to ~/.zshrc:\n  source <(aigg completion zsh)\n\n  # Fish\n  aigg completion fish > ~/.config/fish/completions/aigg.fish")
 			}
 
 			shell := args[0]
@@ -33,66 +35,239 @@ func completionCmd() *Command {
 
 const bashCompletion = `# aigg bash completion script
 
+# _aigg_dynamic prints suggestions aigg finds in the project, the cache or a
+# registry: aigg __complete <packages|deps|dev|peer|files|images|refs|templates> [word]
+_aigg_dynamic() {
+    aigg __complete "$@" 2>/dev/null
+}
+
 _aigg_completions() {
     local cur prev words cword
-    _init_completion || return
+    # Package references contain colons: keep them in one word
+    _init_completion -n : || return
 
     # Main commands
-    local commands="init add install uninstall exec clean rm validate scan build push pull login logout list show-deps remove remove-all delete search version completion"
+    local commands="init add install uninstall doctor ide hooks exec clean migrate rm files check-ignore validate lint scan build push pack pull export import diff login logout whoami list info tree show-deps licenses cache serve proxy mcp remove remove-all delete history search config schema version self-update completion"
+    local global_flags="--quiet --verbose --debug --trace --log-file --color --plain --output --json"
+    # Plugins: aigg-<name> executables on PATH
+    local plugins=$(compgen -c aigg- 2>/dev/null | sed 's/^aigg-//' | sort -u)
 
     # Subcommands for add/rm
-    local add_subcommands="file dep dev"
-    local rm_subcommands="file dep dev"
+    local add_subcommands="file dep dev peer"
+    local rm_subcommands="file dep dev peer"
+    local files_subcommands="freeze"
+    local ide_subcommands="setup"
+    local hooks_subcommands="install run"
+    local hooks_names="pre-commit pre-push"
+    local config_subcommands="get set"
+    local cache_subcommands="ls rm prune path"
+    local config_keys="registry namespace.python namespace.javascript store install.mode install.python_layout tag_policy.mutable tag_policy.overwrite cache color plain output concurrency insecure_registries retry.attempts retry.backoff log_file history auth.encrypt auth.key_command"
 
     # Flags
-    local build_flags="--force --no-validate"
-    local push_flags="--from"
+    local init_flags="--no-detect --import-deps --template --yes -y"
+    local build_flags="--force --no-validate --no-cache --ignore-scripts"
+    local install_flags="--ignore-scripts --render-templates --force --strict --frozen --offline --production --tsconfig --python --max-extract-size"
+    local push_flags="--from --deprecate --replacement --undeprecate --allow-private --dry-run --registry"
     local delete_flags="--all"
+    local export_flags="-o --format --force"
+    local diff_flags="--summary --exit-code -U --remote --force-large"
+    local pack_flags="-o --tag --force"
+    local import_flags="--tag --force --max-extract-size"
     local add_file_flags="--force"
-    local add_dep_flags="--from-pyproject"
-    local add_dev_flags="--from-pyproject"
+    local add_dep_flags="--from-pyproject --from-requirements --from-lock --compatible --from-conda --from-cargo --from-gomod --language"
+    local add_dev_flags="--from-pyproject --from-requirements --from-lock --compatible --from-conda --from-cargo --language"
     local show_deps_flags="--format"
-    local show_deps_formats="text pyproject pep621 poetry requirements pip npm package-json yarn"
+    local show_deps_formats="text pyproject pep621 poetry uv requirements pip conda npm package-json yarn yarn-berry berry pnpm pnpm-workspace pnpm-catalog gemfile bundler maven pom gradle nuget csproj composer"
     local clean_flags="--envs --cache --store --all"
-    local login_flags="-u -p --dockerhub"
+    local serve_flags="--addr --remote"
+    local proxy_flags="--addr --dir --default-registry --tag-ttl --offline"
+    local mcp_flags="--read-only --offline"
+    local migrate_flags="--dry-run"
+    local list_flags="--filter --language --sort --remote --long"
+    local history_flags="--command --ref --user --since --failed -n"
+    local cache_rm_flags="--all --force"
+    local cache_prune_flags="--older-than --max-size --dry-run"
+    local doctor_flags="--fix --python"
+    local login_flags="-u -p --dockerhub --encrypt --decrypt"
+    local scan_flags="--offline --no-cache"
+    local validate_flags="--no-cache --lock --strict --format --schema"
+    local lint_flags="--strict --format --list-rules"
+    local files_freeze_flags="--dry-run --force"
+    local ide_setup_flags="--vscode --pycharm"
+    local hooks_install_flags="--force --pre-commit-config"
+    local schema_flags="--output"
+    local licenses_flags="--allow --deny --offline"
+    local version_bumps="patch minor major"
+    local version_flags="--git"
+    local self_update_flags="--check --force --skip-verify"
 
-    # Get cached images for completion
-    local cached_images=""
-    if [ -d "$HOME/.aigogo/cache" ]; then
-        cached_images=$(aigg list 2>/dev/null | grep -E '^[🔨📦]' | awk '{print $2}' || echo "")
+    # The value of --color
+    if [[ $prev == "--color" ]]; then
+        COMPREPLY=($(compgen -W "auto always never" -- "$cur"))
+        return
+    fi
+    # The value of --output (schema's own --output is a file)
+    if [[ $prev == "--output" && " ${words[*]} " != *" schema "* ]]; then
+        COMPREPLY=($(compgen -W "text github" -- "$cur"))
+        return
+    fi
+    # The value of --log-file
+    if [[ $prev == "--log-file" ]]; then
+        COMPREPLY=($(compgen -f -- "$cur"))
+        return
     fi
 
     case $cword in
         1)
-            # Complete main commands
-            COMPREPLY=($(compgen -W "$commands" -- "$cur"))
+            # Complete main commands, or the global options before them
+            if [[ $cur == -* ]]; then
+                COMPREPLY=($(compgen -W "$global_flags" -- "$cur"))
+            else
+                COMPREPLY=($(compgen -W "$commands $plugins" -- "$cur"))
+            fi
             ;;
         2)
             # Complete subcommands or arguments based on previous command
             case $prev in
                 add)
-                    COMPREPLY=($(compgen -W "$add_subcommands" -- "$cur"))
+                    # ./, ../ and / start a local package directory
+                    if [[ $cur == .* || $cur == /* ]]; then
+                        COMPREPLY=($(compgen -d -- "$cur"))
+                    elif [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "--from-file - --force --strict --dev --optional --registry --max-extract-size" -- "$cur"))
+                    else
+                        COMPREPLY=($(compgen -W "$add_subcommands $(_aigg_dynamic refs "$cur")" -- "$cur"))
+                        __ltrim_colon_completions "$cur"
+                    fi
                     ;;
                 rm)
                     COMPREPLY=($(compgen -W "$rm_subcommands" -- "$cur"))
                     ;;
+                files)
+                    COMPREPLY=($(compgen -W "$files_subcommands" -- "$cur"))
+                    ;;
+                ide)
+                    COMPREPLY=($(compgen -W "$ide_subcommands" -- "$cur"))
+                    ;;
+                hooks)
+                    COMPREPLY=($(compgen -W "$hooks_subcommands" -- "$cur"))
+                    ;;
+                config)
+                    COMPREPLY=($(compgen -W "$config_subcommands" -- "$cur"))
+                    ;;
+                cache)
+                    COMPREPLY=($(compgen -W "$cache_subcommands" -- "$cur"))
+                    ;;
+                check-ignore)
+                    COMPREPLY=($(compgen -f -- "$cur"))
+                    ;;
                 completion)
                     COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur"))
                     ;;
                 exec)
                     # Complete with package names from aigogo.lock
-                    local lock_packages=""
-                    if [ -f "aigogo.lock" ]; then
-                        lock_packages=$(python3 -c "import json; f=open('aigogo.lock'); d=json.load(f); print(' '.join(d.get('packages',{}).keys()))" 2>/dev/null || echo "")
-                    fi
-                    COMPREPLY=($(compgen -W "$lock_packages" -- "$cur"))
+                    COMPREPLY=($(compgen -W "$(_aigg_dynamic packages)" -- "$cur"))
                     ;;
                 clean)
                     COMPREPLY=($(compgen -W "$clean_flags" -- "$cur"))
                     ;;
+                serve)
+                    COMPREPLY=($(compgen -W "$serve_flags" -- "$cur"))
+                    ;;
+                proxy)
+                    COMPREPLY=($(compgen -W "$proxy_flags" -- "$cur"))
+                    ;;
+                mcp)
+                    COMPREPLY=($(compgen -W "$mcp_flags" -- "$cur"))
+                    ;;
+                migrate)
+                    COMPREPLY=($(compgen -W "$migrate_flags" -- "$cur"))
+                    ;;
+                list)
+                    COMPREPLY=($(compgen -W "$list_flags" -- "$cur"))
+                    ;;
+                history)
+                    COMPREPLY=($(compgen -W "$history_flags" -- "$cur"))
+                    ;;
+                doctor)
+                    COMPREPLY=($(compgen -W "$doctor_flags" -- "$cur"))
+                    ;;
+                init)
+                    COMPREPLY=($(compgen -W "$init_flags" -- "$cur"))
+                    ;;
+                install)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$install_flags" -- "$cur"))
+                    else
+                        COMPREPLY=($(compgen -W "$(_aigg_dynamic packages)" -- "$cur"))
+                    fi
+                    ;;
+                scan)
+                    COMPREPLY=($(compgen -W "$scan_flags" -- "$cur"))
+                    ;;
+                validate)
+                    COMPREPLY=($(compgen -W "$validate_flags" -- "$cur"))
+                    ;;
+                lint)
+                    COMPREPLY=($(compgen -W "$lint_flags" -- "$cur"))
+                    ;;
+                licenses)
+                    COMPREPLY=($(compgen -W "$licenses_flags" -- "$cur"))
+                    ;;
+                schema)
+                    COMPREPLY=($(compgen -W "$schema_flags" -- "$cur"))
+                    ;;
+                version)
+                    COMPREPLY=($(compgen -W "$version_bumps $version_flags" -- "$cur"))
+                    ;;
+                self-update)
+                    COMPREPLY=($(compgen -W "$self_update_flags" -- "$cur"))
+                    ;;
+                logout)
+                    [[ $cur == -* ]] && COMPREPLY=($(compgen -W "--all" -- "$cur"))
+                    ;;
+                whoami)
+                    [[ $cur == -* ]] && COMPREPLY=($(compgen -W "--no-check" -- "$cur"))
+                    ;;
                 remove)
                     # Complete with cached image names
-                    COMPREPLY=($(compgen -W "$cached_images" -- "$cur"))
+                    COMPREPLY=($(compgen -W "$(_aigg_dynamic images)" -- "$cur"))
+                    __ltrim_colon_completions "$cur"
+                    ;;
+                info|pull|delete)
+                    # Cached images, or a registry repository's tags after the colon
+                    if [[ $cur == -* ]]; then
+                        [[ $prev == info ]] && COMPREPLY=($(compgen -W "--readme --remote" -- "$cur"))
+                        [[ $prev == delete ]] && COMPREPLY=($(compgen -W "$delete_flags" -- "$cur"))
+                        [[ $prev == pull ]] && COMPREPLY=($(compgen -W "--all --production --registry --max-extract-size" -- "$cur"))
+                    else
+                        COMPREPLY=($(compgen -W "$(_aigg_dynamic refs "$cur")" -- "$cur"))
+                        __ltrim_colon_completions "$cur"
+                    fi
+                    ;;
+                export)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$export_flags" -- "$cur"))
+                    else
+                        COMPREPLY=($(compgen -W "$(_aigg_dynamic refs "$cur")" -- "$cur"))
+                        __ltrim_colon_completions "$cur"
+                    fi
+                    ;;
+                diff)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$diff_flags" -- "$cur"))
+                    else
+                        COMPREPLY=($(compgen -W "$(_aigg_dynamic refs "$cur")" -- "$cur"))
+                        __ltrim_colon_completions "$cur"
+                    fi
+                    ;;
+                import)
+                    # A bundle, an OCI layout or package directory, or a git URL
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$import_flags" -- "$cur"))
+                    else
+                        COMPREPLY=($(compgen -f -- "$cur"))
+                    fi
                     ;;
                 remove-all)
                     # Complete with flags only
@@ -110,7 +285,16 @@ _aigg_completions() {
                     ;;
                 build|push)
                     # Complete with cached images for reference
-                    COMPREPLY=($(compgen -W "$cached_images" -- "$cur"))
+                    COMPREPLY=($(compgen -W "$(_aigg_dynamic images)" -- "$cur"))
+                    __ltrim_colon_completions "$cur"
+                    ;;
+                pack)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$pack_flags" -- "$cur"))
+                    else
+                        COMPREPLY=($(compgen -W "$(_aigg_dynamic images)" -- "$cur"))
+                        __ltrim_colon_completions "$cur"
+                    fi
                     ;;
                 *)
                     ;;
@@ -130,46 +314,271 @@ _aigg_completions() {
                             fi
                             ;;
                         dep)
-                            # Complete --from-pyproject flag
+                            # Complete --from-* flags, or the requirements file path
                             if [[ $cur == -* ]]; then
                                 COMPREPLY=($(compgen -W "$add_dep_flags" -- "$cur"))
+                            elif [[ $prev == "--from-requirements" || $prev == "--from-conda" ]]; then
+                                COMPREPLY=($(compgen -f -- "$cur"))
+                            elif [[ $prev == "--language" ]]; then
+                                COMPREPLY=($(compgen -W "python javascript go rust ruby java csharp php" -- "$cur"))
                             fi
                             ;;
                         dev)
-                            # Complete --from-pyproject flag
+                            # Complete --from-* flags, or the requirements file path
                             if [[ $cur == -* ]]; then
                                 COMPREPLY=($(compgen -W "$add_dev_flags" -- "$cur"))
+                            elif [[ $prev == "--from-requirements" || $prev == "--from-conda" ]]; then
+                                COMPREPLY=($(compgen -f -- "$cur"))
+                            elif [[ $prev == "--language" ]]; then
+                                COMPREPLY=($(compgen -W "python javascript go rust ruby java csharp php" -- "$cur"))
+                            fi
+                            ;;
+                        peer)
+                            if [[ $cur == -* ]]; then
+                                COMPREPLY=($(compgen -W "--language" -- "$cur"))
+                            elif [[ $prev == "--language" ]]; then
+                                COMPREPLY=($(compgen -W "python javascript go rust ruby java csharp php" -- "$cur"))
                             fi
                             ;;
                         *)
+                            # A package reference or list: only --force, --strict, --dev and --optional follow it
+                            if [[ $prev == "--from-file" ]]; then
+                                COMPREPLY=($(compgen -f -- "$cur"))
+                            elif [[ $cur == -* ]]; then
+                                COMPREPLY=($(compgen -W "--from-file --force --strict --dev --optional --registry --max-extract-size" -- "$cur"))
+                            fi
                             ;;
                     esac
                     ;;
                 rm)
                     case ${words[2]} in
                         file)
-                            # Complete file paths
-                            COMPREPLY=($(compgen -f -- "$cur"))
+                            # Complete with the include list of aigogo.json
+                            COMPREPLY=($(compgen -W "$(_aigg_dynamic files)" -- "$cur"))
                             ;;
-                        *)
+                        dep|dev|peer)
+                            # Complete with the dependencies in aigogo.json
+                            local kind=${words[2]}
+                            [[ $kind == dep ]] && kind=deps
+                            COMPREPLY=($(compgen -W "$(_aigg_dynamic $kind)" -- "$cur"))
+                            ;;
+                    esac
+                    ;;
+                files)
+                    if [[ ${words[2]} == "freeze" ]]; then
+                        COMPREPLY=($(compgen -W "$files_freeze_flags" -- "$cur"))
+                    fi
+                    ;;
+                ide)
+                    if [[ ${words[2]} == "setup" ]]; then
+                        COMPREPLY=($(compgen -W "$ide_setup_flags" -- "$cur"))
+                    fi
+                    ;;
+                hooks)
+                    if [[ ${words[2]} == "install" ]]; then
+                        COMPREPLY=($(compgen -W "$hooks_install_flags" -- "$cur"))
+                    elif [[ ${words[2]} == "run" && $cword -eq 3 ]]; then
+                        COMPREPLY=($(compgen -W "$hooks_names" -- "$cur"))
+                    fi
+                    ;;
+                config)
+                    if [[ ${words[2]} == "set" && $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "--project" -- "$cur"))
+                    elif [[ $prev == "get" || $prev == "set" || $prev == "--project" ]]; then
+                        COMPREPLY=($(compgen -W "$config_keys" -- "$cur"))
+                    elif [[ $prev == "color" ]]; then
+                        COMPREPLY=($(compgen -W "auto always never" -- "$cur"))
+                    elif [[ $prev == "plain" || $prev == "history" || $prev == "auth.encrypt" ]]; then
+                        COMPREPLY=($(compgen -W "true false" -- "$cur"))
+                    elif [[ $prev == "output" ]]; then
+                        COMPREPLY=($(compgen -W "text github" -- "$cur"))
+                    elif [[ $prev == "install.mode" ]]; then
+                        COMPREPLY=($(compgen -W "link copy" -- "$cur"))
+                    elif [[ $prev == "install.python_layout" ]]; then
+                        COMPREPLY=($(compgen -W "imports pypackages" -- "$cur"))
+                    elif [[ $prev == tag_policy.* ]]; then
+                        COMPREPLY=($(compgen -W "allow warn block" -- "$cur"))
+                    fi
+                    ;;
+                cache)
+                    case ${words[2]} in
+                        ls)
+                            if [[ $cur == -* ]]; then
+                                COMPREPLY=($(compgen -W "$list_flags" -- "$cur"))
+                            elif [[ $prev == "--sort" ]]; then
+                                COMPREPLY=($(compgen -W "name date size" -- "$cur"))
+                            fi
+                            ;;
+                        rm|path)
+                            if [[ $cur == -* ]]; then
+                                [[ ${words[2]} == rm ]] && COMPREPLY=($(compgen -W "$cache_rm_flags" -- "$cur"))
+                            else
+                                COMPREPLY=($(compgen -W "$(_aigg_dynamic images)" -- "$cur"))
+                                __ltrim_colon_completions "$cur"
+                            fi
+                            ;;
+                        prune)
+                            if [[ $cur == -* ]]; then
+                                COMPREPLY=($(compgen -W "$cache_prune_flags" -- "$cur"))
+                            fi
                             ;;
                     esac
                     ;;
+                check-ignore)
+                    COMPREPLY=($(compgen -f -- "$cur"))
+                    ;;
+                export)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$export_flags" -- "$cur"))
+                    elif [[ $prev == "--format" ]]; then
+                        COMPREPLY=($(compgen -W "tar oci" -- "$cur"))
+                    elif [[ $prev == "-o" ]]; then
+                        COMPREPLY=($(compgen -f -- "$cur"))
+                    fi
+                    ;;
+                diff)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$diff_flags" -- "$cur"))
+                    elif [[ $prev != "-U" ]]; then
+                        COMPREPLY=($(compgen -W "$(_aigg_dynamic refs "$cur")" -- "$cur"))
+                        __ltrim_colon_completions "$cur"
+                    fi
+                    ;;
+                import)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$import_flags" -- "$cur"))
+                    elif [[ $prev != "--tag" ]]; then
+                        COMPREPLY=($(compgen -f -- "$cur"))
+                    fi
+                    ;;
+                init)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$init_flags" -- "$cur"))
+                    elif [[ $prev == "--template" ]]; then
+                        COMPREPLY=($(compgen -W "$(_aigg_dynamic templates)" -- "$cur"))
+                    fi
+                    ;;
                 clean)
                     if [[ $cur == -* ]]; then
                         COMPREPLY=($(compgen -W "$clean_flags" -- "$cur"))
                     fi
                     ;;
+                serve)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$serve_flags" -- "$cur"))
+                    fi
+                    ;;
+                proxy)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$proxy_flags" -- "$cur"))
+                    elif [[ $prev == "--dir" ]]; then
+                        COMPREPLY=($(compgen -d -- "$cur"))
+                    fi
+                    ;;
+                mcp)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$mcp_flags" -- "$cur"))
+                    fi
+                    ;;
+                migrate)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$migrate_flags" -- "$cur"))
+                    fi
+                    ;;
+                list)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$list_flags" -- "$cur"))
+                    elif [[ $prev == "--sort" ]]; then
+                        COMPREPLY=($(compgen -W "name date size" -- "$cur"))
+                    elif [[ $prev == "--language" ]]; then
+                        COMPREPLY=($(compgen -W "python javascript go rust ruby java csharp php" -- "$cur"))
+                    elif [[ $prev == "--filter" ]]; then
+                        compopt -o nospace 2>/dev/null
+                        COMPREPLY=($(compgen -W "name= version= type=local type=registry" -- "$cur"))
+                    fi
+                    ;;
+                history)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$history_flags" -- "$cur"))
+                    elif [[ $prev == "--command" ]]; then
+                        COMPREPLY=($(compgen -W "add install build push delete" -- "$cur"))
+                    fi
+                    ;;
+                doctor)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$doctor_flags" -- "$cur"))
+                    elif [[ $prev == "--python" ]]; then
+                        COMPREPLY=($(compgen -f -- "$cur"))
+                    fi
+                    ;;
                 build)
                     if [[ $cur == -* ]]; then
                         COMPREPLY=($(compgen -W "$build_flags" -- "$cur"))
                     fi
                     ;;
+                install)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$install_flags" -- "$cur"))
+                    elif [[ $prev == "--python" ]]; then
+                        COMPREPLY=($(compgen -f -- "$cur"))
+                    else
+                        COMPREPLY=($(compgen -W "$(_aigg_dynamic packages)" -- "$cur"))
+                    fi
+                    ;;
+                scan)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$scan_flags" -- "$cur"))
+                    fi
+                    ;;
+                validate)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$validate_flags" -- "$cur"))
+                    elif [[ $prev == "--format" ]]; then
+                        COMPREPLY=($(compgen -W "text sarif" -- "$cur"))
+                    fi
+                    ;;
+                lint)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$lint_flags" -- "$cur"))
+                    elif [[ $prev == "--format" ]]; then
+                        COMPREPLY=($(compgen -W "text sarif" -- "$cur"))
+                    fi
+                    ;;
+                licenses)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$licenses_flags" -- "$cur"))
+                    fi
+                    ;;
+                schema)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$schema_flags" -- "$cur"))
+                    elif [[ $prev == "--output" ]]; then
+                        COMPREPLY=($(compgen -f -- "$cur"))
+                    fi
+                    ;;
                 push)
                     if [[ $cur == -* ]]; then
                         COMPREPLY=($(compgen -W "$push_flags" -- "$cur"))
                     elif [[ $prev == "--from" ]]; then
-                        COMPREPLY=($(compgen -W "$cached_images" -- "$cur"))
+                        COMPREPLY=($(compgen -W "$(_aigg_dynamic images)" -- "$cur"))
+                        __ltrim_colon_completions "$cur"
+                    fi
+                    ;;
+                pack)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$pack_flags" -- "$cur"))
+                    elif [[ $prev == "-o" ]]; then
+                        COMPREPLY=($(compgen -d -- "$cur"))
+                    fi
+                    ;;
+                pull)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "--all --production --registry --max-extract-size" -- "$cur"))
+                    fi
+                    ;;
+                search)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "--registry" -- "$cur"))
                     fi
                     ;;
                 delete)
@@ -182,6 +591,11 @@ _aigg_completions() {
                         COMPREPLY=($(compgen -W "--force" -- "$cur"))
                     fi
                     ;;
+                info)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "--readme --remote" -- "$cur"))
+                    fi
+                    ;;
                 show-deps)
                     if [[ $cur == -* ]]; then
                         COMPREPLY=($(compgen -W "$show_deps_flags" -- "$cur"))
@@ -196,6 +610,16 @@ _aigg_completions() {
                         COMPREPLY=($(compgen -W "$login_flags" -- "$cur"))
                     fi
                     ;;
+                version)
+                    if [[ $cur == -* ]]; then
+                        COMPREPLY=($(compgen -W "$version_flags" -- "$cur"))
+                    else
+                        COMPREPLY=($(compgen -W "$version_bumps" -- "$cur"))
+                    fi
+                    ;;
+                self-update)
+                    COMPREPLY=($(compgen -W "$self_update_flags" -- "$cur"))
+                    ;;
                 *)
                     ;;
             esac
@@ -208,6 +632,14 @@ complete -F _aigg_completions aigg
 
 const zshCompletion = `#compdef aigg
 
+# _aigg_dynamic adds suggestions aigg finds in the project, the cache or a
+# registry: aigg __complete <packages|deps|dev|peer|files|images|refs|templates> [word]
+_aigg_dynamic() {
+    local -a suggestions
+    suggestions=(${(f)"$(aigg __complete "$@" 2>/dev/null)"})
+    compadd -a suggestions
+}
+
 _aigg() {
     local -a commands
     commands=(
@@ -215,31 +647,60 @@ _aigg() {
         'add:Add packages, files or dependencies'
         'install:Install packages from aigogo.lock'
         'uninstall:Remove installed packages and import configuration'
+        'doctor:Find problems with installed packages, such as broken links and orphaned .pth files'
+        'ide:Configure editors to resolve installed packages'
+        'hooks:Install git hooks that check the packages of the repository'
         'exec:Execute an agent script'
         'clean:Show disk usage or clean cached data'
+        'migrate:Move ~/.aigogo to the XDG config, data and cache directories'
         'rm:Remove files or dependencies'
+        'files:Manage the files included in the package'
+        'check-ignore:Explain whether paths are packaged or ignored'
         'validate:Validate the manifest'
+        'lint:Check the manifest and files for publishing problems'
         'scan:Scan for dependencies'
         'build:Build a package locally'
         'push:Push a package to registry'
+        'pack:Write a local build as an OCI image layout'
         'pull:Pull a package from registry'
+        'export:Export a package to a tarball or OCI image layout'
+        'import:Import a package bundle, directory or git repository'
+        'diff:Show how the files of two packages differ'
         'login:Login to a registry'
         'logout:Logout from a registry'
+        'whoami:Show the user logged in to each registry'
         'list:List cached packages'
+        'info:Show package metadata, files and dependencies, or README'
+        'tree:Show the project packages and their dependencies as a tree'
         'show-deps:Show dependencies in various formats'
-        'remove:Remove a cached package'
-        'remove-all:Remove all cached packages'
+        'licenses:Report dependency licenses and check a license policy'
+        'cache:List, remove and prune cached packages'
+        'serve:Serve the local cache, store and aigogo.lock as read-only JSON over HTTP'
+        'proxy:Run a pull-through registry cache shared by a team or CI fleet'
+        'mcp:Serve aigogo packages to coding agents over the Model Context Protocol on stdio'
+        'remove:Remove a cached package (now aigg cache rm)'
+        'remove-all:Remove all cached packages (now aigg cache rm --all)'
         'delete:Delete a package from registry'
+        'history:Show the adds, installs, builds, pushes and deletes aigg ran'
         'search:Search for packages'
-        'version:Show version information'
+        'config:Get and set settings in the user config.toml or .aigogo/config.toml'
+        'schema:Print the JSON Schema for aigogo.json'
+        'version:Show version information or bump the package version'
+        'self-update:Update aigg to the latest release from GitHub'
         'completion:Generate completion scripts'
     )
+    # Plugins: aigg-<name> executables on PATH
+    local plugin
+    for plugin in ${(u)${(f)"$(whence -pm 'aigg-*' 2>/dev/null)"}:t}; do
+        commands+=("${plugin#aigg-}:Plugin ($plugin)")
+    done
 
     local -a add_subcommands
     add_subcommands=(
         'file:Add files to include list'
         'dep:Add runtime dependency'
         'dev:Add development dependency'
+        'peer:Add peer dependency (provided by the consuming project)'
     )
 
     local -a rm_subcommands
@@ -247,15 +708,61 @@ _aigg() {
         'file:Remove files from include list'
         'dep:Remove runtime dependency'
         'dev:Remove development dependency'
+        'peer:Remove peer dependency'
+    )
+
+    local -a files_subcommands
+    files_subcommands=(
+        'freeze:Replace files.include with the files it resolves to'
+    )
+
+    local -a config_subcommands
+    config_subcommands=(
+        'get:Print a setting, or every setting that is set and where'
+        'set:Set a setting (--project: in .aigogo/config.toml)'
+    )
+
+    local -a cache_subcommands
+    cache_subcommands=(
+        'ls:List cached packages'
+        'rm:Remove cached packages (--all: every one)'
+        'prune:Remove packages by age (--older-than) or total size (--max-size)'
+        'path:Print the cache directory or a cached package directory'
+    )
+
+    local -a config_keys
+    config_keys=(registry namespace.python namespace.javascript store install.mode install.python_layout tag_policy.mutable tag_policy.overwrite cache color plain output concurrency insecure_registries retry.attempts retry.backoff log_file history auth.encrypt auth.key_command)
+
+    local -a ide_subcommands
+    ide_subcommands=(
+        'setup:Point VS Code and PyCharm at .aigogo/imports'
+    )
+
+    local -a hooks_subcommands
+    hooks_subcommands=(
+        'install:Install pre-commit and pre-push hooks in the repository'
+        'run:Run the checks of a hook, as the installed hooks do'
     )
 
     local -a shells
     shells=('bash' 'zsh' 'fish')
 
-    # Get cached images
-    local -a cached_images
-    if [[ -d "$HOME/.aigogo/cache" ]]; then
-        cached_images=(${(f)"$(aigg list 2>/dev/null | grep -E '^[🔨📦]' | awk '{print $2}')"})
+    # Global options, before the command
+    if [[ $words[$CURRENT-1] == "--color" ]]; then
+        _values 'mode' auto always never
+        return
+    fi
+    if [[ $words[$CURRENT-1] == "--output" && $words[(I)schema] -eq 0 ]]; then
+        _values 'format' text github
+        return
+    fi
+    if [[ $words[$CURRENT-1] == "--log-file" ]]; then
+        _files
+        return
+    fi
+    if [[ $CURRENT -eq 2 && $words[2] == -* ]]; then
+        _arguments '(-q --quiet)'{-q,--quiet}'[Print only results, warnings and errors]' '(-v --verbose)'{-v,--verbose}'[Also print HTTP requests, paths and hashes]' '--debug[Also dump registry responses (secrets redacted)]' '--trace[Record registry request metadata (secrets redacted)]' '--log-file[Append a debug log to a file]:file:_files' '--color[Style output]:mode:(auto always never)' '--plain[Print ASCII markers in place of emoji and symbols]' '--output[Output format]:format:(text github)' '--json[Print errors as JSON with their code]'
+        return
     fi
 
     case $state in
@@ -266,18 +773,74 @@ _aigg() {
             case $words[2] in
                 exec)
                     # Complete with package names from aigogo.lock
-                    local -a lock_packages
-                    if [[ -f "aigogo.lock" ]]; then
-                        lock_packages=(${(f)"$(python3 -c "import json; f=open('aigogo.lock'); d=json.load(f); print('\n'.join(d.get('packages',{}).keys()))" 2>/dev/null)"})
+                    _aigg_dynamic packages
+                    ;;
+                init)
+                    _arguments '--no-detect[Do not pre-fill from pyproject.toml, package.json, Cargo.toml or go.mod]' '--import-deps[Import the detected dependencies without asking]' '(-y --yes)'{-y,--yes}'[Write aigogo.json without prompting]' '--template[Lay out the package from a template]:template:(${(f)"$(_aigg_dynamic templates)"})'
+                    ;;
+                install)
+                    if [[ $words[$CURRENT] != -* && $words[$CURRENT-1] != "--python" ]]; then
+                        _aigg_dynamic packages
                     fi
-                    _values 'agent' $lock_packages
+                    _arguments '--ignore-scripts[Do not run postinstall scripts]' '--render-templates[Render template files into the project]' '--force[Install even if the environment does not match]' '--strict[Fail if a package is deprecated]' '--frozen[Fail if aigogo.lock is incomplete or out of date]' '--offline[Use only the local store and cache]' '--production[Skip dev and optional packages]' '--tsconfig[Write tsconfig.aigogo.json for @aigogo/* imports]' '--python[Python interpreter or environment for the .pth file]:path:_files' '--max-extract-size[Refuse packages larger than this]:size:'
+                    ;;
+                scan)
+                    _arguments '--offline[Do not query PyPI/npm for latest versions]' '--no-cache[Re-scan every file]'
+                    ;;
+                validate)
+                    _arguments '--no-cache[Re-scan every file]' '--lock[Check locked packages for dependency conflicts]' '--strict[Fail on warnings]' '--format[Output format]:format:(text sarif)' '--schema[Check aigogo.json against the JSON Schema]'
+                    ;;
+                lint)
+                    _arguments '--strict[Fail on warnings]' '--format[Output format]:format:(text sarif)' '--list-rules[List lint rules]'
+                    ;;
+                licenses)
+                    _arguments '--allow[Comma-separated allowed licenses]:licenses:' '--deny[Comma-separated denied licenses]:licenses:' '--offline[Use cached license data only]'
+                    ;;
+                version)
+                    if [[ $words[$CURRENT] == -* ]]; then
+                        _arguments '--git[Commit aigogo.json and tag v<version>]'
+                    else
+                        _values 'version bump' patch minor major
+                    fi
+                    ;;
+                self-update)
+                    _arguments '--check[Only report whether a newer release is available]' '--force[Update even when a package manager installed aigg]' '--skip-verify[Verify only the checksum, not the build attestation]'
+                    ;;
+                schema)
+                    _arguments '--output[Write the schema to a file]:file:_files'
+                    ;;
+                serve)
+                    _arguments '--addr[Address to listen on]:address:' '--remote[Fetch registry packages that are not local]'
+                    ;;
+                proxy)
+                    _arguments '--addr[Address to listen on]:address:' '--dir[Cache directory]:directory:_files -/' '--default-registry[Registry of names that do not start with one]:registry:' '--tag-ttl[How long a tag is served from the cache, e.g. 5m]:duration:' '--offline[Serve only what is cached]'
+                    ;;
+                mcp)
+                    _arguments '--read-only[Do not offer add_package]' '--offline[Only read packages that are local]'
                     ;;
                 clean)
                     _arguments '--envs[Remove exec environments]' '--cache[Remove build/pull cache]' '--store[Remove package store]' '--all[Remove everything]'
                     ;;
+                migrate)
+                    _arguments '--dry-run[Show what would be moved without moving anything]'
+                    ;;
+                list)
+                    _arguments '*--filter[Only list packages matching key=value]:filter:(name= version= type=local type=registry)' '--language[Only list packages in this language]:language:(python javascript go rust ruby java csharp php)' '--sort[Sort order]:order:(name date size)' '--remote[Check whether each reference exists in its registry]' '--long[Show details instead of a table]'
+                    ;;
+                history)
+                    _arguments '--command[Show only these commands, comma-separated]:command:(add install build push delete)' '--ref[Show only commands on packages whose reference contains this]:text:' '--user[Show only the commands of this user]:user:_users' '--since[Show only commands since this age or date]:age or date:' '--failed[Show only commands that failed]' '-n[Show at most this many commands]:count:'
+                    ;;
+                doctor)
+                    _arguments '--fix[Reinstall packages with broken links and remove orphaned aigogo.pth files]' '--python[Also check this Python interpreter or environment]:path:_files'
+                    ;;
                 add)
-                    if [[ $words[3] == "" ]] || [[ ${#words[@]} -eq 3 ]]; then
+                    if [[ $words[3] == .* || $words[3] == /* ]] && [[ $CURRENT -eq 3 ]]; then
+                        _files -/
+                    elif [[ $words[3] == -* ]] && [[ $CURRENT -eq 3 ]]; then
+                        _arguments '--from-file[Add every package listed in a file]:file:_files' '--force[Add even if the environment does not match]' '--strict[Refuse a deprecated package]' '(--optional)--dev[Add to the dev group]' '(--dev)--optional[Add to the optional group]' '--registry[Registry for a reference without one]:registry:' '--max-extract-size[Refuse packages larger than this]:size:'
+                    elif [[ $words[3] == "" ]] || [[ ${#words[@]} -eq 3 ]]; then
                         _describe 'subcommand' add_subcommands
+                        _aigg_dynamic refs "$words[3]"
                     elif [[ $words[3] == "file" ]]; then
                         if [[ $words[$CURRENT] == -* ]]; then
                             _arguments '--force[Add files even if ignored]'
@@ -286,36 +849,177 @@ _aigg() {
                         fi
                     elif [[ $words[3] == "dep" ]] || [[ $words[3] == "dev" ]]; then
                         if [[ $words[$CURRENT] == -* ]]; then
-                            _arguments '--from-pyproject[Import from pyproject.toml]'
+                            _arguments '--from-pyproject[Import from pyproject.toml]' '--from-requirements[Import from a requirements file]' '--from-lock[Import at uv.lock/poetry.lock versions]' '--compatible[Record compatible ranges from the lock file]' '--from-conda[Import from a conda environment.yml]' '--from-cargo[Import from Cargo.toml]' '--from-gomod[Import from go.mod]' '--language[Language of a multi-language package]:language:(python javascript go rust ruby java csharp php)'
+                        elif [[ $words[$CURRENT-1] == "--from-requirements" || $words[$CURRENT-1] == "--from-conda" ]]; then
+                            _files
+                        fi
+                    elif [[ $words[3] == "peer" ]]; then
+                        if [[ $words[$CURRENT] == -* ]]; then
+                            _arguments '--language[Language of a multi-language package]:language:(python javascript go rust ruby java csharp php)'
                         fi
+                    elif [[ $words[$CURRENT-1] == "--from-file" ]]; then
+                        _files
+                    elif [[ $words[$CURRENT] == -* ]]; then
+                        _arguments '--from-file[Add every package listed in a file]:file:_files' '--force[Add even if the environment does not match]' '--strict[Refuse a deprecated package]' '(--optional)--dev[Add to the dev group]' '(--dev)--optional[Add to the optional group]' '--registry[Registry for a reference without one]:registry:' '--max-extract-size[Refuse packages larger than this]:size:'
                     fi
                     ;;
                 rm)
                     if [[ $words[3] == "" ]] || [[ ${#words[@]} -eq 3 ]]; then
                         _describe 'subcommand' rm_subcommands
                     elif [[ $words[3] == "file" ]]; then
-                        _files
+                        _aigg_dynamic files
+                    elif [[ $words[3] == "dep" ]]; then
+                        _aigg_dynamic deps
+                    elif [[ $words[3] == (dev|peer) ]]; then
+                        _aigg_dynamic $words[3]
+                    fi
+                    ;;
+                config)
+                    if [[ $CURRENT -eq 3 ]]; then
+                        _describe 'subcommand' config_subcommands
+                    elif [[ $words[3] == "set" && $words[$CURRENT] == -* ]]; then
+                        _arguments '--project[Set it in .aigogo/config.toml]'
+                    elif [[ $words[$CURRENT-1] == (get|set|--project) ]]; then
+                        _values 'key' $config_keys
+                    elif [[ $words[$CURRENT-1] == "color" ]]; then
+                        _values 'color' auto always never
+                    elif [[ $words[$CURRENT-1] == (plain|history|auth.encrypt) ]]; then
+                        _values 'value' true false
+                    elif [[ $words[$CURRENT-1] == "output" ]]; then
+                        _values 'output' text github
+                    fi
+                    ;;
+                cache)
+                    if [[ $CURRENT -eq 3 ]]; then
+                        _describe 'subcommand' cache_subcommands
+                    elif [[ $words[3] == "ls" ]]; then
+                        _arguments '*--filter[Only list packages matching key=value]:filter:' '--language[Only list packages in this language]:language:(python javascript go rust ruby java csharp php)' '--sort[Sort order]:order:(name date size)' '--remote[Check each reference in its registry]' '--long[Show details instead of a table]'
+                    elif [[ $words[3] == "prune" ]]; then
+                        _arguments '--older-than[Remove packages older than this age, e.g. 30d]:age:' '--max-size[Remove the oldest packages until the cache fits, e.g. 500MB]:size:' '--dry-run[Print what would be removed]'
+                    elif [[ $words[$CURRENT] == -* && $words[3] == "rm" ]]; then
+                        _arguments '--all[Remove every cached package]' '--force[Skip the confirmation of --all]'
+                    elif [[ $words[3] == (rm|path) ]]; then
+                        _aigg_dynamic images
+                    fi
+                    ;;
+                files)
+                    if [[ $words[3] == "" ]] || [[ ${#words[@]} -eq 3 ]]; then
+                        _describe 'subcommand' files_subcommands
+                    elif [[ $words[3] == "freeze" ]]; then
+                        _arguments '--dry-run[Show the resolved list without writing]' '--force[Skip confirmation]'
+                    fi
+                    ;;
+                ide)
+                    if [[ $words[3] == "" ]] || [[ ${#words[@]} -eq 3 ]]; then
+                        _describe 'subcommand' ide_subcommands
+                    elif [[ $words[3] == "setup" ]]; then
+                        _arguments '--vscode[Update .vscode/settings.json]' '--pycharm[Mark .aigogo/imports as a PyCharm sources root]'
+                    fi
+                    ;;
+                hooks)
+                    if [[ $words[3] == "" ]] || [[ ${#words[@]} -eq 3 ]]; then
+                        _describe 'subcommand' hooks_subcommands
+                    elif [[ $words[3] == "install" ]]; then
+                        _arguments '--force[Replace hooks that aigg did not install]' '--pre-commit-config[Add the hooks to .pre-commit-config.yaml instead]'
+                    elif [[ $words[3] == "run" ]]; then
+                        _values 'hook' pre-commit pre-push
                     fi
                     ;;
+                check-ignore)
+                    _files
+                    ;;
                 completion)
                     _values 'shell' $shells
                     ;;
                 remove)
-                    _values 'cached images' $cached_images
+                    _aigg_dynamic images
                     ;;
-                build|push)
-                    _values 'image reference' $cached_images
+                info)
+                    if [[ $words[$CURRENT] == -* ]]; then
+                        _arguments '--readme[Render the package README]' '--remote[Read the package from its registry]'
+                    else
+                        _aigg_dynamic refs "$words[$CURRENT]"
+                    fi
+                    ;;
+                pull)
+                    if [[ $words[$CURRENT] == -* ]]; then
+                        _arguments '--all[Fetch every package in aigogo.lock]' '--production[With --all, skip dev and optional packages]' '--registry[Registry for a reference without one]:registry:' '--max-extract-size[Refuse packages larger than this]:size:'
+                    else
+                        _aigg_dynamic refs "$words[$CURRENT]"
+                    fi
+                    ;;
+                search)
+                    _arguments '--registry[Registry to search]:registry:'
+                    ;;
+                export)
+                    if [[ $words[$CURRENT] == -* ]]; then
+                        _arguments '-o[Where to write the bundle]:path:_files' '--format[Bundle format]:format:(tar oci)' '--force[Overwrite an existing output]'
+                    else
+                        _aigg_dynamic refs "$words[$CURRENT]"
+                    fi
+                    ;;
+                diff)
+                    if [[ $words[$CURRENT] == -* ]]; then
+                        _arguments '--summary[Only list the files that differ]' '--exit-code[Exit with code 9 when the packages differ]' '-U[Lines of context]:lines:' '--remote[Read registry references from the registry]' '--force-large[Diff files over 1MB or 5000 lines too]'
+                    else
+                        _aigg_dynamic refs "$words[$CURRENT]"
+                    fi
+                    ;;
+                import)
+                    if [[ $words[$CURRENT] == -* ]]; then
+                        _arguments '--tag[Cache the package under another name and version]:reference:' '--force[Replace a cached package of the same name and version]' '--max-extract-size[Refuse packages larger than this]:size:'
+                    else
+                        _files
+                    fi
+                    ;;
+                delete)
+                    if [[ $words[$CURRENT] == -* ]]; then
+                        _arguments '--all[Delete every tag of the repository]'
+                    else
+                        _aigg_dynamic refs "$words[$CURRENT]"
+                    fi
+                    ;;
+                build)
+                    if [[ $words[$CURRENT] == -* ]]; then
+                        _arguments '--force[Rebuild even if unchanged]' '--no-validate[Skip validation]' '--no-cache[Re-scan every file]' '--ignore-scripts[Do not run prebuild/postbuild scripts]'
+                    else
+                        _aigg_dynamic images
+                    fi
+                    ;;
+                push)
+                    if [[ $words[$CURRENT] == -* ]]; then
+                        _arguments '--from[Push from local build]' '--deprecate[Deprecate a pushed package]:message:' '--replacement[Package to use instead]:reference:' '--undeprecate[Remove the deprecation]' '--allow-private[Push a package marked private]' '--dry-run[Run the checks and show what would be pushed]' '--registry[Registry for a reference without one]:registry:'
+                    else
+                        _aigg_dynamic images
+                    fi
+                    ;;
+                pack)
+                    if [[ $words[$CURRENT] == -* ]]; then
+                        _arguments '-o[OCI image layout directory to write or add to]:directory:_files -/' '--tag[Tag to name the image in the layout]:tag:' '--force[Replace an image with the same tag]'
+                    else
+                        _aigg_dynamic images
+                    fi
                     ;;
                 show-deps)
                     if [[ $words[$CURRENT] == -* ]]; then
-                        _arguments '--format[Output format]:format:(text pyproject pep621 poetry requirements pip npm package-json yarn)'
+                        _arguments '--format[Output format]:format:(text pyproject pep621 poetry uv requirements pip conda npm package-json yarn yarn-berry berry pnpm pnpm-workspace pnpm-catalog gemfile bundler maven pom gradle nuget csproj composer)'
                     else
                         _files
                     fi
                     ;;
                 login)
                     if [[ $words[$CURRENT] == -* ]]; then
-                        _arguments '-u[Username]' '-p[Read password from stdin]' '--dockerhub[Use Docker Hub]'
+                        _arguments '-u[Username]' '-p[Read password from stdin]' '--dockerhub[Use Docker Hub]' '(--decrypt)--encrypt[Encrypt auth.json with a passphrase]' '(--encrypt)--decrypt[Store auth.json unencrypted]'
+                    fi
+                    ;;
+                logout)
+                    if [[ $words[$CURRENT] == -* ]]; then
+                        _arguments '--all[Remove the credentials of every registry]'
+                    fi
+                    ;;
+                whoami)
+                    if [[ $words[$CURRENT] == -* ]]; then
+                        _arguments '--no-check[Do not contact the registries]'
                     fi
                     ;;
                 *)
@@ -332,61 +1036,171 @@ const fishCompletion = `# aigg fish completion script
 
 # Main commands
 complete -c aigg -f
+
+# Global options
+complete -c aigg -s q -l quiet -d "Print only results, warnings and errors"
+complete -c aigg -s v -l verbose -d "Also print HTTP requests, paths and hashes"
+complete -c aigg -l debug -d "Also dump registry responses (secrets redacted)"
+complete -c aigg -l trace -d "Record registry request metadata (secrets redacted)"
+complete -c aigg -l log-file -r -F -d "Append a debug log to a file"
+complete -c aigg -l color -x -a "auto always never" -d "Style output"
+complete -c aigg -l plain -d "Print ASCII markers in place of emoji and symbols"
+complete -c aigg -n "not __fish_seen_subcommand_from schema" -l output -x -a "text github" -d "Output format (github: GitHub Actions annotations and job summaries)"
+complete -c aigg -l json -d "Print errors as JSON with their code"
 complete -c aigg -n "__fish_use_subcommand" -a "init" -d "Initialize a new aigogo package"
+# Plugins: aigg-<name> executables on PATH
+complete -c aigg -n "__fish_use_subcommand" -a "(complete -C'aigg-' | string match -r '^aigg-[^\\t]+' | string replace 'aigg-' '')" -d "Plugin"
 complete -c aigg -n "__fish_use_subcommand" -a "add" -d "Add packages, files or dependencies"
 complete -c aigg -n "__fish_use_subcommand" -a "install" -d "Install packages from aigogo.lock"
 complete -c aigg -n "__fish_use_subcommand" -a "uninstall" -d "Remove installed packages and import configuration"
+complete -c aigg -n "__fish_use_subcommand" -a "doctor" -d "Find problems with installed packages, such as broken links and orphaned .pth files"
+complete -c aigg -n "__fish_use_subcommand" -a "ide" -d "Configure editors to resolve installed packages"
+complete -c aigg -n "__fish_use_subcommand" -a "hooks" -d "Install git hooks that check the repository's packages"
 complete -c aigg -n "__fish_use_subcommand" -a "exec" -d "Execute an agent script"
 complete -c aigg -n "__fish_use_subcommand" -a "clean" -d "Show disk usage or clean cached data"
+complete -c aigg -n "__fish_use_subcommand" -a "migrate" -d "Move ~/.aigogo to the XDG config, data and cache directories"
 complete -c aigg -n "__fish_use_subcommand" -a "rm" -d "Remove files or dependencies"
+complete -c aigg -n "__fish_use_subcommand" -a "files" -d "Manage the files included in the package"
+complete -c aigg -n "__fish_use_subcommand" -a "check-ignore" -d "Explain whether paths are packaged or ignored"
 complete -c aigg -n "__fish_use_subcommand" -a "validate" -d "Validate the manifest"
+complete -c aigg -n "__fish_use_subcommand" -a "lint" -d "Check the manifest and files for publishing problems"
 complete -c aigg -n "__fish_use_subcommand" -a "scan" -d "Scan for dependencies"
 complete -c aigg -n "__fish_use_subcommand" -a "build" -d "Build a package locally"
 complete -c aigg -n "__fish_use_subcommand" -a "push" -d "Push a package to registry"
+complete -c aigg -n "__fish_use_subcommand" -a "pack" -d "Write a local build as an OCI image layout"
 complete -c aigg -n "__fish_use_subcommand" -a "pull" -d "Pull a package from registry"
+complete -c aigg -n "__fish_use_subcommand" -a "export" -d "Export a package to a tarball or OCI image layout"
+complete -c aigg -n "__fish_use_subcommand" -a "import" -d "Import a package bundle, directory or git repository"
+complete -c aigg -n "__fish_use_subcommand" -a "diff" -d "Show how the files of two packages differ"
 complete -c aigg -n "__fish_use_subcommand" -a "login" -d "Login to a registry"
 complete -c aigg -n "__fish_use_subcommand" -a "logout" -d "Logout from a registry"
+complete -c aigg -n "__fish_use_subcommand" -a "whoami" -d "Show the user logged in to each registry"
 complete -c aigg -n "__fish_use_subcommand" -a "list" -d "List cached packages"
+complete -c aigg -n "__fish_use_subcommand" -a "info" -d "Show package metadata, files and dependencies, or README"
+complete -c aigg -n "__fish_use_subcommand" -a "tree" -d "Show the project's packages and their dependencies as a tree"
 complete -c aigg -n "__fish_use_subcommand" -a "show-deps" -d "Show dependencies in various formats"
-complete -c aigg -n "__fish_use_subcommand" -a "remove" -d "Remove a cached package"
-complete -c aigg -n "__fish_use_subcommand" -a "remove-all" -d "Remove all cached packages"
+complete -c aigg -n "__fish_use_subcommand" -a "licenses" -d "Report dependency licenses and check a license policy"
+complete -c aigg -n "__fish_use_subcommand" -a "cache" -d "List, remove and prune cached packages"
+complete -c aigg -n "__fish_use_subcommand" -a "serve" -d "Serve the local cache, store and aigogo.lock as read-only JSON over HTTP"
+complete -c aigg -n "__fish_use_subcommand" -a "proxy" -d "Run a pull-through registry cache shared by a team or CI fleet"
+complete -c aigg -n "__fish_use_subcommand" -a "mcp" -d "Serve aigogo packages to coding agents over the Model Context Protocol on stdio"
+complete -c aigg -n "__fish_use_subcommand" -a "remove" -d "Remove a cached package (now aigg cache rm)"
+complete -c aigg -n "__fish_use_subcommand" -a "remove-all" -d "Remove all cached packages (now aigg cache rm --all)"
 complete -c aigg -n "__fish_use_subcommand" -a "delete" -d "Delete a package from registry"
+complete -c aigg -n "__fish_use_subcommand" -a "history" -d "Show the adds, installs, builds, pushes and deletes aigg ran"
 complete -c aigg -n "__fish_use_subcommand" -a "search" -d "Search for packages"
-complete -c aigg -n "__fish_use_subcommand" -a "version" -d "Show version information"
+complete -c aigg -n "__fish_use_subcommand" -a "config" -d "Get and set settings in the user config.toml or .aigogo/config.toml"
+complete -c aigg -n "__fish_use_subcommand" -a "schema" -d "Print the JSON Schema for aigogo.json"
+complete -c aigg -n "__fish_use_subcommand" -a "version" -d "Show version information or bump the package version"
+complete -c aigg -n "__fish_use_subcommand" -a "self-update" -d "Update aigg to the latest release from GitHub"
 complete -c aigg -n "__fish_use_subcommand" -a "completion" -d "Generate completion scripts"
 
 # add subcommands
-complete -c aigg -n "__fish_seen_subcommand_from add; and not __fish_seen_subcommand_from file dep dev" -a "file" -d "Add files to include list"
-complete -c aigg -n "__fish_seen_subcommand_from add; and not __fish_seen_subcommand_from file dep dev" -a "dep" -d "Add runtime dependency"
-complete -c aigg -n "__fish_seen_subcommand_from add; and not __fish_seen_subcommand_from file dep dev" -a "dev" -d "Add development dependency"
+complete -c aigg -n "__fish_seen_subcommand_from add; and not __fish_seen_subcommand_from file dep dev peer" -a "file" -d "Add files to include list"
+complete -c aigg -n "__fish_seen_subcommand_from add; and not __fish_seen_subcommand_from file dep dev peer" -a "dep" -d "Add runtime dependency"
+complete -c aigg -n "__fish_seen_subcommand_from add; and not __fish_seen_subcommand_from file dep dev peer" -a "dev" -d "Add development dependency"
+complete -c aigg -n "__fish_seen_subcommand_from add; and not __fish_seen_subcommand_from file dep dev peer" -a "peer" -d "Add peer dependency (provided by the consuming project)"
+complete -c aigg -n "__fish_seen_subcommand_from add; and not __fish_seen_subcommand_from file dep dev peer" -a "(__fish_complete_directories)" -d "Local package directory"
 
 # rm subcommands
-complete -c aigg -n "__fish_seen_subcommand_from rm; and not __fish_seen_subcommand_from file dep dev" -a "file" -d "Remove files from include list"
-complete -c aigg -n "__fish_seen_subcommand_from rm; and not __fish_seen_subcommand_from file dep dev" -a "dep" -d "Remove runtime dependency"
-complete -c aigg -n "__fish_seen_subcommand_from rm; and not __fish_seen_subcommand_from file dep dev" -a "dev" -d "Remove development dependency"
+complete -c aigg -n "__fish_seen_subcommand_from rm; and not __fish_seen_subcommand_from file dep dev peer" -a "file" -d "Remove files from include list"
+complete -c aigg -n "__fish_seen_subcommand_from rm; and not __fish_seen_subcommand_from file dep dev peer" -a "dep" -d "Remove runtime dependency"
+complete -c aigg -n "__fish_seen_subcommand_from rm; and not __fish_seen_subcommand_from file dep dev peer" -a "dev" -d "Remove development dependency"
+complete -c aigg -n "__fish_seen_subcommand_from rm; and not __fish_seen_subcommand_from file dep dev peer" -a "peer" -d "Remove peer dependency"
+
+# files subcommands
+complete -c aigg -n "__fish_seen_subcommand_from files; and not __fish_seen_subcommand_from freeze" -a "freeze" -d "Replace files.include with the files it resolves to"
+complete -c aigg -n "__fish_seen_subcommand_from cache; and not __fish_seen_subcommand_from ls rm prune path" -a "ls" -d "List cached packages"
+complete -c aigg -n "__fish_seen_subcommand_from cache; and not __fish_seen_subcommand_from ls rm prune path" -a "rm" -d "Remove cached packages (--all: every one)"
+complete -c aigg -n "__fish_seen_subcommand_from cache; and not __fish_seen_subcommand_from ls rm prune path" -a "prune" -d "Remove packages by age or total size"
+complete -c aigg -n "__fish_seen_subcommand_from cache; and not __fish_seen_subcommand_from ls rm prune path" -a "path" -d "Print the cache directory or a cached package directory"
+complete -c aigg -n "__fish_seen_subcommand_from cache; and __fish_seen_subcommand_from rm path" -a "(__aigg_dynamic images)" -d "Cached package"
+complete -c aigg -n "__fish_seen_subcommand_from cache; and __fish_seen_subcommand_from rm" -l "all" -d "Remove every cached package"
+complete -c aigg -n "__fish_seen_subcommand_from cache; and __fish_seen_subcommand_from rm" -l "force" -d "Skip the confirmation of --all"
+complete -c aigg -n "__fish_seen_subcommand_from cache; and __fish_seen_subcommand_from prune" -l "older-than" -x -d "Remove packages older than this age, e.g. 30d"
+complete -c aigg -n "__fish_seen_subcommand_from cache; and __fish_seen_subcommand_from prune" -l "max-size" -x -d "Remove the oldest packages until the cache fits, e.g. 500MB"
+complete -c aigg -n "__fish_seen_subcommand_from cache; and __fish_seen_subcommand_from prune" -l "dry-run" -d "Print what would be removed"
+complete -c aigg -n "__fish_seen_subcommand_from cache; and __fish_seen_subcommand_from ls" -l "long" -d "Show details instead of a table"
+complete -c aigg -n "__fish_seen_subcommand_from cache; and __fish_seen_subcommand_from ls" -l "sort" -x -a "name date size" -d "Sort order"
+complete -c aigg -n "__fish_seen_subcommand_from config; and not __fish_seen_subcommand_from get set" -a "get" -d "Print a setting, or every setting that is set and where"
+complete -c aigg -n "__fish_seen_subcommand_from config; and not __fish_seen_subcommand_from get set" -a "set" -d "Set a setting (--project: in .aigogo/config.toml)"
+complete -c aigg -n "__fish_seen_subcommand_from config; and __fish_seen_subcommand_from get set" -a "registry namespace.python namespace.javascript store install.mode install.python_layout tag_policy.mutable tag_policy.overwrite cache color plain output concurrency insecure_registries retry.attempts retry.backoff log_file history auth.encrypt auth.key_command"
+complete -c aigg -n "__fish_seen_subcommand_from config; and __fish_seen_subcommand_from set" -l "project" -d "Set it in .aigogo/config.toml"
+complete -c aigg -n "__fish_seen_subcommand_from files; and __fish_seen_subcommand_from freeze" -l "dry-run" -d "Show the resolved list without writing"
+complete -c aigg -n "__fish_seen_subcommand_from files; and __fish_seen_subcommand_from freeze" -l "force" -d "Skip confirmation"
+
+# ide subcommands
+complete -c aigg -n "__fish_seen_subcommand_from ide; and not __fish_seen_subcommand_from setup" -a "setup" -d "Point VS Code and PyCharm at .aigogo/imports"
+complete -c aigg -n "__fish_seen_subcommand_from ide; and __fish_seen_subcommand_from setup" -l "vscode" -d "Update .vscode/settings.json"
+complete -c aigg -n "__fish_seen_subcommand_from ide; and __fish_seen_subcommand_from setup" -l "pycharm" -d "Mark .aigogo/imports as a PyCharm sources root"
+
+# hooks subcommands
+complete -c aigg -n "__fish_seen_subcommand_from hooks; and not __fish_seen_subcommand_from install run" -a "install" -d "Install pre-commit and pre-push hooks in the repository"
+complete -c aigg -n "__fish_seen_subcommand_from hooks; and not __fish_seen_subcommand_from install run" -a "run" -d "Run a hook's checks, as the installed hooks do"
+complete -c aigg -n "__fish_seen_subcommand_from hooks; and __fish_seen_subcommand_from install" -l "force" -d "Replace hooks that aigg didn't install"
+complete -c aigg -n "__fish_seen_subcommand_from hooks; and __fish_seen_subcommand_from install" -l "pre-commit-config" -d "Add the hooks to .pre-commit-config.yaml instead"
+complete -c aigg -n "__fish_seen_subcommand_from hooks; and __fish_seen_subcommand_from run; and not __fish_seen_subcommand_from pre-commit pre-push" -a "pre-commit pre-push" -d "Hook"
+
+# check-ignore paths
+complete -c aigg -n "__fish_seen_subcommand_from check-ignore" -F
 
 # completion shells
 complete -c aigg -n "__fish_seen_subcommand_from completion" -a "bash zsh fish"
 
-# Cached images for remove, build, push
-function __aigg_cached_images
-    if test -d "$HOME/.aigogo/cache"
-        aigg list 2>/dev/null | grep -E '^[🔨📦]' | awk '{print $2}'
-    end
+# Suggestions aigg finds in the project, the cache or a registry:
+# aigg __complete <packages|deps|dev|peer|files|images|refs|templates> [word]
+function __aigg_dynamic
+    aigg __complete $argv 2>/dev/null
 end
 
-complete -c aigg -n "__fish_seen_subcommand_from remove" -a "(__aigg_cached_images)" -d "Cached package"
-complete -c aigg -n "__fish_seen_subcommand_from build" -a "(__aigg_cached_images)" -d "Package reference"
-complete -c aigg -n "__fish_seen_subcommand_from push" -a "(__aigg_cached_images)" -d "Package reference"
+# Cached images for remove, build, push, pack; for info, pull, delete, export, diff and add also
+# a registry repository's tags after the colon
+complete -c aigg -n "__fish_seen_subcommand_from remove" -a "(__aigg_dynamic images)" -d "Cached package"
+complete -c aigg -n "__fish_seen_subcommand_from info pull delete export diff" -a "(__aigg_dynamic refs (commandline -ct))" -d "Package reference"
+complete -c aigg -n "__fish_seen_subcommand_from info" -l "readme" -d "Render the package README"
+complete -c aigg -n "__fish_seen_subcommand_from info" -l "remote" -d "Read the package from its registry"
+complete -c aigg -n "__fish_seen_subcommand_from info" -a "(__aigg_dynamic packages)" -d "Locked package"
+complete -c aigg -n "__fish_seen_subcommand_from build" -a "(__aigg_dynamic images)" -d "Package reference"
+complete -c aigg -n "__fish_seen_subcommand_from push" -a "(__aigg_dynamic images)" -d "Package reference"
+complete -c aigg -n "__fish_seen_subcommand_from pack" -a "(__aigg_dynamic images)" -d "Local build"
+complete -c aigg -n "__fish_seen_subcommand_from add; and not __fish_seen_subcommand_from file dep dev peer" -a "(__aigg_dynamic refs (commandline -ct))" -d "Package reference"
 
-# exec — complete with package names from aigogo.lock
-function __aigg_lock_packages
-    if test -f "aigogo.lock"
-        python3 -c "import json; f=open('aigogo.lock'); d=json.load(f); print('\n'.join(d.get('packages',{}).keys()))" 2>/dev/null
-    end
-end
+# exec and install — complete with package names from aigogo.lock
+complete -c aigg -n "__fish_seen_subcommand_from exec install" -a "(__aigg_dynamic packages)" -d "Package"
+
+# rm — complete with the files and dependencies in aigogo.json
+complete -c aigg -n "__fish_seen_subcommand_from rm; and __fish_seen_subcommand_from file" -a "(__aigg_dynamic files)" -d "Included file"
+complete -c aigg -n "__fish_seen_subcommand_from rm; and __fish_seen_subcommand_from dep" -a "(__aigg_dynamic deps)" -d "Dependency"
+complete -c aigg -n "__fish_seen_subcommand_from rm; and __fish_seen_subcommand_from dev" -a "(__aigg_dynamic dev)" -d "Dev dependency"
+complete -c aigg -n "__fish_seen_subcommand_from rm; and __fish_seen_subcommand_from peer" -a "(__aigg_dynamic peer)" -d "Peer dependency"
 
-complete -c aigg -n "__fish_seen_subcommand_from exec" -a "(__aigg_lock_packages)" -d "Agent"
+# list flags
+complete -c aigg -n "__fish_seen_subcommand_from list" -l "filter" -x -a "name= version= type=local type=registry" -d "Only list packages matching key=value"
+complete -c aigg -n "__fish_seen_subcommand_from list" -l "language" -x -a "python javascript go rust ruby java csharp php" -d "Only list packages in this language"
+complete -c aigg -n "__fish_seen_subcommand_from list" -l "sort" -x -a "name date size" -d "Sort order"
+complete -c aigg -n "__fish_seen_subcommand_from list" -l "remote" -d "Check whether each reference exists in its registry"
+complete -c aigg -n "__fish_seen_subcommand_from list" -l "long" -d "Show details instead of a table"
+complete -c aigg -n "__fish_seen_subcommand_from history" -l "command" -x -a "add install build push delete" -d "Show only these commands, comma-separated"
+complete -c aigg -n "__fish_seen_subcommand_from history" -l "ref" -x -d "Show only commands on packages whose reference contains this"
+complete -c aigg -n "__fish_seen_subcommand_from history" -l "user" -x -a "(__fish_complete_users)" -d "Show only this user's commands"
+complete -c aigg -n "__fish_seen_subcommand_from history" -l "since" -x -d "Show only commands since this age (7d) or date (YYYY-MM-DD)"
+complete -c aigg -n "__fish_seen_subcommand_from history" -l "failed" -d "Show only commands that failed"
+complete -c aigg -n "__fish_seen_subcommand_from history" -s "n" -x -d "Show at most this many commands"
+
+# serve flags
+complete -c aigg -n "__fish_seen_subcommand_from serve" -l "addr" -x -d "Address to listen on"
+complete -c aigg -n "__fish_seen_subcommand_from serve" -l "remote" -d "Fetch registry packages that aren't local"
+
+# proxy flags
+complete -c aigg -n "__fish_seen_subcommand_from proxy" -l "addr" -x -d "Address to listen on"
+complete -c aigg -n "__fish_seen_subcommand_from proxy" -l "dir" -r -a "(__fish_complete_directories)" -d "Cache directory"
+complete -c aigg -n "__fish_seen_subcommand_from proxy" -l "default-registry" -x -d "Registry of names that don't start with one"
+complete -c aigg -n "__fish_seen_subcommand_from proxy" -l "tag-ttl" -x -d "How long a tag is served from the cache, e.g. 5m"
+complete -c aigg -n "__fish_seen_subcommand_from proxy" -l "offline" -d "Serve only what is cached"
+
+# mcp flags
+complete -c aigg -n "__fish_seen_subcommand_from mcp" -l "read-only" -d "Don't offer add_package"
+complete -c aigg -n "__fish_seen_subcommand_from mcp" -l "offline" -d "Only read packages that are local"
 
 # clean flags
 complete -c aigg -n "__fish_seen_subcommand_from clean" -l "envs" -d "Remove exec environments"
@@ -394,20 +1208,95 @@ complete -c aigg -n "__fish_seen_subcommand_from clean" -l "cache" -d "Remove bu
 complete -c aigg -n "__fish_seen_subcommand_from clean" -l "store" -d "Remove package store"
 complete -c aigg -n "__fish_seen_subcommand_from clean" -l "all" -d "Remove everything"
 
+# migrate flags
+complete -c aigg -n "__fish_seen_subcommand_from migrate" -l "dry-run" -d "Show what would be moved without moving anything"
+complete -c aigg -n "__fish_seen_subcommand_from doctor" -l "fix" -d "Reinstall packages with broken links and remove orphaned aigogo.pth files"
+complete -c aigg -n "__fish_seen_subcommand_from doctor" -l "python" -r -F -d "Also check this Python interpreter or environment"
+
 # Flags
-complete -c aigg -n "__fish_seen_subcommand_from build" -l "force" -d "Force rebuild"
+complete -c aigg -n "__fish_seen_subcommand_from init" -l "no-detect" -d "Don't pre-fill from pyproject.toml, package.json, Cargo.toml or go.mod"
+complete -c aigg -n "__fish_seen_subcommand_from init" -l "import-deps" -d "Import the detected dependencies without asking"
+complete -c aigg -n "__fish_seen_subcommand_from init" -s y -l "yes" -d "Write aigogo.json without prompting"
+complete -c aigg -n "__fish_seen_subcommand_from init" -l "template" -x -a "(__aigg_dynamic templates)" -d "Lay out the package from a template"
+complete -c aigg -n "__fish_seen_subcommand_from scan" -l "offline" -d "Don't query PyPI/npm for latest versions"
+complete -c aigg -n "__fish_seen_subcommand_from scan validate build" -l "no-cache" -d "Re-scan every file"
+complete -c aigg -n "__fish_seen_subcommand_from validate" -l "lock" -d "Check locked packages for dependency conflicts"
+complete -c aigg -n "__fish_seen_subcommand_from validate lint" -l "strict" -d "Fail on warnings"
+complete -c aigg -n "__fish_seen_subcommand_from validate lint" -l "format" -d "Output format" -a "text sarif"
+complete -c aigg -n "__fish_seen_subcommand_from lint" -l "list-rules" -d "List lint rules"
+complete -c aigg -n "__fish_seen_subcommand_from validate" -l "schema" -d "Check aigogo.json against the JSON Schema"
+complete -c aigg -n "__fish_seen_subcommand_from schema" -l "output" -d "Write the schema to a file" -r -F
+complete -c aigg -n "__fish_seen_subcommand_from licenses" -l "allow" -d "Comma-separated allowed licenses" -r
+complete -c aigg -n "__fish_seen_subcommand_from licenses" -l "deny" -d "Comma-separated denied licenses" -r
+complete -c aigg -n "__fish_seen_subcommand_from licenses" -l "offline" -d "Use cached license data only"
+complete -c aigg -n "__fish_seen_subcommand_from build" -l "force" -d "Rebuild even if unchanged"
 complete -c aigg -n "__fish_seen_subcommand_from build" -l "no-validate" -d "Skip validation"
+complete -c aigg -n "__fish_seen_subcommand_from build install" -l "ignore-scripts" -d "Don't run lifecycle scripts"
+complete -c aigg -n "__fish_seen_subcommand_from install" -l "render-templates" -d "Render template files into the project"
+complete -c aigg -n "__fish_seen_subcommand_from install" -l "force" -d "Install even if the environment does not match"
+complete -c aigg -n "__fish_seen_subcommand_from add; and not __fish_seen_subcommand_from file dep dev peer" -l "from-file" -r -F -d "Add every package listed in a file"
+complete -c aigg -n "__fish_seen_subcommand_from add; and not __fish_seen_subcommand_from file dep dev peer" -l "force" -d "Add even if the environment does not match"
+complete -c aigg -n "__fish_seen_subcommand_from add; and not __fish_seen_subcommand_from file dep dev peer" -l "strict" -d "Refuse a deprecated package"
+complete -c aigg -n "__fish_seen_subcommand_from add; and not __fish_seen_subcommand_from file dep dev peer" -l "dev" -d "Add to the dev group (skipped by install --production)"
+complete -c aigg -n "__fish_seen_subcommand_from add; and not __fish_seen_subcommand_from file dep dev peer" -l "optional" -d "Add to the optional group (skipped by install --production)"
+complete -c aigg -n "__fish_seen_subcommand_from install" -l "strict" -d "Fail if a package is deprecated"
+complete -c aigg -n "__fish_seen_subcommand_from install" -l "frozen" -d "Fail if aigogo.lock is incomplete or out of date"
+complete -c aigg -n "__fish_seen_subcommand_from install" -l "offline" -d "Use only the local store and cache"
+complete -c aigg -n "__fish_seen_subcommand_from install" -l "production" -d "Skip dev and optional packages"
+complete -c aigg -n "__fish_seen_subcommand_from install" -l "tsconfig" -d "Write tsconfig.aigogo.json for @aigogo/* imports"
+complete -c aigg -n "__fish_seen_subcommand_from install pull import" -l "max-extract-size" -x -d "Refuse packages larger than this, e.g. 2GB"
+complete -c aigg -n "__fish_seen_subcommand_from add; and not __fish_seen_subcommand_from file dep dev peer" -l "max-extract-size" -x -d "Refuse packages larger than this, e.g. 2GB"
+complete -c aigg -n "__fish_seen_subcommand_from install" -l "python" -r -F -d "Python interpreter or environment for the .pth file"
 complete -c aigg -n "__fish_seen_subcommand_from push" -l "from" -d "Push from local build"
+complete -c aigg -n "__fish_seen_subcommand_from push" -l "deprecate" -d "Deprecate a pushed package" -r
+complete -c aigg -n "__fish_seen_subcommand_from push" -l "replacement" -d "Package to use instead" -r
+complete -c aigg -n "__fish_seen_subcommand_from push" -l "undeprecate" -d "Remove the deprecation"
+complete -c aigg -n "__fish_seen_subcommand_from push" -l "allow-private" -d "Push a package marked private"
+complete -c aigg -n "__fish_seen_subcommand_from push" -l "dry-run" -d "Run the checks and show what would be pushed"
+complete -c aigg -n "__fish_seen_subcommand_from pull" -l "all" -d "Fetch every package in aigogo.lock"
+complete -c aigg -n "__fish_seen_subcommand_from pull" -l "production" -d "With --all, skip dev and optional packages"
+complete -c aigg -n "__fish_seen_subcommand_from add push pull; and not __fish_seen_subcommand_from file dep dev peer" -l "registry" -r -d "Registry for a reference without one"
+complete -c aigg -n "__fish_seen_subcommand_from search" -l "registry" -r -d "Registry to search"
 complete -c aigg -n "__fish_seen_subcommand_from delete" -l "all" -d "Delete all tags"
+complete -c aigg -n "__fish_seen_subcommand_from export" -s o -r -F -d "Where to write the bundle"
+complete -c aigg -n "__fish_seen_subcommand_from export" -l "format" -x -a "tar oci" -d "Bundle format"
+complete -c aigg -n "__fish_seen_subcommand_from export" -l "force" -d "Overwrite an existing output"
+complete -c aigg -n "__fish_seen_subcommand_from diff" -l "summary" -d "Only list the files that differ"
+complete -c aigg -n "__fish_seen_subcommand_from diff" -l "exit-code" -d "Exit with code 9 when the packages differ"
+complete -c aigg -n "__fish_seen_subcommand_from diff" -l "force-large" -d "Diff files over 1MB or 5000 lines too"
+complete -c aigg -n "__fish_seen_subcommand_from diff" -s U -x -d "Lines of context"
+complete -c aigg -n "__fish_seen_subcommand_from diff" -l "remote" -d "Read registry references from the registry"
+complete -c aigg -n "__fish_seen_subcommand_from pack" -s o -r -a "(__fish_complete_directories)" -d "OCI image layout directory to write or add to"
+complete -c aigg -n "__fish_seen_subcommand_from pack" -l "tag" -x -d "Tag to name the image in the layout"
+complete -c aigg -n "__fish_seen_subcommand_from pack" -l "force" -d "Replace an image with the same tag"
+complete -c aigg -n "__fish_seen_subcommand_from import" -F -d "Bundle, OCI layout or package directory"
+complete -c aigg -n "__fish_seen_subcommand_from import" -l "tag" -r -d "Cache the package as this name:version"
+complete -c aigg -n "__fish_seen_subcommand_from import" -l "force" -d "Replace a cached package of the same name and version"
+complete -c aigg -n "__fish_seen_subcommand_from version; and not __fish_seen_subcommand_from patch minor major" -a "patch minor major" -d "Version bump"
+complete -c aigg -n "__fish_seen_subcommand_from version" -l "git" -d "Commit aigogo.json and tag v<version>"
+complete -c aigg -n "__fish_seen_subcommand_from self-update" -l "check" -d "Only report whether a newer release is available"
+complete -c aigg -n "__fish_seen_subcommand_from self-update" -l "force" -d "Update even when a package manager installed aigg"
+complete -c aigg -n "__fish_seen_subcommand_from self-update" -l "skip-verify" -d "Verify only the checksum, not the build attestation"
 complete -c aigg -n "__fish_seen_subcommand_from remove-all" -l "force" -d "Skip confirmation"
 complete -c aigg -n "__fish_seen_subcommand_from add; and __fish_seen_subcommand_from file" -l "force" -d "Add files even if ignored"
 complete -c aigg -n "__fish_seen_subcommand_from add; and __fish_seen_subcommand_from dep" -l "from-pyproject" -d "Import from pyproject.toml"
 complete -c aigg -n "__fish_seen_subcommand_from add; and __fish_seen_subcommand_from dev" -l "from-pyproject" -d "Import from pyproject.toml"
-complete -c aigg -n "__fish_seen_subcommand_from show-deps" -l "format" -d "Output format" -a "text pyproject pep621 poetry requirements pip npm package-json yarn"
+complete -c aigg -n "__fish_seen_subcommand_from add; and __fish_seen_subcommand_from dep dev" -l "from-requirements" -r -F -d "Import from a requirements file"
+complete -c aigg -n "__fish_seen_subcommand_from add; and __fish_seen_subcommand_from dep dev" -l "from-lock" -d "Import at uv.lock/poetry.lock versions"
+complete -c aigg -n "__fish_seen_subcommand_from add; and __fish_seen_subcommand_from dep dev" -l "compatible" -d "Record compatible ranges from the lock file"
+complete -c aigg -n "__fish_seen_subcommand_from add; and __fish_seen_subcommand_from dep dev" -l "from-conda" -r -F -d "Import from a conda environment.yml"
+complete -c aigg -n "__fish_seen_subcommand_from add; and __fish_seen_subcommand_from dep dev" -l "from-cargo" -d "Import from Cargo.toml"
+complete -c aigg -n "__fish_seen_subcommand_from add; and __fish_seen_subcommand_from dep" -l "from-gomod" -d "Import from go.mod"
+complete -c aigg -n "__fish_seen_subcommand_from add; and __fish_seen_subcommand_from dep dev peer" -l "language" -x -a "python javascript go rust ruby java csharp php" -d "Language of a multi-language package"
+complete -c aigg -n "__fish_seen_subcommand_from show-deps" -l "format" -d "Output format" -a "text pyproject pep621 poetry uv requirements pip conda npm package-json yarn yarn-berry berry pnpm pnpm-workspace pnpm-catalog gemfile bundler maven pom gradle nuget csproj composer"
 complete -c aigg -n "__fish_seen_subcommand_from login" -s "u" -l "username" -d "Username"
 complete -c aigg -n "__fish_seen_subcommand_from login" -s "p" -d "Read password from stdin (prevents password in shell history)"
 complete -c aigg -n "__fish_seen_subcommand_from login" -l "dockerhub" -d "Use Docker Hub (docker.io) as registry"
+complete -c aigg -n "__fish_seen_subcommand_from login" -l "encrypt" -d "Encrypt auth.json with a passphrase"
+complete -c aigg -n "__fish_seen_subcommand_from login" -l "decrypt" -d "Store auth.json unencrypted"
+complete -c aigg -n "__fish_seen_subcommand_from logout" -l "all" -d "Remove the credentials of every registry"
+complete -c aigg -n "__fish_seen_subcommand_from whoami" -l "no-check" -d "Show the stored users without contacting the registries"
 
 # Complete --from with cached images
-complete -c aigg -n "__fish_seen_subcommand_from push; and __fish_seen_argument -l from" -a "(__aigg_cached_images)" -d "Local build"
+complete -c aigg -n "__fish_seen_subcommand_from push; and __fish_seen_argument -l from" -a "(__aigg_dynamic images)" -d "Local build"
 `
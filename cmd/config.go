@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/config"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+)
+
+const configUsage = `usage: aigg config <get|set> [args...]
+
+Subcommands:
+  get [key]                     Print a setting, or every setting that is set and where
+  set [--project] <key> <value> Set a setting in the user config.toml (--project: .aigogo/config.toml); "" unsets it
+
+Keys: `
+
+func configCmd() *Command {
+	return &Command{
+		Name:        "config",
+		Description: "Get and set settings in the user config.toml or .aigogo/config.toml",
+		Run: func(args []string) error {
+			if len(args) == 0 {
+				return errcode.Errorf(errcode.Usage, "%s%s", configUsage, strings.Join(config.Keys(), ", "))
+			}
+
+			switch args[0] {
+			case "get":
+				return configGet(args[1:])
+			case "set":
+				return configSet(args[1:])
+			default:
+				return errcode.Errorf(errcode.Usage, "unknown subcommand '%s'\nValid subcommands: get, set", args[0])
+			}
+		},
+	}
+}
+
+// configGet prints the value of a setting from every configuration layer,
+// or without a key, every setting that is set and the layer it comes from
+func configGet(args []string) error {
+	if len(args) > 1 {
+		return errcode.Errorf(errcode.Usage, "usage: aigg config get [key]")
+	}
+	layers, err := config.Load(configProjectDir())
+	if err != nil {
+		return errcode.Wrap(errcode.Config, err)
+	}
+
+	if len(args) == 1 {
+		value, err := config.Merge(layers).Get(args[0])
+		if err != nil {
+			return errcode.Wrap(errcode.Config, err)
+		}
+		if value == "" {
+			return errcode.Errorf(errcode.NotFound, "%s is not set", args[0])
+		}
+		fmt.Println(value)
+		return nil
+	}
+
+	for _, key := range config.Keys() {
+		// The last layer setting it wins
+		for i := len(layers) - 1; i >= 0; i-- {
+			value, _ := layers[i].Config.Get(key)
+			if value != "" {
+				fmt.Printf("%s = %s  (%s)\n", key, value, layers[i].Source)
+				break
+			}
+		}
+	}
+	// Webhooks are [[webhooks]] tables, edited in the files themselves. Their
+	// URLs often hold a secret, so only the count is shown.
+	for i := len(layers) - 1; i >= 0; i-- {
+		if hooks := layers[i].Config.Webhooks; hooks != nil {
+			fmt.Printf("webhooks = %d configured  (%s)\n", len(hooks), layers[i].Source)
+			break
+		}
+	}
+	return nil
+}
+
+// configSet sets a setting in the user's config file, or with --project in
+// the project's, unsetting it for an empty value
+func configSet(args []string) error {
+	project := false
+	var rest []string
+	for _, arg := range args {
+		if arg == "--project" {
+			project = true
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	if len(rest) != 2 {
+		return errcode.Errorf(errcode.Usage, "usage: aigg config set [--project] <key> <value>")
+	}
+	key, value := rest[0], rest[1]
+	if project && config.UserOnly(key) {
+		return errcode.Errorf(errcode.Usage, "%s can only be set in your own config.toml, not a project's; set it without --project", key)
+	}
+
+	path, err := config.UserPath()
+	if err != nil {
+		return err
+	}
+	if project {
+		projectDir := configProjectDir()
+		if projectDir == "" {
+			if projectDir, err = os.Getwd(); err != nil {
+				return err
+			}
+		}
+		path = config.ProjectPath(projectDir)
+	}
+
+	cfg, err := config.LoadFile(path)
+	if err != nil {
+		return errcode.Wrap(errcode.Config, err)
+	}
+	if err := cfg.Set(key, value); err != nil {
+		return errcode.Wrap(errcode.Config, err)
+	}
+	if err := cfg.Save(path); err != nil {
+		return err
+	}
+
+	if value == "" {
+		fmt.Printf("✓ Unset %s in %s\n", key, path)
+	} else {
+		fmt.Printf("✓ Set %s = %s in %s\n", key, value, path)
+	}
+	if env := config.EnvVar(key); os.Getenv(env) != "" {
+		fmt.Printf("⚠ %s is set and overrides it\n", env)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/config"
+)
+
+func TestConfigSet(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+	for _, k := range config.Keys() {
+		t.Setenv(config.EnvVar(k), "")
+	}
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, "aigogo.lock"), []byte(`{"version": 1, "packages": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	subDir := filepath.Join(projectDir, "src")
+	if err := os.Mkdir(subDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(subDir)
+
+	if err := configSet([]string{"registry", "ghcr.io/ourco"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := configSet([]string{"--project", "registry", "localhost:5000"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"insecure_registries", "auth.encrypt"} {
+		if err := configSet([]string{key, "true", "--project"}); err == nil || !strings.Contains(err.Error(), "set it without --project") {
+			t.Errorf("config set %s --project error = %v, want it refused", key, err)
+		}
+	}
+
+	// --project writes next to aigogo.lock, not in the working directory
+	data, err := os.ReadFile(config.ProjectPath(projectDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `registry = "localhost:5000"`) {
+		t.Errorf("project config:\n%s", data)
+	}
+	userPath, err := config.UserPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(userPath)
+	if !strings.Contains(string(data), `registry = "ghcr.io/ourco"`) {
+		t.Errorf("user config:\n%s", data)
+	}
+
+	// The project's config file overrides the user's
+	if settings := loadProjectSettings(projectDir); settings.Registry != "localhost:5000" {
+		t.Errorf("registry = %q, want localhost:5000", settings.Registry)
+	}
+
+	if err := configSet([]string{"concurrency", "-2"}); err == nil {
+		t.Error("expected a negative concurrency to fail")
+	}
+	if err := configSet([]string{"registry"}); err == nil {
+		t.Error("expected a missing value to fail")
+	}
+}
+
+func TestConfigProjectDir(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+	// The user's ~/.aigogo/config.toml doesn't make the home directory a
+	// project
+	if err := os.MkdirAll(filepath.Join(home, ".aigogo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".aigogo", config.FileName), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(home, "work")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	if got := configProjectDir(); got != "" {
+		t.Errorf("configProjectDir() = %q, want none", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "aigogo.json"), []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := configProjectDir(); got != dir {
+		t.Errorf("configProjectDir() = %q, want %q", got, dir)
+	}
+}
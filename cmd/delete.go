@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
 )
 
 func deleteCmd() *Command {
@@ -21,7 +22,7 @@ func deleteCmd() *Command {
 		Flags:       flags,
 		Run: func(args []string) error {
 			if len(args) < 1 {
-				return fmt.Errorf("usage: aigg delete <registry>/<name>:<tag> [--all]")
+				return errcode.Errorf(errcode.Usage, "usage: aigg delete <registry>/<name>:<tag> [--all]")
 			}
 
 			imageRef := args[0]
@@ -63,7 +64,12 @@ func deleteCmd() *Command {
 				}
 
 				fmt.Println()
-				if err := deleter.DeleteAll(registry, repository); err != nil {
+				deleted, err := deleter.DeleteAll(commandContext(), registry, repository)
+				for _, d := range deleted {
+					noteHistory(d.Ref, d.Digest)
+				}
+				notifyDelete(deleted)
+				if err != nil {
 					return fmt.Errorf("failed to delete all tags: %w", err)
 				}
 
@@ -82,14 +88,17 @@ func deleteCmd() *Command {
 
 				fmt.Printf("Deleting %s from registry...\n", imageRef)
 
-				if err := deleter.Delete(imageRef); err != nil {
+				digest, err := deleter.Delete(commandContext(), imageRef)
+				if err != nil {
 					return fmt.Errorf("failed to delete: %w", err)
 				}
 
+				noteHistory(imageRef, digest)
 				fmt.Printf("✓ Successfully deleted %s from registry\n", imageRef)
+				notifyDelete([]docker.DeletedImage{{Ref: imageRef, Digest: digest}})
 				fmt.Println()
 				fmt.Println("Note: The local cache is not affected. To remove from cache, run:")
-				fmt.Printf("  aigg remove %s\n", imageRef)
+				fmt.Printf("  aigg cache rm %s\n", imageRef)
 			}
 
 			return nil
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/ghactions"
+)
+
+func diffCmd() *Command {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	summary := flags.Bool("summary", false, "Only list the files that differ and a total, without their diffs")
+	exitCode := flags.Bool("exit-code", false, "Exit with code 9 (diff_found) when the packages differ")
+	unified := flags.Int("U", aigogo.DefaultDiffContext, "Lines of context around each change")
+	remote := flags.Bool("remote", false, "Read registry references from the registry even when they're cached")
+	forceLarge := flags.Bool("force-large", false, "Diff files over 1MB or 5000 lines too, instead of only comparing their digests (uses a lot of memory)")
+
+	return &Command{
+		Name:        "diff",
+		Description: "Show how the files of two packages or versions differ",
+		Flags:       flags,
+		Run: func(args []string) error {
+			if len(args) != 2 {
+				return errcode.Errorf(errcode.Usage, "usage: aigg diff <from> <to> [--summary] [--exit-code] [-U lines] [--remote] [--force-large]\n<from> and <to> are name:tag, registry/name:tag, a locked name or a sha256: hash")
+			}
+			if *unified < 0 {
+				return errcode.Errorf(errcode.Usage, "-U must be 0 or more, got %d", *unified)
+			}
+
+			client, err := newClient()
+			if err != nil {
+				return err
+			}
+			client.SetLogger(stderrLogger{})
+			context := *unified
+			if context == 0 {
+				context = -1 // DiffOptions treats 0 as the default
+			}
+			result, err := client.Diff(commandContext(), args[0], args[1], aigogo.DiffOptions{Context: context, Remote: *remote, ForceLarge: *forceLarge})
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetEscapeHTML(false)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(result); err != nil {
+					return err
+				}
+			} else if *summary {
+				printDiffSummary(os.Stdout, result)
+			} else {
+				printDiff(os.Stdout, result, useColor(int(os.Stdout.Fd())), githubOutput())
+			}
+			addJobSummary(diffJobSummary(result, *summary))
+
+			if *exitCode && !result.Identical() {
+				return errcode.Errorf(errcode.DiffFound, "%s and %s differ", args[0], args[1])
+			}
+			return nil
+		},
+	}
+}
+
+// printDiffSummary lists the files that differ with their line counts,
+// then the totals, as git diff --stat does
+func printDiffSummary(w io.Writer, result *aigogo.DiffResult) {
+	if result.Identical() {
+		fmt.Fprintf(w, "No differences between %s and %s\n", result.From, result.To)
+		return
+	}
+	width := 0
+	for _, f := range result.Files {
+		width = max(width, len(f.Path))
+	}
+	for _, f := range result.Files {
+		fmt.Fprintf(w, "  %-8s %-*s  %s\n", f.Status, width, f.Path, fileChanges(f))
+	}
+	added, removed, modified, insertions, deletions := result.Summary()
+	fmt.Fprintf(w, "%d file(s) changed (%d added, %d removed, %d modified), %d insertion(s), %d deletion(s)\n",
+		len(result.Files), added, removed, modified, insertions, deletions)
+}
+
+// fileChanges describes how a file that differs changed: the lines inserted
+// and deleted, or the sizes of a binary or large file
+func fileChanges(f aigogo.FileDiff) string {
+	switch {
+	case f.Binary:
+		return fmt.Sprintf("binary, %d -> %d bytes", f.OldSize, f.NewSize)
+	case f.Large:
+		return fmt.Sprintf("large, %d -> %d bytes", f.OldSize, f.NewSize)
+	}
+	return fmt.Sprintf("+%d -%d", f.Insertions, f.Deletions)
+}
+
+// printDiff writes the unified diff of every file that differs, coloring
+// removed and added lines when color is set, and each file in a GitHub
+// Actions log group when groups is set
+func printDiff(w io.Writer, result *aigogo.DiffResult, color, groups bool) {
+	if result.Identical() {
+		fmt.Fprintf(w, "No differences between %s and %s\n", result.From, result.To)
+		return
+	}
+	for _, f := range result.Files {
+		if groups {
+			fmt.Fprintln(w, ghactions.Group(fmt.Sprintf("%s %s (+%d -%d)", f.Status, f.Path, f.Insertions, f.Deletions)))
+		}
+		fmt.Fprintf(w, "diff %s %s %s\n", result.From, result.To, f.Path)
+		switch {
+		case f.Binary:
+			fmt.Fprintf(w, "Binary file %s differs (%d -> %d bytes)\n", f.Path, f.OldSize, f.NewSize)
+		case f.Large:
+			fmt.Fprintf(w, "Large files differ (use --force-large to diff): %s (%d -> %d bytes)\n", f.Path, f.OldSize, f.NewSize)
+		default:
+			for _, line := range strings.SplitAfter(f.Unified, "\n") {
+				if color {
+					line = colorDiffLine(line)
+				}
+				fmt.Fprint(w, line)
+			}
+		}
+		if groups {
+			fmt.Fprintln(w, ghactions.EndGroup)
+		}
+	}
+}
+
+// colorDiffLine colors a line of a unified diff as git does: headers bold,
+// hunk ranges cyan, deletions red and insertions green
+func colorDiffLine(line string) string {
+	text := strings.TrimSuffix(line, "\n")
+	if text == "" {
+		return line
+	}
+	var code string
+	switch {
+	case strings.HasPrefix(text, "--- "), strings.HasPrefix(text, "+++ "):
+		code = "\x1b[1m"
+	case strings.HasPrefix(text, "@@"):
+		code = "\x1b[36m"
+	case text[0] == '-':
+		code = "\x1b[31m"
+	case text[0] == '+':
+		code = "\x1b[32m"
+	default:
+		return line
+	}
+	return code + text + "\x1b[0m" + line[len(text):]
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+)
+
+func TestPrintDiffSummary(t *testing.T) {
+	result := &aigogo.DiffResult{
+		From: "utils:1.0.0",
+		To:   "utils:1.1.0",
+		Files: []aigogo.FileDiff{
+			{Path: "data.csv", Status: "added", NewSize: 2 << 20, Large: true},
+			{Path: "logo.png", Status: "modified", OldSize: 10, NewSize: 12, Binary: true},
+			{Path: "utils.py", Status: "modified", Insertions: 3, Deletions: 1},
+		},
+	}
+	var buf bytes.Buffer
+	printDiffSummary(&buf, result)
+	want := "  added    data.csv  large, 0 -> 2097152 bytes\n" +
+		"  modified logo.png  binary, 10 -> 12 bytes\n" +
+		"  modified utils.py  +3 -1\n" +
+		"3 file(s) changed (1 added, 0 removed, 2 modified), 3 insertion(s), 1 deletion(s)\n"
+	if buf.String() != want {
+		t.Errorf("printDiffSummary() =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	printDiffSummary(&buf, &aigogo.DiffResult{From: "a", To: "b"})
+	if got := buf.String(); got != "No differences between a and b\n" {
+		t.Errorf("printDiffSummary() of identical packages = %q", got)
+	}
+}
+
+func TestColorDiffLine(t *testing.T) {
+	tests := map[string]string{
+		"--- a/x\n":     "\x1b[1m--- a/x\x1b[0m\n",
+		"@@ -1 +1 @@\n": "\x1b[36m@@ -1 +1 @@\x1b[0m\n",
+		"-old\n":        "\x1b[31m-old\x1b[0m\n",
+		"+new\n":        "\x1b[32m+new\x1b[0m\n",
+		" same\n":       " same\n",
+		"":              "",
+	}
+	for line, want := range tests {
+		if got := colorDiffLine(line); got != want {
+			t.Errorf("colorDiffLine(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
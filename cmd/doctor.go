@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/imports"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+)
+
+func doctorCmd() *Command {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	fix := flags.Bool("fix", false, "Remove orphaned aigogo.pth files and stale tracking entries, and reinstall packages with broken links")
+	python := flags.String("python", "", "Also check this Python interpreter or environment directory")
+
+	return &Command{
+		Name:        "doctor",
+		Description: "Find problems with installed packages, such as broken links and orphaned .pth files",
+		Flags:       flags,
+		Run: func(args []string) error {
+			return runDoctor(*python, *fix)
+		},
+	}
+}
+
+// runDoctor checks the aigogo.pth files of the project and of every Python
+// environment aigg install could write to, reporting ones that point to an
+// imports directory that no longer exists and tracked ones that are gone,
+// and the links of the project's installed packages
+func runDoctor(python string, fix bool) error {
+	// Outside a project, only the environments are checked
+	projectDir, _ := findProjectDir()
+
+	files, err := imports.FindPthFiles(projectDir, python)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Python .pth files:")
+	if len(files) == 0 {
+		fmt.Println("  (none found)")
+	}
+	problems, fixed := 0, 0
+	for _, f := range files {
+		switch {
+		case f.Missing:
+			problems++
+			fmt.Printf("  ✗ %s\n      tracked by this project but no longer exists\n", f.Path)
+			if fix {
+				if err := imports.UntrackPthFile(projectDir, f.Path); err != nil {
+					fmt.Printf("      ⚠ Warning: %v\n", err)
+				} else {
+					fixed++
+					fmt.Println("      ✓ Removed from .aigogo/.pth-location")
+				}
+			}
+		case f.Orphaned():
+			problems++
+			fmt.Printf("  ✗ %s\n      orphaned: %s no longer exists\n", f.Path, f.Target)
+			if fix {
+				if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+					fmt.Printf("      ⚠ Warning: failed to remove: %v\n", err)
+					continue
+				}
+				if f.Tracked {
+					if err := imports.UntrackPthFile(projectDir, f.Path); err != nil {
+						fmt.Printf("      ⚠ Warning: %v\n", err)
+					}
+				}
+				fixed++
+				fmt.Println("      ✓ Removed")
+			}
+		default:
+			fmt.Printf("  ✓ %s\n      → %s\n", f.Path, f.Target)
+		}
+	}
+
+	// Package links, in a project with packages
+	if projectDir != "" {
+		if lock, err := lockfile.Load(filepath.Join(projectDir, "aigogo.lock")); err == nil && len(lock.Packages) > 0 {
+			fmt.Println()
+			linkProblems, linksFixed, err := checkPackageLinks(projectDir, lock, fix)
+			if err != nil {
+				return err
+			}
+			problems += linkProblems
+			fixed += linksFixed
+		}
+	}
+
+	fmt.Println()
+	switch {
+	case problems == 0:
+		fmt.Println("✓ No problems found")
+		return nil
+	case fix && fixed == problems:
+		fmt.Printf("✓ Fixed %d problem(s)\n", fixed)
+		return nil
+	case fix:
+		return errcode.Errorf(errcode.Validation, "fixed %d of %d problem(s)", fixed, problems)
+	}
+	fmt.Println("💡 Run 'aigg doctor --fix' to fix them")
+	return errcode.Errorf(errcode.Validation, "found %d problem(s)", problems)
+}
+
+// checkPackageLinks reports the broken links of the project's installed
+// packages, as aigg install finds them, and with fix reinstalls the
+// packages, which repairs them. It returns the number of problems found and
+// fixed.
+func checkPackageLinks(projectDir string, lock *lockfile.LockFile, fix bool) (int, int, error) {
+	settings := loadProjectSettings(projectDir)
+	cas, err := aigogo.OpenStore(settings)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to initialize store: %w", err)
+	}
+	setupMgr, err := imports.NewSetupManager(projectDir)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to initialize imports manager: %w", err)
+	}
+	setupMgr.SetNamespace(imports.NamespaceFor(settings.Namespace))
+	setupMgr.SetInstallMode(settings.InstallMode())
+	if err := aigogo.SetupPythonLayout(setupMgr, settings, lock, ""); err != nil {
+		return 0, 0, err
+	}
+
+	broken := aigogo.BrokenLinks(setupMgr, cas, lock)
+	names := make([]string, 0, len(lock.Packages))
+	for name := range lock.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println("Package links:")
+	problems := 0
+	var reinstall []string
+	for _, name := range names {
+		if len(broken[name]) == 0 {
+			fmt.Printf("  ✓ %s\n", name)
+			continue
+		}
+		for _, p := range broken[name] {
+			problems++
+			fmt.Printf("  ✗ %s: %s\n      %s\n", name, p.Path, p.Reason)
+		}
+		reinstall = append(reinstall, name)
+	}
+	if !fix || len(reinstall) == 0 {
+		return problems, 0, nil
+	}
+
+	// A repair doesn't run postinstall scripts; aigg install lists them
+	fmt.Println()
+	if err := runInstall(aigogo.InstallOptions{Packages: reinstall, IgnoreScripts: true}); err != nil {
+		fmt.Printf("⚠ Warning: failed to reinstall: %v\n", err)
+		return problems, 0, nil
+	}
+	return problems, problems, nil
+}
@@ -1,22 +1,23 @@
 package cmd
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
-	"strconv"
 	"strings"
 
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/interpreter"
 	"github.com/aupeachmo/aigogo/pkg/lockfile"
 	"github.com/aupeachmo/aigogo/pkg/manifest"
-	"github.com/aupeachmo/aigogo/pkg/store"
+	"github.com/aupeachmo/aigogo/pkg/userdirs"
 )
 
-var versionSegmentRe = regexp.MustCompile(`^(\d+)`)
-
 func execCmd() *Command {
 	return &Command{
 		Name:        "exec",
@@ -27,7 +28,7 @@ func execCmd() *Command {
 				fmt.Println()
 				fmt.Println("Run an agent's entrypoint script.")
 				fmt.Println("The agent must be in aigogo.lock and have a \"scripts\" field in its manifest.")
-				return fmt.Errorf("agent name is required")
+				return errcode.Errorf(errcode.Usage, "agent name is required")
 			}
 
 			agentName := args[0]
@@ -40,7 +41,7 @@ func execCmd() *Command {
 
 func runExec(agentName string, args []string) error {
 	// 1. Find lock file and resolve the package
-	_, lock, err := lockfile.FindLockFile()
+	lockPath, lock, err := lockfile.FindLockFile()
 	if err != nil {
 		return fmt.Errorf("failed to find aigogo.lock: %w\n"+
 			"Run 'aigg add <source>' to add the agent first", err)
@@ -60,25 +61,25 @@ func runExec(agentName string, args []string) error {
 		}
 	}
 	if !exists {
-		return fmt.Errorf("agent %q not found in aigogo.lock\n"+
+		return errcode.Errorf(errcode.NotFound, "agent %q not found in aigogo.lock\n"+
 			"To add it, run: aigg add <registry>/%s:<tag> && aigg install", agentName, agentName)
 	}
 
 	// 2. Get from content-addressable store
-	cas, err := store.NewStore()
+	cas, err := aigogo.OpenStore(loadProjectSettings(filepath.Dir(lockPath)))
 	if err != nil {
 		return fmt.Errorf("failed to initialize store: %w", err)
 	}
 
 	hash := pkg.GetIntegrityHash()
-	if !cas.Has(hash) {
+	if !pkg.IsLocal() && !cas.Has(hash) {
 		return fmt.Errorf("agent %q not installed (not in store)\n"+
 			"Run: aigg install", agentName)
 	}
 
-	storedPkg, err := cas.Get(hash)
+	storedPkg, err := aigogo.OpenLockedPackage(cas, filepath.Dir(lockPath), pkg)
 	if err != nil {
-		return fmt.Errorf("failed to get agent from store: %w", err)
+		return fmt.Errorf("failed to get agent %q: %w", agentName, err)
 	}
 
 	// 3. Load manifest to get scripts and language info
@@ -86,25 +87,29 @@ func runExec(agentName string, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to load agent manifest: %w", err)
 	}
+	if pkg.IsLocal() {
+		hash = localEnvHash(storedPkg.FilesDir, m)
+	}
 
-	if len(m.Scripts) == 0 {
+	scripts := m.EntryScripts()
+	if len(scripts) == 0 {
 		return fmt.Errorf("agent %q has no scripts defined in its manifest\n"+
 			"The package author must add a \"scripts\" field to aigogo.json", agentName)
 	}
 
 	// Find the script to run: try agent name, normalized name, then fallback to single script
-	scriptFile, ok := m.Scripts[agentName]
+	scriptFile, ok := scripts[agentName]
 	if !ok && lookupName != agentName {
-		scriptFile, ok = m.Scripts[lookupName]
+		scriptFile, ok = scripts[lookupName]
 	}
 	if !ok {
-		if len(m.Scripts) == 1 {
-			for _, v := range m.Scripts {
+		if len(scripts) == 1 {
+			for _, v := range scripts {
 				scriptFile = v
 			}
 		} else {
 			var available []string
-			for k := range m.Scripts {
+			for k := range scripts {
 				available = append(available, k)
 			}
 			return fmt.Errorf("no script named %q found. Available scripts: %s",
@@ -114,7 +119,17 @@ func runExec(agentName string, args []string) error {
 
 	scriptPath := filepath.Join(storedPkg.FilesDir, scriptFile)
 	if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
-		return fmt.Errorf("script file %q not found in package", scriptFile)
+		return errcode.Errorf(errcode.NotFound, "script file %q not found in package", scriptFile)
+	}
+
+	// A multi-language package runs the script with the interpreter and
+	// dependencies of the script's language, in an env of its own
+	envKey := hash
+	if len(m.Languages) > 0 {
+		if lang := manifest.LanguageOfFile(scriptFile, m.AllLanguages()); lang != "" {
+			m = m.ForLanguage(lang)
+			envKey = hash + "-" + lang
+		}
 	}
 
 	// 4. Find and validate the interpreter
@@ -124,7 +139,7 @@ func runExec(agentName string, args []string) error {
 	}
 
 	// 5. Set up dependencies environment if needed
-	envDir, err := setupExecEnv(hash, m, interpreter)
+	envDir, err := setupExecEnv(envKey, m, interpreter)
 	if err != nil {
 		return fmt.Errorf("failed to set up execution environment: %w", err)
 	}
@@ -137,240 +152,30 @@ func runExec(agentName string, args []string) error {
 func findInterpreter(lang manifest.Language) (string, error) {
 	switch lang.Name {
 	case "python":
-		return findPythonInterpreter(lang.Version)
+		return interpreter.FindPython(lang.Version)
 	case "javascript", "typescript":
-		return findNodeInterpreter(lang.Version)
+		return interpreter.FindNode(lang.Version)
 	default:
 		return "", fmt.Errorf("exec is not supported for language %q (supported: python, javascript)", lang.Name)
 	}
 }
 
-// findPythonInterpreter searches for a suitable Python interpreter
-func findPythonInterpreter(versionConstraint string) (string, error) {
-	candidates := []string{}
-
-	// 1. Check active virtual environment
-	if venv := os.Getenv("VIRTUAL_ENV"); venv != "" {
-		candidates = append(candidates, filepath.Join(venv, "bin", "python"))
-	}
-
-	// 2. Check local project venvs
-	cwd, _ := os.Getwd()
-	if cwd != "" {
-		for _, dir := range []string{"venv", ".venv"} {
-			candidates = append(candidates, filepath.Join(cwd, dir, "bin", "python"))
-		}
-	}
-
-	// 3. System interpreters
-	candidates = append(candidates, "python3", "python")
-
-	for _, candidate := range candidates {
-		path, err := exec.LookPath(candidate)
-		if err != nil {
-			continue
-		}
-
-		// Validate version if constraint is specified
-		if versionConstraint != "" {
-			version, err := getPythonVersion(path)
-			if err != nil {
-				continue
-			}
-			if !checkVersionConstraint(version, versionConstraint) {
-				continue // Try next candidate
-			}
-		}
-
-		return path, nil
-	}
-
-	if versionConstraint != "" {
-		return "", fmt.Errorf("no Python interpreter found satisfying %s\n"+
-			"Install a compatible Python version or activate a virtual environment", versionConstraint)
-	}
-	return "", fmt.Errorf("python not found on PATH\nInstall Python 3 to run Python agents")
-}
-
-// findNodeInterpreter searches for a suitable Node.js interpreter
-func findNodeInterpreter(versionConstraint string) (string, error) {
-	path, err := exec.LookPath("node")
-	if err != nil {
-		return "", fmt.Errorf("node not found on PATH\nInstall Node.js to run JavaScript agents")
-	}
-
-	if versionConstraint != "" {
-		version, err := getNodeVersion(path)
-		if err != nil {
-			return path, nil // Can't check, proceed anyway
-		}
-		if !checkVersionConstraint(version, versionConstraint) {
-			return "", fmt.Errorf("node %s does not satisfy %s\nInstall a compatible Node.js version",
-				version, versionConstraint)
-		}
-	}
-
-	return path, nil
-}
-
-// getPythonVersion runs python --version and returns the version string
-func getPythonVersion(pythonPath string) (string, error) {
-	out, err := exec.Command(pythonPath, "--version").Output()
-	if err != nil {
-		return "", err
-	}
-	// Output: "Python 3.11.5"
-	parts := strings.Fields(strings.TrimSpace(string(out)))
-	if len(parts) >= 2 {
-		return parts[1], nil
-	}
-	return "", fmt.Errorf("unexpected python version output: %s", string(out))
-}
-
-// getNodeVersion runs node --version and returns the version string
-func getNodeVersion(nodePath string) (string, error) {
-	out, err := exec.Command(nodePath, "--version").Output()
+// envsDir returns the path to envs/ in the data directory
+func envsDir() (string, error) {
+	dir, err := userdirs.DataDir()
 	if err != nil {
 		return "", err
 	}
-	// Output: "v20.10.0"
-	version := strings.TrimSpace(string(out))
-	version = strings.TrimPrefix(version, "v")
-	return version, nil
+	return filepath.Join(dir, "envs"), nil
 }
 
-// checkVersionConstraint checks if a version satisfies a constraint like ">=3.8,<4.0" or ">=18"
-func checkVersionConstraint(version, constraint string) bool {
-	parts := parseVersion(version)
-	if parts == nil {
-		return true // Can't parse, allow it
-	}
-
-	// Split constraints by comma
-	constraints := strings.Split(constraint, ",")
-	for _, c := range constraints {
-		c = strings.TrimSpace(c)
-		if c == "" {
-			continue
-		}
-
-		if !evaluateConstraint(parts, c) {
-			return false
-		}
-	}
-
-	return true
-}
-
-// parseVersion parses "3.11.5" into [3, 11, 5]
-func parseVersion(v string) []int {
-	v = strings.TrimPrefix(v, "v")
-	segments := strings.Split(v, ".")
-	result := make([]int, 0, len(segments))
-	for _, s := range segments {
-		// Strip any pre-release suffix (e.g., "5rc1")
-		match := versionSegmentRe.FindString(s)
-		if match == "" {
-			break
-		}
-		n, err := strconv.Atoi(match)
-		if err != nil {
-			break
-		}
-		result = append(result, n)
-	}
-	if len(result) == 0 {
-		return nil
-	}
-	return result
-}
-
-// evaluateConstraint evaluates a single constraint like ">=3.8"
-func evaluateConstraint(version []int, constraint string) bool {
-	var op string
-	var target string
-
-	if strings.HasPrefix(constraint, ">=") {
-		op = ">="
-		target = strings.TrimPrefix(constraint, ">=")
-	} else if strings.HasPrefix(constraint, "<=") {
-		op = "<="
-		target = strings.TrimPrefix(constraint, "<=")
-	} else if strings.HasPrefix(constraint, "!=") {
-		op = "!="
-		target = strings.TrimPrefix(constraint, "!=")
-	} else if strings.HasPrefix(constraint, ">") {
-		op = ">"
-		target = strings.TrimPrefix(constraint, ">")
-	} else if strings.HasPrefix(constraint, "<") {
-		op = "<"
-		target = strings.TrimPrefix(constraint, "<")
-	} else if strings.HasPrefix(constraint, "==") {
-		op = "=="
-		target = strings.TrimPrefix(constraint, "==")
-	} else {
-		return true // Unknown operator, allow
-	}
-
-	targetParts := parseVersion(target)
-	if targetParts == nil {
-		return true
-	}
-
-	cmp := compareVersions(version, targetParts)
-
-	switch op {
-	case ">=":
-		return cmp >= 0
-	case "<=":
-		return cmp <= 0
-	case ">":
-		return cmp > 0
-	case "<":
-		return cmp < 0
-	case "==":
-		return cmp == 0
-	case "!=":
-		return cmp != 0
-	}
-
-	return true
-}
-
-// compareVersions compares two version arrays, returns -1, 0, or 1
-func compareVersions(a, b []int) int {
-	maxLen := len(a)
-	if len(b) > maxLen {
-		maxLen = len(b)
-	}
-
-	for i := 0; i < maxLen; i++ {
-		av := 0
-		if i < len(a) {
-			av = a[i]
-		}
-		bv := 0
-		if i < len(b) {
-			bv = b[i]
-		}
-
-		if av < bv {
-			return -1
-		}
-		if av > bv {
-			return 1
-		}
-	}
-	return 0
-}
-
-// envsDir returns the path to ~/.aigogo/envs/
-func envsDir() (string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
-	}
-	return filepath.Join(home, ".aigogo", "envs"), nil
+// localEnvHash returns the key of a local path package's exec environment,
+// which changes with the package's directory and dependencies, so editing
+// them sets up a new environment
+func localEnvHash(dir string, m *manifest.Manifest) string {
+	deps, _ := json.Marshal(m.Dependencies)
+	sum := sha256.Sum256(append([]byte(dir+"\x00"), deps...))
+	return hex.EncodeToString(sum[:])
 }
 
 // envPath returns the path for a specific package's env directory
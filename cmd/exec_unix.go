@@ -8,5 +8,6 @@ import "syscall"
 // On Unix, this uses syscall.Exec which ensures signals, exit codes,
 // and stdio are properly forwarded with zero overhead.
 func replaceProcess(binary string, args []string, env []string) error {
+	flushOutput()
 	return syscall.Exec(binary, args, env)
 }
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func exportCmd() *Command {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := flags.String("o", "", "Where to write the bundle (default: <name>-<version>.tar.gz, or <name>-<version> for --format oci)")
+	format := flags.String("format", "tar", "Bundle format: tar (a gzip-compressed tarball) or oci (an OCI image layout directory)")
+	force := flags.Bool("force", false, "Overwrite an existing output")
+
+	return &Command{
+		Name:        "export",
+		Description: "Export a package to a tarball or OCI image layout, to share it without a registry",
+		Flags:       flags,
+		Run: func(args []string) error {
+			if len(args) != 1 {
+				return errcode.Errorf(errcode.Usage, "usage: aigg export <name:tag|registry/name:tag|locked name|sha256:hash> [-o path] [--format tar|oci] [--force]")
+			}
+			if *format != "tar" && *format != "oci" {
+				return errcode.Errorf(errcode.Usage, "invalid --format %q (expected tar or oci)", *format)
+			}
+			return exportPackage(args[0], *output, *format, *force)
+		},
+	}
+}
+
+// exportPackage writes the package ref names, found as aigg info finds it,
+// as a bundle aigg import reads back
+func exportPackage(ref, output, format string, force bool) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	client.SetLogger(stderrLogger{})
+	pkg, err := client.OpenPackage(commandContext(), ref, false)
+	if err != nil {
+		return err
+	}
+	var m *manifest.Manifest
+	if data, err := pkg.ReadFile("aigogo.json"); err == nil {
+		m = &manifest.Manifest{}
+		if err := json.Unmarshal(data, m); err != nil {
+			return fmt.Errorf("failed to parse aigogo.json of %s: %w", ref, err)
+		}
+	}
+	name, version, _ := aigogo.PackageIdentity(ref, m)
+
+	layer, err := pkg.Layer(m)
+	if err != nil {
+		return fmt.Errorf("failed to package %s: %w", ref, err)
+	}
+
+	if output == "" {
+		output = name + "-" + version
+		if format == "tar" {
+			output += ".tar.gz"
+		}
+	}
+	if _, err := os.Lstat(output); err == nil {
+		if !force {
+			return errcode.Errorf(errcode.Validation, "%s already exists\nUse --force to overwrite it", output)
+		}
+		if err := os.RemoveAll(output); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", output, err)
+		}
+	}
+
+	if format == "oci" {
+		annotations := map[string]string{}
+		if m != nil {
+			annotations = docker.Annotations(m)
+		}
+		if _, err := docker.WriteOCILayout(output, version, layer, annotations); err != nil {
+			return fmt.Errorf("failed to write OCI layout: %w", err)
+		}
+	} else {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(layer); err != nil {
+			return fmt.Errorf("failed to compress bundle: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("failed to compress bundle: %w", err)
+		}
+		if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", output, err)
+		}
+	}
+
+	fmt.Printf("✓ Exported %s@%s to %s\n", name, version, output)
+	if format == "oci" {
+		fmt.Printf("  Push it with e.g.: skopeo copy oci:%s:%s docker://<registry>/%s:%s\n", output, version, name, version)
+	}
+	fmt.Printf("  Import it with: aigg import %s\n", output)
+	return nil
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"flag"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+)
+
+// maxExtractSizeFlag adds --max-extract-size to the flags of a command that
+// downloads or extracts package layers
+func maxExtractSizeFlag(flags *flag.FlagSet) *string {
+	return flags.String("max-extract-size", "", "Refuse packages whose layer or files are larger than this, e.g. 2GB (default 512MB)")
+}
+
+// setMaxExtractSize applies a --max-extract-size, when given
+func setMaxExtractSize(value string) error {
+	if value == "" {
+		return nil
+	}
+	n, err := parseByteSize(value)
+	if err != nil {
+		return errcode.Errorf(errcode.Usage, "invalid --max-extract-size: %v", err)
+	}
+	docker.SetMaxExtractSize(n)
+	return nil
+}
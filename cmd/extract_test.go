@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+)
+
+func TestSetMaxExtractSize(t *testing.T) {
+	t.Cleanup(func() { docker.SetMaxExtractSize(docker.DefaultMaxExtractSize) })
+	for value, ok := range map[string]bool{"": true, "2GB": true, "64KB": true, "0": false, "nope": false} {
+		err := setMaxExtractSize(value)
+		if (err == nil) != ok || (err != nil && errcode.Of(err) != errcode.Usage) {
+			t.Errorf("setMaxExtractSize(%q) = %v", value, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func filesCmd() *Command {
+	return &Command{
+		Name:        "files",
+		Description: "Manage the files included in the package",
+		Run: func(args []string) error {
+			if len(args) == 0 {
+				return errcode.Errorf(errcode.Usage, "usage: aigg files <freeze> [args...]\n\nSubcommands:\n  freeze [--dry-run] [--force]  Replace files.include with the files it currently resolves to")
+			}
+
+			switch args[0] {
+			case "freeze":
+				return freezeFiles(args[1:])
+			default:
+				return errcode.Errorf(errcode.Usage, "unknown subcommand '%s'\nValid subcommands: freeze", args[0])
+			}
+		},
+	}
+}
+
+// freezeFiles rewrites files.include as the explicit list of files that
+// discovery finds for it, so that "auto" or glob patterns stop picking up
+// new files and aigg add file / rm file can manage the list
+func freezeFiles(args []string) error {
+	fs := flag.NewFlagSet("files freeze", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Show the resolved list without changing aigogo.json")
+	force := fs.Bool("force", false, "Skip confirmation prompt")
+	if err := fs.Parse(args); err != nil {
+		return errcode.Wrap(errcode.Usage, err)
+	}
+	if fs.NArg() > 0 {
+		return errcode.Errorf(errcode.Usage, "unexpected argument: %s\nUsage: aigg files freeze [--dry-run] [--force]", fs.Arg(0))
+	}
+
+	m, manifestDir, err := manifest.FindManifest()
+	if err != nil {
+		return fmt.Errorf("failed to find aigogo.json: %w\nRun 'aigg init' first", err)
+	}
+	manifestPath := filepath.Join(manifestDir, "aigogo.json")
+
+	files, err := aigogo.IncludedFiles(manifestDir, m)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("files.include matches no files; nothing to freeze")
+	}
+
+	current, _ := m.Files.GetIncludePatterns()
+	if reflect.DeepEqual(current, files) {
+		fmt.Println("files.include is already an explicit list of files")
+		return nil
+	}
+
+	fmt.Printf("files.include (%s) resolves to %d file(s):\n", describeInclude(&m.Files), len(files))
+	for _, f := range files {
+		fmt.Printf("  %s\n", f)
+	}
+	fmt.Println()
+
+	if *dryRun {
+		fmt.Println("Dry run: aigogo.json not changed")
+		return nil
+	}
+
+	if !*force {
+		fmt.Print("Write this list to files.include? (yes/no): ")
+		reader := bufio.NewReader(os.Stdin)
+		response, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read confirmation: %w", err)
+		}
+		if strings.TrimSpace(strings.ToLower(response)) != "yes" {
+			fmt.Println("Operation cancelled.")
+			return nil
+		}
+	}
+
+	m.Files.Include = files
+	if err := saveManifest(manifestPath, m); err != nil {
+		return fmt.Errorf("failed to save manifest: %w", err)
+	}
+
+	fmt.Printf("✓ Froze files.include to %d file(s)\n", len(files))
+	fmt.Println("New files are no longer picked up automatically; add them with 'aigg add file <path>'")
+	return nil
+}
+
+// describeInclude formats files.include for display: "auto" or the patterns
+func describeInclude(spec *manifest.FileSpec) string {
+	patterns, auto := spec.GetIncludePatterns()
+	if auto {
+		return `"auto"`
+	}
+	return strings.Join(patterns, ", ")
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func TestFreezeFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	for _, f := range []string{"utils.py", "lib/helpers.py", "notes.txt", "tests/test_utils.py"} {
+		if err := os.MkdirAll(filepath.Dir(f), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(f, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifestPath := filepath.Join(dir, "aigogo.json")
+	m := &manifest.Manifest{
+		Name:     "utils",
+		Version:  "1.0.0",
+		Language: manifest.Language{Name: "python"},
+		Files:    manifest.FileSpec{Include: "auto", Exclude: []string{"tests/"}},
+	}
+	if err := manifest.Save(manifestPath, m); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := freezeFiles([]string{"--dry-run"}); err != nil {
+		t.Fatalf("freezeFiles --dry-run failed: %v", err)
+	}
+	if m, _ = manifest.Load(manifestPath); m.Files.Include != "auto" {
+		t.Fatalf("--dry-run changed files.include to %v", m.Files.Include)
+	}
+
+	if err := freezeFiles([]string{"--force"}); err != nil {
+		t.Fatalf("freezeFiles failed: %v", err)
+	}
+	m, err := manifest.Load(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, auto := m.Files.GetIncludePatterns()
+	want := []string{"lib/helpers.py", "utils.py"}
+	if auto || !reflect.DeepEqual(got, want) {
+		t.Errorf("files.include = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(m.Files.Exclude, []string{"tests/"}) {
+		t.Errorf("files.exclude changed to %v", m.Files.Exclude)
+	}
+
+	// Freezing an explicit list is a no-op
+	if err := freezeFiles([]string{"--force"}); err != nil {
+		t.Errorf("freezeFiles on an explicit list failed: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/depgen"
+	"github.com/aupeachmo/aigogo/pkg/ghactions"
+	"github.com/aupeachmo/aigogo/pkg/webhook"
+)
+
+// maxSummaryDiff is how much of a unified diff the job summary shows, well
+// under the 1 MiB GitHub allows a step's summary
+const maxSummaryDiff = 64 << 10
+
+// printFindingAnnotations prints findings as annotations with --output
+// github, where depgen.LocateFindings puts them. title names the command,
+// and each finding's rule is added to it.
+func printFindingAnnotations(title string, findings []depgen.Finding, manifestPath string) {
+	if !githubOutput() {
+		return
+	}
+	for i, loc := range depgen.LocateFindings(findings, manifestPath) {
+		level := ghactions.LevelNotice
+		switch findings[i].Severity {
+		case depgen.SeverityError:
+			level = ghactions.LevelError
+		case depgen.SeverityWarning:
+			level = ghactions.LevelWarning
+		}
+		fmt.Println(ghactions.Annotation{
+			Level:   level,
+			File:    annotationPath(loc.File),
+			Line:    loc.Line,
+			Title:   fmt.Sprintf("%s: %s", title, findings[i].Rule),
+			Message: loc.Message,
+		})
+	}
+}
+
+// annotationPath returns path relative to the repository's root, as GitHub
+// reads an annotation's file: aigg may run in a subdirectory of the
+// checkout ($GITHUB_WORKSPACE)
+func annotationPath(path string) string {
+	workspace := os.Getenv("GITHUB_WORKSPACE")
+	if workspace == "" || path == "" {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(workspace, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// startGroup starts a collapsed group of log lines with --output github,
+// and returns the function that ends it
+func startGroup(title string) func() {
+	if !githubOutput() {
+		return func() {}
+	}
+	fmt.Println(ghactions.Group(title))
+	return func() { fmt.Println(ghactions.EndGroup) }
+}
+
+// addJobSummary adds Markdown to the job summary with --output github. The
+// command's work is done by then, so a summary that can't be written is a
+// warning.
+func addJobSummary(markdown string) {
+	if !githubOutput() {
+		return
+	}
+	if err := ghactions.AppendSummary(markdown); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ Warning: %v\n", err)
+	}
+}
+
+// fileTableRow is a file in a job summary's table of changed files
+type fileTableRow struct {
+	path, status, changes string
+}
+
+// writeFileTable writes a Markdown table of changed files
+func writeFileTable(b *strings.Builder, rows []fileTableRow) {
+	b.WriteString("| File | Status | Changes |\n|------|--------|---------|\n")
+	for _, r := range rows {
+		fmt.Fprintf(b, "| %s | %s | %s |\n", ghactions.EscapeMarkdown(r.path), r.status, r.changes)
+	}
+}
+
+// diffJobSummary describes a diff for the job summary: the totals, a table
+// of the files that differ and, unless summaryOnly, their diffs, up to
+// maxSummaryDiff
+func diffJobSummary(result *aigogo.DiffResult, summaryOnly bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### aigg diff %s %s\n\n", ghactions.EscapeMarkdown(result.From), ghactions.EscapeMarkdown(result.To))
+	if result.Identical() {
+		fmt.Fprintf(&b, "No differences (%d file(s) compared)\n", result.Unchanged)
+		return b.String()
+	}
+	added, removed, modified, insertions, deletions := result.Summary()
+	fmt.Fprintf(&b, "%d file(s) changed (%d added, %d removed, %d modified), %d insertion(s), %d deletion(s)\n\n",
+		len(result.Files), added, removed, modified, insertions, deletions)
+	var rows []fileTableRow
+	for _, f := range result.Files {
+		rows = append(rows, fileTableRow{f.Path, f.Status, fileChanges(f)})
+	}
+	writeFileTable(&b, rows)
+	if summaryOnly {
+		return b.String()
+	}
+
+	shown := 0
+	for _, f := range result.Files {
+		if f.Binary || f.Unified == "" {
+			continue
+		}
+		if shown+len(f.Unified) > maxSummaryDiff {
+			b.WriteString("\nThe rest of the diff is in the step's log.\n")
+			break
+		}
+		shown += len(f.Unified)
+		fmt.Fprintf(&b, "\n<details><summary>%s</summary>\n\n```diff\n%s", ghactions.EscapeMarkdown(f.Path), f.Unified)
+		if !strings.HasSuffix(f.Unified, "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString("```\n\n</details>\n")
+	}
+	return b.String()
+}
+
+// pushJobSummary describes a push for the job summary, with how it differs
+// from the previous version when the push event has a diff
+func pushJobSummary(result *aigogo.PushResult, e *webhook.Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### aigg push %s\n\n", ghactions.EscapeMarkdown(result.Ref))
+	b.WriteString("| | |\n|---|---|\n")
+	if e.Name != "" {
+		fmt.Fprintf(&b, "| Package | %s %s |\n", ghactions.EscapeMarkdown(e.Name), ghactions.EscapeMarkdown(e.Version))
+	}
+	fmt.Fprintf(&b, "| From | %s |\n", ghactions.EscapeMarkdown(result.From))
+	if result.Digest != "" {
+		fmt.Fprintf(&b, "| Digest | `%s` |\n", result.Digest)
+	}
+	fmt.Fprintf(&b, "| Files | %d |\n", result.Files)
+	if e.Diff == nil {
+		return b.String()
+	}
+
+	previous := ghactions.EscapeMarkdown(e.Previous)
+	if len(e.Diff.Files) == 0 {
+		fmt.Fprintf(&b, "\nIdentical to %s\n", previous)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "\nChanges since %s: %d file(s), %d insertion(s), %d deletion(s)\n\n", previous, len(e.Diff.Files), e.Diff.Insertions, e.Diff.Deletions)
+	var rows []fileTableRow
+	for _, f := range e.Diff.Files {
+		rows = append(rows, fileTableRow{f.Path, f.Status, fmt.Sprintf("+%d -%d", f.Insertions, f.Deletions)})
+	}
+	writeFileTable(&b, rows)
+	return b.String()
+}
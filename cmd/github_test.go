@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/webhook"
+)
+
+func TestJobSummaries(t *testing.T) {
+	result := &aigogo.DiffResult{From: "utils:1.0.0", To: "utils:1.1.0", Unchanged: 2, Files: []aigogo.FileDiff{
+		{Path: "my_utils.py", Status: "modified", Insertions: 1, Unified: "--- a/my_utils.py\n+++ b/my_utils.py\n@@ -1 +1,2 @@\n x\n+y\n"},
+		{Path: "logo.png", Status: "added", Binary: true, NewSize: 10},
+	}}
+	summary := diffJobSummary(result, false)
+	for _, want := range []string{"### aigg diff utils:1.0.0 utils:1.1.0", "2 file(s) changed (1 added, 0 removed, 1 modified)",
+		`| my\_utils.py | modified | +1 -0 |`, "| logo.png | added | binary, 0 -> 10 bytes |", "<summary>my\\_utils.py</summary>\n\n```diff\n--- a/my_utils.py"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("diffJobSummary() = %s\nwant it to contain %q", summary, want)
+		}
+	}
+	if summary := diffJobSummary(result, true); strings.Contains(summary, "```diff") {
+		t.Errorf("diffJobSummary() with summaryOnly = %s, want no diffs", summary)
+	}
+	if summary := diffJobSummary(&aigogo.DiffResult{From: "a", To: "b", Unchanged: 3}, false); !strings.Contains(summary, "No differences (3 file(s) compared)") {
+		t.Errorf("diffJobSummary() of identical packages = %s", summary)
+	}
+
+	push := &aigogo.PushResult{Ref: "ghcr.io/org/utils:1.1.0", From: "utils:1.1.0", Digest: "sha256:abc", Files: 3}
+	e := &webhook.Event{Name: "utils", Version: "1.1.0", Previous: "1.0.0",
+		Diff: &webhook.DiffSummary{Insertions: 1, Files: []webhook.FileChange{{Path: "utils.py", Status: "modified", Insertions: 1}}}}
+	summary = pushJobSummary(push, e)
+	for _, want := range []string{"### aigg push ghcr.io/org/utils:1.1.0", "| Package | utils 1.1.0 |", "| Digest | `sha256:abc` |",
+		"Changes since 1.0.0: 1 file(s), 1 insertion(s), 0 deletion(s)", "| utils.py | modified | +1 -0 |"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("pushJobSummary() = %s\nwant it to contain %q", summary, want)
+		}
+	}
+}
+
+func TestAnnotationPath(t *testing.T) {
+	workspace := t.TempDir()
+	t.Chdir(workspace)
+	if err := os.Mkdir("pkg", 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir("pkg")
+
+	t.Setenv("GITHUB_WORKSPACE", "")
+	if got := annotationPath("utils.py"); got != "utils.py" {
+		t.Errorf("annotationPath() outside GitHub Actions = %q", got)
+	}
+	t.Setenv("GITHUB_WORKSPACE", workspace)
+	if got := annotationPath("utils.py"); got != "pkg/utils.py" {
+		t.Errorf("annotationPath() = %q, want pkg/utils.py", got)
+	}
+	t.Setenv("GITHUB_WORKSPACE", filepath.Join(workspace, "elsewhere"))
+	if got := annotationPath("utils.py"); got != "utils.py" {
+		t.Errorf("annotationPath() outside the workspace = %q", got)
+	}
+}
@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/history"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+)
+
+// historyCommands are the commands recorded in the history
+var historyCommands = map[string]bool{"add": true, "install": true, "build": true, "push": true, "delete": true}
+
+// historyEnabled is the history setting: whether historyCommands are
+// recorded
+var historyEnabled = true
+
+// historyRefs are the packages the running command has acted on so far,
+// for its history entry
+var historyRefs []history.Ref
+
+// noteHistory adds a package the running command acted on to its history
+// entry, with its digest when it has one
+func noteHistory(ref, digest string) {
+	historyRefs = append(historyRefs, history.Ref{Ref: ref, Digest: digest})
+}
+
+// noteAdded adds the packages add locked to its history entry, with their
+// integrity hashes
+func noteAdded(result *aigogo.AddResult) {
+	for _, a := range result.Added {
+		if a.Locked.IsLocal() {
+			noteHistory(a.Locked.Path, "")
+		} else {
+			noteHistory(a.Locked.Source, a.Locked.Integrity)
+		}
+	}
+}
+
+// noteInstalled adds the packages install linked to its history entry,
+// with the sources and integrity hashes aigogo.lock has for them
+func noteInstalled(result *aigogo.InstallResult) {
+	lock, err := lockfile.Load(result.LockPath)
+	if err != nil {
+		logging.Verbosef("not recording installed packages in the history: %v\n", err)
+		return
+	}
+	for _, name := range result.Installed {
+		pkg, ok := lock.Get(name)
+		switch {
+		case !ok:
+			noteHistory(name, "")
+		case pkg.IsLocal():
+			noteHistory(pkg.Path, "")
+		default:
+			noteHistory(pkg.Source, pkg.Integrity)
+		}
+	}
+}
+
+// recordsHistory reports whether running command with args is recorded:
+// the commands that change packages, not add's subcommands that edit
+// aigogo.json
+func recordsHistory(command string, args []string) bool {
+	if !historyEnabled || !historyCommands[command] {
+		return false
+	}
+	if command == "add" && len(args) > 0 {
+		switch args[0] {
+		case "file", "dep", "dev", "peer":
+			return false
+		}
+	}
+	return true
+}
+
+// recordHistory appends the command that ran with args since start, and
+// failed with err or succeeded, to the history. The command has run by
+// then, so a history that can't be written only shows with --verbose.
+func recordHistory(command string, args []string, start time.Time, err error) {
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	e := &history.Entry{
+		Time:     start.UTC(),
+		Command:  command,
+		Args:     args,
+		Refs:     historyRefs,
+		User:     history.CurrentUser(),
+		Status:   history.StatusOK,
+		Duration: time.Since(start).Round(time.Millisecond).Seconds(),
+		Tool:     "aigg/" + version,
+	}
+	e.Host, _ = os.Hostname()
+	e.Dir, _ = os.Getwd()
+	if err != nil {
+		code := errcode.Of(err)
+		e.Status, e.ExitCode = string(code), code.ExitCode()
+		e.Error, _, _ = strings.Cut(err.Error(), "\n")
+	}
+
+	path, pathErr := history.Path()
+	if pathErr == nil {
+		pathErr = history.Append(path, e)
+	}
+	if pathErr != nil {
+		logging.Verbosef("not recorded in the history: %v\n", pathErr)
+	}
+}
+
+func historyCmd() *Command {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	command := flags.String("command", "", "Show only these commands, comma-separated: add, install, build, push, delete")
+	ref := flags.String("ref", "", "Show only commands on a package whose reference contains this")
+	user := flags.String("user", "", "Show only this user's commands")
+	since := flags.String("since", "", "Show only commands since this long ago (e.g. 12h, 7d, 2w) or this date (YYYY-MM-DD)")
+	failed := flags.Bool("failed", false, "Show only commands that failed")
+	limit := flags.Int("n", 20, "Show at most this many commands, the latest (0 for all)")
+
+	return &Command{
+		Name:        "history",
+		Description: "Show the adds, installs, builds, pushes and deletes aigg ran",
+		Flags:       flags,
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return errcode.Errorf(errcode.Usage, "unexpected argument: %s\nUsage: aigg history [--command <name,...>] [--ref <text>] [--user <name>] [--since <age|date>] [--failed] [-n <count>]", args[0])
+			}
+			filter := history.Filter{Ref: *ref, User: *user, Failed: *failed}
+			if *command != "" {
+				for _, c := range strings.Split(*command, ",") {
+					c = strings.TrimSpace(c)
+					if !historyCommands[c] {
+						return errcode.Errorf(errcode.Usage, "--command %s isn't recorded (expected add, install, build, push or delete)", c)
+					}
+					filter.Commands = append(filter.Commands, c)
+				}
+			}
+			if *since != "" {
+				t, err := parseSince(*since, time.Now())
+				if err != nil {
+					return errcode.Errorf(errcode.Usage, "invalid --since: %w", err)
+				}
+				filter.Since = t
+			}
+			if *limit < 0 {
+				return errcode.Errorf(errcode.Usage, "invalid -n: %d (expected 0 or more)", *limit)
+			}
+
+			path, err := history.Path()
+			if err != nil {
+				return err
+			}
+			entries, skipped, err := history.Read(path, filter)
+			if err != nil {
+				return err
+			}
+			if skipped > 0 {
+				fmt.Fprintf(os.Stderr, "⚠ Warning: skipped %d line(s) of %s that couldn't be read\n", skipped, path)
+			}
+			if *limit > 0 && len(entries) > *limit {
+				entries = entries[len(entries)-*limit:]
+			}
+			// Latest first
+			for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+				entries[i], entries[j] = entries[j], entries[i]
+			}
+
+			if jsonOutput {
+				if entries == nil {
+					entries = []history.Entry{}
+				}
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetEscapeHTML(false)
+				enc.SetIndent("", "  ")
+				return enc.Encode(entries)
+			}
+			if len(entries) == 0 {
+				fmt.Println("No commands in the history")
+				if !historyEnabled {
+					fmt.Println("Recording is off: the history setting is false")
+				}
+				return nil
+			}
+			printHistoryTable(entries)
+			return nil
+		},
+	}
+}
+
+// parseSince parses --since: an age before now (see parseAge), or a date
+// (YYYY-MM-DD, local time) or time (RFC 3339)
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	age, err := parseAge(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s (expected an age such as 12h, 7d or 2w, or a date such as 2026-01-31)", s)
+	}
+	return now.Add(-age), nil
+}
+
+// printHistoryTable prints one row per command, with the packages it acted
+// on; --json has their digests, arguments and errors
+func printHistoryTable(entries []history.Entry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tUSER\tCOMMAND\tSTATUS\tDURATION\tPACKAGES")
+	for _, e := range entries {
+		var refs []string
+		for _, r := range e.Refs {
+			refs = append(refs, r.Ref)
+		}
+		packages := "-"
+		switch {
+		case len(refs) > 3:
+			packages = strings.Join(refs[:3], ", ") + fmt.Sprintf(" and %d more", len(refs)-3)
+		case len(refs) > 0:
+			packages = strings.Join(refs, ", ")
+		}
+		user := e.User
+		if e.Host != "" {
+			user += "@" + e.Host
+		}
+		duration := time.Duration(e.Duration * float64(time.Second)).Round(time.Millisecond)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Time.Local().Format("2006-01-02 15:04:05"), user, e.Command, e.Status, duration, packages)
+	}
+	_ = w.Flush()
+}
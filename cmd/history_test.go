@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/history"
+)
+
+func TestRecordHistory(t *testing.T) {
+	setHome(t, t.TempDir())
+	t.Cleanup(func() { historyRefs = nil })
+
+	if recordsHistory("add", []string{"dep", "requests", ">=2"}) || recordsHistory("list", nil) {
+		t.Error("recordsHistory is true for a command that doesn't change packages")
+	}
+	if !recordsHistory("add", []string{"ghcr.io/team/utils:1.0.0"}) {
+		t.Error("recordsHistory is false for adding a package")
+	}
+
+	start := time.Now()
+	noteHistory("ghcr.io/team/utils:1.0.0", "sha256:abc")
+	recordHistory("push", []string{"ghcr.io/team/utils:1.0.0", "--from", "utils:1.0.0"}, start, nil)
+	historyRefs = nil
+	recordHistory("delete", []string{"ghcr.io/team/utils:0.9.0"}, start, errcode.Errorf(errcode.Auth, "unauthorized\nRun 'aigg login'"))
+
+	path, err := history.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, _, err := history.Read(path, history.Filter{})
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Read = %v, %v; want 2 entries", entries, err)
+	}
+	push, failed := entries[0], entries[1]
+	if push.Status != history.StatusOK || len(push.Refs) != 1 || push.Refs[0].Digest != "sha256:abc" || push.User == "" {
+		t.Errorf("push entry = %+v", push)
+	}
+	if failed.Status != string(errcode.Auth) || failed.ExitCode != 4 || failed.Error != "unauthorized" || len(failed.Refs) != 0 {
+		t.Errorf("failed delete entry = %+v", failed)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"12h", now.Add(-12 * time.Hour)},
+		{"7d", now.AddDate(0, 0, -7)},
+		{"2026-03-01", time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)},
+		{"2026-03-01T08:00:00Z", time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseSince(tt.in, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseSince(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := parseSince("yesterday", now); err == nil {
+		t.Error("parseSince(yesterday) succeeded")
+	}
+}
@@ -0,0 +1,385 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/config"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// gitHooks are the git hooks aigg hooks install writes
+var gitHooks = []string{"pre-commit", "pre-push"}
+
+// hookScript is a git hook aigg hooks install writes. It only calls aigg,
+// so the checks are upgraded with aigg.
+const hookScript = `#!/bin/sh
+# Installed by 'aigg hooks install': runs aigg's %[1]s checks of the
+# repository's aigogo packages. Skip them once with --no-verify.
+exec aigg hooks run %[1]s "$@"
+`
+
+// hookMarker is in the hooks aigg writes, and in pre-commit configurations
+// that already run them
+const hookMarker = "aigg hooks run"
+
+// preCommitConfigFile is the pre-commit framework's configuration
+const preCommitConfigFile = ".pre-commit-config.yaml"
+
+// preCommitHooks are aigg's hooks as a local repository of a pre-commit
+// framework configuration
+const preCommitHooks = `  - repo: local
+    hooks:
+      - id: aigg-pre-commit
+        name: aigg validate and diff
+        entry: aigg hooks run pre-commit
+        language: system
+        pass_filenames: false
+        stages: [pre-commit]
+      - id: aigg-pre-push
+        name: aigg lock check
+        entry: aigg hooks run pre-push
+        language: system
+        pass_filenames: false
+        always_run: true
+        stages: [pre-push]
+`
+
+func hooksCmd() *Command {
+	return &Command{
+		Name:        "hooks",
+		Description: "Install git hooks that check the repository's packages",
+		Run: func(args []string) error {
+			if len(args) == 0 {
+				return errcode.Errorf(errcode.Usage, "usage: aigg hooks <install|run> [args...]\n\nSubcommands:\n  install [--force] [--pre-commit-config]  Install pre-commit and pre-push hooks in the repository\n  run <pre-commit|pre-push>                Run a hook's checks, as the installed hooks do")
+			}
+
+			switch args[0] {
+			case "install":
+				return installHooks(args[1:])
+			case "run":
+				if len(args) != 2 {
+					return errcode.Errorf(errcode.Usage, "usage: aigg hooks run <pre-commit|pre-push>")
+				}
+				return runHook(args[1])
+			default:
+				return errcode.Errorf(errcode.Usage, "unknown subcommand '%s'\nValid subcommands: install, run", args[0])
+			}
+		},
+	}
+}
+
+// installHooks writes the pre-commit and pre-push hooks of the git
+// repository around the working directory, or with --pre-commit-config
+// adds them to the pre-commit framework's configuration instead
+func installHooks(args []string) error {
+	fs := flag.NewFlagSet("hooks install", flag.ContinueOnError)
+	force := fs.Bool("force", false, "Replace hooks that aigg didn't install")
+	preCommitConfig := fs.Bool("pre-commit-config", false, "Add the hooks to .pre-commit-config.yaml, for the pre-commit framework, instead")
+	if err := fs.Parse(args); err != nil {
+		return errcode.Wrap(errcode.Usage, err)
+	}
+	if fs.NArg() > 0 {
+		return errcode.Errorf(errcode.Usage, "unexpected argument: %s\nUsage: aigg hooks install [--force] [--pre-commit-config]", fs.Arg(0))
+	}
+
+	root, err := gitRoot()
+	if err != nil {
+		return err
+	}
+	if *preCommitConfig {
+		return writePreCommitConfig(root)
+	}
+
+	// core.hooksPath moves the hooks, and git-path resolves it
+	hooksDir, err := runGit(".", "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(hooksDir) {
+		if hooksDir, err = filepath.Abs(hooksDir); err != nil {
+			return err
+		}
+	}
+
+	// Check every hook before writing any, so none is half installed
+	for _, hook := range gitHooks {
+		data, err := os.ReadFile(filepath.Join(hooksDir, hook))
+		if err == nil && !strings.Contains(string(data), hookMarker) && !*force {
+			return fmt.Errorf("%s already has a %s hook that aigg didn't install: %s\nAdd '%s %s' to it, or use --force to replace it", root, hook, filepath.Join(hooksDir, hook), hookMarker, hook)
+		}
+	}
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+	for _, hook := range gitHooks {
+		hookPath := filepath.Join(hooksDir, hook)
+		if err := os.WriteFile(hookPath, []byte(fmt.Sprintf(hookScript, hook)), 0755); err != nil {
+			return fmt.Errorf("failed to write %s hook: %w", hook, err)
+		}
+		// WriteFile keeps the mode of a hook that was already there
+		if err := os.Chmod(hookPath, 0755); err != nil {
+			return fmt.Errorf("failed to make %s hook executable: %w", hook, err)
+		}
+		fmt.Printf("✓ Installed %s hook: %s\n", hook, hookPath)
+	}
+	logging.Println("\npre-commit validates the packages a commit changes, and fails if one differs from its version already pushed")
+	logging.Println("pre-push fails if an aigogo.lock is out of date")
+	logging.Println("Skip them once with 'git commit --no-verify' or 'git push --no-verify'")
+	return nil
+}
+
+// writePreCommitConfig adds aigg's hooks to the repository's
+// .pre-commit-config.yaml, creating it when there is none. One that
+// doesn't run them yet is left for the user to edit, as rewriting its YAML
+// would lose its comments.
+func writePreCommitConfig(root string) error {
+	configPath := filepath.Join(root, preCommitConfigFile)
+	data, err := os.ReadFile(configPath)
+	switch {
+	case err == nil && strings.Contains(string(data), hookMarker):
+		fmt.Printf("✓ %s already runs aigg's hooks\n", configPath)
+		return nil
+	case err == nil:
+		fmt.Printf("%s has other hooks; add aigg's to its repos:\n\n%s\n", configPath, preCommitHooks)
+		fmt.Println("Then run: pre-commit install --hook-type pre-commit --hook-type pre-push")
+		return nil
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("failed to read %s: %w", preCommitConfigFile, err)
+	}
+
+	content := "default_install_hook_types: [pre-commit, pre-push]\nrepos:\n" + preCommitHooks
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", preCommitConfigFile, err)
+	}
+	fmt.Printf("✓ Wrote %s\n", configPath)
+	logging.Println("Install its hooks with: pre-commit install")
+	return nil
+}
+
+// gitRoot returns the top directory of the git repository around the
+// working directory
+func gitRoot() (string, error) {
+	root, err := runGit(".", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", errcode.Errorf(errcode.Usage, "not in a git repository: %w", err)
+	}
+	return root, nil
+}
+
+// runHook runs the checks of a git hook
+func runHook(hook string) error {
+	root, err := gitRoot()
+	if err != nil {
+		return err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find aigg's executable: %w", err)
+	}
+	switch hook {
+	case "pre-commit":
+		return runPreCommit(root, self)
+	case "pre-push":
+		return runPrePush(root)
+	default:
+		return errcode.Errorf(errcode.Usage, "unknown hook '%s'\nValid hooks: pre-commit, pre-push", hook)
+	}
+}
+
+// runPreCommit checks the packages that the staged changes are in: each is
+// validated and, when its version is already pushed, built and compared
+// with it, so a release isn't changed without a new version. The files are
+// checked as they are in the working tree.
+func runPreCommit(root, self string) error {
+	manifests, err := gitFiles(root, "aigogo.json", "ls-files", "-z", "--", "*aigogo.json")
+	if err != nil {
+		return err
+	}
+	staged, err := gitFiles(root, "", "diff", "--cached", "--name-only", "-z")
+	if err != nil {
+		return err
+	}
+	packages := changedPackages(manifests, staged)
+	if len(packages) == 0 {
+		return nil
+	}
+
+	// Builds go to a cache of their own, leaving the user's builds alone
+	cache, err := os.MkdirTemp("", "aigogo-hook-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(cache) }()
+
+	var failures []error
+	for _, dir := range packages {
+		logging.Printf("aigg pre-commit: checking %s\n", dir)
+		if err := checkPackage(self, filepath.Join(root, filepath.FromSlash(dir)), cache); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %v\n", dir, err)
+			failures = append(failures, err)
+		}
+	}
+	if len(failures) > 0 {
+		return errcode.Errorf(errcode.Of(failures[0]), "%d of %d package(s) failed the pre-commit checks\nFix them and commit again, or skip the checks with 'git commit --no-verify'", len(failures), len(packages))
+	}
+	return nil
+}
+
+// checkPackage validates the package in dir and compares it with its
+// version in the registry, if that was pushed. Comparisons that can't be
+// made, such as offline, are warnings.
+func checkPackage(self, dir, cache string) error {
+	validate := exec.Command(self, "validate")
+	validate.Dir = dir
+	validate.Stdout, validate.Stderr = os.Stdout, os.Stderr
+	if err := validate.Run(); err != nil {
+		return errcode.Errorf(exitErrorCode(err), "validation failed")
+	}
+
+	m, err := manifest.Load(filepath.Join(dir, "aigogo.json"))
+	if err != nil || m.Name == "" || m.Version == "" {
+		return nil
+	}
+	registry := loadProjectSettings(config.FindProjectDir(dir)).Registry
+	if registry == "" {
+		logging.Verbosef("no registry setting for %s, not comparing it with a pushed version\n", m.Name)
+		return nil
+	}
+	local := m.Name + ":" + m.Version
+	pushed := aigogo.QualifyImageRef(local, registry)
+	env := append(os.Environ(), config.EnvVar("cache")+"="+cache)
+
+	build := exec.Command(self, "build", local, "--no-validate", "--ignore-scripts")
+	build.Dir, build.Env = dir, env
+	if out, err := build.CombinedOutput(); err != nil {
+		return errcode.Errorf(exitErrorCode(err), "failed to build %s:\n%s", local, strings.TrimSpace(string(out)))
+	}
+
+	diff := exec.Command(self, "diff", pushed, local, "--exit-code", "--summary")
+	diff.Dir, diff.Env = dir, env
+	out, err := diff.CombinedOutput()
+	switch code := exitErrorCode(err); {
+	case err == nil:
+		logging.Printf("✓ %s matches its pushed version\n", local)
+	case code == errcode.DiffFound:
+		fmt.Print(string(out))
+		return errcode.Errorf(errcode.DiffFound, "%s is already pushed as %s with other content\nBump its version with 'aigg version patch'", local, pushed)
+	case code == errcode.NotFound:
+		logging.Verbosef("%s isn't pushed yet\n", pushed)
+	default:
+		fmt.Fprintf(os.Stderr, "⚠ Warning: couldn't compare %s with %s: %s\n", local, pushed, lastLine(string(out)))
+	}
+	return nil
+}
+
+// runPrePush checks that each aigogo.lock in the repository is complete and
+// up to date, as aigg install --frozen does in CI, but without installing:
+// a hook mustn't fetch packages or change the work tree being pushed, so
+// packages that aren't in the package store are only checked for being
+// complete
+func runPrePush(root string) error {
+	locks, err := gitFiles(root, "aigogo.lock", "ls-files", "-z", "--", "*aigogo.lock")
+	if err != nil {
+		return err
+	}
+
+	var failures []error
+	for _, lock := range locks {
+		logging.Printf("aigg pre-push: checking %s\n", lock)
+		client := aigogo.NewClient(filepath.Join(root, filepath.FromSlash(path.Dir(lock))))
+		client.SetOutput(os.Stdout, os.Stderr)
+		client.SetLogger(progressLogger{})
+		client.SetSettingsLoader(loadProjectSettings)
+		unchecked, err := client.CheckFrozen()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s:\n%v\n", lock, err)
+			failures = append(failures, err)
+			continue
+		}
+		if len(unchecked) > 0 {
+			logging.Verbosef("  not in the package store, so only checked for being complete: %s\n", strings.Join(unchecked, ", "))
+		}
+	}
+	if len(failures) > 0 {
+		return errcode.Errorf(errcode.Of(failures[0]), "%d aigogo.lock file(s) are out of date or incomplete\nRun 'aigg add <package>' for the packages above and commit aigogo.lock, or skip the check with 'git push --no-verify'", len(failures))
+	}
+	return nil
+}
+
+// gitFiles returns the NUL-separated paths a git command lists in root,
+// keeping only those named base unless base is ""
+func gitFiles(root, base string, args ...string) ([]string, error) {
+	out, err := runGit(root, args...)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range strings.Split(out, "\x00") {
+		if file != "" && (base == "" || path.Base(file) == base) {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// changedPackages returns the directories of the manifests, relative to the
+// repository's root, that changed files are in. A file belongs to the
+// deepest package around it.
+func changedPackages(manifests, changed []string) []string {
+	dirs := make([]string, len(manifests))
+	for i, m := range manifests {
+		dirs[i] = path.Dir(m)
+	}
+	found := map[string]bool{}
+	for _, file := range changed {
+		owner := ""
+		for _, dir := range dirs {
+			inside := dir == "." || strings.HasPrefix(file, dir+"/")
+			if inside && (owner == "" || owner == "." || len(dir) > len(owner)) {
+				owner = dir
+			}
+		}
+		if owner != "" {
+			found[owner] = true
+		}
+	}
+	var packages []string
+	for dir := range found {
+		packages = append(packages, dir)
+	}
+	sort.Strings(packages)
+	return packages
+}
+
+// exitErrorCode returns the code of the error an aigg subprocess exited
+// with
+func exitErrorCode(err error) errcode.Code {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return errcode.Generic
+	}
+	for _, code := range errcode.Codes() {
+		if code.ExitCode() == exitErr.ExitCode() {
+			return code
+		}
+	}
+	return errcode.Generic
+}
+
+// lastLine returns the last line of an aigg subprocess's output that
+// isn't blank, without the "Error: " it prints an error with
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimPrefix(lines[len(lines)-1], "Error: ")
+}
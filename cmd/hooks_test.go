@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/store"
+)
+
+func TestChangedPackages(t *testing.T) {
+	manifests := []string{"aigogo.json", "packages/utils/aigogo.json", "packages/utils/vendor/aigogo.json", "p/aigogo.json"}
+	tests := []struct {
+		changed []string
+		want    []string
+	}{
+		{[]string{"README.md"}, []string{"."}},
+		{[]string{"packages/utils/utils.py"}, []string{"packages/utils"}},
+		{[]string{"packages/utils/vendor/x.py", "p/a.py"}, []string{"p", "packages/utils/vendor"}},
+		// packages/utilsx isn't inside packages/utils
+		{[]string{"packages/utilsx/a.py", "packages/utils/aigogo.json"}, []string{".", "packages/utils"}},
+	}
+	for _, tt := range tests {
+		if got := changedPackages(manifests, tt.changed); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("changedPackages(%v) = %v, want %v", tt.changed, got, tt.want)
+		}
+	}
+
+	if got := changedPackages([]string{"lib/aigogo.json"}, []string{"app/main.py"}); got != nil {
+		t.Errorf("changedPackages outside any package = %v, want none", got)
+	}
+}
+
+func TestInstallHooks(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if _, err := runGit(dir, "init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+	hooksDir := filepath.Join(dir, ".git", "hooks")
+
+	if err := installHooks(nil); err != nil {
+		t.Fatalf("installHooks failed: %v", err)
+	}
+	for _, hook := range gitHooks {
+		info, err := os.Stat(filepath.Join(hooksDir, hook))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(filepath.Join(hooksDir, hook))
+		if !strings.Contains(string(data), "exec aigg hooks run "+hook) {
+			t.Errorf("%s hook = %q", hook, data)
+		}
+		if info.Mode().Perm()&0100 == 0 && runtime.GOOS != "windows" {
+			t.Errorf("%s hook isn't executable: %v", hook, info.Mode())
+		}
+	}
+	// aigg's own hooks are replaced without --force
+	if err := installHooks(nil); err != nil {
+		t.Errorf("reinstalling failed: %v", err)
+	}
+
+	// Another hook is kept, and neither is written
+	custom := "#!/bin/sh\nmake lint\n"
+	if err := os.WriteFile(filepath.Join(hooksDir, "pre-push"), []byte(custom), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(hooksDir, "pre-commit")); err != nil {
+		t.Fatal(err)
+	}
+	if err := installHooks(nil); err == nil || !strings.Contains(err.Error(), "didn't install") {
+		t.Errorf("expected a foreign hook error, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(hooksDir, "pre-push")); string(data) != custom {
+		t.Errorf("pre-push hook = %q, want it kept", data)
+	}
+	if _, err := os.Stat(filepath.Join(hooksDir, "pre-commit")); !os.IsNotExist(err) {
+		t.Errorf("pre-commit hook was written: %v", err)
+	}
+	if err := installHooks([]string{"--force"}); err != nil {
+		t.Errorf("installHooks --force failed: %v", err)
+	}
+
+	// The pre-commit framework's configuration is written once
+	if err := installHooks([]string{"--pre-commit-config"}); err != nil {
+		t.Fatalf("installHooks --pre-commit-config failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, preCommitConfigFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "entry: aigg hooks run pre-push") || !strings.HasPrefix(string(data), "default_install_hook_types:") {
+		t.Errorf("%s = %q", preCommitConfigFile, data)
+	}
+	if err := installHooks([]string{"--pre-commit-config"}); err != nil {
+		t.Errorf("installHooks --pre-commit-config again failed: %v", err)
+	}
+	if again, _ := os.ReadFile(filepath.Join(dir, preCommitConfigFile)); string(again) != string(data) {
+		t.Errorf("%s was rewritten: %q", preCommitConfigFile, again)
+	}
+}
+
+func TestRunPrePush(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	setHome(t, t.TempDir())
+	t.Setenv("AIGOGO_STORE", "")
+
+	src := t.TempDir()
+	manifestData := []byte(`{"name": "utils", "version": "1.0.0", "language": {"name": "python"}}`)
+	if err := os.WriteFile(filepath.Join(src, "utils.py"), []byte("def util(): pass\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cas, err := store.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := cas.Store(context.Background(), src, []string{"utils.py"}, manifestData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if _, err := runGit(dir, "init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+	lockPath := filepath.Join(dir, "app", lockfile.LockFileName)
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	lock := lockfile.New()
+	lock.Add("utils", lockfile.LockedPackage{Version: "1.0.0", Integrity: "sha256:" + hash, Source: "docker.io/org/utils:1.0.0", Language: "python", Files: []string{"utils.py"}})
+	// Not in the store: checked for being complete, not fetched
+	lock.Add("other", lockfile.LockedPackage{Version: "2.0.0", Integrity: "sha256:" + strings.Repeat("0", 64), Source: "docker.io/org/other:2.0.0", Language: "python", Files: []string{"other.py"}})
+	if err := lockfile.Save(lockPath, lock); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(dir, "add", "-A"); err != nil {
+		t.Fatal(err)
+	}
+
+	// snapshot lists the work tree's files and their contents
+	snapshot := func() map[string]string {
+		t.Helper()
+		files := map[string]string{}
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			rel, _ := filepath.Rel(dir, path)
+			if d.IsDir() {
+				files[rel] = "dir"
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			files[rel] = string(data)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return files
+	}
+	before := snapshot()
+	if err := runPrePush(dir); err != nil {
+		t.Errorf("runPrePush of an up-to-date aigogo.lock = %v", err)
+	}
+	if after := snapshot(); !reflect.DeepEqual(after, before) {
+		t.Errorf("runPrePush changed the work tree:\nbefore %v\nafter  %v", before, after)
+	}
+
+	pkg := lock.Packages["utils"]
+	pkg.Version = "0.9.0"
+	lock.Add("utils", pkg)
+	if err := lockfile.Save(lockPath, lock); err != nil {
+		t.Fatal(err)
+	}
+	before = snapshot()
+	if err := runPrePush(dir); err == nil || !strings.Contains(err.Error(), "out of date") {
+		t.Errorf("runPrePush of a stale aigogo.lock = %v, want an error", err)
+	}
+	if after := snapshot(); !reflect.DeepEqual(after, before) {
+		t.Errorf("runPrePush changed the work tree:\nbefore %v\nafter  %v", before, after)
+	}
+}
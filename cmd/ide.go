@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/imports"
+)
+
+func ideCmd() *Command {
+	return &Command{
+		Name:        "ide",
+		Description: "Configure editors to resolve installed packages",
+		Run: func(args []string) error {
+			if len(args) == 0 {
+				return errcode.Errorf(errcode.Usage, "usage: aigg ide <setup> [args...]\n\nSubcommands:\n  setup [--vscode] [--pycharm]  Point VS Code and PyCharm at .aigogo/imports/")
+			}
+
+			switch args[0] {
+			case "setup":
+				return ideSetup(args[1:])
+			default:
+				return errcode.Errorf(errcode.Usage, "unknown subcommand '%s'\nValid subcommands: setup", args[0])
+			}
+		},
+	}
+}
+
+// ideSetup writes the editor settings that make VS Code and PyCharm resolve
+// aigogo imports. Without flags it configures the editors the project has
+// settings for (.vscode/, .idea/), or both when it has neither.
+func ideSetup(args []string) error {
+	fs := flag.NewFlagSet("ide setup", flag.ContinueOnError)
+	vscode := fs.Bool("vscode", false, "Update .vscode/settings.json")
+	pycharm := fs.Bool("pycharm", false, "Mark .aigogo/imports as a sources root of the PyCharm module")
+	if err := fs.Parse(args); err != nil {
+		return errcode.Wrap(errcode.Usage, err)
+	}
+	if fs.NArg() > 0 {
+		return errcode.Errorf(errcode.Usage, "unexpected argument: %s\nUsage: aigg ide setup [--vscode] [--pycharm]", fs.Arg(0))
+	}
+
+	projectDir, err := findProjectDir()
+	if err != nil {
+		return fmt.Errorf("not an aigogo project (no .aigogo/ directory or aigogo.lock found)\nRun 'aigg add <package>' and 'aigg install' first")
+	}
+	if !*vscode && !*pycharm {
+		*vscode = dirExists(filepath.Join(projectDir, ".vscode"))
+		*pycharm = dirExists(filepath.Join(projectDir, ".idea"))
+		if !*vscode && !*pycharm {
+			*vscode, *pycharm = true, true
+		}
+	}
+
+	setupMgr, err := imports.NewSetupManager(projectDir)
+	if err != nil {
+		return fmt.Errorf("failed to create setup manager: %w", err)
+	}
+	settings := loadProjectSettings(projectDir)
+	setupMgr.SetNamespace(imports.NamespaceFor(settings.Namespace))
+	ns := setupMgr.GetNamespace()
+
+	if *vscode {
+		changed, commented, err := setupMgr.WriteVSCodeSettings()
+		if err != nil {
+			return err
+		}
+		if changed {
+			fmt.Printf("✓ Added %s/imports to python.analysis.extraPaths in %s\n", imports.ImportsDir, imports.VSCodeSettingsFile)
+			if commented {
+				fmt.Printf("Note: %s was rewritten as plain JSON; its comments and trailing commas were removed\n", imports.VSCodeSettingsFile)
+			}
+		} else {
+			fmt.Printf("✓ %s already lists %s/imports\n", imports.VSCodeSettingsFile, imports.ImportsDir)
+		}
+	}
+
+	if *pycharm {
+		module, changed, err := setupMgr.WritePyCharmModule()
+		if err != nil {
+			return err
+		}
+		if changed {
+			fmt.Printf("✓ Marked %s/imports as a sources root in %s\n", imports.ImportsDir, module)
+		} else {
+			fmt.Printf("✓ %s already has %s/imports as a sources root\n", module, imports.ImportsDir)
+		}
+		absImports, _ := filepath.Abs(setupMgr.GetImportsDir())
+		fmt.Printf("  Reopen the project if PyCharm has it open. To use the packages from other\n")
+		fmt.Printf("  projects too, add %s to the interpreter paths instead\n", absImports)
+		fmt.Printf("  (Python Interpreter → Show All → Show Interpreter Paths).\n")
+	}
+
+	// TypeScript path mappings come from tsconfig, which both editors read
+	if dirExists(setupMgr.GetJavaScriptScopePath()) {
+		if err := setupMgr.WriteTSConfig(); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Wrote %s mapping %s/* imports\n", imports.TSConfigFileName, ns.JavaScript)
+		fmt.Printf("  Add \"extends\": \"./%s\" to tsconfig.json (or jsconfig.json) if it isn't there yet\n", imports.TSConfigFileName)
+	}
+	return nil
+}
+
+// dirExists reports whether path is a directory
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
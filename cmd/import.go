@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func importCmd() *Command {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	tag := flags.String("tag", "", "Cache the package as this name:version instead of its aigogo.json's")
+	force := flags.Bool("force", false, "Replace a cached package of the same name and version")
+	maxExtractSize := maxExtractSizeFlag(flags)
+
+	return &Command{
+		Name:        "import",
+		Description: "Import a package bundle, directory or git repository into the cache and store",
+		Flags:       flags,
+		Run: func(args []string) error {
+			if len(args) != 1 {
+				return errcode.Errorf(errcode.Usage, "usage: aigg import <bundle.tar.gz|oci-layout-dir[:tag]|package-dir|git-url[#ref]> [--tag name:version] [--force] [--max-extract-size <size>]")
+			}
+			if err := setMaxExtractSize(*maxExtractSize); err != nil {
+				return err
+			}
+			return importPackage(args[0], *tag, *force)
+		},
+	}
+}
+
+// importPackage caches the package source points to, as aigg build would
+// have, and stores it in the package store, so aigg add finds it without a
+// registry
+func importPackage(source, tag string, force bool) error {
+	builder := docker.NewLocalBuilder()
+	var imageRef string
+
+	switch {
+	case isGitURL(source):
+		dir, err := cloneGitSource(source)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = os.RemoveAll(dir) }()
+		if imageRef, err = importPackageDir(builder, dir, tag, force); err != nil {
+			return err
+		}
+	default:
+		layerSource, layer, err := readBundle(source)
+		if err != nil {
+			return err
+		}
+		if layer == nil {
+			if imageRef, err = importPackageDir(builder, source, tag, force); err != nil {
+				return err
+			}
+			break
+		}
+		if imageRef, err = importLayer(builder, layerSource, layer, tag, force); err != nil {
+			return err
+		}
+	}
+
+	return storeImported(imageRef, builder.ImagePath(imageRef))
+}
+
+// readBundle reads the layer of a tarball written by aigg export --format
+// tar, or of an OCI image layout, given as <dir> or <dir>:<tag>. It returns
+// a nil layer for a package directory, which is built instead.
+func readBundle(source string) (string, []byte, error) {
+	dir, tag := source, ""
+	if _, err := os.Stat(source); err != nil {
+		if i := strings.LastIndex(source, ":"); i > 0 {
+			dir, tag = source[:i], source[i+1:]
+		}
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", nil, errcode.Errorf(errcode.NotFound, "%s not found: %w", source, err)
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if !info.IsDir() {
+		data, err := os.ReadFile(dir)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read %s: %w", source, err)
+		}
+		layer, err := docker.Gunzip(data)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to decompress %s: %w", source, err)
+		}
+		return abs, layer, nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, docker.OCILayoutFile)); err == nil {
+		layer, _, err := docker.ReadOCILayout(dir, tag)
+		if err != nil {
+			return "", nil, errcode.Errorf(errcode.Validation, "failed to read %s: %w", source, err)
+		}
+		return abs, layer, nil
+	}
+	if tag != "" {
+		return "", nil, errcode.Errorf(errcode.NotFound, "%s not found", source)
+	}
+	return abs, nil, nil
+}
+
+// importLayer caches the files of a bundle's layer, named tag or the
+// name:version of the aigogo.json it holds
+func importLayer(builder *docker.LocalBuilder, source string, layer []byte, tag string, force bool) (string, error) {
+	var m *manifest.Manifest
+	if data, ok := docker.ReadFileFromTar(layer, "aigogo.json"); ok {
+		m = &manifest.Manifest{}
+		if err := json.Unmarshal(data, m); err != nil {
+			return "", errcode.Errorf(errcode.Validation, "failed to parse the bundle's aigogo.json: %w", err)
+		}
+		if err := m.CheckAigogoVersion(); err != nil {
+			return "", err
+		}
+	}
+	imageRef, err := importRef(tag, m)
+	if err != nil {
+		return "", err
+	}
+
+	logging.Printf("Importing %s from %s\n", imageRef, source)
+	if err := builder.BuildFromLayer(imageRef, layer, source, m, force); err != nil {
+		return "", fmt.Errorf("failed to import %s: %w", source, err)
+	}
+	return imageRef, nil
+}
+
+// importPackageDir builds the package in dir to the cache, as aigg build
+// does but without running its lifecycle scripts, which an imported
+// package isn't trusted with
+func importPackageDir(builder *docker.LocalBuilder, dir, tag string, force bool) (string, error) {
+	m, err := manifest.Load(filepath.Join(dir, "aigogo.json"))
+	if err != nil {
+		return "", errcode.Errorf(errcode.NotFound, "%s is not a package bundle or a package directory: %w", dir, err)
+	}
+	if err := manifest.Validate(m); err != nil {
+		return "", errcode.Errorf(errcode.Validation, "invalid aigogo.json: %w", err)
+	}
+	imageRef, err := importRef(tag, m)
+	if err != nil {
+		return "", err
+	}
+
+	logging.Printf("Building %s from %s\n", imageRef, dir)
+	if _, err := builder.BuildFromDir(dir, imageRef, m, force); err != nil {
+		return "", fmt.Errorf("failed to build %s: %w", dir, err)
+	}
+	return imageRef, nil
+}
+
+// importRef is the name:version an imported package is cached as
+func importRef(tag string, m *manifest.Manifest) (string, error) {
+	if tag != "" {
+		if !strings.Contains(tag, ":") || strings.Contains(tag, "/") {
+			return "", errcode.Errorf(errcode.Usage, "invalid --tag %q (expected name:version)", tag)
+		}
+		return tag, nil
+	}
+	if m == nil || m.Name == "" || m.Version == "" {
+		return "", errcode.Errorf(errcode.Validation, "the package has no aigogo.json naming it\nName it with --tag name:version")
+	}
+	return m.Name + ":" + m.Version, nil
+}
+
+// storeImported stores the cached package imageRef, in dir, in the package
+// store, as aigg add would
+func storeImported(imageRef, dir string) error {
+	files, err := docker.ListDirFiles(dir)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", imageRef, err)
+	}
+	relFiles := make([]string, len(files))
+	for i, f := range files {
+		relFiles[i] = f.Path
+	}
+
+	var m *manifest.Manifest
+	manifestData, err := os.ReadFile(filepath.Join(dir, "aigogo.json"))
+	if err == nil {
+		m = &manifest.Manifest{}
+		if err := json.Unmarshal(manifestData, m); err != nil {
+			m = nil
+		}
+	}
+	name, version, language := aigogo.PackageIdentity(imageRef, m)
+	if m == nil {
+		manifestData, _ = json.MarshalIndent(map[string]interface{}{
+			"name":     name,
+			"version":  version,
+			"language": map[string]string{"name": language},
+		}, "", "  ")
+	}
+
+	projectDir := "."
+	if lockPath, _, err := lockfile.FindLockFile(); err == nil {
+		projectDir = filepath.Dir(lockPath)
+	}
+	cas, err := aigogo.OpenStore(loadProjectSettings(projectDir))
+	if err != nil {
+		return fmt.Errorf("failed to initialize store: %w", err)
+	}
+	hash, err := cas.Store(commandContext(), dir, relFiles, manifestData)
+	if err != nil {
+		return fmt.Errorf("failed to store package: %w", err)
+	}
+	if err := cas.MakeReadOnly(hash); err != nil {
+		fmt.Printf("⚠ Warning: failed to make files read-only: %v\n", err)
+	}
+	if m != nil {
+		if err := aigogo.ApplyExecutableBits(cas, hash, m); err != nil {
+			fmt.Printf("⚠ Warning: failed to set executable files: %v\n", err)
+		}
+	}
+
+	fmt.Printf("✓ Imported %s@%s as %s\n", name, version, imageRef)
+	fmt.Printf("  Hash: sha256:%s\n", hash[:16]+"...")
+	fmt.Printf("  Files: %d\n", len(relFiles))
+	fmt.Printf("  Add it to a project with: aigg add %s\n", imageRef)
+	return nil
+}
+
+// isGitURL reports whether source is a git repository URL rather than a
+// local path
+func isGitURL(source string) bool {
+	for _, prefix := range []string{"https://", "http://", "ssh://", "git://", "file://", "git@"} {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	repo, _, _ := strings.Cut(source, "#")
+	return strings.HasSuffix(repo, ".git") && !isDir(repo)
+}
+
+// isDir reports whether path is an existing directory
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// cloneGitSource makes a shallow clone of a git URL, optionally followed by
+// #<branch or tag>, into a temporary directory the caller removes
+func cloneGitSource(source string) (string, error) {
+	repo, ref, _ := strings.Cut(source, "#")
+	dir, err := os.MkdirTemp("", "aigogo-import-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	args := []string{"-c", "advice.detachedHead=false", "clone", "--quiet", "--depth", "1"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	args = append(args, "--", repo, dir)
+	logging.Printf("Cloning %s...\n", source)
+	git := exec.Command("git", args...)
+	git.Stdout, git.Stderr = os.Stderr, os.Stderr
+	if err := git.Run(); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", errcode.Errorf(errcode.Network, "failed to clone %s: %w", source, err)
+	}
+	return dir, nil
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/store"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+	t.Setenv("AIGOGO_STORE", "")
+	t.Chdir(t.TempDir())
+
+	src := t.TempDir()
+	for name, content := range map[string]string{
+		"aigogo.json": `{"name": "utils", "version": "1.2.0", "language": {"name": "python", "version": ">=3.8"},
+			"files": {"include": ["utils.py", "bin/run.sh"], "attributes": [{"path": "bin/run.sh", "executable": true}]}}`,
+		"utils.py":   "def util(): pass\n",
+		"bin/run.sh": "#!/bin/sh\n",
+	} {
+		path := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := importPackage(src, "", false); err != nil {
+		t.Fatalf("importPackage(dir) error = %v", err)
+	}
+	if docker.GetCachePath("utils:1.2.0") == "" {
+		t.Fatal("utils:1.2.0 not cached after import")
+	}
+
+	for _, format := range []string{"tar", "oci"} {
+		t.Run(format, func(t *testing.T) {
+			bundle := filepath.Join(t.TempDir(), "bundle")
+			if err := exportPackage("utils:1.2.0", bundle, format, false); err != nil {
+				t.Fatalf("exportPackage() error = %v", err)
+			}
+			if err := exportPackage("utils:1.2.0", bundle, format, false); err == nil {
+				t.Error("exportPackage() overwrote its output without --force")
+			}
+
+			tag := "utils-" + format + ":1.2.0"
+			if err := importPackage(bundle, tag, false); err != nil {
+				t.Fatalf("importPackage(%s bundle) error = %v", format, err)
+			}
+			// The same files again keep the cached build
+			if err := importPackage(bundle, tag, false); err != nil {
+				t.Errorf("importPackage(%s bundle) again error = %v", format, err)
+			}
+			dir := docker.GetCachePath(tag)
+			files, err := docker.ListDirFiles(dir)
+			if err != nil || len(files) != 3 {
+				t.Fatalf("imported files = %v, %v, want 3", files, err)
+			}
+			info, err := os.Stat(filepath.Join(dir, "bin", "run.sh"))
+			if err != nil || info.Mode()&0111 == 0 {
+				t.Errorf("bin/run.sh = %v, %v, want it executable", info, err)
+			}
+		})
+	}
+
+	// The import is stored under the hash aigg add locks for it
+	pkg, err := aigogo.NewClient(".").OpenPackage(context.Background(), "utils-tar:1.2.0", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := pkg.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestData, err := pkg.ReadFile("aigogo.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := pkg.Digest(files, manifestData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cas, err := store.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cas.Has(digest) {
+		t.Errorf("%s not in the package store", digest)
+	}
+
+	// Other files under a cached name need force
+	if err := os.WriteFile(filepath.Join(src, "utils.py"), []byte("def util(): return 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := importPackage(src, "", true); err != nil {
+		t.Fatal(err)
+	}
+	changed := filepath.Join(t.TempDir(), "changed.tar.gz")
+	if err := exportPackage("utils:1.2.0", changed, "tar", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := importPackage(changed, "utils-tar:1.2.0", false); err == nil || !strings.Contains(err.Error(), "different content") {
+		t.Errorf("importPackage() of other files without --force = %v, want a different content error", err)
+	}
+	if err := importPackage(changed, "utils-tar:1.2.0", true); err != nil {
+		t.Errorf("importPackage() of other files with --force error = %v", err)
+	}
+}
+
+func TestBuildReusesUnchangedContent(t *testing.T) {
+	setHome(t, t.TempDir())
+	t.Setenv("AIGOGO_STORE", "")
+
+	src := t.TempDir()
+	for name, content := range map[string]string{
+		"aigogo.json": `{"name": "utils", "version": "1.2.0", "language": {"name": "python", "version": ">=3.8"},
+			"files": {"include": ["utils.py"]}}`,
+		"utils.py": "def util(): pass\n",
+	} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err := manifest.Load(filepath.Join(src, "aigogo.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builder := docker.NewLocalBuilder()
+	first, err := builder.BuildFromDir(src, "utils:1.2.0", m, false)
+	if err != nil {
+		t.Fatalf("BuildFromDir() error = %v", err)
+	}
+	if first.Reused {
+		t.Error("first build reused a build that didn't exist")
+	}
+	again, err := builder.BuildFromDir(src, "utils:1.2.0", m, false)
+	if err != nil {
+		t.Fatalf("BuildFromDir() of unchanged content error = %v", err)
+	}
+	if !again.Reused || again.ContentHash != first.ContentHash {
+		t.Errorf("second build = %+v, want the first (%s) reused", again, first.ContentHash)
+	}
+
+	// The recorded hash is the integrity aigg add locks for the build
+	pkg, err := aigogo.NewClient(".").OpenPackage(context.Background(), "utils:1.2.0", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := pkg.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestData, err := pkg.ReadFile("aigogo.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest, err := pkg.Digest(files, manifestData); err != nil || digest != first.ContentHash {
+		t.Errorf("Digest() = %s, %v, want %s", digest, err, first.ContentHash)
+	}
+	metadata, err := os.ReadFile(filepath.Join(first.Path, ".aigogo-metadata.json"))
+	if err != nil || !strings.Contains(string(metadata), first.ContentHash) {
+		t.Errorf(".aigogo-metadata.json = %s, %v, want content_hash %s", metadata, err, first.ContentHash)
+	}
+
+	if err := os.WriteFile(filepath.Join(src, "utils.py"), []byte("def util(): return 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := builder.BuildFromDir(src, "utils:1.2.0", m, false); err == nil {
+		t.Error("BuildFromDir() replaced a build with other content without force")
+	}
+	rebuilt, err := builder.BuildFromDir(src, "utils:1.2.0", m, true)
+	if err != nil || rebuilt.Reused || rebuilt.ContentHash == first.ContentHash {
+		t.Errorf("forced rebuild = %+v, %v, want new content", rebuilt, err)
+	}
+}
+
+func TestImportRejectsEscapingLayer(t *testing.T) {
+	setHome(t, t.TempDir())
+	t.Setenv("AIGOGO_STORE", "")
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	content := "pwned\n"
+	if err := tw.WriteHeader(&tar.Header{Name: "../evil.py", Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	bundle := filepath.Join(t.TempDir(), "evil.tar")
+	if err := os.WriteFile(bundle, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := importPackage(bundle, "evil:1.0.0", false)
+	if err == nil || !strings.Contains(err.Error(), "outside the package") {
+		t.Fatalf("importPackage() error = %v, want it refused", err)
+	}
+	if docker.GetCachePath("evil:1.0.0") != "" {
+		t.Error("refused bundle left a cache entry")
+	}
+}
+
+func TestIsGitURL(t *testing.T) {
+	for source, want := range map[string]bool{
+		"https://github.com/org/pkg.git": true,
+		"git@github.com:org/pkg.git#v1":  true,
+		"file:///srv/pkg":                true,
+		"pkg-1.0.0.tar.gz":               false,
+		"./pkg":                          false,
+	} {
+		if got := isGitURL(source); got != want {
+			t.Errorf("isGitURL(%q) = %v, want %v", source, got, want)
+		}
+	}
+}
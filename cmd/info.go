@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/markdown"
+	"golang.org/x/term"
+)
+
+// defaultReadme is read when a package's manifest has no readme field
+const defaultReadme = "README.md"
+
+// maxReadmeWidth caps the wrap width on wide terminals, for readability
+const maxReadmeWidth = 100
+
+func infoCmd() *Command {
+	flags := flag.NewFlagSet("info", flag.ContinueOnError)
+	readme := flags.Bool("readme", false, "Show the package's README")
+	remote := flags.Bool("remote", false, "Read the package from its registry even when it's cached, without caching it")
+
+	return &Command{
+		Name:        "info",
+		Description: "Show a package's metadata, files and dependencies, or its README",
+		Flags:       flags,
+		Run: func(args []string) error {
+			if len(args) != 1 {
+				return errcode.Errorf(errcode.Usage, "usage: aigg info <name:tag|registry/name:tag|locked name|sha256:hash> [--readme] [--remote]")
+			}
+
+			client, err := newClient()
+			if err != nil {
+				return err
+			}
+			client.SetLogger(stderrLogger{})
+			pkg, err := client.OpenPackage(commandContext(), args[0], *remote)
+			if err != nil {
+				return err
+			}
+			data, err := pkg.ReadFile("aigogo.json")
+			if err != nil {
+				return fmt.Errorf("failed to read aigogo.json of %s: %w", args[0], err)
+			}
+			var m manifest.Manifest
+			if err := json.Unmarshal(data, &m); err != nil {
+				return fmt.Errorf("failed to parse aigogo.json of %s: %w", args[0], err)
+			}
+
+			if *readme {
+				return printReadme(pkg, &m)
+			}
+			return printInfo(pkg, &m, data)
+		},
+	}
+}
+
+// readRegistryManifest returns the aigogo.json of a registry package that a
+// manifest extends. The package is pulled into the cache the first time, so
+// later loads don't hit the registry; aigg pull refreshes it.
+func readRegistryManifest(ref string) ([]byte, error) {
+	if docker.GetCachePath(ref) == "" && !docker.IsLocalReference(ref) {
+		fmt.Fprintf(os.Stderr, "Pulling %s for extends...\n", ref)
+		if err := docker.NewPuller().Pull(commandContext(), ref); err != nil {
+			return nil, err
+		}
+	}
+	return docker.ReadCachedFile(ref, "aigogo.json")
+}
+
+// printReadme renders the package's README for the terminal, or prints
+// plain text when stdout isn't one
+func printReadme(pkg *aigogo.Package, m *manifest.Manifest) error {
+	file := m.Readme
+	if file == "" {
+		file = defaultReadme
+	}
+	data, err := pkg.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		if m.Readme == "" {
+			return fmt.Errorf("%s has no README\nPackage authors can add one with \"readme\": \"README.md\" in aigogo.json", pkg.Ref)
+		}
+		return fmt.Errorf("%s declares readme %s but the package doesn't contain it", pkg.Ref, m.Readme)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	opts := markdown.Options{}
+	fd := int(stdoutFile.Fd())
+	opts.Styled = useColor(fd)
+	if term.IsTerminal(fd) {
+		if width, _, err := term.GetSize(fd); err == nil {
+			opts.Width = min(width, maxReadmeWidth)
+		}
+	}
+	fmt.Print(markdown.Render(string(data), opts))
+	return nil
+}
+
+// printInfo prints the package's manifest metadata, files, digest and
+// dependencies, and how to install it
+func printInfo(pkg *aigogo.Package, m *manifest.Manifest, manifestData []byte) error {
+	files, err := pkg.Files()
+	if err != nil {
+		return fmt.Errorf("failed to list the files of %s: %w", pkg.Ref, err)
+	}
+	digest, err := pkg.Digest(files, manifestData)
+	if err != nil {
+		return fmt.Errorf("failed to compute the digest of %s: %w", pkg.Ref, err)
+	}
+
+	fmt.Printf("%s@%s\n", m.Name, m.Version)
+	if m.Description != "" {
+		fmt.Printf("  %s\n", m.Description)
+	}
+	if d := aigogo.PackageDeprecation(commandContext(), pkg.Ref, m); d != nil {
+		fmt.Printf("  ⚠️  DEPRECATED: %s\n", d.Message)
+		if d.Replacement != "" {
+			fmt.Printf("  Use instead: %s\n", d.Replacement)
+		}
+	}
+	fmt.Println()
+	fmt.Printf("   Source: %s (%s)\n", pkg.Ref, pkg.Source)
+	fmt.Printf("   Digest: %s\n", digest)
+	if m.Author != "" {
+		fmt.Printf("   Author: %s\n", m.Author)
+	}
+	if m.Language.Name != "" {
+		fmt.Printf("   %s\n", describeLanguages(m))
+	}
+	printMetadata(m.Metadata, "   ")
+	if m.Readme != "" {
+		fmt.Printf("   Readme: %s (aigg info %s --readme)\n", m.Readme, pkg.Ref)
+	}
+
+	var total int64
+	pathWidth, sizeWidth := 0, 0
+	for _, f := range files {
+		total += f.Size
+		pathWidth = max(pathWidth, len(f.Path))
+		sizeWidth = max(sizeWidth, len(formatSize(f.Size)))
+	}
+	fmt.Printf("\n   Files (%d, %s):\n", len(files), formatSize(total))
+	for _, f := range files {
+		fmt.Printf("     %-*s  %*s\n", pathWidth, f.Path, sizeWidth, formatSize(f.Size))
+	}
+
+	if m.Dependencies != nil {
+		groups := []struct {
+			label string
+			deps  []manifest.Dependency
+		}{
+			{"", m.Dependencies.Runtime},
+			{" (dev)", m.Dependencies.Dev},
+			{" (peer)", m.Dependencies.Peer},
+		}
+		printed := false
+		for _, g := range groups {
+			for _, dep := range g.deps {
+				if !printed {
+					fmt.Println("\n   Dependencies:")
+					printed = true
+				}
+				fmt.Printf("     %s %s%s%s\n", dep.Package, dep.Version, g.label, languageSuffix(m, dep))
+			}
+		}
+	}
+
+	switch {
+	case pkg.Locked:
+		fmt.Println("\n   Install: aigg install (it's in aigogo.lock)")
+	case !pkg.Stored():
+		fmt.Printf("\n   Install: aigg add %s && aigg install\n", pkg.Ref)
+	}
+	return nil
+}
@@ -1,61 +1,289 @@
 package cmd
 
 import (
+	"flag"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/aupeachmo/aigogo/pkg/depimport"
 	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/scaffold"
+	"golang.org/x/term"
 )
 
+// defaultLanguageVersions are used when the project's own manifest doesn't
+// constrain the language version, which aigogo.json needs for dependencies
+var defaultLanguageVersions = map[string]string{
+	"python":     ">=3.8,<4.0",
+	"javascript": ">=18",
+	"rust":       "1.70",
+	"go":         "1.21",
+}
+
 func initCmd() *Command {
+	flags := flag.NewFlagSet("init", flag.ContinueOnError)
+	noDetect := flags.Bool("no-detect", false, "Don't pre-fill aigogo.json from pyproject.toml, package.json, Cargo.toml or go.mod")
+	importDeps := flags.Bool("import-deps", false, "Import the detected project's dependencies without asking")
+	templateName := flags.String("template", "", "Lay out the package from a template: python-lib, ts-lib, prompt-pack, notebook, or one in the user templates directory (templates/ next to the user's config.toml)")
+	yes := flags.Bool("yes", false, "Don't prompt; write aigogo.json from the detected or default values")
+	flags.BoolVar(yes, "y", false, "Shorthand for --yes")
+
 	return &Command{
 		Name:        "init",
 		Description: "Initialize a new agent in the current directory",
+		Flags:       flags,
 		Run: func(args []string) error {
-			manifestPath := "aigogo.json"
-
-			// Create default manifest
-			m := &manifest.Manifest{
-				Schema:      "https://github.com/aupeachmo/aigogo/blob/master/aigogo.schema.json",
-				Name:        getCurrentDirName(),
-				Version:     "0.1.0",
-				Description: "An AI agent",
-				Author:      "",
-				Language: manifest.Language{
-					Name:    "python",
-					Version: ">=3.8,<4.0",
-				},
-				Dependencies: &manifest.Dependencies{
-					Runtime: []manifest.Dependency{},
-					Dev:     []manifest.Dependency{},
-				},
-				Files: manifest.FileSpec{
-					Include: []string{},
-					Exclude: []string{},
-				},
-				Metadata: manifest.Metadata{
-					License: "MPL-2.0",
-					Tags:    []string{},
-				},
+			if *templateName != "" {
+				if *importDeps {
+					return fmt.Errorf("--import-deps can't be used with --template, which doesn't read existing project files")
+				}
+				return runInitTemplate(*templateName)
+			}
+			if !*yes && term.IsTerminal(int(os.Stdin.Fd())) {
+				return runInitWizard(newInitPrompter(os.Stdin, os.Stdout), *noDetect, *importDeps)
 			}
+			return runInit(*noDetect, *importDeps)
+		},
+	}
+}
+
+// runInit writes aigogo.json without prompting, from the detected project
+// or the defaults
+func runInit(noDetect, importDeps bool) error {
+	manifestPath := "aigogo.json"
+	m := newInitManifest()
 
-			// Save manifest
-			if err := manifest.Save(manifestPath, m); err != nil {
-				return err
+	// Start from what an existing project manifest says
+	if !noDetect {
+		project, err := detectInitProject()
+		if err != nil {
+			return err
+		}
+		if project != nil {
+			fmt.Printf("🔍 Found %s\n", project.File)
+			for _, line := range applyProject(m, project) {
+				fmt.Println(line)
 			}
+			importProjectDependencies(os.Stdout, m, project, importDeps, nil)
+			fmt.Println()
+			if err := manifest.Validate(m); err != nil {
+				return fmt.Errorf("aigogo.json pre-filled from %s is invalid: %w\nRun 'aigg init --no-detect' to start from an empty manifest", project.File, err)
+			}
+		}
+	}
+
+	// Save manifest
+	if err := manifest.Save(manifestPath, m); err != nil {
+		return err
+	}
 
-			fmt.Println("✓ Initialized aigogo package")
-			fmt.Printf("  Created %s\n\n", manifestPath)
-			fmt.Println("Next steps:")
-			fmt.Println("  1. Edit aigogo.json to configure language and metadata")
-			fmt.Println("  2. Add files: aigg add file <path>...")
-			fmt.Println("  3. Add dependencies: aigg add dep <package> <version>")
-			fmt.Println("  4. Run 'aigg validate' to check your configuration")
-			fmt.Println("  5. Build and share: aigg build <name>:<tag>")
+	fmt.Println("✓ Initialized aigogo package")
+	fmt.Printf("  Created %s\n\n", manifestPath)
+	printInitNextSteps(os.Stdout)
 
-			return nil
+	return nil
+}
+
+// newInitManifest returns the manifest aigg init starts from
+func newInitManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Schema:      "https://github.com/aupeachmo/aigogo/blob/master/aigogo.schema.json",
+		Name:        getCurrentDirName(),
+		Version:     "0.1.0",
+		Description: "An AI agent",
+		Author:      "",
+		Language: manifest.Language{
+			Name:    "python",
+			Version: defaultLanguageVersions["python"],
+		},
+		Dependencies: &manifest.Dependencies{
+			Runtime: []manifest.Dependency{},
+			Dev:     []manifest.Dependency{},
+		},
+		Files: manifest.FileSpec{
+			Include: []string{},
+			Exclude: []string{},
 		},
+		Metadata: manifest.Metadata{
+			License: "MPL-2.0",
+			Tags:    []string{},
+		},
+	}
+}
+
+// detectInitProject reads the metadata of the project manifest in the
+// current directory, or returns nil when there is none
+func detectInitProject() (*depimport.Project, error) {
+	project, err := depimport.DetectProject(".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project metadata: %w\nRun 'aigg init --no-detect' to start from an empty manifest", err)
+	}
+	return project, nil
+}
+
+// runInitTemplate lays out a new package in the current directory from the
+// named template. Existing files are never overwritten.
+func runInitTemplate(name string) error {
+	userDir, err := scaffold.UserTemplatesDir()
+	if err != nil {
+		return err
+	}
+	tmpl, err := scaffold.Find(name, userDir)
+	if err != nil {
+		return err
+	}
+	files, err := tmpl.Render(scaffold.NewData(getCurrentDirName()))
+	if err != nil {
+		return err
+	}
+
+	created, err := writeScaffold(".", files)
+	if err != nil {
+		return err
 	}
+
+	m, err := manifest.Load("aigogo.json")
+	if err == nil {
+		err = manifest.Validate(m)
+	}
+	if err != nil {
+		return fmt.Errorf("template %s (%s) created an invalid aigogo.json: %w", tmpl.Name, tmpl.Source, err)
+	}
+
+	fmt.Printf("✓ Initialized aigogo package from template %s", tmpl.Name)
+	if tmpl.Source != "built-in" {
+		fmt.Printf(" (%s)", tmpl.Source)
+	}
+	fmt.Println()
+	for _, path := range created {
+		fmt.Printf("  Created %s\n", path)
+	}
+	fmt.Println()
+	printInitNextSteps(os.Stdout)
+	return nil
+}
+
+// writeScaffold writes a template's files under dir and returns their
+// paths. Nothing is written if any of them already exists.
+func writeScaffold(dir string, files []scaffold.File) ([]string, error) {
+	var existing []string
+	for _, f := range files {
+		if _, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(f.Path))); err == nil {
+			existing = append(existing, f.Path)
+		}
+	}
+	if len(existing) > 0 {
+		return nil, fmt.Errorf("the template would overwrite existing files: %s\nMove them aside or run 'aigg init --template' in a new directory", strings.Join(existing, ", "))
+	}
+
+	var created []string
+	for _, f := range files {
+		path := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return created, fmt.Errorf("failed to create directory for %s: %w", f.Path, err)
+		}
+		if err := os.WriteFile(path, f.Content, 0644); err != nil {
+			return created, fmt.Errorf("failed to write %s: %w", f.Path, err)
+		}
+		created = append(created, f.Path)
+	}
+	return created, nil
+}
+
+func printInitNextSteps(out io.Writer) {
+	fmt.Fprintln(out, "Next steps:")
+	fmt.Fprintln(out, "  1. Edit aigogo.json to configure language and metadata")
+	fmt.Fprintln(out, "  2. Add files: aigg add file <path>...")
+	fmt.Fprintln(out, "  3. Add dependencies: aigg add dep <package> <version>")
+	fmt.Fprintln(out, "  4. Run 'aigg validate' to check your configuration")
+	fmt.Fprintln(out, "  5. Build and share: aigg build <name>:<tag>")
+}
+
+// applyProject fills the manifest's fields from a detected project and
+// returns a line describing each field set or skipped. Metadata aigogo.json
+// would reject, such as a license that isn't an SPDX expression, is left
+// out.
+func applyProject(m *manifest.Manifest, p *depimport.Project) []string {
+	var lines []string
+	set := func(field string, target *string, value string) {
+		if value == "" {
+			return
+		}
+		*target = value
+		lines = append(lines, fmt.Sprintf("  %-12s %s", field+":", value))
+	}
+
+	set("name", &m.Name, p.Name)
+	set("version", &m.Version, p.Version)
+	set("description", &m.Description, p.Description)
+	set("author", &m.Author, p.Author)
+
+	m.Language = manifest.Language{Name: p.Language, Version: p.LanguageVersion}
+	if m.Language.Version == "" {
+		m.Language.Version = defaultLanguageVersions[p.Language]
+		lines = append(lines, fmt.Sprintf("  %-12s %s %s (default; %s doesn't say)", "language:", m.Language.Name, m.Language.Version, p.File))
+	} else {
+		lines = append(lines, fmt.Sprintf("  %-12s %s %s", "language:", m.Language.Name, m.Language.Version))
+	}
+
+	metadata := []struct {
+		field  string
+		target *string
+		value  string
+		check  manifest.Metadata
+	}{
+		{"license", &m.Metadata.License, p.License, manifest.Metadata{License: p.License}},
+		{"repository", &m.Metadata.Repository, p.Repository, manifest.Metadata{Repository: p.Repository}},
+		{"homepage", &m.Metadata.Homepage, p.Homepage, manifest.Metadata{Homepage: p.Homepage}},
+	}
+	for _, md := range metadata {
+		if md.value == "" {
+			continue
+		}
+		if err := manifest.ValidateMetadata(md.check); err != nil {
+			// Don't leave a default that misstates the project's license
+			*md.target = ""
+			lines = append(lines, fmt.Sprintf("⚠ Skipping %s: %v", md.field, err))
+			continue
+		}
+		set(md.field, md.target, md.value)
+	}
+	if len(p.Keywords) > 0 {
+		if err := manifest.ValidateMetadata(manifest.Metadata{Keywords: p.Keywords}); err != nil {
+			lines = append(lines, fmt.Sprintf("⚠ Skipping keywords: %v", err))
+		} else {
+			m.Metadata.Keywords = p.Keywords
+			lines = append(lines, fmt.Sprintf("  %-12s %s", "keywords:", strings.Join(p.Keywords, ", ")))
+		}
+	}
+	return lines
+}
+
+// importProjectDependencies adds the detected project's dependencies to the
+// manifest when importDeps is set or confirm agrees. With no confirm to ask,
+// they are left out.
+func importProjectDependencies(out io.Writer, m *manifest.Manifest, p *depimport.Project, importDeps bool, confirm func(question string) bool) {
+	for _, w := range p.Warnings {
+		fmt.Fprintf(out, "⚠ Skipping %s\n", w)
+	}
+	if len(p.Runtime) == 0 && len(p.Dev) == 0 {
+		return
+	}
+
+	if !importDeps {
+		question := fmt.Sprintf("Import %d runtime and %d dev dependencies from %s?", len(p.Runtime), len(p.Dev), p.File)
+		if confirm == nil || !confirm(question) {
+			fmt.Fprintln(out, "  Dependencies not imported; run 'aigg init --import-deps' to import them")
+			return
+		}
+	}
+
+	m.Dependencies.Runtime = append(m.Dependencies.Runtime, p.Runtime...)
+	m.Dependencies.Dev = append(m.Dependencies.Dev, p.Dev...)
+	fmt.Fprintf(out, "✓ Imported %d runtime and %d dev dependencies\n", len(p.Runtime), len(p.Dev))
 }
 
 func getCurrentDirName() string {
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/depimport"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/scaffold"
+)
+
+func TestApplyProject(t *testing.T) {
+	m := &manifest.Manifest{
+		Name:     "dirname",
+		Version:  "0.1.0",
+		Language: manifest.Language{Name: "python", Version: ">=3.8,<4.0"},
+		Metadata: manifest.Metadata{License: "MPL-2.0"},
+	}
+	p := &depimport.Project{
+		File:        "package.json",
+		Name:        "chat-tools",
+		Version:     "2.0.1",
+		Author:      "Ada <ada@example.com>",
+		License:     "MIT",
+		Repository:  "https://github.com/acme/chat-tools",
+		Keywords:    []string{"chat"},
+		Language:    "javascript",
+		Description: "Chat helpers",
+	}
+
+	lines := applyProject(m, p)
+
+	if m.Name != "chat-tools" || m.Version != "2.0.1" || m.Description != "Chat helpers" || m.Author != "Ada <ada@example.com>" {
+		t.Errorf("manifest fields not set from project: %+v", m)
+	}
+	if m.Metadata.License != "MIT" || m.Metadata.Repository != "https://github.com/acme/chat-tools" || len(m.Metadata.Keywords) != 1 {
+		t.Errorf("metadata not set from project: %+v", m.Metadata)
+	}
+	if m.Language.Name != "javascript" || m.Language.Version != defaultLanguageVersions["javascript"] {
+		t.Errorf("Language = %+v, want javascript with the default version", m.Language)
+	}
+	if !strings.Contains(strings.Join(lines, "\n"), "default; package.json doesn't say") {
+		t.Errorf("lines don't note the default language version:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestApplyProjectSkipsInvalidMetadata(t *testing.T) {
+	m := &manifest.Manifest{Metadata: manifest.Metadata{License: "MPL-2.0"}}
+	p := &depimport.Project{
+		File:            "pyproject.toml",
+		Name:            "agent",
+		License:         "BSD License",
+		Homepage:        "not a url",
+		Language:        "python",
+		LanguageVersion: ">=3.10",
+	}
+
+	lines := applyProject(m, p)
+
+	if m.Metadata.License != "" {
+		t.Errorf("License = %q, want the default cleared rather than kept", m.Metadata.License)
+	}
+	if m.Metadata.Homepage != "" {
+		t.Errorf("Homepage = %q, want it left out", m.Metadata.Homepage)
+	}
+	out := strings.Join(lines, "\n")
+	for _, want := range []string{"Skipping license", "Skipping homepage"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("lines missing %q:\n%s", want, out)
+		}
+	}
+	if m.Language.Version != ">=3.10" {
+		t.Errorf("Language.Version = %q, want the project's", m.Language.Version)
+	}
+}
+
+func TestWriteScaffold(t *testing.T) {
+	dir := t.TempDir()
+	files := []scaffold.File{
+		{Path: "aigogo.json", Content: []byte("{}")},
+		{Path: "prompts/a.md", Content: []byte("a")},
+	}
+
+	created, err := writeScaffold(dir, files)
+	if err != nil {
+		t.Fatalf("writeScaffold failed: %v", err)
+	}
+	if len(created) != 2 {
+		t.Errorf("created = %v", created)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "prompts", "a.md")); err != nil || string(data) != "a" {
+		t.Errorf("prompts/a.md = %q, %v", data, err)
+	}
+
+	// Nothing is written when any file exists
+	if err := os.WriteFile(filepath.Join(dir, "aigogo.json"), []byte("mine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	more := append([]scaffold.File{{Path: "new.py", Content: []byte("x")}}, files...)
+	_, err = writeScaffold(dir, more)
+	if err == nil || !strings.Contains(err.Error(), "aigogo.json, prompts/a.md") {
+		t.Errorf("err = %v, want the existing files listed", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.py")); !os.IsNotExist(err) {
+		t.Error("new.py was written despite the conflict")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "aigogo.json")); string(data) != "mine" {
+		t.Errorf("aigogo.json was overwritten: %q", data)
+	}
+}
@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/depimport"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// errInitCancelled is returned when the input ends before the wizard has
+// its answers
+var errInitCancelled = errors.New("init cancelled; nothing was written")
+
+// initIgnorePatterns are what the wizard's .aigogoignore leaves out of a
+// package, per language, on top of the built-in excludes such as .venv/ and
+// node_modules/. Other languages get defaultInitIgnorePatterns.
+var initIgnorePatterns = map[string][]string{
+	"python":     {"tests/", ".pytest_cache/", ".mypy_cache/", ".ruff_cache/", ".tox/"},
+	"javascript": {"test/", "tests/", "coverage/", ".nyc_output/"},
+	"go":         {"*_test.go", "testdata/"},
+	"rust":       {"tests/", "benches/"},
+}
+
+var defaultInitIgnorePatterns = []string{"tests/"}
+
+// initPrompter asks the init wizard's questions
+type initPrompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newInitPrompter(in io.Reader, out io.Writer) *initPrompter {
+	return &initPrompter{in: bufio.NewReader(in), out: out}
+}
+
+// ask prints question with its default in brackets and returns the answer,
+// or the default for an empty one. Answers check rejects are asked again.
+func (p *initPrompter) ask(question, def string, check func(string) error) (string, error) {
+	for {
+		if def != "" {
+			fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", question)
+		}
+		line, err := p.in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			fmt.Fprintln(p.out)
+			return "", errInitCancelled
+		}
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			answer = def
+		}
+		if check != nil {
+			if err := check(answer); err != nil {
+				fmt.Fprintf(p.out, "  ⚠ %v\n", err)
+				continue
+			}
+		}
+		return answer, nil
+	}
+}
+
+// confirm asks a yes/no question, answered def on an empty answer
+func (p *initPrompter) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		fmt.Fprintf(p.out, "%s (%s): ", question, hint)
+		line, err := p.in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			fmt.Fprintln(p.out)
+			return false, errInitCancelled
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(p.out, "  ⚠ Answer yes or no")
+	}
+}
+
+// runInitWizard asks for the package's metadata, starting from what the
+// project's own manifest or source files say, offers to import its
+// dependencies and shows a summary before writing aigogo.json and a
+// .aigogoignore. An existing .aigogoignore is kept, and an existing
+// aigogo.json is only overwritten when the user agrees.
+func runInitWizard(p *initPrompter, noDetect, importDeps bool) error {
+	const manifestPath = "aigogo.json"
+
+	fmt.Fprintln(p.out, "This creates aigogo.json for the package in this directory.")
+	fmt.Fprintln(p.out, "Press Enter to keep the value in brackets.")
+	fmt.Fprintln(p.out)
+
+	if _, err := os.Lstat(manifestPath); err == nil {
+		overwrite, err := p.confirm("aigogo.json already exists. Overwrite it?", false)
+		if err != nil {
+			return err
+		}
+		if !overwrite {
+			fmt.Fprintln(p.out, "Nothing written")
+			return nil
+		}
+	}
+
+	m := newInitManifest()
+	var project *depimport.Project
+	if !noDetect {
+		var err error
+		if project, err = detectInitProject(); err != nil {
+			return err
+		}
+		if project != nil {
+			fmt.Fprintf(p.out, "🔍 Found %s\n", project.File)
+			for _, line := range applyProject(m, project) {
+				fmt.Fprintln(p.out, line)
+			}
+			fmt.Fprintln(p.out)
+		} else {
+			lang, err := manifest.DetectLanguage(".")
+			if err != nil {
+				return fmt.Errorf("failed to detect the package's language: %w", err)
+			}
+			if lang != "" {
+				m.Language = manifest.Language{Name: lang, Version: defaultLanguageVersions[lang]}
+				fmt.Fprintf(p.out, "🔍 Found %s source files\n\n", lang)
+			}
+		}
+	}
+
+	if err := askInitFields(p, m); err != nil {
+		return err
+	}
+
+	if project != nil {
+		if m.Language.Name != project.Language {
+			fmt.Fprintf(p.out, "  Dependencies not imported: %s lists %s dependencies\n", project.File, project.Language)
+		} else {
+			importProjectDependencies(p.out, m, project, importDeps, func(question string) bool {
+				// A cancelled answer is seen again by the next question
+				ok, _ := p.confirm(question, true)
+				return ok
+			})
+		}
+	}
+
+	if err := manifest.Validate(m); err != nil {
+		return fmt.Errorf("aigogo.json would be invalid: %w", err)
+	}
+
+	var ignorePatterns []string
+	if _, err := os.Lstat(manifest.AigogoIgnoreFile); os.IsNotExist(err) {
+		ignorePatterns = initIgnorePatterns[m.Language.Name]
+		if ignorePatterns == nil {
+			ignorePatterns = defaultInitIgnorePatterns
+		}
+	}
+
+	printInitSummary(p.out, m, ignorePatterns)
+	write, err := p.confirm("Write these files?", true)
+	if err != nil {
+		return err
+	}
+	if !write {
+		fmt.Fprintln(p.out, "Nothing written")
+		return nil
+	}
+
+	if err := manifest.Save(manifestPath, m); err != nil {
+		return err
+	}
+	created := []string{manifestPath}
+	if ignorePatterns != nil {
+		content := "# Tests and local tooling stay out of the package\n" + strings.Join(ignorePatterns, "\n") + "\n"
+		if err := os.WriteFile(manifest.AigogoIgnoreFile, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", manifest.AigogoIgnoreFile, err)
+		}
+		created = append(created, manifest.AigogoIgnoreFile)
+	}
+
+	fmt.Fprintln(p.out)
+	fmt.Fprintln(p.out, "✓ Initialized aigogo package")
+	for _, path := range created {
+		fmt.Fprintf(p.out, "  Created %s\n", path)
+	}
+	fmt.Fprintln(p.out)
+	printInitNextSteps(p.out)
+	return nil
+}
+
+// askInitFields asks for the manifest's metadata, defaulting to what it
+// already holds
+func askInitFields(p *initPrompter, m *manifest.Manifest) error {
+	var err error
+	if m.Name, err = p.ask("Package name", m.Name, checkInitName); err != nil {
+		return err
+	}
+	if m.Version, err = p.ask("Version", m.Version, func(v string) error {
+		_, err := manifest.ParseSemver(v)
+		return err
+	}); err != nil {
+		return err
+	}
+	if m.Description, err = p.ask("Description", m.Description, nil); err != nil {
+		return err
+	}
+	if m.Author, err = p.ask("Author", m.Author, nil); err != nil {
+		return err
+	}
+
+	lang, err := p.ask("Language", m.Language.Name, func(v string) error {
+		if !manifest.ValidateLanguage(v) {
+			return fmt.Errorf("unsupported language: %s (supported: %s)", v, strings.Join(manifest.SupportedLanguages(), ", "))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if lang != m.Language.Name {
+		m.Language = manifest.Language{Name: lang, Version: defaultLanguageVersions[lang]}
+	}
+	if m.Language.Version, err = p.ask(lang+" version", m.Language.Version, func(v string) error {
+		if v == "" {
+			return fmt.Errorf("a version constraint is required, e.g. >=3.8")
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	m.Metadata.License, err = p.ask("License (SPDX expression)", m.Metadata.License, func(v string) error {
+		return manifest.ValidateMetadata(manifest.Metadata{License: v})
+	})
+	return err
+}
+
+// checkInitName rejects package names that can't be part of a reference
+// such as docker.io/org/<name>:1.0.0
+func checkInitName(name string) error {
+	if name == "" {
+		return fmt.Errorf("a name is required")
+	}
+	if strings.ContainsAny(name, " \t/:@") {
+		return fmt.Errorf("a name can't contain spaces, '/', ':' or '@'")
+	}
+	return nil
+}
+
+// printInitSummary prints what the wizard is about to write; ignorePatterns
+// is nil when an existing .aigogoignore is kept
+func printInitSummary(out io.Writer, m *manifest.Manifest, ignorePatterns []string) {
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Summary:")
+	fields := []struct{ name, value string }{
+		{"name", m.Name},
+		{"version", m.Version},
+		{"description", m.Description},
+		{"author", m.Author},
+		{"language", m.Language.Name + " " + m.Language.Version},
+		{"license", m.Metadata.License},
+	}
+	for _, f := range fields {
+		if f.value != "" {
+			fmt.Fprintf(out, "  %-13s %s\n", f.name+":", f.value)
+		}
+	}
+	fmt.Fprintf(out, "  %-13s %d runtime, %d dev\n", "dependencies:", len(m.Dependencies.Runtime), len(m.Dependencies.Dev))
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Files:")
+	fmt.Fprintln(out, "  aigogo.json")
+	if ignorePatterns != nil {
+		fmt.Fprintf(out, "  %s (excludes %s)\n", manifest.AigogoIgnoreFile, strings.Join(ignorePatterns, " "))
+	} else {
+		fmt.Fprintf(out, "  %s exists and is kept\n", manifest.AigogoIgnoreFile)
+	}
+	fmt.Fprintln(out)
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// runWizard runs the init wizard in a new directory holding files, with
+// the given lines as answers, and returns what it printed
+func runWizard(t *testing.T, files map[string]string, answers ...string) (string, error) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out bytes.Buffer
+	in := strings.NewReader(strings.Join(answers, "\n") + "\n")
+	err := runInitWizard(newInitPrompter(in, &out), false, false)
+	return out.String(), err
+}
+
+func TestInitWizard(t *testing.T) {
+	// A re-asked invalid version, a changed description and defaults for
+	// the rest
+	out, err := runWizard(t, map[string]string{"main.go": "package main\n"},
+		"agent-tools", "one", "1.2.0", "Tools for agents", "", "", "", "", "")
+	if err != nil {
+		t.Fatalf("runInitWizard() error = %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "invalid semver") {
+		t.Errorf("invalid version not re-asked:\n%s", out)
+	}
+	if !strings.Contains(out, "Found go source files") || !strings.Contains(out, "Summary:") {
+		t.Errorf("output missing detection or summary:\n%s", out)
+	}
+
+	m, err := manifest.Load("aigogo.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "agent-tools" || m.Version != "1.2.0" || m.Description != "Tools for agents" {
+		t.Errorf("manifest = %s %s %q", m.Name, m.Version, m.Description)
+	}
+	if m.Language.Name != "go" || m.Language.Version != defaultLanguageVersions["go"] || m.Metadata.License != "MPL-2.0" {
+		t.Errorf("language = %+v, license = %s", m.Language, m.Metadata.License)
+	}
+	ignore, err := os.ReadFile(manifest.AigogoIgnoreFile)
+	if err != nil || !strings.Contains(string(ignore), "*_test.go") {
+		t.Errorf(".aigogoignore = %q, %v", ignore, err)
+	}
+}
+
+func TestInitWizardImportsDependencies(t *testing.T) {
+	pyproject := `[project]
+name = "chat-tools"
+version = "2.0.0"
+requires-python = ">=3.10"
+dependencies = ["requests>=2.31"]
+`
+	out, err := runWizard(t, map[string]string{"pyproject.toml": pyproject, manifest.AigogoIgnoreFile: "data/\n"},
+		"", "", "", "", "", "", "", "y", "y")
+	if err != nil {
+		t.Fatalf("runInitWizard() error = %v\n%s", err, out)
+	}
+	m, err := manifest.Load("aigogo.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "chat-tools" || m.Language.Version != ">=3.10" || len(m.Dependencies.Runtime) != 1 {
+		t.Errorf("manifest = %s %+v %v", m.Name, m.Language, m.Dependencies.Runtime)
+	}
+	if ignore, _ := os.ReadFile(manifest.AigogoIgnoreFile); string(ignore) != "data/\n" {
+		t.Errorf("existing .aigogoignore overwritten: %q", ignore)
+	}
+}
+
+func TestInitWizardKeepsExistingManifest(t *testing.T) {
+	out, err := runWizard(t, map[string]string{"aigogo.json": "{}"}, "")
+	if err != nil || !strings.Contains(out, "Nothing written") {
+		t.Errorf("runInitWizard() = %v\n%s", err, out)
+	}
+	if data, _ := os.ReadFile("aigogo.json"); string(data) != "{}" {
+		t.Errorf("aigogo.json overwritten: %s", data)
+	}
+}
+
+func TestInitWizardCancelled(t *testing.T) {
+	// Input ends before the summary is confirmed
+	_, err := runWizard(t, nil, "pkg")
+	if !errors.Is(err, errInitCancelled) {
+		t.Errorf("runInitWizard() error = %v, want %v", err, errInitCancelled)
+	}
+	if _, err := os.Stat("aigogo.json"); !os.IsNotExist(err) {
+		t.Error("aigogo.json written after cancelling")
+	}
+}
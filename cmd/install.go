@@ -1,259 +1,217 @@
 package cmd
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
-	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
 	"github.com/aupeachmo/aigogo/pkg/imports"
-	"github.com/aupeachmo/aigogo/pkg/lockfile"
-	"github.com/aupeachmo/aigogo/pkg/store"
+	"github.com/aupeachmo/aigogo/pkg/logging"
 )
 
 func installCmd() *Command {
+	flags := flag.NewFlagSet("install", flag.ContinueOnError)
+	ignoreScripts := flags.Bool("ignore-scripts", false, "Don't run packages' postinstall scripts")
+	withTemplates := flags.Bool("render-templates", false, "Render packages' template files into the project")
+	force := flags.Bool("force", false, "Install packages even if their environment constraints don't match this machine")
+	strict := flags.Bool("strict", false, "Fail if a locked package is deprecated")
+	python := flags.String("python", "", "Python interpreter or environment directory whose site-packages gets the aigogo.pth file (default: detected)")
+	tsconfig := flags.Bool("tsconfig", false, "Write tsconfig.aigogo.json mapping @aigogo/* imports to the installed packages, for tsconfig.json to extend")
+	frozen := flags.Bool("frozen", false, "Fail if aigogo.lock is incomplete or out of date, or a package doesn't match its integrity hash (for CI)")
+	offline := flags.Bool("offline", false, "Use only the local store and cache; fail listing the packages that would need fetching")
+	production := flags.Bool("production", false, "Skip dev and optional packages, leaving no links to them")
+	maxExtractSize := maxExtractSizeFlag(flags)
+
 	return &Command{
 		Name:        "install",
 		Description: "Install packages from aigogo.lock",
+		Flags:       flags,
 		Run: func(args []string) error {
-			return runInstall()
+			if err := setMaxExtractSize(*maxExtractSize); err != nil {
+				return err
+			}
+			return runInstall(aigogo.InstallOptions{
+				Packages:        args,
+				IgnoreScripts:   *ignoreScripts,
+				RenderTemplates: *withTemplates,
+				Force:           *force,
+				Strict:          *strict,
+				TSConfig:        *tsconfig,
+				Frozen:          *frozen,
+				Offline:         *offline,
+				Production:      *production,
+				Python:          *python,
+			})
 		},
 	}
 }
 
-func runInstall() error {
-	// Find lock file
-	lockPath, lock, err := lockfile.FindLockFile()
-	if err != nil {
-		return fmt.Errorf("failed to find aigogo.lock: %w\nRun 'aigg add <package>' first to add packages", err)
-	}
-
-	projectDir := filepath.Dir(lockPath)
-	fmt.Printf("Installing packages from %s\n\n", lockPath)
-
-	if len(lock.Packages) == 0 {
-		fmt.Println("No packages to install")
-		return nil
-	}
-
-	// Initialize store
-	cas, err := store.NewStore()
+// runInstall installs the packages in aigogo.lock (see
+// aigogo.Client.Install) and prints what was installed, the problems found
+// along the way and how to use the packages
+func runInstall(opts aigogo.InstallOptions) error {
+	client, err := newClient()
 	if err != nil {
-		return fmt.Errorf("failed to initialize store: %w", err)
+		return err
 	}
-
-	// Initialize setup manager
-	setupMgr, err := imports.NewSetupManager(projectDir)
+	result, err := client.Install(commandContext(), opts)
 	if err != nil {
-		return fmt.Errorf("failed to initialize imports manager: %w", err)
+		return err
 	}
-
-	// Clean existing imports
-	if err := setupMgr.Clean(); err != nil {
-		return fmt.Errorf("failed to clean existing imports: %w", err)
+	noteInstalled(result)
+	if len(result.Installed) == 0 && len(result.Skipped) == 0 {
+		fmt.Println("No packages to install")
+		return nil
 	}
 
-	// Track languages for namespace setup
-	hasPython := false
-	hasJavaScript := false
-
-	// Count packages by language
-	for _, pkg := range lock.Packages {
-		switch pkg.Language {
-		case "python":
-			hasPython = true
-		case "javascript", "typescript":
-			hasJavaScript = true
-		}
+	printRepaired(result.Repaired)
+	printPeerWarnings(result.PeerProblems)
+	if result.Conflicts != nil {
+		fmt.Println()
+		printConflictReport(os.Stdout, "⚠️  Dependency conflicts between installed packages:", result.Conflicts)
 	}
 
-	// Setup namespaces
-	if hasPython {
-		if err := setupMgr.SetupPythonNamespace(); err != nil {
-			return fmt.Errorf("failed to setup Python namespace: %w", err)
-		}
-	}
-	if hasJavaScript {
-		if err := setupMgr.SetupJavaScriptNamespace(); err != nil {
-			return fmt.Errorf("failed to setup JavaScript namespace: %w", err)
-		}
+	logging.Println()
+	fmt.Printf("✓ Installed %d package(s)", len(result.Installed))
+	if result.Fetched > 0 {
+		fmt.Printf(" (%d fetched)", result.Fetched)
 	}
+	fmt.Println()
 
-	// Install each package
-	var installed, fetched int
-	for name, pkg := range lock.Packages {
-		hash := pkg.GetIntegrityHash()
-
-		// Check if in store
-		if !cas.Has(hash) {
-			// Need to fetch from source
-			fmt.Printf("Fetching %s from %s...\n", name, pkg.Source)
-			if err := fetchAndStore(cas, pkg); err != nil {
-				return fmt.Errorf("failed to fetch %s: %w", name, err)
-			}
-
-			// Verify hash matches
-			if !cas.Has(hash) {
-				return fmt.Errorf("integrity check failed for %s: hash mismatch", name)
-			}
-			fetched++
-		}
-
-		// Get stored package
-		storedPkg, err := cas.Get(hash)
-		if err != nil {
-			return fmt.Errorf("failed to get package %s from store: %w", name, err)
-		}
-
-		// Create symlink
-		storePath := cas.GetPath(hash)
-		if err := setupMgr.CreatePackageLink(name, pkg.Language, storePath); err != nil {
-			return fmt.Errorf("failed to create link for %s: %w", name, err)
-		}
-
-		// Display info
-		linkName := name
-		if pkg.Language == "python" {
-			linkName = lockfile.NormalizeName(name)
-		}
-
-		fmt.Printf("✓ Installed %s (%d files)\n", name, len(pkg.Files))
-		_ = storedPkg // Use variable
-		installed++
+	printInstallHints(result)
+	return nil
+}
 
-		// Show import hint
-		switch pkg.Language {
-		case "python":
-			fmt.Printf("  import: from aigogo.%s import ...\n", linkName)
-		case "javascript", "typescript":
-			fmt.Printf("  import: import ... from '@aigogo/%s'\n", name)
+// printInstallHints says how to use the installed packages from each of
+// their languages
+func printInstallHints(result *aigogo.InstallResult) {
+	setupMgr := result.Setup
+	if setupMgr == nil {
+		return
+	}
+	ns := setupMgr.GetNamespace()
+
+	logging.Println("\nTo use installed packages:")
+	if result.HasLanguage("python") {
+		if setupMgr.UsesPyPackages() {
+			pythonPath, _ := filepath.Rel(result.ProjectDir, setupMgr.GetPythonPath())
+			logging.Printf("  Python: Installed into %s (PEP 582); run with 'pdm run', or add to PYTHONPATH:\n", pythonPath)
+			logging.Printf("    export PYTHONPATH=\"%s:$PYTHONPATH\"\n", setupMgr.GetPythonPath())
+			logging.Printf("    from %s.<package_name> import ...\n", ns.Python)
+		} else if result.PythonEnv != nil {
+			logging.Printf("  Python: Path auto-configured via .pth file in the %s environment\n", result.PythonEnv.Kind)
+			logging.Printf("    %s\n", result.PythonEnv.SitePackages)
+			logging.Printf("    from %s.<package_name> import ...\n", ns.Python)
+		} else {
+			logging.Println("  Python: Add to PYTHONPATH (auto-configuration failed):")
+			logging.Printf("    export PYTHONPATH=\"%s:$PYTHONPATH\"\n", setupMgr.GetImportsDir())
 		}
 	}
-
-	// Update .gitignore
-	if err := setupMgr.UpdateGitignore(); err != nil {
-		fmt.Printf("⚠ Warning: failed to update .gitignore: %v\n", err)
-	}
-
-	// Auto-configure Python path via .pth file
-	// Note: Clean() already removed any stale .pth file at the start of install.
-	pythonPathConfigured := false
-	if hasPython {
-		if err := imports.InstallPthFile(setupMgr.GetImportsDir()); err != nil {
-			fmt.Printf("⚠ Could not auto-configure Python path: %v\n", err)
+	if result.HasLanguage("javascript") {
+		if result.RegisterScript {
+			logging.Println("  JavaScript: Add to entry point (CommonJS):")
+			logging.Println("    require('./.aigogo/register');")
+			logging.Println("  Or use as preload (CommonJS):")
+			logging.Println("    node --require ./.aigogo/register.js app.js")
+			logging.Println("  ES modules: preload the ESM loader (Node.js 18.19+):")
+			logging.Println("    node --import ./.aigogo/register.mjs app.mjs")
+			logging.Println("  Bun: Preload the aigogo plugin (or add it to preload in bunfig.toml):")
+			logging.Println("    bun --preload ./.aigogo/bun-plugin.js app.js")
 		} else {
-			pythonPathConfigured = true
+			logging.Println("  JavaScript: Add to NODE_PATH:")
+			logging.Printf("    export NODE_PATH=\"%s:$NODE_PATH\"\n", setupMgr.GetImportsDir())
 		}
-	}
-
-	fmt.Printf("\n✓ Installed %d package(s)", installed)
-	if fetched > 0 {
-		fmt.Printf(" (%d fetched)", fetched)
-	}
-	fmt.Println()
-
-	// Generate Node.js register script
-	// Note: Clean() already removed any stale register script at the start of install.
-	jsRegisterInstalled := false
-	if hasJavaScript {
-		if err := imports.InstallRegisterScript(projectDir); err != nil {
-			fmt.Printf("⚠ Warning: failed to create register script: %v\n", err)
+		if result.ImportMap {
+			logging.Println("  Deno: Use the generated import map:")
+			logging.Printf("    deno run --import-map=./%s/%s app.js\n", imports.ImportsDir, imports.ImportMapFileName)
+		}
+		if result.TSConfig {
+			logging.Printf("  TypeScript: Extend %s from tsconfig.json:\n", imports.TSConfigFileName)
+			logging.Printf("    \"extends\": \"./%s\"\n", imports.TSConfigFileName)
 		} else {
-			jsRegisterInstalled = true
+			logging.Printf("  TypeScript: Run 'aigg install --tsconfig' to resolve %s/* imports\n", ns.JavaScript)
 		}
 	}
-
-	// Print setup hints
-	fmt.Println("\nTo use installed packages:")
-	if hasPython {
-		if pythonPathConfigured {
-			fmt.Println("  Python: Path auto-configured via .pth file")
-			fmt.Println("    from aigogo.<package_name> import ...")
+	if result.PathEnv {
+		logging.Println("  Or load these variables from the files install generated:")
+		logging.Printf("    direnv: echo 'source_env %s/%s' >> .envrc && direnv allow\n", imports.ImportsDir, imports.EnvrcFileName)
+		logging.Printf("    shell:  set -a && . %s/%s && set +a\n", imports.ImportsDir, imports.PathEnvFileName)
+	}
+	if result.HasLanguage("ruby") {
+		logging.Println("  Ruby: Add to the load path:")
+		logging.Printf("    export RUBYLIB=\"%s:$RUBYLIB\"\n", setupMgr.GetRubyLoadPath())
+		logging.Println("    require 'aigogo/<package_name>/<file>'")
+	}
+	if result.HasLanguage("java") {
+		logging.Println("  Java: Add each package directory as a source root:")
+		logging.Printf("    javac -sourcepath \"%s/<package-name>\" ...\n", setupMgr.GetJavaSourceRootPath())
+		logging.Println("    (Gradle: sourceSets.main.java.srcDir, Maven: build-helper add-source)")
+	}
+	if result.HasLanguage("go") {
+		if result.GoMod {
+			logging.Println("  Go: go.mod requires each package's module and replaces it with .aigogo/imports/go/<module>:")
+			logging.Println("    import \"aigogo/<package_name>\"")
+			logging.Println("    go mod tidy   # adds go.sum entries for the packages' own dependencies")
 		} else {
-			fmt.Println("  Python: Add to PYTHONPATH (auto-configuration failed):")
-			fmt.Printf("    export PYTHONPATH=\"%s:$PYTHONPATH\"\n", setupMgr.GetImportsDir())
+			logging.Println("  Go: No go.mod in this directory; run 'go mod init <module>' and 'aigg install' again, or add:")
+			logging.Printf("    replace <module> => %s/<module>\n", setupMgr.GetGoModulesPath())
 		}
 	}
-	if hasJavaScript {
-		if jsRegisterInstalled {
-			fmt.Println("  JavaScript: Add to entry point (CommonJS):")
-			fmt.Println("    require('./.aigogo/register');")
-			fmt.Println("  Or use as preload (CommonJS and ESM):")
-			fmt.Println("    node --require ./.aigogo/register.js app.js")
+	if result.HasLanguage("rust") {
+		if result.CargoToml {
+			logging.Println("  Rust: Cargo.toml depends on each crate in .aigogo/imports/rust/ by path (or patches it):")
+			logging.Println("    use <crate_name>::...;")
 		} else {
-			fmt.Println("  JavaScript: Add to NODE_PATH:")
-			fmt.Printf("    export NODE_PATH=\"%s:$NODE_PATH\"\n", setupMgr.GetImportsDir())
+			logging.Println("  Rust: No Cargo.toml in this directory; add a path dependency:")
+			logging.Printf("    <crate> = { path = \"%s/<crate>\" }\n", setupMgr.GetRustCratesPath())
 		}
 	}
-
-	return nil
 }
 
-// fetchAndStore pulls a package from the registry and stores it in the CAS
-func fetchAndStore(cas *store.Store, pkg lockfile.LockedPackage) error {
-	// Pull the package using existing Puller
-	puller := docker.NewPuller()
-	if err := puller.Pull(pkg.Source); err != nil {
-		return fmt.Errorf("failed to pull: %w", err)
+// printRepaired reports the packages whose broken links install repaired
+func printRepaired(broken map[string][]imports.LinkProblem) {
+	if len(broken) == 0 {
+		return
 	}
-
-	// Extract to temp directory
-	tmpDir, err := os.MkdirTemp("", "aigogo-install-*")
-	if err != nil {
-		return fmt.Errorf("failed to create temp directory: %w", err)
-	}
-	defer func() { _ = os.RemoveAll(tmpDir) }()
-
-	extractor := docker.NewExtractor()
-	extractedFiles, err := extractor.Extract(pkg.Source, tmpDir, true)
-	if err != nil {
-		return fmt.Errorf("failed to extract: %w", err)
+	names := make([]string, 0, len(broken))
+	for name := range broken {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	// Convert to relative paths
-	var relFiles []string
-	for _, f := range extractedFiles {
-		relPath, err := filepath.Rel(tmpDir, f)
-		if err != nil {
-			return err
+	fmt.Printf("\n✓ Repaired %d package(s) with broken links:\n", len(names))
+	for _, name := range names {
+		for _, p := range broken[name] {
+			fmt.Printf("  %s: %s (%s)\n", name, p.Path, p.Reason)
 		}
-		relFiles = append(relFiles, relPath)
 	}
+}
 
-	// Read manifest if present
-	manifestPath := filepath.Join(tmpDir, "aigogo.json")
-	var manifestData []byte
-	if _, err := os.Stat(manifestPath); err == nil {
-		manifestData, _ = os.ReadFile(manifestPath)
-	} else {
-		// Create minimal manifest
-		manifest := map[string]interface{}{
-			"name":     lockfile.GetPackageName(pkg.Source),
-			"version":  pkg.Version,
-			"language": map[string]string{"name": pkg.Language},
-		}
-		manifestData, _ = json.MarshalIndent(manifest, "", "  ")
+// printPeerWarnings lists, by package, the peer dependencies the project
+// doesn't provide. They are only reported: the project installs them with
+// its own package manager.
+func printPeerWarnings(warnings map[string][]aigogo.PeerProblem) {
+	if len(warnings) == 0 {
+		return
 	}
-
-	// Store in CAS
-	hash, err := cas.Store(tmpDir, relFiles, manifestData)
-	if err != nil {
-		return fmt.Errorf("failed to store: %w", err)
+	names := make([]string, 0, len(warnings))
+	for name := range warnings {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	// Verify integrity
-	expectedHash := pkg.GetIntegrityHash()
-	if hash != expectedHash {
-		// Clean up
-		_ = cas.Delete(hash)
-		return fmt.Errorf("integrity mismatch: expected %s, got %s", expectedHash, hash)
-	}
-
-	// Make read-only
-	if err := cas.MakeReadOnly(hash); err != nil {
-		fmt.Printf("⚠ Warning: failed to make files read-only: %v\n", err)
+	fmt.Println()
+	fmt.Println("⚠️  Peer dependencies the project doesn't provide (aigg doesn't install them):")
+	for _, name := range names {
+		for _, p := range warnings[name] {
+			fmt.Printf("  - %s: %s\n", name, p.Message)
+		}
 	}
-
-	return nil
 }
+
+// maxParallelFetches bounds how many packages add, install and pull fetch
+// at once; the concurrency setting changes it
+var maxParallelFetches = 4
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var (
+	interruptOnce sync.Once
+	interruptCtx  context.Context
+	// stopInterrupt restores the default handling of Ctrl-C; Execute calls
+	// it when the command returns
+	stopInterrupt = func() {}
+)
+
+// commandContext returns the context registry and store operations run
+// with. The first call starts handling Ctrl-C and SIGTERM: the first signal
+// cancels the context, so pushes, pulls and installs stop and clean up
+// what they started, and a second one exits at once. Commands that never
+// call it, such as those that prompt or exec another program, keep the
+// default handling.
+func commandContext() context.Context {
+	interruptOnce.Do(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		signals := make(chan os.Signal, 2)
+		done := make(chan struct{})
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			select {
+			case <-signals:
+			case <-done:
+				return
+			}
+			fmt.Fprintln(os.Stderr, "\nInterrupted, cleaning up (press Ctrl-C again to quit at once)")
+			cancel()
+			select {
+			case <-signals:
+				os.Exit(130)
+			case <-done:
+			}
+		}()
+		interruptCtx = ctx
+		stopInterrupt = func() {
+			signal.Stop(signals)
+			close(done)
+			cancel()
+		}
+	})
+	return interruptCtx
+}
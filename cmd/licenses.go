@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/depgen"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// licenseTarget is a package whose declared dependencies are checked
+type licenseTarget struct {
+	name     string
+	version  string
+	language string
+	deps     []manifest.Dependency
+	note     string // set when the dependencies could not be read
+}
+
+func licensesCmd() *Command {
+	flags := flag.NewFlagSet("licenses", flag.ContinueOnError)
+	allow := flags.String("allow", "", "Comma-separated list of allowed licenses (e.g. MIT,Apache-2.0)")
+	deny := flags.String("deny", "", "Comma-separated list of denied licenses (e.g. GPL-3.0,AGPL-3.0)")
+	offline := flags.Bool("offline", false, "Don't query PyPI/npm; use cached license data only")
+
+	return &Command{
+		Name:        "licenses",
+		Description: "Report dependency licenses and check a license policy",
+		Flags:       flags,
+		Run: func(args []string) error {
+			targets, source, err := loadLicenseTargets()
+			if err != nil {
+				return err
+			}
+
+			if len(targets) == 0 {
+				fmt.Printf("No packages in %s\n", source)
+				return nil
+			}
+
+			fmt.Printf("Resolving licenses for %d package(s) from %s...\n\n", len(targets), source)
+
+			resolver := depgen.NewVersionResolver(*offline)
+			defer resolver.SaveCache()
+			policy := depgen.NewLicensePolicy(*allow, *deny)
+
+			counts := make(map[string]int)
+			var violations []string
+
+			for _, t := range targets {
+				fmt.Printf("%s@%s (%s)\n", t.name, t.version, t.language)
+				if t.note != "" {
+					fmt.Printf("  ⚠ %s\n\n", t.note)
+					continue
+				}
+				if len(t.deps) == 0 {
+					fmt.Println("  (no runtime dependencies)")
+					fmt.Println()
+					continue
+				}
+
+				for _, dep := range t.deps {
+					license, err := resolver.License(dep.Package, t.language)
+					display := license
+					switch {
+					case err != nil:
+						display = fmt.Sprintf("unknown (%v)", err)
+					case license == "":
+						display = "unknown"
+					}
+					counts[licenseLabel(license, err)]++
+
+					status := ""
+					if ok, reason := policy.Check(license); !ok {
+						status = "  ❌ " + reason
+						violations = append(violations, fmt.Sprintf("%s → %s: %s", t.name, dep.Package, reason))
+					}
+					fmt.Printf("  %-30s %s%s\n", dep.Package, display, status)
+				}
+				fmt.Println()
+			}
+
+			// Summary
+			labels := make([]string, 0, len(counts))
+			for label := range counts {
+				labels = append(labels, label)
+			}
+			sort.Strings(labels)
+			if len(labels) > 0 {
+				fmt.Println("Licenses:")
+				for _, label := range labels {
+					fmt.Printf("  %-30s %d\n", label, counts[label])
+				}
+				fmt.Println()
+			}
+
+			if !policy.Enabled() {
+				return nil
+			}
+			if len(violations) > 0 {
+				fmt.Printf("❌ %d license policy violation(s):\n", len(violations))
+				for _, v := range violations {
+					fmt.Printf("  %s\n", v)
+				}
+				return errcode.Errorf(errcode.Validation, "license policy check failed")
+			}
+			fmt.Println("✅ All dependency licenses satisfy the policy")
+			return nil
+		},
+	}
+}
+
+// licenseLabel groups a resolved license for the summary
+func licenseLabel(license string, err error) string {
+	if err != nil || license == "" {
+		return "unknown"
+	}
+	return license
+}
+
+// loadLicenseTargets returns the packages to check: every package in
+// aigogo.lock (using the manifest kept in the store), or the current
+// aigogo.json when there is no lock file.
+func loadLicenseTargets() ([]licenseTarget, string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	lockPath, lock, err := lockfile.FindLockFileFrom(cwd)
+	if err != nil {
+		m, manifestDir, findErr := manifest.FindManifest()
+		if findErr != nil {
+			return nil, "", fmt.Errorf("no %s or aigogo.json found\nRun 'aigg add <package>' or 'aigg init' first", lockfile.LockFileName)
+		}
+		t := licenseTarget{name: m.Name, version: m.Version, language: strings.ToLower(m.Language.Name)}
+		return splitLicenseTarget(t, m), filepath.Join(manifestDir, "aigogo.json"), nil
+	}
+
+	cas, err := aigogo.OpenStore(loadProjectSettings(filepath.Dir(lockPath)))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open store: %w", err)
+	}
+
+	names := make([]string, 0, len(lock.Packages))
+	for name := range lock.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	targets := make([]licenseTarget, 0, len(names))
+	for _, name := range names {
+		pkg := lock.Packages[name]
+		t := licenseTarget{name: name, version: pkg.Version, language: pkg.Language}
+
+		if stored, err := aigogo.OpenLockedPackage(cas, filepath.Dir(lockPath), pkg); err != nil {
+			if pkg.IsLocal() {
+				t.note = err.Error()
+			} else {
+				t.note = "not in local store; run 'aigg install' first"
+			}
+		} else if m, err := manifest.Load(stored.Manifest); err != nil {
+			t.note = fmt.Sprintf("failed to read manifest: %v", err)
+		} else {
+			targets = append(targets, splitLicenseTarget(t, m.WithExtras(pkg.Extras))...)
+			continue
+		}
+		targets = append(targets, t)
+	}
+
+	return targets, lockPath, nil
+}
+
+// splitLicenseTarget fills in t's dependencies from m, returning one target
+// per language for multi-language packages so each dependency's license is
+// looked up in the right registry
+func splitLicenseTarget(t licenseTarget, m *manifest.Manifest) []licenseTarget {
+	if len(m.Languages) == 0 {
+		if m.Dependencies != nil {
+			t.deps = m.Dependencies.Runtime
+		}
+		return []licenseTarget{t}
+	}
+
+	var targets []licenseTarget
+	for _, lang := range m.AllLanguages() {
+		lt := t
+		lt.language = lang.Name
+		lt.deps = nil
+		if view := m.ForLanguage(lang.Name); view.Dependencies != nil {
+			lt.deps = view.Dependencies.Runtime
+		}
+		targets = append(targets, lt)
+	}
+	return targets
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aupeachmo/aigogo/pkg/depgen"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func lintCmd() *Command {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	strict := flags.Bool("strict", false, "Fail on warnings as well as errors")
+	format := flags.String("format", "text", "Output format: text, sarif")
+	listRules := flags.Bool("list-rules", false, "List the lint rules and their default severities")
+
+	return &Command{
+		Name:        "lint",
+		Description: "Check the manifest and included files for publishing problems",
+		Flags:       flags,
+		Run: func(args []string) error {
+			if *listRules {
+				printLintRules()
+				return nil
+			}
+
+			sarif := false
+			switch *format {
+			case "text":
+			case "sarif":
+				sarif = true
+			default:
+				return errcode.Errorf(errcode.Usage, "unsupported format: %s\nSupported formats: text, sarif", *format)
+			}
+
+			m, err := manifest.Load("aigogo.json")
+			if err != nil {
+				return fmt.Errorf("failed to load aigogo.json: %w\nRun 'aigg init' first", err)
+			}
+
+			discovery, err := manifest.NewFileDiscovery(".", m.Files.Exclude)
+			if err != nil {
+				return fmt.Errorf("failed to initialize file discovery: %w", err)
+			}
+			files, err := discovery.Discover(m.Files, m.AllLanguages()...)
+			if err != nil {
+				return fmt.Errorf("failed to discover files: %w", err)
+			}
+
+			result, err := depgen.Lint(m, ".", files)
+			if err != nil {
+				return err
+			}
+
+			if sarif {
+				if err := depgen.WriteLintSARIF(os.Stdout, result.Findings, "aigogo.json", version); err != nil {
+					return fmt.Errorf("failed to write SARIF: %w", err)
+				}
+				if result.Failed(*strict) {
+					return errcode.Errorf(errcode.Validation, "lint failed")
+				}
+				return nil
+			}
+
+			fmt.Printf("Linting %s (%d file(s))...\n\n", m.Name, len(files))
+
+			printLintFindings("❌ Errors:", depgen.SeverityError, result.Findings)
+			printLintFindings("⚠️  Warnings:", depgen.SeverityWarning, result.Findings)
+			printLintFindings("ℹ️  Info:", depgen.SeverityInfo, result.Findings)
+			printFindingAnnotations("aigg lint", result.Findings, "aigogo.json")
+
+			if !result.Failed(*strict) {
+				fmt.Println("✅ Lint passed!")
+				return nil
+			}
+			if *strict {
+				fmt.Println("❌ Lint failed (--strict treats warnings as errors)")
+			} else {
+				fmt.Println("❌ Lint failed")
+			}
+			fmt.Println("Fix the issues above, or change a rule's severity with lint.rules in aigogo.json")
+			return errcode.Errorf(errcode.Validation, "lint failed")
+		},
+	}
+}
+
+// printLintFindings lists the findings of one severity with their rule IDs,
+// which are the keys lint.rules accepts
+func printLintFindings(heading, severity string, findings []depgen.Finding) {
+	printed := false
+	for _, f := range findings {
+		if f.Severity != severity {
+			continue
+		}
+		if !printed {
+			fmt.Println(heading)
+			printed = true
+		}
+		fmt.Printf("  - %s [%s]\n", f.Message, f.Rule)
+	}
+	if printed {
+		fmt.Println()
+	}
+}
+
+// printLintRules lists each rule with its default severity
+func printLintRules() {
+	fmt.Println("Lint rules (default severity):")
+	for _, rule := range depgen.LintRules {
+		fmt.Printf("  %-26s %-8s %s\n", rule.ID, rule.Severity, rule.Description)
+	}
+	fmt.Println()
+	fmt.Println(`Change a severity in aigogo.json: "lint": {"rules": {"<rule>": "error|warning|info|off"}}`)
+}
@@ -1,86 +1,360 @@
 package cmd
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
 )
 
+// listSorts are the orders aigg list --sort accepts
+var listSorts = []string{"name", "date", "size"}
+
+// listFilterKeys are the keys aigg list --filter accepts
+var listFilterKeys = []string{"name", "version", "type"}
+
 func listCmd() *Command {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	var filters []listFilter
+	flags.Func("filter", "Only list packages matching key=value, where key is name or version (glob patterns) or type (local or registry); repeat to combine", func(value string) error {
+		f, err := parseListFilter(value)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, f)
+		return nil
+	})
+	language := flags.String("language", "", "Only list packages in this language")
+	sortBy := flags.String("sort", "name", "Sort by name, date (newest first) or size (largest first)")
+	remote := flags.Bool("remote", false, "Check whether each package's reference exists in its registry")
+	long := flags.Bool("long", false, "Show each package's details instead of a table")
+
 	return &Command{
 		Name:        "list",
 		Description: "List cached agents",
+		Flags:       flags,
 		Run: func(args []string) error {
+			if len(args) > 0 {
+				return errcode.Errorf(errcode.Usage, "unexpected argument: %s", args[0])
+			}
+			if !slices.Contains(listSorts, *sortBy) {
+				return errcode.Errorf(errcode.Usage, "invalid --sort: %s (expected %s)", *sortBy, strings.Join(listSorts, ", "))
+			}
+
 			lister := docker.NewLister()
 			images, err := lister.ListDetailed()
 			if err != nil {
 				return fmt.Errorf("failed to list images: %w", err)
 			}
 
-			if len(images) == 0 {
+			entries := []listEntry{}
+			for _, img := range images {
+				entry := newListEntry(img)
+				if entry.matches(filters, *language) {
+					entries = append(entries, entry)
+				}
+			}
+			sortListEntries(entries, *sortBy)
+
+			if *remote {
+				checkListRemotes(entries)
+			}
+
+			if jsonOutput {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetEscapeHTML(false)
+				enc.SetIndent("", "  ")
+				return enc.Encode(entries)
+			}
+
+			if len(entries) == 0 {
+				if len(images) > 0 {
+					fmt.Printf("No cached agents match (%d cached)\n", len(images))
+					return nil
+				}
 				fmt.Println("No cached agents found")
 				fmt.Println("\nTip: Build a local package with: aigg build <name>:<tag>")
 				fmt.Println("     Or add from registry with:  aigg add <registry>/<name>:<tag>")
 				return nil
 			}
 
-			fmt.Printf("Cached agents (%d):\n\n", len(images))
+			fmt.Printf("Cached agents (%d):\n\n", len(entries))
+			if *long {
+				printListDetails(entries)
+			} else {
+				printListTable(entries, *remote)
+			}
+			return nil
+		},
+	}
+}
+
+// listEntry is one cached package as aigg list shows it, and as --json
+// prints it
+type listEntry struct {
+	Name         string             `json:"name"`
+	Package      string             `json:"package"`
+	Version      string             `json:"version,omitempty"`
+	Language     string             `json:"language,omitempty"`
+	Type         string             `json:"type"`   // "local-build" or "registry-pull"
+	Source       string             `json:"source"` // "local" or the registry pulled from
+	Size         int64              `json:"size"`
+	Time         *time.Time         `json:"time,omitempty"` // when it was built or pulled
+	Remote       *bool              `json:"remote,omitempty"`
+	RemoteError  string             `json:"remote_error,omitempty"`
+	Dependencies *listDependencies  `json:"dependencies,omitempty"`
+	Metadata     *manifest.Metadata `json:"metadata,omitempty"`
 
-			for _, img := range images {
-				// Format the image line
-				typeIndicator := "📦"
-				if img.Type == "local-build" {
-					typeIndicator = "🔨"
-				}
+	manifest *manifest.Manifest
+}
 
-				fmt.Printf("%s %s\n", typeIndicator, img.Name)
+type listDependencies struct {
+	Runtime int `json:"runtime"`
+	Dev     int `json:"dev"`
+}
 
-				// Show type
-				typeLabel := "local build"
-				if img.Type == "registry-pull" {
-					typeLabel = "pulled from registry"
-				}
-				fmt.Printf("   Type: %s\n", typeLabel)
-
-				// Show time
-				timeAgo := formatTimeAgo(img.BuildTime)
-				fmt.Printf("   Time: %s\n", timeAgo)
-
-				// Show size
-				sizeStr := formatSize(img.Size)
-				fmt.Printf("   Size: %s\n", sizeStr)
-
-				// Show language and version if manifest is available
-				if img.Manifest != nil {
-					if img.Manifest.Language.Name != "" {
-						langStr := img.Manifest.Language.Name
-						if img.Manifest.Language.Version != "" {
-							langStr += " " + img.Manifest.Language.Version
-						}
-						fmt.Printf("   Language: %s\n", langStr)
-					}
-
-					// Show dependency count
-					if img.Manifest.Dependencies != nil {
-						runtimeCount := len(img.Manifest.Dependencies.Runtime)
-						devCount := len(img.Manifest.Dependencies.Dev)
-
-						if runtimeCount > 0 || devCount > 0 {
-							depStr := fmt.Sprintf("%d runtime", runtimeCount)
-							if devCount > 0 {
-								depStr += fmt.Sprintf(", %d dev", devCount)
-							}
-							fmt.Printf("   Dependencies: %s\n", depStr)
-						}
-					}
-				}
+// newListEntry describes a cached image, taking the package name, version
+// and language from its aigogo.json when it has one and from its reference
+// otherwise
+func newListEntry(img docker.CachedImage) listEntry {
+	entry := listEntry{
+		Name:     img.Name,
+		Type:     img.Type,
+		Source:   "local",
+		Size:     img.Size,
+		manifest: img.Manifest,
+	}
+	if !img.BuildTime.IsZero() {
+		t := img.BuildTime
+		entry.Time = &t
+	}
+
+	registry, repository, tag, err := docker.ParseImageRef(img.Name)
+	if err == nil {
+		entry.Package = path.Base(repository)
+		if strings.Contains(img.Name, ":") {
+			entry.Version = tag
+		}
+		if img.Type == "registry-pull" {
+			entry.Source = registry
+		}
+	}
+
+	if m := img.Manifest; m != nil {
+		if m.Name != "" {
+			entry.Package = m.Name
+		}
+		if m.Version != "" {
+			entry.Version = m.Version
+		}
+		entry.Language = m.Language.Name
+		if m.Dependencies != nil {
+			entry.Dependencies = &listDependencies{Runtime: len(m.Dependencies.Runtime), Dev: len(m.Dependencies.Dev)}
+		}
+		if !reflect.DeepEqual(m.Metadata, manifest.Metadata{}) {
+			md := m.Metadata
+			entry.Metadata = &md
+		}
+	}
+	return entry
+}
+
+// listFilter is one --filter key=value
+type listFilter struct {
+	key, value string
+}
+
+func parseListFilter(s string) (listFilter, error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || value == "" {
+		return listFilter{}, fmt.Errorf("expected key=value, e.g. name=utils*, got %q", s)
+	}
+	if !slices.Contains(listFilterKeys, key) {
+		return listFilter{}, fmt.Errorf("unknown filter key %q (expected %s)", key, strings.Join(listFilterKeys, ", "))
+	}
+	if key == "type" && value != "local" && value != "registry" {
+		return listFilter{}, fmt.Errorf("invalid type %q (expected local or registry)", value)
+	}
+	if _, err := path.Match(value, ""); err != nil {
+		return listFilter{}, fmt.Errorf("invalid pattern %q: %w", value, err)
+	}
+	return listFilter{key: key, value: value}, nil
+}
 
-				fmt.Println()
+// matches reports whether the entry passes every filter and, when
+// language isn't empty, is written in it. A name pattern matches the
+// package name or the full reference.
+func (e listEntry) matches(filters []listFilter, language string) bool {
+	for _, f := range filters {
+		switch f.key {
+		case "name":
+			byPackage, _ := path.Match(f.value, e.Package)
+			byRef, _ := path.Match(f.value, e.Name)
+			if !byPackage && !byRef {
+				return false
+			}
+		case "version":
+			if ok, _ := path.Match(f.value, e.Version); !ok {
+				return false
+			}
+		case "type":
+			if (f.value == "local") != (e.Type == "local-build") {
+				return false
 			}
+		}
+	}
+	if language != "" && (e.manifest == nil || !e.manifest.HasLanguage(language)) {
+		return false
+	}
+	return true
+}
 
-			return nil
-		},
+// sortListEntries sorts by name, or by date or size with the newest or
+// largest first and ties by name
+func sortListEntries(entries []listEntry, by string) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch by {
+		case "date":
+			ta, tb := listTime(a), listTime(b)
+			if !ta.Equal(tb) {
+				return ta.After(tb)
+			}
+		case "size":
+			if a.Size != b.Size {
+				return a.Size > b.Size
+			}
+		}
+		return a.Name < b.Name
+	})
+}
+
+func listTime(e listEntry) time.Time {
+	if e.Time == nil {
+		return time.Time{}
+	}
+	return *e.Time
+}
+
+// checkListRemotes sets whether each entry's reference is in its registry.
+// Local builds named without a registry or namespace, such as utils:1.0.0,
+// have nowhere to check and are left unset.
+func checkListRemotes(entries []listEntry) {
+	puller := docker.NewPuller()
+	for i := range entries {
+		if !strings.Contains(entries[i].Name, "/") {
+			continue
+		}
+		exists, err := puller.Exists(commandContext(), entries[i].Name)
+		if err != nil {
+			entries[i].RemoteError = err.Error()
+			continue
+		}
+		entries[i].Remote = &exists
+	}
+}
+
+// printListTable prints one row per package
+func printListTable(entries []listEntry, remote bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "NAME\tVERSION\tLANGUAGE\tSOURCE\tSIZE\tBUILT/PULLED"
+	if remote {
+		header += "\tREMOTE"
+	}
+	fmt.Fprintln(w, header)
+	for _, e := range entries {
+		row := strings.Join([]string{e.Name, orDash(e.Version), orDash(e.Language), e.Source, formatSize(e.Size), formatTimeAgo(listTime(e))}, "\t")
+		if remote {
+			row += "\t" + describeListRemote(e)
+		}
+		fmt.Fprintln(w, row)
+	}
+	_ = w.Flush()
+}
+
+func describeListRemote(e listEntry) string {
+	switch {
+	case e.RemoteError != "":
+		return "error: " + e.RemoteError
+	case e.Remote == nil:
+		return "-"
+	case *e.Remote:
+		return "yes"
+	}
+	return "no"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// printListDetails prints each package's details, for --long
+func printListDetails(entries []listEntry) {
+	for _, e := range entries {
+		typeIndicator := "📦"
+		typeLabel := "pulled from " + e.Source
+		if e.Type == "local-build" {
+			typeIndicator = "🔨"
+			typeLabel = "local build"
+		}
+		fmt.Printf("%s %s\n", typeIndicator, e.Name)
+		fmt.Printf("   Type: %s\n", typeLabel)
+		if e.Version != "" {
+			fmt.Printf("   Version: %s\n", e.Version)
+		}
+		fmt.Printf("   Time: %s\n", formatTimeAgo(listTime(e)))
+		fmt.Printf("   Size: %s\n", formatSize(e.Size))
+		if e.Remote != nil || e.RemoteError != "" {
+			fmt.Printf("   Remote: %s\n", describeListRemote(e))
+		}
+
+		// Show language and dependencies if manifest is available
+		if m := e.manifest; m != nil {
+			if m.Language.Name != "" {
+				fmt.Printf("   %s\n", describeLanguages(m))
+			}
+			if d := e.Dependencies; d != nil && (d.Runtime > 0 || d.Dev > 0) {
+				depStr := fmt.Sprintf("%d runtime", d.Runtime)
+				if d.Dev > 0 {
+					depStr += fmt.Sprintf(", %d dev", d.Dev)
+				}
+				fmt.Printf("   Dependencies: %s\n", depStr)
+			}
+			printMetadata(m.Metadata, "   ")
+		}
+
+		fmt.Println()
+	}
+}
+
+// printMetadata prints the package metadata fields that are set, one per
+// line
+func printMetadata(md manifest.Metadata, indent string) {
+	if md.License != "" {
+		fmt.Printf("%sLicense: %s\n", indent, md.License)
+	}
+	if md.Repository != "" {
+		fmt.Printf("%sRepository: %s\n", indent, md.Repository)
+	}
+	if md.Homepage != "" {
+		fmt.Printf("%sHomepage: %s\n", indent, md.Homepage)
+	}
+	if len(md.Keywords) > 0 {
+		fmt.Printf("%sKeywords: %s\n", indent, strings.Join(md.Keywords, ", "))
 	}
 }
 
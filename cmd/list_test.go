@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func TestNewListEntry(t *testing.T) {
+	pulled := newListEntry(docker.CachedImage{
+		Name:     "ghcr.io/org/utils:1.2.0",
+		Type:     "registry-pull",
+		Manifest: &manifest.Manifest{Name: "utils", Version: "1.2.0", Language: manifest.Language{Name: "python"}},
+	})
+	if pulled.Package != "utils" || pulled.Version != "1.2.0" || pulled.Source != "ghcr.io" || pulled.Language != "python" {
+		t.Errorf("pulled entry = %+v", pulled)
+	}
+	if pulled.Time != nil || pulled.Metadata != nil {
+		t.Errorf("unknown time and empty metadata should be left out: %+v", pulled)
+	}
+
+	// Without an aigogo.json, the name and version come from the reference
+	local := newListEntry(docker.CachedImage{Name: "agents:0.3.0", Type: "local-build", BuildTime: time.Now()})
+	if local.Package != "agents" || local.Version != "0.3.0" || local.Source != "local" || local.Time == nil {
+		t.Errorf("local entry = %+v", local)
+	}
+}
+
+func TestListFilters(t *testing.T) {
+	entries := []listEntry{
+		newListEntry(docker.CachedImage{Name: "docker.io/org/utils:1.0.0", Type: "registry-pull", Manifest: &manifest.Manifest{Name: "utils", Language: manifest.Language{Name: "python"}}}),
+		newListEntry(docker.CachedImage{Name: "agents:2.0.0", Type: "local-build", Manifest: &manifest.Manifest{Name: "agents", Language: manifest.Language{Name: "javascript"}}}),
+		newListEntry(docker.CachedImage{Name: "helpers:2.1.0", Type: "local-build"}),
+	}
+	tests := []struct {
+		filters  []string
+		language string
+		want     []string
+	}{
+		{nil, "", []string{"docker.io/org/utils:1.0.0", "agents:2.0.0", "helpers:2.1.0"}},
+		{[]string{"name=util*"}, "", []string{"docker.io/org/utils:1.0.0"}},
+		{[]string{"name=docker.io/org/*"}, "", []string{"docker.io/org/utils:1.0.0"}},
+		{[]string{"type=local", "version=2.*"}, "", []string{"agents:2.0.0", "helpers:2.1.0"}},
+		{nil, "javascript", []string{"agents:2.0.0"}},
+	}
+	for _, tt := range tests {
+		var filters []listFilter
+		for _, s := range tt.filters {
+			f, err := parseListFilter(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filters = append(filters, f)
+		}
+		var got []string
+		for _, e := range entries {
+			if e.matches(filters, tt.language) {
+				got = append(got, e.Name)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("filters %q, language %q = %q, want %q", tt.filters, tt.language, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"name", "owner=me", "type=remote", "name=[a"} {
+		if _, err := parseListFilter(bad); err == nil {
+			t.Errorf("parseListFilter(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestSortListEntries(t *testing.T) {
+	now := time.Now()
+	older := now.Add(-time.Hour)
+	entries := []listEntry{
+		{Name: "b", Size: 10, Time: &older},
+		{Name: "c", Size: 30},
+		{Name: "a", Size: 10, Time: &now},
+	}
+	tests := map[string][]string{
+		"name": {"a", "b", "c"},
+		"date": {"a", "b", "c"},
+		"size": {"c", "a", "b"},
+	}
+	for by, want := range tests {
+		sortListEntries(entries, by)
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.Name)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("sort by %s = %q, want %q", by, got, want)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/aupeachmo/aigogo/pkg/auth"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
 	"golang.org/x/term"
 )
 
@@ -16,17 +17,29 @@ func loginCmd() *Command {
 	username := flags.String("u", "", "Username")
 	passwordStdin := flags.Bool("p", false, "Read password from stdin (prevents password in shell history)")
 	dockerhub := flags.Bool("dockerhub", false, "Use Docker Hub (docker.io) as registry")
+	encrypt := flags.Bool("encrypt", false, "Encrypt auth.json with a passphrase (see auth.key_command); without a registry, encrypt the stored credentials")
+	decrypt := flags.Bool("decrypt", false, "Store auth.json unencrypted; without a registry, decrypt the stored credentials")
 
 	return &Command{
 		Name:        "login",
 		Description: "Login to a container registry",
 		Flags:       flags,
 		Run: func(args []string) error {
+			if *encrypt && *decrypt {
+				return errcode.Errorf(errcode.Usage, "--encrypt and --decrypt can't be used together")
+			}
+			if *encrypt || *decrypt {
+				auth.SetEncrypt(encrypt)
+				if len(args) == 0 && !*dockerhub {
+					return rewriteCredentials(*encrypt)
+				}
+			}
+
 			var registry string
 			if *dockerhub {
 				registry = "docker.io"
 			} else if len(args) < 1 {
-				return fmt.Errorf("usage: aigg login <registry> [-u username] [-p] [--dockerhub]")
+				return errcode.Errorf(errcode.Usage, "usage: aigg login <registry> [-u username] [-p] [--dockerhub] [--encrypt | --decrypt]\n       aigg login --encrypt | --decrypt")
 			} else {
 				registry = args[0]
 			}
@@ -63,27 +76,98 @@ func loginCmd() *Command {
 			// Store credentials
 			authManager := auth.NewManager()
 			if err := authManager.Login(registry, user, pass); err != nil {
-				return fmt.Errorf("login failed: %w", err)
+				return errcode.Errorf(errcode.Auth, "login failed: %w", err)
 			}
 
-			fmt.Printf("Successfully logged in to %s\n", registry)
+			if encrypted, _ := authManager.Encrypted(); encrypted {
+				fmt.Printf("Successfully logged in to %s (credentials encrypted)\n", registry)
+			} else {
+				fmt.Printf("Successfully logged in to %s\n", registry)
+			}
 			return nil
 		},
 	}
 }
 
+// rewriteCredentials writes the stored credentials again, encrypted when
+// encrypt is set and unencrypted otherwise
+func rewriteCredentials(encrypt bool) error {
+	authManager := auth.NewManager()
+	registries, err := authManager.Registries()
+	if err != nil {
+		return errcode.Errorf(errcode.Auth, "failed to read credentials: %w", err)
+	}
+	if len(registries) == 0 {
+		fmt.Println("Not logged in to any registry")
+		return nil
+	}
+	if err := authManager.Rewrite(); err != nil {
+		return errcode.Errorf(errcode.Auth, "failed to write credentials: %w", err)
+	}
+	if encrypt {
+		fmt.Printf("✓ Encrypted the credentials for %s\n", strings.Join(registries, ", "))
+	} else {
+		fmt.Printf("✓ Decrypted the credentials for %s\n", strings.Join(registries, ", "))
+	}
+	return nil
+}
+
+// promptPassphrase asks for the passphrase of auth.json on the terminal,
+// twice when confirm is set as it's a new one
+func promptPassphrase(confirm bool) (string, error) {
+	fmt.Fprint(os.Stderr, "Passphrase for auth.json: ")
+	pass, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat the passphrase: ")
+		again, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		if string(again) != string(pass) {
+			return "", fmt.Errorf("the passphrases don't match")
+		}
+	}
+	return string(pass), nil
+}
+
 func logoutCmd() *Command {
+	flags := flag.NewFlagSet("logout", flag.ExitOnError)
+	all := flags.Bool("all", false, "Remove the credentials of every registry")
+
 	return &Command{
 		Name:        "logout",
 		Description: "Logout from a container registry",
+		Flags:       flags,
 		Run: func(args []string) error {
+			authManager := auth.NewManager()
+			if *all {
+				if len(args) > 0 {
+					return errcode.Errorf(errcode.Usage, "usage: aigg logout --all (without a registry)")
+				}
+				registries, err := authManager.LogoutAll()
+				if err != nil {
+					return fmt.Errorf("logout failed: %w", err)
+				}
+				if len(registries) == 0 {
+					fmt.Println("Not logged in to any registry")
+					return nil
+				}
+				for _, registry := range registries {
+					fmt.Printf("Successfully logged out from %s\n", registry)
+				}
+				return nil
+			}
 			if len(args) < 1 {
-				return fmt.Errorf("usage: aigg logout <registry>")
+				return errcode.Errorf(errcode.Usage, "usage: aigg logout <registry> | --all")
 			}
 
 			registry := args[0]
 
-			authManager := auth.NewManager()
 			if err := authManager.Logout(registry); err != nil {
 				return fmt.Errorf("logout failed: %w", err)
 			}
@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// saveManifest saves an updated manifest, noting when the comments and
+// trailing commas it was loaded with are dropped
+func saveManifest(path string, m *manifest.Manifest) error {
+	if err := manifest.Save(path, m); err != nil {
+		return err
+	}
+	if m.HasComments() {
+		fmt.Printf("Note: %s was rewritten as plain JSON; its comments and trailing commas were removed\n", filepath.Base(path))
+	}
+	return nil
+}
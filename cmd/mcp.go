@@ -0,0 +1,512 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/mcp"
+	"golang.org/x/term"
+)
+
+// mcpInstructions tell agents how aigg mcp's tools fit together
+const mcpInstructions = `aigogo packages are reusable code snippets, referenced as registry/name:tag, by their name in aigogo.lock, or by sha256:hash. ` +
+	`Find packages with search_packages or list_packages, read them with get_package_info and get_package_readme, and compare versions with diff_versions before upgrading. ` +
+	`add_package only records a package in aigogo.lock; the user runs 'aigg install' to link it into the project.`
+
+func mcpCmd() *Command {
+	flags := flag.NewFlagSet("mcp", flag.ContinueOnError)
+	readOnly := flags.Bool("read-only", false, "Don't offer add_package, so agents can only read packages")
+	offline := flags.Bool("offline", false, "Only read packages that are local, without contacting registries")
+
+	return &Command{
+		Name:        "mcp",
+		Description: "Serve aigogo packages to coding agents over the Model Context Protocol on stdio",
+		Flags:       flags,
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return errcode.Errorf(errcode.Usage, "unexpected argument: %s", args[0])
+			}
+			if term.IsTerminal(int(os.Stdin.Fd())) {
+				fmt.Fprintln(os.Stderr, "aigg mcp speaks the Model Context Protocol on stdin and stdout; add it to your coding agent as an MCP server with the command 'aigg mcp'")
+			}
+
+			server := newMCPServer(func() (*aigogo.Client, error) {
+				client, err := newClient()
+				if err != nil {
+					return nil, err
+				}
+				// stdout carries the protocol, so nothing else may write to
+				// it or read stdin
+				client.SetInput(strings.NewReader(""))
+				client.SetOutput(os.Stderr, os.Stderr)
+				client.SetLogger(stderrLogger{})
+				client.SetOffline(*offline)
+				return client, nil
+			}, mcpOptions{readOnly: *readOnly, offline: *offline})
+			err := server.Serve(commandContext(), os.Stdin, stdoutFile)
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return err
+		},
+	}
+}
+
+// mcpOptions are the flags of aigg mcp that change its tools
+type mcpOptions struct {
+	readOnly bool // don't offer add_package
+	offline  bool // don't list registry tags
+}
+
+// mcpTools runs aigg mcp's tools. Each call gets its own client, as
+// newClient returns them.
+type mcpTools struct {
+	newClient func() (*aigogo.Client, error)
+	opts      mcpOptions
+}
+
+// newMCPServer returns the MCP server of aigg mcp, reading and adding
+// packages through the clients newClient returns
+func newMCPServer(newClient func() (*aigogo.Client, error), opts mcpOptions) *mcp.Server {
+	t := &mcpTools{newClient: newClient, opts: opts}
+	server := mcp.NewServer("aigogo", version)
+	server.SetInstructions(mcpInstructions)
+
+	readOnly := &mcp.Annotations{ReadOnly: true, Idempotent: true, OpenWorld: !opts.offline}
+	server.AddTool(mcp.Tool{
+		Name:  "search_packages",
+		Title: "Search packages",
+		Description: "Search the packages in the local cache and the project's aigogo.lock by name, description, keywords, tags and AI summary and capabilities. " +
+			"Give a repository (registry/name) to also list the versions pushed to it.",
+		InputSchema: mcpSchema(map[string]interface{}{
+			"query":      mcpString("Words that must all appear in the package's metadata; empty matches every package"),
+			"repository": mcpString("A registry repository, e.g. ghcr.io/org/utils, whose tags to list"),
+		}),
+		Annotations: readOnly,
+		Run:         t.searchPackages,
+	})
+	server.AddTool(mcp.Tool{
+		Name:        "list_packages",
+		Title:       "List packages",
+		Description: "List the packages of the project's aigogo.lock and of the local cache.",
+		InputSchema: mcpSchema(map[string]interface{}{}),
+		Annotations: &mcp.Annotations{ReadOnly: true, Idempotent: true},
+		Run:         t.listPackages,
+	})
+	server.AddTool(mcp.Tool{
+		Name:        "get_package_info",
+		Title:       "Get package info",
+		Description: "Get a package's aigogo.json, including its AI summary, usage, inputs and outputs, its files with their sizes, and whether it is deprecated.",
+		InputSchema: mcpSchema(map[string]interface{}{
+			"ref": mcpString("The package: registry/name:tag, a name in aigogo.lock, a local build name:tag, or sha256:hash"),
+		}, "ref"),
+		Annotations: readOnly,
+		Run:         t.getPackageInfo,
+	})
+	server.AddTool(mcp.Tool{
+		Name:        "get_package_readme",
+		Title:       "Get package README",
+		Description: "Get a package's README as Markdown.",
+		InputSchema: mcpSchema(map[string]interface{}{
+			"ref": mcpString("The package: registry/name:tag, a name in aigogo.lock, a local build name:tag, or sha256:hash"),
+		}, "ref"),
+		Annotations: readOnly,
+		Run:         t.getPackageReadme,
+	})
+	server.AddTool(mcp.Tool{
+		Name:        "diff_versions",
+		Title:       "Diff package versions",
+		Description: "Compare the files of two packages, typically two versions of one, as unified diffs. Use summary to get only which files changed and by how many lines.",
+		InputSchema: mcpSchema(map[string]interface{}{
+			"from":    mcpString("The older package, as for get_package_info"),
+			"to":      mcpString("The newer package, as for get_package_info"),
+			"summary": map[string]interface{}{"type": "boolean", "description": "Leave out the unified diffs"},
+			"context": map[string]interface{}{"type": "integer", "minimum": 0, "description": "Lines of context around changes (default 3)"},
+		}, "from", "to"),
+		Annotations: readOnly,
+		Run:         t.diffVersions,
+	})
+	if !opts.readOnly {
+		server.AddTool(mcp.Tool{
+			Name:  "add_package",
+			Title: "Add package",
+			Description: "Add a package to the project's aigogo.lock and the package store, as 'aigg add' does. " +
+				"Packages that are deprecated or don't support this machine's environment are refused. " +
+				"No package scripts run; the user runs 'aigg install' to link added packages into the project.",
+			InputSchema: mcpSchema(map[string]interface{}{
+				"ref":              mcpString("The package: registry/name:tag, optionally selecting extras as name:tag[extra], or a local build name:tag"),
+				"group":            map[string]interface{}{"type": "string", "enum": []string{lockfile.GroupDev, lockfile.GroupOptional}, "description": "Lock it as a dev or optional package instead of a runtime one"},
+				"allow_deprecated": map[string]interface{}{"type": "boolean", "description": "Add it even if it is deprecated, with a warning"},
+			}, "ref"),
+			Annotations: &mcp.Annotations{OpenWorld: !opts.offline},
+			Run:         t.addPackage,
+		})
+	}
+	return server
+}
+
+// mcpSchema returns the JSON schema of a tool's arguments
+func mcpSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func mcpString(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description}
+}
+
+// decodeMCPArgs decodes a tool's arguments into v, refusing arguments the
+// tool doesn't take
+func decodeMCPArgs(args json.RawMessage, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(args))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return errcode.Errorf(errcode.Usage, "invalid arguments: %v", err)
+	}
+	return nil
+}
+
+// mcpPackage is a package as search_packages and list_packages return it
+type mcpPackage struct {
+	Ref          string   `json:"ref"` // what to pass to the other tools
+	Name         string   `json:"name"`
+	Version      string   `json:"version,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	Language     string   `json:"language,omitempty"`
+	Summary      string   `json:"summary,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+	Keywords     []string `json:"keywords,omitempty"`
+	Source       string   `json:"source"` // "aigogo.lock" or "local cache"
+	Deprecated   string   `json:"deprecated,omitempty"`
+	Problem      string   `json:"problem,omitempty"` // why its aigogo.json couldn't be read
+}
+
+func newMCPPackage(ref, source string, m *manifest.Manifest) mcpPackage {
+	p := mcpPackage{Ref: ref, Name: ref, Source: source}
+	if m == nil {
+		return p
+	}
+	if m.Name != "" {
+		p.Name = m.Name
+	}
+	p.Version = m.Version
+	p.Description = m.Description
+	p.Language = m.Language.Name
+	if m.AI != nil {
+		p.Summary = m.AI.Summary
+		p.Capabilities = m.AI.Capabilities
+	}
+	p.Keywords = append(append([]string{}, m.Metadata.Keywords...), m.Metadata.Tags...)
+	if len(p.Keywords) == 0 {
+		p.Keywords = nil
+	}
+	if m.Deprecated != nil {
+		p.Deprecated = m.Deprecated.Message
+	}
+	return p
+}
+
+// matches reports whether every word of query appears in the package's
+// metadata, ignoring case
+func (p mcpPackage) matches(query string) bool {
+	text := strings.ToLower(strings.Join(append([]string{p.Ref, p.Name, p.Description, p.Summary},
+		append(p.Capabilities, p.Keywords...)...), "\n"))
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// localPackages returns the packages of the project's aigogo.lock, then
+// those of the local cache, each sorted by name
+func (t *mcpTools) localPackages() (locked, cached []mcpPackage, err error) {
+	client, err := t.newClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	lockPath, lock, err := lockfile.FindLockFileFrom(client.Dir())
+	if err != nil && errcode.Of(err) != errcode.NotFound {
+		return nil, nil, err
+	}
+	if lock != nil {
+		projectDir := filepath.Dir(lockPath)
+		cas, err := aigogo.OpenStore(loadProjectSettings(projectDir))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open package store: %w", err)
+		}
+		for name, pkg := range lock.Packages {
+			m, problem := aigogo.LockedManifest(cas, projectDir, pkg)
+			p := newMCPPackage(name, "aigogo.lock", m)
+			if p.Version == "" {
+				p.Version = pkg.Version
+			}
+			p.Problem = problem
+			locked = append(locked, p)
+		}
+	}
+
+	images, err := docker.NewLister().ListDetailed()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	for _, img := range images {
+		cached = append(cached, newMCPPackage(img.Name, "local cache", img.Manifest))
+	}
+
+	for _, pkgs := range [][]mcpPackage{locked, cached} {
+		sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Ref < pkgs[j].Ref })
+	}
+	return locked, cached, nil
+}
+
+func (t *mcpTools) searchPackages(ctx context.Context, args json.RawMessage) (*mcp.Result, error) {
+	var in struct {
+		Query      string `json:"query"`
+		Repository string `json:"repository"`
+	}
+	if err := decodeMCPArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.Repository != "" && t.opts.offline {
+		return nil, errcode.Errorf(errcode.Usage, "aigg mcp is offline, so registry tags can't be listed")
+	}
+
+	locked, cached, err := t.localPackages()
+	if err != nil {
+		return nil, err
+	}
+	out := struct {
+		Packages []mcpPackage `json:"packages"`
+		Tags     []string     `json:"tags,omitempty"` // of the repository
+	}{Packages: []mcpPackage{}}
+	for _, p := range append(locked, cached...) {
+		if p.matches(in.Query) {
+			out.Packages = append(out.Packages, p)
+		}
+	}
+	if in.Repository != "" {
+		tags, err := docker.NewPuller().Tags(ctx, in.Repository)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the tags of %s: %w", in.Repository, err)
+		}
+		out.Tags = tags
+	}
+	return mcp.JSONResult(out)
+}
+
+func (t *mcpTools) listPackages(ctx context.Context, args json.RawMessage) (*mcp.Result, error) {
+	var in struct{}
+	if err := decodeMCPArgs(args, &in); err != nil {
+		return nil, err
+	}
+	locked, cached, err := t.localPackages()
+	if err != nil {
+		return nil, err
+	}
+	if locked == nil {
+		locked = []mcpPackage{}
+	}
+	if cached == nil {
+		cached = []mcpPackage{}
+	}
+	return mcp.JSONResult(map[string][]mcpPackage{"locked": locked, "cached": cached})
+}
+
+// openMCPPackage opens the package the ref argument names, and reads its
+// aigogo.json
+func (t *mcpTools) openMCPPackage(ctx context.Context, args json.RawMessage) (*aigogo.Package, *manifest.Manifest, []byte, error) {
+	var in struct {
+		Ref string `json:"ref"`
+	}
+	if err := decodeMCPArgs(args, &in); err != nil {
+		return nil, nil, nil, err
+	}
+	if in.Ref == "" {
+		return nil, nil, nil, errcode.Errorf(errcode.Usage, "ref is required")
+	}
+	client, err := t.newClient()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	pkg, err := client.OpenPackage(ctx, in.Ref, false)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	data, err := pkg.ReadFile("aigogo.json")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read aigogo.json of %s: %w", in.Ref, err)
+	}
+	var m manifest.Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse aigogo.json of %s: %w", in.Ref, err)
+	}
+	return pkg, &m, data, nil
+}
+
+func (t *mcpTools) getPackageInfo(ctx context.Context, args json.RawMessage) (*mcp.Result, error) {
+	pkg, m, data, err := t.openMCPPackage(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	files, err := pkg.Files()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the files of %s: %w", pkg.Ref, err)
+	}
+	if files == nil {
+		files = []docker.PackageFile{}
+	}
+	out := struct {
+		Ref        string                `json:"ref"`
+		Source     string                `json:"source"`
+		Manifest   json.RawMessage       `json:"manifest"`
+		Files      []docker.PackageFile  `json:"files"`
+		Deprecated *manifest.Deprecation `json:"deprecated,omitempty"`
+	}{Ref: pkg.Ref, Source: pkg.Source, Manifest: data, Files: files}
+	if !t.opts.offline {
+		out.Deprecated = aigogo.PackageDeprecation(ctx, pkg.Ref, m)
+	} else {
+		out.Deprecated = m.Deprecated
+	}
+	return mcp.JSONResult(out)
+}
+
+func (t *mcpTools) getPackageReadme(ctx context.Context, args json.RawMessage) (*mcp.Result, error) {
+	pkg, m, _, err := t.openMCPPackage(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	file := m.Readme
+	if file == "" {
+		file = defaultReadme
+	}
+	data, err := pkg.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		if m.Readme == "" {
+			return nil, errcode.Errorf(errcode.NotFound, "%s has no README; its aigogo.json describes it", pkg.Ref)
+		}
+		return nil, errcode.Errorf(errcode.NotFound, "%s declares readme %s but the package doesn't contain it", pkg.Ref, m.Readme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return mcp.TextResult(string(data)), nil
+}
+
+func (t *mcpTools) diffVersions(ctx context.Context, args json.RawMessage) (*mcp.Result, error) {
+	var in struct {
+		From    string `json:"from"`
+		To      string `json:"to"`
+		Summary bool   `json:"summary"`
+		Context *int   `json:"context"`
+	}
+	if err := decodeMCPArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.From == "" || in.To == "" {
+		return nil, errcode.Errorf(errcode.Usage, "from and to are required")
+	}
+	opts := aigogo.DiffOptions{}
+	if in.Context != nil {
+		if *in.Context < 0 {
+			return nil, errcode.Errorf(errcode.Usage, "context can't be negative")
+		}
+		opts.Context = *in.Context
+		if opts.Context == 0 {
+			opts.Context = -1
+		}
+	}
+
+	client, err := t.newClient()
+	if err != nil {
+		return nil, err
+	}
+	result, err := client.Diff(ctx, in.From, in.To, opts)
+	if err != nil {
+		return nil, err
+	}
+	if in.Summary {
+		for i := range result.Files {
+			result.Files[i].Unified = ""
+		}
+	}
+	return mcp.JSONResult(result)
+}
+
+func (t *mcpTools) addPackage(ctx context.Context, args json.RawMessage) (*mcp.Result, error) {
+	var in struct {
+		Ref             string `json:"ref"`
+		Group           string `json:"group"`
+		AllowDeprecated bool   `json:"allow_deprecated"`
+	}
+	if err := decodeMCPArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.Ref == "" {
+		return nil, errcode.Errorf(errcode.Usage, "ref is required")
+	}
+	if in.Group != "" && in.Group != lockfile.GroupDev && in.Group != lockfile.GroupOptional {
+		return nil, errcode.Errorf(errcode.Usage, "invalid group: %s (expected %s or %s)", in.Group, lockfile.GroupDev, lockfile.GroupOptional)
+	}
+	if aigogo.LooksLikeLocalPath(in.Ref) {
+		// A path would let an agent lock any directory on the machine
+		return nil, errcode.Errorf(errcode.Usage, "add_package takes package references, not paths: %s", in.Ref)
+	}
+	// An invalid ref is left to the client to refuse
+	if t.opts.offline && !docker.IsLocalReference(in.Ref) && docker.ValidateCacheRef(in.Ref) == nil && docker.GetCachePath(in.Ref) == "" {
+		return nil, errcode.Errorf(errcode.NotFound, "package %s not found in local cache, and aigg mcp is offline", in.Ref)
+	}
+
+	client, err := t.newClient()
+	if err != nil {
+		return nil, err
+	}
+	// Warnings, such as for a deprecated package, go back to the agent
+	var warnings bytes.Buffer
+	client.SetOutput(&warnings, &warnings)
+	result, err := client.AddPackage(ctx, in.Ref, aigogo.AddOptions{Strict: !in.AllowDeprecated, Group: in.Group})
+	if err != nil {
+		return nil, err
+	}
+
+	type added struct {
+		Name     string `json:"name"`
+		Version  string `json:"version"`
+		LockName string `json:"lock_name"`
+		Source   string `json:"source"`
+		Files    int    `json:"files"`
+	}
+	out := struct {
+		LockFile string   `json:"lock_file"`
+		Added    []added  `json:"added"`
+		Warnings []string `json:"warnings,omitempty"`
+		Next     string   `json:"next"`
+	}{LockFile: result.LockPath, Next: "run 'aigg install' to link the package into the project"}
+	for _, a := range result.Added {
+		out.Added = append(out.Added, added{Name: a.Name, Version: a.Version, LockName: a.LockName, Source: a.Locked.Source, Files: a.Files})
+	}
+	for _, line := range strings.Split(warnings.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out.Warnings = append(out.Warnings, line)
+		}
+	}
+	return mcp.JSONResult(out)
+}
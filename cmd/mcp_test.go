@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/mcp"
+	"github.com/aupeachmo/aigogo/pkg/store"
+)
+
+// callMCPTool calls a tool of server and returns its text result and
+// whether it failed
+func callMCPTool(t *testing.T, server *mcp.Server, name string, args string) (string, bool) {
+	t.Helper()
+	request := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + name + `","arguments":` + args + `}}`
+	var out bytes.Buffer
+	if err := server.Serve(context.Background(), strings.NewReader(request+"\n"), &out); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	var resp struct {
+		Result *mcp.Result `json:"result"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil || resp.Result == nil || len(resp.Result.Content) != 1 {
+		t.Fatalf("%s(%s) = %s, %v", name, args, out.String(), err)
+	}
+	return resp.Result.Content[0].Text, resp.Result.IsError
+}
+
+func TestMCPTools(t *testing.T) {
+	setHome(t, t.TempDir())
+	t.Setenv("AIGOGO_STORE", "")
+	project := t.TempDir()
+
+	src := t.TempDir()
+	manifestData := []byte(`{"name": "utils", "version": "1.0.0", "description": "String helpers", "readme": "README.md",
+		"language": {"name": "python", "version": ">=3.8"},
+		"ai": {"summary": "Slugify titles", "capabilities": ["make URL slugs"]}}`)
+	files := map[string]string{"aigogo.json": string(manifestData), "utils.py": "def slug(): pass\n", "README.md": "# utils\n"}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cas, err := store.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := cas.Store(context.Background(), src, []string{"README.md", "aigogo.json", "utils.py"}, manifestData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock := lockfile.New()
+	lock.Add("utils", lockfile.LockedPackage{Version: "1.0.0", Integrity: "sha256:" + hash, Source: "docker.io/org/utils:1.0.0"})
+	if err := lockfile.Save(filepath.Join(project, lockfile.LockFileName), lock); err != nil {
+		t.Fatal(err)
+	}
+
+	server := newMCPServer(func() (*aigogo.Client, error) {
+		client := aigogo.NewClient(project)
+		client.SetOffline(true)
+		return client, nil
+	}, mcpOptions{offline: true})
+
+	text, failed := callMCPTool(t, server, "list_packages", `{}`)
+	if failed || !strings.Contains(text, `"ref": "utils"`) || !strings.Contains(text, `"summary": "Slugify titles"`) {
+		t.Errorf("list_packages = %s", text)
+	}
+	if text, _ := callMCPTool(t, server, "search_packages", `{"query": "url SLUGS"}`); !strings.Contains(text, `"ref": "utils"`) {
+		t.Errorf("search_packages matching the capabilities = %s", text)
+	}
+	if text, _ := callMCPTool(t, server, "search_packages", `{"query": "yaml"}`); strings.Contains(text, `"ref": "utils"`) {
+		t.Errorf("search_packages not matching = %s", text)
+	}
+	if text, failed := callMCPTool(t, server, "search_packages", `{"repository": "ghcr.io/org/utils"}`); !failed || !strings.Contains(text, "offline") {
+		t.Errorf("search_packages of a repository offline = %s, want an error", text)
+	}
+
+	text, failed = callMCPTool(t, server, "get_package_info", `{"ref": "utils"}`)
+	if failed || !strings.Contains(text, `"path": "utils.py"`) || !strings.Contains(text, `"summary": "Slugify titles"`) {
+		t.Errorf("get_package_info = %s", text)
+	}
+	if text, failed := callMCPTool(t, server, "get_package_readme", `{"ref": "sha256:`+hash+`"}`); failed || text != "# utils\n" {
+		t.Errorf("get_package_readme = %q", text)
+	}
+	if text, failed := callMCPTool(t, server, "get_package_readme", `{"ref": "other"}`); !failed || !strings.Contains(text, "not found") {
+		t.Errorf("get_package_readme of a missing package = %s, want an error", text)
+	}
+	if text, failed := callMCPTool(t, server, "get_package_info", `{"ref": "utils", "extra": true}`); !failed || !strings.Contains(text, "invalid arguments") {
+		t.Errorf("get_package_info with an unknown argument = %s, want an error", text)
+	}
+
+	text, failed = callMCPTool(t, server, "diff_versions", `{"from": "utils", "to": "sha256:`+hash+`", "summary": true}`)
+	if failed || !strings.Contains(text, `"unchanged": 3`) {
+		t.Errorf("diff_versions = %s", text)
+	}
+
+	// Refs naming directories outside the store or the cache are refused
+	for _, call := range []struct{ tool, args string }{
+		{"get_package_info", `{"ref": "sha256:../.."}`},
+		{"get_package_readme", `{"ref": "sha256:../outside"}`},
+		{"get_package_info", `{"ref": ".."}`},
+		{"diff_versions", `{"from": "utils", "to": "sha256:../.."}`},
+		{"diff_versions", `{"from": "..", "to": "utils"}`},
+		{"add_package", `{"ref": "sha256:../.."}`},
+	} {
+		if text, failed := callMCPTool(t, server, call.tool, call.args); !failed || !strings.Contains(text, "invalid") {
+			t.Errorf("%s %s = %s, want an error", call.tool, call.args, text)
+		}
+	}
+
+	// add_package refuses paths, and with --offline packages that aren't
+	// cached
+	if text, failed := callMCPTool(t, server, "add_package", `{"ref": "../elsewhere"}`); !failed || !strings.Contains(text, "not paths") {
+		t.Errorf("add_package of a path = %s, want an error", text)
+	}
+	if text, failed := callMCPTool(t, server, "add_package", `{"ref": "ghcr.io/org/other:1.0.0"}`); !failed || !strings.Contains(text, "offline") {
+		t.Errorf("add_package offline = %s, want an error", text)
+	}
+	if text, failed := callMCPTool(t, server, "add_package", `{"ref": "utils:1.0.0", "group": "test"}`); !failed || !strings.Contains(text, "invalid group") {
+		t.Errorf("add_package with a bad group = %s, want an error", text)
+	}
+}
+
+func TestMCPReadOnly(t *testing.T) {
+	names := func(opts mcpOptions) string {
+		var out bytes.Buffer
+		request := `{"jsonrpc":"2.0","id":1,"method":"tools/list"}` + "\n"
+		if err := newMCPServer(nil, opts).Serve(context.Background(), strings.NewReader(request), &out); err != nil {
+			t.Fatal(err)
+		}
+		return out.String()
+	}
+	if !strings.Contains(names(mcpOptions{}), `"add_package"`) {
+		t.Error("add_package missing from the tools")
+	}
+	if list := names(mcpOptions{readOnly: true}); strings.Contains(list, `"add_package"`) || !strings.Contains(list, `"diff_versions"`) {
+		t.Errorf("--read-only tools = %s, want all but add_package", list)
+	}
+}
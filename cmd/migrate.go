@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/history"
+	"github.com/aupeachmo/aigogo/pkg/userdirs"
+)
+
+func migrateCmd() *Command {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "Show what would be moved without moving anything")
+
+	return &Command{
+		Name:        "migrate",
+		Description: "Move ~/.aigogo to the XDG config, data and cache directories",
+		Flags:       flags,
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return errcode.Errorf(errcode.Usage, "usage: aigg migrate [--dry-run]")
+			}
+			return migrate(*dryRun)
+		},
+	}
+}
+
+// migration is what becomes of an entry of ~/.aigogo: it moves to dest, or
+// is removed when dest is empty
+type migration struct {
+	name string
+	dest string
+	why  string // Why a removed entry isn't moved
+}
+
+// migrations returns what becomes of each entry aigg keeps in ~/.aigogo,
+// given the XDG config, data and cache directories
+func migrations(config, data, cache string) []migration {
+	return []migration{
+		{name: "config.toml", dest: filepath.Join(config, "config.toml")},
+		{name: "auth.json", dest: filepath.Join(config, "auth.json")},
+		{name: "templates", dest: filepath.Join(config, "templates")},
+		{name: history.FileName, dest: filepath.Join(data, history.FileName)},
+		{name: "store", dest: filepath.Join(data, "store")},
+		{name: "cache", dest: filepath.Join(cache, "packages")},
+		{name: completeCacheFile, dest: filepath.Join(cache, completeCacheFile)},
+		// Virtual environments have their own path written into them, so
+		// they can't move; aigg exec sets them up again
+		{name: "envs", why: "exec environments are set up again when needed"},
+		{name: "locks", why: "lock files are recreated when needed"},
+	}
+}
+
+// migrate moves the entries of ~/.aigogo to the XDG directories and
+// removes ~/.aigogo, after which aigg uses the XDG directories. Nothing is
+// moved while ~/.aigogo holds anything aigg doesn't know where to put, or a
+// destination already exists; a move that fails leaves the rest in place,
+// so migrate can run again.
+func migrate(dryRun bool) error {
+	if !userdirs.SupportsXDG() {
+		fmt.Printf("Nothing to migrate: aigg keeps its files in ~/%s on %s\n", userdirs.LegacyDirName, runtime.GOOS)
+		return nil
+	}
+	legacy, err := userdirs.LegacyDir()
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(legacy)
+	if os.IsNotExist(err) {
+		fmt.Printf("Nothing to migrate: %s doesn't exist\n", legacy)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", legacy, err)
+	}
+
+	config, data, cache, err := userdirs.XDGDirs()
+	if err != nil {
+		return err
+	}
+	known := map[string]migration{}
+	for _, m := range migrations(config, data, cache) {
+		known[m.name] = m
+	}
+
+	// Check everything before moving anything
+	var plan []migration
+	var unknown, conflicts []string
+	for _, entry := range entries {
+		m, ok := known[entry.Name()]
+		if !ok {
+			unknown = append(unknown, filepath.Join(legacy, entry.Name()))
+			continue
+		}
+		if m.dest != "" {
+			if _, err := os.Lstat(m.dest); err == nil {
+				conflicts = append(conflicts, m.dest)
+				continue
+			}
+		}
+		plan = append(plan, m)
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%s holds files aigg doesn't know where to put:\n  %s\nMove or remove them, then run 'aigg migrate' again", legacy, strings.Join(unknown, "\n  "))
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("already exists:\n  %s\nMerge it with its counterpart in %s by hand, then run 'aigg migrate' again", strings.Join(conflicts, "\n  "), legacy)
+	}
+
+	verb, removed := "Moved", "Removed"
+	if dryRun {
+		verb, removed = "Would move", "Would remove"
+	}
+	for _, m := range plan {
+		src := filepath.Join(legacy, m.name)
+		if m.dest == "" {
+			if !dryRun {
+				if err := os.RemoveAll(src); err != nil {
+					return fmt.Errorf("failed to remove %s: %w", src, err)
+				}
+			}
+			fmt.Printf("✓ %s %s (%s)\n", removed, src, m.why)
+			continue
+		}
+		if !dryRun {
+			if err := os.MkdirAll(filepath.Dir(m.dest), 0755); err != nil {
+				return fmt.Errorf("failed to create %s: %w", filepath.Dir(m.dest), err)
+			}
+			if err := os.Rename(src, m.dest); err != nil {
+				return fmt.Errorf("failed to move %s to %s: %w\nMove it by hand, then run 'aigg migrate' again", src, m.dest, err)
+			}
+		}
+		fmt.Printf("✓ %s %s → %s\n", verb, src, m.dest)
+	}
+
+	if dryRun {
+		fmt.Printf("✓ %s %s\n", removed, legacy)
+		return nil
+	}
+	if err := os.Remove(legacy); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", legacy, err)
+	}
+	fmt.Printf("✓ %s %s\n", removed, legacy)
+	fmt.Println()
+	fmt.Printf("aigg now keeps its files in %s, %s and %s\n", config, data, cache)
+	if slices.ContainsFunc(plan, func(m migration) bool { return m.name == "store" }) {
+		fmt.Println("Run 'aigg install' in each project to link its packages from the moved store")
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/userdirs"
+)
+
+func TestMigrate(t *testing.T) {
+	if !userdirs.SupportsXDG() {
+		t.Skip("aigg keeps ~/.aigogo on this system")
+	}
+	home := t.TempDir()
+	setHome(t, home)
+	legacy := filepath.Join(home, ".aigogo")
+	files := map[string]string{
+		"config.toml":                 "registry = \"ghcr.io/ourco\"\n",
+		"auth.json":                   "{}",
+		"history.jsonl":               "{}\n",
+		"store/sha256/ab/abc/files/x": "x",
+		"cache/utils_1.0.0/x":         "x",
+		"envs/abc/x":                  "x",
+		"locks/store-abc.lock":        "",
+	}
+	for name, content := range files {
+		path := filepath.Join(legacy, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A dry run moves nothing
+	if err := migrate(true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(legacy, "config.toml")); err != nil {
+		t.Fatalf("dry run moved config.toml: %v", err)
+	}
+
+	// Nothing moves while ~/.aigogo has something unknown
+	stray := filepath.Join(legacy, "notes.txt")
+	if err := os.WriteFile(stray, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate(false); err == nil || !strings.Contains(err.Error(), stray) {
+		t.Fatalf("migrate() error = %v, want %s named", err, stray)
+	}
+	if _, err := os.Stat(filepath.Join(legacy, "store")); err != nil {
+		t.Fatalf("store moved despite the stray file: %v", err)
+	}
+	if err := os.Remove(stray); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrate(false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Fatalf("~/.aigogo still exists: %v", err)
+	}
+	for _, path := range []string{
+		".config/aigogo/config.toml",
+		".config/aigogo/auth.json",
+		".local/share/aigogo/store/sha256/ab/abc/files/x",
+		".local/share/aigogo/history.jsonl",
+		".cache/aigogo/packages/utils_1.0.0/x",
+	} {
+		if _, err := os.Stat(filepath.Join(home, path)); err != nil {
+			t.Errorf("%s not moved: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(home, ".local/share/aigogo/envs")); !os.IsNotExist(err) {
+		t.Errorf("envs moved: %v", err)
+	}
+
+	// aigg now finds its files in the XDG directories
+	dir, err := userdirs.ConfigDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, ".config", "aigogo"); dir != want {
+		t.Errorf("ConfigDir() = %s after migrating, want %s", dir, want)
+	}
+	if err := migrate(false); err != nil {
+		t.Errorf("migrate() again error = %v", err)
+	}
+}
+
+func TestMigrateConflict(t *testing.T) {
+	if !userdirs.SupportsXDG() {
+		t.Skip("aigg keeps ~/.aigogo on this system")
+	}
+	home := t.TempDir()
+	setHome(t, home)
+	for _, path := range []string{".aigogo/auth.json", ".config/aigogo/auth.json"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(home, path)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(home, path), []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := migrate(false)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("migrate() error = %v, want the existing auth.json named", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".aigogo", "auth.json")); err != nil {
+		t.Errorf("auth.json moved: %v", err)
+	}
+}
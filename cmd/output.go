@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/ghactions"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+)
+
+// stdoutFile and stderrFile are the process's stdout and stderr. --plain
+// and --output github route os.Stdout and os.Stderr through pipes, so
+// whether output goes to a terminal is checked on these.
+var (
+	stdoutFile = os.Stdout
+	stderrFile = os.Stderr
+)
+
+// outputPipe is the write end of a pipe whose output is copied, as
+// rewritten, to stdout or stderr until done is closed
+type outputPipe struct {
+	w    *os.File
+	done chan struct{}
+}
+
+// outputPipes are the pipes os.Stdout and os.Stderr write to with --plain
+// or --output github
+var outputPipes []outputPipe
+
+// flushWriter is a writer that holds back part of what it's given until
+// Flush
+type flushWriter interface {
+	io.Writer
+	Flush() error
+}
+
+// setFilteredOutput routes everything written to os.Stdout and os.Stderr,
+// including by the logging package and child processes, through
+// ghactions.Writer with --output github, then logging.PlainWriter with
+// --plain, until flushOutput
+func setFilteredOutput() error {
+	for _, f := range []**os.File{&os.Stdout, &os.Stderr} {
+		r, w, err := os.Pipe()
+		if err != nil {
+			flushOutput()
+			return err
+		}
+		// Warnings are found before --plain replaces their marker
+		var out io.Writer = *f
+		var filters []flushWriter
+		if plainOutput {
+			pw := logging.NewPlainWriter(out)
+			out, filters = pw, append(filters, pw)
+		}
+		if githubOutput() {
+			gw := ghactions.NewWriter(out)
+			out, filters = gw, append(filters, gw)
+		}
+		done := make(chan struct{})
+		go func() {
+			_, _ = io.Copy(out, r)
+			for i := len(filters) - 1; i >= 0; i-- {
+				_ = filters[i].Flush()
+			}
+			_ = r.Close()
+			close(done)
+		}()
+		*f = w
+		outputPipes = append(outputPipes, outputPipe{w: w, done: done})
+	}
+	logging.SetOutput(os.Stdout, os.Stderr)
+	return nil
+}
+
+// flushOutput writes out what is still in the output pipes and puts
+// os.Stdout and os.Stderr back. It's called before aigg exits or replaces
+// itself, as output still in a pipe would be lost.
+func flushOutput() {
+	if len(outputPipes) == 0 {
+		return
+	}
+	for _, p := range outputPipes {
+		_ = p.w.Close()
+		<-p.done
+	}
+	outputPipes = nil
+	os.Stdout, os.Stderr = stdoutFile, stderrFile
+	logging.SetOutput(os.Stdout, os.Stderr)
+}
+
+// plainError is an error whose message is printed with ASCII markers
+type plainError struct {
+	err error
+}
+
+func (e plainError) Error() string { return logging.Plain(e.err.Error()) }
+
+func (e plainError) Unwrap() error { return e.err }
+
+// jsonError is how --json prints the error aigg fails with
+type jsonError struct {
+	Error struct {
+		Code     errcode.Code `json:"code"`
+		ExitCode int          `json:"exit_code"`
+		Message  string       `json:"message"`
+	} `json:"error"`
+}
+
+// PrintError prints the error aigg fails with to stderr, as JSON with
+// --json or an ::error annotation with --output github, and returns the exit
+// code for it
+func PrintError(err error) int {
+	code := errcode.Of(err)
+	if !jsonOutput {
+		if githubOutput() {
+			// A difference diff --exit-code reports is an answer, not a failure
+			level := ghactions.LevelError
+			if code == errcode.DiffFound {
+				level = ghactions.LevelWarning
+			}
+			fmt.Fprintln(os.Stderr, ghactions.Annotation{Level: level, Title: fmt.Sprintf("aigg: %s (exit code %d)", code, code.ExitCode()), Message: err.Error()})
+			return code.ExitCode()
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return code.ExitCode()
+	}
+
+	var out jsonError
+	out.Error.Code = code
+	out.Error.ExitCode = code.ExitCode()
+	out.Error.Message = err.Error()
+	enc := json.NewEncoder(os.Stderr)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(out)
+	return code.ExitCode()
+}
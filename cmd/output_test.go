@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/config"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+)
+
+func TestPlainOutput(t *testing.T) {
+	out, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = out.Close() }()
+
+	origStdout, origStderr, origFile := os.Stdout, os.Stderr, stdoutFile
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr, stdoutFile = origStdout, origStderr, origFile
+		logging.SetOutput(os.Stdout, os.Stderr)
+		plainOutput = false
+	})
+	os.Stdout, stdoutFile = out, out
+	plainOutput = true
+
+	if err := setFilteredOutput(); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println("✓ Installed 1 package")
+	logging.Printf("⚠️  Warning: %s\n", "no README")
+	flushOutput()
+
+	if os.Stdout != out {
+		t.Error("flushOutput() didn't restore os.Stdout")
+	}
+	data, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[ok] Installed 1 package\n[warn]  Warning: no README\n"; string(data) != want {
+		t.Errorf("printed %q, want %q", data, want)
+	}
+}
+
+func TestGitHubOutput(t *testing.T) {
+	out, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = out.Close() }()
+
+	origStdout, origStderr, origFile := os.Stdout, os.Stderr, stdoutFile
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr, stdoutFile = origStdout, origStderr, origFile
+		logging.SetOutput(os.Stdout, os.Stderr)
+		plainOutput, outputFormat = false, config.OutputText
+	})
+	os.Stdout, stdoutFile = out, out
+	plainOutput, outputFormat = true, config.OutputGitHub
+
+	if err := setFilteredOutput(); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println("✓ Installed 1 package")
+	fmt.Printf("⚠ Warning: 50%% of %s\n", "the files")
+	flushOutput()
+
+	data, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[ok] Installed 1 package\n::warning::50%25 of the files\n"; string(data) != want {
+		t.Errorf("printed %q, want %q", data, want)
+	}
+
+	// The error aigg fails with is an annotation too
+	os.Stderr = out
+	if exit := PrintError(errcode.Errorf(errcode.Validation, "validation failed\nFix it")); exit != 8 {
+		t.Errorf("PrintError() = %d, want 8", exit)
+	}
+	data, _ = os.ReadFile(out.Name())
+	if !strings.HasSuffix(string(data), "::error title=aigg%3A validation_failure (exit code 8)::validation failed%0AFix it\n") {
+		t.Errorf("printed %q", data)
+	}
+}
+
+func TestPlainError(t *testing.T) {
+	base := errors.New("not found")
+	err := plainError{fmt.Errorf("❌ lookup failed: %w", base)}
+	if err.Error() != "[error] lookup failed: not found" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if !errors.Is(err, base) {
+		t.Error("plainError doesn't unwrap")
+	}
+}
+
+func TestPrintErrorJSON(t *testing.T) {
+	stderr, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = stderr.Close() }()
+	origStderr := os.Stderr
+	t.Cleanup(func() {
+		os.Stderr = origStderr
+		jsonOutput = false
+	})
+	os.Stderr = stderr
+	jsonOutput = true
+
+	err = fmt.Errorf("failed to pull <ref>: %w", errcode.Errorf(errcode.Auth, "not logged in to ghcr.io"))
+	if exit := PrintError(err); exit != 4 {
+		t.Errorf("PrintError() = %d, want 4", exit)
+	}
+	data, _ := os.ReadFile(stderr.Name())
+	want := `{"error":{"code":"auth_error","exit_code":4,"message":"failed to pull <ref>: not logged in to ghcr.io"}}` + "\n"
+	if string(data) != want {
+		t.Errorf("printed %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func packCmd() *Command {
+	flags := flag.NewFlagSet("pack", flag.ContinueOnError)
+	output := flags.String("o", "", "The OCI image layout directory to write or add to (default: <name>-<version>)")
+	tag := flags.String("tag", "", "The tag to name the image in the layout (default: the build's tag)")
+	force := flags.Bool("force", false, "Replace an image with the same tag in the layout")
+
+	return &Command{
+		Name:        "pack",
+		Description: "Write a local build as an OCI image layout, exactly as aigg push would upload it",
+		Flags:       flags,
+		Run: func(args []string) error {
+			if len(args) != 1 {
+				return errcode.Errorf(errcode.Usage, "usage: aigg pack <name:tag> [-o dir] [--tag tag] [--force]")
+			}
+			return packLocalBuild(args[0], *output, *tag, *force)
+		},
+	}
+}
+
+// packLocalBuild writes the local build localRef into the OCI image layout
+// output, with the layer, config and manifest pushFromLocalBuild uploads,
+// so a tool that copies the layout to a registry gives the package the
+// digest aigg push would. A layout already at output gains the image,
+// which replaces one with the same tag only with force.
+func packLocalBuild(localRef, output, tag string, force bool) error {
+	cacheDir, err := docker.CacheDir()
+	if err != nil {
+		return err
+	}
+	localPath := filepath.Join(cacheDir, docker.SanitizeImageRef(localRef))
+	if info, err := os.Stat(localPath); err != nil || !info.IsDir() {
+		return errcode.Errorf(errcode.NotFound, "local build not found: %s\nBuild it first with: aigg build %s (or write a pulled package with aigg export --format oci)", localRef, localRef)
+	}
+
+	var m *manifest.Manifest
+	if loaded, err := manifest.Load(filepath.Join(localPath, "aigogo.json")); err == nil {
+		m = loaded
+	}
+	name, version, _ := aigogo.PackageIdentity(localRef, m)
+	if tag == "" {
+		_, _, tag, err = docker.ParseImageRef(localRef)
+		if err != nil {
+			return errcode.Wrap(errcode.Usage, err)
+		}
+	}
+	if output == "" {
+		output = name + "-" + version
+	}
+	if err := checkPackOutput(output, tag, force); err != nil {
+		return err
+	}
+
+	files, err := aigogo.LocalBuildFiles(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to read local build: %w", err)
+	}
+	layer, err := docker.BuildLayer(localPath, files, aigogo.LayerManifest(localRef, m))
+	if err != nil {
+		return fmt.Errorf("failed to package %s: %w", localRef, err)
+	}
+	var annotations map[string]string
+	if m != nil {
+		annotations = docker.Annotations(m)
+	}
+	digest, err := docker.WriteOCILayout(output, tag, layer, annotations)
+	if err != nil {
+		return fmt.Errorf("failed to write OCI layout: %w", err)
+	}
+
+	fmt.Printf("✓ Packed %s into %s as :%s (%d file(s))\n", localRef, output, tag, len(files))
+	fmt.Printf("  Manifest: %s\n", digest)
+	fmt.Println("  Copy it to a registry with e.g.:")
+	fmt.Printf("    oras cp --from-oci-layout %s:%s <registry>/%s:%s\n", output, tag, name, tag)
+	fmt.Printf("    skopeo copy oci:%s:%s docker://<registry>/%s:%s\n", output, tag, name, tag)
+	return nil
+}
+
+// checkPackOutput checks that pack can write an image tagged tag to
+// output: a directory that doesn't exist, is empty, or is an OCI image
+// layout without that tag (or with it, when force is set)
+func checkPackOutput(output, tag string, force bool) error {
+	info, err := os.Stat(output)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", output, err)
+	}
+	if !info.IsDir() {
+		return errcode.Errorf(errcode.Validation, "%s exists and isn't a directory", output)
+	}
+	if _, err := os.Stat(filepath.Join(output, docker.OCILayoutFile)); err != nil {
+		entries, err := os.ReadDir(output)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", output, err)
+		}
+		if len(entries) > 0 {
+			return errcode.Errorf(errcode.Validation, "%s exists and isn't an OCI image layout\nChoose another directory with -o", output)
+		}
+		return nil
+	}
+
+	tags, err := docker.OCILayoutTags(output)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(tags, tag) && !force {
+		return errcode.Errorf(errcode.Validation, "%s already has an image tagged %s\nUse --force to replace it, or --tag to name this one differently", output, tag)
+	}
+	return nil
+}
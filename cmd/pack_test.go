@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+)
+
+func TestPackLocalBuild(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+	t.Setenv("AIGOGO_STORE", "")
+	t.Chdir(t.TempDir())
+
+	src := t.TempDir()
+	for name, content := range map[string]string{
+		"aigogo.json": `{"name": "utils", "version": "1.2.0", "language": {"name": "python", "version": ">=3.8"},
+			"files": {"include": ["utils.py"]}}`,
+		"utils.py": "def util(): pass\n",
+	} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := importPackage(src, "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := packLocalBuild("missing:1.0.0", "", "", false); err == nil {
+		t.Error("packLocalBuild() packed a build that doesn't exist")
+	}
+
+	// The layout defaults to <name>-<version>, tagged with the build's tag
+	if err := packLocalBuild("utils:1.2.0", "", "", false); err != nil {
+		t.Fatalf("packLocalBuild() error = %v", err)
+	}
+	if err := packLocalBuild("utils:1.2.0", "", "", false); err == nil {
+		t.Error("packLocalBuild() replaced a tag without --force")
+	}
+	if err := packLocalBuild("utils:1.2.0", "", "", true); err != nil {
+		t.Errorf("packLocalBuild() with force error = %v", err)
+	}
+	if err := packLocalBuild("utils:1.2.0", "utils-1.2.0", "latest", false); err != nil {
+		t.Fatalf("packLocalBuild() with another tag error = %v", err)
+	}
+	tags, err := docker.OCILayoutTags("utils-1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(tags, []string{"1.2.0", "latest"}) {
+		t.Errorf("tags = %v, want [1.2.0 latest]", tags)
+	}
+
+	layer, annotations, err := docker.ReadOCILayout("utils-1.2.0", "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if annotations[docker.AnnotationVersion] != "1.2.0" {
+		t.Errorf("annotations = %v, want the package version", annotations)
+	}
+	files, err := docker.ExtractLayer(layer, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(files, "utils.py") {
+		t.Errorf("layer files = %v, want utils.py", files)
+	}
+
+	// A directory holding something else isn't written into
+	other := t.TempDir()
+	if err := os.WriteFile(filepath.Join(other, "notes.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := packLocalBuild("utils:1.2.0", other, "", false); err == nil {
+		t.Error("packLocalBuild() wrote into a directory that isn't an OCI layout")
+	}
+}
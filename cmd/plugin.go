@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+)
+
+// pluginPrefix is the prefix of plugin executables: aigg foo runs aigg-foo
+const pluginPrefix = "aigg-"
+
+// findPlugin returns the path of the plugin executable for the command name
+// on PATH, or "" when there is none. Names that could be paths are never
+// looked up.
+func findPlugin(name string) string {
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, `/\.`) {
+		return ""
+	}
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// listPlugins returns the names of the plugins on PATH that don't shadow a
+// builtin command, sorted
+func listPlugins(commands map[string]*Command) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := strings.CutPrefix(e.Name(), pluginPrefix)
+			if !ok || e.IsDir() {
+				continue
+			}
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			if seen[name] || commands[name] != nil || findPlugin(name) == "" {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pluginEnv returns the current environment plus the AIGOGO_* variables a
+// plugin gets. AIGOGO_STORE and AIGOGO_CACHE are also settings, so aigg
+// commands the plugin runs use the same store and cache.
+func pluginEnv() []string {
+	env := os.Environ()
+	if exe, err := os.Executable(); err == nil {
+		env = append(env, "AIGOGO_BIN="+exe)
+	}
+	env = append(env,
+		"AIGOGO_VERSION="+version,
+		"AIGOGO_LOG_LEVEL="+logging.GetLevel().String(),
+		"AIGOGO_COLOR="+colorMode,
+		"AIGOGO_PLAIN="+strconv.FormatBool(plainOutput),
+		"AIGOGO_OUTPUT="+outputFormat,
+	)
+
+	projectDir := configProjectDir()
+	env = append(env, "AIGOGO_PROJECT_DIR="+projectDir)
+	if s, err := aigogo.OpenStore(loadProjectSettings(projectDir)); err == nil {
+		env = append(env, "AIGOGO_STORE="+s.RootDir())
+	}
+	if cacheDir, err := docker.CacheDir(); err == nil {
+		env = append(env, "AIGOGO_CACHE="+cacheDir)
+	}
+	return env
+}
+
+// runPlugin runs the plugin at path with args in place of aigg, so it gets
+// the terminal, signals and exit code. Windows has no process replacement,
+// so there the plugin runs as a child and aigg exits with its code.
+func runPlugin(path string, args []string) error {
+	env := pluginEnv()
+	if runtime.GOOS != "windows" {
+		if err := replaceProcess(path, append([]string{path}, args...), env); err != nil {
+			return fmt.Errorf("failed to run plugin %s: %w", path, err)
+		}
+		return nil
+	}
+
+	c := exec.Command(path, args...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	c.Env = env
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			flushOutput()
+			os.Exit(exitErr.ExitCode())
+		}
+		return fmt.Errorf("failed to run plugin %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/store"
+)
+
+func TestFindAndListPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	dir := t.TempDir()
+	for name, mode := range map[string]os.FileMode{
+		"aigg-lint-prompts": 0755,
+		"aigg-sync":         0755,
+		"aigg-install":      0755, // shadowed by the builtin
+		"aigg-notes.txt":    0644, // not executable
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+
+	if got := findPlugin("sync"); got != filepath.Join(dir, "aigg-sync") {
+		t.Errorf("findPlugin(sync) = %q", got)
+	}
+	for _, name := range []string{"missing", "../sync", "-sync", ""} {
+		if got := findPlugin(name); got != "" {
+			t.Errorf("findPlugin(%q) = %q, want none", name, got)
+		}
+	}
+
+	commands := map[string]*Command{"install": {}}
+	if got, want := listPlugins(commands), []string{"lint-prompts", "sync"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("listPlugins() = %q, want %q", got, want)
+	}
+}
+
+func TestPluginEnv(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+	t.Setenv("AIGOGO_STORE", "")
+	project, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(project, "aigogo.lock"), []byte(`{"version": 1, "packages": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(project)
+
+	vars := map[string]string{}
+	for _, kv := range pluginEnv() {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, "AIGOGO_") {
+			vars[k] = v
+		}
+	}
+	storeDir, err := store.DefaultDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"AIGOGO_VERSION":     version,
+		"AIGOGO_LOG_LEVEL":   "normal",
+		"AIGOGO_COLOR":       "auto",
+		"AIGOGO_PLAIN":       "false",
+		"AIGOGO_PROJECT_DIR": project,
+		"AIGOGO_STORE":       storeDir,
+	}
+	for k, v := range want {
+		if vars[k] != v {
+			t.Errorf("%s = %q, want %q", k, vars[k], v)
+		}
+	}
+	if vars["AIGOGO_BIN"] == "" || vars["AIGOGO_CACHE"] == "" {
+		t.Errorf("AIGOGO_BIN and AIGOGO_CACHE should be set: %v", vars)
+	}
+}
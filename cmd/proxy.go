@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+)
+
+// defaultProxyAddr is where aigg proxy listens unless --addr says otherwise
+const defaultProxyAddr = "127.0.0.1:5050"
+
+func proxyCmd() *Command {
+	flags := flag.NewFlagSet("proxy", flag.ContinueOnError)
+	addr := flags.String("addr", defaultProxyAddr, "Address to listen on")
+	dir := flags.String("dir", "", "Cache directory (default: proxy/ in the package cache)")
+	defaultRegistry := flags.String("default-registry", "docker.io", "Registry of names that don't start with one")
+	tagTTL := flags.Duration("tag-ttl", docker.DefaultProxyTagTTL, "How long a tag is served from the cache before the registry is asked again")
+	offline := flags.Bool("offline", false, "Serve only what is cached, without contacting registries")
+
+	return &Command{
+		Name:        "proxy",
+		Description: "Run a pull-through registry cache shared by a team or CI fleet",
+		Flags:       flags,
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return errcode.Errorf(errcode.Usage, "unexpected argument: %s", args[0])
+			}
+			if *tagTTL < 0 {
+				return errcode.Errorf(errcode.Usage, "--tag-ttl can't be negative")
+			}
+			if *defaultRegistry == "" || strings.ContainsAny(*defaultRegistry, "/ ") {
+				return errcode.Errorf(errcode.Usage, "--default-registry must be a registry host, e.g. ghcr.io or localhost:5000, got %q", *defaultRegistry)
+			}
+
+			cacheDir := *dir
+			if cacheDir == "" {
+				var err error
+				if cacheDir, err = docker.ProxyDir(); err != nil {
+					return fmt.Errorf("failed to find the proxy cache directory: %w", err)
+				}
+			}
+			if err := os.MkdirAll(cacheDir, 0755); err != nil {
+				return fmt.Errorf("failed to create %s: %w", cacheDir, err)
+			}
+			proxy := docker.NewProxy(cacheDir, *defaultRegistry)
+			proxy.SetTagTTL(*tagTTL)
+			proxy.SetOffline(*offline)
+
+			ln, err := net.Listen("tcp", *addr)
+			if err != nil {
+				return fmt.Errorf("failed to listen on %s: %w", *addr, err)
+			}
+			if !isLoopbackAddr(ln.Addr()) {
+				fmt.Fprintf(os.Stderr, "⚠ Warning: %s can be reached from other machines, and aigg proxy serves them whatever your registry logins can read\n", ln.Addr())
+			}
+
+			fmt.Printf("Proxying registries on http://%s, caching in %s (press Ctrl-C to stop)\n", ln.Addr(), cacheDir)
+			if *offline {
+				fmt.Println("Offline: only cached packages are served")
+			} else if *tagTTL > 0 {
+				fmt.Printf("Tags are checked upstream every %s\n", tagTTL.Round(time.Second))
+			}
+			fmt.Printf("Reach it over HTTP with: aigg config set insecure_registries %s\n", ln.Addr())
+			fmt.Printf("Then pull through it:    aigg add %s/ghcr.io/org/utils:1.0.0\n", ln.Addr())
+			return serveUntilInterrupted(ln, proxy)
+		},
+	}
+}
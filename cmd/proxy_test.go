@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+)
+
+// fakeRegistry serves one image, org/utils:1.0.0, and counts the requests
+// for each path
+type fakeRegistry struct {
+	mu       sync.Mutex
+	requests map[string]int
+	manifest []byte
+	blobs    map[string][]byte
+}
+
+func sha256Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func newFakeRegistry(layer []byte) *fakeRegistry {
+	config := []byte(`{}`)
+	r := &fakeRegistry{
+		requests: map[string]int{},
+		blobs:    map[string][]byte{sha256Digest(layer): layer, sha256Digest(config): config},
+	}
+	r.manifest = []byte(fmt.Sprintf(`{"schemaVersion": 2, "mediaType": "application/vnd.docker.distribution.manifest.v2+json", `+
+		`"config": {"mediaType": "application/vnd.docker.container.image.v1+json", "digest": %q, "size": %d}, `+
+		`"layers": [{"mediaType": "application/vnd.docker.image.rootfs.diff.tar", "digest": %q, "size": %d}]}`,
+		sha256Digest(config), len(config), sha256Digest(layer), len(layer)))
+	return r
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests[r.URL.Path]++
+	f.mu.Unlock()
+	switch {
+	case r.URL.Path == "/v2/org/utils/manifests/1.0.0" || r.URL.Path == "/v2/org/utils/manifests/"+sha256Digest(f.manifest):
+		w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.v2+json")
+		_, _ = w.Write(f.manifest)
+	case r.URL.Path == "/v2/org/utils/tags/list":
+		_, _ = w.Write([]byte(`{"name": "org/utils", "tags": ["1.0.0"]}`))
+	case strings.HasPrefix(r.URL.Path, "/v2/org/utils/blobs/"):
+		digest := strings.TrimPrefix(r.URL.Path, "/v2/org/utils/blobs/")
+		if data, ok := f.blobs[digest]; ok {
+			_, _ = w.Write(data)
+			return
+		}
+		if strings.HasSuffix(digest, "bad") {
+			_, _ = w.Write([]byte("not what was asked for"))
+			return
+		}
+		http.NotFound(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeRegistry) count(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[path]
+}
+
+func TestProxy(t *testing.T) {
+	setHome(t, t.TempDir())
+	docker.SetRetryPolicy(1, 0)
+	t.Cleanup(func() { docker.SetRetryPolicy(3, 500*time.Millisecond) })
+
+	layer := []byte("pretend this is a tar")
+	upstream := newFakeRegistry(layer)
+	upstreamServer := httptest.NewServer(upstream)
+	upstreamHost := strings.TrimPrefix(upstreamServer.URL, "http://")
+	docker.SetInsecureRegistries([]string{upstreamHost})
+	t.Cleanup(func() { docker.SetInsecureRegistries(nil) })
+
+	dir := t.TempDir()
+	proxy := docker.NewProxy(dir, upstreamHost)
+	get := func(h http.Handler, method, path string, wantStatus int) *http.Response {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		if rec.Code != wantStatus {
+			t.Fatalf("%s %s = %d %s, want %d", method, path, rec.Code, rec.Body, wantStatus)
+		}
+		return rec.Result()
+	}
+	body := func(resp *http.Response) string {
+		data, _ := io.ReadAll(resp.Body)
+		return string(data)
+	}
+
+	if resp := get(proxy, "GET", "/v2/", http.StatusOK); resp.Header.Get("Docker-Distribution-API-Version") != "registry/2.0" {
+		t.Errorf("/v2/ headers = %v", resp.Header)
+	}
+
+	resp := get(proxy, "GET", "/v2/org/utils/manifests/1.0.0", http.StatusOK)
+	if got := body(resp); got != string(upstream.manifest) || resp.Header.Get("X-Aigogo-Cache") != "miss" ||
+		resp.Header.Get("Docker-Content-Digest") != sha256Digest(upstream.manifest) {
+		t.Errorf("manifest = %q, %v", got, resp.Header)
+	}
+	if resp := get(proxy, "GET", "/v2/org/utils/manifests/1.0.0", http.StatusOK); resp.Header.Get("X-Aigogo-Cache") != "hit" {
+		t.Errorf("second manifest request = %v, want a cache hit", resp.Header)
+	}
+	if resp := get(proxy, "GET", "/v2/org/utils/manifests/"+sha256Digest(upstream.manifest), http.StatusOK); resp.Header.Get("X-Aigogo-Cache") != "hit" {
+		t.Errorf("manifest by digest = %v, want a cache hit", resp.Header)
+	}
+	if n := upstream.count("/v2/org/utils/manifests/1.0.0"); n != 1 {
+		t.Errorf("upstream manifest fetched %d times, want once", n)
+	}
+
+	blobPath := "/v2/org/utils/blobs/" + sha256Digest(layer)
+	if got := body(get(proxy, "GET", blobPath, http.StatusOK)); got != string(layer) {
+		t.Errorf("blob = %q", got)
+	}
+	get(proxy, "HEAD", blobPath, http.StatusOK)
+	if n := upstream.count(blobPath); n != 1 {
+		t.Errorf("upstream blob fetched %d times, want once", n)
+	}
+	get(proxy, "GET", "/v2/org/utils/blobs/sha256:"+strings.Repeat("0", 61)+"bad", http.StatusBadGateway)
+	get(proxy, "GET", "/v2/org/utils/blobs/sha256:"+strings.Repeat("0", 64), http.StatusNotFound)
+	get(proxy, "GET", "/v2/org/utils/manifests/2.0.0", http.StatusNotFound)
+	if got := body(get(proxy, "GET", "/v2/org/utils/tags/list", http.StatusOK)); !strings.Contains(got, `"1.0.0"`) {
+		t.Errorf("tags = %s", got)
+	}
+
+	// Pushes, deletes and names that aren't repositories are refused
+	get(proxy, "PUT", "/v2/org/utils/manifests/1.0.0", http.StatusMethodNotAllowed)
+	get(proxy, "DELETE", blobPath, http.StatusMethodNotAllowed)
+	get(proxy, "GET", "/v2/org/../utils/manifests/1.0.0", http.StatusBadRequest)
+	get(proxy, "GET", "/v2/org/utils/manifests/..", http.StatusBadRequest)
+
+	// aigg pulls through it as from any registry
+	proxyServer := httptest.NewServer(proxy)
+	defer proxyServer.Close()
+	proxyHost := strings.TrimPrefix(proxyServer.URL, "http://")
+	docker.SetInsecureRegistries([]string{upstreamHost, proxyHost})
+	data, err := docker.NewPuller().FetchLayer(context.Background(), proxyHost+"/org/utils:1.0.0")
+	if err != nil || string(data) != string(layer) {
+		t.Errorf("FetchLayer() through the proxy = %q, %v", data, err)
+	}
+
+	// With the registry down, tags are served from the cache once their TTL
+	// has passed
+	upstreamServer.Close()
+	proxy.SetTagTTL(0)
+	if resp := get(proxy, "GET", "/v2/org/utils/manifests/1.0.0", http.StatusOK); resp.Header.Get("X-Aigogo-Cache") != "stale" {
+		t.Errorf("manifest during an outage = %v, want stale", resp.Header)
+	}
+	if resp := get(proxy, "GET", "/v2/org/utils/tags/list", http.StatusOK); resp.Header.Get("X-Aigogo-Cache") != "stale" {
+		t.Errorf("tags during an outage = %v, want stale", resp.Header)
+	}
+	get(proxy, "GET", "/v2/org/other/manifests/1.0.0", http.StatusBadGateway)
+
+	offline := docker.NewProxy(dir, upstreamHost)
+	offline.SetOffline(true)
+	get(offline, "GET", "/v2/org/utils/manifests/1.0.0", http.StatusOK)
+	get(offline, "GET", "/v2/org/other/manifests/1.0.0", http.StatusNotFound)
+}
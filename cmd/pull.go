@@ -1,33 +1,81 @@
 package cmd
 
 import (
+	"flag"
 	"fmt"
 
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
 	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/logging"
 )
 
 func pullCmd() *Command {
+	flags := flag.NewFlagSet("pull", flag.ContinueOnError)
+	registry := registryFlag(flags)
+	all := flags.Bool("all", false, "Fetch every package in aigogo.lock into the package store")
+	production := flags.Bool("production", false, "With --all, skip dev and optional packages")
+	maxExtractSize := maxExtractSizeFlag(flags)
+
 	return &Command{
 		Name:        "pull",
 		Description: "Pull an agent from a registry (without extracting)",
+		Flags:       flags,
 		Run: func(args []string) error {
+			if err := setMaxExtractSize(*maxExtractSize); err != nil {
+				return err
+			}
+			if *all {
+				if len(args) > 0 || *registry != "" {
+					return errcode.Errorf(errcode.Usage, "usage: aigg pull --all [--production]\n--all pulls the packages in aigogo.lock, from the registries they were locked from")
+				}
+				return pullLocked(*production)
+			}
+			if *production {
+				return errcode.Errorf(errcode.Usage, "--production only applies to aigg pull --all")
+			}
 			if len(args) < 1 {
-				return fmt.Errorf("usage: aigg pull <registry>/<name>:<tag>")
+				return errcode.Errorf(errcode.Usage, "usage: aigg pull <registry>/<name>:<tag> [--registry <host[/namespace]>]\n       aigg pull --all [--production]")
 			}
 
-			imageRef := args[0]
+			defaultRegistry, err := commandRegistry(*registry)
+			if err != nil {
+				return err
+			}
+			imageRef := aigogo.QualifyImageRef(args[0], defaultRegistry)
 
-			fmt.Printf("Pulling %s...\n", imageRef)
+			logging.Printf("Pulling %s...\n", imageRef)
 
 			puller := docker.NewPuller()
-			if err := puller.Pull(imageRef); err != nil {
+			if err := puller.Pull(commandContext(), imageRef); err != nil {
 				return fmt.Errorf("failed to pull image: %w", err)
 			}
 
 			fmt.Printf("Successfully pulled %s\n", imageRef)
-			fmt.Println("Use 'aigg add' + 'aigg install' to set up import links")
+			logging.Println("Use 'aigg add' + 'aigg install' to set up import links")
 
 			return nil
 		},
 	}
 }
+
+// pullLocked fetches every package in the project's aigogo.lock that isn't
+// in the package store (see aigogo.Client.Prefetch), reporting the
+// packages that were already stored and the local path packages, which
+// have nothing to fetch
+func pullLocked(production bool) error {
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	result, err := client.Prefetch(commandContext(), production)
+	if err != nil {
+		return err
+	}
+	if result.Packages == 0 {
+		fmt.Println("No packages to pull")
+		return nil
+	}
+	fmt.Printf("✓ Fetched %d package(s), %d already in the store\n", result.Fetched, len(result.Present))
+	return nil
+}
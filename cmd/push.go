@@ -3,14 +3,22 @@ package cmd
 import (
 	"flag"
 	"fmt"
-	"os"
 
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
 	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/webhook"
 )
 
 func pushCmd() *Command {
 	flags := flag.NewFlagSet("push", flag.ExitOnError)
 	from := flags.String("from", "", "Push from existing local build (required)")
+	deprecate := flags.String("deprecate", "", "Mark an already pushed package as deprecated, with this message")
+	replacement := flags.String("replacement", "", "Package reference to use instead (with --deprecate)")
+	undeprecate := flags.Bool("undeprecate", false, "Remove the deprecation of an already pushed package")
+	allowPrivate := flags.Bool("allow-private", false, "Push a package marked \"private\": true in its aigogo.json")
+	dryRun := flags.Bool("dry-run", false, "Run the push checks and show what would be pushed, without pushing")
+	registry := registryFlag(flags)
 
 	return &Command{
 		Name:        "push",
@@ -18,126 +26,108 @@ func pushCmd() *Command {
 		Flags:       flags,
 		Run: func(args []string) error {
 			if len(args) < 1 {
-				return fmt.Errorf("usage: aigg push <registry>/<name>:<tag> --from <local-build>")
+				return errcode.Errorf(errcode.Usage, "usage: aigg push <registry>/<name>:<tag> --from <local-build> [--dry-run] [--registry <host[/namespace]>]\n       aigg push <registry>/<name>:<tag> --deprecate <message> [--replacement <ref>]\n       aigg push <registry>/<name>:<tag> --undeprecate")
 			}
 
-			imageRef := args[0]
+			defaultRegistry, err := commandRegistry(*registry)
+			if err != nil {
+				return err
+			}
+			imageRef := aigogo.QualifyImageRef(args[0], defaultRegistry)
+
+			if *deprecate != "" || *undeprecate {
+				if *from != "" {
+					return fmt.Errorf("--deprecate and --undeprecate change a package that is already pushed; they can't be combined with --from\nTo deprecate a new build, set \"deprecated\" in its aigogo.json")
+				}
+				if *deprecate != "" && *undeprecate {
+					return fmt.Errorf("--deprecate and --undeprecate can't be combined")
+				}
+				return setDeprecation(imageRef, *deprecate, *replacement, *undeprecate)
+			}
+			if *replacement != "" {
+				return errcode.Errorf(errcode.Usage, "--replacement requires --deprecate")
+			}
 
 			// Require --from flag
 			if *from == "" {
-				return fmt.Errorf("--from flag is required\n\nWorkflow:\n  1. aigg build <name>:<tag>\n  2. aigg push %s --from <name>:<tag>\n\nExample:\n  aigg build utils:1.0.0\n  aigg push %s --from utils:1.0.0", imageRef, imageRef)
+				return errcode.Errorf(errcode.Usage, "--from flag is required\n\nWorkflow:\n  1. aigg build <name>:<tag>\n  2. aigg push %s --from <name>:<tag>\n\nExample:\n  aigg build utils:1.0.0\n  aigg push %s --from utils:1.0.0", imageRef, imageRef)
 			}
 
 			// Push from the specified local build
-			return pushFromLocalBuild(imageRef, *from)
+			return pushFromLocalBuild(imageRef, *from, *allowPrivate, *dryRun)
 		},
 	}
 }
 
-// pushFromLocalBuild pushes an existing local build to a registry
-func pushFromLocalBuild(registryRef, localRef string) error {
-	// Check if local build exists
-	if !docker.ImageExistsInCache(localRef) {
-		return fmt.Errorf("local build not found: %s\nBuild it first with: aigg build %s", localRef, localRef)
-	}
-
-	fmt.Printf("Pushing local build %s to %s...\n", localRef, registryRef)
-
-	// Get cache directory
-	home, err := os.UserHomeDir()
+// pushFromLocalBuild pushes an existing local build to a registry (see
+// aigogo.Client.Push). With dryRun it only reports what would be pushed.
+func pushFromLocalBuild(registryRef, localRef string, allowPrivate, dryRun bool) error {
+	client, err := newClient()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return err
 	}
-	cacheDir := home + "/.aigogo/cache"
-
-	// Sanitize local ref to get cache path
-	sanitized := docker.SanitizeImageRef(localRef)
-	localPath := cacheDir + "/" + sanitized
-
-	// Read files from local cache
-	files, err := getFilesFromLocalBuild(localPath)
+	endGroup := startGroup("Pushing " + registryRef)
+	result, err := client.Push(commandContext(), registryRef, aigogo.PushOptions{From: localRef, AllowPrivate: allowPrivate, DryRun: dryRun})
+	endGroup()
 	if err != nil {
-		return fmt.Errorf("failed to read local build: %w", err)
+		return err
 	}
-
-	fmt.Printf("  Found %d file(s) in local build\n", len(files))
-
-	// Build Docker image from local files
-	fmt.Println("Building image for registry...")
-	builder := docker.NewBuilder()
-
-	// Create a simple manifest for the builder
-	simpleManifest := map[string]interface{}{
-		"name":    localRef,
-		"version": "local",
-	}
-
-	if err := builder.BuildImageFromPath(registryRef, localPath, files, simpleManifest); err != nil {
-		return fmt.Errorf("failed to build image: %w", err)
+	if dryRun {
+		fmt.Printf("Would push %s to %s: %d file(s), content %s\n", result.From, result.Ref, result.Files, result.Content)
+		return nil
 	}
-
-	// Push to registry
-	fmt.Printf("Pushing to %s...\n", registryRef)
-	pusher := docker.NewPusher()
-	if err := pusher.Push(registryRef); err != nil {
-		return fmt.Errorf("failed to push image: %w", err)
-	}
-
-	fmt.Printf("✓ Successfully pushed %s\n", registryRef)
+	noteHistory(result.Ref, result.Digest)
+	fmt.Printf("✓ Successfully pushed %s\n", result.Ref)
+	reportPush(client, result)
 	return nil
 }
 
-// getFilesFromLocalBuild returns list of files in a local build (relative paths)
-func getFilesFromLocalBuild(localPath string) ([]string, error) {
-	var files []string
-
-	entries, err := os.ReadDir(localPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read local build directory: %w", err)
+// reportPush tells the push webhooks about a push and, with --output
+// github, adds it to the job summary, with how the build differs from the
+// version before it in the repository
+func reportPush(client *aigogo.Client, result *aigogo.PushResult) {
+	hooks := loadWebhooks(webhook.EventPush)
+	if len(hooks) == 0 && !githubOutput() {
+		return
 	}
-
-	for _, entry := range entries {
-		// Skip metadata files
-		if entry.Name() == ".aigogo-metadata.json" {
-			continue
-		}
-
-		if entry.IsDir() {
-			// Recursively add files from subdirectories
-			subFiles, err := getFilesFromDir(localPath+"/"+entry.Name(), entry.Name())
-			if err != nil {
-				return nil, err
-			}
-			files = append(files, subFiles...)
-		} else {
-			files = append(files, entry.Name())
-		}
+	e := pushEvent(commandContext(), client, result)
+	addJobSummary(pushJobSummary(result, e))
+	if len(hooks) > 0 {
+		notifyWebhooks(hooks, e)
 	}
-
-	return files, nil
 }
 
-// getFilesFromDir recursively gets files from a directory
-func getFilesFromDir(fullPath, relPath string) ([]string, error) {
-	var files []string
-
-	entries, err := os.ReadDir(fullPath)
-	if err != nil {
-		return nil, err
+// setDeprecation marks a pushed package as deprecated, or with undeprecate
+// removes the mark, by updating its registry annotations. The package's
+// files are left as they are.
+func setDeprecation(imageRef, message, replacement string, undeprecate bool) error {
+	if docker.IsLocalReference(imageRef) {
+		return fmt.Errorf("%s is a local reference; deprecation applies to packages in a registry\nGive the full reference, e.g. docker.io/org/%s", imageRef, imageRef)
 	}
+	noteHistory(imageRef, "")
 
-	for _, entry := range entries {
-		entryRelPath := relPath + "/" + entry.Name()
-		if entry.IsDir() {
-			subFiles, err := getFilesFromDir(fullPath+"/"+entry.Name(), entryRelPath)
-			if err != nil {
-				return nil, err
-			}
-			files = append(files, subFiles...)
-		} else {
-			files = append(files, entryRelPath)
+	pusher := docker.NewPusher()
+	if undeprecate {
+		if err := pusher.Annotate(commandContext(), imageRef, nil, []string{docker.AnnotationDeprecated, docker.AnnotationReplacement}); err != nil {
+			return fmt.Errorf("failed to update %s: %w", imageRef, err)
 		}
+		fmt.Printf("✓ %s is no longer deprecated\n", imageRef)
+		return nil
 	}
 
-	return files, nil
+	set := map[string]string{docker.AnnotationDeprecated: message}
+	remove := []string{docker.AnnotationReplacement}
+	if replacement != "" {
+		set[docker.AnnotationReplacement] = replacement
+		remove = nil
+	}
+	if err := pusher.Annotate(commandContext(), imageRef, set, remove); err != nil {
+		return fmt.Errorf("failed to update %s: %w", imageRef, err)
+	}
+	fmt.Printf("✓ Deprecated %s: %s\n", imageRef, message)
+	if replacement != "" {
+		fmt.Printf("  Replacement: %s\n", replacement)
+	}
+	fmt.Println("  aigg add and aigg install now warn about it (--strict refuses it)")
+	return nil
 }
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"flag"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// registryFlag adds --registry to a command's flags
+func registryFlag(flags *flag.FlagSet) *string {
+	return flags.String("registry", "", "Registry, and optionally namespace, for a reference without one (default: the registry setting)")
+}
+
+// commandRegistry returns the registry a command resolves references
+// against: --registry when given, else the registry setting of the project
+// around the working directory
+func commandRegistry(flagRegistry string) (string, error) {
+	if flagRegistry != "" {
+		return flagRegistry, checkRegistryFlag(flagRegistry)
+	}
+	return loadProjectSettings(configProjectDir()).Registry, nil
+}
+
+// checkRegistryFlag checks a --registry as the registry setting is checked
+func checkRegistryFlag(registry string) error {
+	if err := (&manifest.Settings{Registry: registry}).Validate(); err != nil {
+		return errcode.Errorf(errcode.Usage, "--registry: %w", err)
+	}
+	return nil
+}
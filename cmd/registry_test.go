@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestCheckRegistryFlag(t *testing.T) {
+	for registry, ok := range map[string]bool{
+		"ghcr.io/ourco":       true,
+		"localhost:5000":      true,
+		"https://ghcr.io":     false,
+		"ghcr.io/ourco/":      false,
+		"ghcr.io/our co/pkgs": false,
+	} {
+		if err := checkRegistryFlag(registry); (err == nil) != ok {
+			t.Errorf("checkRegistryFlag(%q) = %v", registry, err)
+		}
+	}
+}
@@ -2,28 +2,22 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
-	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
 )
 
+// removeCmd is the old name of aigg cache rm, kept for scripts that use it
 func removeCmd() *Command {
 	return &Command{
 		Name:        "remove",
-		Description: "Remove a cached agent",
+		Description: "Remove a cached agent (now aigg cache rm)",
 		Run: func(args []string) error {
 			if len(args) < 1 {
-				return fmt.Errorf("usage: aigg remove <name>:<tag>")
+				return errcode.Errorf(errcode.Usage, "usage: aigg remove <name>:<tag>")
 			}
-
-			imageRef := args[0]
-
-			remover := docker.NewRemover()
-			if err := remover.Remove(imageRef); err != nil {
-				return fmt.Errorf("failed to remove image: %w", err)
-			}
-
-			fmt.Printf("Successfully removed %s\n", imageRef)
-			return nil
+			fmt.Fprintln(os.Stderr, "Note: aigg remove is now aigg cache rm")
+			return cacheRm(args)
 		},
 	}
 }
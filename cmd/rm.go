@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/aupeachmo/aigogo/pkg/errcode"
 	"github.com/aupeachmo/aigogo/pkg/manifest"
 )
 
@@ -16,7 +17,7 @@ func rmCmd() *Command {
 		Description: "Remove files or dependencies from aigogo.json",
 		Run: func(args []string) error {
 			if len(args) == 0 {
-				return fmt.Errorf("usage: aigg rm <file|dep|dev> [args...]\n\nSubcommands:\n  file <path>...  Remove files from include list\n  dep <pkg>       Remove runtime dependency\n  dev <pkg>       Remove development dependency")
+				return errcode.Errorf(errcode.Usage, "usage: aigg rm <file|dep|dev|peer> [args...]\n\nSubcommands:\n  file <path>...  Remove files from include list\n  dep <pkg>       Remove runtime dependency\n  dev <pkg>       Remove development dependency\n  peer <pkg>      Remove peer dependency")
 			}
 
 			subcommand := args[0]
@@ -26,11 +27,13 @@ func rmCmd() *Command {
 			case "file":
 				return rmFiles(subArgs)
 			case "dep":
-				return rmDependency(subArgs, false)
+				return rmDependency(subArgs, groupRuntime)
 			case "dev":
-				return rmDependency(subArgs, true)
+				return rmDependency(subArgs, groupDev)
+			case "peer":
+				return rmDependency(subArgs, groupPeer)
 			default:
-				return fmt.Errorf("unknown subcommand '%s'\nValid subcommands: file, dep, dev", subcommand)
+				return errcode.Errorf(errcode.Usage, "unknown subcommand '%s'\nValid subcommands: file, dep, dev, peer", subcommand)
 			}
 		},
 	}
@@ -47,7 +50,7 @@ func rmFiles(args []string) error {
 
 	// Check if files.include is "auto"
 	if str, ok := m.Files.Include.(string); ok && str == "auto" {
-		return fmt.Errorf("files.include is set to 'auto'\nCannot remove individual files when using auto-discovery")
+		return fmt.Errorf("files.include is set to 'auto'\nRun 'aigg files freeze' to turn it into an explicit list before removing files")
 	}
 
 	// Get existing patterns
@@ -79,7 +82,7 @@ func rmFiles(args []string) error {
 	}
 
 	if len(filePaths) == 0 {
-		return fmt.Errorf("at least one file path is required")
+		return errcode.Errorf(errcode.Usage, "at least one file path is required")
 	}
 
 	// Remove specified files
@@ -112,7 +115,7 @@ func rmFiles(args []string) error {
 	}
 
 	// Save updated manifest
-	if err := manifest.Save(manifestPath, m); err != nil {
+	if err := saveManifest(manifestPath, m); err != nil {
 		return fmt.Errorf("failed to save aigogo.json: %w", err)
 	}
 
@@ -134,11 +137,8 @@ func rmFiles(args []string) error {
 	return nil
 }
 
-func rmDependency(args []string, isDev bool) error {
-	depType := "runtime"
-	if isDev {
-		depType = "development"
-	}
+func rmDependency(args []string, group string) error {
+	depType := group
 
 	// Find and load manifest (supports subdirectories)
 	m, manifestDir, err := manifest.FindManifest()
@@ -154,8 +154,11 @@ func rmDependency(args []string, isDev bool) error {
 	}
 
 	targetList := m.Dependencies.Runtime
-	if isDev {
+	switch group {
+	case groupDev:
 		targetList = m.Dependencies.Dev
+	case groupPeer:
+		targetList = m.Dependencies.Peer
 	}
 
 	if len(targetList) == 0 {
@@ -185,7 +188,7 @@ func rmDependency(args []string, isDev bool) error {
 	}
 
 	if pkgName == "" {
-		return fmt.Errorf("package name is required")
+		return errcode.Errorf(errcode.Usage, "package name is required")
 	}
 
 	// Find and remove the dependency
@@ -204,23 +207,26 @@ func rmDependency(args []string, isDev bool) error {
 	}
 
 	if !found {
-		return fmt.Errorf("package '%s' not found in %s dependencies", pkgName, depType)
+		return errcode.Errorf(errcode.NotFound, "package '%s' not found in %s dependencies", pkgName, depType)
 	}
 
 	// Update the dependencies
-	if isDev {
+	switch group {
+	case groupDev:
 		m.Dependencies.Dev = newList
-	} else {
+	case groupPeer:
+		m.Dependencies.Peer = newList
+	default:
 		m.Dependencies.Runtime = newList
 	}
 
 	// Clean up if no dependencies left
-	if len(m.Dependencies.Runtime) == 0 && len(m.Dependencies.Dev) == 0 {
+	if len(m.Dependencies.Runtime) == 0 && len(m.Dependencies.Dev) == 0 && len(m.Dependencies.Peer) == 0 {
 		m.Dependencies = nil
 	}
 
 	// Save updated manifest
-	if err := manifest.Save(manifestPath, m); err != nil {
+	if err := saveManifest(manifestPath, m); err != nil {
 		return fmt.Errorf("failed to save aigogo.json: %w", err)
 	}
 
@@ -228,14 +234,9 @@ func rmDependency(args []string, isDev bool) error {
 
 	// Show remaining dependencies
 	if m.Dependencies != nil {
-		remaining := m.Dependencies.Runtime
-		if isDev {
-			remaining = m.Dependencies.Dev
-		}
-
-		if len(remaining) > 0 {
-			fmt.Printf("\nRemaining %s dependencies (%d):\n", depType, len(remaining))
-			for _, dep := range remaining {
+		if len(newList) > 0 {
+			fmt.Printf("\nRemaining %s dependencies (%d):\n", depType, len(newList))
+			for _, dep := range newList {
 				fmt.Printf("  - %s (%s)\n", dep.Package, dep.Version)
 			}
 		} else {
@@ -4,7 +4,16 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"runtime"
 	"strings"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/auth"
+	"github.com/aupeachmo/aigogo/pkg/config"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"golang.org/x/term"
 )
 
 // Command represents a CLI command
@@ -16,33 +25,90 @@ type Command struct {
 }
 
 // Execute runs the root command
-func Execute() error {
+func Execute() (err error) {
+	manifest.FetchRegistryManifest = readRegistryManifest
+	defer func() { stopInterrupt() }()
+
 	commands := map[string]*Command{
-		"init":       initCmd(),
-		"add":        addCmd(),
-		"install":    installCmd(),
-		"rm":         rmCmd(),
-		"validate":   validateCmd(),
-		"scan":       scanCmd(),
-		"build":      buildCmd(),
-		"push":       pushCmd(),
-		"pull":       pullCmd(),
-		"login":      loginCmd(),
-		"logout":     logoutCmd(),
-		"list":       listCmd(),
-		"show-deps":  showDepsCmd(),
-		"remove":     removeCmd(),
-		"remove-all": removeAllCmd(),
-		"delete":     deleteCmd(),
-		"uninstall":  uninstallCmd(),
-		"exec":       execCmd(),
-		"clean":      cleanCmd(),
-		"search":     searchCmd(),
-		"version":    versionCmd(),
-		"completion": completionCmd(),
-	}
-
-	args := os.Args[1:]
+		"init":         initCmd(),
+		"add":          addCmd(),
+		"install":      installCmd(),
+		"rm":           rmCmd(),
+		"validate":     validateCmd(),
+		"lint":         lintCmd(),
+		"scan":         scanCmd(),
+		"build":        buildCmd(),
+		"push":         pushCmd(),
+		"pull":         pullCmd(),
+		"login":        loginCmd(),
+		"logout":       logoutCmd(),
+		"whoami":       whoamiCmd(),
+		"list":         listCmd(),
+		"info":         infoCmd(),
+		"tree":         treeCmd(),
+		"show-deps":    showDepsCmd(),
+		"licenses":     licensesCmd(),
+		"cache":        cacheCmd(),
+		"export":       exportCmd(),
+		"diff":         diffCmd(),
+		"pack":         packCmd(),
+		"serve":        serveCmd(),
+		"proxy":        proxyCmd(),
+		"mcp":          mcpCmd(),
+		"import":       importCmd(),
+		"remove":       removeCmd(),
+		"remove-all":   removeAllCmd(),
+		"delete":       deleteCmd(),
+		"uninstall":    uninstallCmd(),
+		"doctor":       doctorCmd(),
+		"ide":          ideCmd(),
+		"hooks":        hooksCmd(),
+		"history":      historyCmd(),
+		"exec":         execCmd(),
+		"clean":        cleanCmd(),
+		"migrate":      migrateCmd(),
+		"search":       searchCmd(),
+		"schema":       schemaCmd(),
+		"files":        filesCmd(),
+		"check-ignore": checkIgnoreCmd(),
+		"config":       configCmd(),
+		"version":      versionCmd(),
+		"self-update":  selfUpdateCmd(),
+		"completion":   completionCmd(),
+		"__complete":   completeCmd(),
+	}
+
+	args, err := globalFlags(os.Args[1:], commands)
+	if err != nil {
+		return errcode.Wrap(errcode.Usage, err)
+	}
+	applyConfig()
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		auth.SetPassphrasePrompt(promptPassphrase)
+	}
+	if logFile != "" {
+		if err := logging.OpenLogFile(logFile); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ Warning: not logging to %s: %v\n", logFile, err)
+		}
+		logging.Logf("aigg %s (%s, %s/%s): %s", version, runtime.Version(), runtime.GOOS, runtime.GOARCH, strings.Join(os.Args[1:], " "))
+		defer func() {
+			if err != nil {
+				logging.LogError(err)
+			}
+			logging.CloseLogFile()
+		}()
+	}
+	if plainOutput || githubOutput() {
+		if err := setFilteredOutput(); err != nil {
+			return fmt.Errorf("failed to set up output: %w", err)
+		}
+		defer func() {
+			flushOutput()
+			if err != nil {
+				err = plainError{err}
+			}
+		}()
+	}
 
 	if len(args) == 0 {
 		printUsage(commands)
@@ -53,59 +119,230 @@ func Execute() error {
 
 	cmd, ok := commands[cmdName]
 	if !ok {
+		if plugin := findPlugin(cmdName); plugin != "" {
+			return runPlugin(plugin, args[1:])
+		}
 		fmt.Printf("Unknown command: %s\n\n", cmdName)
 		printUsage(commands)
-		return fmt.Errorf("unknown command: %s", cmdName)
+		return errcode.Errorf(errcode.Usage, "unknown command: %s", cmdName)
+	}
+
+	if recordsHistory(cmdName, args[1:]) {
+		start := time.Now()
+		err = runCommand(cmd, args[1:])
+		recordHistory(cmdName, args[1:], start, err)
+		return err
 	}
+	return runCommand(cmd, args[1:])
+}
 
-	// Parse flags if command has them
+// runCommand parses cmd's flags, if it has them, and runs it with the
+// remaining arguments
+func runCommand(cmd *Command, args []string) error {
 	if cmd.Flags != nil {
-		// Separate flags and positional args manually
-		// This allows flags to appear anywhere (before or after positional args)
-		var flagArgs []string
-		var posArgs []string
-
-		for i := 1; i < len(args); i++ {
-			arg := args[i]
-			if strings.HasPrefix(arg, "-") {
-				// This is a flag
-				flagArgs = append(flagArgs, arg)
-				// Check if next arg is the flag value (doesn't start with -)
-				if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+		var err error
+		if args, err = parseFlags(cmd.Flags, args); err != nil {
+			return err
+		}
+	}
+	return cmd.Run(args)
+}
+
+// parseFlags parses the flags in args, which may appear before or after
+// the positional arguments, and returns the positional arguments
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	// Separate flags and positional args manually
+	// This allows flags to appear anywhere (before or after positional args)
+	var flagArgs []string
+	var posArgs []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if strings.HasPrefix(arg, "-") {
+			// This is a flag
+			flagArgs = append(flagArgs, arg)
+			// Check if next arg is the flag value (doesn't start with -)
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				flagArgs = append(flagArgs, args[i])
+			}
+		} else {
+			// This is a positional arg
+			posArgs = append(posArgs, arg)
+		}
+	}
+
+	// Parse flags first
+	if err := flags.Parse(flagArgs); err != nil {
+		return nil, errcode.Wrap(errcode.Usage, err)
+	}
+
+	// Then add any remaining args from flag parsing
+	return append(posArgs, flags.Args()...), nil
+}
+
+// colorFlag is the --color option, "" when it isn't given
+var colorFlag string
+
+// plainFlag is set by --plain
+var plainFlag bool
+
+// logFileFlag is the --log-file option, "" when it isn't given
+var logFileFlag string
+
+// outputFlag is the --output option, "" when it isn't given
+var outputFlag string
+
+// jsonOutput is set by --json: the error aigg fails with is printed as JSON,
+// and commands that support it, such as list, print their results as JSON
+var jsonOutput bool
+
+// globalFlags sets the log level from --quiet (-q), --verbose (-v) and
+// --debug, tracing from --trace, colorFlag from --color, plainFlag from
+// --plain, outputFlag from --output, logFileFlag from --log-file and
+// jsonOutput from --json, and returns args without them. They can come
+// before the command or among its arguments, but not after "--" or in the
+// arguments of exec or a plugin (a command that isn't in commands), which
+// belong to the agent or the plugin. A command's own flag of the same name,
+// such as schema's --output, wins among its arguments.
+func globalFlags(args []string, commands map[string]*Command) ([]string, error) {
+	var rest []string
+	var quiet, verbose, debug bool
+	colorFlag, plainFlag, outputFlag, logFileFlag, jsonOutput = "", false, "", "", false
+	logging.SetTrace(false)
+	passthrough := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !passthrough {
+			if value, ok := strings.CutPrefix(arg, "--color="); ok {
+				colorFlag = value
+				if colorFlag == "" {
+					return nil, errcode.Errorf(errcode.Usage, "--color needs a value: %s, %s or %s", config.ColorAuto, config.ColorAlways, config.ColorNever)
+				}
+				continue
+			}
+			if value, ok := strings.CutPrefix(arg, "--output="); ok && !commandHasFlag(rest, commands, "output") {
+				outputFlag = value
+				if outputFlag == "" {
+					return nil, errcode.Errorf(errcode.Usage, "--output needs a value: %s or %s", config.OutputText, config.OutputGitHub)
+				}
+				continue
+			}
+			if value, ok := strings.CutPrefix(arg, "--log-file="); ok {
+				if value == "" {
+					return nil, errcode.Errorf(errcode.Usage, "--log-file needs a path")
+				}
+				logFileFlag = value
+				continue
+			}
+			switch arg {
+			case "-q", "--quiet":
+				quiet = true
+				continue
+			case "-v", "--verbose":
+				verbose = true
+				continue
+			case "--debug":
+				debug = true
+				continue
+			case "--color":
+				if i+1 == len(args) {
+					return nil, errcode.Errorf(errcode.Usage, "--color needs a value: %s, %s or %s", config.ColorAuto, config.ColorAlways, config.ColorNever)
+				}
+				i++
+				colorFlag = args[i]
+				continue
+			case "--trace":
+				logging.SetTrace(true)
+				continue
+			case "--log-file":
+				if i+1 == len(args) {
+					return nil, errcode.Errorf(errcode.Usage, "--log-file needs a path")
+				}
+				i++
+				logFileFlag = args[i]
+				continue
+			case "--output":
+				if !commandHasFlag(rest, commands, "output") {
+					if i+1 == len(args) {
+						return nil, errcode.Errorf(errcode.Usage, "--output needs a value: %s or %s", config.OutputText, config.OutputGitHub)
+					}
 					i++
-					flagArgs = append(flagArgs, args[i])
+					outputFlag = args[i]
+					continue
+				}
+			case "--plain":
+				plainFlag = true
+				continue
+			case "--json":
+				jsonOutput = true
+				continue
+			case "--":
+				passthrough = true
+			default:
+				if len(rest) == 0 && !strings.HasPrefix(arg, "-") {
+					passthrough = arg == "exec" || commands[arg] == nil
 				}
-			} else {
-				// This is a positional arg
-				posArgs = append(posArgs, arg)
 			}
 		}
+		rest = append(rest, arg)
+	}
 
-		// Parse flags first
-		if err := cmd.Flags.Parse(flagArgs); err != nil {
-			return err
+	if colorFlag != "" {
+		if err := (&config.Config{Color: colorFlag}).Validate(); err != nil {
+			return nil, fmt.Errorf("--color: %w", err)
+		}
+	}
+	if outputFlag != "" {
+		if err := (&config.Config{Output: outputFlag}).Validate(); err != nil {
+			return nil, fmt.Errorf("--output: %w", err)
 		}
+	}
 
-		// Then add any remaining args from flag parsing
-		posArgs = append(posArgs, cmd.Flags.Args()...)
-		args = posArgs
-	} else {
-		args = args[1:]
+	switch {
+	case quiet && (verbose || debug):
+		return nil, fmt.Errorf("--quiet can't be combined with --verbose or --debug")
+	case quiet:
+		logging.SetLevel(logging.LevelQuiet)
+	case debug:
+		logging.SetLevel(logging.LevelDebug)
+	case verbose:
+		logging.SetLevel(logging.LevelVerbose)
 	}
+	return rest, nil
+}
 
-	return cmd.Run(args)
+// commandHasFlag reports whether the command rest starts with defines a
+// flag called name
+func commandHasFlag(rest []string, commands map[string]*Command, name string) bool {
+	if len(rest) == 0 {
+		return false
+	}
+	cmd := commands[rest[0]]
+	return cmd != nil && cmd.Flags != nil && cmd.Flags.Lookup(name) != nil
 }
 
 func printUsage(commands map[string]*Command) {
 	fmt.Println("aigg - Easily manage and reuse your AI agents between projects")
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Println("  aigg <command> [options]")
+	fmt.Println("  aigg [global options] <command> [options]")
+	fmt.Println()
+	fmt.Println("Global options:")
+	fmt.Println("  -q, --quiet     Print only results, warnings and errors")
+	fmt.Println("  -v, --verbose   Also print HTTP requests, paths and hashes")
+	fmt.Println("  --debug         Also dump registry responses (secrets redacted)")
+	fmt.Println("  --trace         Record each registry request's metadata as JSON (secrets redacted)")
+	fmt.Println("  --log-file <f>  Append a debug log of the command to a file, as JSON lines")
+	fmt.Println("  --color <mode>  Style output: auto (default), always or never")
+	fmt.Println("  --plain         Print ASCII markers in place of emoji and symbols")
+	fmt.Println("  --output <fmt>  text (default), or github: GitHub Actions annotations, groups and job summaries")
+	fmt.Println("  --json          Print errors as JSON with their code (see Exit codes), and list's and tree's results as JSON")
 	fmt.Println()
 	fmt.Println("Commands:")
 
 	// Define order for better UX
-	order := []string{"init", "add", "install", "uninstall", "exec", "clean", "rm", "validate", "scan", "build", "push", "pull", "list", "show-deps", "remove", "remove-all", "delete", "login", "logout", "search", "version", "completion"}
+	order := []string{"init", "add", "install", "uninstall", "doctor", "ide", "hooks", "exec", "clean", "migrate", "rm", "files", "check-ignore", "validate", "lint", "scan", "build", "push", "pack", "pull", "export", "import", "diff", "list", "info", "tree", "show-deps", "licenses", "cache", "serve", "proxy", "mcp", "delete", "history", "login", "logout", "whoami", "search", "config", "schema", "version", "self-update", "completion"}
 
 	for _, name := range order {
 		if cmd, ok := commands[name]; ok {
@@ -113,6 +350,14 @@ func printUsage(commands map[string]*Command) {
 		}
 	}
 
+	if plugins := listPlugins(commands); len(plugins) > 0 {
+		fmt.Println()
+		fmt.Println("Plugins (aigg-<name> on PATH):")
+		for _, name := range plugins {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+
 	fmt.Println()
 	fmt.Println("Workflow (package consumer):")
 	fmt.Println("  aigg add docker.io/org/my-utils:1.0.0  # Add package to aigogo.lock")
@@ -125,6 +370,14 @@ func printUsage(commands map[string]*Command) {
 	fmt.Println("  aigg build utils:1.0.0                 # Build locally")
 	fmt.Println("  aigg push docker.io/org/utils:1.0.0    # Push to registry")
 	fmt.Println()
+	fmt.Println("Exit codes (with the code --json prints):")
+	var codes []string
+	for _, code := range errcode.Codes() {
+		codes = append(codes, fmt.Sprintf("%d %s", code.ExitCode(), code))
+	}
+	fmt.Printf("  %s\n", strings.Join(codes[:5], ", "))
+	fmt.Printf("  %s\n", strings.Join(codes[5:], ", "))
+	fmt.Println()
 	// fmt.Println("For more information, visit: https://github.com/aupeachmo/aigogo")
 	// fmt.Println("For more information, visit: https://github.com/aupeachmo/aigogo")
 }
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/logging"
+)
+
+func TestGlobalFlags(t *testing.T) {
+	t.Cleanup(func() { logging.SetLevel(logging.LevelNormal) })
+
+	tests := []struct {
+		args      []string
+		wantArgs  []string
+		wantLevel logging.Level
+	}{
+		{[]string{"install"}, []string{"install"}, logging.LevelNormal},
+		{[]string{"--quiet", "install"}, []string{"install"}, logging.LevelQuiet},
+		{[]string{"install", "-q", "--frozen"}, []string{"install", "--frozen"}, logging.LevelQuiet},
+		{[]string{"pull", "-v", "docker.io/org/utils:1.0.0"}, []string{"pull", "docker.io/org/utils:1.0.0"}, logging.LevelVerbose},
+		{[]string{"--verbose", "add", "utils:1.0.0", "--debug"}, []string{"add", "utils:1.0.0"}, logging.LevelDebug},
+		// exec's arguments and those after -- belong to the agent
+		{[]string{"-v", "exec", "agent", "--verbose"}, []string{"exec", "agent", "--verbose"}, logging.LevelVerbose},
+		{[]string{"install", "--", "-q"}, []string{"install", "--", "-q"}, logging.LevelNormal},
+		// and a plugin's arguments to the plugin
+		{[]string{"-q", "lint-prompts", "-v"}, []string{"lint-prompts", "-v"}, logging.LevelQuiet},
+	}
+	commands := map[string]*Command{"install": {}, "pull": {}, "add": {}, "exec": {}}
+	for _, tt := range tests {
+		logging.SetLevel(logging.LevelNormal)
+		args, err := globalFlags(tt.args, commands)
+		if err != nil {
+			t.Errorf("globalFlags(%q) failed: %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(args, tt.wantArgs) {
+			t.Errorf("globalFlags(%q) = %q, want %q", tt.args, args, tt.wantArgs)
+		}
+		if level := logging.GetLevel(); level != tt.wantLevel {
+			t.Errorf("globalFlags(%q) set level %d, want %d", tt.args, level, tt.wantLevel)
+		}
+	}
+
+	if _, err := globalFlags([]string{"--quiet", "install", "--verbose"}, commands); err == nil {
+		t.Error("expected --quiet with --verbose to fail")
+	}
+}
+
+func TestGlobalOutputFlags(t *testing.T) {
+	commands := map[string]*Command{"install": {}, "exec": {}}
+
+	args, err := globalFlags([]string{"--color", "never", "install", "--plain", "--frozen"}, commands)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"install", "--frozen"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("globalFlags() = %q, want %q", args, want)
+	}
+	if colorFlag != "never" || !plainFlag || jsonOutput {
+		t.Errorf("colorFlag = %q, plainFlag = %v, jsonOutput = %v", colorFlag, plainFlag, jsonOutput)
+	}
+	if _, err := globalFlags([]string{"install", "--json"}, commands); err != nil || !jsonOutput {
+		t.Errorf("--json: jsonOutput = %v, err = %v", jsonOutput, err)
+	}
+
+	if _, err := globalFlags([]string{"--color=always", "install"}, commands); err != nil || colorFlag != "always" || plainFlag {
+		t.Errorf("--color=always: colorFlag = %q, plainFlag = %v, err = %v", colorFlag, plainFlag, err)
+	}
+	// exec's arguments belong to the agent
+	if _, err := globalFlags([]string{"exec", "agent", "--plain"}, commands); err != nil || plainFlag {
+		t.Errorf("exec agent --plain: plainFlag = %v, err = %v", plainFlag, err)
+	}
+
+	if args, err := globalFlags([]string{"--output", "github", "install", "--output=text"}, commands); err != nil || outputFlag != "text" || len(args) != 1 {
+		t.Errorf("--output: outputFlag = %q, args = %q, err = %v", outputFlag, args, err)
+	}
+	// schema's own --output names a file
+	schema := map[string]*Command{"schema": schemaCmd()}
+	if args, err := globalFlags([]string{"--output=github", "schema", "--output", "schema.json"}, schema); err != nil || outputFlag != "github" || len(args) != 3 {
+		t.Errorf("schema --output: outputFlag = %q, args = %q, err = %v", outputFlag, args, err)
+	}
+
+	for _, bad := range [][]string{{"--color", "sometimes", "install"}, {"install", "--color"}, {"--color=", "install"}, {"--output", "gitlab", "install"}, {"install", "--output"}} {
+		if _, err := globalFlags(bad, commands); err == nil {
+			t.Errorf("globalFlags(%q) should fail", bad)
+		}
+	}
+}
+
+func TestGlobalLogFlags(t *testing.T) {
+	commands := map[string]*Command{"pull": {}}
+	t.Cleanup(func() { logging.SetTrace(false) })
+
+	args, err := globalFlags([]string{"--log-file", "aigg.log", "pull", "--trace", "ref"}, commands)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"pull", "ref"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("globalFlags() = %q, want %q", args, want)
+	}
+	if logFileFlag != "aigg.log" {
+		t.Errorf("logFileFlag = %q", logFileFlag)
+	}
+	if _, err := globalFlags([]string{"--log-file=debug.log", "pull"}, commands); err != nil || logFileFlag != "debug.log" {
+		t.Errorf("--log-file=debug.log: logFileFlag = %q, err = %v", logFileFlag, err)
+	}
+	for _, bad := range [][]string{{"pull", "--log-file"}, {"--log-file=", "pull"}} {
+		if _, err := globalFlags(bad, commands); err == nil {
+			t.Errorf("globalFlags(%q) should fail", bad)
+		}
+	}
+}
+
+// setHome points HOME at home for the test, with the XDG base directory
+// variables unset so that aigg's files are all in home
+func setHome(t *testing.T, home string) {
+	t.Helper()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	for _, name := range []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_CACHE_HOME"} {
+		t.Setenv(name, "")
+	}
+}
@@ -1,16 +1,24 @@
 package cmd
 
 import (
+	"flag"
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/aupeachmo/aigogo/pkg/depgen"
 	"github.com/aupeachmo/aigogo/pkg/manifest"
 )
 
 func scanCmd() *Command {
+	flags := flag.NewFlagSet("scan", flag.ContinueOnError)
+	offline := flags.Bool("offline", false, "Don't query PyPI/npm for latest versions")
+	noCache := flags.Bool("no-cache", false, "Re-scan every file, ignoring .aigogo/scan-cache.json")
+
 	return &Command{
 		Name:        "scan",
 		Description: "Scan source files and suggest dependencies",
+		Flags:       flags,
 		Run: func(args []string) error {
 			// Load manifest
 			m, err := manifest.Load("aigogo.json")
@@ -26,21 +34,45 @@ func scanCmd() *Command {
 			if err != nil {
 				return fmt.Errorf("failed to initialize file discovery: %w", err)
 			}
-			files, err := discovery.Discover(m.Files, m.Language)
+			files, err := discovery.Discover(m.Files, m.AllLanguages()...)
 			if err != nil {
 				return fmt.Errorf("failed to discover files: %w", err)
 			}
+			files = m.Files.WithoutData(files)
 
 			if len(files) == 0 {
 				fmt.Println("No files found to scan")
 				return nil
 			}
 
-			// Scan for imports
-			scanner := depgen.NewScanner()
-			imports, err := scanner.ScanFiles(files, m.Language.Name)
-			if err != nil {
-				return fmt.Errorf("scan failed: %w", err)
+			// Scan for imports, one language at a time for multi-language
+			// packages. The scan cache holds a single language's results,
+			// so only the primary language uses it.
+			type languageImport struct {
+				depgen.ImportInfo
+				view *manifest.Manifest
+			}
+			var imports []languageImport
+			groups := m.GroupFilesByLanguage(files)
+			cache := openScanCache(".", *noCache)
+			for i, lang := range m.AllLanguages() {
+				scanner := depgen.NewScanner()
+				scanner.SetLanguageVersion(lang.Version)
+				scanner.SetProgress(printScanProgress)
+				if i == 0 {
+					scanner.SetCache(cache)
+				}
+				found, err := scanner.ScanFiles(groups[lang.Name], lang.Name)
+				if err != nil {
+					return fmt.Errorf("scan failed: %w", err)
+				}
+				view := m.ForLanguage(lang.Name)
+				for _, imp := range found {
+					imports = append(imports, languageImport{imp, view})
+				}
+			}
+			if cache != nil {
+				cache.Save()
 			}
 
 			if len(imports) == 0 {
@@ -48,35 +80,48 @@ func scanCmd() *Command {
 				return nil
 			}
 
+			endGroup := startGroup(fmt.Sprintf("Found %d external dependencies", len(imports)))
 			fmt.Printf("Found %d external dependencies:\n", len(imports))
 			for _, imp := range imports {
-				fmt.Printf("  - %s (in %s)\n", imp.Package, imp.SourceFile)
-			}
-			fmt.Println()
-
-			// Get currently declared dependencies
-			declaredDeps := make(map[string]bool)
-			if m.Dependencies != nil {
-				for _, dep := range m.Dependencies.Runtime {
-					declaredDeps[dep.Package] = true
+				if imp.Cell > 0 {
+					fmt.Printf("  - %s (in %s cell %d)\n", imp.Package, imp.SourceFile, imp.Cell)
+				} else {
+					fmt.Printf("  - %s (in %s)\n", imp.Package, imp.SourceFile)
 				}
 			}
+			fmt.Println()
+			endGroup()
 
 			// Find missing
-			var missing []string
+			var missing []languageImport
 			for _, imp := range imports {
-				if !declaredDeps[imp.Package] {
-					missing = append(missing, imp.Package)
+				if !isDeclared(imp.view, imp.Package) {
+					missing = append(missing, imp)
 				}
 			}
 
 			if len(missing) > 0 {
 				fmt.Println("💡 Add these to your aigogo.json dependencies:")
 				fmt.Println()
-				for _, pkg := range missing {
-					suggestion := suggestDependency(pkg, m.Language.Name)
+				resolver := depgen.NewVersionResolver(*offline)
+				var findings []depgen.Finding
+				for _, imp := range missing {
+					suggestion := suggestDependencyLatest(resolver, imp.Package, imp.view.Language.Name)
+					if len(m.Languages) > 0 {
+						// Tell the author which language the dependency is for
+						suggestion = strings.TrimSuffix(suggestion, "}") + fmt.Sprintf(`, "language": "%s"}`, imp.view.Language.Name)
+					}
 					fmt.Printf("  %s\n", suggestion)
+					findings = append(findings, depgen.Finding{
+						Severity: depgen.SeverityWarning,
+						Rule:     depgen.RuleMissingDependency,
+						Message:  fmt.Sprintf("%s is imported but not declared in aigogo.json; add %s", imp.Package, suggestion),
+						Package:  imp.Package,
+						Import:   &imp.ImportInfo,
+					})
 				}
+				resolver.SaveCache()
+				printFindingAnnotations("aigg scan", findings, "aigogo.json")
 			} else {
 				fmt.Println("✅ All detected dependencies are already declared!")
 			}
@@ -86,6 +131,45 @@ func scanCmd() *Command {
 	}
 }
 
+// printScanProgress reports progress when scanning large trees
+func printScanProgress(done, total int) {
+	fmt.Printf("  Scanned %d/%d files...\n", done, total)
+}
+
+// openScanCache loads the scan cache of the project in dir, or returns nil
+// when caching is disabled with --no-cache
+func openScanCache(dir string, noCache bool) *depgen.ScanCache {
+	if noCache {
+		return nil
+	}
+	return depgen.LoadScanCache(filepath.Join(dir, depgen.ScanCacheFile))
+}
+
+// isDeclared reports whether an imported package is covered by a declared
+// runtime dependency
+func isDeclared(m *manifest.Manifest, pkg string) bool {
+	if m.Dependencies == nil {
+		return false
+	}
+	for _, dep := range m.Dependencies.Runtime {
+		if depgen.DependencyMatchesImport(m.Language.Name, dep.Package, pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestDependencyLatest suggests a dependency entry using the latest
+// published version from PyPI/npm, falling back to a placeholder range when
+// the lookup fails or is unsupported for the language.
+func suggestDependencyLatest(resolver *depgen.VersionResolver, pkg, lang string) string {
+	latest, err := resolver.Latest(pkg, lang)
+	if err != nil {
+		return suggestDependency(pkg, lang)
+	}
+	return fmt.Sprintf(`{"package": "%s", "version": "%s"}`, pkg, depgen.SuggestConstraint(latest, lang))
+}
+
 func suggestDependency(pkg, lang string) string {
 	switch lang {
 	case "python":
@@ -96,6 +180,14 @@ func suggestDependency(pkg, lang string) string {
 		return fmt.Sprintf(`{"package": "%s", "version": "v1.0.0"}`, pkg)
 	case "rust":
 		return fmt.Sprintf(`{"package": "%s", "version": "1.0"}`, pkg)
+	case "ruby":
+		return fmt.Sprintf(`{"package": "%s", "version": "~> 1.0"}`, pkg)
+	case "java":
+		return fmt.Sprintf(`{"package": "%s:<artifactId>", "version": "[1.0,2.0)"}`, pkg)
+	case "csharp":
+		return fmt.Sprintf(`{"package": "%s", "version": "[1.0.0,2.0.0)"}`, pkg)
+	case "php":
+		return fmt.Sprintf(`{"package": "%s", "version": "^1.0"}`, depgen.ComposerPackageHint(pkg))
 	}
 	return fmt.Sprintf(`{"package": "%s", "version": "1.0.0"}`, pkg)
 }
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func schemaCmd() *Command {
+	flags := flag.NewFlagSet("schema", flag.ContinueOnError)
+	output := flags.String("output", "", "Write the schema to a file instead of stdout")
+
+	return &Command{
+		Name:        "schema",
+		Description: "Print the JSON Schema for aigogo.json",
+		Flags:       flags,
+		Run: func(args []string) error {
+			if *output == "" {
+				_, err := os.Stdout.Write(manifest.Schema())
+				return err
+			}
+
+			if err := os.WriteFile(*output, manifest.Schema(), 0644); err != nil {
+				return fmt.Errorf("failed to write schema: %w", err)
+			}
+			fmt.Printf("✓ Wrote %s\n", *output)
+			fmt.Printf("  Point editors at it with \"$schema\": \"%s\" in aigogo.json\n", *output)
+			return nil
+		},
+	}
+}
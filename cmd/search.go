@@ -1,28 +1,88 @@
 package cmd
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
 )
 
 func searchCmd() *Command {
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	registry := registryFlag(flags)
+
 	return &Command{
 		Name:        "search",
 		Description: "Search for agents in a registry",
+		Flags:       flags,
 		Run: func(args []string) error {
 			if len(args) < 1 {
-				return fmt.Errorf("usage: aigg search <term>")
+				return errcode.Errorf(errcode.Usage, "usage: aigg search <term> [--registry <host[/namespace]>]")
+			}
+			searchRegistry, err := commandRegistry(*registry)
+			if err != nil {
+				return err
+			}
+			if searchRegistry == "" {
+				searchRegistry = "docker.io"
 			}
 
-			// Note: This is a placeholder. Actual registry search would require
-			// implementing the Docker Registry HTTP API V2 search endpoint.
-			// Most registries don't support search via API without authentication
-			// and additional complexity.
-
-			fmt.Println("Search functionality coming soon!")
-			fmt.Println("For now, search directly on your registry's web interface.")
-			// fmt.Println("  - Package Registry (e.g., Docker Hub, GHCR, GitLab, etc.)")
-
+			results, err := searchPackages(commandContext(), searchRegistry, args[0])
+			if err != nil {
+				return err
+			}
+			if len(results) == 0 {
+				fmt.Printf("No packages matching %q in %s\n", args[0], searchRegistry)
+			}
+			for _, r := range results {
+				if r.err != nil {
+					fmt.Printf("%s  (tags unavailable: %v)\n", r.ref, r.err)
+				} else {
+					fmt.Printf("%s  %s\n", r.ref, strings.Join(r.tags, ", "))
+				}
+			}
 			return nil
 		},
 	}
 }
+
+// searchResult is a repository aigg search found, with its tags or the
+// error listing them
+type searchResult struct {
+	ref  string
+	tags []string
+	err  error
+}
+
+// searchPackages finds the repositories of registry whose name contains
+// term, from its catalog. A registry with a namespace, such as
+// localhost:5000/team, is searched within it.
+func searchPackages(ctx context.Context, registry, term string) ([]searchResult, error) {
+	host, namespace, _ := strings.Cut(registry, "/")
+	if host == "docker.io" {
+		return nil, errcode.Errorf(errcode.Usage, "Docker Hub doesn't list its repositories through the registry API\nSearch on https://hub.docker.com, or give --registry for a registry that does, e.g. a self-hosted one")
+	}
+
+	puller := docker.NewPuller()
+	repositories, err := puller.Catalog(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s: %w", host, err)
+	}
+
+	var results []searchResult
+	for _, repository := range repositories {
+		if namespace != "" && !strings.HasPrefix(repository, namespace+"/") {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(repository), strings.ToLower(term)) {
+			continue
+		}
+		r := searchResult{ref: host + "/" + repository}
+		r.tags, r.err = puller.Tags(ctx, r.ref)
+		results = append(results, r)
+	}
+	return results, nil
+}
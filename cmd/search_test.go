@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+)
+
+func TestSearchPackages(t *testing.T) {
+	setHome(t, t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/_catalog":
+			_, _ = w.Write([]byte(`{"repositories": ["team/string-utils", "team/http", "other/utils"]}`))
+		case "/v2/team/string-utils/tags/list":
+			_, _ = w.Write([]byte(`{"name": "team/string-utils", "tags": ["1.0.0", "1.1.0"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "http://")
+	docker.SetInsecureRegistries([]string{registry})
+	t.Cleanup(func() { docker.SetInsecureRegistries(nil) })
+
+	results, err := searchPackages(context.Background(), registry, "UTILS")
+	if err != nil {
+		t.Fatalf("searchPackages() error = %v", err)
+	}
+	if len(results) != 2 || results[0].ref != registry+"/team/string-utils" || !reflect.DeepEqual(results[0].tags, []string{"1.0.0", "1.1.0"}) {
+		t.Fatalf("searchPackages() = %+v", results)
+	}
+	// A repository whose tags can't be listed is still found
+	if results[1].ref != registry+"/other/utils" || errcode.Of(results[1].err) != errcode.NotFound {
+		t.Errorf("searchPackages() other/utils = %+v, want a not found error", results[1])
+	}
+
+	// A namespace limits the search to its repositories
+	results, err = searchPackages(context.Background(), registry+"/team", "utils")
+	if err != nil || len(results) != 1 || results[0].ref != registry+"/team/string-utils" {
+		t.Errorf("searchPackages(namespace) = %+v, %v", results, err)
+	}
+
+	if _, err := searchPackages(context.Background(), "docker.io/org", "utils"); errcode.Of(err) != errcode.Usage {
+		t.Errorf("searchPackages(docker.io) error = %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/selfupdate"
+)
+
+func selfUpdateCmd() *Command {
+	flags := flag.NewFlagSet("self-update", flag.ContinueOnError)
+	check := flags.Bool("check", false, "Only report whether a newer release is available")
+	force := flags.Bool("force", false, "Update even when a package manager installed aigg, or it is up to date")
+	skipVerify := flags.Bool("skip-verify", false, "Don't verify the build attestation, only the checksum (when gh isn't installed)")
+
+	return &Command{
+		Name:        "self-update",
+		Description: "Update aigg to the latest release from GitHub",
+		Flags:       flags,
+		Run: func(args []string) error {
+			if len(args) > 1 {
+				return errcode.Errorf(errcode.Usage, "usage: aigg self-update [version] [--check] [--force] [--skip-verify]")
+			}
+
+			var release *selfupdate.Release
+			var err error
+			if len(args) == 1 {
+				tag := "v" + strings.TrimPrefix(args[0], "v")
+				if release, err = selfupdate.ReleaseByTag(tag); err != nil {
+					return fmt.Errorf("failed to look up aigg %s: %w", tag, err)
+				}
+			} else if release, err = selfupdate.LatestRelease(); err != nil {
+				return fmt.Errorf("failed to look up the latest release: %w", err)
+			}
+
+			// An explicit version may be a downgrade
+			if len(args) == 0 && !*force && !newerVersion(release.Tag, version) {
+				fmt.Printf("✓ aigg %s is up to date\n", version)
+				return nil
+			}
+			if *check {
+				fmt.Printf("aigg %s is available (installed: %s)\n", release.Tag, version)
+				fmt.Printf("  %s\n", release.URL)
+				fmt.Println("Run 'aigg self-update' to update")
+				return nil
+			}
+
+			exe, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("failed to locate the aigg executable: %w", err)
+			}
+			if exe, err = filepath.EvalSymlinks(exe); err != nil {
+				return fmt.Errorf("failed to locate the aigg executable: %w", err)
+			}
+			if name, upgrade := selfupdate.Manager(exe); name != "" && !*force {
+				msg := fmt.Sprintf("%s was installed by %s, which would undo or break the update", exe, name)
+				if upgrade != "" {
+					msg += fmt.Sprintf("\nUpdate it with: %s", upgrade)
+				}
+				return fmt.Errorf("%s\nUse --force to replace it anyway", msg)
+			}
+
+			logging.Printf("Downloading aigg %s for %s/%s...\n", release.Tag, runtime.GOOS, runtime.GOARCH)
+			archive, binary, err := selfupdate.Download(release)
+			if err != nil {
+				return err
+			}
+			logging.Println("✓ Verified SHA-256 checksum")
+			if err := verifyProvenance(archive, selfupdate.ArchiveName(runtime.GOOS, runtime.GOARCH), *skipVerify); err != nil {
+				return err
+			}
+
+			if err := selfupdate.Replace(exe, binary); err != nil {
+				if errors.Is(err, os.ErrPermission) {
+					return fmt.Errorf("%w\nRun it again with permission to write %s (e.g. with sudo)", err, filepath.Dir(exe))
+				}
+				return err
+			}
+			fmt.Printf("✓ Updated aigg %s -> %s (%s)\n", version, release.Tag, exe)
+			return nil
+		},
+	}
+}
+
+// newerVersion reports whether the release tagged tag is newer than the
+// current version. A current version that isn't semver, such as a
+// development build, is older than any release.
+func newerVersion(tag, current string) bool {
+	next, err := manifest.ParseSemver(strings.TrimPrefix(tag, "v"))
+	if err != nil {
+		return false
+	}
+	cur, err := manifest.ParseSemver(strings.TrimPrefix(current, "v"))
+	if err != nil {
+		return true
+	}
+	return next.Compare(cur) > 0
+}
+
+// verifyProvenance checks the build attestation of a release archive with
+// the GitHub CLI. Without gh it fails, so the binary isn't replaced on the
+// checksum alone, unless skip is set (--skip-verify), which is reported.
+func verifyProvenance(archive []byte, name string, skip bool) error {
+	if skip {
+		fmt.Fprintln(os.Stderr, "⚠ Warning: skipped verifying the build attestation (--skip-verify); only the checksum was verified")
+		return nil
+	}
+	gh, err := exec.LookPath("gh")
+	if err != nil {
+		return errcode.Errorf(errcode.Integrity, "can't verify the build attestation of %s: gh not found\nInstall the GitHub CLI (https://cli.github.com) and log in with 'gh auth login', or pass --skip-verify to rely on the SHA-256 checksum alone", name)
+	}
+
+	dir, err := os.MkdirTemp("", "aigg-update-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, archive, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	out, err := exec.Command(gh, "attestation", "verify", path, "--repo", selfupdate.Repo).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to verify the build attestation of %s: %s\n(gh needs to be logged in: gh auth login)", name, strings.TrimSpace(string(out)))
+	}
+	logging.Println("✓ Verified build attestation")
+	return nil
+}
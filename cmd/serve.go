@@ -0,0 +1,378 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+)
+
+// defaultServeAddr is where aigg serve listens unless --addr says otherwise
+const defaultServeAddr = "127.0.0.1:7878"
+
+// serveShutdownTimeout is how long aigg serve and aigg proxy wait for
+// requests in flight when they're stopped
+const serveShutdownTimeout = 5 * time.Second
+
+func serveCmd() *Command {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", defaultServeAddr, "Address to listen on")
+	remote := flags.Bool("remote", false, "Fetch registry packages that aren't local, without caching them")
+
+	return &Command{
+		Name:        "serve",
+		Description: "Serve the local cache, store and aigogo.lock as read-only JSON over HTTP",
+		Flags:       flags,
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return errcode.Errorf(errcode.Usage, "unexpected argument: %s", args[0])
+			}
+
+			ln, err := net.Listen("tcp", *addr)
+			if err != nil {
+				return fmt.Errorf("failed to listen on %s: %w", *addr, err)
+			}
+			if !isLoopbackAddr(ln.Addr()) {
+				fmt.Fprintf(os.Stderr, "⚠ Warning: %s can be reached from other machines, and aigg serve has no authentication\n", ln.Addr())
+			}
+
+			handler := newServeHandler(*addr, func() (*aigogo.Client, error) {
+				client, err := newClient()
+				if err != nil {
+					return nil, err
+				}
+				client.SetOutput(os.Stderr, os.Stderr)
+				client.SetLogger(stderrLogger{})
+				client.SetOffline(!*remote)
+				return client, nil
+			})
+			fmt.Printf("Serving package metadata on http://%s (press Ctrl-C to stop)\n", ln.Addr())
+			return serveUntilInterrupted(ln, handler)
+		},
+	}
+}
+
+// serveUntilInterrupted serves handler on ln until commandContext is
+// canceled, then waits for the requests in flight
+func serveUntilInterrupted(ln net.Listener, handler http.Handler) error {
+	srv := &http.Server{Handler: logRequests(handler), ReadHeaderTimeout: 10 * time.Second}
+	ctx := commandContext()
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("server failed: %w", err)
+	}
+	<-stopped
+	return nil
+}
+
+// logRequests logs each request handler answers, and its status, for
+// --verbose
+func logRequests(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(rec, r)
+		logging.Verbosef("%s %s %d\n", r.Method, r.URL.RequestURI(), rec.status)
+	})
+}
+
+// isLoopbackAddr reports whether addr only accepts connections from this
+// machine
+func isLoopbackAddr(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	return ok && tcp.IP.IsLoopback()
+}
+
+// serveEndpoints are the endpoints of aigg serve, as GET / lists them
+var serveEndpoints = map[string]string{
+	"/api/packages":                "cached packages, as aigg list --json prints them (?filter=key=value, ?language=, ?sort=)",
+	"/api/lock":                    "the project's aigogo.lock, with whether each package is in the package store",
+	"/api/manifest?ref=":           "the aigogo.json of a package",
+	"/api/files?ref=":              "the files of a package, with their sizes",
+	"/api/diff?from=&to=&context=": "how two packages differ, as aigg diff --json prints it",
+}
+
+// serveHandler answers aigg serve's requests. Each request gets its own
+// client, as a client isn't safe for concurrent use.
+type serveHandler struct {
+	newClient func() (*aigogo.Client, error)
+}
+
+// newServeHandler returns the handler of aigg serve's endpoints for a
+// server listening on addr, reading packages through the clients newClient
+// returns
+func newServeHandler(addr string, newClient func() (*aigogo.Client, error)) http.Handler {
+	h := &serveHandler{newClient: newClient}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", h.index)
+	mux.HandleFunc("GET /api/packages", h.packages)
+	mux.HandleFunc("GET /api/lock", h.lock)
+	mux.HandleFunc("GET /api/manifest", h.manifest)
+	mux.HandleFunc("GET /api/files", h.files)
+	mux.HandleFunc("GET /api/diff", h.diff)
+	return checkServeHost(addr, mux)
+}
+
+// checkServeHost refuses requests whose Host header isn't localhost, an IP
+// address or the host of addr, so a web page can't reach aigg serve
+// through a domain name that it points at this machine (DNS rebinding)
+func checkServeHost(addr string, handler http.Handler) http.Handler {
+	listenHost, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		listenHost = addr
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = strings.TrimSuffix(strings.TrimPrefix(r.Host, "["), "]")
+		}
+		if !strings.EqualFold(host, "localhost") && net.ParseIP(host) == nil && (listenHost == "" || !strings.EqualFold(host, listenHost)) {
+			writeServeError(w, errcode.Errorf(errcode.Usage, "host %q isn't served: use localhost, an IP address or the --addr host", r.Host))
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// statusRecorder remembers the status of a response, for the request log
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (h *serveHandler) index(w http.ResponseWriter, r *http.Request) {
+	writeServeJSON(w, map[string]interface{}{"version": version, "endpoints": serveEndpoints})
+}
+
+func (h *serveHandler) packages(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var filters []listFilter
+	for _, value := range query["filter"] {
+		f, err := parseListFilter(value)
+		if err != nil {
+			writeServeError(w, errcode.Wrap(errcode.Usage, err))
+			return
+		}
+		filters = append(filters, f)
+	}
+	sortBy := query.Get("sort")
+	if sortBy == "" {
+		sortBy = "name"
+	}
+	if !slices.Contains(listSorts, sortBy) {
+		writeServeError(w, errcode.Errorf(errcode.Usage, "invalid sort: %s (expected %s)", sortBy, strings.Join(listSorts, ", ")))
+		return
+	}
+
+	images, err := docker.NewLister().ListDetailed()
+	if err != nil {
+		writeServeError(w, fmt.Errorf("failed to list images: %w", err))
+		return
+	}
+	entries := []listEntry{}
+	for _, img := range images {
+		entry := newListEntry(img)
+		if entry.matches(filters, query.Get("language")) {
+			entries = append(entries, entry)
+		}
+	}
+	sortListEntries(entries, sortBy)
+	writeServeJSON(w, entries)
+}
+
+// serveLock is the project's aigogo.lock as GET /api/lock returns it
+type serveLock struct {
+	Path     string                        `json:"path"`
+	Version  int                           `json:"version"`
+	Packages map[string]serveLockedPackage `json:"packages"`
+}
+
+// serveLockedPackage is a package of aigogo.lock and whether its content
+// is in the package store; Stored is unset for local path packages
+type serveLockedPackage struct {
+	lockfile.LockedPackage
+	Stored *bool `json:"stored,omitempty"`
+}
+
+func (h *serveHandler) lock(w http.ResponseWriter, r *http.Request) {
+	client, err := h.newClient()
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	lockPath, lock, err := lockfile.FindLockFileFrom(client.Dir())
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	cas, err := aigogo.OpenStore(loadProjectSettings(filepath.Dir(lockPath)))
+	if err != nil {
+		writeServeError(w, fmt.Errorf("failed to open package store: %w", err))
+		return
+	}
+
+	out := serveLock{Path: lockPath, Version: lock.Version, Packages: map[string]serveLockedPackage{}}
+	for name, pkg := range lock.Packages {
+		entry := serveLockedPackage{LockedPackage: pkg}
+		if !pkg.IsLocal() {
+			stored := cas.Has(pkg.GetIntegrityHash())
+			entry.Stored = &stored
+		}
+		out.Packages[name] = entry
+	}
+	writeServeJSON(w, out)
+}
+
+func (h *serveHandler) manifest(w http.ResponseWriter, r *http.Request) {
+	pkg, ok := h.openPackage(w, r)
+	if !ok {
+		return
+	}
+	data, err := pkg.ReadFile("aigogo.json")
+	if err != nil {
+		writeServeError(w, fmt.Errorf("failed to read aigogo.json of %s: %w", pkg.Ref, err))
+		return
+	}
+	if !json.Valid(data) {
+		writeServeError(w, fmt.Errorf("aigogo.json of %s is not valid JSON", pkg.Ref))
+		return
+	}
+	writeServeJSON(w, json.RawMessage(data))
+}
+
+func (h *serveHandler) files(w http.ResponseWriter, r *http.Request) {
+	pkg, ok := h.openPackage(w, r)
+	if !ok {
+		return
+	}
+	files, err := pkg.Files()
+	if err != nil {
+		writeServeError(w, fmt.Errorf("failed to list the files of %s: %w", pkg.Ref, err))
+		return
+	}
+	if files == nil {
+		files = []docker.PackageFile{}
+	}
+	writeServeJSON(w, map[string]interface{}{"ref": pkg.Ref, "source": pkg.Source, "files": files})
+}
+
+func (h *serveHandler) diff(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, to := query.Get("from"), query.Get("to")
+	if from == "" || to == "" {
+		writeServeError(w, errcode.Errorf(errcode.Usage, "from and to are required"))
+		return
+	}
+	opts := aigogo.DiffOptions{}
+	if value := query.Get("context"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeServeError(w, errcode.Errorf(errcode.Usage, "invalid context: %s", value))
+			return
+		}
+		opts.Context = n
+		if n == 0 {
+			opts.Context = -1
+		}
+	}
+
+	client, err := h.newClient()
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	result, err := client.Diff(r.Context(), from, to, opts)
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	writeServeJSON(w, result)
+}
+
+// openPackage opens the package the request's ref parameter names, or
+// writes the error and returns false
+func (h *serveHandler) openPackage(w http.ResponseWriter, r *http.Request) (*aigogo.Package, bool) {
+	ref := r.URL.Query().Get("ref")
+	if ref == "" {
+		writeServeError(w, errcode.Errorf(errcode.Usage, "ref is required"))
+		return nil, false
+	}
+	client, err := h.newClient()
+	if err != nil {
+		writeServeError(w, err)
+		return nil, false
+	}
+	pkg, err := client.OpenPackage(r.Context(), ref, false)
+	if err != nil {
+		writeServeError(w, err)
+		return nil, false
+	}
+	return pkg, true
+}
+
+// writeServeJSON writes v as the JSON body of a successful response
+func writeServeJSON(w http.ResponseWriter, v interface{}) {
+	writeServeResponse(w, http.StatusOK, v)
+}
+
+// writeServeError writes err as aigg --json prints errors, with the HTTP
+// status of its kind
+func writeServeError(w http.ResponseWriter, err error) {
+	code := errcode.Of(err)
+	var out jsonError
+	out.Error.Code = code
+	out.Error.ExitCode = code.ExitCode()
+	out.Error.Message = err.Error()
+	writeServeResponse(w, serveStatus(code), out)
+}
+
+func writeServeResponse(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// serveStatus is the HTTP status of a failure of kind code: registry
+// failures are the fault of the registry aigg serve reads from, not of the
+// request
+func serveStatus(code errcode.Code) int {
+	switch code {
+	case errcode.Usage, errcode.Validation:
+		return http.StatusBadRequest
+	case errcode.NotFound:
+		return http.StatusNotFound
+	case errcode.Auth, errcode.Network:
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/store"
+)
+
+func TestServeHandler(t *testing.T) {
+	setHome(t, t.TempDir())
+	t.Setenv("AIGOGO_STORE", "")
+	project := t.TempDir()
+
+	src := t.TempDir()
+	manifestData := []byte(`{"name": "utils", "version": "1.0.0"}`)
+	if err := os.WriteFile(filepath.Join(src, "aigogo.json"), manifestData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "utils.py"), []byte("def util(): pass\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cas, err := store.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := cas.Store(context.Background(), src, []string{"aigogo.json", "utils.py"}, manifestData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock := lockfile.New()
+	lock.Add("utils", lockfile.LockedPackage{Version: "1.0.0", Integrity: "sha256:" + hash, Source: "docker.io/org/utils:1.0.0"})
+	lock.Add("missing", lockfile.LockedPackage{Version: "1.0.0", Integrity: "sha256:" + hash[:60] + "0000", Source: "docker.io/org/missing:1.0.0"})
+	if err := lockfile.Save(filepath.Join(project, lockfile.LockFileName), lock); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := newServeHandler(defaultServeAddr, func() (*aigogo.Client, error) {
+		client := aigogo.NewClient(project)
+		client.SetOffline(true)
+		return client, nil
+	})
+	get := func(method, target string, wantStatus int, out interface{}) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, nil)
+		req.Host = defaultServeAddr
+		handler.ServeHTTP(rec, req)
+		if rec.Code != wantStatus {
+			t.Fatalf("%s %s = %d %s, want %d", method, target, rec.Code, rec.Body, wantStatus)
+		}
+		if out != nil {
+			if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+				t.Fatalf("%s %s: %v\n%s", method, target, err, rec.Body)
+			}
+		}
+	}
+
+	var lockOut struct {
+		Packages map[string]struct {
+			Version string `json:"version"`
+			Stored  *bool  `json:"stored"`
+		} `json:"packages"`
+	}
+	get("GET", "/api/lock", http.StatusOK, &lockOut)
+	if p := lockOut.Packages["utils"]; p.Version != "1.0.0" || p.Stored == nil || !*p.Stored {
+		t.Errorf("/api/lock utils = %+v, want stored", p)
+	}
+	if p := lockOut.Packages["missing"]; p.Stored == nil || *p.Stored {
+		t.Errorf("/api/lock missing = %+v, want not stored", p)
+	}
+
+	var m struct {
+		Name string `json:"name"`
+	}
+	get("GET", "/api/manifest?ref=utils", http.StatusOK, &m)
+	if m.Name != "utils" {
+		t.Errorf("/api/manifest name = %q, want utils", m.Name)
+	}
+
+	var files struct {
+		Source string `json:"source"`
+		Files  []struct {
+			Path string `json:"path"`
+			Size int64  `json:"size"`
+		} `json:"files"`
+	}
+	get("GET", "/api/files?ref=sha256:"+hash, http.StatusOK, &files)
+	if files.Source != "package store" || len(files.Files) != 2 || files.Files[1].Path != "utils.py" || files.Files[1].Size != 17 {
+		t.Errorf("/api/files = %+v", files)
+	}
+
+	var diff aigogo.DiffResult
+	get("GET", "/api/diff?from=utils&to=sha256:"+hash, http.StatusOK, &diff)
+	if !diff.Identical() || diff.Unchanged != 2 {
+		t.Errorf("/api/diff = %+v, want identical", diff)
+	}
+
+	var errOut jsonError
+	get("GET", "/api/manifest", http.StatusBadRequest, &errOut)
+	if errOut.Error.Code != "usage_error" {
+		t.Errorf("/api/manifest without ref = %+v", errOut)
+	}
+	// Offline, registry packages that aren't local are not found
+	get("GET", "/api/files?ref=example.com/org/other:1.0.0", http.StatusNotFound, &errOut)
+	get("GET", "/api/diff?from=utils&to=utils&context=x", http.StatusBadRequest, nil)
+
+	// Refs naming directories outside the store or the cache are rejected,
+	// even when there's a package-like directory there
+	outside := filepath.Join(filepath.Dir(cas.RootDir()), "outside")
+	if err := os.MkdirAll(filepath.Join(outside, "files"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "aigogo.json"), manifestData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// sha256:../outside was <store>/sha256/../../outside, beside the store
+	for _, ref := range []string{"sha256:../outside", "sha256:..", "..", "../outside", `..\outside`} {
+		query := url.QueryEscape(ref)
+		for _, target := range []string{"/api/manifest?ref=" + query, "/api/files?ref=" + query, "/api/diff?from=utils&to=" + query} {
+			get("GET", target, http.StatusBadRequest, &errOut)
+			if errOut.Error.Code != "usage_error" {
+				t.Errorf("%s = %+v, want a usage error", target, errOut)
+			}
+		}
+	}
+	get("POST", "/api/lock", http.StatusMethodNotAllowed, nil)
+	get("GET", "/api/unknown", http.StatusNotFound, nil)
+	get("GET", "/", http.StatusOK, nil)
+}
+
+func TestServeHostCheck(t *testing.T) {
+	newClient := func() (*aigogo.Client, error) { return aigogo.NewClient(t.TempDir()), nil }
+	tests := []struct {
+		addr, host string
+		want       int
+	}{
+		{defaultServeAddr, "127.0.0.1:7878", http.StatusOK},
+		{defaultServeAddr, "localhost:7878", http.StatusOK},
+		{defaultServeAddr, "LOCALHOST", http.StatusOK},
+		{defaultServeAddr, "[::1]:7878", http.StatusOK},
+		{"0.0.0.0:7878", "192.168.1.20:7878", http.StatusOK},
+		{"devbox.lan:7878", "devbox.lan:7878", http.StatusOK},
+		// A name pointed at this machine by a web page's DNS
+		{defaultServeAddr, "attacker.example.com:7878", http.StatusBadRequest},
+		{defaultServeAddr, "localhost.attacker.example.com", http.StatusBadRequest},
+		{":7878", "attacker.example.com", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		newServeHandler(tt.addr, newClient).ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("Host %s on %s = %d %s, want %d", tt.host, tt.addr, rec.Code, rec.Body, tt.want)
+		}
+	}
+}
+
+func TestServeStatus(t *testing.T) {
+	tests := map[errcode.Code]int{
+		errcode.Usage:    http.StatusBadRequest,
+		errcode.NotFound: http.StatusNotFound,
+		errcode.Network:  http.StatusBadGateway,
+		errcode.Auth:     http.StatusBadGateway,
+		errcode.Generic:  http.StatusInternalServerError,
+	}
+	for code, want := range tests {
+		if got := serveStatus(code); got != want {
+			t.Errorf("serveStatus(%s) = %d, want %d", code, got, want)
+		}
+	}
+}
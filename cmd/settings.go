@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aupeachmo/aigogo/pkg/auth"
+	"github.com/aupeachmo/aigogo/pkg/config"
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"golang.org/x/term"
+)
+
+// colorMode is the color setting: auto, always or never
+var colorMode = config.ColorAuto
+
+// plainOutput is set when emoji and symbols are printed as ASCII markers
+var plainOutput bool
+
+// outputFormat is the output setting: text, or github for GitHub Actions
+// workflow commands
+var outputFormat = config.OutputText
+
+// githubOutput reports whether output is for GitHub Actions (--output
+// github): warnings, errors and findings become annotations, long output is
+// grouped, and results are added to the job summary
+func githubOutput() bool {
+	return outputFormat == config.OutputGitHub
+}
+
+// logFile is the log_file setting, or --log-file: the file the command's
+// debug log is appended to, "" for none
+var logFile string
+
+// configWarned is set once a configuration that can't be read is reported
+var configWarned bool
+
+// loadProjectSettings returns the settings of the project in projectDir,
+// from its aigogo.json and [tool.aigogo] in its pyproject.toml, over the
+// user's config.toml and under the project's .aigogo/config.toml
+// and AIGOGO_* environment variables. Settings that can't be read are
+// reported and ignored, as every setting has a default the command can
+// carry on with.
+func loadProjectSettings(projectDir string) *manifest.Settings {
+	layers, err := config.Load(projectDir)
+	if err != nil {
+		warnConfig(err)
+		return &manifest.Settings{}
+	}
+	return &config.Merge(layers).Settings
+}
+
+// applyConfig applies the settings of aigg itself for the project around
+// the working directory: the cache directory, fetch concurrency, color,
+// plain output, output format, insecure registries, retry policy, log file,
+// history and how auth.json is encrypted. --color, --plain, --output and --log-file override the color, plain,
+// output and log file settings.
+func applyConfig() {
+	defer func() {
+		if colorFlag != "" {
+			colorMode = colorFlag
+		}
+		plainOutput = plainOutput || plainFlag
+		if outputFlag != "" {
+			outputFormat = outputFlag
+		}
+		if logFileFlag != "" {
+			logFile = logFileFlag
+		}
+	}()
+
+	layers, err := config.Load(configProjectDir())
+	if err != nil {
+		warnConfig(err)
+		return
+	}
+	cfg := config.Merge(layers)
+	if cfg.Cache != "" {
+		docker.SetCacheDir(cfg.Cache)
+	}
+	if cfg.Concurrency > 0 {
+		maxParallelFetches = cfg.Concurrency
+	}
+	colorMode = cfg.ColorMode()
+	plainOutput = cfg.PlainOutput()
+	outputFormat = cfg.OutputFormat()
+	docker.SetInsecureRegistries(cfg.InsecureRegistries)
+	docker.SetRetryPolicy(cfg.RetryPolicy())
+	logFile = cfg.LogFile
+	historyEnabled = cfg.HistoryEnabled()
+	authSettings := cfg.AuthSettings()
+	auth.SetEncrypt(authSettings.Encrypt)
+	auth.SetKeyCommand(authSettings.KeyCommand)
+}
+
+// warnConfig reports, once, that settings are ignored because of err
+func warnConfig(err error) {
+	if configWarned {
+		return
+	}
+	configWarned = true
+	fmt.Fprintf(os.Stderr, "⚠ Warning: ignoring project settings: %v\n", err)
+}
+
+// configProjectDir returns the directory of the project around the working
+// directory, or "" outside a project (see config.FindProjectDir)
+func configProjectDir() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	return config.FindProjectDir(dir)
+}
+
+// useColor reports whether output to the file descriptor fd is styled: on a
+// terminal other than TERM=dumb without NO_COLOR, unless the color setting
+// is always or never
+func useColor(fd int) bool {
+	switch colorMode {
+	case config.ColorAlways:
+		return true
+	case config.ColorNever:
+		return false
+	}
+	return term.IsTerminal(fd) && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+}
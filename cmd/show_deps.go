@@ -7,12 +7,15 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/aupeachmo/aigogo/pkg/depgen"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
 	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/pyproject"
 )
 
 func showDepsCmd() *Command {
 	flags := flag.NewFlagSet("show-deps", flag.ExitOnError)
-	format := flags.String("format", "text", "Output format: text, pyproject, poetry, requirements, npm, yarn")
+	format := flags.String("format", "text", "Output format: text, pyproject, poetry, uv, requirements, conda, npm, yarn, yarn-berry, pnpm, pnpm-workspace, gemfile, maven, gradle, nuget, composer")
 
 	return &Command{
 		Name:        "show-deps",
@@ -20,7 +23,7 @@ func showDepsCmd() *Command {
 		Flags:       flags,
 		Run: func(args []string) error {
 			if len(args) < 1 {
-				return fmt.Errorf("usage: aigg show-deps <path-to-aigogo.json-or-directory> [--format text|pyproject|poetry|requirements|npm|yarn]\n\nExamples:\n  aigg show-deps aigogo.json\n  aigg show-deps vendor/my-snippet\n  aigg show-deps aigogo.json --format pyproject\n  aigg show-deps . --format requirements\n  aigg show-deps . --format npm\n  aigg show-deps . --format yarn")
+				return errcode.Errorf(errcode.Usage, "usage: aigg show-deps <path-to-aigogo.json-or-directory> [--format text|pyproject|poetry|uv|requirements|conda|npm|yarn|yarn-berry|pnpm|pnpm-workspace|gemfile|maven|gradle|nuget|composer]\n\nExamples:\n  aigg show-deps aigogo.json\n  aigg show-deps vendor/my-snippet\n  aigg show-deps aigogo.json --format pyproject\n  aigg show-deps . --format uv\n  aigg show-deps . --format requirements\n  aigg show-deps . --format conda\n  aigg show-deps . --format npm\n  aigg show-deps . --format yarn\n  aigg show-deps . --format pnpm\n  aigg show-deps . --format gemfile\n  aigg show-deps . --format maven\n  aigg show-deps . --format nuget\n  aigg show-deps . --format composer")
 			}
 
 			targetPath := args[0]
@@ -42,6 +45,12 @@ func showDepsCmd() *Command {
 				return fmt.Errorf("failed to load manifest: %w", err)
 			}
 
+			// A multi-language package shows the dependencies of the
+			// language the format is for
+			if lang := formatLanguage(*format); len(m.Languages) > 0 && m.HasLanguage(lang) {
+				m = m.ForLanguage(lang)
+			}
+
 			// Output based on format
 			switch strings.ToLower(*format) {
 			case "text":
@@ -50,34 +59,70 @@ func showDepsCmd() *Command {
 				return outputPyproject(m)
 			case "poetry":
 				return outputPoetry(m)
+			case "uv":
+				return outputUv(m)
 			case "requirements", "pip":
 				return outputRequirements(m)
 			case "npm", "package-json":
 				return outputNpm(m)
 			case "yarn":
 				return outputYarn(m)
+			case "yarn-berry", "berry":
+				return outputYarnBerry(m)
+			case "pnpm":
+				return outputPnpm(m)
+			case "pnpm-workspace", "pnpm-catalog":
+				return outputPnpmWorkspace(m)
+			case "gemfile", "bundler":
+				return outputGemfile(m)
+			case "maven", "pom":
+				return outputMaven(m)
+			case "gradle":
+				return outputGradle(m)
+			case "nuget", "csproj":
+				return outputNuget(m)
+			case "composer":
+				return outputComposer(m)
+			case "conda":
+				return outputConda(m)
 			default:
-				return fmt.Errorf("unsupported format: %s\nSupported formats: text, pyproject, poetry, requirements, npm, yarn", *format)
+				return errcode.Errorf(errcode.Usage, "unsupported format: %s\nSupported formats: text, pyproject, poetry, uv, requirements, conda, npm, yarn, yarn-berry, pnpm, pnpm-workspace, gemfile, maven, gradle, nuget, composer", *format)
 			}
 		},
 	}
 }
 
+// formatLanguage returns the language a show-deps format is for, or "" for
+// text
+func formatLanguage(format string) string {
+	switch strings.ToLower(format) {
+	case "pyproject", "pep621", "poetry", "uv", "requirements", "pip", "conda":
+		return "python"
+	case "npm", "package-json", "yarn", "yarn-berry", "berry", "pnpm", "pnpm-workspace", "pnpm-catalog":
+		return "javascript"
+	case "gemfile", "bundler":
+		return "ruby"
+	case "maven", "pom", "gradle":
+		return "java"
+	case "nuget", "csproj":
+		return "csharp"
+	case "composer":
+		return "php"
+	}
+	return ""
+}
+
 func outputText(m *manifest.Manifest) error {
 	fmt.Printf("Package: %s\n", m.Name)
 	fmt.Printf("Version: %s\n", m.Version)
 
 	if m.Language.Name != "" {
-		langStr := m.Language.Name
-		if m.Language.Version != "" {
-			langStr += " " + m.Language.Version
-		}
-		fmt.Printf("Language: %s\n", langStr)
+		fmt.Println(describeLanguages(m))
 	}
 
 	fmt.Println()
 
-	if m.Dependencies == nil || (len(m.Dependencies.Runtime) == 0 && len(m.Dependencies.Dev) == 0) {
+	if m.Dependencies == nil || (len(m.Dependencies.Runtime) == 0 && len(m.Dependencies.Dev) == 0 && len(m.Dependencies.Peer) == 0 && len(m.Dependencies.Extras) == 0) {
 		fmt.Println("No dependencies declared")
 		return nil
 	}
@@ -85,11 +130,7 @@ func outputText(m *manifest.Manifest) error {
 	if len(m.Dependencies.Runtime) > 0 {
 		fmt.Printf("Runtime Dependencies (%d):\n", len(m.Dependencies.Runtime))
 		for _, dep := range m.Dependencies.Runtime {
-			optional := ""
-			if dep.Optional {
-				optional = " (optional)"
-			}
-			fmt.Printf("  • %s %s%s\n", dep.Package, dep.Version, optional)
+			fmt.Printf("  • %s%s\n", textDependency(dep), languageSuffix(m, dep))
 		}
 		fmt.Println()
 	}
@@ -97,24 +138,74 @@ func outputText(m *manifest.Manifest) error {
 	if len(m.Dependencies.Dev) > 0 {
 		fmt.Printf("Development Dependencies (%d):\n", len(m.Dependencies.Dev))
 		for _, dep := range m.Dependencies.Dev {
-			optional := ""
-			if dep.Optional {
-				optional = " (optional)"
+			fmt.Printf("  • %s%s\n", textDependency(dep), languageSuffix(m, dep))
+		}
+	}
+
+	if len(m.Dependencies.Peer) > 0 {
+		if len(m.Dependencies.Dev) > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Peer Dependencies (%d, provided by the consuming project):\n", len(m.Dependencies.Peer))
+		for _, dep := range m.Dependencies.Peer {
+			fmt.Printf("  • %s%s\n", textDependency(dep), languageSuffix(m, dep))
+		}
+	}
+
+	if extras := m.ExtraNames(); len(extras) > 0 {
+		if len(m.Dependencies.Dev) > 0 || len(m.Dependencies.Peer) > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Extras (%d, selected with aigg add %s:%s[extra]):\n", len(extras), m.Name, m.Version)
+		for _, extra := range extras {
+			fmt.Printf("  [%s]\n", extra)
+			for _, dep := range m.Dependencies.Extras[extra] {
+				fmt.Printf("    • %s%s\n", textDependency(dep), languageSuffix(m, dep))
 			}
-			fmt.Printf("  • %s %s%s\n", dep.Package, dep.Version, optional)
 		}
 	}
 
 	return nil
 }
 
+// describeLanguages formats a package's languages and their version
+// constraints: "Language: python >=3.8" or "Languages: python >=3.8,
+// javascript >=18"
+func describeLanguages(m *manifest.Manifest) string {
+	var langs []string
+	for _, lang := range m.AllLanguages() {
+		langStr := lang.Name
+		if lang.Version != "" {
+			langStr += " " + lang.Version
+		}
+		langs = append(langs, langStr)
+	}
+	if len(langs) > 1 {
+		return "Languages: " + strings.Join(langs, ", ")
+	}
+	return "Language: " + langs[0]
+}
+
+// languageSuffix labels a dependency of a multi-language package with its
+// language
+func languageSuffix(m *manifest.Manifest, dep manifest.Dependency) string {
+	if len(m.Languages) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", m.DependencyLanguage(dep))
+}
+
 func outputPyproject(m *manifest.Manifest) error {
 	if strings.ToLower(m.Language.Name) != "python" {
 		return fmt.Errorf("pyproject format is only supported for Python packages (current language: %s)", m.Language.Name)
 	}
 
 	fmt.Println("# Add these to your pyproject.toml")
-	fmt.Println("# Install with: pip install -e '.[aigogo]' or pip install -e '.[aigogo,aigogo-dev]'")
+	if m.PythonStyle() == manifest.PythonStyleDependencyGroups {
+		fmt.Println("# Install with: uv sync --group aigogo or pip install --group aigogo (pip 25.1+)")
+	} else {
+		fmt.Println("# Install with: pip install -e '.[aigogo]' or pip install -e '.[aigogo,aigogo-dev]'")
+	}
 	fmt.Println()
 
 	if m.Language.Version != "" {
@@ -125,15 +216,16 @@ func outputPyproject(m *manifest.Manifest) error {
 
 	hasRuntime := m.Dependencies != nil && len(m.Dependencies.Runtime) > 0
 	hasDev := m.Dependencies != nil && len(m.Dependencies.Dev) > 0
+	extras := m.ExtraNames()
 
-	if hasRuntime || hasDev {
-		fmt.Println("[project.optional-dependencies]")
+	if hasRuntime || hasDev || len(extras) > 0 {
+		fmt.Println(depgen.PythonGroupTable(m))
 	}
 
 	if hasRuntime {
 		fmt.Println("aigogo = [")
 		for _, dep := range m.Dependencies.Runtime {
-			fmt.Printf("    \"%s%s\",\n", dep.Package, dep.Version)
+			fmt.Printf("    \"%s\",\n", depgen.PythonRequirement(dep))
 		}
 		fmt.Println("]")
 	}
@@ -141,7 +233,15 @@ func outputPyproject(m *manifest.Manifest) error {
 	if hasDev {
 		fmt.Println("aigogo-dev = [")
 		for _, dep := range m.Dependencies.Dev {
-			fmt.Printf("    \"%s%s\",\n", dep.Package, dep.Version)
+			fmt.Printf("    \"%s\",\n", depgen.PythonRequirement(dep))
+		}
+		fmt.Println("]")
+	}
+
+	for _, extra := range extras {
+		fmt.Printf("%s = [\n", depgen.PythonExtraGroup(extra))
+		for _, dep := range m.Dependencies.Extras[extra] {
+			fmt.Printf("    \"%s\",\n", depgen.PythonRequirement(dep))
 		}
 		fmt.Println("]")
 	}
@@ -149,6 +249,23 @@ func outputPyproject(m *manifest.Manifest) error {
 	return nil
 }
 
+// textDependency describes a dependency for the text format:
+// "requests[socks] >=2.31 (optional); python_version < '3.11'"
+func textDependency(dep manifest.Dependency) string {
+	desc := dep.Package
+	if len(dep.Extras) > 0 {
+		desc += "[" + strings.Join(dep.Extras, ",") + "]"
+	}
+	desc += " " + dep.Version
+	if dep.Optional {
+		desc += " (optional)"
+	}
+	if dep.Marker != "" {
+		desc += "; " + dep.Marker
+	}
+	return desc
+}
+
 func outputPoetry(m *manifest.Manifest) error {
 	if strings.ToLower(m.Language.Name) != "python" {
 		return fmt.Errorf("poetry format is only supported for Python packages (current language: %s)", m.Language.Name)
@@ -167,7 +284,7 @@ func outputPoetry(m *manifest.Manifest) error {
 	if m.Dependencies != nil && len(m.Dependencies.Runtime) > 0 {
 		fmt.Println("[tool.poetry.group.aigogo.dependencies]")
 		for _, dep := range m.Dependencies.Runtime {
-			fmt.Printf("%s = \"%s\"\n", dep.Package, dep.Version)
+			fmt.Printf("%s = %s\n", dep.Package, poetryDependency(dep))
 		}
 		fmt.Println()
 	}
@@ -175,13 +292,75 @@ func outputPoetry(m *manifest.Manifest) error {
 	if m.Dependencies != nil && len(m.Dependencies.Dev) > 0 {
 		fmt.Println("[tool.poetry.group.aigogo-dev.dependencies]")
 		for _, dep := range m.Dependencies.Dev {
-			fmt.Printf("%s = \"%s\"\n", dep.Package, dep.Version)
+			fmt.Printf("%s = %s\n", dep.Package, poetryDependency(dep))
+		}
+	}
+
+	// Extras become optional groups, installed with --with aigogo-<extra>
+	for _, extra := range m.ExtraNames() {
+		group := depgen.PythonExtraGroup(extra)
+		fmt.Println()
+		fmt.Printf("[tool.poetry.group.%s]\n", group)
+		fmt.Println("optional = true")
+		fmt.Println()
+		fmt.Printf("[tool.poetry.group.%s.dependencies]\n", group)
+		for _, dep := range m.Dependencies.Extras[extra] {
+			fmt.Printf("%s = %s\n", dep.Package, poetryDependency(dep))
 		}
 	}
 
 	return nil
 }
 
+// poetryDependency formats a dependency's Poetry constraint, switching to
+// an inline table when it has extras or an environment marker
+func poetryDependency(dep manifest.Dependency) string {
+	if len(dep.Extras) == 0 && dep.Marker == "" {
+		return fmt.Sprintf("\"%s\"", dep.Version)
+	}
+	fields := []string{fmt.Sprintf("version = \"%s\"", dep.Version)}
+	if len(dep.Extras) > 0 {
+		extras := make([]string, len(dep.Extras))
+		for i, extra := range dep.Extras {
+			extras[i] = fmt.Sprintf("\"%s\"", extra)
+		}
+		fields = append(fields, fmt.Sprintf("extras = [%s]", strings.Join(extras, ", ")))
+	}
+	if dep.Marker != "" {
+		fields = append(fields, fmt.Sprintf("markers = \"%s\"", strings.ReplaceAll(dep.Marker, `"`, "'")))
+	}
+	return "{ " + strings.Join(fields, ", ") + " }"
+}
+
+// outputUv prints uv add commands that record the dependencies in the
+// aigogo and aigogo-dev groups of the consuming project
+func outputUv(m *manifest.Manifest) error {
+	if strings.ToLower(m.Language.Name) != "python" {
+		return fmt.Errorf("uv format is only supported for Python packages (current language: %s)", m.Language.Name)
+	}
+
+	hasRuntime := m.Dependencies != nil && len(m.Dependencies.Runtime) > 0
+	hasDev := m.Dependencies != nil && len(m.Dependencies.Dev) > 0
+	extras := m.ExtraNames()
+	if !hasRuntime && !hasDev && len(extras) == 0 {
+		fmt.Println("# No dependencies")
+		return nil
+	}
+
+	fmt.Println("# Run in your uv project to add these dependencies")
+	if hasRuntime {
+		fmt.Println(depgen.UvAddCommand(m, "aigogo", m.Dependencies.Runtime))
+	}
+	if hasDev {
+		fmt.Println(depgen.UvAddCommand(m, "aigogo-dev", m.Dependencies.Dev))
+	}
+	for _, extra := range extras {
+		fmt.Println(depgen.UvAddCommand(m, depgen.PythonExtraGroup(extra), m.Dependencies.Extras[extra]))
+	}
+
+	return nil
+}
+
 func outputRequirements(m *manifest.Manifest) error {
 	if strings.ToLower(m.Language.Name) != "python" {
 		return fmt.Errorf("requirements format is only supported for Python packages (current language: %s)", m.Language.Name)
@@ -195,7 +374,7 @@ func outputRequirements(m *manifest.Manifest) error {
 	fmt.Println("# aigogo-managed runtime dependencies")
 	fmt.Println("# To remove: delete these entries and uninstall the packages")
 	for _, dep := range m.Dependencies.Runtime {
-		fmt.Printf("%s%s\n", dep.Package, dep.Version)
+		fmt.Println(depgen.PythonRequirement(dep))
 	}
 
 	return nil
@@ -206,15 +385,6 @@ func isJavaScript(lang string) bool {
 	return l == "javascript" || l == "typescript" || l == "js" || l == "ts"
 }
 
-func npmVersionRange(ver string) string {
-	if ver == "" {
-		return "*"
-	}
-	// Convert Python-style version ranges to npm-compatible semver
-	// ">=1.0.0,<2.0.0" → ">=1.0.0 <2.0.0"
-	return strings.ReplaceAll(ver, ",", " ")
-}
-
 func outputNpm(m *manifest.Manifest) error {
 	if !isJavaScript(m.Language.Name) {
 		return fmt.Errorf("npm format is only supported for JavaScript/TypeScript packages (current language: %s)", m.Language.Name)
@@ -232,7 +402,7 @@ func outputNpm(m *manifest.Manifest) error {
 			if i == len(m.Dependencies.Runtime)-1 {
 				comma = ""
 			}
-			fmt.Printf("    \"%s\": \"%s\"%s\n", dep.Package, npmVersionRange(dep.Version), comma)
+			fmt.Printf("    \"%s\": \"%s\"%s\n", dep.Package, depgen.NpmVersionRange(dep.Version), comma)
 		}
 		fmt.Println("  },")
 	}
@@ -244,7 +414,7 @@ func outputNpm(m *manifest.Manifest) error {
 			if i == len(m.Dependencies.Dev)-1 {
 				comma = ""
 			}
-			fmt.Printf("    \"%s\": \"%s\"%s\n", dep.Package, npmVersionRange(dep.Version), comma)
+			fmt.Printf("    \"%s\": \"%s\"%s\n", dep.Package, depgen.NpmVersionRange(dep.Version), comma)
 		}
 		fmt.Println("  },")
 	}
@@ -294,20 +464,343 @@ func outputYarn(m *manifest.Manifest) error {
 	fmt.Println("# aigogo-managed dependencies")
 	fmt.Println("# To remove: uninstall these packages and delete the \"aigogo\" key from package.json")
 
+	printJSAddCommand("yarn add", m.Dependencies.Runtime)
+	printJSAddCommand("yarn add --dev", m.Dependencies.Dev)
+
+	return nil
+}
+
+// outputYarnBerry prints yarn add commands for Yarn 2+, preceded by the
+// .yarnrc.yml setting aigogo packages need
+func outputYarnBerry(m *manifest.Manifest) error {
+	if !isJavaScript(m.Language.Name) {
+		return fmt.Errorf("yarn-berry format is only supported for JavaScript/TypeScript packages (current language: %s)", m.Language.Name)
+	}
+
+	if m.Dependencies == nil || (len(m.Dependencies.Runtime) == 0 && len(m.Dependencies.Dev) == 0) {
+		fmt.Println("# No dependencies")
+		return nil
+	}
+
+	fmt.Println("# aigogo-managed dependencies (Yarn 2+)")
+	fmt.Println("# aigogo packages resolve dependencies from node_modules; add to .yarnrc.yml:")
+	fmt.Println("#   nodeLinker: node-modules")
+	fmt.Println("# To remove: yarn remove these packages and delete the \"aigogo\" key from package.json")
+	printJSAddCommand("yarn add", m.Dependencies.Runtime)
+	printJSAddCommand("yarn add --dev", m.Dependencies.Dev)
+
+	return nil
+}
+
+// outputPnpm prints pnpm add commands
+func outputPnpm(m *manifest.Manifest) error {
+	if !isJavaScript(m.Language.Name) {
+		return fmt.Errorf("pnpm format is only supported for JavaScript/TypeScript packages (current language: %s)", m.Language.Name)
+	}
+
+	if m.Dependencies == nil || (len(m.Dependencies.Runtime) == 0 && len(m.Dependencies.Dev) == 0) {
+		fmt.Println("# No dependencies")
+		return nil
+	}
+
+	fmt.Println("# aigogo-managed dependencies")
+	fmt.Println("# To remove: pnpm remove these packages and delete the \"aigogo\" key from package.json")
+	printJSAddCommand("pnpm add", m.Dependencies.Runtime)
+	printJSAddCommand("pnpm add --save-dev", m.Dependencies.Dev)
+
+	return nil
+}
+
+// outputPnpmWorkspace prints pnpm-workspace.yaml catalogs named aigogo and
+// aigogo-dev, so every workspace package can depend on the same versions
+func outputPnpmWorkspace(m *manifest.Manifest) error {
+	if !isJavaScript(m.Language.Name) {
+		return fmt.Errorf("pnpm-workspace format is only supported for JavaScript/TypeScript packages (current language: %s)", m.Language.Name)
+	}
+
+	if m.Dependencies == nil || (len(m.Dependencies.Runtime) == 0 && len(m.Dependencies.Dev) == 0) {
+		fmt.Println("# No dependencies")
+		return nil
+	}
+
+	fmt.Println("# Add these to your pnpm-workspace.yaml (pnpm 9.5+)")
+	fmt.Println("# Reference them from package.json as \"<package>\": \"catalog:aigogo\"")
+	fmt.Println("catalogs:")
+	printPnpmCatalog("aigogo", m.Dependencies.Runtime)
+	printPnpmCatalog("aigogo-dev", m.Dependencies.Dev)
+
+	return nil
+}
+
+// printJSAddCommand prints an install command followed by quoted
+// package@range arguments; nothing is printed when deps is empty
+func printJSAddCommand(command string, deps []manifest.Dependency) {
+	if len(deps) == 0 {
+		return
+	}
+	fmt.Print(command)
+	for _, dep := range deps {
+		fmt.Printf(" \"%s@%s\"", dep.Package, depgen.NpmVersionRange(dep.Version))
+	}
+	fmt.Println()
+}
+
+func printPnpmCatalog(name string, deps []manifest.Dependency) {
+	if len(deps) == 0 {
+		return
+	}
+	fmt.Printf("  %s:\n", name)
+	for _, dep := range deps {
+		// Scoped names start with "@", which YAML reserves
+		fmt.Printf("    '%s': '%s'\n", dep.Package, depgen.NpmVersionRange(dep.Version))
+	}
+}
+
+func outputGemfile(m *manifest.Manifest) error {
+	if strings.ToLower(m.Language.Name) != "ruby" {
+		return fmt.Errorf("gemfile format is only supported for Ruby packages (current language: %s)", m.Language.Name)
+	}
+
+	if m.Dependencies == nil || (len(m.Dependencies.Runtime) == 0 && len(m.Dependencies.Dev) == 0) {
+		fmt.Println("# No dependencies")
+		return nil
+	}
+
+	fmt.Println("# aigogo-managed dependencies")
+	fmt.Println("# To remove: delete these groups and run 'bundle install'")
+
 	if len(m.Dependencies.Runtime) > 0 {
-		fmt.Print("yarn add")
+		fmt.Println("group :aigogo do")
 		for _, dep := range m.Dependencies.Runtime {
-			fmt.Printf(" \"%s@%s\"", dep.Package, npmVersionRange(dep.Version))
+			fmt.Printf("  gem %s\n", gemArgs(dep))
 		}
-		fmt.Println()
+		fmt.Println("end")
 	}
 
 	if len(m.Dependencies.Dev) > 0 {
-		fmt.Print("yarn add --dev")
+		fmt.Println("group :aigogo_dev do")
 		for _, dep := range m.Dependencies.Dev {
-			fmt.Printf(" \"%s@%s\"", dep.Package, npmVersionRange(dep.Version))
+			fmt.Printf("  gem %s\n", gemArgs(dep))
+		}
+		fmt.Println("end")
+	}
+
+	return nil
+}
+
+// gemArgs formats the arguments of a Gemfile gem line:
+// "rack", ">= 2.0", "< 3.0"
+func gemArgs(dep manifest.Dependency) string {
+	args := []string{fmt.Sprintf("\"%s\"", dep.Package)}
+	for _, part := range strings.Split(dep.Version, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			args = append(args, fmt.Sprintf("\"%s\"", part))
+		}
+	}
+	return strings.Join(args, ", ")
+}
+
+func outputMaven(m *manifest.Manifest) error {
+	if strings.ToLower(m.Language.Name) != "java" {
+		return fmt.Errorf("maven format is only supported for Java packages (current language: %s)", m.Language.Name)
+	}
+
+	if m.Dependencies == nil || (len(m.Dependencies.Runtime) == 0 && len(m.Dependencies.Dev) == 0) {
+		fmt.Println("<!-- No dependencies -->")
+		return nil
+	}
+
+	fmt.Println("<!-- aigogo-managed dependencies -->")
+	fmt.Println("<dependencies>")
+	printMavenDeps := func(deps []manifest.Dependency, scope string) {
+		for _, dep := range deps {
+			groupID, artifactID := depgen.MavenCoordinates(dep.Package)
+			fmt.Println("  <dependency>")
+			fmt.Printf("    <groupId>%s</groupId>\n", groupID)
+			fmt.Printf("    <artifactId>%s</artifactId>\n", artifactID)
+			if dep.Version != "" {
+				fmt.Printf("    <version>%s</version>\n", dep.Version)
+			}
+			if scope != "" {
+				fmt.Printf("    <scope>%s</scope>\n", scope)
+			}
+			fmt.Println("  </dependency>")
+		}
+	}
+	printMavenDeps(m.Dependencies.Runtime, "")
+	printMavenDeps(m.Dependencies.Dev, "test")
+	fmt.Println("</dependencies>")
+
+	return nil
+}
+
+func outputGradle(m *manifest.Manifest) error {
+	if strings.ToLower(m.Language.Name) != "java" {
+		return fmt.Errorf("gradle format is only supported for Java packages (current language: %s)", m.Language.Name)
+	}
+
+	if m.Dependencies == nil || (len(m.Dependencies.Runtime) == 0 && len(m.Dependencies.Dev) == 0) {
+		fmt.Println("// No dependencies")
+		return nil
+	}
+
+	fmt.Println("// aigogo-managed dependencies")
+	fmt.Println("dependencies {")
+	for _, dep := range m.Dependencies.Runtime {
+		fmt.Printf("    implementation '%s'\n", gradleCoordinate(dep))
+	}
+	for _, dep := range m.Dependencies.Dev {
+		fmt.Printf("    testImplementation '%s'\n", gradleCoordinate(dep))
+	}
+	fmt.Println("}")
+
+	return nil
+}
+
+// gradleCoordinate formats "group:artifact:version"
+func gradleCoordinate(dep manifest.Dependency) string {
+	groupID, artifactID := depgen.MavenCoordinates(dep.Package)
+	if dep.Version == "" {
+		return groupID + ":" + artifactID
+	}
+	return groupID + ":" + artifactID + ":" + dep.Version
+}
+
+func outputNuget(m *manifest.Manifest) error {
+	if strings.ToLower(m.Language.Name) != "csharp" {
+		return fmt.Errorf("nuget format is only supported for C# packages (current language: %s)", m.Language.Name)
+	}
+
+	if m.Dependencies == nil || (len(m.Dependencies.Runtime) == 0 && len(m.Dependencies.Dev) == 0) {
+		fmt.Println("<!-- No dependencies -->")
+		return nil
+	}
+
+	fmt.Println("<!-- aigogo-managed dependencies -->")
+	if len(m.Dependencies.Runtime) > 0 {
+		fmt.Println("<ItemGroup Label=\"aigogo\">")
+		for _, dep := range m.Dependencies.Runtime {
+			fmt.Printf("  %s\n", depgen.PackageReference(dep, false))
+		}
+		fmt.Println("</ItemGroup>")
+	}
+	if len(m.Dependencies.Dev) > 0 {
+		fmt.Println("<ItemGroup Label=\"aigogo-dev\">")
+		for _, dep := range m.Dependencies.Dev {
+			fmt.Printf("  %s\n", depgen.PackageReference(dep, true))
+		}
+		fmt.Println("</ItemGroup>")
+	}
+
+	return nil
+}
+
+func outputComposer(m *manifest.Manifest) error {
+	if strings.ToLower(m.Language.Name) != "php" {
+		return fmt.Errorf("composer format is only supported for PHP packages (current language: %s)", m.Language.Name)
+	}
+
+	hasRuntime := m.Dependencies != nil && len(m.Dependencies.Runtime) > 0
+	hasDev := m.Dependencies != nil && len(m.Dependencies.Dev) > 0
+
+	fmt.Println("{")
+
+	printBlock := func(key string, deps []manifest.Dependency) {
+		fmt.Printf("  \"%s\": {\n", key)
+		for i, dep := range deps {
+			comma := ","
+			if i == len(deps)-1 {
+				comma = ""
+			}
+			version := dep.Version
+			if version == "" {
+				version = "*"
+			}
+			fmt.Printf("    \"%s\": \"%s\"%s\n", dep.Package, version, comma)
+		}
+		fmt.Println("  },")
+	}
+	if hasRuntime {
+		printBlock("require", m.Dependencies.Runtime)
+	}
+	if hasDev {
+		printBlock("require-dev", m.Dependencies.Dev)
+	}
+
+	printNames := func(deps []manifest.Dependency) {
+		for i, dep := range deps {
+			if i > 0 {
+				fmt.Print(", ")
+			}
+			fmt.Printf("\"%s\"", dep.Package)
+		}
+	}
+
+	fmt.Println("  \"extra\": {")
+	fmt.Println("    \"aigogo\": {")
+	fmt.Print("      \"managed-dependencies\": [")
+	if hasRuntime {
+		printNames(m.Dependencies.Runtime)
+	}
+	fmt.Println("],")
+	fmt.Print("      \"managed-dev-dependencies\": [")
+	if hasDev {
+		printNames(m.Dependencies.Dev)
+	}
+	fmt.Println("]")
+	fmt.Println("    }")
+	fmt.Println("  }")
+	fmt.Println("}")
+
+	return nil
+}
+
+func outputConda(m *manifest.Manifest) error {
+	if strings.ToLower(m.Language.Name) != "python" {
+		return fmt.Errorf("conda format is only supported for Python packages (current language: %s)", m.Language.Name)
+	}
+
+	fmt.Println("# aigogo-managed dependencies for a conda environment.yml")
+	fmt.Println("# Create with: conda env create -f environment.yml")
+	fmt.Println("# Packages that are not on conda-forge can be moved to the pip: list")
+	if m.Name != "" {
+		fmt.Printf("name: %s\n", m.Name)
+	}
+	fmt.Println("channels:")
+	fmt.Println("  - conda-forge")
+	fmt.Println("dependencies:")
+
+	if m.Language.Version != "" {
+		fmt.Printf("  - python%s\n", strings.ReplaceAll(m.Language.Version, " ", ""))
+	}
+
+	// Conda match specs can't express extras or environment markers, so
+	// those dependencies go to the pip: list as PEP 508 requirements
+	var pipDeps []string
+	printDeps := func(deps []manifest.Dependency) {
+		for _, dep := range deps {
+			if len(dep.Extras) > 0 || dep.Marker != "" {
+				pipDeps = append(pipDeps, depgen.PythonRequirement(dep))
+				continue
+			}
+			fmt.Printf("  - %s\n", pyproject.CondaMatchSpec(dep))
+		}
+	}
+
+	if m.Dependencies != nil {
+		printDeps(m.Dependencies.Runtime)
+		if len(m.Dependencies.Dev) > 0 {
+			fmt.Println("  # aigogo-dev")
+			printDeps(m.Dependencies.Dev)
+		}
+	}
+
+	if len(pipDeps) > 0 {
+		fmt.Println("  - pip")
+		fmt.Println("  - pip:")
+		for _, req := range pipDeps {
+			fmt.Printf("    - \"%s\"\n", req)
 		}
-		fmt.Println()
 	}
 
 	return nil
@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/depgen"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/store"
+)
+
+func treeCmd() *Command {
+	return &Command{
+		Name:        "tree",
+		Description: "Show the project's packages and their dependencies as a tree",
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return errcode.Errorf(errcode.Usage, "unexpected argument: %s", args[0])
+			}
+
+			lockPath, lock, err := lockfile.FindLockFile()
+			if err != nil {
+				return fmt.Errorf("failed to find aigogo.lock: %w\nRun 'aigg add <package>' first to add packages", err)
+			}
+			projectDir := filepath.Dir(lockPath)
+			cas, err := aigogo.OpenStore(loadProjectSettings(projectDir))
+			if err != nil {
+				return fmt.Errorf("failed to initialize store: %w", err)
+			}
+
+			tree := buildDependencyTree(projectDir, lock, cas)
+			if jsonOutput {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetEscapeHTML(false)
+				enc.SetIndent("", "  ")
+				return enc.Encode(tree)
+			}
+			printDependencyTree(os.Stdout, tree, useColor(int(os.Stdout.Fd())))
+			return nil
+		},
+	}
+}
+
+// dependencyTree is what aigg tree shows, and what --json prints: the
+// project, its own runtime dependencies and its locked packages with
+// theirs
+type dependencyTree struct {
+	Project      string                      `json:"project"` // aigogo.json's name, else the project directory's
+	Version      string                      `json:"version,omitempty"`
+	Dependencies []treeDependency            `json:"dependencies"`
+	Packages     []treePackage               `json:"packages"`
+	Conflicts    []depgen.DependencyConflict `json:"conflicts"`
+}
+
+// treePackage is a locked package in the tree
+type treePackage struct {
+	Name         string           `json:"name"`
+	Version      string           `json:"version"`
+	Source       string           `json:"source"` // the reference it was added from, or a local path
+	Group        string           `json:"group,omitempty"`
+	Extras       []string         `json:"extras,omitempty"`
+	Missing      string           `json:"missing,omitempty"` // why its dependencies couldn't be read
+	Dependencies []treeDependency `json:"dependencies"`
+}
+
+// treeDependency is a declared runtime dependency of the project or a
+// package
+type treeDependency struct {
+	Package  string `json:"package"`
+	Version  string `json:"version,omitempty"`
+	Language string `json:"language"`
+	Marker   string `json:"marker,omitempty"`
+	Conflict bool   `json:"conflict,omitempty"` // no version satisfies every package constraining it
+}
+
+// buildDependencyTree reads the runtime dependencies of the project's
+// aigogo.json and of every package in lock, and marks the ones
+// depgen.FindConflicts finds no common version for. Packages that aren't
+// in the store are listed with the reason.
+func buildDependencyTree(projectDir string, lock *lockfile.LockFile, cas *store.Store) *dependencyTree {
+	tree := &dependencyTree{
+		Project:      filepath.Base(projectDir),
+		Dependencies: []treeDependency{},
+		Packages:     []treePackage{},
+	}
+	var pkgs []depgen.PackageDependencies
+
+	if m, err := manifest.Load(filepath.Join(projectDir, "aigogo.json")); err == nil {
+		tree.Project, tree.Version = m.Name, m.Version
+		own := aigogo.LanguageDependencies("aigogo.json", m)
+		pkgs = append(pkgs, own...)
+		tree.Dependencies = treeDependencies(own)
+	}
+
+	names := make([]string, 0, len(lock.Packages))
+	for name := range lock.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		pkg := lock.Packages[name]
+		node := treePackage{
+			Name:         name,
+			Version:      pkg.Version,
+			Source:       pkg.Source,
+			Group:        pkg.Group,
+			Extras:       pkg.Extras,
+			Dependencies: []treeDependency{},
+		}
+		if pkg.IsLocal() {
+			node.Source = pkg.Path
+		}
+		m, reason := aigogo.LockedManifest(cas, projectDir, pkg)
+		if m == nil {
+			node.Missing = reason
+		} else {
+			deps := aigogo.LanguageDependencies(name, m)
+			pkgs = append(pkgs, deps...)
+			node.Dependencies = treeDependencies(deps)
+		}
+		tree.Packages = append(tree.Packages, node)
+	}
+
+	tree.Conflicts = depgen.FindConflicts(pkgs).Conflicts
+	if tree.Conflicts == nil {
+		tree.Conflicts = []depgen.DependencyConflict{}
+	}
+	markConflicts(tree.Dependencies, "aigogo.json", tree.Conflicts)
+	for _, node := range tree.Packages {
+		markConflicts(node.Dependencies, node.Name, tree.Conflicts)
+	}
+	return tree
+}
+
+// treeDependencies flattens a package's per-language dependencies
+func treeDependencies(pkgs []depgen.PackageDependencies) []treeDependency {
+	deps := []treeDependency{}
+	for _, pkg := range pkgs {
+		for _, dep := range pkg.Dependencies {
+			deps = append(deps, treeDependency{Package: dep.Package, Version: dep.Version, Language: pkg.Language, Marker: dep.Marker})
+		}
+	}
+	return deps
+}
+
+// markConflicts sets Conflict on the dependencies of pkg that are in one of
+// conflicts
+func markConflicts(deps []treeDependency, pkg string, conflicts []depgen.DependencyConflict) {
+	for i, dep := range deps {
+		for _, c := range conflicts {
+			if dep.Marker == "" && c.Involves(pkg, dep.Language, dep.Package) {
+				deps[i].Conflict = true
+			}
+		}
+	}
+}
+
+// printDependencyTree draws the tree with box-drawing branches, styling
+// conflicting dependencies red when styled is set, and lists the conflicts
+// after it
+func printDependencyTree(out io.Writer, tree *dependencyTree, styled bool) {
+	root := tree.Project
+	if tree.Version != "" {
+		root += "@" + tree.Version
+	}
+	fmt.Fprintln(out, root)
+
+	// The project's own dependencies come first, then its packages
+	type branch struct {
+		label    string
+		children []string
+	}
+	var branches []branch
+	for _, dep := range tree.Dependencies {
+		branches = append(branches, branch{label: treeDependencyLabel(dep, styled)})
+	}
+	for _, node := range tree.Packages {
+		label := node.Name + "@" + node.Version
+		if node.Group != "" {
+			label += " [" + node.Group + "]"
+		}
+		if len(node.Extras) > 0 {
+			label += " [extras: " + strings.Join(node.Extras, ", ") + "]"
+		}
+		label += " (" + node.Source + ")"
+
+		var children []string
+		if node.Missing != "" {
+			children = append(children, "? "+node.Missing)
+		}
+		for _, dep := range node.Dependencies {
+			children = append(children, treeDependencyLabel(dep, styled))
+		}
+		branches = append(branches, branch{label: label, children: children})
+	}
+
+	for i, b := range branches {
+		last := i == len(branches)-1
+		connector, indent := "├── ", "│   "
+		if last {
+			connector, indent = "└── ", "    "
+		}
+		fmt.Fprintln(out, connector+b.label)
+		for j, child := range b.children {
+			if j == len(b.children)-1 {
+				fmt.Fprintln(out, indent+"└── "+child)
+			} else {
+				fmt.Fprintln(out, indent+"├── "+child)
+			}
+		}
+	}
+	if len(branches) == 0 {
+		fmt.Fprintln(out, "└── (no packages or dependencies)")
+	}
+
+	if len(tree.Conflicts) > 0 {
+		fmt.Fprintln(out)
+		printConflictReport(out, "⚠️  Dependency conflicts:", &depgen.ConflictReport{Conflicts: tree.Conflicts})
+	}
+}
+
+// treeDependencyLabel is a dependency as the tree shows it: its name,
+// constraint, language and marker, and a warning when it conflicts
+func treeDependencyLabel(dep treeDependency, styled bool) string {
+	label := dep.Package
+	if dep.Version != "" {
+		label += " " + dep.Version
+	}
+	label += " (" + dep.Language
+	if dep.Marker != "" {
+		label += "; " + dep.Marker
+	}
+	label += ")"
+	if dep.Conflict {
+		label += " ⚠ conflict"
+		if styled {
+			label = "\x1b[31m" + label + "\x1b[39m"
+		}
+	}
+	return label
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/store"
+)
+
+func TestBuildDependencyTree(t *testing.T) {
+	project := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		path = filepath.Join(project, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("aigogo.json", `{"name": "app", "version": "0.1.0", "language": {"name": "python", "version": ">=3.8"},
+		"dependencies": {"runtime": [{"package": "requests", "version": ">=2.31"}]}}`)
+	write("libs/client/aigogo.json", `{"name": "client", "version": "1.0.0", "language": {"name": "python", "version": ">=3.8"},
+		"dependencies": {"runtime": [{"package": "Requests", "version": "<2.0"}, {"package": "pyyaml", "version": ">=6"}]}}`)
+
+	lock := lockfile.New()
+	lock.Add("client", lockfile.LockedPackage{Version: "1.0.0", Source: lockfile.SourcePath, Path: "libs/client", Language: "python"})
+	lock.Add("utils", lockfile.LockedPackage{Version: "2.0.0", Integrity: "sha256:" + strings.Repeat("0", 64), Source: "docker.io/org/utils:2.0.0", Language: "python", Group: lockfile.GroupDev})
+
+	cas, err := store.NewStoreAt(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := buildDependencyTree(project, lock, cas)
+
+	if tree.Project != "app" || tree.Version != "0.1.0" || len(tree.Packages) != 2 {
+		t.Fatalf("tree = %+v", tree)
+	}
+	if len(tree.Conflicts) != 1 || tree.Conflicts[0].Dependency != "requests" {
+		t.Fatalf("conflicts = %+v", tree.Conflicts)
+	}
+	if !tree.Dependencies[0].Conflict {
+		t.Error("project's requests not marked as conflicting")
+	}
+	client := tree.Packages[0]
+	if client.Source != "libs/client" || len(client.Dependencies) != 2 || !client.Dependencies[0].Conflict || client.Dependencies[1].Conflict {
+		t.Errorf("client = %+v", client)
+	}
+	if utils := tree.Packages[1]; !strings.Contains(utils.Missing, "not installed") {
+		t.Errorf("utils = %+v, want it missing", utils)
+	}
+
+	var out bytes.Buffer
+	printDependencyTree(&out, tree, false)
+	want := `app@0.1.0
+├── requests >=2.31 (python) ⚠ conflict
+├── client@1.0.0 (libs/client)
+│   ├── Requests <2.0 (python) ⚠ conflict
+│   └── pyyaml >=6 (python)
+└── utils@2.0.0 [dev] (docker.io/org/utils:2.0.0)
+    └── ? not installed (run 'aigg install')
+`
+	if got := out.String(); !strings.HasPrefix(got, want) || !strings.Contains(got, "Dependency conflicts:") {
+		t.Errorf("printDependencyTree() =\n%s\nwant it to start with\n%s", got, want)
+	}
+}
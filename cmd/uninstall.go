@@ -5,8 +5,11 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/aupeachmo/aigogo/pkg/config"
 	"github.com/aupeachmo/aigogo/pkg/imports"
 	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
 )
 
 func uninstallCmd() *Command {
@@ -28,29 +31,72 @@ func runUninstall() error {
 
 	aigogoDir := filepath.Join(projectDir, imports.ImportsDir)
 
-	// Check if there's anything to uninstall
+	// Remove the Python packages the PEP 582 layout put in __pypackages__/
+	setupMgr, _ := imports.NewSetupManager(projectDir)
+	setupMgr.SetNamespace(imports.NamespaceFor(loadProjectSettings(projectDir).Namespace))
+	removed, err := setupMgr.RemovePyPackages()
+	if err != nil {
+		fmt.Printf("⚠ Warning: %v\n", err)
+	} else if removed > 0 {
+		fmt.Printf("✓ Removed Python packages from %s/\n", imports.PyPackagesDir)
+	}
+
+	// Check if there's anything else to uninstall
 	if _, err := os.Stat(aigogoDir); os.IsNotExist(err) {
-		fmt.Println("Nothing to uninstall (.aigogo/ directory not found)")
+		if removed == 0 {
+			fmt.Println("Nothing to uninstall (.aigogo/ directory not found)")
+		}
 		return nil
 	}
 
-	// Remove .pth file from Python site-packages (only if tracking file exists)
-	pthTrackingPath := filepath.Join(aigogoDir, ".pth-location")
-	if _, err := os.Stat(pthTrackingPath); err == nil {
+	// Remove the .pth files from every Python environment installed into
+	if tracked, err := imports.TrackedPthFiles(projectDir); err != nil {
+		fmt.Printf("⚠ Warning: %v\n", err)
+	} else if len(tracked) > 0 {
 		if err := imports.RemovePthFile(projectDir); err != nil {
-			fmt.Printf("⚠ Warning: failed to remove Python .pth file: %v\n", err)
-		} else {
+			fmt.Printf("⚠ Warning: failed to remove Python .pth files: %v\n", err)
+		} else if len(tracked) == 1 {
 			fmt.Println("✓ Removed Python .pth file from site-packages")
+		} else {
+			fmt.Printf("✓ Removed Python .pth files from %d environments\n", len(tracked))
 		}
 	}
 
-	// Remove register.js
+	// Remove register.js, register.mjs and the ESM loader
 	registerPath := filepath.Join(aigogoDir, "register.js")
 	if _, err := os.Stat(registerPath); err == nil {
 		if err := imports.RemoveRegisterScript(projectDir); err != nil {
 			fmt.Printf("⚠ Warning: failed to remove Node.js register script: %v\n", err)
 		} else {
-			fmt.Println("✓ Removed Node.js register script")
+			fmt.Println("✓ Removed Node.js register scripts")
+		}
+	}
+
+	// Remove the go.mod block, whose replace directives point into .aigogo/
+	if _, err := os.Stat(filepath.Join(projectDir, "go.mod")); err == nil {
+		setupMgr, _ := imports.NewSetupManager(projectDir)
+		if modules, _ := setupMgr.GoModules(); len(modules) > 0 {
+			if err := os.RemoveAll(setupMgr.GetGoModulesPath()); err != nil {
+				fmt.Printf("⚠ Warning: failed to remove Go modules: %v\n", err)
+			} else if _, err := setupMgr.UpdateGoMod(); err != nil {
+				fmt.Printf("⚠ Warning: failed to update go.mod: %v\n", err)
+			} else {
+				fmt.Println("✓ Removed aigogo require and replace directives from go.mod")
+			}
+		}
+	}
+
+	// Likewise the Cargo.toml section, whose path dependencies point into .aigogo/
+	if _, err := os.Stat(filepath.Join(projectDir, "Cargo.toml")); err == nil {
+		setupMgr, _ := imports.NewSetupManager(projectDir)
+		if crates, _ := setupMgr.RustCrates(); len(crates) > 0 {
+			if err := os.RemoveAll(setupMgr.GetRustCratesPath()); err != nil {
+				fmt.Printf("⚠ Warning: failed to remove Rust crates: %v\n", err)
+			} else if _, err := setupMgr.UpdateCargoToml(); err != nil {
+				fmt.Printf("⚠ Warning: failed to update Cargo.toml: %v\n", err)
+			} else {
+				fmt.Println("✓ Removed aigogo path dependencies from Cargo.toml")
+			}
 		}
 	}
 
@@ -63,6 +109,12 @@ func runUninstall() error {
 			var totalEnvSize int64
 			for _, pkg := range lock.Packages {
 				hash := pkg.GetIntegrityHash()
+				if pkg.IsLocal() {
+					dir := pkg.LocalDir(projectDir)
+					if m, err := manifest.Load(filepath.Join(dir, "aigogo.json")); err == nil {
+						hash = localEnvHash(dir, m)
+					}
+				}
 				if hash == "" {
 					continue
 				}
@@ -84,14 +136,30 @@ func runUninstall() error {
 		}
 	}
 
-	// Remove the entire .aigogo/ directory
-	if err := os.RemoveAll(aigogoDir); err != nil {
-		return fmt.Errorf("failed to remove .aigogo/ directory: %w", err)
+	// Remove the entire .aigogo/ directory, but the project's config file
+	if _, err := os.Stat(config.ProjectPath(projectDir)); err == nil {
+		entries, err := os.ReadDir(aigogoDir)
+		if err != nil {
+			return fmt.Errorf("failed to read .aigogo/ directory: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.Name() == config.FileName {
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(aigogoDir, entry.Name())); err != nil {
+				return fmt.Errorf("failed to remove .aigogo/%s: %w", entry.Name(), err)
+			}
+		}
+		fmt.Printf("✓ Removed .aigogo/ directory (kept %s)\n", config.FileName)
+	} else {
+		if err := os.RemoveAll(aigogoDir); err != nil {
+			return fmt.Errorf("failed to remove .aigogo/ directory: %w", err)
+		}
+		fmt.Println("✓ Removed .aigogo/ directory")
 	}
-	fmt.Println("✓ Removed .aigogo/ directory")
 
 	fmt.Println("\nUninstall complete. The aigogo.lock file has been preserved.")
-	fmt.Println("Run 'aigg install' to reinstall packages.")
+	logging.Println("Run 'aigg install' to reinstall packages.")
 
 	return nil
 }
@@ -1,77 +1,131 @@
 package cmd
 
 import (
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
 	"github.com/aupeachmo/aigogo/pkg/depgen"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/ghactions"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
 	"github.com/aupeachmo/aigogo/pkg/manifest"
 )
 
 func validateCmd() *Command {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	noCache := flags.Bool("no-cache", false, "Re-scan every file, ignoring .aigogo/scan-cache.json")
+	lock := flags.Bool("lock", false, "Check the packages in aigogo.lock for conflicting dependency versions")
+	strict := flags.Bool("strict", false, "Fail on warnings as well as errors")
+	format := flags.String("format", "text", "Output format: text, sarif")
+	schema := flags.Bool("schema", false, "Check aigogo.json against the JSON Schema for unknown fields and wrong types")
+
 	return &Command{
 		Name:        "validate",
 		Description: "Validate dependencies against actual imports in source files",
+		Flags:       flags,
 		Run: func(args []string) error {
+			if *lock {
+				return runValidateLock()
+			}
+			if *schema {
+				return runValidateSchema("aigogo.json")
+			}
+
+			sarif := false
+			switch *format {
+			case "text":
+			case "sarif":
+				sarif = true
+			default:
+				return errcode.Errorf(errcode.Usage, "unsupported format: %s\nSupported formats: text, sarif", *format)
+			}
+
 			// Load manifest
 			m, err := manifest.Load("aigogo.json")
 			if err != nil {
 				return fmt.Errorf("failed to load aigogo.json: %w\nRun 'aigg init' first", err)
 			}
 
-			fmt.Println("Validating manifest...")
-			fmt.Println()
+			if !sarif {
+				fmt.Println("Validating manifest...")
+				fmt.Println()
+			}
 
 			// Discover files
 			discovery, err := manifest.NewFileDiscovery(".", m.Files.Exclude)
 			if err != nil {
 				return fmt.Errorf("failed to initialize file discovery: %w", err)
 			}
-			files, err := discovery.Discover(m.Files, m.Language)
+			files, err := discovery.Discover(m.Files, m.AllLanguages()...)
 			if err != nil {
 				return fmt.Errorf("failed to discover files: %w", err)
 			}
+			// Data files ship as-is and are never scanned
+			files = m.Files.WithoutData(files)
 
 			if len(files) == 0 {
 				return fmt.Errorf("no files found to validate")
 			}
 
-			fmt.Printf("📁 Found %d file(s) to scan\n", len(files))
-			fmt.Println()
-
 			// Validate dependencies
 			validator := depgen.NewValidator()
+			if !sarif {
+				fmt.Printf("📁 Found %d file(s) to scan\n", len(files))
+				fmt.Println()
+				validator.SetProgress(printScanProgress)
+			}
+			cache := openScanCache(".", *noCache)
+			validator.SetCache(cache)
 			result, err := validator.Validate(m, files)
 			if err != nil {
-				return fmt.Errorf("validation failed: %w", err)
+				return errcode.Errorf(errcode.Validation, "validation failed: %w", err)
+			}
+			if cache != nil {
+				cache.Save()
 			}
 
-			// Print detected imports
-			if len(result.Imports) > 0 {
-				fmt.Println("📦 Detected external imports:")
-				for _, imp := range result.Imports {
-					fmt.Printf("  - %s (in %s:%d)\n", imp.Package, imp.SourceFile, imp.LineNumber)
-				}
-				fmt.Println()
+			// Peer dependencies come from the consuming project, but the
+			// package's own code runs against what this machine has
+			for _, p := range aigogo.PeerProblems(m, aigogo.NewHostPackages(".")) {
+				result.AddFinding(depgen.Finding{
+					Severity: depgen.SeverityWarning,
+					Rule:     depgen.RulePeerDependency,
+					Message:  "Peer dependency " + p.Message,
+					Package:  p.Package,
+				})
 			}
 
-			// Print errors
-			if len(result.Errors) > 0 {
-				fmt.Println("❌ Errors:")
-				for _, err := range result.Errors {
-					fmt.Printf("  - %s\n", err)
+			if sarif {
+				if err := depgen.WriteSARIF(os.Stdout, result.Findings, "aigogo.json", version); err != nil {
+					return fmt.Errorf("failed to write SARIF: %w", err)
 				}
-				fmt.Println()
+				if result.Failed(*strict) {
+					return errcode.Errorf(errcode.Validation, "validation failed")
+				}
+				return nil
 			}
 
-			// Print warnings
-			if len(result.Warnings) > 0 {
-				fmt.Println("⚠️  Warnings:")
-				for _, warn := range result.Warnings {
-					fmt.Printf("  - %s\n", warn)
+			// Print detected imports
+			if len(result.Imports) > 0 {
+				endGroup := startGroup(fmt.Sprintf("Detected %d external import(s)", len(result.Imports)))
+				fmt.Println("📦 Detected external imports:")
+				for _, imp := range result.Imports {
+					fmt.Printf("  - %s (in %s)\n", imp.Package, imp.Location())
 				}
 				fmt.Println()
+				endGroup()
 			}
 
+			printFindings("❌ Errors:", depgen.SeverityError, result.Findings)
+			printFindings("⚠️  Warnings:", depgen.SeverityWarning, result.Findings)
+			printFindings("ℹ️  Info:", depgen.SeverityInfo, result.Findings)
+			printFindingAnnotations("aigg validate", result.Findings, "aigogo.json")
+
 			// Print suggestions
 			if len(result.Suggestions) > 0 {
 				fmt.Println("💡 Suggestions:")
@@ -82,14 +136,151 @@ func validateCmd() *Command {
 			}
 
 			// Final status
-			if result.Valid {
+			if !result.Failed(*strict) {
 				fmt.Println("✅ Validation passed!")
 				return nil
 			}
 
-			fmt.Println("❌ Validation failed")
+			if result.Valid {
+				fmt.Println("❌ Validation failed (--strict treats warnings as errors)")
+			} else {
+				fmt.Println("❌ Validation failed")
+			}
 			fmt.Println("Fix the issues above before pushing")
-			return fmt.Errorf("validation failed")
+			return errcode.Errorf(errcode.Validation, "validation failed")
 		},
 	}
 }
+
+// printFindings lists the findings of one severity under a heading,
+// with the location of the first import when there is one
+func printFindings(heading, severity string, findings []depgen.Finding) {
+	printed := false
+	for _, f := range findings {
+		if f.Severity != severity {
+			continue
+		}
+		if !printed {
+			fmt.Println(heading)
+			printed = true
+		}
+		if f.Import != nil {
+			fmt.Printf("  - %s (%s)\n", f.Message, f.Import.Location())
+		} else {
+			fmt.Printf("  - %s\n", f.Message)
+		}
+	}
+	if printed {
+		fmt.Println()
+	}
+}
+
+// runValidateSchema checks a manifest against the embedded JSON Schema,
+// reporting each problem with its line and JSON path
+func runValidateSchema(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w\nRun 'aigg init' first", path, err)
+	}
+
+	fmt.Printf("Checking %s against the schema...\n\n", path)
+
+	errs, err := manifest.ValidateSchema(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if len(errs) == 0 {
+		fmt.Println("✅ Schema validation passed!")
+		return nil
+	}
+
+	fmt.Println("❌ Schema errors:")
+	for _, e := range errs {
+		message := e.Message
+		if e.Path != "" {
+			message = e.Path + ": " + e.Message
+		}
+		fmt.Printf("  %s:%d: %s\n", path, e.Line, message)
+		if githubOutput() {
+			fmt.Println(ghactions.Annotation{Level: ghactions.LevelError, File: annotationPath(path), Line: e.Line, Title: "aigg validate --schema", Message: message})
+		}
+	}
+	fmt.Println()
+	fmt.Println("❌ Validation failed")
+	return errcode.Errorf(errcode.Validation, "found %d schema error(s)", len(errs))
+}
+
+// runValidateLock checks that the dependencies declared by every package in
+// aigogo.lock, and by the project's own aigogo.json, can be installed
+// together
+func runValidateLock() error {
+	lockPath, lock, err := lockfile.FindLockFile()
+	if err != nil {
+		return fmt.Errorf("failed to find aigogo.lock: %w\nRun 'aigg add <package>' first to add packages", err)
+	}
+
+	cas, err := aigogo.OpenStore(loadProjectSettings(filepath.Dir(lockPath)))
+	if err != nil {
+		return fmt.Errorf("failed to initialize store: %w", err)
+	}
+
+	fmt.Printf("Checking dependencies of %d locked package(s) in %s\n\n", len(lock.Packages), lockPath)
+
+	pkgs, skipped := aigogo.LockedDependencies(filepath.Dir(lockPath), lock, cas)
+	if len(skipped) > 0 {
+		fmt.Println("⚠️  Skipped:")
+		for _, s := range skipped {
+			fmt.Printf("  - %s\n", s)
+		}
+		fmt.Println()
+	}
+
+	report := depgen.FindConflicts(pkgs)
+	if len(report.Conflicts) > 0 {
+		printConflictReport(os.Stdout, "❌ Dependency conflicts:", report)
+		fmt.Println()
+		if githubOutput() {
+			for _, conflict := range report.Conflicts {
+				var clashes []string
+				for _, c := range conflict.Constraints {
+					clashes = append(clashes, c.Package+" requires "+c.Version)
+				}
+				fmt.Println(ghactions.Annotation{
+					Level:   ghactions.LevelError,
+					File:    annotationPath(lockPath),
+					Title:   "aigg validate --lock",
+					Message: fmt.Sprintf("%s (%s): no version satisfies every package: %s\n%s", conflict.Dependency, conflict.Language, strings.Join(clashes, ", "), conflict.Resolution),
+				})
+			}
+		}
+	}
+	if len(report.Unchecked) > 0 {
+		fmt.Println("⚠️  Could not check:")
+		for _, u := range report.Unchecked {
+			fmt.Printf("  - %s\n", u)
+		}
+		fmt.Println()
+	}
+
+	if len(report.Conflicts) > 0 {
+		fmt.Println("❌ Validation failed")
+		return errcode.Errorf(errcode.Validation, "found %d dependency conflict(s)", len(report.Conflicts))
+	}
+
+	fmt.Println("✅ No dependency conflicts")
+	return nil
+}
+
+// printConflictReport lists each conflicting dependency with the
+// constraints that clash and a suggested resolution
+func printConflictReport(out io.Writer, heading string, report *depgen.ConflictReport) {
+	fmt.Fprintln(out, heading)
+	for _, conflict := range report.Conflicts {
+		fmt.Fprintf(out, "  - %s (%s): no version satisfies every package\n", conflict.Dependency, conflict.Language)
+		for _, c := range conflict.Constraints {
+			fmt.Fprintf(out, "      %s requires %s\n", c.Package, c.Version)
+		}
+		fmt.Fprintf(out, "    💡 %s\n", conflict.Resolution)
+	}
+}
@@ -1,8 +1,15 @@
 package cmd
 
 import (
+	"flag"
 	"fmt"
+	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
 )
 
 // version is set by main package from build-time ldflags
@@ -13,6 +20,7 @@ func SetVersion(v string) {
 	if v != "" {
 		version = v
 	}
+	manifest.CLIVersion = version
 }
 
 // GetVersion returns the current version
@@ -21,10 +29,24 @@ func GetVersion() string {
 }
 
 func versionCmd() *Command {
+	flags := flag.NewFlagSet("version", flag.ExitOnError)
+	useGit := flags.Bool("git", false, "Commit aigogo.json and tag the commit v<version>")
+
 	return &Command{
 		Name:        "version",
-		Description: "Show aigg version information",
+		Description: "Show aigg version information, or bump the package version",
+		Flags:       flags,
 		Run: func(args []string) error {
+			if len(args) > 1 {
+				return errcode.Errorf(errcode.Usage, "usage: aigg version [patch|minor|major|<version>] [--git]")
+			}
+			if len(args) == 1 {
+				return bumpPackageVersion(args[0], *useGit)
+			}
+			if *useGit {
+				return fmt.Errorf("--git needs a version to bump to: aigg version patch|minor|major|<version> --git")
+			}
+
 			fmt.Printf("aigg version %s\n", version)
 			fmt.Printf("  Platform: %s/%s\n", runtime.GOOS, runtime.GOARCH)
 			fmt.Printf("  Go version: %s\n", runtime.Version())
@@ -35,3 +57,105 @@ func versionCmd() *Command {
 		},
 	}
 }
+
+// nextVersion resolves the argument of aigg version against the current
+// package version. An explicit version must be semver and greater than the
+// current one; a leading "v" is dropped.
+func nextVersion(current, arg string) (string, error) {
+	switch arg {
+	case manifest.BumpMajor, manifest.BumpMinor, manifest.BumpPatch:
+		return manifest.BumpVersion(current, arg)
+	}
+
+	next, err := manifest.ParseSemver(strings.TrimPrefix(arg, "v"))
+	if err != nil {
+		return "", fmt.Errorf("%w, or one of patch, minor, major", err)
+	}
+	if cur, err := manifest.ParseSemver(current); err == nil && next.Compare(cur) <= 0 {
+		return "", fmt.Errorf("version %s is not greater than the current version %s", next, current)
+	}
+	return next.String(), nil
+}
+
+// bumpPackageVersion sets the version in aigogo.json and, with useGit,
+// commits the change and tags it
+func bumpPackageVersion(arg string, useGit bool) error {
+	m, manifestDir, err := manifest.FindManifest()
+	if err != nil {
+		return fmt.Errorf("failed to find manifest: %w", err)
+	}
+
+	next, err := nextVersion(m.Version, arg)
+	if err != nil {
+		return err
+	}
+
+	// Check the tag is free before touching aigogo.json
+	tag := "v" + next
+	if useGit {
+		if err := checkGitTag(manifestDir, tag); err != nil {
+			return err
+		}
+	}
+
+	previous := m.Version
+	m.Version = next
+	if err := saveManifest(filepath.Join(manifestDir, "aigogo.json"), m); err != nil {
+		return fmt.Errorf("failed to update aigogo.json: %w", err)
+	}
+	fmt.Printf("✓ Updated aigogo.json version: %s -> %s\n", previous, next)
+
+	if useGit {
+		if err := gitCommitAndTag(manifestDir, tag); err != nil {
+			return fmt.Errorf("updated aigogo.json, but %w", err)
+		}
+		fmt.Printf("✓ Committed aigogo.json and tagged %s\n", tag)
+	}
+
+	// A bare aigg build would increment the version again, so name it
+	ref := m.Name + ":" + next
+	fmt.Println("\nNext steps:")
+	fmt.Printf("  Build:            aigg build %s\n", ref)
+	fmt.Printf("  Push to registry: aigg push <registry>/myorg/%s --from %s\n", ref, ref)
+	if useGit {
+		fmt.Printf("  Push the tag:     git push origin %s\n", tag)
+	}
+	return nil
+}
+
+// checkGitTag returns an error unless dir is in a git work tree without tag
+func checkGitTag(dir, tag string) error {
+	if _, err := runGit(dir, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return fmt.Errorf("--git: %s is not in a git repository", dir)
+	}
+	if _, err := runGit(dir, "rev-parse", "-q", "--verify", "refs/tags/"+tag); err == nil {
+		return fmt.Errorf("--git: tag %s already exists", tag)
+	}
+	return nil
+}
+
+// gitCommitAndTag commits only aigogo.json, so other staged changes stay
+// staged, and tags the commit
+func gitCommitAndTag(dir, tag string) error {
+	if _, err := runGit(dir, "add", "aigogo.json"); err != nil {
+		return err
+	}
+	if _, err := runGit(dir, "commit", "-m", tag, "--", "aigogo.json"); err != nil {
+		return err
+	}
+	if _, err := runGit(dir, "tag", "-a", tag, "-m", tag); err != nil {
+		return err
+	}
+	return nil
+}
+
+// runGit runs git in dir and returns its trimmed output
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
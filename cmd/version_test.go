@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func TestNextVersion(t *testing.T) {
+	tests := []struct {
+		current, arg string
+		want         string
+		wantErr      string
+	}{
+		{current: "1.2.3", arg: "patch", want: "1.2.4"},
+		{current: "1.2.3", arg: "minor", want: "1.3.0"},
+		{current: "1.2.3", arg: "major", want: "2.0.0"},
+		{current: "1.2.3", arg: "1.4.0-rc.1", want: "1.4.0-rc.1"},
+		{current: "1.2.3", arg: "v2.0.0", want: "2.0.0"},
+		{current: "1.2.3", arg: "1.2.3", wantErr: "not greater"},
+		{current: "1.2.3", arg: "1.2.3-rc.1", wantErr: "not greater"},
+		{current: "1.2.3", arg: "2.0", wantErr: "invalid semver"},
+		{current: "1.2", arg: "patch", wantErr: "invalid semver"},
+		// A current version that isn't semver can still be replaced
+		{current: "dev", arg: "0.1.0", want: "0.1.0"},
+	}
+
+	for _, tt := range tests {
+		got, err := nextVersion(tt.current, tt.arg)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("nextVersion(%q, %q) error = %v, want %q", tt.current, tt.arg, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("nextVersion(%q, %q) = %q, %v, want %q", tt.current, tt.arg, got, err, tt.want)
+		}
+	}
+}
+
+func TestBumpPackageVersionGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	git := func(args ...string) string {
+		t.Helper()
+		out, err := runGit(dir, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	git("init", "-q")
+	git("config", "user.email", "test@example.com")
+	git("config", "user.name", "test")
+
+	m := &manifest.Manifest{Name: "utils", Version: "1.2.3", Language: manifest.Language{Name: "python"}}
+	if err := manifest.Save(filepath.Join(dir, "aigogo.json"), m); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "other.py"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "other.py")
+
+	if err := bumpPackageVersion("minor", true); err != nil {
+		t.Fatalf("bumpPackageVersion failed: %v", err)
+	}
+
+	updated, err := manifest.Load(filepath.Join(dir, "aigogo.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != "1.3.0" {
+		t.Errorf("version = %s, want 1.3.0", updated.Version)
+	}
+	if got := git("tag", "--list"); got != "v1.3.0" {
+		t.Errorf("tags = %q, want v1.3.0", got)
+	}
+	if got := git("show", "--name-only", "--format=%s", "HEAD"); got != "v1.3.0\n\naigogo.json" {
+		t.Errorf("commit = %q, want only aigogo.json", got)
+	}
+	if got := git("diff", "--cached", "--name-only"); got != "other.py" {
+		t.Errorf("staged = %q, want other.py left staged", got)
+	}
+
+	// An existing tag is refused before aigogo.json changes
+	m.Version = "1.2.9"
+	if err := manifest.Save(filepath.Join(dir, "aigogo.json"), m); err != nil {
+		t.Fatal(err)
+	}
+	if err := bumpPackageVersion("minor", true); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected an existing-tag error, got %v", err)
+	}
+	if unchanged, _ := manifest.Load(filepath.Join(dir, "aigogo.json")); unchanged.Version != "1.2.9" {
+		t.Errorf("version = %s, want 1.2.9 left alone", unchanged.Version)
+	}
+}
+
+func TestNewerVersion(t *testing.T) {
+	tests := []struct {
+		tag, current string
+		want         bool
+	}{
+		{"v1.3.0", "v1.2.3", true},
+		{"v1.2.3", "1.2.3", false},
+		{"v1.2.3", "v1.3.0", false},
+		{"v1.3.0", "v1.3.0-rc.1", true},
+		// Development builds are older than any release
+		{"v0.1.0", "dev", true},
+		{"nightly", "v1.2.3", false},
+	}
+	for _, tt := range tests {
+		if got := newerVersion(tt.tag, tt.current); got != tt.want {
+			t.Errorf("newerVersion(%q, %q) = %v, want %v", tt.tag, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyProvenanceWithoutGh(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	err := verifyProvenance([]byte("archive"), "aigg-linux-amd64.tar.gz", false)
+	if errcode.Of(err) != errcode.Integrity || !strings.Contains(err.Error(), "--skip-verify") {
+		t.Errorf("verifyProvenance without gh = %v, want an integrity error suggesting --skip-verify", err)
+	}
+	if err := verifyProvenance([]byte("archive"), "aigg-linux-amd64.tar.gz", true); err != nil {
+		t.Errorf("verifyProvenance with --skip-verify = %v, want nil", err)
+	}
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/aigogo"
+	"github.com/aupeachmo/aigogo/pkg/config"
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/webhook"
+)
+
+// loadWebhooks returns the user's webhooks that want event (a project's
+// are ignored, see config.Config.Webhooks). Settings that can't be read are reported and
+// ignored.
+func loadWebhooks(event string) []webhook.Hook {
+	layers, err := config.Load(configProjectDir())
+	if err != nil {
+		warnConfig(err)
+		return nil
+	}
+	var hooks []webhook.Hook
+	for _, h := range config.Merge(layers).Webhooks {
+		if h.Wants(event) {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks
+}
+
+// notifyWebhooks sends e to hooks. The change e reports has already been
+// made, so a webhook that fails is a warning, not a failure of the command.
+func notifyWebhooks(hooks []webhook.Hook, e *webhook.Event) {
+	if registry, repository, tag, err := docker.ParseImageRef(e.Ref); err == nil {
+		e.Registry, e.Repository, e.Tag = registry, repository, tag
+	}
+	e.Time = time.Now().UTC()
+	e.Tool = "aigg/" + version
+
+	errs := webhook.NewSender().Send(commandContext(), hooks, e)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "⚠ Warning: %v\n", err)
+	}
+	if sent := len(hooks) - len(errs); sent > 0 {
+		logging.Printf("Notified %d webhook(s)\n", sent)
+	}
+}
+
+// pushEvent describes a push for webhooks and the job summary. The build is
+// compared with the highest version tag below the pushed one; pushes of tags
+// that aren't versions, and first versions, have no diff.
+func pushEvent(ctx context.Context, client *aigogo.Client, result *aigogo.PushResult) *webhook.Event {
+	e := &webhook.Event{Event: webhook.EventPush, Ref: result.Ref, Digest: result.Digest}
+	if data, err := docker.ReadCachedFile(result.From, "aigogo.json"); err == nil {
+		var m manifest.Manifest
+		if json.Unmarshal(data, &m) == nil {
+			e.Name, e.Version = m.Name, m.Version
+		}
+	}
+	e.Summary = "Pushed " + result.Ref
+
+	previous, err := previousVersionRef(ctx, result.Ref)
+	if err != nil {
+		logging.Verbosef("no diff for webhooks: %v\n", err)
+	}
+	if previous == "" {
+		return e
+	}
+	diff, err := client.Diff(ctx, previous, result.From, aigogo.DiffOptions{Context: -1})
+	if err != nil {
+		logging.Verbosef("no diff for webhooks: failed to compare with %s: %v\n", previous, err)
+		return e
+	}
+	_, _, tag, _ := docker.ParseImageRef(previous)
+	e.Previous = tag
+	e.Diff = &webhook.DiffSummary{Files: []webhook.FileChange{}}
+	e.Diff.Added, e.Diff.Removed, e.Diff.Modified, e.Diff.Insertions, e.Diff.Deletions = diff.Summary()
+	for _, f := range diff.Files {
+		e.Diff.Files = append(e.Diff.Files, webhook.FileChange{Path: f.Path, Status: f.Status, Insertions: f.Insertions, Deletions: f.Deletions})
+	}
+	if diff.Identical() {
+		e.Summary += fmt.Sprintf(", identical to %s", tag)
+	} else {
+		e.Summary += fmt.Sprintf(", %d file(s) changed since %s (+%d -%d)", len(diff.Files), tag, e.Diff.Insertions, e.Diff.Deletions)
+	}
+	return e
+}
+
+// previousVersionRef returns the reference of the highest version tag of
+// ref's repository that is lower than ref's, or "" when ref's tag isn't a
+// version or none is lower
+func previousVersionRef(ctx context.Context, ref string) (string, error) {
+	registry, repository, tag, err := docker.ParseImageRef(ref)
+	if err != nil {
+		return "", err
+	}
+	current, err := manifest.ParseSemver(strings.TrimPrefix(tag, "v"))
+	if err != nil {
+		return "", nil
+	}
+	tags, err := docker.NewPuller().Tags(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to list the tags of %s/%s: %w", registry, repository, err)
+	}
+
+	best, bestVersion := "", manifest.Semver{}
+	for _, t := range tags {
+		v, err := manifest.ParseSemver(strings.TrimPrefix(t, "v"))
+		if err != nil || v.Compare(current) >= 0 {
+			continue
+		}
+		if best == "" || v.Compare(bestVersion) > 0 {
+			best, bestVersion = t, v
+		}
+	}
+	if best == "" {
+		return "", nil
+	}
+	return registry + "/" + repository + ":" + best, nil
+}
+
+// notifyDelete tells the delete webhooks about deleted images
+func notifyDelete(deleted []docker.DeletedImage) {
+	if len(deleted) == 0 {
+		return
+	}
+	hooks := loadWebhooks(webhook.EventDelete)
+	if len(hooks) == 0 {
+		return
+	}
+	for _, d := range deleted {
+		notifyWebhooks(hooks, &webhook.Event{Event: webhook.EventDelete, Ref: d.Ref, Digest: d.Digest, Summary: "Deleted " + d.Ref})
+	}
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+)
+
+func TestPreviousVersionRef(t *testing.T) {
+	setHome(t, t.TempDir())
+	docker.SetRetryPolicy(1, 0)
+	t.Cleanup(func() { docker.SetRetryPolicy(3, 500*time.Millisecond) })
+
+	server := httptest.NewServer(newFakeRegistry([]byte("pretend this is a tar")))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	docker.SetInsecureRegistries([]string{host})
+	t.Cleanup(func() { docker.SetInsecureRegistries(nil) })
+
+	tests := map[string]string{
+		"1.1.0":  host + "/org/utils:1.0.0",
+		"v2.0.0": host + "/org/utils:1.0.0",
+		"1.0.0":  "",
+		"0.9.0":  "",
+		"latest": "",
+	}
+	for tag, want := range tests {
+		got, err := previousVersionRef(context.Background(), host+"/org/utils:"+tag)
+		if err != nil || got != want {
+			t.Errorf("previousVersionRef(%s) = %q, %v, want %q", tag, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/auth"
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+)
+
+func whoamiCmd() *Command {
+	flags := flag.NewFlagSet("whoami", flag.ExitOnError)
+	noCheck := flags.Bool("no-check", false, "Show the stored users without contacting the registries")
+
+	return &Command{
+		Name:        "whoami",
+		Description: "Show the user logged in to each registry and whether it still authenticates",
+		Flags:       flags,
+		Run: func(args []string) error {
+			return whoami(args, !*noCheck)
+		},
+	}
+}
+
+// whoami prints the user stored for each of registries, or for every
+// registry with credentials and the project's registry when registries is
+// empty, checking with each registry that the credentials still work when
+// check is set. It fails when a registry isn't logged in to or a check
+// fails.
+func whoami(registries []string, check bool) error {
+	authManager := auth.NewManager()
+	stored, err := authManager.Registries()
+	if err != nil {
+		return fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	if len(registries) == 0 {
+		registries = stored
+		// The registry the project resolves references against, when it
+		// isn't logged in to
+		if configured := loadProjectSettings(configProjectDir()).Registry; configured != "" {
+			host, _, _ := strings.Cut(configured, "/")
+			if !slices.Contains(stored, host) {
+				fmt.Printf("- %s: not logged in (the registry setting)\n", host)
+			}
+		}
+		if len(registries) == 0 {
+			fmt.Println("Not logged in to any registry")
+			fmt.Println("Run 'aigg login <registry>' to log in")
+			return nil
+		}
+	}
+
+	var failed []string
+	var code errcode.Code
+	for _, registry := range registries {
+		user, err := authManager.Username(registry)
+		if err != nil {
+			fmt.Printf("✗ %s: %v\n", registry, err)
+			failed, code = append(failed, registry), errcode.Auth
+			continue
+		}
+		if !check {
+			fmt.Printf("  %s: %s\n", registry, user)
+			continue
+		}
+		if err := docker.CheckLogin(commandContext(), registry); err != nil {
+			if errcode.Of(err) == errcode.Auth {
+				fmt.Printf("✗ %s: %s (%v)\n", registry, user, err)
+				code = errcode.Auth
+			} else {
+				fmt.Printf("⚠ %s: %s (can't check: %v)\n", registry, user, err)
+				if code == "" {
+					code = errcode.Of(err)
+				}
+			}
+			failed = append(failed, registry)
+			continue
+		}
+		fmt.Printf("✓ %s: %s\n", registry, user)
+	}
+
+	if len(failed) > 0 {
+		return errcode.Errorf(code, "%d of %d registries failed: %s", len(failed), len(registries), strings.Join(failed, ", "))
+	}
+	return nil
+}
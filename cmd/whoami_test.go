@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/auth"
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+)
+
+func TestWhoami(t *testing.T) {
+	setHome(t, t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); r.URL.Path != "/v2/" || !ok || user != "alice" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "http://")
+	docker.SetInsecureRegistries([]string{registry})
+	t.Cleanup(func() { docker.SetInsecureRegistries(nil) })
+
+	authManager := auth.NewManager()
+	if err := whoami(nil, true); err != nil {
+		t.Errorf("whoami() without credentials error = %v", err)
+	}
+	if err := authManager.Login(registry, "alice", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := whoami(nil, true); err != nil {
+		t.Errorf("whoami() error = %v", err)
+	}
+	if user, err := authManager.Username(registry); err != nil || user != "alice" {
+		t.Errorf("Username() = %q, %v", user, err)
+	}
+
+	// Credentials the registry no longer accepts
+	if err := authManager.Login(registry, "alice", "expired"); err != nil {
+		t.Fatal(err)
+	}
+	if err := whoami(nil, true); errcode.Of(err) != errcode.Auth {
+		t.Errorf("whoami() with rejected credentials error = %v, want an auth error", err)
+	}
+	if err := whoami(nil, false); err != nil {
+		t.Errorf("whoami(no check) error = %v", err)
+	}
+	if err := whoami([]string{"ghcr.io"}, false); errcode.Of(err) != errcode.Auth {
+		t.Errorf("whoami(ghcr.io) error = %v, want not logged in", err)
+	}
+}
+
+func TestLogoutAll(t *testing.T) {
+	setHome(t, t.TempDir())
+	authManager := auth.NewManager()
+	for _, registry := range []string{"ghcr.io", "docker.io"} {
+		if err := authManager.Login(registry, "alice", "secret"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := runCommand(logoutCmd(), []string{"--all"}); err != nil {
+		t.Fatal(err)
+	}
+	if registries, err := authManager.Registries(); err != nil || len(registries) != 0 {
+		t.Errorf("Registries() after logout --all = %q, %v", registries, err)
+	}
+	if err := runCommand(logoutCmd(), []string{"--all", "ghcr.io"}); errcode.Of(err) != errcode.Usage {
+		t.Errorf("logout --all ghcr.io error = %v, want a usage error", err)
+	}
+}
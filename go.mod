@@ -6,7 +6,6 @@ toolchain go1.24.10
 
 require (
 	github.com/BurntSushi/toml v1.6.0
+	golang.org/x/sys v0.41.0
 	golang.org/x/term v0.40.0
 )
-
-require golang.org/x/sys v0.41.0 // indirect
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/aupeachmo/aigogo/cmd"
@@ -16,7 +15,6 @@ func main() {
 	cmd.SetVersion(Version)
 
 	if err := cmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(cmd.PrintError(err))
 	}
 }
@@ -0,0 +1,603 @@
+package aigogo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// AddOptions are the options of Client.AddPackage and Client.AddPackages
+type AddOptions struct {
+	Force    bool   // add packages whose environment doesn't match
+	Strict   bool   // refuse deprecated packages
+	Group    string // lockfile.GroupDev, lockfile.GroupOptional, or "" for runtime
+	Registry string // registry, and optionally namespace, for references without one
+}
+
+// AddedPackage is a package that was checked, stored and entered in
+// aigogo.lock
+type AddedPackage struct {
+	Ref      string // as given
+	Name     string
+	Version  string
+	LockName string // its aigogo.lock entry
+	Locked   lockfile.LockedPackage
+	Hash     string // its store hash; empty for a local path package
+	Files    int
+}
+
+// AddResult is what Client.AddPackage or Client.AddPackages added
+type AddResult struct {
+	LockPath string
+	Added    []*AddedPackage
+}
+
+// LooksLikePackageRef checks if the argument looks like a package reference
+// Package refs contain "/" or ":" (e.g., docker.io/org/pkg:1.0.0 or pkg:1.0.0)
+func LooksLikePackageRef(arg string) bool {
+	return strings.Contains(arg, "/") || strings.Contains(arg, ":")
+}
+
+// LooksLikeLocalPath checks if the argument is a package directory rather
+// than a reference: ., .., or a path starting with ./, ../ or /
+func LooksLikeLocalPath(arg string) bool {
+	slashed := filepath.ToSlash(arg)
+	return slashed == "." || slashed == ".." ||
+		strings.HasPrefix(slashed, "./") || strings.HasPrefix(slashed, "../") ||
+		filepath.IsAbs(arg)
+}
+
+// splitPackageExtras splits the extras off a package reference, as in
+// docker.io/org/pkg:1.0.0[viz,cli]. selected is false when the reference
+// names no extras at all; an empty list, pkg:1.0.0[], selects none.
+func splitPackageExtras(ref string) (imageRef string, extras []string, selected bool, err error) {
+	if !strings.HasSuffix(ref, "]") {
+		return ref, nil, false, nil
+	}
+	open := strings.LastIndex(ref, "[")
+	if open <= 0 {
+		return "", nil, false, fmt.Errorf("invalid package reference %s: unmatched ]", ref)
+	}
+	for _, extra := range strings.Split(ref[open+1:len(ref)-1], ",") {
+		extra = strings.TrimSpace(extra)
+		if extra == "" {
+			continue
+		}
+		if !containsString(extras, extra) {
+			extras = append(extras, extra)
+		}
+	}
+	return ref[:open], extras, true, nil
+}
+
+// findAddLockFile returns the path of the project's aigogo.lock, or of a
+// new one in the client's directory. It is changed with lockfile.Update,
+// which reads it again under its lock.
+func (c *Client) findAddLockFile() string {
+	lockPath, _, err := c.findLockFile()
+	if err != nil {
+		// Create new lock file in the client's directory
+		lockPath = filepath.Join(c.dir, lockfile.LockFileName)
+	}
+	return lockPath
+}
+
+// AddPackage adds a package to aigogo.lock: a registry or local cache
+// reference is stored in the package store, and a directory (see
+// LooksLikeLocalPath) is locked as a local path package, which install
+// links to instead of a store copy. opts.Force adds it even if its
+// environment constraints don't match this machine, and opts.Strict
+// refuses it if it is deprecated. The package is locked in opts.Group, so
+// re-adding it without a group makes it a runtime package again. The
+// reference may select extras, e.g. pkg:1.0.0[viz]; without any, a package
+// that is already locked keeps the extras selected before. The project's
+// tag_policy.mutable may warn about or refuse a tag that isn't a version.
+func (c *Client) AddPackage(ctx context.Context, ref string, opts AddOptions) (*AddResult, error) {
+	lockPath := c.findAddLockFile()
+	lockDir := filepath.Dir(lockPath)
+
+	if LooksLikeLocalPath(ref) {
+		c.log.Printf("Adding local package: %s\n\n", ref)
+		var added *AddedPackage
+		if err := lockfile.Update(lockPath, func(lock *lockfile.LockFile) error {
+			var err error
+			added, err = c.lockLocalPackage(ctx, lock, lockDir, ref, opts)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		return &AddResult{LockPath: lockPath, Added: []*AddedPackage{added}}, nil
+	}
+
+	imageRef, extras, extrasSelected, err := splitPackageExtras(ref)
+	if err != nil {
+		return nil, err
+	}
+	settings := c.settings(lockDir)
+	imageRef = projectImageRef(imageRef, opts.Registry, settings)
+	if err := docker.ValidateCacheRef(imageRef); err != nil {
+		return nil, err
+	}
+	if err := c.checkMutableTag("add", imageRef, settings); err != nil {
+		return nil, err
+	}
+	c.log.Printf("Adding package: %s\n\n", imageRef)
+
+	// Check local cache first before pulling from registry
+	if docker.GetCachePath(imageRef) != "" {
+		c.log.Printf("Found in local cache...\n")
+	} else {
+		c.log.Printf("Pulling from registry...\n")
+	}
+	src, err := fetchPackageSource(ctx, imageRef)
+	if err != nil {
+		return nil, err
+	}
+	defer src.cleanup()
+
+	c.log.Printf("Storing in content-addressable store...\n")
+	var added *AddedPackage
+	if err := lockfile.Update(lockPath, func(lock *lockfile.LockFile) error {
+		added, err = c.lockPackage(ctx, lock, settings, imageRef, extras, extrasSelected, src, opts)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	added.Ref = ref
+	return &AddResult{LockPath: lockPath, Added: []*AddedPackage{added}}, nil
+}
+
+// batchPackage is a package of a batch add
+type batchPackage struct {
+	ref            string // as listed
+	local          bool
+	imageRef       string
+	extras         []string
+	extrasSelected bool
+}
+
+// AddPackages adds every package in refs as AddPackage would one at a
+// time, but pulls those that aren't cached several at a time (see
+// SetConcurrency) and writes aigogo.lock once. When any package fails,
+// every failure is reported and aigogo.lock is left as it was.
+func (c *Client) AddPackages(ctx context.Context, refs []string, opts AddOptions) (*AddResult, error) {
+	lockPath := c.findAddLockFile()
+	lockDir := filepath.Dir(lockPath)
+	settings := c.settings(lockDir)
+
+	pkgs := make([]batchPackage, len(refs))
+	for i, ref := range refs {
+		pkg := batchPackage{ref: ref, local: LooksLikeLocalPath(ref)}
+		if !pkg.local {
+			if !LooksLikePackageRef(ref) {
+				return nil, errcode.Errorf(errcode.Usage, "%s is neither a package reference nor a directory\nReferences look like docker.io/org/package:tag; directories start with ./, ../ or /", ref)
+			}
+			var err error
+			if pkg.imageRef, pkg.extras, pkg.extrasSelected, err = splitPackageExtras(ref); err != nil {
+				return nil, err
+			}
+			pkg.imageRef = projectImageRef(pkg.imageRef, opts.Registry, settings)
+			if err := c.checkMutableTag("add", pkg.imageRef, settings); err != nil {
+				return nil, err
+			}
+		}
+		pkgs[i] = pkg
+	}
+
+	sources, fetchErrs := c.fetchPackageSources(ctx, pkgs)
+	defer func() {
+		for _, src := range sources {
+			src.cleanup()
+		}
+	}()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Packages are locked in list order, so a later entry for the same
+	// name wins as it would adding them one at a time
+	result := &AddResult{LockPath: lockPath}
+	if err := lockfile.Update(lockPath, func(lock *lockfile.LockFile) error {
+		var failures []error
+		for _, pkg := range pkgs {
+			var a *AddedPackage
+			var err error
+			switch {
+			case pkg.local:
+				a, err = c.lockLocalPackage(ctx, lock, lockDir, pkg.ref, opts)
+			case fetchErrs[pkg.imageRef] != nil:
+				err = fetchErrs[pkg.imageRef]
+			default:
+				a, err = c.lockPackage(ctx, lock, settings, pkg.imageRef, pkg.extras, pkg.extrasSelected, sources[pkg.imageRef], opts)
+			}
+			if err != nil {
+				failures = append(failures, fmt.Errorf("failed to add %s: %w", pkg.ref, err))
+				continue
+			}
+			a.Ref = pkg.ref
+			result.Added = append(result.Added, a)
+		}
+		if len(failures) > 0 {
+			return fmt.Errorf("%w\n%s was not changed", errors.Join(failures...), lockfile.LockFileName)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// fetchPackageSources fetches the registry packages of a batch, each
+// reference once, several at a time, logging a line as each finishes. It
+// returns the sources and the failures by reference.
+func (c *Client) fetchPackageSources(ctx context.Context, pkgs []batchPackage) (map[string]*packageSource, map[string]error) {
+	var imageRefs []string
+	seen := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if !pkg.local && !seen[pkg.imageRef] {
+			seen[pkg.imageRef] = true
+			imageRefs = append(imageRefs, pkg.imageRef)
+		}
+	}
+	sources := make(map[string]*packageSource)
+	errs := make(map[string]error)
+	if len(imageRefs) == 0 {
+		return sources, errs
+	}
+
+	workers := min(c.concurrency, len(imageRefs))
+	c.log.Printf("Fetching %d package(s)...\n", len(imageRefs))
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for imageRef := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				cached := docker.GetCachePath(imageRef) != ""
+				src, err := fetchPackageSource(ctx, imageRef)
+
+				mu.Lock()
+				done++
+				switch {
+				case err != nil:
+					errs[imageRef] = err
+					c.printf("✗ [%d/%d] %s\n", done, len(imageRefs), imageRef)
+				case cached:
+					sources[imageRef] = src
+					c.log.Printf("✓ [%d/%d] Found %s in the local cache\n", done, len(imageRefs), imageRef)
+				default:
+					sources[imageRef] = src
+					c.log.Printf("✓ [%d/%d] Pulled %s\n", done, len(imageRefs), imageRef)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, imageRef := range imageRefs {
+		jobs <- imageRef
+	}
+	close(jobs)
+	wg.Wait()
+	c.log.Printf("\n")
+	return sources, errs
+}
+
+// packageSource is where a package's files are read from when it is added:
+// its local cache directory, or a temporary extraction of a pulled layer
+type packageSource struct {
+	dir     string
+	files   []string // relative to dir
+	cleanup func()
+}
+
+// fetchPackageSource finds imageRef in the local cache, or pulls it and
+// extracts it to a temporary directory, which cleanup removes
+func fetchPackageSource(ctx context.Context, imageRef string) (*packageSource, error) {
+	if cachePath := docker.GetCachePath(imageRef); cachePath != "" {
+		files, err := LocalBuildFiles(cachePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read local cache: %w", err)
+		}
+		return &packageSource{dir: cachePath, files: files, cleanup: func() {}}, nil
+	}
+
+	// Pull from registry
+	puller := docker.NewPuller()
+	if err := puller.Pull(ctx, imageRef); err != nil {
+		return nil, fmt.Errorf("failed to pull package: %w", err)
+	}
+
+	// Extract to temp directory
+	tmpDir, err := os.MkdirTemp("", "aigogo-add-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	src := &packageSource{dir: tmpDir, cleanup: func() { _ = os.RemoveAll(tmpDir) }}
+
+	extractor := docker.NewExtractor()
+	extractedFiles, err := extractor.Extract(imageRef, tmpDir, true)
+	if err != nil {
+		src.cleanup()
+		return nil, fmt.Errorf("failed to extract package: %w", err)
+	}
+
+	// Convert to relative paths, slash-separated as aigogo.lock lists them
+	for _, f := range extractedFiles {
+		relPath, err := filepath.Rel(tmpDir, f)
+		if err != nil {
+			src.cleanup()
+			return nil, err
+		}
+		src.files = append(src.files, filepath.ToSlash(relPath))
+	}
+	return src, nil
+}
+
+// lockPackage checks the package fetched from imageRef into src, stores it
+// in the CAS and enters it in lock with extras, keeping the extras locked
+// before unless extrasSelected
+func (c *Client) lockPackage(ctx context.Context, lock *lockfile.LockFile, settings *manifest.Settings, imageRef string, extras []string, extrasSelected bool, src *packageSource, opts AddOptions) (*AddedPackage, error) {
+	// Read manifest to get metadata
+	manifestPath := filepath.Join(src.dir, "aigogo.json")
+	var pkgManifest *manifest.Manifest
+	var manifestData []byte
+
+	if data, err := os.ReadFile(manifestPath); err == nil {
+		manifestData = data
+		var m manifest.Manifest
+		if err := json.Unmarshal(data, &m); err == nil {
+			pkgManifest = &m
+		}
+	}
+
+	// Determine package info
+	pkgName, pkgVersion, pkgLanguage := PackageIdentity(imageRef, pkgManifest)
+
+	if pkgManifest != nil {
+		if err := pkgManifest.CheckAigogoVersion(); err != nil {
+			return nil, err
+		}
+		if err := c.checkEnvironment(pkgName, pkgManifest, currentHost(), opts.Force, "add"); err != nil {
+			return nil, err
+		}
+		if err := pkgManifest.CheckExtras(extras); err != nil {
+			return nil, err
+		}
+	} else {
+		if len(extras) > 0 {
+			return nil, fmt.Errorf("%s has no aigogo.json, so it has no extras", imageRef)
+		}
+		// Create minimal manifest
+		manifestData, _ = json.MarshalIndent(map[string]interface{}{
+			"name":     pkgName,
+			"version":  pkgVersion,
+			"language": map[string]string{"name": pkgLanguage},
+		}, "", "  ")
+	}
+	if err := c.checkDeprecation(pkgName, pkgVersion, PackageDeprecation(ctx, imageRef, pkgManifest), opts.Strict); err != nil {
+		return nil, err
+	}
+
+	// Store in CAS
+	cas, err := OpenStore(settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize store: %w", err)
+	}
+
+	hash, err := cas.Store(ctx, src.dir, src.files, manifestData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store package: %w", err)
+	}
+
+	// Make read-only
+	if err := cas.MakeReadOnly(hash); err != nil {
+		c.warnf("failed to make files read-only: %v\n", err)
+	}
+	if pkgManifest != nil {
+		if err := ApplyExecutableBits(cas, hash, pkgManifest); err != nil {
+			c.warnf("failed to set executable files: %v\n", err)
+		}
+	}
+
+	// Add package to lock file
+	lockName, locked := lockEntry(imageRef, pkgManifest, hash, src.files)
+	locked.Extras = extras
+	locked.Group = opts.Group
+	if existing, ok := lock.Packages[lockName]; ok && !extrasSelected && pkgManifest != nil {
+		for _, extra := range existing.Extras {
+			if pkgManifest.CheckExtras([]string{extra}) == nil {
+				locked.Extras = append(locked.Extras, extra)
+			} else {
+				c.warnf("%s@%s no longer has extra %q; dropping it\n", pkgName, pkgVersion, extra)
+			}
+		}
+	}
+	lock.Add(lockName, locked)
+
+	return &AddedPackage{Name: pkgName, Version: pkgVersion, LockName: lockName, Locked: locked, Hash: hash, Files: len(src.files)}, nil
+}
+
+// lockLocalPackage checks the package directory ref names and enters it in
+// lock, whose directory is lockDir, as a local path package. The entry
+// records the directory relative to aigogo.lock and the package's current
+// files, but no integrity hash, as the files change.
+func (c *Client) lockLocalPackage(ctx context.Context, lock *lockfile.LockFile, lockDir, ref string, opts AddOptions) (*AddedPackage, error) {
+	path, extras, extrasSelected, err := splitPackageExtras(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Clean(c.path(path))
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", path)
+	}
+	manifestPath := filepath.Join(dir, "aigogo.json")
+	if _, err := os.Stat(manifestPath); err != nil {
+		return nil, fmt.Errorf("%s has no aigogo.json\nRun 'aigg init' there to make it a package", path)
+	}
+	m, err := manifest.Load(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", filepath.Join(path, "aigogo.json"), err)
+	}
+
+	if dir == lockDir {
+		return nil, fmt.Errorf("%s is this project's own directory; add a package from another directory", path)
+	}
+
+	pkgName, pkgVersion, _ := PackageIdentity(filepath.Base(dir), m)
+	if err := c.checkEnvironment(pkgName, m, currentHost(), opts.Force, "add"); err != nil {
+		return nil, err
+	}
+	if err := m.CheckExtras(extras); err != nil {
+		return nil, err
+	}
+	if err := c.checkDeprecation(pkgName, pkgVersion, PackageDeprecation(ctx, "", m), opts.Strict); err != nil {
+		return nil, err
+	}
+
+	files, err := IncludedFiles(dir, m)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s has no files to package\nCheck files.include in its aigogo.json", path)
+	}
+
+	// The lock file is shared, so the directory is recorded relative to it
+	// where possible
+	rel, err := filepath.Rel(lockDir, dir)
+	if err != nil {
+		rel = dir
+	}
+
+	lockName, locked := lockEntry(filepath.Base(dir), m, "", files)
+	locked.Integrity, locked.Source, locked.Path = "", lockfile.SourcePath, filepath.ToSlash(rel)
+	locked.Extras = extras
+	locked.Group = opts.Group
+	if existing, ok := lock.Packages[lockName]; ok && !extrasSelected {
+		for _, extra := range existing.Extras {
+			if m.CheckExtras([]string{extra}) == nil {
+				locked.Extras = append(locked.Extras, extra)
+			}
+		}
+	}
+	lock.Add(lockName, locked)
+
+	return &AddedPackage{Ref: ref, Name: pkgName, Version: pkgVersion, LockName: lockName, Locked: locked, Files: len(files)}, nil
+}
+
+// IncludedFiles returns the files the manifest's include patterns select,
+// as sorted slash-separated paths relative to dir
+func IncludedFiles(dir string, m *manifest.Manifest) ([]string, error) {
+	discovery, err := manifest.NewFileDiscovery(dir, m.Files.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize file discovery: %w", err)
+	}
+	found, err := discovery.Discover(m.Files, m.AllLanguages()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover files: %w", err)
+	}
+
+	files := make([]string, 0, len(found))
+	for _, f := range found {
+		files = append(files, filepath.ToSlash(f))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// PackageIdentity returns the name, version and language a package is
+// locked under: from its manifest m, or with none, from the reference
+func PackageIdentity(imageRef string, m *manifest.Manifest) (name, version, language string) {
+	name, version, language = lockfile.GetPackageName(imageRef), "unknown", "python"
+	if m == nil {
+		// Extract version from tag if no manifest
+		if idx := strings.LastIndex(imageRef, ":"); idx != -1 {
+			version = imageRef[idx+1:]
+		}
+		return name, version, language
+	}
+	if m.Name != "" {
+		name = m.Name
+	}
+	if m.Version != "" {
+		version = m.Version
+	}
+	if m.Language.Name != "" {
+		language = strings.ToLower(m.Language.Name)
+	}
+	return name, version, language
+}
+
+// lockEntry returns the name and entry recorded in aigogo.lock for a
+// package stored under hash, without extras. m is the package's manifest,
+// or nil if it has none.
+func lockEntry(imageRef string, m *manifest.Manifest, hash string, files []string) (string, lockfile.LockedPackage) {
+	name, version, language := PackageIdentity(imageRef, m)
+
+	// Normalize name for Python and Ruby (hyphens → underscores); keep original for JS and Java
+	lockName := name
+	if language == "python" || language == "ruby" {
+		lockName = lockfile.NormalizeName(name)
+	}
+	locked := lockfile.LockedPackage{
+		Version:   version,
+		Integrity: "sha256:" + hash,
+		Source:    imageRef,
+		Language:  language,
+		Files:     files,
+	}
+	// Multi-language packages record which files belong to which language
+	if m != nil && len(m.Languages) > 0 {
+		locked.Languages = make(map[string][]string)
+		for _, f := range files {
+			if lang := manifest.LanguageOfFile(f, m.AllLanguages()); lang != "" {
+				locked.Languages[lang] = append(locked.Languages[lang], f)
+			}
+		}
+	}
+	return lockName, locked
+}
+
+// collectFiles recursively collects file paths from a directory, returning
+// paths relative to the given prefix.
+func collectFiles(dir string, prefix string) ([]string, error) {
+	var files []string
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		relPath := prefix + "/" + entry.Name()
+		if entry.IsDir() {
+			subFiles, err := collectFiles(filepath.Join(dir, entry.Name()), relPath)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, subFiles...)
+		} else {
+			files = append(files, relPath)
+		}
+	}
+	return files, nil
+}
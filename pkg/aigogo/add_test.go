@@ -0,0 +1,195 @@
+package aigogo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func TestSplitPackageExtras(t *testing.T) {
+	tests := []struct {
+		ref      string
+		imageRef string
+		extras   []string
+		selected bool
+		wantErr  bool
+	}{
+		{"docker.io/org/plots:1.0.0", "docker.io/org/plots:1.0.0", nil, false, false},
+		{"plots:1.0.0[viz]", "plots:1.0.0", []string{"viz"}, true, false},
+		{"docker.io/org/plots:1.0.0[viz, cli,viz]", "docker.io/org/plots:1.0.0", []string{"viz", "cli"}, true, false},
+		{"plots:1.0.0[]", "plots:1.0.0", nil, true, false},
+		{"plots:1.0.0]", "", nil, false, true},
+	}
+	for _, tt := range tests {
+		imageRef, extras, selected, err := splitPackageExtras(tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("splitPackageExtras(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			continue
+		}
+		if imageRef != tt.imageRef || !reflect.DeepEqual(extras, tt.extras) || selected != tt.selected {
+			t.Errorf("splitPackageExtras(%q) = %q, %v, %v; want %q, %v, %v",
+				tt.ref, imageRef, extras, selected, tt.imageRef, tt.extras, tt.selected)
+		}
+	}
+}
+
+func TestLooksLikeLocalPath(t *testing.T) {
+	tests := map[string]bool{
+		"./utils":                   true,
+		"../libs/utils":             true,
+		".":                         true,
+		"..":                        true,
+		"/home/me/utils":            true,
+		"utils:1.0.0":               false,
+		"docker.io/org/utils:1.0.0": false,
+		".utils":                    false,
+	}
+	for arg, want := range tests {
+		if got := LooksLikeLocalPath(arg); got != want {
+			t.Errorf("LooksLikeLocalPath(%q) = %v, want %v", arg, got, want)
+		}
+	}
+}
+
+func TestAddPackageLocal(t *testing.T) {
+	root := t.TempDir()
+	libDir := filepath.Join(root, "libs", "str-utils")
+	appDir := filepath.Join(root, "app")
+	for _, dir := range []string{libDir, appDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		"aigogo.json": `{"name": "str-utils", "version": "0.1.0", "language": {"name": "python", "version": ">=3.8"}, "files": {"include": ["*.py"]}}`,
+		"strings.py":  "def upper(s):\n    return s.upper()\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(libDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := NewClient(appDir)
+
+	result, err := client.AddPackage(context.Background(), "../libs/str-utils", AddOptions{Group: lockfile.GroupDev})
+	if err != nil {
+		t.Fatalf("AddPackage() = %v", err)
+	}
+	if result.LockPath != filepath.Join(appDir, lockfile.LockFileName) || len(result.Added) != 1 || result.Added[0].Name != "str-utils" {
+		t.Errorf("AddPackage() = %+v", result)
+	}
+	lock, err := lockfile.Load(filepath.Join(appDir, lockfile.LockFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := lock.Get("str_utils")
+	if !ok {
+		t.Fatalf("aigogo.lock has no str_utils: %v", lock.Packages)
+	}
+	want := lockfile.LockedPackage{
+		Version:  "0.1.0",
+		Source:   lockfile.SourcePath,
+		Language: "python",
+		Files:    []string{"strings.py"},
+		Path:     "../libs/str-utils",
+		Group:    lockfile.GroupDev,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lock entry = %+v, want %+v", got, want)
+	}
+	if err := lock.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+
+	// A project that is a package itself can't add itself
+	if err := os.WriteFile(filepath.Join(appDir, "aigogo.json"), []byte(files["aigogo.json"]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.AddPackage(context.Background(), ".", AddOptions{}); err == nil || !strings.Contains(err.Error(), "own directory") {
+		t.Errorf("AddPackage(\".\") = %v, want an error for the project's own directory", err)
+	}
+	if _, err := client.AddPackage(context.Background(), "../libs", AddOptions{}); err == nil {
+		t.Error("AddPackage should fail for a directory without aigogo.json")
+	}
+}
+
+func TestAddPackages(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+	t.Setenv("AIGOGO_STORE", "")
+	project := t.TempDir()
+	client := NewClient(project)
+
+	// Two local builds in the cache
+	for _, name := range []string{"alpha", "beta"} {
+		dir := filepath.Join(home, ".aigogo", "cache", docker.SanitizeImageRef(name+":1.0.0"))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		files := map[string]string{
+			"aigogo.json": `{"name": "` + name + `", "version": "1.0.0", "language": {"name": "python", "version": ">=3.8"}}`,
+			name + ".py":  "def run(): pass\n",
+		}
+		for file, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// A failure leaves aigogo.lock alone, however many packages succeed
+	_, err := client.AddPackages(context.Background(), []string{"alpha:1.0.0", "beta:1.0.0[nope]", "./nowhere"}, AddOptions{})
+	if err == nil || !strings.Contains(err.Error(), "failed to add beta:1.0.0[nope]") || !strings.Contains(err.Error(), "failed to add ./nowhere") {
+		t.Fatalf("AddPackages() error = %v, want the failures listed", err)
+	}
+	if _, err := os.Stat(filepath.Join(project, lockfile.LockFileName)); !os.IsNotExist(err) {
+		t.Fatalf("aigogo.lock written despite failures: %v", err)
+	}
+
+	result, err := client.AddPackages(context.Background(), []string{"alpha:1.0.0", "beta:1.0.0"}, AddOptions{Group: lockfile.GroupDev})
+	if err != nil {
+		t.Fatalf("AddPackages() error = %v", err)
+	}
+	if len(result.Added) != 2 {
+		t.Errorf("AddPackages() added %d packages, want 2", len(result.Added))
+	}
+	lock, err := lockfile.Load(result.LockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"alpha", "beta"} {
+		pkg, ok := lock.Get(name)
+		if !ok || pkg.Source != name+":1.0.0" || pkg.Group != lockfile.GroupDev || pkg.Integrity == "" {
+			t.Errorf("lock entry %s = %+v, %v", name, pkg, ok)
+		}
+	}
+}
+
+func TestIncludedFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"a.py", "b.py", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, f), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &manifest.Manifest{
+		Name:     "utils",
+		Language: manifest.Language{Name: "python"},
+		Files:    manifest.FileSpec{Include: []interface{}{"*.py", "c.txt"}},
+	}
+
+	got, err := IncludedFiles(dir, m)
+	if err != nil {
+		t.Fatalf("IncludedFiles failed: %v", err)
+	}
+	if want := []string{"a.py", "b.py", "c.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IncludedFiles() = %v, want %v", got, want)
+	}
+}
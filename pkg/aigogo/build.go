@@ -0,0 +1,194 @@
+package aigogo
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/depgen"
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// BuildOptions are the options of Client.Build
+type BuildOptions struct {
+	// ImageRef is the name:tag to build. When empty, the manifest's name is
+	// built at the next patch version, which is written to aigogo.json.
+	ImageRef      string
+	Force         bool // rebuild an unchanged build, or replace one with other content
+	NoValidate    bool // skip dependency validation
+	NoCache       bool // re-scan every file, ignoring .aigogo/scan-cache.json
+	IgnoreScripts bool // don't run the prebuild and postbuild scripts
+}
+
+// BuildResult is what Client.Build built
+type BuildResult struct {
+	ImageRef    string
+	ManifestDir string // the package's directory
+	Path        string // the build in the local cache
+	ContentHash string // sha256:<hex>, the integrity aigg add locks for it
+	Reused      bool   // the cached build had the same content and was kept
+	Version     string // the version written to aigogo.json, or ""
+}
+
+// Build builds the package whose aigogo.json is in the client's directory
+// or its parents into the local cache. Its prebuild script runs first, as
+// it may generate files, then its dependencies are validated against its
+// imports. A build whose content hasn't changed is reused, without running
+// the postbuild script again.
+func (c *Client) Build(ctx context.Context, opts BuildOptions) (*BuildResult, error) {
+	m, manifestDir, err := manifest.FindManifestFrom(c.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find manifest: %w", err)
+	}
+
+	imageRef := opts.ImageRef
+	if imageRef == "" {
+		if m.Name == "" {
+			return nil, fmt.Errorf("no package name specified and aigogo.json has no name field")
+		}
+		if m.Version == "" {
+			return nil, fmt.Errorf("no version in aigogo.json (required for auto-increment)")
+		}
+		newVersion, err := manifest.IncrementVersion(m.Version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to increment version: %w", err)
+		}
+		imageRef = fmt.Sprintf("%s:%s", m.Name, newVersion)
+		c.log.Printf("Auto-incrementing version: %s -> %s\n", m.Version, newVersion)
+	}
+	c.log.Printf("Building package: %s\n", imageRef)
+
+	// The prebuild script may generate files, so it runs before
+	// validation and file discovery
+	lifecycle := lifecycleContext{
+		Name:       m.Name,
+		Version:    buildVersion(imageRef, m.Version),
+		PackageDir: manifestDir,
+		ProjectDir: manifestDir,
+		ImageRef:   imageRef,
+	}
+	if script, ok := lifecycleScript(m, manifest.ScriptPrebuild, lifecycle); ok && !opts.IgnoreScripts {
+		if err := c.runLifecycleScript(ctx, script, manifestDir); err != nil {
+			return nil, fmt.Errorf("%w\nUse --ignore-scripts to skip", err)
+		}
+		// The script may have edited aigogo.json
+		if reloaded, err := manifest.Load(filepath.Join(manifestDir, "aigogo.json")); err == nil {
+			m = reloaded
+		}
+	}
+
+	if !opts.NoValidate {
+		c.log.Printf("Validating dependencies...\n")
+		if err := c.validateManifest(m, manifestDir, opts.NoCache); err != nil {
+			return nil, fmt.Errorf("validation failed: %w\nUse --no-validate to skip", err)
+		}
+		c.log.Printf("✓ Validation passed\n")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	built, err := docker.NewLocalBuilder().BuildFromDir(manifestDir, imageRef, m, opts.Force)
+	if err != nil {
+		return nil, fmt.Errorf("build failed: %w", err)
+	}
+	result := &BuildResult{
+		ImageRef:    imageRef,
+		ManifestDir: manifestDir,
+		Path:        built.Path,
+		ContentHash: built.ContentHash,
+		Reused:      built.Reused,
+	}
+
+	if opts.ImageRef == "" {
+		m.Version = buildVersion(imageRef, m.Version)
+		if err := c.saveManifest(filepath.Join(manifestDir, "aigogo.json"), m); err != nil {
+			c.printf("⚠️  Warning: Built successfully but failed to update version in aigogo.json: %v\n", err)
+		} else {
+			result.Version = m.Version
+		}
+	}
+
+	// A reused build was already through the postbuild script
+	if !built.Reused {
+		lifecycle.PackageDir = built.Path
+		if script, ok := lifecycleScript(m, manifest.ScriptPostbuild, lifecycle); ok && !opts.IgnoreScripts {
+			if err := c.runLifecycleScript(ctx, script, manifestDir); err != nil {
+				return nil, fmt.Errorf("built %s, but %w", imageRef, err)
+			}
+		}
+	}
+	return result, nil
+}
+
+// buildVersion returns the version a build produces: the tag of imageRef,
+// or the manifest version if the reference has no tag
+func buildVersion(imageRef, manifestVersion string) string {
+	if i := strings.LastIndex(imageRef, ":"); i > strings.LastIndex(imageRef, "/") {
+		return imageRef[i+1:]
+	}
+	return manifestVersion
+}
+
+// saveManifest saves an updated manifest, noting when the comments and
+// trailing commas of a JSONC aigogo.json were lost
+func (c *Client) saveManifest(path string, m *manifest.Manifest) error {
+	if err := manifest.Save(path, m); err != nil {
+		return err
+	}
+	if m.HasComments() {
+		c.printf("Note: %s was rewritten as plain JSON; its comments and trailing commas were removed\n", filepath.Base(path))
+	}
+	return nil
+}
+
+// validateManifest checks the manifest's declared dependencies against the
+// imports in its files, the same check 'aigg validate' runs
+func (c *Client) validateManifest(m *manifest.Manifest, manifestDir string, noCache bool) error {
+	discovery, err := manifest.NewFileDiscovery(manifestDir, m.Files.Exclude)
+	if err != nil {
+		return fmt.Errorf("failed to initialize file discovery: %w", err)
+	}
+	files, err := discovery.Discover(m.Files, m.AllLanguages()...)
+	if err != nil {
+		return fmt.Errorf("failed to discover files: %w", err)
+	}
+	files = m.Files.WithoutData(files)
+	if len(files) == 0 {
+		return nil
+	}
+	for i, f := range files {
+		files[i] = filepath.Join(manifestDir, f)
+	}
+
+	validator := depgen.NewValidator()
+	validator.SetProgress(func(done, total int) {
+		c.log.Printf("  Scanned %d/%d files...\n", done, total)
+	})
+	var cache *depgen.ScanCache
+	if !noCache {
+		cache = depgen.LoadScanCache(filepath.Join(manifestDir, depgen.ScanCacheFile))
+		validator.SetCache(cache)
+	}
+	result, err := validator.Validate(m, files)
+	if err != nil {
+		return err
+	}
+	if cache != nil {
+		cache.Save()
+	}
+
+	if !result.Valid {
+		msg := strings.Join(result.Errors, "; ")
+		if len(result.MissingDeps) > 0 {
+			missing := append([]string(nil), result.MissingDeps...)
+			sort.Strings(missing)
+			msg += ": " + strings.Join(missing, ", ")
+		}
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
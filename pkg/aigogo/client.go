@@ -0,0 +1,167 @@
+// Package aigogo is the Go API behind aigg. A Client adds, installs,
+// builds, pushes and diffs packages as the commands do, for the project in
+// the directory it is given, and returns what each operation did as a
+// typed result. Nothing is printed unless the caller asks: progress goes
+// to a Logger, and warnings and lifecycle script output to the writers set
+// with SetOutput. Registry access, the local cache and the package store
+// are configured as for aigg itself (see the docker and store packages).
+package aigogo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/config"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/store"
+)
+
+// DefaultConcurrency is how many packages are fetched at once unless
+// SetConcurrency changes it
+const DefaultConcurrency = 4
+
+// Logger receives an operation's progress: Printf the steps aigg prints
+// by default, Verbosef the detail it prints with --verbose, such as paths
+// and hashes
+type Logger interface {
+	Printf(format string, args ...interface{})
+	Verbosef(format string, args ...interface{})
+}
+
+// discardLogger is the Logger of a client that hasn't been given one
+type discardLogger struct{}
+
+func (discardLogger) Printf(string, ...interface{})   {}
+func (discardLogger) Verbosef(string, ...interface{}) {}
+
+// Client runs operations on the project in a directory
+type Client struct {
+	dir            string
+	in             io.Reader
+	out, errOut    io.Writer
+	log            Logger
+	concurrency    int
+	loadSettings   func(projectDir string) *manifest.Settings
+	settingsWarned bool
+	offline        bool
+
+	// fetch fetches a locked package into the store; tests replace it
+	fetch func(ctx context.Context, cas *store.Store, pkg lockfile.LockedPackage, offline bool) error
+}
+
+// NewClient returns a client for the project in dir. aigogo.lock and
+// aigogo.json are looked for there and in its parents, as aigg looks
+// from the working directory, and relative paths given to the client's
+// operations are resolved against dir.
+func NewClient(dir string) *Client {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	c := &Client{
+		dir:         dir,
+		in:          strings.NewReader(""),
+		out:         io.Discard,
+		errOut:      io.Discard,
+		log:         discardLogger{},
+		concurrency: DefaultConcurrency,
+	}
+	c.fetch = c.fetchAndStore
+	return c
+}
+
+// Dir returns the directory the client works in
+func (c *Client) Dir() string {
+	return c.dir
+}
+
+// SetOutput sets where warnings and the output of lifecycle scripts go:
+// out, and errOut for the scripts' standard error. Both discard by
+// default.
+func (c *Client) SetOutput(out, errOut io.Writer) {
+	c.out, c.errOut = out, errOut
+}
+
+// SetInput sets what lifecycle scripts read as standard input; by default
+// they read nothing
+func (c *Client) SetInput(in io.Reader) {
+	c.in = in
+}
+
+// SetLogger sets where progress is reported; by default it is discarded
+func (c *Client) SetLogger(l Logger) {
+	c.log = l
+}
+
+// SetConcurrency sets how many packages are fetched at once
+func (c *Client) SetConcurrency(n int) {
+	if n > 0 {
+		c.concurrency = n
+	}
+}
+
+// SetOffline stops OpenPackage, and Diff, from downloading packages from
+// their registry: only the local cache, aigogo.lock and the package store
+// are read
+func (c *Client) SetOffline(offline bool) {
+	c.offline = offline
+}
+
+// SetSettingsLoader replaces how the settings of the project in a
+// directory are read. By default they are read with config.Load, and
+// settings that can't be read are reported once and ignored.
+func (c *Client) SetSettingsLoader(load func(projectDir string) *manifest.Settings) {
+	c.loadSettings = load
+}
+
+// settings returns the settings of the project in projectDir
+func (c *Client) settings(projectDir string) *manifest.Settings {
+	if c.loadSettings != nil {
+		return c.loadSettings(projectDir)
+	}
+	layers, err := config.Load(projectDir)
+	if err != nil {
+		if !c.settingsWarned {
+			c.settingsWarned = true
+			c.warnf("ignoring project settings: %v\n", err)
+		}
+		return &manifest.Settings{}
+	}
+	return &config.Merge(layers).Settings
+}
+
+// OpenStore opens the package store the project settings name, or the
+// default store/ in the data directory
+func OpenStore(settings *manifest.Settings) (*store.Store, error) {
+	if settings.Store != "" {
+		return store.NewStoreAt(settings.Store)
+	}
+	return store.NewStore()
+}
+
+// path resolves path against the client's directory
+func (c *Client) path(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(c.dir, path)
+}
+
+// printf writes to the client's output
+func (c *Client) printf(format string, args ...interface{}) {
+	_, _ = fmt.Fprintf(c.out, format, args...)
+}
+
+// warnf writes a warning to the client's output
+func (c *Client) warnf(format string, args ...interface{}) {
+	c.printf("⚠ Warning: "+format, args...)
+}
+
+// findLockFile returns the path and contents of the aigogo.lock of the
+// project around the client's directory
+func (c *Client) findLockFile() (string, *lockfile.LockFile, error) {
+	return lockfile.FindLockFileFrom(c.dir)
+}
@@ -0,0 +1,16 @@
+package aigogo
+
+import (
+	"testing"
+)
+
+// setHome points HOME at home for the test, with the XDG base directory
+// variables unset so that aigg's files are all in home
+func setHome(t *testing.T, home string) {
+	t.Helper()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	for _, name := range []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_CACHE_HOME"} {
+		t.Setenv(name, "")
+	}
+}
@@ -0,0 +1,55 @@
+package aigogo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// fetchAnnotations returns the registry annotations of a package; tests
+// replace it
+var fetchAnnotations = func(ctx context.Context, ref string) (map[string]string, error) {
+	return docker.NewPuller().Annotations(ctx, ref)
+}
+
+// PackageDeprecation returns how the package added from source is
+// deprecated, or nil. The package's own manifest is checked first, then,
+// for registry references, the annotations aigg push --deprecate sets after
+// release. Registry errors are ignored, so packages still install offline.
+func PackageDeprecation(ctx context.Context, source string, m *manifest.Manifest) *manifest.Deprecation {
+	if m != nil && m.Deprecated != nil {
+		return m.Deprecated
+	}
+	if source == "" || docker.IsLocalReference(source) {
+		return nil
+	}
+	annotations, err := fetchAnnotations(ctx, source)
+	if err != nil {
+		return nil
+	}
+	return docker.DeprecationFromAnnotations(annotations)
+}
+
+// checkDeprecation warns that package name is deprecated, or with strict
+// returns an error instead. d may be nil.
+func (c *Client) checkDeprecation(name, version string, d *manifest.Deprecation, strict bool) error {
+	if d == nil {
+		return nil
+	}
+	hint := ""
+	if d.Replacement != "" {
+		hint = fmt.Sprintf("\nUse %s instead", d.Replacement)
+	}
+	if strict {
+		return fmt.Errorf("%s@%s is deprecated: %s%s\nRun without --strict to use it anyway", name, version, d.Message, hint)
+	}
+	c.printf("\n⚠️  DEPRECATED: %s@%s\n", name, version)
+	c.printf("   %s\n", d.Message)
+	if d.Replacement != "" {
+		c.printf("   Use instead: %s\n", d.Replacement)
+	}
+	c.printf("\n")
+	return nil
+}
@@ -0,0 +1,68 @@
+package aigogo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func TestPackageDeprecation(t *testing.T) {
+	defer func(f func(context.Context, string) (map[string]string, error)) { fetchAnnotations = f }(fetchAnnotations)
+
+	var fetched []string
+	fetchAnnotations = func(_ context.Context, ref string) (map[string]string, error) {
+		fetched = append(fetched, ref)
+		switch ref {
+		case "ghcr.io/org/old:1.0.0":
+			return map[string]string{
+				docker.AnnotationDeprecated:  "No longer maintained",
+				docker.AnnotationReplacement: "ghcr.io/org/new:2.0.0",
+			}, nil
+		case "ghcr.io/org/current:1.0.0":
+			return map[string]string{docker.AnnotationTitle: "current"}, nil
+		}
+		return nil, errors.New("offline")
+	}
+
+	fromManifest := &manifest.Manifest{Deprecated: &manifest.Deprecation{Message: "Built deprecated"}}
+	if got := PackageDeprecation(context.Background(), "ghcr.io/org/old:1.0.0", fromManifest); got != fromManifest.Deprecated {
+		t.Errorf("manifest deprecation should win, got %+v", got)
+	}
+
+	want := &manifest.Deprecation{Message: "No longer maintained", Replacement: "ghcr.io/org/new:2.0.0"}
+	if got := PackageDeprecation(context.Background(), "ghcr.io/org/old:1.0.0", &manifest.Manifest{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("PackageDeprecation(annotated) = %+v, want %+v", got, want)
+	}
+	if got := PackageDeprecation(context.Background(), "ghcr.io/org/current:1.0.0", nil); got != nil {
+		t.Errorf("PackageDeprecation(current) = %+v, want nil", got)
+	}
+	if got := PackageDeprecation(context.Background(), "ghcr.io/org/unreachable:1.0.0", nil); got != nil {
+		t.Errorf("registry errors should be ignored, got %+v", got)
+	}
+
+	fetched = nil
+	if got := PackageDeprecation(context.Background(), "utils:1.0.0", nil); got != nil || len(fetched) != 0 {
+		t.Errorf("local references shouldn't reach a registry: got %+v, fetched %v", got, fetched)
+	}
+}
+
+func TestCheckDeprecation(t *testing.T) {
+	if err := NewClient(t.TempDir()).checkDeprecation("utils", "1.0.0", nil, true); err != nil {
+		t.Errorf("checkDeprecation(nil) = %v", err)
+	}
+
+	d := &manifest.Deprecation{Message: "No longer maintained", Replacement: "ghcr.io/org/new:2.0.0"}
+	if err := NewClient(t.TempDir()).checkDeprecation("utils", "1.0.0", d, false); err != nil {
+		t.Errorf("checkDeprecation without --strict should only warn, got %v", err)
+	}
+	err := NewClient(t.TempDir()).checkDeprecation("utils", "1.0.0", d, true)
+	if err == nil || !strings.Contains(err.Error(), "utils@1.0.0 is deprecated: No longer maintained") ||
+		!strings.Contains(err.Error(), "Use ghcr.io/org/new:2.0.0 instead") {
+		t.Errorf("checkDeprecation with --strict = %v", err)
+	}
+}
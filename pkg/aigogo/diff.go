@@ -0,0 +1,370 @@
+package aigogo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+)
+
+// DefaultDiffContext is how many unchanged lines surround each change in a
+// unified diff unless DiffOptions.Context says otherwise
+const DefaultDiffContext = 3
+
+// A file with a version larger than LargeDiffSize bytes or LargeDiffLines
+// lines isn't diffed line by line unless DiffOptions.ForceLarge is set:
+// finding the changes takes memory in proportion to the product of the two
+// versions' line counts. Such files are compared by digest instead.
+const (
+	LargeDiffSize  = 1 << 20
+	LargeDiffLines = 5000
+)
+
+// DiffOptions are the options of Client.Diff
+type DiffOptions struct {
+	Context    int  // lines of context around changes; 0 means DefaultDiffContext, negative none
+	Remote     bool // read registry references from the registry, as aigg info --remote
+	ForceLarge bool // diff files past LargeDiffSize or LargeDiffLines line by line too
+}
+
+// FileDiff is one file that differs between two packages
+type FileDiff struct {
+	Path       string `json:"path"`
+	Status     string `json:"status"` // "added", "removed" or "modified"
+	OldSize    int64  `json:"old_size"`
+	NewSize    int64  `json:"new_size"`
+	Binary     bool   `json:"binary,omitempty"`
+	Large      bool   `json:"large,omitempty"`      // compared by digest, not diffed (see LargeDiffSize)
+	OldDigest  string `json:"old_digest,omitempty"` // of a large file
+	NewDigest  string `json:"new_digest,omitempty"`
+	Insertions int    `json:"insertions"`
+	Deletions  int    `json:"deletions"`
+	Unified    string `json:"unified,omitempty"` // the unified diff; empty for binary and large files
+}
+
+// DiffResult is how the packages Client.Diff compared differ
+type DiffResult struct {
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	Files     []FileDiff `json:"files"` // the files that differ, sorted by path
+	Unchanged int        `json:"unchanged"`
+}
+
+// Identical reports whether the packages have the same files and contents
+func (r *DiffResult) Identical() bool {
+	return len(r.Files) == 0
+}
+
+// Summary returns how many files were added, removed and modified, and the
+// lines inserted and deleted across them
+func (r *DiffResult) Summary() (added, removed, modified, insertions, deletions int) {
+	for _, f := range r.Files {
+		switch f.Status {
+		case "added":
+			added++
+		case "removed":
+			removed++
+		default:
+			modified++
+		}
+		insertions += f.Insertions
+		deletions += f.Deletions
+	}
+	return added, removed, modified, insertions, deletions
+}
+
+// Diff compares the files of the packages from and to, each found as
+// OpenPackage finds it: a local build, a locked package, a sha256: hash of
+// the package store or a registry reference
+func (c *Client) Diff(ctx context.Context, from, to string, opts DiffOptions) (*DiffResult, error) {
+	contextLines := opts.Context
+	if contextLines == 0 {
+		contextLines = DefaultDiffContext
+	} else if contextLines < 0 {
+		contextLines = 0
+	}
+
+	oldPkg, err := c.OpenPackage(ctx, from, opts.Remote)
+	if err != nil {
+		return nil, err
+	}
+	newPkg, err := c.OpenPackage(ctx, to, opts.Remote)
+	if err != nil {
+		return nil, err
+	}
+	oldFiles, err := oldPkg.Files()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files of %s: %w", from, err)
+	}
+	newFiles, err := newPkg.Files()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files of %s: %w", to, err)
+	}
+
+	result := &DiffResult{From: from, To: to, Files: []FileDiff{}}
+	oldSizes := map[string]int64{}
+	for _, f := range oldFiles {
+		oldSizes[f.Path] = f.Size
+	}
+	newSizes := map[string]int64{}
+	for _, f := range newFiles {
+		newSizes[f.Path] = f.Size
+	}
+
+	for _, path := range unionPaths(oldFiles, newFiles) {
+		oldSize, inOld := oldSizes[path]
+		newSize, inNew := newSizes[path]
+		if !opts.ForceLarge && max(oldSize, newSize) > LargeDiffSize {
+			fd, err := diffLargeFile(oldPkg, newPkg, path, oldSize, newSize, inOld, inNew)
+			if err != nil {
+				return nil, err
+			}
+			if fd == nil {
+				result.Unchanged++
+			} else {
+				result.Files = append(result.Files, *fd)
+			}
+			continue
+		}
+
+		var oldData, newData []byte
+		if inOld {
+			if oldData, err = oldPkg.ReadFile(path); err != nil {
+				return nil, fmt.Errorf("failed to read %s from %s: %w", path, from, err)
+			}
+		}
+		if inNew {
+			if newData, err = newPkg.ReadFile(path); err != nil {
+				return nil, fmt.Errorf("failed to read %s from %s: %w", path, to, err)
+			}
+		}
+
+		fd := FileDiff{Path: path, OldSize: oldSize, NewSize: newSize}
+		switch {
+		case !inOld:
+			fd.Status = "added"
+		case !inNew:
+			fd.Status = "removed"
+		case bytes.Equal(oldData, newData):
+			result.Unchanged++
+			continue
+		default:
+			fd.Status = "modified"
+		}
+
+		if isBinary(oldData) || isBinary(newData) {
+			fd.Binary = true
+			result.Files = append(result.Files, fd)
+			continue
+		}
+		oldLines, newLines := splitLines(oldData), splitLines(newData)
+		if !opts.ForceLarge && max(len(oldLines), len(newLines)) > LargeDiffLines {
+			fd.Large = true
+			if inOld {
+				fd.OldDigest = docker.CalculateDigest(oldData)
+			}
+			if inNew {
+				fd.NewDigest = docker.CalculateDigest(newData)
+			}
+		} else {
+			oldName, newName := "a/"+path, "b/"+path
+			if !inOld {
+				oldName = "/dev/null"
+			}
+			if !inNew {
+				newName = "/dev/null"
+			}
+			fd.Unified, fd.Insertions, fd.Deletions = computeUnifiedDiff(oldName, newName, oldLines, newLines, contextLines)
+		}
+		result.Files = append(result.Files, fd)
+	}
+	return result, nil
+}
+
+// diffLargeFile compares a file larger than LargeDiffSize in either package
+// by digest, without holding both versions in memory, and returns how it
+// differs, or nil when it doesn't
+func diffLargeFile(oldPkg, newPkg *Package, path string, oldSize, newSize int64, inOld, inNew bool) (*FileDiff, error) {
+	fd := &FileDiff{Path: path, OldSize: oldSize, NewSize: newSize, Large: true}
+	var err error
+	if inOld {
+		if fd.OldDigest, err = oldPkg.fileDigest(path); err != nil {
+			return nil, fmt.Errorf("failed to read %s from %s: %w", path, oldPkg.Ref, err)
+		}
+	}
+	if inNew {
+		if fd.NewDigest, err = newPkg.fileDigest(path); err != nil {
+			return nil, fmt.Errorf("failed to read %s from %s: %w", path, newPkg.Ref, err)
+		}
+	}
+	switch {
+	case !inOld:
+		fd.Status = "added"
+	case !inNew:
+		fd.Status = "removed"
+	case fd.OldDigest == fd.NewDigest:
+		return nil, nil
+	default:
+		fd.Status = "modified"
+	}
+	return fd, nil
+}
+
+// unionPaths returns the paths of both file lists, sorted and without
+// duplicates
+func unionPaths(a, b []docker.PackageFile) []string {
+	seen := map[string]bool{}
+	var paths []string
+	for _, list := range [][]docker.PackageFile{a, b} {
+		for _, f := range list {
+			if !seen[f.Path] {
+				seen[f.Path] = true
+				paths = append(paths, f.Path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// isBinary reports whether data looks like a binary file, as git decides:
+// a NUL byte near the start, or content that isn't UTF-8
+func isBinary(data []byte) bool {
+	head := data
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	return bytes.IndexByte(head, 0) >= 0 || !utf8.Valid(data)
+}
+
+// splitLines splits data into lines that keep their "\n", so a last line
+// without one differs from the same line with one
+func splitLines(data []byte) []string {
+	var lines []string
+	text := string(data)
+	for text != "" {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			lines = append(lines, text)
+			break
+		}
+		lines = append(lines, text[:i+1])
+		text = text[i+1:]
+	}
+	return lines
+}
+
+// diffOp is one line of an edit script: ' ' kept, '-' deleted from a or
+// '+' inserted from b
+type diffOp struct {
+	kind byte
+	line string
+	a, b int // the line's index in a and b, or where it would be
+}
+
+// computeUnifiedDiff returns the unified diff turning lines a into lines b,
+// with contextLines lines around each change, and the lines it inserts and
+// deletes. It finds a longest common subsequence of the lines.
+func computeUnifiedDiff(oldName, newName string, a, b []string, contextLines int) (string, int, int) {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	insertions, deletions := 0, 0
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			insertions++
+			j++
+		default:
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			deletions++
+			i++
+		}
+	}
+	if insertions == 0 && deletions == 0 {
+		return "", 0, 0
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(ops); {
+		// Find the next change, then extend the hunk while changes are at
+		// most 2*contextLines kept lines apart
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		end := start
+		for k := start; k < len(ops); k++ {
+			if ops[k].kind != ' ' {
+				end = k + 1
+			} else if k-end >= 2*contextLines {
+				break
+			}
+		}
+		from := max(start-contextLines, 0)
+		to := min(end+contextLines, len(ops))
+		writeHunk(&sb, ops[from:to])
+		start = to
+	}
+	return sb.String(), insertions, deletions
+}
+
+// writeHunk writes a unified diff hunk of ops, with its @@ header
+func writeHunk(sb *strings.Builder, ops []diffOp) {
+	aLen, bLen := 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			aLen++
+		}
+		if op.kind != '-' {
+			bLen++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(ops[0].a, aLen), hunkRange(ops[0].b, bLen))
+	for _, op := range ops {
+		sb.WriteByte(op.kind)
+		sb.WriteString(op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats a hunk's start line and length as diff -u does: an
+// empty range starts at the line before it
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
@@ -0,0 +1,220 @@
+package aigogo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/store"
+)
+
+func TestComputeUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      string
+		context   int
+		want      string
+		ins, dels int
+	}{
+		{
+			name:    "identical",
+			a:       "one\ntwo\n",
+			b:       "one\ntwo\n",
+			context: 3,
+			want:    "",
+		},
+		{
+			name:    "changed line",
+			a:       "one\ntwo\nthree\n",
+			b:       "one\n2\nthree\n",
+			context: 3,
+			want:    "--- a\n+++ b\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+			ins:     1,
+			dels:    1,
+		},
+		{
+			name:    "added file",
+			a:       "",
+			b:       "one\n",
+			context: 3,
+			want:    "--- a\n+++ b\n@@ -0,0 +1 @@\n+one\n",
+			ins:     1,
+		},
+		{
+			name:    "missing newline",
+			a:       "one\n",
+			b:       "one",
+			context: 3,
+			want:    "--- a\n+++ b\n@@ -1 +1 @@\n-one\n+one\n\\ No newline at end of file\n",
+			ins:     1,
+			dels:    1,
+		},
+		{
+			name:    "separate hunks",
+			a:       "1\n2\n3\n4\n5\n6\n7\n8\n",
+			b:       "x\n2\n3\n4\n5\n6\n7\ny\n",
+			context: 1,
+			want:    "--- a\n+++ b\n@@ -1,2 +1,2 @@\n-1\n+x\n 2\n@@ -7,2 +7,2 @@\n 7\n-8\n+y\n",
+			ins:     2,
+			dels:    2,
+		},
+		{
+			name:    "merged hunks",
+			a:       "1\n2\n3\n4\n",
+			b:       "x\n2\n3\ny\n",
+			context: 1,
+			want:    "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n-4\n+y\n",
+			ins:     2,
+			dels:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ins, dels := computeUnifiedDiff("a", "b", splitLines([]byte(tt.a)), splitLines([]byte(tt.b)), tt.context)
+			if got != tt.want {
+				t.Errorf("computeUnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if ins != tt.ins || dels != tt.dels {
+				t.Errorf("computeUnifiedDiff() = +%d -%d, want +%d -%d", ins, dels, tt.ins, tt.dels)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	setHome(t, t.TempDir())
+	t.Setenv("AIGOGO_STORE", "")
+	cas, err := store.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storePackage := func(files map[string]string) string {
+		src := t.TempDir()
+		var paths []string
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			paths = append(paths, name)
+		}
+		hash, err := cas.Store(context.Background(), src, paths, []byte(files["aigogo.json"]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "sha256:" + hash
+	}
+	from := storePackage(map[string]string{
+		"aigogo.json": `{"name": "utils", "version": "1.0.0"}`,
+		"utils.py":    "def util():\n    return 1\n",
+		"old.py":      "pass\n",
+		"logo.bin":    "\x00\x01",
+	})
+	to := storePackage(map[string]string{
+		"aigogo.json": `{"name": "utils", "version": "1.0.0"}`,
+		"utils.py":    "def util():\n    return 2\n",
+		"new.py":      "pass\n",
+		"logo.bin":    "\x00\x02",
+	})
+
+	result, err := NewClient(t.TempDir()).Diff(context.Background(), from, to, DiffOptions{})
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if result.Identical() || result.Unchanged != 1 {
+		t.Errorf("Diff() = %+v, want differences and 1 unchanged file", result)
+	}
+	var got []string
+	for _, f := range result.Files {
+		got = append(got, f.Path+" "+f.Status)
+	}
+	want := []string{"logo.bin modified", "new.py added", "old.py removed", "utils.py modified"}
+	if len(got) != len(want) {
+		t.Fatalf("Diff() files = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Diff() files = %v, want %v", got, want)
+			break
+		}
+	}
+	if !result.Files[0].Binary || result.Files[0].Unified != "" {
+		t.Errorf("logo.bin = %+v, want a binary file without a diff", result.Files[0])
+	}
+	if want := "--- /dev/null\n+++ b/new.py\n@@ -0,0 +1 @@\n+pass\n"; result.Files[1].Unified != want {
+		t.Errorf("new.py diff =\n%s\nwant\n%s", result.Files[1].Unified, want)
+	}
+	added, removed, modified, ins, dels := result.Summary()
+	if added != 1 || removed != 1 || modified != 2 || ins != 2 || dels != 2 {
+		t.Errorf("Summary() = %d %d %d +%d -%d, want 1 1 2 +2 -2", added, removed, modified, ins, dels)
+	}
+
+	same, err := NewClient(t.TempDir()).Diff(context.Background(), from, from, DiffOptions{})
+	if err != nil || !same.Identical() {
+		t.Errorf("Diff() of a package with itself = %+v, %v, want identical", same, err)
+	}
+}
+
+func TestDiffLargeFiles(t *testing.T) {
+	setHome(t, t.TempDir())
+	t.Setenv("AIGOGO_STORE", "")
+	cas, err := store.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	storePackage := func(files map[string]string) string {
+		src := t.TempDir()
+		var paths []string
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			paths = append(paths, name)
+		}
+		hash, err := cas.Store(context.Background(), src, paths, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "sha256:" + hash
+	}
+
+	manyLines := strings.Repeat("row\n", LargeDiffLines)
+	big := strings.Repeat("x", LargeDiffSize)
+	from := storePackage(map[string]string{
+		"lines.txt": manyLines + "old\n",
+		"big.txt":   big + "1\n",
+		"same.txt":  big + "same\n",
+	})
+	to := storePackage(map[string]string{
+		"lines.txt": manyLines + "new\n",
+		"big.txt":   big + "2\n",
+		"same.txt":  big + "same\n",
+	})
+
+	client := NewClient(t.TempDir())
+	result, err := client.Diff(context.Background(), from, to, DiffOptions{})
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if len(result.Files) != 2 || result.Unchanged != 1 {
+		t.Fatalf("Diff() = %+v, want 2 large files and 1 unchanged", result)
+	}
+	for _, f := range result.Files {
+		if !f.Large || f.Unified != "" || !strings.HasPrefix(f.OldDigest, "sha256:") || f.OldDigest == f.NewDigest {
+			t.Errorf("%s = %+v, want a large file compared by digest", f.Path, f)
+		}
+	}
+
+	forced, err := client.Diff(context.Background(), from, to, DiffOptions{ForceLarge: true})
+	if err != nil {
+		t.Fatalf("Diff(ForceLarge) error = %v", err)
+	}
+	for _, f := range forced.Files {
+		if f.Large || f.Insertions != 1 || f.Deletions != 1 {
+			t.Errorf("%s with ForceLarge = %+v, want a line diff", f.Path, f)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package aigogo
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/aupeachmo/aigogo/pkg/interpreter"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// hostEnvironment describes the machine packages are added or installed
+// on. The Python implementation and Node.js version are only probed when a
+// package constrains them, and "" means the interpreter wasn't found.
+type hostEnvironment struct {
+	OS                   string
+	Arch                 string
+	PythonImplementation func() string
+	NodeVersion          func() string
+}
+
+// currentHost returns the environment of this machine
+func currentHost() hostEnvironment {
+	return hostEnvironment{
+		OS:                   runtime.GOOS,
+		Arch:                 runtime.GOARCH,
+		PythonImplementation: sync.OnceValue(detectPythonImplementation),
+		NodeVersion:          sync.OnceValue(detectNodeVersion),
+	}
+}
+
+// detectPythonImplementation returns sys.implementation.name of the Python
+// interpreter aigg exec would use
+func detectPythonImplementation() string {
+	python, err := interpreter.FindPython("")
+	if err != nil {
+		return ""
+	}
+	out, err := exec.Command(python, "-c", "import sys; print(sys.implementation.name)").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// detectNodeVersion returns the version of node on PATH
+func detectNodeVersion() string {
+	node, err := interpreter.FindNode("")
+	if err != nil {
+		return ""
+	}
+	version, err := interpreter.NodeVersion(node)
+	if err != nil {
+		return ""
+	}
+	return version
+}
+
+// environmentProblems lists the ways host doesn't satisfy env
+func environmentProblems(env *manifest.EnvironmentSpec, host hostEnvironment) []string {
+	if env == nil {
+		return nil
+	}
+	var problems []string
+	if len(env.OS) > 0 && !containsString(env.OS, host.OS) {
+		problems = append(problems, fmt.Sprintf("requires os %s (this machine: %s)", strings.Join(env.OS, " or "), host.OS))
+	}
+	if len(env.Arch) > 0 && !containsString(env.Arch, host.Arch) {
+		problems = append(problems, fmt.Sprintf("requires arch %s (this machine: %s)", strings.Join(env.Arch, " or "), host.Arch))
+	}
+	if len(env.PythonImplementation) > 0 {
+		wanted := strings.Join(env.PythonImplementation, " or ")
+		switch impl := host.PythonImplementation(); {
+		case impl == "":
+			problems = append(problems, fmt.Sprintf("requires Python implementation %s, but no Python interpreter was found", wanted))
+		case !containsString(env.PythonImplementation, impl):
+			problems = append(problems, fmt.Sprintf("requires Python implementation %s (found %s)", wanted, impl))
+		}
+	}
+	if env.Node != "" {
+		switch version := host.NodeVersion(); {
+		case version == "":
+			problems = append(problems, fmt.Sprintf("requires Node.js %s, but node was not found on PATH", env.Node))
+		case !interpreter.SatisfiesConstraint(version, env.Node):
+			problems = append(problems, fmt.Sprintf("requires Node.js %s (found %s)", env.Node, version))
+		}
+	}
+	return problems
+}
+
+// checkEnvironment returns an error naming every environment constraint of
+// package name that host doesn't satisfy. With force it writes the
+// problems as a warning instead.
+func (c *Client) checkEnvironment(name string, m *manifest.Manifest, host hostEnvironment, force bool, action string) error {
+	problems := environmentProblems(m.Environment, host)
+	if len(problems) == 0 {
+		return nil
+	}
+	if force {
+		c.warnf("%s may not run on this machine (--force):\n", name)
+		for _, p := range problems {
+			c.printf("  - %s\n", p)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s can't run on this machine:\n  - %s\nUse --force to %s it anyway",
+		name, strings.Join(problems, "\n  - "), action)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
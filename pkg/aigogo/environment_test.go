@@ -0,0 +1,90 @@
+package aigogo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func testHost(python, node string) hostEnvironment {
+	return hostEnvironment{
+		OS:                   "linux",
+		Arch:                 "amd64",
+		PythonImplementation: func() string { return python },
+		NodeVersion:          func() string { return node },
+	}
+}
+
+func TestEnvironmentProblems(t *testing.T) {
+	tests := []struct {
+		name string
+		env  *manifest.EnvironmentSpec
+		host hostEnvironment
+		want []string // substrings, one per expected problem
+	}{
+		{"no constraints", nil, testHost("", ""), nil},
+		{"matching", &manifest.EnvironmentSpec{
+			OS:                   []string{"linux", "darwin"},
+			Arch:                 []string{"amd64"},
+			PythonImplementation: []string{"cpython"},
+			Node:                 ">=18,<23",
+		}, testHost("cpython", "20.10.0"), nil},
+		{"wrong os", &manifest.EnvironmentSpec{OS: []string{"darwin", "windows"}},
+			testHost("", ""), []string{"requires os darwin or windows (this machine: linux)"}},
+		{"wrong arch", &manifest.EnvironmentSpec{Arch: []string{"arm64"}},
+			testHost("", ""), []string{"requires arch arm64 (this machine: amd64)"}},
+		{"wrong python implementation", &manifest.EnvironmentSpec{PythonImplementation: []string{"cpython"}},
+			testHost("pypy", ""), []string{"found pypy"}},
+		{"no python", &manifest.EnvironmentSpec{PythonImplementation: []string{"cpython"}},
+			testHost("", ""), []string{"no Python interpreter was found"}},
+		{"old node", &manifest.EnvironmentSpec{Node: ">=18"},
+			testHost("", "16.20.0"), []string{"requires Node.js >=18 (found 16.20.0)"}},
+		{"no node", &manifest.EnvironmentSpec{Node: ">=18"},
+			testHost("", ""), []string{"node was not found"}},
+		{"several", &manifest.EnvironmentSpec{OS: []string{"windows"}, Arch: []string{"arm64"}},
+			testHost("", ""), []string{"requires os", "requires arch"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := environmentProblems(tt.env, tt.host)
+			if len(got) != len(tt.want) {
+				t.Fatalf("environmentProblems() = %q, want %d problem(s)", got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("problem %d = %q, want it to contain %q", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvironmentProblemsOnlyProbesConstrainedInterpreters(t *testing.T) {
+	host := hostEnvironment{
+		OS:                   "linux",
+		Arch:                 "amd64",
+		PythonImplementation: func() string { t.Error("probed Python without a python_implementation constraint"); return "" },
+		NodeVersion:          func() string { t.Error("probed Node.js without a node constraint"); return "" },
+	}
+	environmentProblems(&manifest.EnvironmentSpec{OS: []string{"linux"}}, host)
+}
+
+func TestCheckEnvironment(t *testing.T) {
+	m := &manifest.Manifest{Name: "native-pkg", Environment: &manifest.EnvironmentSpec{OS: []string{"windows"}}}
+
+	err := NewClient(t.TempDir()).checkEnvironment("native-pkg", m, testHost("", ""), false, "install")
+	if err == nil {
+		t.Fatal("checkEnvironment() = nil, want an error")
+	}
+	for _, want := range []string{"native-pkg can't run on this machine", "requires os windows", "--force to install"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+
+	if err := NewClient(t.TempDir()).checkEnvironment("native-pkg", m, testHost("", ""), true, "install"); err != nil {
+		t.Errorf("checkEnvironment() with force = %v, want nil", err)
+	}
+}
@@ -0,0 +1,134 @@
+package aigogo
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/store"
+)
+
+// filesWithAttribute returns the files under filesDir, relative to it,
+// whose files.attributes entries satisfy want
+func filesWithAttribute(m *manifest.Manifest, filesDir string, want func(manifest.FileAttributes) bool) ([]string, error) {
+	if len(m.Files.Attributes) == 0 {
+		return nil, nil
+	}
+	var files []string
+	err := filepath.WalkDir(filesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(filesDir, path)
+		if err != nil {
+			return err
+		}
+		if want(m.Files.AttributesOf(filepath.ToSlash(rel))) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// ApplyExecutableBits sets the executable bit on the stored files that the
+// package's manifest marks executable. Install calls it for every package,
+// so the bits are right even when the layer's tar headers lost them.
+func ApplyExecutableBits(cas *store.Store, hash string, m *manifest.Manifest) error {
+	pkg, err := cas.Get(hash)
+	if err != nil {
+		return err
+	}
+	files, err := filesWithAttribute(m, pkg.FilesDir, func(a manifest.FileAttributes) bool { return a.Executable })
+	if err != nil {
+		return fmt.Errorf("failed to list package files: %w", err)
+	}
+	return cas.MakeExecutable(hash, files)
+}
+
+// templateData is what a package's template files can refer to, e.g.
+// {{.Project.Name}} or {{env "USER"}}
+type templateData struct {
+	Project struct {
+		Name string // the project's aigogo.json name, or its directory name
+		Dir  string
+	}
+	Package struct {
+		Name    string
+		Version string
+	}
+}
+
+// renderedTemplate is the outcome of rendering one template file
+type renderedTemplate struct {
+	Path    string // written file, relative to the project
+	Skipped bool   // the file already existed and was left alone
+}
+
+// renderTemplates renders the package's template files into projectDir,
+// at their package path minus the .template suffix. Existing files are
+// never overwritten.
+func renderTemplates(m *manifest.Manifest, filesDir, projectDir, version string) ([]renderedTemplate, error) {
+	templates, err := filesWithAttribute(m, filesDir, func(a manifest.FileAttributes) bool { return a.Template })
+	if err != nil {
+		return nil, fmt.Errorf("failed to list package files: %w", err)
+	}
+	if len(templates) == 0 {
+		return nil, nil
+	}
+
+	var data templateData
+	data.Project.Name = filepath.Base(projectDir)
+	if project, err := manifest.Load(filepath.Join(projectDir, "aigogo.json")); err == nil && project.Name != "" {
+		data.Project.Name = project.Name
+	}
+	data.Project.Dir = projectDir
+	data.Package.Name = m.Name
+	data.Package.Version = version
+
+	var results []renderedTemplate
+	for _, file := range templates {
+		target := strings.TrimSuffix(file, manifest.TemplateSuffix)
+		targetPath := filepath.Join(projectDir, target)
+		if _, err := os.Lstat(targetPath); err == nil {
+			results = append(results, renderedTemplate{Path: target, Skipped: true})
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(filesDir, file))
+		if err != nil {
+			return results, fmt.Errorf("failed to read template %s: %w", file, err)
+		}
+		tmpl, err := template.New(file).
+			Option("missingkey=error").
+			Funcs(template.FuncMap{"env": os.Getenv}).
+			Parse(string(content))
+		if err != nil {
+			return results, fmt.Errorf("invalid template %s: %w", file, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return results, fmt.Errorf("failed to render template %s: %w", file, err)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+			return results, fmt.Errorf("failed to create directory for %s: %w", target, err)
+		}
+		mode := os.FileMode(0644)
+		if m.Files.AttributesOf(filepath.ToSlash(file)).Executable {
+			mode = 0755
+		}
+		if err := os.WriteFile(targetPath, buf.Bytes(), mode); err != nil {
+			return results, fmt.Errorf("failed to write %s: %w", target, err)
+		}
+		results = append(results, renderedTemplate{Path: target})
+	}
+	return results, nil
+}
@@ -0,0 +1,123 @@
+package aigogo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/store"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	filesDir := t.TempDir()
+	projectDir := t.TempDir()
+	writeFiles(t, filesDir, map[string]string{
+		"config/settings.yaml.template": "project: {{.Project.Name}}\npackage: {{.Package.Name}}@{{.Package.Version}}\nuser: {{env \"AIGOGO_TEST_USER\"}}\n",
+		"run.sh.template":               "#!/bin/sh\n",
+		"keep.txt.template":             "new\n",
+		"notes.template":                "not marked as a template\n",
+	})
+	writeFiles(t, projectDir, map[string]string{
+		"aigogo.json": `{"name": "my-app", "version": "0.1.0", "language": {"name": "python"}, "files": {"include": []}}`,
+		"keep.txt":    "mine\n",
+	})
+	t.Setenv("AIGOGO_TEST_USER", "alice")
+
+	m := &manifest.Manifest{Name: "utils", Files: manifest.FileSpec{Attributes: []manifest.FileAttributes{
+		{Path: "config/*.template", Template: true},
+		{Path: "run.sh.template", Template: true, Executable: true},
+		{Path: "keep.txt.template", Template: true},
+	}}}
+
+	results, err := renderTemplates(m, filesDir, projectDir, "1.2.0")
+	if err != nil {
+		t.Fatalf("renderTemplates failed: %v", err)
+	}
+	want := []renderedTemplate{
+		{Path: filepath.Join("config", "settings.yaml")},
+		{Path: "keep.txt", Skipped: true},
+		{Path: "run.sh"},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("renderTemplates() = %+v, want %+v", results, want)
+	}
+
+	got, err := os.ReadFile(filepath.Join(projectDir, "config", "settings.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "project: my-app\npackage: utils@1.2.0\nuser: alice\n" {
+		t.Errorf("rendered settings.yaml = %q", got)
+	}
+	if kept, _ := os.ReadFile(filepath.Join(projectDir, "keep.txt")); string(kept) != "mine\n" {
+		t.Errorf("existing keep.txt was overwritten with %q", kept)
+	}
+	if info, err := os.Stat(filepath.Join(projectDir, "run.sh")); err != nil || info.Mode().Perm()&0111 == 0 {
+		t.Errorf("run.sh should be executable, got %v, %v", info, err)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, "notes")); err == nil {
+		t.Error("notes.template is not marked as a template and should not be rendered")
+	}
+}
+
+func TestRenderTemplatesUnknownField(t *testing.T) {
+	filesDir := t.TempDir()
+	writeFiles(t, filesDir, map[string]string{"a.template": "{{.Project.Nmae}}"})
+	m := &manifest.Manifest{Name: "utils", Files: manifest.FileSpec{Attributes: []manifest.FileAttributes{
+		{Path: "a.template", Template: true},
+	}}}
+
+	_, err := renderTemplates(m, filesDir, t.TempDir(), "1.0.0")
+	if err == nil || !strings.Contains(err.Error(), "a.template") {
+		t.Errorf("expected a render error naming the template, got %v", err)
+	}
+}
+
+func TestApplyExecutableBits(t *testing.T) {
+	srcDir := t.TempDir()
+	writeFiles(t, srcDir, map[string]string{"bin/cli.py": "", "lib.py": ""})
+
+	cas, err := store.NewStoreAt(filepath.Join(t.TempDir(), "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := cas.Store(context.Background(), srcDir, []string{filepath.Join("bin", "cli.py"), "lib.py"}, []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &manifest.Manifest{Files: manifest.FileSpec{Attributes: []manifest.FileAttributes{
+		{Path: "bin/*", Executable: true},
+	}}}
+	if err := ApplyExecutableBits(cas, hash, m); err != nil {
+		t.Fatalf("ApplyExecutableBits failed: %v", err)
+	}
+
+	pkg, _ := cas.Get(hash)
+	for file, executable := range map[string]bool{filepath.Join("bin", "cli.py"): true, "lib.py": false} {
+		info, err := os.Stat(filepath.Join(pkg.FilesDir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode()&0111 != 0; got != executable {
+			t.Errorf("%s executable = %v, want %v", file, got, executable)
+		}
+	}
+}
@@ -0,0 +1,1047 @@
+package aigogo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aupeachmo/aigogo/pkg/depgen"
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/imports"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/store"
+)
+
+// InstallOptions are the options of Client.Install
+type InstallOptions struct {
+	Packages        []string // lock file entries to install; all when empty
+	IgnoreScripts   bool     // list postinstall scripts instead of running them
+	RenderTemplates bool     // render packages' template files into the project
+	Force           bool     // install packages whose environment doesn't match
+	Strict          bool     // fail on deprecated packages
+	TSConfig        bool     // write tsconfig.aigogo.json
+	Frozen          bool     // fail on an incomplete or stale aigogo.lock
+	Offline         bool     // never access the network
+	Production      bool     // skip dev and optional packages
+	Python          string   // interpreter or environment for the .pth file
+}
+
+// InstallResult is what Client.Install installed and configured
+type InstallResult struct {
+	LockPath   string
+	ProjectDir string
+	Installed  []string // the packages linked, sorted
+	Fetched    int      // how many were fetched into the store
+	Skipped    []string // the dev and optional packages Production left out
+	// Languages are those of the installed packages, sorted, with
+	// typescript counted as javascript, as they share their links
+	Languages []string
+
+	// Repaired lists, by package, the links broken since the last install,
+	// which installing repaired
+	Repaired map[string][]imports.LinkProblem
+	// PeerProblems lists, by package, the peer dependencies the project
+	// doesn't provide
+	PeerProblems map[string][]PeerProblem
+	// Conflicts are the shared dependencies the locked packages need
+	// incompatible versions of, or nil
+	Conflicts *depgen.ConflictReport
+
+	// Setup is the project's imports layout, for its paths and namespace
+	Setup *imports.SetupManager
+	// PythonEnv is the environment given the aigogo.pth file, or nil
+	PythonEnv *imports.PythonEnv
+
+	RegisterScript bool // .aigogo/register.js and friends were written
+	ImportMap      bool // the Deno import map was written
+	PathEnv        bool // .aigogo/path.env and .aigogo/envrc were written
+	GoMod          bool // go.mod was updated
+	CargoToml      bool // Cargo.toml was updated
+	TSConfig       bool // tsconfig.aigogo.json was written
+}
+
+// HasLanguage reports whether an installed package is for language
+func (r *InstallResult) HasLanguage(language string) bool {
+	return containsString(r.Languages, language)
+}
+
+// Install installs the packages in aigogo.lock: each is fetched into the
+// package store if it isn't there, checked, and linked into the project's
+// .aigogo/imports, and the languages' search paths are configured. With
+// Frozen, as in CI, it fails instead of installing from a lock file that
+// AddPackage would write differently now, and checks every package against
+// its integrity hash, cached or not. With Offline, packages not in the
+// store come only from the local cache, and registry deprecation notices
+// aren't checked. Naming Packages re-links just those, leaving the
+// others' links in place. With Production, dev and optional packages are
+// skipped and their links removed, so a deployed project has only its
+// runtime packages. aigogo.lock itself is never written.
+func (c *Client) Install(ctx context.Context, opts InstallOptions) (*InstallResult, error) {
+	// Find lock file
+	lockPath, lock, err := c.findLockFile()
+	if err != nil {
+		if opts.Frozen {
+			return nil, fmt.Errorf("failed to find aigogo.lock: %w\n--frozen installs only from a committed aigogo.lock; run 'aigg add <package>' and commit it", err)
+		}
+		return nil, fmt.Errorf("failed to find aigogo.lock: %w\nRun 'aigg add <package>' first to add packages", err)
+	}
+
+	projectDir := filepath.Dir(lockPath)
+	result := &InstallResult{LockPath: lockPath, ProjectDir: projectDir}
+	if opts.Frozen {
+		if err := lock.Validate(); err != nil {
+			return nil, errcode.Errorf(errcode.Validation, "%s is incomplete: %w\nRe-add the package with 'aigg add <package>' and commit aigogo.lock", lockPath, err)
+		}
+		c.log.Printf("Installing packages from %s (frozen)\n\n", lockPath)
+	} else {
+		c.log.Printf("Installing packages from %s\n\n", lockPath)
+	}
+
+	allPackages := lock
+	if len(opts.Packages) > 0 {
+		if lock, err = selectPackages(lock, opts.Packages); err != nil {
+			return nil, err
+		}
+	}
+	selected := lock
+	if opts.Production {
+		allPackages, _ = productionPackages(allPackages)
+		lock, result.Skipped = productionPackages(selected)
+		if len(result.Skipped) > 0 {
+			c.log.Printf("Skipping %d dev/optional package(s) (--production): %s\n\n", len(result.Skipped), strings.Join(result.Skipped, ", "))
+		}
+	}
+	if len(lock.Packages) == 0 && len(result.Skipped) == 0 {
+		return result, nil
+	}
+
+	// Initialize store
+	settings := c.settings(projectDir)
+	cas, err := OpenStore(settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize store: %w", err)
+	}
+	if opts.Offline {
+		if err := checkOffline(cas, lock); err != nil {
+			return nil, err
+		}
+	}
+
+	// Initialize setup manager
+	setupMgr, err := imports.NewSetupManager(projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize imports manager: %w", err)
+	}
+	setupMgr.SetNamespace(imports.NamespaceFor(settings.Namespace))
+	setupMgr.SetInstallMode(settings.InstallMode())
+	if err := SetupPythonLayout(setupMgr, settings, lock, opts.Python); err != nil {
+		return nil, err
+	}
+	result.Setup = setupMgr
+	ns := setupMgr.GetNamespace()
+
+	// Links broken since the last install, by a pruned store entry or a
+	// moved home directory, and edited copies are repaired by linking the
+	// packages again below; note them first to report what was repaired
+	result.Repaired = BrokenLinks(setupMgr, cas, lock)
+
+	// Clean existing imports, or only the named packages' links
+	if len(opts.Packages) == 0 {
+		if err := setupMgr.Clean(); err != nil {
+			return nil, fmt.Errorf("failed to clean existing imports: %w", err)
+		}
+	} else {
+		for name, pkg := range lock.Packages {
+			for _, lang := range pkg.LanguageNames() {
+				if err := setupMgr.RemovePackageLink(name, lang); err != nil {
+					return nil, fmt.Errorf("failed to remove %s link for %s: %w", lang, name, err)
+				}
+			}
+		}
+		// Skipped packages keep no link from an earlier install
+		for _, name := range result.Skipped {
+			pkg, _ := selected.Get(name)
+			for _, lang := range pkg.LanguageNames() {
+				if err := setupMgr.RemovePackageLink(name, lang); err != nil {
+					return nil, fmt.Errorf("failed to remove %s link for %s: %w", lang, name, err)
+				}
+			}
+		}
+	}
+
+	// Track languages for namespace setup
+	for _, pkg := range lock.Packages {
+		for _, lang := range pkg.LanguageNames() {
+			if lang == "typescript" {
+				lang = "javascript"
+			}
+			if !containsString(result.Languages, lang) {
+				result.Languages = append(result.Languages, lang)
+			}
+		}
+	}
+	sort.Strings(result.Languages)
+	hasPython := result.HasLanguage("python")
+	hasJavaScript := result.HasLanguage("javascript")
+
+	// Setup namespaces
+	if hasPython {
+		if err := setupMgr.SetupPythonNamespace(); err != nil {
+			return nil, fmt.Errorf("failed to setup Python namespace: %w", err)
+		}
+	}
+	if hasJavaScript {
+		if err := setupMgr.SetupJavaScriptNamespace(); err != nil {
+			return nil, fmt.Errorf("failed to setup JavaScript namespace: %w", err)
+		}
+	}
+
+	// Fetch the packages not in the store yet
+	if result.Fetched, err = c.fetchMissing(ctx, cas, lock, opts.Offline); err != nil {
+		return nil, err
+	}
+
+	// Install each package, in name order
+	names := make([]string, 0, len(lock.Packages))
+	for name := range lock.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	host := currentHost()
+	hostPkgs := NewHostPackages(projectDir)
+	result.PeerProblems = make(map[string][]PeerProblem)
+	var postinstall []lifecycleContext
+	for _, name := range names {
+		pkg := lock.Packages[name]
+		hash := pkg.GetIntegrityHash()
+		if opts.Frozen && pkg.IsLocal() {
+			return nil, errcode.Errorf(errcode.Validation, "%s is a local path package (%s)\n--frozen installs only packages pinned by an integrity hash; add it from a registry for CI", name, pkg.Path)
+		}
+
+		// Get stored package, or a local package's working directory
+		storedPkg, err := OpenLockedPackage(cas, projectDir, pkg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get package %s: %w", name, err)
+		}
+		if opts.Frozen {
+			if err := checkFrozen(cas, name, pkg, storedPkg); err != nil {
+				return nil, err
+			}
+		}
+
+		m, manifestErr := manifest.Load(storedPkg.Manifest)
+		if errors.Is(manifestErr, manifest.ErrUpgradeRequired) {
+			return nil, manifestErr
+		}
+		if manifestErr == nil {
+			if err := c.checkEnvironment(name, m, host, opts.Force, "install"); err != nil {
+				return nil, err
+			}
+		}
+		source := pkg.Source
+		if opts.Offline || pkg.IsLocal() {
+			source = "" // registry deprecation notices need the network
+		}
+		if err := c.checkDeprecation(name, pkg.Version, PackageDeprecation(ctx, source, m), opts.Strict); err != nil {
+			return nil, err
+		}
+
+		// Create symlinks, one per language for multi-language packages
+		storePath := filepath.Dir(storedPkg.FilesDir)
+		if pkg.IsLocal() {
+			if storePath, err = setupMgr.LinkLocalPackage(name, storedPkg.FilesDir); err != nil {
+				return nil, fmt.Errorf("failed to link %s: %w", name, err)
+			}
+		} else {
+			c.log.Verbosef("%s: sha256:%s at %s\n", name, strings.TrimPrefix(hash, "sha256:"), storePath)
+		}
+		var exports map[string]string
+		if manifestErr == nil {
+			exports = m.Exports
+		}
+		for _, lang := range pkg.LanguageNames() {
+			if err := setupMgr.CreatePackageLink(name, lang, storePath, exports); err != nil {
+				return nil, fmt.Errorf("failed to create %s link for %s: %w", lang, name, err)
+			}
+		}
+
+		if pkg.IsLocal() {
+			c.log.Printf("✓ Installed %s (linked to %s)\n", name, pkg.Path)
+		} else {
+			c.log.Printf("✓ Installed %s (%d files)\n", name, len(pkg.Files))
+		}
+		result.Installed = append(result.Installed, name)
+
+		if manifestErr == nil {
+			// A local package's files are its author's working copy
+			if !pkg.IsLocal() {
+				if err := ApplyExecutableBits(cas, hash, m); err != nil {
+					c.warnf("failed to set executable files of %s: %v\n", name, err)
+				}
+			}
+			if err := c.installTemplates(m, storedPkg.FilesDir, projectDir, pkg.Version, opts.RenderTemplates); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			if problems := PeerProblems(m, hostPkgs); len(problems) > 0 {
+				result.PeerProblems[name] = problems
+			}
+
+			// postinstall scripts run once every package is linked, so they
+			// can import each other
+			if script, ok := lifecycleScript(m, manifest.ScriptPostinstall, lifecycleContext{
+				Name:       m.Name,
+				Version:    pkg.Version,
+				PackageDir: storedPkg.FilesDir,
+				ProjectDir: projectDir,
+			}); ok {
+				postinstall = append(postinstall, script)
+			}
+		}
+
+		// Show import hint
+		for _, lang := range pkg.LanguageNames() {
+			switch lang {
+			case "python":
+				c.log.Printf("  import: from %s.%s import ...\n", ns.Python, lockfile.NormalizeName(name))
+				for _, export := range namedExports(exports, lang) {
+					c.log.Printf("  import: from %s.%s.%s import ...\n", ns.Python, lockfile.NormalizeName(name), manifest.PythonExportModule(export))
+				}
+			case "javascript", "typescript":
+				c.log.Printf("  import: import ... from '%s/%s'\n", ns.JavaScript, name)
+				for _, export := range namedExports(exports, lang) {
+					c.log.Printf("  import: import ... from '%s/%s/%s'\n", ns.JavaScript, name, export)
+				}
+			case "ruby":
+				c.log.Printf("  require: require 'aigogo/%s/<file>'\n", lockfile.NormalizeName(name))
+			case "java":
+				c.log.Printf("  source root: %s\n", filepath.Join(setupMgr.GetJavaSourceRootPath(), name))
+			case "go":
+				c.log.Printf("  import: import \"%s\"\n", imports.GoModulePath(name, storePath))
+			case "rust":
+				c.log.Printf("  use: use %s::...;\n", strings.ReplaceAll(imports.RustCrateName(name, storePath), "-", "_"))
+			}
+		}
+	}
+
+	// Packages can each be fine on their own yet need incompatible versions
+	// of a shared dependency, including ones not reinstalled now
+	if pkgs, _ := LockedDependencies(projectDir, allPackages, cas); len(pkgs) > 1 {
+		if report := depgen.FindConflicts(pkgs); len(report.Conflicts) > 0 {
+			result.Conflicts = report
+		}
+	}
+
+	// Update .gitignore
+	if err := setupMgr.UpdateGitignore(); err != nil {
+		c.warnf("failed to update .gitignore: %v\n", err)
+	}
+
+	// Auto-configure Python path via .pth file. Files written into other
+	// environments by earlier installs stay, and uninstall removes them all.
+	// PEP 582 tools put __pypackages__/ on the path themselves.
+	if (!hasPython || setupMgr.UsesPyPackages()) && len(opts.Packages) == 0 {
+		if err := imports.RemovePthFile(projectDir); err != nil {
+			c.warnf("failed to remove Python .pth files: %v\n", err)
+		}
+	}
+	if hasPython && !setupMgr.UsesPyPackages() {
+		env, err := imports.FindPythonEnv(projectDir, opts.Python)
+		if err == nil {
+			err = imports.InstallPthFile(setupMgr.GetImportsDir(), env)
+		}
+		if err != nil {
+			c.printf("⚠ Could not auto-configure Python path: %v\n", err)
+		} else {
+			result.PythonEnv = env
+		}
+	}
+
+	// Generate Node.js register script
+	// Note: Clean() already removed any stale register script at the start of install.
+	if hasJavaScript {
+		if err := imports.InstallRegisterScript(projectDir, setupMgr.GetNamespace().JavaScript); err != nil {
+			c.warnf("failed to create register script: %v\n", err)
+		} else {
+			result.RegisterScript = true
+		}
+	}
+
+	// The path variables that couldn't be configured otherwise are written
+	// to .aigogo/path.env and .aigogo/envrc, to enable with one command.
+	// Installing named packages keeps the variables of the languages not
+	// installed now.
+	pathVars := make(map[string]string)
+	if len(opts.Packages) > 0 {
+		existing, err := setupMgr.ReadPathEnv()
+		if err != nil {
+			c.warnf("%v\n", err)
+		} else {
+			pathVars = existing
+		}
+		if hasPython {
+			delete(pathVars, "PYTHONPATH")
+		}
+		if hasJavaScript {
+			delete(pathVars, "NODE_PATH")
+		}
+	}
+	if hasPython && !setupMgr.UsesPyPackages() && result.PythonEnv == nil {
+		pathVars["PYTHONPATH"] = setupMgr.GetImportsDir()
+	}
+	if hasJavaScript && !result.RegisterScript {
+		pathVars["NODE_PATH"] = setupMgr.GetImportsDir()
+	}
+	if len(pathVars) > 0 {
+		if err := setupMgr.WritePathEnv(pathVars); err != nil {
+			c.warnf("failed to write %s: %v\n", imports.PathEnvFileName, err)
+		} else {
+			result.PathEnv = true
+		}
+	}
+
+	// Deno doesn't use node_modules resolution; it reads an import map
+	if hasJavaScript {
+		if err := setupMgr.WriteImportMap(); err != nil {
+			c.warnf("failed to write Deno import map: %v\n", err)
+		} else {
+			result.ImportMap = true
+		}
+	}
+
+	// The go.mod block is kept in step with the installed Go modules, and
+	// removed once there are none
+	if _, err := os.Stat(filepath.Join(projectDir, "go.mod")); err == nil {
+		if _, err := setupMgr.UpdateGoMod(); err != nil {
+			c.warnf("failed to update go.mod: %v\n", err)
+		} else {
+			result.GoMod = true
+		}
+	}
+
+	// Likewise the Cargo.toml section and the installed Rust crates
+	if _, err := os.Stat(filepath.Join(projectDir, "Cargo.toml")); err == nil {
+		if _, err := setupMgr.UpdateCargoToml(); err != nil {
+			c.warnf("failed to update Cargo.toml: %v\n", err)
+		} else {
+			result.CargoToml = true
+		}
+	}
+
+	// Once written, tsconfig.aigogo.json is kept in step with the installed
+	// packages
+	if opts.TSConfig || setupMgr.HasTSConfig() {
+		if err := setupMgr.WriteTSConfig(); err != nil {
+			c.warnf("failed to write %s: %v\n", imports.TSConfigFileName, err)
+		} else {
+			result.TSConfig = true
+		}
+	}
+
+	if err := c.runPostinstallScripts(ctx, postinstall, opts.IgnoreScripts); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SetupPythonLayout switches setupMgr to the PEP 582 layout when the
+// project settings ask for it and there are Python packages to install. The
+// version directory is that of the Python environment install would write a
+// .pth file into (python being InstallOptions.Python), or else the
+// project's only one.
+func SetupPythonLayout(setupMgr *imports.SetupManager, settings *manifest.Settings, lock *lockfile.LockFile, python string) error {
+	if settings.PythonLayout() != manifest.PythonLayoutPyPackages {
+		return nil
+	}
+	hasPython := false
+	for _, pkg := range lock.Packages {
+		for _, lang := range pkg.LanguageNames() {
+			hasPython = hasPython || lang == "python"
+		}
+	}
+	if !hasPython {
+		return nil
+	}
+
+	projectDir := setupMgr.GetProjectDir()
+	env, err := imports.FindPythonEnv(projectDir, python)
+	var version string
+	if err == nil {
+		version, err = imports.PythonVersion(env)
+	}
+	if err != nil {
+		existing, _ := filepath.Glob(filepath.Join(projectDir, imports.PyPackagesDir, "*", "lib"))
+		if len(existing) != 1 {
+			return fmt.Errorf("install.python_layout %s needs the Python version for %s/<X.Y>/lib: %w", manifest.PythonLayoutPyPackages, imports.PyPackagesDir, err)
+		}
+		version = filepath.Base(filepath.Dir(existing[0]))
+	}
+	setupMgr.SetPyPackages(version)
+	return nil
+}
+
+// BrokenLinks checks the installed links of the locked packages and
+// returns their problems by package name
+func BrokenLinks(setupMgr *imports.SetupManager, cas *store.Store, lock *lockfile.LockFile) map[string][]imports.LinkProblem {
+	broken := make(map[string][]imports.LinkProblem)
+	for name, pkg := range lock.Packages {
+		storePath := filepath.Join(setupMgr.GetLocalPackagesPath(), name)
+		if !pkg.IsLocal() {
+			path, err := cas.GetPath(pkg.GetIntegrityHash())
+			if err != nil {
+				continue // an invalid integrity hash has no store entry to link
+			}
+			storePath = path
+		}
+		for _, lang := range pkg.LanguageNames() {
+			problems, err := setupMgr.CheckPackageLink(name, lang, storePath)
+			if err != nil {
+				continue // unsupported languages have no link
+			}
+			if len(problems) > 0 {
+				broken[name] = append(broken[name], problems...)
+			}
+		}
+	}
+	return broken
+}
+
+// CheckFrozen checks aigogo.lock as Install does with Frozen, without
+// installing anything: the lock file must be complete, and each package in
+// the package store must have the content of its integrity hash and the
+// entry aigg add would write for it. Nothing is fetched and nothing in the
+// project is written. It returns the packages that aren't in the store,
+// whose content it can't check.
+func (c *Client) CheckFrozen() (unchecked []string, err error) {
+	lockPath, lock, err := c.findLockFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find aigogo.lock: %w", err)
+	}
+	if err := lock.Validate(); err != nil {
+		return nil, errcode.Errorf(errcode.Validation, "%s is incomplete: %w\nRe-add the package with 'aigg add <package>' and commit aigogo.lock", lockPath, err)
+	}
+	cas, err := OpenStore(c.settings(filepath.Dir(lockPath)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize store: %w", err)
+	}
+
+	names := make([]string, 0, len(lock.Packages))
+	for name := range lock.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pkg := lock.Packages[name]
+		if pkg.IsLocal() {
+			return nil, errcode.Errorf(errcode.Validation, "%s is a local path package (%s)\n--frozen installs only packages pinned by an integrity hash; add it from a registry for CI", name, pkg.Path)
+		}
+		hash := pkg.GetIntegrityHash()
+		if !cas.Has(hash) {
+			unchecked = append(unchecked, name)
+			continue
+		}
+		storedPkg, err := cas.Get(hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get package %s: %w", name, err)
+		}
+		if err := checkFrozen(cas, name, pkg, storedPkg); err != nil {
+			return nil, err
+		}
+	}
+	return unchecked, nil
+}
+
+// checkFrozen checks that a stored package still has the content its
+// integrity hash was computed from, and that its aigogo.lock entry is the
+// one aigg add would write for it
+func checkFrozen(cas *store.Store, name string, pkg lockfile.LockedPackage, storedPkg *store.StoredPackage) error {
+	hash := pkg.GetIntegrityHash()
+	if err := cas.Verify(hash); err != nil {
+		return errcode.Errorf(errcode.Integrity, "integrity check failed for %s: %w\nThe store copy was modified; run 'aigg clean --store' and install again", name, err)
+	}
+
+	// Read the manifest as aigg add does
+	data, err := os.ReadFile(storedPkg.Manifest)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest of %s: %w", name, err)
+	}
+	var m *manifest.Manifest
+	var parsed manifest.Manifest
+	if json.Unmarshal(data, &parsed) == nil {
+		m = &parsed
+	}
+	files, err := cas.ListFiles(hash)
+	if err != nil {
+		return fmt.Errorf("failed to list files of %s: %w", name, err)
+	}
+
+	wantName, want := lockEntry(pkg.Source, m, hash, files)
+	var problems []string
+	if name != wantName {
+		problems = append(problems, fmt.Sprintf("locked as %s, but the package is named %s", name, wantName))
+	}
+	if pkg.Version != want.Version {
+		problems = append(problems, fmt.Sprintf("version is %s, but the package is %s", pkg.Version, want.Version))
+	}
+	if pkg.Language != want.Language {
+		problems = append(problems, fmt.Sprintf("language is %s, but the package is %s", pkg.Language, want.Language))
+	}
+	if !sameFiles(pkg.Files, want.Files) {
+		problems = append(problems, "files don't match the package's")
+	}
+	var languages []string
+	for lang := range pkg.Languages {
+		languages = append(languages, lang)
+	}
+	for lang := range want.Languages {
+		if _, ok := pkg.Languages[lang]; !ok {
+			languages = append(languages, lang)
+		}
+	}
+	sort.Strings(languages)
+	for _, lang := range languages {
+		if !sameFiles(pkg.Languages[lang], want.Languages[lang]) {
+			problems = append(problems, fmt.Sprintf("%s files don't match the package's", lang))
+		}
+	}
+	for _, extra := range pkg.Extras {
+		if m == nil || m.CheckExtras([]string{extra}) != nil {
+			problems = append(problems, fmt.Sprintf("extra %q doesn't exist", extra))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errcode.Errorf(errcode.DiffFound, "aigogo.lock is out of date for %s:\n  - %s\nRun 'aigg add %s' and commit aigogo.lock",
+			name, strings.Join(problems, "\n  - "), pkg.Source)
+	}
+	return nil
+}
+
+// sameFiles reports whether two lists hold the same file paths, in any
+// order
+func sameFiles(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := make([]string, len(a))
+	sortedB := make([]string, len(b))
+	for i := range a {
+		sortedA[i] = filepath.ToSlash(a[i])
+		sortedB[i] = filepath.ToSlash(b[i])
+	}
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// installTemplates renders a package's template files into the project,
+// or with render false just says that it has some
+func (c *Client) installTemplates(m *manifest.Manifest, filesDir, projectDir, version string, render bool) error {
+	if !render {
+		templates, err := filesWithAttribute(m, filesDir, func(a manifest.FileAttributes) bool { return a.Template })
+		if err == nil && len(templates) > 0 {
+			c.log.Printf("  templates: %d (render them with 'aigg install --render-templates')\n", len(templates))
+		}
+		return nil
+	}
+
+	results, err := renderTemplates(m, filesDir, projectDir, version)
+	for _, r := range results {
+		if r.Skipped {
+			c.log.Printf("  kept existing %s (template not rendered)\n", r.Path)
+		} else {
+			c.log.Printf("  rendered %s\n", r.Path)
+		}
+	}
+	return err
+}
+
+// runPostinstallScripts runs installed packages' postinstall scripts in
+// name order, from the project directory since the store is read-only. With
+// ignoreScripts they are listed instead.
+func (c *Client) runPostinstallScripts(ctx context.Context, scripts []lifecycleContext, ignoreScripts bool) error {
+	if len(scripts) == 0 {
+		return nil
+	}
+	sort.Slice(scripts, func(i, j int) bool { return scripts[i].Name < scripts[j].Name })
+
+	c.log.Printf("\n")
+	if ignoreScripts {
+		for _, script := range scripts {
+			c.log.Printf("Skipped postinstall script for %s (--ignore-scripts)\n", script.Name)
+		}
+		return nil
+	}
+	for _, script := range scripts {
+		if err := c.runLifecycleScript(ctx, script, script.ProjectDir); err != nil {
+			return fmt.Errorf("%s: %w\nUse --ignore-scripts to skip", script.Name, err)
+		}
+	}
+	return nil
+}
+
+// productionPackages returns a copy of lock with only the runtime packages,
+// and the sorted names of the dev and optional ones it leaves out
+func productionPackages(lock *lockfile.LockFile) (*lockfile.LockFile, []string) {
+	runtime := &lockfile.LockFile{Version: lock.Version, Packages: make(map[string]lockfile.LockedPackage)}
+	var skipped []string
+	for name, pkg := range lock.Packages {
+		if pkg.IsRuntime() {
+			runtime.Add(name, pkg)
+		} else {
+			skipped = append(skipped, name)
+		}
+	}
+	sort.Strings(skipped)
+	return runtime, skipped
+}
+
+// selectPackages returns a copy of lock with only the named packages. Names
+// match lock file entries as written or normalized, so my-utils finds
+// my_utils.
+func selectPackages(lock *lockfile.LockFile, names []string) (*lockfile.LockFile, error) {
+	selected := &lockfile.LockFile{Version: lock.Version, Packages: make(map[string]lockfile.LockedPackage)}
+	var unknown []string
+	for _, name := range names {
+		if pkg, ok := lock.Get(name); ok {
+			selected.Add(name, pkg)
+			continue
+		}
+		found := false
+		for locked, pkg := range lock.Packages {
+			if lockfile.NormalizeName(locked) == lockfile.NormalizeName(name) {
+				selected.Add(locked, pkg)
+				found = true
+			}
+		}
+		if !found {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		var locked []string
+		for name := range lock.Packages {
+			locked = append(locked, name)
+		}
+		sort.Strings(locked)
+		available := "none"
+		if len(locked) > 0 {
+			available = strings.Join(locked, ", ")
+		}
+		return nil, fmt.Errorf("not in aigogo.lock: %s\nLocked packages: %s\nRun 'aigg add <package>' to add a package", strings.Join(unknown, ", "), available)
+	}
+	return selected, nil
+}
+
+// OpenLockedPackage returns where a locked package's files and manifest
+// are: its store entry, or a local path package's working directory,
+// resolved against projectDir, the directory of aigogo.lock
+func OpenLockedPackage(cas *store.Store, projectDir string, pkg lockfile.LockedPackage) (*store.StoredPackage, error) {
+	if !pkg.IsLocal() {
+		return cas.Get(pkg.GetIntegrityHash())
+	}
+	dir := pkg.LocalDir(projectDir)
+	manifestPath := filepath.Join(dir, "aigogo.json")
+	if _, err := os.Stat(manifestPath); err != nil {
+		return nil, fmt.Errorf("local package directory %s has no aigogo.json: %w", pkg.Path, err)
+	}
+	return &store.StoredPackage{FilesDir: dir, Manifest: manifestPath}, nil
+}
+
+// checkOffline checks that every locked package is in the store or the
+// local cache, so that an offline install needs no network. The error
+// lists the packages that aren't, with the commands to prefetch them.
+func checkOffline(cas *store.Store, lock *lockfile.LockFile) error {
+	names := make([]string, 0, len(lock.Packages))
+	for name := range lock.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var missing, prefetch []string
+	for _, name := range names {
+		pkg := lock.Packages[name]
+		if pkg.IsLocal() || cas.Has(pkg.GetIntegrityHash()) || docker.ImageExistsInCache(pkg.Source) {
+			continue
+		}
+		missing = append(missing, fmt.Sprintf("  %s (%s)", name, pkg.Source))
+		if docker.IsLocalReference(pkg.Source) {
+			prefetch = append(prefetch, fmt.Sprintf("  aigg build %s   # a local build", pkg.Source))
+		} else {
+			prefetch = append(prefetch, fmt.Sprintf("  aigg pull %s", pkg.Source))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("--offline: %d package(s) are in neither the store nor the local cache:\n%s\n"+
+		"Prefetch them where the registry is reachable (aigg pull --all fetches every locked package), then copy the package store and cache here (aigg clean shows where they are):\n%s",
+		len(missing), strings.Join(missing, "\n"), strings.Join(prefetch, "\n"))
+}
+
+// fetchMissing fetches the locked packages that aren't in the store,
+// several at a time (see SetConcurrency), and returns how many it fetched.
+// A line is logged as each fetch finishes. Every failure is reported, in name order,
+// not just the first. With offline, packages are stored from the local
+// cache instead of pulled.
+func (c *Client) fetchMissing(ctx context.Context, cas *store.Store, lock *lockfile.LockFile, offline bool) (int, error) {
+	names := make([]string, 0, len(lock.Packages))
+	for name := range lock.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Packages with the same content are fetched once
+	var missing []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		pkg := lock.Packages[name]
+		if pkg.IsLocal() {
+			continue
+		}
+		hash := pkg.GetIntegrityHash()
+		if !seen[hash] && !cas.Has(hash) {
+			missing = append(missing, name)
+		}
+		seen[hash] = true
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+	verb := "Fetching"
+	if offline {
+		verb = "Storing"
+	}
+
+	workers := min(c.concurrency, len(missing))
+	c.log.Printf("%s %d package(s)...\n", verb, len(missing))
+
+	errs := make([]error, len(missing))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var progressMu sync.Mutex
+	done := 0
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				name := missing[i]
+				pkg := lock.Packages[name]
+				err := c.fetch(ctx, cas, pkg, offline)
+				if err == nil && !cas.Has(pkg.GetIntegrityHash()) {
+					err = errcode.Errorf(errcode.Integrity, "integrity check failed: hash mismatch")
+				}
+				errs[i] = err
+
+				progressMu.Lock()
+				done++
+				switch {
+				case err != nil:
+					c.printf("✗ [%d/%d] %s from %s\n", done, len(missing), name, pkg.Source)
+				case offline:
+					c.log.Printf("✓ [%d/%d] Stored %s from the local cache\n", done, len(missing), name)
+				default:
+					c.log.Printf("✓ [%d/%d] Fetched %s from %s\n", done, len(missing), name, pkg.Source)
+				}
+				progressMu.Unlock()
+			}
+		}()
+	}
+
+	for i := range missing {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	c.log.Printf("\n")
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var failures []error
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, fmt.Errorf("failed to fetch %s: %w", missing[i], err))
+		}
+	}
+	if len(failures) > 0 {
+		return 0, errors.Join(failures...)
+	}
+	return len(missing), nil
+}
+
+// fetchAndStore pulls a package from the registry and stores it in the CAS.
+// With offline it is stored from the local cache without pulling.
+func (c *Client) fetchAndStore(ctx context.Context, cas *store.Store, pkg lockfile.LockedPackage, offline bool) error {
+	if !offline {
+		// Pull the package using existing Puller
+		puller := docker.NewPuller()
+		if err := puller.Pull(ctx, pkg.Source); err != nil {
+			return fmt.Errorf("failed to pull: %w", err)
+		}
+	}
+
+	// Extract to temp directory
+	tmpDir, err := os.MkdirTemp("", "aigogo-install-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	extractor := docker.NewExtractor()
+	extractedFiles, err := extractor.Extract(pkg.Source, tmpDir, true)
+	if err != nil {
+		return fmt.Errorf("failed to extract: %w", err)
+	}
+
+	// Convert to relative paths, slash-separated as aigogo.lock lists them
+	var relFiles []string
+	for _, f := range extractedFiles {
+		relPath, err := filepath.Rel(tmpDir, f)
+		if err != nil {
+			return err
+		}
+		relFiles = append(relFiles, filepath.ToSlash(relPath))
+	}
+
+	// Read manifest if present
+	manifestPath := filepath.Join(tmpDir, "aigogo.json")
+	var manifestData []byte
+	if _, err := os.Stat(manifestPath); err == nil {
+		manifestData, _ = os.ReadFile(manifestPath)
+	} else {
+		// Create minimal manifest
+		manifest := map[string]interface{}{
+			"name":     lockfile.GetPackageName(pkg.Source),
+			"version":  pkg.Version,
+			"language": map[string]string{"name": pkg.Language},
+		}
+		manifestData, _ = json.MarshalIndent(manifest, "", "  ")
+	}
+
+	// Store in CAS
+	hash, err := cas.Store(ctx, tmpDir, relFiles, manifestData)
+	if err != nil {
+		return fmt.Errorf("failed to store: %w", err)
+	}
+
+	// Verify integrity
+	expectedHash := pkg.GetIntegrityHash()
+	if hash != expectedHash {
+		// Clean up
+		_ = cas.Delete(hash)
+		return errcode.Errorf(errcode.Integrity, "integrity mismatch: expected %s, got %s", expectedHash, hash)
+	}
+
+	// Make read-only
+	if err := cas.MakeReadOnly(hash); err != nil {
+		c.warnf("failed to make files read-only: %v\n", err)
+	}
+
+	return nil
+}
+
+// namedExports returns the sorted names of the exports a language imports,
+// leaving out the package itself
+func namedExports(exports map[string]string, language string) []string {
+	var names []string
+	for name := range manifest.ExportsFor(exports, language) {
+		if name != manifest.ExportRoot {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LockedDependencies reads the runtime dependencies of each locked package,
+// with those of its selected extras, from its manifest in the store, plus
+// those of aigogo.json in projectDir if there is one. Packages that are not
+// in the store yet are skipped and described in the second return value.
+func LockedDependencies(projectDir string, lock *lockfile.LockFile, cas *store.Store) ([]depgen.PackageDependencies, []string) {
+	var pkgs []depgen.PackageDependencies
+	var skipped []string
+
+	if m, err := manifest.Load(filepath.Join(projectDir, "aigogo.json")); err == nil {
+		pkgs = append(pkgs, LanguageDependencies("aigogo.json", m)...)
+	}
+
+	names := make([]string, 0, len(lock.Packages))
+	for name := range lock.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		pkg := lock.Packages[name]
+		m, reason := LockedManifest(cas, projectDir, pkg)
+		if m == nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %s", name, reason))
+			continue
+		}
+		pkgs = append(pkgs, LanguageDependencies(name, m)...)
+	}
+
+	return pkgs, skipped
+}
+
+// LockedManifest reads a locked package's manifest from the store, or
+// for a local path package its directory, with its selected extras applied.
+// It returns nil and the reason when the manifest can't be read.
+func LockedManifest(cas *store.Store, projectDir string, pkg lockfile.LockedPackage) (*manifest.Manifest, string) {
+	stored, err := OpenLockedPackage(cas, projectDir, pkg)
+	if err != nil {
+		if pkg.IsLocal() {
+			return nil, err.Error()
+		}
+		return nil, "not installed (run 'aigg install')"
+	}
+	if _, err := os.Stat(stored.Manifest); err != nil {
+		return nil, "no manifest in store"
+	}
+	m, err := manifest.Load(stored.Manifest)
+	if err != nil {
+		return nil, err.Error()
+	}
+	if len(m.Languages) == 0 {
+		// The lock file's language wins for single-language packages
+		m.Language.Name = pkg.Language
+	}
+	return m.WithExtras(pkg.Extras), ""
+}
+
+// LanguageDependencies returns a package's runtime dependencies, split by
+// language for multi-language packages
+func LanguageDependencies(name string, m *manifest.Manifest) []depgen.PackageDependencies {
+	var pkgs []depgen.PackageDependencies
+	for _, lang := range m.AllLanguages() {
+		view := m.ForLanguage(lang.Name)
+		if view.Dependencies == nil || len(view.Dependencies.Runtime) == 0 {
+			continue
+		}
+		pkgs = append(pkgs, depgen.PackageDependencies{
+			Package:      name,
+			Language:     lang.Name,
+			Dependencies: view.Dependencies.Runtime,
+		})
+	}
+	return pkgs
+}
@@ -0,0 +1,416 @@
+package aigogo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/imports"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/store"
+)
+
+func TestCheckFrozen(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcDir := filepath.Join(tmpDir, "src")
+	if err := os.MkdirAll(srcDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"utils.py": "x = 1", "helpers.py": "y = 2"} {
+		if err := os.WriteFile(filepath.Join(srcDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifestData := []byte(`{"name": "my-utils", "version": "1.2.0", "language": {"name": "python", "version": ">=3.8"},
+		"dependencies": {"extras": {"viz": [{"package": "matplotlib", "version": ">=3.0"}]}}}`)
+
+	cas, err := store.NewStoreAt(filepath.Join(tmpDir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := cas.Store(context.Background(), srcDir, []string{"utils.py", "helpers.py"}, manifestData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storedPkg, err := cas.Get(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := "docker.io/org/my-utils:1.2.0"
+	name, locked := lockEntry(source, nil, hash, nil)
+	locked.Version, locked.Files = "1.2.0", []string{"helpers.py", "utils.py"}
+
+	tests := []struct {
+		name    string
+		lock    string
+		edit    func(p *lockfile.LockedPackage)
+		wantErr string
+	}{
+		{name: "up to date", lock: name},
+		{name: "selected extra", lock: name, edit: func(p *lockfile.LockedPackage) { p.Extras = []string{"viz"} }},
+		{name: "renamed", lock: "my-utils", wantErr: "locked as my-utils, but the package is named my_utils"},
+		{name: "stale version", lock: name, edit: func(p *lockfile.LockedPackage) { p.Version = "1.1.0" }, wantErr: "version is 1.1.0"},
+		{name: "missing file", lock: name, edit: func(p *lockfile.LockedPackage) { p.Files = []string{"utils.py"} }, wantErr: "files don't match"},
+		{name: "unknown extra", lock: name, edit: func(p *lockfile.LockedPackage) { p.Extras = []string{"cli"} }, wantErr: `extra "cli" doesn't exist`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg := locked
+			if tt.edit != nil {
+				tt.edit(&pkg)
+			}
+			err := checkFrozen(cas, tt.lock, pkg, storedPkg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkFrozen() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkFrozen() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// A store copy changed after it was stored fails the integrity check
+	if err := os.WriteFile(filepath.Join(storedPkg.FilesDir, "utils.py"), []byte("x = 2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkFrozen(cas, name, locked, storedPkg); err == nil || !strings.Contains(err.Error(), "integrity check failed") {
+		t.Errorf("checkFrozen() of a modified store copy = %v, want an integrity error", err)
+	}
+}
+
+func TestFetchMissing(t *testing.T) {
+	tmpDir := t.TempDir()
+	cas, err := store.NewStoreAt(filepath.Join(tmpDir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each package's content is stored elsewhere first to learn its hash
+	scratch, err := store.NewStoreAt(filepath.Join(tmpDir, "scratch"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcDir := filepath.Join(tmpDir, "src")
+	if err := os.MkdirAll(srcDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	lock := lockfile.New()
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("pkg%d", i)
+		if err := os.WriteFile(filepath.Join(srcDir, "mod.py"), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		hash, err := scratch.Store(context.Background(), srcDir, []string{"mod.py"}, []byte(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lock.Add(name, lockfile.LockedPackage{Integrity: "sha256:" + hash, Source: "registry.example/" + name + ":1.0.0"})
+	}
+	// The same content under a second name is fetched once
+	lock.Add("pkg0-alias", lock.Packages["pkg0"])
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	calls := make(map[string]int)
+	c := NewClient(tmpDir)
+	c.fetch = func(_ context.Context, cas *store.Store, pkg lockfile.LockedPackage, offline bool) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		calls[pkg.Source]++
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		if strings.Contains(pkg.Source, "pkg3") || strings.Contains(pkg.Source, "pkg7") {
+			return errors.New("registry unavailable")
+		}
+		if strings.Contains(pkg.Source, "pkg5") {
+			return nil // stores nothing, as if the content didn't match
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(pkg.Source, "registry.example/"), ":1.0.0")
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "mod.py"), []byte(name), 0644); err != nil {
+			return err
+		}
+		_, err := cas.Store(context.Background(), dir, []string{"mod.py"}, []byte(name))
+		return err
+	}
+
+	fetched, err := c.fetchMissing(context.Background(), cas, lock, false)
+	if err == nil {
+		t.Fatal("fetchMissing should fail when fetches fail")
+	}
+	for _, want := range []string{"failed to fetch pkg3: registry unavailable", "failed to fetch pkg5: integrity check failed", "failed to fetch pkg7"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't report %q", err, want)
+		}
+	}
+	if fetched != 0 {
+		t.Errorf("fetched = %d on failure, want 0", fetched)
+	}
+	if maxRunning > DefaultConcurrency {
+		t.Errorf("%d fetches ran at once, want at most %d", maxRunning, DefaultConcurrency)
+	}
+	if maxRunning < 2 {
+		t.Errorf("fetches ran one at a time")
+	}
+	if calls["registry.example/pkg0:1.0.0"] != 1 {
+		t.Errorf("pkg0 fetched %d times, want once", calls["registry.example/pkg0:1.0.0"])
+	}
+
+	// A second run fetches only what is still missing
+	calls = make(map[string]int)
+	c.fetch = func(_ context.Context, cas *store.Store, pkg lockfile.LockedPackage, offline bool) error {
+		mu.Lock()
+		calls[pkg.Source]++
+		mu.Unlock()
+		return nil
+	}
+	if _, err := c.fetchMissing(context.Background(), cas, lock, false); err == nil {
+		t.Fatal("fetchMissing should still fail for packages that store nothing")
+	}
+	if len(calls) != 3 {
+		t.Errorf("second run fetched %v, want only pkg3, pkg5 and pkg7", calls)
+	}
+
+	// Once canceled, nothing more is fetched
+	calls = make(map[string]int)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.fetchMissing(ctx, cas, lock, false); !errors.Is(err, context.Canceled) {
+		t.Errorf("fetchMissing(canceled) error = %v, want context.Canceled", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("fetchMissing(canceled) fetched %v", calls)
+	}
+}
+
+func TestCheckOffline(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+	cas, err := store.NewStoreAt(filepath.Join(home, ".aigogo", "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srcDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(srcDir, "mod.py"), []byte("x = 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	storedHash, err := cas.Store(context.Background(), srcDir, []string{"mod.py"}, []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A pulled image in the local cache can be stored without the network
+	cachedRef := "ghcr.io/org/cached:1.0.0"
+	if err := os.MkdirAll(filepath.Join(home, ".aigogo", "cache", "images", docker.SanitizeImageRef(cachedRef)), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	lock := lockfile.New()
+	lock.Add("stored", lockfile.LockedPackage{Integrity: "sha256:" + storedHash, Source: "ghcr.io/org/stored:1.0.0"})
+	lock.Add("cached", lockfile.LockedPackage{Integrity: "sha256:" + strings.Repeat("1", 64), Source: cachedRef})
+	// Local path packages need neither
+	lock.Add("linked", lockfile.LockedPackage{Source: lockfile.SourcePath, Path: "../linked"})
+	if err := checkOffline(cas, lock); err != nil {
+		t.Fatalf("checkOffline() = %v, want nil", err)
+	}
+
+	lock.Add("remote", lockfile.LockedPackage{Integrity: "sha256:" + strings.Repeat("2", 64), Source: "ghcr.io/org/remote:2.0.0"})
+	lock.Add("local", lockfile.LockedPackage{Integrity: "sha256:" + strings.Repeat("3", 64), Source: "local:0.1.0"})
+	err = checkOffline(cas, lock)
+	if err == nil {
+		t.Fatal("checkOffline() should fail for packages in neither the store nor the cache")
+	}
+	for _, want := range []string{"2 package(s)", "remote (ghcr.io/org/remote:2.0.0)", "aigg pull ghcr.io/org/remote:2.0.0", "aigg build local:0.1.0", "aigg pull --all"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't contain %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "cached") || strings.Contains(err.Error(), "stored") {
+		t.Errorf("error %q lists available packages", err)
+	}
+}
+
+func TestProductionPackages(t *testing.T) {
+	lock := lockfile.New()
+	lock.Add("app-utils", lockfile.LockedPackage{Version: "1.0.0"})
+	lock.Add("test-fixtures", lockfile.LockedPackage{Version: "1.0.0", Group: lockfile.GroupDev})
+	lock.Add("plots", lockfile.LockedPackage{Version: "1.0.0", Group: lockfile.GroupOptional})
+
+	runtime, skipped := productionPackages(lock)
+	if len(runtime.Packages) != 1 || !runtime.Has("app-utils") {
+		t.Errorf("productionPackages() kept %v, want only app-utils", runtime.Packages)
+	}
+	if want := []string{"plots", "test-fixtures"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("productionPackages() skipped %v, want %v", skipped, want)
+	}
+	if len(lock.Packages) != 3 {
+		t.Errorf("productionPackages changed the lock file: %v", lock.Packages)
+	}
+}
+
+func TestSelectPackages(t *testing.T) {
+	lock := lockfile.New()
+	lock.Add("my_utils", lockfile.LockedPackage{Version: "1.0.0", Language: "python"})
+	lock.Add("ui-kit", lockfile.LockedPackage{Version: "2.0.0", Language: "javascript"})
+	lock.Add("other", lockfile.LockedPackage{Version: "3.0.0", Language: "python"})
+
+	tests := []struct {
+		names   []string
+		want    []string
+		wantErr string
+	}{
+		{names: []string{"ui-kit"}, want: []string{"ui-kit"}},
+		{names: []string{"my-utils"}, want: []string{"my_utils"}},
+		{names: []string{"my_utils", "ui-kit"}, want: []string{"my_utils", "ui-kit"}},
+		{names: []string{"ui-kit", "missing", "gone"}, wantErr: "not in aigogo.lock: missing, gone\nLocked packages: my_utils, other, ui-kit"},
+	}
+	for _, tt := range tests {
+		selected, err := selectPackages(lock, tt.names)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("selectPackages(%v) error = %v, want %q", tt.names, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("selectPackages(%v) failed: %v", tt.names, err)
+			continue
+		}
+		if len(selected.Packages) != len(tt.want) {
+			t.Errorf("selectPackages(%v) selected %d packages, want %v", tt.names, len(selected.Packages), tt.want)
+		}
+		for _, name := range tt.want {
+			if !selected.Has(name) {
+				t.Errorf("selectPackages(%v) didn't select %s", tt.names, name)
+			}
+		}
+	}
+
+	// The lock file itself is left alone
+	if len(lock.Packages) != 3 {
+		t.Errorf("selectPackages changed the lock file: %v", lock.Packages)
+	}
+}
+
+func TestBrokenLinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcDir := filepath.Join(tmpDir, "src")
+	if err := os.MkdirAll(srcDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "utils.py"), []byte("x = 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cas, err := store.NewStoreAt(filepath.Join(tmpDir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := cas.Store(context.Background(), srcDir, []string{"utils.py"}, []byte(`{"name": "my-utils", "version": "1.0.0", "language": {"name": "python"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock := lockfile.New()
+	lock.Add("my_utils", lockfile.LockedPackage{Version: "1.0.0", Integrity: "sha256:" + hash, Language: "python"})
+
+	setupMgr, err := imports.NewSetupManager(filepath.Join(tmpDir, "project"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	storePath, err := cas.GetPath(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := setupMgr.CreatePackageLink("my_utils", "python", storePath, nil); err != nil {
+		t.Fatal(err)
+	}
+	if broken := BrokenLinks(setupMgr, cas, lock); len(broken) != 0 {
+		t.Errorf("BrokenLinks() = %v, want none", broken)
+	}
+
+	// Pruning the store entry breaks the link
+	if err := cas.Delete(hash); err != nil {
+		t.Fatal(err)
+	}
+	broken := BrokenLinks(setupMgr, cas, lock)
+	if problems := broken["my_utils"]; len(broken) != 1 || len(problems) != 1 || !strings.HasPrefix(problems[0].Reason, "dangling link") {
+		t.Errorf("BrokenLinks() = %v, want my_utils dangling", broken)
+	}
+}
+
+func TestInstallPackagesKeepsPathEnv(t *testing.T) {
+	setHome(t, t.TempDir())
+	t.Setenv("AIGOGO_STORE", "")
+	cas, err := store.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock := lockfile.New()
+	for _, p := range []struct{ name, lang, file string }{{"utils", "python", "utils.py"}, {"helpers", "javascript", "index.js"}} {
+		src := t.TempDir()
+		if err := os.WriteFile(filepath.Join(src, p.file), []byte("// "+p.name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		manifestData := []byte(fmt.Sprintf(`{"name": %q, "version": "1.0.0", "language": {"name": %q}}`, p.name, p.lang))
+		hash, err := cas.Store(context.Background(), src, []string{p.file}, manifestData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lock.Add(p.name, lockfile.LockedPackage{Version: "1.0.0", Integrity: "sha256:" + hash, Source: "docker.io/org/" + p.name + ":1.0.0", Language: p.lang, Files: []string{p.file}})
+	}
+	project := t.TempDir()
+	if err := lockfile.Save(filepath.Join(project, lockfile.LockFileName), lock); err != nil {
+		t.Fatal(err)
+	}
+
+	// No Python environment, so PYTHONPATH goes to path.env; NODE_PATH is
+	// there from an install that couldn't write the register script
+	noPython := filepath.Join(t.TempDir(), "python")
+	client := NewClient(project)
+	client.SetOutput(io.Discard, io.Discard)
+	if _, err := client.Install(context.Background(), InstallOptions{Offline: true, Python: noPython}); err != nil {
+		t.Fatal(err)
+	}
+	setupMgr, err := imports.NewSetupManager(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	importsDir := setupMgr.GetImportsDir()
+	if err := setupMgr.WritePathEnv(map[string]string{"PYTHONPATH": importsDir, "NODE_PATH": importsDir}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := client.Install(context.Background(), InstallOptions{Offline: true, Python: noPython, Packages: []string{"utils"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars, err := setupMgr.ReadPathEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.PathEnv || vars["PYTHONPATH"] == "" || vars["NODE_PATH"] == "" {
+		t.Errorf("path.env after installing utils = %v, want PYTHONPATH and NODE_PATH kept", vars)
+	}
+}
@@ -0,0 +1,74 @@
+package aigogo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// lifecycleContext describes a lifecycle script and the package it runs
+// for. The package fields are passed to the script as AIGOGO_* environment
+// variables.
+type lifecycleContext struct {
+	Event      string // prebuild, postbuild or postinstall
+	Command    string // the manifest's scripts entry for Event
+	Name       string
+	Version    string
+	PackageDir string // the package's files
+	ProjectDir string // the project being built or installed into
+	ImageRef   string // the build's name:tag, for prebuild and postbuild
+}
+
+// env returns the current environment plus the AIGOGO_* variables
+func (c lifecycleContext) env() []string {
+	env := append(os.Environ(),
+		"AIGOGO_LIFECYCLE_EVENT="+c.Event,
+		"AIGOGO_PACKAGE_NAME="+c.Name,
+		"AIGOGO_PACKAGE_VERSION="+c.Version,
+		"AIGOGO_PACKAGE_DIR="+c.PackageDir,
+		"AIGOGO_PROJECT_DIR="+c.ProjectDir,
+	)
+	if c.ImageRef != "" {
+		env = append(env, "AIGOGO_IMAGE_REF="+c.ImageRef)
+	}
+	return env
+}
+
+// lifecycleScript returns the context for running m's script for event,
+// or false if the manifest has none
+func lifecycleScript(m *manifest.Manifest, event string, script lifecycleContext) (lifecycleContext, bool) {
+	script.Event = event
+	script.Command = m.Scripts[event]
+	return script, script.Command != ""
+}
+
+// runLifecycleScript runs script.Command with the shell in dir, with the
+// client's input and output. It returns an error if the script exits
+// non-zero; canceling ctx kills it.
+func (c *Client) runLifecycleScript(ctx context.Context, script lifecycleContext, dir string) error {
+	c.printf("> %s %s: %s\n", script.Name, script.Event, script.Command)
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", script.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", script.Command)
+	}
+	cmd.Dir = dir
+	cmd.Env = script.env()
+	cmd.Stdin = c.in
+	cmd.Stdout = c.out
+	cmd.Stderr = c.errOut
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s script interrupted: %w", script.Event, ctx.Err())
+		}
+		return fmt.Errorf("%s script failed: %w", script.Event, err)
+	}
+	return nil
+}
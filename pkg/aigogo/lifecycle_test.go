@@ -0,0 +1,72 @@
+package aigogo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func TestBuildVersion(t *testing.T) {
+	tests := []struct {
+		ref, manifestVersion, want string
+	}{
+		{"my-utils:1.2.0", "1.1.0", "1.2.0"},
+		{"ghcr.io/org/my-utils:2.0.0", "1.1.0", "2.0.0"},
+		{"localhost:5000/my-utils", "1.1.0", "1.1.0"},
+		{"my-utils", "1.1.0", "1.1.0"},
+	}
+	for _, tt := range tests {
+		if got := buildVersion(tt.ref, tt.manifestVersion); got != tt.want {
+			t.Errorf("buildVersion(%q, %q) = %q, want %q", tt.ref, tt.manifestVersion, got, tt.want)
+		}
+	}
+}
+
+func TestLifecycleScript(t *testing.T) {
+	m := &manifest.Manifest{Scripts: map[string]string{"run": "main.py", "postinstall": "echo hi"}}
+
+	if _, ok := lifecycleScript(m, manifest.ScriptPrebuild, lifecycleContext{}); ok {
+		t.Error("expected no prebuild script")
+	}
+	script, ok := lifecycleScript(m, manifest.ScriptPostinstall, lifecycleContext{Name: "my-utils"})
+	if !ok || script.Event != "postinstall" || script.Command != "echo hi" || script.Name != "my-utils" {
+		t.Errorf("lifecycleScript() = %+v, %v", script, ok)
+	}
+}
+
+func TestRunLifecycleScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell command")
+	}
+
+	dir := t.TempDir()
+	script := lifecycleContext{
+		Event:      "postinstall",
+		Command:    `echo "$AIGOGO_LIFECYCLE_EVENT $AIGOGO_PACKAGE_NAME $AIGOGO_PACKAGE_VERSION $AIGOGO_PACKAGE_DIR" > out.txt`,
+		Name:       "my-utils",
+		Version:    "1.0.0",
+		PackageDir: "/store/abc/files",
+		ProjectDir: dir,
+	}
+	if err := NewClient(dir).runLifecycleScript(context.Background(), script, dir); err != nil {
+		t.Fatalf("runLifecycleScript failed: %v", err)
+	}
+
+	out, err := os.ReadFile(filepath.Join(dir, "out.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.TrimSpace(string(out)), "postinstall my-utils 1.0.0 /store/abc/files"; got != want {
+		t.Errorf("script saw %q, want %q", got, want)
+	}
+
+	script.Command = "exit 3"
+	if err := NewClient(dir).runLifecycleScript(context.Background(), script, dir); err == nil || !strings.Contains(err.Error(), "postinstall script failed") {
+		t.Errorf("expected a postinstall failure, got %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package aigogo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/store"
+)
+
+// Package reads the files of a package from the local cache, the package
+// store or, for a registry reference that isn't cached, from one download
+// of its layer
+type Package struct {
+	Ref    string
+	Source string // where the files are read from, e.g. "package store"
+	Locked bool   // found by its name in aigogo.lock
+
+	layer  []byte // set when read from the registry or a pulled image
+	dir    string // set for a store entry: its files directory
+	digest string // set for a store entry: its hash
+}
+
+// OpenPackage finds ref in the local cache, then in the package store, as
+// the name of a package in aigogo.lock or a sha256: hash, or fetches it from
+// its registry without caching it. With remote, it's always fetched. A
+// client set offline never fetches it.
+func (c *Client) OpenPackage(ctx context.Context, ref string, remote bool) (*Package, error) {
+	if remote {
+		if docker.IsLocalReference(ref) {
+			return nil, errcode.Errorf(errcode.Usage, "--remote needs a registry reference (registry/name:tag), got %s", ref)
+		}
+		return c.fetchPackageFiles(ctx, ref)
+	}
+	if !strings.HasPrefix(ref, "sha256:") {
+		if err := docker.ValidateCacheRef(ref); err != nil {
+			return nil, err
+		}
+	}
+	if layer, err := docker.ReadCachedLayer(ref); err == nil {
+		return &Package{Ref: ref, layer: layer, Source: "local cache, pulled"}, nil
+	}
+	if docker.GetCachePath(ref) != "" {
+		return &Package{Ref: ref, Source: "local cache"}, nil
+	}
+	pkg, err := c.openStoredPackage(ref)
+	if err != nil || pkg != nil {
+		return pkg, err
+	}
+	if docker.IsLocalReference(ref) {
+		return nil, errcode.Errorf(errcode.NotFound, "package %s not found in local cache, aigogo.lock or the package store\nBuild it with 'aigg build' or give a registry reference (registry/name:tag)", ref)
+	}
+	return c.fetchPackageFiles(ctx, ref)
+}
+
+// fetchPackageFiles downloads the layer of a registry package without
+// caching it
+func (c *Client) fetchPackageFiles(ctx context.Context, ref string) (*Package, error) {
+	if c.offline {
+		return nil, errcode.Errorf(errcode.NotFound, "package %s not found in local cache, aigogo.lock or the package store, and registries aren't read offline", ref)
+	}
+	c.log.Printf("Fetching %s from registry...\n", ref)
+	layer, err := docker.NewPuller().FetchLayer(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", ref, err)
+	}
+	return &Package{Ref: ref, layer: layer, Source: "registry"}, nil
+}
+
+// openStoredPackage finds ref in the package store, given as a sha256: hash
+// or the name of a package in the project's aigogo.lock, or returns nil
+// when ref is neither
+func (c *Client) openStoredPackage(ref string) (*Package, error) {
+	projectDir := c.dir
+	hash := ""
+	locked := false
+	lockPath, lock, err := c.findLockFile()
+	if err == nil {
+		projectDir = filepath.Dir(lockPath)
+	}
+	if strings.HasPrefix(ref, "sha256:") {
+		hash = ref
+	} else if lock != nil {
+		if pkg, ok := lock.Packages[ref]; ok && pkg.Integrity != "" {
+			hash, locked = pkg.Integrity, true
+		}
+	}
+	if hash == "" {
+		return nil, nil
+	}
+	if err := store.ValidateHash(hash); err != nil {
+		return nil, err
+	}
+
+	cas, err := OpenStore(c.settings(projectDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open package store: %w", err)
+	}
+	stored, err := cas.Get(hash)
+	if err != nil {
+		if locked {
+			return nil, fmt.Errorf("%s is in aigogo.lock but not in the package store; run 'aigg install': %w", ref, err)
+		}
+		return nil, err
+	}
+	return &Package{
+		Ref:    ref,
+		dir:    stored.FilesDir,
+		digest: "sha256:" + strings.TrimPrefix(hash, "sha256:"),
+		Locked: locked,
+		Source: "package store",
+	}, nil
+}
+
+// Stored reports whether the package was found in the package store
+func (p *Package) Stored() bool {
+	return p.dir != ""
+}
+
+// ReadFile returns a file of the package, given as a slash-separated path
+func (p *Package) ReadFile(file string) ([]byte, error) {
+	switch {
+	case p.layer != nil:
+		data, ok := docker.ReadFileFromTar(p.layer, path.Clean(file))
+		if !ok {
+			return nil, fmt.Errorf("%s not found in %s: %w", file, p.Ref, fs.ErrNotExist)
+		}
+		return data, nil
+	case p.dir != "":
+		file = path.Clean(file)
+		if path.IsAbs(file) || file == ".." || strings.HasPrefix(file, "../") {
+			return nil, fmt.Errorf("invalid package path: %s", file)
+		}
+		data, err := os.ReadFile(filepath.Join(p.dir, filepath.FromSlash(file)))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s not found in %s: %w", file, p.Ref, fs.ErrNotExist)
+		}
+		return data, err
+	}
+	return docker.ReadCachedFile(p.Ref, file)
+}
+
+// fileDigest returns the sha256: digest of a file of the package. A store
+// entry's file is streamed into the hash rather than read into memory.
+func (p *Package) fileDigest(file string) (string, error) {
+	if p.layer != nil || p.dir == "" {
+		data, err := p.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		return docker.CalculateDigest(data), nil
+	}
+	file = path.Clean(file)
+	if path.IsAbs(file) || file == ".." || strings.HasPrefix(file, "../") {
+		return "", fmt.Errorf("invalid package path: %s", file)
+	}
+	f, err := os.Open(filepath.Join(p.dir, filepath.FromSlash(file)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%s not found in %s: %w", file, p.Ref, fs.ErrNotExist)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Files returns the files of the package with their sizes, sorted by path
+func (p *Package) Files() ([]docker.PackageFile, error) {
+	switch {
+	case p.layer != nil:
+		return docker.ListTarFiles(p.layer)
+	case p.dir != "":
+		return docker.ListDirFiles(p.dir)
+	}
+	return docker.ListCachedFiles(p.Ref)
+}
+
+// Digest returns the package's integrity, the hash aigg add locks for it
+// in aigogo.lock, given its files and its aigogo.json
+func (p *Package) Digest(files []docker.PackageFile, manifestData []byte) (string, error) {
+	if p.digest != "" {
+		return p.digest, nil
+	}
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+	hash, err := store.ContentHash(paths, p.ReadFile, manifestData)
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hash, nil
+}
+
+// Layer returns the package's layer as Client.Push uploads it: a pulled
+// image's as it was pulled, else one built from its files. m is the
+// package's manifest, or nil if it has none.
+func (p *Package) Layer(m *manifest.Manifest) ([]byte, error) {
+	if p.layer != nil {
+		return p.layer, nil
+	}
+	dir := p.dir
+	if dir == "" {
+		dir = docker.GetCachePath(p.Ref)
+	}
+	files, err := p.Files()
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+	return docker.BuildLayer(dir, paths, LayerManifest(p.Ref, m))
+}
@@ -0,0 +1,119 @@
+package aigogo
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/store"
+)
+
+func TestPackageFromLayer(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"aigogo.json":    `{"name": "demo"}`,
+		"docs/README.md": "# Demo\n",
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	pkg := &Package{Ref: "example.com/org/demo:1.0.0", layer: buf.Bytes(), Source: "registry"}
+
+	data, err := pkg.ReadFile("./docs/README.md")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(data) != "# Demo\n" {
+		t.Errorf("ReadFile() = %q, want %q", data, "# Demo\n")
+	}
+
+	if _, err := pkg.ReadFile("README.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadFile() of a missing file error = %v, want fs.ErrNotExist", err)
+	}
+
+	files, err := pkg.Files()
+	want := []docker.PackageFile{{Path: "aigogo.json", Size: 16}, {Path: "docs/README.md", Size: 7}}
+	if err != nil || !reflect.DeepEqual(files, want) {
+		t.Errorf("Files() = %v, %v, want %v", files, err, want)
+	}
+}
+
+func TestPackageFromStore(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+	t.Setenv("AIGOGO_STORE", "")
+	project := t.TempDir()
+	client := NewClient(project)
+
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "utils.py"), []byte("def util(): pass\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifestData := []byte(`{"name": "utils", "version": "1.0.0"}`)
+	if err := os.WriteFile(filepath.Join(src, "aigogo.json"), manifestData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cas, err := store.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := cas.Store(context.Background(), src, []string{"aigogo.json", "utils.py"}, manifestData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock := lockfile.New()
+	lock.Add("utils", lockfile.LockedPackage{Version: "1.0.0", Integrity: "sha256:" + hash, Source: "docker.io/org/utils:1.0.0"})
+	if err := lockfile.Save(filepath.Join(project, lockfile.LockFileName), lock); err != nil {
+		t.Fatal(err)
+	}
+
+	pkg, err := client.OpenPackage(context.Background(), "utils", false)
+	if err != nil {
+		t.Fatalf("OpenPackage() error = %v", err)
+	}
+	if !pkg.Locked || pkg.Source != "package store" {
+		t.Errorf("OpenPackage() = %+v, want the locked store entry", pkg)
+	}
+	files, err := pkg.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []docker.PackageFile{{Path: "aigogo.json", Size: int64(len(manifestData))}, {Path: "utils.py", Size: 17}}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("Files() = %v, want %v", files, want)
+	}
+
+	// The digest computed from the files is the one aigg add locked
+	pkg.digest = ""
+	if digest, err := pkg.Digest(files, manifestData); err != nil || digest != "sha256:"+hash {
+		t.Errorf("Digest() = %s, %v, want sha256:%s", digest, err, hash)
+	}
+
+	if _, err := client.OpenPackage(context.Background(), "missing", false); errcode.Of(err) != errcode.NotFound {
+		t.Errorf("OpenPackage(missing) error = %v, want not_found", err)
+	}
+
+	// Offline, a registry reference that isn't local isn't fetched
+	client.SetOffline(true)
+	if _, err := client.OpenPackage(context.Background(), "example.com/org/other:1.0.0", false); errcode.Of(err) != errcode.NotFound {
+		t.Errorf("OpenPackage(offline) error = %v, want not_found", err)
+	}
+}
@@ -0,0 +1,126 @@
+package aigogo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aupeachmo/aigogo/pkg/depgen"
+	"github.com/aupeachmo/aigogo/pkg/interpreter"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// listPythonDistributions prints the installed distributions as a JSON
+// object of name to version
+const listPythonDistributions = `import importlib.metadata as md, json
+print(json.dumps({d.metadata["Name"]: d.version for d in md.distributions() if d.metadata["Name"]}))`
+
+// HostPackages looks up the libraries a project already provides, for
+// peer dependencies: Python distributions installed for the interpreter
+// aigg exec would use, and JavaScript packages in the project's
+// node_modules. Python is only asked once, on first use.
+type HostPackages struct {
+	projectDir string
+	python     func() map[string]string
+}
+
+// NewHostPackages returns the HostPackages of the project in projectDir
+func NewHostPackages(projectDir string) *HostPackages {
+	return &HostPackages{
+		projectDir: projectDir,
+		python:     sync.OnceValue(detectPythonDistributions),
+	}
+}
+
+// detectPythonDistributions returns the installed distributions keyed by
+// their PEP 503 normalized names, or nil when Python isn't available
+func detectPythonDistributions() map[string]string {
+	python, err := interpreter.FindPython("")
+	if err != nil {
+		return nil
+	}
+	out, err := exec.Command(python, "-c", listPythonDistributions).Output()
+	if err != nil {
+		return nil
+	}
+	var dists map[string]string
+	if err := json.Unmarshal(out, &dists); err != nil {
+		return nil
+	}
+	normalized := make(map[string]string, len(dists))
+	for name, version := range dists {
+		normalized[normalizePythonName(name)] = version
+	}
+	return normalized
+}
+
+// normalizePythonName applies PEP 503 name normalization
+func normalizePythonName(name string) string {
+	return strings.NewReplacer("_", "-", ".", "-").Replace(strings.ToLower(name))
+}
+
+// Version returns the version of pkg the host provides, or "" when it
+// doesn't. checked is false for languages whose packages can't be looked
+// up, and for Python when no interpreter was found.
+func (h *HostPackages) Version(language, pkg string) (version string, checked bool) {
+	switch language {
+	case "python":
+		dists := h.python()
+		if dists == nil {
+			return "", false
+		}
+		return dists[normalizePythonName(pkg)], true
+	case "javascript", "typescript":
+		data, err := os.ReadFile(filepath.Join(h.projectDir, "node_modules", pkg, "package.json"))
+		if err != nil {
+			return "", true
+		}
+		var pj struct {
+			Version string `json:"version"`
+		}
+		if err := json.Unmarshal(data, &pj); err != nil {
+			return "", true
+		}
+		return pj.Version, true
+	}
+	return "", false
+}
+
+// PeerProblem is a peer dependency the host doesn't provide at a
+// compatible version
+type PeerProblem struct {
+	Package string
+	Message string // e.g. "torch >=2.0 is not installed"
+}
+
+// PeerProblems lists the peer dependencies of m that host doesn't provide
+// at a compatible version. Dependencies with an environment marker are
+// skipped, since the marker can't be evaluated here.
+func PeerProblems(m *manifest.Manifest, host *HostPackages) []PeerProblem {
+	if m.Dependencies == nil {
+		return nil
+	}
+	var problems []PeerProblem
+	for _, dep := range m.Dependencies.Peer {
+		if dep.Marker != "" {
+			continue
+		}
+		language := m.DependencyLanguage(dep)
+		version, checked := host.Version(language, dep.Package)
+		if !checked {
+			continue
+		}
+		if version == "" {
+			problems = append(problems, PeerProblem{dep.Package, fmt.Sprintf("%s %s is not installed", dep.Package, dep.Version)})
+			continue
+		}
+		if ok, known := depgen.VersionSatisfies(language, dep.Version, version); known && !ok {
+			problems = append(problems, PeerProblem{dep.Package, fmt.Sprintf("%s %s is required, found %s", dep.Package, dep.Version, version)})
+		}
+	}
+	return problems
+}
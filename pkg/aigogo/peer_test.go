@@ -0,0 +1,60 @@
+package aigogo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func TestPeerProblems(t *testing.T) {
+	projectDir := t.TempDir()
+	reactDir := filepath.Join(projectDir, "node_modules", "react")
+	if err := os.MkdirAll(reactDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(reactDir, "package.json"), []byte(`{"name": "react", "version": "17.0.2"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	host := &HostPackages{
+		projectDir: projectDir,
+		python: func() map[string]string {
+			return map[string]string{"torch": "2.1.0", "scikit-learn": "1.3.0"}
+		},
+	}
+
+	m := &manifest.Manifest{
+		Language:  manifest.Language{Name: "python"},
+		Languages: []manifest.Language{{Name: "javascript", Version: ">=18"}},
+		Dependencies: &manifest.Dependencies{
+			Peer: []manifest.Dependency{
+				{Package: "torch", Version: ">=2.0"},
+				{Package: "scikit_learn", Version: ">=1.4"},
+				{Package: "jax", Version: ">=0.4"},
+				{Package: "tomli", Version: ">=2.0", Marker: `python_version < "3.11"`},
+				{Package: "react", Version: "^18.0.0", Language: "javascript"},
+				{Package: "vue", Version: "^3.0.0", Language: "javascript"},
+			},
+		},
+	}
+
+	want := []PeerProblem{
+		{"scikit_learn", "scikit_learn >=1.4 is required, found 1.3.0"},
+		{"jax", "jax >=0.4 is not installed"},
+		{"react", "react ^18.0.0 is required, found 17.0.2"},
+		{"vue", "vue ^3.0.0 is not installed"},
+	}
+	if got := PeerProblems(m, host); !reflect.DeepEqual(got, want) {
+		t.Errorf("PeerProblems() =\n%v\nwant\n%v", got, want)
+	}
+
+	// Without a Python interpreter, Python peers can't be checked
+	host.python = func() map[string]string { return nil }
+	m.Dependencies.Peer = m.Dependencies.Peer[:1]
+	if got := PeerProblems(m, host); len(got) != 0 {
+		t.Errorf("PeerProblems() without Python = %v, want none", got)
+	}
+}
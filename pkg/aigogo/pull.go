@@ -0,0 +1,73 @@
+package aigogo
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// PrefetchResult is what Client.Prefetch fetched
+type PrefetchResult struct {
+	Packages int      // the locked packages considered
+	Fetched  int      // how many were fetched into the store
+	Present  []string // the packages already in the store
+	Local    []string // the local path packages, which have nothing to fetch
+	Skipped  []string // the dev and optional packages production left out
+}
+
+// Prefetch fetches every package in the project's aigogo.lock that isn't
+// in the package store, as Install would, without linking anything, so a
+// later install (or an offline one) needs no registry. With production,
+// dev and optional packages are left out.
+func (c *Client) Prefetch(ctx context.Context, production bool) (*PrefetchResult, error) {
+	lockPath, lock, err := c.findLockFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find aigogo.lock: %w\nRun 'aigg add <package>' first to add packages", err)
+	}
+	result := &PrefetchResult{}
+	if production {
+		lock, result.Skipped = productionPackages(lock)
+		if len(result.Skipped) > 0 {
+			c.log.Printf("Skipping %d dev/optional package(s) (--production)\n", len(result.Skipped))
+		}
+	}
+	result.Packages = len(lock.Packages)
+	if result.Packages == 0 {
+		return result, nil
+	}
+
+	cas, err := OpenStore(c.settings(filepath.Dir(lockPath)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize store: %w", err)
+	}
+
+	names := make([]string, 0, len(lock.Packages))
+	for name := range lock.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pkg := lock.Packages[name]
+		switch {
+		case pkg.IsLocal():
+			result.Local = append(result.Local, name)
+		case cas.Has(pkg.GetIntegrityHash()):
+			result.Present = append(result.Present, name)
+		}
+	}
+	for _, name := range result.Present {
+		c.log.Printf("✓ %s already in the store\n", name)
+	}
+	for _, name := range result.Local {
+		c.log.Printf("- %s is a local path package (%s), nothing to fetch\n", name, lock.Packages[name].Path)
+	}
+	if len(result.Present)+len(result.Local) > 0 {
+		c.log.Printf("\n")
+	}
+
+	if result.Fetched, err = c.fetchMissing(ctx, cas, lock, false); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,94 @@
+package aigogo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/store"
+)
+
+func TestPrefetch(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+	t.Setenv("AIGOGO_STORE", "")
+	project := t.TempDir()
+	client := NewClient(project)
+
+	// Each package's content is stored elsewhere first to learn its hash
+	scratch, err := store.NewStoreAt(filepath.Join(t.TempDir(), "scratch"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contentDir := func(name string) string {
+		dir := filepath.Join(t.TempDir(), name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "mod.py"), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+	lock := lockfile.New()
+	for _, name := range []string{"stored", "remote", "helper"} {
+		hash, err := scratch.Store(context.Background(), contentDir(name), []string{"mod.py"}, []byte(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkg := lockfile.LockedPackage{Integrity: "sha256:" + hash, Source: "registry.example/" + name + ":1.0.0"}
+		if name == "helper" {
+			pkg.Group = lockfile.GroupDev
+		}
+		lock.Add(name, pkg)
+	}
+	lock.Add("linked", lockfile.LockedPackage{Source: lockfile.SourcePath, Path: "../linked"})
+	if err := lockfile.Save(filepath.Join(project, "aigogo.lock"), lock); err != nil {
+		t.Fatal(err)
+	}
+
+	cas, err := store.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cas.Store(context.Background(), contentDir("stored"), []string{"mod.py"}, []byte("stored")); err != nil {
+		t.Fatal(err)
+	}
+
+	var fetched []string
+	client.fetch = func(_ context.Context, cas *store.Store, pkg lockfile.LockedPackage, offline bool) error {
+		name := lockfile.GetPackageName(pkg.Source)
+		fetched = append(fetched, name)
+		_, err := cas.Store(context.Background(), contentDir(name), []string{"mod.py"}, []byte(name))
+		return err
+	}
+
+	// --production leaves out the dev package; the stored and local path
+	// packages are never fetched
+	result, err := client.Prefetch(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Prefetch(production) error = %v", err)
+	}
+	if result.Fetched != 1 || len(result.Present) != 1 || len(result.Local) != 1 || len(result.Skipped) != 1 {
+		t.Errorf("Prefetch(production) = %+v", result)
+	}
+	if len(fetched) != 1 || fetched[0] != "remote" {
+		t.Errorf("fetched %v, want [remote]", fetched)
+	}
+
+	fetched = nil
+	if _, err := client.Prefetch(context.Background(), false); err != nil {
+		t.Fatalf("Prefetch() error = %v", err)
+	}
+	if len(fetched) != 1 || fetched[0] != "helper" {
+		t.Errorf("fetched %v, want [helper]", fetched)
+	}
+	for _, name := range []string{"stored", "remote", "helper"} {
+		pkg := lock.Packages[name]
+		if !cas.Has(pkg.GetIntegrityHash()) {
+			t.Errorf("%s not in the store", name)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package aigogo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/config"
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// PushOptions are the options of Client.Push
+type PushOptions struct {
+	From         string // the local build to push, as name:tag
+	AllowPrivate bool   // push a package marked "private": true
+	DryRun       bool   // run the checks and report what would be pushed
+}
+
+// PushResult is what Client.Push pushed
+type PushResult struct {
+	Ref    string // the registry reference pushed to
+	From   string // the local build pushed
+	Digest string // of the manifest pushed; empty for a dry run
+	// Content is the digest of the local build's files (see
+	// docker.CalculateDirectoryDigest), set for a dry run
+	Content string
+	Files   int
+}
+
+// Push pushes the local build opts.From to the registry reference ref.
+// Blocked registries, private packages and the project's tag_policy are
+// checked first. With opts.DryRun nothing is pushed.
+func (c *Client) Push(ctx context.Context, ref string, opts PushOptions) (*PushResult, error) {
+	localRef := opts.From
+	if !docker.ImageExistsInCache(localRef) {
+		return nil, errcode.Errorf(errcode.NotFound, "local build not found: %s\nBuild it first with: aigg build %s", localRef, localRef)
+	}
+	cacheDir, err := docker.CacheDir()
+	if err != nil {
+		return nil, err
+	}
+	localPath := filepath.Join(cacheDir, docker.SanitizeImageRef(localRef))
+
+	// The package's aigogo.json supplies the metadata for the layer's
+	// manifest and the registry annotations. Builds without one still push.
+	var m *manifest.Manifest
+	if loaded, err := manifest.Load(filepath.Join(localPath, "aigogo.json")); err == nil {
+		m = loaded
+	}
+
+	if err := c.checkPushAllowed(ref, m, opts.AllowPrivate); err != nil {
+		return nil, err
+	}
+	settings := c.settings(config.FindProjectDir(c.dir))
+	if err := c.checkMutableTag("push", ref, settings); err != nil {
+		return nil, err
+	}
+	if err := c.checkTagOverwrite(ctx, ref, settings); err != nil {
+		return nil, err
+	}
+
+	files, err := LocalBuildFiles(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local build: %w", err)
+	}
+	if opts.DryRun {
+		content, err := docker.CalculateDirectoryDigest(localPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read local build: %w", err)
+		}
+		return &PushResult{Ref: ref, From: localRef, Content: content, Files: len(files)}, nil
+	}
+
+	c.log.Printf("Pushing local build %s to %s...\n", localRef, ref)
+	c.log.Printf("  Found %d file(s) in local build\n", len(files))
+
+	c.log.Printf("Building image for registry...\n")
+	if err := docker.NewBuilder().BuildImageFromPath(ref, localPath, files, LayerManifest(localRef, m)); err != nil {
+		return nil, fmt.Errorf("failed to build image: %w", err)
+	}
+
+	var annotations map[string]string
+	if m != nil {
+		annotations = docker.Annotations(m)
+	}
+	c.log.Printf("Pushing to %s...\n", ref)
+	digest, err := docker.NewPusher().Push(ctx, ref, annotations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to push image: %w", err)
+	}
+	return &PushResult{Ref: ref, From: localRef, Digest: digest, Files: len(files)}, nil
+}
+
+// blockedRegistriesEnv lists registries to refuse for every package,
+// comma-separated, in addition to the manifest's publish.blocked_registries
+const blockedRegistriesEnv = "AIGOGO_BLOCKED_REGISTRIES"
+
+// checkPushAllowed refuses pushes to a blocked registry, and pushes of a
+// private package unless allowPrivate is set. m may be nil.
+func (c *Client) checkPushAllowed(registryRef string, m *manifest.Manifest, allowPrivate bool) error {
+	var blocked []string
+	if m != nil && m.Publish != nil {
+		blocked = m.Publish.BlockedRegistries
+	}
+	if entry := blockedRegistry(registryRef, blocked); entry != "" {
+		return fmt.Errorf("refusing to push to %s: %s is blocked by publish.blocked_registries in aigogo.json", registryRef, entry)
+	}
+	if entry := blockedRegistry(registryRef, strings.Split(os.Getenv(blockedRegistriesEnv), ",")); entry != "" {
+		return fmt.Errorf("refusing to push to %s: %s is blocked by %s", registryRef, entry, blockedRegistriesEnv)
+	}
+
+	if m == nil || !m.Private {
+		return nil
+	}
+	if !allowPrivate {
+		return fmt.Errorf("refusing to push %s: it is marked \"private\": true in aigogo.json\nRun with --allow-private if it is meant for %s", m.Name, registryRef)
+	}
+	c.printf("⚠️  Pushing private package %s (--allow-private)\n", m.Name)
+	return nil
+}
+
+// blockedRegistry returns the entry of blocked that registryRef falls
+// under, or "". An entry is a registry host, which blocks the whole
+// registry, or a host/namespace prefix. Docker Hub's host names are
+// treated as one.
+func blockedRegistry(registryRef string, blocked []string) string {
+	registry, repository, _, err := docker.ParseImageRef(registryRef)
+	if err != nil {
+		return ""
+	}
+	target := canonicalRegistry(registry) + "/" + repository
+
+	for _, entry := range blocked {
+		prefix := strings.Trim(strings.TrimSpace(entry), "/")
+		if prefix == "" {
+			continue
+		}
+		host, rest, _ := strings.Cut(prefix, "/")
+		prefix = canonicalRegistry(host)
+		if rest != "" {
+			prefix += "/" + rest
+		}
+		if target == prefix || strings.HasPrefix(target, prefix+"/") {
+			return strings.TrimSpace(entry)
+		}
+	}
+	return ""
+}
+
+// canonicalRegistry lowercases a registry host and maps Docker Hub's
+// aliases to docker.io
+func canonicalRegistry(host string) string {
+	host = strings.ToLower(host)
+	switch host {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com", "hub.docker.com":
+		return "docker.io"
+	}
+	return host
+}
+
+// LayerManifest returns the .aigogo-manifest.json written into the pushed
+// layer: the local build it came from plus the package's metadata
+func LayerManifest(localRef string, m *manifest.Manifest) map[string]interface{} {
+	layer := map[string]interface{}{
+		"name":    localRef,
+		"version": "local",
+	}
+	if m == nil {
+		return layer
+	}
+
+	set := func(key, value string) {
+		if value != "" {
+			layer[key] = value
+		}
+	}
+	set("package", m.Name)
+	set("packageVersion", m.Version)
+	set("description", m.Description)
+	set("author", m.Author)
+	set("license", m.Metadata.License)
+	set("repository", m.Metadata.Repository)
+	set("homepage", m.Metadata.Homepage)
+	if len(m.Metadata.Keywords) > 0 {
+		layer["keywords"] = m.Metadata.Keywords
+	}
+	return layer
+}
+
+// LocalBuildFiles returns the files of the local build in localPath, as
+// slash-separated paths relative to it
+func LocalBuildFiles(localPath string) ([]string, error) {
+	var files []string
+
+	entries, err := os.ReadDir(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local build directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		// Skip metadata files
+		if entry.Name() == ".aigogo-metadata.json" {
+			continue
+		}
+
+		if entry.IsDir() {
+			subFiles, err := collectFiles(filepath.Join(localPath, entry.Name()), entry.Name())
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, subFiles...)
+		} else {
+			files = append(files, entry.Name())
+		}
+	}
+
+	return files, nil
+}
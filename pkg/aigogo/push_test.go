@@ -0,0 +1,142 @@
+package aigogo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func TestLayerManifest(t *testing.T) {
+	if got := LayerManifest("utils:1.0.0", nil); !reflect.DeepEqual(got, map[string]interface{}{"name": "utils:1.0.0", "version": "local"}) {
+		t.Errorf("LayerManifest without aigogo.json = %v", got)
+	}
+
+	m := &manifest.Manifest{
+		Name:        "utils",
+		Version:     "1.0.0",
+		Description: "String helpers",
+		Metadata: manifest.Metadata{
+			License:    "MIT",
+			Repository: "https://github.com/org/utils",
+			Keywords:   []string{"strings"},
+		},
+	}
+	want := map[string]interface{}{
+		"name":           "utils:1.0.0",
+		"version":        "local",
+		"package":        "utils",
+		"packageVersion": "1.0.0",
+		"description":    "String helpers",
+		"license":        "MIT",
+		"repository":     "https://github.com/org/utils",
+		"keywords":       []string{"strings"},
+	}
+	if got := LayerManifest("utils:1.0.0", m); !reflect.DeepEqual(got, want) {
+		t.Errorf("LayerManifest() = %v, want %v", got, want)
+	}
+}
+
+func TestPushDryRun(t *testing.T) {
+	setHome(t, t.TempDir())
+	t.Setenv("AIGOGO_STORE", "")
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"aigogo.json": `{"name": "utils", "version": "1.0.0", "language": {"name": "python", "version": ">=3.8"},
+			"files": {"include": ["utils.py"]}}`,
+		"utils.py": "def util(): pass\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := NewClient(dir)
+	if _, err := c.Build(context.Background(), BuildOptions{ImageRef: "utils:1.0.0", NoValidate: true, IgnoreScripts: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := c.Push(context.Background(), "localhost:1/me/utils:1.0.0", PushOptions{From: "utils:1.0.0", DryRun: true})
+	if err != nil {
+		t.Fatalf("Push() dry run error = %v", err)
+	}
+	want, err := docker.CalculateDirectoryDigest(docker.GetCachePath("utils:1.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Content != want || result.Digest != "" || result.Files != 2 {
+		t.Errorf("Push() dry run = %+v, want content %s, 2 files and nothing pushed", result, want)
+	}
+}
+
+func TestDeprecationAnnotations(t *testing.T) {
+	m := &manifest.Manifest{
+		Name:       "utils",
+		Deprecated: &manifest.Deprecation{Message: "Use text-utils", Replacement: "ghcr.io/org/text-utils:2.0.0"},
+	}
+	annotations := docker.Annotations(m)
+	if annotations[docker.AnnotationDeprecated] != "Use text-utils" || annotations[docker.AnnotationReplacement] != "ghcr.io/org/text-utils:2.0.0" {
+		t.Errorf("Annotations() = %v, want the deprecation", annotations)
+	}
+	if got := docker.DeprecationFromAnnotations(annotations); !reflect.DeepEqual(got, m.Deprecated) {
+		t.Errorf("DeprecationFromAnnotations() = %+v, want %+v", got, m.Deprecated)
+	}
+	if got := docker.DeprecationFromAnnotations(docker.Annotations(&manifest.Manifest{Name: "utils"})); got != nil {
+		t.Errorf("DeprecationFromAnnotations() without deprecation = %+v", got)
+	}
+}
+
+func TestBlockedRegistry(t *testing.T) {
+	blocked := []string{"docker.io", " ghcr.io/public-org/ ", ""}
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"docker.io/me/utils:1.0.0", "docker.io"},
+		{"me/utils:1.0.0", "docker.io"},
+		{"index.docker.io/me/utils:1.0.0", "docker.io"},
+		{"ghcr.io/public-org/utils:1.0.0", "ghcr.io/public-org/"},
+		{"GHCR.io/public-org/team/utils:1.0.0", "ghcr.io/public-org/"},
+		{"ghcr.io/public-org-internal/utils:1.0.0", ""},
+		{"ghcr.io/internal/utils:1.0.0", ""},
+		{"registry.internal.example.com/team/utils:1.0.0", ""},
+	}
+	for _, tt := range tests {
+		if got := blockedRegistry(tt.ref, blocked); got != strings.TrimSpace(tt.want) {
+			t.Errorf("blockedRegistry(%q) = %q, want %q", tt.ref, got, strings.TrimSpace(tt.want))
+		}
+	}
+}
+
+func TestCheckPushAllowed(t *testing.T) {
+	t.Setenv(blockedRegistriesEnv, "quay.io, ghcr.io/leaky")
+	c := NewClient(t.TempDir())
+
+	if err := c.checkPushAllowed("ghcr.io/org/utils:1.0.0", nil, false); err != nil {
+		t.Errorf("push without aigogo.json: %v", err)
+	}
+	if err := c.checkPushAllowed("ghcr.io/leaky/utils:1.0.0", nil, true); err == nil || !strings.Contains(err.Error(), blockedRegistriesEnv) {
+		t.Errorf("push to a registry blocked by the environment: err = %v", err)
+	}
+
+	m := &manifest.Manifest{
+		Name:    "utils",
+		Private: true,
+		Publish: &manifest.PublishSpec{BlockedRegistries: []string{"docker.io"}},
+	}
+	err := c.checkPushAllowed("docker.io/org/utils:1.0.0", m, true)
+	if err == nil || !strings.Contains(err.Error(), "publish.blocked_registries") {
+		t.Errorf("--allow-private must not override the blocklist: err = %v", err)
+	}
+	err = c.checkPushAllowed("registry.example.com/org/utils:1.0.0", m, false)
+	if err == nil || !strings.Contains(err.Error(), "--allow-private") {
+		t.Errorf("push of a private package: err = %v", err)
+	}
+	if err := c.checkPushAllowed("registry.example.com/org/utils:1.0.0", m, true); err != nil {
+		t.Errorf("push of a private package with --allow-private: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package aigogo
+
+import (
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// QualifyImageRef returns ref as found in registry, when ref names no
+// registry host: a name:tag gets the registry and its namespace, a
+// namespace/name:tag only the registry host
+func QualifyImageRef(ref, registry string) string {
+	if registry == "" || !docker.IsLocalReference(ref) {
+		return ref
+	}
+	if !strings.Contains(ref, "/") {
+		return registry + "/" + ref
+	}
+	host, _, _ := strings.Cut(registry, "/")
+	return host + "/" + ref
+}
+
+// projectImageRef returns the reference a package is added from: one
+// without a registry host comes from --registry, or else the project's
+// registry, unless it is a name:tag in the local cache
+func projectImageRef(imageRef, flagRegistry string, settings *manifest.Settings) string {
+	if flagRegistry != "" {
+		return QualifyImageRef(imageRef, flagRegistry)
+	}
+	if !strings.Contains(imageRef, "/") && docker.GetCachePath(imageRef) != "" {
+		return imageRef
+	}
+	return QualifyImageRef(imageRef, settings.Registry)
+}
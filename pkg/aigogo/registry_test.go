@@ -0,0 +1,59 @@
+package aigogo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func TestQualifyImageRef(t *testing.T) {
+	tests := []struct {
+		ref, registry, want string
+	}{
+		{"utils:1.0.0", "", "utils:1.0.0"},
+		{"utils:1.0.0", "ghcr.io/ourco", "ghcr.io/ourco/utils:1.0.0"},
+		{"org/utils:1.0.0", "ghcr.io/ourco", "ghcr.io/org/utils:1.0.0"},
+		{"org/utils:1.0.0", "localhost:5000", "localhost:5000/org/utils:1.0.0"},
+		{"docker.io/org/utils:1.0.0", "ghcr.io/ourco", "docker.io/org/utils:1.0.0"},
+		{"localhost/utils:1.0.0", "ghcr.io/ourco", "localhost/utils:1.0.0"},
+	}
+	for _, tt := range tests {
+		if got := QualifyImageRef(tt.ref, tt.registry); got != tt.want {
+			t.Errorf("QualifyImageRef(%q, %q) = %q, want %q", tt.ref, tt.registry, got, tt.want)
+		}
+	}
+}
+
+func TestProjectImageRef(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+	if err := os.MkdirAll(filepath.Join(home, ".aigogo", "cache", docker.SanitizeImageRef("utils:1.0.0")), 0755); err != nil {
+		t.Fatal(err)
+	}
+	settings := &manifest.Settings{Registry: "ghcr.io/ourco"}
+
+	// A local build wins over the registry setting, but not over --registry
+	if got := projectImageRef("utils:1.0.0", "", settings); got != "utils:1.0.0" {
+		t.Errorf("projectImageRef(local build) = %q", got)
+	}
+	if got := projectImageRef("utils:1.0.0", "quay.io/team", settings); got != "quay.io/team/utils:1.0.0" {
+		t.Errorf("projectImageRef(local build, --registry) = %q", got)
+	}
+	if got := projectImageRef("org/utils:1.0.0", "", settings); got != "ghcr.io/org/utils:1.0.0" {
+		t.Errorf("projectImageRef(namespace/name) = %q", got)
+	}
+}
+
+func TestParseQualifiedImageRef(t *testing.T) {
+	// --registry localhost:5000 qualifies references with a registry port
+	registry, repository, tag, err := docker.ParseImageRef(QualifyImageRef("org/utils:1.0.0", "localhost:5000"))
+	if err != nil || registry != "localhost:5000" || repository != "org/utils" || tag != "1.0.0" {
+		t.Errorf("ParseImageRef() = %q, %q, %q, %v", registry, repository, tag, err)
+	}
+	if _, _, _, err := docker.ParseImageRef("org/utils:1.0:0"); err == nil {
+		t.Error("ParseImageRef() accepted two tags")
+	}
+}
@@ -0,0 +1,55 @@
+package aigogo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// checkMutableTag applies tag_policy.mutable to pushing or adding imageRef
+// (action is "push" or "add"): a tag that isn't a version, such as latest,
+// is refused by block and warned about by warn
+func (c *Client) checkMutableTag(action, imageRef string, settings *manifest.Settings) error {
+	_, _, tag, err := docker.ParseImageRef(imageRef)
+	if err != nil || manifest.IsVersionTag(tag) {
+		return nil
+	}
+	switch settings.MutableTagPolicy() {
+	case manifest.TagPolicyBlock:
+		return fmt.Errorf("refusing to %s %s: :%s isn't a version tag, and tag_policy.mutable is block\nUse a version tag such as :1.0.0, which always means the same package", action, imageRef, tag)
+	case manifest.TagPolicyWarn:
+		c.warnf("%s is tagged :%s, which can change under projects that use it; prefer a version tag such as :1.0.0 (tag_policy.mutable)\n", imageRef, tag)
+	}
+	return nil
+}
+
+// checkTagOverwrite applies tag_policy.overwrite to pushing imageRef: when
+// its version tag is already in the registry, block refuses the push and
+// warn warns about it. A registry that can't be asked is warned about
+// rather than refused, as the push would fail the same way.
+func (c *Client) checkTagOverwrite(ctx context.Context, imageRef string, settings *manifest.Settings) error {
+	policy := settings.OverwriteTagPolicy()
+	if policy == manifest.TagPolicyAllow {
+		return nil
+	}
+	_, _, tag, err := docker.ParseImageRef(imageRef)
+	if err != nil || !manifest.IsVersionTag(tag) {
+		return nil
+	}
+
+	exists, err := docker.NewPuller().Exists(ctx, imageRef)
+	if err != nil {
+		c.warnf("couldn't check whether %s is already pushed (tag_policy.overwrite): %v\n", imageRef, err)
+		return nil
+	}
+	if !exists {
+		return nil
+	}
+	if policy == manifest.TagPolicyBlock {
+		return fmt.Errorf("refusing to push %s: the registry already has :%s, and tag_policy.overwrite is block\nPublish the change under a new version instead: aigg version patch, then build and push it", imageRef, tag)
+	}
+	c.warnf("%s is already pushed; pushing replaces it for everyone who adds it later (tag_policy.overwrite)\n", imageRef)
+	return nil
+}
@@ -0,0 +1,61 @@
+package aigogo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/docker"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func TestCheckMutableTag(t *testing.T) {
+	tests := []struct {
+		ref, policy string
+		wantErr     bool
+	}{
+		{"ghcr.io/org/utils:latest", manifest.TagPolicyBlock, true},
+		{"ghcr.io/org/utils", manifest.TagPolicyBlock, true},
+		{"utils:main", manifest.TagPolicyBlock, true},
+		{"ghcr.io/org/utils:1.0.0", manifest.TagPolicyBlock, false},
+		{"ghcr.io/org/utils:latest", manifest.TagPolicyWarn, false},
+		{"ghcr.io/org/utils:latest", "", false},
+	}
+	for _, tt := range tests {
+		settings := &manifest.Settings{TagPolicy: &manifest.TagPolicySpec{Mutable: tt.policy}}
+		err := NewClient(t.TempDir()).checkMutableTag("add", tt.ref, settings)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkMutableTag(%q, %q) error = %v, want error %v", tt.ref, tt.policy, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckTagOverwrite(t *testing.T) {
+	setHome(t, t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/org/utils/manifests/1.0.0" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.v2+json")
+		_, _ = w.Write([]byte(`{"schemaVersion": 2, "layers": []}`))
+	}))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "http://")
+	docker.SetInsecureRegistries([]string{registry})
+	t.Cleanup(func() { docker.SetInsecureRegistries(nil) })
+
+	block := &manifest.Settings{TagPolicy: &manifest.TagPolicySpec{Overwrite: manifest.TagPolicyBlock}}
+	if err := NewClient(t.TempDir()).checkTagOverwrite(context.Background(), registry+"/org/utils:1.0.0", block); err == nil || !strings.Contains(err.Error(), "already has :1.0.0") {
+		t.Errorf("checkTagOverwrite(pushed version) error = %v, want it refused", err)
+	}
+	if err := NewClient(t.TempDir()).checkTagOverwrite(context.Background(), registry+"/org/utils:1.0.1", block); err != nil {
+		t.Errorf("checkTagOverwrite(new version) error = %v", err)
+	}
+	warn := &manifest.Settings{TagPolicy: &manifest.TagPolicySpec{Overwrite: manifest.TagPolicyWarn}}
+	if err := NewClient(t.TempDir()).checkTagOverwrite(context.Background(), registry+"/org/utils:1.0.0", warn); err != nil {
+		t.Errorf("checkTagOverwrite(warn) error = %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+)
+
+// PassphraseEnv is the environment variable holding the passphrase of an
+// encrypted auth.json, when the auth.key_command setting doesn't give it
+const PassphraseEnv = "AIGOGO_AUTH_PASSPHRASE"
+
+// The format of an encrypted auth.json: AES-256-GCM under a key derived
+// from the passphrase with PBKDF2-SHA256
+const (
+	cipherName = "aes-256-gcm"
+	kdfName    = "pbkdf2-sha256"
+	// maxIterations bounds the work a damaged file can ask for
+	maxIterations = 100_000_000
+)
+
+// kdfIterations is the PBKDF2 iteration count of newly encrypted files, as
+// OWASP recommends for PBKDF2-SHA256
+var kdfIterations = 600_000
+
+// encryptedConfig is an encrypted auth.json. Its fields are readable
+// without the passphrase; the credentials are only in Ciphertext.
+type encryptedConfig struct {
+	Encrypted  string `json:"encrypted"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+var (
+	// encryptSetting is whether auth.json is written encrypted; nil keeps
+	// it as it is (SetEncrypt)
+	encryptSetting *bool
+	// keyCommand prints the passphrase (SetKeyCommand)
+	keyCommand string
+	// passphrasePrompt asks for the passphrase (SetPassphrasePrompt)
+	passphrasePrompt func(confirm bool) (string, error)
+
+	// passphraseMu guards the passphrase and the keys derived from it,
+	// which are kept for the life of the process, as registry requests
+	// read the credentials many times
+	passphraseMu sync.Mutex
+	passphrase   string
+	derivedKeys  = map[string][]byte{}
+)
+
+// SetEncrypt sets whether auth.json is written encrypted with a passphrase.
+// With nil, an encrypted file stays encrypted and a plain one plain.
+func SetEncrypt(encrypt *bool) {
+	encryptSetting = encrypt
+}
+
+// SetKeyCommand sets a command printing the passphrase of auth.json, run
+// with the shell, such as age -d to decrypt a key file or a KMS or password
+// manager CLI. "" reads it from PassphraseEnv or the prompt instead.
+func SetKeyCommand(command string) {
+	keyCommand = command
+}
+
+// SetPassphrasePrompt sets the function asking the user for the passphrase
+// when neither the key command nor PassphraseEnv gives it, with confirm set
+// when it's a new one to type twice; nil when there's no one to ask
+func SetPassphrasePrompt(prompt func(confirm bool) (string, error)) {
+	passphrasePrompt = prompt
+}
+
+// getPassphrase returns the passphrase of auth.json from the key command,
+// PassphraseEnv or the prompt, asking for confirmation when confirm is set
+// and it has to prompt. passphraseMu must be held.
+func getPassphrase(confirm bool) (string, error) {
+	if passphrase != "" {
+		return passphrase, nil
+	}
+	var value string
+	switch {
+	case keyCommand != "":
+		out, err := runKeyCommand(keyCommand)
+		if err != nil {
+			return "", errcode.Errorf(errcode.Auth, "auth.key_command failed: %w", err)
+		}
+		value = out
+	case os.Getenv(PassphraseEnv) != "":
+		value = os.Getenv(PassphraseEnv)
+	case passphrasePrompt != nil:
+		prompted, err := passphrasePrompt(confirm)
+		if err != nil {
+			return "", errcode.Wrap(errcode.Auth, err)
+		}
+		value = prompted
+	default:
+		return "", errcode.Errorf(errcode.Auth, "auth.json is encrypted and no passphrase was given\nSet %s or the auth.key_command setting, or run aigg on a terminal to be asked for it", PassphraseEnv)
+	}
+	if value == "" {
+		return "", errcode.Errorf(errcode.Auth, "the passphrase of auth.json is empty")
+	}
+	passphrase = value
+	return passphrase, nil
+}
+
+// runKeyCommand runs command with the shell and returns what it prints,
+// without the trailing newline. It shares aigg's terminal, so it can ask
+// for a passphrase or touch of its own.
+func runKeyCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	var out bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(out.String(), "\r\n"), nil
+}
+
+// deriveKey returns the AES key of the passphrase for salt and iterations.
+// passphraseMu must be held.
+func deriveKey(pass string, salt []byte, iterations int) ([]byte, error) {
+	id := fmt.Sprintf("%d:%x", iterations, salt)
+	if key, ok := derivedKeys[id]; ok {
+		return key, nil
+	}
+	key, err := pbkdf2.Key(sha256.New, pass, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	derivedKeys[id] = key
+	return key, nil
+}
+
+// isEncrypted reports whether data is an encrypted auth.json, returning it
+// parsed
+func isEncrypted(data []byte) (*encryptedConfig, bool) {
+	var enc encryptedConfig
+	if err := json.Unmarshal(data, &enc); err != nil || enc.Encrypted == "" {
+		return nil, false
+	}
+	return &enc, true
+}
+
+// decryptConfig returns the plain auth.json enc holds
+func decryptConfig(enc *encryptedConfig) ([]byte, error) {
+	if enc.Encrypted != cipherName || enc.KDF != kdfName {
+		return nil, errcode.Errorf(errcode.Auth, "auth.json is encrypted with %s and %s, which this aigg can't read", enc.Encrypted, enc.KDF)
+	}
+	if enc.Iterations < 1 || enc.Iterations > maxIterations || len(enc.Salt) == 0 {
+		return nil, errcode.Errorf(errcode.Integrity, "auth.json is damaged: invalid key derivation parameters")
+	}
+
+	passphraseMu.Lock()
+	defer passphraseMu.Unlock()
+	pass, err := getPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(pass, enc.Salt, enc.Iterations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive the auth.json key: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(enc.Nonce) != gcm.NonceSize() {
+		return nil, errcode.Errorf(errcode.Integrity, "auth.json is damaged: invalid nonce")
+	}
+	plain, err := gcm.Open(nil, enc.Nonce, enc.Ciphertext, nil)
+	if err != nil {
+		return nil, errcode.Errorf(errcode.Auth, "failed to decrypt auth.json: wrong passphrase, or the file was modified")
+	}
+	return plain, nil
+}
+
+// encryptConfig encrypts a plain auth.json, with salt when it's the salt
+// of the file being replaced and a new one when it's nil
+func encryptConfig(plain, salt []byte) (*encryptedConfig, error) {
+	confirm := salt == nil
+	if salt == nil {
+		salt = make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+	}
+
+	passphraseMu.Lock()
+	defer passphraseMu.Unlock()
+	pass, err := getPassphrase(confirm)
+	if err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(pass, salt, kdfIterations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive the auth.json key: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return &encryptedConfig{
+		Encrypted:  cipherName,
+		KDF:        kdfName,
+		Iterations: kdfIterations,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plain, nil),
+	}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
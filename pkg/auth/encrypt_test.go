@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+)
+
+// newTestManager returns a Manager of auth.json in a temporary directory,
+// with the encryption settings and the passphrase cache reset until the
+// test ends
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	oldIterations := kdfIterations
+	kdfIterations = 1000
+	t.Setenv(PassphraseEnv, "")
+	t.Cleanup(func() {
+		kdfIterations = oldIterations
+		SetEncrypt(nil)
+		SetKeyCommand("")
+		SetPassphrasePrompt(nil)
+		forgetPassphrase()
+	})
+	forgetPassphrase()
+	return &Manager{configPath: filepath.Join(t.TempDir(), "auth.json")}
+}
+
+// forgetPassphrase clears the passphrase and keys a process keeps
+func forgetPassphrase() {
+	passphraseMu.Lock()
+	defer passphraseMu.Unlock()
+	passphrase = ""
+	derivedKeys = map[string][]byte{}
+}
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	m := newTestManager(t)
+	if err := m.Login("ghcr.io", "alice", "plain-secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	on := true
+	SetEncrypt(&on)
+	t.Setenv(PassphraseEnv, "correct horse")
+	if err := m.Login("registry.example.com", "bob", "other-secret"); err != nil {
+		t.Fatalf("Login with encryption failed: %v", err)
+	}
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leak := range []string{"alice", "bob", "ghcr.io", base64.StdEncoding.EncodeToString([]byte("alice:plain-secret"))} {
+		if strings.Contains(string(data), leak) {
+			t.Errorf("the encrypted auth.json contains %q:\n%s", leak, data)
+		}
+	}
+	if !strings.Contains(string(data), `"encrypted": "aes-256-gcm"`) {
+		t.Errorf("auth.json isn't encrypted:\n%s", data)
+	}
+
+	// A later process, without the setting: the file stays encrypted
+	SetEncrypt(nil)
+	forgetPassphrase()
+	if user, pass, err := m.GetCredentials("ghcr.io"); err != nil || user != "alice" || pass != "plain-secret" {
+		t.Errorf("GetCredentials = %s, %s, %v", user, pass, err)
+	}
+	if err := m.Logout("ghcr.io"); err != nil {
+		t.Fatal(err)
+	}
+	if encrypted, err := m.Encrypted(); err != nil || !encrypted {
+		t.Errorf("Encrypted() after Logout = %v, %v", encrypted, err)
+	}
+
+	off := false
+	SetEncrypt(&off)
+	if err := m.Rewrite(); err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	data, _ = os.ReadFile(m.configPath)
+	if !strings.Contains(string(data), `"registry.example.com"`) {
+		t.Errorf("auth.json wasn't decrypted:\n%s", data)
+	}
+}
+
+func TestEncryptedWrongPassphrase(t *testing.T) {
+	m := newTestManager(t)
+	on := true
+	SetEncrypt(&on)
+	t.Setenv(PassphraseEnv, "right")
+	if err := m.Login("ghcr.io", "alice", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(m.configPath)
+
+	forgetPassphrase()
+	t.Setenv(PassphraseEnv, "wrong")
+	if _, err := m.Registries(); errcode.Of(err) != errcode.Auth {
+		t.Errorf("Registries with the wrong passphrase = %v, want an auth error", err)
+	}
+	// Logging in to another registry mustn't replace the file
+	if err := m.Login("docker.io", "alice", "secret"); errcode.Of(err) != errcode.Auth {
+		t.Errorf("Login with the wrong passphrase = %v, want an auth error", err)
+	}
+	if after, _ := os.ReadFile(m.configPath); string(after) != string(before) {
+		t.Error("auth.json changed")
+	}
+
+	forgetPassphrase()
+	t.Setenv(PassphraseEnv, "")
+	if _, err := m.Registries(); errcode.Of(err) != errcode.Auth || !strings.Contains(err.Error(), PassphraseEnv) {
+		t.Errorf("Registries without a passphrase = %v, want an auth error naming %s", err, PassphraseEnv)
+	}
+}
+
+func TestEncryptedKeyCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the key command is a POSIX shell command")
+	}
+	m := newTestManager(t)
+	on := true
+	SetEncrypt(&on)
+	t.Setenv(PassphraseEnv, "ignored")
+	SetKeyCommand("printf 'from-command\\n'")
+	if err := m.Login("ghcr.io", "alice", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The key command's output, without the newline, is the passphrase
+	forgetPassphrase()
+	SetKeyCommand("")
+	t.Setenv(PassphraseEnv, "from-command")
+	if _, err := m.Username("ghcr.io"); err != nil {
+		t.Errorf("Username with the key command's passphrase failed: %v", err)
+	}
+
+	forgetPassphrase()
+	SetKeyCommand("exit 3")
+	if _, err := m.Username("ghcr.io"); errcode.Of(err) != errcode.Auth || !strings.Contains(err.Error(), "auth.key_command") {
+		t.Errorf("Username with a failing key command = %v", err)
+	}
+}
+
+func TestEncryptedPrompt(t *testing.T) {
+	m := newTestManager(t)
+	on := true
+	SetEncrypt(&on)
+	var confirms []bool
+	SetPassphrasePrompt(func(confirm bool) (string, error) {
+		confirms = append(confirms, confirm)
+		return "typed", nil
+	})
+	if err := m.Login("ghcr.io", "alice", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	forgetPassphrase()
+	if _, err := m.Registries(); err != nil {
+		t.Fatal(err)
+	}
+	// A new passphrase is confirmed; one opening the file isn't
+	if len(confirms) != 2 || !confirms[0] || confirms[1] {
+		t.Errorf("prompts confirming = %v, want [true false]", confirms)
+	}
+}
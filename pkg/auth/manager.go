@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -8,11 +9,19 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+	"github.com/aupeachmo/aigogo/pkg/userdirs"
 )
 
 type Manager struct {
 	configPath string
+	// salt is the key derivation salt of auth.json when it was read
+	// encrypted, reused when it's written back; nil when it's plain
+	salt []byte
 }
 
 type AuthConfig struct {
@@ -24,15 +33,18 @@ type AuthEntry struct {
 }
 
 func NewManager() *Manager {
-	home, _ := os.UserHomeDir()
-	configPath := filepath.Join(home, ".aigogo", "auth.json")
+	dir, _ := userdirs.ConfigDir()
+	configPath := filepath.Join(dir, "auth.json")
 	return &Manager{configPath: configPath}
 }
 
 // Login stores credentials for a registry
 func (m *Manager) Login(registry, username, password string) error {
 	config, err := m.loadConfig()
-	if err != nil {
+	if errcode.Of(err) == errcode.Auth {
+		// Starting over would drop the other registries' credentials
+		return err
+	} else if err != nil {
 		config = &AuthConfig{Auths: make(map[string]AuthEntry)}
 	}
 
@@ -56,11 +68,91 @@ func (m *Manager) Logout(registry string) error {
 	return m.saveConfig(config)
 }
 
+// LogoutAll removes the credentials of every registry and returns the
+// registries they were for
+func (m *Manager) LogoutAll() ([]string, error) {
+	registries, err := m.Registries()
+	if err != nil {
+		return nil, err
+	}
+	if len(registries) == 0 {
+		return nil, nil
+	}
+	if err := m.saveConfig(&AuthConfig{Auths: make(map[string]AuthEntry)}); err != nil {
+		return nil, err
+	}
+	return registries, nil
+}
+
+// Rewrite writes auth.json again, encrypted or not as SetEncrypt says, so
+// turning encryption on or off applies to stored credentials without
+// logging in again
+func (m *Manager) Rewrite() error {
+	config, err := m.loadConfig()
+	if err != nil {
+		return err
+	}
+	return m.saveConfig(config)
+}
+
+// Encrypted reports whether auth.json is, or would be once written,
+// encrypted, without needing its passphrase
+func (m *Manager) Encrypted() (bool, error) {
+	if encryptSetting != nil {
+		return *encryptSetting, nil
+	}
+	data, err := os.ReadFile(m.configPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	_, encrypted := isEncrypted(data)
+	return encrypted, nil
+}
+
+// Registries returns the registries with stored credentials, sorted
+func (m *Manager) Registries() ([]string, error) {
+	config, err := m.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	registries := make([]string, 0, len(config.Auths))
+	for registry := range config.Auths {
+		registries = append(registries, registry)
+	}
+	sort.Strings(registries)
+	return registries, nil
+}
+
+// Username returns the user whose credentials are stored for a registry,
+// without contacting it
+func (m *Manager) Username(registry string) (string, error) {
+	config, err := m.loadConfig()
+	if err != nil {
+		return "", err
+	}
+	entry, exists := config.Auths[registry]
+	if !exists {
+		return "", errcode.Errorf(errcode.Auth, "not logged in to %s", registry)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+	if err != nil {
+		return "", errcode.Errorf(errcode.Auth, "invalid auth token")
+	}
+	parts := splitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return "", errcode.Errorf(errcode.Auth, "invalid auth token format")
+	}
+	return parts[0], nil
+}
+
 // GetToken retrieves an auth token for a registry
 // For Docker Hub, exchanges credentials for OAuth2 token with repository scope
 // For other registries, returns base64 encoded username:password
-// repository is optional but required for Docker Hub to get proper scopes
-func (m *Manager) GetToken(registry, repository string) (string, error) {
+// repository is optional but required for Docker Hub to get proper scopes.
+// ctx bounds the Docker Hub token exchange.
+func (m *Manager) GetToken(ctx context.Context, registry, repository string) (string, error) {
 	config, err := m.loadConfig()
 	if err != nil {
 		return "", err
@@ -68,35 +160,35 @@ func (m *Manager) GetToken(registry, repository string) (string, error) {
 
 	entry, exists := config.Auths[registry]
 	if !exists {
-		return "", fmt.Errorf("not logged in to %s", registry)
+		return "", errcode.Errorf(errcode.Auth, "not logged in to %s", registry)
 	}
 
 	// For Docker Hub, exchange credentials for OAuth2 token
 	if registry == "docker.io" {
-		return m.getDockerHubToken(entry.Auth, repository)
+		return m.getDockerHubToken(ctx, entry.Auth, repository)
 	}
 
 	// For other registries, return base64 encoded credentials
 	// Decode to verify it's valid
 	_, err = base64.StdEncoding.DecodeString(entry.Auth)
 	if err != nil {
-		return "", fmt.Errorf("invalid auth token")
+		return "", errcode.Errorf(errcode.Auth, "invalid auth token")
 	}
 
 	return entry.Auth, nil
 }
 
 // getDockerHubToken exchanges Docker Hub credentials for an OAuth2 token
-func (m *Manager) getDockerHubToken(base64Auth, repository string) (string, error) {
+func (m *Manager) getDockerHubToken(ctx context.Context, base64Auth, repository string) (string, error) {
 	// Decode credentials
 	decoded, err := base64.StdEncoding.DecodeString(base64Auth)
 	if err != nil {
-		return "", fmt.Errorf("invalid auth token")
+		return "", errcode.Errorf(errcode.Auth, "invalid auth token")
 	}
 
 	parts := splitN(string(decoded), ":", 2)
 	if len(parts) != 2 {
-		return "", fmt.Errorf("invalid auth token format")
+		return "", errcode.Errorf(errcode.Auth, "invalid auth token format")
 	}
 
 	username, password := parts[0], parts[1]
@@ -115,7 +207,7 @@ func (m *Manager) getDockerHubToken(base64Auth, repository string) (string, erro
 
 	authURL := fmt.Sprintf("https://auth.docker.io/token?service=registry.docker.io&scope=%s", scope)
 
-	req, err := http.NewRequest("GET", authURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", authURL, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create auth request: %w", err)
 	}
@@ -123,7 +215,7 @@ func (m *Manager) getDockerHubToken(base64Auth, repository string) (string, erro
 	// Use Basic Auth
 	req.SetBasicAuth(username, password)
 
-	client := &http.Client{}
+	client := logging.NewHTTPClient(0)
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to authenticate with Docker Hub: %w", err)
@@ -132,7 +224,7 @@ func (m *Manager) getDockerHubToken(base64Auth, repository string) (string, erro
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("docker hub authentication failed: %s - %s", resp.Status, string(body))
+		return "", errcode.Errorf(errcode.FromHTTPStatus(resp.StatusCode), "docker hub authentication failed: %s - %s", resp.Status, string(body))
 	}
 
 	var tokenResponse struct {
@@ -150,27 +242,32 @@ func (m *Manager) getDockerHubToken(base64Auth, repository string) (string, erro
 	return tokenResponse.Token, nil
 }
 
-// GetCredentials returns the username and password for a registry
+// GetCredentials returns the username and password stored for a registry
 func (m *Manager) GetCredentials(registry string) (username, password string, err error) {
-	token, err := m.GetToken(registry, "")
+	config, err := m.loadConfig()
 	if err != nil {
 		return "", "", err
 	}
+	entry, exists := config.Auths[registry]
+	if !exists {
+		return "", "", errcode.Errorf(errcode.Auth, "not logged in to %s", registry)
+	}
 
-	decoded, err := base64.StdEncoding.DecodeString(token)
+	decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
 	if err != nil {
-		return "", "", fmt.Errorf("invalid auth token")
+		return "", "", errcode.Errorf(errcode.Auth, "invalid auth token")
 	}
 
 	parts := splitN(string(decoded), ":", 2)
 	if len(parts) != 2 {
-		return "", "", fmt.Errorf("invalid auth token format")
+		return "", "", errcode.Errorf(errcode.Auth, "invalid auth token format")
 	}
 
 	return parts[0], parts[1], nil
 }
 
 func (m *Manager) loadConfig() (*AuthConfig, error) {
+	m.salt = nil
 	data, err := os.ReadFile(m.configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -178,6 +275,12 @@ func (m *Manager) loadConfig() (*AuthConfig, error) {
 		}
 		return nil, err
 	}
+	if enc, ok := isEncrypted(data); ok {
+		if data, err = decryptConfig(enc); err != nil {
+			return nil, err
+		}
+		m.salt = enc.Salt
+	}
 
 	var config AuthConfig
 	if err := json.Unmarshal(data, &config); err != nil {
@@ -202,6 +305,22 @@ func (m *Manager) saveConfig(config *AuthConfig) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
+	encrypt := m.salt != nil
+	if encryptSetting != nil {
+		encrypt = *encryptSetting
+	}
+	if encrypt {
+		enc, err := encryptConfig(data, m.salt)
+		if err != nil {
+			return err
+		}
+		if data, err = json.MarshalIndent(enc, "", "  "); err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+		m.salt = enc.Salt
+	} else {
+		m.salt = nil
+	}
 
 	// Write with restricted permissions
 	if err := os.WriteFile(m.configPath, data, 0600); err != nil {
@@ -0,0 +1,426 @@
+// Package config reads aigg's configuration: config.toml in the config
+// directory (see userdirs) for the user, .aigogo/config.toml for a project checkout, and AIGOGO_* environment
+// variables. Besides the project settings (registry, namespace, store and
+// install), it holds settings of aigg itself, such as the cache directory,
+// concurrency and how registries are reached.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/userdirs"
+	"github.com/aupeachmo/aigogo/pkg/webhook"
+)
+
+// FileName is the name of the config file in the user's config directory
+// (~/.config/aigogo/ or ~/.aigogo/) and a project's .aigogo/
+const FileName = "config.toml"
+
+// Color settings: whether output is styled with ANSI colors
+const (
+	// ColorAuto styles output on a terminal, unless NO_COLOR is set; the
+	// default
+	ColorAuto = "auto"
+	// ColorAlways styles output even when it isn't a terminal
+	ColorAlways = "always"
+	// ColorNever never styles output
+	ColorNever = "never"
+)
+
+// Output settings: how output is formatted for what reads it
+const (
+	// OutputText is aigg's own output; the default
+	OutputText = "text"
+	// OutputGitHub adds GitHub Actions workflow commands: annotations for
+	// warnings, errors and findings, collapsible groups, and job summaries
+	OutputGitHub = "github"
+)
+
+// RetrySpec configures how failed registry requests are retried
+type RetrySpec struct {
+	Attempts int    `toml:"attempts,omitzero"` // Tries in all, the first included; 1 disables retries
+	Backoff  string `toml:"backoff,omitempty"` // Wait before the first retry, doubled for each later one, e.g. "500ms"
+}
+
+// AuthSpec configures how the credentials aigg login stores in auth.json
+// are kept. Only the user's config file and the environment set it: a
+// project checkout could otherwise run a command on every login or write the
+// credentials in the clear.
+type AuthSpec struct {
+	Encrypt    *bool  `toml:"encrypt,omitempty"`     // Encrypt auth.json with a passphrase; unset keeps it as it is
+	KeyCommand string `toml:"key_command,omitempty"` // Command printing the passphrase, e.g. age -d ~/.config/aigogo/auth-key.age
+}
+
+// Config is aigg's configuration from one source, or from all of them
+// merged. The project settings it embeds can also be set in the project's
+// aigogo.json and pyproject.toml.
+type Config struct {
+	manifest.Settings
+	Cache              string     `toml:"cache,omitempty"`               // Build/pull cache directory, ~ for the home directory
+	Color              string     `toml:"color,omitempty"`               // auto (default), always or never
+	Plain              *bool      `toml:"plain,omitempty"`               // ASCII markers in place of emoji and symbols
+	Output             string     `toml:"output,omitempty"`              // text (default) or github
+	Concurrency        int        `toml:"concurrency,omitzero"`          // Packages install fetches at once
+	InsecureRegistries []string   `toml:"insecure_registries,omitempty"` // Registries reached over plain HTTP, e.g. localhost:5000
+	Retry              *RetrySpec `toml:"retry,omitempty"`               // Retries of failed registry requests
+	LogFile            string     `toml:"log_file,omitempty"`            // File every command appends its debug log to, ~ for the home directory
+	History            *bool      `toml:"history,omitempty"`             // Record adds, installs, builds, pushes and deletes in history.jsonl
+	Auth               *AuthSpec  `toml:"auth,omitempty"`                // How auth.json is encrypted
+
+	// Webhooks are notified after aigg push and aigg delete, as
+	// [[webhooks]] tables; aigg config get/set don't set them. Only the
+	// user's config file sets them: $VARS are expanded in their URLs and
+	// headers, so a project checkout's hooks could send the user's secrets
+	// anywhere.
+	Webhooks []webhook.Hook `toml:"webhooks,omitempty"`
+}
+
+// Layer is the configuration from one source
+type Layer struct {
+	Source string // The file it was read from, or "environment"
+	Config *Config
+}
+
+// UserPath returns the path of the user's config file, config.toml in the
+// config directory: ~/.config/aigogo/config.toml or ~/.aigogo/config.toml
+func UserPath() (string, error) {
+	dir, err := userdirs.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// ProjectPath returns the path of the config file of the project in
+// projectDir, .aigogo/config.toml
+func ProjectPath(projectDir string) string {
+	return filepath.Join(projectDir, ".aigogo", FileName)
+}
+
+// FindProjectDir returns the directory of the project around startDir, the
+// nearest with an aigogo.lock, aigogo.json or .aigogo/config.toml, or ""
+// outside a project. ~/.aigogo/config.toml may be the user's config file,
+// so the home directory is only a project with one of the others.
+func FindProjectDir(startDir string) string {
+	home, _ := os.UserHomeDir()
+	dir := startDir
+	for {
+		markers := []string{filepath.Join(dir, lockfile.LockFileName), filepath.Join(dir, "aigogo.json")}
+		if dir != home {
+			markers = append(markers, ProjectPath(dir))
+		}
+		for _, marker := range markers {
+			if _, err := os.Stat(marker); err == nil {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Load returns the configuration layers of the project in projectDir, lowest
+// precedence first: the user's config file, the project's settings from
+// aigogo.json and pyproject.toml, the project's config file, and the
+// environment. Relative directories are resolved against the home directory
+// in the user's config file and against projectDir in the project's. Outside
+// a project, projectDir is empty and only the user's config file and the
+// environment are read.
+func Load(projectDir string) ([]Layer, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	userPath, err := UserPath()
+	if err != nil {
+		return nil, err
+	}
+	user, err := loadResolved(userPath, home)
+	if err != nil {
+		return nil, err
+	}
+	layers := []Layer{{Source: userPath, Config: user}}
+
+	if projectDir != "" {
+		settings, err := manifest.LoadSettings(projectDir)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, Layer{Source: "aigogo.json / pyproject.toml", Config: &Config{Settings: *settings}})
+
+		projectPath := ProjectPath(projectDir)
+		project, err := loadResolved(projectPath, projectDir)
+		if err != nil {
+			return nil, err
+		}
+		// See UserOnly and Webhooks
+		project.Auth = nil
+		project.InsecureRegistries = nil
+		project.Webhooks = nil
+		layers = append(layers, Layer{Source: projectPath, Config: project})
+	}
+
+	env, err := FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return append(layers, Layer{Source: "environment", Config: env}), nil
+}
+
+// Merge returns the configuration of layers, where a setting takes the value
+// of the last layer that sets it
+func Merge(layers []Layer) *Config {
+	merged := &Config{}
+	for _, l := range layers {
+		merged.Override(l.Config)
+	}
+	return merged
+}
+
+// LoadFile reads a config file as written, without resolving its
+// directories. A missing file is an empty configuration.
+func LoadFile(path string) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	meta, err := toml.Decode(string(data), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		var unknown []string
+		for _, key := range undecoded {
+			unknown = append(unknown, key.String())
+		}
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%s: unknown settings %s", path, strings.Join(unknown, ", "))
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Save writes cfg to path, creating its directory
+func (c *Config) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	// Empty tables would be written as headers without keys
+	if ns := c.Namespace; ns != nil && ns.Python == "" && ns.JavaScript == "" {
+		c.Namespace = nil
+	}
+	if in := c.Install; in != nil && in.Mode == "" && in.PythonLayout == "" {
+		c.Install = nil
+	}
+	if p := c.TagPolicy; p != nil && p.Mutable == "" && p.Overwrite == "" {
+		c.TagPolicy = nil
+	}
+	if r := c.Retry; r != nil && r.Attempts == 0 && r.Backoff == "" {
+		c.Retry = nil
+	}
+	if a := c.Auth; a != nil && a.Encrypt == nil && a.KeyCommand == "" {
+		c.Auth = nil
+	}
+
+	var b strings.Builder
+	enc := toml.NewEncoder(&b)
+	enc.Indent = ""
+	if err := enc.Encode(c); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadResolved reads a config file and resolves its directories against dir
+func loadResolved(path, dir string) (*Config, error) {
+	cfg, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.resolve(dir); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// resolve makes the store and cache directories and the log file absolute
+func (c *Config) resolve(dir string) error {
+	for _, path := range []*string{&c.Store, &c.Cache, &c.LogFile} {
+		if *path == "" {
+			continue
+		}
+		resolved, err := manifest.ResolvePath(*path, dir)
+		if err != nil {
+			return err
+		}
+		*path = resolved
+	}
+	return nil
+}
+
+// Override sets each setting that other sets
+func (c *Config) Override(other *Config) {
+	c.Settings.Override(&other.Settings)
+	if other.Cache != "" {
+		c.Cache = other.Cache
+	}
+	if other.Color != "" {
+		c.Color = other.Color
+	}
+	if other.Plain != nil {
+		c.Plain = other.Plain
+	}
+	if other.Output != "" {
+		c.Output = other.Output
+	}
+	if other.History != nil {
+		c.History = other.History
+	}
+	if other.Concurrency != 0 {
+		c.Concurrency = other.Concurrency
+	}
+	if other.InsecureRegistries != nil {
+		c.InsecureRegistries = other.InsecureRegistries
+	}
+	if other.Retry != nil {
+		if c.Retry == nil {
+			c.Retry = &RetrySpec{}
+		}
+		if other.Retry.Attempts != 0 {
+			c.Retry.Attempts = other.Retry.Attempts
+		}
+		if other.Retry.Backoff != "" {
+			c.Retry.Backoff = other.Retry.Backoff
+		}
+	}
+	if other.LogFile != "" {
+		c.LogFile = other.LogFile
+	}
+	if other.Auth != nil {
+		if c.Auth == nil {
+			c.Auth = &AuthSpec{}
+		}
+		if other.Auth.Encrypt != nil {
+			c.Auth.Encrypt = other.Auth.Encrypt
+		}
+		if other.Auth.KeyCommand != "" {
+			c.Auth.KeyCommand = other.Auth.KeyCommand
+		}
+	}
+	if other.Webhooks != nil {
+		c.Webhooks = other.Webhooks
+	}
+}
+
+// Validate checks the project settings, and that the color, output, concurrency,
+// insecure registries, retry policy and webhooks are usable
+func (c *Config) Validate() error {
+	if err := c.Settings.Validate(); err != nil {
+		return err
+	}
+	switch c.Color {
+	case "", ColorAuto, ColorAlways, ColorNever:
+	default:
+		return fmt.Errorf("invalid color: %s (expected %s, %s or %s)", c.Color, ColorAuto, ColorAlways, ColorNever)
+	}
+	switch c.Output {
+	case "", OutputText, OutputGitHub:
+	default:
+		return fmt.Errorf("invalid output: %s (expected %s or %s)", c.Output, OutputText, OutputGitHub)
+	}
+	if c.Concurrency < 0 {
+		return fmt.Errorf("invalid concurrency: %d (expected a positive number)", c.Concurrency)
+	}
+	for _, r := range c.InsecureRegistries {
+		if r == "" || strings.Contains(r, "://") || strings.ContainsAny(r, "/ \t") {
+			return fmt.Errorf("invalid insecure_registries entry: %q (expected a registry host, e.g. localhost:5000)", r)
+		}
+	}
+	if c.Retry != nil {
+		if c.Retry.Attempts < 0 {
+			return fmt.Errorf("invalid retry.attempts: %d (expected a positive number)", c.Retry.Attempts)
+		}
+		if c.Retry.Backoff != "" {
+			if d, err := time.ParseDuration(c.Retry.Backoff); err != nil || d < 0 {
+				return fmt.Errorf("invalid retry.backoff: %s (expected a duration, e.g. 500ms)", c.Retry.Backoff)
+			}
+		}
+	}
+	for i := range c.Webhooks {
+		if err := c.Webhooks[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RetryPolicy returns how many times to try a registry request and how long
+// to wait before the first retry, defaulting to 3 tries and 500ms
+func (c *Config) RetryPolicy() (attempts int, backoff time.Duration) {
+	attempts, backoff = 3, 500*time.Millisecond
+	if c.Retry == nil {
+		return attempts, backoff
+	}
+	if c.Retry.Attempts > 0 {
+		attempts = c.Retry.Attempts
+	}
+	if d, err := time.ParseDuration(c.Retry.Backoff); err == nil {
+		backoff = d
+	}
+	return attempts, backoff
+}
+
+// ColorMode returns the color setting, defaulting to auto
+func (c *Config) ColorMode() string {
+	if c.Color == "" {
+		return ColorAuto
+	}
+	return c.Color
+}
+
+// OutputFormat returns the output setting, defaulting to text
+func (c *Config) OutputFormat() string {
+	if c.Output == "" {
+		return OutputText
+	}
+	return c.Output
+}
+
+// PlainOutput reports whether output uses ASCII markers in place of emoji
+// and symbols; off unless set
+func (c *Config) PlainOutput() bool {
+	return c.Plain != nil && *c.Plain
+}
+
+// AuthSettings returns the auth table, empty when it isn't set
+func (c *Config) AuthSettings() AuthSpec {
+	if c.Auth == nil {
+		return AuthSpec{}
+	}
+	return *c.Auth
+}
+
+// HistoryEnabled reports whether commands are recorded in the history; on
+// unless set to false
+func (c *Config) HistoryEnabled() bool {
+	return c.History == nil || *c.History
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	for _, k := range Keys() {
+		t.Setenv(EnvVar(k), "")
+	}
+	projectDir := t.TempDir()
+
+	writeFile(t, filepath.Join(home, ".aigogo", FileName), `
+registry = "ghcr.io/user"
+store = "aigogo-store"
+history = false
+concurrency = 8
+insecure_registries = ["localhost:5000"]
+
+[namespace]
+python = "user.snippets"
+
+[retry]
+attempts = 5
+backoff = "1s"
+
+[auth]
+encrypt = true
+key_command = "age -d auth-key.age"
+
+[[webhooks]]
+url = "https://hooks.example.com/user"
+`)
+	writeFile(t, filepath.Join(projectDir, "aigogo.json"), `{"registry": "ghcr.io/project", "namespace": {"javascript": "@project"}}`)
+	writeFile(t, ProjectPath(projectDir), "cache = \"cache\"\ncolor = \"never\"\nplain = true\noutput = \"github\"\nlog_file = \"logs/aigg.log\"\n"+
+		"insecure_registries = [\"registry.example.com\"]\n"+
+		"[auth]\nencrypt = false\nkey_command = \"./steal.sh\"\n"+
+		"[[webhooks]]\nurl = \"$SLACK_WEBHOOK\"\nevents = [\"push\"]\ntemplate = '{\"text\": {{json .Summary}}}'\n")
+	t.Setenv("AIGOGO_RETRY_ATTEMPTS", "2")
+
+	layers, err := Load(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 4 {
+		t.Fatalf("got %d layers, want 4", len(layers))
+	}
+	cfg := Merge(layers)
+
+	want := map[string]string{
+		"registry":             "ghcr.io/project",
+		"namespace.python":     "user.snippets",
+		"namespace.javascript": "@project",
+		"store":                filepath.Join(home, "aigogo-store"),
+		"cache":                filepath.Join(projectDir, "cache"),
+		"color":                "never",
+		"plain":                "true",
+		"output":               "github",
+		"concurrency":          "8",
+		"retry.attempts":       "2",
+		"retry.backoff":        "1s",
+		"log_file":             filepath.Join(projectDir, "logs", "aigg.log"),
+		"history":              "false",
+		// The project's auth table and insecure_registries are ignored
+		"auth.encrypt":        "true",
+		"auth.key_command":    "age -d auth-key.age",
+		"insecure_registries": "localhost:5000",
+	}
+	for name, value := range want {
+		if got, _ := cfg.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if cfg.HistoryEnabled() {
+		t.Error("HistoryEnabled() = true with history = false")
+	}
+	if attempts, backoff := cfg.RetryPolicy(); attempts != 2 || backoff != time.Second {
+		t.Errorf("RetryPolicy() = %d, %s", attempts, backoff)
+	}
+	// The project's webhooks are ignored
+	if len(cfg.Webhooks) != 1 || cfg.Webhooks[0].URL != "https://hooks.example.com/user" {
+		t.Errorf("webhooks = %+v", cfg.Webhooks)
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"unknown settings colour":        `colour = "never"`,
+		"invalid color: sometimes":       `color = "sometimes"`,
+		"invalid output: gitlab":         `output = "gitlab"`,
+		"invalid concurrency":            `concurrency = -1`,
+		`last key "plain"`:               `plain = "yes"`,
+		"invalid retry.backoff: soon":    "[retry]\nbackoff = \"soon\"",
+		"invalid insecure_registries":    `insecure_registries = ["http://localhost:5000"]`,
+		"invalid namespace.javascript: ": "[namespace]\njavascript = \"ourco\"",
+		"invalid webhooks url":           "[[webhooks]]\nurl = \"hooks.example.com\"",
+		"invalid webhooks event":         "[[webhooks]]\nurl = \"https://hooks.example.com\"\nevents = [\"pull\"]",
+		"invalid webhooks template":      "[[webhooks]]\nurl = \"https://hooks.example.com\"\ntemplate = \"{{.Ref\"",
+	}
+	for want, content := range tests {
+		path := filepath.Join(dir, FileName)
+		writeFile(t, path, content)
+		if _, err := LoadFile(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadFile(%q) error = %v, want %q", content, err, want)
+		}
+	}
+}
+
+func TestSetAndSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".aigogo", FileName)
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range map[string]string{
+		"registry":            "ghcr.io/ourco",
+		"insecure_registries": "localhost:5000, registry.local",
+		"retry.attempts":      "4",
+		"namespace.python":    "ourco",
+	} {
+		if err := cfg.Set(name, value); err != nil {
+			t.Fatalf("Set(%s) failed: %v", name, err)
+		}
+	}
+	// An empty value unsets the setting, and its table when it empties it
+	if err := cfg.Set("namespace.python", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "namespace") {
+		t.Errorf("saved an empty namespace table:\n%s", data)
+	}
+	saved, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Registry != "ghcr.io/ourco" || saved.Retry == nil || saved.Retry.Attempts != 4 {
+		t.Errorf("saved config = %+v", saved)
+	}
+	if !reflect.DeepEqual(saved.InsecureRegistries, []string{"localhost:5000", "registry.local"}) {
+		t.Errorf("insecure_registries = %q", saved.InsecureRegistries)
+	}
+
+	if err := cfg.Set("colour", "never"); err == nil || !strings.Contains(err.Error(), "unknown setting") {
+		t.Errorf("Set(colour) error = %v", err)
+	}
+	if err := cfg.Set("concurrency", "many"); err == nil {
+		t.Error("expected a non-numeric concurrency to fail")
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	for _, k := range Keys() {
+		t.Setenv(EnvVar(k), "")
+	}
+	t.Setenv("AIGOGO_INSTALL_MODE", "copy")
+	t.Setenv("AIGOGO_INSECURE_REGISTRIES", "localhost:5000")
+	cfg, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.InstallMode() != "copy" || len(cfg.InsecureRegistries) != 1 {
+		t.Errorf("FromEnv() = %+v", cfg)
+	}
+
+	t.Setenv("AIGOGO_COLOR", "sometimes")
+	if _, err := FromEnv(); err == nil || !strings.Contains(err.Error(), "invalid color") {
+		t.Errorf("FromEnv() error = %v", err)
+	}
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// key is a setting addressable by aigg config get/set and an environment
+// variable
+type key struct {
+	name string
+	get  func(c *Config) string
+	set  func(c *Config, value string) error // An empty value unsets it
+}
+
+// keys are the settings in the order aigg config get lists them
+var keys = []key{
+	{"registry",
+		func(c *Config) string { return c.Registry },
+		func(c *Config, v string) error { c.Registry = v; return nil }},
+	{"namespace.python",
+		func(c *Config) string {
+			if c.Namespace == nil {
+				return ""
+			}
+			return c.Namespace.Python
+		},
+		func(c *Config, v string) error { namespace(c).Python = v; return nil }},
+	{"namespace.javascript",
+		func(c *Config) string {
+			if c.Namespace == nil {
+				return ""
+			}
+			return c.Namespace.JavaScript
+		},
+		func(c *Config, v string) error { namespace(c).JavaScript = v; return nil }},
+	{"store",
+		func(c *Config) string { return c.Store },
+		func(c *Config, v string) error { c.Store = v; return nil }},
+	{"install.mode",
+		func(c *Config) string {
+			if c.Install == nil {
+				return ""
+			}
+			return c.Install.Mode
+		},
+		func(c *Config, v string) error { install(c).Mode = v; return nil }},
+	{"install.python_layout",
+		func(c *Config) string {
+			if c.Install == nil {
+				return ""
+			}
+			return c.Install.PythonLayout
+		},
+		func(c *Config, v string) error { install(c).PythonLayout = v; return nil }},
+	{"tag_policy.mutable",
+		func(c *Config) string {
+			if c.TagPolicy == nil {
+				return ""
+			}
+			return c.TagPolicy.Mutable
+		},
+		func(c *Config, v string) error { tagPolicy(c).Mutable = v; return nil }},
+	{"tag_policy.overwrite",
+		func(c *Config) string {
+			if c.TagPolicy == nil {
+				return ""
+			}
+			return c.TagPolicy.Overwrite
+		},
+		func(c *Config, v string) error { tagPolicy(c).Overwrite = v; return nil }},
+	{"cache",
+		func(c *Config) string { return c.Cache },
+		func(c *Config, v string) error { c.Cache = v; return nil }},
+	{"color",
+		func(c *Config) string { return c.Color },
+		func(c *Config, v string) error { c.Color = v; return nil }},
+	{"plain",
+		func(c *Config) string { return formatBool(c.Plain) },
+		func(c *Config, v string) error { return parseBool(v, "plain", &c.Plain) }},
+	{"output",
+		func(c *Config) string { return c.Output },
+		func(c *Config, v string) error { c.Output = v; return nil }},
+	{"concurrency",
+		func(c *Config) string { return formatInt(c.Concurrency) },
+		func(c *Config, v string) error { return parseInt(v, "concurrency", &c.Concurrency) }},
+	{"insecure_registries",
+		func(c *Config) string { return strings.Join(c.InsecureRegistries, ",") },
+		func(c *Config, v string) error {
+			c.InsecureRegistries = nil
+			for _, r := range strings.Split(v, ",") {
+				if r = strings.TrimSpace(r); r != "" {
+					c.InsecureRegistries = append(c.InsecureRegistries, r)
+				}
+			}
+			return nil
+		}},
+	{"retry.attempts",
+		func(c *Config) string {
+			if c.Retry == nil {
+				return ""
+			}
+			return formatInt(c.Retry.Attempts)
+		},
+		func(c *Config, v string) error { return parseInt(v, "retry.attempts", &retry(c).Attempts) }},
+	{"retry.backoff",
+		func(c *Config) string {
+			if c.Retry == nil {
+				return ""
+			}
+			return c.Retry.Backoff
+		},
+		func(c *Config, v string) error { retry(c).Backoff = v; return nil }},
+	{"log_file",
+		func(c *Config) string { return c.LogFile },
+		func(c *Config, v string) error { c.LogFile = v; return nil }},
+	{"history",
+		func(c *Config) string { return formatBool(c.History) },
+		func(c *Config, v string) error { return parseBool(v, "history", &c.History) }},
+	{"auth.encrypt",
+		func(c *Config) string {
+			if c.Auth == nil {
+				return ""
+			}
+			return formatBool(c.Auth.Encrypt)
+		},
+		func(c *Config, v string) error { return parseBool(v, "auth.encrypt", &authSpec(c).Encrypt) }},
+	{"auth.key_command",
+		func(c *Config) string {
+			if c.Auth == nil {
+				return ""
+			}
+			return c.Auth.KeyCommand
+		},
+		func(c *Config, v string) error { authSpec(c).KeyCommand = v; return nil }},
+}
+
+// Keys returns the names of the settings aigg config get/set take
+func Keys() []string {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k.name
+	}
+	return names
+}
+
+// EnvVar returns the environment variable overriding a setting, such as
+// AIGOGO_RETRY_ATTEMPTS for retry.attempts
+func EnvVar(name string) string {
+	return "AIGOGO_" + strings.ToUpper(strings.ReplaceAll(name, ".", "_"))
+}
+
+// Get returns the value of the setting name, or "" when it isn't set.
+// insecure_registries is a comma-separated list.
+func (c *Config) Get(name string) (string, error) {
+	k, err := lookup(name)
+	if err != nil {
+		return "", err
+	}
+	return k.get(c), nil
+}
+
+// Set sets the setting name to value, or unsets it when value is empty,
+// and checks the result
+func (c *Config) Set(name, value string) error {
+	k, err := lookup(name)
+	if err != nil {
+		return err
+	}
+	if err := k.set(c, value); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// FromEnv returns the settings set by AIGOGO_* environment variables, with
+// relative directories resolved against the working directory
+func FromEnv() (*Config, error) {
+	cfg := &Config{}
+	for _, k := range keys {
+		value := os.Getenv(EnvVar(k.name))
+		if value == "" {
+			continue
+		}
+		if err := k.set(cfg, value); err != nil {
+			return nil, fmt.Errorf("%s: %w", EnvVar(k.name), err)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("environment: %w", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.resolve(wd); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// lookup returns the setting called name
+func lookup(name string) (key, error) {
+	for _, k := range keys {
+		if k.name == name {
+			return k, nil
+		}
+	}
+	return key{}, fmt.Errorf("unknown setting: %s (expected one of %s)", name, strings.Join(Keys(), ", "))
+}
+
+// namespace returns c's namespace table, adding it if needed
+func namespace(c *Config) *manifest.NamespaceSpec {
+	if c.Namespace == nil {
+		c.Namespace = &manifest.NamespaceSpec{}
+	}
+	return c.Namespace
+}
+
+// install returns c's install table, adding it if needed
+func install(c *Config) *manifest.InstallSpec {
+	if c.Install == nil {
+		c.Install = &manifest.InstallSpec{}
+	}
+	return c.Install
+}
+
+// tagPolicy returns c's tag_policy table, adding it if needed
+func tagPolicy(c *Config) *manifest.TagPolicySpec {
+	if c.TagPolicy == nil {
+		c.TagPolicy = &manifest.TagPolicySpec{}
+	}
+	return c.TagPolicy
+}
+
+// retry returns c's retry table, adding it if needed
+func retry(c *Config) *RetrySpec {
+	if c.Retry == nil {
+		c.Retry = &RetrySpec{}
+	}
+	return c.Retry
+}
+
+// authSpec returns c's auth table, adding it if needed
+func authSpec(c *Config) *AuthSpec {
+	if c.Auth == nil {
+		c.Auth = &AuthSpec{}
+	}
+	return c.Auth
+}
+
+// UserOnly reports whether the setting name applies to the user only, so
+// a project's .aigogo/config.toml can't set it (see AuthSpec; a project
+// with insecure_registries could have credentials sent over plain HTTP)
+func UserOnly(name string) bool {
+	return name == "insecure_registries" || strings.HasPrefix(name, "auth.")
+}
+
+// formatInt formats n, with 0 (unset) as ""
+func formatInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// formatBool formats a boolean setting, "" when it isn't set
+func formatBool(b *bool) string {
+	if b == nil {
+		return ""
+	}
+	return strconv.FormatBool(*b)
+}
+
+// parseBool sets *b to v, or to nil (unset) when v is empty
+func parseBool(v, name string, b **bool) error {
+	if v == "" {
+		*b = nil
+		return nil
+	}
+	parsed, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %s (expected true or false)", name, v)
+	}
+	*b = &parsed
+	return nil
+}
+
+// parseInt sets *n to v, or to 0 (unset) when v is empty
+func parseInt(v, name string, n *int) error {
+	if v == "" {
+		*n = 0
+		return nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %s (expected a number)", name, v)
+	}
+	*n = i
+	return nil
+}
@@ -0,0 +1,711 @@
+package depgen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// PackageDependencies lists the runtime dependencies declared by one package
+type PackageDependencies struct {
+	Package      string
+	Language     string
+	Dependencies []manifest.Dependency
+}
+
+// ConstraintSource is one package's constraint on a shared dependency
+type ConstraintSource struct {
+	Package string `json:"package"`
+	Version string `json:"version"`
+}
+
+// DependencyConflict is a dependency whose constraints from different
+// packages cannot all be satisfied by a single version
+type DependencyConflict struct {
+	Dependency  string             `json:"dependency"`
+	Language    string             `json:"language"`
+	Constraints []ConstraintSource `json:"constraints"`
+	Resolution  string             `json:"resolution"`
+}
+
+// Involves reports whether the conflict is over dependency dep, in language
+// lang, and pkg is one of the packages whose constraints clash
+func (c DependencyConflict) Involves(pkg, lang, dep string) bool {
+	lang = conflictLanguage(lang)
+	if lang != c.Language || normalizeDependencyName(lang, dep) != normalizeDependencyName(lang, c.Dependency) {
+		return false
+	}
+	for _, source := range c.Constraints {
+		if source.Package == pkg {
+			return true
+		}
+	}
+	return false
+}
+
+// ConflictReport is the result of FindConflicts
+type ConflictReport struct {
+	Conflicts []DependencyConflict
+	Unchecked []string // shared dependencies with constraints that could not be compared
+}
+
+// FindConflicts intersects the version constraints that several packages
+// place on the same dependency and reports the dependencies no single
+// version satisfies. Dependencies are matched per language using the
+// language's name normalization ("Foo_Bar" and "foo-bar" are one Python
+// package). Constraints that cannot be parsed, such as npm "||" unions, are
+// listed in Unchecked rather than guessed at.
+func FindConflicts(pkgs []PackageDependencies) *ConflictReport {
+	type group struct {
+		name        string
+		language    string
+		constraints []ConstraintSource
+	}
+
+	groups := make(map[string]*group)
+	var keys []string
+	for _, pkg := range pkgs {
+		lang := conflictLanguage(pkg.Language)
+		for _, dep := range pkg.Dependencies {
+			// Marker-qualified requirements only apply in some
+			// environments, so they can't be intersected with the rest
+			if dep.Marker != "" {
+				continue
+			}
+			key := lang + "\x00" + normalizeDependencyName(lang, dep.Package)
+			g, ok := groups[key]
+			if !ok {
+				g = &group{name: dep.Package, language: lang}
+				groups[key] = g
+				keys = append(keys, key)
+			}
+			g.constraints = append(g.constraints, ConstraintSource{Package: pkg.Package, Version: dep.Version})
+		}
+	}
+	sort.Strings(keys)
+
+	report := &ConflictReport{}
+	for _, key := range keys {
+		g := groups[key]
+		if len(g.constraints) < 2 {
+			continue
+		}
+
+		combined := anyVersion()
+		ranges := make([]versionRange, len(g.constraints))
+		parsed := true
+		for i, c := range g.constraints {
+			r, ok := parseConstraint(g.language, c.Version)
+			if !ok {
+				report.Unchecked = append(report.Unchecked,
+					fmt.Sprintf("%s: cannot compare constraint %q from %s", g.name, c.Version, c.Package))
+				parsed = false
+				break
+			}
+			ranges[i] = r
+			combined = combined.intersect(r)
+		}
+		if !parsed || !combined.empty() {
+			continue
+		}
+
+		report.Conflicts = append(report.Conflicts, DependencyConflict{
+			Dependency:  g.name,
+			Language:    g.language,
+			Constraints: g.constraints,
+			Resolution:  suggestResolution(g.name, g.constraints, ranges),
+		})
+	}
+	return report
+}
+
+// suggestResolution names the package holding the lowest upper bound, which
+// is usually the one that has fallen behind, and the constraint it needs to
+// accept
+func suggestResolution(dep string, constraints []ConstraintSource, ranges []versionRange) string {
+	low, high := -1, -1
+	for i, r := range ranges {
+		if r.upper != nil && (low == -1 || ranges[low].upper == nil || tighterUpper(r.upper, ranges[low].upper)) {
+			low = i
+		}
+		if r.lower != nil && (high == -1 || tighterLower(r.lower, ranges[high].lower)) {
+			high = i
+		}
+	}
+	if low == -1 || high == -1 || low == high {
+		return fmt.Sprintf("Align the %s constraints so that one version satisfies every package", dep)
+	}
+
+	stale, wanted := constraints[low], constraints[high]
+	return fmt.Sprintf("Update %s (requires %s %s) to a release compatible with %s %s required by %s, or use an older %s that accepts %s %s",
+		stale.Package, dep, stale.Version, dep, wanted.Version, wanted.Package, wanted.Package, dep, stale.Version)
+}
+
+// conflictLanguage maps language aliases onto the name constraints are
+// parsed with
+func conflictLanguage(lang string) string {
+	switch strings.ToLower(lang) {
+	case "typescript", "ts", "js":
+		return "javascript"
+	}
+	return strings.ToLower(lang)
+}
+
+// normalizeDependencyName returns the key under which two spellings of a
+// dependency name are the same package
+func normalizeDependencyName(lang, name string) string {
+	switch lang {
+	case "python":
+		// PEP 503
+		return strings.NewReplacer("_", "-", ".", "-").Replace(strings.ToLower(name))
+	case "php", "csharp", "ruby":
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// Version ranges
+
+// versionNumber is a release version as numeric segments. Pre-release and
+// build suffixes are ignored, which is precise enough to find ranges that
+// cannot overlap.
+type versionNumber []int
+
+// versionBound is one end of a range
+type versionBound struct {
+	version   versionNumber
+	inclusive bool
+}
+
+// versionRange is the set of versions between two optional bounds, minus
+// any excluded versions
+type versionRange struct {
+	lower    *versionBound
+	upper    *versionBound
+	excluded []versionNumber
+}
+
+func anyVersion() versionRange {
+	return versionRange{}
+}
+
+func atLeast(v versionNumber, inclusive bool) versionRange {
+	return versionRange{lower: &versionBound{version: v, inclusive: inclusive}}
+}
+
+func below(v versionNumber, inclusive bool) versionRange {
+	return versionRange{upper: &versionBound{version: v, inclusive: inclusive}}
+}
+
+func between(lower, upper versionNumber) versionRange {
+	return versionRange{
+		lower: &versionBound{version: lower, inclusive: true},
+		upper: &versionBound{version: upper},
+	}
+}
+
+func exactly(v versionNumber) versionRange {
+	return versionRange{
+		lower: &versionBound{version: v, inclusive: true},
+		upper: &versionBound{version: v, inclusive: true},
+	}
+}
+
+// intersect returns the versions in both r and o
+func (r versionRange) intersect(o versionRange) versionRange {
+	result := versionRange{
+		lower:    r.lower,
+		upper:    r.upper,
+		excluded: append(append([]versionNumber{}, r.excluded...), o.excluded...),
+	}
+	if o.lower != nil && (result.lower == nil || tighterLower(o.lower, result.lower)) {
+		result.lower = o.lower
+	}
+	if o.upper != nil && (result.upper == nil || tighterUpper(o.upper, result.upper)) {
+		result.upper = o.upper
+	}
+	return result
+}
+
+// contains reports whether v is in the range
+func (r versionRange) contains(v versionNumber) bool {
+	if r.lower != nil {
+		c := compareVersionNumbers(v, r.lower.version)
+		if c < 0 || (c == 0 && !r.lower.inclusive) {
+			return false
+		}
+	}
+	if r.upper != nil {
+		c := compareVersionNumbers(v, r.upper.version)
+		if c > 0 || (c == 0 && !r.upper.inclusive) {
+			return false
+		}
+	}
+	for _, ex := range r.excluded {
+		if compareVersionNumbers(v, ex) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// VersionSatisfies reports whether version is in the range of constraint,
+// written in the syntax of lang. ok is false when either can't be parsed.
+func VersionSatisfies(lang, constraint, version string) (satisfied, ok bool) {
+	r, ok := parseConstraint(conflictLanguage(lang), constraint)
+	if !ok {
+		return false, false
+	}
+	v, ok := parseVersionNumber(version)
+	if !ok {
+		return false, false
+	}
+	return r.contains(v), true
+}
+
+// empty reports whether no version is in the range
+func (r versionRange) empty() bool {
+	if r.lower == nil || r.upper == nil {
+		return false
+	}
+	switch c := compareVersionNumbers(r.lower.version, r.upper.version); {
+	case c > 0:
+		return true
+	case c == 0:
+		if !r.lower.inclusive || !r.upper.inclusive {
+			return true
+		}
+		for _, ex := range r.excluded {
+			if compareVersionNumbers(ex, r.lower.version) == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func tighterLower(a, b *versionBound) bool {
+	c := compareVersionNumbers(a.version, b.version)
+	return c > 0 || (c == 0 && !a.inclusive)
+}
+
+func tighterUpper(a, b *versionBound) bool {
+	c := compareVersionNumbers(a.version, b.version)
+	return c < 0 || (c == 0 && !a.inclusive)
+}
+
+// compareVersionNumbers compares a and b, treating missing trailing
+// segments as zero
+func compareVersionNumbers(a, b versionNumber) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// parseVersionNumber parses "1.2.3", "v1.2" or "2.0.0-rc.1". Wildcard
+// segments are not accepted; see parseWildcard.
+func parseVersionNumber(s string) (versionNumber, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if idx := strings.IndexAny(s, "-+"); idx != -1 {
+		s = s[:idx]
+	}
+	if s == "" {
+		return nil, false
+	}
+	var v versionNumber
+	for _, segment := range strings.Split(s, ".") {
+		if segment == "" || segment[0] < '0' || segment[0] > '9' {
+			return nil, false
+		}
+		// Python pre-releases ("1.0rc1") keep their numeric prefix
+		v = append(v, leadingInt(segment))
+	}
+	return v, true
+}
+
+// bump returns v truncated to i+1 segments with segment i incremented:
+// bump(1.2.3, 0) is 2, bump(1.2.3, 1) is 1.3
+func bump(v versionNumber, i int) versionNumber {
+	bumped := append(versionNumber{}, v[:i+1]...)
+	bumped[i]++
+	return bumped
+}
+
+// parseWildcard parses "1.2.*", "1.x" or a partial version such as "1.2"
+// into the range of versions with that prefix. "*" alone is any version.
+func parseWildcard(s string) (versionRange, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	var prefix []string
+	for _, segment := range strings.Split(s, ".") {
+		if segment == "*" || segment == "x" || segment == "X" {
+			break
+		}
+		prefix = append(prefix, segment)
+	}
+	if len(prefix) == 0 {
+		return anyVersion(), true
+	}
+	v, ok := parseVersionNumber(strings.Join(prefix, "."))
+	if !ok {
+		return versionRange{}, false
+	}
+	return between(v, bump(v, len(v)-1)), true
+}
+
+func isWildcard(s string) bool {
+	return strings.ContainsAny(s, "*xX") || s == ""
+}
+
+// parseComparator handles the ">=", "<=", ">" and "<" operators shared by
+// every ecosystem. handled is false when s does not start with one of them.
+func parseComparator(s string) (r versionRange, ok, handled bool) {
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		if !strings.HasPrefix(s, op) {
+			continue
+		}
+		v, ok := parseVersionNumber(s[len(op):])
+		if !ok {
+			return versionRange{}, false, true
+		}
+		switch op {
+		case ">=":
+			return atLeast(v, true), true, true
+		case "<=":
+			return below(v, true), true, true
+		case ">":
+			return atLeast(v, false), true, true
+		default:
+			return below(v, false), true, true
+		}
+	}
+	return versionRange{}, false, false
+}
+
+// caretRange is npm, Cargo and Composer "^": changes that do not modify the
+// left-most non-zero segment
+func caretRange(s string) (versionRange, bool) {
+	v, ok := parseVersionNumber(s)
+	if !ok {
+		return parseWildcard(s)
+	}
+	for i, segment := range v {
+		if segment != 0 {
+			return between(v, bump(v, i)), true
+		}
+	}
+	return between(v, bump(v, len(v)-1)), true
+}
+
+// tildeRange is npm and Cargo "~": patch-level changes when a minor version
+// is given, otherwise minor-level changes
+func tildeRange(s string) (versionRange, bool) {
+	v, ok := parseVersionNumber(s)
+	if !ok {
+		return parseWildcard(s)
+	}
+	if len(v) >= 2 {
+		return between(v, bump(v, 1)), true
+	}
+	return between(v, bump(v, 0)), true
+}
+
+// pessimisticRange is RubyGems "~>", Python "~=" and Composer "~": the last
+// given segment may increase ("~> 2.7" is >= 2.7, < 3)
+func pessimisticRange(s string) (versionRange, bool) {
+	v, ok := parseVersionNumber(s)
+	if !ok {
+		return versionRange{}, false
+	}
+	if len(v) == 1 {
+		return between(v, bump(v, 0)), true
+	}
+	return between(v, bump(v, len(v)-2)), true
+}
+
+// parseConstraint parses a version constraint in the syntax of lang
+func parseConstraint(lang, constraint string) (versionRange, bool) {
+	constraint = strings.TrimSpace(constraint)
+	if constraint == "" || constraint == "*" || constraint == "latest" {
+		return anyVersion(), true
+	}
+
+	switch lang {
+	case "python":
+		return parseClauses(strings.Split(constraint, ","), parsePythonClause)
+	case "javascript":
+		return parseNpmConstraint(constraint)
+	case "rust":
+		return parseClauses(strings.Split(constraint, ","), parseCargoClause)
+	case "ruby":
+		return parseClauses(strings.Split(constraint, ","), parseRubyClause)
+	case "php":
+		return parseComposerConstraint(constraint)
+	case "go":
+		// Minimal version selection picks the highest minimum, so Go
+		// requirements on one module path never conflict
+		v, ok := parseVersionNumber(constraint)
+		if !ok {
+			return versionRange{}, false
+		}
+		return atLeast(v, true), true
+	case "java":
+		if !strings.ContainsAny(constraint, "[(") {
+			// A bare Maven version is a soft requirement that dependency
+			// mediation may override
+			return anyVersion(), true
+		}
+		return parseIntervalConstraint(constraint)
+	case "csharp":
+		if !strings.ContainsAny(constraint, "[(") {
+			// NuGet "1.0" means 1.0 or later
+			v, ok := parseVersionNumber(constraint)
+			if !ok {
+				return versionRange{}, false
+			}
+			return atLeast(v, true), true
+		}
+		return parseIntervalConstraint(constraint)
+	}
+	return versionRange{}, false
+}
+
+// parseClauses intersects the ranges of several ANDed clauses
+func parseClauses(clauses []string, parse func(string) (versionRange, bool)) (versionRange, bool) {
+	result := anyVersion()
+	for _, clause := range clauses {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+		r, ok := parse(clause)
+		if !ok {
+			return versionRange{}, false
+		}
+		result = result.intersect(r)
+	}
+	return result, true
+}
+
+// parsePythonClause parses one PEP 440 specifier clause
+func parsePythonClause(c string) (versionRange, bool) {
+	switch {
+	case strings.HasPrefix(c, "==="):
+		v, ok := parseVersionNumber(c[3:])
+		return exactly(v), ok
+	case strings.HasPrefix(c, "=="):
+		if strings.HasSuffix(c, ".*") {
+			return parseWildcard(c[2:])
+		}
+		v, ok := parseVersionNumber(c[2:])
+		return exactly(v), ok
+	case strings.HasPrefix(c, "!="):
+		if strings.HasSuffix(c, ".*") {
+			// Excluding a whole series leaves a gap the range cannot
+			// express; ignoring it can only miss a conflict
+			return anyVersion(), true
+		}
+		v, ok := parseVersionNumber(c[2:])
+		return versionRange{excluded: []versionNumber{v}}, ok
+	case strings.HasPrefix(c, "~="):
+		return pessimisticRange(c[2:])
+	}
+	if r, ok, handled := parseComparator(c); handled {
+		return r, ok
+	}
+	v, ok := parseVersionNumber(c)
+	return exactly(v), ok
+}
+
+// parseNpmConstraint parses a node-semver range. Unions ("||") are not
+// supported.
+func parseNpmConstraint(constraint string) (versionRange, bool) {
+	if strings.Contains(constraint, "||") || strings.Contains(constraint, ":") {
+		return versionRange{}, false
+	}
+
+	// Hyphen ranges: "1.2.3 - 2.3.4"
+	if lowerStr, upperStr, found := strings.Cut(constraint, " - "); found {
+		lower, ok := parseWildcard(lowerStr)
+		if !ok || lower.lower == nil {
+			return versionRange{}, false
+		}
+		upperStr = strings.TrimSpace(upperStr)
+		if v, ok := parseVersionNumber(upperStr); ok && len(v) == 3 && !isWildcard(upperStr) {
+			return atLeast(lower.lower.version, true).intersect(below(v, true)), true
+		}
+		upper, ok := parseWildcard(upperStr)
+		if !ok {
+			return versionRange{}, false
+		}
+		return versionRange{lower: lower.lower, upper: upper.upper}, true
+	}
+
+	return parseClauses(joinOperators(strings.Fields(constraint)), parseNpmClause)
+}
+
+// joinOperators rejoins an operator separated from its version by a space
+// (">= 1.2.0")
+func joinOperators(tokens []string) []string {
+	var joined []string
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if strings.Trim(token, "<>=~^") == "" && i+1 < len(tokens) {
+			token += tokens[i+1]
+			i++
+		}
+		joined = append(joined, token)
+	}
+	return joined
+}
+
+func parseNpmClause(c string) (versionRange, bool) {
+	switch {
+	case strings.HasPrefix(c, "^"):
+		return caretRange(c[1:])
+	case strings.HasPrefix(c, "~>"):
+		return tildeRange(c[2:])
+	case strings.HasPrefix(c, "~"):
+		return tildeRange(c[1:])
+	}
+	if r, ok, handled := parseComparator(c); handled {
+		return r, ok
+	}
+	c = strings.TrimPrefix(c, "=")
+	if v, ok := parseVersionNumber(c); ok && len(v) >= 3 {
+		return exactly(v), true
+	}
+	return parseWildcard(c)
+}
+
+// parseCargoClause parses one Cargo requirement; a bare version is a caret
+// requirement
+func parseCargoClause(c string) (versionRange, bool) {
+	switch {
+	case strings.HasPrefix(c, "^"):
+		return caretRange(strings.TrimSpace(c[1:]))
+	case strings.HasPrefix(c, "~"):
+		return tildeRange(strings.TrimSpace(c[1:]))
+	case strings.HasPrefix(c, "="):
+		s := strings.TrimSpace(c[1:])
+		if v, ok := parseVersionNumber(s); ok && len(v) >= 3 {
+			return exactly(v), true
+		}
+		return parseWildcard(s)
+	}
+	if r, ok, handled := parseComparator(strings.ReplaceAll(c, " ", "")); handled {
+		return r, ok
+	}
+	if isWildcard(c) {
+		return parseWildcard(c)
+	}
+	return caretRange(c)
+}
+
+// parseRubyClause parses one RubyGems requirement; a bare version is exact
+func parseRubyClause(c string) (versionRange, bool) {
+	c = strings.ReplaceAll(c, " ", "")
+	switch {
+	case strings.HasPrefix(c, "~>"):
+		return pessimisticRange(c[2:])
+	case strings.HasPrefix(c, "!="):
+		v, ok := parseVersionNumber(c[2:])
+		return versionRange{excluded: []versionNumber{v}}, ok
+	case strings.HasPrefix(c, "="):
+		v, ok := parseVersionNumber(c[1:])
+		return exactly(v), ok
+	}
+	if r, ok, handled := parseComparator(c); handled {
+		return r, ok
+	}
+	v, ok := parseVersionNumber(c)
+	return exactly(v), ok
+}
+
+// parseComposerConstraint parses a Composer constraint. Alternatives ("|"
+// or "||") are not supported.
+func parseComposerConstraint(constraint string) (versionRange, bool) {
+	if strings.Contains(constraint, "|") {
+		return versionRange{}, false
+	}
+	// Stability flags ("^1.0@beta") don't change the range
+	if idx := strings.Index(constraint, "@"); idx != -1 {
+		constraint = constraint[:idx]
+	}
+	if strings.Contains(constraint, " - ") {
+		return parseNpmConstraint(constraint)
+	}
+	clauses := joinOperators(strings.FieldsFunc(constraint, func(r rune) bool { return r == ',' || r == ' ' }))
+	return parseClauses(clauses, func(c string) (versionRange, bool) {
+		switch {
+		case strings.HasPrefix(c, "^"):
+			return caretRange(c[1:])
+		case strings.HasPrefix(c, "~"):
+			return pessimisticRange(c[1:])
+		case strings.HasPrefix(c, "!="):
+			v, ok := parseVersionNumber(c[2:])
+			return versionRange{excluded: []versionNumber{v}}, ok
+		}
+		if r, ok, handled := parseComparator(c); handled {
+			return r, ok
+		}
+		c = strings.TrimPrefix(strings.TrimPrefix(c, "=="), "=")
+		if isWildcard(c) {
+			return parseWildcard(c)
+		}
+		v, ok := parseVersionNumber(c)
+		return exactly(v), ok
+	})
+}
+
+// parseIntervalConstraint parses Maven and NuGet interval notation:
+// "[1.0,2.0)", "(,1.0]", "[1.0,)" or "[1.0]". Unions of several intervals
+// are not supported.
+func parseIntervalConstraint(constraint string) (versionRange, bool) {
+	constraint = strings.ReplaceAll(constraint, " ", "")
+	if len(constraint) < 3 || strings.Count(constraint, ",") > 1 {
+		return versionRange{}, false
+	}
+	opening, closing := constraint[0], constraint[len(constraint)-1]
+	if (opening != '[' && opening != '(') || (closing != ']' && closing != ')') {
+		return versionRange{}, false
+	}
+	inner := constraint[1 : len(constraint)-1]
+
+	lowerStr, upperStr, found := strings.Cut(inner, ",")
+	if !found {
+		v, ok := parseVersionNumber(inner)
+		return exactly(v), ok && opening == '[' && closing == ']'
+	}
+
+	result := anyVersion()
+	if lowerStr != "" {
+		v, ok := parseVersionNumber(lowerStr)
+		if !ok {
+			return versionRange{}, false
+		}
+		result = result.intersect(atLeast(v, opening == '['))
+	}
+	if upperStr != "" {
+		v, ok := parseVersionNumber(upperStr)
+		if !ok {
+			return versionRange{}, false
+		}
+		result = result.intersect(below(v, closing == ']'))
+	}
+	return result, true
+}
@@ -0,0 +1,177 @@
+package depgen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func TestFindConflicts(t *testing.T) {
+	pkgs := []PackageDependencies{
+		{
+			Package:  "http-client",
+			Language: "python",
+			Dependencies: []manifest.Dependency{
+				{Package: "requests", Version: ">=2.0.0,<2.20.0"},
+				{Package: "PyYAML", Version: ">=5.0"},
+			},
+		},
+		{
+			Package:  "scraper",
+			Language: "python",
+			Dependencies: []manifest.Dependency{
+				{Package: "requests", Version: ">=2.28.0"},
+				{Package: "pyyaml", Version: "~=6.0"},
+			},
+		},
+		{
+			Package:  "web-utils",
+			Language: "javascript",
+			Dependencies: []manifest.Dependency{
+				// Same name in another ecosystem is a different package
+				{Package: "requests", Version: "^1.0.0"},
+			},
+		},
+	}
+
+	report := FindConflicts(pkgs)
+	if len(report.Unchecked) != 0 {
+		t.Errorf("unexpected unchecked constraints: %v", report.Unchecked)
+	}
+	if len(report.Conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %+v", report.Conflicts)
+	}
+
+	conflict := report.Conflicts[0]
+	if conflict.Dependency != "requests" || conflict.Language != "python" {
+		t.Errorf("unexpected conflict: %+v", conflict)
+	}
+	if len(conflict.Constraints) != 2 {
+		t.Errorf("expected 2 constraints, got %+v", conflict.Constraints)
+	}
+	if !strings.HasPrefix(conflict.Resolution, "Update http-client (requires requests >=2.0.0,<2.20.0)") {
+		t.Errorf("unexpected resolution: %s", conflict.Resolution)
+	}
+
+	if !conflict.Involves("scraper", "python", "Requests") {
+		t.Error("Involves() = false for scraper's requests")
+	}
+	if conflict.Involves("web-utils", "javascript", "requests") || conflict.Involves("scraper", "python", "pyyaml") {
+		t.Error("Involves() = true for a dependency not in the conflict")
+	}
+}
+
+func TestFindConflictsSkipsMarkers(t *testing.T) {
+	pkgs := []PackageDependencies{
+		{Package: "aigogo.json", Language: "python", Dependencies: []manifest.Dependency{
+			{Package: "numpy", Version: "<2", Marker: `python_version < "3.9"`},
+			{Package: "numpy", Version: ">=2", Marker: `python_version >= "3.9"`},
+		}},
+	}
+
+	if report := FindConflicts(pkgs); len(report.Conflicts) != 0 {
+		t.Errorf("marker-qualified requirements should not conflict, got %+v", report.Conflicts)
+	}
+}
+
+func TestFindConflictsUnchecked(t *testing.T) {
+	pkgs := []PackageDependencies{
+		{Package: "a", Language: "javascript", Dependencies: []manifest.Dependency{{Package: "lodash", Version: "^3.0.0 || ^4.0.0"}}},
+		{Package: "b", Language: "javascript", Dependencies: []manifest.Dependency{{Package: "lodash", Version: "^4.17.0"}}},
+	}
+
+	report := FindConflicts(pkgs)
+	if len(report.Conflicts) != 0 {
+		t.Errorf("expected no conflicts, got %+v", report.Conflicts)
+	}
+	if len(report.Unchecked) != 1 || !strings.Contains(report.Unchecked[0], "lodash") {
+		t.Errorf("expected lodash to be unchecked, got %v", report.Unchecked)
+	}
+}
+
+func TestConstraintsOverlap(t *testing.T) {
+	tests := []struct {
+		lang    string
+		a, b    string
+		overlap bool
+	}{
+		// Python
+		{"python", ">=2.28.0", ">=2.0,<3", true},
+		{"python", ">=2.28.0", "<2.20", false},
+		{"python", "==1.24.*", ">=1.25", false},
+		{"python", "==1.24.*", "~=1.24.3", true},
+		{"python", "~=1.4", ">=2.0", false},
+		{"python", "==2.0.0", "!=2.0.0", false},
+		{"python", "*", "==1.0", true},
+		// JavaScript
+		{"javascript", "^4.17.0", "^4.18.2", true},
+		{"javascript", "^4.17.0", "^5.0.0", false},
+		{"javascript", "^0.2.0", "0.3.1", false},
+		{"javascript", "~1.2.0", ">=1.2.5 <1.3", true},
+		{"javascript", "1.x", ">= 2.0.0", false},
+		{"javascript", "1.0.0 - 1.5.0", "1.5.0", true},
+		// Rust: bare versions are caret requirements
+		{"rust", "1.0", "1.4", true},
+		{"rust", "0.3", "0.4", false},
+		{"rust", "=1.2.3", ">=1.3, <2", false},
+		// Ruby
+		{"ruby", "~> 2.7", ">= 2.9", true},
+		{"ruby", "~> 2.7.1", ">= 2.8", false},
+		{"ruby", "1.2.0", "~> 1.2", true},
+		// PHP
+		{"php", "^7.0", "~7.5", true},
+		{"php", "^7.0", "^6.5", false},
+		{"php", "1.2.*", ">=1.3", false},
+		// Go never conflicts under minimal version selection
+		{"go", "v1.2.0", "v1.9.3", true},
+		// Java: bare versions are soft, ranges are hard
+		{"java", "2.10.1", "2.9.0", true},
+		{"java", "[2.10,3.0)", "[1.0,2.0]", false},
+		// C#: bare versions are minimums
+		{"csharp", "13.0.1", "[12.0,13.0)", false},
+		{"csharp", "12.0.1", "[12.0,13.0)", true},
+	}
+
+	for _, tt := range tests {
+		a, ok := parseConstraint(tt.lang, tt.a)
+		if !ok {
+			t.Errorf("%s: failed to parse %q", tt.lang, tt.a)
+			continue
+		}
+		b, ok := parseConstraint(tt.lang, tt.b)
+		if !ok {
+			t.Errorf("%s: failed to parse %q", tt.lang, tt.b)
+			continue
+		}
+		if overlap := !a.intersect(b).empty(); overlap != tt.overlap {
+			t.Errorf("%s: %q and %q overlap = %v, want %v", tt.lang, tt.a, tt.b, overlap, tt.overlap)
+		}
+	}
+}
+
+func TestVersionSatisfies(t *testing.T) {
+	tests := []struct {
+		lang, constraint, version string
+		satisfied, ok             bool
+	}{
+		{"python", ">=2.0,<3", "2.1.0", true, true},
+		{"python", ">=2.0,<3", "1.13.1", false, true},
+		{"python", ">=2.0", "2.1.0+cu118", true, true},
+		{"python", "!=2.0.0", "2.0.0", false, true},
+		{"python", "~=1.4", "1.9", true, true},
+		{"javascript", "^18.2.0", "18.3.1", true, true},
+		{"javascript", "^18.2.0", "17.0.2", false, true},
+		{"typescript", ">=5", "5.4.2", true, true},
+		{"python", ">=2.0", "not-a-version", false, false},
+		{"python", "bogus", "1.0", false, false},
+	}
+
+	for _, tt := range tests {
+		satisfied, ok := VersionSatisfies(tt.lang, tt.constraint, tt.version)
+		if satisfied != tt.satisfied || ok != tt.ok {
+			t.Errorf("VersionSatisfies(%s, %q, %q) = %v, %v; want %v, %v",
+				tt.lang, tt.constraint, tt.version, satisfied, ok, tt.satisfied, tt.ok)
+		}
+	}
+}
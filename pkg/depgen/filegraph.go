@@ -0,0 +1,291 @@
+package depgen
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Local import patterns, per language. Each captures the imported module
+// or path of an import that may refer to another included file.
+var (
+	pyLocalImportRegex   = regexp.MustCompile(`^\s*import\s+([a-zA-Z0-9_\.]+(?:\s*,\s*[a-zA-Z0-9_\.]+)*)`)
+	pyLocalFromRegex     = regexp.MustCompile(`^\s*from\s+(\.*[a-zA-Z0-9_\.]*)\s+import\s+\(?\s*([a-zA-Z0-9_, ]+)`)
+	jsLocalImportRegex   = regexp.MustCompile(`(?:\bfrom|\bimport|\brequire\s*\()\s*\(?\s*['"](\.{1,2}/[^'"]*|\.{1,2})['"]`)
+	rubyLocalImportRegex = regexp.MustCompile(`^\s*(require_relative|require|load)\s*\(?\s*['"]([^'"]+)['"]`)
+	rustLocalModRegex    = regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?mod\s+([a-zA-Z0-9_]+)\s*;`)
+	phpLocalIncludeRegex = regexp.MustCompile(`\b(?:require|include)(?:_once)?\s*\(?\s*(?:__DIR__\s*\.\s*)?['"]([^'"]+\.php)['"]`)
+)
+
+// jsExtensions are tried, in order, when resolving an extensionless
+// JavaScript or TypeScript import
+var jsExtensions = []string{".js", ".mjs", ".cjs", ".jsx", ".ts", ".tsx", ".mts", ".cts"}
+
+// sourceExtensions lists the file types that can import each other, per
+// language. Languages whose files share a package namespace instead of
+// importing one another (go, java, csharp) have no entry.
+var sourceExtensions = map[string][]string{
+	"python":     {".py"},
+	"javascript": jsExtensions,
+	"ruby":       {".rb"},
+	"rust":       {".rs"},
+	"php":        {".php"},
+}
+
+// entryPointNames are file names a language loads by convention rather than
+// through an import from a sibling file
+var entryPointNames = map[string][]string{
+	"python":     {"__init__.py", "__main__.py", "conftest.py", "setup.py"},
+	"javascript": {"index", "main", "cli"},
+	"rust":       {"lib.rs", "main.rs", "build.rs"},
+	"ruby":       {"Rakefile", "Gemfile"},
+	"php":        {"index.php", "autoload.php"},
+}
+
+// FindUnusedFiles returns the included source files that no other included
+// file imports, in the order of files. Import graphs are built for Python
+// (relative and package-qualified imports), JavaScript/TypeScript
+// (relative specifiers), Ruby (require_relative), Rust (mod declarations)
+// and PHP (require/include); other languages return nil.
+//
+// Files are only reported when the package has an import graph at all, so
+// a collection of independent modules is left alone. Files that import
+// other included files are treated as entry points, as are conventional
+// entry files (__init__.py, index.js, lib.rs, ...) and files named in
+// entryHints, such as the package's script commands.
+func FindUnusedFiles(files []string, language string, entryHints []string) []string {
+	exts, ok := sourceExtensions[language]
+	if !ok {
+		return nil
+	}
+
+	// Index the included source files by slash-separated, cleaned path
+	sources := make(map[string]string)
+	var order []string
+	for _, f := range files {
+		if !hasAnySuffix(f, exts) {
+			continue
+		}
+		key := path.Clean(filepath.ToSlash(f))
+		sources[key] = f
+		order = append(order, key)
+	}
+	if len(order) < 2 {
+		return nil
+	}
+
+	imported := make(map[string]bool)
+	importer := make(map[string]bool)
+	for _, key := range order {
+		for _, target := range localImports(sources[key], key, language, sources) {
+			if target == key {
+				continue
+			}
+			imported[target] = true
+			importer[key] = true
+		}
+	}
+	if len(imported) == 0 {
+		return nil
+	}
+
+	var unused []string
+	for _, key := range order {
+		if imported[key] || importer[key] || isEntryPoint(key, language, entryHints) {
+			continue
+		}
+		unused = append(unused, sources[key])
+	}
+	return unused
+}
+
+// localImports returns the included files that the file at key imports
+func localImports(filename, key, language string, sources map[string]string) []string {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = file.Close() }()
+
+	dir := path.Dir(key)
+	seen := make(map[string]bool)
+	var targets []string
+	add := func(candidates ...string) {
+		for _, c := range candidates {
+			c = path.Clean(c)
+			if _, ok := sources[c]; ok {
+				if !seen[c] {
+					seen[c] = true
+					targets = append(targets, c)
+				}
+				return
+			}
+		}
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+
+		switch language {
+		case "python":
+			if m := pyLocalFromRegex.FindStringSubmatch(line); m != nil {
+				for _, name := range strings.Split(m[2], ",") {
+					name = strings.TrimSpace(strings.SplitN(strings.TrimSpace(name), " ", 2)[0])
+					if name == "" {
+						continue
+					}
+					// from pkg import mod can name a submodule
+					sub := m[1] + "." + name
+					if strings.HasSuffix(m[1], ".") {
+						sub = m[1] + name
+					}
+					if !resolvePython(sub, dir, sources, add) {
+						resolvePython(m[1], dir, sources, add)
+					}
+				}
+			} else if m := pyLocalImportRegex.FindStringSubmatch(line); m != nil {
+				for _, name := range strings.Split(m[1], ",") {
+					resolvePython(strings.TrimSpace(name), dir, sources, add)
+				}
+			}
+		case "javascript":
+			for _, m := range jsLocalImportRegex.FindAllStringSubmatch(line, -1) {
+				base := path.Join(dir, m[1])
+				candidates := []string{base}
+				for _, ext := range jsExtensions {
+					candidates = append(candidates, base+ext)
+				}
+				// TypeScript sources import each other with .js specifiers
+				if stem := strings.TrimSuffix(base, ".js"); stem != base {
+					candidates = append(candidates, stem+".ts", stem+".tsx")
+				}
+				for _, ext := range jsExtensions {
+					candidates = append(candidates, path.Join(base, "index"+ext))
+				}
+				add(candidates...)
+			}
+		case "ruby":
+			if m := rubyLocalImportRegex.FindStringSubmatch(line); m != nil {
+				target := m[2]
+				if m[1] != "require_relative" && !strings.HasPrefix(target, ".") {
+					// require 'lib/foo' resolves against the load path,
+					// which for a vendored package is its root
+					add(target, target+".rb")
+					continue
+				}
+				base := path.Join(dir, target)
+				add(base, base+".rb")
+			}
+		case "rust":
+			if m := rustLocalModRegex.FindStringSubmatch(line); m != nil {
+				modDir := dir
+				if name := path.Base(key); name != "lib.rs" && name != "main.rs" && name != "mod.rs" {
+					modDir = path.Join(dir, strings.TrimSuffix(name, ".rs"))
+				}
+				add(path.Join(modDir, m[1]+".rs"), path.Join(modDir, m[1], "mod.rs"))
+			}
+		case "php":
+			for _, m := range phpLocalIncludeRegex.FindAllStringSubmatch(line, -1) {
+				target := strings.TrimPrefix(m[1], "/")
+				add(path.Join(dir, target), target)
+			}
+		}
+	}
+	return targets
+}
+
+// resolvePython resolves a possibly relative dotted module name imported
+// from dir to an included file, reporting whether one was found. Absolute
+// names match any included file whose path ends with the module path, so
+// packages nested under src/ or a project directory still resolve.
+func resolvePython(module, dir string, sources map[string]string, add func(...string)) bool {
+	if module == "" {
+		return false
+	}
+
+	var rel string
+	if strings.HasPrefix(module, ".") {
+		dots := len(module) - len(strings.TrimLeft(module, "."))
+		base := dir
+		for i := 1; i < dots; i++ {
+			base = path.Dir(base)
+		}
+		rest := strings.ReplaceAll(strings.TrimLeft(module, "."), ".", "/")
+		if rest == "" {
+			rel = path.Join(base, "__init__")
+		} else {
+			rel = path.Join(base, rest)
+		}
+		return addFirst(sources, add, rel+".py", path.Join(rel, "__init__.py"))
+	}
+
+	rel = strings.ReplaceAll(module, ".", "/")
+	for _, suffix := range []string{rel + ".py", rel + "/__init__.py"} {
+		// Prefer a sibling of the importing file, then any match
+		if addFirst(sources, add, path.Join(dir, suffix), suffix) {
+			return true
+		}
+		var matches []string
+		for key := range sources {
+			if strings.HasSuffix(key, "/"+suffix) {
+				matches = append(matches, key)
+			}
+		}
+		if len(matches) > 0 {
+			sort.Strings(matches)
+			add(matches[0])
+			return true
+		}
+	}
+	return false
+}
+
+// addFirst adds the first candidate that is an included file
+func addFirst(sources map[string]string, add func(...string), candidates ...string) bool {
+	for _, c := range candidates {
+		if _, ok := sources[path.Clean(c)]; ok {
+			add(c)
+			return true
+		}
+	}
+	return false
+}
+
+// isEntryPoint reports whether a file is loaded by convention or named in
+// one of the entry hints
+func isEntryPoint(key, language string, entryHints []string) bool {
+	name := path.Base(key)
+	stem := strings.TrimSuffix(name, path.Ext(name))
+	for _, entry := range entryPointNames[language] {
+		if name == entry || stem == entry {
+			return true
+		}
+	}
+	for _, hint := range entryHints {
+		for _, field := range strings.Fields(hint) {
+			if path.Clean(filepath.ToSlash(field)) == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasAnySuffix reports whether s ends with one of suffixes
+func hasAnySuffix(s string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package depgen
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// writeTree creates files under dir and returns their paths in order
+func writeTree(t *testing.T, dir string, files [][2]string) []string {
+	t.Helper()
+	var paths []string
+	for _, f := range files {
+		p := filepath.Join(dir, f[0])
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(f[1]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+func TestFindUnusedFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		files    [][2]string
+		hints    []string
+		want     []string
+	}{
+		{
+			name:     "python relative and absolute imports",
+			language: "python",
+			files: [][2]string{
+				{"pkg/__init__.py", "from .client import Client\n"},
+				{"pkg/client.py", "from . import retry\nimport requests\n"},
+				{"pkg/retry.py", "from pkg.util.backoff import delay\n"},
+				{"pkg/util/backoff.py", "import time\n"},
+				{"pkg/legacy.py", "import json\n"},
+			},
+			want: []string{"pkg/legacy.py"},
+		},
+		{
+			name:     "independent modules are left alone",
+			language: "python",
+			files: [][2]string{
+				{"a.py", "import os\n"},
+				{"b.py", "import sys\n"},
+			},
+		},
+		{
+			name:     "script commands are entry points",
+			language: "python",
+			files: [][2]string{
+				{"app.py", "from helpers import greet\n"},
+				{"helpers.py", ""},
+				{"tools/migrate.py", ""},
+				{"old.py", ""},
+			},
+			hints: []string{"python $DIR/tools/migrate.py --dry-run"},
+			want:  []string{"old.py"},
+		},
+		{
+			name:     "javascript relative specifiers",
+			language: "javascript",
+			files: [][2]string{
+				{"src/index.ts", "export { fetch } from './http.js'\n"},
+				{"src/http.ts", "const u = require('./utils')\n"},
+				{"src/utils/index.js", "import x from 'lodash'\n"},
+				{"src/unused.js", ""},
+				{"README.md", ""},
+			},
+			want: []string{"src/unused.js"},
+		},
+		{
+			name:     "ruby require_relative",
+			language: "ruby",
+			files: [][2]string{
+				{"lib/client.rb", "require_relative 'client/config'\nrequire 'json'\n"},
+				{"lib/client/config.rb", ""},
+				{"lib/stale.rb", ""},
+			},
+			want: []string{"lib/stale.rb"},
+		},
+		{
+			name:     "rust mod declarations",
+			language: "rust",
+			files: [][2]string{
+				{"src/lib.rs", "pub mod parser;\n"},
+				{"src/parser.rs", "mod lexer;\n"},
+				{"src/parser/lexer.rs", ""},
+				{"src/orphan.rs", ""},
+			},
+			want: []string{"src/orphan.rs"},
+		},
+		{
+			name:     "go files share a package namespace",
+			language: "go",
+			files: [][2]string{
+				{"a.go", "package a\n"},
+				{"b.go", "package a\n"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := writeTree(t, dir, tt.files)
+
+			var hints []string
+			for _, h := range tt.hints {
+				hints = append(hints, strings.ReplaceAll(h, "$DIR", dir))
+			}
+			var want []string
+			for _, w := range tt.want {
+				want = append(want, filepath.Join(dir, w))
+			}
+
+			got := FindUnusedFiles(files, tt.language, hints)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("FindUnusedFiles() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateUnusedFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := writeTree(t, dir, [][2]string{
+		{"main.py", "from .helpers import greet\n"},
+		{"helpers.py", "def greet(): pass\n"},
+		{"scratch.py", "print('hi')\n"},
+	})
+
+	v := NewValidator()
+	result, err := v.Validate(&manifest.Manifest{Language: manifest.Language{Name: "python"}}, files)
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	if len(result.Findings) != 1 {
+		t.Fatalf("Expected 1 finding, got %+v", result.Findings)
+	}
+	f := result.Findings[0]
+	if f.Rule != RuleUnusedFile || f.Severity != SeverityWarning || f.File != files[2] {
+		t.Errorf("unexpected finding: %+v", f)
+	}
+	if result.Failed(false) || !result.Failed(true) {
+		t.Error("unused files should only fail strict validation")
+	}
+}
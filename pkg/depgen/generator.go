@@ -1,6 +1,7 @@
 package depgen
 
 import (
+	"encoding/xml"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -17,12 +18,25 @@ func NewGenerator() *Generator {
 	return &Generator{}
 }
 
-// Generate creates appropriate dependency files based on manifest
+// Generate creates appropriate dependency files based on manifest. A
+// multi-language package gets the files of each of its languages.
 func (g *Generator) Generate(m *manifest.Manifest, outputDir string) ([]string, error) {
 	if m.Dependencies == nil {
 		return nil, nil // No dependencies to generate
 	}
 
+	if len(m.Languages) > 0 {
+		var generated []string
+		for _, lang := range m.AllLanguages() {
+			files, err := g.Generate(m.ForLanguage(lang.Name), outputDir)
+			if err != nil {
+				return nil, err
+			}
+			generated = append(generated, files...)
+		}
+		return generated, nil
+	}
+
 	switch m.Language.Name {
 	case "python":
 		return g.generatePython(m, outputDir)
@@ -32,6 +46,14 @@ func (g *Generator) Generate(m *manifest.Manifest, outputDir string) ([]string,
 		return g.generateGo(m, outputDir)
 	case "rust":
 		return g.generateRust(m, outputDir)
+	case "ruby":
+		return g.generateRuby(m, outputDir)
+	case "java":
+		return g.generateJava(m, outputDir)
+	case "csharp":
+		return g.generateCSharp(m, outputDir)
+	case "php":
+		return g.generatePHP(m, outputDir)
 	default:
 		return nil, fmt.Errorf("unsupported language: %s", m.Language.Name)
 	}
@@ -65,7 +87,7 @@ func (g *Generator) writePythonRequirements(path string, deps []manifest.Depende
 	content.WriteString("# To remove: delete these entries and uninstall the packages\n\n")
 
 	for _, dep := range deps {
-		fmt.Fprintf(&content, "%s%s\n", dep.Package, dep.Version)
+		fmt.Fprintf(&content, "%s\n", PythonRequirement(dep))
 	}
 
 	return os.WriteFile(path, []byte(content.String()), 0644)
@@ -77,7 +99,9 @@ func (g *Generator) writePythonRequirements(path string, deps []manifest.Depende
 // aigogo packages are distributed via registries and installed via symlinks, not
 // pip. The generated pyproject.toml serves as reference/documentation. Using
 // dedicated groups clearly labels deps as aigogo-managed so consumers can easily
-// identify and remove them.
+// identify and remove them. Each extra gets its own aigogo-<extra> group. With
+// "generator": {"python": "dependency-groups"} the same groups are written as
+// PEP 735 [dependency-groups] instead.
 func (g *Generator) writePyproject(path string, m *manifest.Manifest) error {
 	var content strings.Builder
 
@@ -97,15 +121,16 @@ func (g *Generator) writePyproject(path string, m *manifest.Manifest) error {
 
 	hasRuntime := len(m.Dependencies.Runtime) > 0
 	hasDev := len(m.Dependencies.Dev) > 0
+	extras := m.ExtraNames()
 
-	if hasRuntime || hasDev {
-		content.WriteString("\n[project.optional-dependencies]\n")
+	if hasRuntime || hasDev || len(extras) > 0 {
+		fmt.Fprintf(&content, "\n%s\n", PythonGroupTable(m))
 	}
 
 	if hasRuntime {
 		content.WriteString("aigogo = [\n")
 		for _, dep := range m.Dependencies.Runtime {
-			fmt.Fprintf(&content, "    \"%s%s\",\n", dep.Package, dep.Version)
+			fmt.Fprintf(&content, "    \"%s\",\n", PythonRequirement(dep))
 		}
 		content.WriteString("]\n")
 	}
@@ -113,7 +138,15 @@ func (g *Generator) writePyproject(path string, m *manifest.Manifest) error {
 	if hasDev {
 		content.WriteString("aigogo-dev = [\n")
 		for _, dep := range m.Dependencies.Dev {
-			fmt.Fprintf(&content, "    \"%s%s\",\n", dep.Package, dep.Version)
+			fmt.Fprintf(&content, "    \"%s\",\n", PythonRequirement(dep))
+		}
+		content.WriteString("]\n")
+	}
+
+	for _, extra := range extras {
+		fmt.Fprintf(&content, "%s = [\n", PythonExtraGroup(extra))
+		for _, dep := range m.Dependencies.Extras[extra] {
+			fmt.Fprintf(&content, "    \"%s\",\n", PythonRequirement(dep))
 		}
 		content.WriteString("]\n")
 	}
@@ -125,18 +158,112 @@ func (g *Generator) writePyproject(path string, m *manifest.Manifest) error {
 	return os.WriteFile(path, []byte(content.String()), 0644)
 }
 
+// PythonRequirement formats a dependency as a PEP 508 requirement string:
+// name, extras, version specifier and environment marker, as in
+// requests[socks]>=2.31; python_version < '3.11'. A "*" version is omitted.
+// Double quotes in markers become single quotes so the string can be
+// embedded in TOML and shell double-quoted strings.
+func PythonRequirement(dep manifest.Dependency) string {
+	req := dep.Package
+	if len(dep.Extras) > 0 {
+		req += "[" + strings.Join(dep.Extras, ",") + "]"
+	}
+	if dep.Version != "*" {
+		req += dep.Version
+	}
+	if dep.Marker != "" {
+		req += "; " + strings.ReplaceAll(dep.Marker, `"`, "'")
+	}
+	return req
+}
+
+// PythonExtraGroup returns the name of the pyproject.toml group that holds
+// an extra's dependencies, e.g. aigogo-viz
+func PythonExtraGroup(extra string) string {
+	return "aigogo-" + extra
+}
+
+// PythonGroupTable returns the pyproject.toml table that holds the aigogo
+// and aigogo-dev groups for the manifest's Python dependency style
+func PythonGroupTable(m *manifest.Manifest) string {
+	if m.PythonStyle() == manifest.PythonStyleDependencyGroups {
+		return "[dependency-groups]"
+	}
+	return "[project.optional-dependencies]"
+}
+
+// UvAddCommand returns the uv command that adds deps to the given aigogo
+// group: "--group" for PEP 735 dependency groups, "--optional" for extras.
+// A "*" version is omitted so uv picks the latest release.
+func UvAddCommand(m *manifest.Manifest, group string, deps []manifest.Dependency) string {
+	flag := "--optional"
+	if m.PythonStyle() == manifest.PythonStyleDependencyGroups {
+		flag = "--group"
+	}
+
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "uv add %s %s", flag, group)
+	for _, dep := range deps {
+		fmt.Fprintf(&cmd, " \"%s\"", PythonRequirement(dep))
+	}
+	return cmd.String()
+}
+
 // JavaScript generation
 func (g *Generator) generateJavaScript(m *manifest.Manifest, outputDir string) ([]string, error) {
 	pkgPath := filepath.Join(outputDir, "package.json")
 	if err := g.writePackageJson(pkgPath, m); err != nil {
 		return nil, err
 	}
-	return []string{"package.json"}, nil
+	files := []string{"package.json"}
+
+	if m.JSPackageManager() == manifest.JSPackageManagerYarnBerry {
+		if err := os.WriteFile(filepath.Join(outputDir, ".yarnrc.yml"), []byte(YarnrcContent), 0644); err != nil {
+			return nil, err
+		}
+		files = append(files, ".yarnrc.yml")
+	}
+
+	return files, nil
+}
+
+// YarnrcContent is the .yarnrc.yml written for Yarn 2+. aigogo packages are
+// linked into .aigogo/imports and resolve their dependencies from
+// node_modules, which Plug'n'Play does not create.
+const YarnrcContent = `# Generated by aigogo
+# aigogo packages resolve dependencies from node_modules, so Plug'n'Play is disabled
+nodeLinker: node-modules
+`
+
+// NpmVersionRange converts a version constraint to a node-semver range:
+// comma-separated clauses become space-separated (">=1.0.0,<2.0.0" becomes
+// ">=1.0.0 <2.0.0"), "==" becomes an exact version and an empty version
+// matches anything. npm tolerates some other forms; pnpm and Yarn 2+ do not.
+func NpmVersionRange(version string) string {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return "*"
+	}
+	var clauses []string
+	for _, clause := range strings.Split(version, ",") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+		clauses = append(clauses, strings.TrimPrefix(clause, "=="))
+	}
+	return strings.Join(clauses, " ")
 }
 
 func (g *Generator) writePackageJson(path string, m *manifest.Manifest) error {
 	var content strings.Builder
 
+	// pnpm and Yarn 2+ reject ranges npm would accept
+	version := func(dep manifest.Dependency) string { return dep.Version }
+	if m.JSPackageManager() != manifest.JSPackageManagerNpm {
+		version = func(dep manifest.Dependency) string { return NpmVersionRange(dep.Version) }
+	}
+
 	hasRuntime := len(m.Dependencies.Runtime) > 0
 	hasDev := len(m.Dependencies.Dev) > 0
 
@@ -160,7 +287,7 @@ func (g *Generator) writePackageJson(path string, m *manifest.Manifest) error {
 			if i > 0 {
 				content.WriteString(",\n")
 			}
-			fmt.Fprintf(&content, "    \"%s\": \"%s\"", dep.Package, dep.Version)
+			fmt.Fprintf(&content, "    \"%s\": \"%s\"", dep.Package, version(dep))
 		}
 		content.WriteString("\n  },\n")
 	}
@@ -171,7 +298,20 @@ func (g *Generator) writePackageJson(path string, m *manifest.Manifest) error {
 			if i > 0 {
 				content.WriteString(",\n")
 			}
-			fmt.Fprintf(&content, "    \"%s\": \"%s\"", dep.Package, dep.Version)
+			fmt.Fprintf(&content, "    \"%s\": \"%s\"", dep.Package, version(dep))
+		}
+		content.WriteString("\n  },\n")
+	}
+
+	// Peer dependencies are the host project's to install, so they are
+	// not listed as managed below
+	if len(m.Dependencies.Peer) > 0 {
+		content.WriteString("  \"peerDependencies\": {\n")
+		for i, dep := range m.Dependencies.Peer {
+			if i > 0 {
+				content.WriteString(",\n")
+			}
+			fmt.Fprintf(&content, "    \"%s\": \"%s\"", dep.Package, version(dep))
 		}
 		content.WriteString("\n  },\n")
 	}
@@ -275,3 +415,318 @@ func (g *Generator) writeCargoToml(path string, m *manifest.Manifest) error {
 
 	return os.WriteFile(path, []byte(content.String()), 0644)
 }
+
+// Ruby generation
+func (g *Generator) generateRuby(m *manifest.Manifest, outputDir string) ([]string, error) {
+	gemfilePath := filepath.Join(outputDir, "Gemfile")
+	if err := g.writeGemfile(gemfilePath, m); err != nil {
+		return nil, err
+	}
+	return []string{"Gemfile"}, nil
+}
+
+// writeGemfile generates a Gemfile. As with pyproject.toml, dependencies are
+// placed in dedicated :aigogo and :aigogo_dev groups so consumers can identify
+// and remove aigogo-managed gems.
+func (g *Generator) writeGemfile(path string, m *manifest.Manifest) error {
+	var content strings.Builder
+
+	content.WriteString("# Generated by aigogo\n")
+	content.WriteString("source \"https://rubygems.org\"\n")
+
+	if m.Language.Version != "" {
+		fmt.Fprintf(&content, "\nruby %s\n", rubyRequirementArgs(m.Language.Version))
+	}
+
+	if len(m.Dependencies.Runtime) > 0 {
+		content.WriteString("\ngroup :aigogo do\n")
+		for _, dep := range m.Dependencies.Runtime {
+			fmt.Fprintf(&content, "  %s\n", gemLine(dep))
+		}
+		content.WriteString("end\n")
+	}
+
+	if len(m.Dependencies.Dev) > 0 {
+		content.WriteString("\ngroup :aigogo_dev do\n")
+		for _, dep := range m.Dependencies.Dev {
+			fmt.Fprintf(&content, "  %s\n", gemLine(dep))
+		}
+		content.WriteString("end\n")
+	}
+
+	return os.WriteFile(path, []byte(content.String()), 0644)
+}
+
+// gemLine formats a Gemfile entry: gem "rack", "~> 3.0"
+func gemLine(dep manifest.Dependency) string {
+	if strings.TrimSpace(dep.Version) == "" {
+		return fmt.Sprintf("gem \"%s\"", dep.Package)
+	}
+	return fmt.Sprintf("gem \"%s\", %s", dep.Package, rubyRequirementArgs(dep.Version))
+}
+
+// rubyRequirementArgs turns ">= 1.0, < 2.0" into "\">= 1.0\", \"< 2.0\""
+func rubyRequirementArgs(version string) string {
+	var args []string
+	for _, part := range strings.Split(version, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			args = append(args, fmt.Sprintf("\"%s\"", part))
+		}
+	}
+	return strings.Join(args, ", ")
+}
+
+// Java generation
+func (g *Generator) generateJava(m *manifest.Manifest, outputDir string) ([]string, error) {
+	pomPath := filepath.Join(outputDir, "pom.xml")
+	if err := g.writePomXML(pomPath, m); err != nil {
+		return nil, err
+	}
+
+	gradlePath := filepath.Join(outputDir, "build.gradle")
+	if err := g.writeBuildGradle(gradlePath, m); err != nil {
+		return nil, err
+	}
+
+	return []string{"pom.xml", "build.gradle"}, nil
+}
+
+func (g *Generator) writePomXML(path string, m *manifest.Manifest) error {
+	var content strings.Builder
+
+	content.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	content.WriteString("<!-- Generated by aigogo -->\n")
+	content.WriteString("<project xmlns=\"http://maven.apache.org/POM/4.0.0\">\n")
+	content.WriteString("  <modelVersion>4.0.0</modelVersion>\n")
+	content.WriteString("  <groupId>aigogo</groupId>\n")
+	fmt.Fprintf(&content, "  <artifactId>%s</artifactId>\n", xmlEscape(m.Name))
+	fmt.Fprintf(&content, "  <version>%s</version>\n", xmlEscape(m.Version))
+
+	if m.Description != "" {
+		fmt.Fprintf(&content, "  <description>%s</description>\n", xmlEscape(m.Description))
+	}
+
+	if len(m.Dependencies.Runtime) > 0 || len(m.Dependencies.Dev) > 0 {
+		content.WriteString("  <dependencies>\n")
+		for _, dep := range m.Dependencies.Runtime {
+			writePomDependency(&content, dep, "")
+		}
+		for _, dep := range m.Dependencies.Dev {
+			writePomDependency(&content, dep, "test")
+		}
+		content.WriteString("  </dependencies>\n")
+	}
+
+	content.WriteString("</project>\n")
+
+	return os.WriteFile(path, []byte(content.String()), 0644)
+}
+
+func writePomDependency(content *strings.Builder, dep manifest.Dependency, scope string) {
+	groupID, artifactID := MavenCoordinates(dep.Package)
+	content.WriteString("    <dependency>\n")
+	fmt.Fprintf(content, "      <groupId>%s</groupId>\n", xmlEscape(groupID))
+	fmt.Fprintf(content, "      <artifactId>%s</artifactId>\n", xmlEscape(artifactID))
+	if dep.Version != "" {
+		fmt.Fprintf(content, "      <version>%s</version>\n", xmlEscape(dep.Version))
+	}
+	if scope != "" {
+		fmt.Fprintf(content, "      <scope>%s</scope>\n", scope)
+	}
+	content.WriteString("    </dependency>\n")
+}
+
+func (g *Generator) writeBuildGradle(path string, m *manifest.Manifest) error {
+	var content strings.Builder
+
+	content.WriteString("// Generated by aigogo\n")
+	content.WriteString("dependencies {\n")
+	for _, dep := range m.Dependencies.Runtime {
+		fmt.Fprintf(&content, "    implementation '%s'\n", gradleNotation(dep))
+	}
+	for _, dep := range m.Dependencies.Dev {
+		fmt.Fprintf(&content, "    testImplementation '%s'\n", gradleNotation(dep))
+	}
+	content.WriteString("}\n")
+
+	return os.WriteFile(path, []byte(content.String()), 0644)
+}
+
+// MavenCoordinates splits "groupId:artifactId". A bare name is used for both.
+func MavenCoordinates(pkg string) (groupID, artifactID string) {
+	if i := strings.Index(pkg, ":"); i >= 0 {
+		return pkg[:i], pkg[i+1:]
+	}
+	return pkg, pkg
+}
+
+// gradleNotation formats "group:artifact:version"
+func gradleNotation(dep manifest.Dependency) string {
+	groupID, artifactID := MavenCoordinates(dep.Package)
+	if dep.Version == "" {
+		return groupID + ":" + artifactID
+	}
+	return groupID + ":" + artifactID + ":" + dep.Version
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// C# generation
+func (g *Generator) generateCSharp(m *manifest.Manifest, outputDir string) ([]string, error) {
+	name := m.Name + ".csproj"
+	if err := g.writeCsproj(filepath.Join(outputDir, name), m); err != nil {
+		return nil, err
+	}
+	return []string{name}, nil
+}
+
+// writeCsproj generates an SDK-style project file. Runtime and dev packages
+// go in separate ItemGroups labelled "aigogo" and "aigogo-dev"; dev packages
+// are marked PrivateAssets="all" so they don't flow to consumers.
+func (g *Generator) writeCsproj(path string, m *manifest.Manifest) error {
+	var content strings.Builder
+
+	content.WriteString("<!-- Generated by aigogo -->\n")
+	content.WriteString("<Project Sdk=\"Microsoft.NET.Sdk\">\n")
+	content.WriteString("  <PropertyGroup>\n")
+	if strings.HasPrefix(m.Language.Version, "net") {
+		fmt.Fprintf(&content, "    <TargetFramework>%s</TargetFramework>\n", xmlEscape(m.Language.Version))
+	}
+	fmt.Fprintf(&content, "    <PackageId>%s</PackageId>\n", xmlEscape(m.Name))
+	fmt.Fprintf(&content, "    <Version>%s</Version>\n", xmlEscape(m.Version))
+	if m.Description != "" {
+		fmt.Fprintf(&content, "    <Description>%s</Description>\n", xmlEscape(m.Description))
+	}
+	content.WriteString("  </PropertyGroup>\n")
+
+	if len(m.Dependencies.Runtime) > 0 {
+		content.WriteString("  <ItemGroup Label=\"aigogo\">\n")
+		for _, dep := range m.Dependencies.Runtime {
+			fmt.Fprintf(&content, "    %s\n", PackageReference(dep, false))
+		}
+		content.WriteString("  </ItemGroup>\n")
+	}
+
+	if len(m.Dependencies.Dev) > 0 {
+		content.WriteString("  <ItemGroup Label=\"aigogo-dev\">\n")
+		for _, dep := range m.Dependencies.Dev {
+			fmt.Fprintf(&content, "    %s\n", PackageReference(dep, true))
+		}
+		content.WriteString("  </ItemGroup>\n")
+	}
+
+	content.WriteString("</Project>\n")
+
+	return os.WriteFile(path, []byte(content.String()), 0644)
+}
+
+// PackageReference formats a NuGet <PackageReference> element
+func PackageReference(dep manifest.Dependency, dev bool) string {
+	ref := fmt.Sprintf("<PackageReference Include=\"%s\"", xmlEscape(dep.Package))
+	if dep.Version != "" {
+		ref += fmt.Sprintf(" Version=\"%s\"", xmlEscape(dep.Version))
+	}
+	if dev {
+		ref += " PrivateAssets=\"all\""
+	}
+	return ref + " />"
+}
+
+// PHP generation
+func (g *Generator) generatePHP(m *manifest.Manifest, outputDir string) ([]string, error) {
+	composerPath := filepath.Join(outputDir, "composer.json")
+	if err := g.writeComposerJSON(composerPath, m); err != nil {
+		return nil, err
+	}
+	return []string{"composer.json"}, nil
+}
+
+// writeComposerJSON generates a composer.json. As with package.json, an
+// "aigogo" key under "extra" lists the managed packages so consumers can
+// identify and remove them; Composer ignores unknown keys in "extra".
+func (g *Generator) writeComposerJSON(path string, m *manifest.Manifest) error {
+	var content strings.Builder
+
+	hasRuntime := len(m.Dependencies.Runtime) > 0 || m.Language.Version != ""
+	hasDev := len(m.Dependencies.Dev) > 0
+
+	content.WriteString("{\n")
+	fmt.Fprintf(&content, "  \"name\": \"%s\",\n", composerName(m.Name))
+	fmt.Fprintf(&content, "  \"version\": \"%s\",\n", m.Version)
+
+	if m.Description != "" {
+		fmt.Fprintf(&content, "  \"description\": \"%s\",\n", m.Description)
+	}
+
+	if hasRuntime {
+		content.WriteString("  \"require\": {\n")
+		var entries []string
+		if m.Language.Version != "" {
+			entries = append(entries, fmt.Sprintf("    \"php\": \"%s\"", m.Language.Version))
+		}
+		for _, dep := range m.Dependencies.Runtime {
+			entries = append(entries, fmt.Sprintf("    \"%s\": \"%s\"", dep.Package, composerConstraint(dep.Version)))
+		}
+		content.WriteString(strings.Join(entries, ",\n"))
+		content.WriteString("\n  },\n")
+	}
+
+	if hasDev {
+		content.WriteString("  \"require-dev\": {\n")
+		for i, dep := range m.Dependencies.Dev {
+			if i > 0 {
+				content.WriteString(",\n")
+			}
+			fmt.Fprintf(&content, "    \"%s\": \"%s\"", dep.Package, composerConstraint(dep.Version))
+		}
+		content.WriteString("\n  },\n")
+	}
+
+	content.WriteString("  \"extra\": {\n")
+	content.WriteString("    \"aigogo\": {\n")
+	content.WriteString("      \"managed-dependencies\": [")
+	for i, dep := range m.Dependencies.Runtime {
+		if i > 0 {
+			content.WriteString(", ")
+		}
+		fmt.Fprintf(&content, "\"%s\"", dep.Package)
+	}
+	content.WriteString("],\n")
+	content.WriteString("      \"managed-dev-dependencies\": [")
+	for i, dep := range m.Dependencies.Dev {
+		if i > 0 {
+			content.WriteString(", ")
+		}
+		fmt.Fprintf(&content, "\"%s\"", dep.Package)
+	}
+	content.WriteString("]\n")
+	content.WriteString("    }\n")
+	content.WriteString("  }\n")
+
+	content.WriteString("}\n")
+
+	return os.WriteFile(path, []byte(content.String()), 0644)
+}
+
+// composerName returns a valid "vendor/package" name. Packages without a
+// vendor are placed under "aigogo/".
+func composerName(name string) string {
+	name = strings.ToLower(name)
+	if strings.Contains(name, "/") {
+		return name
+	}
+	return "aigogo/" + name
+}
+
+// composerConstraint converts a manifest version to Composer syntax.
+// An empty version means any version.
+func composerConstraint(version string) string {
+	if strings.TrimSpace(version) == "" {
+		return "*"
+	}
+	return version
+}
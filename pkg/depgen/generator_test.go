@@ -1,6 +1,7 @@
 package depgen
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
@@ -65,9 +66,14 @@ func TestGeneratePython(t *testing.T) {
 			Runtime: []manifest.Dependency{
 				{Package: "requests", Version: ">=2.31.0"},
 				{Package: "click", Version: "~=8.1.0"},
+				{Package: "tomli", Version: ">=2.0", Marker: `python_version < "3.11"`},
 			},
 			Dev: []manifest.Dependency{
 				{Package: "pytest", Version: ">=7.0.0"},
+				{Package: "coverage", Version: ">=7.0", Extras: []string{"toml"}},
+			},
+			Extras: map[string][]manifest.Dependency{
+				"viz": {{Package: "matplotlib", Version: ">=3.7"}},
 			},
 		},
 	}
@@ -93,6 +99,9 @@ func TestGeneratePython(t *testing.T) {
 	if !strings.Contains(string(reqContent), "click~=8.1.0") {
 		t.Error("requirements.txt missing click")
 	}
+	if !strings.Contains(string(reqContent), "tomli>=2.0; python_version < '3.11'") {
+		t.Error("requirements.txt missing tomli with its environment marker")
+	}
 
 	// Check pyproject.toml
 	pyprojectContent, err := os.ReadFile(filepath.Join(tmpDir, "pyproject.toml"))
@@ -122,12 +131,92 @@ func TestGeneratePython(t *testing.T) {
 	if !strings.Contains(pyStr, "pytest>=7.0.0") {
 		t.Error("pyproject.toml missing pytest in aigogo-dev group")
 	}
+	if !strings.Contains(pyStr, "aigogo-viz = [\n    \"matplotlib>=3.7\",\n]") {
+		t.Error("pyproject.toml missing aigogo-viz group for the viz extra")
+	}
+	if strings.Contains(string(reqContent), "matplotlib") {
+		t.Error("requirements.txt should not include extras")
+	}
+	if !strings.Contains(pyStr, `"coverage[toml]>=7.0",`) {
+		t.Error("pyproject.toml missing coverage with its extras")
+	}
+	if !strings.Contains(pyStr, `"tomli>=2.0; python_version < '3.11'",`) {
+		t.Error("pyproject.toml missing tomli with its environment marker")
+	}
 	// Runtime deps should NOT be in a top-level dependencies section
 	if strings.Contains(pyStr, "dependencies = [") {
 		t.Error("pyproject.toml should not have top-level dependencies = [...], use [project.optional-dependencies] aigogo instead")
 	}
 }
 
+func TestGeneratePythonDependencyGroups(t *testing.T) {
+	g := NewGenerator()
+	tmpDir := t.TempDir()
+
+	m := &manifest.Manifest{
+		Name:      "my-package",
+		Version:   "1.0.0",
+		Language:  manifest.Language{Name: "python", Version: ">=3.8"},
+		Generator: &manifest.GeneratorSpec{Python: manifest.PythonStyleDependencyGroups},
+		Dependencies: &manifest.Dependencies{
+			Runtime: []manifest.Dependency{{Package: "requests", Version: ">=2.31.0"}},
+			Dev:     []manifest.Dependency{{Package: "pytest", Version: ">=7.0.0"}},
+		},
+	}
+
+	if _, err := g.Generate(m, tmpDir); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "pyproject.toml"))
+	if err != nil {
+		t.Fatalf("Failed to read pyproject.toml: %v", err)
+	}
+
+	pyStr := string(content)
+	if !strings.Contains(pyStr, "[dependency-groups]\naigogo = [\n    \"requests>=2.31.0\",\n]\naigogo-dev = [\n    \"pytest>=7.0.0\",\n]\n") {
+		t.Errorf("pyproject.toml missing [dependency-groups] with aigogo groups:\n%s", pyStr)
+	}
+	if strings.Contains(pyStr, "[project.optional-dependencies]") {
+		t.Error("pyproject.toml should not have [project.optional-dependencies] with dependency-groups style")
+	}
+}
+
+func TestPythonRequirement(t *testing.T) {
+	tests := []struct {
+		dep  manifest.Dependency
+		want string
+	}{
+		{manifest.Dependency{Package: "requests", Version: ">=2.31"}, "requests>=2.31"},
+		{manifest.Dependency{Package: "rich", Version: "*"}, "rich"},
+		{manifest.Dependency{Package: "requests", Version: ">=2.31", Extras: []string{"socks", "security"}}, "requests[socks,security]>=2.31"},
+		{manifest.Dependency{Package: "tomli", Version: "*", Marker: `python_version < "3.11"`}, "tomli; python_version < '3.11'"},
+	}
+
+	for _, tt := range tests {
+		if got := PythonRequirement(tt.dep); got != tt.want {
+			t.Errorf("PythonRequirement(%+v) = %q, want %q", tt.dep, got, tt.want)
+		}
+	}
+}
+
+func TestUvAddCommand(t *testing.T) {
+	deps := []manifest.Dependency{
+		{Package: "requests", Version: ">=2.31.0"},
+		{Package: "rich", Version: "*"},
+	}
+
+	m := &manifest.Manifest{}
+	if got, want := UvAddCommand(m, "aigogo", deps), `uv add --optional aigogo "requests>=2.31.0" "rich"`; got != want {
+		t.Errorf("UvAddCommand() = %q, want %q", got, want)
+	}
+
+	m.Generator = &manifest.GeneratorSpec{Python: manifest.PythonStyleDependencyGroups}
+	if got, want := UvAddCommand(m, "aigogo-dev", deps), `uv add --group aigogo-dev "requests>=2.31.0" "rich"`; got != want {
+		t.Errorf("UvAddCommand() = %q, want %q", got, want)
+	}
+}
+
 func TestGenerateJavaScript(t *testing.T) {
 	g := NewGenerator()
 	tmpDir := t.TempDir()
@@ -280,6 +369,237 @@ func TestGenerateRust(t *testing.T) {
 	}
 }
 
+func TestGenerateRuby(t *testing.T) {
+	g := NewGenerator()
+	tmpDir := t.TempDir()
+
+	m := &manifest.Manifest{
+		Name:     "my-gem",
+		Version:  "1.0.0",
+		Language: manifest.Language{Name: "ruby", Version: ">= 3.0"},
+		Dependencies: &manifest.Dependencies{
+			Runtime: []manifest.Dependency{
+				{Package: "faraday", Version: "~> 2.7"},
+				{Package: "nokogiri", Version: ">= 1.15, < 2.0"},
+			},
+			Dev: []manifest.Dependency{
+				{Package: "rspec", Version: "~> 3.12"},
+			},
+		},
+	}
+
+	files, err := g.Generate(m, tmpDir)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if len(files) != 1 || files[0] != "Gemfile" {
+		t.Errorf("Expected [Gemfile], got %v", files)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "Gemfile"))
+	if err != nil {
+		t.Fatalf("Failed to read Gemfile: %v", err)
+	}
+
+	gemfile := string(content)
+	for _, want := range []string{
+		`source "https://rubygems.org"`,
+		`ruby ">= 3.0"`,
+		"group :aigogo do",
+		`gem "faraday", "~> 2.7"`,
+		`gem "nokogiri", ">= 1.15", "< 2.0"`,
+		"group :aigogo_dev do",
+		`gem "rspec", "~> 3.12"`,
+	} {
+		if !strings.Contains(gemfile, want) {
+			t.Errorf("Gemfile missing %q:\n%s", want, gemfile)
+		}
+	}
+}
+
+func TestGenerateJava(t *testing.T) {
+	g := NewGenerator()
+	tmpDir := t.TempDir()
+
+	m := &manifest.Manifest{
+		Name:     "my-lib",
+		Version:  "1.0.0",
+		Language: manifest.Language{Name: "java", Version: ">=17"},
+		Dependencies: &manifest.Dependencies{
+			Runtime: []manifest.Dependency{
+				{Package: "com.google.code.gson:gson", Version: "2.10.1"},
+			},
+			Dev: []manifest.Dependency{
+				{Package: "org.junit.jupiter:junit-jupiter", Version: "5.10.0"},
+			},
+		},
+	}
+
+	files, err := g.Generate(m, tmpDir)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if len(files) != 2 {
+		t.Errorf("Expected 2 files, got %v", files)
+	}
+
+	pom, err := os.ReadFile(filepath.Join(tmpDir, "pom.xml"))
+	if err != nil {
+		t.Fatalf("Failed to read pom.xml: %v", err)
+	}
+	pomStr := string(pom)
+	for _, want := range []string{
+		"<artifactId>my-lib</artifactId>",
+		"<groupId>com.google.code.gson</groupId>",
+		"<artifactId>gson</artifactId>",
+		"<version>2.10.1</version>",
+		"<scope>test</scope>",
+	} {
+		if !strings.Contains(pomStr, want) {
+			t.Errorf("pom.xml missing %q", want)
+		}
+	}
+
+	gradle, err := os.ReadFile(filepath.Join(tmpDir, "build.gradle"))
+	if err != nil {
+		t.Fatalf("Failed to read build.gradle: %v", err)
+	}
+	gradleStr := string(gradle)
+	if !strings.Contains(gradleStr, "implementation 'com.google.code.gson:gson:2.10.1'") {
+		t.Error("build.gradle missing gson")
+	}
+	if !strings.Contains(gradleStr, "testImplementation 'org.junit.jupiter:junit-jupiter:5.10.0'") {
+		t.Error("build.gradle missing junit")
+	}
+}
+
+func TestGenerateCSharp(t *testing.T) {
+	g := NewGenerator()
+	tmpDir := t.TempDir()
+
+	m := &manifest.Manifest{
+		Name:     "MyUtils",
+		Version:  "1.0.0",
+		Language: manifest.Language{Name: "csharp", Version: "net8.0"},
+		Dependencies: &manifest.Dependencies{
+			Runtime: []manifest.Dependency{
+				{Package: "Newtonsoft.Json", Version: "13.0.3"},
+			},
+			Dev: []manifest.Dependency{
+				{Package: "xunit", Version: "2.6.1"},
+			},
+		},
+	}
+
+	files, err := g.Generate(m, tmpDir)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if len(files) != 1 || files[0] != "MyUtils.csproj" {
+		t.Errorf("Expected [MyUtils.csproj], got %v", files)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "MyUtils.csproj"))
+	if err != nil {
+		t.Fatalf("Failed to read csproj: %v", err)
+	}
+
+	csproj := string(content)
+	for _, want := range []string{
+		"<TargetFramework>net8.0</TargetFramework>",
+		`<ItemGroup Label="aigogo">`,
+		`<PackageReference Include="Newtonsoft.Json" Version="13.0.3" />`,
+		`<ItemGroup Label="aigogo-dev">`,
+		`<PackageReference Include="xunit" Version="2.6.1" PrivateAssets="all" />`,
+	} {
+		if !strings.Contains(csproj, want) {
+			t.Errorf("csproj missing %q:\n%s", want, csproj)
+		}
+	}
+}
+
+func TestGeneratePHP(t *testing.T) {
+	g := NewGenerator()
+	tmpDir := t.TempDir()
+
+	m := &manifest.Manifest{
+		Name:     "my-utils",
+		Version:  "1.0.0",
+		Language: manifest.Language{Name: "php", Version: ">=8.1"},
+		Dependencies: &manifest.Dependencies{
+			Runtime: []manifest.Dependency{
+				{Package: "guzzlehttp/guzzle", Version: "^7.8"},
+				{Package: "monolog/monolog", Version: ""},
+			},
+			Dev: []manifest.Dependency{
+				{Package: "phpunit/phpunit", Version: "^10.5"},
+			},
+		},
+	}
+
+	files, err := g.Generate(m, tmpDir)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if len(files) != 1 || files[0] != "composer.json" {
+		t.Errorf("Expected [composer.json], got %v", files)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "composer.json"))
+	if err != nil {
+		t.Fatalf("Failed to read composer.json: %v", err)
+	}
+
+	var composer struct {
+		Name       string            `json:"name"`
+		Require    map[string]string `json:"require"`
+		RequireDev map[string]string `json:"require-dev"`
+		Extra      struct {
+			Aigogo struct {
+				Managed    []string `json:"managed-dependencies"`
+				ManagedDev []string `json:"managed-dev-dependencies"`
+			} `json:"aigogo"`
+		} `json:"extra"`
+	}
+	if err := json.Unmarshal(content, &composer); err != nil {
+		t.Fatalf("composer.json is not valid JSON: %v\n%s", err, content)
+	}
+
+	if composer.Name != "aigogo/my-utils" {
+		t.Errorf("name = %q, want aigogo/my-utils", composer.Name)
+	}
+	if composer.Require["php"] != ">=8.1" {
+		t.Errorf("require.php = %q", composer.Require["php"])
+	}
+	if composer.Require["guzzlehttp/guzzle"] != "^7.8" {
+		t.Errorf("require guzzle = %q", composer.Require["guzzlehttp/guzzle"])
+	}
+	if composer.Require["monolog/monolog"] != "*" {
+		t.Errorf("require monolog = %q, want *", composer.Require["monolog/monolog"])
+	}
+	if composer.RequireDev["phpunit/phpunit"] != "^10.5" {
+		t.Errorf("require-dev phpunit = %q", composer.RequireDev["phpunit/phpunit"])
+	}
+	if len(composer.Extra.Aigogo.Managed) != 2 || len(composer.Extra.Aigogo.ManagedDev) != 1 {
+		t.Errorf("extra.aigogo = %+v", composer.Extra.Aigogo)
+	}
+}
+
+func TestMavenCoordinates(t *testing.T) {
+	group, artifact := MavenCoordinates("com.google.code.gson:gson")
+	if group != "com.google.code.gson" || artifact != "gson" {
+		t.Errorf("MavenCoordinates = %q, %q", group, artifact)
+	}
+	group, artifact = MavenCoordinates("junit")
+	if group != "junit" || artifact != "junit" {
+		t.Errorf("MavenCoordinates(bare) = %q, %q", group, artifact)
+	}
+}
+
 func TestGeneratePythonNoDevDeps(t *testing.T) {
 	g := NewGenerator()
 	tmpDir := t.TempDir()
@@ -319,6 +639,70 @@ func TestGeneratePythonNoDevDeps(t *testing.T) {
 	}
 }
 
+func TestGenerateJavaScriptYarnBerry(t *testing.T) {
+	g := NewGenerator()
+	tmpDir := t.TempDir()
+
+	m := &manifest.Manifest{
+		Name:      "my-package",
+		Version:   "1.0.0",
+		Language:  manifest.Language{Name: "javascript"},
+		Generator: &manifest.GeneratorSpec{JavaScript: manifest.JSPackageManagerYarnBerry},
+		Dependencies: &manifest.Dependencies{
+			Runtime: []manifest.Dependency{{Package: "axios", Version: ">=1.6.0,<2.0.0"}},
+			Dev:     []manifest.Dependency{{Package: "jest", Version: "==29.7.0"}},
+		},
+	}
+
+	files, err := g.Generate(m, tmpDir)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(files) != 2 || files[1] != ".yarnrc.yml" {
+		t.Errorf("Expected package.json and .yarnrc.yml, got %v", files)
+	}
+
+	yarnrc, err := os.ReadFile(filepath.Join(tmpDir, ".yarnrc.yml"))
+	if err != nil {
+		t.Fatalf("Failed to read .yarnrc.yml: %v", err)
+	}
+	if !strings.Contains(string(yarnrc), "nodeLinker: node-modules") {
+		t.Error(".yarnrc.yml missing nodeLinker: node-modules")
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "package.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonStr := string(content)
+	if !strings.Contains(jsonStr, `"axios": ">=1.6.0 <2.0.0"`) {
+		t.Errorf("package.json should use a semver range for axios:\n%s", jsonStr)
+	}
+	if !strings.Contains(jsonStr, `"jest": "29.7.0"`) {
+		t.Errorf("package.json should use an exact version for jest:\n%s", jsonStr)
+	}
+}
+
+func TestNpmVersionRange(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"^1.6.0", "^1.6.0"},
+		{">=1.0.0,<2.0.0", ">=1.0.0 <2.0.0"},
+		{">=1.0.0, <2.0.0", ">=1.0.0 <2.0.0"},
+		{"==1.2.3", "1.2.3"},
+		{"", "*"},
+		{"*", "*"},
+	}
+
+	for _, tt := range tests {
+		if got := NpmVersionRange(tt.version); got != tt.want {
+			t.Errorf("NpmVersionRange(%q) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
 func TestGenerateJavaScriptNoDevDeps(t *testing.T) {
 	g := NewGenerator()
 	tmpDir := t.TempDir()
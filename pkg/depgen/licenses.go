@@ -0,0 +1,166 @@
+package depgen
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// pypiClassifierLicenses maps common "License :: ..." trove classifiers to
+// SPDX identifiers
+var pypiClassifierLicenses = map[string]string{
+	"MIT License":                                         "MIT",
+	"Apache Software License":                             "Apache-2.0",
+	"BSD License":                                         "BSD",
+	"ISC License (ISCL)":                                  "ISC",
+	"The Unlicense (Unlicense)":                           "Unlicense",
+	"Python Software Foundation License":                  "PSF-2.0",
+	"Mozilla Public License 2.0 (MPL 2.0)":                "MPL-2.0",
+	"GNU General Public License v2 (GPLv2)":               "GPL-2.0",
+	"GNU General Public License v3 (GPLv3)":               "GPL-3.0",
+	"GNU Lesser General Public License v2 (LGPLv2)":       "LGPL-2.0",
+	"GNU Lesser General Public License v3 (LGPLv3)":       "LGPL-3.0",
+	"GNU Affero General Public License v3":                "AGPL-3.0",
+	"GNU Library or Lesser General Public License (LGPL)": "LGPL",
+	"GNU General Public License (GPL)":                    "GPL",
+	"Eclipse Public License 2.0 (EPL-2.0)":                "EPL-2.0",
+	"Historical Permission Notice and Disclaimer (HPND)":  "HPND",
+	"Zope Public License":                                 "ZPL",
+}
+
+// pypiLicense picks the most precise license PyPI reports: the PEP 639
+// license_expression, then a short license field, then trove classifiers.
+// The license field often holds the full license text, which is ignored.
+func pypiLicense(expression, license string, classifiers []string) string {
+	if expression = strings.TrimSpace(expression); expression != "" {
+		return expression
+	}
+
+	if license = strings.TrimSpace(license); license != "" && len(license) <= 64 && !strings.Contains(license, "\n") {
+		return license
+	}
+
+	var ids []string
+	for _, c := range classifiers {
+		if !strings.HasPrefix(c, "License :: ") {
+			continue
+		}
+		name := c[strings.LastIndex(c, " :: ")+4:]
+		if id, ok := pypiClassifierLicenses[name]; ok {
+			name = id
+		}
+		ids = append(ids, name)
+	}
+	return strings.Join(ids, " OR ")
+}
+
+// npmLicense reads the npm "license" field, which is normally an SPDX string
+// but is an object ({"type": "MIT"}) in some older packages
+func npmLicense(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var obj struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &obj); err == nil {
+		return obj.Type
+	}
+	return ""
+}
+
+// LicensePolicy is an allowlist/denylist of license identifiers.
+// Identifiers are compared case-insensitively.
+type LicensePolicy struct {
+	allow map[string]bool
+	deny  map[string]bool
+}
+
+// NewLicensePolicy creates a policy from comma-separated allow and deny lists.
+// An empty allow list allows every license that is not denied.
+func NewLicensePolicy(allow, deny string) *LicensePolicy {
+	return &LicensePolicy{
+		allow: licenseSet(allow),
+		deny:  licenseSet(deny),
+	}
+}
+
+func licenseSet(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, id := range strings.Split(list, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			set[strings.ToLower(id)] = true
+		}
+	}
+	return set
+}
+
+// Enabled reports whether the policy has any rules
+func (p *LicensePolicy) Enabled() bool {
+	return len(p.allow) > 0 || len(p.deny) > 0
+}
+
+// Check reports whether a license expression satisfies the policy, with a
+// reason when it does not. "A OR B" passes if either alternative passes;
+// "A AND B" passes only if both do. An unknown (empty) license fails only
+// when an allow list is set.
+func (p *LicensePolicy) Check(expression string) (bool, string) {
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		if len(p.allow) > 0 {
+			return false, "license unknown"
+		}
+		return true, ""
+	}
+
+	var reason string
+	for _, alt := range splitLicenseExpr(expression, " OR ") {
+		ok := true
+		for _, id := range splitLicenseExpr(alt, " AND ") {
+			if passed, why := p.checkID(id); !passed {
+				ok = false
+				reason = why
+				break
+			}
+		}
+		if ok {
+			return true, ""
+		}
+	}
+	return false, reason
+}
+
+func (p *LicensePolicy) checkID(id string) (bool, string) {
+	key := strings.ToLower(id)
+	if p.deny[key] {
+		return false, id + " is denied"
+	}
+	if len(p.allow) > 0 && !p.allow[key] {
+		return false, id + " is not in the allow list"
+	}
+	return true, ""
+}
+
+// splitLicenseExpr splits an SPDX expression on an operator, ignoring case
+// and stripping parentheses from the parts
+func splitLicenseExpr(expr, op string) []string {
+	var parts []string
+	upper := strings.ToUpper(expr)
+	for {
+		i := strings.Index(upper, op)
+		if i < 0 {
+			break
+		}
+		parts = append(parts, expr[:i])
+		expr, upper = expr[i+len(op):], upper[i+len(op):]
+	}
+	parts = append(parts, expr)
+
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), "()")
+	}
+	return parts
+}
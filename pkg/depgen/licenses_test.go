@@ -0,0 +1,100 @@
+package depgen
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPypiLicense(t *testing.T) {
+	tests := []struct {
+		name        string
+		expression  string
+		license     string
+		classifiers []string
+		want        string
+	}{
+		{"expression wins", "MIT OR Apache-2.0", "MIT", nil, "MIT OR Apache-2.0"},
+		{"short license field", "", "BSD-3-Clause", nil, "BSD-3-Clause"},
+		{"license text falls back to classifiers", "", strings.Repeat("Permission is hereby granted ", 10),
+			[]string{"Programming Language :: Python", "License :: OSI Approved :: MIT License"}, "MIT"},
+		{"unmapped classifier", "", "", []string{"License :: OSI Approved :: Some Custom License"}, "Some Custom License"},
+		{"multiple classifiers", "", "", []string{
+			"License :: OSI Approved :: Apache Software License",
+			"License :: OSI Approved :: BSD License",
+		}, "Apache-2.0 OR BSD"},
+		{"nothing", "", "", nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got := pypiLicense(tt.expression, tt.license, tt.classifiers); got != tt.want {
+			t.Errorf("%s: pypiLicense = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNpmLicense(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{`"MIT"`, "MIT"},
+		{`{"type": "BSD-2-Clause", "url": "https://example.com"}`, "BSD-2-Clause"},
+		{``, ""},
+		{`[1, 2]`, ""},
+	}
+
+	for _, tt := range tests {
+		if got := npmLicense(json.RawMessage(tt.raw)); got != tt.want {
+			t.Errorf("npmLicense(%s) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestLicensePolicy(t *testing.T) {
+	tests := []struct {
+		allow   string
+		deny    string
+		license string
+		ok      bool
+	}{
+		// No policy
+		{"", "", "GPL-3.0", true},
+		{"", "", "", true},
+		// Deny list
+		{"", "GPL-3.0,AGPL-3.0", "MIT", true},
+		{"", "GPL-3.0,AGPL-3.0", "gpl-3.0", false},
+		{"", "GPL-3.0", "GPL-3.0 OR MIT", true},
+		{"", "GPL-3.0", "GPL-3.0 AND MIT", false},
+		{"", "GPL-3.0", "", true},
+		// Allow list
+		{"MIT,Apache-2.0", "", "MIT", true},
+		{"MIT,Apache-2.0", "", "BSD-3-Clause", false},
+		{"MIT,Apache-2.0", "", "(MIT OR BSD-3-Clause)", true},
+		{"MIT,Apache-2.0", "", "MIT AND Apache-2.0", true},
+		{"MIT,Apache-2.0", "", "MIT AND BSD-3-Clause", false},
+		{"MIT,Apache-2.0", "", "", false},
+		// Deny takes precedence
+		{"MIT", "MIT", "MIT", false},
+	}
+
+	for _, tt := range tests {
+		p := NewLicensePolicy(tt.allow, tt.deny)
+		ok, reason := p.Check(tt.license)
+		if ok != tt.ok {
+			t.Errorf("allow=%q deny=%q Check(%q) = %v (%s), want %v", tt.allow, tt.deny, tt.license, ok, reason, tt.ok)
+		}
+		if !ok && reason == "" {
+			t.Errorf("Check(%q) failed without a reason", tt.license)
+		}
+	}
+}
+
+func TestLicensePolicyEnabled(t *testing.T) {
+	if NewLicensePolicy("", " , ").Enabled() {
+		t.Error("empty policy should not be enabled")
+	}
+	if !NewLicensePolicy("MIT", "").Enabled() {
+		t.Error("policy with allow list should be enabled")
+	}
+}
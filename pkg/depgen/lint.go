@@ -0,0 +1,189 @@
+package depgen
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// SeverityOff disables a lint rule
+const SeverityOff = manifest.LintOff
+
+// Lint rule IDs, stable for SARIF consumers and lint.rules in aigogo.json
+const (
+	RuleMissingDescription     = "missing-description"
+	RuleMissingLicense         = "missing-license"
+	RuleMissingRepository      = "missing-repository"
+	RuleAutoInclude            = "auto-include"
+	RuleExactRuntimeVersion    = "exact-runtime-version"
+	RuleMissingLanguageVersion = "missing-language-version"
+	RuleMissingAIMetadata      = "missing-ai-metadata"
+	RuleSensitiveFile          = "sensitive-file"
+	RuleLargeFile              = "large-file"
+	RuleConsumerStateIncluded  = "consumer-state-included"
+)
+
+// LintRules describes each lint rule and its default severity
+var LintRules = []RuleInfo{
+	{RuleMissingDescription, "Package has no description", SeverityWarning},
+	{RuleMissingLicense, "Package has no metadata.license", SeverityWarning},
+	{RuleMissingRepository, "Package has no metadata.repository", SeverityInfo},
+	{RuleAutoInclude, "files.include is \"auto\", so any new file is published", SeverityWarning},
+	{RuleExactRuntimeVersion, "Runtime dependency is pinned to an exact version, which causes conflicts for consumers", SeverityWarning},
+	{RuleMissingLanguageVersion, "Language has no version constraint", SeverityInfo},
+	{RuleMissingAIMetadata, "Package has no ai section for agents to discover it", SeverityInfo},
+	{RuleSensitiveFile, "Included file looks like a secret or credential", SeverityError},
+	{RuleLargeFile, "Included file is larger than 1 MiB", SeverityWarning},
+	{RuleConsumerStateIncluded, "Included file belongs to a consuming project (aigogo.lock or .aigogo/)", SeverityWarning},
+}
+
+// initDescription is the placeholder description written by aigg init
+const initDescription = "An AI agent"
+
+// largeFileSize is the size above which an included file is reported
+const largeFileSize = 1 << 20
+
+// LintResult contains the findings of Lint
+type LintResult struct {
+	Findings []Finding
+}
+
+// Failed reports whether linting failed: on errors, and in strict mode on
+// warnings as well
+func (r *LintResult) Failed(strict bool) bool {
+	for _, f := range r.Findings {
+		if f.Severity == SeverityError || (strict && f.Severity == SeverityWarning) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lint checks a package for problems that don't stop it building but
+// matter once it is published: missing metadata, loose file selection,
+// exact pins and files that shouldn't ship. files are the included files,
+// relative to dir. The severity of each rule can be changed, or the rule
+// turned off, with lint.rules in the manifest.
+func Lint(m *manifest.Manifest, dir string, files []string) (*LintResult, error) {
+	severities := make(map[string]string, len(LintRules))
+	for _, rule := range LintRules {
+		severities[rule.ID] = rule.Severity
+	}
+	if m.Lint != nil {
+		for id, severity := range m.Lint.Rules {
+			if _, ok := severities[id]; !ok {
+				return nil, fmt.Errorf("unknown lint rule in lint.rules: %s (run 'aigg lint --list-rules')", id)
+			}
+			severities[id] = severity
+		}
+	}
+
+	result := &LintResult{Findings: []Finding{}}
+	add := func(f Finding) {
+		f.Severity = severities[f.Rule]
+		if f.Severity != SeverityOff {
+			result.Findings = append(result.Findings, f)
+		}
+	}
+
+	lintManifest(m, add)
+
+	sorted := append([]string(nil), files...)
+	sort.Strings(sorted)
+	for _, file := range sorted {
+		rel := filepath.ToSlash(file)
+		switch {
+		case isSensitiveFile(path.Base(rel)):
+			add(Finding{Rule: RuleSensitiveFile, File: file,
+				Message: fmt.Sprintf("%s looks like a secret; add it to files.exclude or .aigogoignore", rel)})
+		case path.Base(rel) == "aigogo.lock" || rel == ".aigogo" || strings.HasPrefix(rel, ".aigogo/"):
+			add(Finding{Rule: RuleConsumerStateIncluded, File: file,
+				Message: fmt.Sprintf("%s is installed state of a consuming project and should not be published", rel)})
+		}
+
+		info, err := os.Stat(filepath.Join(dir, file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", file, err)
+		}
+		if info.Size() > largeFileSize {
+			add(Finding{Rule: RuleLargeFile, File: file,
+				Message: fmt.Sprintf("%s is %.1f MiB", rel, float64(info.Size())/(1<<20))})
+		}
+	}
+
+	return result, nil
+}
+
+// lintManifest reports the rules that only need aigogo.json
+func lintManifest(m *manifest.Manifest, add func(Finding)) {
+	if desc := strings.TrimSpace(m.Description); desc == "" || desc == initDescription {
+		add(Finding{Rule: RuleMissingDescription, Field: "description",
+			Message: "description is missing; it is shown by aigg list and pushed as an OCI annotation"})
+	}
+	if m.Metadata.License == "" {
+		add(Finding{Rule: RuleMissingLicense, Field: "license",
+			Message: "metadata.license is missing; consumers can't tell whether they may use the package"})
+	}
+	if m.Metadata.Repository == "" {
+		add(Finding{Rule: RuleMissingRepository, Field: "repository",
+			Message: "metadata.repository is missing"})
+	}
+	if include, ok := m.Files.Include.(string); ok && include == "auto" {
+		add(Finding{Rule: RuleAutoInclude, Field: "include",
+			Message: `files.include is "auto"; list the files to publish so new files aren't shipped by accident`})
+	}
+
+	for _, lang := range m.AllLanguages() {
+		if lang.Version == "" {
+			add(Finding{Rule: RuleMissingLanguageVersion, Field: "language",
+				Message: fmt.Sprintf("%s has no version constraint", lang.Name)})
+		}
+	}
+
+	if m.Dependencies != nil {
+		v := NewValidator()
+		for _, dep := range m.Dependencies.Runtime {
+			lang := dep.Language
+			if lang == "" {
+				lang = m.Language.Name
+			}
+			if v.hasExactVersion(dep.Version, lang) && !v.hasNoVersion(dep.Version) {
+				add(Finding{Rule: RuleExactRuntimeVersion, Package: dep.Package,
+					Message: fmt.Sprintf("%s is pinned to %s; use a range so consumers can resolve it alongside other packages", dep.Package, dep.Version)})
+			}
+		}
+	}
+
+	if m.AI == nil {
+		add(Finding{Rule: RuleMissingAIMetadata,
+			Message: "no ai section; add a summary and capabilities so agents can discover the package"})
+	}
+}
+
+// isSensitiveFile reports whether a file name looks like a secret
+func isSensitiveFile(name string) bool {
+	lower := strings.ToLower(name)
+	switch lower {
+	case ".npmrc", ".pypirc", ".netrc", "credentials.json",
+		"id_rsa", "id_dsa", "id_ecdsa", "id_ed25519":
+		return true
+	}
+	if lower == ".env" || strings.HasPrefix(lower, ".env.") {
+		for _, suffix := range []string{".example", ".sample", ".template"} {
+			if strings.HasSuffix(lower, suffix) {
+				return false
+			}
+		}
+		return true
+	}
+	switch path.Ext(lower) {
+	case ".pem", ".key", ".p12", ".pfx", ".keystore":
+		return true
+	}
+	return false
+}
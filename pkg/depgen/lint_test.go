@@ -0,0 +1,241 @@
+package depgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// completeManifest returns a manifest that passes every lint rule
+func completeManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Name:        "my_utils",
+		Version:     "1.0.0",
+		Description: "HTTP helpers",
+		Language:    manifest.Language{Name: "python", Version: ">=3.8"},
+		Dependencies: &manifest.Dependencies{
+			Runtime: []manifest.Dependency{{Package: "requests", Version: ">=2.31,<3"}},
+			Dev:     []manifest.Dependency{{Package: "pytest", Version: "==8.0.0"}},
+		},
+		Files:    manifest.FileSpec{Include: []interface{}{"*.py"}},
+		Metadata: manifest.Metadata{License: "MIT", Repository: "https://github.com/org/my-utils"},
+		AI:       &manifest.AISpec{Summary: "HTTP helpers", Capabilities: []string{"fetch"}},
+	}
+}
+
+func lintRules(findings []Finding) []string {
+	var rules []string
+	for _, f := range findings {
+		rules = append(rules, f.Severity+" "+f.Rule)
+	}
+	return rules
+}
+
+func TestLintManifest(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(m *manifest.Manifest)
+		want   []string
+	}{
+		{name: "complete manifest", modify: func(m *manifest.Manifest) {}},
+		{
+			name: "init defaults",
+			modify: func(m *manifest.Manifest) {
+				m.Description = "An AI agent"
+				m.Language.Version = ""
+				m.Dependencies = nil
+				m.Files.Include = "auto"
+				m.Metadata = manifest.Metadata{}
+				m.AI = nil
+			},
+			want: []string{
+				"warning missing-description",
+				"warning missing-license",
+				"info missing-repository",
+				"warning auto-include",
+				"info missing-language-version",
+				"info missing-ai-metadata",
+			},
+		},
+		{
+			name: "exact runtime pins per language",
+			modify: func(m *manifest.Manifest) {
+				m.Languages = []manifest.Language{{Name: "javascript", Version: ">=18"}}
+				m.Dependencies.Runtime = []manifest.Dependency{
+					{Package: "requests", Version: "==2.31.0"},
+					{Package: "flask", Version: ">=3.0"},
+					{Package: "axios", Version: "1.6.0", Language: "javascript"},
+					{Package: "lodash", Version: "^4.17.0", Language: "javascript"},
+					{Package: "chalk", Version: "", Language: "javascript"},
+				}
+			},
+			want: []string{"warning exact-runtime-version", "warning exact-runtime-version"},
+		},
+		{
+			name: "configured severities",
+			modify: func(m *manifest.Manifest) {
+				m.Metadata = manifest.Metadata{}
+				m.Lint = &manifest.LintSpec{Rules: map[string]string{
+					RuleMissingLicense:    manifest.LintError,
+					RuleMissingRepository: manifest.LintOff,
+				}}
+			},
+			want: []string{"error missing-license"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := completeManifest()
+			tt.modify(m)
+			result, err := Lint(m, t.TempDir(), nil)
+			if err != nil {
+				t.Fatalf("Lint failed: %v", err)
+			}
+			if got := lintRules(result.Findings); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintUnknownRule(t *testing.T) {
+	m := completeManifest()
+	m.Lint = &manifest.LintSpec{Rules: map[string]string{"missing-licence": manifest.LintError}}
+	_, err := Lint(m, t.TempDir(), nil)
+	if err == nil || !strings.Contains(err.Error(), "missing-licence") {
+		t.Errorf("expected an unknown rule error, got %v", err)
+	}
+}
+
+func TestLintFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, [][2]string{
+		{"client.py", "import requests\n"},
+		{".env", "TOKEN=secret\n"},
+		{".env.example", "TOKEN=\n"},
+		{"certs/server.pem", ""},
+		{"aigogo.lock", "{}\n"},
+		{"data/big.bin", strings.Repeat("x", largeFileSize+1)},
+	})
+	files := []string{"client.py", ".env", ".env.example", "certs/server.pem", "aigogo.lock", "data/big.bin"}
+
+	result, err := Lint(completeManifest(), dir, files)
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	want := []string{
+		"error sensitive-file",
+		"warning consumer-state-included",
+		"error sensitive-file",
+		"warning large-file",
+	}
+	if got := lintRules(result.Findings); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lint() = %v, want %v", got, want)
+	}
+	if result.Findings[0].File != ".env" || result.Findings[2].File != "certs/server.pem" {
+		t.Errorf("unexpected finding files: %+v", result.Findings)
+	}
+	if !result.Failed(false) {
+		t.Error("sensitive files should fail linting")
+	}
+
+	if _, err := Lint(completeManifest(), dir, []string{"missing.py"}); err == nil {
+		t.Error("expected an error for a file that doesn't exist")
+	}
+}
+
+func TestLintResultFailed(t *testing.T) {
+	tests := []struct {
+		severities []string
+		strict     bool
+		want       bool
+	}{
+		{nil, true, false},
+		{[]string{SeverityInfo}, true, false},
+		{[]string{SeverityWarning}, false, false},
+		{[]string{SeverityWarning}, true, true},
+		{[]string{SeverityInfo, SeverityError}, false, true},
+	}
+	for _, tt := range tests {
+		r := &LintResult{}
+		for _, s := range tt.severities {
+			r.Findings = append(r.Findings, Finding{Severity: s})
+		}
+		if got := r.Failed(tt.strict); got != tt.want {
+			t.Errorf("Failed(%v) with %v = %v, want %v", tt.strict, tt.severities, got, tt.want)
+		}
+	}
+}
+
+func TestIsSensitiveFile(t *testing.T) {
+	tests := map[string]bool{
+		".env":             true,
+		".env.production":  true,
+		".env.example":     false,
+		".ENV.SAMPLE":      false,
+		"server.key":       true,
+		"cert.PEM":         true,
+		"id_ed25519":       true,
+		"id_ed25519.pub":   false,
+		".npmrc":           true,
+		"credentials.json": true,
+		"config.json":      false,
+		"keys.py":          false,
+	}
+	for name, want := range tests {
+		if got := isSensitiveFile(name); got != want {
+			t.Errorf("isSensitiveFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestWriteLintSARIF(t *testing.T) {
+	manifestPath := filepath.Join(t.TempDir(), "aigogo.json")
+	manifestJSON := `{
+  "name": "my_utils",
+  "files": {
+    "include": "auto"
+  }
+}
+`
+	if err := os.WriteFile(manifestPath, []byte(manifestJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	findings := []Finding{
+		{Severity: SeverityWarning, Rule: RuleAutoInclude, Message: "auto", Field: "include"},
+		{Severity: SeverityWarning, Rule: RuleMissingLicense, Message: "no license", Field: "license"},
+		{Severity: SeverityError, Rule: RuleSensitiveFile, Message: "secret", File: ".env"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteLintSARIF(&buf, findings, manifestPath, "1.2.3"); err != nil {
+		t.Fatalf("WriteLintSARIF failed: %v", err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(LintRules) || len(run.Results) != 3 {
+		t.Fatalf("unexpected SARIF run: %+v", run)
+	}
+
+	include := run.Results[0].Locations[0].PhysicalLocation
+	if include.Region == nil || include.Region.StartLine != 4 {
+		t.Errorf("expected auto-include on line 4, got %+v", include)
+	}
+	if license := run.Results[1].Locations[0].PhysicalLocation; license.Region != nil {
+		t.Errorf("expected no region for a field that isn't set, got %+v", license.Region)
+	}
+	if secret := run.Results[2].Locations[0].PhysicalLocation; secret.ArtifactLocation.URI != ".env" || run.Results[2].Level != "error" {
+		t.Errorf("unexpected sensitive-file result: %+v", run.Results[2])
+	}
+}
@@ -0,0 +1,227 @@
+package depgen
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sarifSchema and sarifVersion identify the SARIF format written by WriteSARIF
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+)
+
+// RuleInfo describes a rule for the SARIF tool metadata and aigg lint
+// --list-rules
+type RuleInfo struct {
+	ID          string
+	Description string
+	Severity    string // default severity
+}
+
+// validationRules describes each rule of Validate
+var validationRules = []RuleInfo{
+	{RuleMissingDependency, "Imported package is not declared in aigogo.json", SeverityError},
+	{RuleUnusedDependency, "Declared dependency is never imported", SeverityWarning},
+	{RuleUnpinnedVersion, "Dependency has no version constraint", SeverityWarning},
+	{RuleExactVersion, "Dependency is pinned to an exact version", SeverityInfo},
+	{RuleUnusedFile, "Included file is not imported by any other included file", SeverityWarning},
+	{RulePeerDependency, "Peer dependency is not installed on this machine at a compatible version", SeverityWarning},
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region *sarifRegion `json:"region,omitempty"`
+	} `json:"physicalLocation"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// WriteSARIF writes findings as a SARIF 2.1.0 log, the format GitHub code
+// scanning turns into pull request annotations. Findings tied to an import
+// point at the importing file, unused-file findings at the file itself, and
+// the rest at the dependency's entry in manifestPath. File paths are written relative to the working directory.
+func WriteSARIF(w io.Writer, findings []Finding, manifestPath, toolVersion string) error {
+	return writeSARIF(w, validationRules, findings, manifestPath, toolVersion)
+}
+
+// WriteLintSARIF writes the findings of Lint as a SARIF 2.1.0 log. Findings
+// about a manifest field point at the line where the field is set.
+func WriteLintSARIF(w io.Writer, findings []Finding, manifestPath, toolVersion string) error {
+	return writeSARIF(w, LintRules, findings, manifestPath, toolVersion)
+}
+
+func writeSARIF(w io.Writer, rules []RuleInfo, findings []Finding, manifestPath, toolVersion string) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "aigogo",
+			Version:        toolVersion,
+			InformationURI: "https://github.com/aupeachmo/aigogo",
+		}},
+		Results: []sarifResult{},
+	}
+	for _, rule := range rules {
+		r := sarifRule{ID: rule.ID, ShortDescription: sarifMessage{Text: rule.Description}}
+		r.DefaultConfiguration.Level = sarifLevel(rule.Severity)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, r)
+	}
+
+	for i, l := range LocateFindings(findings, manifestPath) {
+		var loc sarifLocation
+		loc.PhysicalLocation.ArtifactLocation.URI = l.File
+		if l.Line > 0 {
+			loc.PhysicalLocation.Region = &sarifRegion{StartLine: l.Line}
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    findings[i].Rule,
+			Level:     sarifLevel(findings[i].Severity),
+			Message:   sarifMessage{Text: l.Message},
+			Locations: []sarifLocation{loc},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}})
+}
+
+// Location is where a finding points, for SARIF results and GitHub
+// annotations
+type Location struct {
+	File    string // slash-separated, relative as the finding's paths are
+	Line    int    // 1-based, or 0 for the whole file
+	Message string // the finding's message, with the notebook cell when a line can't address it
+}
+
+// LocateFindings returns the location of each finding, in order. Findings
+// tied to an import point at the importing file, unused-file findings at the
+// file itself, and the rest at their field or dependency in manifestPath.
+func LocateFindings(findings []Finding, manifestPath string) []Location {
+	manifestLines := readLines(manifestPath)
+	locations := make([]Location, 0, len(findings))
+	for _, f := range findings {
+		loc := Location{Message: f.Message}
+		switch {
+		case f.Import != nil:
+			loc.File = filepath.ToSlash(f.Import.SourceFile)
+			if f.Import.Cell > 0 {
+				// Lines can't address notebook cells
+				loc.Message += fmt.Sprintf(" (cell %d, line %d)", f.Import.Cell, f.Import.LineNumber)
+			} else {
+				loc.Line = f.Import.LineNumber
+			}
+		case f.File != "":
+			loc.File = filepath.ToSlash(f.File)
+		default:
+			loc.File = filepath.ToSlash(manifestPath)
+			if f.Field != "" {
+				loc.Line = fieldLine(manifestLines, f.Field)
+			} else if f.Package != "" {
+				loc.Line = dependencyLine(manifestLines, f.Package)
+			}
+		}
+		locations = append(locations, loc)
+	}
+	return locations
+}
+
+// sarifLevel maps a severity to a SARIF result level
+func sarifLevel(severity string) string {
+	switch severity {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "note"
+}
+
+// readLines returns the lines of a file, or nil if it can't be read
+func readLines(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = file.Close() }()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// fieldLine returns the 1-based line of the first "field" key in
+// aigogo.json, or 0
+func fieldLine(lines []string, field string) int {
+	needle := fmt.Sprintf("%q:", field)
+	for i, line := range lines {
+		if strings.Contains(strings.ReplaceAll(line, `" :`, `":`), needle) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// dependencyLine returns the 1-based line declaring pkg in aigogo.json, or 0
+func dependencyLine(lines []string, pkg string) int {
+	needle := fmt.Sprintf("%q", pkg)
+	for i, line := range lines {
+		if strings.Contains(line, `"package"`) && strings.Contains(line, needle) {
+			return i + 1
+		}
+	}
+	return 0
+}
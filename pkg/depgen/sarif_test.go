@@ -0,0 +1,96 @@
+package depgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	manifestPath := filepath.Join(t.TempDir(), "aigogo.json")
+	manifestJSON := `{
+  "dependencies": {
+    "runtime": [
+      {
+        "package": "flask",
+        "version": "*"
+      }
+    ]
+  }
+}
+`
+	if err := os.WriteFile(manifestPath, []byte(manifestJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	findings := []Finding{
+		{
+			Severity: SeverityError,
+			Rule:     RuleMissingDependency,
+			Message:  "Package 'requests' is imported but not declared",
+			Package:  "requests",
+			Import:   &ImportInfo{Package: "requests", SourceFile: "src/app.py", LineNumber: 4},
+		},
+		{
+			Severity: SeverityWarning,
+			Rule:     RuleUnpinnedVersion,
+			Message:  "Package 'flask' has no version constraint",
+			Package:  "flask",
+		},
+		{
+			Severity: SeverityInfo,
+			Rule:     RuleExactVersion,
+			Message:  "Package 'numpy' is imported in a notebook",
+			Package:  "numpy",
+			Import:   &ImportInfo{Package: "numpy", SourceFile: "nb.ipynb", LineNumber: 2, Cell: 3},
+		},
+		{
+			Severity: SeverityWarning,
+			Rule:     RuleUnusedFile,
+			Message:  "File 'src/old.py' is not imported by any other included file",
+			File:     "src/old.py",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, findings, manifestPath, "1.2.3"); err != nil {
+		t.Fatalf("WriteSARIF failed: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected SARIF log: %+v", log)
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "aigogo" || run.Tool.Driver.Version != "1.2.3" || len(run.Tool.Driver.Rules) != len(validationRules) {
+		t.Errorf("unexpected tool driver: %+v", run.Tool.Driver)
+	}
+	if len(run.Results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(run.Results))
+	}
+
+	missing := run.Results[0].Locations[0].PhysicalLocation
+	if run.Results[0].Level != "error" || missing.ArtifactLocation.URI != "src/app.py" || missing.Region == nil || missing.Region.StartLine != 4 {
+		t.Errorf("unexpected missing-dependency result: %+v", run.Results[0])
+	}
+
+	unpinned := run.Results[1].Locations[0].PhysicalLocation
+	if run.Results[1].Level != "warning" || unpinned.ArtifactLocation.URI != filepath.ToSlash(manifestPath) || unpinned.Region == nil || unpinned.Region.StartLine != 5 {
+		t.Errorf("unexpected unpinned-version result: %+v", run.Results[1])
+	}
+
+	notebook := run.Results[2]
+	if notebook.Level != "note" || notebook.Locations[0].PhysicalLocation.Region != nil || notebook.Message.Text != "Package 'numpy' is imported in a notebook (cell 3, line 2)" {
+		t.Errorf("unexpected notebook result: %+v", notebook)
+	}
+
+	unusedFile := run.Results[3].Locations[0].PhysicalLocation
+	if unusedFile.ArtifactLocation.URI != "src/old.py" || unusedFile.Region != nil {
+		t.Errorf("unexpected unused-file result: %+v", run.Results[3])
+	}
+}
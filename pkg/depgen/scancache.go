@@ -0,0 +1,173 @@
+package depgen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// ScanCacheFile is the scan cache location relative to the project root
+	ScanCacheFile = ".aigogo/scan-cache.json"
+	// scanCacheVersion is bumped whenever scanner output changes so that
+	// stale results from an older aigg are discarded
+	scanCacheVersion = 1
+)
+
+// cachedImport is a single import in the scan cache. The source file is not
+// stored since identical content may live under several names.
+type cachedImport struct {
+	Package string `json:"package"`
+	Line    int    `json:"line"`
+	Cell    int    `json:"cell,omitempty"`
+}
+
+// scanCacheData is the on-disk format of the scan cache
+type scanCacheData struct {
+	Version         int                       `json:"version"`
+	Language        string                    `json:"language"`
+	LanguageVersion string                    `json:"language_version,omitempty"`
+	Files           map[string][]cachedImport `json:"files"`
+}
+
+// ScanCache stores per-file scan results keyed by the sha256 of the file
+// content, so repeat scans only re-parse files that changed. It is safe for
+// concurrent use by the scanner's workers.
+type ScanCache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string][]cachedImport
+	used    map[string]bool
+	dirty   bool
+
+	language        string
+	languageVersion string
+}
+
+// LoadScanCache opens the scan cache at path. A missing or unreadable cache
+// starts empty; entries are only reused for the same language and language
+// version since both affect which imports are reported.
+func LoadScanCache(path string) *ScanCache {
+	c := &ScanCache{
+		path:    path,
+		entries: make(map[string][]cachedImport),
+		used:    make(map[string]bool),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c
+	}
+	var stored scanCacheData
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Version != scanCacheVersion {
+		return c
+	}
+	c.language = stored.Language
+	c.languageVersion = stored.LanguageVersion
+	if stored.Files != nil {
+		c.entries = stored.Files
+	}
+	return c
+}
+
+// reset discards all entries unless they were produced for the given
+// language and language version
+func (c *ScanCache) reset(language, languageVersion string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.language == language && c.languageVersion == languageVersion {
+		return
+	}
+	c.language = language
+	c.languageVersion = languageVersion
+	c.entries = make(map[string][]cachedImport)
+	c.used = make(map[string]bool)
+	c.dirty = true
+}
+
+// get returns the cached imports for content with the given hash
+func (c *ScanCache) get(hash, filename string) ([]ImportInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.entries[hash]
+	if !ok {
+		return nil, false
+	}
+	c.used[hash] = true
+
+	imports := make([]ImportInfo, len(cached))
+	for i, imp := range cached {
+		imports[i] = ImportInfo{
+			Package:    imp.Package,
+			SourceFile: filename,
+			LineNumber: imp.Line,
+			Cell:       imp.Cell,
+		}
+	}
+	return imports, true
+}
+
+// put records the imports found in content with the given hash
+func (c *ScanCache) put(hash string, imports []ImportInfo) {
+	cached := make([]cachedImport, len(imports))
+	for i, imp := range imports {
+		cached[i] = cachedImport{Package: imp.Package, Line: imp.LineNumber, Cell: imp.Cell}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[hash] = cached
+	c.used[hash] = true
+	c.dirty = true
+}
+
+// Save writes the cache back to disk, dropping entries for content that was
+// not seen in this run. Errors are ignored since the cache is only an
+// optimisation.
+func (c *ScanCache) Save() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.used) != len(c.entries) {
+		c.dirty = true
+	}
+	if !c.dirty {
+		return
+	}
+
+	stored := scanCacheData{
+		Version:         scanCacheVersion,
+		Language:        c.language,
+		LanguageVersion: c.languageVersion,
+		Files:           make(map[string][]cachedImport, len(c.used)),
+	}
+	for hash := range c.used {
+		stored.Files[hash] = c.entries[hash]
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return
+	}
+	_ = os.WriteFile(c.path, data, 0644)
+	c.entries = stored.Files
+	c.dirty = false
+}
+
+// hashFile returns the hex sha256 of a file's content
+func hashFile(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
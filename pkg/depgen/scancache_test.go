@@ -0,0 +1,135 @@
+package depgen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanCacheReusesUnchangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	cachePath := filepath.Join(dir, ".aigogo", "scan-cache.json")
+	src := filepath.Join(dir, "app.py")
+	if err := os.WriteFile(src, []byte("import requests\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := NewScanner()
+	cache := LoadScanCache(cachePath)
+	scanner.SetCache(cache)
+	if _, err := scanner.ScanFiles([]string{src}, "python"); err != nil {
+		t.Fatalf("ScanFiles failed: %v", err)
+	}
+	cache.Save()
+
+	if _, err := os.Stat(cachePath); err != nil {
+		t.Fatalf("cache file not written: %v", err)
+	}
+
+	// Poison the cached entry to prove the second scan does not re-parse
+	hash, err := hashFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache = LoadScanCache(cachePath)
+	cache.entries[hash] = []cachedImport{{Package: "from-cache", Line: 7}}
+
+	scanner = NewScanner()
+	scanner.SetCache(cache)
+	imports, err := scanner.ScanFiles([]string{src}, "python")
+	if err != nil {
+		t.Fatalf("ScanFiles failed: %v", err)
+	}
+	if len(imports) != 1 || imports[0].Package != "from-cache" || imports[0].SourceFile != src || imports[0].LineNumber != 7 {
+		t.Errorf("expected cached import, got %+v", imports)
+	}
+
+	// Changing the file changes its hash, so it is scanned again
+	if err := os.WriteFile(src, []byte("import flask\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	imports, err = scanner.ScanFiles([]string{src}, "python")
+	if err != nil {
+		t.Fatalf("ScanFiles failed: %v", err)
+	}
+	if len(imports) != 1 || imports[0].Package != "flask" {
+		t.Errorf("expected flask after change, got %+v", imports)
+	}
+}
+
+func TestScanCacheInvalidatedByLanguageVersion(t *testing.T) {
+	dir := t.TempDir()
+	cachePath := filepath.Join(dir, "scan-cache.json")
+	src := filepath.Join(dir, "app.py")
+	if err := os.WriteFile(src, []byte("import tomllib\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// tomllib is only in the standard library from Python 3.11
+	scanner := NewScanner()
+	scanner.SetLanguageVersion(">=3.8")
+	cache := LoadScanCache(cachePath)
+	scanner.SetCache(cache)
+	imports, err := scanner.ScanFiles([]string{src}, "python")
+	if err != nil {
+		t.Fatalf("ScanFiles failed: %v", err)
+	}
+	if len(imports) != 1 {
+		t.Fatalf("expected tomllib to be external on >=3.8, got %+v", imports)
+	}
+	cache.Save()
+
+	scanner = NewScanner()
+	scanner.SetLanguageVersion(">=3.11")
+	scanner.SetCache(LoadScanCache(cachePath))
+	imports, err = scanner.ScanFiles([]string{src}, "python")
+	if err != nil {
+		t.Fatalf("ScanFiles failed: %v", err)
+	}
+	if len(imports) != 0 {
+		t.Errorf("expected cache to be invalidated for >=3.11, got %+v", imports)
+	}
+}
+
+func TestScanCacheSaveDropsUnusedEntries(t *testing.T) {
+	dir := t.TempDir()
+	cachePath := filepath.Join(dir, "scan-cache.json")
+	a := filepath.Join(dir, "a.py")
+	b := filepath.Join(dir, "b.py")
+	if err := os.WriteFile(a, []byte("import requests\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("import flask\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := LoadScanCache(cachePath)
+	scanner := NewScanner()
+	scanner.SetCache(cache)
+	if _, err := scanner.ScanFiles([]string{a, b}, "python"); err != nil {
+		t.Fatal(err)
+	}
+	cache.Save()
+
+	cache = LoadScanCache(cachePath)
+	scanner = NewScanner()
+	scanner.SetCache(cache)
+	if _, err := scanner.ScanFiles([]string{a}, "python"); err != nil {
+		t.Fatal(err)
+	}
+	cache.Save()
+
+	if n := len(LoadScanCache(cachePath).entries); n != 1 {
+		t.Errorf("expected 1 cache entry after save, got %d", n)
+	}
+}
+
+func TestLoadScanCacheIgnoresCorruptFile(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "scan-cache.json")
+	if err := os.WriteFile(cachePath, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(LoadScanCache(cachePath).entries); n != 0 {
+		t.Errorf("expected empty cache, got %d entries", n)
+	}
+}
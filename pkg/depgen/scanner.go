@@ -2,29 +2,105 @@ package depgen
 
 import (
 	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
+	"sync"
 )
 
+// progressInterval is how many files are scanned between progress reports
+const progressInterval = 1000
+
+// ProgressFunc is called periodically while scanning with the number of
+// files scanned so far and the total
+type ProgressFunc func(done, total int)
+
 // Scanner scans source files for imports
-type Scanner struct{}
+type Scanner struct {
+	languageVersion string // language.version from the manifest, e.g. ">=3.9,<4.0"
+	progress        ProgressFunc
+	cache           *ScanCache
+}
 
 // NewScanner creates a new scanner
 func NewScanner() *Scanner {
 	return &Scanner{}
 }
 
-// ScanFiles scans multiple files for imports
+// SetLanguageVersion sets the language version constraint from the manifest.
+// For Python it selects which standard library modules are filtered out.
+func (s *Scanner) SetLanguageVersion(version string) {
+	s.languageVersion = version
+}
+
+// SetProgress registers a callback invoked every 1000 scanned files
+func (s *Scanner) SetProgress(fn ProgressFunc) {
+	s.progress = fn
+}
+
+// SetCache enables reuse of per-file results from a scan cache. The caller
+// is responsible for saving the cache after scanning.
+func (s *Scanner) SetCache(cache *ScanCache) {
+	s.cache = cache
+}
+
+// ScanFiles scans multiple files for imports. Files are scanned in parallel
+// by up to GOMAXPROCS workers; results are merged in input order, so the
+// output is the same as a sequential scan.
 func (s *Scanner) ScanFiles(files []string, language string) ([]ImportInfo, error) {
+	if s.cache != nil {
+		s.cache.reset(language, s.languageVersion)
+	}
+
+	results := make([][]ImportInfo, len(files))
+	errs := make([]error, len(files))
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(files) {
+		workers = len(files)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var progressMu sync.Mutex
+	done := 0
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = s.scanFileCached(files[i], language)
+
+				if s.progress != nil {
+					progressMu.Lock()
+					done++
+					if done%progressInterval == 0 {
+						s.progress(done, len(files))
+					}
+					progressMu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
 	var allImports []ImportInfo
 	seen := make(map[string]bool)
 
-	for _, file := range files {
-		imports, err := s.scanFile(file, language)
-		if err != nil {
-			return nil, err
+	for i, imports := range results {
+		if errs[i] != nil {
+			return nil, errs[i]
 		}
 
 		// Deduplicate
@@ -39,12 +115,38 @@ func (s *Scanner) ScanFiles(files []string, language string) ([]ImportInfo, erro
 	return allImports, nil
 }
 
+// scanFileCached scans a file, reusing the cached result when a file with
+// identical content has been scanned before
+func (s *Scanner) scanFileCached(filename, language string) ([]ImportInfo, error) {
+	if s.cache == nil {
+		return s.scanFile(filename, language)
+	}
+
+	hash, err := hashFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if imports, ok := s.cache.get(hash, filename); ok {
+		return imports, nil
+	}
+
+	imports, err := s.scanFile(filename, language)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(hash, imports)
+	return imports, nil
+}
+
 func (s *Scanner) scanFile(filename, language string) ([]ImportInfo, error) {
 	ext := filepath.Ext(filename)
 
 	// Determine scanner based on language or extension
 	switch language {
 	case "python":
+		if ext == ".ipynb" {
+			return s.scanNotebook(filename)
+		}
 		return s.scanPython(filename)
 	case "javascript":
 		return s.scanJavaScript(filename)
@@ -52,17 +154,35 @@ func (s *Scanner) scanFile(filename, language string) ([]ImportInfo, error) {
 		return s.scanGo(filename)
 	case "rust":
 		return s.scanRust(filename)
+	case "ruby":
+		return s.scanRuby(filename)
+	case "java":
+		return s.scanJava(filename)
+	case "csharp":
+		return s.scanCSharp(filename)
+	case "php":
+		return s.scanPHP(filename)
 	default:
 		// Fallback to extension-based detection
 		switch ext {
 		case ".py":
 			return s.scanPython(filename)
+		case ".ipynb":
+			return s.scanNotebook(filename)
 		case ".js", ".ts", ".jsx", ".tsx", ".mjs", ".cjs":
 			return s.scanJavaScript(filename)
 		case ".go":
 			return s.scanGo(filename)
 		case ".rs":
 			return s.scanRust(filename)
+		case ".rb":
+			return s.scanRuby(filename)
+		case ".java":
+			return s.scanJava(filename)
+		case ".cs":
+			return s.scanCSharp(filename)
+		case ".php":
+			return s.scanPHP(filename)
 		}
 	}
 
@@ -76,11 +196,60 @@ func (s *Scanner) scanPython(filename string) ([]ImportInfo, error) {
 	}
 	defer func() { _ = file.Close() }()
 
+	return s.scanPythonSource(file, filename, 0)
+}
+
+// scanNotebook extracts the code cells of a Jupyter notebook and runs the
+// Python import scanner over each one, recording the cell number.
+func (s *Scanner) scanNotebook(filename string) ([]ImportInfo, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var nb struct {
+		Cells []struct {
+			CellType string          `json:"cell_type"`
+			Source   json.RawMessage `json:"source"`
+		} `json:"cells"`
+	}
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return nil, fmt.Errorf("failed to parse notebook %s: %w", filename, err)
+	}
+
 	var imports []ImportInfo
-	scanner := bufio.NewScanner(file)
+	for i, cell := range nb.Cells {
+		if cell.CellType != "code" {
+			continue
+		}
+
+		// nbformat allows source as a single string or a list of lines
+		var source string
+		var lines []string
+		if err := json.Unmarshal(cell.Source, &lines); err == nil {
+			source = strings.Join(lines, "")
+		} else if err := json.Unmarshal(cell.Source, &source); err != nil {
+			return nil, fmt.Errorf("failed to parse cell %d of %s: %w", i+1, filename, err)
+		}
+
+		cellImports, err := s.scanPythonSource(strings.NewReader(source), filename, i+1)
+		if err != nil {
+			return nil, err
+		}
+		imports = append(imports, cellImports...)
+	}
+
+	return imports, nil
+}
+
+// scanPythonSource scans Python source read from r. cell is the 1-based
+// notebook cell number, or 0 for a regular .py file.
+func (s *Scanner) scanPythonSource(r io.Reader, filename string, cell int) ([]ImportInfo, error) {
+	var imports []ImportInfo
+	scanner := bufio.NewScanner(r)
 	lineNum := 0
 
-	stdlib := pythonStdlib()
+	stdlib := pythonStdlibFor(s.languageVersion)
 
 	importRegex := regexp.MustCompile(`^\s*import\s+([a-zA-Z0-9_\.]+)`)
 	fromImportRegex := regexp.MustCompile(`^\s*from\s+([a-zA-Z0-9_\.]+)\s+import`)
@@ -100,6 +269,7 @@ func (s *Scanner) scanPython(filename string) ([]ImportInfo, error) {
 					Package:    pkg,
 					SourceFile: filename,
 					LineNumber: lineNum,
+					Cell:       cell,
 				})
 			}
 		}
@@ -111,6 +281,7 @@ func (s *Scanner) scanPython(filename string) ([]ImportInfo, error) {
 					Package:    pkg,
 					SourceFile: filename,
 					LineNumber: lineNum,
+					Cell:       cell,
 				})
 			}
 		}
@@ -265,15 +436,245 @@ func (s *Scanner) scanRust(filename string) ([]ImportInfo, error) {
 	return imports, scanner.Err()
 }
 
-func pythonStdlib() map[string]bool {
+func (s *Scanner) scanRuby(filename string) ([]ImportInfo, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var imports []ImportInfo
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+
+	stdlib := rubyStdlib()
+
+	// require 'foo', require("foo/bar"), gem 'foo' (require_relative is local)
+	requireRegex := regexp.MustCompile(`^\s*(?:require|gem)\s*\(?\s*['"]([^'"]+)['"]`)
+
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		if matches := requireRegex.FindStringSubmatch(line); matches != nil {
+			path := matches[1]
+			if strings.HasPrefix(path, ".") || strings.HasPrefix(path, "/") {
+				continue
+			}
+			pkg := strings.Split(path, "/")[0]
+			if !stdlib[pkg] {
+				imports = append(imports, ImportInfo{
+					Package:    pkg,
+					SourceFile: filename,
+					LineNumber: lineNum,
+				})
+			}
+		}
+	}
+
+	return imports, scanner.Err()
+}
+
+func (s *Scanner) scanJava(filename string) ([]ImportInfo, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var imports []ImportInfo
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+
+	importRegex := regexp.MustCompile(`^\s*import\s+(?:static\s+)?([a-zA-Z_][a-zA-Z0-9_.]*)(?:\.\*)?\s*;`)
+
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		if matches := importRegex.FindStringSubmatch(line); matches != nil {
+			if isJavaBuiltin(matches[1]) {
+				continue
+			}
+			if pkg := javaImportPackage(matches[1]); pkg != "" {
+				imports = append(imports, ImportInfo{
+					Package:    pkg,
+					SourceFile: filename,
+					LineNumber: lineNum,
+				})
+			}
+		}
+	}
+
+	return imports, scanner.Err()
+}
+
+// javaImportPackage reduces an import to the package prefix used to match
+// Maven coordinates: class names are dropped and at most three segments are
+// kept ("org.apache.commons.lang3.StringUtils" -> "org.apache.commons").
+func javaImportPackage(name string) string {
+	var segments []string
+	for _, seg := range strings.Split(name, ".") {
+		if seg == "" || (seg[0] >= 'A' && seg[0] <= 'Z') {
+			break
+		}
+		segments = append(segments, seg)
+		if len(segments) == 3 {
+			break
+		}
+	}
+	return strings.Join(segments, ".")
+}
+
+// isJavaBuiltin reports whether an import belongs to the JDK
+func isJavaBuiltin(name string) bool {
+	for _, prefix := range javaBuiltinPrefixes() {
+		if name == prefix || strings.HasPrefix(name, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func javaBuiltinPrefixes() []string {
+	return []string{
+		"java", "javax", "jdk", "sun", "com.sun",
+		"org.w3c.dom", "org.xml.sax", "org.ietf.jgss",
+	}
+}
+
+func (s *Scanner) scanCSharp(filename string) ([]ImportInfo, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var imports []ImportInfo
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+
+	// using Foo.Bar; / global using Foo.Bar; / using static Foo.Bar; / using Alias = Foo.Bar;
+	// using (...) and using var ... are statements, not directives, and don't match.
+	usingRegex := regexp.MustCompile(`^\s*(?:global\s+)?using\s+(?:static\s+)?(?:[A-Za-z_]\w*\s*=\s*)?([A-Za-z_][\w.]*)\s*;`)
+
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		if matches := usingRegex.FindStringSubmatch(line); matches != nil {
+			ns := matches[1]
+			if !isCSharpBCL(ns) {
+				imports = append(imports, ImportInfo{
+					Package:    ns,
+					SourceFile: filename,
+					LineNumber: lineNum,
+				})
+			}
+		}
+	}
+
+	return imports, scanner.Err()
+}
+
+// isCSharpBCL reports whether a namespace ships with the .NET base class library
+func isCSharpBCL(ns string) bool {
+	for _, prefix := range csharpBCLPrefixes() {
+		if hasSegmentPrefix(ns, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func csharpBCLPrefixes() []string {
+	return []string{
+		"System", "Microsoft.CSharp", "Microsoft.VisualBasic", "Microsoft.Win32",
+	}
+}
+
+func (s *Scanner) scanPHP(filename string) ([]ImportInfo, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var imports []ImportInfo
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+
+	builtins := phpBuiltinNamespaces()
+
+	// use Vendor\Pkg\Class; / use function Vendor\fn; / use Vendor\{A, B};
+	useRegex := regexp.MustCompile(`^\s*use\s+(?:function\s+|const\s+)?\\?([A-Za-z_][A-Za-z0-9_]*)\\`)
+	// require/include of a Composer vendor path: require 'vendor/acme/lib/src/x.php'
+	requireRegex := regexp.MustCompile(`(?:require|include)(?:_once)?\b.*?vendor/([a-z0-9_.-]+)/([a-z0-9_.-]+)/`)
+
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "*") {
+			continue
+		}
+
+		// Single-segment names (use Exception;) are global classes or traits
+		if matches := useRegex.FindStringSubmatch(line); matches != nil {
+			if !builtins[matches[1]] {
+				imports = append(imports, ImportInfo{
+					Package:    matches[1],
+					SourceFile: filename,
+					LineNumber: lineNum,
+				})
+			}
+		}
+
+		if matches := requireRegex.FindStringSubmatch(line); matches != nil {
+			imports = append(imports, ImportInfo{
+				Package:    matches[1] + "/" + matches[2],
+				SourceFile: filename,
+				LineNumber: lineNum,
+			})
+		}
+	}
+
+	return imports, scanner.Err()
+}
+
+// phpBuiltinNamespaces lists namespaces shipped with PHP itself
+func phpBuiltinNamespaces() map[string]bool {
+	return map[string]bool{
+		"Dom": true, "FFI": true, "Random": true,
+	}
+}
+
+func rubyStdlib() map[string]bool {
 	return map[string]bool{
-		"os": true, "sys": true, "re": true, "json": true, "time": true,
-		"datetime": true, "collections": true, "itertools": true, "functools": true,
-		"pathlib": true, "typing": true, "abc": true, "io": true, "math": true,
-		"random": true, "string": true, "subprocess": true, "threading": true,
-		"multiprocessing": true, "logging": true, "argparse": true, "configparser": true,
-		"unittest": true, "sqlite3": true, "csv": true, "xml": true, "html": true,
-		"urllib": true, "http": true, "email": true, "base64": true, "hashlib": true,
+		"English": true, "abbrev": true, "base64": true, "benchmark": true,
+		"bigdecimal": true, "bundler": true, "cgi": true, "coverage": true,
+		"csv": true, "date": true, "delegate": true, "did_you_mean": true,
+		"digest": true, "drb": true, "erb": true, "etc": true, "expect": true,
+		"fcntl": true, "fiddle": true, "fileutils": true, "find": true,
+		"forwardable": true, "getoptlong": true, "io": true, "ipaddr": true,
+		"irb": true, "json": true, "logger": true, "matrix": true,
+		"monitor": true, "mutex_m": true, "net": true,
+		"nkf": true, "objspace": true, "observer": true, "open-uri": true,
+		"open3": true, "openssl": true, "optparse": true, "ostruct": true,
+		"pathname": true, "pp": true, "prettyprint": true, "prime": true,
+		"pstore": true, "psych": true, "pty": true, "racc": true,
+		"rbconfig": true, "rdoc": true, "readline": true, "reline": true,
+		"resolv": true, "ripper": true, "rubygems": true, "securerandom": true,
+		"set": true, "shellwords": true, "singleton": true, "socket": true,
+		"stringio": true, "strscan": true, "syslog": true, "tempfile": true,
+		"thread": true, "time": true, "timeout": true,
+		"tmpdir": true, "tsort": true, "un": true, "uri": true,
+		"weakref": true, "yaml": true, "zlib": true,
 	}
 }
 
@@ -1,8 +1,10 @@
 package depgen
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -276,6 +278,244 @@ use crate::utils::helper;
 	}
 }
 
+func TestScanRuby(t *testing.T) {
+	tmpDir := t.TempDir()
+	rbFile := filepath.Join(tmpDir, "test.rb")
+
+	content := `
+require 'json'
+require "net/http"
+require 'faraday'
+require("nokogiri")
+require 'active_support/core_ext'
+require_relative 'helpers'
+gem 'rack'
+# require 'commented_out'
+`
+	if err := os.WriteFile(rbFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScanner()
+	imports, err := s.ScanFiles([]string{rbFile}, "ruby")
+	if err != nil {
+		t.Fatalf("ScanFiles failed: %v", err)
+	}
+
+	found := make(map[string]bool)
+	for _, imp := range imports {
+		found[imp.Package] = true
+	}
+
+	for _, pkg := range []string{"faraday", "nokogiri", "active_support", "rack"} {
+		if !found[pkg] {
+			t.Errorf("Should find %s", pkg)
+		}
+	}
+	for _, pkg := range []string{"json", "net", "helpers", "commented_out"} {
+		if found[pkg] {
+			t.Errorf("Should not find %s", pkg)
+		}
+	}
+}
+
+func TestScanJava(t *testing.T) {
+	tmpDir := t.TempDir()
+	javaFile := filepath.Join(tmpDir, "Test.java")
+
+	content := `
+package com.example.utils;
+
+import java.util.List;
+import javax.annotation.Nullable;
+import org.w3c.dom.Document;
+import com.google.gson.Gson;
+import org.apache.commons.lang3.StringUtils;
+import static org.junit.Assert.assertEquals;
+import com.fasterxml.jackson.databind.*;
+`
+	if err := os.WriteFile(javaFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScanner()
+	imports, err := s.ScanFiles([]string{javaFile}, "java")
+	if err != nil {
+		t.Fatalf("ScanFiles failed: %v", err)
+	}
+
+	found := make(map[string]bool)
+	for _, imp := range imports {
+		found[imp.Package] = true
+	}
+
+	for _, pkg := range []string{"com.google.gson", "org.apache.commons", "org.junit", "com.fasterxml.jackson"} {
+		if !found[pkg] {
+			t.Errorf("Should find %s, got %v", pkg, found)
+		}
+	}
+	for _, pkg := range []string{"java.util", "javax.annotation", "org.w3c.dom", "com.example.utils"} {
+		if found[pkg] {
+			t.Errorf("Should not find %s", pkg)
+		}
+	}
+}
+
+func TestScanCSharp(t *testing.T) {
+	tmpDir := t.TempDir()
+	csFile := filepath.Join(tmpDir, "Test.cs")
+
+	content := `
+using System;
+using System.Collections.Generic;
+using Microsoft.Win32;
+using Newtonsoft.Json.Linq;
+global using Serilog;
+using static Polly.Policy;
+using Json = System.Text.Json;
+using Http = Flurl.Http;
+using (var stream = File.OpenRead(path)) { }
+`
+	if err := os.WriteFile(csFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScanner()
+	imports, err := s.ScanFiles([]string{csFile}, "csharp")
+	if err != nil {
+		t.Fatalf("ScanFiles failed: %v", err)
+	}
+
+	found := make(map[string]bool)
+	for _, imp := range imports {
+		found[imp.Package] = true
+	}
+
+	for _, pkg := range []string{"Newtonsoft.Json.Linq", "Serilog", "Polly.Policy", "Flurl.Http"} {
+		if !found[pkg] {
+			t.Errorf("Should find %s, got %v", pkg, found)
+		}
+	}
+	for _, pkg := range []string{"System", "System.Collections.Generic", "Microsoft.Win32", "System.Text.Json"} {
+		if found[pkg] {
+			t.Errorf("Should not find %s", pkg)
+		}
+	}
+	if len(imports) != 4 {
+		t.Errorf("Expected 4 imports, got %d: %v", len(imports), imports)
+	}
+}
+
+func TestScanPHP(t *testing.T) {
+	tmpDir := t.TempDir()
+	phpFile := filepath.Join(tmpDir, "test.php")
+
+	content := `<?php
+require __DIR__ . '/vendor/autoload.php';
+require_once 'vendor/acme/widgets/src/helpers.php';
+include 'lib/local.php';
+
+use GuzzleHttp\Client;
+use \Monolog\Logger;
+use function Symfony\Component\String\u;
+use Carbon\{Carbon, CarbonInterval};
+use Random\Randomizer;
+use Exception;
+// use Commented\Out;
+
+$fn = function () use ($client) {};
+`
+	if err := os.WriteFile(phpFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScanner()
+	imports, err := s.ScanFiles([]string{phpFile}, "php")
+	if err != nil {
+		t.Fatalf("ScanFiles failed: %v", err)
+	}
+
+	found := make(map[string]bool)
+	for _, imp := range imports {
+		found[imp.Package] = true
+	}
+
+	for _, pkg := range []string{"acme/widgets", "GuzzleHttp", "Monolog", "Symfony", "Carbon"} {
+		if !found[pkg] {
+			t.Errorf("Should find %s, got %v", pkg, found)
+		}
+	}
+	for _, pkg := range []string{"Random", "Exception", "Commented", "autoload.php"} {
+		if found[pkg] {
+			t.Errorf("Should not find %s", pkg)
+		}
+	}
+	if len(imports) != 5 {
+		t.Errorf("Expected 5 imports, got %d: %v", len(imports), imports)
+	}
+}
+
+func TestScanNotebook(t *testing.T) {
+	tmpDir := t.TempDir()
+	nbFile := filepath.Join(tmpDir, "analysis.ipynb")
+
+	content := `{
+ "cells": [
+  {"cell_type": "markdown", "metadata": {}, "source": ["import notapackage\n"]},
+  {"cell_type": "code", "metadata": {}, "outputs": [], "source": ["import os\n", "import pandas as pd\n", "%matplotlib inline\n"]},
+  {"cell_type": "code", "metadata": {}, "outputs": [], "source": "x = 1\nfrom sklearn.model_selection import train_test_split"}
+ ],
+ "metadata": {},
+ "nbformat": 4,
+ "nbformat_minor": 5
+}`
+	if err := os.WriteFile(nbFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScanner()
+	imports, err := s.ScanFiles([]string{nbFile}, "python")
+	if err != nil {
+		t.Fatalf("ScanFiles failed: %v", err)
+	}
+
+	if len(imports) != 2 {
+		t.Fatalf("Expected 2 imports, got %d: %v", len(imports), imports)
+	}
+
+	if imports[0].Package != "pandas" || imports[0].Cell != 2 || imports[0].LineNumber != 2 {
+		t.Errorf("imports[0] = %+v, want pandas in cell 2 line 2", imports[0])
+	}
+	if imports[1].Package != "sklearn" || imports[1].Cell != 3 || imports[1].LineNumber != 2 {
+		t.Errorf("imports[1] = %+v, want sklearn in cell 3 line 2", imports[1])
+	}
+	if got := imports[1].Location(); got != nbFile+" cell 3:2" {
+		t.Errorf("Location() = %q", got)
+	}
+
+	// Extension-based detection
+	imports, err = s.ScanFiles([]string{nbFile}, "")
+	if err != nil {
+		t.Fatalf("ScanFiles by extension failed: %v", err)
+	}
+	if len(imports) != 2 {
+		t.Errorf("Expected 2 imports by extension, got %d", len(imports))
+	}
+}
+
+func TestScanNotebookInvalid(t *testing.T) {
+	tmpDir := t.TempDir()
+	nbFile := filepath.Join(tmpDir, "broken.ipynb")
+	if err := os.WriteFile(nbFile, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScanner()
+	if _, err := s.ScanFiles([]string{nbFile}, "python"); err == nil {
+		t.Error("Expected error for invalid notebook")
+	}
+}
+
 func TestScanMultipleFiles(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -424,3 +664,224 @@ from flask import Flask`
 		}
 	}
 }
+
+func TestScanPythonStdlibModules(t *testing.T) {
+	tmpDir := t.TempDir()
+	pyFile := filepath.Join(tmpDir, "test.py")
+
+	content := `
+import asyncio
+import dataclasses
+from enum import Enum
+import secrets
+from zoneinfo import ZoneInfo
+from __future__ import annotations
+import requests
+`
+	if err := os.WriteFile(pyFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScanner()
+	s.SetLanguageVersion(">=3.9,<4.0")
+	imports, err := s.ScanFiles([]string{pyFile}, "python")
+	if err != nil {
+		t.Fatalf("ScanFiles failed: %v", err)
+	}
+
+	if len(imports) != 1 || imports[0].Package != "requests" {
+		t.Errorf("Expected only requests, got %v", imports)
+	}
+}
+
+func TestScanPythonStdlibVersionAware(t *testing.T) {
+	tmpDir := t.TempDir()
+	pyFile := filepath.Join(tmpDir, "test.py")
+
+	content := "import tomllib\nimport distutils\n"
+	if err := os.WriteFile(pyFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		version string
+		want    map[string]bool
+	}{
+		// distutils is gone from 3.12, inside the range
+		{">=3.8,<4.0", map[string]bool{"tomllib": true, "distutils": true}},
+		{">=3.8,<3.12", map[string]bool{"tomllib": true}},
+		{">=3.11", map[string]bool{"distutils": true}},
+		{">=3.11,<=3.11", map[string]bool{}},
+		{"==3.11.*", map[string]bool{}},
+		{">=3.12", map[string]bool{"distutils": true}},
+		{"", map[string]bool{}},
+	}
+
+	for _, tt := range tests {
+		s := NewScanner()
+		s.SetLanguageVersion(tt.version)
+		imports, err := s.ScanFiles([]string{pyFile}, "python")
+		if err != nil {
+			t.Fatalf("ScanFiles failed: %v", err)
+		}
+
+		found := make(map[string]bool)
+		for _, imp := range imports {
+			found[imp.Package] = true
+		}
+		if len(found) != len(tt.want) {
+			t.Errorf("version %q: got %v, want %v", tt.version, found, tt.want)
+			continue
+		}
+		for pkg := range tt.want {
+			if !found[pkg] {
+				t.Errorf("version %q: expected %s to be external", tt.version, pkg)
+			}
+		}
+	}
+}
+
+func TestPythonMinVersion(t *testing.T) {
+	tests := []struct {
+		constraint string
+		want       pythonVersion
+		ok         bool
+	}{
+		{">=3.8,<4.0", pythonVersion{3, 8}, true},
+		{"<4.0,>=3.10", pythonVersion{3, 10}, true},
+		{"~=3.11", pythonVersion{3, 11}, true},
+		{"^3.9", pythonVersion{3, 9}, true},
+		{"3.12", pythonVersion{3, 12}, true},
+		{"==3.10.*", pythonVersion{3, 10}, true},
+		{"<4.0", pythonVersion{}, false},
+		{"", pythonVersion{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := pythonMinVersion(tt.constraint)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("pythonMinVersion(%q) = %v, %v; want %v, %v", tt.constraint, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestPythonMaxVersion(t *testing.T) {
+	tests := []struct {
+		constraint string
+		want       pythonVersion
+		ok         bool
+	}{
+		{">=3.8,<4.0", pythonVersion{4, 0}, true},
+		{"<3.12,>=3.8,<4.0", pythonVersion{3, 12}, true},
+		{"<=3.11", pythonVersion{3, 12}, true},
+		{"~=3.11", pythonVersion{4, 0}, true},
+		{"~=3.11.2", pythonVersion{3, 12}, true},
+		{"^3.9", pythonVersion{4, 0}, true},
+		{"~3.9", pythonVersion{3, 10}, true},
+		{"3.12", pythonVersion{3, 13}, true},
+		{"==3.10.*", pythonVersion{3, 11}, true},
+		{">=3.9", pythonVersion{}, false},
+		{"", pythonVersion{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := pythonMaxVersion(tt.constraint)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("pythonMaxVersion(%q) = %v, %v; want %v, %v", tt.constraint, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestPythonStdlibRemovedModules(t *testing.T) {
+	for _, mod := range []string{"binhex", "formatter", "parser", "symbol", "dummy_threading", "_dummy_thread", "imp", "cgi"} {
+		if !pythonStdlibFor("==3.8.*")[mod] {
+			t.Errorf("%s isn't stdlib on Python 3.8", mod)
+		}
+		if pythonStdlibFor(">=3.8,<4.0")[mod] {
+			t.Errorf("%s is stdlib for >=3.8,<4.0, though later versions removed it", mod)
+		}
+	}
+}
+
+func TestScanFilesParallelOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	var files []string
+	for i := 0; i < 200; i++ {
+		path := filepath.Join(tmpDir, fmt.Sprintf("mod%03d.py", i))
+		content := fmt.Sprintf("import pkg%03d\nimport shared\n", i)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+
+	s := NewScanner()
+	imports, err := s.ScanFiles(files, "python")
+	if err != nil {
+		t.Fatalf("ScanFiles failed: %v", err)
+	}
+
+	// pkg000, shared, pkg001, pkg002, ... in input order
+	if len(imports) != 201 {
+		t.Fatalf("Expected 201 imports, got %d", len(imports))
+	}
+	if imports[0].Package != "pkg000" || imports[1].Package != "shared" {
+		t.Errorf("Unexpected leading imports: %s, %s", imports[0].Package, imports[1].Package)
+	}
+	for i := 1; i < 200; i++ {
+		want := fmt.Sprintf("pkg%03d", i)
+		if imports[i+1].Package != want {
+			t.Fatalf("imports[%d] = %s, want %s", i+1, imports[i+1].Package, want)
+		}
+	}
+}
+
+func TestScanFilesFirstErrorInInputOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+	good := filepath.Join(tmpDir, "good.py")
+	if err := os.WriteFile(good, []byte("import requests"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing1 := filepath.Join(tmpDir, "missing1.py")
+	missing2 := filepath.Join(tmpDir, "missing2.py")
+
+	s := NewScanner()
+	_, err := s.ScanFiles([]string{good, missing1, missing2}, "python")
+	if err == nil {
+		t.Fatal("Expected error for missing file")
+	}
+	if !strings.Contains(err.Error(), "missing1.py") {
+		t.Errorf("Expected error for first missing file, got %v", err)
+	}
+}
+
+func TestScanFilesProgress(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	var files []string
+	for i := 0; i < 2500; i++ {
+		path := filepath.Join(tmpDir, fmt.Sprintf("f%04d.py", i))
+		if err := os.WriteFile(path, []byte("x = 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+
+	var reports []int
+	s := NewScanner()
+	s.SetProgress(func(done, total int) {
+		if total != 2500 {
+			t.Errorf("total = %d, want 2500", total)
+		}
+		reports = append(reports, done)
+	})
+
+	if _, err := s.ScanFiles(files, "python"); err != nil {
+		t.Fatalf("ScanFiles failed: %v", err)
+	}
+
+	if len(reports) != 2 || reports[0] != 1000 || reports[1] != 2000 {
+		t.Errorf("progress reports = %v, want [1000 2000]", reports)
+	}
+}
@@ -0,0 +1,240 @@
+package depgen
+
+import (
+	"strconv"
+	"strings"
+)
+
+// pythonVersion is a major.minor Python version
+type pythonVersion struct {
+	major int
+	minor int
+}
+
+// less reports whether v is older than other
+func (v pythonVersion) less(other pythonVersion) bool {
+	if v.major != other.major {
+		return v.major < other.major
+	}
+	return v.minor < other.minor
+}
+
+// stdlibChange records when a top-level module entered or left the stdlib.
+// A zero value for added means "present in every supported version";
+// a zero value for removed means "still present".
+type stdlibChange struct {
+	added   pythonVersion
+	removed pythonVersion
+}
+
+// pythonStdlibChanges lists top-level modules whose availability depends on
+// the Python version. Modules not listed here are in pythonStdlibBase and are
+// available in every Python 3 version aigogo knows about.
+var pythonStdlibChanges = map[string]stdlibChange{
+	// Added
+	"secrets":     {added: pythonVersion{3, 6}},
+	"contextvars": {added: pythonVersion{3, 7}},
+	"dataclasses": {added: pythonVersion{3, 7}},
+	"graphlib":    {added: pythonVersion{3, 9}},
+	"zoneinfo":    {added: pythonVersion{3, 9}},
+	"tomllib":     {added: pythonVersion{3, 11}},
+
+	// Removed in 3.8
+	"macpath": {removed: pythonVersion{3, 8}},
+
+	// Removed in 3.9
+	"_dummy_thread":   {removed: pythonVersion{3, 9}},
+	"dummy_threading": {removed: pythonVersion{3, 9}},
+
+	// Removed in 3.10
+	"formatter": {removed: pythonVersion{3, 10}},
+	"parser":    {removed: pythonVersion{3, 10}},
+	"symbol":    {removed: pythonVersion{3, 10}},
+
+	// Removed in 3.11
+	"binhex": {removed: pythonVersion{3, 11}},
+
+	// Removed in 3.12 (PEP 632, PEP 594 early removals)
+	"asynchat":  {removed: pythonVersion{3, 12}},
+	"asyncore":  {removed: pythonVersion{3, 12}},
+	"distutils": {removed: pythonVersion{3, 12}},
+	"imp":       {removed: pythonVersion{3, 12}},
+	"smtpd":     {removed: pythonVersion{3, 12}},
+
+	// Removed in 3.13 (PEP 594 dead batteries)
+	"aifc":        {removed: pythonVersion{3, 13}},
+	"audioop":     {removed: pythonVersion{3, 13}},
+	"cgi":         {removed: pythonVersion{3, 13}},
+	"cgitb":       {removed: pythonVersion{3, 13}},
+	"chunk":       {removed: pythonVersion{3, 13}},
+	"crypt":       {removed: pythonVersion{3, 13}},
+	"imghdr":      {removed: pythonVersion{3, 13}},
+	"lib2to3":     {removed: pythonVersion{3, 13}},
+	"mailcap":     {removed: pythonVersion{3, 13}},
+	"msilib":      {removed: pythonVersion{3, 13}},
+	"nis":         {removed: pythonVersion{3, 13}},
+	"nntplib":     {removed: pythonVersion{3, 13}},
+	"ossaudiodev": {removed: pythonVersion{3, 13}},
+	"pipes":       {removed: pythonVersion{3, 13}},
+	"sndhdr":      {removed: pythonVersion{3, 13}},
+	"spwd":        {removed: pythonVersion{3, 13}},
+	"sunau":       {removed: pythonVersion{3, 13}},
+	"telnetlib":   {removed: pythonVersion{3, 13}},
+	"uu":          {removed: pythonVersion{3, 13}},
+	"xdrlib":      {removed: pythonVersion{3, 13}},
+}
+
+// pythonStdlibBase lists top-level stdlib modules present in every Python 3
+// version aigogo targets (taken from sys.stdlib_module_names).
+var pythonStdlibBase = []string{
+	"__future__", "_thread", "abc", "antigravity", "argparse", "array", "ast",
+	"asyncio", "atexit", "base64", "bdb", "binascii", "bisect", "builtins",
+	"bz2", "cProfile", "calendar", "cmath", "cmd", "code", "codecs", "codeop",
+	"collections", "colorsys", "compileall", "concurrent", "configparser",
+	"contextlib", "copy", "copyreg", "csv", "ctypes", "curses", "datetime",
+	"dbm", "decimal", "difflib", "dis", "doctest", "email", "encodings",
+	"ensurepip", "enum", "errno", "faulthandler", "fcntl", "filecmp",
+	"fileinput", "fnmatch", "fractions", "ftplib", "functools", "gc",
+	"genericpath", "getopt", "getpass", "gettext", "glob", "grp", "gzip",
+	"hashlib", "heapq", "hmac", "html", "http", "idlelib", "imaplib",
+	"importlib", "inspect", "io", "ipaddress", "itertools", "json", "keyword",
+	"linecache", "locale", "logging", "lzma", "mailbox", "marshal", "math",
+	"mimetypes", "mmap", "modulefinder", "msvcrt", "multiprocessing", "netrc",
+	"nt", "ntpath", "nturl2path", "numbers", "opcode", "operator", "optparse",
+	"os", "pathlib", "pdb", "pickle", "pickletools", "pkgutil", "platform",
+	"plistlib", "poplib", "posix", "posixpath", "pprint", "profile", "pstats",
+	"pty", "pwd", "py_compile", "pyclbr", "pydoc", "pydoc_data", "pyexpat",
+	"queue", "quopri", "random", "re", "readline", "reprlib", "resource",
+	"rlcompleter", "runpy", "sched", "select", "selectors", "shelve", "shlex",
+	"shutil", "signal", "site", "smtplib", "socket", "socketserver", "sqlite3",
+	"sre_compile", "sre_constants", "sre_parse", "ssl", "stat", "statistics",
+	"string", "stringprep", "struct", "subprocess", "symtable", "sys",
+	"sysconfig", "syslog", "tabnanny", "tarfile", "tempfile", "termios",
+	"textwrap", "this", "threading", "time", "timeit", "tkinter", "token",
+	"tokenize", "trace", "traceback", "tracemalloc", "tty", "turtle",
+	"turtledemo", "types", "typing", "unicodedata", "unittest", "urllib",
+	"uuid", "venv", "warnings", "wave", "weakref", "webbrowser", "winreg",
+	"winsound", "wsgiref", "xml", "xmlrpc", "zipapp", "zipfile", "zipimport",
+	"zlib",
+}
+
+// pythonStdlibFor returns the set of top-level stdlib modules for the Python
+// version range given in language.version (e.g. ">=3.9,<4.0").
+//
+// A module counts only when every version in the range has it: one added
+// after the lower bound, or removed before the upper bound (or at all, when
+// there is none), has to be installed on some interpreter the package may
+// run on. If no lower bound can be determined, every module that has ever
+// been in the stdlib is included, which avoids false "missing dependency"
+// reports.
+func pythonStdlibFor(constraint string) map[string]bool {
+	stdlib := make(map[string]bool, len(pythonStdlibBase)+len(pythonStdlibChanges))
+	for _, mod := range pythonStdlibBase {
+		stdlib[mod] = true
+	}
+
+	target, ok := pythonMinVersion(constraint)
+	limit, bounded := pythonMaxVersion(constraint)
+	for mod, change := range pythonStdlibChanges {
+		if !ok {
+			stdlib[mod] = true
+			continue
+		}
+		if change.added != (pythonVersion{}) && target.less(change.added) {
+			continue
+		}
+		if change.removed != (pythonVersion{}) && (!bounded || change.removed.less(limit)) {
+			continue
+		}
+		stdlib[mod] = true
+	}
+
+	return stdlib
+}
+
+// pythonMinVersion extracts the lowest allowed major.minor version from a
+// constraint such as ">=3.8,<4.0", "~=3.10", "^3.9" or "3.11".
+func pythonMinVersion(constraint string) (pythonVersion, bool) {
+	for _, part := range strings.Split(constraint, ",") {
+		part = strings.TrimSpace(part)
+		var rest string
+		switch {
+		case strings.HasPrefix(part, ">="):
+			rest = part[2:]
+		case strings.HasPrefix(part, "=="), strings.HasPrefix(part, "~="):
+			rest = part[2:]
+		case strings.HasPrefix(part, ">"):
+			rest = part[1:]
+		case strings.HasPrefix(part, "^"), strings.HasPrefix(part, "~"):
+			rest = part[1:]
+		case part != "" && part[0] >= '0' && part[0] <= '9':
+			rest = part
+		default:
+			continue
+		}
+
+		if v, ok := parsePythonVersion(strings.TrimSpace(rest)); ok {
+			return v, true
+		}
+	}
+	return pythonVersion{}, false
+}
+
+// pythonMaxVersion extracts the major.minor version a constraint such as
+// ">=3.8,<4.0", "~=3.10", "^3.9" or "3.11" stops before: "<=3.11" and
+// "3.11" allow 3.11 but not 3.12. It reports false when the constraint has
+// no upper bound.
+func pythonMaxVersion(constraint string) (pythonVersion, bool) {
+	var limit pythonVersion
+	found := false
+	for _, part := range strings.Split(constraint, ",") {
+		part = strings.TrimSpace(part)
+		var v pythonVersion
+		var ok bool
+		switch {
+		case strings.HasPrefix(part, "<="):
+			v, ok = parsePythonVersion(strings.TrimSpace(part[2:]))
+			v.minor++
+		case strings.HasPrefix(part, "<"):
+			v, ok = parsePythonVersion(strings.TrimSpace(part[1:]))
+		case strings.HasPrefix(part, "~="), strings.HasPrefix(part, "^"):
+			// ~=3.11.2 stops before 3.12, ~=3.11 and ^3.11 before 4.0
+			rest := strings.TrimSpace(strings.TrimLeft(part, "~=^"))
+			v, ok = parsePythonVersion(rest)
+			if strings.HasPrefix(part, "~=") && strings.Count(rest, ".") >= 2 {
+				v.minor++
+			} else {
+				v = pythonVersion{major: v.major + 1}
+			}
+		case strings.HasPrefix(part, "=="), strings.HasPrefix(part, "~"):
+			v, ok = parsePythonVersion(strings.TrimSpace(strings.TrimLeft(part, "=~")))
+			v.minor++
+		case part != "" && part[0] >= '0' && part[0] <= '9':
+			v, ok = parsePythonVersion(part)
+			v.minor++
+		default:
+			continue
+		}
+		if ok && (!found || v.less(limit)) {
+			limit, found = v, true
+		}
+	}
+	return limit, found
+}
+
+// parsePythonVersion parses "3.11" or "3.11.4" into a pythonVersion
+func parsePythonVersion(s string) (pythonVersion, bool) {
+	segments := strings.Split(strings.TrimSuffix(s, ".*"), ".")
+	if len(segments) < 2 {
+		return pythonVersion{}, false
+	}
+	major, err := strconv.Atoi(segments[0])
+	if err != nil {
+		return pythonVersion{}, false
+	}
+	minor, err := strconv.Atoi(segments[1])
+	if err != nil {
+		return pythonVersion{}, false
+	}
+	return pythonVersion{major: major, minor: minor}, true
+}
@@ -7,7 +7,37 @@ import (
 	"github.com/aupeachmo/aigogo/pkg/manifest"
 )
 
-// ValidationResult contains validation results
+// Severity levels of validation findings
+const (
+	SeverityError   = "error"   // always fails validation
+	SeverityWarning = "warning" // fails validation with --strict
+	SeverityInfo    = "info"    // advisory only
+)
+
+// Validation rule IDs, stable for SARIF consumers
+const (
+	RuleMissingDependency = "missing-dependency"
+	RuleUnusedDependency  = "unused-dependency"
+	RuleUnpinnedVersion   = "unpinned-version"
+	RuleExactVersion      = "exact-version"
+	RuleUnusedFile        = "unused-file"
+	RulePeerDependency    = "peer-dependency"
+)
+
+// Finding is a single validation result
+type Finding struct {
+	Severity string
+	Rule     string
+	Message  string
+	Package  string
+	Import   *ImportInfo // first import of Package; nil for findings about aigogo.json itself
+	File     string      // included file the finding is about, for unused-file
+	Field    string      // aigogo.json field the finding is about, for lint findings
+}
+
+// ValidationResult contains validation results. Errors and Warnings hold
+// the messages of Findings as plain strings, with info findings listed
+// under Warnings.
 type ValidationResult struct {
 	Valid       bool
 	Errors      []string
@@ -16,13 +46,50 @@ type ValidationResult struct {
 	MissingDeps []string
 	UnusedDeps  []string
 	Imports     []ImportInfo
+	Findings    []Finding
+}
+
+// Failed reports whether validation failed. In strict mode warnings fail
+// validation as well as errors.
+func (r *ValidationResult) Failed(strict bool) bool {
+	if !r.Valid {
+		return true
+	}
+	if strict {
+		for _, f := range r.Findings {
+			if f.Severity == SeverityWarning {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AddFinding records a finding and its message in the matching legacy
+// list. Callers use it for findings that depend on the machine, such as
+// RulePeerDependency.
+func (r *ValidationResult) AddFinding(f Finding) {
+	r.Findings = append(r.Findings, f)
+	if f.Severity != SeverityError {
+		r.Warnings = append(r.Warnings, f.Message)
+	}
 }
 
 // ImportInfo represents a detected import
 type ImportInfo struct {
 	Package    string
 	SourceFile string
-	LineNumber int
+	LineNumber int // Line within the file, or within the cell for notebooks
+	Cell       int // 1-based notebook cell number; 0 for regular source files
+}
+
+// Location formats where the import was found: "utils.py:3" or
+// "analysis.ipynb cell 2:3"
+func (i ImportInfo) Location() string {
+	if i.Cell > 0 {
+		return fmt.Sprintf("%s cell %d:%d", i.SourceFile, i.Cell, i.LineNumber)
+	}
+	return fmt.Sprintf("%s:%d", i.SourceFile, i.LineNumber)
 }
 
 // Validator validates dependencies
@@ -37,8 +104,24 @@ func NewValidator() *Validator {
 	}
 }
 
-// Validate checks if declared dependencies match actual imports
+// SetProgress registers a callback invoked every 1000 scanned files
+func (v *Validator) SetProgress(fn ProgressFunc) {
+	v.scanner.SetProgress(fn)
+}
+
+// SetCache enables the per-file scan cache
+func (v *Validator) SetCache(cache *ScanCache) {
+	v.scanner.SetCache(cache)
+}
+
+// Validate checks if declared dependencies match actual imports. Each
+// language of a multi-language package is checked against its own files
+// and dependencies.
 func (v *Validator) Validate(m *manifest.Manifest, files []string) (*ValidationResult, error) {
+	if len(m.Languages) > 0 {
+		return v.validateLanguages(m, files)
+	}
+
 	result := &ValidationResult{
 		Valid:       true,
 		Errors:      []string{},
@@ -49,6 +132,7 @@ func (v *Validator) Validate(m *manifest.Manifest, files []string) (*ValidationR
 	}
 
 	// Scan files for imports
+	v.scanner.SetLanguageVersion(m.Language.Version)
 	imports, err := v.scanner.ScanFiles(files, m.Language.Name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan files: %w", err)
@@ -56,11 +140,30 @@ func (v *Validator) Validate(m *manifest.Manifest, files []string) (*ValidationR
 
 	result.Imports = imports
 
+	// First import of each package, in scan order
+	var importOrder []string
+	firstImport := make(map[string]*ImportInfo)
+	for i := range imports {
+		if _, seen := firstImport[imports[i].Package]; !seen {
+			firstImport[imports[i].Package] = &imports[i]
+			importOrder = append(importOrder, imports[i].Package)
+		}
+	}
+
 	if m.Dependencies == nil {
 		if len(imports) > 0 {
 			result.Valid = false
 			result.Errors = append(result.Errors,
 				fmt.Sprintf("Found %d imports but no dependencies declared", len(imports)))
+			for _, pkg := range importOrder {
+				result.Findings = append(result.Findings, Finding{
+					Severity: SeverityError,
+					Rule:     RuleMissingDependency,
+					Message:  fmt.Sprintf("Package '%s' is imported but not declared", pkg),
+					Package:  pkg,
+					Import:   firstImport[pkg],
+				})
+			}
 
 			result.Suggestions = append(result.Suggestions, "Add dependencies section to aigogo.json:")
 			for _, imp := range imports {
@@ -68,50 +171,78 @@ func (v *Validator) Validate(m *manifest.Manifest, files []string) (*ValidationR
 					v.suggestDependency(imp.Package, m.Language.Name))
 			}
 		}
+		v.checkUnusedFiles(m, files, result)
 		return result, nil
 	}
 
-	// Build maps
-	imported := make(map[string]bool)
-	for _, imp := range imports {
-		imported[imp.Package] = true
-	}
-
+	// Peer dependencies are provided by the host project and extras by
+	// consumers that select them, so imports of them are declared too
+	checked := append(append(append([]manifest.Dependency{}, m.Dependencies.Runtime...), m.Dependencies.Peer...), m.ExtraDependencies()...)
 	declared := make(map[string]bool)
-	for _, dep := range m.Dependencies.Runtime {
+	for _, dep := range checked {
 		declared[dep.Package] = true
 	}
 
 	// Find missing dependencies
-	for pkg := range imported {
-		if !declared[pkg] {
+	for _, pkg := range importOrder {
+		if !anyDependencyMatches(m.Language.Name, declared, pkg) {
 			result.MissingDeps = append(result.MissingDeps, pkg)
+			// The legacy Warnings list has always carried this message
+			// alongside the summary error below
 			result.Warnings = append(result.Warnings,
 				fmt.Sprintf("Package '%s' is imported but not declared", pkg))
+			result.Findings = append(result.Findings, Finding{
+				Severity: SeverityError,
+				Rule:     RuleMissingDependency,
+				Message:  fmt.Sprintf("Package '%s' is imported but not declared", pkg),
+				Package:  pkg,
+				Import:   firstImport[pkg],
+			})
 		}
 	}
 
 	// Find unused dependencies
-	for pkg := range declared {
-		if !imported[pkg] {
-			result.UnusedDeps = append(result.UnusedDeps, pkg)
-			result.Warnings = append(result.Warnings,
-				fmt.Sprintf("Package '%s' is declared but not imported", pkg))
+	for _, dep := range checked {
+		used := false
+		for _, imp := range importOrder {
+			if DependencyMatchesImport(m.Language.Name, dep.Package, imp) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			result.UnusedDeps = append(result.UnusedDeps, dep.Package)
+			result.AddFinding(Finding{
+				Severity: SeverityWarning,
+				Rule:     RuleUnusedDependency,
+				Message:  fmt.Sprintf("Package '%s' is declared but not imported", dep.Package),
+				Package:  dep.Package,
+			})
 		}
 	}
 
 	// Check version constraints
-	for _, dep := range m.Dependencies.Runtime {
+	for _, dep := range append(append([]manifest.Dependency{}, m.Dependencies.Runtime...), m.ExtraDependencies()...) {
 		if v.hasNoVersion(dep.Version) {
-			result.Warnings = append(result.Warnings,
-				fmt.Sprintf("Package '%s' has no version constraint", dep.Package))
+			result.AddFinding(Finding{
+				Severity: SeverityWarning,
+				Rule:     RuleUnpinnedVersion,
+				Message:  fmt.Sprintf("Package '%s' has no version constraint", dep.Package),
+				Package:  dep.Package,
+			})
 		}
 		if v.hasExactVersion(dep.Version, m.Language.Name) {
-			result.Warnings = append(result.Warnings,
-				fmt.Sprintf("Package '%s' uses exact version (may cause conflicts)", dep.Package))
+			result.AddFinding(Finding{
+				Severity: SeverityInfo,
+				Rule:     RuleExactVersion,
+				Message:  fmt.Sprintf("Package '%s' uses exact version (may cause conflicts)", dep.Package),
+				Package:  dep.Package,
+			})
 		}
 	}
 
+	v.checkUnusedFiles(m, files, result)
+
 	// Suggest missing dependencies
 	if len(result.MissingDeps) > 0 {
 		result.Suggestions = append(result.Suggestions, "Add missing dependencies:")
@@ -131,6 +262,61 @@ func (v *Validator) Validate(m *manifest.Manifest, files []string) (*ValidationR
 	return result, nil
 }
 
+// validateLanguages validates each language of a multi-language package
+// and merges the results
+func (v *Validator) validateLanguages(m *manifest.Manifest, files []string) (*ValidationResult, error) {
+	groups := m.GroupFilesByLanguage(files)
+	merged := &ValidationResult{
+		Valid:       true,
+		Errors:      []string{},
+		Warnings:    []string{},
+		Suggestions: []string{},
+		MissingDeps: []string{},
+		UnusedDeps:  []string{},
+	}
+
+	// The scan cache holds results for a single language, so only the
+	// primary language uses it
+	cache := v.scanner.cache
+	defer v.scanner.SetCache(cache)
+
+	for i, lang := range m.AllLanguages() {
+		if i > 0 {
+			v.scanner.SetCache(nil)
+		}
+		result, err := v.Validate(m.ForLanguage(lang.Name), groups[lang.Name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", lang.Name, err)
+		}
+		merged.Valid = merged.Valid && result.Valid
+		merged.Errors = append(merged.Errors, result.Errors...)
+		merged.Warnings = append(merged.Warnings, result.Warnings...)
+		merged.Suggestions = append(merged.Suggestions, result.Suggestions...)
+		merged.MissingDeps = append(merged.MissingDeps, result.MissingDeps...)
+		merged.UnusedDeps = append(merged.UnusedDeps, result.UnusedDeps...)
+		merged.Imports = append(merged.Imports, result.Imports...)
+		merged.Findings = append(merged.Findings, result.Findings...)
+	}
+	return merged, nil
+}
+
+// checkUnusedFiles warns about included source files that no other
+// included file imports
+func (v *Validator) checkUnusedFiles(m *manifest.Manifest, files []string, result *ValidationResult) {
+	var hints []string
+	for _, script := range m.Scripts {
+		hints = append(hints, script)
+	}
+	for _, file := range FindUnusedFiles(files, m.Language.Name, hints) {
+		result.AddFinding(Finding{
+			Severity: SeverityWarning,
+			Rule:     RuleUnusedFile,
+			Message:  fmt.Sprintf("File '%s' is not imported by any other included file", file),
+			File:     file,
+		})
+	}
+}
+
 func (v *Validator) hasNoVersion(version string) bool {
 	version = strings.TrimSpace(version)
 	return version == "" || version == "*" || version == "latest"
@@ -146,6 +332,16 @@ func (v *Validator) hasExactVersion(version, lang string) bool {
 		return false // Go uses minimum version selection
 	case "rust":
 		return strings.HasPrefix(version, "=")
+	case "ruby":
+		return !strings.ContainsAny(version, "~><")
+	case "java":
+		// Maven ranges use brackets: [1.0,2.0)
+		return !strings.ContainsAny(version, "[(")
+	case "php":
+		return !strings.ContainsAny(version, "^~><*|")
+	case "csharp":
+		// NuGet "1.0" means >= 1.0; only "[1.0]" pins
+		return strings.HasPrefix(version, "[") && strings.HasSuffix(version, "]") && !strings.Contains(version, ",")
 	}
 	return false
 }
@@ -160,6 +356,68 @@ func (v *Validator) suggestDependency(pkg, lang string) string {
 		return fmt.Sprintf(`  {"package": "%s", "version": "v1.0.0"}`, pkg)
 	case "rust":
 		return fmt.Sprintf(`  {"package": "%s", "version": "1.0"}`, pkg)
+	case "ruby":
+		return fmt.Sprintf(`  {"package": "%s", "version": "~> 1.0"}`, pkg)
+	case "java":
+		return fmt.Sprintf(`  {"package": "%s:<artifactId>", "version": "[1.0,2.0)"}`, pkg)
+	case "csharp":
+		return fmt.Sprintf(`  {"package": "%s", "version": "[1.0.0,2.0.0)"}`, pkg)
+	case "php":
+		return fmt.Sprintf(`  {"package": "%s", "version": "^1.0"}`, ComposerPackageHint(pkg))
 	}
 	return fmt.Sprintf(`  {"package": "%s", "version": "1.0.0"}`, pkg)
 }
+
+// DependencyMatchesImport reports whether a declared dependency satisfies a
+// scanned import. Most languages compare names directly; Java dependencies
+// are Maven coordinates ("groupId:artifactId") and match an import when the
+// groupId and the import's package prefix overlap segment-wise. C# imports
+// are namespaces, which match a NuGet package of the same name or a parent
+// ("Newtonsoft.Json" covers "using Newtonsoft.Json.Linq;"). PHP imports are
+// top-level namespaces, matched case-insensitively against the Composer
+// vendor ("GuzzleHttp" matches "guzzlehttp/guzzle").
+func DependencyMatchesImport(language, depPackage, importPackage string) bool {
+	switch language {
+	case "java":
+		groupID := strings.SplitN(depPackage, ":", 2)[0]
+		return hasSegmentPrefix(groupID, importPackage) || hasSegmentPrefix(importPackage, groupID)
+	case "csharp":
+		return hasSegmentPrefix(importPackage, depPackage)
+	case "php":
+		if strings.Contains(importPackage, "/") {
+			return strings.EqualFold(depPackage, importPackage)
+		}
+		vendor := strings.SplitN(depPackage, "/", 2)[0]
+		return strings.EqualFold(vendor, importPackage)
+	}
+	return depPackage == importPackage
+}
+
+// ComposerPackageHint turns a scanned PHP namespace into a Composer package
+// placeholder ("GuzzleHttp" -> "guzzlehttp/<package>"). Vendor paths found in
+// require statements are already package names and are returned unchanged.
+func ComposerPackageHint(pkg string) string {
+	if strings.Contains(pkg, "/") {
+		return pkg
+	}
+	return strings.ToLower(pkg) + "/<package>"
+}
+
+// anyDependencyMatches reports whether any declared dependency satisfies pkg
+func anyDependencyMatches(language string, declared map[string]bool, pkg string) bool {
+	if declared[pkg] {
+		return true
+	}
+	for dep := range declared {
+		if DependencyMatchesImport(language, dep, pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSegmentPrefix reports whether name equals prefix or starts with prefix
+// followed by a dot ("org.junit.jupiter" has prefix "org.junit")
+func hasSegmentPrefix(name, prefix string) bool {
+	return name == prefix || strings.HasPrefix(name, prefix+".")
+}
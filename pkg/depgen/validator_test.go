@@ -210,6 +210,75 @@ func TestValidateVersionWarnings(t *testing.T) {
 	}
 }
 
+func TestValidateFindingSeverities(t *testing.T) {
+	tmpDir := t.TempDir()
+	pyFile := filepath.Join(tmpDir, "test.py")
+
+	if err := os.WriteFile(pyFile, []byte("import os\nimport a\nimport missing\nimport missing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &manifest.Manifest{
+		Language: manifest.Language{Name: "python"},
+		Dependencies: &manifest.Dependencies{
+			Runtime: []manifest.Dependency{
+				{Package: "a", Version: "==1.0.0"},
+				{Package: "unused", Version: "*"},
+			},
+		},
+	}
+
+	v := NewValidator()
+	result, err := v.Validate(m, []string{pyFile})
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	want := []struct {
+		severity, rule, pkg string
+	}{
+		{SeverityError, RuleMissingDependency, "missing"},
+		{SeverityWarning, RuleUnusedDependency, "unused"},
+		{SeverityInfo, RuleExactVersion, "a"},
+		{SeverityWarning, RuleUnpinnedVersion, "unused"},
+	}
+	if len(result.Findings) != len(want) {
+		t.Fatalf("Expected %d findings, got %+v", len(want), result.Findings)
+	}
+	for i, w := range want {
+		f := result.Findings[i]
+		if f.Severity != w.severity || f.Rule != w.rule || f.Package != w.pkg {
+			t.Errorf("finding %d = %+v, want %s %s %s", i, f, w.severity, w.rule, w.pkg)
+		}
+	}
+
+	missing := result.Findings[0]
+	if missing.Import == nil || missing.Import.LineNumber != 3 {
+		t.Errorf("missing-dependency should point at the first import, got %+v", missing.Import)
+	}
+	if !result.Failed(false) {
+		t.Error("Expected validation to fail with a missing dependency")
+	}
+}
+
+func TestValidationResultFailedStrict(t *testing.T) {
+	result := &ValidationResult{
+		Valid:    true,
+		Findings: []Finding{{Severity: SeverityInfo, Rule: RuleExactVersion}},
+	}
+	if result.Failed(true) {
+		t.Error("info findings should not fail strict validation")
+	}
+
+	result.Findings = append(result.Findings, Finding{Severity: SeverityWarning, Rule: RuleUnusedDependency})
+	if result.Failed(false) {
+		t.Error("warnings should not fail validation without --strict")
+	}
+	if !result.Failed(true) {
+		t.Error("warnings should fail strict validation")
+	}
+}
+
 func TestValidateJavaScriptExactVersion(t *testing.T) {
 	tmpDir := t.TempDir()
 	jsFile := filepath.Join(tmpDir, "test.js")
@@ -291,6 +360,21 @@ func TestValidateHasExactVersion(t *testing.T) {
 		// Rust
 		{"=1.0.0", "rust", true},
 		{"1.0.0", "rust", false},
+		// Ruby
+		{"1.0.0", "ruby", true},
+		{"~> 1.0", "ruby", false},
+		{">= 1.0, < 2.0", "ruby", false},
+		// Java
+		{"2.10.1", "java", true},
+		{"[2.0,3.0)", "java", false},
+		// C#
+		{"[13.0.3]", "csharp", true},
+		{"13.0.3", "csharp", false},
+		{"[13.0,14.0)", "csharp", false},
+		// PHP
+		{"7.8.0", "php", true},
+		{"^7.8", "php", false},
+		{"~7.8.0", "php", false},
 	}
 
 	for _, tt := range tests {
@@ -313,6 +397,8 @@ func TestValidateSuggestDependency(t *testing.T) {
 		{"axios", "javascript", "^1.0.0"},
 		{"errors", "go", "v1.0.0"},
 		{"serde", "rust", "1.0"},
+		{"rack", "ruby", "~> 1.0"},
+		{"com.google.gson", "java", "[1.0,2.0)"},
 		{"pkg", "unknown", "1.0.0"},
 	}
 
@@ -377,3 +463,179 @@ func containsAt(s, substr string) bool {
 	}
 	return false
 }
+
+func TestValidateJavaMavenCoordinates(t *testing.T) {
+	tmpDir := t.TempDir()
+	javaFile := filepath.Join(tmpDir, "App.java")
+
+	content := "import org.apache.commons.lang3.StringUtils;\nimport org.junit.jupiter.api.Test;\nimport com.google.gson.Gson;\n"
+	if err := os.WriteFile(javaFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &manifest.Manifest{
+		Language: manifest.Language{Name: "java"},
+		Dependencies: &manifest.Dependencies{
+			Runtime: []manifest.Dependency{
+				{Package: "org.apache.commons:commons-lang3", Version: "[3.0,4.0)"},
+				{Package: "org.junit.jupiter:junit-jupiter", Version: "[5.0,6.0)"},
+				{Package: "org.slf4j:slf4j-api", Version: "[2.0,3.0)"},
+			},
+		},
+	}
+
+	v := NewValidator()
+	result, err := v.Validate(m, []string{javaFile})
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	if len(result.MissingDeps) != 1 || result.MissingDeps[0] != "com.google.gson" {
+		t.Errorf("MissingDeps = %v, want [com.google.gson]", result.MissingDeps)
+	}
+	if len(result.UnusedDeps) != 1 || result.UnusedDeps[0] != "org.slf4j:slf4j-api" {
+		t.Errorf("UnusedDeps = %v, want [org.slf4j:slf4j-api]", result.UnusedDeps)
+	}
+}
+
+func TestDependencyMatchesImport(t *testing.T) {
+	tests := []struct {
+		lang  string
+		dep   string
+		imp   string
+		match bool
+	}{
+		{"python", "requests", "requests", true},
+		{"python", "requests", "requests_toolbelt", false},
+		{"ruby", "rack", "rack", true},
+		{"java", "org.apache.commons:commons-lang3", "org.apache.commons", true},
+		{"java", "com.fasterxml.jackson.core:jackson-databind", "com.fasterxml.jackson", true},
+		{"java", "org.junit.jupiter:junit-jupiter", "org.junit", true},
+		{"java", "org.apache.commons:commons-lang3", "org.apache.commonsx", false},
+		{"java", "com.google.code.gson:gson", "com.google.gson", false},
+		{"csharp", "Newtonsoft.Json", "Newtonsoft.Json.Linq", true},
+		{"csharp", "Newtonsoft.Json", "Newtonsoft", false},
+		{"csharp", "Serilog", "Serilog.Sinks.File", true},
+		{"php", "guzzlehttp/guzzle", "GuzzleHttp", true},
+		{"php", "monolog/monolog", "Monolog", true},
+		{"php", "acme/widgets", "acme/widgets", true},
+		{"php", "acme/widgets", "acme/other", false},
+		{"php", "monolog/monolog", "Carbon", false},
+	}
+
+	for _, tt := range tests {
+		if got := DependencyMatchesImport(tt.lang, tt.dep, tt.imp); got != tt.match {
+			t.Errorf("DependencyMatchesImport(%q, %q, %q) = %v, want %v", tt.lang, tt.dep, tt.imp, got, tt.match)
+		}
+	}
+}
+
+func TestValidateMultiLanguage(t *testing.T) {
+	tmpDir := t.TempDir()
+	pyFile := filepath.Join(tmpDir, "helper.py")
+	tsFile := filepath.Join(tmpDir, "client.ts")
+	if err := os.WriteFile(pyFile, []byte("import requests\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tsFile, []byte("import axios from 'axios'\nimport { z } from 'zod'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// requests is declared for Python only, so the TypeScript client must
+	// declare its own imports
+	m := &manifest.Manifest{
+		Language:  manifest.Language{Name: "python", Version: ">=3.8"},
+		Languages: []manifest.Language{{Name: "javascript", Version: ">=18"}},
+		Dependencies: &manifest.Dependencies{
+			Runtime: []manifest.Dependency{
+				{Package: "requests", Version: ">=2.31.0"},
+				{Package: "axios", Version: "^1.6.0", Language: "javascript"},
+			},
+		},
+	}
+
+	v := NewValidator()
+	result, err := v.Validate(m, []string{pyFile, tsFile})
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	if result.Valid {
+		t.Error("Expected invalid result for the undeclared JavaScript import")
+	}
+	if len(result.MissingDeps) != 1 || result.MissingDeps[0] != "zod" {
+		t.Errorf("Expected zod to be missing, got %v", result.MissingDeps)
+	}
+	if len(result.UnusedDeps) != 0 {
+		t.Errorf("Expected no unused deps, got %v", result.UnusedDeps)
+	}
+}
+
+func TestValidatePeerDependencies(t *testing.T) {
+	tmpDir := t.TempDir()
+	pyFile := filepath.Join(tmpDir, "model.py")
+	if err := os.WriteFile(pyFile, []byte("import torch\nimport numpy"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &manifest.Manifest{
+		Language: manifest.Language{Name: "python"},
+		Dependencies: &manifest.Dependencies{
+			Runtime: []manifest.Dependency{{Package: "numpy", Version: ">=1.24"}},
+			Peer: []manifest.Dependency{
+				{Package: "torch", Version: ">=2.0"},
+				{Package: "jax", Version: ">=0.4"},
+			},
+		},
+	}
+
+	result, err := NewValidator().Validate(m, []string{pyFile})
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if len(result.MissingDeps) != 0 {
+		t.Errorf("imports of peer dependencies should count as declared, missing: %v", result.MissingDeps)
+	}
+	if len(result.UnusedDeps) != 1 || result.UnusedDeps[0] != "jax" {
+		t.Errorf("UnusedDeps = %v, want [jax]", result.UnusedDeps)
+	}
+}
+
+func TestValidateExtras(t *testing.T) {
+	tmpDir := t.TempDir()
+	pyFile := filepath.Join(tmpDir, "plot.py")
+	if err := os.WriteFile(pyFile, []byte("import numpy\nimport matplotlib"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &manifest.Manifest{
+		Language: manifest.Language{Name: "python"},
+		Dependencies: &manifest.Dependencies{
+			Runtime: []manifest.Dependency{{Package: "numpy", Version: ">=1.24"}},
+			Extras: map[string][]manifest.Dependency{
+				"viz": {{Package: "matplotlib", Version: ">=3.7"}},
+				"cli": {{Package: "click", Version: "*"}},
+			},
+		},
+	}
+
+	result, err := NewValidator().Validate(m, []string{pyFile})
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if len(result.MissingDeps) != 0 {
+		t.Errorf("imports of extras should count as declared, missing: %v", result.MissingDeps)
+	}
+	if len(result.UnusedDeps) != 1 || result.UnusedDeps[0] != "click" {
+		t.Errorf("UnusedDeps = %v, want [click]", result.UnusedDeps)
+	}
+	unpinned := false
+	for _, f := range result.Findings {
+		if f.Rule == RuleUnpinnedVersion && f.Package == "click" {
+			unpinned = true
+		}
+	}
+	if !unpinned {
+		t.Errorf("expected unpinned-version finding for the click extra, got %+v", result.Findings)
+	}
+}
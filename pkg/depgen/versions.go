@@ -0,0 +1,232 @@
+package depgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/logging"
+	"github.com/aupeachmo/aigogo/pkg/userdirs"
+)
+
+const (
+	// defaultPyPIURL is the base URL of the PyPI JSON API
+	defaultPyPIURL = "https://pypi.org/pypi"
+	// defaultNpmURL is the base URL of the npm registry
+	defaultNpmURL = "https://registry.npmjs.org"
+	// versionCacheTTL is how long a looked-up version is considered fresh
+	versionCacheTTL = 24 * time.Hour
+)
+
+// cachedVersion is a single entry in the version cache
+type cachedVersion struct {
+	Version   string    `json:"version"`
+	License   string    `json:"license,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// VersionResolver looks up the latest published version and license of a
+// package on PyPI or npm. Results are cached in versions.json in the default
+// build/pull cache directory.
+type VersionResolver struct {
+	client    *http.Client
+	offline   bool
+	pypiURL   string
+	npmURL    string
+	cachePath string
+	cache     map[string]cachedVersion
+	dirty     bool
+}
+
+// NewVersionResolver creates a resolver. When offline is true no network
+// requests are made and only previously cached versions are returned.
+func NewVersionResolver(offline bool) *VersionResolver {
+	cachePath := ""
+	if dir, err := userdirs.PackageCacheDir(); err == nil {
+		cachePath = filepath.Join(dir, "versions.json")
+	}
+
+	r := &VersionResolver{
+		client:    logging.NewHTTPClient(5 * time.Second),
+		offline:   offline,
+		pypiURL:   defaultPyPIURL,
+		npmURL:    defaultNpmURL,
+		cachePath: cachePath,
+		cache:     make(map[string]cachedVersion),
+	}
+	r.loadCache()
+	return r
+}
+
+// Latest returns the latest published version of pkg for the given language.
+// Only python (PyPI) and javascript (npm) are supported.
+func (r *VersionResolver) Latest(pkg, language string) (string, error) {
+	entry, err := r.lookup(pkg, language)
+	if err != nil {
+		return "", err
+	}
+	return entry.Version, nil
+}
+
+// License returns the license of the latest published version of pkg, as an
+// SPDX identifier or expression where the registry provides one. An empty
+// string means the registry has no license information.
+func (r *VersionResolver) License(pkg, language string) (string, error) {
+	entry, err := r.lookup(pkg, language)
+	if err != nil {
+		return "", err
+	}
+	return entry.License, nil
+}
+
+// lookup returns registry metadata for pkg, from the cache when fresh
+func (r *VersionResolver) lookup(pkg, language string) (cachedVersion, error) {
+	var lookupURL string
+	switch language {
+	case "python":
+		lookupURL = fmt.Sprintf("%s/%s/json", r.pypiURL, url.PathEscape(pkg))
+	case "javascript", "typescript":
+		pkg = npmPackageName(pkg)
+		lookupURL = fmt.Sprintf("%s/%s/latest", r.npmURL, url.PathEscape(pkg))
+	default:
+		return cachedVersion{}, fmt.Errorf("registry lookup is not supported for %s", language)
+	}
+
+	key := language + ":" + pkg
+	if entry, ok := r.cache[key]; ok {
+		if r.offline || time.Since(entry.FetchedAt) < versionCacheTTL {
+			return entry, nil
+		}
+	}
+
+	if r.offline {
+		return cachedVersion{}, fmt.Errorf("no cached metadata for %s (offline)", pkg)
+	}
+
+	entry, err := r.fetch(lookupURL, language)
+	if err != nil {
+		return cachedVersion{}, err
+	}
+
+	entry.FetchedAt = time.Now()
+	r.cache[key] = entry
+	r.dirty = true
+	return entry, nil
+}
+
+// fetch queries the registry and extracts the version and license fields
+func (r *VersionResolver) fetch(lookupURL, language string) (cachedVersion, error) {
+	resp, err := r.client.Get(lookupURL)
+	if err != nil {
+		return cachedVersion{}, fmt.Errorf("failed to query registry: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return cachedVersion{}, fmt.Errorf("registry returned %s", resp.Status)
+	}
+
+	var payload struct {
+		// npm
+		Version string          `json:"version"`
+		License json.RawMessage `json:"license"`
+		// PyPI
+		Info struct {
+			Version           string   `json:"version"`
+			License           string   `json:"license"`
+			LicenseExpression string   `json:"license_expression"`
+			Classifiers       []string `json:"classifiers"`
+		} `json:"info"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return cachedVersion{}, fmt.Errorf("failed to parse registry response: %w", err)
+	}
+
+	var entry cachedVersion
+	if language == "python" {
+		entry.Version = payload.Info.Version
+		entry.License = pypiLicense(payload.Info.LicenseExpression, payload.Info.License, payload.Info.Classifiers)
+	} else {
+		entry.Version = payload.Version
+		entry.License = npmLicense(payload.License)
+	}
+	if entry.Version == "" {
+		return cachedVersion{}, fmt.Errorf("registry response has no version")
+	}
+	return entry, nil
+}
+
+// SaveCache writes looked-up versions back to disk. Errors are ignored since
+// the cache is only an optimisation.
+func (r *VersionResolver) SaveCache() {
+	if !r.dirty || r.cachePath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(r.cache, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(r.cachePath), 0755); err != nil {
+		return
+	}
+	_ = os.WriteFile(r.cachePath, data, 0644)
+	r.dirty = false
+}
+
+func (r *VersionResolver) loadCache() {
+	if r.cachePath == "" {
+		return
+	}
+	data, err := os.ReadFile(r.cachePath)
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(data, &r.cache)
+	if r.cache == nil {
+		r.cache = make(map[string]cachedVersion)
+	}
+}
+
+// npmPackageName strips a subpath from a JS import specifier
+// "lodash/fp" -> "lodash", "@scope/pkg/sub" -> "@scope/pkg"
+func npmPackageName(spec string) string {
+	parts := strings.Split(spec, "/")
+	if strings.HasPrefix(spec, "@") && len(parts) >= 2 {
+		return parts[0] + "/" + parts[1]
+	}
+	return parts[0]
+}
+
+// SuggestConstraint turns a concrete latest version into a compatible range:
+// Python ">=2.31.0,<3.0.0" (or "<0.5.0" for 0.x), JavaScript "^2.31.0".
+func SuggestConstraint(version, language string) string {
+	switch language {
+	case "python":
+		segments := strings.SplitN(version, ".", 3)
+		major := leadingInt(segments[0])
+		if major == 0 && len(segments) > 1 {
+			return fmt.Sprintf(">=%s,<0.%d.0", version, leadingInt(segments[1])+1)
+		}
+		return fmt.Sprintf(">=%s,<%d.0.0", version, major+1)
+	case "javascript", "typescript":
+		return "^" + version
+	}
+	return version
+}
+
+// leadingInt parses the leading digits of s, returning 0 if there are none
+func leadingInt(s string) int {
+	n := 0
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			break
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n
+}
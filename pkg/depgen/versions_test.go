@@ -0,0 +1,165 @@
+package depgen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestResolver(t *testing.T, offline bool, handler http.HandlerFunc) (*VersionResolver, *int) {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	r := &VersionResolver{
+		client:    srv.Client(),
+		offline:   offline,
+		pypiURL:   srv.URL + "/pypi",
+		npmURL:    srv.URL + "/npm",
+		cachePath: filepath.Join(t.TempDir(), "versions.json"),
+		cache:     make(map[string]cachedVersion),
+	}
+	return r, &calls
+}
+
+func TestVersionResolverPyPI(t *testing.T) {
+	r, calls := newTestResolver(t, false, func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/pypi/requests/json" {
+			t.Errorf("unexpected path %s", req.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"info": {"version": "2.32.3"}}`))
+	})
+
+	v, err := r.Latest("requests", "python")
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if v != "2.32.3" {
+		t.Errorf("got %s, want 2.32.3", v)
+	}
+
+	// Second lookup is served from cache
+	if _, err := r.Latest("requests", "python"); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 1 {
+		t.Errorf("expected 1 registry call, got %d", *calls)
+	}
+}
+
+func TestVersionResolverNpmScoped(t *testing.T) {
+	r, _ := newTestResolver(t, false, func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.EscapedPath() != "/npm/@anthropic-ai%2Fsdk/latest" {
+			t.Errorf("unexpected path %s", req.URL.EscapedPath())
+		}
+		_, _ = w.Write([]byte(`{"version": "0.30.1"}`))
+	})
+
+	v, err := r.Latest("@anthropic-ai/sdk/lib/foo", "javascript")
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if v != "0.30.1" {
+		t.Errorf("got %s, want 0.30.1", v)
+	}
+}
+
+func TestVersionResolverOffline(t *testing.T) {
+	r, calls := newTestResolver(t, true, func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"info": {"version": "1.0.0"}}`))
+	})
+
+	if _, err := r.Latest("requests", "python"); err == nil {
+		t.Error("expected error for uncached package in offline mode")
+	}
+
+	// Stale cache entries are still used offline
+	r.cache["python:flask"] = cachedVersion{Version: "3.0.0", FetchedAt: time.Now().Add(-48 * time.Hour)}
+	v, err := r.Latest("flask", "python")
+	if err != nil || v != "3.0.0" {
+		t.Errorf("got %q, %v; want 3.0.0", v, err)
+	}
+	if *calls != 0 {
+		t.Errorf("expected no registry calls offline, got %d", *calls)
+	}
+}
+
+func TestVersionResolverCachePersists(t *testing.T) {
+	r, _ := newTestResolver(t, false, func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"version": "4.17.21"}`))
+	})
+
+	if _, err := r.Latest("lodash", "javascript"); err != nil {
+		t.Fatal(err)
+	}
+	r.SaveCache()
+
+	r2 := &VersionResolver{cachePath: r.cachePath, offline: true, cache: make(map[string]cachedVersion)}
+	r2.loadCache()
+	v, err := r2.Latest("lodash", "javascript")
+	if err != nil || v != "4.17.21" {
+		t.Errorf("got %q, %v; want 4.17.21 from cache", v, err)
+	}
+}
+
+func TestVersionResolverUnsupportedLanguage(t *testing.T) {
+	r := NewVersionResolver(true)
+	if _, err := r.Latest("serde", "rust"); err == nil {
+		t.Error("expected error for unsupported language")
+	}
+}
+
+func TestSuggestConstraint(t *testing.T) {
+	tests := []struct {
+		version  string
+		language string
+		want     string
+	}{
+		{"2.32.3", "python", ">=2.32.3,<3.0.0"},
+		{"0.4.1", "python", ">=0.4.1,<0.5.0"},
+		{"2024.1", "python", ">=2024.1,<2025.0.0"},
+		{"4.17.21", "javascript", "^4.17.21"},
+	}
+
+	for _, tt := range tests {
+		if got := SuggestConstraint(tt.version, tt.language); got != tt.want {
+			t.Errorf("SuggestConstraint(%q, %q) = %q, want %q", tt.version, tt.language, got, tt.want)
+		}
+	}
+}
+
+func TestVersionResolverLicense(t *testing.T) {
+	r, calls := newTestResolver(t, false, func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/pypi/requests/json":
+			_, _ = w.Write([]byte(`{"info": {"version": "2.32.3", "license": "Apache-2.0"}}`))
+		case "/npm/lodash/latest":
+			_, _ = w.Write([]byte(`{"version": "4.17.21", "license": "MIT"}`))
+		default:
+			http.NotFound(w, req)
+		}
+	})
+
+	lic, err := r.License("requests", "python")
+	if err != nil || lic != "Apache-2.0" {
+		t.Errorf("License(requests) = %q, %v", lic, err)
+	}
+	lic, err = r.License("lodash", "javascript")
+	if err != nil || lic != "MIT" {
+		t.Errorf("License(lodash) = %q, %v", lic, err)
+	}
+
+	// Version and license share one cached lookup
+	if _, err := r.Latest("requests", "python"); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 2 {
+		t.Errorf("expected 2 registry calls, got %d", *calls)
+	}
+}
@@ -0,0 +1,157 @@
+// Package depimport reads dependencies from other ecosystems' manifests
+// (Cargo.toml, go.mod) so they can be added to aigogo.json, and the project
+// metadata aigg init starts from (see DetectProject).
+package depimport
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// ParsedDependencies holds the dependencies read from a foreign manifest
+type ParsedDependencies struct {
+	Runtime         []manifest.Dependency
+	Dev             []manifest.Dependency
+	LanguageVersion string   // rust-version or go directive, if present
+	Warnings        []string // dependencies that were skipped
+}
+
+// cargoManifest is the subset of Cargo.toml needed to import dependencies
+type cargoManifest struct {
+	Package struct {
+		RustVersion string `toml:"rust-version"`
+	} `toml:"package"`
+	Dependencies    map[string]interface{} `toml:"dependencies"`
+	DevDependencies map[string]interface{} `toml:"dev-dependencies"`
+	Target          map[string]struct {
+		Dependencies    map[string]interface{} `toml:"dependencies"`
+		DevDependencies map[string]interface{} `toml:"dev-dependencies"`
+	} `toml:"target"`
+	Workspace struct {
+		Dependencies map[string]interface{} `toml:"dependencies"`
+	} `toml:"workspace"`
+}
+
+// FindCargoToml looks for Cargo.toml in dir
+func FindCargoToml(dir string) (string, error) {
+	path := filepath.Join(dir, "Cargo.toml")
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("Cargo.toml not found in %s", dir)
+	}
+	return path, nil
+}
+
+// ParseCargoToml reads [dependencies] and [dev-dependencies] from a
+// Cargo.toml, including platform-specific [target.*] tables. Renamed
+// dependencies are recorded under their crate name; path and git
+// dependencies are skipped since they have no registry version.
+func ParseCargoToml(path string) (*ParsedDependencies, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Cargo.toml: %w", err)
+	}
+
+	var cargo cargoManifest
+	if err := toml.Unmarshal(data, &cargo); err != nil {
+		return nil, fmt.Errorf("failed to parse Cargo.toml: %w", err)
+	}
+
+	result := &ParsedDependencies{
+		Runtime:         []manifest.Dependency{},
+		Dev:             []manifest.Dependency{},
+		LanguageVersion: cargo.Package.RustVersion,
+	}
+
+	runtime := []map[string]interface{}{cargo.Dependencies}
+	dev := []map[string]interface{}{cargo.DevDependencies}
+	targets := make([]string, 0, len(cargo.Target))
+	for name := range cargo.Target {
+		targets = append(targets, name)
+	}
+	sort.Strings(targets)
+	for _, name := range targets {
+		runtime = append(runtime, cargo.Target[name].Dependencies)
+		dev = append(dev, cargo.Target[name].DevDependencies)
+	}
+
+	seen := make(map[string]bool)
+	for _, table := range runtime {
+		result.Runtime = appendCargoDeps(result, result.Runtime, table, cargo.Workspace.Dependencies, seen)
+	}
+	seen = make(map[string]bool)
+	for _, table := range dev {
+		result.Dev = appendCargoDeps(result, result.Dev, table, cargo.Workspace.Dependencies, seen)
+	}
+
+	return result, nil
+}
+
+// appendCargoDeps converts one dependency table, in name order
+func appendCargoDeps(result *ParsedDependencies, deps []manifest.Dependency, table, workspace map[string]interface{}, seen map[string]bool) []manifest.Dependency {
+	names := make([]string, 0, len(table))
+	for name := range table {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		dep, warning := parseCargoDependency(name, table[name], workspace)
+		if warning != "" {
+			result.Warnings = append(result.Warnings, warning)
+			continue
+		}
+		if seen[dep.Package] {
+			continue
+		}
+		seen[dep.Package] = true
+		deps = append(deps, dep)
+	}
+	return deps
+}
+
+// parseCargoDependency converts a Cargo dependency entry, which is either a
+// version string or a table ({ version = "1", package = "real-name", ... })
+func parseCargoDependency(name string, value interface{}, workspace map[string]interface{}) (manifest.Dependency, string) {
+	dep := manifest.Dependency{Package: name}
+
+	switch v := value.(type) {
+	case string:
+		dep.Version = v
+		return dep, ""
+	case map[string]interface{}:
+		if pkg, ok := v["package"].(string); ok && pkg != "" {
+			dep.Package = pkg
+		}
+		if optional, ok := v["optional"].(bool); ok {
+			dep.Optional = optional
+		}
+		if inherit, ok := v["workspace"].(bool); ok && inherit {
+			wsDep, ok := workspace[name]
+			if !ok {
+				return dep, fmt.Sprintf("%s: inherits from [workspace.dependencies] but the workspace does not declare it", name)
+			}
+			inherited, warning := parseCargoDependency(name, wsDep, nil)
+			if warning != "" {
+				return dep, warning
+			}
+			inherited.Optional = dep.Optional
+			return inherited, ""
+		}
+		if version, ok := v["version"].(string); ok && version != "" {
+			dep.Version = version
+			return dep, ""
+		}
+		if _, ok := v["path"]; ok {
+			return dep, fmt.Sprintf("%s: skipped path dependency", name)
+		}
+		if _, ok := v["git"]; ok {
+			return dep, fmt.Sprintf("%s: skipped git dependency", name)
+		}
+	}
+	return dep, fmt.Sprintf("%s: no version found", name)
+}
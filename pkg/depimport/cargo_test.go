@@ -0,0 +1,98 @@
+package depimport
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCargoToml(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Cargo.toml")
+	content := `[package]
+name = "agent"
+version = "0.1.0"
+rust-version = "1.70"
+
+[dependencies]
+serde = { version = "1.0", features = ["derive"] }
+anyhow = "1"
+json = { package = "serde_json", version = "1.0.108" }
+tokio = { workspace = true, optional = true }
+local-helper = { path = "../helper" }
+forked = { git = "https://github.com/example/forked" }
+
+[dev-dependencies]
+pretty_assertions = "1.4"
+
+[target.'cfg(windows)'.dependencies]
+winapi = "0.3"
+
+[workspace.dependencies]
+tokio = { version = "1.35", features = ["full"] }
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	deps, err := ParseCargoToml(path)
+	if err != nil {
+		t.Fatalf("ParseCargoToml failed: %v", err)
+	}
+
+	if deps.LanguageVersion != "1.70" {
+		t.Errorf("LanguageVersion = %q, want 1.70", deps.LanguageVersion)
+	}
+
+	want := map[string]string{
+		"anyhow":     "1",
+		"serde_json": "1.0.108",
+		"serde":      "1.0",
+		"tokio":      "1.35",
+		"winapi":     "0.3",
+	}
+	if len(deps.Runtime) != len(want) {
+		t.Fatalf("expected %d runtime deps, got %+v", len(want), deps.Runtime)
+	}
+	for _, dep := range deps.Runtime {
+		if want[dep.Package] != dep.Version {
+			t.Errorf("%s: version = %q, want %q", dep.Package, dep.Version, want[dep.Package])
+		}
+		if dep.Package == "tokio" && !dep.Optional {
+			t.Error("tokio should keep optional = true from the dependency entry")
+		}
+	}
+
+	if len(deps.Dev) != 1 || deps.Dev[0].Package != "pretty_assertions" || deps.Dev[0].Version != "1.4" {
+		t.Errorf("unexpected dev deps: %+v", deps.Dev)
+	}
+
+	warnings := strings.Join(deps.Warnings, "\n")
+	if !strings.Contains(warnings, "local-helper: skipped path dependency") || !strings.Contains(warnings, "forked: skipped git dependency") {
+		t.Errorf("unexpected warnings:\n%s", warnings)
+	}
+}
+
+func TestParseCargoTomlInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Cargo.toml")
+	if err := os.WriteFile(path, []byte("[dependencies\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseCargoToml(path); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestFindCargoToml(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := FindCargoToml(dir); err == nil {
+		t.Error("expected error when Cargo.toml is missing")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Cargo.toml"), []byte(""), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FindCargoToml(dir); err != nil {
+		t.Errorf("FindCargoToml failed: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package depimport
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// FindGoMod looks for go.mod in dir
+func FindGoMod(dir string) (string, error) {
+	path := filepath.Join(dir, "go.mod")
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("go.mod not found in %s", dir)
+	}
+	return path, nil
+}
+
+// ParseGoMod reads the require directives of a go.mod, both single-line and
+// block form. Indirect requirements are skipped since they are not imported
+// directly, and the go directive is returned as the language version. Go
+// modules have no separate development dependencies.
+func ParseGoMod(path string) (*ParsedDependencies, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read go.mod: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	result := &ParsedDependencies{
+		Runtime: []manifest.Dependency{},
+		Dev:     []manifest.Dependency{},
+	}
+
+	scanner := bufio.NewScanner(file)
+	inRequire := false
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		indirect := strings.HasSuffix(line, "// indirect")
+		if idx := strings.Index(line, "//"); idx != -1 {
+			line = strings.TrimSpace(line[:idx])
+		}
+		if line == "" {
+			continue
+		}
+
+		if inRequire {
+			if line == ")" {
+				inRequire = false
+				continue
+			}
+		} else {
+			fields := strings.Fields(line)
+			switch {
+			case fields[0] == "go" && len(fields) == 2:
+				result.LanguageVersion = fields[1]
+				continue
+			case line == "require (":
+				inRequire = true
+				continue
+			case fields[0] == "require":
+				line = strings.TrimSpace(strings.TrimPrefix(line, "require"))
+			default:
+				continue
+			}
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("go.mod:%d: malformed require %q", lineNum, line)
+		}
+		if indirect {
+			continue
+		}
+		result.Runtime = append(result.Runtime, manifest.Dependency{
+			Package: strings.Trim(fields[0], `"`),
+			Version: fields[1],
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read go.mod: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,65 @@
+package depimport
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseGoMod(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "go.mod")
+	content := `module example.com/agent
+
+go 1.22
+
+require github.com/pkg/errors v0.9.1
+
+require (
+	// HTTP router
+	github.com/go-chi/chi/v5 v5.0.12
+	golang.org/x/sync v0.6.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+replace github.com/pkg/errors => ../errors
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	deps, err := ParseGoMod(path)
+	if err != nil {
+		t.Fatalf("ParseGoMod failed: %v", err)
+	}
+
+	if deps.LanguageVersion != "1.22" {
+		t.Errorf("LanguageVersion = %q, want 1.22", deps.LanguageVersion)
+	}
+
+	want := []struct{ pkg, version string }{
+		{"github.com/pkg/errors", "v0.9.1"},
+		{"github.com/go-chi/chi/v5", "v5.0.12"},
+		{"gopkg.in/yaml.v3", "v3.0.1"},
+	}
+	if len(deps.Runtime) != len(want) {
+		t.Fatalf("expected %d deps, got %+v", len(want), deps.Runtime)
+	}
+	for i, w := range want {
+		if deps.Runtime[i].Package != w.pkg || deps.Runtime[i].Version != w.version {
+			t.Errorf("dep %d = %+v, want %s %s", i, deps.Runtime[i], w.pkg, w.version)
+		}
+	}
+	if len(deps.Dev) != 0 {
+		t.Errorf("expected no dev deps, got %+v", deps.Dev)
+	}
+}
+
+func TestParseGoModMalformedRequire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "go.mod")
+	if err := os.WriteFile(path, []byte("module x\n\nrequire (\n\tgithub.com/pkg/errors\n)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseGoMod(path); err == nil {
+		t.Error("expected error for require without a version")
+	}
+}
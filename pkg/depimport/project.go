@@ -0,0 +1,365 @@
+package depimport
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/pyproject"
+)
+
+// Project is what an existing project manifest says about the project, for
+// aigg init to start from
+type Project struct {
+	File            string // the manifest it was read from, e.g. "pyproject.toml"
+	Name            string
+	Version         string
+	Description     string
+	Author          string
+	License         string
+	Repository      string
+	Homepage        string
+	Keywords        []string
+	Language        string // aigogo language name
+	LanguageVersion string
+	Runtime         []manifest.Dependency
+	Dev             []manifest.Dependency
+	Warnings        []string // dependencies that were skipped
+}
+
+// projectDetectors are tried in order; the first manifest found in the
+// directory is used
+var projectDetectors = []struct {
+	file   string
+	detect func(path string) (*Project, error)
+}{
+	{"pyproject.toml", detectPyProject},
+	{"package.json", detectPackageJSON},
+	{"Cargo.toml", detectCargo},
+	{"go.mod", detectGoMod},
+}
+
+// DetectProject reads the project metadata of dir from its pyproject.toml,
+// package.json, Cargo.toml or go.mod, whichever is found first. It returns
+// nil when there is none. A pyproject.toml with neither a [project] nor a
+// [tool.poetry] table (e.g. one only configuring tools) is passed over.
+func DetectProject(dir string) (*Project, error) {
+	for _, d := range projectDetectors {
+		path := filepath.Join(dir, d.file)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		project, err := d.detect(path)
+		if err != nil {
+			return nil, err
+		}
+		if project != nil {
+			project.File = d.file
+			return project, nil
+		}
+	}
+	return nil, nil
+}
+
+func detectPyProject(path string) (*Project, error) {
+	parsed, err := pyproject.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	var p *Project
+	switch {
+	case parsed.Project != nil:
+		section := parsed.Project
+		p = &Project{
+			Name:        section.Name,
+			Version:     section.Version,
+			Description: section.Description,
+			Keywords:    section.Keywords,
+		}
+		var authors []string
+		for _, a := range section.Authors {
+			authors = append(authors, personString(a.Name, a.Email))
+		}
+		p.Author = strings.Join(authors, ", ")
+		switch license := section.License.(type) {
+		case string:
+			p.License = license
+		case map[string]interface{}:
+			p.License, _ = license["text"].(string)
+		}
+		p.Repository, p.Homepage = projectURLs(section.URLs)
+	case parsed.Tool != nil && parsed.Tool.Poetry != nil:
+		section := parsed.Tool.Poetry
+		p = &Project{
+			Name:        section.Name,
+			Version:     section.Version,
+			Description: section.Description,
+			Author:      strings.Join(section.Authors, ", "),
+			License:     section.License,
+			Repository:  section.Repository,
+			Homepage:    section.Homepage,
+			Keywords:    section.Keywords,
+		}
+	default:
+		return nil, nil
+	}
+
+	deps, err := pyproject.ExtractDependencies(parsed)
+	if err != nil {
+		return nil, err
+	}
+	p.Language = "python"
+	p.LanguageVersion = deps.PythonVersion
+	p.Runtime, p.Dev = deps.Runtime, deps.Dev
+	if deps.Format == "poetry" {
+		p.Runtime, p.Dev = sortDependencies(p.Runtime), sortDependencies(p.Dev)
+	}
+	return p, nil
+}
+
+// projectURLs picks the repository and homepage from [project.urls], whose
+// keys are free-form labels
+func projectURLs(urls map[string]string) (repository, homepage string) {
+	for label, url := range urls {
+		switch strings.ToLower(strings.NewReplacer("-", "", "_", "", " ", "").Replace(label)) {
+		case "repository", "source", "sourcecode", "code":
+			repository = url
+		case "homepage", "home":
+			homepage = url
+		}
+	}
+	return repository, homepage
+}
+
+// packageJSON is the subset of package.json init reads
+type packageJSON struct {
+	Name        string            `json:"name"`
+	Version     string            `json:"version"`
+	Description string            `json:"description"`
+	Author      interface{}       `json:"author"` // "Name <email>" or {"name", "email"}
+	License     interface{}       `json:"license"`
+	Repository  interface{}       `json:"repository"` // URL or {"type", "url"}
+	Homepage    string            `json:"homepage"`
+	Keywords    []string          `json:"keywords"`
+	Engines     map[string]string `json:"engines"`
+	Deps        map[string]string `json:"dependencies"`
+	DevDeps     map[string]string `json:"devDependencies"`
+}
+
+func detectPackageJSON(path string) (*Project, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package.json: %w", err)
+	}
+	var pkg packageJSON
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	p := &Project{
+		Name:            pkg.Name,
+		Version:         pkg.Version,
+		Description:     pkg.Description,
+		Homepage:        pkg.Homepage,
+		Keywords:        pkg.Keywords,
+		Language:        "javascript",
+		LanguageVersion: pkg.Engines["node"],
+	}
+	// A scoped name's scope is a registry namespace, not part of the name
+	if i := strings.Index(p.Name, "/"); strings.HasPrefix(p.Name, "@") && i > 0 {
+		p.Name = p.Name[i+1:]
+	}
+	switch author := pkg.Author.(type) {
+	case string:
+		p.Author = author
+	case map[string]interface{}:
+		name, _ := author["name"].(string)
+		email, _ := author["email"].(string)
+		p.Author = personString(name, email)
+	}
+	p.License, _ = pkg.License.(string)
+	switch repo := pkg.Repository.(type) {
+	case string:
+		p.Repository = repo
+	case map[string]interface{}:
+		p.Repository, _ = repo["url"].(string)
+	}
+
+	p.Runtime = npmDependencies(p, pkg.Deps)
+	p.Dev = npmDependencies(p, pkg.DevDeps)
+	return p, nil
+}
+
+// npmDependencies converts a package.json dependency map, in name order.
+// Dependencies that don't come from the registry are skipped with a warning.
+func npmDependencies(p *Project, deps map[string]string) []manifest.Dependency {
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := []manifest.Dependency{}
+	for _, name := range names {
+		version := deps[name]
+		if kind := npmNonRegistrySpec(version); kind != "" {
+			p.Warnings = append(p.Warnings, fmt.Sprintf("%s: skipped %s dependency", name, kind))
+			continue
+		}
+		result = append(result, manifest.Dependency{Package: name, Version: version})
+	}
+	return result
+}
+
+// npmNonRegistrySpec returns the kind of a package.json version that isn't
+// a registry range (local path, git, URL, workspace or alias), or ""
+func npmNonRegistrySpec(version string) string {
+	switch {
+	case strings.HasPrefix(version, "file:"), strings.HasPrefix(version, "link:"),
+		strings.HasPrefix(version, "./"), strings.HasPrefix(version, "../"):
+		return "path"
+	case strings.HasPrefix(version, "workspace:"):
+		return "workspace"
+	case strings.HasPrefix(version, "npm:"):
+		return "alias"
+	case strings.HasPrefix(version, "git"), strings.Contains(version, "://"), strings.Contains(version, "/"):
+		return "git"
+	}
+	return ""
+}
+
+// cargoPackage is the [package] table of a Cargo.toml. Fields may be
+// inherited from the workspace with { workspace = true }, so they are
+// decoded loosely and only plain values are used.
+type cargoPackage struct {
+	Package struct {
+		Name        string      `toml:"name"`
+		Version     interface{} `toml:"version"`
+		Description interface{} `toml:"description"`
+		Authors     interface{} `toml:"authors"`
+		License     interface{} `toml:"license"`
+		Repository  interface{} `toml:"repository"`
+		Homepage    interface{} `toml:"homepage"`
+		Keywords    interface{} `toml:"keywords"`
+	} `toml:"package"`
+}
+
+func detectCargo(path string) (*Project, error) {
+	var cargo cargoPackage
+	if _, err := toml.DecodeFile(path, &cargo); err != nil {
+		return nil, fmt.Errorf("failed to parse Cargo.toml: %w", err)
+	}
+	deps, err := ParseCargoToml(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := cargo.Package
+	p := &Project{
+		Name:            pkg.Name,
+		Language:        "rust",
+		LanguageVersion: deps.LanguageVersion,
+		Runtime:         deps.Runtime,
+		Dev:             deps.Dev,
+		Warnings:        deps.Warnings,
+	}
+	p.Version, _ = pkg.Version.(string)
+	p.Description, _ = pkg.Description.(string)
+	p.Author = strings.Join(stringList(pkg.Authors), ", ")
+	p.License, _ = pkg.License.(string)
+	p.Repository, _ = pkg.Repository.(string)
+	p.Homepage, _ = pkg.Homepage.(string)
+	p.Keywords = stringList(pkg.Keywords)
+	return p, nil
+}
+
+// majorVersionSuffix matches the /vN element that ends the path of a Go
+// module at major version 2 or later
+var majorVersionSuffix = regexp.MustCompile(`/v[0-9]+$`)
+
+func detectGoMod(path string) (*Project, error) {
+	deps, err := ParseGoMod(path)
+	if err != nil {
+		return nil, err
+	}
+	module, err := goModulePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	module = majorVersionSuffix.ReplaceAllString(module, "")
+	p := &Project{
+		Name:            module[strings.LastIndex(module, "/")+1:],
+		Language:        "go",
+		LanguageVersion: deps.LanguageVersion,
+		Runtime:         deps.Runtime,
+		Dev:             deps.Dev,
+	}
+	// Modules hosted on a forge are named after their repository
+	if strings.HasPrefix(module, "github.com/") || strings.HasPrefix(module, "gitlab.com/") {
+		p.Repository = "https://" + module
+	}
+	return p, nil
+}
+
+// goModulePath returns the module path declared by a go.mod
+func goModulePath(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read go.mod: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read go.mod: %w", err)
+	}
+	return "", fmt.Errorf("go.mod has no module directive")
+}
+
+// personString formats a name and email as "Name <email>"
+func personString(name, email string) string {
+	switch {
+	case email == "":
+		return name
+	case name == "":
+		return "<" + email + ">"
+	}
+	return name + " <" + email + ">"
+}
+
+// stringList returns v as a list of strings when it is a TOML array of
+// strings
+func stringList(v interface{}) []string {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	var list []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// sortDependencies orders dependencies by package name. Poetry tables are
+// maps, so their order would otherwise change from run to run.
+func sortDependencies(deps []manifest.Dependency) []manifest.Dependency {
+	sort.SliceStable(deps, func(i, j int) bool { return deps[i].Package < deps[j].Package })
+	return deps
+}
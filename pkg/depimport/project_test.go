@@ -0,0 +1,185 @@
+package depimport
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func writeProjectFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDetectProjectPEP621(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "pyproject.toml", `[project]
+name = "weather-agent"
+version = "1.2.0"
+description = "Weather tools"
+authors = [{name = "Ada", email = "ada@example.com"}, {name = "Grace"}]
+license = "Apache-2.0"
+keywords = ["weather", "tools"]
+requires-python = ">=3.10"
+dependencies = ["requests>=2.31"]
+
+[project.optional-dependencies]
+dev = ["pytest>=8"]
+
+[project.urls]
+Homepage = "https://weather.example.com"
+"Source Code" = "https://github.com/ada/weather"
+`)
+	// pyproject.toml is preferred when several manifests exist
+	writeProjectFile(t, dir, "package.json", `{"name": "other"}`)
+
+	p, err := DetectProject(dir)
+	if err != nil {
+		t.Fatalf("DetectProject failed: %v", err)
+	}
+	want := &Project{
+		File:            "pyproject.toml",
+		Name:            "weather-agent",
+		Version:         "1.2.0",
+		Description:     "Weather tools",
+		Author:          "Ada <ada@example.com>, Grace",
+		License:         "Apache-2.0",
+		Repository:      "https://github.com/ada/weather",
+		Homepage:        "https://weather.example.com",
+		Keywords:        []string{"weather", "tools"},
+		Language:        "python",
+		LanguageVersion: ">=3.10",
+		Runtime:         []manifest.Dependency{{Package: "requests", Version: ">=2.31"}},
+		Dev:             []manifest.Dependency{{Package: "pytest", Version: ">=8"}},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("DetectProject =\n%+v\nwant\n%+v", p, want)
+	}
+}
+
+func TestDetectProjectPoetry(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "pyproject.toml", `[tool.poetry]
+name = "agent"
+version = "0.3.0"
+authors = ["Ada <ada@example.com>"]
+license = "MIT"
+
+[tool.poetry.dependencies]
+python = "^3.9"
+requests = "^2.31"
+click = "^8.0"
+`)
+
+	p, err := DetectProject(dir)
+	if err != nil {
+		t.Fatalf("DetectProject failed: %v", err)
+	}
+	if p.Name != "agent" || p.Author != "Ada <ada@example.com>" || p.License != "MIT" || p.Language != "python" || p.LanguageVersion == "" {
+		t.Errorf("DetectProject = %+v", p)
+	}
+	if len(p.Runtime) != 2 || p.Runtime[0].Package != "click" || p.Runtime[1].Package != "requests" {
+		t.Errorf("Runtime = %v, want click and requests in name order", p.Runtime)
+	}
+}
+
+func TestDetectProjectSkipsToolOnlyPyProject(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "pyproject.toml", "[tool.black]\nline-length = 100\n")
+	writeProjectFile(t, dir, "go.mod", "module github.com/ada/agent/v2\n\ngo 1.22\n\nrequire github.com/spf13/cobra v1.8.0\n")
+
+	p, err := DetectProject(dir)
+	if err != nil {
+		t.Fatalf("DetectProject failed: %v", err)
+	}
+	want := &Project{
+		File:            "go.mod",
+		Name:            "agent",
+		Repository:      "https://github.com/ada/agent",
+		Language:        "go",
+		LanguageVersion: "1.22",
+		Runtime:         []manifest.Dependency{{Package: "github.com/spf13/cobra", Version: "v1.8.0"}},
+		Dev:             []manifest.Dependency{},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("DetectProject =\n%+v\nwant\n%+v", p, want)
+	}
+}
+
+func TestDetectProjectPackageJSON(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "package.json", `{
+  "name": "@acme/chat-tools",
+  "version": "2.0.1",
+  "description": "Chat helpers",
+  "author": {"name": "Ada", "email": "ada@example.com"},
+  "license": "MIT",
+  "repository": {"type": "git", "url": "git+https://github.com/acme/chat-tools.git"},
+  "engines": {"node": ">=20"},
+  "dependencies": {"zod": "^3.22.0", "local-lib": "file:../lib", "forked": "github:acme/forked"},
+  "devDependencies": {"vitest": "^1.0.0"}
+}`)
+
+	p, err := DetectProject(dir)
+	if err != nil {
+		t.Fatalf("DetectProject failed: %v", err)
+	}
+	want := &Project{
+		File:            "package.json",
+		Name:            "chat-tools",
+		Version:         "2.0.1",
+		Description:     "Chat helpers",
+		Author:          "Ada <ada@example.com>",
+		License:         "MIT",
+		Repository:      "git+https://github.com/acme/chat-tools.git",
+		Language:        "javascript",
+		LanguageVersion: ">=20",
+		Runtime:         []manifest.Dependency{{Package: "zod", Version: "^3.22.0"}},
+		Dev:             []manifest.Dependency{{Package: "vitest", Version: "^1.0.0"}},
+		Warnings:        []string{"forked: skipped git dependency", "local-lib: skipped path dependency"},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("DetectProject =\n%+v\nwant\n%+v", p, want)
+	}
+}
+
+func TestDetectProjectCargo(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "Cargo.toml", `[package]
+name = "agent"
+version = "0.1.0"
+description = "Rust agent"
+authors = ["Ada <ada@example.com>"]
+license.workspace = true
+rust-version = "1.74"
+
+[dependencies]
+serde = "1.0"
+`)
+
+	p, err := DetectProject(dir)
+	if err != nil {
+		t.Fatalf("DetectProject failed: %v", err)
+	}
+	if p.File != "Cargo.toml" || p.Name != "agent" || p.Description != "Rust agent" || p.Author != "Ada <ada@example.com>" {
+		t.Errorf("DetectProject = %+v", p)
+	}
+	if p.License != "" {
+		t.Errorf("License = %q, want a workspace-inherited license left out", p.License)
+	}
+	if p.Language != "rust" || p.LanguageVersion != "1.74" || len(p.Runtime) != 1 {
+		t.Errorf("DetectProject = %+v", p)
+	}
+}
+
+func TestDetectProjectNone(t *testing.T) {
+	p, err := DetectProject(t.TempDir())
+	if err != nil || p != nil {
+		t.Errorf("DetectProject on an empty directory = %+v, %v; want nil", p, err)
+	}
+}
@@ -57,6 +57,11 @@ func (b *Builder) BuildImage(imageRef string, files []string, manifest interface
 	if err != nil {
 		return err
 	}
+	fl, err := lockCache(cache)
+	if err != nil {
+		return err
+	}
+	defer fl.Release()
 
 	imagePath := filepath.Join(cache, "images", sanitizeImageRef(imageRef))
 	if err := os.MkdirAll(imagePath, 0755); err != nil {
@@ -123,6 +128,11 @@ func (b *Builder) BuildImageFromPath(imageRef string, basePath string, files []s
 	if err != nil {
 		return err
 	}
+	fl, err := lockCache(cache)
+	if err != nil {
+		return err
+	}
+	defer fl.Release()
 
 	imagePath := filepath.Join(cache, "images", sanitizeImageRef(imageRef))
 	if err := os.MkdirAll(imagePath, 0755); err != nil {
@@ -198,7 +208,7 @@ func addFileToTarFromPath(tw *tar.Writer, fullPath string, nameInArchive string)
 	header := &tar.Header{
 		Name:    nameInArchive,
 		Size:    stat.Size(),
-		Mode:    int64(stat.Mode()),
+		Mode:    tarMode(stat.Mode()),
 		ModTime: stat.ModTime(),
 	}
 
@@ -210,6 +220,16 @@ func addFileToTarFromPath(tw *tar.Writer, fullPath string, nameInArchive string)
 	return err
 }
 
+// tarMode normalizes a file's permissions to 0755 for executables and 0644
+// otherwise, so the layer records whether a file is executable and nothing
+// about the builder's umask
+func tarMode(mode os.FileMode) int64 {
+	if mode&0111 != 0 {
+		return 0755
+	}
+	return 0644
+}
+
 func addToTar(tw *tar.Writer, name string, data []byte, size int64) error {
 	header := &tar.Header{
 		Name:    name,
@@ -0,0 +1,285 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// OCI image layout files, see
+// https://github.com/opencontainers/image-spec/blob/main/image-layout.md
+const (
+	OCILayoutFile = "oci-layout"
+	OCIIndexFile  = "index.json"
+
+	// AnnotationRefName names a manifest in an OCI layout's index, as
+	// skopeo's oci:<dir>:<tag> and oras' --oci-layout <dir>:<tag> do
+	AnnotationRefName = "org.opencontainers.image.ref.name"
+)
+
+// ociIndex is an OCI layout's index.json
+type ociIndex struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Manifests     []ociDescriptor `json:"manifests"`
+}
+
+// ociDescriptor points to a blob of an OCI layout
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// BuildLayer returns a package layer, as aigg push uploads it: layerManifest
+// as .aigogo-manifest.json followed by files, slash-separated paths
+// relative to baseDir
+func BuildLayer(baseDir string, files []string, layerManifest interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	manifestData, err := json.Marshal(layerManifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := addToTar(tw, ".aigogo-manifest.json", manifestData, int64(len(manifestData))); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if err := addFileToTarFromPath(tw, filepath.Join(baseDir, filepath.FromSlash(file)), file); err != nil {
+			return nil, fmt.Errorf("failed to add file %s: %w", file, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close tar writer: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteOCILayout writes layer as an OCI image layout in dir, with the same
+// config and manifest aigg push uploads, so tools such as skopeo and oras
+// can copy it to a registry aigg pull then reads it from. tag names the
+// manifest in the layout's index. When dir already is a layout, the
+// manifest is added to it, replacing one with the same tag; the replaced
+// manifest's blobs are left in place. It returns the manifest's digest,
+// which is the digest aigg push gives the same layer.
+func WriteOCILayout(dir, tag string, layer []byte, annotations map[string]string) (string, error) {
+	index := ociIndex{
+		SchemaVersion: 2,
+		MediaType:     "application/vnd.oci.image.index.v1+json",
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, OCIIndexFile)); err == nil {
+		if err := json.Unmarshal(data, &index); err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", OCIIndexFile, err)
+		}
+	}
+
+	blobsDir := filepath.Join(dir, "blobs", "sha256")
+	if err := os.MkdirAll(blobsDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", blobsDir, err)
+	}
+	writeBlob := func(data []byte) (string, error) {
+		digest := calculateDigest(data)
+		blobPath := filepath.Join(blobsDir, strings.TrimPrefix(digest, "sha256:"))
+		if err := os.WriteFile(blobPath, data, 0644); err != nil {
+			return "", fmt.Errorf("failed to write blob: %w", err)
+		}
+		return digest, nil
+	}
+
+	configDigest, err := writeBlob([]byte("{}"))
+	if err != nil {
+		return "", err
+	}
+	layerDigest, err := writeBlob(layer)
+	if err != nil {
+		return "", err
+	}
+	manifestData, err := json.Marshal(createManifest(configDigest, layerDigest, int64(len(layer)), annotations))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	manifestDigest, err := writeBlob(manifestData)
+	if err != nil {
+		return "", err
+	}
+
+	index.Manifests = slices.DeleteFunc(index.Manifests, func(d ociDescriptor) bool {
+		return d.Annotations[AnnotationRefName] == tag
+	})
+	index.Manifests = append(index.Manifests, ociDescriptor{
+		MediaType:   "application/vnd.docker.distribution.manifest.v2+json",
+		Digest:      manifestDigest,
+		Size:        int64(len(manifestData)),
+		Annotations: map[string]string{AnnotationRefName: tag},
+	})
+	indexData, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, OCIIndexFile), indexData, 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", OCIIndexFile, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, OCILayoutFile), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", OCILayoutFile, err)
+	}
+	return manifestDigest, nil
+}
+
+// OCILayoutTags returns the tags of the manifests in the OCI image layout
+// in dir, in index order; manifests without one are left out
+func OCILayoutTags(dir string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(dir, OCILayoutFile)); err != nil {
+		return nil, fmt.Errorf("%s is not an OCI image layout: %w", dir, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, OCIIndexFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", OCIIndexFile, err)
+	}
+	var index ociIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", OCIIndexFile, err)
+	}
+	var tags []string
+	for _, m := range index.Manifests {
+		if tag := m.Annotations[AnnotationRefName]; tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// ReadOCILayout returns the package layer of the OCI image layout in dir
+// and the annotations of its manifest. A layout with several manifests
+// must name one with tag. Every blob read is checked against its digest.
+func ReadOCILayout(dir, tag string) ([]byte, map[string]string, error) {
+	if _, err := os.Stat(filepath.Join(dir, OCILayoutFile)); err != nil {
+		return nil, nil, fmt.Errorf("%s is not an OCI image layout: %w", dir, err)
+	}
+	indexData, err := os.ReadFile(filepath.Join(dir, OCIIndexFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", OCIIndexFile, err)
+	}
+	var index ociIndex
+	if err := json.Unmarshal(indexData, &index); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", OCIIndexFile, err)
+	}
+
+	var desc *ociDescriptor
+	for i, m := range index.Manifests {
+		if tag == "" || m.Annotations[AnnotationRefName] == tag {
+			if desc != nil {
+				return nil, nil, fmt.Errorf("%s holds several images; name one with <dir>:<tag>", dir)
+			}
+			desc = &index.Manifests[i]
+		}
+	}
+	if desc == nil {
+		if tag != "" {
+			return nil, nil, fmt.Errorf("%s has no image tagged %s", dir, tag)
+		}
+		return nil, nil, fmt.Errorf("%s holds no images", dir)
+	}
+
+	manifestData, err := readOCIBlob(dir, desc.Digest)
+	if err != nil {
+		return nil, nil, err
+	}
+	var imageManifest struct {
+		Layers      []ociDescriptor   `json:"layers"`
+		Annotations map[string]string `json:"annotations"`
+	}
+	if err := json.Unmarshal(manifestData, &imageManifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse image manifest: %w", err)
+	}
+	if len(imageManifest.Layers) != 1 {
+		return nil, nil, fmt.Errorf("image has %d layers; an aigogo package has one", len(imageManifest.Layers))
+	}
+	layer, err := readOCIBlob(dir, imageManifest.Layers[0].Digest)
+	if err != nil {
+		return nil, nil, err
+	}
+	return layer, imageManifest.Annotations, nil
+}
+
+// readOCIBlob reads a blob of an OCI layout and checks its digest
+func readOCIBlob(dir, digest string) ([]byte, error) {
+	hex, ok := strings.CutPrefix(digest, "sha256:")
+	if !ok || len(hex) != 64 || strings.ContainsAny(hex, "/\\.") {
+		return nil, fmt.Errorf("unsupported blob digest: %s", digest)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "blobs", "sha256", hex))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: %w", digest, err)
+	}
+	if got := calculateDigest(data); got != digest {
+		return nil, fmt.Errorf("blob %s is corrupt: its digest is %s", digest, got)
+	}
+	return data, nil
+}
+
+// Gunzip returns data decompressed when it is gzip-compressed, and as it is
+// otherwise. Decompressing stops at the extract size limit
+// (SetMaxExtractSize), so a small bundle can't expand without bound.
+func Gunzip(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = zr.Close() }()
+	out, err := io.ReadAll(io.LimitReader(zr, maxExtractSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > maxExtractSize {
+		return nil, extractSizeError("the decompressed layer")
+	}
+	return out, nil
+}
+
+// ExtractLayer writes the regular files of a package layer into dir,
+// leaving out .aigogo-manifest.json, and returns their slash-separated
+// paths. The layer may come from an untrusted bundle, so its entries go
+// through checkLayerEntry, as Extractor.Extract's do, and its files must
+// fit in the extract size limit.
+func ExtractLayer(layer []byte, dir string) ([]string, error) {
+	if int64(len(layer)) > maxExtractSize {
+		return nil, extractSizeError(fmt.Sprintf("the layer (%d bytes)", len(layer)))
+	}
+	tr := tar.NewReader(bytes.NewReader(layer))
+	var files []string
+	remaining := maxExtractSize
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layer: %w", err)
+		}
+
+		name, regular, err := checkLayerEntry(header)
+		if err != nil {
+			return nil, err
+		}
+		if !regular || name == ".aigogo-manifest.json" {
+			continue
+		}
+		if _, err := writeLayerFile(tr, header, dir, name, &remaining); err != nil {
+			return nil, err
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
@@ -1,12 +1,14 @@
 package docker
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 
 	"github.com/aupeachmo/aigogo/pkg/auth"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
 )
 
 // Deleter handles deleting images from registries
@@ -17,32 +19,33 @@ type Deleter struct {
 // NewDeleter creates a new deleter
 func NewDeleter() *Deleter {
 	return &Deleter{
-		client: &http.Client{},
+		client: newRegistryClient(),
 	}
 }
 
-// Delete removes an image from a registry
-func (d *Deleter) Delete(imageRef string) error {
+// Delete removes an image from a registry, and returns the digest of the
+// manifest it deleted
+func (d *Deleter) Delete(ctx context.Context, imageRef string) (string, error) {
 	registry, repository, tag, err := parseImageRef(imageRef)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// Get authentication token
 	authManager := auth.NewManager()
-	token, err := authManager.GetToken(registry, repository)
+	token, err := authManager.GetToken(ctx, registry, repository)
 	if err != nil {
-		return fmt.Errorf("not logged in to %s: %w\nRun 'aigg login %s' first", registry, err, registry)
+		return "", fmt.Errorf("not logged in to %s: %w\nRun 'aigg login %s' first", registry, err, registry)
 	}
 
 	// First, get the manifest digest
 	// We need the digest to delete (can't delete by tag directly)
 	apiEndpoint := getRegistryAPIEndpoint(registry)
-	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", apiEndpoint, repository, tag)
+	manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", registryScheme(registry), apiEndpoint, repository, tag)
 
-	req, err := http.NewRequest("HEAD", manifestURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "HEAD", manifestURL, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	setAuthHeader(req, registry, token)
@@ -51,55 +54,55 @@ func (d *Deleter) Delete(imageRef string) error {
 
 	resp, err := d.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to get manifest: %w", err)
+		return "", fmt.Errorf("failed to get manifest: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode == 401 {
-		return fmt.Errorf("authentication required, run 'aigg login %s'", registry)
+		return "", errcode.Errorf(errcode.Auth, "authentication required, run 'aigg login %s'", registry)
 	}
 
 	if resp.StatusCode == 404 {
-		return fmt.Errorf("image not found: %s", imageRef)
+		return "", errcode.Errorf(errcode.NotFound, "image not found: %s", imageRef)
 	}
 
 	if resp.StatusCode != 200 {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to get manifest: %s (status %d)", string(body), resp.StatusCode)
+		return "", errcode.Errorf(errcode.FromHTTPStatus(resp.StatusCode), "failed to get manifest: %s (status %d)", string(body), resp.StatusCode)
 	}
 
 	// Get the digest from the response header
 	digest := resp.Header.Get("Docker-Content-Digest")
 	if digest == "" {
-		return fmt.Errorf("registry did not return manifest digest (may not support deletion)")
+		return "", fmt.Errorf("registry did not return manifest digest (may not support deletion)")
 	}
 
 	// Now delete using the digest
-	deleteURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", apiEndpoint, repository, digest)
+	deleteURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", registryScheme(registry), apiEndpoint, repository, digest)
 
-	deleteReq, err := http.NewRequest("DELETE", deleteURL, nil)
+	deleteReq, err := http.NewRequestWithContext(ctx, "DELETE", deleteURL, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create delete request: %w", err)
+		return "", fmt.Errorf("failed to create delete request: %w", err)
 	}
 
 	setAuthHeader(deleteReq, registry, token)
 
 	deleteResp, err := d.client.Do(deleteReq)
 	if err != nil {
-		return fmt.Errorf("failed to delete manifest: %w", err)
+		return "", fmt.Errorf("failed to delete manifest: %w", err)
 	}
 	defer func() { _ = deleteResp.Body.Close() }()
 
 	if deleteResp.StatusCode == 401 {
-		return fmt.Errorf("authentication failed (insufficient permissions)")
+		return "", errcode.Errorf(errcode.Auth, "authentication failed (insufficient permissions)")
 	}
 
 	if deleteResp.StatusCode == 404 {
-		return fmt.Errorf("manifest not found (may have been already deleted)")
+		return "", errcode.Errorf(errcode.NotFound, "manifest not found (may have been already deleted)")
 	}
 
 	if deleteResp.StatusCode == 405 {
-		return fmt.Errorf("registry does not support deletion (check registry configuration)")
+		return "", fmt.Errorf("registry does not support deletion (check registry configuration)")
 	}
 
 	if deleteResp.StatusCode != 202 && deleteResp.StatusCode != 200 {
@@ -114,52 +117,57 @@ func (d *Deleter) Delete(imageRef string) error {
 		}
 
 		if json.Unmarshal(body, &errResp) == nil && len(errResp.Errors) > 0 {
-			return fmt.Errorf("registry error: %s - %s (status %d)",
+			return "", errcode.Errorf(errcode.FromHTTPStatus(deleteResp.StatusCode), "registry error: %s - %s (status %d)",
 				errResp.Errors[0].Code, errResp.Errors[0].Message, deleteResp.StatusCode)
 		}
 
-		return fmt.Errorf("failed to delete: %s (status %d)", string(body), deleteResp.StatusCode)
+		return "", errcode.Errorf(errcode.FromHTTPStatus(deleteResp.StatusCode), "failed to delete: %s (status %d)", string(body), deleteResp.StatusCode)
 	}
 
-	return nil
+	return digest, nil
 }
 
 // listTags lists all tags in a repository
-func (d *Deleter) listTags(registry, repository string) ([]string, error) {
+func (d *Deleter) listTags(ctx context.Context, registry, repository string) ([]string, error) {
 	authManager := auth.NewManager()
-	token, err := authManager.GetToken(registry, repository)
+	token, err := authManager.GetToken(ctx, registry, repository)
 	if err != nil {
 		return nil, fmt.Errorf("not logged in to %s: %w", registry, err)
 	}
+	return fetchTags(ctx, d.client, registry, repository, token)
+}
 
+// fetchTags lists the tags of a repository with token, which may be empty
+// for public repositories
+func fetchTags(ctx context.Context, client *http.Client, registry, repository, token string) ([]string, error) {
 	// Docker Registry API: GET /v2/<name>/tags/list
 	apiEndpoint := getRegistryAPIEndpoint(registry)
-	url := fmt.Sprintf("https://%s/v2/%s/tags/list", apiEndpoint, repository)
+	url := fmt.Sprintf("%s://%s/v2/%s/tags/list", registryScheme(registry), apiEndpoint, repository)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	setAuthHeader(req, registry, token)
 
-	resp, err := d.client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tags: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode == 401 {
-		return nil, fmt.Errorf("authentication required, run 'aigg login %s'", registry)
+		return nil, errcode.Errorf(errcode.Auth, "authentication required, run 'aigg login %s'", registry)
 	}
 
 	if resp.StatusCode == 404 {
-		return nil, fmt.Errorf("repository not found: %s/%s", registry, repository)
+		return nil, errcode.Errorf(errcode.NotFound, "repository not found: %s/%s", registry, repository)
 	}
 
 	if resp.StatusCode != 200 {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to list tags: %s (status %d)", string(body), resp.StatusCode)
+		return nil, errcode.Errorf(errcode.FromHTTPStatus(resp.StatusCode), "failed to list tags: %s (status %d)", string(body), resp.StatusCode)
 	}
 
 	var result struct {
@@ -174,16 +182,23 @@ func (d *Deleter) listTags(registry, repository string) ([]string, error) {
 	return result.Tags, nil
 }
 
-// DeleteAll deletes all tags in a repository
-func (d *Deleter) DeleteAll(registry, repository string) error {
+// DeletedImage is an image Deleter.DeleteAll deleted
+type DeletedImage struct {
+	Ref    string
+	Digest string // of its manifest
+}
+
+// DeleteAll deletes all tags in a repository, and returns those it deleted,
+// also when others failed. Canceling ctx stops it before the next tag.
+func (d *Deleter) DeleteAll(ctx context.Context, registry, repository string) ([]DeletedImage, error) {
 	// List all tags
-	tags, err := d.listTags(registry, repository)
+	tags, err := d.listTags(ctx, registry, repository)
 	if err != nil {
-		return fmt.Errorf("failed to list tags: %w", err)
+		return nil, fmt.Errorf("failed to list tags: %w", err)
 	}
 
 	if len(tags) == 0 {
-		return fmt.Errorf("no tags found in repository %s/%s", registry, repository)
+		return nil, fmt.Errorf("no tags found in repository %s/%s", registry, repository)
 	}
 
 	fmt.Printf("Found %d tag(s):\n", len(tags))
@@ -194,19 +209,23 @@ func (d *Deleter) DeleteAll(registry, repository string) error {
 
 	// Delete each tag
 	failed := []string{}
-	succeeded := 0
+	var deleted []DeletedImage
 
 	for _, tag := range tags {
+		if ctx.Err() != nil {
+			fmt.Printf("Stopped after deleting %d out of %d tags\n", len(deleted), len(tags))
+			return deleted, ctx.Err()
+		}
 		fullRef := fmt.Sprintf("%s/%s:%s", registry, repository, tag)
 		fmt.Printf("Deleting %s... ", tag)
 
-		err := d.Delete(fullRef)
+		digest, err := d.Delete(ctx, fullRef)
 		if err != nil {
 			fmt.Printf("✗ Failed: %v\n", err)
 			failed = append(failed, tag)
 		} else {
 			fmt.Printf("✓ Deleted\n")
-			succeeded++
+			deleted = append(deleted, DeletedImage{Ref: fullRef, Digest: digest})
 		}
 	}
 
@@ -214,10 +233,10 @@ func (d *Deleter) DeleteAll(registry, repository string) error {
 
 	if len(failed) > 0 {
 		fmt.Printf("⚠️  Warning: %d tag(s) failed to delete: %v\n", len(failed), failed)
-		fmt.Printf("Successfully deleted %d out of %d tags\n", succeeded, len(tags))
-		return fmt.Errorf("failed to delete %d tag(s)", len(failed))
+		fmt.Printf("Successfully deleted %d out of %d tags\n", len(deleted), len(tags))
+		return deleted, fmt.Errorf("failed to delete %d tag(s)", len(failed))
 	}
 
-	fmt.Printf("✓ Successfully deleted all %d tag(s) from %s/%s\n", succeeded, registry, repository)
-	return nil
+	fmt.Printf("✓ Successfully deleted all %d tag(s) from %s/%s\n", len(deleted), registry, repository)
+	return deleted, nil
 }
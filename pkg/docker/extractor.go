@@ -6,16 +6,154 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
 )
 
+// DefaultMaxExtractSize is the default of SetMaxExtractSize
+const DefaultMaxExtractSize int64 = 512 << 20
+
+// maxExtractSize is the largest package layer downloaded, decompressed or
+// extracted, and the most its files may add up to (SetMaxExtractSize)
+var maxExtractSize = DefaultMaxExtractSize
+
+// SetMaxExtractSize sets the largest package layer aigg downloads,
+// decompresses or extracts, and the most a layer's files may add up to.
+// Layers come from registries and bundles aigg doesn't control, so the limit
+// keeps a hostile or broken one from filling the disk or memory.
+func SetMaxExtractSize(n int64) {
+	maxExtractSize = n
+}
+
+// extractSizeError reports that what is over the extract size limit
+func extractSizeError(what string) error {
+	return errcode.Errorf(errcode.Validation, "%s is larger than the extract size limit (%s)\nRaise it with --max-extract-size if you trust the package", what, formatExtractSize(maxExtractSize))
+}
+
+// formatExtractSize formats a size limit as --max-extract-size takes it
+func formatExtractSize(n int64) string {
+	switch {
+	case n%(1<<30) == 0:
+		return fmt.Sprintf("%dGB", n>>30)
+	case n%(1<<20) == 0:
+		return fmt.Sprintf("%dMB", n>>20)
+	case n%(1<<10) == 0:
+		return fmt.Sprintf("%dKB", n>>10)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// unsafeEntry reports a layer entry that extracting would be unsafe
+func unsafeEntry(name, reason string) error {
+	return errcode.Errorf(errcode.Integrity, "refusing layer entry %q: %s", name, reason)
+}
+
+// entryPath returns the slash-separated path of a layer entry or link
+// target relative to the package root, refusing absolute paths (including
+// Windows drive and UNC paths) and paths that leave the root
+func entryPath(name string) (string, error) {
+	slashed := strings.ReplaceAll(name, "\\", "/")
+	clean := path.Clean(slashed)
+	switch {
+	case strings.ContainsRune(slashed, 0):
+		return "", fmt.Errorf("the path has a NUL byte")
+	case path.IsAbs(clean), len(clean) >= 2 && clean[1] == ':' && isLetter(clean[0]):
+		return "", fmt.Errorf("the path is absolute")
+	case clean == "..", strings.HasPrefix(clean, "../"):
+		return "", fmt.Errorf("the path is outside the package")
+	}
+	return clean, nil
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// checkLayerEntry returns the path relative to the package root of the tar
+// entry header describes, and whether it's a regular file to extract.
+// Paths outside the root, links pointing outside it, device nodes and FIFOs
+// are refused; directories and links that stay inside are skipped, as a
+// package holds regular files only.
+func checkLayerEntry(header *tar.Header) (string, bool, error) {
+	name, err := entryPath(header.Name)
+	if err != nil {
+		return "", false, unsafeEntry(header.Name, err.Error())
+	}
+	switch header.Typeflag {
+	case tar.TypeReg:
+		if name == "." {
+			return "", false, unsafeEntry(header.Name, "a file can't be the package root")
+		}
+		return name, true, nil
+	case tar.TypeSymlink, tar.TypeLink:
+		// A symlink's target is relative to its directory, a hard link's to
+		// the root
+		target := strings.ReplaceAll(header.Linkname, "\\", "/")
+		if header.Typeflag == tar.TypeSymlink && !path.IsAbs(target) {
+			target = path.Join(path.Dir(name), target)
+		}
+		if _, err := entryPath(target); err != nil || header.Linkname == "" {
+			return "", false, unsafeEntry(header.Name, fmt.Sprintf("it links to %q, outside the package", header.Linkname))
+		}
+		return name, false, nil
+	case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+		return "", false, unsafeEntry(header.Name, "device nodes and FIFOs can't be package files")
+	}
+	// Directories, and metadata such as PAX and GNU headers
+	return name, false, nil
+}
+
+// writeLayerFile writes the regular file tr is at to name under dir, with
+// mode 0644, or 0755 when the entry has an execute bit, taking its size
+// from *remaining. Whatever is at name already must be a regular file, so
+// nothing on the way can turn the write elsewhere.
+func writeLayerFile(tr io.Reader, header *tar.Header, dir, name string, remaining *int64) (string, error) {
+	if header.Size > *remaining {
+		return "", extractSizeError("the package's files")
+	}
+	*remaining -= header.Size
+
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	if info, err := os.Lstat(target); err == nil && !info.Mode().IsRegular() {
+		return "", unsafeEntry(header.Name, "it would replace a directory or link")
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory for %s: %w", name, err)
+	}
+	mode := os.FileMode(0644)
+	if header.Mode&0111 != 0 {
+		mode = 0755
+	}
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	if _, err := io.Copy(out, io.LimitReader(tr, header.Size)); err != nil {
+		_ = out.Close()
+		return "", fmt.Errorf("failed to extract %s: %w", name, err)
+	}
+	if err := out.Close(); err != nil {
+		return "", fmt.Errorf("failed to close %s: %w", name, err)
+	}
+	// OpenFile's mode only applies to a file it creates
+	if err := os.Chmod(target, mode); err != nil {
+		return "", fmt.Errorf("failed to set permissions of %s: %w", name, err)
+	}
+	return target, nil
+}
+
 type Extractor struct{}
 
 func NewExtractor() *Extractor {
 	return &Extractor{}
 }
 
-// Extract extracts files from a cached image to a directory
+// Extract extracts files from a cached image to a directory. A pulled
+// image's layer is untrusted: its entries go through checkLayerEntry, and it
+// and its files must fit in the extract size limit (SetMaxExtractSize).
 func (e *Extractor) Extract(imageRef, outputDir string, force bool) ([]string, error) {
 	// Create output directory if it doesn't exist
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
@@ -41,6 +179,13 @@ func (e *Extractor) Extract(imageRef, outputDir string, force bool) ([]string, e
 	imagePath := filepath.Join(cache, "images", sanitizeImageRef(imageRef))
 	layerPath := filepath.Join(imagePath, "layer.tar")
 
+	info, err := os.Stat(layerPath)
+	if err != nil {
+		return nil, fmt.Errorf("image not found locally: %w", err)
+	}
+	if info.Size() > maxExtractSize {
+		return nil, extractSizeError(fmt.Sprintf("the layer of %s (%d bytes)", imageRef, info.Size()))
+	}
 	layerData, err := os.ReadFile(layerPath)
 	if err != nil {
 		return nil, fmt.Errorf("image not found locally: %w", err)
@@ -49,6 +194,7 @@ func (e *Extractor) Extract(imageRef, outputDir string, force bool) ([]string, e
 	// Extract tar
 	tr := tar.NewReader(bytes.NewReader(layerData))
 	var extractedFiles []string
+	remaining := maxExtractSize
 
 	for {
 		header, err := tr.Next()
@@ -59,45 +205,27 @@ func (e *Extractor) Extract(imageRef, outputDir string, force bool) ([]string, e
 			return nil, fmt.Errorf("failed to read tar: %w", err)
 		}
 
+		name, regular, err := checkLayerEntry(header)
+		if err != nil {
+			return nil, err
+		}
 		// Skip the manifest file
-		if header.Name == ".aigogo-manifest.json" {
+		if !regular || name == ".aigogo-manifest.json" {
 			continue
 		}
 
-		targetPath := filepath.Join(outputDir, header.Name)
-
 		// Check if file exists
 		if !force {
-			if _, err := os.Stat(targetPath); err == nil {
+			targetPath := filepath.Join(outputDir, filepath.FromSlash(name))
+			if _, err := os.Lstat(targetPath); err == nil {
 				return nil, fmt.Errorf("file already exists: %s (use -f to overwrite)", targetPath)
 			}
 		}
 
-		// Create directory if needed
-		dir := filepath.Dir(targetPath)
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return nil, fmt.Errorf("failed to create directory: %w", err)
-		}
-
-		// Extract file
-		outFile, err := os.Create(targetPath)
+		targetPath, err := writeLayerFile(tr, header, outputDir, name, &remaining)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create file: %w", err)
+			return nil, err
 		}
-
-		if _, err := io.Copy(outFile, tr); err != nil {
-			_ = outFile.Close()
-			return nil, fmt.Errorf("failed to extract file: %w", err)
-		}
-		if err := outFile.Close(); err != nil {
-			return nil, fmt.Errorf("failed to close file: %w", err)
-		}
-
-		// Set permissions
-		if err := os.Chmod(targetPath, os.FileMode(header.Mode)); err != nil {
-			return nil, fmt.Errorf("failed to set permissions: %w", err)
-		}
-
 		extractedFiles = append(extractedFiles, targetPath)
 	}
 
@@ -114,8 +242,8 @@ func (e *Extractor) extractFromDirectory(srcDir, dstDir string, force bool) ([]s
 			return err
 		}
 
-		// Skip metadata file
-		if filepath.Base(path) == ".aigogo-metadata.json" {
+		// Skip metadata file, and links: a build holds regular files
+		if filepath.Base(path) == ".aigogo-metadata.json" || info.Mode()&os.ModeSymlink != 0 {
 			return nil
 		}
 
@@ -0,0 +1,251 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+)
+
+// setMaxExtractSizeForTest sets the extract size limit until the test ends
+func setMaxExtractSizeForTest(t *testing.T, n int64) {
+	t.Helper()
+	old := maxExtractSize
+	SetMaxExtractSize(n)
+	t.Cleanup(func() { SetMaxExtractSize(old) })
+}
+
+// makeLayer returns a tar of headers, each regular file holding its name
+func makeLayer(t testing.TB, headers ...*tar.Header) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, h := range headers {
+		var body []byte
+		if h.Typeflag == tar.TypeReg {
+			body = []byte(h.Name)
+			h.Size = int64(len(body))
+		}
+		if h.Mode == 0 {
+			h.Mode = 0644
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func file(name string) *tar.Header {
+	return &tar.Header{Name: name, Typeflag: tar.TypeReg}
+}
+
+func TestExtractLayer(t *testing.T) {
+	dir := t.TempDir()
+	layer := makeLayer(t,
+		&tar.Header{Name: "lib/", Typeflag: tar.TypeDir, Mode: 0755},
+		file("aigogo.json"),
+		file(".aigogo-manifest.json"),
+		file("./lib/util.py"),
+		&tar.Header{Name: "run.sh", Typeflag: tar.TypeReg, Mode: 04755},
+		&tar.Header{Name: "lib/link.py", Typeflag: tar.TypeSymlink, Linkname: "util.py"},
+	)
+	files, err := ExtractLayer(layer, dir)
+	if err != nil {
+		t.Fatalf("ExtractLayer failed: %v", err)
+	}
+	if got := strings.Join(files, " "); got != "aigogo.json lib/util.py run.sh" {
+		t.Errorf("files = %s", got)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "lib", "util.py")); err != nil || string(data) != "./lib/util.py" {
+		t.Errorf("lib/util.py = %q, %v", data, err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "lib", "link.py")); !os.IsNotExist(err) {
+		t.Errorf("the symlink was extracted: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "run.sh")); err != nil || info.Mode() != 0755 {
+		t.Errorf("run.sh mode = %v, %v; want 0755 without setuid", info.Mode(), err)
+	}
+}
+
+func TestExtractLayerRefusesUnsafeEntries(t *testing.T) {
+	tests := []struct {
+		name   string
+		header *tar.Header
+	}{
+		{"absolute path", file("/etc/passwd")},
+		{"parent directory", file("../escape.py")},
+		{"nested parent directory", file("lib/../../escape.py")},
+		{"backslash parent directory", file("lib\\..\\..\\escape.py")},
+		{"drive letter", file("C:/Windows/escape.py")},
+		{"UNC path", file("\\\\server\\share\\escape.py")},
+		{"root", file(".")},
+		{"symlink out", &tar.Header{Name: "lib/passwd", Typeflag: tar.TypeSymlink, Linkname: "../../etc/passwd"}},
+		{"absolute symlink", &tar.Header{Name: "passwd", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}},
+		{"hard link out", &tar.Header{Name: "passwd", Typeflag: tar.TypeLink, Linkname: "../etc/passwd"}},
+		{"character device", &tar.Header{Name: "null", Typeflag: tar.TypeChar, Devmajor: 1, Devminor: 3}},
+		{"block device", &tar.Header{Name: "sda", Typeflag: tar.TypeBlock, Devmajor: 8}},
+		{"FIFO", &tar.Header{Name: "pipe", Typeflag: tar.TypeFifo}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := t.TempDir()
+			dir := filepath.Join(parent, "pkg")
+			layer := makeLayer(t, tt.header)
+			_, err := ExtractLayer(layer, dir)
+			if err == nil {
+				t.Fatal("ExtractLayer succeeded")
+			}
+			if errcode.Of(err) != errcode.Integrity {
+				t.Errorf("error code = %s, want %s: %v", errcode.Of(err), errcode.Integrity, err)
+			}
+			assertOnlyUnder(t, parent, dir)
+		})
+	}
+
+	// Go's tar writer can't write a NUL byte in a name, but other tars can
+	if _, err := entryPath("lib/util.py\x00.txt"); err == nil {
+		t.Error("entryPath accepted a NUL byte")
+	}
+}
+
+func TestExtractLayerRefusesReplacingLinks(t *testing.T) {
+	dir := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "outside.py")
+	if err := os.Symlink(outside, filepath.Join(dir, "util.py")); err != nil {
+		t.Skipf("can't create symlinks: %v", err)
+	}
+	if _, err := ExtractLayer(makeLayer(t, file("util.py")), dir); errcode.Of(err) != errcode.Integrity {
+		t.Errorf("ExtractLayer over a symlink = %v, want an integrity error", err)
+	}
+	if _, err := os.Stat(outside); !os.IsNotExist(err) {
+		t.Errorf("the write followed the symlink: %v", err)
+	}
+}
+
+func TestExtractSizeLimit(t *testing.T) {
+	setMaxExtractSizeForTest(t, 1024)
+	big := &tar.Header{Name: "big.bin", Typeflag: tar.TypeReg, Mode: 0644, Size: 600}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range []string{"a.bin", "b.bin"} {
+		h := *big
+		h.Name = name
+		if err := tw.WriteHeader(&h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(make([]byte, h.Size)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	layer := buf.Bytes()
+
+	// The tar itself is over the limit
+	if _, err := ExtractLayer(layer, t.TempDir()); errcode.Of(err) != errcode.Validation {
+		t.Errorf("ExtractLayer of a layer over the limit = %v, want a validation error", err)
+	}
+
+	// The files add up to more than what's left of the limit, which a
+	// sparse file can do in a tar smaller than the limit
+	var remaining int64 = 1000
+	dir := t.TempDir()
+	tr := tar.NewReader(bytes.NewReader(layer))
+	for i := 0; ; i++ {
+		header, err := tr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = writeLayerFile(tr, header, dir, header.Name, &remaining)
+		if i == 0 && err != nil {
+			t.Fatalf("first file: %v", err)
+		}
+		if i == 1 {
+			if errcode.Of(err) != errcode.Validation || !strings.Contains(err.Error(), "--max-extract-size") {
+				t.Errorf("second file = %v, want an extract size error", err)
+			}
+			break
+		}
+	}
+}
+
+func TestGunzipLimit(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(make([]byte, 1<<20)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	setMaxExtractSizeForTest(t, 1<<20)
+	if out, err := Gunzip(buf.Bytes()); err != nil || len(out) != 1<<20 {
+		t.Errorf("Gunzip at the limit = %d bytes, %v", len(out), err)
+	}
+	SetMaxExtractSize(1<<20 - 1)
+	if _, err := Gunzip(buf.Bytes()); errcode.Of(err) != errcode.Validation {
+		t.Errorf("Gunzip over the limit = %v, want a validation error", err)
+	}
+}
+
+func TestFormatExtractSize(t *testing.T) {
+	for n, want := range map[int64]string{512 << 20: "512MB", 2 << 30: "2GB", 64 << 10: "64KB", 1000: "1000 bytes"} {
+		if got := formatExtractSize(n); got != want {
+			t.Errorf("formatExtractSize(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+// assertOnlyUnder fails the test if anything in parent is outside dir
+func assertOnlyUnder(t *testing.T, parent, dir string) {
+	t.Helper()
+	_ = filepath.Walk(parent, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == parent {
+			return err
+		}
+		if rel, _ := filepath.Rel(dir, path); rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			t.Errorf("%s was written outside the package directory", path)
+		}
+		return nil
+	})
+}
+
+func FuzzExtractLayer(f *testing.F) {
+	f.Add(makeLayer(f, file("aigogo.json"), file("lib/util.py")))
+	f.Add(makeLayer(f, file("../escape.py")))
+	f.Add(makeLayer(f, &tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "../../x"}, file("link/x")))
+	f.Add(makeLayer(f, &tar.Header{Name: "a", Typeflag: tar.TypeLink, Linkname: "b"}, file("a")))
+	f.Add([]byte("not a tar"))
+
+	f.Fuzz(func(t *testing.T, layer []byte) {
+		setMaxExtractSizeForTest(t, 1<<20)
+		parent := t.TempDir()
+		dir := filepath.Join(parent, "pkg")
+		files, err := ExtractLayer(layer, dir)
+		assertOnlyUnder(t, parent, dir)
+		if err != nil {
+			return
+		}
+		for _, name := range files {
+			info, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(name)))
+			if err != nil || !info.Mode().IsRegular() || info.Mode()&^0755 != 0 {
+				t.Errorf("extracted %s isn't a plain regular file: %v, %v", name, info, err)
+			}
+		}
+	})
+}
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/aupeachmo/aigogo/pkg/manifest"
@@ -26,6 +28,7 @@ type CachedImage struct {
 	Source    string
 	BuildTime time.Time
 	Size      int64
+	Path      string             // The image's directory in the cache
 	Manifest  *manifest.Manifest // The aigogo.json manifest if available
 }
 
@@ -82,6 +85,7 @@ func (l *Lister) ListDetailed() ([]CachedImage, error) {
 					Source:    "local",
 					BuildTime: buildTime,
 					Size:      size,
+					Path:      imageDir,
 					Manifest:  aigogoManifest,
 				})
 			}
@@ -125,6 +129,7 @@ func (l *Lister) ListDetailed() ([]CachedImage, error) {
 						Source:    "registry",
 						BuildTime: metadata.CreatedAt,
 						Size:      size,
+						Path:      imageDir,
 						Manifest:  aigogoManifest,
 					})
 				}
@@ -167,26 +172,56 @@ func getDirSize(path string) (int64, error) {
 
 // extractManifestFromTar extracts aigogo.json from a tar archive
 func extractManifestFromTar(tarData []byte) []byte {
-	tr := tar.NewReader(bytes.NewReader(tarData))
+	data, _ := ReadFileFromTar(tarData, "aigogo.json")
+	return data
+}
 
+// PackageFile is a file of a package and its size in bytes
+type PackageFile struct {
+	Path string `json:"path"` // slash-separated, relative to the package root
+	Size int64  `json:"size"`
+}
+
+// ListTarFiles returns the regular files of a package layer, sorted by
+// path, leaving out the .aigogo-manifest.json the builder adds
+func ListTarFiles(tarData []byte) ([]PackageFile, error) {
+	tr := tar.NewReader(bytes.NewReader(tarData))
+	var files []PackageFile
 	for {
 		header, err := tr.Next()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return nil
+			return nil, fmt.Errorf("failed to read layer: %w", err)
 		}
+		name := path.Clean(header.Name)
+		if !header.FileInfo().Mode().IsRegular() || name == ".aigogo-manifest.json" {
+			continue
+		}
+		files = append(files, PackageFile{Path: name, Size: header.Size})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
 
-		// Look for aigogo.json in the tar
-		if header.Name == "aigogo.json" {
+// ReadFileFromTar returns the contents of the named file in a tar archive,
+// such as a package layer
+func ReadFileFromTar(tarData []byte, name string) ([]byte, bool) {
+	tr := tar.NewReader(bytes.NewReader(tarData))
+
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			return nil, false
+		}
+
+		if path.Clean(header.Name) == name {
 			data, err := io.ReadAll(tr)
 			if err != nil {
-				return nil
+				return nil, false
 			}
-			return data
+			return data, true
 		}
 	}
-
-	return nil
 }
@@ -8,7 +8,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aupeachmo/aigogo/pkg/logging"
 	"github.com/aupeachmo/aigogo/pkg/manifest"
+	"github.com/aupeachmo/aigogo/pkg/store"
 )
 
 // LocalBuilder builds packages to local cache without pushing
@@ -18,26 +20,26 @@ type LocalBuilder struct {
 
 // NewLocalBuilder creates a new local builder
 func NewLocalBuilder() *LocalBuilder {
-	home, err := os.UserHomeDir()
+	cache, err := CacheDir()
 	if err != nil {
-		home = "."
+		cache = filepath.Join(".", ".aigogo", "cache")
 	}
 	return &LocalBuilder{
-		cacheDir: filepath.Join(home, ".aigogo", "cache"),
+		cacheDir: cache,
 	}
 }
 
 // BuildFromDir builds a package from a specific directory
-func (b *LocalBuilder) BuildFromDir(srcDir string, imageRef string, m *manifest.Manifest, force bool) error {
+func (b *LocalBuilder) BuildFromDir(srcDir string, imageRef string, m *manifest.Manifest, force bool) (*BuildResult, error) {
 	// Save current directory
 	originalDir, err := os.Getwd()
 	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
+		return nil, fmt.Errorf("failed to get current directory: %w", err)
 	}
 
 	// Change to source directory
 	if err := os.Chdir(srcDir); err != nil {
-		return fmt.Errorf("failed to change to directory %s: %w", srcDir, err)
+		return nil, fmt.Errorf("failed to change to directory %s: %w", srcDir, err)
 	}
 
 	// Restore directory on exit
@@ -49,25 +51,85 @@ func (b *LocalBuilder) BuildFromDir(srcDir string, imageRef string, m *manifest.
 	return b.Build(imageRef, m, force)
 }
 
-// Build builds a package to the local cache
-func (b *LocalBuilder) Build(imageRef string, m *manifest.Manifest, force bool) error {
-	// Normalize image reference
+// ImagePath returns the cache directory a build of imageRef is written to
+func (b *LocalBuilder) ImagePath(imageRef string) string {
 	normalizedRef := normalizeImageRef(imageRef)
 	cacheKey := strings.ReplaceAll(normalizedRef, "/", "_")
 	cacheKey = strings.ReplaceAll(cacheKey, ":", "_")
+	return filepath.Join(b.cacheDir, cacheKey)
+}
+
+// BuildResult describes the build of a package in the local cache
+type BuildResult struct {
+	Path string // The build's cache directory
+	// ContentHash is the sha256:-prefixed hash of the packaged files and
+	// aigogo.json, the integrity aigg add locks for the build
+	ContentHash string
+	// Reused is set when the cache already held a build of the reference
+	// with the same content, which was kept instead of rebuilt
+	Reused bool
+}
+
+// packagedFile is a file as Build writes it to the cache
+type packagedFile struct {
+	path    string
+	content []byte
+	mode    os.FileMode
+}
+
+// Build builds a package to the local cache. When the cache already holds
+// a build of imageRef with the same content hash, it is kept as it is;
+// one with other content is only replaced with force.
+func (b *LocalBuilder) Build(imageRef string, m *manifest.Manifest, force bool) (*BuildResult, error) {
+	fl, err := lockCache(b.cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	defer fl.Release()
 
-	imagePath := filepath.Join(b.cacheDir, cacheKey)
+	imagePath := b.ImagePath(imageRef)
 
-	// Check if already exists
-	if !force {
-		if _, err := os.Stat(imagePath); err == nil {
-			return fmt.Errorf("package already exists in cache: %s\nUse --force to rebuild", imageRef)
+	// Generate dependency files if needed
+	if m.Dependencies != nil && (len(m.Dependencies.Runtime) > 0 || len(m.Dependencies.Dev) > 0) {
+		logging.Println("Generating dependency files...")
+		if err := generateDependencyFiles(m); err != nil {
+			return nil, fmt.Errorf("failed to generate dependency files: %w", err)
 		}
 	}
 
+	files, err := packageFiles(m)
+	if err != nil {
+		return nil, err
+	}
+
+	// The hash is computed as the store computes it, so it matches the
+	// integrity aigg add locks for the build
+	byPath := make(map[string][]byte, len(files))
+	paths := make([]string, len(files))
+	for i, f := range files {
+		byPath[f.path] = f.content
+		paths[i] = f.path
+	}
+	hash, err := store.ContentHash(paths, func(file string) ([]byte, error) {
+		return byPath[file], nil
+	}, byPath["aigogo.json"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute content hash: %w", err)
+	}
+	result := &BuildResult{Path: imagePath, ContentHash: "sha256:" + hash}
+
+	if _, err := os.Stat(imagePath); err == nil && !force {
+		if cached, err := dirContentHash(imagePath); err == nil && cached == result.ContentHash {
+			result.Reused = true
+			logging.Printf("%s is already built with the same content (%s)\n", imageRef, result.ContentHash)
+			return result, nil
+		}
+		return nil, fmt.Errorf("package already exists in cache with different content: %s\nUse --force to rebuild", imageRef)
+	}
+
 	// Create cache directory
 	if err := os.MkdirAll(b.cacheDir, 0755); err != nil {
-		return fmt.Errorf("failed to create cache directory: %w", err)
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
 	}
 
 	// Clean existing if force rebuild
@@ -77,113 +139,239 @@ func (b *LocalBuilder) Build(imageRef string, m *manifest.Manifest, force bool)
 
 	// Create image directory
 	if err := os.MkdirAll(imagePath, 0755); err != nil {
-		return fmt.Errorf("failed to create image directory: %w", err)
+		return nil, fmt.Errorf("failed to create image directory: %w", err)
 	}
 
-	fmt.Printf("Building to cache: %s\n", imagePath)
+	logging.Printf("Building to cache: %s\n", imagePath)
+	logging.Printf("Packaging %d file(s)...\n", len(files))
 
-	// Generate dependency files if needed
-	if m.Dependencies != nil && (len(m.Dependencies.Runtime) > 0 || len(m.Dependencies.Dev) > 0) {
-		fmt.Println("Generating dependency files...")
-		if err := generateDependencyFiles(m); err != nil {
-			return fmt.Errorf("failed to generate dependency files: %w", err)
+	// Copy files to cache
+	for _, f := range files {
+		dstPath := filepath.Join(imagePath, filepath.FromSlash(f.path))
+
+		// Create parent directories
+		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", f.path, err)
+		}
+
+		// Write to destination. The mode carries the executable attribute
+		// into the pushed layer's tar headers.
+		if err := os.WriteFile(dstPath, f.content, f.mode); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", f.path, err)
 		}
+		if err := os.Chmod(dstPath, f.mode); err != nil {
+			return nil, fmt.Errorf("failed to set mode of %s: %w", f.path, err)
+		}
+
+		logging.Printf("  + %s\n", f.path)
+	}
+
+	// Save metadata
+	metadata := LocalBuildMetadata{
+		Name:        imageRef,
+		Type:        "local-build",
+		BuiltAt:     time.Now().Format(time.RFC3339),
+		Source:      "local",
+		ContentHash: result.ContentHash,
+		Manifest:    m,
+	}
+
+	metadataPath := filepath.Join(imagePath, ".aigogo-metadata.json")
+	metadataJSON, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
+	if err := os.WriteFile(metadataPath, metadataJSON, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write metadata: %w", err)
+	}
+
+	return result, nil
+}
+
+// packageFiles discovers the files of the package in the working
+// directory and reads them as Build writes them to the cache
+func packageFiles(m *manifest.Manifest) ([]packagedFile, error) {
 	// Discover files to include
 	filesToCopy, err := discoverFiles(m)
 	if err != nil {
-		return fmt.Errorf("failed to discover files: %w", err)
+		return nil, fmt.Errorf("failed to discover files: %w", err)
 	}
 
 	if len(filesToCopy) == 0 {
-		return fmt.Errorf("no files to package")
+		return nil, fmt.Errorf("no files to package")
 	}
 
-	// Validate scripts reference files that are being packaged
-	if len(m.Scripts) > 0 {
+	// Validate scripts reference files that are being packaged. Lifecycle
+	// scripts are shell commands, not files.
+	if scripts := m.EntryScripts(); len(scripts) > 0 {
 		fileSet := make(map[string]bool, len(filesToCopy))
 		for _, f := range filesToCopy {
 			fileSet[f] = true
 		}
-		for name, scriptFile := range m.Scripts {
+		for name, scriptFile := range scripts {
 			if !fileSet[scriptFile] {
-				return fmt.Errorf("script %q references file %q which is not in the package files\n"+
+				return nil, fmt.Errorf("script %q references file %q which is not in the package files\n"+
 					"Add it with: aigg add file %s", name, scriptFile, scriptFile)
 			}
 		}
 	}
 
+	if err := manifest.CheckExports(m, filesToCopy); err != nil {
+		return nil, err
+	}
+
 	// Always include aigogo.json if it exists
 	if _, err := os.Stat("aigogo.json"); err == nil {
-		hasManifest := false
-		for _, f := range filesToCopy {
-			if f == "aigogo.json" {
-				hasManifest = true
-				break
-			}
-		}
-		if !hasManifest {
-			filesToCopy = append(filesToCopy, "aigogo.json")
-		}
+		filesToCopy = appendMissing(filesToCopy, "aigogo.json")
 	}
 
-	fmt.Printf("Packaging %d file(s)...\n", len(filesToCopy))
+	// The readme is bundled even when files.include doesn't list it, so
+	// consumers can read it with aigg info --readme
+	if m.Readme != "" {
+		readme := filepath.ToSlash(filepath.Clean(m.Readme))
+		if _, err := os.Stat(readme); err != nil {
+			return nil, fmt.Errorf("readme %s not found: %w", m.Readme, err)
+		}
+		filesToCopy = appendMissing(filesToCopy, readme)
+	}
 
-	// Copy files to cache
+	files := make([]packagedFile, 0, len(filesToCopy))
 	for _, file := range filesToCopy {
-		srcPath := file
-		dstPath := filepath.Join(imagePath, file)
-
-		// Create parent directories
-		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
-			return fmt.Errorf("failed to create directory for %s: %w", file, err)
+		// A manifest that extends a base is packaged merged with it, since
+		// consumers can't resolve the base, and one with comments as plain
+		// JSON, which every consumer can parse.
+		var content []byte
+		if file == "aigogo.json" && (m.Extends != "" || m.HasComments()) {
+			content, err = manifest.Marshal(m.Resolved())
+		} else {
+			content, err = os.ReadFile(file)
 		}
-
-		// Read source file
-		content, err := os.ReadFile(srcPath)
 		if err != nil {
-			return fmt.Errorf("failed to read %s: %w", file, err)
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
 		}
 
-		// Write to destination
-		if err := os.WriteFile(dstPath, content, 0644); err != nil {
-			return fmt.Errorf("failed to write %s: %w", file, err)
+		mode := os.FileMode(0644)
+		if m.Files.AttributesOf(file).Executable {
+			mode = 0755
 		}
+		files = append(files, packagedFile{path: filepath.ToSlash(file), content: content, mode: mode})
+	}
+	return files, nil
+}
 
-		fmt.Printf("  + %s\n", file)
+// dirContentHash returns the content hash of the package directory dir,
+// such as a cached build, as Build computes it
+func dirContentHash(dir string) (string, error) {
+	files, err := ListDirFiles(dir)
+	if err != nil {
+		return "", err
+	}
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+	read := func(file string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+	}
+	manifestData, err := read("aigogo.json")
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	hash, err := store.ContentHash(paths, read, manifestData)
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hash, nil
+}
+
+// BuildFromLayer writes the files of a package layer, such as one from an
+// aigg export bundle, to the local cache as a build of imageRef. source
+// records where the layer came from, and m is the package's manifest. A
+// build of imageRef with the same files is kept; one with other files is
+// only replaced with force.
+func (b *LocalBuilder) BuildFromLayer(imageRef string, layer []byte, source string, m *manifest.Manifest, force bool) error {
+	fl, err := lockCache(b.cacheDir)
+	if err != nil {
+		return err
+	}
+	defer fl.Release()
+
+	imagePath := b.ImagePath(imageRef)
+	if _, err := os.Stat(imagePath); err == nil {
+		if !force {
+			if digest, same := sameLayerContent(b.cacheDir, imagePath, layer); same {
+				logging.Printf("%s is already in the cache with the same content (%s)\n", imageRef, digest)
+				return nil
+			}
+			return fmt.Errorf("package already exists in cache with different content: %s\nUse --force to replace it", imageRef)
+		}
+		_ = os.RemoveAll(imagePath)
+	}
+	if err := os.MkdirAll(imagePath, 0755); err != nil {
+		return fmt.Errorf("failed to create image directory: %w", err)
+	}
+
+	files, err := ExtractLayer(layer, imagePath)
+	if err != nil {
+		_ = os.RemoveAll(imagePath)
+		return err
+	}
+	if len(files) == 0 {
+		_ = os.RemoveAll(imagePath)
+		return fmt.Errorf("no files to package")
 	}
 
-	// Save metadata
 	metadata := LocalBuildMetadata{
 		Name:     imageRef,
 		Type:     "local-build",
 		BuiltAt:  time.Now().Format(time.RFC3339),
-		Source:   "local",
+		Source:   source,
 		Manifest: m,
 	}
-
-	metadataPath := filepath.Join(imagePath, ".aigogo-metadata.json")
 	metadataJSON, err := json.MarshalIndent(metadata, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
-
-	if err := os.WriteFile(metadataPath, metadataJSON, 0644); err != nil {
+	if err := os.WriteFile(filepath.Join(imagePath, ".aigogo-metadata.json"), metadataJSON, 0644); err != nil {
 		return fmt.Errorf("failed to write metadata: %w", err)
 	}
-
 	return nil
 }
 
+// sameLayerContent reports whether layer holds the same files as the
+// cached build in imagePath, by extracting it next to the build and
+// comparing their directory digests, and returns the digest
+func sameLayerContent(cacheDir, imagePath string, layer []byte) (string, bool) {
+	staging, err := os.MkdirTemp(cacheDir, ".import-")
+	if err != nil {
+		return "", false
+	}
+	defer func() { _ = os.RemoveAll(staging) }()
+	if _, err := ExtractLayer(layer, staging); err != nil {
+		return "", false
+	}
+	layerDigest, err := CalculateDirectoryDigest(staging)
+	if err != nil {
+		return "", false
+	}
+	cachedDigest, err := CalculateDirectoryDigest(imagePath)
+	if err != nil || cachedDigest != layerDigest {
+		return "", false
+	}
+	return layerDigest, true
+}
+
 // LocalBuildMetadata stores information about local builds
 type LocalBuildMetadata struct {
-	Name     string             `json:"name"`
-	Type     string             `json:"type"` // "local-build" or "registry-pull"
-	BuiltAt  string             `json:"built_at"`
-	Source   string             `json:"source"` // "local", registry URL, etc.
-	Registry string             `json:"registry,omitempty"`
-	Manifest *manifest.Manifest `json:"manifest,omitempty"`
+	Name     string `json:"name"`
+	Type     string `json:"type"` // "local-build" or "registry-pull"
+	BuiltAt  string `json:"built_at"`
+	Source   string `json:"source"` // "local", registry URL, etc.
+	Registry string `json:"registry,omitempty"`
+	// ContentHash is the sha256:-prefixed content hash of a local build
+	ContentHash string             `json:"content_hash,omitempty"`
+	Manifest    *manifest.Manifest `json:"manifest,omitempty"`
 }
 
 // normalizeImageRef normalizes an image reference for consistent storage
@@ -212,6 +400,16 @@ func normalizeImageRef(ref string) string {
 
 // Helper functions
 
+// appendMissing appends file to files unless it is already there
+func appendMissing(files []string, file string) []string {
+	for _, f := range files {
+		if filepath.ToSlash(f) == file {
+			return files
+		}
+	}
+	return append(files, file)
+}
+
 func generateDependencyFiles(m *manifest.Manifest) error {
 	// Import depgen package logic here or call it
 	// For now, placeholder - will be implemented when needed
@@ -225,5 +423,5 @@ func discoverFiles(m *manifest.Manifest) ([]string, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize file discovery: %w", err)
 	}
-	return discovery.Discover(m.Files, m.Language)
+	return discovery.Discover(m.Files, m.AllLanguages()...)
 }
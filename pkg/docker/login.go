@@ -0,0 +1,41 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/aupeachmo/aigogo/pkg/auth"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+)
+
+// CheckLogin checks that a registry accepts the stored credentials for it,
+// by requesting its API root (GET /v2/) with them. For Docker Hub, the
+// token exchange checks them first.
+func CheckLogin(ctx context.Context, registry string) error {
+	token, err := auth.NewManager().GetToken(ctx, registry, "")
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s://%s/v2/", registryScheme(registry), getRegistryAPIEndpoint(registry))
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	setAuthHeader(req, registry, token)
+
+	resp, err := newRegistryClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", registry, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errcode.Errorf(errcode.Auth, "%s rejected the credentials (status %d), run 'aigg login %s'", registry, resp.StatusCode, registry)
+	}
+	return errcode.Errorf(errcode.FromHTTPStatus(resp.StatusCode), "unexpected response from %s: %s", registry, resp.Status)
+}
@@ -0,0 +1,467 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/auth"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+)
+
+// DefaultProxyTagTTL is how long a Proxy serves a tag's manifest from its
+// cache before asking the upstream registry again
+const DefaultProxyTagTTL = 5 * time.Minute
+
+// Media types a Proxy asks upstream registries for, and the one it serves
+// a cached manifest without a mediaType field as
+const (
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex       = "application/vnd.oci.image.index.v1+json"
+)
+
+var (
+	// proxyRepositoryPattern is the repository name grammar of the
+	// distribution spec
+	proxyRepositoryPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	proxyRegistryPattern   = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9.-]*[A-Za-z0-9])?(?::[0-9]+)?$`)
+	proxyTagPattern        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]{0,127}$`)
+	proxyDigestPattern     = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// Proxy serves the read side of the Docker Registry HTTP API V2 (manifests,
+// blobs and tag lists) from a cache directory, fetching what it doesn't
+// have from upstream registries with the credentials of aigg login. The
+// first component of a repository name is the upstream registry when it
+// looks like a host, as in <proxy>/ghcr.io/org/utils:1.0.0; other names
+// are fetched from the default registry. Blobs and manifests are cached by
+// digest and never fetched again; a tag is checked upstream once its TTL
+// has passed, and served from the cache when the registry can't be reached.
+type Proxy struct {
+	dir             string
+	defaultRegistry string
+	tagTTL          time.Duration
+	offline         bool
+	client          *http.Client
+	now             func() time.Time
+}
+
+// ProxyDir returns the default cache directory of a Proxy: proxy/ in the
+// build/pull cache directory
+func ProxyDir() (string, error) {
+	cache, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cache, "proxy"), nil
+}
+
+// NewProxy returns a proxy caching in dir and fetching names without a
+// registry from defaultRegistry
+func NewProxy(dir, defaultRegistry string) *Proxy {
+	return &Proxy{
+		dir:             dir,
+		defaultRegistry: defaultRegistry,
+		tagTTL:          DefaultProxyTagTTL,
+		client:          newRegistryClient(),
+		now:             time.Now,
+	}
+}
+
+// SetTagTTL sets how long a tag's manifest is served from the cache before
+// the upstream registry is asked again; 0 asks every time
+func (p *Proxy) SetTagTTL(ttl time.Duration) {
+	p.tagTTL = ttl
+}
+
+// SetOffline makes the proxy serve only what it has cached
+func (p *Proxy) SetOffline(offline bool) {
+	p.offline = offline
+}
+
+// proxyRequest is a registry API request, split into the upstream registry
+// and repository it's for
+type proxyRequest struct {
+	registry   string
+	repository string
+	kind       string // "manifests", "blobs" or "tags"
+	reference  string // a tag or digest; empty for tags
+}
+
+// ServeHTTP answers a registry API request. Requests that would change a
+// registry (pushes and deletes) are refused.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+	path := strings.TrimPrefix(r.URL.Path, "/v2/")
+	if r.URL.Path == "/v2" || r.URL.Path == "/v2/" {
+		writeProxyJSON(w, r, http.StatusOK, struct{}{})
+		return
+	}
+	if path == r.URL.Path {
+		writeProxyError(w, r, http.StatusNotFound, "NOT_FOUND", "not a registry API path")
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeProxyError(w, r, http.StatusMethodNotAllowed, "UNSUPPORTED", "the proxy is read-only")
+		return
+	}
+
+	req, err := p.parseRequest(path)
+	if err != nil {
+		writeProxyError(w, r, http.StatusBadRequest, "NAME_INVALID", err.Error())
+		return
+	}
+	switch req.kind {
+	case "manifests":
+		p.serveManifest(w, r, req)
+	case "blobs":
+		p.serveBlob(w, r, req)
+	default:
+		p.serveTags(w, r, req)
+	}
+}
+
+// parseRequest splits the path of a request after /v2/ into what it asks
+// for, refusing names that aren't valid registries, repositories, tags or
+// digests, as they become paths in the cache
+func (p *Proxy) parseRequest(path string) (*proxyRequest, error) {
+	req := &proxyRequest{}
+	var name string
+	if before, ok := strings.CutSuffix(path, "/tags/list"); ok {
+		name, req.kind = before, "tags"
+	} else {
+		for _, kind := range []string{"manifests", "blobs"} {
+			if i := strings.LastIndex(path, "/"+kind+"/"); i > 0 {
+				name, req.kind, req.reference = path[:i], kind, path[i+len(kind)+2:]
+				break
+			}
+		}
+	}
+	if req.kind == "" {
+		return nil, fmt.Errorf("unsupported path: /v2/%s", path)
+	}
+
+	req.registry, req.repository = p.defaultRegistry, name
+	if first, rest, ok := strings.Cut(name, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		req.registry, req.repository = first, rest
+	}
+	if !proxyRegistryPattern.MatchString(req.registry) {
+		return nil, fmt.Errorf("invalid registry: %s", req.registry)
+	}
+	if !proxyRepositoryPattern.MatchString(req.repository) {
+		return nil, fmt.Errorf("invalid repository name: %s", req.repository)
+	}
+	switch {
+	case req.kind == "blobs" && !proxyDigestPattern.MatchString(req.reference):
+		return nil, fmt.Errorf("invalid digest: %s", req.reference)
+	case req.kind == "manifests" && !proxyDigestPattern.MatchString(req.reference) && !proxyTagPattern.MatchString(req.reference):
+		return nil, fmt.Errorf("invalid tag or digest: %s", req.reference)
+	}
+	return req, nil
+}
+
+// proxyTag is what the cache remembers of a tag: the digest of its
+// manifest and when it was fetched
+type proxyTag struct {
+	Digest    string    `json:"digest"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+func (p *Proxy) serveManifest(w http.ResponseWriter, r *http.Request, req *proxyRequest) {
+	digest := req.reference
+	state := "hit"
+	if !proxyDigestPattern.MatchString(digest) {
+		tag, cached := p.readTag(req)
+		if cached && (p.offline || p.now().Sub(tag.FetchedAt) < p.tagTTL) {
+			digest = tag.Digest
+		} else {
+			fetched, err := p.fetchManifest(r.Context(), req, r.Header.Get("Accept"))
+			switch {
+			case err == nil:
+				digest, state = fetched, "miss"
+			case cached && errcode.Of(err) == errcode.Network:
+				logging.Verbosef("Serving %s/%s:%s from the proxy cache: %v\n", req.registry, req.repository, req.reference, err)
+				digest, state = tag.Digest, "stale"
+			default:
+				writeProxyFetchError(w, r, "MANIFEST_UNKNOWN", err)
+				return
+			}
+		}
+	} else if _, err := os.Stat(p.blobPath(digest)); err != nil {
+		if _, err := p.fetchManifest(r.Context(), req, r.Header.Get("Accept")); err != nil {
+			writeProxyFetchError(w, r, "MANIFEST_UNKNOWN", err)
+			return
+		}
+		state = "miss"
+	}
+
+	data, err := os.ReadFile(p.blobPath(digest))
+	if err != nil {
+		writeProxyError(w, r, http.StatusNotFound, "MANIFEST_UNKNOWN", fmt.Sprintf("manifest %s is not in the proxy cache", digest))
+		return
+	}
+	var m struct {
+		MediaType string `json:"mediaType"`
+	}
+	_ = json.Unmarshal(data, &m)
+	if m.MediaType == "" {
+		m.MediaType = mediaTypeDockerManifest
+	}
+	w.Header().Set("Content-Type", m.MediaType)
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("X-Aigogo-Cache", state)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+}
+
+func (p *Proxy) serveBlob(w http.ResponseWriter, r *http.Request, req *proxyRequest) {
+	state := "hit"
+	if _, err := os.Stat(p.blobPath(req.reference)); err != nil {
+		if err := p.fetchBlob(r.Context(), req); err != nil {
+			writeProxyFetchError(w, r, "BLOB_UNKNOWN", err)
+			return
+		}
+		state = "miss"
+	}
+	f, err := os.Open(p.blobPath(req.reference))
+	if err != nil {
+		writeProxyError(w, r, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+	defer func() { _ = f.Close() }()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Docker-Content-Digest", req.reference)
+	w.Header().Set("X-Aigogo-Cache", state)
+	http.ServeContent(w, r, "", time.Time{}, f)
+}
+
+func (p *Proxy) serveTags(w http.ResponseWriter, r *http.Request, req *proxyRequest) {
+	cachePath := filepath.Join(p.dir, "tags", req.registry, filepath.FromSlash(req.repository), "_tags.json")
+	var tags []string
+	var err error
+	state := "miss"
+	if p.offline {
+		err = errcode.Errorf(errcode.NotFound, "the tags of %s/%s are not in the proxy cache", req.registry, req.repository)
+	} else {
+		tags, err = fetchTags(r.Context(), p.client, req.registry, req.repository, p.token(r.Context(), req))
+	}
+	if err == nil {
+		if data, err := json.Marshal(tags); err == nil {
+			_ = writeFileAtomic(cachePath, data)
+		}
+	} else {
+		// Offline or during an outage, the last list fetched is served
+		data, readErr := os.ReadFile(cachePath)
+		if readErr != nil || (!p.offline && errcode.Of(err) != errcode.Network) || json.Unmarshal(data, &tags) != nil {
+			writeProxyFetchError(w, r, "NAME_UNKNOWN", err)
+			return
+		}
+		state = "stale"
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+	w.Header().Set("X-Aigogo-Cache", state)
+	writeProxyJSON(w, r, http.StatusOK, map[string]interface{}{"name": req.registry + "/" + req.repository, "tags": tags})
+}
+
+// fetchManifest fetches a manifest from upstream, stores it by its digest
+// and, for a tag, remembers the tag's digest, and returns the digest
+func (p *Proxy) fetchManifest(ctx context.Context, req *proxyRequest, accept string) (string, error) {
+	if p.offline {
+		return "", errcode.Errorf(errcode.NotFound, "%s/%s:%s is not in the proxy cache", req.registry, req.repository, req.reference)
+	}
+	if accept == "" {
+		accept = strings.Join([]string{mediaTypeDockerManifest, mediaTypeOCIManifest, mediaTypeOCIIndex}, ", ")
+	}
+	url := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", registryScheme(req.registry), getRegistryAPIEndpoint(req.registry), req.repository, req.reference)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Accept", accept)
+	setAuthHeader(httpReq, req.registry, p.token(ctx, req))
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return "", errcode.Wrap(errcode.Network, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", errcode.Errorf(errcode.FromHTTPStatus(resp.StatusCode), "failed to get manifest: %s - %s", resp.Status, string(body))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", errcode.Wrap(errcode.Network, err)
+	}
+
+	digest := calculateDigest(data)
+	if proxyDigestPattern.MatchString(req.reference) && digest != req.reference {
+		return "", errcode.Errorf(errcode.Integrity, "manifest from %s has digest %s, not %s", req.registry, digest, req.reference)
+	}
+	if err := writeFileAtomic(p.blobPath(digest), data); err != nil {
+		return "", err
+	}
+	if !proxyDigestPattern.MatchString(req.reference) {
+		tag, err := json.Marshal(proxyTag{Digest: digest, FetchedAt: p.now()})
+		if err != nil {
+			return "", err
+		}
+		if err := writeFileAtomic(p.tagPath(req), tag); err != nil {
+			return "", err
+		}
+	}
+	return digest, nil
+}
+
+// fetchBlob downloads a blob from upstream into the cache, checking it
+// against its digest as it's written
+func (p *Proxy) fetchBlob(ctx context.Context, req *proxyRequest) error {
+	if p.offline {
+		return errcode.Errorf(errcode.NotFound, "blob %s is not in the proxy cache", req.reference)
+	}
+	url := fmt.Sprintf("%s://%s/v2/%s/blobs/%s", registryScheme(req.registry), getRegistryAPIEndpoint(req.registry), req.repository, req.reference)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	setAuthHeader(httpReq, req.registry, p.token(ctx, req))
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return errcode.Wrap(errcode.Network, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return errcode.Errorf(errcode.FromHTTPStatus(resp.StatusCode), "failed to download blob: %s - %s", resp.Status, string(body))
+	}
+
+	path := p.blobPath(req.reference)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create proxy cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".download-*")
+	if err != nil {
+		return fmt.Errorf("failed to create proxy cache file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errcode.Wrap(errcode.Network, fmt.Errorf("failed to download blob: %w", err))
+	}
+	if got := "sha256:" + hex.EncodeToString(h.Sum(nil)); got != req.reference {
+		return errcode.Errorf(errcode.Integrity, "blob from %s has digest %s, not %s", req.registry, got, req.reference)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readTag returns what the cache remembers of a tag, if it has it and the
+// manifest it names
+func (p *Proxy) readTag(req *proxyRequest) (proxyTag, bool) {
+	var tag proxyTag
+	data, err := os.ReadFile(p.tagPath(req))
+	if err != nil || json.Unmarshal(data, &tag) != nil || !proxyDigestPattern.MatchString(tag.Digest) {
+		return tag, false
+	}
+	if _, err := os.Stat(p.blobPath(tag.Digest)); err != nil {
+		return tag, false
+	}
+	return tag, true
+}
+
+// token returns the credentials of aigg login for the request's registry,
+// or "" to try without
+func (p *Proxy) token(ctx context.Context, req *proxyRequest) string {
+	token, err := auth.NewManager().GetToken(ctx, req.registry, req.repository)
+	if err != nil {
+		return ""
+	}
+	return token
+}
+
+// blobPath is where the cache keeps the blob or manifest with digest
+func (p *Proxy) blobPath(digest string) string {
+	return filepath.Join(p.dir, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:"))
+}
+
+// tagPath is where the cache remembers a tag of a repository
+func (p *Proxy) tagPath(req *proxyRequest) string {
+	return filepath.Join(p.dir, "tags", req.registry, filepath.FromSlash(req.repository), req.reference+".json")
+}
+
+// writeFileAtomic writes data to path through a temporary file, so readers
+// never see part of it
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create proxy cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".write-*")
+	if err != nil {
+		return fmt.Errorf("failed to write proxy cache: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write proxy cache: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// writeProxyFetchError answers a request that failed upstream, with the
+// status the failure's kind has in the registry API
+func writeProxyFetchError(w http.ResponseWriter, r *http.Request, unknownCode string, err error) {
+	switch errcode.Of(err) {
+	case errcode.NotFound:
+		writeProxyError(w, r, http.StatusNotFound, unknownCode, err.Error())
+	case errcode.Auth:
+		writeProxyError(w, r, http.StatusUnauthorized, "UNAUTHORIZED", err.Error())
+	case errcode.Interrupted:
+		// The client went away
+	default:
+		logging.Verbosef("Proxy request %s failed: %v\n", r.URL.Path, err)
+		writeProxyError(w, r, http.StatusBadGateway, "UNKNOWN", err.Error())
+	}
+}
+
+// writeProxyError answers with an error in the registry API's format
+func writeProxyError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	type apiError struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	writeProxyJSON(w, r, status, map[string][]apiError{"errors": {{Code: code, Message: message}}})
+}
+
+func writeProxyJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		status, data = http.StatusInternalServerError, []byte(`{"errors":[{"code":"UNKNOWN"}]}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(data)
+	}
+}
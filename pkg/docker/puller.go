@@ -1,15 +1,19 @@
 package docker
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/aupeachmo/aigogo/pkg/auth"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
 )
 
 type Puller struct {
@@ -18,90 +22,265 @@ type Puller struct {
 
 func NewPuller() *Puller {
 	return &Puller{
-		client: &http.Client{},
+		client: newRegistryClient(),
 	}
 }
 
 // Pull downloads an image from a registry
-func (p *Puller) Pull(imageRef string) error {
+func (p *Puller) Pull(ctx context.Context, imageRef string) error {
+	layerData, size, err := p.fetchLayer(ctx, imageRef)
+	if err != nil {
+		return err
+	}
+
+	// Save to local cache
+	cache, err := getCacheDir()
+	if err != nil {
+		return err
+	}
+	fl, err := lockCache(cache)
+	if err != nil {
+		return err
+	}
+	defer fl.Release()
+
+	imagePath := filepath.Join(cache, "images", sanitizeImageRef(imageRef))
+	if err := os.MkdirAll(imagePath, 0755); err != nil {
+		return fmt.Errorf("failed to create image directory: %w", err)
+	}
+
+	// Save layer
+	layerPath := filepath.Join(imagePath, "layer.tar")
+	if err := os.WriteFile(layerPath, layerData, 0644); err != nil {
+		return fmt.Errorf("failed to write layer: %w", err)
+	}
+
+	// Save metadata
+	metadata := ImageMetadata{
+		Ref:       imageRef,
+		CreatedAt: time.Now(),
+		Size:      size,
+	}
+
+	metadataData, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	metadataPath := filepath.Join(imagePath, "metadata.json")
+	if err := os.WriteFile(metadataPath, metadataData, 0644); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+
+	return nil
+}
+
+// Tags lists the tags of the repository of an image reference, whose tag
+// is ignored. Without credentials for the registry the repository must be
+// public.
+func (p *Puller) Tags(ctx context.Context, imageRef string) ([]string, error) {
+	registry, repository, _, err := parseImageRef(imageRef)
+	if err != nil {
+		return nil, err
+	}
+	token, err := auth.NewManager().GetToken(ctx, registry, repository)
+	if err != nil {
+		// Try without auth for public registries
+		token = ""
+	}
+	return fetchTags(ctx, p.client, registry, repository, token)
+}
+
+// catalogPageSize is how many repositories Catalog asks for at a time
+const catalogPageSize = 1000
+
+// Catalog lists the repositories of a registry (GET /v2/_catalog),
+// following its pages. Not every registry offers it: Docker Hub and GHCR,
+// for instance, answer with an error. Without credentials for the registry
+// it must allow anonymous listing.
+func (p *Puller) Catalog(ctx context.Context, registry string) ([]string, error) {
+	token, err := auth.NewManager().GetToken(ctx, registry, "")
+	if err != nil {
+		// Try without auth for public registries
+		token = ""
+	}
+
+	next := fmt.Sprintf("%s://%s/v2/_catalog?n=%d", registryScheme(registry), getRegistryAPIEndpoint(registry), catalogPageSize)
+	var repositories []string
+	for next != "" {
+		page, link, err := p.catalogPage(ctx, registry, next, token)
+		if err != nil {
+			return nil, err
+		}
+		repositories = append(repositories, page...)
+		if link == next {
+			break
+		}
+		next = link
+	}
+	return repositories, nil
+}
+
+// catalogPage fetches the page of a registry's catalog at pageURL, and
+// returns its repositories and the URL of the next page, if any
+func (p *Puller) catalogPage(ctx context.Context, registry, pageURL, token string) ([]string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	setAuthHeader(req, registry, token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list repositories: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, "", errcode.Errorf(errcode.Auth, "%s doesn't list its repositories without authentication, run 'aigg login %s'", registry, registry)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, "", errcode.Errorf(errcode.FromHTTPStatus(resp.StatusCode), "%s doesn't list its repositories: %s (status %d)", registry, strings.TrimSpace(string(body)), resp.StatusCode)
+	}
+
+	var result struct {
+		Repositories []string `json:"repositories"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	return result.Repositories, nextPageURL(resp), nil
+}
+
+// nextPageURL returns the URL of the page after resp, from its Link
+// header (</v2/_catalog?last=x&n=100>; rel="next"), or ""
+func nextPageURL(resp *http.Response) string {
+	for _, link := range resp.Header.Values("Link") {
+		target, params, ok := strings.Cut(link, ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		ref, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			return ""
+		}
+		return resp.Request.URL.ResolveReference(ref).String()
+	}
+	return ""
+}
+
+// FetchLayer downloads an image's package layer, a tar archive, without
+// adding the image to the local cache. Read files from it with
+// ReadFileFromTar.
+func (p *Puller) FetchLayer(ctx context.Context, imageRef string) ([]byte, error) {
+	layerData, _, err := p.fetchLayer(ctx, imageRef)
+	return layerData, err
+}
+
+// fetchLayer downloads the package layer of an image and returns it with
+// the size the registry manifest gives for it
+func (p *Puller) fetchLayer(ctx context.Context, imageRef string) ([]byte, int64, error) {
 	// Parse image reference
 	registry, repository, tag, err := parseImageRef(imageRef)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
 
 	// Get auth token
 	authManager := auth.NewManager()
-	token, err := authManager.GetToken(registry, repository)
+	token, err := authManager.GetToken(ctx, registry, repository)
 	if err != nil {
 		// Try without auth for public registries
 		token = ""
 	}
 
 	// Get manifest
-	manifest, err := p.getManifest(registry, repository, tag, token)
+	manifest, err := p.getManifest(ctx, registry, repository, tag, token)
 	if err != nil {
-		return fmt.Errorf("failed to get manifest: %w", err)
+		return nil, 0, fmt.Errorf("failed to get manifest: %w", err)
 	}
 
 	// Download layers
 	layers, ok := manifest["layers"].([]interface{})
 	if !ok || len(layers) == 0 {
-		return fmt.Errorf("no layers found in manifest")
+		return nil, 0, fmt.Errorf("no layers found in manifest")
 	}
 
 	// For simplicity, we'll download the first layer (should be our snippet data)
 	layer := layers[0].(map[string]interface{})
 	digest := layer["digest"].(string)
 	size := int64(layer["size"].(float64))
+	if size > maxExtractSize {
+		return nil, 0, extractSizeError(fmt.Sprintf("the layer of %s (%d bytes)", imageRef, size))
+	}
 
-	layerData, err := p.downloadBlob(registry, repository, digest, token)
-
+	layerData, err := p.downloadBlob(ctx, registry, repository, digest, token)
 	if err != nil {
-		return fmt.Errorf("failed to download layer: %w", err)
+		return nil, 0, fmt.Errorf("failed to download layer: %w", err)
 	}
+	return layerData, size, nil
+}
 
-	// Save to local cache
-	cache, err := getCacheDir()
+// Annotations returns the annotations of an image's registry manifest,
+// without downloading its layer
+func (p *Puller) Annotations(ctx context.Context, imageRef string) (map[string]string, error) {
+	registry, repository, tag, err := parseImageRef(imageRef)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	imagePath := filepath.Join(cache, "images", sanitizeImageRef(imageRef))
-	if err := os.MkdirAll(imagePath, 0755); err != nil {
-		return fmt.Errorf("failed to create image directory: %w", err)
+	authManager := auth.NewManager()
+	token, err := authManager.GetToken(ctx, registry, repository)
+	if err != nil {
+		// Try without auth for public registries
+		token = ""
 	}
 
-	// Save layer
-	layerPath := filepath.Join(imagePath, "layer.tar")
-	if err := os.WriteFile(layerPath, layerData, 0644); err != nil {
-		return fmt.Errorf("failed to write layer: %w", err)
+	imageManifest, err := p.getManifest(ctx, registry, repository, tag, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest: %w", err)
 	}
 
-	// Save metadata
-	metadata := ImageMetadata{
-		Ref:       imageRef,
-		CreatedAt: time.Now(),
-		Size:      size,
+	annotations := make(map[string]string)
+	if raw, ok := imageManifest["annotations"].(map[string]interface{}); ok {
+		for k, v := range raw {
+			if s, ok := v.(string); ok {
+				annotations[k] = s
+			}
+		}
 	}
+	return annotations, nil
+}
 
-	metadataData, err := json.MarshalIndent(metadata, "", "  ")
+// Exists reports whether imageRef is in its registry. Errors other than the
+// registry not having it, such as a failed login, are returned.
+func (p *Puller) Exists(ctx context.Context, imageRef string) (bool, error) {
+	registry, repository, tag, err := parseImageRef(imageRef)
 	if err != nil {
-		return fmt.Errorf("failed to marshal metadata: %w", err)
+		return false, err
 	}
 
-	metadataPath := filepath.Join(imagePath, "metadata.json")
-	if err := os.WriteFile(metadataPath, metadataData, 0644); err != nil {
-		return fmt.Errorf("failed to write metadata: %w", err)
+	authManager := auth.NewManager()
+	token, err := authManager.GetToken(ctx, registry, repository)
+	if err != nil {
+		// Try without auth for public registries
+		token = ""
 	}
 
-	return nil
+	if _, err := p.getManifest(ctx, registry, repository, tag, token); err != nil {
+		if errcode.Of(err) == errcode.NotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
 }
 
-func (p *Puller) getManifest(registry, repository, tag, token string) (map[string]interface{}, error) {
+func (p *Puller) getManifest(ctx context.Context, registry, repository, tag, token string) (map[string]interface{}, error) {
 	apiEndpoint := getRegistryAPIEndpoint(registry)
-	url := fmt.Sprintf("https://%s/v2/%s/manifests/%s", apiEndpoint, repository, tag)
-	req, err := http.NewRequest("GET", url, nil)
+	url := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", registryScheme(registry), apiEndpoint, repository, tag)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -117,7 +296,7 @@ func (p *Puller) getManifest(registry, repository, tag, token string) (map[strin
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get manifest: %s - %s", resp.Status, string(body))
+		return nil, errcode.Errorf(errcode.FromHTTPStatus(resp.StatusCode), "failed to get manifest: %s - %s", resp.Status, string(body))
 	}
 
 	var manifest map[string]interface{}
@@ -128,10 +307,10 @@ func (p *Puller) getManifest(registry, repository, tag, token string) (map[strin
 	return manifest, nil
 }
 
-func (p *Puller) downloadBlob(registry, repository, digest, token string) ([]byte, error) {
+func (p *Puller) downloadBlob(ctx context.Context, registry, repository, digest, token string) ([]byte, error) {
 	apiEndpoint := getRegistryAPIEndpoint(registry)
-	url := fmt.Sprintf("https://%s/v2/%s/blobs/%s", apiEndpoint, repository, digest)
-	req, err := http.NewRequest("GET", url, nil)
+	url := fmt.Sprintf("%s://%s/v2/%s/blobs/%s", registryScheme(registry), apiEndpoint, repository, digest)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -146,8 +325,16 @@ func (p *Puller) downloadBlob(registry, repository, digest, token string) ([]byt
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to download blob: %s - %s", resp.Status, string(body))
+		return nil, errcode.Errorf(errcode.FromHTTPStatus(resp.StatusCode), "failed to download blob: %s - %s", resp.Status, string(body))
 	}
 
-	return io.ReadAll(resp.Body)
+	// The manifest's size was checked, but the registry may send more
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxExtractSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxExtractSize {
+		return nil, extractSizeError("blob " + digest)
+	}
+	return data, nil
 }
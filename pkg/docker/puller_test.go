@@ -0,0 +1,54 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+)
+
+func TestCatalog(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/_catalog" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("last") == "" {
+			w.Header().Set("Link", `</v2/_catalog?last=team%2Futils&n=2>; rel="next"`)
+			_, _ = w.Write([]byte(`{"repositories": ["team/strings", "team/utils"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"repositories": ["other/utils"]}`))
+	}))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "http://")
+	SetInsecureRegistries([]string{registry})
+	t.Cleanup(func() { SetInsecureRegistries(nil) })
+
+	repositories, err := NewPuller().Catalog(context.Background(), registry)
+	if err != nil {
+		t.Fatalf("Catalog() error = %v", err)
+	}
+	if want := []string{"team/strings", "team/utils", "other/utils"}; !reflect.DeepEqual(repositories, want) {
+		t.Errorf("Catalog() = %v, want %v", repositories, want)
+	}
+
+	// Registries without a catalog
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer closed.Close()
+	registry = strings.TrimPrefix(closed.URL, "http://")
+	SetInsecureRegistries([]string{registry})
+	if _, err := NewPuller().Catalog(context.Background(), registry); errcode.Of(err) != errcode.NotFound {
+		t.Errorf("Catalog() of a registry without one error = %v, want not found", err)
+	}
+}
@@ -2,6 +2,7 @@ package docker
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,32 +10,143 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/aupeachmo/aigogo/pkg/auth"
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
 )
 
+// Pre-defined OCI annotation keys the package metadata is pushed as, see
+// https://github.com/opencontainers/image-spec/blob/main/annotations.md
+const (
+	AnnotationTitle       = "org.opencontainers.image.title"
+	AnnotationVersion     = "org.opencontainers.image.version"
+	AnnotationDescription = "org.opencontainers.image.description"
+	AnnotationAuthors     = "org.opencontainers.image.authors"
+	AnnotationLicenses    = "org.opencontainers.image.licenses"
+	AnnotationSource      = "org.opencontainers.image.source"
+	AnnotationURL         = "org.opencontainers.image.url"
+)
+
+// AnnotationKeywords holds metadata.keywords, comma-separated. OCI has no
+// key for them.
+const AnnotationKeywords = "io.github.aupeachmo.aigogo.keywords"
+
+// AnnotationDeprecated holds the deprecation message of a deprecated
+// package, and AnnotationReplacement the reference to use instead. aigg
+// push --deprecate sets them on versions that are already pushed.
+const (
+	AnnotationDeprecated  = "io.github.aupeachmo.aigogo.deprecated"
+	AnnotationReplacement = "io.github.aupeachmo.aigogo.replacement"
+)
+
+// cancelUploadTimeout bounds cancelUpload
+const cancelUploadTimeout = 5 * time.Second
+
 type Pusher struct {
 	client *http.Client
 }
 
 func NewPusher() *Pusher {
 	return &Pusher{
-		client: &http.Client{},
+		client: newRegistryClient(),
 	}
 }
 
-// Push uploads an image to a registry using Docker Registry HTTP API V2
-func (p *Pusher) Push(imageRef string) error {
-	// Parse image reference
+// Annotations maps a package's manifest to registry annotations, leaving
+// out fields that are not set
+func Annotations(m *manifest.Manifest) map[string]string {
+	annotations := make(map[string]string)
+	set := func(key, value string) {
+		if value != "" {
+			annotations[key] = value
+		}
+	}
+	set(AnnotationTitle, m.Name)
+	set(AnnotationVersion, m.Version)
+	set(AnnotationDescription, m.Description)
+	set(AnnotationAuthors, m.Author)
+	set(AnnotationLicenses, m.Metadata.License)
+	set(AnnotationSource, m.Metadata.Repository)
+	set(AnnotationURL, m.Metadata.Homepage)
+	set(AnnotationKeywords, strings.Join(m.Metadata.Keywords, ","))
+	if m.Deprecated != nil {
+		set(AnnotationDeprecated, m.Deprecated.Message)
+		set(AnnotationReplacement, m.Deprecated.Replacement)
+	}
+	return annotations
+}
+
+// DeprecationFromAnnotations returns the deprecation recorded in registry
+// annotations, or nil when the package isn't deprecated
+func DeprecationFromAnnotations(annotations map[string]string) *manifest.Deprecation {
+	message := annotations[AnnotationDeprecated]
+	if message == "" {
+		return nil
+	}
+	return &manifest.Deprecation{Message: message, Replacement: annotations[AnnotationReplacement]}
+}
+
+// Annotate changes the annotations of an image that is already in the
+// registry, without pushing its layer again: set adds or replaces
+// annotations and remove deletes them. The tag then points at the updated
+// manifest.
+func (p *Pusher) Annotate(ctx context.Context, imageRef string, set map[string]string, remove []string) error {
 	registry, repository, tag, err := parseImageRef(imageRef)
 	if err != nil {
 		return err
 	}
 
+	authManager := auth.NewManager()
+	token, err := authManager.GetToken(ctx, registry, repository)
+	if err != nil {
+		return errcode.Errorf(errcode.Auth, "authentication required, run 'aigg login %s': %w", registry, err)
+	}
+
+	imageManifest, err := (&Puller{client: p.client}).getManifest(ctx, registry, repository, tag, token)
+	if err != nil {
+		return fmt.Errorf("failed to get manifest: %w", err)
+	}
+
+	annotations := make(map[string]interface{})
+	if existing, ok := imageManifest["annotations"].(map[string]interface{}); ok {
+		for k, v := range existing {
+			annotations[k] = v
+		}
+	}
+	for k, v := range set {
+		annotations[k] = v
+	}
+	for _, k := range remove {
+		delete(annotations, k)
+	}
+	delete(imageManifest, "annotations")
+	if len(annotations) > 0 {
+		imageManifest["annotations"] = annotations
+	}
+
+	if _, err := p.uploadManifest(ctx, registry, repository, tag, imageManifest, token); err != nil {
+		return fmt.Errorf("failed to upload manifest: %w", err)
+	}
+	return nil
+}
+
+// Push uploads an image to a registry using Docker Registry HTTP API V2.
+// annotations are added to the image manifest; they may be nil. It
+// returns the digest of the manifest pushed.
+func (p *Pusher) Push(ctx context.Context, imageRef string, annotations map[string]string) (string, error) {
+	// Parse image reference
+	registry, repository, tag, err := parseImageRef(imageRef)
+	if err != nil {
+		return "", err
+	}
+
 	// Get the image from local cache
 	cache, err := getCacheDir()
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	imagePath := filepath.Join(cache, "images", sanitizeImageRef(imageRef))
@@ -42,46 +154,47 @@ func (p *Pusher) Push(imageRef string) error {
 
 	layerData, err := os.ReadFile(layerPath)
 	if err != nil {
-		return fmt.Errorf("image not found locally, build it first: %w", err)
+		return "", errcode.Errorf(errcode.NotFound, "image not found locally, build it first: %w", err)
 	}
 
 	// Get auth token (with repository scope for Docker Hub)
 	authManager := auth.NewManager()
-	token, err := authManager.GetToken(registry, repository)
+	token, err := authManager.GetToken(ctx, registry, repository)
 	if err != nil {
-		return fmt.Errorf("authentication required, run 'aigg login %s': %w", registry, err)
+		return "", errcode.Errorf(errcode.Auth, "authentication required, run 'aigg login %s': %w", registry, err)
 	}
 
 	// Upload config blob first (required by Docker Registry API)
 	// The config is a minimal JSON object: {}
 	configData := []byte("{}")
-	configDigest, err := p.uploadBlob(registry, repository, configData, token)
+	configDigest, err := p.uploadBlob(ctx, registry, repository, configData, token)
 	if err != nil {
-		return fmt.Errorf("failed to upload config blob: %w", err)
+		return "", fmt.Errorf("failed to upload config blob: %w", err)
 	}
 
 	// Upload layer blob
-	layerDigest, err := p.uploadBlob(registry, repository, layerData, token)
+	layerDigest, err := p.uploadBlob(ctx, registry, repository, layerData, token)
 	if err != nil {
-		return fmt.Errorf("failed to upload layer blob: %w", err)
+		return "", fmt.Errorf("failed to upload layer blob: %w", err)
 	}
 
 	// Create and upload manifest (references both config and layer blobs)
-	manifest := createManifest(configDigest, layerDigest, int64(len(layerData)))
-	if err := p.uploadManifest(registry, repository, tag, manifest, token); err != nil {
-		return fmt.Errorf("failed to upload manifest: %w", err)
+	imageManifest := createManifest(configDigest, layerDigest, int64(len(layerData)), annotations)
+	digest, err := p.uploadManifest(ctx, registry, repository, tag, imageManifest, token)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload manifest: %w", err)
 	}
 
-	return nil
+	return digest, nil
 }
 
-func (p *Pusher) uploadBlob(registry, repository string, data []byte, token string) (string, error) {
+func (p *Pusher) uploadBlob(ctx context.Context, registry, repository string, data []byte, token string) (string, error) {
 	// Get actual API endpoint (Docker Hub uses registry-1.docker.io)
 	apiEndpoint := getRegistryAPIEndpoint(registry)
 
 	// Initiate blob upload
-	url := fmt.Sprintf("https://%s/v2/%s/blobs/uploads/", apiEndpoint, repository)
-	req, err := http.NewRequest("POST", url, nil)
+	url := fmt.Sprintf("%s://%s/v2/%s/blobs/uploads/", registryScheme(registry), apiEndpoint, repository)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
 	if err != nil {
 		return "", err
 	}
@@ -96,7 +209,7 @@ func (p *Pusher) uploadBlob(registry, repository string, data []byte, token stri
 
 	if resp.StatusCode != http.StatusAccepted {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("failed to initiate upload: %s - %s", resp.Status, string(body))
+		return "", errcode.Errorf(errcode.FromHTTPStatus(resp.StatusCode), "failed to initiate upload: %s - %s", resp.Status, string(body))
 	}
 
 	// Get upload URL
@@ -107,15 +220,16 @@ func (p *Pusher) uploadBlob(registry, repository string, data []byte, token stri
 
 	// If relative URL, make it absolute
 	if !strings.HasPrefix(uploadURL, "http") {
-		uploadURL = fmt.Sprintf("https://%s%s", apiEndpoint, uploadURL)
+		uploadURL = fmt.Sprintf("%s://%s%s", registryScheme(registry), apiEndpoint, uploadURL)
 	}
 
 	// Calculate digest
 	digest := calculateDigest(data)
 
 	// Upload the blob
+	sessionURL := uploadURL
 	uploadURL = fmt.Sprintf("%s&digest=%s", uploadURL, digest)
-	req, err = http.NewRequest("PUT", uploadURL, bytes.NewReader(data))
+	req, err = http.NewRequestWithContext(ctx, "PUT", uploadURL, bytes.NewReader(data))
 	if err != nil {
 		return "", err
 	}
@@ -125,31 +239,54 @@ func (p *Pusher) uploadBlob(registry, repository string, data []byte, token stri
 
 	resp, err = p.client.Do(req)
 	if err != nil {
+		p.cancelUpload(registry, sessionURL, token)
 		return "", err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusCreated {
+		p.cancelUpload(registry, sessionURL, token)
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("failed to upload blob: %s - %s", resp.Status, string(body))
+		return "", errcode.Errorf(errcode.FromHTTPStatus(resp.StatusCode), "failed to upload blob: %s - %s", resp.Status, string(body))
 	}
 
 	return digest, nil
 }
 
-func (p *Pusher) uploadManifest(registry, repository, tag string, manifest interface{}, token string) error {
+// cancelUpload abandons a blob upload session that didn't complete, such
+// as one interrupted by Ctrl-C, so the registry drops its partial data
+// instead of keeping it until the session expires. It's best effort, with
+// its own deadline, as the push's context may be the one canceled.
+func (p *Pusher) cancelUpload(registry, sessionURL, token string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cancelUploadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "DELETE", sessionURL, nil)
+	if err != nil {
+		return
+	}
+	setAuthHeader(req, registry, token)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		logging.Verbosef("failed to cancel upload session %s: %v\n", sessionURL, err)
+		return
+	}
+	_ = resp.Body.Close()
+	logging.Verbosef("canceled upload session %s (%s)\n", sessionURL, resp.Status)
+}
+
+func (p *Pusher) uploadManifest(ctx context.Context, registry, repository, tag string, manifest interface{}, token string) (string, error) {
 	manifestData, err := json.Marshal(manifest)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// Get actual API endpoint (Docker Hub uses registry-1.docker.io)
 	apiEndpoint := getRegistryAPIEndpoint(registry)
 
-	url := fmt.Sprintf("https://%s/v2/%s/manifests/%s", apiEndpoint, repository, tag)
-	req, err := http.NewRequest("PUT", url, bytes.NewReader(manifestData))
+	url := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", registryScheme(registry), apiEndpoint, repository, tag)
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(manifestData))
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	req.Header.Set("Content-Type", "application/vnd.docker.distribution.manifest.v2+json")
@@ -157,22 +294,23 @@ func (p *Pusher) uploadManifest(registry, repository, tag string, manifest inter
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to upload manifest: %s - %s", resp.Status, string(body))
+		return "", errcode.Errorf(errcode.FromHTTPStatus(resp.StatusCode), "failed to upload manifest: %s - %s", resp.Status, string(body))
 	}
 
-	return nil
+	// The registry names the manifest by the digest of the bytes sent
+	return calculateDigest(manifestData), nil
 }
 
-func createManifest(configDigest, layerDigest string, layerSize int64) map[string]interface{} {
+func createManifest(configDigest, layerDigest string, layerSize int64, annotations map[string]string) map[string]interface{} {
 	// Create a minimal Docker manifest v2
 	// Both config and layer blobs must be uploaded before creating the manifest
-	return map[string]interface{}{
+	imageManifest := map[string]interface{}{
 		"schemaVersion": 2,
 		"mediaType":     "application/vnd.docker.distribution.manifest.v2+json",
 		"config": map[string]interface{}{
@@ -188,4 +326,8 @@ func createManifest(configDigest, layerDigest string, layerSize int64) map[strin
 			},
 		},
 	}
+	if len(annotations) > 0 {
+		imageManifest["annotations"] = annotations
+	}
+	return imageManifest
 }
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
 )
 
 type Remover struct{}
@@ -18,7 +20,15 @@ func (r *Remover) Remove(imageRef string) error {
 	if err != nil {
 		return err
 	}
+	fl, err := lockCache(cache)
+	if err != nil {
+		return err
+	}
+	defer fl.Release()
 
+	if err := ValidateCacheRef(imageRef); err != nil {
+		return err
+	}
 	sanitized := sanitizeImageRef(imageRef)
 
 	// Check for local build first (most common after our changes)
@@ -39,7 +49,7 @@ func (r *Remover) Remove(imageRef string) error {
 		return nil
 	}
 
-	return fmt.Errorf("image not found: %s", imageRef)
+	return errcode.Errorf(errcode.NotFound, "image not found: %s", imageRef)
 }
 
 // RemoveAll deletes all cached packages (both local builds and registry pulls)
@@ -48,6 +58,11 @@ func (r *Remover) RemoveAll() error {
 	if err != nil {
 		return err
 	}
+	fl, err := lockCache(cache)
+	if err != nil {
+		return err
+	}
+	defer fl.Release()
 
 	// Remove all contents of cache directory
 	entries, err := os.ReadDir(cache)
@@ -0,0 +1,102 @@
+package docker
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/logging"
+)
+
+var (
+	// insecureRegistries are reached over plain HTTP (SetInsecureRegistries)
+	insecureRegistries = map[string]bool{}
+	// retryAttempts and retryBackoff are how registry requests are retried
+	// (SetRetryPolicy)
+	retryAttempts = 3
+	retryBackoff  = 500 * time.Millisecond
+)
+
+// SetInsecureRegistries sets the registries reached over plain HTTP instead
+// of HTTPS, such as a local registry at localhost:5000
+func SetInsecureRegistries(registries []string) {
+	insecureRegistries = make(map[string]bool, len(registries))
+	for _, r := range registries {
+		insecureRegistries[r] = true
+	}
+}
+
+// SetRetryPolicy sets how many times a registry request is tried, and how
+// long to wait before the first retry; each later wait is twice as long
+func SetRetryPolicy(attempts int, backoff time.Duration) {
+	retryAttempts, retryBackoff = attempts, backoff
+}
+
+// registryScheme returns the URL scheme of a registry's API
+func registryScheme(registry string) string {
+	if insecureRegistries[registry] {
+		return "http"
+	}
+	return "https"
+}
+
+// newRegistryClient returns an HTTP client for registry requests, which are
+// logged and retried
+func newRegistryClient() *http.Client {
+	return &http.Client{Transport: &retryTransport{base: &logging.Transport{}}}
+}
+
+// retryTransport retries requests that fail with a network error or a
+// status saying the registry is busy or briefly unavailable
+type retryTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip sends req, retrying it under the retry policy. Requests whose
+// body can't be read again (no GetBody) are sent once.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	wait := retryBackoff
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= retryAttempts || !retryable(resp, err) || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		logging.Verbosef("↻ Retrying %s %s in %s (%s)\n", req.Method, req.URL, wait, reason)
+
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		wait *= 2
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// retryable reports whether a request that got resp or err is worth retrying
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -3,12 +3,23 @@ package docker
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/filelock"
+	"github.com/aupeachmo/aigogo/pkg/userdirs"
 )
 
 type ImageMetadata struct {
@@ -17,14 +28,29 @@ type ImageMetadata struct {
 	Size      int64     `json:"size"`
 }
 
-// getCacheDir returns the cache directory for aigogo
+// cacheDir is the build/pull cache directory set by SetCacheDir
+var cacheDir string
+
+// SetCacheDir sets the build/pull cache directory, instead of the default
+// in userdirs.PackageCacheDir
+func SetCacheDir(dir string) {
+	cacheDir = dir
+}
+
+// CacheDir returns the build/pull cache directory, without creating it
+func CacheDir() (string, error) {
+	if cacheDir != "" {
+		return cacheDir, nil
+	}
+	return userdirs.PackageCacheDir()
+}
+
+// getCacheDir returns the cache directory for aigogo, creating it
 func getCacheDir() (string, error) {
-	home, err := os.UserHomeDir()
+	cache, err := CacheDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
+		return "", err
 	}
-
-	cache := filepath.Join(home, ".aigogo", "cache")
 	if err := os.MkdirAll(cache, 0755); err != nil {
 		return "", fmt.Errorf("failed to create cache directory: %w", err)
 	}
@@ -32,6 +58,22 @@ func getCacheDir() (string, error) {
 	return cache, nil
 }
 
+// LockCache locks the build/pull cache against other aigg processes
+// writing to it, for callers removing its entries themselves
+func LockCache() (*filelock.Lock, error) {
+	cache, err := CacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return lockCache(cache)
+}
+
+// lockCache locks the cache directory dir, which builds, pulls and removals
+// hold while they write to it
+func lockCache(dir string) (*filelock.Lock, error) {
+	return filelock.Acquire(dir, "the package cache")
+}
+
 // SanitizeImageRef converts an image reference to a safe filename
 func SanitizeImageRef(ref string) string {
 	// Replace special characters with underscores
@@ -40,11 +82,28 @@ func SanitizeImageRef(ref string) string {
 	return safe
 }
 
+// ValidateCacheRef checks that ref names a directory inside the cache once
+// sanitized: a reference with .. or a backslash, or one that's only dots,
+// would name the cache itself or a directory outside it
+func ValidateCacheRef(ref string) error {
+	safe := SanitizeImageRef(ref)
+	if safe == "" || strings.Trim(safe, ".") == "" || strings.Contains(safe, "..") || strings.ContainsAny(safe, `/\`) {
+		return errcode.Errorf(errcode.Usage, "invalid package reference: %q", ref)
+	}
+	return nil
+}
+
 // Keep lowercase version for internal use
 func sanitizeImageRef(ref string) string {
 	return SanitizeImageRef(ref)
 }
 
+// ParseImageRef splits an image reference into its registry, repository
+// and tag. The registry defaults to docker.io and the tag to latest.
+func ParseImageRef(ref string) (registry, repository, tag string, err error) {
+	return parseImageRef(ref)
+}
+
 // parseImageRef parses a Docker image reference
 // Format: [registry/]repository[:tag]
 // Examples:
@@ -56,15 +115,16 @@ func parseImageRef(ref string) (registry, repository, tag string, err error) {
 	registry = "docker.io"
 	tag = "latest"
 
-	// Split by ':'
-	parts := strings.Split(ref, ":")
-	if len(parts) > 2 {
-		return "", "", "", fmt.Errorf("invalid image reference: %s", ref)
-	}
-
-	if len(parts) == 2 {
+	// The tag follows the last ':' after the last '/'; an earlier ':' is
+	// a registry port, as in localhost:5000/repo:tag
+	parts := []string{ref}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		parts = []string{ref[:i], ref[i+1:]}
 		tag = parts[1]
 	}
+	if first, rest, _ := strings.Cut(parts[0], "/"); strings.Count(first, ":") > 1 || strings.Contains(rest, ":") || (rest == "" && strings.Contains(first, ":")) {
+		return "", "", "", fmt.Errorf("invalid image reference: %s", ref)
+	}
 
 	// Parse registry and repository
 	repoParts := strings.Split(parts[0], "/")
@@ -106,24 +166,24 @@ func CalculateDigest(data []byte) string {
 	return "sha256:" + hex.EncodeToString(hash[:])
 }
 
-// CalculateDirectoryDigest calculates SHA256 digest of all files in a directory
-// Files are processed in sorted order for deterministic results
+// CalculateDirectoryDigest calculates the SHA256 digest of all files in a
+// directory: of each file's slash-separated path and the SHA256 of its
+// content, in sorted path order. The .aigogo-metadata.json of local builds
+// is left out, so two builds of the same files match. Files are streamed into their hashes, not
+// read into memory, several at a time, so large packages hash quickly; the
+// digest doesn't depend on which finishes first.
 func CalculateDirectoryDigest(dir string) (string, error) {
-	h := sha256.New()
-
 	// Collect all files
 	var files []string
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() == ".aigogo-metadata.json" {
 			return err
 		}
-		if !info.IsDir() {
-			relPath, err := filepath.Rel(dir, path)
-			if err != nil {
-				return err
-			}
-			files = append(files, relPath)
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
 		}
+		files = append(files, filepath.ToSlash(relPath))
 		return nil
 	})
 	if err != nil {
@@ -131,35 +191,54 @@ func CalculateDirectoryDigest(dir string) (string, error) {
 	}
 
 	// Sort for deterministic hashing
-	sortedFiles := make([]string, len(files))
-	copy(sortedFiles, files)
-	// Simple sort
-	for i := 0; i < len(sortedFiles); i++ {
-		for j := i + 1; j < len(sortedFiles); j++ {
-			if sortedFiles[i] > sortedFiles[j] {
-				sortedFiles[i], sortedFiles[j] = sortedFiles[j], sortedFiles[i]
+	sort.Strings(files)
+
+	// Hash the files in parallel, each result in its file's slot
+	digests := make([][]byte, len(files))
+	errs := make([]error, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(runtime.NumCPU(), len(files)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				digests[i], errs[i] = hashFile(filepath.Join(dir, filepath.FromSlash(files[i])))
 			}
-		}
+		}()
 	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
 
-	// Hash each file
-	for _, file := range sortedFiles {
-		// Write filename
-		h.Write([]byte(file))
-		h.Write([]byte{0})
-
-		// Write content
-		content, err := os.ReadFile(filepath.Join(dir, file))
-		if err != nil {
-			return "", fmt.Errorf("failed to read %s: %w", file, err)
+	h := sha256.New()
+	for i, file := range files {
+		if errs[i] != nil {
+			return "", fmt.Errorf("failed to read %s: %w", file, errs[i])
 		}
-		h.Write(content)
+		h.Write([]byte(file))
 		h.Write([]byte{0})
+		h.Write(digests[i])
 	}
-
 	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
 }
 
+// hashFile returns the SHA256 of the content of the file at path
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
 // getRegistryAPIEndpoint returns the actual API endpoint for a registry
 // Docker Hub uses registry-1.docker.io for API, not docker.io
 func getRegistryAPIEndpoint(registry string) string {
@@ -210,6 +289,9 @@ func IsLocalReference(ref string) bool {
 // ImageExistsInCache checks if an image exists in the local cache
 // Checks both local build cache and registry pull cache
 func ImageExistsInCache(ref string) bool {
+	if ValidateCacheRef(ref) != nil {
+		return false
+	}
 	cacheDir, err := getCacheDir()
 	if err != nil {
 		return false
@@ -236,6 +318,9 @@ func ImageExistsInCache(ref string) bool {
 // if the image is not in the cache. Checks local build cache first, then
 // the registry pull cache.
 func GetCachePath(ref string) string {
+	if ValidateCacheRef(ref) != nil {
+		return ""
+	}
 	cacheDir, err := getCacheDir()
 	if err != nil {
 		return ""
@@ -257,3 +342,111 @@ func GetCachePath(ref string) string {
 
 	return ""
 }
+
+// ErrNotCached is returned for an image that is neither a local build nor
+// a pulled image
+var ErrNotCached = errcode.Errorf(errcode.NotFound, "image not found in local cache")
+
+// ReadCachedFile returns a file of a cached image, a local build or a
+// pulled image. file is a slash-separated path inside the package.
+func ReadCachedFile(ref, file string) ([]byte, error) {
+	file = path.Clean(file)
+	if path.IsAbs(file) || file == ".." || strings.HasPrefix(file, "../") {
+		return nil, fmt.Errorf("invalid package path: %s", file)
+	}
+	if err := ValidateCacheRef(ref); err != nil {
+		return nil, err
+	}
+
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	sanitized := sanitizeImageRef(ref)
+
+	localPath := filepath.Join(cacheDir, sanitized)
+	if _, err := os.Stat(localPath); err == nil {
+		data, err := os.ReadFile(filepath.Join(localPath, filepath.FromSlash(file)))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s not found in %s: %w", file, ref, fs.ErrNotExist)
+		}
+		return data, err
+	}
+
+	layerData, err := os.ReadFile(filepath.Join(cacheDir, "images", sanitized, "layer.tar"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ref, ErrNotCached)
+	}
+	data, ok := ReadFileFromTar(layerData, file)
+	if !ok {
+		return nil, fmt.Errorf("%s not found in %s: %w", file, ref, fs.ErrNotExist)
+	}
+	return data, nil
+}
+
+// ReadCachedLayer returns the layer of a pulled image
+func ReadCachedLayer(ref string) ([]byte, error) {
+	if err := ValidateCacheRef(ref); err != nil {
+		return nil, err
+	}
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(cacheDir, "images", sanitizeImageRef(ref), "layer.tar"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ref, ErrNotCached)
+	}
+	return data, nil
+}
+
+// ListCachedFiles returns the files of a cached image, a local build or a
+// pulled image, sorted by path
+func ListCachedFiles(ref string) ([]PackageFile, error) {
+	if err := ValidateCacheRef(ref); err != nil {
+		return nil, err
+	}
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	localPath := filepath.Join(cacheDir, sanitizeImageRef(ref))
+	if _, err := os.Stat(localPath); err != nil {
+		layer, err := ReadCachedLayer(ref)
+		if err != nil {
+			return nil, err
+		}
+		return ListTarFiles(layer)
+	}
+
+	files, err := ListDirFiles(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", ref, err)
+	}
+	return files, nil
+}
+
+// ListDirFiles returns the files of a package directory, such as a local
+// build or a store entry, sorted by path, leaving out the
+// .aigogo-metadata.json of local builds
+func ListDirFiles(dir string) ([]PackageFile, error) {
+	var files []PackageFile
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() == ".aigogo-metadata.json" {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		files = append(files, PackageFile{Path: filepath.ToSlash(rel), Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
@@ -0,0 +1,53 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCalculateDirectoryDigest(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 50; i++ {
+		write(fmt.Sprintf("lib/mod%02d.py", i), fmt.Sprintf("value = %d\n", i))
+	}
+	write("aigogo.json", "{}")
+
+	first, err := CalculateDirectoryDigest(dir)
+	if err != nil {
+		t.Fatalf("CalculateDirectoryDigest failed: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if again, err := CalculateDirectoryDigest(dir); err != nil || again != first {
+			t.Fatalf("digest changed between runs: %s, then %s (%v)", first, again, err)
+		}
+	}
+
+	// A local build's metadata isn't part of its content
+	write(".aigogo-metadata.json", `{"built_at": "now"}`)
+	if withMetadata, err := CalculateDirectoryDigest(dir); err != nil || withMetadata != first {
+		t.Errorf("the build metadata changed the digest: %s, then %s (%v)", first, withMetadata, err)
+	}
+
+	// Moving content between files changes the digest
+	write("lib/mod00.py", "value = 1\n")
+	write("lib/mod01.py", "value = 0\n")
+	if swapped, _ := CalculateDirectoryDigest(dir); swapped == first {
+		t.Error("swapping two files' content kept the digest")
+	}
+
+	if _, err := CalculateDirectoryDigest(filepath.Join(dir, "missing")); err == nil {
+		t.Error("CalculateDirectoryDigest of a missing directory succeeded")
+	}
+}
@@ -0,0 +1,146 @@
+// Package errcode classifies aigg's errors by the kind of failure, so
+// scripts can branch on aigg's exit code, or on the identifier --json
+// prints, instead of on error messages
+package errcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+)
+
+// Code is the kind of a failure; its string is the identifier --json prints
+type Code string
+
+const (
+	// Generic is any failure without a more specific code (exit code 1)
+	Generic Code = "error"
+	// Usage is a command used wrongly: unknown commands, options or
+	// arguments (exit code 2)
+	Usage Code = "usage_error"
+	// Config is an invalid configuration file or setting (exit code 3)
+	Config Code = "config_error"
+	// Auth is missing or rejected registry credentials (exit code 4)
+	Auth Code = "auth_error"
+	// Network is a registry or server that can't be reached, times out or
+	// keeps failing (exit code 5)
+	Network Code = "network_error"
+	// NotFound is a package, tag or file that doesn't exist (exit code 6)
+	NotFound Code = "not_found"
+	// Integrity is content that doesn't match its hash or checksum (exit
+	// code 7)
+	Integrity Code = "integrity_failure"
+	// Validation is a package or project that fails a check: validate, lint,
+	// licenses, doctor (exit code 8)
+	Validation Code = "validation_failure"
+	// DiffFound is a check that found differences, such as an aigogo.lock
+	// that is out of date (exit code 9)
+	DiffFound Code = "diff_found"
+	// Interrupted is an operation stopped by Ctrl-C or SIGTERM (exit code
+	// 130, as shells report a process killed by SIGINT)
+	Interrupted Code = "interrupted"
+)
+
+// exitCodes are the exit codes of the codes, in the order they're listed
+var exitCodes = []struct {
+	code Code
+	exit int
+}{
+	{Generic, 1},
+	{Usage, 2},
+	{Config, 3},
+	{Auth, 4},
+	{Network, 5},
+	{NotFound, 6},
+	{Integrity, 7},
+	{Validation, 8},
+	{DiffFound, 9},
+	{Interrupted, 130},
+}
+
+// Codes returns every code, in the order of their exit codes
+func Codes() []Code {
+	codes := make([]Code, len(exitCodes))
+	for i, e := range exitCodes {
+		codes[i] = e.code
+	}
+	return codes
+}
+
+// ExitCode returns the exit code aigg exits with for a failure of kind c
+func (c Code) ExitCode() int {
+	for _, e := range exitCodes {
+		if e.code == c {
+			return e.exit
+		}
+	}
+	return 1
+}
+
+// Error is an error with the kind of failure it is
+type Error struct {
+	Code Code
+	Err  error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Wrap returns err as a failure of kind code, or nil when err is nil
+func Wrap(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Errorf is fmt.Errorf for a failure of kind code
+func Errorf(code Code, format string, args ...interface{}) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// Of returns the kind of failure err is: Interrupted when it was canceled;
+// Network when a network error caused it, whatever it was wrapped as;
+// otherwise the code of the outermost Error it wraps, as the caller that
+// wrapped it last knows best what failed; otherwise NotFound for a missing
+// file, or Generic
+func Of(err error) Code {
+	if err == nil {
+		return ""
+	}
+	// A request canceled mid-flight is a *url.Error, which is a net.Error
+	if errors.Is(err, context.Canceled) {
+		return Interrupted
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return Network
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return NotFound
+	}
+	return Generic
+}
+
+// FromHTTPStatus returns the kind of failure an unexpected HTTP response
+// status is: Auth for 401 and 403, NotFound for 404, Network for 429 and
+// server errors, Generic otherwise
+func FromHTTPStatus(status int) Code {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return Auth
+	case status == http.StatusNotFound:
+		return NotFound
+	case status == http.StatusTooManyRequests || status >= 500:
+		return Network
+	}
+	return Generic
+}
@@ -0,0 +1,64 @@
+package errcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func TestOf(t *testing.T) {
+	netErr := &url.Error{Op: "Get", URL: "https://r.example/v2/", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+	tests := []struct {
+		err  error
+		want Code
+	}{
+		{errors.New("failed"), Generic},
+		{Errorf(Auth, "authentication required"), Auth},
+		{fmt.Errorf("failed to pull: %w", Errorf(NotFound, "no such tag")), NotFound},
+		// The outermost code wins
+		{Wrap(DiffFound, fmt.Errorf("lock: %w", Errorf(Validation, "invalid"))), DiffFound},
+		// A network error wins over what it was wrapped as
+		{Wrap(Auth, fmt.Errorf("login failed: %w", netErr)), Network},
+		{errors.Join(errors.New("a"), Errorf(Integrity, "hash mismatch")), Integrity},
+		{fmt.Errorf("failed to read manifest: %w", fs.ErrNotExist), NotFound},
+		// A canceled request is a net.Error too
+		{&url.Error{Op: "Get", URL: "https://r.example/v2/", Err: context.Canceled}, Interrupted},
+		{fmt.Errorf("push interrupted: %w", context.Canceled), Interrupted},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := Of(tt.err); got != tt.want {
+			t.Errorf("Of(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	seen := map[int]Code{}
+	for _, code := range Codes() {
+		exit := code.ExitCode()
+		if (exit < 1 || exit > 125) && code != Interrupted {
+			t.Errorf("%s exits with %d", code, exit)
+		}
+		if other, ok := seen[exit]; ok {
+			t.Errorf("%s and %s both exit with %d", code, other, exit)
+		}
+		seen[exit] = code
+	}
+	if Code("unknown").ExitCode() != 1 {
+		t.Error("an unknown code should exit with 1")
+	}
+}
+
+func TestFromHTTPStatus(t *testing.T) {
+	tests := map[int]Code{401: Auth, 403: Auth, 404: NotFound, 429: Network, 503: Network, 400: Generic}
+	for status, want := range tests {
+		if got := FromHTTPStatus(status); got != want {
+			t.Errorf("FromHTTPStatus(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Package filelock keeps aigg processes from writing the same files at
+// once: a project's aigogo.lock, the build/pull cache and the package store.
+// Its locks are advisory, so they only exclude other aigg processes, and
+// the operating system releases them when a process exits, so a killed
+// process never leaves one behind.
+package filelock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/userdirs"
+)
+
+var (
+	// Timeout is how long Acquire waits for another process to release a
+	// lock before giving up
+	Timeout = 2 * time.Minute
+	// pollInterval is how often Acquire retries a lock another process holds
+	pollInterval = 100 * time.Millisecond
+)
+
+// held is a lock this process holds, shared by every Acquire of it
+type held struct {
+	file  *os.File
+	count int
+}
+
+var (
+	mu sync.Mutex
+	// heldLocks are the locks this process holds, by lock file path. A lock
+	// excludes other processes only: goroutines of one process, such as
+	// parallel fetches, share it.
+	heldLocks = map[string]*held{}
+)
+
+// Lock is a held lock on a file or directory
+type Lock struct {
+	path string
+}
+
+// Acquire locks target, a file or directory, against other aigg processes,
+// waiting up to Timeout for one that holds it. what describes target in the
+// messages, e.g. "the package store". The lock itself is a file in locks/
+// in the cache directory, so target needn't exist.
+func Acquire(target, what string) (*Lock, error) {
+	path, err := lockPath(target)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	deadline := time.Now().Add(Timeout)
+	waiting := false
+	for {
+		mu.Lock()
+		if h, ok := heldLocks[path]; ok {
+			h.count++
+			mu.Unlock()
+			return &Lock{path: path}, nil
+		}
+		f, locked, err := tryLock(path)
+		if err != nil {
+			mu.Unlock()
+			return nil, fmt.Errorf("failed to lock %s: %w", what, err)
+		}
+		if locked {
+			heldLocks[path] = &held{file: f, count: 1}
+			mu.Unlock()
+			_ = f.Truncate(0)
+			_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+			return &Lock{path: path}, nil
+		}
+		mu.Unlock()
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("another aigogo process%s is still using %s\nTry again once it has finished", holder(path), what)
+		}
+		if !waiting {
+			waiting = true
+			fmt.Fprintf(os.Stderr, "⏳ Another aigogo process%s is using %s; waiting for it to finish...\n", holder(path), what)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Release releases the lock once every Acquire of it in this process has
+// released it
+func (l *Lock) Release() {
+	mu.Lock()
+	defer mu.Unlock()
+	h, ok := heldLocks[l.path]
+	if !ok {
+		return
+	}
+	if h.count--; h.count > 0 {
+		return
+	}
+	delete(heldLocks, l.path)
+	_ = unlock(h.file)
+	_ = h.file.Close()
+}
+
+// lockPath returns the lock file of target: locks/<name>-<hash>.lock in the
+// cache directory, named after target's base name and the hash of its
+// absolute path
+func lockPath(target string) (string, error) {
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", target, err)
+	}
+	dir, err := userdirs.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	name := strings.TrimPrefix(filepath.Base(abs), ".") + "-" + hex.EncodeToString(sum[:6]) + ".lock"
+	return filepath.Join(dir, "locks", name), nil
+}
+
+// holder returns " (pid N)" for the process holding the lock file at path,
+// or "" when it can't be read
+func holder(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (pid %d)", pid)
+}
@@ -0,0 +1,126 @@
+package filelock
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/userdirs"
+)
+
+// TestMain lets the test binary hold a lock as another process: with
+// FILELOCK_TEST_HOLD set, it locks that path, says so, and holds the lock
+// until its stdin is closed
+func TestMain(m *testing.M) {
+	if target := os.Getenv("FILELOCK_TEST_HOLD"); target != "" {
+		l, err := Acquire(target, "the test target")
+		if err != nil {
+			os.Exit(1)
+		}
+		_, _ = os.Stdout.WriteString("locked\n")
+		_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+		l.Release()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestAcquireSharedInProcess(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CACHE_HOME", "")
+	target := filepath.Join(t.TempDir(), "store")
+
+	first, err := Acquire(target, "the store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Acquire(target, "the store")
+	if err != nil {
+		t.Fatalf("second Acquire in the same process error = %v", err)
+	}
+	second.Release()
+	first.Release()
+
+	path, err := lockPath(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := heldLocks[path]; ok {
+		t.Error("lock still held after every Release")
+	}
+}
+
+func TestAcquireWaitsForOtherProcess(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CACHE_HOME", "")
+	target := filepath.Join(t.TempDir(), "aigogo.lock")
+
+	holder := exec.Command(os.Args[0], "-test.run=^$")
+	holder.Env = append(os.Environ(), "FILELOCK_TEST_HOLD="+target, "HOME="+home)
+	stdin, err := holder.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := holder.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := holder.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = holder.Process.Kill() }()
+	if line, _ := bufio.NewReader(stdout).ReadString('\n'); line != "locked\n" {
+		t.Fatalf("holder said %q", line)
+	}
+
+	defer func(timeout time.Duration) { Timeout = timeout }(Timeout)
+	Timeout = 300 * time.Millisecond
+	_, err = Acquire(target, "aigogo.lock")
+	if err == nil || !strings.Contains(err.Error(), "another aigogo process (pid ") || !strings.Contains(err.Error(), "is still using aigogo.lock") {
+		t.Fatalf("Acquire() error = %v, want the holder named", err)
+	}
+
+	// Once the holder is done, the lock is free
+	_ = stdin.Close()
+	Timeout = 10 * time.Second
+	l, err := Acquire(target, "aigogo.lock")
+	if err != nil {
+		t.Fatalf("Acquire() after the holder finished error = %v", err)
+	}
+	l.Release()
+	_ = holder.Wait()
+}
+
+func TestLockPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	a, err := lockPath("/srv/one/.aigogo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := lockPath("/srv/two/.aigogo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Errorf("lockPath() = %s for two directories", a)
+	}
+	cache, err := userdirs.CacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(a) != filepath.Join(cache, "locks") || !strings.HasPrefix(cache, home) || !strings.HasPrefix(filepath.Base(a), "aigogo-") {
+		t.Errorf("lockPath() = %s", a)
+	}
+}
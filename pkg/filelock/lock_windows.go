@@ -0,0 +1,40 @@
+//go:build windows
+
+package filelock
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockOffset is where the locked byte lies, past the pid written at the
+// start of the file, which waiting processes read to name the holder
+const lockOffset = 1 << 30
+
+// tryLock opens the lock file at path and locks it exclusively with
+// LockFileEx without waiting. locked is false, and the file closed, when
+// another process holds it.
+func tryLock(path string) (f *os.File, locked bool, err error) {
+	f, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, false, err
+	}
+	ol := &windows.Overlapped{Offset: lockOffset}
+	err = windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, ol)
+	if err != nil {
+		_ = f.Close()
+		if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return f, true, nil
+}
+
+// unlock releases the lock on f
+func unlock(f *os.File) error {
+	ol := &windows.Overlapped{Offset: lockOffset}
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, ol)
+}
@@ -0,0 +1,190 @@
+// Package ghactions writes GitHub Actions workflow commands and job
+// summaries, for aigg's --output github: annotations that show on the run
+// and the pull request, collapsible log groups, and Markdown for the job's
+// summary page
+package ghactions
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Annotation levels
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNotice  = "notice"
+)
+
+// SummaryEnv names the file the runner shows as the step's job summary
+const SummaryEnv = "GITHUB_STEP_SUMMARY"
+
+// Annotation is an ::error, ::warning or ::notice workflow command, shown
+// on the file and line when they're set
+type Annotation struct {
+	Level   string
+	File    string // relative to the repository root, as GitHub resolves it
+	Line    int
+	Title   string
+	Message string
+}
+
+// String returns the annotation as a workflow command, without a newline
+func (a Annotation) String() string {
+	var props []string
+	if a.File != "" {
+		props = append(props, "file="+escapeProperty(a.File))
+		if a.Line > 0 {
+			props = append(props, fmt.Sprintf("line=%d", a.Line))
+		}
+	}
+	if a.Title != "" {
+		props = append(props, "title="+escapeProperty(a.Title))
+	}
+	command := "::" + a.Level
+	if len(props) > 0 {
+		command += " " + strings.Join(props, ",")
+	}
+	return command + "::" + escapeData(a.Message)
+}
+
+// Group returns the command that starts a collapsible group of log lines,
+// ended by EndGroup. Groups don't nest.
+func Group(title string) string {
+	return "::group::" + escapeData(title)
+}
+
+// EndGroup ends the group Group started
+const EndGroup = "::endgroup::"
+
+// escapeData escapes a command's message, so a newline or % in it doesn't
+// end or change the command
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a command property's value, in which : and , also
+// delimit
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// AppendSummary adds Markdown to the step's job summary. It does nothing
+// outside GitHub Actions, where SummaryEnv isn't set.
+func AppendSummary(markdown string) error {
+	path := os.Getenv(SummaryEnv)
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open the job summary: %w", err)
+	}
+	if !strings.HasSuffix(markdown, "\n") {
+		markdown += "\n"
+	}
+	if _, err := io.WriteString(f, markdown+"\n"); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write the job summary: %w", err)
+	}
+	return f.Close()
+}
+
+// EscapeMarkdown escapes text for a Markdown table cell or line of a job
+// summary
+func EscapeMarkdown(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "|", "\\|", "`", "\\`", "*", "\\*", "_", "\\_", "<", "&lt;", ">", "&gt;", "\n", " ").Replace(s)
+}
+
+// warningMarkers start the warning lines aigg prints
+var warningMarkers = []string{"⚠ Warning: ", "⚠️  Warning: ", "⚠️ Warning: "}
+
+// Writer writes to another writer with aigg's warning lines ("⚠ Warning:
+// ...") turned into ::warning commands, so they're annotations wherever
+// they were printed from. A line is held only while it may still become a
+// warning; anything else is written straight away.
+type Writer struct {
+	w       io.Writer
+	pending []byte // the start of a line that may be a warning
+	passing bool   // the current line isn't one, and is written as it comes
+}
+
+// NewWriter returns a Writer writing to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes p, as rewritten, and always reports all of it written unless
+// the underlying writer fails
+func (gw *Writer) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		end := bytes.IndexByte(p, '\n') + 1
+		chunk := p
+		if end > 0 {
+			chunk, p = p[:end], p[end:]
+		} else {
+			p = nil
+		}
+
+		if gw.passing {
+			if _, err := gw.w.Write(chunk); err != nil {
+				return 0, err
+			}
+			gw.passing = end == 0
+			continue
+		}
+		gw.pending = append(gw.pending, chunk...)
+		if end > 0 {
+			line := string(gw.pending)
+			gw.pending = nil
+			if _, err := io.WriteString(gw.w, rewriteWarning(line)); err != nil {
+				return 0, err
+			}
+		} else if !mayBeWarning(string(gw.pending)) {
+			data := gw.pending
+			gw.pending, gw.passing = nil, true
+			if _, err := gw.w.Write(data); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Flush writes a line held back that never ended
+func (gw *Writer) Flush() error {
+	if len(gw.pending) == 0 {
+		return nil
+	}
+	_, err := gw.w.Write(gw.pending)
+	gw.pending = nil
+	return err
+}
+
+// mayBeWarning reports whether the start of a line is, or may become, a
+// warning line
+func mayBeWarning(start string) bool {
+	start = strings.TrimLeft(start, " ")
+	for _, marker := range warningMarkers {
+		if strings.HasPrefix(start, marker) || strings.HasPrefix(marker, start) {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteWarning returns line as a ::warning command if it's a warning, and
+// as it is otherwise
+func rewriteWarning(line string) string {
+	text := strings.TrimLeft(strings.TrimRight(line, "\r\n"), " ")
+	for _, marker := range warningMarkers {
+		if message, ok := strings.CutPrefix(text, marker); ok {
+			return Annotation{Level: LevelWarning, Message: message}.String() + "\n"
+		}
+	}
+	return line
+}
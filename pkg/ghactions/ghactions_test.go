@@ -0,0 +1,78 @@
+package ghactions
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAnnotation(t *testing.T) {
+	tests := []struct {
+		a    Annotation
+		want string
+	}{
+		{Annotation{Level: LevelWarning, Message: "no README"}, "::warning::no README"},
+		{Annotation{Level: LevelError, File: "src/a,b.py", Line: 3, Title: "aigg validate: missing-dependency", Message: "50% of\nthe files"},
+			"::error file=src/a%2Cb.py,line=3,title=aigg validate%3A missing-dependency::50%25 of%0Athe files"},
+		// A line without a file means nothing to GitHub
+		{Annotation{Level: LevelNotice, Line: 3, Message: "m"}, "::notice::m"},
+	}
+	for _, tt := range tests {
+		if got := tt.a.String(); got != tt.want {
+			t.Errorf("%+v = %q, want %q", tt.a, got, tt.want)
+		}
+	}
+	if got := Group("diff a\nb"); got != "::group::diff a%0Ab" {
+		t.Errorf("Group() = %q", got)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	// Lines split across writes, warnings indented or not, and a prompt
+	// that never ends
+	for _, s := range []string{"Building...\n  ⚠", " Warning: no", " README\n⚠️  Warning: 100%\nnot ", "a warning\n", "Delete? [y/N] "} {
+		if n, err := w.Write([]byte(s)); err != nil || n != len(s) {
+			t.Fatalf("Write(%q) = %d, %v", s, n, err)
+		}
+	}
+	if want := "Building...\n::warning::no README\n::warning::100%25\nnot a warning\nDelete? [y/N] "; out.String() != want {
+		t.Errorf("wrote %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	_, _ = w.Write([]byte("\n⚠ Warn"))
+	if out.String() != "\n" {
+		t.Errorf("wrote %q before the line could be told from a warning", out.String())
+	}
+	if err := w.Flush(); err != nil || out.String() != "\n⚠ Warn" {
+		t.Errorf("Flush() wrote %q, %v", out.String(), err)
+	}
+}
+
+func TestAppendSummary(t *testing.T) {
+	t.Setenv(SummaryEnv, "")
+	if err := AppendSummary("### nothing"); err != nil {
+		t.Errorf("AppendSummary() outside GitHub Actions = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv(SummaryEnv, path)
+	for _, md := range []string{"### one", "### two\n"} {
+		if err := AppendSummary(md); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "### one\n\n### two\n\n"; string(data) != want {
+		t.Errorf("summary = %q, want %q", data, want)
+	}
+	if got := EscapeMarkdown("a|b_<c>"); got != `a\|b\_&lt;c&gt;` {
+		t.Errorf("EscapeMarkdown() = %q", got)
+	}
+}
@@ -0,0 +1,222 @@
+// Package history keeps the log of what aigg did to packages: every add,
+// install, build, push and delete, with when, by whom, on what and how it
+// ended, as JSON lines in history.jsonl in the data directory. It answers
+// "who pushed what when" on shared machines, and what ran before something
+// broke.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/filelock"
+	"github.com/aupeachmo/aigogo/pkg/userdirs"
+)
+
+// FileName is the name of the history in the data directory
+const FileName = "history.jsonl"
+
+// MaxSize is the size past which the oldest half of the history is
+// dropped, so it never grows without bound
+const MaxSize = 8 << 20
+
+// StatusOK is the status of a command that succeeded; a failed one has its
+// error's code (see errcode)
+const StatusOK = "ok"
+
+// Entry is a command in the history
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Command  string    `json:"command"`        // add, install, build, push or delete
+	Args     []string  `json:"args,omitempty"` // as given, after the global flags
+	Refs     []Ref     `json:"refs,omitempty"` // the packages it added, installed, built, pushed or deleted
+	User     string    `json:"user"`
+	Host     string    `json:"host,omitempty"`
+	Dir      string    `json:"dir,omitempty"` // the working directory
+	Status   string    `json:"status"`        // StatusOK, or the error's code
+	ExitCode int       `json:"exit_code"`
+	Error    string    `json:"error,omitempty"` // the error's first line
+	Duration float64   `json:"duration"`        // in seconds
+	Tool     string    `json:"tool"`            // "aigg/<version>"
+}
+
+// Ref is a package a command acted on, with its digest when it has one: a
+// manifest digest for pushes and deletes, the content hash otherwise
+type Ref struct {
+	Ref    string `json:"ref"`
+	Digest string `json:"digest,omitempty"`
+}
+
+// Path returns the path of the history in the data directory
+func Path() (string, error) {
+	dir, err := userdirs.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// CurrentUser returns the name of the user running aigg, for entries
+func CurrentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	for _, name := range []string{"USER", "USERNAME"} {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return "unknown"
+}
+
+// Append adds e to the history at path as one line. Other aigg processes
+// may be appending too, so the line is written with a single write to a
+// file opened for appending, and trimming holds the file's lock.
+func Append(path string, e *Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	info, statErr := f.Stat()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	if statErr == nil && info.Size() > MaxSize {
+		return trim(path)
+	}
+	return nil
+}
+
+// trim drops the oldest entries of the history at path until it's at most
+// half of MaxSize
+func trim(path string) error {
+	lock, err := filelock.Acquire(path, "the history")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(data) <= MaxSize {
+		return nil // trimmed by another process
+	}
+	keep := data[len(data)-MaxSize/2:]
+	if i := bytes.IndexByte(keep, '\n'); i >= 0 {
+		keep = keep[i+1:]
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, keep, 0644); err != nil {
+		return fmt.Errorf("failed to trim history: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to trim history: %w", err)
+	}
+	return nil
+}
+
+// Filter selects entries of the history. Its zero value selects them all.
+type Filter struct {
+	Commands []string  // any of these commands
+	Ref      string    // a ref containing this
+	User     string    // this user
+	Since    time.Time // at or after this time
+	Failed   bool      // only commands that failed
+}
+
+// Match reports whether the filter selects e
+func (f *Filter) Match(e *Entry) bool {
+	if len(f.Commands) > 0 && !contains(f.Commands, e.Command) {
+		return false
+	}
+	if f.User != "" && e.User != f.User {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if f.Failed && e.Status == StatusOK {
+		return false
+	}
+	if f.Ref != "" {
+		for _, r := range e.Refs {
+			if strings.Contains(r.Ref, f.Ref) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Read returns the entries of the history at path that f selects, oldest
+// first, and how many lines couldn't be read, such as one cut short by a
+// crash. A history that doesn't exist yet is empty.
+func Read(path string, f Filter) ([]Entry, int, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	return read(file, f)
+}
+
+func read(r io.Reader, f Filter) ([]Entry, int, error) {
+	var entries []Entry
+	skipped := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), MaxSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil || e.Command == "" {
+			skipped++
+			continue
+		}
+		if f.Match(&e) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entries, skipped, nil
+}
@@ -0,0 +1,103 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", FileName)
+	now := time.Now().UTC().Truncate(time.Second)
+	entries := []Entry{
+		{Time: now.Add(-48 * time.Hour), Command: "build", User: "ana", Status: StatusOK,
+			Refs: []Ref{{Ref: "utils:1.0.0", Digest: "sha256:aaa"}}},
+		{Time: now.Add(-time.Hour), Command: "push", User: "bo", Status: "auth_failure", ExitCode: 4,
+			Refs: []Ref{{Ref: "ghcr.io/team/utils:1.0.0"}}},
+		{Time: now, Command: "install", User: "ana", Status: StatusOK,
+			Refs: []Ref{{Ref: "ghcr.io/team/strings:2.0.0"}, {Ref: "ghcr.io/team/utils:1.0.0"}}},
+	}
+	for i := range entries {
+		if err := Append(path, &entries[i]); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	all, skipped, err := Read(path, Filter{})
+	if err != nil || skipped != 0 || len(all) != 3 {
+		t.Fatalf("Read = %d entries, %d skipped, %v", len(all), skipped, err)
+	}
+	if all[0].Command != "build" || !all[0].Time.Equal(entries[0].Time) || all[0].Refs[0].Digest != "sha256:aaa" {
+		t.Errorf("first entry = %+v", all[0])
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"commands", Filter{Commands: []string{"push", "install"}}, []string{"push", "install"}},
+		{"ref", Filter{Ref: "team/utils"}, []string{"push", "install"}},
+		{"user", Filter{User: "ana"}, []string{"build", "install"}},
+		{"since", Filter{Since: now.Add(-2 * time.Hour)}, []string{"push", "install"}},
+		{"failed", Filter{Failed: true}, []string{"push"}},
+		{"none", Filter{Ref: "other"}, nil},
+	}
+	for _, tt := range tests {
+		got, _, err := Read(path, tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var commands []string
+		for _, e := range got {
+			commands = append(commands, e.Command)
+		}
+		if strings.Join(commands, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s: got %v, want %v", tt.name, commands, tt.want)
+		}
+	}
+}
+
+func TestReadSkipsBadLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	data := `{"time":"2026-01-02T03:04:05Z","command":"build","user":"ana","status":"ok","exit_code":0,"duration":0.1,"tool":"aigg/dev"}
+
+{"time":"2026-01-02T03:05:00Z","command":"pu`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entries, skipped, err := Read(path, Filter{})
+	if err != nil || len(entries) != 1 || skipped != 1 {
+		t.Errorf("Read = %v, %d skipped, %v; want one entry and one skipped line", entries, skipped, err)
+	}
+
+	entries, skipped, err = Read(filepath.Join(t.TempDir(), FileName), Filter{})
+	if err != nil || entries != nil || skipped != 0 {
+		t.Errorf("Read of a missing history = %v, %d, %v", entries, skipped, err)
+	}
+}
+
+func TestTrim(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	line := `{"command":"build","args":["` + strings.Repeat("x", 1000) + `"]}` + "\n"
+	if err := os.WriteFile(path, []byte(strings.Repeat(line, MaxSize/len(line)+1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	last := &Entry{Command: "push", Status: StatusOK}
+	if err := Append(path, last); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > MaxSize/2 {
+		t.Errorf("history is %d bytes after trimming, want at most %d", info.Size(), MaxSize/2)
+	}
+	entries, skipped, err := Read(path, Filter{})
+	if err != nil || skipped != 0 || len(entries) == 0 || entries[len(entries)-1].Command != "push" {
+		t.Errorf("after trimming: %d entries, %d skipped, %v; want whole lines ending with the push", len(entries), skipped, err)
+	}
+}
@@ -0,0 +1,199 @@
+package imports
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+const (
+	// RustCratesDir is the directory holding a crate per Rust package, named
+	// after the crate, e.g. .aigogo/imports/rust/my-utils
+	RustCratesDir = "rust"
+	// cargoBegin and cargoEnd delimit the section of the project's
+	// Cargo.toml that aigg install maintains
+	cargoBegin = "# aigogo:begin — managed by aigg install, do not edit"
+	cargoEnd   = "# aigogo:end"
+)
+
+// cargoVersionPattern matches the semver versions Cargo accepts for a package
+var cargoVersionPattern = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+([-+][0-9A-Za-z.+-]+)?$`)
+
+// RustCrateName returns the crate a Rust package is used as: the package
+// name its own Cargo.toml declares, or the aigogo package name
+func RustCrateName(name, storePath string) string {
+	var cargo struct {
+		Package struct {
+			Name string `toml:"name"`
+		} `toml:"package"`
+	}
+	if _, err := toml.DecodeFile(filepath.Join(packageFilesDir(storePath), "Cargo.toml"), &cargo); err == nil && cargo.Package.Name != "" {
+		return cargo.Package.Name
+	}
+	return name
+}
+
+// createRustCrate links a Rust package as a crate under
+// .aigogo/imports/rust/. A package that ships a Cargo.toml is linked as a
+// directory; otherwise a real directory with file symlinks gets a generated
+// Cargo.toml with the package's version, its runtime dependencies and its
+// library root (see rustLibPath).
+func (m *SetupManager) createRustCrate(name, storePath string) error {
+	crate := RustCrateName(name, storePath)
+	if _, err := os.Stat(filepath.Join(packageFilesDir(storePath), "Cargo.toml")); err == nil {
+		return m.createDirLink(m.GetRustCratesPath(), crate, storePath)
+	}
+
+	filesDir := packageFilesDir(storePath)
+	crateDir := filepath.Join(m.GetRustCratesPath(), crate)
+	if err := m.createPackageDir(crateDir, storePath); err != nil {
+		return err
+	}
+
+	version := "0.0.0"
+	var deps []manifest.Dependency
+	if pkgManifest, err := manifest.Load(filepath.Join(storePath, "aigogo.json")); err == nil {
+		if cargoVersionPattern.MatchString(pkgManifest.Version) {
+			version = pkgManifest.Version
+		}
+		if rust := pkgManifest.ForLanguage("rust"); rust.Dependencies != nil {
+			deps = rust.Dependencies.Runtime
+		}
+	}
+
+	var content strings.Builder
+	content.WriteString("# Generated by aigogo\n\n")
+	content.WriteString("[package]\n")
+	fmt.Fprintf(&content, "name = %q\n", crate)
+	fmt.Fprintf(&content, "version = %q\n", version)
+	content.WriteString("edition = \"2021\"\n")
+	if lib := rustLibPath(filesDir, crate); lib != "" && lib != "src/lib.rs" {
+		fmt.Fprintf(&content, "\n[lib]\npath = %q\n", lib)
+	}
+	if len(deps) > 0 {
+		content.WriteString("\n[dependencies]\n")
+		for _, dep := range deps {
+			fmt.Fprintf(&content, "%s = %q\n", dep.Package, dep.Version)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(crateDir, "Cargo.toml"), []byte(content.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write Cargo.toml: %w", err)
+	}
+	return nil
+}
+
+// rustLibPath returns the library root of a package without a Cargo.toml:
+// lib.rs or src/lib.rs, else <crate>.rs or the only .rs file at the top
+// level, or "" if there is none
+func rustLibPath(filesDir, crate string) string {
+	candidates := []string{"lib.rs", "src/lib.rs", strings.ReplaceAll(crate, "-", "_") + ".rs"}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(filepath.Join(filesDir, filepath.FromSlash(candidate))); err == nil {
+			return candidate
+		}
+	}
+	matches, _ := filepath.Glob(filepath.Join(filesDir, "*.rs"))
+	if len(matches) == 1 {
+		return filepath.Base(matches[0])
+	}
+	return ""
+}
+
+// RustCrates returns the names of the installed Rust crates, sorted
+func (m *SetupManager) RustCrates() ([]string, error) {
+	entries, err := os.ReadDir(m.GetRustCratesPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list Rust crates: %w", err)
+	}
+	var crates []string
+	for _, entry := range entries {
+		if _, err := os.Stat(filepath.Join(m.GetRustCratesPath(), entry.Name(), "Cargo.toml")); err == nil {
+			crates = append(crates, entry.Name())
+		}
+	}
+	sort.Strings(crates)
+	return crates, nil
+}
+
+// UpdateCargoToml rewrites the aigogo section of the project's Cargo.toml
+// with a path dependency on each installed Rust crate, and returns the
+// crates. A crate the Cargo.toml already depends on outside the section,
+// e.g. from crates.io, is patched instead, so every use of it gets the
+// installed copy. The section is removed when there are no crates.
+func (m *SetupManager) UpdateCargoToml() ([]string, error) {
+	crates, err := m.RustCrates()
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(m.projectDir, "Cargo.toml")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Cargo.toml: %w", err)
+	}
+
+	var kept []string
+	hadSection, inSection := false, false
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == cargoBegin:
+			hadSection, inSection = true, true
+			continue
+		case inSection:
+			if trimmed == cargoEnd {
+				inSection = false
+			}
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if inSection {
+		return nil, fmt.Errorf("Cargo.toml has %q without %q\nRemove the incomplete aigogo section and run 'aigg install' again", cargoBegin, cargoEnd)
+	}
+	if !hadSection && len(crates) == 0 {
+		return nil, nil
+	}
+	for len(kept) > 0 && strings.TrimSpace(kept[len(kept)-1]) == "" {
+		kept = kept[:len(kept)-1]
+	}
+	content := strings.Join(kept, "\n") + "\n"
+
+	var project struct {
+		Dependencies map[string]interface{} `toml:"dependencies"`
+	}
+	if _, err := toml.Decode(content, &project); err != nil {
+		return nil, fmt.Errorf("failed to parse Cargo.toml: %w", err)
+	}
+
+	if len(crates) > 0 {
+		var section strings.Builder
+		section.WriteString("\n" + cargoBegin + "\n")
+		for _, crate := range crates {
+			table := "dependencies"
+			if _, ok := project.Dependencies[crate]; ok {
+				table = "patch.crates-io"
+			}
+			target := filepath.ToSlash(filepath.Join(ImportsDir, "imports", RustCratesDir, crate))
+			fmt.Fprintf(&section, "[%s.%s]\npath = %q\n\n", table, crate, target)
+		}
+		section.WriteString(cargoEnd + "\n")
+		content += section.String()
+	}
+
+	if content != string(data) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write Cargo.toml: %w", err)
+		}
+	}
+	return crates, nil
+}
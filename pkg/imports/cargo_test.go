@@ -0,0 +1,150 @@
+package imports
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCreatePackageLinkRust(t *testing.T) {
+	mgr, err := NewSetupManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a Cargo.toml, the crate is named after the package
+	plain := makeStoreFiles(t, map[string]string{"lib.rs": "pub mod util;\n", "util.rs": ""})
+	manifestJSON := `{"name": "mathx", "version": "1.2.0", "language": {"name": "rust", "version": "1.70"},
+		"dependencies": {"runtime": [{"package": "serde", "version": "1.0"}]}}`
+	if err := os.WriteFile(filepath.Join(plain, "aigogo.json"), []byte(manifestJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.CreatePackageLink("mathx", "rust", plain, nil); err != nil {
+		t.Fatalf("CreatePackageLink failed: %v", err)
+	}
+	crateDir := filepath.Join(mgr.GetRustCratesPath(), "mathx")
+	cargo, err := os.ReadFile(filepath.Join(crateDir, "Cargo.toml"))
+	if err != nil {
+		t.Fatalf("Cargo.toml not generated: %v", err)
+	}
+	for _, want := range []string{`name = "mathx"`, `version = "1.2.0"`, "[lib]\npath = \"lib.rs\"", `serde = "1.0"`} {
+		if !strings.Contains(string(cargo), want) {
+			t.Errorf("Cargo.toml missing %q:\n%s", want, cargo)
+		}
+	}
+
+	// A package's own Cargo.toml names the crate
+	shipped := makeStoreFiles(t, map[string]string{"Cargo.toml": "[package]\nname = \"text-utils\"\nversion = \"0.3.0\"\n", "src/lib.rs": ""})
+	if got := RustCrateName("textutils", shipped); got != "text-utils" {
+		t.Errorf("RustCrateName() = %q, want text-utils", got)
+	}
+	if err := mgr.CreatePackageLink("textutils", "rust", shipped, nil); err != nil {
+		t.Fatalf("CreatePackageLink failed: %v", err)
+	}
+	crates, err := mgr.RustCrates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"mathx", "text-utils"}; !reflect.DeepEqual(crates, want) {
+		t.Errorf("RustCrates() = %v, want %v", crates, want)
+	}
+
+	if err := mgr.RemovePackageLink("mathx", "rust"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(crateDir); !os.IsNotExist(err) {
+		t.Error("RemovePackageLink should remove the crate directory")
+	}
+}
+
+func TestRustLibPath(t *testing.T) {
+	tests := []struct {
+		files []string
+		want  string
+	}{
+		{[]string{"lib.rs", "util.rs"}, "lib.rs"},
+		{[]string{"src/lib.rs", "src/util.rs"}, "src/lib.rs"},
+		{[]string{"my_crate.rs", "other.rs"}, "my_crate.rs"},
+		{[]string{"only.rs"}, "only.rs"},
+		{[]string{"a.rs", "b.rs"}, ""},
+	}
+	for _, tt := range tests {
+		files := make(map[string]string)
+		for _, f := range tt.files {
+			files[f] = ""
+		}
+		filesDir := filepath.Join(makeStoreFiles(t, files), "files")
+		if got := rustLibPath(filesDir, "my-crate"); got != tt.want {
+			t.Errorf("rustLibPath(%v) = %q, want %q", tt.files, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateCargoToml(t *testing.T) {
+	projectDir := t.TempDir()
+	mgr, err := NewSetupManager(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cargoPath := filepath.Join(projectDir, "Cargo.toml")
+	original := "[package]\nname = \"app\"\nversion = \"0.1.0\"\n\n[dependencies]\ntext-utils = \"0.3\"\n"
+	if err := os.WriteFile(cargoPath, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// No crates and no section leaves Cargo.toml alone
+	if _, err := mgr.UpdateCargoToml(); err != nil {
+		t.Fatalf("UpdateCargoToml failed: %v", err)
+	}
+	if data, _ := os.ReadFile(cargoPath); string(data) != original {
+		t.Errorf("Cargo.toml changed without Rust crates:\n%s", data)
+	}
+
+	for _, crate := range []string{"mathx", "text-utils"} {
+		dir := filepath.Join(mgr.GetRustCratesPath(), crate)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "Cargo.toml"), []byte("[package]\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Writing twice gives the same file
+	for i := 0; i < 2; i++ {
+		if _, err := mgr.UpdateCargoToml(); err != nil {
+			t.Fatalf("UpdateCargoToml failed: %v", err)
+		}
+	}
+	data, err := os.ReadFile(cargoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// text-utils is already a dependency, so it is patched
+	want := original + `
+# aigogo:begin — managed by aigg install, do not edit
+[dependencies.mathx]
+path = ".aigogo/imports/rust/mathx"
+
+[patch.crates-io.text-utils]
+path = ".aigogo/imports/rust/text-utils"
+
+# aigogo:end
+`
+	if string(data) != want {
+		t.Errorf("Cargo.toml =\n%s\nwant\n%s", data, want)
+	}
+
+	// Without crates the section is removed
+	if err := os.RemoveAll(mgr.GetRustCratesPath()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.UpdateCargoToml(); err != nil {
+		t.Fatalf("UpdateCargoToml failed: %v", err)
+	}
+	if data, _ := os.ReadFile(cargoPath); string(data) != original {
+		t.Errorf("Cargo.toml after removing the crates =\n%s\nwant\n%s", data, original)
+	}
+}
@@ -0,0 +1,245 @@
+package imports
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+const (
+	// GoModulesDir is the directory holding a module per Go package, at its
+	// module path, e.g. .aigogo/imports/go/aigogo/my-utils
+	GoModulesDir = "go"
+	// GoModulePrefix is the module path prefix of Go packages that don't
+	// ship a go.mod of their own
+	GoModulePrefix = "aigogo"
+	// goModBegin and goModEnd delimit the block of the project's go.mod
+	// that aigg install maintains
+	goModBegin = "// aigogo:begin — managed by aigg install, do not edit"
+	goModEnd   = "// aigogo:end"
+)
+
+var (
+	// goVersionPattern matches go directive versions, e.g. 1.21 or 1.22.3
+	goVersionPattern = regexp.MustCompile(`^1\.[0-9]+(\.[0-9]+)?$`)
+	// goMajorSuffix matches the /vN element that ends the path of a module
+	// at major version 2 or later
+	goMajorSuffix = regexp.MustCompile(`/v([2-9]|[1-9][0-9]+)$`)
+)
+
+// GoModulePath returns the module path a Go package is imported under: the
+// module its own go.mod declares, or aigogo/<name>
+func GoModulePath(name, storePath string) string {
+	if module := goModModule(filepath.Join(packageFilesDir(storePath), "go.mod")); module != "" {
+		return module
+	}
+	return GoModulePrefix + "/" + name
+}
+
+// goModModule returns the module path declared by a go.mod, or "" when
+// there is no go.mod or it has no module directive
+func goModModule(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+// createGoModule links a Go package at its module path under
+// .aigogo/imports/go/. A package that ships a go.mod is linked as a
+// directory; otherwise a real directory with file symlinks gets a generated
+// go.mod declaring aigogo/<name>, the Go version and the runtime
+// dependencies from the package's aigogo.json.
+func (m *SetupManager) createGoModule(name, storePath string) error {
+	module := GoModulePath(name, storePath)
+	modDir := filepath.Join(m.GetGoModulesPath(), filepath.FromSlash(module))
+	if module != GoModulePrefix+"/"+name {
+		return m.createDirLink(filepath.Dir(modDir), filepath.Base(modDir), storePath)
+	}
+
+	if err := m.createPackageDir(modDir, storePath); err != nil {
+		return err
+	}
+
+	var content strings.Builder
+	content.WriteString("// Generated by aigogo\n\n")
+	fmt.Fprintf(&content, "module %s\n", module)
+	if pkgManifest, err := manifest.Load(filepath.Join(storePath, "aigogo.json")); err == nil {
+		pkgManifest = pkgManifest.ForLanguage("go")
+		if goVersionPattern.MatchString(pkgManifest.Language.Version) {
+			fmt.Fprintf(&content, "\ngo %s\n", pkgManifest.Language.Version)
+		}
+		// Requirements need a module version; ranges can't be written
+		var requires []string
+		if pkgManifest.Dependencies != nil {
+			for _, dep := range pkgManifest.Dependencies.Runtime {
+				if strings.HasPrefix(dep.Version, "v") {
+					requires = append(requires, fmt.Sprintf("\t%s %s\n", dep.Package, dep.Version))
+				}
+			}
+		}
+		if len(requires) > 0 {
+			content.WriteString("\nrequire (\n" + strings.Join(requires, "") + ")\n")
+		}
+	}
+	if err := os.WriteFile(filepath.Join(modDir, "go.mod"), []byte(content.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write go.mod: %w", err)
+	}
+	return nil
+}
+
+// GoModules returns the module paths of the installed Go packages, sorted
+func (m *SetupManager) GoModules() ([]string, error) {
+	var modules []string
+	var walk func(dir, module string) error
+	walk = func(dir, module string) error {
+		if module != "" {
+			if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+				modules = append(modules, module)
+				return nil
+			}
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to list Go modules: %w", err)
+		}
+		for _, entry := range entries {
+			// Module roots may be links, which ReadDir doesn't follow
+			path := filepath.Join(dir, entry.Name())
+			if info, err := os.Stat(path); err != nil || !info.IsDir() {
+				continue
+			}
+			if err := walk(path, strings.TrimPrefix(module+"/"+entry.Name(), "/")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(m.GetGoModulesPath(), ""); err != nil {
+		return nil, err
+	}
+	sort.Strings(modules)
+	return modules, nil
+}
+
+// UpdateGoMod rewrites the aigogo block of the project's go.mod with a
+// require and a replace directive for each installed Go module, pointing it
+// at .aigogo/imports/go/<module>, and returns the modules. The block is
+// removed when there are none. A module the go.mod already requires outside
+// the block only gets the replace directive.
+func (m *SetupManager) UpdateGoMod() ([]string, error) {
+	modules, err := m.GoModules()
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(m.projectDir, "go.mod")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read go.mod: %w", err)
+	}
+
+	// Keep everything outside the block, noting what it requires
+	var kept []string
+	required := make(map[string]bool)
+	hadBlock, inBlock, inRequire := false, false, false
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == goModBegin:
+			hadBlock, inBlock = true, true
+			continue
+		case inBlock:
+			if trimmed == goModEnd {
+				inBlock = false
+			}
+			continue
+		}
+		kept = append(kept, line)
+
+		fields := strings.Fields(trimmed)
+		switch {
+		case trimmed == "require (":
+			inRequire = true
+		case inRequire && trimmed == ")":
+			inRequire = false
+		case inRequire && len(fields) >= 2:
+			required[fields[0]] = true
+		case len(fields) >= 3 && fields[0] == "require":
+			required[fields[1]] = true
+		}
+	}
+	if inBlock {
+		return nil, fmt.Errorf("go.mod has %q without %q\nRemove the incomplete aigogo block and run 'aigg install' again", goModBegin, goModEnd)
+	}
+	if !hadBlock && len(modules) == 0 {
+		return nil, nil
+	}
+	for len(kept) > 0 && strings.TrimSpace(kept[len(kept)-1]) == "" {
+		kept = kept[:len(kept)-1]
+	}
+
+	content := strings.Join(kept, "\n") + "\n"
+	if len(modules) > 0 {
+		var requires, replaces []string
+		for _, module := range modules {
+			if !required[module] {
+				requires = append(requires, module+" "+goReplacedVersion(module))
+			}
+			target := "./" + filepath.ToSlash(filepath.Join(ImportsDir, "imports", GoModulesDir, module))
+			replaces = append(replaces, module+" => "+target)
+		}
+		content += "\n" + goModBegin + "\n" +
+			goModDirective("require", requires) +
+			goModDirective("replace", replaces) +
+			goModEnd + "\n"
+	}
+
+	if content != string(data) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write go.mod: %w", err)
+		}
+	}
+	return modules, nil
+}
+
+// goModDirective formats a go.mod directive the way go mod tidy does, so
+// tidying doesn't change the block: one line for a single entry, otherwise a
+// parenthesized block, followed by a blank line
+func goModDirective(verb string, entries []string) string {
+	switch len(entries) {
+	case 0:
+		return ""
+	case 1:
+		return verb + " " + entries[0] + "\n\n"
+	}
+	return verb + " (\n\t" + strings.Join(entries, "\n\t") + "\n)\n\n"
+}
+
+// goReplacedVersion returns the version a replaced module is required at:
+// v0.0.0, or vN.0.0 for a module path ending in /vN
+func goReplacedVersion(module string) string {
+	if match := goMajorSuffix.FindStringSubmatch(module); match != nil {
+		return "v" + match[1] + ".0.0"
+	}
+	return "v0.0.0"
+}
@@ -0,0 +1,163 @@
+package imports
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCreatePackageLinkGo(t *testing.T) {
+	mgr, err := NewSetupManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a go.mod, the module is aigogo/<name> with a generated go.mod
+	plain := makeStoreFiles(t, map[string]string{"strutil.go": "package strutil\n"})
+	manifestJSON := `{"name": "strutil", "version": "1.0.0", "language": {"name": "go", "version": "1.21"},
+		"dependencies": {"runtime": [{"package": "golang.org/x/text", "version": "v0.14.0"}, {"package": "example.com/ranged", "version": ">=1.0"}]}}`
+	if err := os.WriteFile(filepath.Join(plain, "aigogo.json"), []byte(manifestJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := GoModulePath("strutil", plain); got != "aigogo/strutil" {
+		t.Errorf("GoModulePath() = %q, want aigogo/strutil", got)
+	}
+	if err := mgr.CreatePackageLink("strutil", "go", plain, nil); err != nil {
+		t.Fatalf("CreatePackageLink failed: %v", err)
+	}
+	modDir := filepath.Join(mgr.GetGoModulesPath(), "aigogo", "strutil")
+	if _, err := os.Stat(filepath.Join(modDir, "strutil.go")); err != nil {
+		t.Errorf("strutil.go not linked: %v", err)
+	}
+	goMod, err := os.ReadFile(filepath.Join(modDir, "go.mod"))
+	if err != nil {
+		t.Fatalf("go.mod not generated: %v", err)
+	}
+	for _, want := range []string{"module aigogo/strutil\n", "go 1.21\n", "\tgolang.org/x/text v0.14.0\n"} {
+		if !strings.Contains(string(goMod), want) {
+			t.Errorf("go.mod missing %q:\n%s", want, goMod)
+		}
+	}
+	if strings.Contains(string(goMod), "example.com/ranged") {
+		t.Errorf("go.mod requires a dependency without a module version:\n%s", goMod)
+	}
+
+	// A package's own go.mod names the module
+	shipped := makeStoreFiles(t, map[string]string{"go.mod": "module github.com/ex/mathx/v2\n\ngo 1.22\n", "mathx.go": "package mathx\n"})
+	if err := mgr.CreatePackageLink("mathx", "go", shipped, nil); err != nil {
+		t.Fatalf("CreatePackageLink failed: %v", err)
+	}
+	modules, err := mgr.GoModules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"aigogo/strutil", "github.com/ex/mathx/v2"}; !reflect.DeepEqual(modules, want) {
+		t.Errorf("GoModules() = %v, want %v", modules, want)
+	}
+
+	if err := mgr.RemovePackageLink("strutil", "go"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(modDir); !os.IsNotExist(err) {
+		t.Error("RemovePackageLink should remove the module directory")
+	}
+}
+
+func TestUpdateGoMod(t *testing.T) {
+	projectDir := t.TempDir()
+	mgr, err := NewSetupManager(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	goModPath := filepath.Join(projectDir, "go.mod")
+	original := "module example.com/app\n\ngo 1.24\n\nrequire github.com/ex/mathx/v2 v2.3.0\n"
+	if err := os.WriteFile(goModPath, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// No modules and no block leaves go.mod alone
+	if _, err := mgr.UpdateGoMod(); err != nil {
+		t.Fatalf("UpdateGoMod failed: %v", err)
+	}
+	if data, _ := os.ReadFile(goModPath); string(data) != original {
+		t.Errorf("go.mod changed without Go modules:\n%s", data)
+	}
+
+	for _, module := range []string{"aigogo/strutil", "github.com/ex/mathx/v2"} {
+		dir := filepath.Join(mgr.GetGoModulesPath(), filepath.FromSlash(module))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module "+module+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Writing twice gives the same file
+	for i := 0; i < 2; i++ {
+		if _, err := mgr.UpdateGoMod(); err != nil {
+			t.Fatalf("UpdateGoMod failed: %v", err)
+		}
+	}
+	data, err := os.ReadFile(goModPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// mathx is already required, so it only gets a replace directive
+	want := original + `
+// aigogo:begin — managed by aigg install, do not edit
+require aigogo/strutil v0.0.0
+
+replace (
+	aigogo/strutil => ./.aigogo/imports/go/aigogo/strutil
+	github.com/ex/mathx/v2 => ./.aigogo/imports/go/github.com/ex/mathx/v2
+)
+
+// aigogo:end
+`
+	if string(data) != want {
+		t.Errorf("go.mod =\n%s\nwant\n%s", data, want)
+	}
+
+	// Without modules the block is removed
+	if err := os.RemoveAll(mgr.GetGoModulesPath()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.UpdateGoMod(); err != nil {
+		t.Fatalf("UpdateGoMod failed: %v", err)
+	}
+	if data, _ := os.ReadFile(goModPath); string(data) != original {
+		t.Errorf("go.mod after removing the modules =\n%s\nwant\n%s", data, original)
+	}
+}
+
+func TestUpdateGoModUnterminatedBlock(t *testing.T) {
+	projectDir := t.TempDir()
+	mgr, err := NewSetupManager(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "module example.com/app\n\n" + goModBegin + "\nrequire aigogo/x v0.0.0\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "go.mod"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.UpdateGoMod(); err == nil {
+		t.Error("UpdateGoMod should fail on a block without an end marker")
+	}
+}
+
+func TestGoReplacedVersion(t *testing.T) {
+	tests := map[string]string{
+		"aigogo/strutil":         "v0.0.0",
+		"github.com/ex/mathx/v2": "v2.0.0",
+		"github.com/ex/big/v12":  "v12.0.0",
+		"github.com/ex/v1":       "v0.0.0",
+	}
+	for module, want := range tests {
+		if got := goReplacedVersion(module); got != want {
+			t.Errorf("goReplacedVersion(%q) = %q, want %q", module, got, want)
+		}
+	}
+}
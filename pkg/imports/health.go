@@ -0,0 +1,106 @@
+package imports
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LinkProblem is something wrong with an installed package that linking it
+// again repairs
+type LinkProblem struct {
+	Path   string // the link or file, relative to the project directory
+	Reason string // e.g. "dangling link to /old/home/.aigogo/store/..."
+}
+
+// generatedFiles are the files CreatePackageLink writes into package
+// directories, which differ from any file of the same name in the package
+var generatedFiles = map[string]bool{
+	"package.json": true,
+	"go.mod":       true,
+	"Cargo.toml":   true,
+	PyTypedMarker:  true,
+}
+
+// CheckPackageLink checks the installed link of the package at storePath
+// for a language, as CreatePackageLink made it: every symlink must resolve
+// into the package's files, which a pruned store entry, a moved home
+// directory or a changed store location break, and in copy mode every
+// copied file must still match the package's. A package that isn't
+// installed has no problems.
+func (m *SetupManager) CheckPackageLink(name, language, storePath string) ([]LinkProblem, error) {
+	linkPath, err := m.packageLinkPath(name, language)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(language) {
+	case "go":
+		linkPath = filepath.Join(m.GetGoModulesPath(), filepath.FromSlash(GoModulePath(name, storePath)))
+	case "rust":
+		linkPath = filepath.Join(m.GetRustCratesPath(), RustCrateName(name, storePath))
+	}
+	if _, err := os.Lstat(linkPath); err != nil {
+		return nil, nil
+	}
+
+	filesDir := packageFilesDir(storePath)
+	want, wantErr := filepath.EvalSymlinks(filesDir)
+
+	var problems []LinkProblem
+	err = filepath.Walk(linkPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel := m.relPath(path)
+		if isLink(info.Mode()) {
+			target, err := filepath.EvalSymlinks(path)
+			switch {
+			case err != nil:
+				dest, _ := os.Readlink(path)
+				problems = append(problems, LinkProblem{Path: rel, Reason: "dangling link to " + dest})
+			case wantErr == nil && target != want && !strings.HasPrefix(target, want+string(filepath.Separator)):
+				problems = append(problems, LinkProblem{Path: rel, Reason: fmt.Sprintf("links to %s instead of %s", target, want)})
+			}
+			return nil
+		}
+		if info.IsDir() || !m.copyFiles || generatedFiles[info.Name()] {
+			return nil
+		}
+
+		// A copy must match the package's file, unless install generated it
+		pkgRel, err := filepath.Rel(linkPath, path)
+		if err != nil {
+			return nil
+		}
+		if pkgRel == "." {
+			pkgRel = info.Name()
+		}
+		original, err := os.ReadFile(filepath.Join(filesDir, pkgRel))
+		if err != nil {
+			return nil
+		}
+		copied, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		if !bytes.Equal(copied, original) && !bytes.HasPrefix(copied, []byte("# Auto-generated by aigogo")) {
+			problems = append(problems, LinkProblem{Path: rel, Reason: "modified since it was installed"})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check %s: %w", m.relPath(linkPath), err)
+	}
+	return problems, nil
+}
+
+// relPath returns path relative to the project directory, or as it is
+// when it is outside
+func (m *SetupManager) relPath(path string) string {
+	if rel, err := filepath.Rel(m.projectDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,88 @@
+package imports
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func TestCheckPackageLink(t *testing.T) {
+	mgr, err := NewSetupManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	storePath := makeStoreFiles(t, map[string]string{"index.js": "module.exports = 1;\n", "utils.py": ""})
+
+	// Not installed yet
+	if problems, err := mgr.CheckPackageLink("my-utils", "python", storePath); err != nil || problems != nil {
+		t.Fatalf("CheckPackageLink() before install = %v, %v; want no problems", problems, err)
+	}
+
+	for _, lang := range []string{"python", "javascript"} {
+		if err := mgr.CreatePackageLink("my-utils", lang, storePath, nil); err != nil {
+			t.Fatalf("CreatePackageLink(%s) failed: %v", lang, err)
+		}
+		if problems, err := mgr.CheckPackageLink("my-utils", lang, storePath); err != nil || problems != nil {
+			t.Errorf("CheckPackageLink(%s) = %v, %v; want no problems", lang, problems, err)
+		}
+	}
+
+	// Pruning the store entry leaves the links dangling
+	if err := os.RemoveAll(storePath); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := mgr.CheckPackageLink("my-utils", "python", storePath)
+	if err != nil || len(problems) != 1 {
+		t.Fatalf("CheckPackageLink(python) = %v, %v; want 1 problem", problems, err)
+	}
+	if want := filepath.Join(ImportsDir, "imports", "aigogo", "my_utils"); problems[0].Path != want ||
+		!strings.HasPrefix(problems[0].Reason, "dangling link to ") {
+		t.Errorf("problem = %+v; want a dangling link at %s", problems[0], want)
+	}
+	problems, err = mgr.CheckPackageLink("my-utils", "javascript", storePath)
+	if err != nil || len(problems) != 2 {
+		t.Errorf("CheckPackageLink(javascript) = %v, %v; want 2 dangling files", problems, err)
+	}
+
+	// Links into another store entry are reported too
+	other := makeStoreFiles(t, map[string]string{"utils.py": ""})
+	if err := mgr.CreatePackageLink("my-utils", "python", other, nil); err != nil {
+		t.Fatal(err)
+	}
+	problems, err = mgr.CheckPackageLink("my-utils", "python", makeStoreFiles(t, map[string]string{"utils.py": ""}))
+	if err != nil || len(problems) != 1 || !strings.HasPrefix(problems[0].Reason, "links to ") {
+		t.Errorf("CheckPackageLink(python) = %v, %v; want a link to the wrong entry", problems, err)
+	}
+}
+
+func TestCheckPackageLinkCopyMode(t *testing.T) {
+	mgr, err := NewSetupManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr.SetInstallMode(manifest.InstallModeCopy)
+	storePath := makeStoreFiles(t, map[string]string{"index.js": "module.exports = 1;\n", "lib/util.js": ""})
+	if err := mgr.CreatePackageLink("my-utils", "javascript", storePath, nil); err != nil {
+		t.Fatal(err)
+	}
+	if problems, err := mgr.CheckPackageLink("my-utils", "javascript", storePath); err != nil || problems != nil {
+		t.Fatalf("CheckPackageLink() = %v, %v; want no problems", problems, err)
+	}
+
+	// The generated package.json isn't reported, an edited copy is
+	copied := filepath.Join(mgr.GetJavaScriptScopePath(), "my-utils", "lib", "util.js")
+	if err := os.WriteFile(copied, []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := mgr.CheckPackageLink("my-utils", "javascript", storePath)
+	if err != nil || len(problems) != 1 {
+		t.Fatalf("CheckPackageLink() = %v, %v; want 1 problem", problems, err)
+	}
+	if want := filepath.Join(ImportsDir, "imports", "@aigogo", "my-utils", "lib", "util.js"); problems[0].Path != want ||
+		problems[0].Reason != "modified since it was installed" {
+		t.Errorf("problem = %+v; want %s modified", problems[0], want)
+	}
+}
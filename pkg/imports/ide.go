@@ -0,0 +1,264 @@
+package imports
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+const (
+	// VSCodeSettingsFile is the workspace settings file aigg ide setup
+	// updates, relative to the project directory
+	VSCodeSettingsFile = ".vscode/settings.json"
+	// vscodeExtraPathsKey is Pylance's list of extra import roots
+	vscodeExtraPathsKey = "python.analysis.extraPaths"
+	// pycharmDir holds PyCharm's project configuration
+	pycharmDir = ".idea"
+)
+
+// importsRoot is the imports directory relative to the project, with forward
+// slashes as editor settings use
+var importsRoot = filepath.ToSlash(filepath.Join(ImportsDir, "imports"))
+
+// WriteVSCodeSettings adds .aigogo/imports/ to python.analysis.extraPaths in
+// .vscode/settings.json, creating the file if needed, so Pylance resolves
+// aigogo imports without relying on the interpreter's aigogo.pth. Other
+// settings keep their order; comments and trailing commas are not kept, and
+// commented reports whether there were any. changed is false when the path
+// was already listed and the file was left alone.
+func (m *SetupManager) WriteVSCodeSettings() (changed, commented bool, err error) {
+	path := filepath.Join(m.projectDir, filepath.FromSlash(VSCodeSettingsFile))
+
+	settings := &orderedObject{values: map[string]json.RawMessage{}}
+	if data, err := os.ReadFile(path); err == nil {
+		data, commented, err = manifest.StripJSONC(data)
+		if err == nil {
+			settings, err = parseOrderedObject(data)
+		}
+		if err != nil {
+			return false, false, fmt.Errorf("failed to parse %s: %w", VSCodeSettingsFile, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return false, false, fmt.Errorf("failed to read %s: %w", VSCodeSettingsFile, err)
+	}
+
+	var extraPaths []string
+	if raw, ok := settings.values[vscodeExtraPathsKey]; ok {
+		if err := json.Unmarshal(raw, &extraPaths); err != nil {
+			return false, false, fmt.Errorf("%s in %s is not a list of paths: %w", vscodeExtraPathsKey, VSCodeSettingsFile, err)
+		}
+	}
+	for _, p := range extraPaths {
+		if strings.TrimSuffix(strings.TrimPrefix(p, "./"), "/") == importsRoot {
+			return false, false, nil
+		}
+	}
+	if err := settings.set(vscodeExtraPathsKey, append(extraPaths, importsRoot)); err != nil {
+		return false, false, err
+	}
+
+	data, err := settings.marshalIndent()
+	if err != nil {
+		return false, false, fmt.Errorf("failed to marshal %s: %w", VSCodeSettingsFile, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, false, fmt.Errorf("failed to create %s: %w", filepath.Dir(VSCodeSettingsFile), err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return false, false, fmt.Errorf("failed to write %s: %w", VSCodeSettingsFile, err)
+	}
+	return true, commented, nil
+}
+
+// orderedObject is a JSON object that keeps the order of its keys, so a
+// file people edit is rewritten with only the settings aigg changed
+type orderedObject struct {
+	keys   []string
+	values map[string]json.RawMessage
+}
+
+// parseOrderedObject parses a JSON object, keeping its values as they are
+func parseOrderedObject(data []byte) (*orderedObject, error) {
+	obj := &orderedObject{values: map[string]json.RawMessage{}}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return obj, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if _, ok := obj.values[key]; !ok {
+			obj.keys = append(obj.keys, key)
+		}
+		obj.values[key] = value
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// set sets key to value, adding it at the end if it is new
+func (o *orderedObject) set(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = data
+	return nil
+}
+
+// marshalIndent formats the object with two-space indentation
+func (o *orderedObject) marshalIndent() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		name, _ := json.Marshal(key)
+		buf.WriteString("\n  ")
+		buf.Write(name)
+		buf.WriteString(": ")
+		if err := json.Indent(&buf, o.values[key], "  ", "  "); err != nil {
+			return nil, err
+		}
+	}
+	if len(o.keys) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
+}
+
+var (
+	// pycharmContentOpen and pycharmContentEmpty match a module's content
+	// root for the project directory, with and without child elements
+	pycharmContentOpen  = regexp.MustCompile(`<content url="file://\$MODULE_DIR\$">`)
+	pycharmContentEmpty = regexp.MustCompile(`( *)<content url="file://\$MODULE_DIR\$" */>`)
+)
+
+// pycharmSourceFolder marks .aigogo/imports/ as a source root
+var pycharmSourceFolder = `<sourceFolder url="file://$MODULE_DIR$/` + importsRoot + `" isTestSource="false" />`
+
+// WritePyCharmModule marks .aigogo/imports/ as a source root of the
+// project's PyCharm module, so PyCharm (and other IntelliJ IDEs) resolve
+// aigogo imports. The module is the .iml in .idea/; a project without .idea/
+// gets a module file and a modules.xml listing it. It returns the path of
+// the module file, relative to the project, and whether it was changed.
+func (m *SetupManager) WritePyCharmModule() (string, bool, error) {
+	ideaDir := filepath.Join(m.projectDir, pycharmDir)
+	modules, err := filepath.Glob(filepath.Join(ideaDir, "*.iml"))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to find PyCharm modules: %w", err)
+	}
+
+	if len(modules) == 0 {
+		if _, err := os.Stat(filepath.Join(ideaDir, "modules.xml")); err == nil {
+			return "", false, fmt.Errorf("%s/modules.xml lists modules outside %s/; mark %s as a sources root in PyCharm instead", pycharmDir, pycharmDir, importsRoot)
+		}
+		return m.createPyCharmModule(ideaDir)
+	}
+	if len(modules) > 1 {
+		return "", false, fmt.Errorf("%s/ has %d modules; mark %s as a sources root of the right one in PyCharm instead", pycharmDir, len(modules), importsRoot)
+	}
+
+	path := modules[0]
+	rel := filepath.ToSlash(filepath.Join(pycharmDir, filepath.Base(path)))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s: %w", rel, err)
+	}
+	content := string(data)
+	if strings.Contains(content, `"file://$MODULE_DIR$/`+importsRoot+`"`) {
+		return rel, false, nil
+	}
+
+	switch {
+	case pycharmContentOpen.MatchString(content):
+		loc := pycharmContentOpen.FindStringIndex(content)
+		indent := lineIndent(content, loc[0])
+		content = content[:loc[1]] + "\n" + indent + "  " + pycharmSourceFolder + content[loc[1]:]
+	case pycharmContentEmpty.MatchString(content):
+		match := pycharmContentEmpty.FindStringSubmatch(content)
+		indent := match[1]
+		content = strings.Replace(content, match[0],
+			indent+`<content url="file://$MODULE_DIR$">`+"\n"+
+				indent+"  "+pycharmSourceFolder+"\n"+
+				indent+"</content>", 1)
+	default:
+		return "", false, fmt.Errorf("%s has no content root for the project directory; mark %s as a sources root in PyCharm instead", rel, importsRoot)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", false, fmt.Errorf("failed to write %s: %w", rel, err)
+	}
+	return rel, true, nil
+}
+
+// createPyCharmModule writes .idea/<project>.iml with .aigogo/imports/ as a
+// source root, and the modules.xml that makes it the project's module
+func (m *SetupManager) createPyCharmModule(ideaDir string) (string, bool, error) {
+	name := filepath.Base(m.projectDir)
+	rel := filepath.ToSlash(filepath.Join(pycharmDir, name+".iml"))
+
+	module := `<?xml version="1.0" encoding="UTF-8"?>
+<module type="PYTHON_MODULE" version="4">
+  <component name="NewModuleRootManager">
+    <content url="file://$MODULE_DIR$">
+      ` + pycharmSourceFolder + `
+    </content>
+    <orderEntry type="inheritedJdk" />
+    <orderEntry type="sourceFolder" forTests="false" />
+  </component>
+</module>
+`
+	modules := `<?xml version="1.0" encoding="UTF-8"?>
+<project version="4">
+  <component name="ProjectModuleManager">
+    <modules>
+      <module fileurl="file://$PROJECT_DIR$/` + rel + `" filepath="$PROJECT_DIR$/` + rel + `" />
+    </modules>
+  </component>
+</project>
+`
+	if err := os.MkdirAll(ideaDir, 0755); err != nil {
+		return "", false, fmt.Errorf("failed to create %s: %w", pycharmDir, err)
+	}
+	if err := os.WriteFile(filepath.Join(m.projectDir, filepath.FromSlash(rel)), []byte(module), 0644); err != nil {
+		return "", false, fmt.Errorf("failed to write %s: %w", rel, err)
+	}
+	if err := os.WriteFile(filepath.Join(ideaDir, "modules.xml"), []byte(modules), 0644); err != nil {
+		return "", false, fmt.Errorf("failed to write %s/modules.xml: %w", pycharmDir, err)
+	}
+	return rel, true, nil
+}
+
+// lineIndent returns the spaces and tabs that start the line holding offset
+func lineIndent(content string, offset int) string {
+	start := strings.LastIndex(content[:offset], "\n") + 1
+	end := start
+	for end < offset && (content[end] == ' ' || content[end] == '\t') {
+		end++
+	}
+	return content[start:end]
+}
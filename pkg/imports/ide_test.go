@@ -0,0 +1,128 @@
+package imports
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteVSCodeSettings(t *testing.T) {
+	projectDir := t.TempDir()
+	mgr, err := NewSetupManager(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(projectDir, ".vscode", "settings.json")
+
+	// Without a settings file one is created
+	changed, _, err := mgr.WriteVSCodeSettings()
+	if err != nil || !changed {
+		t.Fatalf("WriteVSCodeSettings() = %v, %v; want a change", changed, err)
+	}
+	data, _ := os.ReadFile(path)
+	if want := "{\n  \"python.analysis.extraPaths\": [\n    \".aigogo/imports\"\n  ]\n}\n"; string(data) != want {
+		t.Errorf("settings.json =\n%s\nwant\n%s", data, want)
+	}
+
+	// Existing settings keep their order, comments are reported
+	existing := `{
+  // formatting
+  "editor.formatOnSave": true,
+  "python.analysis.extraPaths": ["src"],
+  "files.exclude": {"**/.git": true},
+}
+`
+	if err := os.WriteFile(path, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+	changed, commented, err := mgr.WriteVSCodeSettings()
+	if err != nil || !changed || !commented {
+		t.Fatalf("WriteVSCodeSettings() = %v, %v, %v; want a change to a commented file", changed, commented, err)
+	}
+	data, _ = os.ReadFile(path)
+	want := `{
+  "editor.formatOnSave": true,
+  "python.analysis.extraPaths": [
+    "src",
+    ".aigogo/imports"
+  ],
+  "files.exclude": {
+    "**/.git": true
+  }
+}
+`
+	if string(data) != want {
+		t.Errorf("settings.json =\n%s\nwant\n%s", data, want)
+	}
+
+	// Running again changes nothing
+	if changed, _, err := mgr.WriteVSCodeSettings(); err != nil || changed {
+		t.Errorf("second WriteVSCodeSettings() = %v, %v; want no change", changed, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"python.analysis.extraPaths": "src"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mgr.WriteVSCodeSettings(); err == nil {
+		t.Error("WriteVSCodeSettings should fail when extraPaths isn't a list")
+	}
+}
+
+func TestWritePyCharmModule(t *testing.T) {
+	// Without .idea/ a module and modules.xml are created
+	projectDir := filepath.Join(t.TempDir(), "myproj")
+	mgr, err := NewSetupManager(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	module, changed, err := mgr.WritePyCharmModule()
+	if err != nil || !changed || module != ".idea/myproj.iml" {
+		t.Fatalf("WritePyCharmModule() = %q, %v, %v", module, changed, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(projectDir, ".idea", "myproj.iml"))
+	if !strings.Contains(string(data), `<sourceFolder url="file://$MODULE_DIR$/.aigogo/imports" isTestSource="false" />`) {
+		t.Errorf("module has no source folder:\n%s", data)
+	}
+	data, _ = os.ReadFile(filepath.Join(projectDir, ".idea", "modules.xml"))
+	if !strings.Contains(string(data), `filepath="$PROJECT_DIR$/.idea/myproj.iml"`) {
+		t.Errorf("modules.xml doesn't list the module:\n%s", data)
+	}
+	if _, changed, err := mgr.WritePyCharmModule(); err != nil || changed {
+		t.Errorf("second WritePyCharmModule() = %v, %v; want no change", changed, err)
+	}
+
+	// An existing module's content root gets the source folder
+	tests := map[string]struct{ content, want string }{
+		"empty content root": {
+			content: "  <component name=\"NewModuleRootManager\">\n    <content url=\"file://$MODULE_DIR$\" />\n  </component>\n",
+			want:    "  <component name=\"NewModuleRootManager\">\n    <content url=\"file://$MODULE_DIR$\">\n      " + pycharmSourceFolder + "\n    </content>\n  </component>\n",
+		},
+		"content root with folders": {
+			content: "    <content url=\"file://$MODULE_DIR$\">\n      <excludeFolder url=\"file://$MODULE_DIR$/venv\" />\n    </content>\n",
+			want:    "    <content url=\"file://$MODULE_DIR$\">\n      " + pycharmSourceFolder + "\n      <excludeFolder url=\"file://$MODULE_DIR$/venv\" />\n    </content>\n",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			projectDir := t.TempDir()
+			mgr, err := NewSetupManager(projectDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(projectDir, ".idea", "app.iml")
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, changed, err := mgr.WritePyCharmModule(); err != nil || !changed {
+				t.Fatalf("WritePyCharmModule() = %v, %v", changed, err)
+			}
+			if data, _ := os.ReadFile(path); string(data) != tt.want {
+				t.Errorf("module =\n%s\nwant\n%s", data, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package imports
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ImportMapFileName is the Deno import map aigg install writes in .aigogo/
+const ImportMapFileName = "import_map.json"
+
+// importMap is the import map format Deno reads with --import-map
+type importMap struct {
+	Imports map[string]string `json:"imports"`
+}
+
+// WriteImportMap writes .aigogo/import_map.json, mapping each installed
+// JavaScript package's entry point and exports, read from the package.json
+// generated for it, to its files under .aigogo/imports/<scope>/. Packages
+// whose files are all importable by path, and those without a package.json,
+// get a "<scope>/<pkg>/" prefix entry. Paths are relative to the map, so it
+// keeps working when the project directory moves.
+func (m *SetupManager) WriteImportMap() error {
+	entries, err := os.ReadDir(m.GetJavaScriptScopePath())
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to list JavaScript packages: %w", err)
+	}
+
+	imports := make(map[string]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		specifier := m.namespace.JavaScript + "/" + name
+		dir := "./" + filepath.ToSlash(filepath.Join("imports", m.namespace.JavaScript, name))
+
+		data, err := os.ReadFile(filepath.Join(m.GetJavaScriptScopePath(), name, "package.json"))
+		if os.IsNotExist(err) {
+			imports[specifier+"/"] = dir + "/"
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to read package.json of %s: %w", specifier, err)
+		}
+		var pkg struct {
+			Exports map[string]string `json:"exports"`
+		}
+		if err := json.Unmarshal(data, &pkg); err != nil {
+			return fmt.Errorf("failed to parse package.json of %s: %w", specifier, err)
+		}
+
+		for subpath, file := range pkg.Exports {
+			switch subpath {
+			case "./package.json":
+			case ".":
+				imports[specifier] = dir + strings.TrimPrefix(file, ".")
+			case "./*":
+				imports[specifier+"/"] = dir + "/"
+			default:
+				imports[specifier+strings.TrimPrefix(subpath, ".")] = dir + strings.TrimPrefix(file, ".")
+			}
+		}
+	}
+
+	// encoding/json sorts map keys, so the file only changes with the packages
+	data, err := json.MarshalIndent(importMap{Imports: imports}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", ImportMapFileName, err)
+	}
+	data = append(data, '\n')
+	path := filepath.Join(m.projectDir, ImportsDir, ImportMapFileName)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create .aigogo directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ImportMapFileName, err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package imports
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteImportMap(t *testing.T) {
+	projectDir := t.TempDir()
+	mgr, err := NewSetupManager(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tools := makeStoreFiles(t, map[string]string{"lib/main.js": "", "bin/cli.mjs": ""})
+	if err := mgr.CreatePackageLink("tools", "javascript", tools, map[string]string{".": "lib/main.js", "cli": "bin/cli.mjs"}); err != nil {
+		t.Fatal(err)
+	}
+	utils := makeStoreFiles(t, map[string]string{"index.js": "", "lib/extra.js": ""})
+	if err := mgr.CreatePackageLink("utils", "javascript", utils, nil); err != nil {
+		t.Fatal(err)
+	}
+	// A package without a package.json is only importable by path
+	if err := os.MkdirAll(filepath.Join(mgr.GetJavaScriptScopePath(), "bare"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mgr.WriteImportMap(); err != nil {
+		t.Fatalf("WriteImportMap failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(projectDir, ImportsDir, ImportMapFileName))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", ImportMapFileName, err)
+	}
+	var got importMap
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("%s is invalid: %v", ImportMapFileName, err)
+	}
+	want := map[string]string{
+		"@aigogo/bare/":     "./imports/@aigogo/bare/",
+		"@aigogo/tools":     "./imports/@aigogo/tools/lib/main.js",
+		"@aigogo/tools/cli": "./imports/@aigogo/tools/bin/cli.mjs",
+		"@aigogo/utils":     "./imports/@aigogo/utils/index.js",
+		"@aigogo/utils/":    "./imports/@aigogo/utils/",
+	}
+	if !reflect.DeepEqual(got.Imports, want) {
+		t.Errorf("imports = %v, want %v", got.Imports, want)
+	}
+
+	// Clean removes the map with the packages
+	if err := mgr.Clean(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, ImportsDir, ImportMapFileName)); !os.IsNotExist(err) {
+		t.Errorf("%s should be removed by Clean", ImportMapFileName)
+	}
+}
+
+func TestWriteImportMapNoPackages(t *testing.T) {
+	projectDir := t.TempDir()
+	mgr, err := NewSetupManager(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.WriteImportMap(); err != nil {
+		t.Fatalf("WriteImportMap failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(projectDir, ImportsDir, ImportMapFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "{\n  \"imports\": {}\n}\n" {
+		t.Errorf("import map = %q, want an empty imports object", data)
+	}
+}
@@ -0,0 +1,89 @@
+package imports
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// symlink creates symbolic links; tests replace it to exercise the
+// fallbacks
+var symlink = os.Symlink
+
+// makeDirLink makes link point at the directory target with a symlink. Where
+// symlinks can't be created, as on Windows without Developer Mode or
+// administrator rights, a directory junction is tried, then a copy of
+// target. A relative target is relative to link's directory.
+func makeDirLink(target, link string) error {
+	err := symlink(target, link)
+	if err == nil {
+		return nil
+	}
+
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(link), target)
+	}
+	if createJunction(target, link) == nil {
+		return nil
+	}
+	if copyErr := copyDir(target, link); copyErr != nil {
+		return fmt.Errorf("failed to create symlink (%v) or copy: %w", err, copyErr)
+	}
+	return nil
+}
+
+// makeFileLink makes link point at the file target with a symlink, or where
+// symlinks can't be created, a copy of target
+func makeFileLink(target, link string) error {
+	err := symlink(target, link)
+	if err == nil {
+		return nil
+	}
+	if copyErr := copyFile(target, link); copyErr != nil {
+		return fmt.Errorf("failed to create symlink (%v) or copy: %w", err, copyErr)
+	}
+	return nil
+}
+
+// copyDir copies the directory tree src to dst
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(dest, 0755)
+		}
+		return copyFile(path, dest)
+	})
+}
+
+// copyFile copies src to dst. The copy is writable, even of a read-only
+// store file, so that it can be removed again on Windows.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm()|0200)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package imports
+
+import (
+	"errors"
+	"os"
+)
+
+// createJunction is only needed on Windows, where symlinks need privileges
+func createJunction(target, link string) error {
+	return errors.New("directory junctions are only available on Windows")
+}
+
+// isLink reports whether mode is of a symlink
+func isLink(mode os.FileMode) bool {
+	return mode&os.ModeSymlink != 0
+}
@@ -0,0 +1,186 @@
+package imports
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// withoutSymlinks makes symlink creation fail, as it does on Windows
+// without Developer Mode or administrator rights
+func withoutSymlinks(t *testing.T) {
+	t.Helper()
+	orig := symlink
+	t.Cleanup(func() { symlink = orig })
+	symlink = func(oldname, newname string) error {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: errors.New("a required privilege is not held by the client")}
+	}
+}
+
+// makeStoreFiles creates a store entry with the given files, read-only
+// like the real store
+func makeStoreFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	storePath := filepath.Join(t.TempDir(), "store", "hash")
+	for name, content := range files {
+		path := filepath.Join(storePath, "files", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0444); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return storePath
+}
+
+func TestMakeDirLinkFallback(t *testing.T) {
+	withoutSymlinks(t)
+	storePath := makeStoreFiles(t, map[string]string{"a.py": "a", "sub/b.py": "b"})
+	link := filepath.Join(t.TempDir(), "pkg")
+
+	if err := makeDirLink(filepath.Join(storePath, "files"), link); err != nil {
+		t.Fatalf("makeDirLink failed: %v", err)
+	}
+
+	for name, want := range map[string]string{"a.py": "a", "sub/b.py": "b"} {
+		data, err := os.ReadFile(filepath.Join(link, name))
+		if err != nil {
+			t.Fatalf("%s not reachable through the link: %v", name, err)
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+
+	if runtime.GOOS != "windows" {
+		// Without junctions the fallback is a copy, which must be removable
+		info, err := os.Lstat(filepath.Join(link, "a.py"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode()&0200 == 0 {
+			t.Errorf("copy of a read-only store file has mode %v, want it writable", info.Mode())
+		}
+	}
+	if err := os.RemoveAll(link); err != nil {
+		t.Errorf("RemoveAll failed: %v", err)
+	}
+}
+
+func TestMakeDirLinkRelativeTargetFallback(t *testing.T) {
+	withoutSymlinks(t)
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "imports", "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// A relative target is resolved from the link's directory
+	link := filepath.Join(dir, "node_modules")
+	if err := makeDirLink("imports", link); err != nil {
+		t.Fatalf("makeDirLink failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(link, "pkg")); err != nil {
+		t.Errorf("imports/pkg not reachable through the link: %v", err)
+	}
+}
+
+func TestMakeFileLinkFallback(t *testing.T) {
+	withoutSymlinks(t)
+	storePath := makeStoreFiles(t, map[string]string{"tokens.js": "module.exports = {}"})
+	link := filepath.Join(t.TempDir(), "tokens.js")
+
+	if err := makeFileLink(filepath.Join(storePath, "files", "tokens.js"), link); err != nil {
+		t.Fatalf("makeFileLink failed: %v", err)
+	}
+	data, err := os.ReadFile(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "module.exports = {}" {
+		t.Errorf("copy = %q", data)
+	}
+}
+
+func TestMakeFileLinkMissingTarget(t *testing.T) {
+	withoutSymlinks(t)
+	dir := t.TempDir()
+
+	if err := makeFileLink(filepath.Join(dir, "missing.js"), filepath.Join(dir, "link.js")); err == nil {
+		t.Error("Expected error when neither a symlink nor a copy can be made")
+	}
+}
+
+func TestCreatePackageLinkWithoutSymlinks(t *testing.T) {
+	withoutSymlinks(t)
+	projectDir := t.TempDir()
+	mgr, err := NewSetupManager(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		language string
+		file     string
+		linked   string // where the file is reachable in the project
+	}{
+		{"python", "utils.py", filepath.Join(mgr.GetPythonNamespacePath(), "my_utils", "utils.py")},
+		{"javascript", "index.js", filepath.Join(mgr.GetJavaScriptScopePath(), "my-utils", "index.js")},
+		{"ruby", "utils.rb", filepath.Join(mgr.GetRubyNamespacePath(), "my_utils", "utils.rb")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			storePath := makeStoreFiles(t, map[string]string{tt.file: "content"})
+
+			// Installing twice replaces the first copy
+			for i := 0; i < 2; i++ {
+				if err := mgr.CreatePackageLink("my-utils", tt.language, storePath, nil); err != nil {
+					t.Fatalf("CreatePackageLink failed: %v", err)
+				}
+			}
+			if _, err := os.Stat(tt.linked); err != nil {
+				t.Errorf("%s not installed: %v", tt.file, err)
+			}
+
+			if err := mgr.RemovePackageLink("my-utils", tt.language); err != nil {
+				t.Fatalf("RemovePackageLink failed: %v", err)
+			}
+			if _, err := os.Stat(tt.linked); !os.IsNotExist(err) {
+				t.Errorf("%s still installed after RemovePackageLink", tt.file)
+			}
+		})
+	}
+}
+
+func TestInstallRegisterScriptWithoutSymlinks(t *testing.T) {
+	withoutSymlinks(t)
+	projectDir := t.TempDir()
+	mgr, err := NewSetupManager(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storePath := makeStoreFiles(t, map[string]string{"index.js": "module.exports = {}"})
+	if err := mgr.CreatePackageLink("tokens", "javascript", storePath, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Installing twice replaces the node_modules copy
+	for i := 0; i < 2; i++ {
+		if err := InstallRegisterScript(projectDir, JavaScriptScope); err != nil {
+			t.Fatalf("InstallRegisterScript failed: %v", err)
+		}
+	}
+	nodeModules := filepath.Join(projectDir, ImportsDir, nodeModulesLink)
+	if _, err := os.Stat(filepath.Join(nodeModules, JavaScriptScope, "tokens", "index.js")); err != nil {
+		t.Errorf("package not reachable through node_modules: %v", err)
+	}
+
+	if err := RemoveRegisterScript(projectDir); err != nil {
+		t.Fatalf("RemoveRegisterScript failed: %v", err)
+	}
+	if _, err := os.Lstat(nodeModules); !os.IsNotExist(err) {
+		t.Error("node_modules still present after RemoveRegisterScript")
+	}
+}
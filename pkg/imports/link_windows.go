@@ -0,0 +1,90 @@
+//go:build windows
+
+package imports
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/sys/windows"
+)
+
+// createJunction creates link as a directory junction to target, which
+// unlike a symlink needs no special privileges. Junctions only point at
+// local directories, so a target on a network share fails.
+func createJunction(target, link string) error {
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(target, `\\`) {
+		return fmt.Errorf("junctions can't point at a network path: %s", target)
+	}
+	if err := os.Mkdir(link, 0755); err != nil {
+		return err
+	}
+	if err := setMountPoint(link, target); err != nil {
+		_ = os.Remove(link)
+		return fmt.Errorf("failed to create junction %s: %w", link, err)
+	}
+	return nil
+}
+
+// setMountPoint turns the empty directory link into a junction to the
+// absolute path target, with the FSCTL_SET_REPARSE_POINT request mklink /J
+// makes
+func setMountPoint(link, target string) error {
+	linkPtr, err := windows.UTF16PtrFromString(link)
+	if err != nil {
+		return err
+	}
+	handle, err := windows.CreateFile(linkPtr, windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING,
+		windows.FILE_FLAG_OPEN_REPARSE_POINT|windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = windows.CloseHandle(handle) }()
+
+	data := mountPointReparseData(target)
+	var returned uint32
+	return windows.DeviceIoControl(handle, windows.FSCTL_SET_REPARSE_POINT, &data[0], uint32(len(data)), nil, 0, &returned, nil)
+}
+
+// mountPointReparseData returns the REPARSE_DATA_BUFFER of a junction to
+// target: the NT path (\??\C:\...) the system follows, then the path shown
+// to users, each NUL-terminated
+func mountPointReparseData(target string) []byte {
+	substitute := utf16.Encode([]rune(`\??\` + target))
+	printName := utf16.Encode([]rune(target))
+
+	// The path buffer follows a 16-byte header: the tag, the data length,
+	// a reserved field, and the offset and length in bytes of each name
+	pathBytes := (len(substitute) + 1 + len(printName) + 1) * 2
+	data := make([]byte, 16+pathBytes)
+	binary.LittleEndian.PutUint32(data[0:], windows.IO_REPARSE_TAG_MOUNT_POINT)
+	binary.LittleEndian.PutUint16(data[4:], uint16(8+pathBytes))
+	binary.LittleEndian.PutUint16(data[8:], 0)
+	binary.LittleEndian.PutUint16(data[10:], uint16(len(substitute)*2))
+	binary.LittleEndian.PutUint16(data[12:], uint16((len(substitute)+1)*2))
+	binary.LittleEndian.PutUint16(data[14:], uint16(len(printName)*2))
+
+	offset := 16
+	for _, name := range [][]uint16{substitute, printName} {
+		for _, c := range name {
+			binary.LittleEndian.PutUint16(data[offset:], c)
+			offset += 2
+		}
+		offset += 2 // NUL
+	}
+	return data
+}
+
+// isLink reports whether mode is of a symlink or a junction, which Go
+// reports as an irregular file rather than a symlink or a directory
+func isLink(mode os.FileMode) bool {
+	return mode&os.ModeSymlink != 0 || mode&os.ModeIrregular != 0
+}
@@ -0,0 +1,94 @@
+//go:build windows
+
+package imports
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateJunction(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "store", "files")
+	if err := os.MkdirAll(filepath.Join(target, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(target, "sub", "a.py"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	link := filepath.Join(dir, "dir with spaces & symbols", "pkg")
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := createJunction(target, link); err != nil {
+		t.Fatalf("createJunction failed: %v", err)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(link, "sub", "a.py")); err != nil || string(data) != "a" {
+		t.Errorf("sub/a.py through the junction = %q, %v", data, err)
+	}
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isLink(info.Mode()) {
+		t.Errorf("isLink(%v) = false for a junction", info.Mode())
+	}
+	resolved, err := filepath.EvalSymlinks(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := filepath.EvalSymlinks(target); resolved != want {
+		t.Errorf("the junction resolves to %s, want %s", resolved, want)
+	}
+
+	// Removing the junction leaves the target
+	if err := os.RemoveAll(link); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "sub", "a.py")); err != nil {
+		t.Errorf("removing the junction removed its target: %v", err)
+	}
+}
+
+func TestCreateJunctionNetworkPath(t *testing.T) {
+	link := filepath.Join(t.TempDir(), "pkg")
+	if err := createJunction(`\\server\share\pkg`, link); err == nil {
+		t.Error("createJunction to a network path succeeded")
+	}
+	if _, err := os.Lstat(link); !os.IsNotExist(err) {
+		t.Errorf("a failed junction left %s behind: %v", link, err)
+	}
+}
+
+func TestCheckPackageLinkDanglingJunction(t *testing.T) {
+	withoutSymlinks(t)
+	mgr, err := NewSetupManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	storePath := makeStoreFiles(t, map[string]string{"utils.py": "x = 1"})
+	if err := mgr.CreatePackageLink("my-utils", "python", storePath, nil); err != nil {
+		t.Fatal(err)
+	}
+	linkPath := filepath.Join(mgr.GetPythonNamespacePath(), "my_utils")
+	if info, err := os.Lstat(linkPath); err != nil || !isLink(info.Mode()) {
+		t.Fatalf("the package wasn't linked with a junction: %v, %v", info, err)
+	}
+	if problems, err := mgr.CheckPackageLink("my-utils", "python", storePath); err != nil || len(problems) != 0 {
+		t.Errorf("CheckPackageLink of a working junction = %v, %v", problems, err)
+	}
+
+	if err := os.RemoveAll(storePath); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := mgr.CheckPackageLink("my-utils", "python", storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 {
+		t.Errorf("CheckPackageLink of a dangling junction = %v, want one problem", problems)
+	}
+}
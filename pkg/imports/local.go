@@ -0,0 +1,81 @@
+package imports
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// LocalPackagesDir holds, under .aigogo/, a store-like directory per local
+// path package, whose files/ links to the package's working directory
+const LocalPackagesDir = "local"
+
+// GetLocalPackagesPath returns the directory of local path packages,
+// .aigogo/local/
+func (m *SetupManager) GetLocalPackagesPath() string {
+	return filepath.Join(m.projectDir, ImportsDir, LocalPackagesDir)
+}
+
+// LinkLocalPackage prepares a local path package for CreatePackageLink and
+// returns the path to pass it as the store path: .aigogo/local/<name>/, with
+// aigogo.json and files/ linking to the package's working directory dir.
+// The package is then linked to its working copy, so edits to it show up in
+// the project without reinstalling.
+func (m *SetupManager) LinkLocalPackage(name, dir string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	if _, err := os.Stat(filepath.Join(absDir, "aigogo.json")); err != nil {
+		return "", fmt.Errorf("%s has no aigogo.json: %w", dir, err)
+	}
+
+	storePath := filepath.Join(m.GetLocalPackagesPath(), name)
+	if err := os.RemoveAll(storePath); err != nil {
+		return "", fmt.Errorf("failed to remove existing local package directory: %w", err)
+	}
+	if err := os.MkdirAll(storePath, 0755); err != nil {
+		return "", fmt.Errorf("failed to create local package directory: %w", err)
+	}
+	if err := makeFileLink(filepath.Join(absDir, "aigogo.json"), filepath.Join(storePath, "aigogo.json")); err != nil {
+		return "", fmt.Errorf("failed to link aigogo.json: %w", err)
+	}
+	if err := makeDirLink(absDir, filepath.Join(storePath, "files")); err != nil {
+		return "", fmt.Errorf("failed to link %s: %w", dir, err)
+	}
+	return storePath, nil
+}
+
+// packageFilesDir returns the directory holding the files of the package
+// at storePath. A local path package's files/ links to its working
+// directory, which is returned instead, so walking it finds the files.
+func packageFilesDir(storePath string) string {
+	filesDir := filepath.Join(storePath, "files")
+	if info, err := os.Lstat(filesDir); err == nil && isLink(info.Mode()) {
+		if resolved, err := filepath.EvalSymlinks(filesDir); err == nil {
+			return resolved
+		}
+	}
+	return filesDir
+}
+
+// localPackageIgnores returns the ignore rules of a local path package's
+// working directory, which holds more than the package's files (.git/,
+// node_modules/, aigogo.json), or nil for a package in the store
+func localPackageIgnores(storePath string) *manifest.IgnoreManager {
+	filesDir := packageFilesDir(storePath)
+	if filesDir == filepath.Join(storePath, "files") {
+		return nil
+	}
+	var excludes []string
+	if m, err := manifest.Load(filepath.Join(storePath, "aigogo.json")); err == nil {
+		excludes = m.Files.Exclude
+	}
+	ignore, err := manifest.NewIgnoreManager(filesDir, excludes)
+	if err != nil {
+		return nil
+	}
+	return ignore
+}
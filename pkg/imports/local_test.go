@@ -0,0 +1,91 @@
+package imports
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinkLocalPackage(t *testing.T) {
+	workDir := t.TempDir()
+	files := map[string]string{
+		"aigogo.json":         `{"name": "utils", "version": "0.1.0", "language": {"name": "javascript", "version": ">=18"}}`,
+		"index.js":            "module.exports = 1;\n",
+		"lib/helpers.js":      "module.exports = 2;\n",
+		"node_modules/dep.js": "",
+		".git/HEAD":           "ref: refs/heads/main\n",
+		"__pycache__/x.pyc":   "",
+		"scratch/notes.js":    "",
+		".aigogoignore":       "scratch/\n",
+		"lib/helpers.test.js": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(workDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mgr, err := NewSetupManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	storePath, err := mgr.LinkLocalPackage("utils", workDir)
+	if err != nil {
+		t.Fatalf("LinkLocalPackage() = %v", err)
+	}
+	if want := filepath.Join(mgr.GetLocalPackagesPath(), "utils"); storePath != want {
+		t.Errorf("LinkLocalPackage() = %q, want %q", storePath, want)
+	}
+
+	// Python links the package directory straight to the working copy
+	if err := mgr.CreatePackageLink("utils", "python", storePath, nil); err != nil {
+		t.Fatalf("CreatePackageLink(python) = %v", err)
+	}
+	target, err := os.Readlink(filepath.Join(mgr.GetPythonNamespacePath(), "utils"))
+	if err != nil || target != workDir {
+		t.Errorf("python link = %q, %v; want %q", target, err, workDir)
+	}
+
+	// JavaScript links the package's files, leaving out ignored ones
+	if err := mgr.CreatePackageLink("utils", "javascript", storePath, nil); err != nil {
+		t.Fatalf("CreatePackageLink(javascript) = %v", err)
+	}
+	pkgDir := filepath.Join(mgr.GetJavaScriptScopePath(), "utils")
+	for _, file := range []string{"index.js", "lib/helpers.js", "lib/helpers.test.js", "package.json"} {
+		if _, err := os.Stat(filepath.Join(pkgDir, filepath.FromSlash(file))); err != nil {
+			t.Errorf("%s missing: %v", file, err)
+		}
+	}
+	for _, file := range []string{"aigogo.json", "node_modules", ".git", "__pycache__", "scratch"} {
+		if _, err := os.Lstat(filepath.Join(pkgDir, file)); !os.IsNotExist(err) {
+			t.Errorf("%s should be left out", file)
+		}
+	}
+
+	// Edits to the working copy show up through the links
+	if err := os.WriteFile(filepath.Join(workDir, "index.js"), []byte("module.exports = 3;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(pkgDir, "index.js")); string(data) != "module.exports = 3;\n" {
+		t.Errorf("index.js = %q, want the edited file", data)
+	}
+
+	// Clean removes the links but never the working copy
+	if err := mgr.Clean(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(mgr.GetLocalPackagesPath()); !os.IsNotExist(err) {
+		t.Error("Clean() should remove .aigogo/local/")
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "index.js")); err != nil {
+		t.Errorf("working copy was touched: %v", err)
+	}
+
+	if _, err := mgr.LinkLocalPackage("other", t.TempDir()); err == nil {
+		t.Error("LinkLocalPackage should fail for a directory without aigogo.json")
+	}
+}
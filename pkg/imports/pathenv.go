@@ -0,0 +1,90 @@
+package imports
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// PathEnvFileName is the dotenv file, in .aigogo/, setting the path
+	// variables install couldn't configure otherwise
+	PathEnvFileName = "path.env"
+	// EnvrcFileName is the direnv snippet, in .aigogo/, adding the same
+	// directories to those variables
+	EnvrcFileName = "envrc"
+)
+
+// WritePathEnv writes .aigogo/path.env and .aigogo/envrc for the path
+// variables install couldn't configure, such as PYTHONPATH when there is no
+// Python environment to write a .pth file into, mapping each variable to the
+// directory to add. path.env sets them, for shells (set -a; . path.env),
+// docker --env-file and editors; envrc prepends the directories with
+// direnv's path_add, for a project's .envrc to source_env.
+func (m *SetupManager) WritePathEnv(vars map[string]string) error {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dotenv := "# Auto-generated by aigogo - do not edit\n"
+	envrc := "# Auto-generated by aigogo - do not edit\n" +
+		"# Enable with: echo 'source_env " + filepath.ToSlash(filepath.Join(ImportsDir, EnvrcFileName)) + "' >> .envrc && direnv allow\n"
+	for _, name := range names {
+		// Both are read from other directories, so the paths are absolute
+		dir, err := filepath.Abs(vars[name])
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", vars[name], err)
+		}
+		dotenv += name + "=" + shellQuote(dir) + "\n"
+		envrc += "path_add " + name + " " + shellQuote(dir) + "\n"
+	}
+
+	aigogoDir := filepath.Join(m.projectDir, ImportsDir)
+	if err := os.MkdirAll(aigogoDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", ImportsDir, err)
+	}
+	if err := os.WriteFile(filepath.Join(aigogoDir, PathEnvFileName), []byte(dotenv), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", PathEnvFileName, err)
+	}
+	if err := os.WriteFile(filepath.Join(aigogoDir, EnvrcFileName), []byte(envrc), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", EnvrcFileName, err)
+	}
+	return nil
+}
+
+// ReadPathEnv returns the variables .aigogo/path.env sets, mapping each to
+// its directory, or none when there's no path.env
+func (m *SetupManager) ReadPathEnv() (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join(m.projectDir, ImportsDir, PathEnvFileName))
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", PathEnvFileName, err)
+	}
+	vars := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		name, value, ok := strings.Cut(line, "=")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+		vars[name] = shellUnquote(value)
+	}
+	return vars, nil
+}
+
+// shellQuote single-quotes s for sh, which dotenv parsers read the same way
+// unless s holds a quote itself
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellUnquote reverses shellQuote
+func shellUnquote(s string) string {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "'"), "'")
+	return strings.ReplaceAll(s, `'\''`, "'")
+}
@@ -0,0 +1,50 @@
+package imports
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWritePathEnv(t *testing.T) {
+	projectDir := filepath.Join(t.TempDir(), "it's")
+	mgr, err := NewSetupManager(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	importsDir := mgr.GetImportsDir()
+	if err := mgr.WritePathEnv(map[string]string{"PYTHONPATH": importsDir, "NODE_PATH": importsDir}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Paths are absolute and quoted for sh
+	quoted := "'" + filepath.Dir(projectDir) + `/it'\''s/.aigogo/imports'`
+	data, err := os.ReadFile(filepath.Join(projectDir, ImportsDir, PathEnvFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Auto-generated by aigogo - do not edit\nNODE_PATH=" + quoted + "\nPYTHONPATH=" + quoted + "\n"
+	if string(data) != want {
+		t.Errorf("%s =\n%s\nwant\n%s", PathEnvFileName, data, want)
+	}
+
+	data, err = os.ReadFile(filepath.Join(projectDir, ImportsDir, EnvrcFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "# Auto-generated by aigogo - do not edit\n" +
+		"# Enable with: echo 'source_env .aigogo/envrc' >> .envrc && direnv allow\n" +
+		"path_add NODE_PATH " + quoted + "\npath_add PYTHONPATH " + quoted + "\n"
+	if string(data) != want {
+		t.Errorf("%s =\n%s\nwant\n%s", EnvrcFileName, data, want)
+	}
+
+	vars, err := mgr.ReadPathEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(filepath.Dir(projectDir), "it's", ".aigogo", "imports")
+	if len(vars) != 2 || vars["PYTHONPATH"] != dir || vars["NODE_PATH"] != dir {
+		t.Errorf("ReadPathEnv() = %v, want both variables set to %s", vars, dir)
+	}
+}
@@ -1,6 +1,7 @@
 package imports
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -11,33 +12,33 @@ import (
 const (
 	// pthFileName is the name of the .pth file written to site-packages
 	pthFileName = "aigogo.pth"
-	// pthLocationFile tracks where the .pth file was written
+	// pthLocationFile tracks where .pth files were written, one per line
 	pthLocationFile = ".pth-location"
 )
 
-// InstallPthFile writes an aigogo.pth file into the active Python environment's
-// site-packages directory. This is the standard mechanism (used by pip install -e)
-// for adding directories to Python's import path.
-func InstallPthFile(importsDir string) error {
-	sitePackages, err := findSitePackages()
-	if err != nil {
-		return err
-	}
-
+// InstallPthFile writes an aigogo.pth file into the site-packages directory
+// of env, found with FindPythonEnv. This is the standard mechanism (used by
+// pip install -e) for adding directories to Python's import path. The file
+// is added to the project's .pth-location, which lists every environment
+// installed into so uninstall can remove them all.
+func InstallPthFile(importsDir string, env *PythonEnv) error {
 	absImportsDir, err := filepath.Abs(importsDir)
 	if err != nil {
 		return fmt.Errorf("failed to resolve imports directory: %w", err)
 	}
 
-	pthPath := filepath.Join(sitePackages, pthFileName)
+	pthPath := filepath.Join(env.SitePackages, pthFileName)
 	if err := os.WriteFile(pthPath, []byte(absImportsDir+"\n"), 0644); err != nil {
 		return fmt.Errorf("failed to write %s: %w", pthPath, err)
 	}
 
 	// Record the .pth file location for cleanup
-	aigogoDir := filepath.Dir(importsDir) // .aigogo/
-	trackingPath := filepath.Join(aigogoDir, pthLocationFile)
-	if err := os.WriteFile(trackingPath, []byte(pthPath+"\n"), 0644); err != nil {
+	projectDir := filepath.Dir(filepath.Dir(absImportsDir)) // above .aigogo/imports
+	tracked, err := TrackedPthFiles(projectDir)
+	if err == nil {
+		err = writeTrackedPthFiles(projectDir, appendUnique(tracked, pthPath))
+	}
+	if err != nil {
 		// Non-fatal: .pth file was written successfully
 		return fmt.Errorf("warning: .pth file installed but failed to record location: %w", err)
 	}
@@ -45,55 +46,311 @@ func InstallPthFile(importsDir string) error {
 	return nil
 }
 
-// RemovePthFile reads the .pth-location tracking file and removes the .pth file
-// if it exists. projectDir is the project root (where .aigogo/ lives).
-func RemovePthFile(projectDir string) error {
-	trackingPath := filepath.Join(projectDir, ImportsDir, pthLocationFile)
-
-	data, err := os.ReadFile(trackingPath)
+// TrackedPthFiles returns the .pth files recorded in the project's
+// .pth-location, in the order they were installed
+func TrackedPthFiles(projectDir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(projectDir, ImportsDir, pthLocationFile))
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil // No tracking file, nothing to clean up
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read .pth-location: %w", err)
+	}
+	var paths []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			paths = appendUnique(paths, line)
 		}
-		return fmt.Errorf("failed to read .pth-location: %w", err)
 	}
+	return paths, nil
+}
 
-	pthPath := strings.TrimSpace(string(data))
-	if pthPath == "" {
+// writeTrackedPthFiles rewrites .pth-location, removing it when paths is empty
+func writeTrackedPthFiles(projectDir string, paths []string) error {
+	trackingPath := filepath.Join(projectDir, ImportsDir, pthLocationFile)
+	if len(paths) == 0 {
+		if err := os.Remove(trackingPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove tracking file: %w", err)
+		}
 		return nil
 	}
+	if err := os.WriteFile(trackingPath, []byte(strings.Join(paths, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write .pth-location: %w", err)
+	}
+	return nil
+}
 
-	if err := os.Remove(pthPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove %s: %w", pthPath, err)
+// UntrackPthFile drops a .pth file from the project's .pth-location
+// without removing the file
+func UntrackPthFile(projectDir, pthPath string) error {
+	tracked, err := TrackedPthFiles(projectDir)
+	if err != nil {
+		return err
 	}
+	var kept []string
+	for _, path := range tracked {
+		if path != pthPath {
+			kept = append(kept, path)
+		}
+	}
+	return writeTrackedPthFiles(projectDir, kept)
+}
 
-	// Remove tracking file
-	if err := os.Remove(trackingPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove tracking file: %w", err)
+// RemovePthFile removes every .pth file listed in the .pth-location tracking
+// file, then the tracking file. Files that fail to be removed stay listed.
+// projectDir is the project root (where .aigogo/ lives).
+func RemovePthFile(projectDir string) error {
+	tracked, err := TrackedPthFiles(projectDir)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	var kept []string
+	var errs []error
+	for _, pthPath := range tracked {
+		if err := os.Remove(pthPath); err != nil && !os.IsNotExist(err) {
+			kept = append(kept, pthPath)
+			errs = append(errs, fmt.Errorf("failed to remove %s: %w", pthPath, err))
+		}
+	}
+	if err := writeTrackedPthFiles(projectDir, kept); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// PthFile is an aigogo.pth file found by FindPthFiles
+type PthFile struct {
+	Path    string // the aigogo.pth file
+	Target  string // the imports directory it adds to Python's path
+	Tracked bool   // listed in the project's .pth-location
+	Missing bool   // tracked, but the file no longer exists
+}
+
+// Orphaned reports whether the file adds an imports directory that no
+// longer exists, as when its project was moved, deleted or uninstalled
+// without cleaning up
+func (p PthFile) Orphaned() bool {
+	if p.Missing {
+		return false
+	}
+	info, err := os.Stat(p.Target)
+	return err != nil || !info.IsDir()
+}
+
+// FindPthFiles returns the aigogo.pth files projectDir tracks and those in
+// the site-packages of every Python environment aigg install could pick
+// (see FindPythonEnv), rather than only the first. projectDir may be "" to
+// only look at the environments.
+func FindPthFiles(projectDir, python string) ([]PthFile, error) {
+	var tracked []string
+	if projectDir != "" {
+		var err error
+		if tracked, err = TrackedPthFiles(projectDir); err != nil {
+			return nil, err
+		}
+	}
+
+	isTracked := make(map[string]bool)
+	for _, path := range tracked {
+		isTracked[path] = true
+	}
+	paths := append([]string(nil), tracked...)
+	for _, sp := range candidateSitePackages(projectDir, python) {
+		paths = appendUnique(paths, filepath.Join(sp, pthFileName))
+	}
+
+	var files []PthFile
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				if isTracked[path] {
+					files = append(files, PthFile{Path: path, Tracked: true, Missing: true})
+				}
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		target, _, _ := strings.Cut(strings.TrimSpace(string(data)), "\n")
+		files = append(files, PthFile{Path: path, Target: strings.TrimSpace(target), Tracked: isTracked[path]})
+	}
+	return files, nil
+}
+
+// candidateSitePackages returns the site-packages directories of the
+// environments FindPythonEnv considers that exist, skipping the rest
+func candidateSitePackages(projectDir, python string) []string {
+	var dirs []string
+	add := func(env *PythonEnv, err error) {
+		if err == nil {
+			dirs = appendUnique(dirs, env.SitePackages)
+		}
+	}
+	if python != "" {
+		add(findOverrideEnv(python))
+	}
+	if venv := os.Getenv("VIRTUAL_ENV"); venv != "" {
+		add(newPythonEnv(PythonEnvVirtualenv, venv))
+	}
+	if prefix := os.Getenv("CONDA_PREFIX"); prefix != "" {
+		add(newPythonEnv(PythonEnvConda, prefix))
+	}
+	if projectDir != "" {
+		if info, err := os.Stat(filepath.Join(projectDir, ".venv")); err == nil && info.IsDir() {
+			add(newPythonEnv(PythonEnvProjectVenv, filepath.Join(projectDir, ".venv")))
+		}
+		if usesPoetry(projectDir) {
+			if venv := poetryEnvPath(projectDir); venv != "" {
+				add(newPythonEnv(PythonEnvPoetry, venv))
+			}
+		}
+	}
+	for _, interpreter := range []string{"python3", "python"} {
+		if sp, err := pythonSitePackages(interpreter); err == nil {
+			dirs = appendUnique(dirs, sp)
+		}
+	}
+	return dirs
+}
+
+// appendUnique appends s to list unless it is already there
+func appendUnique(list []string, s string) []string {
+	for _, existing := range list {
+		if existing == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// Kinds of Python environment FindPythonEnv can pick
+const (
+	PythonEnvOverride    = "selected" // given with --python
+	PythonEnvVirtualenv  = "virtualenv"
+	PythonEnvConda       = "conda"
+	PythonEnvProjectVenv = "project .venv"
+	PythonEnvPoetry      = "Poetry"
+	PythonEnvSystem      = "system"
+)
+
+// PythonEnv is the Python environment aigg install writes aigogo.pth into
+type PythonEnv struct {
+	Kind         string // one of the PythonEnv* kinds
+	Path         string // the environment's directory, or the interpreter for system
+	SitePackages string
 }
 
-// findSitePackages locates the site-packages directory for the active Python environment.
-func findSitePackages() (string, error) {
-	// Check $VIRTUAL_ENV first
+// poetryEnvPath returns the virtualenv Poetry manages for projectDir, or ""
+// if Poetry isn't installed or has none; tests replace it
+var poetryEnvPath = func(projectDir string) string {
+	if _, err := exec.LookPath("poetry"); err != nil {
+		return ""
+	}
+	cmd := exec.Command("poetry", "env", "info", "-p")
+	cmd.Dir = projectDir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// FindPythonEnv picks the Python environment the project's imports should
+// be installed into, the first of:
+//  1. python, from --python: an interpreter or an environment directory
+//  2. the activated virtualenv ($VIRTUAL_ENV, also set by uv and Poetry shells)
+//  3. the activated conda environment ($CONDA_PREFIX), other than base
+//  4. the project's .venv, as uv and Poetry's in-project setting create
+//  5. the virtualenv Poetry manages for a project with a poetry.lock or
+//     [tool.poetry] in pyproject.toml
+//  6. the site-packages of python3 (or python) on PATH
+func FindPythonEnv(projectDir, python string) (*PythonEnv, error) {
+	if python != "" {
+		return findOverrideEnv(python)
+	}
+
 	if venv := os.Getenv("VIRTUAL_ENV"); venv != "" {
-		return findVenvSitePackages(venv)
+		return newPythonEnv(PythonEnvVirtualenv, venv)
 	}
 
-	// Fall back to python3 sysconfig
-	return findSystemSitePackages()
+	if prefix := os.Getenv("CONDA_PREFIX"); prefix != "" && os.Getenv("CONDA_DEFAULT_ENV") != "base" {
+		return newPythonEnv(PythonEnvConda, prefix)
+	}
+
+	projectVenv := filepath.Join(projectDir, ".venv")
+	if info, err := os.Stat(projectVenv); err == nil && info.IsDir() {
+		return newPythonEnv(PythonEnvProjectVenv, projectVenv)
+	}
+
+	if usesPoetry(projectDir) {
+		if venv := poetryEnvPath(projectDir); venv != "" {
+			return newPythonEnv(PythonEnvPoetry, venv)
+		}
+	}
+
+	var errs []string
+	for _, interpreter := range []string{"python3", "python"} {
+		sp, err := pythonSitePackages(interpreter)
+		if err == nil {
+			return &PythonEnv{Kind: PythonEnvSystem, Path: interpreter, SitePackages: sp}, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return nil, fmt.Errorf("no Python environment found: %s (install python3, activate a virtualenv or pass --python)", strings.Join(errs, "; "))
+}
+
+// findOverrideEnv returns the environment given with --python: a directory
+// is an environment prefix, anything else an interpreter to ask
+func findOverrideEnv(python string) (*PythonEnv, error) {
+	if info, err := os.Stat(python); err == nil && info.IsDir() {
+		return newPythonEnv(PythonEnvOverride, python)
+	}
+	sp, err := pythonSitePackages(python)
+	if err != nil {
+		return nil, err
+	}
+	return &PythonEnv{Kind: PythonEnvOverride, Path: python, SitePackages: sp}, nil
+}
+
+// newPythonEnv returns the environment of kind rooted at dir
+func newPythonEnv(kind, dir string) (*PythonEnv, error) {
+	// The .pth location is recorded for uninstall, which may run elsewhere
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s environment path: %w", kind, err)
+	}
+	sp, err := findVenvSitePackages(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s environment %s: %w", kind, dir, err)
+	}
+	return &PythonEnv{Kind: kind, Path: dir, SitePackages: sp}, nil
+}
+
+// usesPoetry reports whether the project is managed by Poetry
+func usesPoetry(projectDir string) bool {
+	if _, err := os.Stat(filepath.Join(projectDir, "poetry.lock")); err == nil {
+		return true
+	}
+	data, err := os.ReadFile(filepath.Join(projectDir, "pyproject.toml"))
+	return err == nil && strings.Contains(string(data), "[tool.poetry")
 }
 
 // findVenvSitePackages finds site-packages inside a virtualenv by globbing for
-// the pythonX.Y directory.
+// the pythonX.Y directory, or on Windows at Lib\site-packages.
 func findVenvSitePackages(venvPath string) (string, error) {
 	pattern := filepath.Join(venvPath, "lib", "python*", "site-packages")
 	matches, err := filepath.Glob(pattern)
 	if err != nil {
 		return "", fmt.Errorf("failed to glob virtualenv site-packages: %w", err)
 	}
+	if len(matches) == 0 {
+		// Windows environments have no version directory: Lib\site-packages
+		windowsSP := filepath.Join(venvPath, "Lib", "site-packages")
+		if info, err := os.Stat(windowsSP); err == nil && info.IsDir() {
+			matches = []string{windowsSP}
+		}
+	}
 	if len(matches) == 0 {
 		return "", fmt.Errorf("no site-packages found in virtualenv %s", venvPath)
 	}
@@ -105,17 +362,22 @@ func findVenvSitePackages(venvPath string) (string, error) {
 	return sp, nil
 }
 
-// findSystemSitePackages uses python3 sysconfig to find the system site-packages.
-func findSystemSitePackages() (string, error) {
-	cmd := exec.Command("python3", "-c", "import sysconfig; print(sysconfig.get_path('purelib'))")
-	out, err := cmd.Output()
+// pythonSitePackagesScript prints the interpreter's site-packages,
+// falling back to the site module where sysconfig has no purelib path
+const pythonSitePackagesScript = `import site, sysconfig
+print(sysconfig.get_path('purelib') or site.getsitepackages()[0])`
+
+// pythonSitePackages asks a Python interpreter for its site-packages
+// directory; tests replace it
+var pythonSitePackages = func(python string) (string, error) {
+	out, err := exec.Command(python, "-c", pythonSitePackagesScript).Output()
 	if err != nil {
-		return "", fmt.Errorf("python3 not found or failed: %w (install python3 or activate a virtualenv)", err)
+		return "", fmt.Errorf("%s not found or failed: %w", python, err)
 	}
 
 	sp := strings.TrimSpace(string(out))
 	if sp == "" {
-		return "", fmt.Errorf("python3 returned empty site-packages path")
+		return "", fmt.Errorf("%s returned empty site-packages path", python)
 	}
 
 	if _, err := os.Stat(sp); err != nil {
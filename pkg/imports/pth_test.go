@@ -26,7 +26,11 @@ func TestInstallPthFileWithVirtualEnv(t *testing.T) {
 	// Set VIRTUAL_ENV
 	t.Setenv("VIRTUAL_ENV", filepath.Join(tmpDir, "venv"))
 
-	if err := InstallPthFile(importsDir); err != nil {
+	env, err := FindPythonEnv(projectDir, "")
+	if err != nil {
+		t.Fatalf("FindPythonEnv failed: %v", err)
+	}
+	if err := InstallPthFile(importsDir, env); err != nil {
 		t.Fatalf("InstallPthFile failed: %v", err)
 	}
 
@@ -54,7 +58,7 @@ func TestInstallPthFileWithVirtualEnv(t *testing.T) {
 	}
 }
 
-func TestInstallPthFileNoVenvNoSitePackages(t *testing.T) {
+func TestFindPythonEnvNoSitePackages(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	// Set VIRTUAL_ENV to a path without site-packages
@@ -65,7 +69,7 @@ func TestInstallPthFileNoVenvNoSitePackages(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err := InstallPthFile(importsDir)
+	_, err := FindPythonEnv(filepath.Join(tmpDir, "project"), "")
 	if err == nil {
 		t.Error("Expected error when no site-packages exists")
 	}
@@ -164,7 +168,11 @@ func TestInstallAndRemovePthFileRoundTrip(t *testing.T) {
 	t.Setenv("VIRTUAL_ENV", filepath.Join(tmpDir, "venv"))
 
 	// Install
-	if err := InstallPthFile(importsDir); err != nil {
+	env, err := FindPythonEnv(projectDir, "")
+	if err != nil {
+		t.Fatalf("FindPythonEnv failed: %v", err)
+	}
+	if err := InstallPthFile(importsDir, env); err != nil {
 		t.Fatalf("InstallPthFile failed: %v", err)
 	}
 
@@ -204,6 +212,39 @@ func TestFindVenvSitePackages(t *testing.T) {
 	}
 }
 
+func TestFindVenvSitePackagesWindows(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Windows virtualenvs have Lib\site-packages with no version directory
+	sp := filepath.Join(tmpDir, "Lib", "site-packages")
+	if err := os.MkdirAll(sp, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(tmpDir, "Scripts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := findVenvSitePackages(tmpDir)
+	if err != nil {
+		t.Fatalf("findVenvSitePackages failed: %v", err)
+	}
+	if result != sp {
+		t.Errorf("result = %q, want %q", result, sp)
+	}
+
+	// A file in its place isn't site-packages
+	fileDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(fileDir, "Lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(fileDir, "Lib", "site-packages"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := findVenvSitePackages(fileDir); err == nil {
+		t.Error("Expected error when Lib/site-packages is a file")
+	}
+}
+
 func TestFindVenvSitePackagesNoMatch(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -213,3 +254,242 @@ func TestFindVenvSitePackagesNoMatch(t *testing.T) {
 		t.Error("Expected error when no site-packages found")
 	}
 }
+
+// makeEnv creates an environment directory with a Unix site-packages
+func makeEnv(t *testing.T, dir string) string {
+	t.Helper()
+	sp := filepath.Join(dir, "lib", "python3.12", "site-packages")
+	if err := os.MkdirAll(sp, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return sp
+}
+
+func TestFindPythonEnv(t *testing.T) {
+	tmpDir := t.TempDir()
+	activeVenv := filepath.Join(tmpDir, "active")
+	condaEnv := filepath.Join(tmpDir, "conda", "envs", "ml")
+	condaBase := filepath.Join(tmpDir, "conda")
+	poetryVenv := filepath.Join(tmpDir, "poetry-venv")
+	override := filepath.Join(tmpDir, "override")
+	systemSP := filepath.Join(tmpDir, "system", "site-packages")
+	for _, dir := range []string{activeVenv, condaEnv, condaBase, poetryVenv, override} {
+		makeEnv(t, dir)
+	}
+	if err := os.MkdirAll(systemSP, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	origPoetry, origPython := poetryEnvPath, pythonSitePackages
+	t.Cleanup(func() { poetryEnvPath, pythonSitePackages = origPoetry, origPython })
+	poetryEnvPath = func(string) string { return poetryVenv }
+	pythonSitePackages = func(python string) (string, error) {
+		if python == "python3" {
+			return systemSP, nil
+		}
+		return "", os.ErrNotExist
+	}
+
+	tests := []struct {
+		name       string
+		python     string
+		virtualEnv string
+		conda      string
+		condaName  string
+		venv       bool // the project has a .venv
+		poetry     bool // the project has a poetry.lock
+		wantKind   string
+		wantPath   string
+	}{
+		{name: "override directory", python: override, virtualEnv: activeVenv, wantKind: PythonEnvOverride, wantPath: override},
+		{name: "activated virtualenv", virtualEnv: activeVenv, conda: condaEnv, condaName: "ml", venv: true, wantKind: PythonEnvVirtualenv, wantPath: activeVenv},
+		{name: "conda env", conda: condaEnv, condaName: "ml", venv: true, wantKind: PythonEnvConda, wantPath: condaEnv},
+		{name: "conda base yields to project .venv", conda: condaBase, condaName: "base", venv: true, wantKind: PythonEnvProjectVenv},
+		{name: "project .venv before Poetry", venv: true, poetry: true, wantKind: PythonEnvProjectVenv},
+		{name: "Poetry", poetry: true, wantKind: PythonEnvPoetry, wantPath: poetryVenv},
+		{name: "system", wantKind: PythonEnvSystem, wantPath: "python3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projectDir := t.TempDir()
+			if tt.venv {
+				makeEnv(t, filepath.Join(projectDir, ".venv"))
+				if tt.wantPath == "" {
+					tt.wantPath = filepath.Join(projectDir, ".venv")
+				}
+			}
+			if tt.poetry {
+				if err := os.WriteFile(filepath.Join(projectDir, "poetry.lock"), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			t.Setenv("VIRTUAL_ENV", tt.virtualEnv)
+			t.Setenv("CONDA_PREFIX", tt.conda)
+			t.Setenv("CONDA_DEFAULT_ENV", tt.condaName)
+
+			env, err := FindPythonEnv(projectDir, tt.python)
+			if err != nil {
+				t.Fatalf("FindPythonEnv failed: %v", err)
+			}
+			if env.Kind != tt.wantKind || env.Path != tt.wantPath {
+				t.Errorf("FindPythonEnv() = %s %s, want %s %s", env.Kind, env.Path, tt.wantKind, tt.wantPath)
+			}
+			if _, err := os.Stat(env.SitePackages); err != nil {
+				t.Errorf("site-packages %s: %v", env.SitePackages, err)
+			}
+		})
+	}
+}
+
+func TestFindPythonEnvWindowsLayout(t *testing.T) {
+	projectDir := t.TempDir()
+	venv := filepath.Join(projectDir, ".venv")
+	sp := filepath.Join(venv, "Lib", "site-packages")
+	if err := os.MkdirAll(sp, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VIRTUAL_ENV", "")
+	t.Setenv("CONDA_PREFIX", "")
+
+	env, err := FindPythonEnv(projectDir, "")
+	if err != nil {
+		t.Fatalf("FindPythonEnv failed: %v", err)
+	}
+	if env.Kind != PythonEnvProjectVenv || env.SitePackages != sp {
+		t.Errorf("FindPythonEnv() = %s %s, want %s %s", env.Kind, env.SitePackages, PythonEnvProjectVenv, sp)
+	}
+}
+
+func TestFindPythonEnvOverrideInterpreter(t *testing.T) {
+	sp := t.TempDir()
+	orig := pythonSitePackages
+	t.Cleanup(func() { pythonSitePackages = orig })
+	pythonSitePackages = func(python string) (string, error) {
+		if python == "/opt/py/bin/python3.12" {
+			return sp, nil
+		}
+		return "", os.ErrNotExist
+	}
+	t.Setenv("VIRTUAL_ENV", "")
+
+	env, err := FindPythonEnv(t.TempDir(), "/opt/py/bin/python3.12")
+	if err != nil {
+		t.Fatalf("FindPythonEnv failed: %v", err)
+	}
+	if env.Kind != PythonEnvOverride || env.SitePackages != sp {
+		t.Errorf("FindPythonEnv() = %+v, want site-packages %s", env, sp)
+	}
+
+	if _, err := FindPythonEnv(t.TempDir(), "/missing/python"); err == nil {
+		t.Error("Expected error for an interpreter that doesn't run")
+	}
+}
+
+func TestInstallPthFileMultipleEnvs(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "project")
+	importsDir := filepath.Join(projectDir, ".aigogo", "imports")
+	if err := os.MkdirAll(importsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Installing into two environments, and into the first again, tracks both once
+	var pthPaths []string
+	for _, name := range []string{"venv-a", "venv-b", "venv-a"} {
+		sp := filepath.Join(tmpDir, name, "lib", "python3.12", "site-packages")
+		if err := os.MkdirAll(sp, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := InstallPthFile(importsDir, &PythonEnv{Kind: PythonEnvVirtualenv, SitePackages: sp}); err != nil {
+			t.Fatalf("InstallPthFile failed: %v", err)
+		}
+		pthPaths = appendUnique(pthPaths, filepath.Join(sp, pthFileName))
+	}
+	tracked, err := TrackedPthFiles(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracked) != 2 || tracked[0] != pthPaths[0] || tracked[1] != pthPaths[1] {
+		t.Errorf("TrackedPthFiles() = %v, want %v", tracked, pthPaths)
+	}
+
+	if err := RemovePthFile(projectDir); err != nil {
+		t.Fatalf("RemovePthFile failed: %v", err)
+	}
+	for _, path := range pthPaths {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed", path)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, ".aigogo", pthLocationFile)); !os.IsNotExist(err) {
+		t.Error("tracking file should have been removed")
+	}
+}
+
+func TestFindPthFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "project")
+	importsDir := filepath.Join(projectDir, ".aigogo", "imports")
+	if err := os.MkdirAll(importsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The project's installed environment, and a system site-packages
+	// holding the .pth file of a deleted project
+	venv := filepath.Join(tmpDir, "venv")
+	venvSP := filepath.Join(venv, "lib", "python3.12", "site-packages")
+	systemSP := filepath.Join(tmpDir, "system-site-packages")
+	for _, dir := range []string{venvSP, systemSP} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := InstallPthFile(importsDir, &PythonEnv{Kind: PythonEnvVirtualenv, SitePackages: venvSP}); err != nil {
+		t.Fatal(err)
+	}
+	orphan := filepath.Join(systemSP, pthFileName)
+	if err := os.WriteFile(orphan, []byte(filepath.Join(tmpDir, "deleted", ".aigogo", "imports")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A tracked file that was deleted by hand
+	gone := filepath.Join(tmpDir, "gone", pthFileName)
+	tracked, _ := TrackedPthFiles(projectDir)
+	if err := writeTrackedPthFiles(projectDir, append(tracked, gone)); err != nil {
+		t.Fatal(err)
+	}
+
+	orig := pythonSitePackages
+	t.Cleanup(func() { pythonSitePackages = orig })
+	pythonSitePackages = func(string) (string, error) { return systemSP, nil }
+	t.Setenv("VIRTUAL_ENV", venv)
+	t.Setenv("CONDA_PREFIX", "")
+
+	files, err := FindPthFiles(projectDir, "")
+	if err != nil {
+		t.Fatalf("FindPthFiles failed: %v", err)
+	}
+	found := make(map[string]PthFile)
+	for _, f := range files {
+		found[f.Path] = f
+	}
+	if len(files) != 3 {
+		t.Fatalf("FindPthFiles() = %+v, want 3 files", files)
+	}
+	if f := found[filepath.Join(venvSP, pthFileName)]; !f.Tracked || f.Missing || f.Orphaned() {
+		t.Errorf("installed file = %+v, want tracked and not orphaned", f)
+	}
+	if f := found[orphan]; f.Tracked || !f.Orphaned() {
+		t.Errorf("deleted project's file = %+v, want untracked and orphaned", f)
+	}
+	if f := found[gone]; !f.Tracked || !f.Missing {
+		t.Errorf("deleted file = %+v, want tracked and missing", f)
+	}
+
+	if err := UntrackPthFile(projectDir, gone); err != nil {
+		t.Fatal(err)
+	}
+	if tracked, _ := TrackedPthFiles(projectDir); len(tracked) != 1 {
+		t.Errorf("TrackedPthFiles() after UntrackPthFile = %v, want one file", tracked)
+	}
+}
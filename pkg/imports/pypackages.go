@@ -0,0 +1,97 @@
+package imports
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// PyPackagesDir is the PEP 582 directory Python packages are installed into
+// with the pypackages layout, as __pypackages__/<X.Y>/lib/, which pdm and
+// other PEP 582 tools put on sys.path
+const PyPackagesDir = "__pypackages__"
+
+// sitePackagesVersion matches the version directory of a site-packages
+// path, e.g. lib/python3.12/site-packages or Debian's dist-packages
+var sitePackagesVersion = regexp.MustCompile(`python(\d+\.\d+)t?[/\\](?:site|dist)-packages$`)
+
+// SetPyPackages switches Python packages to the PEP 582 layout for Python
+// version (X.Y): they are linked under __pypackages__/<version>/lib/ instead
+// of .aigogo/imports/, so no .pth file is needed. An empty version switches
+// back.
+func (m *SetupManager) SetPyPackages(version string) {
+	if version == "" {
+		m.pythonRoot = ""
+		return
+	}
+	m.pythonRoot = filepath.Join(m.projectDir, PyPackagesDir, version, "lib")
+}
+
+// GetPythonPath returns the directory Python packages are linked under,
+// the one put on sys.path: .aigogo/imports/, or __pypackages__/<X.Y>/lib/
+// with the PEP 582 layout
+func (m *SetupManager) GetPythonPath() string {
+	if m.pythonRoot != "" {
+		return m.pythonRoot
+	}
+	return m.importsDir
+}
+
+// UsesPyPackages reports whether Python packages use the PEP 582 layout
+func (m *SetupManager) UsesPyPackages() bool {
+	return m.pythonRoot != ""
+}
+
+// RemovePyPackages removes the namespace directory aigogo's Python packages
+// are linked under from every __pypackages__/<X.Y>/lib/, leaving the
+// packages other tools installed there, and returns how many it removed.
+// Only the namespace's last package is removed: the ones above it are
+// namespace packages others can share.
+func (m *SetupManager) RemovePyPackages() (int, error) {
+	pattern := filepath.Join(append([]string{m.projectDir, PyPackagesDir, "*", "lib"}, strings.Split(m.namespace.Python, ".")...)...)
+	dirs, err := filepath.Glob(pattern)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find %s packages: %w", PyPackagesDir, err)
+	}
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return 0, fmt.Errorf("failed to remove %s: %w", m.relPath(dir), err)
+		}
+	}
+	return len(dirs), nil
+}
+
+// PythonVersion returns the X.Y version of an environment's Python, from
+// its site-packages path or else by asking its interpreter
+func PythonVersion(env *PythonEnv) (string, error) {
+	if match := sitePackagesVersion.FindStringSubmatch(env.SitePackages); match != nil {
+		return match[1], nil
+	}
+	interpreter := env.Path
+	if info, err := os.Stat(env.Path); err == nil && info.IsDir() {
+		if runtime.GOOS == "windows" {
+			interpreter = filepath.Join(env.Path, "Scripts", "python.exe")
+		} else {
+			interpreter = filepath.Join(env.Path, "bin", "python")
+		}
+	}
+	return pythonVersion(interpreter)
+}
+
+// pythonVersion asks a Python interpreter for its X.Y version; tests
+// replace it
+var pythonVersion = func(python string) (string, error) {
+	out, err := exec.Command(python, "-c", "import sys; print('%d.%d' % sys.version_info[:2])").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s not found or failed: %w", python, err)
+	}
+	version := strings.TrimSpace(string(out))
+	if version == "" {
+		return "", fmt.Errorf("%s returned no version", python)
+	}
+	return version, nil
+}
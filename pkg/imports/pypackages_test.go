@@ -0,0 +1,86 @@
+package imports
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPyPackagesLayout(t *testing.T) {
+	projectDir := t.TempDir()
+	mgr, err := NewSetupManager(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr.SetPyPackages("3.12")
+	libDir := filepath.Join(projectDir, PyPackagesDir, "3.12", "lib")
+	if !mgr.UsesPyPackages() || mgr.GetPythonPath() != libDir {
+		t.Fatalf("GetPythonPath() = %s, want %s", mgr.GetPythonPath(), libDir)
+	}
+
+	// Packages other tools installed stay when aigogo's are removed
+	if err := os.MkdirAll(filepath.Join(libDir, "requests"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	storePath := makeStoreFiles(t, map[string]string{"utils.py": ""})
+	if err := mgr.SetupPythonNamespace(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.CreatePackageLink("my-utils", "python", storePath, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(libDir, "aigogo", "my_utils", "utils.py")); err != nil {
+		t.Errorf("package not linked into %s: %v", libDir, err)
+	}
+
+	if err := mgr.UpdateGitignore(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(projectDir, ".gitignore")); string(data) != ".aigogo/\n__pypackages__/\n" {
+		t.Errorf(".gitignore = %q", data)
+	}
+
+	if err := mgr.Clean(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(libDir, "aigogo")); !os.IsNotExist(err) {
+		t.Errorf("Clean() left %s/aigogo", libDir)
+	}
+	if _, err := os.Stat(filepath.Join(libDir, "requests")); err != nil {
+		t.Errorf("Clean() removed another tool's package: %v", err)
+	}
+}
+
+func TestPythonVersion(t *testing.T) {
+	orig := pythonVersion
+	t.Cleanup(func() { pythonVersion = orig })
+	var asked string
+	pythonVersion = func(python string) (string, error) {
+		asked = python
+		return "3.11", nil
+	}
+
+	tests := []struct {
+		name      string
+		env       PythonEnv
+		want      string
+		wantAsked bool
+	}{
+		{"virtualenv", PythonEnv{Path: "/venv", SitePackages: "/venv/lib/python3.12/site-packages"}, "3.12", false},
+		{"free-threaded", PythonEnv{Path: "/venv", SitePackages: "/venv/lib/python3.13t/site-packages"}, "3.13", false},
+		{"debian system", PythonEnv{Path: "python3", SitePackages: "/usr/local/lib/python3.11/dist-packages"}, "3.11", false},
+		{"windows", PythonEnv{Path: "python", SitePackages: `C:\venv\Lib\site-packages`}, "3.11", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asked = ""
+			got, err := PythonVersion(&tt.env)
+			if err != nil || got != tt.want {
+				t.Errorf("PythonVersion() = %q, %v; want %q", got, err, tt.want)
+			}
+			if (asked != "") != tt.wantAsked {
+				t.Errorf("asked interpreter %q, want asked = %v", asked, tt.wantAsked)
+			}
+		})
+	}
+}
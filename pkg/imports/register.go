@@ -4,12 +4,22 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
 const (
 	// registerFileName is the name of the register script
 	registerFileName = "register.js"
+	// esmRegisterFileName is the name of the ESM register script
+	esmRegisterFileName = "register.mjs"
+	// esmLoaderFileName is the name of the module hooks register.mjs loads
+	esmLoaderFileName = "loader.mjs"
+	// bunPluginFileName is the name of the Bun runtime plugin
+	bunPluginFileName = "bun-plugin.js"
+	// nodeModulesLink is .aigogo/node_modules, a link to the imports
+	// directory that lets Node's ESM resolver find @aigogo/ packages
+	nodeModulesLink = "node_modules"
 )
 
 // registerScript is the content of the generated .aigogo/register.js file.
@@ -21,9 +31,11 @@ const registerScript = `// Auto-generated by aigogo — do not edit
 // Usage (CommonJS):
 //   require('./.aigogo/register');
 //
-// Usage (preload, works with both CommonJS and ESM):
+// Usage (preload):
 //   node --require ./.aigogo/register.js app.js
 //
+// ES modules don't use NODE_PATH; preload register.mjs for them instead.
+//
 const path = require('path');
 const importsDir = path.join(__dirname, 'imports');
 if (!process.env.NODE_PATH || !process.env.NODE_PATH.split(path.delimiter).includes(importsDir)) {
@@ -32,28 +44,111 @@ if (!process.env.NODE_PATH || !process.env.NODE_PATH.split(path.delimiter).inclu
 }
 `
 
+// esmRegisterScript is the content of the generated .aigogo/register.mjs
+// file, which installs the resolve hook in loader.mjs (Node.js 18.19+)
+const esmRegisterScript = `// Auto-generated by aigogo — do not edit
+// Lets ES modules import @aigogo/ packages from .aigogo/imports/.
+//
+// Usage (preload, works with both ESM and CommonJS imports):
+//   node --import ./.aigogo/register.mjs app.mjs
+//
+import { register } from 'node:module';
+
+register('./loader.mjs', import.meta.url);
+`
+
+// esmLoaderScript is the content of the generated .aigogo/loader.mjs file.
+// It resolves @aigogo/ specifiers as if they were imported from a file in
+// .aigogo/, where node_modules links to imports/, so Node's own resolver
+// applies each package's package.json (main, exports, type).
+const esmLoaderScript = `// Auto-generated by aigogo — do not edit
+// Module resolve hook loaded by register.mjs.
+const parentURL = import.meta.url;
+
+export async function resolve(specifier, context, nextResolve) {
+  if (specifier.startsWith('@aigogo/')) {
+    return nextResolve(specifier, { ...context, parentURL });
+  }
+  return nextResolve(specifier, context);
+}
+`
+
+// bunPluginScript is the content of the generated .aigogo/bun-plugin.js
+// file. Bun doesn't run Node's module hooks, so a runtime plugin resolves
+// @aigogo/ specifiers from .aigogo/ the way loader.mjs does.
+const bunPluginScript = `// Auto-generated by aigogo — do not edit
+// Lets Bun import and require @aigogo/ packages from .aigogo/imports/.
+//
+// Usage (preload):
+//   bun --preload ./.aigogo/bun-plugin.js app.js
+//
+// or in bunfig.toml:
+//   preload = ["./.aigogo/bun-plugin.js"]
+//
+Bun.plugin({
+  name: 'aigogo',
+  setup(build) {
+    build.onResolve({ filter: /^@aigogo\// }, (args) => ({
+      path: Bun.resolveSync(args.path, import.meta.dir),
+    }));
+  },
+});
+`
+
 // InstallRegisterScript writes the .aigogo/register.js file that enables
-// Node.js to resolve @aigogo/ scoped packages without manual NODE_PATH setup.
-func InstallRegisterScript(projectDir string) error {
+// Node.js to resolve packages in the given scope, e.g. @aigogo, without
+// manual NODE_PATH setup, and register.mjs and loader.mjs, which do the same
+// for ES modules through the .aigogo/node_modules link to the imports
+// directory. bun-plugin.js does the same for Bun.
+func InstallRegisterScript(projectDir, scope string) error {
 	aigogoDir := filepath.Join(projectDir, ImportsDir)
 	if err := os.MkdirAll(aigogoDir, 0755); err != nil {
 		return fmt.Errorf("failed to create .aigogo directory: %w", err)
 	}
 
-	registerPath := filepath.Join(aigogoDir, registerFileName)
-	if err := os.WriteFile(registerPath, []byte(registerScript), 0644); err != nil {
-		return fmt.Errorf("failed to write register script: %w", err)
+	scripts := []struct {
+		name    string
+		content string
+	}{
+		{registerFileName, registerScript},
+		{esmRegisterFileName, esmRegisterScript},
+		{esmLoaderFileName, esmLoaderScript},
+		{bunPluginFileName, bunPluginScript},
+	}
+	// The scripts are written for @aigogo; the resolve hooks match on it
+	scoped := strings.NewReplacer(
+		JavaScriptScope+`\/`, regexp.QuoteMeta(scope)+`\/`,
+		JavaScriptScope+"/", scope+"/",
+	)
+	for _, script := range scripts {
+		if err := os.WriteFile(filepath.Join(aigogoDir, script.name), []byte(scoped.Replace(script.content)), 0644); err != nil {
+			return fmt.Errorf("failed to write register script %s: %w", script.name, err)
+		}
+	}
+
+	// A relative link keeps working when the project directory moves
+	linkPath := filepath.Join(aigogoDir, nodeModulesLink)
+	if _, err := os.Lstat(linkPath); err == nil {
+		if err := os.RemoveAll(linkPath); err != nil {
+			return fmt.Errorf("failed to remove existing %s link: %w", nodeModulesLink, err)
+		}
+	}
+	if err := makeDirLink("imports", linkPath); err != nil {
+		return fmt.Errorf("failed to create %s link: %w", nodeModulesLink, err)
 	}
 
 	return nil
 }
 
-// RemoveRegisterScript removes the .aigogo/register.js file if it exists.
+// RemoveRegisterScript removes the register scripts, the Bun plugin and the
+// node_modules link if they exist.
 func RemoveRegisterScript(projectDir string) error {
-	registerPath := filepath.Join(projectDir, ImportsDir, registerFileName)
-
-	if err := os.Remove(registerPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove register script: %w", err)
+	aigogoDir := filepath.Join(projectDir, ImportsDir)
+	for _, name := range []string{registerFileName, esmRegisterFileName, esmLoaderFileName, bunPluginFileName, nodeModulesLink} {
+		// RemoveAll, as the link is a copy where symlinks can't be created
+		if err := os.RemoveAll(filepath.Join(aigogoDir, name)); err != nil {
+			return fmt.Errorf("failed to remove register script: %w", err)
+		}
 	}
 
 	return nil
@@ -10,7 +10,7 @@ import (
 func TestInstallRegisterScript(t *testing.T) {
 	tmpDir := t.TempDir()
 
-	if err := InstallRegisterScript(tmpDir); err != nil {
+	if err := InstallRegisterScript(tmpDir, JavaScriptScope); err != nil {
 		t.Fatalf("InstallRegisterScript failed: %v", err)
 	}
 
@@ -39,6 +39,74 @@ func TestInstallRegisterScript(t *testing.T) {
 	}
 }
 
+func TestInstallRegisterScriptESM(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Installing twice replaces the node_modules link
+	for i := 0; i < 2; i++ {
+		if err := InstallRegisterScript(tmpDir, JavaScriptScope); err != nil {
+			t.Fatalf("InstallRegisterScript failed: %v", err)
+		}
+	}
+
+	aigogoDir := filepath.Join(tmpDir, ImportsDir)
+	register, err := os.ReadFile(filepath.Join(aigogoDir, esmRegisterFileName))
+	if err != nil {
+		t.Fatalf("Failed to read ESM register script: %v", err)
+	}
+	if !strings.Contains(string(register), "register('./loader.mjs', import.meta.url)") {
+		t.Errorf("register.mjs doesn't register the loader:\n%s", register)
+	}
+
+	loader, err := os.ReadFile(filepath.Join(aigogoDir, esmLoaderFileName))
+	if err != nil {
+		t.Fatalf("Failed to read ESM loader: %v", err)
+	}
+	if !strings.Contains(string(loader), "export async function resolve") {
+		t.Errorf("loader.mjs has no resolve hook:\n%s", loader)
+	}
+
+	plugin, err := os.ReadFile(filepath.Join(aigogoDir, bunPluginFileName))
+	if err != nil {
+		t.Fatalf("Failed to read Bun plugin: %v", err)
+	}
+	if !strings.Contains(string(plugin), "Bun.resolveSync(args.path, import.meta.dir)") {
+		t.Errorf("bun-plugin.js doesn't resolve from .aigogo/:\n%s", plugin)
+	}
+
+	target, err := os.Readlink(filepath.Join(aigogoDir, nodeModulesLink))
+	if err != nil {
+		t.Fatalf("node_modules link not created: %v", err)
+	}
+	if target != "imports" {
+		t.Errorf("node_modules link target = %q, want imports", target)
+	}
+}
+
+func TestInstallRegisterScriptScope(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := InstallRegisterScript(tmpDir, "@our.co"); err != nil {
+		t.Fatalf("InstallRegisterScript failed: %v", err)
+	}
+
+	tests := map[string]string{
+		esmLoaderFileName: "specifier.startsWith('@our.co/')",
+		bunPluginFileName: `filter: /^@our\.co\//`,
+	}
+	for name, want := range tests {
+		content, err := os.ReadFile(filepath.Join(tmpDir, ImportsDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(content), want) {
+			t.Errorf("%s doesn't match the scope, want %q:\n%s", name, want, content)
+		}
+		if strings.Contains(string(content), JavaScriptScope) {
+			t.Errorf("%s still mentions %s:\n%s", name, JavaScriptScope, content)
+		}
+	}
+}
+
 func TestInstallRegisterScriptCreatesDir(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -48,7 +116,7 @@ func TestInstallRegisterScriptCreatesDir(t *testing.T) {
 		t.Fatal(".aigogo should not exist before test")
 	}
 
-	if err := InstallRegisterScript(tmpDir); err != nil {
+	if err := InstallRegisterScript(tmpDir, JavaScriptScope); err != nil {
 		t.Fatalf("InstallRegisterScript failed: %v", err)
 	}
 
@@ -62,7 +130,7 @@ func TestRemoveRegisterScript(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	// Install first
-	if err := InstallRegisterScript(tmpDir); err != nil {
+	if err := InstallRegisterScript(tmpDir, JavaScriptScope); err != nil {
 		t.Fatal(err)
 	}
 
@@ -76,8 +144,10 @@ func TestRemoveRegisterScript(t *testing.T) {
 		t.Fatalf("RemoveRegisterScript failed: %v", err)
 	}
 
-	if _, err := os.Stat(registerPath); !os.IsNotExist(err) {
-		t.Error("register script should have been removed")
+	for _, name := range []string{registerFileName, esmRegisterFileName, esmLoaderFileName, bunPluginFileName, nodeModulesLink} {
+		if _, err := os.Lstat(filepath.Join(tmpDir, ImportsDir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed", name)
+		}
 	}
 }
 
@@ -94,7 +164,7 @@ func TestInstallAndRemoveRegisterScriptRoundTrip(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	// Install
-	if err := InstallRegisterScript(tmpDir); err != nil {
+	if err := InstallRegisterScript(tmpDir, JavaScriptScope); err != nil {
 		t.Fatal(err)
 	}
 
@@ -4,42 +4,103 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
 )
 
 const (
 	// ImportsDir is the directory containing import symlinks
 	ImportsDir = ".aigogo"
-	// PythonNamespace is the Python package namespace
+	// PythonNamespace is the default Python package namespace
 	PythonNamespace = "aigogo"
-	// JavaScriptScope is the JavaScript package scope
+	// JavaScriptScope is the default JavaScript package scope
 	JavaScriptScope = "@aigogo"
+	// RubyLoadPath is the directory added to Ruby's $LOAD_PATH; packages live
+	// under ruby/aigogo/ so they are required as 'aigogo/<name>/<file>'
+	RubyLoadPath = "ruby"
+	// RubyNamespace is the Ruby require prefix
+	RubyNamespace = "aigogo"
+	// JavaSourceRoot is the directory holding one source root per Java package
+	JavaSourceRoot = "java"
+	// PyTypedMarker marks a Python package as shipping type information (PEP 561)
+	PyTypedMarker = "py.typed"
 )
 
+// Namespace is where installed Python and JavaScript packages are imported
+// from: from <Python>.<pkg> import ... and '<JavaScript>/<pkg>'
+type Namespace struct {
+	Python     string // Dotted Python package name, e.g. aigogo or ourco.snippets
+	JavaScript string // npm scope, e.g. @aigogo
+}
+
+// DefaultNamespace returns the aigogo and @aigogo namespace
+func DefaultNamespace() Namespace {
+	return Namespace{Python: PythonNamespace, JavaScript: JavaScriptScope}
+}
+
+// NamespaceFor returns the namespace a project's aigogo.json configures,
+// defaulting to aigogo and @aigogo for what it leaves unset. spec may be nil.
+func NamespaceFor(spec *manifest.NamespaceSpec) Namespace {
+	ns := DefaultNamespace()
+	if spec != nil {
+		if spec.Python != "" {
+			ns.Python = spec.Python
+		}
+		if spec.JavaScript != "" {
+			ns.JavaScript = spec.JavaScript
+		}
+	}
+	return ns
+}
+
 // SetupManager manages the .aigogo/imports/ directory structure
 type SetupManager struct {
-	projectDir string // Project root (where aigogo.lock lives)
-	importsDir string // .aigogo/imports/
+	projectDir string    // Project root (where aigogo.lock lives)
+	importsDir string    // .aigogo/imports/
+	namespace  Namespace // Python package and npm scope packages are linked under
+	copyFiles  bool      // copy package files instead of linking to the store
+	pythonRoot string    // __pypackages__/<X.Y>/lib/ with the PEP 582 layout, else ""
 }
 
-// NewSetupManager creates a new SetupManager for the given project directory
+// NewSetupManager creates a new SetupManager for the given project directory,
+// using the default namespace
 func NewSetupManager(projectDir string) (*SetupManager, error) {
 	importsDir := filepath.Join(projectDir, ImportsDir, "imports")
 
 	return &SetupManager{
 		projectDir: projectDir,
 		importsDir: importsDir,
+		namespace:  DefaultNamespace(),
 	}, nil
 }
 
-// SetupPythonNamespace creates the Python namespace package structure
-// Creates .aigogo/imports/aigogo/__init__.py
+// SetNamespace sets the namespace packages are linked under
+func (m *SetupManager) SetNamespace(ns Namespace) {
+	m.namespace = ns
+}
+
+// GetNamespace returns the namespace packages are linked under
+func (m *SetupManager) GetNamespace() Namespace {
+	return m.namespace
+}
+
+// SetInstallMode sets how packages are put into .aigogo/imports/:
+// manifest.InstallModeLink or manifest.InstallModeCopy
+func (m *SetupManager) SetInstallMode(mode string) {
+	m.copyFiles = mode == manifest.InstallModeCopy
+}
+
+// SetupPythonNamespace creates the Python namespace package structure, e.g.
+// .aigogo/imports/aigogo/__init__.py. For a dotted namespace only the last
+// package gets an __init__.py; the ones above it stay implicit namespace
+// packages, so they can be shared with other distributions.
 func (m *SetupManager) SetupPythonNamespace() error {
-	namespaceDir := filepath.Join(m.importsDir, PythonNamespace)
+	namespaceDir := m.GetPythonNamespacePath()
 	if err := os.MkdirAll(namespaceDir, 0755); err != nil {
 		return fmt.Errorf("failed to create Python namespace directory: %w", err)
 	}
@@ -49,6 +110,11 @@ func (m *SetupManager) SetupPythonNamespace() error {
 	initContent := `# Auto-generated by aigogo - do not edit
 # Python path is configured via .pth file in site-packages
 `
+	if m.UsesPyPackages() {
+		initContent = `# Auto-generated by aigogo - do not edit
+# PEP 582 tools put __pypackages__/<X.Y>/lib on the Python path
+`
+	}
 
 	if err := os.WriteFile(initPath, []byte(initContent), 0644); err != nil {
 		return fmt.Errorf("failed to write __init__.py: %w", err)
@@ -57,36 +123,58 @@ func (m *SetupManager) SetupPythonNamespace() error {
 	return nil
 }
 
-// SetupJavaScriptNamespace creates the JavaScript scope directory
-// Creates .aigogo/imports/@aigogo/
+// SetupJavaScriptNamespace creates the JavaScript scope directory, e.g.
+// .aigogo/imports/@aigogo/
 func (m *SetupManager) SetupJavaScriptNamespace() error {
-	scopeDir := filepath.Join(m.importsDir, JavaScriptScope)
+	scopeDir := m.GetJavaScriptScopePath()
 	if err := os.MkdirAll(scopeDir, 0755); err != nil {
 		return fmt.Errorf("failed to create JavaScript scope directory: %w", err)
 	}
 	return nil
 }
 
-// CreatePackageLink creates a package link for imports.
-// For Python: creates a directory symlink .aigogo/imports/aigogo/my_utils -> store/files/
+// CreatePackageLink creates a package link for imports. exports is the
+// package's exports map from aigogo.json, or nil.
+// For Python: creates a directory symlink .aigogo/imports/aigogo/my_utils -> store/files/,
+// or, when the package exports Python files or ships .pyi stubs without a
+// py.typed marker, a real directory with individual file symlinks, generated
+// shim modules and the marker.
 // For JavaScript: creates a real directory with individual file symlinks and a
 // generated package.json for proper Node.js module resolution.
-func (m *SetupManager) CreatePackageLink(name, language, storePath string) error {
+// For Ruby: creates a directory symlink .aigogo/imports/ruby/aigogo/my_utils -> store/files/
+// For Java: creates a directory symlink .aigogo/imports/java/my-utils -> store/files/
+// which is used as a source root.
+// For Go: creates .aigogo/imports/go/<module>, the module's directory for the
+// project's go.mod to replace it with (see createGoModule).
+// For Rust: creates .aigogo/imports/rust/<crate>, a crate for the project's
+// Cargo.toml to depend on by path (see createRustCrate).
+func (m *SetupManager) CreatePackageLink(name, language, storePath string, exports map[string]string) error {
 	switch strings.ToLower(language) {
 	case "python":
-		return m.createPythonLink(name, storePath)
+		if pyExports := manifest.ExportsFor(exports, "python"); len(pyExports) > 0 || needsTypedMarker(storePath) {
+			return m.createPythonPackage(name, storePath, pyExports)
+		}
+		return m.createDirLink(m.GetPythonNamespacePath(), lockfile.NormalizeName(name), storePath)
 	case "javascript", "typescript":
-		return m.createJavaScriptPackage(name, storePath)
+		return m.createJavaScriptPackage(name, storePath, manifest.ExportsFor(exports, "javascript"))
+	case "ruby":
+		return m.createDirLink(m.GetRubyNamespacePath(), lockfile.NormalizeName(name), storePath)
+	case "java":
+		return m.createDirLink(m.GetJavaSourceRootPath(), name, storePath)
+	case "go":
+		return m.createGoModule(name, storePath)
+	case "rust":
+		return m.createRustCrate(name, storePath)
 	default:
 		return fmt.Errorf("unsupported language: %s", language)
 	}
 }
 
-// createPythonLink creates a directory symlink for a Python package.
-func (m *SetupManager) createPythonLink(name, storePath string) error {
-	linkDir := filepath.Join(m.importsDir, PythonNamespace)
-	linkName := lockfile.NormalizeName(name)
-
+// createDirLink creates linkDir/linkName as a directory symlink to the
+// package files in the store, or a local path package's working directory,
+// or a junction or copy where symlinks can't be created, or a copy in copy
+// mode.
+func (m *SetupManager) createDirLink(linkDir, linkName, storePath string) error {
 	if err := os.MkdirAll(linkDir, 0755); err != nil {
 		return fmt.Errorf("failed to create link directory: %w", err)
 	}
@@ -95,31 +183,221 @@ func (m *SetupManager) createPythonLink(name, storePath string) error {
 
 	// Remove existing link if present
 	if _, err := os.Lstat(linkPath); err == nil {
-		if err := os.Remove(linkPath); err != nil {
+		if err := os.RemoveAll(linkPath); err != nil {
 			return fmt.Errorf("failed to remove existing link: %w", err)
 		}
 	}
 
-	filesDir := filepath.Join(storePath, "files")
-	if err := os.Symlink(filesDir, linkPath); err != nil {
+	filesDir := packageFilesDir(storePath)
+	if m.copyFiles {
+		if err := copyDir(filesDir, linkPath); err != nil {
+			return fmt.Errorf("failed to copy package files: %w", err)
+		}
+		return nil
+	}
+	if err := makeDirLink(filesDir, linkPath); err != nil {
 		return fmt.Errorf("failed to create symlink: %w", err)
 	}
 
 	return nil
 }
 
+// createPythonPackage creates a real directory for a Python package that
+// declares exports or needs a py.typed marker, containing individual file
+// symlinks plus a generated __init__.py for the "." export, a shim module for
+// each named export that isn't already importable under its name, and the
+// marker (see needsTypedMarker).
+func (m *SetupManager) createPythonPackage(name, storePath string, exports map[string]string) error {
+	pkgDir := filepath.Join(m.GetPythonNamespacePath(), lockfile.NormalizeName(name))
+	if err := m.createPackageDir(pkgDir, storePath); err != nil {
+		return err
+	}
+
+	for exportName, file := range exports {
+		var shim string
+		if exportName == manifest.ExportRoot {
+			if file == "__init__.py" {
+				continue
+			}
+			shim = "__init__.py"
+		} else {
+			module := manifest.PythonExportModule(exportName)
+			if file == module+".py" || file == module+"/__init__.py" {
+				continue
+			}
+			shim = module + ".py"
+		}
+
+		shimPath := filepath.Join(pkgDir, shim)
+		if _, err := os.Lstat(shimPath); err == nil {
+			if err := os.Remove(shimPath); err != nil {
+				return fmt.Errorf("failed to replace %s: %w", shim, err)
+			}
+		}
+		content := fmt.Sprintf(`# Auto-generated by aigogo from the exports in aigogo.json - do not edit
+from .%s import *  # noqa: F401,F403
+`, manifest.PythonModulePath(file))
+		if err := os.WriteFile(shimPath, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", shim, err)
+		}
+	}
+
+	if needsTypedMarker(storePath) {
+		if err := os.WriteFile(filepath.Join(pkgDir, PyTypedMarker), nil, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", PyTypedMarker, err)
+		}
+	}
+
+	return nil
+}
+
+// needsTypedMarker reports whether a Python package ships .pyi stubs but no
+// py.typed marker. mypy ignores the types of packages on the path of an
+// installed environment, as .aigogo/imports/ is through aigogo.pth, unless
+// the package is marked typed (PEP 561).
+func needsTypedMarker(storePath string) bool {
+	filesDir := packageFilesDir(storePath)
+	if _, err := os.Stat(filepath.Join(filesDir, PyTypedMarker)); err == nil {
+		return false
+	}
+	hasStubs := false
+	_ = filepath.WalkDir(filesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || hasStubs {
+			return filepath.SkipAll
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".pyi") {
+			hasStubs = true
+		}
+		return nil
+	})
+	return hasStubs
+}
+
 // createJavaScriptPackage creates a real directory for a JavaScript package
 // containing individual file symlinks and a generated package.json with a
-// "main" entry point, so that require('@aigogo/pkg') works correctly.
-func (m *SetupManager) createJavaScriptPackage(name, storePath string) error {
-	linkDir := filepath.Join(m.importsDir, JavaScriptScope)
+// "main" entry point and an "exports" map, so that require('@aigogo/pkg')
+// and import from '@aigogo/pkg' work correctly. When the package declares
+// exports, the map lists only them, so require('@aigogo/pkg/<name>') loads
+// the exported file; otherwise every file is exported by its path.
+func (m *SetupManager) createJavaScriptPackage(name, storePath string, exports map[string]string) error {
+	linkDir := m.GetJavaScriptScopePath()
 	if err := os.MkdirAll(linkDir, 0755); err != nil {
 		return fmt.Errorf("failed to create link directory: %w", err)
 	}
 
 	pkgDir := filepath.Join(linkDir, name)
+	filesDir := packageFilesDir(storePath)
+	if err := m.createPackageDir(pkgDir, storePath); err != nil {
+		return err
+	}
+
+	// Generate package.json with entry point
+	entryPoint, err := resolveJSEntryPoint(filesDir)
+	if root, ok := exports[manifest.ExportRoot]; ok {
+		entryPoint, err = root, nil
+	}
+	if err != nil && len(exports) == 0 {
+		// No top-level JS files found — check subdirs and warn
+		if hasJSFilesInSubdirs(filesDir) {
+			fmt.Fprintf(os.Stderr, "⚠ Warning: package %q has no top-level .js files; "+
+				"require('%[2]s') will not resolve an entry point. "+
+				"Use explicit paths, e.g. require('%[2]s/sub/file').\n", name, m.namespace.JavaScript+"/"+name)
+		}
+		return nil
+	}
+
+	pkgJSON := map[string]interface{}{
+		"name": m.namespace.JavaScript + "/" + name,
+	}
+	if err == nil {
+		pkgJSON["main"] = entryPoint
+		if types := declarationFile(filesDir, entryPoint); types != "" {
+			pkgJSON["types"] = types
+		}
+	}
+	// The package's own module type decides whether Node loads its .js
+	// files as ES modules or CommonJS
+	if moduleType := packageModuleType(filesDir); moduleType != "" {
+		pkgJSON["type"] = moduleType
+	}
+	exportsMap := map[string]string{"./package.json": "./package.json"}
+	if err == nil {
+		exportsMap["."] = "./" + entryPoint
+	}
+	if len(exports) > 0 {
+		for exportName, file := range exports {
+			if exportName != manifest.ExportRoot {
+				exportsMap["./"+exportName] = "./" + file
+			}
+		}
+	} else {
+		// Without declared exports every file stays importable by its path
+		exportsMap["./*"] = "./*"
+	}
+	pkgJSON["exports"] = exportsMap
+	pkgData, err := json.MarshalIndent(pkgJSON, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal package.json: %w", err)
+	}
+	pkgData = append(pkgData, '\n')
+
+	// Replace the link to a package.json the package ships rather than
+	// writing through it into the store
+	pkgJSONPath := filepath.Join(pkgDir, "package.json")
+	if err := os.Remove(pkgJSONPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to replace package.json: %w", err)
+	}
+	if err := os.WriteFile(pkgJSONPath, pkgData, 0644); err != nil {
+		return fmt.Errorf("failed to write package.json: %w", err)
+	}
+
+	return nil
+}
+
+// declarationFile returns the TypeScript declaration file the package
+// ships next to its entry point, e.g. index.d.ts for index.js, or ""
+func declarationFile(filesDir, entryPoint string) string {
+	ext := filepath.Ext(entryPoint)
+	declExt := map[string]string{".js": ".d.ts", ".mjs": ".d.mts", ".cjs": ".d.cts"}[ext]
+	if declExt == "" {
+		return ""
+	}
+	decl := strings.TrimSuffix(entryPoint, ext) + declExt
+	if _, err := os.Stat(filepath.Join(filesDir, filepath.FromSlash(decl))); err != nil {
+		return ""
+	}
+	return decl
+}
 
-	// Remove existing package directory if present
+// packageModuleType returns the "type" ("module" or "commonjs") of the
+// package.json a JavaScript package ships, or "" if it has none
+func packageModuleType(filesDir string) string {
+	data, err := os.ReadFile(filepath.Join(filesDir, "package.json"))
+	if err != nil {
+		return ""
+	}
+	var pkg struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return ""
+	}
+	switch pkg.Type {
+	case "module", "commonjs":
+		return pkg.Type
+	}
+	return ""
+}
+
+// createPackageDir replaces pkgDir with a real directory holding a symlink
+// to each file of the package at storePath, or in copy mode a copy, so
+// generated files can sit next to them. A local path package's ignored
+// files are left out.
+func (m *SetupManager) createPackageDir(pkgDir, storePath string) error {
+	filesDir := packageFilesDir(storePath)
+	ignore := localPackageIgnores(storePath)
+
+	// Remove existing package directory or link if present
 	if _, err := os.Lstat(pkgDir); err == nil {
 		if err := os.RemoveAll(pkgDir); err != nil {
 			return fmt.Errorf("failed to remove existing package directory: %w", err)
@@ -132,7 +410,6 @@ func (m *SetupManager) createJavaScriptPackage(name, storePath string) error {
 	}
 
 	// Symlink each file from the store individually
-	filesDir := filepath.Join(storePath, "files")
 	err := filepath.Walk(filesDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -146,6 +423,12 @@ func (m *SetupManager) createJavaScriptPackage(name, storePath string) error {
 		if relPath == "." {
 			return nil
 		}
+		if ignore != nil && ignore.ShouldIgnore(filepath.ToSlash(relPath), info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 
 		destPath := filepath.Join(pkgDir, relPath)
 
@@ -158,89 +441,98 @@ func (m *SetupManager) createJavaScriptPackage(name, storePath string) error {
 			return err
 		}
 
-		return os.Symlink(path, destPath)
+		if m.copyFiles {
+			return copyFile(path, destPath)
+		}
+		return makeFileLink(path, destPath)
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create file symlinks: %w", err)
 	}
+	return nil
+}
 
-	// Generate package.json with entry point
-	entryPoint, err := resolveJSEntryPoint(filesDir)
-	if err != nil {
-		// No top-level JS files found — check subdirs and warn
-		if hasJSFilesInSubdirs(filesDir) {
-			fmt.Fprintf(os.Stderr, "⚠ Warning: package %q has no top-level .js files; "+
-				"require('@aigogo/%s') will not resolve an entry point. "+
-				"Use explicit paths, e.g. require('@aigogo/%s/sub/file').\n", name, name, name)
-		}
-		return nil
-	}
-
-	pkgJSON := map[string]string{
-		"name": "@aigogo/" + name,
-		"main": entryPoint,
-	}
-	pkgData, err := json.MarshalIndent(pkgJSON, "", "  ")
+// RemovePackageLink removes a package link (symlink for Python, Ruby and Java,
+// directory for JavaScript and for Python packages with exports). Links are
+// removed with RemoveAll, as they are junctions or copies where symlinks
+// can't be created.
+func (m *SetupManager) RemovePackageLink(name, language string) error {
+	linkPath, err := m.packageLinkPath(name, language)
 	if err != nil {
-		return fmt.Errorf("failed to marshal package.json: %w", err)
+		return err
 	}
-	pkgData = append(pkgData, '\n')
-
-	pkgJSONPath := filepath.Join(pkgDir, "package.json")
-	if err := os.WriteFile(pkgJSONPath, pkgData, 0644); err != nil {
-		return fmt.Errorf("failed to write package.json: %w", err)
+	if _, err := os.Lstat(linkPath); err == nil {
+		return os.RemoveAll(linkPath)
 	}
-
-	return nil
+	return nil // Already doesn't exist
 }
 
-// RemovePackageLink removes a package link (symlink for Python, directory for JavaScript)
-func (m *SetupManager) RemovePackageLink(name, language string) error {
-	var linkPath string
-
+// packageLinkPath returns where CreatePackageLink puts a package for a
+// language. A Go package shipping its own go.mod is at a module path that
+// only its files say, and a Rust package whose Cargo.toml renames the crate
+// under that name; for them it is the path the package would have without.
+func (m *SetupManager) packageLinkPath(name, language string) (string, error) {
 	switch strings.ToLower(language) {
 	case "python":
-		linkPath = filepath.Join(m.importsDir, PythonNamespace, lockfile.NormalizeName(name))
-		if _, err := os.Lstat(linkPath); err == nil {
-			return os.Remove(linkPath)
-		}
+		// A symlink, or a directory when the package declares exports
+		return filepath.Join(m.GetPythonNamespacePath(), lockfile.NormalizeName(name)), nil
+	case "ruby":
+		return filepath.Join(m.GetRubyNamespacePath(), lockfile.NormalizeName(name)), nil
+	case "java":
+		return filepath.Join(m.GetJavaSourceRootPath(), name), nil
 	case "javascript", "typescript":
-		linkPath = filepath.Join(m.importsDir, JavaScriptScope, name)
-		if _, err := os.Lstat(linkPath); err == nil {
-			return os.RemoveAll(linkPath)
-		}
+		return filepath.Join(m.GetJavaScriptScopePath(), name), nil
+	case "go":
+		return filepath.Join(m.GetGoModulesPath(), GoModulePrefix, name), nil
+	case "rust":
+		return filepath.Join(m.GetRustCratesPath(), name), nil
 	default:
-		return fmt.Errorf("unsupported language: %s", language)
+		return "", fmt.Errorf("unsupported language: %s", language)
 	}
-
-	return nil // Already doesn't exist
 }
 
-// Clean removes the entire .aigogo/imports/ directory, any installed .pth file,
-// and the Node.js register script.
+// Clean removes the entire .aigogo/imports/ directory, the local path
+// package links in .aigogo/local/, the Node.js register script and the
+// packages in __pypackages__/ (RemovePyPackages). The .pth files stay, as
+// the directory they add to Python's path is recreated at the same place;
+// RemovePthFile removes them.
 func (m *SetupManager) Clean() error {
-	// Remove .pth file before removing imports directory
-	if err := RemovePthFile(m.projectDir); err != nil {
-		fmt.Fprintf(os.Stderr, "⚠ Warning: failed to remove .pth file: %v\n", err)
-	}
-
 	// Remove register script
 	if err := RemoveRegisterScript(m.projectDir); err != nil {
 		fmt.Fprintf(os.Stderr, "⚠ Warning: failed to remove register script: %v\n", err)
 	}
 
+	// Remove the Deno import map
+	if err := os.Remove(filepath.Join(m.projectDir, ImportsDir, ImportMapFileName)); err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "⚠ Warning: failed to remove %s: %v\n", ImportMapFileName, err)
+	}
+
+	if err := os.RemoveAll(m.GetLocalPackagesPath()); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ Warning: failed to remove local package links: %v\n", err)
+	}
+
+	if _, err := m.RemovePyPackages(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ Warning: %v\n", err)
+	}
+
 	if _, err := os.Stat(m.importsDir); err == nil {
 		return os.RemoveAll(m.importsDir)
 	}
 	return nil // Already doesn't exist
 }
 
-// UpdateGitignore ensures .aigogo/ is in .gitignore
+// UpdateGitignore ensures .aigogo/ is in .gitignore, and __pypackages__/
+// with the PEP 582 layout, as both hold links into the store
 func (m *SetupManager) UpdateGitignore() error {
 	gitignorePath := filepath.Join(m.projectDir, ".gitignore")
+	entries := []string{ImportsDir}
+	if m.UsesPyPackages() {
+		entries = append(entries, PyPackagesDir)
+	}
 
 	// Check if .gitignore exists
 	var lines []string
+	ignored := make(map[string]bool)
 	if _, err := os.Stat(gitignorePath); err == nil {
 		// Read existing content
 		file, err := os.Open(gitignorePath)
@@ -253,12 +545,9 @@ func (m *SetupManager) UpdateGitignore() error {
 			line := scanner.Text()
 			lines = append(lines, line)
 
-			// Check if .aigogo/ is already ignored
-			trimmed := strings.TrimSpace(line)
-			if trimmed == ".aigogo/" || trimmed == ".aigogo" || trimmed == "/.aigogo/" || trimmed == "/.aigogo" {
-				_ = file.Close()
-				return nil // Already ignored
-			}
+			// Check if an entry is already ignored, e.g. .aigogo/ or /.aigogo
+			trimmed := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(line), "/"), "/")
+			ignored[trimmed] = true
 		}
 		_ = file.Close()
 
@@ -267,7 +556,17 @@ func (m *SetupManager) UpdateGitignore() error {
 		}
 	}
 
-	// Add .aigogo/ to .gitignore
+	var missing []string
+	for _, entry := range entries {
+		if !ignored[entry] {
+			missing = append(missing, entry)
+		}
+	}
+	if len(missing) == 0 {
+		return nil // Already ignored
+	}
+
+	// Add the entries to .gitignore
 	file, err := os.OpenFile(gitignorePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to open .gitignore for writing: %w", err)
@@ -281,9 +580,10 @@ func (m *SetupManager) UpdateGitignore() error {
 		}
 	}
 
-	// Add the entry
-	if _, err := file.WriteString(".aigogo/\n"); err != nil {
-		return fmt.Errorf("failed to write to .gitignore: %w", err)
+	for _, entry := range missing {
+		if _, err := file.WriteString(entry + "/\n"); err != nil {
+			return fmt.Errorf("failed to write to .gitignore: %w", err)
+		}
 	}
 
 	return nil
@@ -299,14 +599,40 @@ func (m *SetupManager) GetImportsDir() string {
 	return m.importsDir
 }
 
-// GetPythonNamespacePath returns the Python namespace directory path
+// GetPythonNamespacePath returns the Python namespace directory path, a
+// directory per part of a dotted namespace under GetPythonPath
 func (m *SetupManager) GetPythonNamespacePath() string {
-	return filepath.Join(m.importsDir, PythonNamespace)
+	return filepath.Join(append([]string{m.GetPythonPath()}, strings.Split(m.namespace.Python, ".")...)...)
 }
 
 // GetJavaScriptScopePath returns the JavaScript scope directory path
 func (m *SetupManager) GetJavaScriptScopePath() string {
-	return filepath.Join(m.importsDir, JavaScriptScope)
+	return filepath.Join(m.importsDir, m.namespace.JavaScript)
+}
+
+// GetRubyLoadPath returns the directory to add to Ruby's $LOAD_PATH
+func (m *SetupManager) GetRubyLoadPath() string {
+	return filepath.Join(m.importsDir, RubyLoadPath)
+}
+
+// GetRubyNamespacePath returns the Ruby namespace directory path
+func (m *SetupManager) GetRubyNamespacePath() string {
+	return filepath.Join(m.importsDir, RubyLoadPath, RubyNamespace)
+}
+
+// GetJavaSourceRootPath returns the directory containing Java source roots
+func (m *SetupManager) GetJavaSourceRootPath() string {
+	return filepath.Join(m.importsDir, JavaSourceRoot)
+}
+
+// GetGoModulesPath returns the directory containing Go modules
+func (m *SetupManager) GetGoModulesPath() string {
+	return filepath.Join(m.importsDir, GoModulesDir)
+}
+
+// GetRustCratesPath returns the directory containing Rust crates
+func (m *SetupManager) GetRustCratesPath() string {
+	return filepath.Join(m.importsDir, RustCratesDir)
 }
 
 // HasPythonPackages checks if there are any Python package links
@@ -319,7 +645,7 @@ func (m *SetupManager) HasPythonPackages() bool {
 
 	// Check for any directories (excluding __init__.py)
 	for _, entry := range entries {
-		if entry.Name() != "__init__.py" && entry.IsDir() || (entry.Type()&os.ModeSymlink != 0) {
+		if entry.Name() != "__init__.py" && entry.IsDir() || isLink(entry.Type()) {
 			return true
 		}
 	}
@@ -358,5 +684,29 @@ func (m *SetupManager) ListPackageLinks() (map[string][]string, error) {
 		}
 	}
 
+	// Check Ruby packages
+	if entries, err := os.ReadDir(m.GetRubyNamespacePath()); err == nil {
+		for _, entry := range entries {
+			result["ruby"] = append(result["ruby"], entry.Name())
+		}
+	}
+
+	// Check Java packages
+	if entries, err := os.ReadDir(m.GetJavaSourceRootPath()); err == nil {
+		for _, entry := range entries {
+			result["java"] = append(result["java"], entry.Name())
+		}
+	}
+
+	// Check Go modules, listed by module path
+	if modules, err := m.GoModules(); err == nil && len(modules) > 0 {
+		result["go"] = modules
+	}
+
+	// Check Rust crates
+	if crates, err := m.RustCrates(); err == nil && len(crates) > 0 {
+		result["rust"] = crates
+	}
+
 	return result, nil
 }
@@ -1,10 +1,13 @@
 package imports
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
 )
 
 func TestNewSetupManager(t *testing.T) {
@@ -53,6 +56,97 @@ func TestSetupPythonNamespace(t *testing.T) {
 	}
 }
 
+func TestCustomNamespace(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	mgr, err := NewSetupManager(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr.SetNamespace(Namespace{Python: "ourco.snippets", JavaScript: "@ourco"})
+
+	// Only the innermost Python package gets an __init__.py
+	if err := mgr.SetupPythonNamespace(); err != nil {
+		t.Fatalf("SetupPythonNamespace failed: %v", err)
+	}
+	if want := filepath.Join(mgr.GetImportsDir(), "ourco", "snippets"); mgr.GetPythonNamespacePath() != want {
+		t.Errorf("GetPythonNamespacePath() = %q, want %q", mgr.GetPythonNamespacePath(), want)
+	}
+	if _, err := os.Stat(filepath.Join(mgr.GetPythonNamespacePath(), "__init__.py")); err != nil {
+		t.Errorf("__init__.py not created: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(mgr.GetImportsDir(), "ourco", "__init__.py")); !os.IsNotExist(err) {
+		t.Error("ourco/ should stay a namespace package without __init__.py")
+	}
+
+	storePath := makeStoreFiles(t, map[string]string{"index.js": ""})
+	if err := mgr.CreatePackageLink("my-utils", "javascript", storePath, nil); err != nil {
+		t.Fatalf("CreatePackageLink failed: %v", err)
+	}
+	pkgDir := filepath.Join(mgr.GetImportsDir(), "@ourco", "my-utils")
+	data, err := os.ReadFile(filepath.Join(pkgDir, "package.json"))
+	if err != nil {
+		t.Fatalf("package.json not generated: %v", err)
+	}
+	var pkgJSON map[string]interface{}
+	if err := json.Unmarshal(data, &pkgJSON); err != nil {
+		t.Fatal(err)
+	}
+	if pkgJSON["name"] != "@ourco/my-utils" {
+		t.Errorf("package.json name = %v, want @ourco/my-utils", pkgJSON["name"])
+	}
+
+	if err := mgr.RemovePackageLink("my-utils", "javascript"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(pkgDir); !os.IsNotExist(err) {
+		t.Error("RemovePackageLink should remove the package in the configured scope")
+	}
+}
+
+func TestCopyInstallMode(t *testing.T) {
+	mgr, err := NewSetupManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr.SetInstallMode(manifest.InstallModeCopy)
+
+	storePath := makeStoreFiles(t, map[string]string{"index.js": "module.exports = 1;\n", "utils.py": ""})
+	for _, lang := range []string{"python", "javascript"} {
+		if err := mgr.CreatePackageLink("my-utils", lang, storePath, nil); err != nil {
+			t.Fatalf("CreatePackageLink(%s) failed: %v", lang, err)
+		}
+	}
+
+	for _, path := range []string{
+		filepath.Join(mgr.GetPythonNamespacePath(), "my_utils"),
+		filepath.Join(mgr.GetPythonNamespacePath(), "my_utils", "utils.py"),
+		filepath.Join(mgr.GetJavaScriptScopePath(), "my-utils", "index.js"),
+	} {
+		info, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			t.Errorf("%s is a link, want a copy", path)
+		}
+	}
+	// Copies are writable, so they can be removed again
+	if err := mgr.Clean(); err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+}
+
+func TestNamespaceFor(t *testing.T) {
+	if got := NamespaceFor(nil); got != DefaultNamespace() {
+		t.Errorf("NamespaceFor(nil) = %v, want the default", got)
+	}
+	spec := &manifest.NamespaceSpec{JavaScript: "@ourco"}
+	if got, want := NamespaceFor(spec), (Namespace{Python: PythonNamespace, JavaScript: "@ourco"}); got != want {
+		t.Errorf("NamespaceFor() = %v, want %v", got, want)
+	}
+}
+
 func TestSetupJavaScriptNamespace(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -103,7 +197,7 @@ func TestCreatePackageLink(t *testing.T) {
 	}
 
 	// Create link
-	if err := mgr.CreatePackageLink("my-utils", "python", storePath); err != nil {
+	if err := mgr.CreatePackageLink("my-utils", "python", storePath, nil); err != nil {
 		t.Fatalf("CreatePackageLink failed: %v", err)
 	}
 
@@ -157,7 +251,7 @@ func TestCreatePackageLinkJavaScript(t *testing.T) {
 	}
 
 	// Create link - JS keeps original name
-	if err := mgr.CreatePackageLink("my-utils", "javascript", storePath); err != nil {
+	if err := mgr.CreatePackageLink("my-utils", "javascript", storePath, nil); err != nil {
 		t.Fatalf("CreatePackageLink failed: %v", err)
 	}
 
@@ -235,7 +329,7 @@ func TestCreatePackageLinkJavaScriptSubdirs(t *testing.T) {
 
 	_ = mgr.SetupJavaScriptNamespace()
 
-	if err := mgr.CreatePackageLink("deep-pkg", "javascript", storePath); err != nil {
+	if err := mgr.CreatePackageLink("deep-pkg", "javascript", storePath, nil); err != nil {
 		t.Fatalf("CreatePackageLink failed: %v", err)
 	}
 
@@ -284,7 +378,7 @@ func TestRemovePackageLinkJavaScript(t *testing.T) {
 	}
 
 	_ = mgr.SetupJavaScriptNamespace()
-	_ = mgr.CreatePackageLink("js-pkg", "javascript", storePath)
+	_ = mgr.CreatePackageLink("js-pkg", "javascript", storePath, nil)
 
 	pkgDir := filepath.Join(mgr.GetJavaScriptScopePath(), "js-pkg")
 	if _, err := os.Stat(pkgDir); os.IsNotExist(err) {
@@ -300,6 +394,60 @@ func TestRemovePackageLinkJavaScript(t *testing.T) {
 	}
 }
 
+func TestCreatePackageLinkRubyAndJava(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	storePath := filepath.Join(tmpDir, "store", "abc123")
+	filesPath := filepath.Join(storePath, "files")
+	if err := os.MkdirAll(filesPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr, err := NewSetupManager(filepath.Join(tmpDir, "project"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mgr.CreatePackageLink("my-gem", "ruby", storePath, nil); err != nil {
+		t.Fatalf("CreatePackageLink(ruby) failed: %v", err)
+	}
+	if err := mgr.CreatePackageLink("my-lib", "java", storePath, nil); err != nil {
+		t.Fatalf("CreatePackageLink(java) failed: %v", err)
+	}
+
+	rubyLink := filepath.Join(mgr.GetImportsDir(), "ruby", "aigogo", "my_gem")
+	javaLink := filepath.Join(mgr.GetImportsDir(), "java", "my-lib")
+	for _, link := range []string{rubyLink, javaLink} {
+		target, err := os.Readlink(link)
+		if err != nil {
+			t.Fatalf("Symlink %s not found: %v", link, err)
+		}
+		if target != filesPath {
+			t.Errorf("Symlink target = %q, want %q", target, filesPath)
+		}
+	}
+
+	links, err := mgr.ListPackageLinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links["ruby"]) != 1 || len(links["java"]) != 1 {
+		t.Errorf("ListPackageLinks = %v", links)
+	}
+
+	if err := mgr.RemovePackageLink("my-gem", "ruby"); err != nil {
+		t.Fatalf("RemovePackageLink(ruby) failed: %v", err)
+	}
+	if err := mgr.RemovePackageLink("my-lib", "java"); err != nil {
+		t.Fatalf("RemovePackageLink(java) failed: %v", err)
+	}
+	for _, link := range []string{rubyLink, javaLink} {
+		if _, err := os.Lstat(link); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", link)
+		}
+	}
+}
+
 func TestCreatePackageLinkUnsupportedLanguage(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -308,7 +456,7 @@ func TestCreatePackageLinkUnsupportedLanguage(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = mgr.CreatePackageLink("pkg", "rust", "/some/path")
+	err = mgr.CreatePackageLink("pkg", "rust", "/some/path", nil)
 	if err == nil {
 		t.Error("Expected error for unsupported language")
 	}
@@ -477,7 +625,7 @@ func TestRemovePackageLink(t *testing.T) {
 
 	// Setup and create link
 	_ = mgr.SetupPythonNamespace()
-	_ = mgr.CreatePackageLink("test-pkg", "python", storePath)
+	_ = mgr.CreatePackageLink("test-pkg", "python", storePath, nil)
 
 	// Verify link exists
 	linkPath := filepath.Join(mgr.GetPythonNamespacePath(), "test_pkg")
@@ -522,8 +670,8 @@ func TestListPackageLinks(t *testing.T) {
 	// Setup namespaces and create links
 	_ = mgr.SetupPythonNamespace()
 	_ = mgr.SetupJavaScriptNamespace()
-	_ = mgr.CreatePackageLink("py-utils", "python", storePath)
-	_ = mgr.CreatePackageLink("js-utils", "javascript", storePath)
+	_ = mgr.CreatePackageLink("py-utils", "python", storePath, nil)
+	_ = mgr.CreatePackageLink("js-utils", "javascript", storePath, nil)
 
 	// List
 	links, err := mgr.ListPackageLinks()
@@ -539,3 +687,223 @@ func TestListPackageLinks(t *testing.T) {
 		t.Errorf("JavaScript packages = %d, want 1", len(links["javascript"]))
 	}
 }
+
+func TestCreatePackageLinkPythonExports(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	storePath := filepath.Join(tmpDir, "store")
+	filesPath := filepath.Join(storePath, "files")
+	for _, f := range []string{"main.py", "cli.py", "tools/run.py"} {
+		path := filepath.Join(filesPath, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x = 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mgr, err := NewSetupManager(filepath.Join(tmpDir, "project"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exports := map[string]string{".": "main.py", "cli": "cli.py", "run-tool": "tools/run.py", "web": "web.js"}
+	if err := mgr.CreatePackageLink("my-utils", "python", storePath, exports); err != nil {
+		t.Fatalf("CreatePackageLink failed: %v", err)
+	}
+
+	pkgDir := filepath.Join(mgr.GetPythonNamespacePath(), "my_utils")
+	info, err := os.Lstat(pkgDir)
+	if err != nil {
+		t.Fatalf("Package directory not found: %v", err)
+	}
+	if !info.IsDir() || info.Mode()&os.ModeSymlink != 0 {
+		t.Fatal("Expected a real directory for a package with exports")
+	}
+
+	shims := map[string]string{
+		"__init__.py": "from .main import *",
+		"run_tool.py": "from .tools.run import *",
+	}
+	for shim, want := range shims {
+		data, err := os.ReadFile(filepath.Join(pkgDir, shim))
+		if err != nil {
+			t.Fatalf("shim %s not generated: %v", shim, err)
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s = %q, want it to contain %q", shim, data, want)
+		}
+	}
+
+	// cli.py is already importable as .cli, so it stays a link to the store
+	if info, err := os.Lstat(filepath.Join(pkgDir, "cli.py")); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("cli.py should be a symlink to the store: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(pkgDir, "web.py")); err == nil {
+		t.Error("JavaScript export should not get a Python shim")
+	}
+
+	if err := mgr.RemovePackageLink("my-utils", "python"); err != nil {
+		t.Fatalf("RemovePackageLink failed: %v", err)
+	}
+	if _, err := os.Lstat(pkgDir); !os.IsNotExist(err) {
+		t.Error("Package directory should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(filesPath, "main.py")); err != nil {
+		t.Errorf("Store files should be kept: %v", err)
+	}
+}
+
+func TestCreatePackageLinkJavaScriptExports(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	storePath := filepath.Join(tmpDir, "store")
+	filesPath := filepath.Join(storePath, "files")
+	for _, f := range []string{"index.js", "lib/main.js", "bin/cli.mjs"} {
+		path := filepath.Join(filesPath, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(""), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mgr, err := NewSetupManager(filepath.Join(tmpDir, "project"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exports := map[string]string{".": "lib/main.js", "cli": "bin/cli.mjs", "py": "main.py"}
+	if err := mgr.CreatePackageLink("my-utils", "javascript", storePath, exports); err != nil {
+		t.Fatalf("CreatePackageLink failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(mgr.GetJavaScriptScopePath(), "my-utils", "package.json"))
+	if err != nil {
+		t.Fatalf("Failed to read package.json: %v", err)
+	}
+	var pkgJSON struct {
+		Main    string            `json:"main"`
+		Exports map[string]string `json:"exports"`
+	}
+	if err := json.Unmarshal(data, &pkgJSON); err != nil {
+		t.Fatalf("package.json is invalid: %v", err)
+	}
+	if pkgJSON.Main != "lib/main.js" {
+		t.Errorf("main = %q, want lib/main.js", pkgJSON.Main)
+	}
+	want := map[string]string{
+		".":              "./lib/main.js",
+		"./cli":          "./bin/cli.mjs",
+		"./package.json": "./package.json",
+	}
+	if len(pkgJSON.Exports) != len(want) {
+		t.Errorf("exports = %v, want %v", pkgJSON.Exports, want)
+	}
+	for k, v := range want {
+		if pkgJSON.Exports[k] != v {
+			t.Errorf("exports[%q] = %q, want %q", k, pkgJSON.Exports[k], v)
+		}
+	}
+}
+
+func TestCreatePackageLinkJavaScriptModuleType(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	storePath := filepath.Join(tmpDir, "store")
+	filesPath := filepath.Join(storePath, "files")
+	if err := os.MkdirAll(filesPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(filesPath, "index.js"), []byte("export const x = 1;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(filesPath, "index.d.ts"), []byte("export declare const x: number;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	shipped := []byte(`{"name": "esm-utils", "type": "module"}` + "\n")
+	if err := os.WriteFile(filepath.Join(filesPath, "package.json"), shipped, 0444); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr, err := NewSetupManager(filepath.Join(tmpDir, "project"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.CreatePackageLink("esm-utils", "javascript", storePath, nil); err != nil {
+		t.Fatalf("CreatePackageLink failed: %v", err)
+	}
+
+	pkgJSONPath := filepath.Join(mgr.GetJavaScriptScopePath(), "esm-utils", "package.json")
+	info, err := os.Lstat(pkgJSONPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		t.Error("package.json should be generated, not a link to the store")
+	}
+	data, err := os.ReadFile(pkgJSONPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pkgJSON struct {
+		Type    string            `json:"type"`
+		Types   string            `json:"types"`
+		Exports map[string]string `json:"exports"`
+	}
+	if err := json.Unmarshal(data, &pkgJSON); err != nil {
+		t.Fatalf("package.json is invalid: %v", err)
+	}
+	if pkgJSON.Type != "module" {
+		t.Errorf("type = %q, want module", pkgJSON.Type)
+	}
+	if pkgJSON.Types != "index.d.ts" {
+		t.Errorf("types = %q, want index.d.ts", pkgJSON.Types)
+	}
+	if pkgJSON.Exports["."] != "./index.js" || pkgJSON.Exports["./*"] != "./*" {
+		t.Errorf("exports = %v, want the entry point and every file", pkgJSON.Exports)
+	}
+
+	// The store's copy is untouched
+	if stored, err := os.ReadFile(filepath.Join(filesPath, "package.json")); err != nil || string(stored) != string(shipped) {
+		t.Errorf("store package.json changed: %q, %v", stored, err)
+	}
+}
+
+func TestCreatePackageLinkPythonStubs(t *testing.T) {
+	mgr, err := NewSetupManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Stubs without a marker get a real directory with a py.typed
+	stubs := makeStoreFiles(t, map[string]string{"__init__.py": "", "core.py": "", "core.pyi": "def f() -> int: ...\n"})
+	if err := mgr.CreatePackageLink("stubbed", "python", stubs, nil); err != nil {
+		t.Fatalf("CreatePackageLink failed: %v", err)
+	}
+	pkgDir := filepath.Join(mgr.GetPythonNamespacePath(), "stubbed")
+	if info, err := os.Lstat(pkgDir); err != nil || info.Mode()&os.ModeSymlink != 0 {
+		t.Fatalf("%s should be a real directory", pkgDir)
+	}
+	for _, file := range []string{PyTypedMarker, "core.pyi", "core.py"} {
+		if _, err := os.Stat(filepath.Join(pkgDir, file)); err != nil {
+			t.Errorf("%s missing: %v", file, err)
+		}
+	}
+
+	// A package that ships its own marker, or has no stubs, is linked as is
+	for name, files := range map[string]map[string]string{
+		"marked":  {"__init__.py": "", "core.pyi": "", PyTypedMarker: ""},
+		"untyped": {"__init__.py": "", "core.py": ""},
+	} {
+		if err := mgr.CreatePackageLink(name, "python", makeStoreFiles(t, files), nil); err != nil {
+			t.Fatalf("CreatePackageLink failed: %v", err)
+		}
+		if info, err := os.Lstat(filepath.Join(mgr.GetPythonNamespacePath(), name)); err != nil || info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("%s should be a directory link", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(mgr.GetPythonNamespacePath(), "untyped", PyTypedMarker)); !os.IsNotExist(err) {
+		t.Error("a package without stubs should not be marked typed")
+	}
+}
@@ -0,0 +1,130 @@
+package imports
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// TSConfigFileName is the tsconfig aigg install --tsconfig writes in the
+	// project directory, for the project's tsconfig.json to extend
+	TSConfigFileName = "tsconfig.aigogo.json"
+	// defaultTypeRoot is where TypeScript looks for global types when
+	// typeRoots isn't set; it is kept when typeRoots is written
+	defaultTypeRoot = "./node_modules/@types"
+)
+
+// HasTSConfig reports whether the project has a tsconfig.aigogo.json, which
+// aigg install then keeps up to date
+func (m *SetupManager) HasTSConfig() bool {
+	_, err := os.Stat(filepath.Join(m.projectDir, TSConfigFileName))
+	return err == nil
+}
+
+// WriteTSConfig writes tsconfig.aigogo.json with a compilerOptions.paths
+// entry mapping each installed JavaScript package, and its subpaths, to its
+// directory under .aigogo/imports/<scope>/. The scope directory is added to
+// typeRoots when every package there ships type declarations, as TypeScript
+// reports an error for a type root entry without them. An existing file is
+// updated: stale paths and type roots into .aigogo/imports/, including
+// those of a previously configured scope, are replaced and other settings
+// are kept.
+func (m *SetupManager) WriteTSConfig() error {
+	path := filepath.Join(m.projectDir, TSConfigFileName)
+
+	config := map[string]interface{}{}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return fmt.Errorf("failed to parse %s: %w\nDelete it to have it regenerated", TSConfigFileName, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", TSConfigFileName, err)
+	}
+
+	options, _ := config["compilerOptions"].(map[string]interface{})
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+	paths, _ := options["paths"].(map[string]interface{})
+	if paths == nil {
+		paths = map[string]interface{}{}
+	}
+	importsPrefix := "./" + filepath.ToSlash(filepath.Join(ImportsDir, "imports")) + "/"
+	for pattern, targets := range paths {
+		if targets, ok := targets.([]interface{}); ok && len(targets) > 0 {
+			if target, ok := targets[0].(string); ok && strings.HasPrefix(target, importsPrefix) {
+				delete(paths, pattern)
+			}
+		}
+	}
+
+	scope := m.namespace.JavaScript
+	scopeDir := importsPrefix + scope
+	entries, err := os.ReadDir(m.GetJavaScriptScopePath())
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to list JavaScript packages: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	allTyped := len(names) > 0
+	for _, name := range names {
+		dir := scopeDir + "/" + name
+		paths[scope+"/"+name] = []string{dir}
+		paths[scope+"/"+name+"/*"] = []string{dir + "/*"}
+		if !hasTypeDeclarations(filepath.Join(m.GetJavaScriptScopePath(), name)) {
+			allTyped = false
+		}
+	}
+	options["paths"] = paths
+
+	// typeRoots replaces TypeScript's default, so the default is kept
+	var typeRoots []interface{}
+	if existing, ok := options["typeRoots"].([]interface{}); ok {
+		for _, root := range existing {
+			if dir, ok := root.(string); !ok || !strings.HasPrefix(dir, importsPrefix) {
+				typeRoots = append(typeRoots, root)
+			}
+		}
+	} else {
+		typeRoots = []interface{}{defaultTypeRoot}
+	}
+	if allTyped {
+		typeRoots = append(typeRoots, scopeDir)
+	}
+	options["typeRoots"] = typeRoots
+	config["compilerOptions"] = options
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", TSConfigFileName, err)
+	}
+	data = append(data, '\n')
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", TSConfigFileName, err)
+	}
+	return nil
+}
+
+// hasTypeDeclarations reports whether a linked JavaScript package has type
+// declarations TypeScript finds without a path: a "types" entry in its
+// package.json or an index.d.ts
+func hasTypeDeclarations(pkgDir string) bool {
+	if data, err := os.ReadFile(filepath.Join(pkgDir, "package.json")); err == nil {
+		var pkg struct {
+			Types string `json:"types"`
+		}
+		if json.Unmarshal(data, &pkg) == nil && pkg.Types != "" {
+			return true
+		}
+	}
+	_, err := os.Stat(filepath.Join(pkgDir, "index.d.ts"))
+	return err == nil
+}
@@ -0,0 +1,167 @@
+package imports
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// linkJSPackages creates a linked JavaScript package per name, with an
+// index.d.ts for those in typed
+func linkJSPackages(t *testing.T, mgr *SetupManager, names []string, typed map[string]bool) {
+	t.Helper()
+	for _, name := range names {
+		dir := filepath.Join(mgr.GetJavaScriptScopePath(), name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "index.js"), []byte(""), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if typed[name] {
+			if err := os.WriteFile(filepath.Join(dir, "index.d.ts"), []byte("export {};\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func readTSConfig(t *testing.T, projectDir string) map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(projectDir, TSConfigFileName))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", TSConfigFileName, err)
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatalf("%s is invalid: %v", TSConfigFileName, err)
+	}
+	return config
+}
+
+func TestWriteTSConfig(t *testing.T) {
+	projectDir := t.TempDir()
+	mgr, err := NewSetupManager(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mgr.HasTSConfig() {
+		t.Fatal("HasTSConfig() = true before it was written")
+	}
+
+	// An existing file keeps its own settings, loses stale @aigogo/ paths
+	existing := `{
+  "compilerOptions": {
+    "strict": true,
+    "paths": {"@app/*": ["./src/*"], "@aigogo/removed": ["./.aigogo/imports/@aigogo/removed"]}
+  },
+  "include": ["src"]
+}`
+	if err := os.WriteFile(filepath.Join(projectDir, TSConfigFileName), []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+	linkJSPackages(t, mgr, []string{"utils", "tokens"}, map[string]bool{"utils": true})
+
+	if err := mgr.WriteTSConfig(); err != nil {
+		t.Fatalf("WriteTSConfig failed: %v", err)
+	}
+	if !mgr.HasTSConfig() {
+		t.Error("HasTSConfig() = false after it was written")
+	}
+
+	config := readTSConfig(t, projectDir)
+	options := config["compilerOptions"].(map[string]interface{})
+	if options["strict"] != true || config["include"] == nil {
+		t.Errorf("existing settings not kept: %v", config)
+	}
+
+	paths := options["paths"].(map[string]interface{})
+	want := map[string]interface{}{
+		"@app/*":           []interface{}{"./src/*"},
+		"@aigogo/tokens":   []interface{}{"./.aigogo/imports/@aigogo/tokens"},
+		"@aigogo/tokens/*": []interface{}{"./.aigogo/imports/@aigogo/tokens/*"},
+		"@aigogo/utils":    []interface{}{"./.aigogo/imports/@aigogo/utils"},
+		"@aigogo/utils/*":  []interface{}{"./.aigogo/imports/@aigogo/utils/*"},
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+
+	// tokens has no declarations, so the scope isn't a type root
+	if roots := options["typeRoots"]; !reflect.DeepEqual(roots, []interface{}{defaultTypeRoot}) {
+		t.Errorf("typeRoots = %v, want only %s", roots, defaultTypeRoot)
+	}
+}
+
+func TestWriteTSConfigTypeRoots(t *testing.T) {
+	projectDir := t.TempDir()
+	mgr, err := NewSetupManager(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	linkJSPackages(t, mgr, []string{"utils"}, map[string]bool{"utils": true})
+
+	// Writing twice doesn't repeat the type root
+	for i := 0; i < 2; i++ {
+		if err := mgr.WriteTSConfig(); err != nil {
+			t.Fatalf("WriteTSConfig failed: %v", err)
+		}
+	}
+
+	options := readTSConfig(t, projectDir)["compilerOptions"].(map[string]interface{})
+	want := []interface{}{defaultTypeRoot, "./.aigogo/imports/@aigogo"}
+	if roots := options["typeRoots"]; !reflect.DeepEqual(roots, want) {
+		t.Errorf("typeRoots = %v, want %v", roots, want)
+	}
+}
+
+func TestWriteTSConfigInvalid(t *testing.T) {
+	projectDir := t.TempDir()
+	mgr, err := NewSetupManager(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, TSConfigFileName), []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = mgr.WriteTSConfig()
+	if err == nil || !strings.Contains(err.Error(), "Delete it to have it regenerated") {
+		t.Fatalf("WriteTSConfig() error = %v, want a parse error", err)
+	}
+}
+
+func TestWriteTSConfigNamespaceChange(t *testing.T) {
+	projectDir := t.TempDir()
+	mgr, err := NewSetupManager(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	linkJSPackages(t, mgr, []string{"utils"}, map[string]bool{"utils": true})
+	if err := mgr.WriteTSConfig(); err != nil {
+		t.Fatalf("WriteTSConfig failed: %v", err)
+	}
+
+	// Paths and type roots of the previous scope are replaced
+	mgr.SetNamespace(Namespace{Python: PythonNamespace, JavaScript: "@ourco"})
+	linkJSPackages(t, mgr, []string{"utils"}, map[string]bool{"utils": true})
+	if err := mgr.WriteTSConfig(); err != nil {
+		t.Fatalf("WriteTSConfig failed: %v", err)
+	}
+
+	options := readTSConfig(t, projectDir)["compilerOptions"].(map[string]interface{})
+	wantPaths := map[string]interface{}{
+		"@ourco/utils":   []interface{}{"./.aigogo/imports/@ourco/utils"},
+		"@ourco/utils/*": []interface{}{"./.aigogo/imports/@ourco/utils/*"},
+	}
+	if paths := options["paths"]; !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("paths = %v, want %v", paths, wantPaths)
+	}
+	wantRoots := []interface{}{defaultTypeRoot, "./.aigogo/imports/@ourco"}
+	if roots := options["typeRoots"]; !reflect.DeepEqual(roots, wantRoots) {
+		t.Errorf("typeRoots = %v, want %v", roots, wantRoots)
+	}
+}
@@ -0,0 +1,234 @@
+// Package interpreter finds the Python and Node.js interpreters aigg runs
+// packages with, and checks their versions against a package's constraints
+package interpreter
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var versionSegmentRe = regexp.MustCompile(`^(\d+)`)
+
+// FindPython searches for a suitable Python interpreter
+func FindPython(versionConstraint string) (string, error) {
+	candidates := []string{}
+
+	// 1. Check active virtual environment
+	if venv := os.Getenv("VIRTUAL_ENV"); venv != "" {
+		candidates = append(candidates, filepath.Join(venv, "bin", "python"))
+	}
+
+	// 2. Check local project venvs
+	cwd, _ := os.Getwd()
+	if cwd != "" {
+		for _, dir := range []string{"venv", ".venv"} {
+			candidates = append(candidates, filepath.Join(cwd, dir, "bin", "python"))
+		}
+	}
+
+	// 3. System interpreters
+	candidates = append(candidates, "python3", "python")
+
+	for _, candidate := range candidates {
+		path, err := exec.LookPath(candidate)
+		if err != nil {
+			continue
+		}
+
+		// Validate version if constraint is specified
+		if versionConstraint != "" {
+			version, err := PythonVersion(path)
+			if err != nil {
+				continue
+			}
+			if !SatisfiesConstraint(version, versionConstraint) {
+				continue // Try next candidate
+			}
+		}
+
+		return path, nil
+	}
+
+	if versionConstraint != "" {
+		return "", fmt.Errorf("no Python interpreter found satisfying %s\n"+
+			"Install a compatible Python version or activate a virtual environment", versionConstraint)
+	}
+	return "", fmt.Errorf("python not found on PATH\nInstall Python 3 to run Python agents")
+}
+
+// FindNode searches for a suitable Node.js interpreter
+func FindNode(versionConstraint string) (string, error) {
+	path, err := exec.LookPath("node")
+	if err != nil {
+		return "", fmt.Errorf("node not found on PATH\nInstall Node.js to run JavaScript agents")
+	}
+
+	if versionConstraint != "" {
+		version, err := NodeVersion(path)
+		if err != nil {
+			return path, nil // Can't check, proceed anyway
+		}
+		if !SatisfiesConstraint(version, versionConstraint) {
+			return "", fmt.Errorf("node %s does not satisfy %s\nInstall a compatible Node.js version",
+				version, versionConstraint)
+		}
+	}
+
+	return path, nil
+}
+
+// PythonVersion runs python --version and returns the version string
+func PythonVersion(pythonPath string) (string, error) {
+	out, err := exec.Command(pythonPath, "--version").Output()
+	if err != nil {
+		return "", err
+	}
+	// Output: "Python 3.11.5"
+	parts := strings.Fields(strings.TrimSpace(string(out)))
+	if len(parts) >= 2 {
+		return parts[1], nil
+	}
+	return "", fmt.Errorf("unexpected python version output: %s", string(out))
+}
+
+// NodeVersion runs node --version and returns the version string
+func NodeVersion(nodePath string) (string, error) {
+	out, err := exec.Command(nodePath, "--version").Output()
+	if err != nil {
+		return "", err
+	}
+	// Output: "v20.10.0"
+	version := strings.TrimSpace(string(out))
+	version = strings.TrimPrefix(version, "v")
+	return version, nil
+}
+
+// SatisfiesConstraint checks if a version satisfies a constraint like ">=3.8,<4.0" or ">=18"
+func SatisfiesConstraint(version, constraint string) bool {
+	parts := parseVersion(version)
+	if parts == nil {
+		return true // Can't parse, allow it
+	}
+
+	// Split constraints by comma
+	constraints := strings.Split(constraint, ",")
+	for _, c := range constraints {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+
+		if !evaluateConstraint(parts, c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseVersion parses "3.11.5" into [3, 11, 5]
+func parseVersion(v string) []int {
+	v = strings.TrimPrefix(v, "v")
+	segments := strings.Split(v, ".")
+	result := make([]int, 0, len(segments))
+	for _, s := range segments {
+		// Strip any pre-release suffix (e.g., "5rc1")
+		match := versionSegmentRe.FindString(s)
+		if match == "" {
+			break
+		}
+		n, err := strconv.Atoi(match)
+		if err != nil {
+			break
+		}
+		result = append(result, n)
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// evaluateConstraint evaluates a single constraint like ">=3.8"
+func evaluateConstraint(version []int, constraint string) bool {
+	var op string
+	var target string
+
+	if strings.HasPrefix(constraint, ">=") {
+		op = ">="
+		target = strings.TrimPrefix(constraint, ">=")
+	} else if strings.HasPrefix(constraint, "<=") {
+		op = "<="
+		target = strings.TrimPrefix(constraint, "<=")
+	} else if strings.HasPrefix(constraint, "!=") {
+		op = "!="
+		target = strings.TrimPrefix(constraint, "!=")
+	} else if strings.HasPrefix(constraint, ">") {
+		op = ">"
+		target = strings.TrimPrefix(constraint, ">")
+	} else if strings.HasPrefix(constraint, "<") {
+		op = "<"
+		target = strings.TrimPrefix(constraint, "<")
+	} else if strings.HasPrefix(constraint, "==") {
+		op = "=="
+		target = strings.TrimPrefix(constraint, "==")
+	} else {
+		return true // Unknown operator, allow
+	}
+
+	targetParts := parseVersion(target)
+	if targetParts == nil {
+		return true
+	}
+
+	cmp := compareVersions(version, targetParts)
+
+	switch op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	}
+
+	return true
+}
+
+// compareVersions compares two version arrays, returns -1, 0, or 1
+func compareVersions(a, b []int) int {
+	maxLen := len(a)
+	if len(b) > maxLen {
+		maxLen = len(b)
+	}
+
+	for i := 0; i < maxLen; i++ {
+		av := 0
+		if i < len(a) {
+			av = a[i]
+		}
+		bv := 0
+		if i < len(b) {
+			bv = b[i]
+		}
+
+		if av < bv {
+			return -1
+		}
+		if av > bv {
+			return 1
+		}
+	}
+	return 0
+}
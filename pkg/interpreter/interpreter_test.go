@@ -0,0 +1,123 @@
+package interpreter
+
+import (
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []int
+	}{
+		{"3.11.5", []int{3, 11, 5}},
+		{"3.8", []int{3, 8}},
+		{"20", []int{20}},
+		{"v20.10.0", []int{20, 10, 0}},
+		{"3.12.0rc1", []int{3, 12, 0}},
+		{"", nil},
+		{"abc", nil},
+	}
+
+	for _, tt := range tests {
+		got := parseVersion(tt.input)
+		if tt.want == nil {
+			if got != nil {
+				t.Errorf("parseVersion(%q) = %v, want nil", tt.input, got)
+			}
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseVersion(%q) = %v, want %v", tt.input, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("parseVersion(%q) = %v, want %v", tt.input, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b []int
+		want int
+	}{
+		{[]int{3, 11, 5}, []int{3, 11, 5}, 0},
+		{[]int{3, 11, 5}, []int{3, 11, 4}, 1},
+		{[]int{3, 11, 5}, []int{3, 11, 6}, -1},
+		{[]int{3, 12}, []int{3, 11, 99}, 1},
+		{[]int{4}, []int{3, 99, 99}, 1},
+		{[]int{3, 8}, []int{3, 8, 0}, 0},
+		{[]int{3}, []int{3, 0, 0}, 0},
+	}
+
+	for _, tt := range tests {
+		got := compareVersions(tt.a, tt.b)
+		if got != tt.want {
+			t.Errorf("compareVersions(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCheckVersionConstraint(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		want       bool
+	}{
+		// Python-style constraints
+		{"3.11.5", ">=3.8", true},
+		{"3.11.5", ">=3.8,<4.0", true},
+		{"3.7.2", ">=3.8,<4.0", false},
+		{"4.0.0", ">=3.8,<4.0", false},
+		{"3.8.0", ">=3.8", true},
+
+		// Node-style constraints
+		{"20.10.0", ">=18", true},
+		{"16.0.0", ">=18", false},
+
+		// Edge cases
+		{"3.11.5", "", true},
+		{"", ">=3.8", true}, // Can't parse, allow
+	}
+
+	for _, tt := range tests {
+		got := SatisfiesConstraint(tt.version, tt.constraint)
+		if got != tt.want {
+			t.Errorf("SatisfiesConstraint(%q, %q) = %v, want %v",
+				tt.version, tt.constraint, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateConstraint(t *testing.T) {
+	tests := []struct {
+		version    []int
+		constraint string
+		want       bool
+	}{
+		{[]int{3, 11}, ">=3.8", true},
+		{[]int{3, 7}, ">=3.8", false},
+		{[]int{3, 8}, ">=3.8", true},
+		{[]int{4, 0}, "<4.0", false},
+		{[]int{3, 99}, "<4.0", true},
+		{[]int{3, 8}, ">3.8", false},
+		{[]int{3, 9}, ">3.8", true},
+		{[]int{3, 8}, "<=3.8", true},
+		{[]int{3, 9}, "<=3.8", false},
+		{[]int{3, 8}, "==3.8", true},
+		{[]int{3, 9}, "==3.8", false},
+		{[]int{3, 8}, "!=3.8", false},
+		{[]int{3, 9}, "!=3.8", true},
+	}
+
+	for _, tt := range tests {
+		got := evaluateConstraint(tt.version, tt.constraint)
+		if got != tt.want {
+			t.Errorf("evaluateConstraint(%v, %q) = %v, want %v",
+				tt.version, tt.constraint, got, tt.want)
+		}
+	}
+}
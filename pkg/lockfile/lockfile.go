@@ -6,7 +6,11 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/filelock"
 )
 
 const (
@@ -14,6 +18,14 @@ const (
 	LockFileName = "aigogo.lock"
 	// CurrentVersion is the current lock file format version
 	CurrentVersion = 1
+	// SourcePath is the source of local path packages, which are linked to
+	// their working directory instead of the store
+	SourcePath = "path"
+	// GroupDev marks packages only needed to develop the project, such as
+	// test helpers
+	GroupDev = "dev"
+	// GroupOptional marks packages the project can run without
+	GroupOptional = "optional"
 )
 
 // LockFile represents the aigogo.lock file
@@ -25,10 +37,22 @@ type LockFile struct {
 // LockedPackage represents a single package entry in the lock file
 type LockedPackage struct {
 	Version   string   `json:"version"`
-	Integrity string   `json:"integrity"` // sha256:...
-	Source    string   `json:"source"`    // registry/repo:tag
-	Language  string   `json:"language"`  // python|javascript
-	Files     []string `json:"files"`
+	Integrity string   `json:"integrity"` // sha256:..., empty for local path packages
+	Source    string   `json:"source"`    // registry/repo:tag, or "path"
+	Language  string   `json:"language"`  // python|javascript|ruby|java
+	Files     []string `json:"files"`     // relative to the package root, with forward slashes
+	// Languages maps each language of a multi-language package to its
+	// source files; empty for single-language packages
+	Languages map[string][]string `json:"languages,omitempty"`
+	// Extras are the package's optional dependency groups selected with
+	// aigg add name:tag[extra]
+	Extras []string `json:"extras,omitempty"`
+	// Path is the directory of a local path package, relative to the
+	// directory of aigogo.lock, with forward slashes
+	Path string `json:"path,omitempty"`
+	// Group is GroupDev or GroupOptional for packages aigg install
+	// --production skips, and empty for runtime packages
+	Group string `json:"group,omitempty"`
 }
 
 // New creates a new empty LockFile
@@ -44,7 +68,7 @@ func Load(path string) (*LockFile, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("lock file not found: %s", path)
+			return nil, errcode.Errorf(errcode.NotFound, "lock file not found: %s", path)
 		}
 		return nil, fmt.Errorf("failed to read lock file: %w", err)
 	}
@@ -53,16 +77,24 @@ func Load(path string) (*LockFile, error) {
 	if err := json.Unmarshal(data, &lock); err != nil {
 		return nil, fmt.Errorf("failed to parse lock file: %w", err)
 	}
+	if lock.Version > CurrentVersion {
+		return nil, fmt.Errorf("%s uses lock file format %d, but this aigogo only reads format %d: please upgrade aigogo (https://github.com/aupeachmo/aigogo#installation)", path, lock.Version, CurrentVersion)
+	}
 
 	// Initialize map if nil (empty packages)
 	if lock.Packages == nil {
 		lock.Packages = make(map[string]LockedPackage)
 	}
+	for name, pkg := range lock.Packages {
+		lock.Packages[name] = pkg.slashPaths()
+	}
 
 	return &lock, nil
 }
 
-// Save writes the lock file to the given path
+// Save writes the lock file to the given path. It holds the file's lock
+// while writing, and replaces the file whole, so another process never
+// reads half of it.
 func Save(path string, lock *LockFile) error {
 	data, err := json.MarshalIndent(lock, "", "  ")
 	if err != nil {
@@ -72,13 +104,108 @@ func Save(path string, lock *LockFile) error {
 	// Add trailing newline
 	data = append(data, '\n')
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	fl, err := filelock.Acquire(path, path)
+	if err != nil {
+		return err
+	}
+	defer fl.Release()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+LockFileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
 		return fmt.Errorf("failed to write lock file: %w", err)
 	}
 
 	return nil
 }
 
+// Update applies change to the lock file at path, or to a new one when
+// there is none, and saves it, all while holding the file's lock: aigg
+// processes updating it at the same time take turns instead of overwriting
+// each other's changes. The file is left as it was when change fails.
+func Update(path string, change func(lock *LockFile) error) error {
+	fl, err := filelock.Acquire(path, path)
+	if err != nil {
+		return err
+	}
+	defer fl.Release()
+
+	lock := New()
+	if _, err := os.Stat(path); err == nil {
+		if lock, err = Load(path); err != nil {
+			return err
+		}
+	}
+	if err := change(lock); err != nil {
+		return err
+	}
+	return Save(path, lock)
+}
+
+// integrityPattern matches a complete integrity hash
+var integrityPattern = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// Validate checks that the lock file is complete, as aigg add writes it: the
+// current format version and, for every package, a version, a sha256
+// integrity hash, a source, a language and its files, and no unknown group.
+// Local path packages have a path instead of an integrity hash. Lock files written by older
+// aigogo versions or edited by hand may not be.
+func (l *LockFile) Validate() error {
+	if l.Version != CurrentVersion {
+		return fmt.Errorf("lock file format is %d, expected %d", l.Version, CurrentVersion)
+	}
+
+	names := make([]string, 0, len(l.Packages))
+	for name := range l.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		pkg := l.Packages[name]
+		required := []struct {
+			field   string
+			missing bool
+		}{
+			{"version", pkg.Version == ""},
+			{"source", pkg.Source == ""},
+			{"language", pkg.Language == ""},
+			{"files", len(pkg.Files) == 0},
+		}
+		for _, r := range required {
+			if r.missing {
+				return fmt.Errorf("%s: missing %s", name, r.field)
+			}
+		}
+		if pkg.Group != "" && pkg.Group != GroupDev && pkg.Group != GroupOptional {
+			return fmt.Errorf("%s: unknown group %q (expected %s or %s)", name, pkg.Group, GroupDev, GroupOptional)
+		}
+		if pkg.IsLocal() {
+			if pkg.Path == "" {
+				return fmt.Errorf("%s: missing path", name)
+			}
+			continue
+		}
+		if !integrityPattern.MatchString(pkg.Integrity) {
+			return fmt.Errorf("%s: integrity %q is not a sha256:<hex> hash", name, pkg.Integrity)
+		}
+	}
+	return nil
+}
+
 // FindLockFile searches for aigogo.lock starting from the current directory
 // and walking up the directory tree (similar to how git finds .git)
 func FindLockFile() (string, *LockFile, error) {
@@ -113,7 +240,7 @@ func FindLockFileFrom(startDir string) (string, *LockFile, error) {
 		dir = parent
 	}
 
-	return "", nil, fmt.Errorf("aigogo.lock not found")
+	return "", nil, errcode.Errorf(errcode.NotFound, "aigogo.lock not found")
 }
 
 // NormalizeName converts a package name to a valid Python module name
@@ -159,7 +286,7 @@ func GetPackageName(imageRef string) string {
 
 // Add adds or updates a package in the lock file
 func (l *LockFile) Add(name string, pkg LockedPackage) {
-	l.Packages[name] = pkg
+	l.Packages[name] = pkg.slashPaths()
 }
 
 // Remove removes a package from the lock file
@@ -183,6 +310,70 @@ func (l *LockFile) Get(name string) (LockedPackage, bool) {
 	return pkg, exists
 }
 
+// LanguageNames returns the package's primary language followed by the
+// other languages it has files for, in name order
+func (p *LockedPackage) LanguageNames() []string {
+	names := []string{p.Language}
+	var others []string
+	for lang := range p.Languages {
+		if lang != p.Language {
+			others = append(others, lang)
+		}
+	}
+	sort.Strings(others)
+	return append(names, others...)
+}
+
+// IsRuntime reports whether the package is in no group, so that aigg
+// install --production installs it
+func (p *LockedPackage) IsRuntime() bool {
+	return p.Group == ""
+}
+
+// IsLocal reports whether the package is a local path package, added with
+// aigg add <dir>
+func (p *LockedPackage) IsLocal() bool {
+	return p.Source == SourcePath
+}
+
+// LocalDir returns the directory of a local path package, given the
+// directory of aigogo.lock
+func (p *LockedPackage) LocalDir(lockDir string) string {
+	dir := filepath.FromSlash(p.Path)
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(lockDir, dir)
+}
+
+// slashPaths returns p with its files and directory slash-separated, so a
+// lock file reads the same on every platform, even one written with
+// Windows separators
+func (p LockedPackage) slashPaths() LockedPackage {
+	p.Files = slashed(p.Files)
+	if p.Languages != nil {
+		languages := make(map[string][]string, len(p.Languages))
+		for lang, files := range p.Languages {
+			languages[lang] = slashed(files)
+		}
+		p.Languages = languages
+	}
+	p.Path = strings.ReplaceAll(p.Path, "\\", "/")
+	return p
+}
+
+// slashed returns paths with backslashes replaced by forward slashes
+func slashed(paths []string) []string {
+	if paths == nil {
+		return nil
+	}
+	out := make([]string, len(paths))
+	for i, path := range paths {
+		out[i] = strings.ReplaceAll(path, "\\", "/")
+	}
+	return out
+}
+
 // GetIntegrityHash returns just the hash portion of the integrity string
 // "sha256:abc123..." -> "abc123..."
 func (p *LockedPackage) GetIntegrityHash() string {
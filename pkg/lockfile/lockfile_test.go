@@ -1,11 +1,90 @@
 package lockfile
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
+// TestMain lets the test binary update a lock file as another aigg
+// process: with LOCKFILE_TEST_UPDATE=<path>:<id> set, it adds 100 packages
+// named after id, one Update each
+func TestMain(m *testing.M) {
+	if spec := os.Getenv("LOCKFILE_TEST_UPDATE"); spec != "" {
+		i := strings.LastIndex(spec, ":")
+		path, id := spec[:i], spec[i+1:]
+		for n := 0; n < 100; n++ {
+			err := Update(path, func(lock *LockFile) error {
+				lock.Add(fmt.Sprintf("pkg_%s_%d", id, n), LockedPackage{Version: "1.0.0", Source: "test", Language: "python"})
+				return nil
+			})
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestUpdateConcurrentProcesses(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CACHE_HOME", "")
+	lockPath := filepath.Join(t.TempDir(), LockFileName)
+
+	var procs []*exec.Cmd
+	for id := 0; id < 4; id++ {
+		p := exec.Command(os.Args[0], "-test.run=^$")
+		p.Env = append(os.Environ(), fmt.Sprintf("LOCKFILE_TEST_UPDATE=%s:%d", lockPath, id), "HOME="+home)
+		p.Stderr = os.Stderr
+		if err := p.Start(); err != nil {
+			t.Fatal(err)
+		}
+		procs = append(procs, p)
+	}
+	for _, p := range procs {
+		if err := p.Wait(); err != nil {
+			t.Fatalf("updating process failed: %v", err)
+		}
+	}
+
+	lock, err := Load(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without the lock, processes would overwrite each other's additions
+	if len(lock.Packages) != 400 {
+		t.Errorf("lock file has %d packages, want 400", len(lock.Packages))
+	}
+}
+
+func TestUpdateFailureLeavesFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CACHE_HOME", "")
+	lockPath := filepath.Join(t.TempDir(), LockFileName)
+
+	failed := errors.New("nope")
+	if err := Update(lockPath, func(lock *LockFile) error {
+		lock.Add("never", LockedPackage{Version: "1.0.0"})
+		return failed
+	}); !errors.Is(err, failed) {
+		t.Fatalf("Update() error = %v, want the change's", err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("failed Update wrote %s: %v", lockPath, err)
+	}
+}
+
 func TestNew(t *testing.T) {
 	lock := New()
 
@@ -69,6 +148,36 @@ func TestSaveAndLoad(t *testing.T) {
 	}
 }
 
+func TestLoadWindowsPaths(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), LockFileName)
+	data := `{"version": 1, "packages": {"kit": {"version": "1.0.0", "source": "path", "language": "python", "path": "..\\shared\\kit",
+		"files": ["kit.py", "lib\\core.py"], "languages": {"javascript": ["js\\index.js"]}}}}`
+	if err := os.WriteFile(lockPath, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := loaded.Packages["kit"]
+	if got := strings.Join(pkg.Files, " "); got != "kit.py lib/core.py" {
+		t.Errorf("Files = %s, want slash-separated paths", got)
+	}
+	if got := pkg.Languages["javascript"]; len(got) != 1 || got[0] != "js/index.js" {
+		t.Errorf("Languages = %v", pkg.Languages)
+	}
+	if pkg.Path != "../shared/kit" {
+		t.Errorf("Path = %s, want ../shared/kit", pkg.Path)
+	}
+
+	// Packages added with Windows separators are locked with slashes too
+	loaded.Add("other", LockedPackage{Files: []string{`a\b.py`}})
+	if got := loaded.Packages["other"].Files[0]; got != "a/b.py" {
+		t.Errorf("added file = %s, want a/b.py", got)
+	}
+}
+
 func TestLoadNotFound(t *testing.T) {
 	_, err := Load("/nonexistent/path/aigogo.lock")
 	if err == nil {
@@ -222,6 +331,26 @@ func TestGetIntegrityHash(t *testing.T) {
 	}
 }
 
+func TestLanguageNames(t *testing.T) {
+	single := LockedPackage{Language: "python"}
+	if got := single.LanguageNames(); !reflect.DeepEqual(got, []string{"python"}) {
+		t.Errorf("LanguageNames() = %v, want [python]", got)
+	}
+
+	multi := LockedPackage{
+		Language: "python",
+		Languages: map[string][]string{
+			"ruby":       {"client.rb"},
+			"python":     {"helper.py"},
+			"javascript": {"client.ts"},
+		},
+	}
+	want := []string{"python", "javascript", "ruby"}
+	if got := multi.LanguageNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("LanguageNames() = %v, want %v", got, want)
+	}
+}
+
 func TestLoadEmptyPackages(t *testing.T) {
 	tmpDir := t.TempDir()
 	lockPath := filepath.Join(tmpDir, "aigogo.lock")
@@ -241,3 +370,83 @@ func TestLoadEmptyPackages(t *testing.T) {
 		t.Error("Packages map should be initialized, not nil")
 	}
 }
+
+func TestLoadNewerFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockFileName)
+	if err := os.WriteFile(path, []byte(`{"version": 99, "packages": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), "please upgrade aigogo") {
+		t.Errorf("Load of a newer lock file: err = %v, want an upgrade message", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	complete := LockedPackage{
+		Version:   "1.0.0",
+		Integrity: "sha256:" + strings.Repeat("ab", 32),
+		Source:    "docker.io/org/utils:1.0.0",
+		Language:  "python",
+		Files:     []string{"utils.py"},
+	}
+
+	tests := []struct {
+		name    string
+		version int
+		edit    func(p *LockedPackage)
+		wantErr string
+	}{
+		{name: "complete", version: CurrentVersion},
+		{name: "no format version", version: 0, wantErr: "format is 0"},
+		{name: "missing source", version: CurrentVersion, edit: func(p *LockedPackage) { p.Source = "" }, wantErr: "utils: missing source"},
+		{name: "missing files", version: CurrentVersion, edit: func(p *LockedPackage) { p.Files = nil }, wantErr: "utils: missing files"},
+		{name: "bare hash", version: CurrentVersion, edit: func(p *LockedPackage) { p.Integrity = strings.Repeat("ab", 32) }, wantErr: "not a sha256"},
+		{name: "short hash", version: CurrentVersion, edit: func(p *LockedPackage) { p.Integrity = "sha256:abc" }, wantErr: "not a sha256"},
+		{name: "local path package", version: CurrentVersion, edit: func(p *LockedPackage) { p.Integrity, p.Source, p.Path = "", SourcePath, "../utils" }},
+		{name: "dev package", version: CurrentVersion, edit: func(p *LockedPackage) { p.Group = GroupDev }},
+		{name: "unknown group", version: CurrentVersion, edit: func(p *LockedPackage) { p.Group = "test" }, wantErr: `utils: unknown group "test"`},
+		{name: "local without path", version: CurrentVersion, edit: func(p *LockedPackage) { p.Integrity, p.Source = "", SourcePath }, wantErr: "utils: missing path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg := complete
+			if tt.edit != nil {
+				tt.edit(&pkg)
+			}
+			lock := &LockFile{Version: tt.version, Packages: map[string]LockedPackage{"utils": pkg}}
+			err := lock.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLocalDir(t *testing.T) {
+	lockDir := filepath.Join("home", "app")
+	pkg := LockedPackage{Source: SourcePath, Path: "../libs/utils"}
+	if !pkg.IsLocal() {
+		t.Fatal("IsLocal() = false for a path package")
+	}
+	if got, want := pkg.LocalDir(lockDir), filepath.Join("home", "libs", "utils"); got != want {
+		t.Errorf("LocalDir() = %q, want %q", got, want)
+	}
+
+	abs, _ := filepath.Abs(filepath.Join("libs", "utils"))
+	pkg.Path = filepath.ToSlash(abs)
+	if got := pkg.LocalDir(lockDir); got != abs {
+		t.Errorf("LocalDir() = %q, want %q", got, abs)
+	}
+
+	registry := LockedPackage{Source: "docker.io/org/utils:1.0.0"}
+	if registry.IsLocal() {
+		t.Error("IsLocal() = true for a registry package")
+	}
+}
@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"time"
+)
+
+// maxDebugBody is how much of a response body --debug dumps
+const maxDebugBody = 4096
+
+// Transport is an http.RoundTripper that logs each request with --verbose,
+// dumps requests and responses with --debug (and to the log file), and
+// records their metadata with --trace
+type Transport struct {
+	Base http.RoundTripper // http.DefaultTransport when nil
+}
+
+// NewHTTPClient returns an HTTP client that logs through Transport, with a
+// timeout when it isn't zero
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: &Transport{}, Timeout: timeout}
+}
+
+// RoundTrip logs req and its response around the base transport
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	traced := tracing()
+	if !recording(LevelVerbose) && !traced {
+		return base.RoundTrip(req)
+	}
+
+	Verbosef("→ %s %s\n", req.Method, req.URL)
+	if recording(LevelDebug) {
+		if dump, err := httputil.DumpRequestOut(req, false); err == nil {
+			Debugf("%s", indent(string(dump)))
+		}
+	}
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	if traced {
+		t := &HTTPTrace{
+			Method:         req.Method,
+			URL:            Redact(req.URL.String()),
+			DurationMS:     time.Since(start).Milliseconds(),
+			RequestHeaders: traceHeaders(req.Header),
+		}
+		if err != nil {
+			t.Error = Redact(err.Error())
+		} else {
+			t.Status = resp.StatusCode
+			t.ResponseHeaders = traceHeaders(resp.Header)
+		}
+		traceRequest(t)
+	}
+	if err != nil {
+		Verbosef("✗ %s %s: %v\n", req.Method, req.URL, err)
+		return nil, err
+	}
+	Verbosef("← %s (%s)\n", resp.Status, time.Since(start).Round(time.Millisecond))
+
+	// Only text bodies are dumped: blobs are tarballs
+	if recording(LevelDebug) {
+		contentType := resp.Header.Get("Content-Type")
+		textBody := strings.Contains(contentType, "json") || strings.HasPrefix(contentType, "text/")
+		if dump, err := httputil.DumpResponse(resp, textBody); err == nil {
+			s := string(dump)
+			if len(s) > maxDebugBody {
+				s = s[:maxDebugBody] + "\n... (truncated)"
+			}
+			Debugf("%s\n", indent(s))
+		}
+	}
+	return resp, nil
+}
+
+// indent indents each line of a dump under the request line
+func indent(s string) string {
+	s = strings.TrimRight(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	return "    " + strings.ReplaceAll(s, "\n", "\n    ") + "\n"
+}
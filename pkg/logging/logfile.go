@@ -0,0 +1,173 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	// logFile receives every message as a JSON line, whatever the level,
+	// when --log-file or log_file is set
+	logFile io.WriteCloser
+	// trace is set by --trace: each registry request's metadata is recorded
+	// to the log file, or to stderr without one
+	trace bool
+)
+
+// Entry is a line of the log file
+type Entry struct {
+	Time  string     `json:"time"`
+	Level string     `json:"level"` // info (progress), verbose, debug, trace or error
+	Msg   string     `json:"msg,omitempty"`
+	HTTP  *HTTPTrace `json:"http,omitempty"` // The request, for trace entries
+}
+
+// HTTPTrace is what --trace records of a registry request: no bodies, and
+// credentials redacted
+type HTTPTrace struct {
+	Method          string              `json:"method"`
+	URL             string              `json:"url"`
+	Status          int                 `json:"status,omitempty"`
+	DurationMS      int64               `json:"duration_ms"`
+	RequestHeaders  map[string][]string `json:"request_headers,omitempty"`
+	ResponseHeaders map[string][]string `json:"response_headers,omitempty"`
+	Error           string              `json:"error,omitempty"`
+}
+
+// OpenLogFile appends every message from now on to the file at path as
+// JSON lines (Entry), whatever the level, creating it and its directory
+func OpenLogFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	SetLogFile(f)
+	return nil
+}
+
+// SetLogFile sets where log file entries are written, closing the previous
+// one; nil stops writing them. Tests use it.
+func SetLogFile(w io.WriteCloser) {
+	mu.Lock()
+	defer mu.Unlock()
+	if logFile != nil {
+		_ = logFile.Close()
+	}
+	logFile = w
+}
+
+// CloseLogFile stops writing log file entries
+func CloseLogFile() {
+	SetLogFile(nil)
+}
+
+// SetTrace sets whether registry requests are traced (--trace)
+func SetTrace(on bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	trace = on
+}
+
+// Logf writes a message to the log file without printing it, such as the
+// command being run
+func Logf(format string, args ...interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+	writeEntry(Entry{Level: levelName(LevelNormal), Msg: Redact(fmt.Sprintf(format, args...))})
+}
+
+// LogError writes the error a command failed with to the log file
+func LogError(err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	writeEntry(Entry{Level: "error", Msg: Redact(err.Error())})
+}
+
+// recording reports whether messages at l are printed or logged
+func recording(l Level) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return level >= l || logFile != nil
+}
+
+// tracing reports whether registry requests are traced
+func tracing() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return trace
+}
+
+// traceRequest records t to the log file, or to stderr without one
+func traceRequest(t *HTTPTrace) {
+	mu.Lock()
+	defer mu.Unlock()
+	e := Entry{Level: "trace", HTTP: t}
+	if logFile != nil {
+		writeEntry(e)
+		return
+	}
+	e.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	if data, err := json.Marshal(e); err == nil {
+		_, _ = fmt.Fprintf(stderr, "%s\n", data)
+	}
+}
+
+// writeEntry writes e to the log file, if there is one; mu is held
+func writeEntry(e Entry) {
+	if logFile == nil {
+		return
+	}
+	e.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	e.Msg = strings.TrimRight(e.Msg, "\n")
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	_, _ = logFile.Write(append(data, '\n'))
+}
+
+// levelName names l in log file entries
+func levelName(l Level) string {
+	if l == LevelNormal {
+		return "info"
+	}
+	return l.String()
+}
+
+// secretHeaders are headers whose values --trace leaves out
+var secretHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// traceHeaders returns a copy of h with the values of secretHeaders
+// replaced by [REDACTED]
+func traceHeaders(h http.Header) map[string][]string {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(h))
+	for name, values := range h {
+		if secretHeaders[http.CanonicalHeaderKey(name)] {
+			out[name] = []string{"[REDACTED]"}
+			continue
+		}
+		redacted := make([]string, len(values))
+		for i, v := range values {
+			redacted[i] = Redact(v)
+		}
+		out[name] = redacted
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readEntries returns the entries of the log file at path
+func readEntries(t *testing.T, path string) []Entry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("log line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestLogFile(t *testing.T) {
+	out, detail := capture(t, LevelQuiet)
+	path := filepath.Join(t.TempDir(), "logs", "aigg.log")
+	if err := OpenLogFile(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(CloseLogFile)
+
+	Logf("aigg pull %s", "ref")
+	Println("progress")
+	Verbosef("verbose\n")
+	Debugf("Authorization: Bearer abc\n")
+	CloseLogFile()
+
+	// Quiet on the terminal, everything in the file
+	if out.Len() != 0 || detail.Len() != 0 {
+		t.Errorf("printed %q and %q", out, detail)
+	}
+	var got []string
+	for _, e := range readEntries(t, path) {
+		if e.Time == "" {
+			t.Errorf("entry %+v has no time", e)
+		}
+		got = append(got, e.Level+": "+e.Msg)
+	}
+	want := []string{"info: aigg pull ref", "info: progress", "verbose: verbose", "debug: Authorization: Bearer [REDACTED]"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("log file has %q, want %q", got, want)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Docker-Distribution-Api-Version", "registry/2.0")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, detail := capture(t, LevelNormal)
+	SetTrace(true)
+	t.Cleanup(func() { SetTrace(false) })
+
+	req, _ := http.NewRequest("GET", server.URL+"/v2/?token=abc", nil)
+	req.Header.Set("Authorization", "Bearer abc")
+	resp, err := NewHTTPClient(0).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	var e Entry
+	if err := json.Unmarshal(detail.Bytes(), &e); err != nil {
+		t.Fatalf("trace %q: %v", detail, err)
+	}
+	if e.Level != "trace" || e.HTTP == nil {
+		t.Fatalf("trace entry = %+v", e)
+	}
+	h := e.HTTP
+	if h.Method != "GET" || h.URL != server.URL+"/v2/?token=[REDACTED]" || h.Status != http.StatusUnauthorized {
+		t.Errorf("trace = %+v", h)
+	}
+	if got := h.ResponseHeaders["Docker-Distribution-Api-Version"]; len(got) != 1 || got[0] != "registry/2.0" {
+		t.Errorf("response headers = %v", h.ResponseHeaders)
+	}
+	if strings.Contains(detail.String(), "abc") || strings.Contains(detail.String(), "session=secret") {
+		t.Errorf("trace leaks a secret: %s", detail)
+	}
+}
@@ -0,0 +1,126 @@
+// Package logging prints aigg's output at the level set by --quiet,
+// --verbose and --debug. Progress goes to stdout as before, unless quiet;
+// verbose and debug detail goes to stderr, so it never mixes with output
+// other tools read. With --log-file, everything down to debug is also
+// written to a file as JSON lines, and --trace records the metadata of each
+// registry request.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+)
+
+// Level is how much aigg prints
+type Level int
+
+const (
+	// LevelQuiet prints only results, warnings and errors (--quiet)
+	LevelQuiet Level = iota - 1
+	// LevelNormal also prints progress; the default
+	LevelNormal
+	// LevelVerbose also prints HTTP requests, paths and hashes (--verbose)
+	LevelVerbose
+	// LevelDebug also dumps registry requests and responses, with secrets
+	// redacted (--debug)
+	LevelDebug
+)
+
+// String returns the level's name: quiet, normal, verbose or debug
+func (l Level) String() string {
+	switch l {
+	case LevelQuiet:
+		return "quiet"
+	case LevelVerbose:
+		return "verbose"
+	case LevelDebug:
+		return "debug"
+	}
+	return "normal"
+}
+
+var (
+	mu     sync.Mutex
+	level            = LevelNormal
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// SetLevel sets how much is printed
+func SetLevel(l Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	level = l
+}
+
+// GetLevel returns how much is printed
+func GetLevel() Level {
+	mu.Lock()
+	defer mu.Unlock()
+	return level
+}
+
+// SetOutput sets where progress and detail are written; tests use it
+func SetOutput(out, detail io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	stdout, stderr = out, detail
+}
+
+// Enabled reports whether messages at l are printed
+func Enabled(l Level) bool {
+	return GetLevel() >= l
+}
+
+// Printf prints progress, like fmt.Printf, unless quiet
+func Printf(format string, args ...interface{}) {
+	write(LevelNormal, stdout, fmt.Sprintf(format, args...))
+}
+
+// Println prints progress, like fmt.Println, unless quiet
+func Println(args ...interface{}) {
+	write(LevelNormal, stdout, fmt.Sprintln(args...))
+}
+
+// Verbosef prints detail with --verbose or --debug
+func Verbosef(format string, args ...interface{}) {
+	write(LevelVerbose, stderr, Redact(fmt.Sprintf(format, args...)))
+}
+
+// Debugf prints detail with --debug
+func Debugf(format string, args ...interface{}) {
+	write(LevelDebug, stderr, Redact(fmt.Sprintf(format, args...)))
+}
+
+// write prints s to w when messages at l are printed, and logs it to the
+// log file at any level
+func write(l Level, w io.Writer, s string) {
+	mu.Lock()
+	defer mu.Unlock()
+	writeEntry(Entry{Level: levelName(l), Msg: s})
+	if level < l {
+		return
+	}
+	_, _ = io.WriteString(w, s)
+}
+
+var (
+	// authHeaderPattern matches credentials in Authorization headers
+	authHeaderPattern = regexp.MustCompile(`(?im)^([ \t]*(?:Proxy-)?Authorization:[ \t]*\S+[ \t]+)\S+`)
+	// secretFieldPattern matches secrets in JSON bodies, such as a token
+	// endpoint's response
+	secretFieldPattern = regexp.MustCompile(`(?i)("(?:token|access_token|refresh_token|id_token|password|secret|auth|identitytoken)"\s*:\s*")[^"]*(")`)
+	// secretQueryPattern matches secrets in URL query strings
+	secretQueryPattern = regexp.MustCompile(`(?i)([?&](?:token|access_token|password|secret)=)[^&\s]*`)
+)
+
+// Redact replaces credentials in s, such as Authorization headers and the
+// tokens in registry auth responses, with [REDACTED]
+func Redact(s string) string {
+	s = authHeaderPattern.ReplaceAllString(s, "${1}[REDACTED]")
+	s = secretFieldPattern.ReplaceAllString(s, "${1}[REDACTED]${2}")
+	return secretQueryPattern.ReplaceAllString(s, "${1}[REDACTED]")
+}
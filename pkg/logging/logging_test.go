@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// capture sets level and collects what is printed until the test ends
+func capture(t *testing.T, l Level) (out, detail *bytes.Buffer) {
+	t.Helper()
+	out, detail = &bytes.Buffer{}, &bytes.Buffer{}
+	origLevel := GetLevel()
+	SetLevel(l)
+	SetOutput(out, detail)
+	t.Cleanup(func() {
+		SetLevel(origLevel)
+		SetOutput(os.Stdout, os.Stderr)
+	})
+	return out, detail
+}
+
+func TestLevels(t *testing.T) {
+	tests := []struct {
+		level               Level
+		wantOut, wantDetail string
+	}{
+		{LevelQuiet, "", ""},
+		{LevelNormal, "progress\n", ""},
+		{LevelVerbose, "progress\n", "verbose\n"},
+		{LevelDebug, "progress\n", "verbose\ndebug\n"},
+	}
+	for _, tt := range tests {
+		out, detail := capture(t, tt.level)
+		Println("progress")
+		Verbosef("verbose\n")
+		Debugf("debug\n")
+		if out.String() != tt.wantOut || detail.String() != tt.wantDetail {
+			t.Errorf("level %d printed %q and %q, want %q and %q", tt.level, out, detail, tt.wantOut, tt.wantDetail)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := map[string]string{
+		"Authorization: Bearer abc.def":                  "Authorization: Bearer [REDACTED]",
+		"    authorization: Basic dXNlcjpwYXNz":          "    authorization: Basic [REDACTED]",
+		`{"token": "abc", "expires_in": 300}`:            `{"token": "[REDACTED]", "expires_in": 300}`,
+		`{"access_token":"abc","issued_at":"now"}`:       `{"access_token":"[REDACTED]","issued_at":"now"}`,
+		"GET https://r.example/v2/?token=abc&scope=pull": "GET https://r.example/v2/?token=[REDACTED]&scope=pull",
+		"Content-Type: application/json":                 "Content-Type: application/json",
+	}
+	for in, want := range tests {
+		if got := Redact(in); got != want {
+			t.Errorf("Redact(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"token": "secret-token"}`))
+	}))
+	defer server.Close()
+
+	_, detail := capture(t, LevelDebug)
+	req, _ := http.NewRequest("GET", server.URL+"/token", nil)
+	req.SetBasicAuth("user", "password")
+	resp, err := NewHTTPClient(0).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body bytes.Buffer
+	_, _ = body.ReadFrom(resp.Body)
+	_ = resp.Body.Close()
+
+	// The caller still reads the whole body
+	if body.String() != `{"token": "secret-token"}` {
+		t.Errorf("body = %q", body.String())
+	}
+	log := detail.String()
+	for _, want := range []string{"→ GET " + server.URL + "/token", "← 200 OK", "Authorization: Basic [REDACTED]", `{"token": "[REDACTED]"}`} {
+		if !strings.Contains(log, want) {
+			t.Errorf("debug log lacks %q:\n%s", want, log)
+		}
+	}
+	if strings.Contains(log, "secret-token") || strings.Contains(log, "dXNlcjpwYXNzd29yZA") {
+		t.Errorf("debug log leaks a secret:\n%s", log)
+	}
+}
@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// plainMarkers are the ASCII replacements --plain prints for the emoji and
+// symbols in aigg's output. Other characters are printed as they are.
+var plainMarkers = map[rune]string{
+	'✓':      "[ok]",
+	'✅':      "[ok]",
+	'⚠':      "[warn]",
+	'❌':      "[error]",
+	'✗':      "[fail]",
+	'💡':      "[tip]",
+	'ℹ':      "[info]",
+	'📦':      "[pkg]",
+	'🔨':      "[build]",
+	'🔒':      "[lock]",
+	'🔍':      "*",
+	'📁':      "*",
+	'→':      "->",
+	'←':      "<-",
+	'↻':      "~>",
+	'•':      "-",
+	'—':      "-",
+	'│':      "|",
+	'─':      "-",
+	'├':      "|",
+	'└':      "`",
+	'\uFE0F': "", // Emoji presentation selector, as in ⚠️
+}
+
+// Plain returns s with emoji and symbols replaced by ASCII markers
+func Plain(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if marker, ok := plainMarkers[r]; ok {
+			b.WriteString(marker)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// PlainWriter writes to another writer with emoji and symbols replaced by
+// ASCII markers. A character split across writes is held until the rest of
+// it arrives; anything else is written straight away, so prompts without a
+// newline still show.
+type PlainWriter struct {
+	w       io.Writer
+	partial []byte
+}
+
+// NewPlainWriter returns a PlainWriter writing to w
+func NewPlainWriter(w io.Writer) *PlainWriter {
+	return &PlainWriter{w: w}
+}
+
+// Write writes p, as replaced, and always reports all of it written unless
+// the underlying writer fails
+func (pw *PlainWriter) Write(p []byte) (int, error) {
+	data := append(pw.partial, p...)
+
+	// Hold back a trailing character that isn't complete yet
+	end := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				end = i
+			}
+			break
+		}
+	}
+	pw.partial = append([]byte(nil), data[end:]...)
+
+	if _, err := io.WriteString(pw.w, Plain(string(data[:end]))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes what is held of a character that never completed
+func (pw *PlainWriter) Flush() error {
+	if len(pw.partial) == 0 {
+		return nil
+	}
+	_, err := pw.w.Write(pw.partial)
+	pw.partial = nil
+	return err
+}
@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPlain(t *testing.T) {
+	tests := map[string]string{
+		"✓ Built utils:1.0.0":         "[ok] Built utils:1.0.0",
+		"⚠️  Warning: no README":      "[warn]  Warning: no README",
+		"❌ Found 2 error(s)":          "[error] Found 2 error(s)",
+		"  • requests — >=2.0":        "  - requests - >=2.0",
+		"→ GET https://r.example/v2/": "-> GET https://r.example/v2/",
+		"café":                        "café",
+	}
+	for in, want := range tests {
+		if got := Plain(in); got != want {
+			t.Errorf("Plain(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPlainWriterSplitsCharacters(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewPlainWriter(&buf)
+
+	// Write a byte at a time, so every multi-byte character is split
+	for _, b := range []byte("✓ done\nPassword: ") {
+		if _, err := w.Write([]byte{b}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := buf.String(); got != "[ok] done\nPassword: " {
+		t.Errorf("wrote %q", got)
+	}
+
+	// An incomplete character is written as it is on Flush
+	_, _ = w.Write([]byte("✓")[:2])
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "[ok] done\nPassword: \xe2\x9c" {
+		t.Errorf("wrote %q after Flush", got)
+	}
+}
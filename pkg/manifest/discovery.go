@@ -26,13 +26,14 @@ func NewFileDiscovery(baseDir string, manifestExcludes []string) (*FileDiscovery
 	}, nil
 }
 
-// Discover finds files based on FileSpec
-func (fd *FileDiscovery) Discover(spec FileSpec, language Language) ([]string, error) {
+// Discover finds files based on FileSpec. Auto-discovery includes the
+// source files of every given language.
+func (fd *FileDiscovery) Discover(spec FileSpec, languages ...Language) ([]string, error) {
 	patterns, isAuto := spec.GetIncludePatterns()
 
 	if isAuto {
 		// Auto-discovery based on language
-		return fd.autoDiscover(language)
+		return fd.autoDiscover(languages)
 	}
 
 	if len(patterns) == 0 {
@@ -44,8 +45,11 @@ func (fd *FileDiscovery) Discover(spec FileSpec, language Language) ([]string, e
 }
 
 // autoDiscover finds files based on language conventions
-func (fd *FileDiscovery) autoDiscover(language Language) ([]string, error) {
-	patterns := fd.getLanguagePatterns(language.Name)
+func (fd *FileDiscovery) autoDiscover(languages []Language) ([]string, error) {
+	var patterns []string
+	for _, language := range languages {
+		patterns = append(patterns, fd.getLanguagePatterns(language.Name)...)
+	}
 	return fd.discoverWithPatterns(patterns)
 }
 
@@ -56,19 +60,26 @@ func (fd *FileDiscovery) GetIgnoreManager() *IgnoreManager {
 
 // getLanguagePatterns returns file patterns for a language
 func (fd *FileDiscovery) getLanguagePatterns(lang string) []string {
-	patterns := map[string][]string{
-		"python":     {"**/*.py"},
-		"javascript": {"**/*.js", "**/*.ts", "**/*.jsx", "**/*.tsx", "**/*.mjs", "**/*.cjs"},
-		"go":         {"**/*.go"},
-		"rust":       {"**/*.rs"},
-	}
-
-	if p, ok := patterns[lang]; ok {
+	if p, ok := languagePatterns[lang]; ok {
 		return p
 	}
 	return []string{"**/*"}
 }
 
+// languagePatterns are the source files auto-discovery includes per language,
+// along with type information: Python stubs and PEP 561 py.typed markers,
+// and TypeScript declarations (.d.ts, .d.mts, .d.cts)
+var languagePatterns = map[string][]string{
+	"python":     {"**/*.py", "**/*.ipynb", "**/*.pyi", "**/py.typed"},
+	"javascript": {"**/*.js", "**/*.ts", "**/*.jsx", "**/*.tsx", "**/*.mjs", "**/*.cjs", "**/*.mts", "**/*.cts"},
+	"go":         {"**/*.go"},
+	"rust":       {"**/*.rs"},
+	"ruby":       {"**/*.rb"},
+	"java":       {"**/*.java"},
+	"csharp":     {"**/*.cs"},
+	"php":        {"**/*.php"},
+}
+
 // discoverWithPatterns finds files matching include patterns, using IgnoreManager for exclusions
 func (fd *FileDiscovery) discoverWithPatterns(includePatterns []string) ([]string, error) {
 	var files []string
@@ -112,15 +123,15 @@ func (fd *FileDiscovery) discoverWithPatterns(includePatterns []string) ([]strin
 // matchesAnyPattern checks if path matches any include pattern
 func (fd *FileDiscovery) matchesAnyPattern(path string, patterns []string) bool {
 	for _, pattern := range patterns {
-		if fd.matchPattern(path, pattern) {
+		if matchIncludePattern(path, pattern) {
 			return true
 		}
 	}
 	return false
 }
 
-// matchPattern matches a path against a glob pattern
-func (fd *FileDiscovery) matchPattern(path, pattern string) bool {
+// matchIncludePattern matches a path against a files.include glob pattern
+func matchIncludePattern(path, pattern string) bool {
 	// Use filepath.Match for simple patterns without **
 	if !strings.Contains(pattern, "**") {
 		matched, _ := filepath.Match(pattern, path)
@@ -0,0 +1,146 @@
+package manifest
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ExportRoot is the exports entry for the package itself, e.g. what
+// `from aigogo.pkg import x` or require('@aigogo/pkg') loads
+const ExportRoot = "."
+
+// exportNamePattern matches named exports: an identifier that may also
+// contain hyphens, which Python imports see as underscores
+var exportNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// exportExtensions maps the file extensions an export may point at to the
+// language that imports it
+var exportExtensions = map[string]string{
+	".py":  "python",
+	".js":  "javascript",
+	".mjs": "javascript",
+	".cjs": "javascript",
+}
+
+// ExportLanguage returns the language that imports an exported file
+// ("python" or "javascript"), or "" if exports can't point at it
+func ExportLanguage(file string) string {
+	return exportExtensions[strings.ToLower(path.Ext(file))]
+}
+
+// ExportsFor returns the exports of the given language. TypeScript
+// packages are imported through their compiled JavaScript.
+func ExportsFor(exports map[string]string, language string) map[string]string {
+	language = strings.ToLower(language)
+	if language == "typescript" {
+		language = "javascript"
+	}
+	result := make(map[string]string)
+	for name, file := range exports {
+		if ExportLanguage(file) == language {
+			result[name] = file
+		}
+	}
+	return result
+}
+
+// PythonExportModule returns the submodule a named export is imported as
+// from Python, e.g. "cli-tools" is aigogo.<pkg>.cli_tools
+func PythonExportModule(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// PythonModulePath returns the dotted module path of a packaged .py file,
+// e.g. "tools/cli.py" is "tools.cli" and "tools/__init__.py" is "tools"
+func PythonModulePath(file string) string {
+	module := strings.TrimSuffix(file, path.Ext(file))
+	module = strings.TrimSuffix(module, "/__init__")
+	return strings.ReplaceAll(module, "/", ".")
+}
+
+// validateExports checks the names and paths of an exports map
+func validateExports(exports map[string]string) error {
+	names := make([]string, 0, len(exports))
+	for name := range exports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pythonModules := make(map[string]string)
+	for _, name := range names {
+		file := exports[name]
+		if name != ExportRoot && !exportNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid exports name %q: must be %q or start with a letter or underscore and contain only letters, digits, underscores and hyphens", name, ExportRoot)
+		}
+		if file == "" {
+			return fmt.Errorf("exports.%s: file path cannot be empty", name)
+		}
+		clean := path.Clean(file)
+		if path.IsAbs(file) || strings.Contains(file, `\`) || clean != file || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("exports.%s: %s must be a clean relative path inside the package, with forward slashes", name, file)
+		}
+		language := ExportLanguage(file)
+		if language == "" {
+			return fmt.Errorf("exports.%s: %s is not a Python (.py) or JavaScript (.js, .mjs, .cjs) file", name, file)
+		}
+		if language == "python" && name != ExportRoot {
+			module := PythonExportModule(name)
+			if other, ok := pythonModules[module]; ok {
+				return fmt.Errorf("exports %q and %q are both imported from Python as %s", other, name, module)
+			}
+			pythonModules[module] = name
+		}
+	}
+	return nil
+}
+
+// CheckExports checks that the exports of m point at files in the package
+// and that the shims aigg install generates for Python don't replace
+// packaged files. files are the package's paths relative to its root.
+func CheckExports(m *Manifest, files []string) error {
+	if len(m.Exports) == 0 {
+		return nil
+	}
+	packaged := make(map[string]bool, len(files))
+	topLevel := make(map[string]bool)
+	for _, f := range files {
+		packaged[f] = true
+		topLevel[strings.SplitN(f, "/", 2)[0]] = true
+	}
+
+	names := make([]string, 0, len(m.Exports))
+	for name := range m.Exports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		file := m.Exports[name]
+		if !packaged[file] {
+			return fmt.Errorf("exports.%s references file %q which is not in the package files\n"+
+				"Add it with: aigg add file %s", name, file, file)
+		}
+		if ExportLanguage(file) != "python" {
+			continue
+		}
+		if name == ExportRoot {
+			if file != "__init__.py" && packaged["__init__.py"] {
+				return fmt.Errorf("exports.%s would replace the package's own __init__.py\n"+
+					"Remove the %q export or re-export from __init__.py yourself", ExportRoot, ExportRoot)
+			}
+			continue
+		}
+		module := PythonExportModule(name)
+		if file == module+".py" || file == module+"/__init__.py" {
+			continue // already importable under its name
+		}
+		if topLevel[module+".py"] || topLevel[module] {
+			return fmt.Errorf("exports.%s would replace the package's own %s, which Python imports as %s\n"+
+				"Rename the export or the file", name, module, module)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateExports(t *testing.T) {
+	tests := []struct {
+		name    string
+		exports map[string]string
+		wantErr string
+	}{
+		{name: "none"},
+		{name: "root and named", exports: map[string]string{".": "main.py", "cli": "tools/cli.py", "web-ui": "ui/index.mjs"}},
+		{name: "bad name", exports: map[string]string{"./cli": "cli.js"}, wantErr: "invalid exports name"},
+		{name: "empty path", exports: map[string]string{"cli": ""}, wantErr: "cannot be empty"},
+		{name: "escapes package", exports: map[string]string{"cli": "../cli.py"}, wantErr: "inside the package"},
+		{name: "not clean", exports: map[string]string{"cli": "./cli.py"}, wantErr: "inside the package"},
+		{name: "absolute", exports: map[string]string{"cli": "/cli.py"}, wantErr: "inside the package"},
+		{name: "unsupported file", exports: map[string]string{"cli": "cli.rb"}, wantErr: "not a Python"},
+		{name: "python clash", exports: map[string]string{"cli-tools": "a.py", "cli_tools": "b.py"}, wantErr: "both imported from Python as cli_tools"},
+		{name: "clash across languages", exports: map[string]string{"cli-tools": "a.js", "cli_tools": "b.py"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateExports(tt.exports)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateExports() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validateExports() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckExports(t *testing.T) {
+	files := []string{"main.py", "cli.py", "tools/run.py", "index.js", "__init__.py"}
+	tests := []struct {
+		name    string
+		exports map[string]string
+		files   []string
+		wantErr string
+	}{
+		{name: "named", exports: map[string]string{"cli": "cli.py", "run": "tools/run.py", "web": "index.js"}, files: files},
+		{name: "root is __init__.py", exports: map[string]string{".": "__init__.py"}, files: files},
+		{name: "root without __init__.py", exports: map[string]string{".": "main.py"}, files: []string{"main.py"}},
+		{name: "missing file", exports: map[string]string{"cli": "missing.py"}, files: files, wantErr: `references file "missing.py"`},
+		{name: "root replaces __init__.py", exports: map[string]string{".": "main.py"}, files: files, wantErr: "own __init__.py"},
+		{name: "shim replaces file", exports: map[string]string{"cli": "tools/run.py"}, files: files, wantErr: "own cli"},
+		{name: "shim replaces directory", exports: map[string]string{"tools": "main.py"}, files: files, wantErr: "own tools"},
+		{name: "javascript name isn't a module", exports: map[string]string{"tools": "index.js"}, files: files},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckExports(&Manifest{Exports: tt.exports}, tt.files)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CheckExports() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("CheckExports() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExportsFor(t *testing.T) {
+	exports := map[string]string{".": "main.py", "cli": "cli.mjs", "web": "web.js"}
+
+	if got := ExportsFor(exports, "python"); len(got) != 1 || got["."] != "main.py" {
+		t.Errorf("ExportsFor(python) = %v", got)
+	}
+	if got := ExportsFor(exports, "typescript"); len(got) != 2 || got["cli"] != "cli.mjs" {
+		t.Errorf("ExportsFor(typescript) = %v", got)
+	}
+	if got := ExportsFor(nil, "ruby"); len(got) != 0 {
+		t.Errorf("ExportsFor(nil) = %v", got)
+	}
+}
+
+func TestPythonModulePath(t *testing.T) {
+	tests := map[string]string{
+		"main.py":           "main",
+		"tools/cli.py":      "tools.cli",
+		"tools/__init__.py": "tools",
+	}
+	for file, want := range tests {
+		if got := PythonModulePath(file); got != want {
+			t.Errorf("PythonModulePath(%q) = %q, want %q", file, got, want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// FetchRegistryManifest returns the aigogo.json of a registry package, for
+// an "extends" that names one. This package can't reach registries, so the
+// CLI sets it; while it is nil, extending a registry package is an error.
+var FetchRegistryManifest func(ref string) ([]byte, error)
+
+// isFileReference reports whether an extends value is a file path rather
+// than a registry reference: it ends in .json or starts with ./, ../ or /
+func isFileReference(ref string) bool {
+	return strings.HasSuffix(ref, ".json") || strings.HasPrefix(ref, "./") ||
+		strings.HasPrefix(ref, "../") || filepath.IsAbs(ref)
+}
+
+// resolveExtends parses a manifest and merges the manifests it extends
+// underneath it. It returns the merged document and the inherited part on
+// its own, which is nil when the manifest extends nothing. source is the
+// manifest's file path, or "registry:<ref>" for a registry base; chain
+// lists the sources already being resolved, to detect cycles.
+//
+// Precedence: the local manifest wins over its base, which wins over the
+// base it extends. Objects are merged key by key; arrays and other values
+// are replaced whole. Empty strings and nulls don't override an inherited
+// value.
+func resolveExtends(data []byte, source string, chain []string) (merged, inherited map[string]interface{}, err error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	ext, ok := doc["extends"]
+	if !ok || ext == nil {
+		return doc, nil, nil
+	}
+	ref, ok := ext.(string)
+	if !ok || ref == "" {
+		return nil, nil, fmt.Errorf("extends must be a file path or a registry reference")
+	}
+
+	chain = append(chain, source)
+	var baseSource string
+	var baseData []byte
+	if isFileReference(ref) {
+		if strings.HasPrefix(source, "registry:") {
+			return nil, nil, fmt.Errorf("%s extends %s: a registry base can only extend other registry packages", strings.TrimPrefix(source, "registry:"), ref)
+		}
+		path := ref
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(source), path)
+		}
+		baseSource, err = filepath.Abs(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve extends %s: %w", ref, err)
+		}
+		if err := checkExtendsCycle(chain, baseSource); err != nil {
+			return nil, nil, err
+		}
+		if baseData, err = os.ReadFile(baseSource); err != nil {
+			return nil, nil, fmt.Errorf("failed to read extends %s: %w", ref, err)
+		}
+	} else {
+		baseSource = "registry:" + ref
+		if err := checkExtendsCycle(chain, baseSource); err != nil {
+			return nil, nil, err
+		}
+		if FetchRegistryManifest == nil {
+			return nil, nil, fmt.Errorf("extends %s: registry references are not supported here", ref)
+		}
+		if baseData, err = FetchRegistryManifest(ref); err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch extends %s: %w", ref, err)
+		}
+	}
+
+	if baseData, _, err = StripJSONC(baseData); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse extends %s: %w", ref, err)
+	}
+	base, _, err := resolveExtends(baseData, baseSource, chain)
+	if err != nil {
+		return nil, nil, err
+	}
+	delete(base, "extends")
+
+	return mergeJSON(base, doc), base, nil
+}
+
+// checkExtendsCycle returns an error when source is already in chain
+func checkExtendsCycle(chain []string, source string) error {
+	for i, s := range chain {
+		if s == source {
+			names := make([]string, 0, len(chain)-i+1)
+			for _, c := range append(chain[i:], source) {
+				names = append(names, strings.TrimPrefix(c, "registry:"))
+			}
+			return fmt.Errorf("extends cycle: %s", strings.Join(names, " -> "))
+		}
+	}
+	return nil
+}
+
+// mergeJSON returns base with local merged over it, recursing into objects
+// that both have. Neither argument is modified.
+func mergeJSON(base, local map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(local))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range local {
+		if v == nil || v == "" {
+			if _, ok := base[k]; ok {
+				continue
+			}
+		}
+		baseObj, baseIsObj := base[k].(map[string]interface{})
+		localObj, localIsObj := v.(map[string]interface{})
+		if baseIsObj && localIsObj {
+			merged[k] = mergeJSON(baseObj, localObj)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// Resolved returns a copy of the manifest that no longer extends anything:
+// saving it writes every field, inherited or not. Builds package it, so
+// consumers never need the base.
+func (m *Manifest) Resolved() *Manifest {
+	resolved := *m
+	resolved.Extends = ""
+	resolved.inherited = nil
+	resolved.commented = false
+	return &resolved
+}
+
+// withoutInherited returns a copy of the manifest with the fields it
+// inherits unchanged from its base cleared, so that Save writes only what
+// the local file sets
+func (m *Manifest) withoutInherited() *Manifest {
+	local := *m
+	local.inherited = nil
+	stripInherited(reflect.ValueOf(&local).Elem(), m.inherited)
+	return &local
+}
+
+// stripInherited zeroes the fields of the struct v whose JSON equals the
+// corresponding value in base, recursing into objects. Pointers and maps
+// are copied before they are changed, so values v shares with the
+// original manifest are left alone.
+func stripInherited(v reflect.Value, base map[string]interface{}) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		baseValue, ok := base[name]
+		if !ok {
+			continue
+		}
+		fv := v.Field(i)
+		baseObj, baseIsObj := baseValue.(map[string]interface{})
+
+		switch {
+		case baseIsObj && fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct:
+			if fv.IsNil() {
+				continue
+			}
+			cp := reflect.New(fv.Type().Elem())
+			cp.Elem().Set(fv.Elem())
+			stripInherited(cp.Elem(), baseObj)
+			if cp.Elem().IsZero() {
+				fv.Set(reflect.Zero(fv.Type()))
+			} else {
+				fv.Set(cp)
+			}
+		case baseIsObj && fv.Kind() == reflect.Struct:
+			stripInherited(fv, baseObj)
+		case baseIsObj && fv.Kind() == reflect.Map:
+			if fv.IsNil() {
+				continue
+			}
+			cp := reflect.MakeMap(fv.Type())
+			iter := fv.MapRange()
+			for iter.Next() {
+				if !jsonEqual(iter.Value().Interface(), baseObj[iter.Key().String()]) {
+					cp.SetMapIndex(iter.Key(), iter.Value())
+				}
+			}
+			if cp.Len() == 0 {
+				cp = reflect.Zero(fv.Type())
+			}
+			fv.Set(cp)
+		default:
+			if jsonEqual(fv.Interface(), baseValue) {
+				fv.Set(reflect.Zero(fv.Type()))
+			}
+		}
+	}
+}
+
+// jsonEqual reports whether v encodes to the JSON value decoded as want
+func jsonEqual(v interface{}, want interface{}) bool {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+	var got interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(got, want)
+}
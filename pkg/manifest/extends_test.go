@@ -0,0 +1,244 @@
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const baseManifest = `{
+  "name": "base",
+  "version": "0.1.0",
+  "author": "Team",
+  "language": {"name": "python", "version": ">=3.8"},
+  "files": {"include": ["*.py"]},
+  "metadata": {"license": "MIT", "keywords": ["shared"]},
+  "scripts": {"lint": "ruff check .", "test": "pytest"}
+}`
+
+func TestLoadExtends(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "aigogo.base.json"), baseManifest)
+	path := filepath.Join(tmpDir, "pkg", "aigogo.json")
+	writeFile(t, path, `{
+  "extends": "../aigogo.base.json",
+  "name": "child",
+  "version": "1.0.0",
+  "description": "",
+  "language": {"version": ">=3.10"},
+  "metadata": {"keywords": ["child"]},
+  "scripts": {"test": "pytest -q"}
+}`)
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if m.Name != "child" || m.Version != "1.0.0" {
+		t.Errorf("local scalars not kept: %s@%s", m.Name, m.Version)
+	}
+	if m.Author != "Team" {
+		t.Errorf("Author = %q, want inherited %q", m.Author, "Team")
+	}
+	if m.Language != (Language{Name: "python", Version: ">=3.10"}) {
+		t.Errorf("Language = %+v, want objects merged key by key", m.Language)
+	}
+	if m.Metadata.License != "MIT" {
+		t.Errorf("License = %q, want inherited MIT", m.Metadata.License)
+	}
+	if !reflect.DeepEqual(m.Metadata.Keywords, []string{"child"}) {
+		t.Errorf("Keywords = %v, want local array to replace the base's", m.Metadata.Keywords)
+	}
+	wantScripts := map[string]string{"lint": "ruff check .", "test": "pytest -q"}
+	if !reflect.DeepEqual(m.Scripts, wantScripts) {
+		t.Errorf("Scripts = %v, want %v", m.Scripts, wantScripts)
+	}
+	if m.Extends != "../aigogo.base.json" {
+		t.Errorf("Extends = %q", m.Extends)
+	}
+}
+
+func TestLoadExtendsChain(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "root.json"), baseManifest)
+	writeFile(t, filepath.Join(tmpDir, "team", "team.json"), `{"extends": "../root.json", "author": "Team B", "version": "0.2.0"}`)
+	path := filepath.Join(tmpDir, "team", "pkg", "aigogo.json")
+	writeFile(t, path, `{"extends": "../team.json", "name": "child"}`)
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if m.Name != "child" || m.Author != "Team B" || m.Version != "0.2.0" || m.Language.Name != "python" {
+		t.Errorf("chain not merged in order: %+v", m)
+	}
+}
+
+func TestLoadExtendsCycle(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "a.json"), `{"extends": "./b.json"}`)
+	writeFile(t, filepath.Join(tmpDir, "b.json"), `{"extends": "./a.json"}`)
+	path := filepath.Join(tmpDir, "aigogo.json")
+	writeFile(t, path, `{"extends": "./a.json", "name": "child"}`)
+
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), "extends cycle") {
+		t.Fatalf("Load error = %v, want an extends cycle", err)
+	}
+	if !strings.Contains(err.Error(), "a.json -> ") || !strings.Contains(err.Error(), "b.json -> ") {
+		t.Errorf("cycle error should show the chain, got: %v", err)
+	}
+}
+
+func TestLoadExtendsMissingBase(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "aigogo.json")
+	writeFile(t, path, `{"extends": "../nowhere.json", "name": "child"}`)
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "failed to read extends ../nowhere.json") {
+		t.Errorf("Load error = %v, want missing base", err)
+	}
+}
+
+func TestLoadExtendsRegistry(t *testing.T) {
+	defer func(f func(string) ([]byte, error)) { FetchRegistryManifest = f }(FetchRegistryManifest)
+
+	var fetched []string
+	FetchRegistryManifest = func(ref string) ([]byte, error) {
+		fetched = append(fetched, ref)
+		if ref == "ghcr.io/org/base:1.0.0" {
+			return []byte(baseManifest), nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "aigogo.json")
+	writeFile(t, path, `{"extends": "ghcr.io/org/base:1.0.0", "name": "child"}`)
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if m.Author != "Team" || !reflect.DeepEqual(fetched, []string{"ghcr.io/org/base:1.0.0"}) {
+		t.Errorf("registry base not used: author %q, fetched %v", m.Author, fetched)
+	}
+
+	// A registry base can't reach files next to the project
+	FetchRegistryManifest = func(string) ([]byte, error) {
+		return []byte(`{"extends": "../local.json"}`), nil
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "can only extend other registry packages") {
+		t.Errorf("Load error = %v, want registry base rejected", err)
+	}
+}
+
+func TestSaveExtendsKeepsOnlyLocalFields(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "base.json"), baseManifest)
+	path := filepath.Join(tmpDir, "aigogo.json")
+	writeFile(t, path, `{
+  "extends": "./base.json",
+  "name": "child",
+  "language": {"version": ">=3.10"},
+  "scripts": {"test": "pytest -q"}
+}`)
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	m.Version = "1.0.1"
+	m.Scripts["fmt"] = "ruff format ."
+	if err := Save(path, m); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]interface{}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	// Fields without omitempty are written empty, and empty values don't
+	// override the base, so they still inherit
+	want := map[string]interface{}{
+		"extends":  "./base.json",
+		"name":     "child",
+		"version":  "1.0.1",
+		"language": map[string]interface{}{"name": "", "version": ">=3.10"},
+		"files":    map[string]interface{}{},
+		"scripts":  map[string]interface{}{"test": "pytest -q", "fmt": "ruff format ."},
+		"metadata": map[string]interface{}{},
+	}
+	if !reflect.DeepEqual(saved, want) {
+		t.Errorf("saved manifest =\n%s\nwant only local fields", data)
+	}
+
+	// Saving must not touch the loaded manifest
+	if m.Author != "Team" || m.Scripts["lint"] != "ruff check ." {
+		t.Errorf("Save modified the manifest: %+v", m)
+	}
+
+	// And the saved file loads back to the same manifest
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if !reflect.DeepEqual(reloaded.Resolved(), m.Resolved()) {
+		t.Errorf("reloaded manifest differs:\n%+v\n%+v", reloaded, m)
+	}
+}
+
+func TestResolved(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "base.json"), baseManifest)
+	path := filepath.Join(tmpDir, "aigogo.json")
+	writeFile(t, path, `{"extends": "./base.json", "name": "child"}`)
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	data, err := Marshal(m.Resolved())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "extends") || !strings.Contains(string(data), `"author": "Team"`) {
+		t.Errorf("resolved manifest should be standalone, got:\n%s", data)
+	}
+}
+
+func TestValidateSchemaExtends(t *testing.T) {
+	errs, err := ValidateSchema([]byte(`{"extends": "../aigogo.base.json", "name": "child"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 0 {
+		t.Errorf("manifest with extends may omit required fields, got %v", errs)
+	}
+
+	errs, err = ValidateSchema([]byte(`{"name": "child"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 3 {
+		t.Errorf("expected version, language and files to be required, got %v", errs)
+	}
+}
@@ -0,0 +1,93 @@
+package manifest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// extraName matches an extra's name: the normalized form PEP 685 uses, so
+// it can become a pyproject optional-dependencies group as is
+var extraName = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// ExtraNames returns the names of the package's extras, sorted
+func (m *Manifest) ExtraNames() []string {
+	if m.Dependencies == nil {
+		return nil
+	}
+	names := make([]string, 0, len(m.Dependencies.Extras))
+	for name := range m.Dependencies.Extras {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExtraDependencies returns the dependencies of every extra, in the order
+// of the extras' names
+func (m *Manifest) ExtraDependencies() []Dependency {
+	var deps []Dependency
+	for _, name := range m.ExtraNames() {
+		deps = append(deps, m.Dependencies.Extras[name]...)
+	}
+	return deps
+}
+
+// CheckExtras returns an error naming the first of extras the package
+// doesn't define, along with the ones it does
+func (m *Manifest) CheckExtras(extras []string) error {
+	names := m.ExtraNames()
+	for _, extra := range extras {
+		if containsString(names, extra) {
+			continue
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("%s has no extra %q (it defines none)", m.Name, extra)
+		}
+		return fmt.Errorf("%s has no extra %q (available: %s)", m.Name, extra, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// WithExtras returns a copy of the manifest whose runtime dependencies also
+// include those of the named extras, as a consumer that selected them
+// needs. Extras the package doesn't define are ignored.
+func (m *Manifest) WithExtras(extras []string) *Manifest {
+	if len(extras) == 0 || m.Dependencies == nil {
+		return m
+	}
+	view := *m
+	deps := *m.Dependencies
+	deps.Runtime = append([]Dependency{}, m.Dependencies.Runtime...)
+	for _, extra := range extras {
+		deps.Runtime = append(deps.Runtime, m.Dependencies.Extras[extra]...)
+	}
+	view.Dependencies = &deps
+	return &view
+}
+
+// validateExtras checks extra names and the dependencies they list
+func validateExtras(m *Manifest) error {
+	for _, name := range m.ExtraNames() {
+		if !extraName.MatchString(name) {
+			return fmt.Errorf("invalid extra name %q: use lowercase letters, digits and single hyphens", name)
+		}
+		group := m.Dependencies.Extras[name]
+		if len(group) == 0 {
+			return fmt.Errorf("extra %s has no dependencies", name)
+		}
+		for _, dep := range group {
+			if dep.Package == "" {
+				return fmt.Errorf("extra %s: dependency package name is required", name)
+			}
+			if dep.Version == "" {
+				return fmt.Errorf("extra %s: dependency version is required for %s", name, dep.Package)
+			}
+			if err := validateDependencyLanguage(m, dep); err != nil {
+				return fmt.Errorf("extra %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package manifest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func extrasManifest() *Manifest {
+	return &Manifest{
+		Name:     "plots",
+		Version:  "1.0.0",
+		Language: Language{Name: "python", Version: ">=3.8"},
+		Dependencies: &Dependencies{
+			Runtime: []Dependency{{Package: "numpy", Version: ">=1.24"}},
+			Extras: map[string][]Dependency{
+				"viz": {{Package: "matplotlib", Version: ">=3.7"}},
+				"cli": {{Package: "click", Version: ">=8.0"}, {Package: "rich", Version: ">=13.0"}},
+			},
+		},
+	}
+}
+
+func TestExtraNames(t *testing.T) {
+	m := extrasManifest()
+	if got := m.ExtraNames(); !reflect.DeepEqual(got, []string{"cli", "viz"}) {
+		t.Errorf("ExtraNames() = %v, want sorted [cli viz]", got)
+	}
+	var packages []string
+	for _, dep := range m.ExtraDependencies() {
+		packages = append(packages, dep.Package)
+	}
+	if !reflect.DeepEqual(packages, []string{"click", "rich", "matplotlib"}) {
+		t.Errorf("ExtraDependencies() = %v", packages)
+	}
+	if got := (&Manifest{}).ExtraNames(); len(got) != 0 {
+		t.Errorf("ExtraNames() without dependencies = %v", got)
+	}
+}
+
+func TestCheckExtras(t *testing.T) {
+	m := extrasManifest()
+	if err := m.CheckExtras([]string{"viz", "cli"}); err != nil {
+		t.Errorf("CheckExtras(viz, cli) = %v", err)
+	}
+	err := m.CheckExtras([]string{"viz", "gpu"})
+	if err == nil || !strings.Contains(err.Error(), `no extra "gpu" (available: cli, viz)`) {
+		t.Errorf("CheckExtras(gpu) = %v, want the available extras listed", err)
+	}
+	err = (&Manifest{Name: "bare"}).CheckExtras([]string{"viz"})
+	if err == nil || !strings.Contains(err.Error(), "defines none") {
+		t.Errorf("CheckExtras on a package without extras = %v", err)
+	}
+}
+
+func TestWithExtras(t *testing.T) {
+	m := extrasManifest()
+	withViz := m.WithExtras([]string{"viz", "unknown"})
+
+	var packages []string
+	for _, dep := range withViz.Dependencies.Runtime {
+		packages = append(packages, dep.Package)
+	}
+	if !reflect.DeepEqual(packages, []string{"numpy", "matplotlib"}) {
+		t.Errorf("runtime with viz = %v, want [numpy matplotlib]", packages)
+	}
+	if len(m.Dependencies.Runtime) != 1 {
+		t.Errorf("WithExtras modified the manifest: %v", m.Dependencies.Runtime)
+	}
+	if m.WithExtras(nil) != m {
+		t.Error("WithExtras(nil) should return the manifest itself")
+	}
+}
+
+func TestForLanguageExtras(t *testing.T) {
+	m := extrasManifest()
+	m.Languages = []Language{{Name: "javascript", Version: ">=18"}}
+	m.Dependencies.Extras["viz"] = append(m.Dependencies.Extras["viz"], Dependency{Package: "d3", Version: "^7.0.0", Language: "javascript"})
+
+	js := m.ForLanguage("javascript")
+	if want := map[string][]Dependency{"viz": {{Package: "d3", Version: "^7.0.0", Language: "javascript"}}}; !reflect.DeepEqual(js.Dependencies.Extras, want) {
+		t.Errorf("javascript extras = %v, want %v", js.Dependencies.Extras, want)
+	}
+	if py := m.ForLanguage("python"); len(py.Dependencies.Extras["viz"]) != 1 || len(py.Dependencies.Extras["cli"]) != 2 {
+		t.Errorf("python extras = %v", py.Dependencies.Extras)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
 )
 
 // FindManifestDir searches for aigogo.json starting from the current directory
@@ -15,7 +17,12 @@ func FindManifestDir() (string, string, error) {
 	if err != nil {
 		return "", "", fmt.Errorf("failed to get current directory: %w", err)
 	}
+	return FindManifestDirFrom(currentDir)
+}
 
+// FindManifestDirFrom searches for aigogo.json starting from startDir, as
+// FindManifestDir does from the current directory
+func FindManifestDirFrom(startDir string) (string, string, error) {
 	// Get home directory to prevent searching too high
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -23,7 +30,7 @@ func FindManifestDir() (string, string, error) {
 	}
 
 	// Search up the directory tree
-	dir := currentDir
+	dir := startDir
 	for {
 		manifestPath := filepath.Join(dir, "aigogo.json")
 
@@ -43,7 +50,7 @@ func FindManifestDir() (string, string, error) {
 		dir = parent
 	}
 
-	return "", "", fmt.Errorf("aigogo.json not found in current directory or any parent directory")
+	return "", "", errcode.Errorf(errcode.NotFound, "aigogo.json not found in current directory or any parent directory")
 }
 
 // FindManifest searches for and loads the manifest from the current or parent directories
@@ -52,7 +59,21 @@ func FindManifest() (*Manifest, string, error) {
 	if err != nil {
 		return nil, "", err
 	}
+	return loadFound(manifestDir, manifestPath)
+}
+
+// FindManifestFrom searches for and loads the manifest from startDir or its
+// parent directories
+func FindManifestFrom(startDir string) (*Manifest, string, error) {
+	manifestDir, manifestPath, err := FindManifestDirFrom(startDir)
+	if err != nil {
+		return nil, "", err
+	}
+	return loadFound(manifestDir, manifestPath)
+}
 
+// loadFound loads the manifest a search found
+func loadFound(manifestDir, manifestPath string) (*Manifest, string, error) {
 	// Load the manifest
 	manifest, err := Load(manifestPath)
 	if err != nil {
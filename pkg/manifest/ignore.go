@@ -3,22 +3,43 @@ package manifest
 import (
 	"bufio"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 const AigogoIgnoreFile = ".aigogoignore"
 
+// Sources of ignore patterns other than .aigogoignore files, as reported
+// by IgnoreMatch.Source
+const (
+	IgnoreSourceDefaults = "defaults"      // built-in default excludes
+	IgnoreSourceManifest = "files.exclude" // files.exclude in aigogo.json
+)
+
 // Pattern represents a parsed gitignore pattern
 type Pattern struct {
 	original   string // original line for error messages
 	pattern    string // the pattern to match
 	negated    bool   // starts with !
 	dirOnly    bool   // ends with /
-	anchored   bool   // contains / (not at end) - anchored to root
+	anchored   bool   // contains / at the start or in the middle - anchored to base
+	base       string // directory of the .aigogoignore file it came from, relative to the root ("" for the root)
+	source     string // IgnoreSourceDefaults, IgnoreSourceManifest or the .aigogoignore file's path
 	lineNumber int    // line number in file for error messages
 }
 
+// IgnoreMatch describes the pattern that decided whether a path is ignored
+type IgnoreMatch struct {
+	Ignored bool   // false when a negated pattern re-included the path
+	Pattern string // the pattern as written
+	Source  string // IgnoreSourceDefaults, IgnoreSourceManifest or the path of a .aigogoignore file
+	Line    int    // line number in the .aigogoignore file, 0 for other sources
+	Dir     string // the parent directory the pattern matched, if the path is ignored because it is inside it
+}
+
 // IgnoreManager handles .aigogoignore and manifest exclude patterns
 type IgnoreManager struct {
 	baseDir         string
@@ -36,26 +57,59 @@ func NewIgnoreManager(baseDir string, manifestExcludes []string) (*IgnoreManager
 	}
 
 	// Add default excludes first (lowest priority)
-	for _, p := range getDefaultExcludes() {
-		im.patterns = append(im.patterns, parsePattern(p, 0))
+	for _, line := range getDefaultExcludes() {
+		p := parsePattern(line, 0)
+		p.source = IgnoreSourceDefaults
+		im.patterns = append(im.patterns, p)
 	}
 
 	// Add manifest excludes (medium priority)
-	for _, p := range manifestExcludes {
-		im.patterns = append(im.patterns, parsePattern(p, 0))
+	for _, line := range manifestExcludes {
+		p := parsePattern(line, 0)
+		p.source = IgnoreSourceManifest
+		im.patterns = append(im.patterns, p)
 	}
 
-	// Load .aigogoignore if it exists (highest priority)
-	if err := im.loadAigogoIgnore(); err != nil {
+	// Load .aigogoignore files (highest priority), deeper ones last so
+	// they override their parents
+	if err := im.loadAigogoIgnores(); err != nil {
 		return nil, err
 	}
 
 	return im, nil
 }
 
-// loadAigogoIgnore loads patterns from .aigogoignore file if it exists
-func (im *IgnoreManager) loadAigogoIgnore() error {
-	ignorePath := filepath.Join(im.baseDir, AigogoIgnoreFile)
+// loadAigogoIgnores loads the .aigogoignore file of the base directory and
+// of every subdirectory that isn't ignored. Like .gitignore files, a
+// nested file's patterns are relative to its own directory. The walk is
+// top-down, so a directory's file is read only after those of its parents
+// have been applied, and files in ignored directories are never read.
+func (im *IgnoreManager) loadAigogoIgnores() error {
+	return filepath.WalkDir(im.baseDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(im.baseDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			rel = ""
+		} else if im.ShouldIgnore(rel, true) {
+			return filepath.SkipDir
+		}
+		return im.loadAigogoIgnore(rel)
+	})
+}
+
+// loadAigogoIgnore loads patterns from the .aigogoignore file in dir, a
+// directory relative to baseDir, if it exists
+func (im *IgnoreManager) loadAigogoIgnore(dir string) error {
+	ignorePath := filepath.Join(im.baseDir, filepath.FromSlash(dir), AigogoIgnoreFile)
 
 	file, err := os.Open(ignorePath)
 	if os.IsNotExist(err) {
@@ -72,21 +126,24 @@ func (im *IgnoreManager) loadAigogoIgnore() error {
 	lineNum := 0
 	for scanner.Scan() {
 		lineNum++
-		line := scanner.Text()
+		line := strings.TrimSuffix(scanner.Text(), "\r")
 
-		// Skip empty lines and comments
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		// Skip blank lines and comments. As in git, only a # in the first
+		// column starts a comment, and \# is a literal #.
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
 
-		im.patterns = append(im.patterns, parsePattern(line, lineNum))
+		p := parsePattern(line, lineNum)
+		p.base = dir
+		p.source = path.Join(dir, AigogoIgnoreFile)
+		im.patterns = append(im.patterns, p)
 	}
 
 	return scanner.Err()
 }
 
-// HasAigogoIgnore returns true if .aigogoignore file was found
+// HasAigogoIgnore returns true if any .aigogoignore file was found
 func (im *IgnoreManager) HasAigogoIgnore() bool {
 	return im.hasAigogoIgnore
 }
@@ -99,35 +156,81 @@ func (im *IgnoreManager) ShouldIgnore(path string, isDir bool) bool {
 	return ignored
 }
 
-// ShouldIgnoreWithReason returns whether path is ignored and the reason
+// ShouldIgnoreWithReason returns whether path is ignored and the reason.
+// As in git, a path inside an ignored directory is ignored too, and no
+// pattern can re-include it.
 func (im *IgnoreManager) ShouldIgnoreWithReason(path string, isDir bool) (bool, string) {
+	p, _ := im.decide(path, isDir)
+	if p == nil || p.negated {
+		return false, ""
+	}
+	return true, p.reason()
+}
+
+// Explain returns the pattern that decides whether path is ignored, or nil
+// if no pattern matches it
+func (im *IgnoreManager) Explain(path string, isDir bool) *IgnoreMatch {
+	p, dir := im.decide(path, isDir)
+	if p == nil {
+		return nil
+	}
+	return &IgnoreMatch{
+		Ignored: !p.negated,
+		Pattern: p.original,
+		Source:  p.source,
+		Line:    p.lineNumber,
+		Dir:     dir,
+	}
+}
+
+// decide returns the pattern that decides whether path is ignored, and
+// the parent directory it matched when the path is inside an ignored one
+func (im *IgnoreManager) decide(path string, isDir bool) (*Pattern, string) {
 	// Normalize path to use forward slashes
 	path = filepath.ToSlash(path)
 
 	// Remove leading ./ if present
 	path = strings.TrimPrefix(path, "./")
+	path = strings.TrimSuffix(path, "/")
 
-	ignored := false
-	reason := ""
-
-	// Process patterns in order - later patterns can override earlier ones
-	for _, p := range im.patterns {
-		if matchPattern(path, isDir, p) {
-			if p.negated {
-				ignored = false
-				reason = ""
-			} else {
-				ignored = true
-				if p.lineNumber > 0 {
-					reason = ".aigogoignore:" + itoa(p.lineNumber) + ": " + p.original
-				} else {
-					reason = p.original
-				}
+	for i := 0; i < len(path); i++ {
+		if path[i] != '/' {
+			continue
+		}
+		if p := im.lastMatch(path[:i], true); p != nil && !p.negated {
+			return p, path[:i]
+		}
+	}
+
+	return im.lastMatch(path, isDir), ""
+}
+
+// lastMatch returns the last pattern that matches path, which decides
+// whether it is ignored, or nil if none does. Patterns from a nested
+// .aigogoignore only apply below its directory.
+func (im *IgnoreManager) lastMatch(path string, isDir bool) *Pattern {
+	for i := len(im.patterns) - 1; i >= 0; i-- {
+		p := im.patterns[i]
+		rel := path
+		if p.base != "" {
+			if !strings.HasPrefix(path, p.base+"/") {
+				continue
 			}
+			rel = path[len(p.base)+1:]
+		}
+		if matchPattern(rel, isDir, p) {
+			return p
 		}
 	}
+	return nil
+}
 
-	return ignored, reason
+// reason describes where the pattern comes from, for messages
+func (p *Pattern) reason() string {
+	if p.lineNumber > 0 {
+		return p.source + ":" + itoa(p.lineNumber) + ": " + p.original
+	}
+	return p.original
 }
 
 // parsePattern parses a gitignore pattern line
@@ -137,13 +240,9 @@ func parsePattern(line string, lineNumber int) *Pattern {
 		lineNumber: lineNumber,
 	}
 
-	pattern := line
-
-	// Handle trailing spaces (can be escaped with \)
-	// For simplicity, just trim trailing spaces
-	pattern = strings.TrimRight(pattern, " \t")
+	pattern := trimTrailingSpaces(line)
 
-	// Check for negation
+	// Check for negation; \! starts a pattern with a literal !
 	if strings.HasPrefix(pattern, "!") {
 		p.negated = true
 		pattern = strings.TrimPrefix(pattern, "!")
@@ -155,9 +254,9 @@ func parsePattern(line string, lineNumber int) *Pattern {
 		pattern = strings.TrimSuffix(pattern, "/")
 	}
 
-	// Check if anchored (contains / not at the end)
-	// A pattern is anchored if it contains a slash (that's not trailing)
-	// Exception: patterns starting with **/ are NOT anchored (they match at any depth)
+	// A slash at the start or in the middle anchors the pattern to the
+	// base directory; without one it matches a name at any depth.
+	// Patterns starting with **/ match at any depth either way.
 	if strings.Contains(pattern, "/") && !strings.HasPrefix(pattern, "**/") {
 		p.anchored = true
 		// Remove leading / if present
@@ -168,6 +267,22 @@ func parsePattern(line string, lineNumber int) *Pattern {
 	return p
 }
 
+// trimTrailingSpaces removes the trailing spaces of a pattern line, except
+// those escaped with a backslash
+func trimTrailingSpaces(line string) string {
+	end := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			continue
+		case '\\':
+			i++ // the escaped character, if any, is kept
+		}
+		end = min(i+1, len(line))
+	}
+	return line[:end]
+}
+
 // matchPattern checks if a path matches a pattern
 func matchPattern(path string, isDir bool, p *Pattern) bool {
 	// Directory-only patterns don't match files
@@ -175,103 +290,177 @@ func matchPattern(path string, isDir bool, p *Pattern) bool {
 		return false
 	}
 
-	pattern := p.pattern
-
-	// Handle ** patterns
-	if strings.Contains(pattern, "**") {
-		return matchDoublestar(path, pattern)
+	// A pattern without a slash is matched against the name alone, at any
+	// depth; other patterns against the whole path from the base directory
+	if !p.anchored && !strings.Contains(p.pattern, "/") {
+		return wildmatch(p.pattern, path[strings.LastIndex(path, "/")+1:])
 	}
-
-	if p.anchored {
-		// Anchored patterns match from the root
-		return matchGlob(path, pattern)
-	}
-
-	// Non-anchored patterns can match anywhere in the path
-	// Try matching against the full path
-	if matchGlob(path, pattern) {
-		return true
-	}
-
-	// Try matching against each path component
-	parts := strings.Split(path, "/")
-	for i := range parts {
-		subpath := strings.Join(parts[i:], "/")
-		if matchGlob(subpath, pattern) {
-			return true
-		}
-	}
-
-	// Also try matching just the basename
-	return matchGlob(filepath.Base(path), pattern)
+	return wildmatch(p.pattern, path)
 }
 
-// matchDoublestar handles patterns with **
-func matchDoublestar(path, pattern string) bool {
-	// Split pattern by **
-	parts := strings.Split(pattern, "**")
+// wildmatch reports whether path matches a gitignore glob: * matches
+// within a path segment, ? one character other than /, [...] a character
+// class, and \ escapes the next character. ** matches across segments
+// when it is a segment of its own: a leading **/ matches in every
+// directory, a trailing /** everything inside, and /**/ zero or more
+// directories. Any other ** is a plain *.
+func wildmatch(pattern, path string) bool {
+	return matchFrom(pattern, path, true)
+}
 
-	if len(parts) == 2 {
-		prefix := parts[0]
-		suffix := parts[1]
+// matchFrom is wildmatch; segmentStart says whether pattern starts a path
+// segment, which decides whether a ** there spans directories
+func matchFrom(pattern, path string, segmentStart bool) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '\\':
+			if len(pattern) < 2 || path == "" || path[0] != pattern[1] {
+				return false
+			}
+			pattern, path = pattern[2:], path[1:]
 
-		// Remove leading/trailing slashes from suffix
-		suffix = strings.TrimPrefix(suffix, "/")
+		case '?':
+			r, size := utf8.DecodeRuneInString(path)
+			if path == "" || r == '/' {
+				return false
+			}
+			pattern, path = pattern[1:], path[size:]
 
-		// **/ at start - matches in any directory
-		if prefix == "" {
-			// Match suffix against path or any subpath
-			if suffix == "" {
-				return true // ** matches everything
+		case '[':
+			r, size := utf8.DecodeRuneInString(path)
+			if path == "" || r == '/' {
+				return false
 			}
+			rest, ok := matchClass(pattern, r)
+			if !ok {
+				return false
+			}
+			pattern, path = rest, path[size:]
 
-			// Try matching suffix against path and all subpaths
-			pathParts := strings.Split(path, "/")
-			for i := range pathParts {
-				subpath := strings.Join(pathParts[i:], "/")
-				if matchGlob(subpath, suffix) {
+		case '*':
+			stars := len(pattern) - len(strings.TrimLeft(pattern, "*"))
+			rest := pattern[stars:]
+			if stars >= 2 && segmentStart && (rest == "" || rest[0] == '/') {
+				if rest == "" {
+					return true
+				}
+				// Zero or more whole directories
+				for {
+					if matchFrom(rest[1:], path, true) {
+						return true
+					}
+					slash := strings.IndexByte(path, '/')
+					if slash < 0 {
+						return false
+					}
+					path = path[slash+1:]
+				}
+			}
+			for i := 0; ; i++ {
+				if matchFrom(rest, path[i:], false) {
 					return true
 				}
+				if i == len(path) || path[i] == '/' {
+					return false
+				}
 			}
-			// Also match just the basename
-			return matchGlob(filepath.Base(path), suffix)
+
+		default:
+			if path == "" || path[0] != pattern[0] {
+				return false
+			}
+			segmentStart = pattern[0] == '/'
+			pattern, path = pattern[1:], path[1:]
+			continue
 		}
+		segmentStart = false
+	}
+	return path == ""
+}
+
+// posixClasses are the [:name:] classes allowed in a bracket expression
+var posixClasses = map[string]func(rune) bool{
+	"alnum":  func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) },
+	"alpha":  unicode.IsLetter,
+	"blank":  func(r rune) bool { return r == ' ' || r == '\t' },
+	"cntrl":  unicode.IsControl,
+	"digit":  unicode.IsDigit,
+	"graph":  func(r rune) bool { return unicode.IsGraphic(r) && !unicode.IsSpace(r) },
+	"lower":  unicode.IsLower,
+	"print":  unicode.IsPrint,
+	"punct":  unicode.IsPunct,
+	"space":  unicode.IsSpace,
+	"upper":  unicode.IsUpper,
+	"xdigit": func(r rune) bool { return strings.ContainsRune("0123456789abcdefABCDEF", r) },
+}
+
+// matchClass matches r against the bracket expression at the start of
+// pattern and returns the pattern after it. A leading ! or ^ negates the
+// class, a ] right after the opening bracket is literal, and an unclosed
+// bracket matches nothing.
+func matchClass(pattern string, r rune) (string, bool) {
+	i := 1
+	negated := false
+	if i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^') {
+		negated = true
+		i++
+	}
 
-		// prefix/** at end - matches everything under prefix
-		if suffix == "" || suffix == "/" {
-			prefix = strings.TrimSuffix(prefix, "/")
-			return strings.HasPrefix(path, prefix+"/") || path == prefix
+	matched := false
+	for first := true; ; first = false {
+		if i >= len(pattern) {
+			return "", false
+		}
+		if pattern[i] == ']' && !first {
+			i++
+			break
 		}
 
-		// prefix/**/suffix - prefix must match start, suffix must match end
-		prefix = strings.TrimSuffix(prefix, "/")
-		if !strings.HasPrefix(path, prefix+"/") && path != prefix {
-			return false
+		if strings.HasPrefix(pattern[i:], "[:") {
+			if end := strings.Index(pattern[i+2:], ":]"); end >= 0 {
+				name := pattern[i+2 : i+2+end]
+				is, ok := posixClasses[name]
+				if !ok {
+					return "", false
+				}
+				matched = matched || is(r)
+				i += end + 4
+				continue
+			}
 		}
 
-		// Check if suffix matches the remaining path
-		remaining := strings.TrimPrefix(path, prefix+"/")
-		remainingParts := strings.Split(remaining, "/")
-		for i := range remainingParts {
-			subpath := strings.Join(remainingParts[i:], "/")
-			if matchGlob(subpath, suffix) {
-				return true
+		lo, size := classChar(pattern[i:])
+		if size == 0 {
+			return "", false
+		}
+		i += size
+		hi := lo
+		if i+1 < len(pattern) && pattern[i] == '-' && pattern[i+1] != ']' {
+			if hi, size = classChar(pattern[i+1:]); size == 0 {
+				return "", false
 			}
+			i += 1 + size
+		}
+		if lo <= r && r <= hi {
+			matched = true
 		}
 	}
 
-	return false
+	return pattern[i:], matched != negated
 }
 
-// matchGlob matches a path against a glob pattern (supports *, ?, [...])
-func matchGlob(path, pattern string) bool {
-	// Use filepath.Match for basic glob matching
-	// It handles *, ?, and [...] but not **
-	matched, err := filepath.Match(pattern, path)
-	if err != nil {
-		return false
+// classChar returns the character at the start of s within a bracket
+// expression, unescaping \x, and how many bytes it takes
+func classChar(s string) (rune, int) {
+	if s[0] == '\\' {
+		if len(s) < 2 {
+			return 0, 0
+		}
+		r, size := utf8.DecodeRuneInString(s[1:])
+		return r, size + 1
 	}
-	return matched
+	r, size := utf8.DecodeRuneInString(s)
+	return r, size
 }
 
 // getDefaultExcludes returns patterns to always exclude
@@ -288,6 +477,7 @@ func getDefaultExcludes() []string {
 		".venv/",
 		"env/",
 		"__pycache__/",
+		".ipynb_checkpoints/",
 		"*.pyc",
 		".eggs/",
 		"*.egg-info/",
@@ -3,6 +3,7 @@ package manifest
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -332,6 +333,221 @@ func TestIgnoreManagerEmptyLines(t *testing.T) {
 	}
 }
 
+// TestGitignoreConformance checks matching against the gitignore rules.
+// The expectations were confirmed with git check-ignore.
+func TestGitignoreConformance(t *testing.T) {
+	tests := []struct {
+		ignore string // .aigogoignore contents
+		path   string
+		isDir  bool
+		want   bool
+	}{
+		// A pattern without a slash matches a name at any depth
+		{"*.log", "a.log", false, true},
+		{"*.log", "x/a.log", false, true},
+		{"frotz/", "a/frotz", true, true},
+		{"frotz/", "frotz", false, false},
+		{"?", "a", false, true},
+		{"?", "ab", false, false},
+
+		// A slash at the start or in the middle anchors it
+		{"/*.log", "a.log", false, true},
+		{"/*.log", "x/a.log", false, false},
+		{"foo/bar", "foo/bar", false, true},
+		{"foo/bar", "x/foo/bar", false, false},
+		{"doc/frotz/", "doc/frotz", true, true},
+		{"doc/frotz/", "a/doc/frotz", true, false},
+		{"*/y", "x/y", false, true},
+		{"*/y", "a/x/y", false, false},
+		{"/x", "x", false, true},
+		{"/x", "a/x", false, false},
+
+		// * and ? don't match a slash
+		{"f?o", "f/o", false, false},
+		{"f*o", "f/o", false, false},
+		{"x/*", "x/y", false, true},
+
+		// ** as a segment of its own spans directories
+		{"**/foo", "foo", false, true},
+		{"**/foo", "a/b/foo", false, true},
+		{"**/foo/bar", "foo/bar", false, true},
+		{"**/foo/bar", "a/foo/bar", false, true},
+		{"abc/**", "abc/x", false, true},
+		{"abc/**", "abc/x/y", false, true},
+		{"abc/**", "abc", true, false},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"a/**/b", "a/xb", false, false},
+		{"a/**/b/**/c", "a/b/c", false, true},
+		{"a/**/b/**/c", "a/1/b/2/3/c", false, true},
+		{"a/**/b/**/c", "a/b/x", false, false},
+
+		// Any other ** is a plain *
+		{"a**b", "axxb", false, true},
+		{"a**b", "ax/xb", false, false},
+		{"**foo", "a/xfoo", false, true},
+		{"foo**", "foox", false, true},
+		{"foo/**bar", "foo/xbar", false, true},
+		{"foo/**bar", "foo/x/bar", false, false},
+
+		// Escapes
+		{`\#file`, "#file", false, true},
+		{`\!imp`, "!imp", false, true},
+		{`f\*o`, "f*o", false, true},
+		{`f\*o`, "fxo", false, false},
+
+		// Trailing spaces are dropped unless escaped
+		{"foo  ", "foo", false, true},
+		{`foo\ `, "foo ", false, true},
+		{`foo\ `, "foo", false, false},
+
+		// Character classes
+		{"f[ab]o", "fbo", false, true},
+		{"f[!ab]o", "fco", false, true},
+		{"f[!ab]o", "fao", false, false},
+		{"f[^ab]o", "fco", false, true},
+		{"f[a-c]o", "fbo", false, true},
+		{"f[a-c]o", "fdo", false, false},
+		{"f[]]o", "f]o", false, true},
+		{"f[[:digit:]]o", "f1o", false, true},
+		{"f[[:digit:]]o", "fxo", false, false},
+		{"f[[:upper:]]o", "fXo", false, true},
+		{"f[/]o", "f/o", false, false},
+		{"f[ab", "f[ab", false, false},
+
+		// Everything inside an ignored directory is ignored, and can't be
+		// re-included
+		{"x/", "x/y", false, true},
+		{"x/*", "x/y/z", false, true},
+		{"build/\n!build/keep.py", "build/keep.py", false, true},
+		{"abc/**\n!abc/keep", "abc/keep", false, false},
+
+		// The last matching pattern decides
+		{"*.log\n!keep.log", "keep.log", false, false},
+		{"!keep.log\n*.log", "keep.log", false, true},
+
+		// Comments only start in the first column
+		{"#foo", "#foo", false, false},
+		{" #foo", " #foo", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ignore+" "+tt.path, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmpDir, ".aigogoignore"), []byte(tt.ignore+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			im, err := NewIgnoreManager(tmpDir, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := im.ShouldIgnore(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("ShouldIgnore(%q, %v) with %q = %v, want %v", tt.path, tt.isDir, tt.ignore, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNestedAigogoIgnore(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".aigogoignore"), "*.log\nfixtures/\n")
+	// Patterns are relative to the file's directory, and override the root's
+	writeFile(t, filepath.Join(tmpDir, "services", "api", ".aigogoignore"), "/local.py\n*.tmp\n!keep.log\n")
+	writeFile(t, filepath.Join(tmpDir, "services", "api", "sub", ".aigogoignore"), "!*.tmp\n")
+	// Files in ignored directories are never read
+	writeFile(t, filepath.Join(tmpDir, "fixtures", ".aigogoignore"), "!*.log\n")
+
+	im, err := NewIgnoreManager(tmpDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path   string
+		want   bool
+		reason string
+	}{
+		{"app.log", true, ".aigogoignore:1: *.log"},
+		{"local.py", false, ""},
+		{"services/api/local.py", true, "services/api/.aigogoignore:1: /local.py"},
+		{"services/api/sub/local.py", false, ""},
+		{"services/api/x.tmp", true, "services/api/.aigogoignore:2: *.tmp"},
+		{"services/x.tmp", false, ""},
+		{"services/api/sub/x.tmp", false, ""},
+		{"services/api/keep.log", false, ""},
+		{"services/api/other.log", true, ".aigogoignore:1: *.log"},
+		{"services/web/keep.log", true, ".aigogoignore:1: *.log"},
+		{"fixtures/a.log", true, ".aigogoignore:2: fixtures/"},
+	}
+	for _, tt := range tests {
+		ignored, reason := im.ShouldIgnoreWithReason(tt.path, false)
+		if ignored != tt.want || reason != tt.reason {
+			t.Errorf("ShouldIgnoreWithReason(%q) = %v, %q; want %v, %q", tt.path, ignored, reason, tt.want, tt.reason)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".aigogoignore"), "# logs\n*.log\n!keep.log\n")
+	writeFile(t, filepath.Join(tmpDir, "sub", ".aigogoignore"), "*.tmp\n")
+
+	im, err := NewIgnoreManager(tmpDir, []string{"tests/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  *IgnoreMatch
+	}{
+		{"utils.py", false, nil},
+		{"app.log", false, &IgnoreMatch{Ignored: true, Pattern: "*.log", Source: ".aigogoignore", Line: 2}},
+		{"keep.log", false, &IgnoreMatch{Ignored: false, Pattern: "!keep.log", Source: ".aigogoignore", Line: 3}},
+		{"sub/x.tmp", false, &IgnoreMatch{Ignored: true, Pattern: "*.tmp", Source: "sub/.aigogoignore", Line: 1}},
+		{"tests", true, &IgnoreMatch{Ignored: true, Pattern: "tests/", Source: IgnoreSourceManifest}},
+		{"tests/test_a.py", false, &IgnoreMatch{Ignored: true, Pattern: "tests/", Source: IgnoreSourceManifest, Dir: "tests"}},
+		{"a/node_modules/x/y.js", false, &IgnoreMatch{Ignored: true, Pattern: "node_modules/", Source: IgnoreSourceDefaults, Dir: "a/node_modules"}},
+	}
+	for _, tt := range tests {
+		if got := im.Explain(tt.path, tt.isDir); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Explain(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestShouldIgnoreInsideIgnoredDirectory(t *testing.T) {
+	im, err := NewIgnoreManager(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ignored, reason := im.ShouldIgnoreWithReason("node_modules/lodash/index.js", false)
+	if !ignored || reason != "node_modules/" {
+		t.Errorf("ShouldIgnoreWithReason() = %v, %q; want ignored by node_modules/", ignored, reason)
+	}
+}
+
+func TestTrimTrailingSpaces(t *testing.T) {
+	tests := map[string]string{
+		"foo":    "foo",
+		"foo   ": "foo",
+		`foo\ `:  `foo\ `,
+		`foo\  `: `foo\ `,
+		`foo\\ `: `foo\\`,
+		"   ":    "",
+		`trail\`: `trail\`,
+		" lead":  " lead",
+		"a b  ":  "a b",
+	}
+	for in, want := range tests {
+		if got := trimTrailingSpaces(in); got != want {
+			t.Errorf("trimTrailingSpaces(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
 // Helper function
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsHelper(s, substr))
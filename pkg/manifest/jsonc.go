@@ -0,0 +1,78 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// StripJSONC turns JSONC (JSON with // and /* */ comments and trailing
+// commas) into plain JSON by blanking out the comments and trailing commas
+// with spaces. Newlines are kept, so the result has the same length and
+// line numbers as data. changed reports whether there was anything to
+// remove.
+func StripJSONC(data []byte) (out []byte, changed bool, err error) {
+	out = bytes.Clone(data)
+	inString := false
+	comma := -1 // offset of the last comma not yet followed by a value
+	line := 1
+
+	for i := 0; i < len(out); i++ {
+		c := out[i]
+		if c == '\n' {
+			line++
+		}
+
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+			i-- // let the loop count the newline
+			changed = true
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			start := line
+			end := bytes.Index(out[i+2:], []byte("*/"))
+			if end < 0 {
+				return nil, false, fmt.Errorf("line %d: unterminated /* comment", start)
+			}
+			for j := i; j < i+2+end+2; j++ {
+				if out[j] == '\n' {
+					line++
+				} else {
+					out[j] = ' '
+				}
+			}
+			i += 2 + end + 1
+			changed = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+		case (c == '}' || c == ']') && comma >= 0:
+			out[comma] = ' '
+			comma = -1
+			changed = true
+		case c == ',':
+			comma = i
+		default:
+			comma = -1
+			if c == '"' {
+				inString = true
+			}
+		}
+	}
+	return out, changed, nil
+}
+
+// HasComments reports whether the manifest was loaded from a file with
+// comments or trailing commas, which Save doesn't keep
+func (m *Manifest) HasComments() bool {
+	return m.commented
+}
@@ -0,0 +1,117 @@
+package manifest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStripJSONC(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		changed bool
+	}{
+		{"plain", `{"a": [1, 2]}`, `{"a": [1, 2]}`, false},
+		{"line comment", "{\"a\": 1 // one\n}", "{\"a\": 1       \n}", true},
+		{"block comment", "{/* a\nb */\"a\": 1}", "{    \n    \"a\": 1}", true},
+		{"trailing comma in object", `{"a": 1,}`, `{"a": 1 }`, true},
+		{"trailing comma in array", "[1, 2,\n]", "[1, 2 \n]", true},
+		{"trailing comma before comment", "[1, // x\n]", "[1      \n]", true},
+		{"comment markers in strings", `{"url": "https://x.io/*", "s": "a,}"}`, `{"url": "https://x.io/*", "s": "a,}"}`, false},
+		{"escaped quote", `{"s": "\" // no"}`, `{"s": "\" // no"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := StripJSONC([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("StripJSONC failed: %v", err)
+			}
+			if string(got) != tt.want || changed != tt.changed {
+				t.Errorf("StripJSONC(%q) = %q, %v; want %q, %v", tt.input, got, changed, tt.want, tt.changed)
+			}
+			if !json.Valid(got) {
+				t.Errorf("StripJSONC(%q) is not valid JSON: %q", tt.input, got)
+			}
+		})
+	}
+
+	if _, _, err := StripJSONC([]byte("{\n/* open\n")); err == nil || !strings.Contains(err.Error(), "line 2: unterminated /* comment") {
+		t.Errorf("StripJSONC with an unterminated comment = %v, want an error", err)
+	}
+}
+
+const commentedManifest = `// Shared HTTP helpers
+{
+  "name": "utils",
+  "version": "1.0.0", // bumped by aigg version
+  /* the language block */
+  "language": {"name": "python", "version": ">=3.8",},
+  "files": {"include": ["*.py",]},
+}
+`
+
+func TestLoadJSONC(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aigogo.json")
+	writeFile(t, path, commentedManifest)
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if m.Name != "utils" || m.Language.Version != ">=3.8" || !m.HasComments() {
+		t.Errorf("Load = %+v, HasComments %v", m, m.HasComments())
+	}
+
+	// Save writes plain JSON
+	if err := Save(path, m); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(data) {
+		t.Errorf("saved manifest is not plain JSON:\n%s", data)
+	}
+	if m, err = Load(path); err != nil || m.HasComments() {
+		t.Errorf("reloaded manifest: %v, HasComments %v", err, m != nil && m.HasComments())
+	}
+}
+
+func TestLoadExtendsJSONC(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, "base.json"), "{\n  // team defaults\n  \"author\": \"Team\",\n}")
+	path := filepath.Join(tmpDir, "aigogo.json")
+	writeFile(t, path, `{"extends": "./base.json", "name": "child", "version": "1.0.0", "language": {"name": "python"}, "files": {"include": "auto"}}`)
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if m.Author != "Team" || m.HasComments() {
+		t.Errorf("Load = author %q, HasComments %v; want the commented base merged", m.Author, m.HasComments())
+	}
+}
+
+func TestValidateSchemaJSONC(t *testing.T) {
+	errs, err := ValidateSchema([]byte(commentedManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 0 {
+		t.Errorf("commented manifest should pass, got %v", errs)
+	}
+
+	// Line numbers still refer to the original file
+	errs, err = ValidateSchema([]byte(strings.Replace(commentedManifest, `"version": "1.0.0"`, `"version": 1`, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 || errs[0].Line != 4 {
+		t.Errorf("expected one error on line 4, got %v", errs)
+	}
+}
@@ -0,0 +1,164 @@
+package manifest
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// AllLanguages returns the package's primary language followed by any
+// additional languages of a multi-language package
+func (m *Manifest) AllLanguages() []Language {
+	return append([]Language{m.Language}, m.Languages...)
+}
+
+// HasLanguage reports whether name is one of the package's languages
+func (m *Manifest) HasLanguage(name string) bool {
+	for _, lang := range m.AllLanguages() {
+		if lang.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DependencyLanguage returns the language a dependency belongs to
+func (m *Manifest) DependencyLanguage(dep Dependency) string {
+	if dep.Language != "" {
+		return dep.Language
+	}
+	return m.Language.Name
+}
+
+// ForLanguage returns a single-language view of the manifest: name becomes
+// the primary language and only its dependencies are kept. Code that only
+// understands one language per package can work on each view in turn.
+// The manifest itself is returned when it has a single language.
+func (m *Manifest) ForLanguage(name string) *Manifest {
+	if len(m.Languages) == 0 && m.Language.Name == name {
+		return m
+	}
+
+	view := *m
+	view.Languages = nil
+	for _, lang := range m.AllLanguages() {
+		if lang.Name == name {
+			view.Language = lang
+		}
+	}
+
+	if m.Dependencies != nil {
+		deps := &Dependencies{}
+		for _, dep := range m.Dependencies.Runtime {
+			if m.DependencyLanguage(dep) == name {
+				deps.Runtime = append(deps.Runtime, dep)
+			}
+		}
+		for _, dep := range m.Dependencies.Dev {
+			if m.DependencyLanguage(dep) == name {
+				deps.Dev = append(deps.Dev, dep)
+			}
+		}
+		for _, dep := range m.Dependencies.Peer {
+			if m.DependencyLanguage(dep) == name {
+				deps.Peer = append(deps.Peer, dep)
+			}
+		}
+		for extra, group := range m.Dependencies.Extras {
+			for _, dep := range group {
+				if m.DependencyLanguage(dep) == name {
+					if deps.Extras == nil {
+						deps.Extras = make(map[string][]Dependency)
+					}
+					deps.Extras[extra] = append(deps.Extras[extra], dep)
+				}
+			}
+		}
+		view.Dependencies = deps
+		if len(deps.Runtime) == 0 && len(deps.Dev) == 0 && len(deps.Peer) == 0 && len(deps.Extras) == 0 {
+			view.Dependencies = nil
+		}
+	}
+	return &view
+}
+
+// LanguageOfFile returns which of languages a file's extension belongs to,
+// or "" if none
+func LanguageOfFile(path string, languages []Language) string {
+	base := filepath.Base(path)
+	for _, lang := range languages {
+		for _, pattern := range languagePatterns[lang.Name] {
+			if matched, _ := filepath.Match(strings.TrimPrefix(pattern, "**/"), base); matched {
+				return lang.Name
+			}
+		}
+	}
+	return ""
+}
+
+// GroupFilesByLanguage splits a package's files by language, keyed by
+// language name. Files that belong to no language, such as READMEs and
+// data files, are grouped with the primary language, which is what
+// single-language packages have always done with them.
+func (m *Manifest) GroupFilesByLanguage(files []string) map[string][]string {
+	languages := m.AllLanguages()
+	groups := make(map[string][]string, len(languages))
+	for _, f := range files {
+		lang := LanguageOfFile(f, languages)
+		if lang == "" {
+			lang = m.Language.Name
+		}
+		groups[lang] = append(groups[lang], f)
+	}
+	return groups
+}
+
+// DetectLanguage returns the supported language most of the source files
+// under dir are written in, or "" if there are none. Files and directories
+// the package would exclude, such as node_modules/ and .venv/, aren't
+// counted.
+func DetectLanguage(dir string) (string, error) {
+	im, err := NewIgnoreManager(dir, nil)
+	if err != nil {
+		return "", err
+	}
+	var languages []Language
+	for _, name := range SupportedLanguages() {
+		languages = append(languages, Language{Name: name})
+	}
+
+	counts := map[string]int{}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if rel == "." {
+			return nil
+		}
+		if im.ShouldIgnore(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			if lang := LanguageOfFile(rel, languages); lang != "" {
+				counts[lang]++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Ties go to the language listed first in SupportedLanguages
+	best := ""
+	for _, lang := range languages {
+		if counts[lang.Name] > counts[best] {
+			best = lang.Name
+		}
+	}
+	return best, nil
+}
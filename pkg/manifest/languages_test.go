@@ -0,0 +1,129 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func multiLanguageManifest() *Manifest {
+	return &Manifest{
+		Name:      "test",
+		Version:   "1.0.0",
+		Language:  Language{Name: "python", Version: ">=3.8"},
+		Languages: []Language{{Name: "javascript", Version: ">=18"}},
+		Dependencies: &Dependencies{
+			Runtime: []Dependency{
+				{Package: "requests", Version: ">=2.31.0"},
+				{Package: "axios", Version: "^1.6.0", Language: "javascript"},
+			},
+			Dev: []Dependency{
+				{Package: "pytest", Version: ">=7.0"},
+			},
+		},
+	}
+}
+
+func TestForLanguage(t *testing.T) {
+	m := multiLanguageManifest()
+
+	py := m.ForLanguage("python")
+	if py.Language.Name != "python" || py.Languages != nil {
+		t.Errorf("python view has languages %v %v", py.Language, py.Languages)
+	}
+	if len(py.Dependencies.Runtime) != 1 || py.Dependencies.Runtime[0].Package != "requests" {
+		t.Errorf("python view runtime = %v", py.Dependencies.Runtime)
+	}
+	if len(py.Dependencies.Dev) != 1 {
+		t.Errorf("python view dev = %v", py.Dependencies.Dev)
+	}
+
+	js := m.ForLanguage("javascript")
+	if js.Language.Name != "javascript" || js.Language.Version != ">=18" {
+		t.Errorf("javascript view language = %v", js.Language)
+	}
+	if len(js.Dependencies.Runtime) != 1 || js.Dependencies.Runtime[0].Package != "axios" {
+		t.Errorf("javascript view runtime = %v", js.Dependencies.Runtime)
+	}
+	if len(js.Dependencies.Dev) != 0 {
+		t.Errorf("javascript view dev = %v", js.Dependencies.Dev)
+	}
+
+	// the original is left alone
+	if len(m.Dependencies.Runtime) != 2 || len(m.Languages) != 1 {
+		t.Error("ForLanguage modified the manifest")
+	}
+
+	single := &Manifest{Language: Language{Name: "go"}}
+	if single.ForLanguage("go") != single {
+		t.Error("ForLanguage should return a single-language manifest as is")
+	}
+}
+
+func TestGroupFilesByLanguage(t *testing.T) {
+	m := multiLanguageManifest()
+	got := m.GroupFilesByLanguage([]string{
+		"pkg/client.py",
+		"web/client.ts",
+		"web/index.mjs",
+		"README.md",
+		"config.yaml",
+	})
+	want := map[string][]string{
+		"python":     {"pkg/client.py", "README.md", "config.yaml"},
+		"javascript": {"web/client.ts", "web/index.mjs"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupFilesByLanguage() = %v, want %v", got, want)
+	}
+}
+
+func TestLanguageOfFile(t *testing.T) {
+	languages := []Language{{Name: "python"}, {Name: "rust"}}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"a/b/mod.py", "python"},
+		{"a/b/mod.pyi", "python"},
+		{"a/py.typed", "python"},
+		{"src/lib.rs", "rust"},
+		{"index.js", ""},
+		{"notes.txt", ""},
+	}
+	for _, tt := range tests {
+		if got := LanguageOfFile(tt.path, languages); got != tt.want {
+			t.Errorf("LanguageOfFile(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"main.go",
+		"util/strings.go",
+		"scripts/release.py",
+		// Excluded directories aren't counted
+		"node_modules/a/index.js",
+		"node_modules/b/index.js",
+		"node_modules/c/index.js",
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, err := DetectLanguage(dir); err != nil || got != "go" {
+		t.Errorf("DetectLanguage() = %q, %v, want go", got, err)
+	}
+	if got, err := DetectLanguage(t.TempDir()); err != nil || got != "" {
+		t.Errorf("DetectLanguage(empty) = %q, %v, want none", got, err)
+	}
+}
@@ -3,21 +3,49 @@ package manifest
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 )
 
-// Load reads and parses aigogo.json
+// Load reads and parses aigogo.json. Comments and trailing commas (JSONC)
+// are accepted.
 func Load(path string) (*Manifest, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read manifest: %w", err)
 	}
+	data, commented, err := StripJSONC(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	// A manifest that extends another is loaded merged with it
+	merged, inherited, err := resolveExtends(data, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if inherited != nil {
+		if data, err = json.Marshal(merged); err != nil {
+			return nil, fmt.Errorf("failed to merge manifest: %w", err)
+		}
+	}
 
 	var manifest Manifest
 	if err := json.Unmarshal(data, &manifest); err != nil {
 		return nil, fmt.Errorf("failed to parse manifest: %w", err)
 	}
+	manifest.inherited = inherited
+	manifest.commented = commented
+
+	// A manifest written for a newer aigogo may not validate here at all
+	if err := manifest.CheckAigogoVersion(); err != nil {
+		return nil, err
+	}
 
 	// Validate required fields
 	if err := Validate(&manifest); err != nil {
@@ -27,21 +55,73 @@ func Load(path string) (*Manifest, error) {
 	return &manifest, nil
 }
 
-// Save writes manifest to file
+// Save writes manifest to file. A manifest loaded with extends is written
+// without the fields it inherits unchanged. The file is always plain JSON:
+// comments and trailing commas it was loaded with are not kept.
 func Save(path string, manifest *Manifest) error {
+	if manifest.inherited != nil {
+		manifest = manifest.withoutInherited()
+	}
+
+	data, err := Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
+
+// Marshal encodes manifest as indented JSON, the way Save writes it
+func Marshal(manifest *Manifest) ([]byte, error) {
 	var buf bytes.Buffer
 	encoder := json.NewEncoder(&buf)
 	encoder.SetEscapeHTML(false) // Don't escape <, >, & for better readability
 	encoder.SetIndent("", "  ")
 
 	if err := encoder.Encode(manifest); err != nil {
-		return fmt.Errorf("failed to marshal manifest: %w", err)
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
 	}
+	return buf.Bytes(), nil
+}
 
-	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
-		return fmt.Errorf("failed to write manifest: %w", err)
+// CLIVersion is the version of the running aigg, set by cmd. Manifests
+// whose aigogoVersion it doesn't satisfy fail to load. When it is empty or
+// not a version, manifests aren't checked.
+var CLIVersion string
+
+// ErrUpgradeRequired is wrapped by the errors for manifests that need a
+// newer aigogo
+var ErrUpgradeRequired = errors.New("please upgrade aigogo (https://github.com/aupeachmo/aigogo#installation)")
+
+// CheckAigogoVersion returns an error wrapping ErrUpgradeRequired when the
+// running aigg doesn't satisfy the manifest's aigogoVersion. Pre-release
+// and build suffixes of the running version, such as those of git describe
+// builds, are ignored.
+func (m *Manifest) CheckAigogoVersion() error {
+	if m.AigogoVersion == "" {
+		return nil
+	}
+	cli, err := parseLooseSemver(CLIVersion)
+	if err != nil {
+		return nil
 	}
+	cli.Prerelease, cli.Build = "", ""
 
+	ok, err := SatisfiesConstraint(cli.String(), m.AigogoVersion)
+	if err != nil {
+		return fmt.Errorf("invalid aigogoVersion: %w", err)
+	}
+	if !ok {
+		name := m.Name
+		if name == "" {
+			name = "this manifest"
+		}
+		return fmt.Errorf("%s requires aigogo %s, but this is aigogo %s: %w", name, m.AigogoVersion, CLIVersion, ErrUpgradeRequired)
+	}
 	return nil
 }
 
@@ -53,6 +133,11 @@ func Validate(m *Manifest) error {
 	if m.Version == "" {
 		return fmt.Errorf("version is required")
 	}
+	if m.AigogoVersion != "" {
+		if _, err := parseConstraint(m.AigogoVersion); err != nil {
+			return fmt.Errorf("invalid aigogoVersion: %w", err)
+		}
+	}
 	if m.Language.Name == "" {
 		return fmt.Errorf("language.name is required")
 	}
@@ -63,6 +148,85 @@ func Validate(m *Manifest) error {
 	if m.Dependencies != nil && m.Language.Version == "" {
 		return fmt.Errorf("language.version is required when dependencies are specified")
 	}
+	seen := map[string]bool{m.Language.Name: true}
+	for i, lang := range m.Languages {
+		if lang.Name == "" {
+			return fmt.Errorf("languages[%d].name is required", i)
+		}
+		if !ValidateLanguage(lang.Name) {
+			return fmt.Errorf("unsupported language: %s (supported: %v)", lang.Name, SupportedLanguages())
+		}
+		if seen[lang.Name] {
+			return fmt.Errorf("language %s is listed more than once", lang.Name)
+		}
+		seen[lang.Name] = true
+	}
+
+	if m.Generator != nil {
+		switch m.Generator.Python {
+		case "", PythonStyleOptionalDependencies, PythonStyleDependencyGroups:
+		default:
+			return fmt.Errorf("invalid generator.python: %s (expected %s or %s)",
+				m.Generator.Python, PythonStyleOptionalDependencies, PythonStyleDependencyGroups)
+		}
+		switch m.Generator.JavaScript {
+		case "", JSPackageManagerNpm, JSPackageManagerPnpm, JSPackageManagerYarnBerry:
+		default:
+			return fmt.Errorf("invalid generator.javascript: %s (expected %s, %s or %s)",
+				m.Generator.JavaScript, JSPackageManagerNpm, JSPackageManagerPnpm, JSPackageManagerYarnBerry)
+		}
+	}
+
+	if err := m.ProjectSettings().Validate(); err != nil {
+		return err
+	}
+
+	if m.Readme != "" {
+		clean := filepath.ToSlash(filepath.Clean(m.Readme))
+		if filepath.IsAbs(m.Readme) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("readme must be a path inside the package: %s", m.Readme)
+		}
+	}
+
+	if m.Environment != nil {
+		if err := validateEnvironment(m.Environment); err != nil {
+			return err
+		}
+	}
+	if m.Deprecated != nil && strings.TrimSpace(m.Deprecated.Message) == "" {
+		return fmt.Errorf("deprecated.message is required: say why the package is deprecated")
+	}
+	if m.Publish != nil {
+		for i, registry := range m.Publish.BlockedRegistries {
+			if strings.TrimSpace(registry) == "" || strings.Contains(registry, "://") {
+				return fmt.Errorf("publish.blocked_registries[%d]: expected a registry host or host/namespace, e.g. docker.io or ghcr.io/org, got %q", i, registry)
+			}
+		}
+	}
+
+	for i, a := range m.Files.Attributes {
+		if a.Path == "" {
+			return fmt.Errorf("files.attributes[%d].path is required", i)
+		}
+		if a.Template && !strings.HasSuffix(a.Path, TemplateSuffix) {
+			return fmt.Errorf("files.attributes[%d]: template file %s must end in %s", i, a.Path, TemplateSuffix)
+		}
+	}
+
+	if m.Lint != nil {
+		for rule, severity := range m.Lint.Rules {
+			switch severity {
+			case LintError, LintWarning, LintInfo, LintOff:
+			default:
+				return fmt.Errorf("invalid lint.rules.%s: %s (expected %s, %s, %s or %s)",
+					rule, severity, LintError, LintWarning, LintInfo, LintOff)
+			}
+		}
+	}
+
+	if err := ValidateMetadata(m.Metadata); err != nil {
+		return err
+	}
 
 	// Validate scripts
 	if m.Scripts != nil {
@@ -70,12 +234,19 @@ func Validate(m *Manifest) error {
 			if name == "" {
 				return fmt.Errorf("script name cannot be empty")
 			}
+			if file == "" && IsLifecycleScript(name) {
+				return fmt.Errorf("script command cannot be empty for %s", name)
+			}
 			if file == "" {
 				return fmt.Errorf("script file path cannot be empty for %s", name)
 			}
 		}
 	}
 
+	if err := validateExports(m.Exports); err != nil {
+		return err
+	}
+
 	// Validate dependencies
 	if m.Dependencies != nil {
 		for _, dep := range m.Dependencies.Runtime {
@@ -85,6 +256,9 @@ func Validate(m *Manifest) error {
 			if dep.Version == "" {
 				return fmt.Errorf("dependency version is required for %s", dep.Package)
 			}
+			if err := validateDependencyLanguage(m, dep); err != nil {
+				return err
+			}
 		}
 		for _, dep := range m.Dependencies.Dev {
 			if dep.Package == "" {
@@ -93,8 +267,181 @@ func Validate(m *Manifest) error {
 			if dep.Version == "" {
 				return fmt.Errorf("dev dependency version is required for %s", dep.Package)
 			}
+			if err := validateDependencyLanguage(m, dep); err != nil {
+				return err
+			}
+		}
+		for _, dep := range m.Dependencies.Peer {
+			if dep.Package == "" {
+				return fmt.Errorf("peer dependency package name is required")
+			}
+			if dep.Version == "" {
+				return fmt.Errorf("peer dependency version is required for %s", dep.Package)
+			}
+			if err := validateDependencyLanguage(m, dep); err != nil {
+				return err
+			}
+			for _, runtime := range m.Dependencies.Runtime {
+				if runtime.Package == dep.Package && m.DependencyLanguage(runtime) == m.DependencyLanguage(dep) {
+					return fmt.Errorf("%s is declared as both a runtime and a peer dependency", dep.Package)
+				}
+			}
+		}
+		if err := validateExtras(m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateDependencyLanguage checks that a dependency's language is one of
+// the package's and that its fields suit that language
+func validateDependencyLanguage(m *Manifest, dep Dependency) error {
+	language := m.DependencyLanguage(dep)
+	if !m.HasLanguage(language) {
+		return fmt.Errorf("dependency %s is for %s, which is not one of the package's languages", dep.Package, language)
+	}
+	if language != m.Language.Name {
+		for _, lang := range m.Languages {
+			if lang.Name == language && lang.Version == "" {
+				return fmt.Errorf("languages: version is required for %s when it has dependencies", language)
+			}
+		}
+	}
+	return validateRequirementFields(language, dep)
+}
+
+// maxKeywordLength is the longest keyword metadata.keywords accepts
+const maxKeywordLength = 50
+
+// ValidateMetadata checks the optional package metadata: an SPDX license
+// expression, absolute repository and homepage URLs, and distinct keywords
+func ValidateMetadata(md Metadata) error {
+	if md.License != "" && !isLicenseExpression(md.License) {
+		return fmt.Errorf("metadata.license %q is not an SPDX license expression (e.g. MIT, Apache-2.0 OR MIT)", md.License)
+	}
+	if md.Repository != "" && !isURL(md.Repository, "https", "http", "git", "ssh", "git+https", "git+ssh") {
+		return fmt.Errorf("metadata.repository %q is not a repository URL (e.g. https://github.com/org/repo)", md.Repository)
+	}
+	if md.Homepage != "" && !isURL(md.Homepage, "https", "http") {
+		return fmt.Errorf("metadata.homepage %q is not an http(s) URL", md.Homepage)
+	}
+
+	seen := make(map[string]bool)
+	for i, keyword := range md.Keywords {
+		keyword = strings.TrimSpace(keyword)
+		if keyword == "" {
+			return fmt.Errorf("metadata.keywords[%d] is empty", i)
+		}
+		if len(keyword) > maxKeywordLength {
+			return fmt.Errorf("metadata.keywords[%d] is longer than %d characters", i, maxKeywordLength)
+		}
+		if seen[strings.ToLower(keyword)] {
+			return fmt.Errorf("metadata.keywords lists %q more than once", keyword)
+		}
+		seen[strings.ToLower(keyword)] = true
+	}
+	return nil
+}
+
+// nodeRangePart matches one comma-separated part of environment.node
+var nodeRangePart = regexp.MustCompile(`^(>=|<=|!=|==|>|<)\s*v?\d+(\.\d+){0,2}$`)
+
+// validateEnvironment checks environment values against the names aigg
+// compares them with on the installing machine
+func validateEnvironment(env *EnvironmentSpec) error {
+	lists := []struct {
+		field  string
+		values []string
+		known  []string
+	}{
+		{"os", env.OS, EnvironmentOSes},
+		{"arch", env.Arch, EnvironmentArches},
+		{"python_implementation", env.PythonImplementation, EnvironmentPythonImplementations},
+	}
+	for _, l := range lists {
+		for _, v := range l.values {
+			if !containsString(l.known, v) {
+				return fmt.Errorf("invalid environment.%s: %s (expected one of %s)", l.field, v, strings.Join(l.known, ", "))
+			}
+		}
+	}
+
+	if env.Node != "" {
+		for _, part := range strings.Split(env.Node, ",") {
+			if !nodeRangePart.MatchString(strings.TrimSpace(part)) {
+				return fmt.Errorf("invalid environment.node: %s (expected comparisons joined by commas, e.g. >=18,<23)", env.Node)
+			}
+		}
+	}
+	return nil
+}
+
+// spdxIdentifier matches an SPDX license or exception identifier, e.g.
+// MIT, GPL-2.0+, LicenseRef-Proprietary
+var spdxIdentifier = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.\-]*\+?$`)
+
+// isLicenseExpression reports whether s is a well-formed SPDX license
+// expression: identifiers joined by AND, OR and WITH, optionally in
+// parentheses. Identifiers are not checked against the SPDX list.
+func isLicenseExpression(s string) bool {
+	tokens := strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(s))
+	expectIdentifier, depth := true, 0
+	for _, tok := range tokens {
+		switch tok {
+		case "(":
+			if !expectIdentifier {
+				return false
+			}
+			depth++
+		case ")":
+			if expectIdentifier || depth == 0 {
+				return false
+			}
+			depth--
+		case "AND", "OR", "WITH":
+			if expectIdentifier {
+				return false
+			}
+			expectIdentifier = true
+		default:
+			if !expectIdentifier || !spdxIdentifier.MatchString(tok) {
+				return false
+			}
+			expectIdentifier = false
+		}
+	}
+	return !expectIdentifier && depth == 0
+}
+
+// isURL reports whether s is an absolute URL with a host and one of schemes
+func isURL(s string, schemes ...string) bool {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			return true
 		}
 	}
+	return false
+}
 
+// validateRequirementFields rejects extras and markers outside Python, where
+// no generated dependency file could express them
+func validateRequirementFields(language string, dep Dependency) error {
+	if len(dep.Extras) == 0 && dep.Marker == "" {
+		return nil
+	}
+	if language != "python" {
+		return fmt.Errorf("extras and marker are only supported for Python dependencies (%s)", dep.Package)
+	}
+	for _, extra := range dep.Extras {
+		if extra == "" || strings.ContainsAny(extra, "[], ;") {
+			return fmt.Errorf("invalid extra %q for %s", extra, dep.Package)
+		}
+	}
 	return nil
 }
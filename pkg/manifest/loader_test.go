@@ -1,8 +1,10 @@
 package manifest
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -246,6 +248,133 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid peer dep",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python", Version: ">=3.8"},
+				Dependencies: &Dependencies{
+					Runtime: []Dependency{{Package: "numpy", Version: ">=1.24"}},
+					Peer:    []Dependency{{Package: "torch", Version: ">=2.0"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "peer dep missing version",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python", Version: ">=3.8"},
+				Dependencies: &Dependencies{
+					Peer: []Dependency{{Package: "torch"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "peer dep also a runtime dep",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python", Version: ">=3.8"},
+				Dependencies: &Dependencies{
+					Runtime: []Dependency{{Package: "torch", Version: ">=2.0"}},
+					Peer:    []Dependency{{Package: "torch", Version: ">=2.0"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "deprecated with message",
+			m: &Manifest{
+				Name:       "test",
+				Version:    "1.0.0",
+				Language:   Language{Name: "python"},
+				Deprecated: &Deprecation{Message: "Use text-utils", Replacement: "ghcr.io/org/text-utils:2.0.0"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "deprecated without message",
+			m: &Manifest{
+				Name:       "test",
+				Version:    "1.0.0",
+				Language:   Language{Name: "python"},
+				Deprecated: &Deprecation{Replacement: "ghcr.io/org/text-utils:2.0.0"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "private with blocked registries",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python"},
+				Private:  true,
+				Publish:  &PublishSpec{BlockedRegistries: []string{"docker.io", "ghcr.io/public-org"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "blocked registry given as a URL",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python"},
+				Publish:  &PublishSpec{BlockedRegistries: []string{"https://docker.io"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid extras",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python", Version: ">=3.8"},
+				Dependencies: &Dependencies{
+					Extras: map[string][]Dependency{"viz": {{Package: "matplotlib", Version: ">=3.7"}}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "extra with invalid name",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python", Version: ">=3.8"},
+				Dependencies: &Dependencies{
+					Extras: map[string][]Dependency{"Viz_Tools": {{Package: "matplotlib", Version: ">=3.7"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "extra without dependencies",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python", Version: ">=3.8"},
+				Dependencies: &Dependencies{
+					Extras: map[string][]Dependency{"viz": {}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "extra dep missing version",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python", Version: ">=3.8"},
+				Dependencies: &Dependencies{
+					Extras: map[string][]Dependency{"viz": {{Package: "matplotlib"}}},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "valid scripts",
 			m: &Manifest{
@@ -276,6 +405,307 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "lifecycle script",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python"},
+				Scripts:  map[string]string{"my-agent": "run.py", "prebuild": "python gen.py"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "lifecycle script with empty command",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python"},
+				Scripts:  map[string]string{"postinstall": ""},
+			},
+			wantErr: true,
+		},
+		{
+			name: "generator dependency-groups",
+			m: &Manifest{
+				Name:      "test",
+				Version:   "1.0.0",
+				Language:  Language{Name: "python"},
+				Generator: &GeneratorSpec{Python: PythonStyleDependencyGroups},
+			},
+			wantErr: false,
+		},
+		{
+			name: "generator unknown python style",
+			m: &Manifest{
+				Name:      "test",
+				Version:   "1.0.0",
+				Language:  Language{Name: "python"},
+				Generator: &GeneratorSpec{Python: "extras"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "python extras and marker",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python", Version: ">=3.8"},
+				Dependencies: &Dependencies{Runtime: []Dependency{
+					{Package: "requests", Version: ">=2.31", Extras: []string{"socks"}, Marker: `python_version < "3.11"`},
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "extras outside python",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "javascript", Version: ">=18"},
+				Dependencies: &Dependencies{Runtime: []Dependency{
+					{Package: "axios", Version: "^1.6.0", Extras: []string{"socks"}},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "multi-language package",
+			m: &Manifest{
+				Name:      "test",
+				Version:   "1.0.0",
+				Language:  Language{Name: "python", Version: ">=3.8"},
+				Languages: []Language{{Name: "javascript", Version: ">=18"}},
+				Dependencies: &Dependencies{Runtime: []Dependency{
+					{Package: "requests", Version: ">=2.31.0"},
+					{Package: "axios", Version: "^1.6.0", Language: "javascript"},
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "languages repeats the primary language",
+			m: &Manifest{
+				Name:      "test",
+				Version:   "1.0.0",
+				Language:  Language{Name: "python"},
+				Languages: []Language{{Name: "python"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "languages unsupported",
+			m: &Manifest{
+				Name:      "test",
+				Version:   "1.0.0",
+				Language:  Language{Name: "python"},
+				Languages: []Language{{Name: "cobol"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "dependency language not in package",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python", Version: ">=3.8"},
+				Dependencies: &Dependencies{Runtime: []Dependency{
+					{Package: "axios", Version: "^1.6.0", Language: "javascript"},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "secondary language dependencies without a version",
+			m: &Manifest{
+				Name:      "test",
+				Version:   "1.0.0",
+				Language:  Language{Name: "python", Version: ">=3.8"},
+				Languages: []Language{{Name: "javascript"}},
+				Dependencies: &Dependencies{Runtime: []Dependency{
+					{Package: "axios", Version: "^1.6.0", Language: "javascript"},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "generator pnpm",
+			m: &Manifest{
+				Name:      "test",
+				Version:   "1.0.0",
+				Language:  Language{Name: "javascript"},
+				Generator: &GeneratorSpec{JavaScript: JSPackageManagerPnpm},
+			},
+			wantErr: false,
+		},
+		{
+			name: "generator unknown javascript package manager",
+			m: &Manifest{
+				Name:      "test",
+				Version:   "1.0.0",
+				Language:  Language{Name: "javascript"},
+				Generator: &GeneratorSpec{JavaScript: "bower"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "file attributes",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python"},
+				Files: FileSpec{Attributes: []FileAttributes{
+					{Path: "cli.py", Executable: true},
+					{Path: "config/*.yaml.template", Template: true},
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "file attributes without a path",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python"},
+				Files:    FileSpec{Attributes: []FileAttributes{{Executable: true}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "template file without the .template suffix",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python"},
+				Files:    FileSpec{Attributes: []FileAttributes{{Path: "config.yaml", Template: true}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "readme",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python"},
+				Readme:   "docs/README.md",
+			},
+			wantErr: false,
+		},
+		{
+			name: "readme outside the package",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python"},
+				Readme:   "../README.md",
+			},
+			wantErr: true,
+		},
+		{
+			name: "environment constraints",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python"},
+				Environment: &EnvironmentSpec{
+					OS:                   []string{"linux", "darwin"},
+					Arch:                 []string{"amd64", "arm64"},
+					PythonImplementation: []string{"cpython"},
+					Node:                 ">=18, <23",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "environment unknown os",
+			m: &Manifest{
+				Name:        "test",
+				Version:     "1.0.0",
+				Language:    Language{Name: "python"},
+				Environment: &EnvironmentSpec{OS: []string{"macos"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "environment unknown arch",
+			m: &Manifest{
+				Name:        "test",
+				Version:     "1.0.0",
+				Language:    Language{Name: "python"},
+				Environment: &EnvironmentSpec{Arch: []string{"x86_64"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "environment unknown python implementation",
+			m: &Manifest{
+				Name:        "test",
+				Version:     "1.0.0",
+				Language:    Language{Name: "python"},
+				Environment: &EnvironmentSpec{PythonImplementation: []string{"CPython"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "environment node range with spaces",
+			m: &Manifest{
+				Name:        "test",
+				Version:     "1.0.0",
+				Language:    Language{Name: "javascript"},
+				Environment: &EnvironmentSpec{Node: ">=18 <23"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "lint rule severities",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python"},
+				Lint:     &LintSpec{Rules: map[string]string{"missing-license": LintError, "auto-include": LintOff}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "lint rule unknown severity",
+			m: &Manifest{
+				Name:     "test",
+				Version:  "1.0.0",
+				Language: Language{Name: "python"},
+				Lint:     &LintSpec{Rules: map[string]string{"missing-license": "fatal"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "custom namespace",
+			m: &Manifest{
+				Name:      "test",
+				Version:   "1.0.0",
+				Language:  Language{Name: "python"},
+				Namespace: &NamespaceSpec{Python: "ourco.snippets", JavaScript: "@ourco"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "python namespace not a package name",
+			m: &Manifest{
+				Name:      "test",
+				Version:   "1.0.0",
+				Language:  Language{Name: "python"},
+				Namespace: &NamespaceSpec{Python: "our-co.snippets"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "javascript namespace without @",
+			m: &Manifest{
+				Name:      "test",
+				Version:   "1.0.0",
+				Language:  Language{Name: "python"},
+				Namespace: &NamespaceSpec{JavaScript: "ourco"},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -288,6 +718,65 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidateMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		md      Metadata
+		wantErr string
+	}{
+		{name: "empty"},
+		{
+			name: "all fields",
+			md: Metadata{
+				License:    "Apache-2.0 OR MIT",
+				Repository: "https://github.com/org/repo",
+				Homepage:   "https://example.com/docs",
+				Keywords:   []string{"http", "retry"},
+			},
+		},
+		{name: "license with exception", md: Metadata{License: "(GPL-2.0-or-later WITH Classpath-exception-2.0) AND MIT"}},
+		{name: "license or-later suffix", md: Metadata{License: "GPL-2.0+"}},
+		{name: "license prose", md: Metadata{License: "MIT License"}, wantErr: "metadata.license"},
+		{name: "license dangling operator", md: Metadata{License: "MIT OR"}, wantErr: "metadata.license"},
+		{name: "license unbalanced parentheses", md: Metadata{License: "(MIT OR Apache-2.0"}, wantErr: "metadata.license"},
+		{name: "repository git+ssh", md: Metadata{Repository: "git+ssh://git@github.com/org/repo.git"}},
+		{name: "repository without scheme", md: Metadata{Repository: "github.com/org/repo"}, wantErr: "metadata.repository"},
+		{name: "homepage ftp", md: Metadata{Homepage: "ftp://example.com"}, wantErr: "metadata.homepage"},
+		{name: "empty keyword", md: Metadata{Keywords: []string{"http", " "}}, wantErr: "metadata.keywords[1] is empty"},
+		{name: "duplicate keyword", md: Metadata{Keywords: []string{"HTTP", "http"}}, wantErr: "more than once"},
+		{name: "long keyword", md: Metadata{Keywords: []string{strings.Repeat("k", 51)}}, wantErr: "longer than 50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manifest{Name: "test", Version: "1.0.0", Language: Language{Name: "python"}, Metadata: tt.md}
+			err := Validate(m)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEntryScripts(t *testing.T) {
+	m := &Manifest{Scripts: map[string]string{
+		"my-agent":    "run.py",
+		"prebuild":    "python gen.py",
+		"postbuild":   "echo built",
+		"postinstall": "echo installed",
+	}}
+	got := m.EntryScripts()
+	if len(got) != 1 || got["my-agent"] != "run.py" {
+		t.Errorf("EntryScripts() = %v, want only my-agent", got)
+	}
+}
+
 func TestValidateLanguage(t *testing.T) {
 	tests := []struct {
 		lang  string
@@ -329,3 +818,41 @@ func TestSupportedLanguages(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadAigogoVersion(t *testing.T) {
+	previous := CLIVersion
+	t.Cleanup(func() { CLIVersion = previous })
+
+	path := filepath.Join(t.TempDir(), "aigogo.json")
+	// A newer format may use values this version rejects; the version
+	// check must come first
+	content := `{"aigogoVersion": ">=2.0", "name": "utils", "version": "1.0.0", "language": {"name": "cobol"}, "files": {"include": []}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	CLIVersion = "1.9.3"
+	_, err := Load(path)
+	if !errors.Is(err, ErrUpgradeRequired) {
+		t.Fatalf("Load with aigogo 1.9.3: err = %v, want ErrUpgradeRequired", err)
+	}
+	if !strings.Contains(err.Error(), "utils requires aigogo >=2.0, but this is aigogo 1.9.3") {
+		t.Errorf("unexpected message: %v", err)
+	}
+
+	// Builds from git describe count as the release they follow, and
+	// unversioned builds aren't checked
+	for _, v := range []string{"v2.0.0-3-gdeadbee", "dev", ""} {
+		CLIVersion = v
+		if _, err := Load(path); errors.Is(err, ErrUpgradeRequired) {
+			t.Errorf("Load with aigogo %q: %v", v, err)
+		}
+	}
+}
+
+func TestValidateAigogoVersion(t *testing.T) {
+	m := &Manifest{Name: "utils", Version: "1.0.0", Language: Language{Name: "python"}, AigogoVersion: ">= soon"}
+	if err := Validate(m); err == nil || !strings.Contains(err.Error(), "invalid aigogoVersion") {
+		t.Errorf("Validate: err = %v, want an invalid aigogoVersion error", err)
+	}
+}
@@ -0,0 +1,389 @@
+package manifest
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// schemaJSON is a copy of aigogo.schema.json at the repository root, which
+// is what the "$schema" URL written by aigg init points to
+//
+//go:embed aigogo.schema.json
+var schemaJSON []byte
+
+// Schema returns the JSON Schema (draft-07) for aigogo.json
+func Schema() []byte {
+	return schemaJSON
+}
+
+// SchemaError is a place where aigogo.json does not match the schema
+type SchemaError struct {
+	Path    string // JSON path, e.g. dependencies.runtime[0].version
+	Line    int    // 1-based line in the manifest
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Path, e.Message)
+}
+
+// ValidateSchema checks the contents of an aigogo.json against the embedded
+// schema and returns every violation in document order. Unlike Load, which
+// ignores unknown fields, it reports misspelled and unsupported fields. The
+// error is non-nil only when data is not valid JSON (comments and trailing
+// commas are accepted, as by Load).
+func ValidateSchema(data []byte) ([]SchemaError, error) {
+	var root schemaNode
+	if err := json.Unmarshal(schemaJSON, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse embedded schema: %w", err)
+	}
+
+	data, _, err := StripJSONC(data)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := parseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &schemaValidator{definitions: root.Definitions}
+	v.validate(doc, &root, "")
+	return v.errors, nil
+}
+
+// schemaNode is the subset of JSON Schema draft-07 that aigogo.schema.json
+// uses
+type schemaNode struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 string                 `json:"type"`
+	Required             []string               `json:"required"`
+	Properties           map[string]*schemaNode `json:"properties"`
+	AdditionalProperties json.RawMessage        `json:"additionalProperties"`
+	Items                *schemaNode            `json:"items"`
+	Enum                 []string               `json:"enum"`
+	Pattern              string                 `json:"pattern"`
+	OneOf                []*schemaNode          `json:"oneOf"`
+	If                   *schemaNode            `json:"if"`
+	Then                 *schemaNode            `json:"then"`
+	Else                 *schemaNode            `json:"else"`
+	Definitions          map[string]*schemaNode `json:"definitions"`
+}
+
+// jsonValue is a parsed JSON value that remembers where it was written
+type jsonValue struct {
+	kind     string // object, array, string, number, boolean or null
+	line     int
+	str      string
+	keys     []string // object keys in document order
+	fields   map[string]*jsonValue
+	keyLines map[string]int
+	items    []*jsonValue
+}
+
+// parseDocument parses JSON into a tree of values with line numbers
+func parseDocument(data []byte) (*jsonValue, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	p := &documentParser{data: data, dec: dec}
+	value, err := p.parse()
+	if err != nil {
+		return nil, p.syntaxError(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("line %d: unexpected data after the top-level value", p.lineAt(dec.InputOffset()))
+	}
+	return value, nil
+}
+
+type documentParser struct {
+	data []byte
+	dec  *json.Decoder
+}
+
+func (p *documentParser) parse() (*jsonValue, error) {
+	line := p.lineAt(p.dec.InputOffset())
+	tok, err := p.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			obj := &jsonValue{kind: "object", line: line, fields: make(map[string]*jsonValue), keyLines: make(map[string]int)}
+			for p.dec.More() {
+				keyLine := p.lineAt(p.dec.InputOffset())
+				keyTok, err := p.dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key := keyTok.(string)
+				value, err := p.parse()
+				if err != nil {
+					return nil, err
+				}
+				if _, dup := obj.fields[key]; !dup {
+					obj.keys = append(obj.keys, key)
+				}
+				obj.fields[key] = value
+				obj.keyLines[key] = keyLine
+			}
+			_, err := p.dec.Token() // closing }
+			return obj, err
+		}
+		arr := &jsonValue{kind: "array", line: line}
+		for p.dec.More() {
+			item, err := p.parse()
+			if err != nil {
+				return nil, err
+			}
+			arr.items = append(arr.items, item)
+		}
+		_, err := p.dec.Token() // closing ]
+		return arr, err
+	case string:
+		return &jsonValue{kind: "string", line: line, str: t}, nil
+	case json.Number:
+		return &jsonValue{kind: "number", line: line, str: t.String()}, nil
+	case bool:
+		return &jsonValue{kind: "boolean", line: line}, nil
+	}
+	return &jsonValue{kind: "null", line: line}, nil
+}
+
+// lineAt returns the line of the next token at or after offset, skipping
+// the whitespace and separators the decoder hasn't consumed yet
+func (p *documentParser) lineAt(offset int64) int {
+	i := int(offset)
+	for i < len(p.data) && strings.IndexByte(" \t\r\n,:", p.data[i]) != -1 {
+		i++
+	}
+	return bytes.Count(p.data[:i], []byte("\n")) + 1
+}
+
+// syntaxError adds the line number to a JSON syntax error
+func (p *documentParser) syntaxError(err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line := bytes.Count(p.data[:min(int(syntaxErr.Offset), len(p.data))], []byte("\n")) + 1
+		return fmt.Errorf("line %d: invalid JSON: %w", line, err)
+	}
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return fmt.Errorf("invalid JSON: unexpected end of file")
+	}
+	return fmt.Errorf("invalid JSON: %w", err)
+}
+
+type schemaValidator struct {
+	definitions map[string]*schemaNode
+	errors      []SchemaError
+}
+
+func (v *schemaValidator) addError(path string, line int, format string, args ...interface{}) {
+	v.errors = append(v.errors, SchemaError{Path: path, Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) validate(value *jsonValue, s *schemaNode, path string) {
+	if s.Ref != "" {
+		s = v.definitions[strings.TrimPrefix(s.Ref, "#/definitions/")]
+		if s == nil {
+			return
+		}
+	}
+
+	if len(s.OneOf) > 0 {
+		v.validateOneOf(value, s.OneOf, path)
+		return
+	}
+
+	if s.Type != "" && !typeMatches(value, s.Type) {
+		v.addError(path, value.line, "expected %s, got %s", s.Type, describeValue(value))
+		return
+	}
+
+	if s.If != nil {
+		v.validateCondition(value, s, path)
+	}
+
+	switch value.kind {
+	case "string":
+		if len(s.Enum) > 0 && !containsString(s.Enum, value.str) {
+			v.addError(path, value.line, "%q is not one of: %s", value.str, strings.Join(s.Enum, ", "))
+		}
+		if s.Pattern != "" {
+			if re, err := regexp.Compile(s.Pattern); err == nil && !re.MatchString(value.str) {
+				v.addError(path, value.line, "%q does not match pattern %s", value.str, s.Pattern)
+			}
+		}
+	case "array":
+		if s.Items != nil {
+			for i, item := range value.items {
+				v.validate(item, s.Items, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case "object":
+		v.validateObject(value, s, path)
+	}
+}
+
+func (v *schemaValidator) validateObject(value *jsonValue, s *schemaNode, path string) {
+	for _, name := range s.Required {
+		if _, ok := value.fields[name]; !ok {
+			v.addError(path, value.line, "missing required field %q", name)
+		}
+	}
+
+	// additionalProperties is either false or a schema for the other fields
+	closed := string(s.AdditionalProperties) == "false"
+	var additional *schemaNode
+	if len(s.AdditionalProperties) > 0 && !closed {
+		additional = &schemaNode{}
+		if err := json.Unmarshal(s.AdditionalProperties, additional); err != nil {
+			additional = nil
+		}
+	}
+
+	for _, key := range value.keys {
+		childPath := joinPath(path, key)
+		if prop, ok := s.Properties[key]; ok {
+			v.validate(value.fields[key], prop, childPath)
+			continue
+		}
+		if closed {
+			msg := "unknown field"
+			if suggestion := closestField(key, s.Properties); suggestion != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+			}
+			v.addError(childPath, value.keyLines[key], "%s", msg)
+			continue
+		}
+		if additional != nil {
+			v.validate(value.fields[key], additional, childPath)
+		}
+	}
+}
+
+// validateOneOf accepts a value that matches one of the alternatives. When
+// none match, the errors of the alternative with the value's type are
+// reported, since that is almost always the one the author meant.
+func (v *schemaValidator) validateOneOf(value *jsonValue, alternatives []*schemaNode, path string) {
+	var types []string
+	var candidate []SchemaError
+	found := false
+	for _, alt := range alternatives {
+		sub := &schemaValidator{definitions: v.definitions}
+		sub.validate(value, alt, path)
+		if len(sub.errors) == 0 {
+			return
+		}
+		types = append(types, alt.Type)
+		if !found && typeMatches(value, alt.Type) {
+			candidate = sub.errors
+			found = true
+		}
+	}
+
+	if found {
+		v.errors = append(v.errors, candidate...)
+		return
+	}
+	v.addError(path, value.line, "expected %s, got %s", strings.Join(types, " or "), describeValue(value))
+}
+
+// validateCondition applies then when the value matches if, and else
+// otherwise
+func (v *schemaValidator) validateCondition(value *jsonValue, s *schemaNode, path string) {
+	sub := &schemaValidator{definitions: v.definitions}
+	sub.validate(value, s.If, path)
+	switch {
+	case len(sub.errors) == 0 && s.Then != nil:
+		v.validate(value, s.Then, path)
+	case len(sub.errors) > 0 && s.Else != nil:
+		v.validate(value, s.Else, path)
+	}
+}
+
+// typeMatches reports whether a value has the given JSON Schema type
+func typeMatches(value *jsonValue, schemaType string) bool {
+	switch schemaType {
+	case "":
+		return true
+	case "integer":
+		return value.kind == "number" && !strings.ContainsAny(value.str, ".eE")
+	}
+	return value.kind == schemaType
+}
+
+// describeValue names a value's type for error messages, quoting short
+// scalars so the offending value is visible
+func describeValue(value *jsonValue) string {
+	switch value.kind {
+	case "string":
+		return fmt.Sprintf("string %q", value.str)
+	case "number":
+		return "number " + value.str
+	}
+	return value.kind
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// closestField returns the known field name nearest to key, if any is
+// within a couple of edits
+func closestField(key string, properties map[string]*schemaNode) string {
+	best, bestDistance := "", 3
+	for name := range properties {
+		d := editDistance(strings.ToLower(key), strings.ToLower(name))
+		if d < bestDistance || (d == bestDistance && best != "" && name < best) {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,140 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchemaMatchesRepositoryCopy(t *testing.T) {
+	published, err := os.ReadFile(filepath.Join("..", "..", "aigogo.schema.json"))
+	if err != nil {
+		t.Fatalf("failed to read aigogo.schema.json: %v", err)
+	}
+	if !bytes.Equal(published, Schema()) {
+		t.Error("pkg/manifest/aigogo.schema.json is out of date; copy it from the repository root")
+	}
+}
+
+// Every field Load understands must be in the schema, or schema validation
+// would reject manifests that aigg itself writes
+func TestSchemaCoversManifestFields(t *testing.T) {
+	var root schemaNode
+	if err := json.Unmarshal(Schema(), &root); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+
+	var check func(typ reflect.Type, s *schemaNode, path string)
+	check = func(typ reflect.Type, s *schemaNode, path string) {
+		if s.Ref != "" {
+			s = root.Definitions[strings.TrimPrefix(s.Ref, "#/definitions/")]
+		}
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			prop, ok := s.Properties[name]
+			if !ok {
+				t.Errorf("schema is missing %s%s", path, name)
+				continue
+			}
+
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice {
+				ft = ft.Elem()
+				if prop.Items != nil {
+					prop = prop.Items
+				}
+			}
+			if ft.Kind() == reflect.Struct {
+				check(ft, prop, path+name+".")
+			}
+		}
+	}
+	check(reflect.TypeOf(Manifest{}), &root, "")
+}
+
+func TestValidateSchema(t *testing.T) {
+	content := `{
+  "name": "my-utils",
+  "version": "1.0.0",
+  "language": {
+    "name": "perl",
+    "version": ">=3.8"
+  },
+  "dependencies": {
+    "runtime": [
+      {"pakage": "requests", "version": ">=2.31.0"},
+      {"package": "flask", "version": 2}
+    ]
+  },
+  "files": {
+    "include": 5
+  },
+  "metadata": {"tags": "http"},
+  "licence": "MIT"
+}`
+
+	errs, err := ValidateSchema([]byte(content))
+	if err != nil {
+		t.Fatalf("ValidateSchema failed: %v", err)
+	}
+
+	want := []SchemaError{
+		{Path: "language.name", Line: 5, Message: `"perl" is not one of: python, javascript, go, rust, ruby, java, csharp, php`},
+		{Path: "dependencies.runtime[0]", Line: 10, Message: `missing required field "package"`},
+		{Path: "dependencies.runtime[0].pakage", Line: 10, Message: `unknown field (did you mean "package"?)`},
+		{Path: "dependencies.runtime[1].version", Line: 11, Message: "expected string, got number 2"},
+		{Path: "files.include", Line: 15, Message: "expected string or array, got number 5"},
+		{Path: "metadata.tags", Line: 17, Message: `expected array, got string "http"`},
+		{Path: "licence", Line: 18, Message: "unknown field"},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("ValidateSchema() =\n%v\nwant\n%v", errs, want)
+	}
+}
+
+func TestValidateSchemaValidManifest(t *testing.T) {
+	m := &Manifest{
+		Schema:   "https://github.com/aupeachmo/aigogo/blob/master/aigogo.schema.json",
+		Name:     "my_utils",
+		Version:  "0.1.0",
+		Language: Language{Name: "python", Version: ">=3.8,<4.0"},
+		Dependencies: &Dependencies{
+			Runtime: []Dependency{{Package: "requests", Version: ">=2.31", Extras: []string{"socks"}}},
+		},
+		Files:     FileSpec{Include: "auto", Exclude: []string{"tests/"}},
+		Scripts:   map[string]string{"run": "main.py"},
+		Metadata:  Metadata{License: "MIT", Tags: []string{"http"}},
+		Generator: &GeneratorSpec{Python: PythonStyleDependencyGroups},
+	}
+	path := filepath.Join(t.TempDir(), "aigogo.json")
+	if err := Save(path, m); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs, err := ValidateSchema(data)
+	if err != nil {
+		t.Fatalf("ValidateSchema failed: %v", err)
+	}
+	if len(errs) != 0 {
+		t.Errorf("expected no schema errors, got %v", errs)
+	}
+}
+
+func TestValidateSchemaInvalidJSON(t *testing.T) {
+	_, err := ValidateSchema([]byte("{\n  \"name\": \"x\",\n  \"version\": \n}"))
+	if err == nil || !strings.Contains(err.Error(), "line 4") {
+		t.Errorf("expected a syntax error on line 4, got %v", err)
+	}
+}
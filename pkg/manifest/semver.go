@@ -0,0 +1,221 @@
+package manifest
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version bump levels accepted by BumpVersion
+const (
+	BumpMajor = "major"
+	BumpMinor = "minor"
+	BumpPatch = "patch"
+)
+
+// semverPattern is the regular expression from semver.org
+var semverPattern = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// Semver is a parsed semantic version. Build metadata is kept for String
+// but ignored when comparing.
+type Semver struct {
+	Major, Minor, Patch int
+	Prerelease          string // e.g. beta.1
+	Build               string // e.g. 20240101
+}
+
+// ParseSemver parses a semantic version such as 1.2.3, 1.0.0-rc.1 or
+// 1.0.0+build.5
+func ParseSemver(version string) (Semver, error) {
+	match := semverPattern.FindStringSubmatch(version)
+	if match == nil {
+		return Semver{}, fmt.Errorf("invalid semver: %q (expected X.Y.Z, e.g. 1.2.3 or 1.2.3-beta.1)", version)
+	}
+	var v Semver
+	// The pattern guarantees the numbers parse; only overflow can fail
+	var err error
+	if v.Major, err = strconv.Atoi(match[1]); err != nil {
+		return Semver{}, fmt.Errorf("invalid semver: %q: %w", version, err)
+	}
+	if v.Minor, err = strconv.Atoi(match[2]); err != nil {
+		return Semver{}, fmt.Errorf("invalid semver: %q: %w", version, err)
+	}
+	if v.Patch, err = strconv.Atoi(match[3]); err != nil {
+		return Semver{}, fmt.Errorf("invalid semver: %q: %w", version, err)
+	}
+	v.Prerelease = match[4]
+	v.Build = match[5]
+	return v, nil
+}
+
+func (v Semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 as v has lower, equal or higher precedence
+// than other, following the semver.org rules
+func (v Semver) Compare(other Semver) int {
+	for _, d := range [][2]int{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if d[0] != d[1] {
+			return compareInts(d[0], d[1])
+		}
+	}
+
+	// A pre-release sorts before the release itself
+	switch {
+	case v.Prerelease == other.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	}
+
+	a, b := strings.Split(v.Prerelease, "."), strings.Split(other.Prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		na, errA := strconv.Atoi(a[i])
+		nb, errB := strconv.Atoi(b[i])
+		switch {
+		case errA == nil && errB == nil:
+			return compareInts(na, nb)
+		case errA == nil:
+			return -1 // numeric identifiers sort before alphanumeric ones
+		case errB == nil:
+			return 1
+		}
+		return strings.Compare(a[i], b[i])
+	}
+	return compareInts(len(a), len(b))
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// BumpVersion returns the next major, minor or patch version, the way npm
+// version does: bumping a pre-release releases it at the level asked for
+// ("1.1.0-rc.1" minor -> "1.1.0"), and pre-release and build metadata are
+// dropped
+func BumpVersion(version, level string) (string, error) {
+	v, err := ParseSemver(version)
+	if err != nil {
+		return "", err
+	}
+
+	pre := v.Prerelease != ""
+	switch level {
+	case BumpMajor:
+		if !pre || v.Minor != 0 || v.Patch != 0 {
+			v.Major, v.Minor, v.Patch = v.Major+1, 0, 0
+		}
+	case BumpMinor:
+		if !pre || v.Patch != 0 {
+			v.Minor, v.Patch = v.Minor+1, 0
+		}
+	case BumpPatch:
+		if !pre {
+			v.Patch++
+		}
+	default:
+		return "", fmt.Errorf("invalid version bump %q (expected %s, %s or %s)", level, BumpMajor, BumpMinor, BumpPatch)
+	}
+	v.Prerelease, v.Build = "", ""
+	return v.String(), nil
+}
+
+// SatisfiesConstraint reports whether version is in the range of
+// constraint, a comma-separated list of comparisons such as ">=1.4.0" or
+// ">=1.4, <2". A bare version means that version or later. Versions may
+// start with "v" and leave out the minor and patch numbers, which are then
+// zero.
+func SatisfiesConstraint(version, constraint string) (bool, error) {
+	v, err := parseLooseSemver(version)
+	if err != nil {
+		return false, err
+	}
+	comparisons, err := parseConstraint(constraint)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range comparisons {
+		if !c.matches(v) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// comparison is one term of a version constraint, e.g. >=1.4.0
+type comparison struct {
+	op      string
+	version Semver
+}
+
+func (c comparison) matches(v Semver) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "=", "==":
+		return cmp == 0
+	}
+	return cmp >= 0 // ">=" or a bare version
+}
+
+// parseConstraint parses a constraint for SatisfiesConstraint
+func parseConstraint(constraint string) ([]comparison, error) {
+	var comparisons []comparison
+	for _, term := range strings.Split(constraint, ",") {
+		term = strings.TrimSpace(term)
+		op := ""
+		for _, candidate := range []string{">=", "<=", "==", ">", "<", "="} {
+			if strings.HasPrefix(term, candidate) {
+				op = candidate
+				break
+			}
+		}
+		v, err := parseLooseSemver(strings.TrimSpace(term[len(op):]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+		}
+		comparisons = append(comparisons, comparison{op: op, version: v})
+	}
+	return comparisons, nil
+}
+
+// parseLooseSemver parses a version that may start with "v" and leave out
+// its minor and patch numbers, e.g. v1.4
+func parseLooseSemver(version string) (Semver, error) {
+	version = strings.TrimPrefix(version, "v")
+	core, suffix := version, ""
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		core, suffix = version[:i], version[i:]
+	}
+	if n := strings.Count(core, "."); core != "" && n < 2 {
+		core += strings.Repeat(".0", 2-n)
+	}
+	return ParseSemver(core + suffix)
+}
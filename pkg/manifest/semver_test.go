@@ -0,0 +1,135 @@
+package manifest
+
+import "testing"
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Semver
+		wantErr bool
+	}{
+		{input: "1.2.3", want: Semver{Major: 1, Minor: 2, Patch: 3}},
+		{input: "0.1.0-rc.1", want: Semver{Minor: 1, Prerelease: "rc.1"}},
+		{input: "1.0.0-beta+exp.sha.5114f85", want: Semver{Major: 1, Prerelease: "beta", Build: "exp.sha.5114f85"}},
+		{input: "1.2", wantErr: true},
+		{input: "01.2.3", wantErr: true},
+		{input: "v1.2.3", wantErr: true},
+		{input: "1.2.3-", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSemver(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSemver(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSemver(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+			if !tt.wantErr && got.String() != tt.input {
+				t.Errorf("String() = %q, want %q", got.String(), tt.input)
+			}
+		})
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	// In increasing order, from the semver.org precedence example
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.10.0",
+		"2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, _ := ParseSemver(ordered[i])
+			b, _ := ParseSemver(ordered[j])
+			if got, want := a.Compare(b), compareInts(i, j); got != want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+
+	a, _ := ParseSemver("1.0.0+build.1")
+	b, _ := ParseSemver("1.0.0+build.2")
+	if a.Compare(b) != 0 {
+		t.Error("build metadata should not affect precedence")
+	}
+}
+
+func TestBumpVersion(t *testing.T) {
+	tests := []struct {
+		version, level, want string
+	}{
+		{"1.2.3", BumpPatch, "1.2.4"},
+		{"1.2.3", BumpMinor, "1.3.0"},
+		{"1.2.3", BumpMajor, "2.0.0"},
+		{"1.2.3+build.7", BumpPatch, "1.2.4"},
+		{"1.2.3-rc.1", BumpPatch, "1.2.3"},
+		{"1.3.0-rc.1", BumpMinor, "1.3.0"},
+		{"1.2.3-rc.1", BumpMinor, "1.3.0"},
+		{"2.0.0-rc.1", BumpMajor, "2.0.0"},
+		{"2.1.0-rc.1", BumpMajor, "3.0.0"},
+	}
+	for _, tt := range tests {
+		got, err := BumpVersion(tt.version, tt.level)
+		if err != nil {
+			t.Errorf("BumpVersion(%q, %q) error = %v", tt.version, tt.level, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("BumpVersion(%q, %q) = %q, want %q", tt.version, tt.level, got, tt.want)
+		}
+	}
+
+	if _, err := BumpVersion("1.2.3", "micro"); err == nil {
+		t.Error("expected an error for an unknown bump level")
+	}
+	if _, err := BumpVersion("1.2", BumpPatch); err == nil {
+		t.Error("expected an error for a version that isn't semver")
+	}
+}
+
+func TestSatisfiesConstraint(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		want       bool
+		wantErr    bool
+	}{
+		{version: "1.4.0", constraint: ">=1.4.0", want: true},
+		{version: "1.3.9", constraint: ">=1.4.0", want: false},
+		{version: "1.4.0", constraint: "1.4", want: true},
+		{version: "2.0.0", constraint: "1.4", want: true},
+		{version: "v1.5.2", constraint: ">=1.4, <2", want: true},
+		{version: "2.0.0", constraint: ">=1.4, <2", want: false},
+		{version: "2.0.0-rc.1", constraint: "<2", want: true},
+		{version: "1.4.0", constraint: ">1.4", want: false},
+		{version: "1.4.0", constraint: "<=1.4.0", want: true},
+		{version: "1.4.0", constraint: "==1.4.0", want: true},
+		{version: "1.4.1", constraint: "=1.4.0", want: false},
+		{version: "1.4.0", constraint: ">=one", wantErr: true},
+		{version: "1.4.0", constraint: ">=1.4,", wantErr: true},
+		{version: "dev", constraint: ">=1.4", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := SatisfiesConstraint(tt.version, tt.constraint)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SatisfiesConstraint(%q, %q) error = %v, wantErr %v", tt.version, tt.constraint, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("SatisfiesConstraint(%q, %q) = %v, want %v", tt.version, tt.constraint, got, tt.want)
+		}
+	}
+}
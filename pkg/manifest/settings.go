@@ -0,0 +1,291 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Install modes: how aigg install puts packages into .aigogo/imports/
+const (
+	// InstallModeLink links to the files in the store, falling back to
+	// junctions or copies where symlinks can't be made; the default
+	InstallModeLink = "link"
+	// InstallModeCopy copies the files, for tools that don't follow links
+	// out of the project, such as a Docker build context
+	InstallModeCopy = "copy"
+)
+
+// Python layouts: where aigg install puts Python packages
+const (
+	// PythonLayoutImports links them under .aigogo/imports/, which a .pth
+	// file adds to the environment's path; the default
+	PythonLayoutImports = "imports"
+	// PythonLayoutPyPackages links them under the PEP 582
+	// __pypackages__/<X.Y>/lib/, for pdm and other tools that use it
+	PythonLayoutPyPackages = "pypackages"
+)
+
+// Tag policies: what aigg push and aigg add do with a tag a policy covers
+const (
+	// TagPolicyAllow accepts it; the default
+	TagPolicyAllow = "allow"
+	// TagPolicyWarn accepts it with a warning
+	TagPolicyWarn = "warn"
+	// TagPolicyBlock refuses it
+	TagPolicyBlock = "block"
+)
+
+var (
+	// pythonNamespacePattern matches dotted Python package names
+	pythonNamespacePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+	// npmScopePattern matches npm scopes, which are lowercase
+	npmScopePattern = regexp.MustCompile(`^@[a-z0-9][a-z0-9._-]*$`)
+)
+
+// InstallSpec configures aigg install
+type InstallSpec struct {
+	Mode         string `json:"mode,omitempty" toml:"mode,omitempty"`                   // link (default) or copy
+	PythonLayout string `json:"python_layout,omitempty" toml:"python_layout,omitempty"` // imports (default) or pypackages
+}
+
+// TagPolicySpec nudges a project toward immutable, version-tagged packages
+type TagPolicySpec struct {
+	Mutable   string `json:"mutable,omitempty" toml:"mutable,omitempty"`     // Pushing or adding a tag that isn't a version, such as latest: allow (default), warn or block
+	Overwrite string `json:"overwrite,omitempty" toml:"overwrite,omitempty"` // Pushing a version tag the registry already has: allow (default), warn or block
+}
+
+// Settings are the settings of the project aigg works in rather than of a
+// package: where installed packages go and where packages come from. They
+// are read from the project's aigogo.json and the [tool.aigogo] table of its
+// pyproject.toml, which take the same keys.
+type Settings struct {
+	Namespace *NamespaceSpec `json:"namespace,omitempty" toml:"namespace,omitempty"`
+	Store     string         `json:"store,omitempty" toml:"store,omitempty"`           // Store directory; relative to the project, ~ for the home directory
+	Install   *InstallSpec   `json:"install,omitempty" toml:"install,omitempty"`       // How aigg install links packages
+	Registry  string         `json:"registry,omitempty" toml:"registry,omitempty"`     // Registry, and optionally namespace, for references without one
+	TagPolicy *TagPolicySpec `json:"tag_policy,omitempty" toml:"tag_policy,omitempty"` // What push and add do with mutable and existing tags
+}
+
+// ProjectSettings returns the project settings set in the manifest
+func (m *Manifest) ProjectSettings() *Settings {
+	return &Settings{
+		Namespace: m.Namespace,
+		Store:     m.Store,
+		Install:   m.Install,
+		Registry:  m.Registry,
+		TagPolicy: m.TagPolicy,
+	}
+}
+
+// InstallMode returns the configured install mode, defaulting to link
+func (s *Settings) InstallMode() string {
+	if s.Install == nil || s.Install.Mode == "" {
+		return InstallModeLink
+	}
+	return s.Install.Mode
+}
+
+// PythonLayout returns the configured Python layout, defaulting to imports
+func (s *Settings) PythonLayout() string {
+	if s.Install == nil || s.Install.PythonLayout == "" {
+		return PythonLayoutImports
+	}
+	return s.Install.PythonLayout
+}
+
+// MutableTagPolicy returns the policy for tags that aren't versions,
+// defaulting to allow
+func (s *Settings) MutableTagPolicy() string {
+	if s.TagPolicy == nil || s.TagPolicy.Mutable == "" {
+		return TagPolicyAllow
+	}
+	return s.TagPolicy.Mutable
+}
+
+// OverwriteTagPolicy returns the policy for pushing a version tag the
+// registry already has, defaulting to allow
+func (s *Settings) OverwriteTagPolicy() string {
+	if s.TagPolicy == nil || s.TagPolicy.Overwrite == "" {
+		return TagPolicyAllow
+	}
+	return s.TagPolicy.Overwrite
+}
+
+// IsVersionTag reports whether an image tag is a version, such as 1.2.3 or
+// v1.2.3-rc.1, rather than a tag meant to move, such as latest
+func IsVersionTag(tag string) bool {
+	_, err := ParseSemver(strings.TrimPrefix(tag, "v"))
+	return err == nil
+}
+
+// LoadSettings reads the project settings of projectDir from [tool.aigogo]
+// in pyproject.toml and from aigogo.json, where a key set in both takes
+// aigogo.json's value. Either file may be missing, and aigogo.json needn't
+// be a complete manifest: a project that only installs packages can have
+// one with just its settings. A relative store is resolved against
+// projectDir.
+func LoadSettings(projectDir string) (*Settings, error) {
+	settings := &Settings{}
+
+	pyprojectPath := filepath.Join(projectDir, "pyproject.toml")
+	if data, err := os.ReadFile(pyprojectPath); err == nil {
+		var pyproject struct {
+			Tool struct {
+				Aigogo *Settings `toml:"aigogo"`
+			} `toml:"tool"`
+		}
+		meta, err := toml.Decode(string(data), &pyproject)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", pyprojectPath, err)
+		}
+		var unknown []string
+		for _, key := range meta.Undecoded() {
+			if len(key) > 2 && key[0] == "tool" && key[1] == "aigogo" {
+				unknown = append(unknown, key.String())
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return nil, fmt.Errorf("%s: unknown settings %s", pyprojectPath, strings.Join(unknown, ", "))
+		}
+		if pyproject.Tool.Aigogo != nil {
+			if err := pyproject.Tool.Aigogo.Validate(); err != nil {
+				return nil, fmt.Errorf("%s: [tool.aigogo] %w", pyprojectPath, err)
+			}
+			settings.Override(pyproject.Tool.Aigogo)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", pyprojectPath, err)
+	}
+
+	manifestPath := filepath.Join(projectDir, "aigogo.json")
+	if data, err := os.ReadFile(manifestPath); err == nil {
+		if data, _, err = StripJSONC(data); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", manifestPath, err)
+		}
+		var project Settings
+		if err := json.Unmarshal(data, &project); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", manifestPath, err)
+		}
+		if err := project.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", manifestPath, err)
+		}
+		settings.Override(&project)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", manifestPath, err)
+	}
+
+	if settings.Store != "" {
+		store, err := ResolvePath(settings.Store, projectDir)
+		if err != nil {
+			return nil, err
+		}
+		settings.Store = store
+	}
+	return settings, nil
+}
+
+// Override sets each setting that other sets
+func (s *Settings) Override(other *Settings) {
+	if other.Namespace != nil {
+		if s.Namespace == nil {
+			s.Namespace = &NamespaceSpec{}
+		}
+		if other.Namespace.Python != "" {
+			s.Namespace.Python = other.Namespace.Python
+		}
+		if other.Namespace.JavaScript != "" {
+			s.Namespace.JavaScript = other.Namespace.JavaScript
+		}
+	}
+	if other.Store != "" {
+		s.Store = other.Store
+	}
+	if other.Install != nil {
+		if s.Install == nil {
+			s.Install = &InstallSpec{}
+		}
+		if other.Install.Mode != "" {
+			s.Install.Mode = other.Install.Mode
+		}
+		if other.Install.PythonLayout != "" {
+			s.Install.PythonLayout = other.Install.PythonLayout
+		}
+	}
+	if other.Registry != "" {
+		s.Registry = other.Registry
+	}
+	if other.TagPolicy != nil {
+		if s.TagPolicy == nil {
+			s.TagPolicy = &TagPolicySpec{}
+		}
+		if other.TagPolicy.Mutable != "" {
+			s.TagPolicy.Mutable = other.TagPolicy.Mutable
+		}
+		if other.TagPolicy.Overwrite != "" {
+			s.TagPolicy.Overwrite = other.TagPolicy.Overwrite
+		}
+	}
+}
+
+// Validate checks that the namespace is an importable Python package name
+// and an npm scope, and the install mode, Python layout, registry and tag
+// policies are usable
+func (s *Settings) Validate() error {
+	if ns := s.Namespace; ns != nil {
+		if ns.Python != "" && !pythonNamespacePattern.MatchString(ns.Python) {
+			return fmt.Errorf("invalid namespace.python: %s (expected a dotted Python package name, e.g. ourco.snippets)", ns.Python)
+		}
+		if ns.JavaScript != "" && !npmScopePattern.MatchString(ns.JavaScript) {
+			return fmt.Errorf("invalid namespace.javascript: %s (expected an npm scope, e.g. @ourco)", ns.JavaScript)
+		}
+	}
+	if s.Install != nil {
+		switch s.Install.Mode {
+		case "", InstallModeLink, InstallModeCopy:
+		default:
+			return fmt.Errorf("invalid install.mode: %s (expected %s or %s)", s.Install.Mode, InstallModeLink, InstallModeCopy)
+		}
+		switch s.Install.PythonLayout {
+		case "", PythonLayoutImports, PythonLayoutPyPackages:
+		default:
+			return fmt.Errorf("invalid install.python_layout: %s (expected %s or %s)", s.Install.PythonLayout, PythonLayoutImports, PythonLayoutPyPackages)
+		}
+	}
+	if s.Registry != "" && (strings.Contains(s.Registry, "://") || strings.ContainsAny(s.Registry, " \t") || strings.HasSuffix(s.Registry, "/")) {
+		return fmt.Errorf("invalid registry: %q (expected a registry host or host/namespace, e.g. ghcr.io/ourco)", s.Registry)
+	}
+	if p := s.TagPolicy; p != nil {
+		for _, setting := range [][2]string{{"tag_policy.mutable", p.Mutable}, {"tag_policy.overwrite", p.Overwrite}} {
+			switch setting[1] {
+			case "", TagPolicyAllow, TagPolicyWarn, TagPolicyBlock:
+			default:
+				return fmt.Errorf("invalid %s: %s (expected %s, %s or %s)", setting[0], setting[1], TagPolicyAllow, TagPolicyWarn, TagPolicyBlock)
+			}
+		}
+	}
+	return nil
+}
+
+// ResolvePath makes a configured directory, such as the store, absolute: ~
+// is the home directory and other relative paths are relative to dir
+func ResolvePath(path, dir string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+	}
+	if !filepath.IsAbs(path) {
+		return filepath.Join(dir, path), nil
+	}
+	return filepath.Clean(path), nil
+}
@@ -0,0 +1,170 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeProjectFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadSettings(t *testing.T) {
+	dir := writeProjectFiles(t, map[string]string{
+		"pyproject.toml": `[project]
+name = "app"
+
+[tool.aigogo]
+store = ".aigogo-store"
+registry = "ghcr.io/ourco"
+
+[tool.aigogo.namespace]
+python = "ourco.snippets"
+javascript = "@ourco"
+
+[tool.aigogo.install]
+mode = "copy"
+
+[tool.aigogo.tag_policy]
+mutable = "block"
+overwrite = "warn"
+`,
+		// A consuming project's aigogo.json may hold only settings
+		"aigogo.json": `{
+  // aigogo.json wins over pyproject.toml
+  "namespace": {"javascript": "@shared"},
+  "registry": "registry.example.com/team",
+  "install": {"python_layout": "pypackages"},
+  "tag_policy": {"overwrite": "block"},
+}`,
+	})
+
+	settings, err := LoadSettings(dir)
+	if err != nil {
+		t.Fatalf("LoadSettings() error = %v", err)
+	}
+	want := &Settings{
+		Namespace: &NamespaceSpec{Python: "ourco.snippets", JavaScript: "@shared"},
+		Store:     filepath.Join(dir, ".aigogo-store"),
+		Install:   &InstallSpec{Mode: InstallModeCopy, PythonLayout: PythonLayoutPyPackages},
+		Registry:  "registry.example.com/team",
+		TagPolicy: &TagPolicySpec{Mutable: TagPolicyBlock, Overwrite: TagPolicyBlock},
+	}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("LoadSettings() = %+v, want %+v", settings, want)
+	}
+}
+
+func TestLoadSettingsDefaults(t *testing.T) {
+	settings, err := LoadSettings(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadSettings() error = %v", err)
+	}
+	if !reflect.DeepEqual(settings, &Settings{}) {
+		t.Errorf("LoadSettings() = %+v, want no settings", settings)
+	}
+	if settings.InstallMode() != InstallModeLink {
+		t.Errorf("InstallMode() = %s, want %s", settings.InstallMode(), InstallModeLink)
+	}
+	if settings.PythonLayout() != PythonLayoutImports {
+		t.Errorf("PythonLayout() = %s, want %s", settings.PythonLayout(), PythonLayoutImports)
+	}
+	if settings.MutableTagPolicy() != TagPolicyAllow || settings.OverwriteTagPolicy() != TagPolicyAllow {
+		t.Errorf("tag policies = %s, %s, want %s", settings.MutableTagPolicy(), settings.OverwriteTagPolicy(), TagPolicyAllow)
+	}
+
+	// A pyproject.toml without [tool.aigogo] sets nothing either
+	dir := writeProjectFiles(t, map[string]string{"pyproject.toml": "[project]\nname = \"app\"\n\n[tool.ruff]\nline-length = 100\n"})
+	if settings, err := LoadSettings(dir); err != nil || !reflect.DeepEqual(settings, &Settings{}) {
+		t.Errorf("LoadSettings() = %+v, %v, want no settings", settings, err)
+	}
+}
+
+func TestLoadSettingsStorePath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	dir := writeProjectFiles(t, map[string]string{"aigogo.json": `{"store": "~/cache/aigogo"}`})
+	settings, err := LoadSettings(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, "cache", "aigogo"); settings.Store != want {
+		t.Errorf("Store = %s, want %s", settings.Store, want)
+	}
+}
+
+func TestLoadSettingsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			"unknown pyproject key",
+			map[string]string{"pyproject.toml": "[tool.aigogo]\ninstall_mode = \"copy\"\n"},
+			"unknown settings tool.aigogo.install_mode",
+		},
+		{
+			"bad install mode",
+			map[string]string{"pyproject.toml": "[tool.aigogo.install]\nmode = \"hardlink\"\n"},
+			"invalid install.mode: hardlink",
+		},
+		{
+			"bad python layout",
+			map[string]string{"aigogo.json": `{"install": {"python_layout": "venv"}}`},
+			"invalid install.python_layout: venv",
+		},
+		{
+			"bad scope",
+			map[string]string{"aigogo.json": `{"namespace": {"javascript": "ourco"}}`},
+			"invalid namespace.javascript: ourco",
+		},
+		{
+			"registry with scheme",
+			map[string]string{"aigogo.json": `{"registry": "https://ghcr.io/ourco"}`},
+			"invalid registry",
+		},
+		{
+			"bad tag policy",
+			map[string]string{"aigogo.json": `{"tag_policy": {"mutable": "deny"}}`},
+			"invalid tag_policy.mutable: deny",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadSettings(writeProjectFiles(t, tt.files))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadSettings() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsVersionTag(t *testing.T) {
+	for tag, want := range map[string]bool{
+		"1.0.0":       true,
+		"v1.2.3":      true,
+		"1.0.0-rc.1":  true,
+		"latest":      false,
+		"main":        false,
+		"1.0":         false,
+		"sha-abc1234": false,
+		"2024-01-01":  false,
+	} {
+		if got := IsVersionTag(tag); got != want {
+			t.Errorf("IsVersionTag(%q) = %v, want %v", tag, got, want)
+		}
+	}
+}
@@ -2,51 +2,254 @@ package manifest
 
 // Manifest represents the aigogo.json configuration (v2)
 type Manifest struct {
-	Schema       string            `json:"$schema,omitempty"`
-	Name         string            `json:"name"`
-	Version      string            `json:"version"`
-	Description  string            `json:"description,omitempty"`
-	Author       string            `json:"author,omitempty"`
-	Language     Language          `json:"language"`
-	Dependencies *Dependencies     `json:"dependencies,omitempty"`
-	Files        FileSpec          `json:"files"`
-	Scripts      map[string]string `json:"scripts,omitempty"`
-	Metadata     Metadata          `json:"metadata,omitempty"`
-	AI           *AISpec           `json:"ai,omitempty"`
+	Schema        string            `json:"$schema,omitempty"`
+	AigogoVersion string            `json:"aigogoVersion,omitempty"` // aigogo versions that can use the manifest, e.g. >=1.4.0
+	Extends       string            `json:"extends,omitempty"`       // Base manifest: a .json path relative to this file, or a registry reference
+	Name          string            `json:"name"`
+	Version       string            `json:"version"`
+	Description   string            `json:"description,omitempty"`
+	Author        string            `json:"author,omitempty"`
+	Readme        string            `json:"readme,omitempty"` // Path of the package's README, always bundled
+	Deprecated    *Deprecation      `json:"deprecated,omitempty"`
+	Private       bool              `json:"private,omitempty"` // aigg push refuses to publish it without --allow-private
+	Publish       *PublishSpec      `json:"publish,omitempty"`
+	Language      Language          `json:"language"`
+	Languages     []Language        `json:"languages,omitempty"` // Additional languages of a multi-language package
+	Dependencies  *Dependencies     `json:"dependencies,omitempty"`
+	Environment   *EnvironmentSpec  `json:"environment,omitempty"`
+	Files         FileSpec          `json:"files"`
+	Scripts       map[string]string `json:"scripts,omitempty"`
+	Exports       map[string]string `json:"exports,omitempty"` // Import names ("." or a name) mapped to packaged files
+	Metadata      Metadata          `json:"metadata,omitempty"`
+	AI            *AISpec           `json:"ai,omitempty"`
+	Generator     *GeneratorSpec    `json:"generator,omitempty"`
+	Lint          *LintSpec         `json:"lint,omitempty"`
+	Namespace     *NamespaceSpec    `json:"namespace,omitempty"`  // Project setting: where aigg install puts dependencies
+	Store         string            `json:"store,omitempty"`      // Project setting: package store directory
+	Install       *InstallSpec      `json:"install,omitempty"`    // Project setting: how aigg install links packages
+	Registry      string            `json:"registry,omitempty"`   // Project setting: registry for references without one
+	TagPolicy     *TagPolicySpec    `json:"tag_policy,omitempty"` // Project setting: what push and add do with mutable and existing tags
+
+	inherited map[string]interface{} // fields merged in from Extends, for Save
+	commented bool                   // the file had comments or trailing commas
+}
+
+// Lifecycle events. A "scripts" entry named after one is a shell command
+// that aigg build or aigg install runs, not an entrypoint for aigg exec.
+const (
+	ScriptPrebuild    = "prebuild"    // before validation and packaging
+	ScriptPostbuild   = "postbuild"   // after the package is built
+	ScriptPostinstall = "postinstall" // after the package is installed into a project
+)
+
+// IsLifecycleScript reports whether a scripts entry names a lifecycle event
+func IsLifecycleScript(name string) bool {
+	switch name {
+	case ScriptPrebuild, ScriptPostbuild, ScriptPostinstall:
+		return true
+	}
+	return false
+}
+
+// EntryScripts returns the scripts aigg exec can run, leaving out lifecycle
+// commands
+func (m *Manifest) EntryScripts() map[string]string {
+	scripts := make(map[string]string, len(m.Scripts))
+	for name, file := range m.Scripts {
+		if !IsLifecycleScript(name) {
+			scripts[name] = file
+		}
+	}
+	return scripts
+}
+
+// Python dependency styles for generated pyproject.toml and show-deps output
+const (
+	// PythonStyleOptionalDependencies puts dependencies in
+	// [project.optional-dependencies] (PEP 621 extras); the default
+	PythonStyleOptionalDependencies = "optional-dependencies"
+	// PythonStyleDependencyGroups puts dependencies in [dependency-groups]
+	// (PEP 735), which uv and pip 25.1+ understand
+	PythonStyleDependencyGroups = "dependency-groups"
+)
+
+// JavaScript package managers that generated files can target
+const (
+	// JSPackageManagerNpm writes package.json with versions as declared; the
+	// default, also suitable for Yarn classic
+	JSPackageManagerNpm = "npm"
+	// JSPackageManagerPnpm writes package.json with versions normalized to
+	// node-semver ranges, which pnpm requires
+	JSPackageManagerPnpm = "pnpm"
+	// JSPackageManagerYarnBerry normalizes versions like pnpm and also writes
+	// a .yarnrc.yml for Yarn 2+
+	JSPackageManagerYarnBerry = "yarn-berry"
+)
+
+// GeneratorSpec customizes the dependency files aigogo generates
+type GeneratorSpec struct {
+	Python     string `json:"python,omitempty"`     // optional-dependencies (default) or dependency-groups
+	JavaScript string `json:"javascript,omitempty"` // npm (default), pnpm or yarn-berry
+}
+
+// Lint rule severities. The linter defines the rules; a manifest can
+// change their severity or turn them off.
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+	LintInfo    = "info"
+	LintOff     = "off"
+)
+
+// LintSpec configures aigg lint
+type LintSpec struct {
+	Rules map[string]string `json:"rules,omitempty"` // rule ID -> error, warning, info or off
+}
+
+// NamespaceSpec sets the import namespace aigg install uses for a project's
+// installed packages, in place of aigogo and @aigogo
+type NamespaceSpec struct {
+	Python     string `json:"python,omitempty" toml:"python,omitempty"`         // Dotted package name, e.g. ourco.snippets
+	JavaScript string `json:"javascript,omitempty" toml:"javascript,omitempty"` // npm scope, e.g. @ourco
+}
+
+// PythonStyle returns the configured Python dependency style, defaulting to
+// optional-dependencies
+func (m *Manifest) PythonStyle() string {
+	if m.Generator == nil || m.Generator.Python == "" {
+		return PythonStyleOptionalDependencies
+	}
+	return m.Generator.Python
+}
+
+// JSPackageManager returns the configured JavaScript package manager,
+// defaulting to npm
+func (m *Manifest) JSPackageManager() string {
+	if m.Generator == nil || m.Generator.JavaScript == "" {
+		return JSPackageManagerNpm
+	}
+	return m.Generator.JavaScript
 }
 
 // Language specifies the programming language and version requirements
 type Language struct {
-	Name    string `json:"name"`              // Required: python, javascript, go, rust
+	Name    string `json:"name"`              // Required: python, javascript, go, rust, ruby, java, csharp, php
 	Runtime string `json:"runtime,omitempty"` // Optional: cpython, pypy, node, deno, bun
 	Version string `json:"version"`           // Required: >=3.8,<4.0
 }
 
+// EnvironmentSpec restricts where a package can be installed, for packages
+// with native code or platform-specific behaviour. aigg add and aigg install
+// refuse to install it elsewhere unless --force is given. Empty fields
+// don't restrict anything.
+type EnvironmentSpec struct {
+	OS                   []string `json:"os,omitempty"`                    // Go names: linux, darwin, windows, freebsd
+	Arch                 []string `json:"arch,omitempty"`                  // Go names: amd64, arm64, 386, arm
+	PythonImplementation []string `json:"python_implementation,omitempty"` // sys.implementation.name: cpython, pypy, graalpy
+	Node                 string   `json:"node,omitempty"`                  // Node.js engine range, e.g. >=18
+}
+
+// Deprecation marks a package as deprecated. aigg add and aigg install warn
+// about deprecated packages, or refuse them with --strict. Versions that
+// are already pushed are deprecated with aigg push --deprecate, which sets
+// the registry annotations instead.
+type Deprecation struct {
+	Message     string `json:"message"`
+	Replacement string `json:"replacement,omitempty"` // Package reference to use instead
+}
+
+// PublishSpec restricts where aigg push may publish the package
+type PublishSpec struct {
+	// BlockedRegistries are registry hosts, or host/namespace prefixes
+	// such as ghcr.io/public-org, that aigg push refuses to push to
+	BlockedRegistries []string `json:"blocked_registries,omitempty"`
+}
+
+// Values accepted in environment.os, environment.arch and
+// environment.python_implementation
+var (
+	EnvironmentOSes                  = []string{"linux", "darwin", "windows", "freebsd", "openbsd", "netbsd"}
+	EnvironmentArches                = []string{"amd64", "arm64", "386", "arm", "ppc64le", "s390x", "riscv64"}
+	EnvironmentPythonImplementations = []string{"cpython", "pypy", "graalpy", "ironpython", "jython"}
+)
+
 // Dependencies specifies runtime and development dependencies
 type Dependencies struct {
 	Runtime []Dependency `json:"runtime,omitempty"`
 	Dev     []Dependency `json:"dev,omitempty"`
+	Peer    []Dependency `json:"peer,omitempty"` // Expected from the host project; checked, never installed
+	// Extras are named groups of optional runtime dependencies that
+	// consumers select with aigg add name:tag[extra]
+	Extras map[string][]Dependency `json:"extras,omitempty"`
 }
 
 // Dependency represents a single package dependency
 type Dependency struct {
-	Package  string `json:"package"`
-	Version  string `json:"version"`
-	Optional bool   `json:"optional,omitempty"`
+	Package  string   `json:"package"`
+	Version  string   `json:"version"`
+	Optional bool     `json:"optional,omitempty"`
+	Extras   []string `json:"extras,omitempty"`   // Python only: requests[socks]
+	Marker   string   `json:"marker,omitempty"`   // Python only: PEP 508 environment marker, e.g. python_version < "3.11"
+	Language string   `json:"language,omitempty"` // Multi-language packages: which language this is for; defaults to language.name
 }
 
 // FileSpec defines which files to include/exclude
 type FileSpec struct {
-	Include interface{} `json:"include,omitempty"` // string "auto" or []string patterns
-	Exclude []string    `json:"exclude,omitempty"`
+	Include    interface{}      `json:"include,omitempty"` // string "auto" or []string patterns
+	Exclude    []string         `json:"exclude,omitempty"`
+	Attributes []FileAttributes `json:"attributes,omitempty"`
+}
+
+// FileAttributes annotates the included files matching Path
+type FileAttributes struct {
+	Path       string `json:"path"`                 // file or glob pattern, like files.include
+	Executable bool   `json:"executable,omitempty"` // packaged and installed with the executable bit set
+	Template   bool   `json:"template,omitempty"`   // a .template file aigg install --render-templates renders into the project
+	Data       bool   `json:"data,omitempty"`       // shipped as-is; never scanned for imports
+}
+
+// TemplateSuffix ends the name of every template file. The rendered copy
+// is written without it.
+const TemplateSuffix = ".template"
+
+// AttributesOf returns the attributes of file, a path relative to the
+// package root, combined across every entry that matches it
+func (f *FileSpec) AttributesOf(file string) FileAttributes {
+	attrs := FileAttributes{Path: file}
+	for _, a := range f.Attributes {
+		if a.Path != file && !matchIncludePattern(file, a.Path) {
+			continue
+		}
+		attrs.Executable = attrs.Executable || a.Executable
+		attrs.Template = attrs.Template || a.Template
+		attrs.Data = attrs.Data || a.Data
+	}
+	return attrs
+}
+
+// WithoutData returns files minus those marked as data, which are shipped
+// but not scanned for imports
+func (f *FileSpec) WithoutData(files []string) []string {
+	if len(f.Attributes) == 0 {
+		return files
+	}
+	var code []string
+	for _, file := range files {
+		if !f.AttributesOf(file).Data {
+			code = append(code, file)
+		}
+	}
+	return code
 }
 
 // Metadata holds optional package metadata
 type Metadata struct {
-	License  string            `json:"license,omitempty"`
-	Homepage string            `json:"homepage,omitempty"`
-	Tags     []string          `json:"tags,omitempty"`
-	Extra    map[string]string `json:"extra,omitempty"`
+	License    string            `json:"license,omitempty"`    // SPDX license expression: MIT, Apache-2.0 OR MIT
+	Repository string            `json:"repository,omitempty"` // Source repository URL
+	Homepage   string            `json:"homepage,omitempty"`
+	Keywords   []string          `json:"keywords,omitempty"` // Search terms
+	Tags       []string          `json:"tags,omitempty"`
+	Extra      map[string]string `json:"extra,omitempty"`
 }
 
 // AISpec provides metadata for AI agent discovery and usage
@@ -86,7 +289,7 @@ func (f *FileSpec) GetIncludePatterns() ([]string, bool) {
 
 // SupportedLanguages returns list of supported language names
 func SupportedLanguages() []string {
-	return []string{"python", "javascript", "go", "rust"}
+	return []string{"python", "javascript", "go", "rust", "ruby", "java", "csharp", "php"}
 }
 
 // ValidateLanguage checks if language name is supported
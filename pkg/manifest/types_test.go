@@ -0,0 +1,47 @@
+package manifest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAttributesOf(t *testing.T) {
+	spec := &FileSpec{Attributes: []FileAttributes{
+		{Path: "bin/cli.py", Executable: true},
+		{Path: "bin/*.sh", Executable: true},
+		{Path: "**/*.template", Template: true},
+		{Path: "fixtures/*", Data: true},
+		{Path: "fixtures/run.sh.template", Executable: true},
+	}}
+
+	tests := []struct {
+		file string
+		want FileAttributes
+	}{
+		{"bin/cli.py", FileAttributes{Path: "bin/cli.py", Executable: true}},
+		{"bin/setup.sh", FileAttributes{Path: "bin/setup.sh", Executable: true}},
+		{"config.yaml.template", FileAttributes{Path: "config.yaml.template", Template: true}},
+		{"fixtures/run.sh.template", FileAttributes{Path: "fixtures/run.sh.template", Executable: true, Template: true, Data: true}},
+		{"fixtures/sample.py", FileAttributes{Path: "fixtures/sample.py", Data: true}},
+		{"utils.py", FileAttributes{Path: "utils.py"}},
+	}
+	for _, tt := range tests {
+		if got := spec.AttributesOf(tt.file); got != tt.want {
+			t.Errorf("AttributesOf(%q) = %+v, want %+v", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestWithoutData(t *testing.T) {
+	files := []string{"utils.py", "fixtures/sample.py", "fixtures/data.json"}
+
+	spec := &FileSpec{}
+	if got := spec.WithoutData(files); !reflect.DeepEqual(got, files) {
+		t.Errorf("WithoutData() without attributes = %v, want %v", got, files)
+	}
+
+	spec.Attributes = []FileAttributes{{Path: "fixtures/*", Data: true}}
+	if got, want := spec.WithoutData(files), []string{"utils.py"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("WithoutData() = %v, want %v", got, want)
+	}
+}
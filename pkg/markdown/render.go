@@ -0,0 +1,344 @@
+// Package markdown renders Markdown as text for a terminal, so package
+// READMEs can be read with aigg info --readme.
+package markdown
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Options controls how Render lays out and styles its output
+type Options struct {
+	Width  int  // wrap paragraphs at this many columns; 0 means 80
+	Styled bool // use ANSI bold, italics, underline and colour
+}
+
+const defaultWidth = 80
+
+// ANSI escape sequences. Each style has its own "off" code, so styles can
+// nest without a reset ending the outer one.
+const (
+	boldOn       = "\x1b[1m"
+	boldOff      = "\x1b[22m"
+	italicOn     = "\x1b[3m"
+	italicOff    = "\x1b[23m"
+	underlineOn  = "\x1b[4m"
+	underlineOff = "\x1b[24m"
+	codeOn       = "\x1b[36m"
+	codeOff      = "\x1b[39m"
+)
+
+// block kinds, used to decide where blank lines go
+const (
+	blockNone = iota
+	blockParagraph
+	blockHeading
+	blockList
+	blockQuote
+	blockCode
+	blockTable
+	blockRule
+)
+
+var (
+	headingPattern    = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	bulletPattern     = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedPattern    = regexp.MustCompile(`^(\s*)(\d{1,9})[.)]\s+(.*)$`)
+	rulePattern       = regexp.MustCompile(`^(\*\s*){3,}$|^(-\s*){3,}$|^(_\s*){3,}$`)
+	setextH1Pattern   = regexp.MustCompile(`^=+\s*$`)
+	setextH2Pattern   = regexp.MustCompile(`^-+\s*$`)
+	imagePattern      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	linkPattern       = regexp.MustCompile(`\[([^\]]+)\]\(\s*([^)\s]+)[^)]*\)`)
+	autolinkPattern   = regexp.MustCompile(`<((?:https?|ftp|mailto):[^>\s]+)>`)
+	htmlTagPattern    = regexp.MustCompile(`</?[a-zA-Z][a-zA-Z0-9-]*(\s[^<>]*)?/?>`)
+	htmlComment       = regexp.MustCompile(`<!--.*?-->`)
+	strongPattern     = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	emphasisPattern   = regexp.MustCompile(`\*([^*\s](?:[^*]*[^*\s])?)\*|(^|[^\w])_([^_\s](?:[^_]*[^_\s])?)_([^\w]|$)`)
+	escapePattern     = regexp.MustCompile(`\\([\\` + "`" + `*_{}\[\]()#+\-.!|<>])`)
+	ansiPattern       = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	escapePlaceholder = '\uE000' // private use; escaped characters are offset from it
+)
+
+// Render converts Markdown to text for a terminal: headings, lists, block
+// quotes, code blocks and rules are laid out, paragraphs are wrapped and
+// inline markup is turned into styles, or stripped when Styled is false.
+// Tables and anything it doesn't recognise are passed through.
+func Render(src string, opts Options) string {
+	if opts.Width <= 0 {
+		opts.Width = defaultWidth
+	}
+	r := &renderer{opts: opts}
+
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.ReplaceAll(lines[i], "\t", "    ")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence := trimmed[:3]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			r.codeBlock(code)
+
+		case trimmed == "":
+			r.flush()
+
+		case r.paraKind == blockParagraph && setextH1Pattern.MatchString(trimmed):
+			r.heading(1, r.takeParagraph())
+
+		case r.paraKind == blockParagraph && setextH2Pattern.MatchString(trimmed):
+			r.heading(2, r.takeParagraph())
+
+		case headingPattern.MatchString(trimmed):
+			m := headingPattern.FindStringSubmatch(trimmed)
+			r.heading(len(m[1]), m[2])
+
+		case rulePattern.MatchString(trimmed):
+			r.rule()
+
+		case strings.HasPrefix(trimmed, ">"):
+			r.startParagraph(blockQuote, "│ ", "│ ")
+			r.para = append(r.para, strings.TrimSpace(strings.TrimPrefix(trimmed, ">")))
+
+		case bulletPattern.MatchString(line):
+			m := bulletPattern.FindStringSubmatch(line)
+			indent := strings.Repeat("  ", len(m[1])/2+1)
+			r.startParagraph(blockList, indent+"• ", indent+"  ")
+			r.para = append(r.para, m[2])
+
+		case orderedPattern.MatchString(line):
+			m := orderedPattern.FindStringSubmatch(line)
+			indent := strings.Repeat("  ", len(m[1])/2+1)
+			marker := m[2] + ". "
+			r.startParagraph(blockList, indent+marker, indent+strings.Repeat(" ", len(marker)))
+			r.para = append(r.para, m[3])
+
+		case strings.HasPrefix(trimmed, "|"):
+			r.flush()
+			r.separate(blockTable)
+			r.writeLine(line)
+
+		case r.paraKind == blockNone && strings.HasPrefix(line, "    "):
+			var code []string
+			for ; i < len(lines) && (strings.HasPrefix(lines[i], "    ") || strings.HasPrefix(lines[i], "\t")); i++ {
+				code = append(code, strings.TrimPrefix(strings.TrimPrefix(lines[i], "\t"), "    "))
+			}
+			i--
+			r.codeBlock(code)
+
+		default:
+			// Plain text starts a paragraph or continues the current one,
+			// including a list item or quote
+			if r.paraKind == blockNone {
+				r.startParagraph(blockParagraph, "", "")
+			}
+			r.para = append(r.para, trimmed)
+		}
+	}
+	r.flush()
+
+	return strings.TrimRight(r.out.String(), "\n") + "\n"
+}
+
+type renderer struct {
+	opts Options
+	out  strings.Builder
+
+	last int // kind of the last block written
+
+	// The paragraph, list item or quote being collected
+	para                    []string
+	paraKind                int
+	firstPrefix, restPrefix string
+}
+
+// startParagraph ends the current paragraph and starts a new one
+func (r *renderer) startParagraph(kind int, firstPrefix, restPrefix string) {
+	r.flush()
+	r.paraKind = kind
+	r.firstPrefix, r.restPrefix = firstPrefix, restPrefix
+}
+
+// takeParagraph returns the text of the paragraph being collected and
+// discards it, for a setext heading underline
+func (r *renderer) takeParagraph() string {
+	text := strings.Join(r.para, " ")
+	r.para, r.paraKind = nil, blockNone
+	return text
+}
+
+// flush writes the paragraph being collected, wrapped to the width
+func (r *renderer) flush() {
+	if r.paraKind == blockNone {
+		return
+	}
+	kind := r.paraKind
+	text := r.inline(strings.Join(r.para, " "))
+	r.para, r.paraKind = nil, blockNone
+
+	r.separate(kind)
+	for _, l := range wrap(text, r.opts.Width, r.firstPrefix, r.restPrefix) {
+		r.writeLine(l)
+	}
+}
+
+// separate writes the blank line that goes between two blocks. Items of
+// one list, lines of one quote and rows of one table stay together.
+func (r *renderer) separate(kind int) {
+	if r.last != blockNone && !(r.last == kind && (kind == blockList || kind == blockQuote || kind == blockTable)) {
+		r.out.WriteString("\n")
+	}
+	r.last = kind
+}
+
+func (r *renderer) writeLine(line string) {
+	r.out.WriteString(strings.TrimRight(line, " "))
+	r.out.WriteString("\n")
+}
+
+func (r *renderer) heading(level int, text string) {
+	r.flush()
+	r.separate(blockHeading)
+	text = r.inline(text)
+	if !r.opts.Styled {
+		r.writeLine(text)
+		switch level {
+		case 1:
+			r.writeLine(strings.Repeat("=", visibleWidth(text)))
+		case 2:
+			r.writeLine(strings.Repeat("-", visibleWidth(text)))
+		}
+		return
+	}
+	if level == 1 {
+		r.writeLine(boldOn + underlineOn + text + underlineOff + boldOff)
+		return
+	}
+	r.writeLine(boldOn + text + boldOff)
+}
+
+func (r *renderer) rule() {
+	r.flush()
+	r.separate(blockRule)
+	r.writeLine(strings.Repeat("─", r.opts.Width))
+}
+
+// codeBlock writes code indented and unwrapped
+func (r *renderer) codeBlock(code []string) {
+	r.flush()
+	r.separate(blockCode)
+	for _, l := range code {
+		l = strings.ReplaceAll(l, "\t", "    ")
+		if r.opts.Styled && strings.TrimSpace(l) != "" {
+			l = codeOn + l + codeOff
+		}
+		r.writeLine("    " + l)
+	}
+}
+
+// inline renders inline markup: code spans, links, images, emphasis,
+// HTML tags and backslash escapes
+func (r *renderer) inline(text string) string {
+	text = escapePattern.ReplaceAllStringFunc(text, func(s string) string {
+		c, _ := utf8.DecodeRuneInString(s[1:])
+		return string(escapePlaceholder + c)
+	})
+
+	// Code spans are literal, so the rest of the markup is only rendered
+	// between them
+	parts := strings.Split(text, "`")
+	var b strings.Builder
+	for i, part := range parts {
+		switch {
+		case i%2 == 0:
+			b.WriteString(r.markup(part))
+		case i == len(parts)-1:
+			// An unmatched backtick
+			b.WriteString("`" + r.markup(part))
+		case r.opts.Styled:
+			b.WriteString(codeOn + part + codeOff)
+		default:
+			b.WriteString("`" + part + "`")
+		}
+	}
+
+	return strings.Map(func(c rune) rune {
+		if c >= escapePlaceholder && c < escapePlaceholder+128 {
+			return c - escapePlaceholder
+		}
+		return c
+	}, b.String())
+}
+
+// markup renders the inline markup outside code spans
+func (r *renderer) markup(text string) string {
+	text = htmlComment.ReplaceAllString(text, "")
+	text = imagePattern.ReplaceAllString(text, "[image: $1]")
+	text = autolinkPattern.ReplaceAllString(text, "$1")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = linkPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := linkPattern.FindStringSubmatch(s)
+		label, url := m[1], m[2]
+		if r.opts.Styled {
+			label = underlineOn + label + underlineOff
+		}
+		if m[1] == url || strings.HasPrefix(url, "#") {
+			return label
+		}
+		return label + " (" + url + ")"
+	})
+
+	text = strongPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := strongPattern.FindStringSubmatch(s)
+		inner := m[1] + m[2]
+		if r.opts.Styled {
+			return boldOn + inner + boldOff
+		}
+		return inner
+	})
+	text = emphasisPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := emphasisPattern.FindStringSubmatch(s)
+		before, inner, after := "", m[1], ""
+		if inner == "" {
+			before, inner, after = m[2], m[3], m[4]
+		}
+		if r.opts.Styled {
+			return before + italicOn + inner + italicOff + after
+		}
+		return before + inner + after
+	})
+	return text
+}
+
+// wrap splits text into lines no wider than width, counting the prefixes
+// and ignoring ANSI escapes. Words longer than a line are not broken.
+func wrap(text string, width int, firstPrefix, restPrefix string) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{firstPrefix}
+	}
+
+	var lines []string
+	line, prefix := "", firstPrefix
+	for _, word := range words {
+		if line != "" && visibleWidth(prefix+line+" "+word) > width {
+			lines = append(lines, prefix+line)
+			line, prefix = "", restPrefix
+		}
+		if line == "" {
+			line = word
+		} else {
+			line += " " + word
+		}
+	}
+	return append(lines, prefix+line)
+}
+
+// visibleWidth is the number of characters s takes up on a terminal
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(ansiPattern.ReplaceAllString(s, ""))
+}
@@ -0,0 +1,115 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderPlain(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		width int
+		want  string
+	}{
+		{
+			name: "headings",
+			src:  "# Title\n\n## Usage\n\n### Details\ntext",
+			want: "Title\n=====\n\nUsage\n-----\n\nDetails\n\ntext\n",
+		},
+		{
+			name: "setext headings",
+			src:  "Title\n=====\n\nUsage\n---\n",
+			want: "Title\n=====\n\nUsage\n-----\n",
+		},
+		{
+			name: "inline markup stripped",
+			src:  "Some **bold**, *italic*, __strong__ and _em_ text with `co*de*` and snake_case_name",
+			want: "Some bold, italic, strong and em text with `co*de*` and snake_case_name\n",
+		},
+		{
+			name:  "links and images",
+			src:   "See [the docs](https://example.com/docs), [https://x.io](https://x.io), [below](#usage) and ![logo](logo.png) <https://auto.link>",
+			width: 200,
+			want:  "See the docs (https://example.com/docs), https://x.io, below and [image: logo] https://auto.link\n",
+		},
+		{
+			name: "escapes and html",
+			src:  "\\*not emphasis\\* <br/> <!-- hidden -->shown",
+			want: "*not emphasis* shown\n",
+		},
+		{
+			name: "lists",
+			src:  "- one\n  continued\n* two\n  - nested\n\n1. first\n2) second",
+			want: "  • one continued\n  • two\n    • nested\n  1. first\n  2. second\n",
+		},
+		{
+			name: "block quote",
+			src:  "> quoted\n> lines",
+			want: "│ quoted\n│ lines\n",
+		},
+		{
+			name: "fenced code kept verbatim",
+			src:  "Run:\n\n```bash\naigg  install   # **not bold**\n```\nafter",
+			want: "Run:\n\n    aigg  install   # **not bold**\n\nafter\n",
+		},
+		{
+			name: "indented code",
+			src:  "Text\n\n    x = 1\n    y = 2\n\nMore",
+			want: "Text\n\n    x = 1\n    y = 2\n\nMore\n",
+		},
+		{
+			name: "table passed through",
+			src:  "| a | b |\n|---|---|\n| **1** | 2 |",
+			want: "| a | b |\n|---|---|\n| **1** | 2 |\n",
+		},
+		{
+			name:  "rule",
+			src:   "above\n\n***\n\nbelow",
+			width: 20,
+			want:  "above\n\n" + strings.Repeat("─", 20) + "\n\nbelow\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Render(tt.src, Options{Width: tt.width})
+			if got != tt.want {
+				t.Errorf("Render() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderWraps(t *testing.T) {
+	src := "The quick brown fox jumps over the lazy dog and keeps running\n\n- a list item that is long enough to wrap onto a second line"
+	got := Render(src, Options{Width: 24})
+	want := "The quick brown fox\njumps over the lazy dog\nand keeps running\n\n" +
+		"  • a list item that is\n    long enough to wrap\n    onto a second line\n"
+	if got != want {
+		t.Errorf("Render() =\n%s\nwant\n%s", got, want)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(got, "\n"), "\n") {
+		if visibleWidth(line) > 24 {
+			t.Errorf("line %q is wider than 24 columns", line)
+		}
+	}
+}
+
+func TestRenderStyled(t *testing.T) {
+	got := Render("# Title\n\n**bold** *it* `code` [link](https://x.io)", Options{Styled: true})
+	want := boldOn + underlineOn + "Title" + underlineOff + boldOff + "\n\n" +
+		boldOn + "bold" + boldOff + " " + italicOn + "it" + italicOff + " " +
+		codeOn + "code" + codeOff + " " + underlineOn + "link" + underlineOff + " (https://x.io)\n"
+	if got != want {
+		t.Errorf("Render() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestWrapIgnoresANSI(t *testing.T) {
+	text := boldOn + "aaaa" + boldOff + " bbbb"
+	lines := wrap(text, 9, "", "")
+	if len(lines) != 1 {
+		t.Errorf("wrap() = %q, want one line: escapes take no columns", lines)
+	}
+}
@@ -0,0 +1,284 @@
+// Package mcp is a Model Context Protocol server for tools: it answers
+// JSON-RPC 2.0 messages, one per line, as MCP clients such as coding
+// agents send them over a process's standard input and output
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+)
+
+// ProtocolVersion is the MCP version the server speaks unless the client
+// asks for another it supports
+const ProtocolVersion = "2025-06-18"
+
+// supportedVersions are the MCP versions the server can answer a client in
+var supportedVersions = []string{"2024-11-05", "2025-03-26", ProtocolVersion}
+
+// maxMessageSize is the longest message the server reads
+const maxMessageSize = 16 << 20
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Tool is a tool the server offers, and the function that runs it
+type Tool struct {
+	Name        string                 `json:"name"`
+	Title       string                 `json:"title,omitempty"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+	Annotations *Annotations           `json:"annotations,omitempty"`
+
+	// Run runs the tool with the arguments the client called it with. An
+	// error is reported to the client as the tool's failed result.
+	Run func(ctx context.Context, args json.RawMessage) (*Result, error) `json:"-"`
+}
+
+// Annotations tell clients how a tool behaves, so they can decide which
+// calls to confirm with the user
+type Annotations struct {
+	ReadOnly    bool `json:"readOnlyHint"`
+	Destructive bool `json:"destructiveHint"`
+	Idempotent  bool `json:"idempotentHint"`
+	OpenWorld   bool `json:"openWorldHint"` // it reaches outside the machine, e.g. a registry
+}
+
+// Content is a block of a tool's result
+type Content struct {
+	Type string `json:"type"` // "text"
+	Text string `json:"text"`
+}
+
+// Result is what a tool call returns
+type Result struct {
+	Content    []Content   `json:"content"`
+	Structured interface{} `json:"structuredContent,omitempty"`
+	IsError    bool        `json:"isError,omitempty"`
+}
+
+// TextResult returns a result of text
+func TextResult(text string) *Result {
+	return &Result{Content: []Content{{Type: "text", Text: text}}}
+}
+
+// JSONResult returns v as a structured result, and as its indented JSON
+// for clients that only read text
+func JSONResult(v interface{}) (*Result, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Content: []Content{{Type: "text", Text: string(data)}}, Structured: v}, nil
+}
+
+// Server answers MCP clients with its tools
+type Server struct {
+	name, version string
+	instructions  string
+	tools         []Tool
+}
+
+// NewServer returns a server that introduces itself to clients as name at
+// version
+func NewServer(name, version string) *Server {
+	return &Server{name: name, version: version}
+}
+
+// SetInstructions sets what clients are told about using the server's
+// tools, such as a hint for the model
+func (s *Server) SetInstructions(instructions string) {
+	s.instructions = instructions
+}
+
+// AddTool offers a tool
+func (s *Server) AddTool(tool Tool) {
+	s.tools = append(s.tools, tool)
+}
+
+// request is a JSON-RPC request, or a notification when it has no id
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve answers the messages read from in on out, one at a time, until
+// in ends or ctx is canceled. Canceling ctx also cancels the tool call
+// running.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+		for scanner.Scan() {
+			line := slices.Clone(scanner.Bytes())
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	var mu sync.Mutex
+	write := func(resp response) error {
+		mu.Lock()
+		defer mu.Unlock()
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(append(data, '\n'))
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			if err != nil {
+				return fmt.Errorf("failed to read MCP message: %w", err)
+			}
+			return nil
+		case line := <-lines:
+			if len(line) == 0 {
+				continue
+			}
+			if resp, ok := s.handle(ctx, line); ok {
+				if err := write(resp); err != nil {
+					return fmt.Errorf("failed to write MCP response: %w", err)
+				}
+			}
+		}
+	}
+}
+
+// handle answers one message, or returns false for a notification, which
+// gets no answer
+func (s *Server) handle(ctx context.Context, line []byte) (response, bool) {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return errorResponse(json.RawMessage("null"), codeParseError, "invalid JSON: "+err.Error()), true
+	}
+	if len(req.ID) == 0 {
+		return response{}, false
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, codeInvalidRequest, "not a JSON-RPC 2.0 request"), true
+	}
+
+	var result interface{}
+	var err error
+	switch req.Method {
+	case "initialize":
+		result, err = s.initialize(req.Params)
+	case "ping":
+		result = struct{}{}
+	case "tools/list":
+		result = map[string][]Tool{"tools": s.toolList()}
+	case "tools/call":
+		result, err = s.callTool(ctx, req.Params)
+	default:
+		return errorResponse(req.ID, codeMethodNotFound, "method not found: "+req.Method), true
+	}
+	if err != nil {
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			return errorResponse(req.ID, rpcErr.Code, rpcErr.Message), true
+		}
+		return errorResponse(req.ID, codeInvalidParams, err.Error()), true
+	}
+	return response{JSONRPC: "2.0", ID: req.ID, Result: result}, true
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+func errorResponse(id json.RawMessage, code int, message string) response {
+	return response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}}
+}
+
+// initialize answers the client's introduction with the protocol version
+// to speak, which is the client's when the server supports it
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+	}
+	version := ProtocolVersion
+	if slices.Contains(supportedVersions, p.ProtocolVersion) {
+		version = p.ProtocolVersion
+	}
+	result := map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities":    map[string]interface{}{"tools": map[string]bool{"listChanged": false}},
+		"serverInfo":      map[string]string{"name": s.name, "version": s.version},
+	}
+	if s.instructions != "" {
+		result["instructions"] = s.instructions
+	}
+	return result, nil
+}
+
+func (s *Server) toolList() []Tool {
+	if s.tools == nil {
+		return []Tool{}
+	}
+	return s.tools
+}
+
+// callTool runs the tool a tools/call request names. A tool that fails
+// returns an error result rather than a JSON-RPC error, so the model sees
+// why.
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (*Result, error) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(s.tools, func(t Tool) bool { return t.Name == p.Name })
+	if i < 0 {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "unknown tool: " + p.Name}
+	}
+	if len(p.Arguments) == 0 || string(p.Arguments) == "null" {
+		p.Arguments = json.RawMessage("{}")
+	}
+	result, err := s.tools[i].Run(ctx, p.Arguments)
+	if err != nil {
+		result = TextResult(err.Error())
+		result.IsError = true
+	}
+	return result, nil
+}
@@ -0,0 +1,151 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// serve sends the messages to a server and returns its responses by id
+func serve(t *testing.T, s *Server, messages ...string) map[string]map[string]json.RawMessage {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(strings.Join(messages, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	responses := map[string]map[string]json.RawMessage{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var resp map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("response %q: %v", line, err)
+		}
+		responses[string(resp["id"])] = resp
+	}
+	return responses
+}
+
+func testServer() *Server {
+	s := NewServer("test", "1.0.0")
+	s.SetInstructions("be nice")
+	s.AddTool(Tool{
+		Name:        "echo",
+		Description: "Echo the text",
+		InputSchema: map[string]interface{}{"type": "object"},
+		Annotations: &Annotations{ReadOnly: true},
+		Run: func(ctx context.Context, args json.RawMessage) (*Result, error) {
+			var in struct {
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal(args, &in); err != nil {
+				return nil, err
+			}
+			if in.Text == "" {
+				return nil, errors.New("text is required")
+			}
+			return JSONResult(in)
+		},
+	})
+	return s
+}
+
+func TestServe(t *testing.T) {
+	responses := serve(t, testServer(),
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"c","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		``,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":"three","method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"echo"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"nope","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":7,"method":"ping"}`,
+		`not json`,
+	)
+	if len(responses) != 8 {
+		t.Fatalf("got %d responses, want 8 (none for the notification): %v", len(responses), responses)
+	}
+
+	var init struct {
+		ProtocolVersion string            `json:"protocolVersion"`
+		ServerInfo      map[string]string `json:"serverInfo"`
+		Instructions    string            `json:"instructions"`
+	}
+	if err := json.Unmarshal(responses["1"]["result"], &init); err != nil {
+		t.Fatal(err)
+	}
+	if init.ProtocolVersion != "2024-11-05" || init.ServerInfo["name"] != "test" || init.Instructions != "be nice" {
+		t.Errorf("initialize = %+v, want the client's version", init)
+	}
+
+	var list struct {
+		Tools []struct {
+			Name        string                 `json:"name"`
+			Annotations map[string]interface{} `json:"annotations"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(responses["2"]["result"], &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Tools) != 1 || list.Tools[0].Name != "echo" || list.Tools[0].Annotations["readOnlyHint"] != true {
+		t.Errorf("tools/list = %+v", list)
+	}
+
+	var result Result
+	if err := json.Unmarshal(responses[`"three"`]["result"], &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError || len(result.Content) != 1 || !strings.Contains(result.Content[0].Text, `"text": "hi"`) {
+		t.Errorf("echo = %+v", result)
+	}
+
+	// A failing tool returns an error result; protocol mistakes are errors
+	if err := json.Unmarshal(responses["4"]["result"], &result); err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsError || result.Content[0].Text != "text is required" {
+		t.Errorf("echo without text = %+v, want an error result", result)
+	}
+	for id, code := range map[string]string{"5": "-32602", "6": "-32601", "null": "-32700"} {
+		var rpcErr struct {
+			Code json.Number `json:"code"`
+		}
+		if err := json.Unmarshal(responses[id]["error"], &rpcErr); err != nil || string(rpcErr.Code) != code {
+			t.Errorf("response %s = %s, want error %s", id, responses[id], code)
+		}
+	}
+	if string(responses["7"]["result"]) != "{}" {
+		t.Errorf("ping = %s", responses["7"])
+	}
+}
+
+func TestInitializeUnsupportedVersion(t *testing.T) {
+	responses := serve(t, NewServer("test", "1.0.0"),
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	var init struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if err := json.Unmarshal(responses["1"]["result"], &init); err != nil || init.ProtocolVersion != ProtocolVersion {
+		t.Errorf("initialize = %s, want %s", responses["1"], ProtocolVersion)
+	}
+	if string(responses["2"]["result"]) != `{"tools":[]}` {
+		t.Errorf("tools/list = %s, want no tools", responses["2"]["result"])
+	}
+}
+
+func TestServeCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	blocked, w := io.Pipe()
+	defer func() { _ = w.Close() }()
+	if err := NewServer("test", "1.0.0").Serve(ctx, blocked, &bytes.Buffer{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Serve() with a canceled context = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,306 @@
+package pyproject
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// condaToPyPI maps conda package names to PyPI names where they differ
+var condaToPyPI = map[string]string{
+	"pytorch":           "torch",
+	"py-opencv":         "opencv-python",
+	"matplotlib-base":   "matplotlib",
+	"msgpack-python":    "msgpack",
+	"pytables":          "tables",
+	"pyqt":              "PyQt5",
+	"python-graphviz":   "graphviz",
+	"py-xgboost":        "xgboost",
+	"tensorflow-base":   "tensorflow",
+	"pytorch-lightning": "lightning",
+	"jupyterlab_server": "jupyterlab-server",
+	"typing_extensions": "typing-extensions",
+}
+
+// pypiToConda is the reverse of condaToPyPI, used for export. Where several
+// conda names map to one PyPI name the canonical conda package is listed.
+var pypiToConda = map[string]string{
+	"torch":             "pytorch",
+	"opencv-python":     "py-opencv",
+	"msgpack":           "msgpack-python",
+	"tables":            "pytables",
+	"pyqt5":             "pyqt",
+	"graphviz":          "python-graphviz",
+	"xgboost":           "py-xgboost",
+	"lightning":         "pytorch-lightning",
+	"jupyterlab-server": "jupyterlab_server",
+	"typing-extensions": "typing_extensions",
+}
+
+// condaNonPython lists conda packages that are tooling or native libraries
+// rather than Python dependencies; they are skipped on import
+var condaNonPython = map[string]bool{
+	"pip":             true,
+	"setuptools":      true,
+	"wheel":           true,
+	"conda":           true,
+	"cudatoolkit":     true,
+	"cuda-toolkit":    true,
+	"cudnn":           true,
+	"mkl":             true,
+	"nodejs":          true,
+	"r-base":          true,
+	"openssl":         true,
+	"ca-certificates": true,
+	"libgcc-ng":       true,
+	"libstdcxx-ng":    true,
+	"_libgcc_mutex":   true,
+}
+
+// PyPIName returns the PyPI name for a conda package
+func PyPIName(conda string) string {
+	if name, ok := condaToPyPI[strings.ToLower(conda)]; ok {
+		return name
+	}
+	return conda
+}
+
+// CondaName returns the conda package name for a PyPI package
+func CondaName(pypi string) string {
+	if name, ok := pypiToConda[normalizeName(pypi)]; ok {
+		return name
+	}
+	return pypi
+}
+
+// CondaEnvironment holds the dependencies read from a conda environment.yml
+type CondaEnvironment struct {
+	Name          string
+	Dependencies  []manifest.Dependency
+	PythonVersion string
+	Warnings      []string
+}
+
+// FindCondaEnvironment searches dir for environment.yml or environment.yaml
+func FindCondaEnvironment(dir string) (string, error) {
+	for _, name := range []string{"environment.yml", "environment.yaml"} {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("environment.yml not found in %s", dir)
+}
+
+// ParseCondaEnvironment reads the dependencies of a conda environment.yml,
+// including the nested "pip:" list. Conda names are translated to PyPI
+// names and conda match specs ("numpy=1.24", "numpy 1.24.*") to PEP 440
+// specifiers. Only the subset of YAML used by environment files is
+// understood.
+func ParseCondaEnvironment(path string) (*CondaEnvironment, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	env := &CondaEnvironment{Dependencies: []manifest.Dependency{}}
+	seen := make(map[string]bool)
+	add := func(dep manifest.Dependency) {
+		key := normalizeName(dep.Package)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		env.Dependencies = append(env.Dependencies, dep)
+	}
+
+	section := ""
+	pipIndent := -1 // indentation of the "- pip:" item while inside its list
+	lineNum := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNum++
+		raw := stripComment(scanner.Text())
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		indent := len(raw) - len(strings.TrimLeft(raw, " \t"))
+
+		// Top-level keys
+		if indent == 0 && !strings.HasPrefix(line, "-") {
+			key, value, _ := strings.Cut(line, ":")
+			section = strings.TrimSpace(key)
+			pipIndent = -1
+			if section == "name" {
+				env.Name = unquote(strings.TrimSpace(value))
+			}
+			continue
+		}
+
+		if section != "dependencies" || !strings.HasPrefix(line, "-") {
+			continue
+		}
+		item := unquote(strings.TrimSpace(strings.TrimPrefix(line, "-")))
+
+		if pipIndent >= 0 && indent <= pipIndent {
+			pipIndent = -1
+		}
+
+		if pipIndent >= 0 {
+			// pip requirement strings use PyPI names and PEP 508 syntax
+			spec, _ := splitMarker(item)
+			if strings.Contains(spec, "://") || strings.Contains(spec, " @ ") || strings.HasPrefix(spec, "-") {
+				env.Warnings = append(env.Warnings, fmt.Sprintf("line %d: skipped pip entry %q", lineNum, item))
+				continue
+			}
+			if dep := parsePEP508Dependency(item); dep != nil {
+				add(*dep)
+			}
+			continue
+		}
+
+		if item == "pip:" {
+			pipIndent = indent
+			continue
+		}
+
+		name, version := parseCondaSpec(item)
+		switch {
+		case name == "python":
+			env.PythonVersion = condaPythonVersion(version)
+		case condaNonPython[strings.ToLower(name)]:
+			env.Warnings = append(env.Warnings, fmt.Sprintf("line %d: skipped non-Python package %q", lineNum, name))
+		default:
+			add(manifest.Dependency{Package: PyPIName(name), Version: version})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return env, nil
+}
+
+// parseCondaSpec splits a conda match spec into a name and a PEP 440
+// specifier. The channel prefix ("conda-forge::") and build string
+// ("=py310_0") are dropped.
+//
+//	numpy          -> numpy *
+//	numpy=1.24     -> numpy ==1.24.*
+//	numpy==1.24.3  -> numpy ==1.24.3
+//	numpy 1.24.*   -> numpy ==1.24.*
+//	numpy>=1.20,<2 -> numpy >=1.20,<2
+func parseCondaSpec(spec string) (string, string) {
+	if idx := strings.Index(spec, "::"); idx != -1 {
+		spec = spec[idx+2:]
+	}
+
+	idx := strings.IndexAny(spec, " =<>!~")
+	if idx == -1 {
+		return spec, "*"
+	}
+	name := spec[:idx]
+	rest := strings.TrimSpace(spec[idx:])
+
+	switch {
+	case rest == "":
+		return name, "*"
+	case strings.HasPrefix(rest, "=="):
+		version, _, _ := strings.Cut(rest[2:], "=")
+		return name, "==" + strings.TrimSpace(version)
+	case strings.HasPrefix(rest, "="):
+		version, _, _ := strings.Cut(rest[1:], "=")
+		return name, fuzzyVersion(strings.TrimSpace(version))
+	case strings.ContainsAny(rest[:1], "<>!~"):
+		version, _, _ := strings.Cut(rest, " ")
+		return name, version
+	default:
+		// "numpy 1.24.*" or "numpy 1.24.3 py310_0"
+		version, _, _ := strings.Cut(rest, " ")
+		return name, fuzzyVersion(version)
+	}
+}
+
+// fuzzyVersion converts a conda prefix match ("1.24" matches 1.24.x) to
+// a PEP 440 wildcard
+func fuzzyVersion(version string) string {
+	if strings.HasSuffix(version, "*") {
+		return "==" + version
+	}
+	return "==" + version + ".*"
+}
+
+// condaPythonVersion turns the python entry of an environment into a
+// language.version range: "=3.10" (==3.10.*) becomes ">=3.10,<3.11"
+func condaPythonVersion(version string) string {
+	if !strings.HasPrefix(version, "==") || !strings.HasSuffix(version, ".*") {
+		if version == "*" {
+			return ""
+		}
+		return version
+	}
+	v := strings.TrimSuffix(strings.TrimPrefix(version, "=="), ".*")
+	parts := strings.Split(v, ".")
+	if len(parts) != 2 {
+		return version
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return version
+	}
+	return fmt.Sprintf(">=%s,<%s.%d", v, parts[0], minor+1)
+}
+
+// CondaMatchSpec formats a dependency as a conda match spec, translating
+// the name, PEP 440 operators conda does not understand ("~=") and
+// wildcards ("==1.24.*" becomes conda's prefix match "=1.24")
+func CondaMatchSpec(dep manifest.Dependency) string {
+	name := CondaName(dep.Package)
+	version := strings.ReplaceAll(dep.Version, " ", "")
+	if version == "" || version == "*" {
+		return name
+	}
+
+	var parts []string
+	for _, clause := range strings.Split(version, ",") {
+		if strings.HasPrefix(clause, "~=") {
+			parts = append(parts, compatibleRelease(clause[2:])...)
+			continue
+		}
+		if strings.HasPrefix(clause, "==") && strings.HasSuffix(clause, ".*") {
+			parts = append(parts, "="+strings.TrimSuffix(clause[2:], ".*"))
+			continue
+		}
+		parts = append(parts, clause)
+	}
+	return name + strings.Join(parts, ",")
+}
+
+// compatibleRelease expands "~=1.4.2" to ">=1.4.2" and "<1.5"
+func compatibleRelease(version string) []string {
+	segments := strings.Split(version, ".")
+	if len(segments) < 2 {
+		return []string{">=" + version}
+	}
+	upper := segments[:len(segments)-1]
+	last, err := strconv.Atoi(upper[len(upper)-1])
+	if err != nil {
+		return []string{">=" + version}
+	}
+	bumped := append(append([]string{}, upper[:len(upper)-1]...), strconv.Itoa(last+1))
+	return []string{">=" + version, "<" + strings.Join(bumped, ".")}
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,124 @@
+package pyproject
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func TestParseCondaEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "environment.yml")
+	writeFile(t, path, `# ML environment
+name: "ml-agent"
+channels:
+  - conda-forge
+  - defaults
+dependencies:
+  - python=3.10
+  - conda-forge::numpy>=1.24,<2   # arrays
+  - pytorch=2.1=py3.10_cuda11.8_0
+  - pandas 2.1.*
+  - scikit-learn==1.3.2
+  - msgpack-python
+  - cudatoolkit=11.8
+  - pip
+  - pip:
+      - requests>=2.31; python_version >= "3.8"
+      - Torch>=2.0
+      - git+https://github.com/example/tool.git
+variables:
+  FOO: bar
+`)
+
+	env, err := ParseCondaEnvironment(path)
+	if err != nil {
+		t.Fatalf("ParseCondaEnvironment failed: %v", err)
+	}
+
+	if env.Name != "ml-agent" {
+		t.Errorf("Name = %q, want ml-agent", env.Name)
+	}
+	if env.PythonVersion != ">=3.10,<3.11" {
+		t.Errorf("PythonVersion = %q, want >=3.10,<3.11", env.PythonVersion)
+	}
+
+	want := []manifest.Dependency{
+		{Package: "numpy", Version: ">=1.24,<2"},
+		{Package: "torch", Version: "==2.1.*"},
+		{Package: "pandas", Version: "==2.1.*"},
+		{Package: "scikit-learn", Version: "==1.3.2"},
+		{Package: "msgpack", Version: "*"},
+		{Package: "requests", Version: ">=2.31", Marker: `python_version >= "3.8"`},
+	}
+	if len(env.Dependencies) != len(want) {
+		t.Fatalf("expected %d dependencies, got %+v", len(want), env.Dependencies)
+	}
+	for i, dep := range env.Dependencies {
+		if !reflect.DeepEqual(dep, want[i]) {
+			t.Errorf("dependency %d = %+v, want %+v", i, dep, want[i])
+		}
+	}
+
+	warnings := strings.Join(env.Warnings, "\n")
+	for _, s := range []string{`"cudatoolkit"`, `"pip"`, "git+https://github.com/example/tool.git"} {
+		if !strings.Contains(warnings, s) {
+			t.Errorf("expected warning mentioning %s, got:\n%s", s, warnings)
+		}
+	}
+}
+
+func TestParseCondaSpec(t *testing.T) {
+	tests := []struct {
+		spec, name, version string
+	}{
+		{"numpy", "numpy", "*"},
+		{"numpy=1.24", "numpy", "==1.24.*"},
+		{"numpy==1.24.3", "numpy", "==1.24.3"},
+		{"numpy 1.24.*", "numpy", "==1.24.*"},
+		{"numpy 1.24.3 py310_0", "numpy", "==1.24.3.*"},
+		{"numpy>=1.20,<2", "numpy", ">=1.20,<2"},
+		{"numpy >=1.20", "numpy", ">=1.20"},
+		{"conda-forge::numpy=1.24=py310h", "numpy", "==1.24.*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			name, version := parseCondaSpec(tt.spec)
+			if name != tt.name || version != tt.version {
+				t.Errorf("parseCondaSpec(%q) = %q, %q; want %q, %q", tt.spec, name, version, tt.name, tt.version)
+			}
+		})
+	}
+}
+
+func TestCondaMatchSpec(t *testing.T) {
+	tests := []struct {
+		dep  manifest.Dependency
+		want string
+	}{
+		{manifest.Dependency{Package: "torch", Version: ">=2.0,<3"}, "pytorch>=2.0,<3"},
+		{manifest.Dependency{Package: "numpy", Version: "*"}, "numpy"},
+		{manifest.Dependency{Package: "pandas", Version: "==2.1.*"}, "pandas=2.1"},
+		{manifest.Dependency{Package: "click", Version: "~=8.1.0"}, "click>=8.1.0,<8.2"},
+		{manifest.Dependency{Package: "opencv_python", Version: ">= 4.8"}, "py-opencv>=4.8"},
+	}
+	for _, tt := range tests {
+		if got := CondaMatchSpec(tt.dep); got != tt.want {
+			t.Errorf("CondaMatchSpec(%+v) = %q, want %q", tt.dep, got, tt.want)
+		}
+	}
+}
+
+func TestCondaNameRoundTrip(t *testing.T) {
+	for conda, pypi := range map[string]string{"pytorch": "torch", "py-opencv": "opencv-python", "numpy": "numpy"} {
+		if got := PyPIName(conda); got != pypi {
+			t.Errorf("PyPIName(%q) = %q, want %q", conda, got, pypi)
+		}
+		if got := CondaName(pypi); got != conda {
+			t.Errorf("CondaName(%q) = %q, want %q", pypi, got, conda)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package pyproject
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// lockFileNames are the Python lock files searched for, in order
+var lockFileNames = []string{"uv.lock", "poetry.lock"}
+
+// pythonLockFile is the subset of poetry.lock and uv.lock needed to read
+// resolved versions. Both formats list every resolved package as a
+// [[package]] table with a name and version.
+type pythonLockFile struct {
+	Package []struct {
+		Name    string `toml:"name"`
+		Version string `toml:"version"`
+	} `toml:"package"`
+}
+
+// FindLockFile searches dir for uv.lock or poetry.lock
+func FindLockFile(dir string) (string, error) {
+	for _, name := range lockFileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("uv.lock or poetry.lock not found in %s", dir)
+}
+
+// ParseLockVersions reads the resolved version of every package in a
+// poetry.lock or uv.lock, keyed by normalized package name
+func ParseLockVersions(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+
+	var lock pythonLockFile
+	if err := toml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+
+	versions := make(map[string]string, len(lock.Package))
+	for _, pkg := range lock.Package {
+		if pkg.Name != "" && pkg.Version != "" {
+			versions[normalizeName(pkg.Name)] = pkg.Version
+		}
+	}
+	return versions, nil
+}
+
+// PinToLock replaces the version of each dependency with the version
+// resolved in the lock file. constraint turns a resolved version into the
+// recorded constraint, e.g. "==1.2.3". Dependencies missing from the lock
+// file keep their declared version and are returned by name.
+func PinToLock(deps []manifest.Dependency, locked map[string]string, constraint func(version string) string) ([]manifest.Dependency, []string) {
+	pinned := make([]manifest.Dependency, 0, len(deps))
+	var missing []string
+	for _, dep := range deps {
+		if version, ok := locked[normalizeName(dep.Package)]; ok {
+			dep.Version = constraint(version)
+		} else {
+			missing = append(missing, dep.Package)
+		}
+		pinned = append(pinned, dep)
+	}
+	return pinned, missing
+}
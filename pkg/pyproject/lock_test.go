@@ -0,0 +1,100 @@
+package pyproject
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func TestParseLockVersionsUV(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "uv.lock")
+	writeFile(t, path, `version = 1
+requires-python = ">=3.10"
+
+[[package]]
+name = "agent"
+version = "0.1.0"
+source = { editable = "." }
+dependencies = [{ name = "requests" }]
+
+[[package]]
+name = "requests"
+version = "2.32.3"
+source = { registry = "https://pypi.org/simple" }
+
+[[package]]
+name = "typing-extensions"
+version = "4.12.2"
+source = { registry = "https://pypi.org/simple" }
+`)
+
+	versions, err := ParseLockVersions(path)
+	if err != nil {
+		t.Fatalf("ParseLockVersions failed: %v", err)
+	}
+	if versions["requests"] != "2.32.3" || versions["typing-extensions"] != "4.12.2" {
+		t.Errorf("unexpected versions: %v", versions)
+	}
+}
+
+func TestParseLockVersionsPoetry(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "poetry.lock")
+	writeFile(t, path, `[[package]]
+name = "PyYAML"
+version = "6.0.1"
+description = "YAML parser and emitter for Python"
+optional = false
+python-versions = ">=3.6"
+files = [
+    {file = "PyYAML-6.0.1.tar.gz", hash = "sha256:bfdf"},
+]
+
+[metadata]
+lock-version = "2.0"
+python-versions = "^3.10"
+content-hash = "abc"
+`)
+
+	versions, err := ParseLockVersions(path)
+	if err != nil {
+		t.Fatalf("ParseLockVersions failed: %v", err)
+	}
+	if versions["pyyaml"] != "6.0.1" {
+		t.Errorf("expected normalized pyyaml entry, got %v", versions)
+	}
+
+	found, err := FindLockFile(dir)
+	if err != nil || found != path {
+		t.Errorf("FindLockFile = %q, %v", found, err)
+	}
+}
+
+func TestPinToLock(t *testing.T) {
+	deps := []manifest.Dependency{
+		{Package: "Requests", Version: ">=2"},
+		{Package: "py_yaml", Version: "*"},
+		{Package: "unlocked", Version: ">=1.0"},
+	}
+	locked := map[string]string{"requests": "2.32.3", "py-yaml": "6.0.1"}
+
+	pinned, missing := PinToLock(deps, locked, func(v string) string { return "==" + v })
+
+	want := []string{"==2.32.3", "==6.0.1", ">=1.0"}
+	for i, dep := range pinned {
+		if dep.Version != want[i] {
+			t.Errorf("%s: version = %q, want %q", dep.Package, dep.Version, want[i])
+		}
+	}
+	if len(missing) != 1 || missing[0] != "unlocked" {
+		t.Errorf("missing = %v, want [unlocked]", missing)
+	}
+}
+
+func TestFindLockFileMissing(t *testing.T) {
+	if _, err := FindLockFile(t.TempDir()); err == nil {
+		t.Error("expected error when no lock file exists")
+	}
+}
@@ -25,11 +25,21 @@ type ProjectSection struct {
 	Name           string              `toml:"name"`
 	Version        string              `toml:"version"`
 	Description    string              `toml:"description"`
+	Authors        []Person            `toml:"authors"`
+	License        interface{}         `toml:"license"` // SPDX string (PEP 639), or a {text = ...} or {file = ...} table
+	Keywords       []string            `toml:"keywords"`
+	URLs           map[string]string   `toml:"urls"`
 	RequiresPython string              `toml:"requires-python"`
 	Dependencies   []string            `toml:"dependencies"`
 	OptionalDeps   map[string][]string `toml:"optional-dependencies"`
 }
 
+// Person is an entry of [project] authors
+type Person struct {
+	Name  string `toml:"name"`
+	Email string `toml:"email"`
+}
+
 // ToolSection represents the [tool] section
 type ToolSection struct {
 	Poetry *PoetrySection `toml:"poetry"`
@@ -40,6 +50,11 @@ type PoetrySection struct {
 	Name         string                 `toml:"name"`
 	Version      string                 `toml:"version"`
 	Description  string                 `toml:"description"`
+	Authors      []string               `toml:"authors"` // "Name <email>"
+	License      string                 `toml:"license"`
+	Keywords     []string               `toml:"keywords"`
+	Homepage     string                 `toml:"homepage"`
+	Repository   string                 `toml:"repository"`
 	Dependencies map[string]interface{} `toml:"dependencies"`
 	DevDeps      map[string]interface{} `toml:"dev-dependencies"`
 	Group        map[string]*DepGroup   `toml:"group"`
@@ -179,22 +194,28 @@ func ExtractDependencies(pyproject *PyProject) (*ParsedDependencies, error) {
 	return nil, fmt.Errorf("pyproject.toml does not contain [project] (PEP 621) or [tool.poetry] sections")
 }
 
+// ParseRequirement parses a PEP 508 requirement string such as
+// "requests[socks]>=2.31; python_version < '3.11'", returning nil when it
+// names no package
+func ParseRequirement(req string) *manifest.Dependency {
+	return parsePEP508Dependency(req)
+}
+
 // parsePEP508Dependency parses a PEP 508 dependency string
 // Examples: "requests>=2.31.0", "flask==2.0.0", "numpy>=1.20,<2.0"
+// Extras ("requests[socks]") and environment markers
+// ("; python_version < '3.8'") are kept on the dependency.
 func parsePEP508Dependency(depStr string) *manifest.Dependency {
-	depStr = strings.TrimSpace(depStr)
+	depStr, marker := splitMarker(strings.TrimSpace(depStr))
 	if depStr == "" {
 		return nil
 	}
 
-	// Split on common version specifiers
+	// Split at the first version specifier
 	var pkg, version string
-	for _, sep := range []string{">=", "<=", "==", "~=", "!=", ">", "<"} {
-		if idx := strings.Index(depStr, sep); idx != -1 {
-			pkg = strings.TrimSpace(depStr[:idx])
-			version = strings.TrimSpace(depStr[idx:])
-			break
-		}
+	if idx := strings.IndexAny(depStr, "<>=!~"); idx != -1 {
+		pkg = strings.TrimSpace(depStr[:idx])
+		version = strings.ReplaceAll(depStr[idx:], " ", "")
 	}
 
 	// If no version specifier found, treat entire string as package name
@@ -203,15 +224,24 @@ func parsePEP508Dependency(depStr string) *manifest.Dependency {
 		version = "*"
 	}
 
+	dep := &manifest.Dependency{
+		Version: version,
+		Marker:  marker,
+	}
+
 	// Handle extras like "requests[security]>=2.31.0"
 	if idx := strings.Index(pkg, "["); idx != -1 {
-		pkg = pkg[:idx]
+		extras := strings.TrimSuffix(strings.TrimSpace(pkg[idx+1:]), "]")
+		for _, extra := range strings.Split(extras, ",") {
+			if extra = strings.TrimSpace(extra); extra != "" {
+				dep.Extras = append(dep.Extras, extra)
+			}
+		}
+		pkg = strings.TrimSpace(pkg[:idx])
 	}
+	dep.Package = pkg
 
-	return &manifest.Dependency{
-		Package: pkg,
-		Version: version,
-	}
+	return dep
 }
 
 // parsePoetryDependency parses a Poetry dependency
@@ -235,6 +265,16 @@ func parsePoetryDependency(pkg string, value interface{}) *manifest.Dependency {
 			if optional, ok := v["optional"].(bool); ok {
 				dep.Optional = optional
 			}
+			if extras, ok := v["extras"].([]interface{}); ok {
+				for _, extra := range extras {
+					if s, ok := extra.(string); ok {
+						dep.Extras = append(dep.Extras, s)
+					}
+				}
+			}
+			if markers, ok := v["markers"].(string); ok {
+				dep.Marker = markers
+			}
 			return dep
 		}
 	}
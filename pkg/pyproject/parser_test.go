@@ -3,6 +3,7 @@ package pyproject
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -224,16 +225,22 @@ func TestParsePEP508Dependency(t *testing.T) {
 		input   string
 		pkg     string
 		version string
+		extras  string
+		marker  string
 	}{
-		{"requests>=2.31.0", "requests", ">=2.31.0"},
-		{"flask==2.0.0", "flask", "==2.0.0"},
-		{"numpy>=1.20,<2.0", "numpy", ">=1.20,<2.0"},
-		{"click~=8.1.0", "click", "~=8.1.0"},
-		{"django>3.0", "django", ">3.0"},
-		{"boto3<2.0", "boto3", "<2.0"},
-		{"requests[security]>=2.31.0", "requests", ">=2.31.0"},
-		{"simple-package", "simple-package", "*"},
-		{"", "", ""},
+		{"requests>=2.31.0", "requests", ">=2.31.0", "", ""},
+		{"flask==2.0.0", "flask", "==2.0.0", "", ""},
+		{"numpy>=1.20,<2.0", "numpy", ">=1.20,<2.0", "", ""},
+		{"click~=8.1.0", "click", "~=8.1.0", "", ""},
+		{"django>3.0", "django", ">3.0", "", ""},
+		{"boto3<2.0", "boto3", "<2.0", "", ""},
+		{"requests[security]>=2.31.0", "requests", ">=2.31.0", "security", ""},
+		{"requests [security, socks] >= 2.31.0, < 3", "requests", ">=2.31.0,<3", "security,socks", ""},
+		{"numpy!=1.5,>=1.0", "numpy", "!=1.5,>=1.0", "", ""},
+		{"tomli>=2.0; python_version < '3.11'", "tomli", ">=2.0", "", "python_version < '3.11'"},
+		{"uvicorn[standard]; sys_platform != 'win32'", "uvicorn", "*", "standard", "sys_platform != 'win32'"},
+		{"simple-package", "simple-package", "*", "", ""},
+		{"", "", "", "", ""},
 	}
 
 	for _, tt := range tests {
@@ -258,6 +265,14 @@ func TestParsePEP508Dependency(t *testing.T) {
 			if result.Version != tt.version {
 				t.Errorf("Version = %q, want %q", result.Version, tt.version)
 			}
+
+			if extras := strings.Join(result.Extras, ","); extras != tt.extras {
+				t.Errorf("Extras = %q, want %q", extras, tt.extras)
+			}
+
+			if result.Marker != tt.marker {
+				t.Errorf("Marker = %q, want %q", result.Marker, tt.marker)
+			}
 		})
 	}
 }
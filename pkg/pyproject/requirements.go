@@ -0,0 +1,246 @@
+package pyproject
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+// runtimeRequirementsFiles and devRequirementsFiles are the file names
+// searched for, in order, when no path is given
+var (
+	runtimeRequirementsFiles = []string{"requirements.txt"}
+	devRequirementsFiles     = []string{"requirements-dev.txt", "dev-requirements.txt", "requirements/dev.txt"}
+)
+
+// ParsedRequirements holds the dependencies read from a pip requirements file
+// and its -r includes
+type ParsedRequirements struct {
+	Dependencies []manifest.Dependency
+	Files        []string // every file read, in order
+	Warnings     []string // lines that were skipped or simplified
+}
+
+// FindRequirements searches dir for requirements.txt, or for a dev
+// requirements file (requirements-dev.txt, dev-requirements.txt or
+// requirements/dev.txt) when dev is true
+func FindRequirements(dir string, dev bool) (string, error) {
+	candidates := runtimeRequirementsFiles
+	if dev {
+		candidates = devRequirementsFiles
+	}
+	for _, name := range candidates {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found in %s", strings.Join(candidates, ", "), dir)
+}
+
+// ParseRequirements reads a pip requirements file. "-r" includes are
+// followed and their requirements merged. "-c" constraints files only
+// supply versions for packages that are required without one; a
+// constraints file passed directly is read as a list of requirements.
+// Extras and environment markers are dropped since aigogo.json cannot
+// express them, and a warning is recorded for each marker.
+func ParseRequirements(path string) (*ParsedRequirements, error) {
+	p := &requirementsParser{
+		result:      &ParsedRequirements{},
+		index:       make(map[string]int),
+		constraints: make(map[string]string),
+		seen:        make(map[string]bool),
+	}
+	if err := p.parseFile(path, false); err != nil {
+		return nil, err
+	}
+
+	// Apply constraints to requirements that have no version of their own
+	for i, dep := range p.result.Dependencies {
+		if dep.Version != "*" {
+			continue
+		}
+		if v, ok := p.constraints[normalizeName(dep.Package)]; ok {
+			p.result.Dependencies[i].Version = v
+		}
+	}
+
+	return p.result, nil
+}
+
+type requirementsParser struct {
+	result      *ParsedRequirements
+	index       map[string]int    // normalized name -> position in result
+	constraints map[string]string // normalized name -> version from -c files
+	seen        map[string]bool   // files already read, to stop include cycles
+}
+
+func (p *requirementsParser) parseFile(path string, constraint bool) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	if p.seen[abs] {
+		return nil
+	}
+	p.seen[abs] = true
+	p.result.Files = append(p.result.Files, path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	var pending string
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		// Backslash continues a requirement onto the next line
+		if strings.HasSuffix(line, "\\") {
+			pending += strings.TrimSuffix(line, "\\")
+			continue
+		}
+		line = pending + line
+		pending = ""
+
+		if err := p.parseLine(path, lineNum, stripComment(line), constraint); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if pending != "" {
+		return p.parseLine(path, lineNum, stripComment(pending), constraint)
+	}
+	return nil
+}
+
+func (p *requirementsParser) parseLine(path string, lineNum int, line string, constraint bool) error {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+
+	if strings.HasPrefix(line, "-") {
+		opt, arg := splitOption(line)
+		switch opt {
+		case "-r", "--requirement":
+			return p.parseFile(resolveInclude(path, arg), constraint)
+		case "-c", "--constraint":
+			return p.parseFile(resolveInclude(path, arg), true)
+		case "-e", "--editable":
+			p.warn(path, lineNum, "skipped editable requirement %q", arg)
+		}
+		// Index URLs, --hash, --pre and other pip options don't affect
+		// which packages are required
+		return nil
+	}
+
+	// Per-requirement options such as --hash follow the specifier and marker
+	if idx := strings.Index(line, " --"); idx != -1 {
+		line = strings.TrimSpace(line[:idx])
+	}
+
+	spec, marker := splitMarker(line)
+	if strings.Contains(spec, "://") || strings.Contains(spec, " @ ") || strings.HasPrefix(spec, ".") || strings.HasPrefix(spec, "/") {
+		p.warn(path, lineNum, "skipped direct reference %q", spec)
+		return nil
+	}
+
+	dep := parsePEP508Dependency(spec)
+	if dep == nil {
+		return nil
+	}
+	dep.Marker = marker
+
+	key := normalizeName(dep.Package)
+	if constraint {
+		if dep.Version != "*" {
+			p.constraints[key] = dep.Version
+		}
+		return nil
+	}
+
+	if i, ok := p.index[key]; ok && p.result.Dependencies[i].Marker == dep.Marker {
+		// pip combines the specifiers of a repeated requirement
+		existing := &p.result.Dependencies[i]
+		switch {
+		case existing.Version == "*":
+			existing.Version = dep.Version
+		case dep.Version != "*" && dep.Version != existing.Version:
+			existing.Version += "," + dep.Version
+		}
+		return nil
+	}
+
+	p.index[key] = len(p.result.Dependencies)
+	p.result.Dependencies = append(p.result.Dependencies, *dep)
+	return nil
+}
+
+func (p *requirementsParser) warn(path string, lineNum int, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	p.result.Warnings = append(p.result.Warnings, fmt.Sprintf("%s:%d: %s", path, lineNum, msg))
+}
+
+// stripComment removes a "#" comment that starts a line or follows whitespace
+func stripComment(line string) string {
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return ""
+	}
+	if idx := strings.Index(line, " #"); idx != -1 {
+		return line[:idx]
+	}
+	if idx := strings.Index(line, "\t#"); idx != -1 {
+		return line[:idx]
+	}
+	return line
+}
+
+// splitOption splits "-r base.txt", "-rbase.txt" or "--requirement=base.txt"
+// into the option and its argument
+func splitOption(line string) (string, string) {
+	if strings.HasPrefix(line, "--") {
+		if idx := strings.IndexAny(line, "= \t"); idx != -1 {
+			return line[:idx], strings.TrimSpace(line[idx+1:])
+		}
+		return line, ""
+	}
+	if len(line) > 2 {
+		return line[:2], strings.TrimSpace(line[2:])
+	}
+	return line, ""
+}
+
+// splitMarker separates a PEP 508 environment marker ("; python_version < '3.8'")
+// from the requirement
+func splitMarker(line string) (string, string) {
+	if idx := strings.Index(line, ";"); idx != -1 {
+		return strings.TrimSpace(line[:idx]), strings.TrimSpace(line[idx+1:])
+	}
+	return line, ""
+}
+
+// resolveInclude resolves an -r/-c path relative to the including file
+func resolveInclude(from, include string) string {
+	if filepath.IsAbs(include) {
+		return include
+	}
+	return filepath.Join(filepath.Dir(from), include)
+}
+
+// normalizeName applies PEP 503 name normalization so that "Foo_Bar" and
+// "foo-bar" are treated as the same package
+func normalizeName(name string) string {
+	name = strings.ToLower(name)
+	return strings.NewReplacer("_", "-", ".", "-").Replace(name)
+}
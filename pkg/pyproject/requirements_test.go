@@ -0,0 +1,151 @@
+package pyproject
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseRequirements(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "base.txt"), "urllib3>=1.26\n")
+	writeFile(t, filepath.Join(dir, "constraints.txt"), "certifi==2024.2.2\nurllib3<3\n")
+	writeFile(t, filepath.Join(dir, "requirements.txt"), `# Runtime dependencies
+-r base.txt
+--constraint constraints.txt
+--index-url https://pypi.org/simple
+
+requests[security] >= 2.31.0, < 3  # HTTP
+tomli>=2.0; python_version < "3.11"
+certifi
+numpy \
+    >=1.24
+Flask_Login>=0.6
+flask-login<1.0
+-e ./local-lib
+mylib @ https://example.com/mylib.tar.gz
+`)
+
+	reqs, err := ParseRequirements(filepath.Join(dir, "requirements.txt"))
+	if err != nil {
+		t.Fatalf("ParseRequirements failed: %v", err)
+	}
+
+	want := map[string]string{
+		"urllib3":     ">=1.26",
+		"requests":    ">=2.31.0,<3",
+		"tomli":       ">=2.0",
+		"certifi":     "==2024.2.2",
+		"numpy":       ">=1.24",
+		"Flask_Login": ">=0.6,<1.0",
+	}
+	if len(reqs.Dependencies) != len(want) {
+		t.Fatalf("expected %d dependencies, got %+v", len(want), reqs.Dependencies)
+	}
+	for _, dep := range reqs.Dependencies {
+		if want[dep.Package] != dep.Version {
+			t.Errorf("%s: version = %q, want %q", dep.Package, dep.Version, want[dep.Package])
+		}
+	}
+
+	if reqs.Dependencies[0].Package != "urllib3" {
+		t.Errorf("expected -r include to come first, got %s", reqs.Dependencies[0].Package)
+	}
+	if len(reqs.Files) != 3 {
+		t.Errorf("expected 3 files read, got %v", reqs.Files)
+	}
+
+	for _, dep := range reqs.Dependencies {
+		switch dep.Package {
+		case "requests":
+			if len(dep.Extras) != 1 || dep.Extras[0] != "security" {
+				t.Errorf("requests: extras = %v, want [security]", dep.Extras)
+			}
+		case "tomli":
+			if dep.Marker != `python_version < "3.11"` {
+				t.Errorf("tomli: marker = %q", dep.Marker)
+			}
+		}
+	}
+
+	warnings := strings.Join(reqs.Warnings, "\n")
+	if strings.Contains(warnings, "marker") {
+		t.Errorf("environment markers should be kept, got warnings:\n%s", warnings)
+	}
+	for _, s := range []string{"skipped editable", "skipped direct reference"} {
+		if !strings.Contains(warnings, s) {
+			t.Errorf("expected warning containing %q, got:\n%s", s, warnings)
+		}
+	}
+}
+
+func TestParseRequirementsMarkerVariants(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "requirements.txt"), `numpy<2; python_version < "3.9" --hash=sha256:abc
+numpy>=2; python_version >= "3.9"
+`)
+
+	reqs, err := ParseRequirements(filepath.Join(dir, "requirements.txt"))
+	if err != nil {
+		t.Fatalf("ParseRequirements failed: %v", err)
+	}
+	if len(reqs.Dependencies) != 2 {
+		t.Fatalf("expected one dependency per marker, got %+v", reqs.Dependencies)
+	}
+	if reqs.Dependencies[0].Version != "<2" || reqs.Dependencies[0].Marker != `python_version < "3.9"` {
+		t.Errorf("unexpected first requirement: %+v", reqs.Dependencies[0])
+	}
+}
+
+func TestParseRequirementsIncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.txt"), "-r b.txt\nrequests\n")
+	writeFile(t, filepath.Join(dir, "b.txt"), "-r a.txt\nflask\n")
+
+	reqs, err := ParseRequirements(filepath.Join(dir, "a.txt"))
+	if err != nil {
+		t.Fatalf("ParseRequirements failed: %v", err)
+	}
+	if len(reqs.Dependencies) != 2 {
+		t.Errorf("expected 2 dependencies, got %+v", reqs.Dependencies)
+	}
+}
+
+func TestParseRequirementsMissingInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "requirements.txt"), "-r missing.txt\n")
+
+	if _, err := ParseRequirements(filepath.Join(dir, "requirements.txt")); err == nil {
+		t.Error("expected error for missing include")
+	}
+}
+
+func TestFindRequirements(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := FindRequirements(dir, false); err == nil {
+		t.Error("expected error when requirements.txt is missing")
+	}
+
+	writeFile(t, filepath.Join(dir, "requirements.txt"), "requests\n")
+	writeFile(t, filepath.Join(dir, "requirements", "dev.txt"), "pytest\n")
+
+	path, err := FindRequirements(dir, false)
+	if err != nil || filepath.Base(path) != "requirements.txt" {
+		t.Errorf("FindRequirements(runtime) = %q, %v", path, err)
+	}
+	path, err = FindRequirements(dir, true)
+	if err != nil || path != filepath.Join(dir, "requirements", "dev.txt") {
+		t.Errorf("FindRequirements(dev) = %q, %v", path, err)
+	}
+}
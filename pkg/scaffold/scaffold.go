@@ -0,0 +1,162 @@
+// Package scaffold lays out new packages from templates for aigg init
+// --template: the built-in ones embedded in aigg, or the user's own in
+// templates/<name>/ in the config directory.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/aupeachmo/aigogo/pkg/lockfile"
+	"github.com/aupeachmo/aigogo/pkg/userdirs"
+)
+
+//go:embed all:templates
+var builtin embed.FS
+
+const (
+	// TemplateSuffix marks the files of a template that are rendered with
+	// text/template; the suffix is dropped from the file created
+	TemplateSuffix = ".tmpl"
+
+	// ModulePlaceholder in a template's file paths is replaced by the
+	// package's import name
+	ModulePlaceholder = "__module__"
+)
+
+// Data is what a template's files can refer to, e.g. {{.Name}}
+type Data struct {
+	Name   string // the package name, as in aigogo.json
+	Module string // the name packages are imported by, e.g. aigogo.<Module>
+}
+
+// NewData returns the template data for a package called name
+func NewData(name string) Data {
+	return Data{Name: name, Module: lockfile.NormalizeName(name)}
+}
+
+// Template is a directory of files laid out for a new package
+type Template struct {
+	Name   string
+	Source string // "built-in", or the directory it was read from
+	fsys   fs.FS
+}
+
+// File is a file a template creates, relative to the package directory
+type File struct {
+	Path    string // slash-separated
+	Content []byte
+}
+
+// UserTemplatesDir returns the directory user templates are read from,
+// templates/ in the config directory. It may not exist.
+func UserTemplatesDir() (string, error) {
+	dir, err := userdirs.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "templates"), nil
+}
+
+// Find returns the template called name. A directory of that name in
+// userDir takes the place of the built-in template.
+func Find(name, userDir string) (*Template, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return nil, fmt.Errorf("invalid template name: %q", name)
+	}
+	if userDir != "" {
+		dir := filepath.Join(userDir, name)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return &Template{Name: name, Source: dir, fsys: os.DirFS(dir)}, nil
+		}
+	}
+	if info, err := fs.Stat(builtin, "templates/"+name); err == nil && info.IsDir() {
+		sub, err := fs.Sub(builtin, "templates/"+name)
+		if err != nil {
+			return nil, err
+		}
+		return &Template{Name: name, Source: "built-in", fsys: sub}, nil
+	}
+
+	names, err := List(userDir)
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("unknown template: %s (available: %s)", name, strings.Join(names, ", "))
+}
+
+// List returns the names of the built-in templates and those in userDir,
+// sorted
+func List(userDir string) ([]string, error) {
+	seen := map[string]bool{}
+	entries, err := fs.ReadDir(builtin, "templates")
+	if err != nil {
+		return nil, err
+	}
+	if userDir != "" {
+		userEntries, err := os.ReadDir(userDir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s: %w", userDir, err)
+		}
+		entries = append(entries, userEntries...)
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && !seen[e.Name()] {
+			seen[e.Name()] = true
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Render returns the files the template creates for data, in path order.
+// Every template must create an aigogo.json.
+func (t *Template) Render(data Data) ([]File, error) {
+	var files []File
+	err := fs.WalkDir(t.fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(t.fsys, path)
+		if err != nil {
+			return err
+		}
+
+		target := strings.ReplaceAll(path, ModulePlaceholder, data.Module)
+		if strings.HasSuffix(target, TemplateSuffix) {
+			target = strings.TrimSuffix(target, TemplateSuffix)
+			tmpl, err := template.New(path).Option("missingkey=error").Parse(string(content))
+			if err != nil {
+				return fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, data); err != nil {
+				return fmt.Errorf("failed to render %s: %w", path, err)
+			}
+			content = buf.Bytes()
+		}
+		files = append(files, File{Path: target, Content: content})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", t.Name, err)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	for _, f := range files {
+		if f.Path == "aigogo.json" {
+			return files, nil
+		}
+	}
+	return nil, fmt.Errorf("template %s has no aigogo.json or aigogo.json%s", t.Name, TemplateSuffix)
+}
@@ -0,0 +1,191 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/manifest"
+)
+
+func TestBuiltinTemplatesRender(t *testing.T) {
+	names, err := List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"notebook", "prompt-pack", "python-lib", "ts-lib"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("List = %v, want %v", names, want)
+	}
+
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			tmpl, err := Find(name, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			files, err := tmpl.Render(NewData("my-agent"))
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+
+			dir := t.TempDir()
+			for _, f := range files {
+				if strings.HasSuffix(f.Path, TemplateSuffix) || strings.Contains(f.Path, ModulePlaceholder) {
+					t.Errorf("file %s wasn't rendered", f.Path)
+				}
+				if strings.Contains(string(f.Content), "{{") {
+					t.Errorf("%s has unrendered template actions", f.Path)
+				}
+				path := filepath.Join(dir, filepath.FromSlash(f.Path))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, f.Content, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			m, err := manifest.Load(filepath.Join(dir, "aigogo.json"))
+			if err != nil {
+				t.Fatalf("aigogo.json doesn't load: %v", err)
+			}
+			if err := manifest.Validate(m); err != nil {
+				t.Errorf("aigogo.json is invalid: %v", err)
+			}
+			data, _ := os.ReadFile(filepath.Join(dir, "aigogo.json"))
+			if problems, err := manifest.ValidateSchema(data); err != nil || len(problems) > 0 {
+				t.Errorf("aigogo.json doesn't match the schema: %v %v", problems, err)
+			}
+			if m.Name != "my-agent" {
+				t.Errorf("name = %q, want my-agent", m.Name)
+			}
+
+			// Everything the manifest includes must be created, unless a
+			// prebuild script (ts-lib's tsc) generates it
+			patterns, _ := m.Files.GetIncludePatterns()
+			if m.Scripts[manifest.ScriptPrebuild] != "" {
+				patterns = nil
+			}
+			for _, pattern := range patterns {
+				matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+				if len(matches) == 0 {
+					t.Errorf("files.include pattern %s matches no file", pattern)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderModulePlaceholder(t *testing.T) {
+	tmpl, err := Find("notebook", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := tmpl.Render(NewData("data-explorer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	want := []string{".aigogoignore", "aigogo.json", "data_explorer.ipynb"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+}
+
+func TestUserTemplates(t *testing.T) {
+	userDir := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(userDir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("python-lib/aigogo.json.tmpl", `{"name": "{{.Name}}"}`)
+	write("team-tool/aigogo.json", `{"name": "fixed"}`)
+	write("team-tool/{{.Name}}.txt", "kept as is")
+	write("broken/README.md", "no manifest")
+
+	names, err := List(userDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"broken", "notebook", "prompt-pack", "python-lib", "team-tool", "ts-lib"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("List = %v, want %v", names, want)
+	}
+
+	// A user template replaces the built-in one of the same name
+	tmpl, err := Find("python-lib", userDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.Source != filepath.Join(userDir, "python-lib") {
+		t.Errorf("Source = %q, want the user directory", tmpl.Source)
+	}
+	files, err := tmpl.Render(NewData("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || string(files[0].Content) != `{"name": "x"}` {
+		t.Errorf("Render = %+v", files)
+	}
+
+	// Files without the .tmpl suffix are copied verbatim
+	tmpl, err = Find("team-tool", userDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err = tmpl.Render(NewData("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Path != "aigogo.json" || files[1].Path != "{{.Name}}.txt" {
+		t.Errorf("Render = %+v", files)
+	}
+
+	tmpl, err = Find("broken", userDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tmpl.Render(NewData("x")); err == nil || !strings.Contains(err.Error(), "no aigogo.json") {
+		t.Errorf("Render of a template without aigogo.json: err = %v", err)
+	}
+}
+
+func TestFindErrors(t *testing.T) {
+	if _, err := Find("nope", t.TempDir()); err == nil || !strings.Contains(err.Error(), "available: notebook, prompt-pack, python-lib, ts-lib") {
+		t.Errorf("Find(nope): err = %v, want the available templates listed", err)
+	}
+	for _, name := range []string{"", "..", "a/b"} {
+		if _, err := Find(name, t.TempDir()); err == nil || !strings.Contains(err.Error(), "invalid template name") {
+			t.Errorf("Find(%q): err = %v, want an invalid name error", name, err)
+		}
+	}
+}
+
+func TestRenderMissingKey(t *testing.T) {
+	userDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(userDir, "t"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(userDir, "t", "aigogo.json.tmpl"), []byte(`{{.Author}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := Find("t", userDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tmpl.Render(NewData("x")); err == nil || !strings.Contains(err.Error(), "failed to render aigogo.json.tmpl") {
+		t.Errorf("Render with an unknown field: err = %v", err)
+	}
+}
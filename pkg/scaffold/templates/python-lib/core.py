@@ -0,0 +1,7 @@
+"""The package's implementation. Modules are imported relative to the
+package root, e.g. `from .core import greet` in __init__.py."""
+
+
+def greet(name: str) -> str:
+    """Return a greeting for name."""
+    return f"Hello, {name}!"
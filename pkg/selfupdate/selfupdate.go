@@ -0,0 +1,299 @@
+// Package selfupdate replaces the running aigg binary with a release
+// published on GitHub. Release archives are checked against the .sha256
+// file published next to them before the binary is extracted.
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+)
+
+// Repo is the GitHub repository aigg is released from
+const Repo = "aupeachmo/aigogo"
+
+// APIURL is the GitHub API the releases are looked up in
+var APIURL = "https://api.github.com"
+
+// maxArchiveSize bounds the size of a downloaded release archive
+const maxArchiveSize = 200 << 20
+
+// Release is a GitHub release of aigg
+type Release struct {
+	Tag    string  `json:"tag_name"` // e.g. v1.2.3
+	URL    string  `json:"html_url"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// LatestRelease returns the latest release of aigg
+func LatestRelease() (*Release, error) {
+	return fetchRelease(fmt.Sprintf("%s/repos/%s/releases/latest", APIURL, Repo))
+}
+
+// ReleaseByTag returns the release of aigg tagged tag, e.g. v1.2.3
+func ReleaseByTag(tag string) (*Release, error) {
+	return fetchRelease(fmt.Sprintf("%s/repos/%s/releases/tags/%s", APIURL, Repo, tag))
+}
+
+// fetchRelease reads a release from the GitHub API
+func fetchRelease(url string) (*Release, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	// Unauthenticated requests are rate limited per IP address
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := logging.NewHTTPClient(30 * time.Second).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach GitHub: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errcode.Errorf(errcode.NotFound, "release not found")
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("GitHub returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to parse release: %w", err)
+	}
+	return &release, nil
+}
+
+// Asset returns the asset called name
+func (r *Release) Asset(name string) (*Asset, error) {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("release %s has no %s", r.Tag, name)
+}
+
+// ArchiveName returns the name of the release archive for a platform, such
+// as aigg-linux-amd64.tar.gz or aigg-windows-arm64.zip
+func ArchiveName(goos, goarch string) string {
+	if goos == "windows" {
+		return fmt.Sprintf("aigg-%s-%s.zip", goos, goarch)
+	}
+	return fmt.Sprintf("aigg-%s-%s.tar.gz", goos, goarch)
+}
+
+// binaryName returns the name of the binary in the release archive for a
+// platform
+func binaryName(goos, goarch string) string {
+	if goos == "windows" {
+		return fmt.Sprintf("aigg-%s-%s.exe", goos, goarch)
+	}
+	return fmt.Sprintf("aigg-%s-%s", goos, goarch)
+}
+
+// Download downloads the release archive for this platform, checks it
+// against its published SHA-256 checksum and returns the archive and the
+// binary in it
+func Download(r *Release) (archive, binary []byte, err error) {
+	name := ArchiveName(runtime.GOOS, runtime.GOARCH)
+	asset, err := r.Asset(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: no release for %s/%s", err, runtime.GOOS, runtime.GOARCH)
+	}
+	sumAsset, err := r.Asset(name + ".sha256")
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: can't verify the download", err)
+	}
+
+	sumFile, err := download(sumAsset.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+	archive, err = download(asset.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := VerifyChecksum(archive, sumFile); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	binary, err = extractBinary(archive, name, binaryName(runtime.GOOS, runtime.GOARCH))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to extract %s: %w", name, err)
+	}
+	return archive, binary, nil
+}
+
+// download returns the content at url
+func download(url string) ([]byte, error) {
+	resp, err := logging.NewHTTPClient(5 * time.Minute).Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxArchiveSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if len(data) > maxArchiveSize {
+		return nil, fmt.Errorf("failed to download %s: larger than %d MB", url, maxArchiveSize>>20)
+	}
+	return data, nil
+}
+
+// VerifyChecksum checks data against a sha256sum-style checksum file, whose
+// first field is the hex SHA-256 digest
+func VerifyChecksum(data, sumFile []byte) error {
+	fields := strings.Fields(string(sumFile))
+	if len(fields) == 0 {
+		return fmt.Errorf("empty checksum file")
+	}
+	want := strings.ToLower(fields[0])
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return errcode.Errorf(errcode.Integrity, "checksum mismatch: got sha256:%s, expected sha256:%s", got, want)
+	}
+	return nil
+}
+
+// extractBinary returns the file called binary from a .tar.gz or .zip
+// release archive
+func extractBinary(archive []byte, archiveName, binary string) ([]byte, error) {
+	if strings.HasSuffix(archiveName, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if f.Name != binary {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer func() { _ = rc.Close() }()
+			return io.ReadAll(io.LimitReader(rc, maxArchiveSize))
+		}
+		return nil, fmt.Errorf("%s not found in archive", binary)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = gz.Close() }()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in archive", binary)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg && filepath.Clean(hdr.Name) == binary {
+			return io.ReadAll(io.LimitReader(tr, maxArchiveSize))
+		}
+	}
+}
+
+// Manager returns the package manager that installed the executable at exe
+// and, when it has one, the command that upgrades aigg with it. The name is
+// "" for a binary installed by hand.
+func Manager(exe string) (name, upgrade string) {
+	path := filepath.ToSlash(exe)
+	switch {
+	case strings.Contains(path, "/Cellar/") || strings.Contains(path, "/homebrew/") || strings.Contains(path, "/.linuxbrew/"):
+		return "Homebrew", "brew upgrade aigg"
+	case strings.Contains(strings.ToLower(path), "/scoop/"):
+		return "Scoop", "scoop update aigg"
+	case strings.HasPrefix(path, "/nix/store/"):
+		return "Nix", ""
+	case strings.HasPrefix(path, "/usr/bin/") || strings.HasPrefix(path, "/bin/"):
+		return "the system package manager", ""
+	}
+	return "", ""
+}
+
+// Replace atomically replaces the executable at exe with binary, keeping
+// its permissions. The new binary is run with "version" first, so one that
+// can't run here never replaces a working one. Windows can't overwrite a
+// running executable, so the old one is moved aside to exe.old.
+func Replace(exe string, binary []byte) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", exe, err)
+	}
+
+	// A temporary file in the same directory can be renamed over exe
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".aigg-update-*")
+	if err != nil {
+		return fmt.Errorf("can't write to %s: %w", filepath.Dir(exe), err)
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if _, err := tmp.Write(binary); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := os.Chmod(tmpPath, info.Mode().Perm()|0111); err != nil {
+		return fmt.Errorf("failed to make %s executable: %w", tmpPath, err)
+	}
+
+	if out, err := exec.Command(tmpPath, "version").CombinedOutput(); err != nil {
+		return fmt.Errorf("the new binary doesn't run: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return fmt.Errorf("failed to move %s aside: %w", exe, err)
+		}
+		if err := os.Rename(tmpPath, exe); err != nil {
+			_ = os.Rename(old, exe)
+			return fmt.Errorf("failed to replace %s: %w", exe, err)
+		}
+		return nil
+	}
+	if err := os.Rename(tmpPath, exe); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", exe, err)
+	}
+	return nil
+}
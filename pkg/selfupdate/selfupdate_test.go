@@ -0,0 +1,168 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// makeArchive returns a release archive holding binary under name
+func makeArchive(t *testing.T, archiveName, name string, binary []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if strings.HasSuffix(archiveName, ".zip") {
+		zw := zip.NewWriter(&buf)
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(binary); err != nil {
+			t.Fatal(err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(binary)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(binary); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func checksum(data []byte, name string) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]) + "  " + name + "\n"
+}
+
+func TestDownload(t *testing.T) {
+	archiveName := ArchiveName(runtime.GOOS, runtime.GOARCH)
+	binary := []byte("new aigg")
+	archive := makeArchive(t, archiveName, binaryName(runtime.GOOS, runtime.GOARCH), binary)
+	sumFile := checksum(archive, archiveName)
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/repos/"+Repo+"/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(Release{Tag: "v9.9.9", Assets: []Asset{
+			{Name: archiveName, URL: server.URL + "/download/" + archiveName},
+			{Name: archiveName + ".sha256", URL: server.URL + "/download/" + archiveName + ".sha256"},
+		}})
+	})
+	mux.HandleFunc("/download/"+archiveName, func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(archive) })
+	mux.HandleFunc("/download/"+archiveName+".sha256", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte(sumFile)) })
+
+	oldURL := APIURL
+	APIURL = server.URL
+	defer func() { APIURL = oldURL }()
+
+	release, err := LatestRelease()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Tag != "v9.9.9" {
+		t.Errorf("Tag = %q, want v9.9.9", release.Tag)
+	}
+	gotArchive, gotBinary, err := Download(release)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotArchive, archive) || !bytes.Equal(gotBinary, binary) {
+		t.Errorf("Download() = %q, want %q", gotBinary, binary)
+	}
+
+	// A tampered archive fails its checksum
+	archive = append(archive, 0)
+	if _, _, err := Download(release); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Download() of a tampered archive error = %v", err)
+	}
+
+	if _, err := ReleaseByTag("v0.0.0"); err == nil || !strings.Contains(err.Error(), "release not found") {
+		t.Errorf("ReleaseByTag() of a missing release error = %v", err)
+	}
+}
+
+func TestExtractBinary(t *testing.T) {
+	for _, archiveName := range []string{"aigg-linux-amd64.tar.gz", "aigg-windows-amd64.zip"} {
+		archive := makeArchive(t, archiveName, "aigg", []byte("binary"))
+		got, err := extractBinary(archive, archiveName, "aigg")
+		if err != nil || string(got) != "binary" {
+			t.Errorf("extractBinary(%s) = %q, %v", archiveName, got, err)
+		}
+		if _, err := extractBinary(archive, archiveName, "other"); err == nil {
+			t.Errorf("extractBinary(%s) found a missing file", archiveName)
+		}
+	}
+}
+
+func TestManager(t *testing.T) {
+	tests := map[string]string{
+		"/opt/homebrew/Cellar/aigg/1.0.0/bin/aigg":              "Homebrew",
+		"/home/linuxbrew/.linuxbrew/Cellar/aigg/1.0.0/bin/aigg": "Homebrew",
+		"/usr/local/Cellar/aigg/1.0.0/bin/aigg":                 "Homebrew",
+		"/nix/store/abc-aigg-1.0.0/bin/aigg":                    "Nix",
+		"/usr/bin/aigg":                                         "the system package manager",
+		"/usr/local/bin/aigg":                                   "",
+		"/home/me/bin/aigg":                                     "",
+	}
+	for exe, want := range tests {
+		if got, _ := Manager(exe); got != want {
+			t.Errorf("Manager(%q) = %q, want %q", exe, got, want)
+		}
+	}
+}
+
+func TestReplace(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("replaces a shell script")
+	}
+	exe := filepath.Join(t.TempDir(), "aigg")
+	if err := os.WriteFile(exe, []byte("#!/bin/sh\necho old\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Replace(exe, []byte("#!/bin/sh\nexit 1\n")); err == nil || !strings.Contains(err.Error(), "doesn't run") {
+		t.Errorf("Replace() with a broken binary error = %v", err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "#!/bin/sh\necho old\n" {
+		t.Errorf("a broken binary replaced the executable: %q", data)
+	}
+
+	if err := Replace(exe, []byte("#!/bin/sh\necho new\n")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "#!/bin/sh\necho new\n" || info.Mode().Perm() != 0755 {
+		t.Errorf("Replace() left %q with mode %v", data, info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(exe)); len(entries) != 1 {
+		t.Errorf("Replace() left %d files, want 1", len(entries))
+	}
+}
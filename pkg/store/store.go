@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -8,13 +9,19 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/filelock"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+	"github.com/aupeachmo/aigogo/pkg/userdirs"
 )
 
 // Store manages the content-addressable storage for aigogo packages
 type Store struct {
-	rootDir string // ~/.aigogo/store
+	rootDir string // store/ in the data directory, by default
 }
 
 // StoredPackage represents a package stored in the CAS
@@ -24,14 +31,13 @@ type StoredPackage struct {
 	Manifest string // Path to the aigogo.json manifest
 }
 
-// NewStore creates a new Store instance with the default location (~/.aigogo/store)
+// NewStore creates a new Store instance with the default location, store/
+// in the data directory (~/.local/share/aigogo/store or ~/.aigogo/store)
 func NewStore() (*Store, error) {
-	home, err := os.UserHomeDir()
+	rootDir, err := DefaultDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
 	}
-
-	rootDir := filepath.Join(home, ".aigogo", "store")
 	if err := os.MkdirAll(rootDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create store directory: %w", err)
 	}
@@ -39,6 +45,15 @@ func NewStore() (*Store, error) {
 	return &Store{rootDir: rootDir}, nil
 }
 
+// DefaultDir returns the default store location, without creating it
+func DefaultDir() (string, error) {
+	dir, err := userdirs.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "store"), nil
+}
+
 // NewStoreAt creates a Store at a specific location (useful for testing)
 func NewStoreAt(rootDir string) (*Store, error) {
 	if err := os.MkdirAll(rootDir, 0755); err != nil {
@@ -52,43 +67,77 @@ func (s *Store) RootDir() string {
 	return s.rootDir
 }
 
-// GetPath returns the full path for a given hash
-// Structure: ~/.aigogo/store/sha256/ab/abcdef123.../
-func (s *Store) GetPath(hash string) string {
-	// Remove sha256: prefix if present
-	hash = strings.TrimPrefix(hash, "sha256:")
+// ValidateHash checks that hash is a SHA256 hash, 64 lowercase hex
+// characters with or without the sha256: prefix. Hashes name directories
+// in the store, so anything else, such as a hash with .. or a path
+// separator from a lock file or a request, is rejected.
+func ValidateHash(hash string) error {
+	hex := strings.TrimPrefix(hash, "sha256:")
+	if len(hex) != 64 || strings.IndexFunc(hex, func(r rune) bool {
+		return (r < '0' || r > '9') && (r < 'a' || r > 'f')
+	}) >= 0 {
+		return errcode.Errorf(errcode.Usage, "invalid package hash: %q (expected sha256: and 64 lowercase hex characters)", hash)
+	}
+	return nil
+}
 
-	if len(hash) < 2 {
-		return filepath.Join(s.rootDir, "sha256", hash)
+// GetPath returns the full path for a given hash
+// Structure: <store>/sha256/ab/abcdef123.../
+func (s *Store) GetPath(hash string) (string, error) {
+	if err := ValidateHash(hash); err != nil {
+		return "", err
 	}
+	hash = strings.TrimPrefix(hash, "sha256:")
 
 	// Use first 2 chars as subdirectory for better filesystem performance
-	return filepath.Join(s.rootDir, "sha256", hash[:2], hash)
+	return filepath.Join(s.rootDir, "sha256", hash[:2], hash), nil
 }
 
-// Has checks if a hash exists in the store
+// Has checks if a hash exists in the store. An invalid hash never does.
 func (s *Store) Has(hash string) bool {
-	path := s.GetPath(hash)
-	_, err := os.Stat(path)
+	path, err := s.GetPath(hash)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
 	return err == nil
 }
 
+// Lock locks the store against other aigg processes writing to it. Store,
+// MakeReadOnly, MakeExecutable and Delete hold it while they write.
+func (s *Store) Lock() (*filelock.Lock, error) {
+	return filelock.Acquire(s.rootDir, "the package store")
+}
+
 // Store stores files from a source directory into the CAS
-// Returns the computed SHA256 hash of the contents
-func (s *Store) Store(srcDir string, files []string, manifestData []byte) (string, error) {
+// Returns the computed SHA256 hash of the contents. Canceling ctx stops the
+// copy and removes what was copied, so no partial package is left stored.
+func (s *Store) Store(ctx context.Context, srcDir string, files []string, manifestData []byte) (string, error) {
 	// Compute hash of all content
 	hash, err := s.computeContentHash(srcDir, files, manifestData)
 	if err != nil {
 		return "", fmt.Errorf("failed to compute content hash: %w", err)
 	}
 
+	// Another process may be storing the same package: wait for it to
+	// finish rather than taking its half-copied files for stored
+	fl, err := s.Lock()
+	if err != nil {
+		return "", err
+	}
+	defer fl.Release()
+
 	// Check if already stored
+	storePath, err := s.GetPath(hash)
+	if err != nil {
+		return "", err
+	}
 	if s.Has(hash) {
+		logging.Verbosef("sha256:%s already stored at %s\n", hash, storePath)
 		return hash, nil
 	}
 
 	// Create storage directory
-	storePath := s.GetPath(hash)
 	filesDir := filepath.Join(storePath, "files")
 	if err := os.MkdirAll(filesDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create store directory: %w", err)
@@ -96,19 +145,23 @@ func (s *Store) Store(srcDir string, files []string, manifestData []byte) (strin
 
 	// Copy files
 	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			_ = removeTree(storePath)
+			return "", err
+		}
 		srcPath := filepath.Join(srcDir, file)
 		dstPath := filepath.Join(filesDir, file)
 
 		// Create parent directories
 		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
 			// Clean up on error
-			_ = os.RemoveAll(storePath)
+			_ = removeTree(storePath)
 			return "", fmt.Errorf("failed to create directory for %s: %w", file, err)
 		}
 
 		// Copy file
 		if err := copyFile(srcPath, dstPath); err != nil {
-			_ = os.RemoveAll(storePath)
+			_ = removeTree(storePath)
 			return "", fmt.Errorf("failed to copy %s: %w", file, err)
 		}
 	}
@@ -116,20 +169,24 @@ func (s *Store) Store(srcDir string, files []string, manifestData []byte) (strin
 	// Write manifest
 	manifestPath := filepath.Join(storePath, "aigogo.json")
 	if err := os.WriteFile(manifestPath, manifestData, 0644); err != nil {
-		_ = os.RemoveAll(storePath)
+		_ = removeTree(storePath)
 		return "", fmt.Errorf("failed to write manifest: %w", err)
 	}
 
+	logging.Verbosef("stored sha256:%s (%d files) at %s\n", hash, len(files), storePath)
 	return hash, nil
 }
 
 // Get retrieves a stored package by hash
 func (s *Store) Get(hash string) (*StoredPackage, error) {
+	storePath, err := s.GetPath(hash)
+	if err != nil {
+		return nil, err
+	}
 	if !s.Has(hash) {
-		return nil, fmt.Errorf("package not found in store: %s", hash)
+		return nil, errcode.Errorf(errcode.NotFound, "package not found in store: %s", hash)
 	}
 
-	storePath := s.GetPath(hash)
 	return &StoredPackage{
 		Hash:     hash,
 		FilesDir: filepath.Join(storePath, "files"),
@@ -137,8 +194,17 @@ func (s *Store) Get(hash string) (*StoredPackage, error) {
 	}, nil
 }
 
-// MakeReadOnly makes all files in a stored package read-only
+// MakeReadOnly makes all files in a stored package read-only. On Windows,
+// where a file's only permission is its read-only attribute, directories
+// are left as they are: the attribute doesn't stop changes to a directory's
+// entries, and only keeps it from being removed.
 func (s *Store) MakeReadOnly(hash string) error {
+	fl, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer fl.Release()
+
 	pkg, err := s.Get(hash)
 	if err != nil {
 		return err
@@ -151,15 +217,47 @@ func (s *Store) MakeReadOnly(hash string) error {
 		}
 
 		if info.IsDir() {
+			if runtime.GOOS == "windows" {
+				return nil
+			}
 			// Directories need execute permission to be traversable
 			return os.Chmod(path, 0555)
 		}
 
-		// Files get read-only
+		// Files get read-only, keeping the executable bit of executables
+		if info.Mode()&0111 != 0 {
+			return os.Chmod(path, 0555)
+		}
 		return os.Chmod(path, 0444)
 	})
 }
 
+// MakeExecutable sets the executable bit on files of a stored package,
+// given relative to its files directory. The files stay read-only.
+func (s *Store) MakeExecutable(hash string, files []string) error {
+	fl, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer fl.Release()
+
+	pkg, err := s.Get(hash)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		path := filepath.Join(pkg.FilesDir, file)
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", file, err)
+		}
+		if err := os.Chmod(path, info.Mode().Perm()&^0222|0555); err != nil {
+			return fmt.Errorf("failed to make %s executable: %w", file, err)
+		}
+	}
+	return nil
+}
+
 // GetManifest reads and returns the manifest for a stored package
 func (s *Store) GetManifest(hash string) (map[string]interface{}, error) {
 	pkg, err := s.Get(hash)
@@ -180,7 +278,8 @@ func (s *Store) GetManifest(hash string) (map[string]interface{}, error) {
 	return manifest, nil
 }
 
-// ListFiles returns all files in a stored package
+// ListFiles returns all files in a stored package, as slash-separated
+// paths relative to its files directory, as aigogo.lock lists them
 func (s *Store) ListFiles(hash string) ([]string, error) {
 	pkg, err := s.Get(hash)
 	if err != nil {
@@ -198,7 +297,7 @@ func (s *Store) ListFiles(hash string) ([]string, error) {
 			if err != nil {
 				return err
 			}
-			files = append(files, relPath)
+			files = append(files, filepath.ToSlash(relPath))
 		}
 		return nil
 	})
@@ -206,13 +305,51 @@ func (s *Store) ListFiles(hash string) ([]string, error) {
 	return files, err
 }
 
+// Verify recomputes the content hash of a stored package, catching files
+// changed or removed since it was stored
+func (s *Store) Verify(hash string) error {
+	pkg, err := s.Get(hash)
+	if err != nil {
+		return err
+	}
+	files, err := s.ListFiles(hash)
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+	manifestData, err := os.ReadFile(pkg.Manifest)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	actual, err := s.computeContentHash(pkg.FilesDir, files, manifestData)
+	if err != nil {
+		return fmt.Errorf("failed to compute content hash: %w", err)
+	}
+	if actual != strings.TrimPrefix(hash, "sha256:") {
+		return errcode.Errorf(errcode.Integrity, "content hash is sha256:%s, expected sha256:%s", actual, strings.TrimPrefix(hash, "sha256:"))
+	}
+	return nil
+}
+
 // computeContentHash computes SHA256 hash of files and manifest
 func (s *Store) computeContentHash(srcDir string, files []string, manifestData []byte) (string, error) {
+	return ContentHash(files, func(file string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(srcDir, file))
+	}, manifestData)
+}
+
+// ContentHash returns the hash Store gives a package, without storing it:
+// its files, read with read, and its manifest. aigg add locks it, with a
+// sha256: prefix, as the package's integrity. Paths are hashed with forward
+// slashes, so a package has the same hash on Windows as elsewhere.
+func ContentHash(files []string, read func(file string) ([]byte, error), manifestData []byte) (string, error) {
 	h := sha256.New()
 
 	// Sort files for deterministic hashing
 	sortedFiles := make([]string, len(files))
-	copy(sortedFiles, files)
+	for i, file := range files {
+		sortedFiles[i] = filepath.ToSlash(file)
+	}
 	sort.Strings(sortedFiles)
 
 	// Hash each file's path and content
@@ -222,8 +359,7 @@ func (s *Store) computeContentHash(srcDir string, files []string, manifestData [
 		h.Write([]byte{0}) // null separator
 
 		// Read and hash file content
-		filePath := filepath.Join(srcDir, file)
-		content, err := os.ReadFile(filePath)
+		content, err := read(file)
 		if err != nil {
 			return "", fmt.Errorf("failed to read %s: %w", file, err)
 		}
@@ -271,8 +407,31 @@ func copyFile(src, dst string) error {
 
 // Delete removes a package from the store
 func (s *Store) Delete(hash string) error {
-	if !s.Has(hash) {
-		return fmt.Errorf("package not found in store: %s", hash)
+	fl, err := s.Lock()
+	if err != nil {
+		return err
 	}
-	return os.RemoveAll(s.GetPath(hash))
+	defer fl.Release()
+
+	pkg, err := s.Get(hash)
+	if err != nil {
+		return err
+	}
+	return removeTree(filepath.Dir(pkg.FilesDir))
+}
+
+// removeTree removes dir and everything in it, first making what
+// MakeReadOnly made read-only writable again: on Unix a read-only
+// directory's entries can't be removed, and on Windows a directory with the
+// read-only attribute, as stored packages had before MakeReadOnly left
+// directories alone there, can't be removed itself
+func removeTree(dir string) error {
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.Mode()&os.ModeSymlink != 0 {
+			return nil
+		}
+		_ = os.Chmod(path, info.Mode().Perm()|0200)
+		return nil
+	})
+	return os.RemoveAll(dir)
 }
@@ -1,11 +1,27 @@
 package store
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
 )
 
+// skipIfRoot skips a test of read-only files as root, who can write them
+// anyway; Windows honors the read-only attribute for administrators too
+func skipIfRoot(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS != "windows" && os.Geteuid() == 0 {
+		t.Skip("root can write read-only files")
+	}
+}
+
 func TestNewStoreAt(t *testing.T) {
 	tmpDir := t.TempDir()
 	storeDir := filepath.Join(tmpDir, "test-store")
@@ -49,7 +65,7 @@ func TestStoreAndRetrieve(t *testing.T) {
 
 	// Store files
 	manifest := []byte(`{"name": "test", "version": "1.0.0"}`)
-	hash, err := s.Store(srcDir, []string{"test.py", "helper.py"}, manifest)
+	hash, err := s.Store(context.Background(), srcDir, []string{"test.py", "helper.py"}, manifest)
 	if err != nil {
 		t.Fatalf("Store failed: %v", err)
 	}
@@ -109,7 +125,7 @@ func TestStoreDeterministicHash(t *testing.T) {
 	manifest := []byte(`{"name": "test"}`)
 
 	// Store with files in one order
-	hash1, err := s.Store(srcDir, []string{"a.py", "b.py"}, manifest)
+	hash1, err := s.Store(context.Background(), srcDir, []string{"a.py", "b.py"}, manifest)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -117,7 +133,7 @@ func TestStoreDeterministicHash(t *testing.T) {
 	// Delete and store with files in different order
 	_ = s.Delete(hash1)
 
-	hash2, err := s.Store(srcDir, []string{"b.py", "a.py"}, manifest)
+	hash2, err := s.Store(context.Background(), srcDir, []string{"b.py", "a.py"}, manifest)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -128,7 +144,50 @@ func TestStoreDeterministicHash(t *testing.T) {
 	}
 }
 
+func TestContentHash(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcDir := filepath.Join(tmpDir, "src")
+	files := map[string]string{"utils.py": "def util(): pass", "lib/core.py": "x = 1"}
+	for name, content := range files {
+		path := filepath.Join(srcDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := NewStoreAt(filepath.Join(tmpDir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := []byte(`{"name": "utils"}`)
+	stored, err := s.Store(context.Background(), srcDir, []string{"utils.py", "lib/core.py"}, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same content read from elsewhere, such as a layer, hashes the same
+	hash, err := ContentHash([]string{"lib/core.py", "utils.py"}, func(file string) ([]byte, error) {
+		return []byte(files[file]), nil
+	}, manifest)
+	if err != nil || hash != stored {
+		t.Errorf("ContentHash() = %s, %v, want %s", hash, err, stored)
+	}
+
+	// Windows paths hash as their slash-separated form
+	if runtime.GOOS == "windows" {
+		hash, err := ContentHash([]string{`lib\core.py`, "utils.py"}, func(file string) ([]byte, error) {
+			return []byte(files[file]), nil
+		}, manifest)
+		if err != nil || hash != stored {
+			t.Errorf("ContentHash() of backslash paths = %s, %v, want %s", hash, err, stored)
+		}
+	}
+}
+
 func TestMakeReadOnly(t *testing.T) {
+	skipIfRoot(t)
 	tmpDir := t.TempDir()
 	storeDir := filepath.Join(tmpDir, "store")
 	srcDir := filepath.Join(tmpDir, "src")
@@ -146,7 +205,7 @@ func TestMakeReadOnly(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	hash, err := s.Store(srcDir, []string{"test.py"}, []byte(`{}`))
+	hash, err := s.Store(context.Background(), srcDir, []string{"test.py"}, []byte(`{}`))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -170,6 +229,63 @@ func TestMakeReadOnly(t *testing.T) {
 	_ = os.Chmod(testPath, 0644)
 }
 
+func TestMakeExecutable(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcDir := filepath.Join(tmpDir, "src")
+	if err := os.MkdirAll(filepath.Join(srcDir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"bin/cli.py", "bin/run.sh", "lib.py"} {
+		mode := os.FileMode(0644)
+		if f == "bin/run.sh" {
+			mode = 0755
+		}
+		if err := os.WriteFile(filepath.Join(srcDir, f), []byte(f), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := NewStoreAt(filepath.Join(tmpDir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := s.Store(context.Background(), srcDir, []string{"bin/cli.py", "bin/run.sh", "lib.py"}, []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MakeReadOnly(hash); err != nil {
+		t.Fatalf("MakeReadOnly failed: %v", err)
+	}
+	if err := s.MakeExecutable(hash, []string{"bin/cli.py"}); err != nil {
+		t.Fatalf("MakeExecutable failed: %v", err)
+	}
+
+	pkg, _ := s.Get(hash)
+	t.Cleanup(func() { _ = removeTree(pkg.FilesDir) })
+	want := map[string]os.FileMode{
+		"bin/cli.py": 0555,
+		"bin/run.sh": 0555, // already executable before MakeReadOnly
+		"lib.py":     0444,
+	}
+	for file, mode := range want {
+		info, err := os.Stat(filepath.Join(pkg.FilesDir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Windows only keeps the read-only attribute, as 0444
+		if runtime.GOOS == "windows" {
+			mode = 0444
+		}
+		if got := info.Mode().Perm(); got != mode {
+			t.Errorf("%s mode = %o, want %o", file, got, mode)
+		}
+	}
+
+	if err := s.MakeExecutable(hash, []string{"missing.py"}); err == nil {
+		t.Error("expected an error for a file that isn't in the package")
+	}
+}
+
 func TestListFiles(t *testing.T) {
 	tmpDir := t.TempDir()
 	storeDir := filepath.Join(tmpDir, "store")
@@ -191,7 +307,7 @@ func TestListFiles(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	hash, err := s.Store(srcDir, []string{"root.py", "subdir/nested.py"}, []byte(`{}`))
+	hash, err := s.Store(context.Background(), srcDir, []string{"root.py", "subdir/nested.py"}, []byte(`{}`))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -204,37 +320,109 @@ func TestListFiles(t *testing.T) {
 	if len(files) != 2 {
 		t.Errorf("ListFiles returned %d files, want 2", len(files))
 	}
+	// Slash-separated on every platform, as aigogo.lock lists them
+	if !slices.Contains(files, "subdir/nested.py") {
+		t.Errorf("ListFiles = %v, want subdir/nested.py in it", files)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcDir := filepath.Join(tmpDir, "src")
+	if err := os.MkdirAll(filepath.Join(srcDir, "subdir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "root.py"), []byte("root"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "subdir", "nested.py"), []byte("nested"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewStoreAt(filepath.Join(tmpDir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := s.Store(context.Background(), srcDir, []string{"root.py", filepath.Join("subdir", "nested.py")}, []byte(`{"name": "pkg"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Verify(hash); err != nil {
+		t.Fatalf("Verify of an untouched package failed: %v", err)
+	}
+	if err := s.Verify("sha256:" + hash); err != nil {
+		t.Errorf("Verify with the sha256: prefix failed: %v", err)
+	}
+
+	pkg, err := s.Get(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pkg.FilesDir, "root.py"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Verify(hash); err == nil {
+		t.Error("Verify should fail after a stored file changed")
+	}
+
+	if err := s.Verify(strings.Repeat("0", 64)); err == nil {
+		t.Error("Verify should fail for a package not in the store")
+	}
 }
 
 func TestGetPath(t *testing.T) {
 	s := &Store{rootDir: "/home/user/.aigogo/store"}
+	hash := "ab" + strings.Repeat("c", 62)
+	want := filepath.Join("/home/user/.aigogo/store", "sha256", "ab", hash)
 
-	tests := []struct {
-		hash string
-		want string
-	}{
-		{
-			hash: "abc123def456",
-			want: "/home/user/.aigogo/store/sha256/ab/abc123def456",
-		},
-		{
-			hash: "sha256:abc123def456",
-			want: "/home/user/.aigogo/store/sha256/ab/abc123def456",
-		},
-		{
-			hash: "xy",
-			want: "/home/user/.aigogo/store/sha256/xy/xy",
-		},
-	}
-
-	for _, tt := range tests {
-		got := s.GetPath(tt.hash)
-		if got != tt.want {
-			t.Errorf("GetPath(%q) = %q, want %q", tt.hash, got, tt.want)
+	for _, ref := range []string{hash, "sha256:" + hash} {
+		got, err := s.GetPath(ref)
+		if err != nil || got != want {
+			t.Errorf("GetPath(%q) = %q, %v; want %q", ref, got, err, want)
 		}
 	}
 }
 
+func TestInvalidHash(t *testing.T) {
+	s, err := NewStoreAt(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A directory with files/ outside the store, where a hash with .. would
+	// point
+	outside := filepath.Join(filepath.Dir(s.RootDir()), "secret")
+	if err := os.MkdirAll(filepath.Join(outside, "files"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hash := range []string{
+		"",
+		"abc123",
+		"sha256:../../../secret",
+		"sha256:" + strings.Repeat("A", 64),
+		"sha256:" + strings.Repeat("0", 63) + "/",
+		"sha256:" + strings.Repeat("0", 63) + `\`,
+		"sha256:" + strings.Repeat("0", 65),
+	} {
+		if _, err := s.GetPath(hash); errcode.Of(err) != errcode.Usage {
+			t.Errorf("GetPath(%q) = %v, want a usage error", hash, err)
+		}
+		if s.Has(hash) {
+			t.Errorf("Has(%q) = true", hash)
+		}
+		if _, err := s.Get(hash); errcode.Of(err) != errcode.Usage {
+			t.Errorf("Get(%q) = %v, want a usage error", hash, err)
+		}
+		if err := s.Delete(hash); errcode.Of(err) != errcode.Usage {
+			t.Errorf("Delete(%q) = %v, want a usage error", hash, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outside, "files")); err != nil {
+		t.Errorf("the directory outside the store was removed: %v", err)
+	}
+}
+
 func TestHasNonExistent(t *testing.T) {
 	tmpDir := t.TempDir()
 	s, err := NewStoreAt(filepath.Join(tmpDir, "store"))
@@ -258,13 +446,19 @@ func TestDelete(t *testing.T) {
 	if err := os.WriteFile(filepath.Join(srcDir, "test.py"), []byte("content"), 0644); err != nil {
 		t.Fatal(err)
 	}
+	if err := os.MkdirAll(filepath.Join(srcDir, "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "lib", "core.py"), []byte("x = 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
 
 	s, err := NewStoreAt(storeDir)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	hash, err := s.Store(srcDir, []string{"test.py"}, []byte(`{}`))
+	hash, err := s.Store(context.Background(), srcDir, []string{"test.py", "lib/core.py"}, []byte(`{}`))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -273,6 +467,11 @@ func TestDelete(t *testing.T) {
 		t.Fatal("Package not stored")
 	}
 
+	// Stored packages are read-only, which Delete undoes
+	if err := s.MakeReadOnly(hash); err != nil {
+		t.Fatal(err)
+	}
+
 	if err := s.Delete(hash); err != nil {
 		t.Fatalf("Delete failed: %v", err)
 	}
@@ -294,3 +493,32 @@ func TestDeleteNonExistent(t *testing.T) {
 		t.Error("Expected error when deleting non-existent package")
 	}
 }
+
+func TestStoreCanceled(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcDir := filepath.Join(tmpDir, "src")
+	if err := os.MkdirAll(srcDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "test.py"), []byte("print('hello')"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStoreAt(filepath.Join(tmpDir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	manifest := []byte(`{"name": "test", "version": "1.0.0"}`)
+	if _, err := s.Store(ctx, srcDir, []string{"test.py"}, manifest); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Store() with a canceled context = %v, want context.Canceled", err)
+	}
+	hash, err := s.computeContentHash(srcDir, []string{"test.py"}, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Has(hash) {
+		t.Error("a canceled Store left the package stored")
+	}
+}
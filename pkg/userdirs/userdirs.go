@@ -0,0 +1,140 @@
+// Package userdirs locates the directories aigg keeps its own files in:
+// the config directory (config.toml, auth.json, templates/), the data
+// directory (the package store and exec environments) and the cache
+// directory (the build/pull cache, completion cache and locks).
+//
+// On Linux and the BSDs they follow the XDG base directory specification:
+// $XDG_CONFIG_HOME/aigogo, $XDG_DATA_HOME/aigogo and $XDG_CACHE_HOME/aigogo,
+// which default to ~/.config/aigogo, ~/.local/share/aigogo and
+// ~/.cache/aigogo. Everything stays in ~/.aigogo on macOS and Windows, and
+// on any system where ~/.aigogo already exists, until 'aigg migrate' moves
+// it.
+package userdirs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// LegacyDirName is the directory in the home directory that held all of
+// aigg's files before the XDG layout, and still does where it exists
+const LegacyDirName = ".aigogo"
+
+// Base directory variables of the XDG specification, with the defaults
+// relative to the home directory that apply when they are unset
+var (
+	configHome = xdgVar{"XDG_CONFIG_HOME", ".config"}
+	dataHome   = xdgVar{"XDG_DATA_HOME", filepath.Join(".local", "share")}
+	cacheHome  = xdgVar{"XDG_CACHE_HOME", ".cache"}
+)
+
+type xdgVar struct {
+	name     string
+	fallback string
+}
+
+// dir returns v's directory for aigg, ignoring a relative value as the
+// specification requires
+func (v xdgVar) dir(home string) string {
+	if value := os.Getenv(v.name); value != "" && filepath.IsAbs(value) {
+		return filepath.Join(value, "aigogo")
+	}
+	return filepath.Join(home, v.fallback, "aigogo")
+}
+
+// LegacyDir returns ~/.aigogo, whether or not it exists
+func LegacyDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, LegacyDirName), nil
+}
+
+// SupportsXDG reports whether the XDG layout is used on this system when
+// there is no ~/.aigogo: everywhere but macOS and Windows
+func SupportsXDG() bool {
+	switch runtime.GOOS {
+	case "darwin", "ios", "windows", "plan9":
+		return false
+	}
+	return true
+}
+
+// UsesLegacy reports whether aigg's files are in ~/.aigogo: on systems
+// without the XDG layout, and where ~/.aigogo exists
+func UsesLegacy() (bool, error) {
+	legacy, err := LegacyDir()
+	if err != nil {
+		return false, err
+	}
+	if !SupportsXDG() {
+		return true, nil
+	}
+	info, err := os.Stat(legacy)
+	return err == nil && info.IsDir(), nil
+}
+
+// ConfigDir returns the directory of config.toml, auth.json and
+// templates/: $XDG_CONFIG_HOME/aigogo, or ~/.aigogo
+func ConfigDir() (string, error) {
+	return resolve(configHome)
+}
+
+// DataDir returns the directory of the package store (store/), exec
+// environments (envs/) and history.jsonl: $XDG_DATA_HOME/aigogo, or
+// ~/.aigogo
+func DataDir() (string, error) {
+	return resolve(dataHome)
+}
+
+// CacheDir returns the directory of the completion cache and locks:
+// $XDG_CACHE_HOME/aigogo, or ~/.aigogo
+func CacheDir() (string, error) {
+	return resolve(cacheHome)
+}
+
+// PackageCacheDir returns the default build/pull cache directory:
+// $XDG_CACHE_HOME/aigogo/packages, or ~/.aigogo/cache
+func PackageCacheDir() (string, error) {
+	legacy, err := UsesLegacy()
+	if err != nil {
+		return "", err
+	}
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	if legacy {
+		return filepath.Join(dir, "cache"), nil
+	}
+	return filepath.Join(dir, "packages"), nil
+}
+
+// XDGDirs returns the directories of the XDG layout, whichever layout is
+// in use, for moving ~/.aigogo into them
+func XDGDirs() (config, data, cache string, err error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return configHome.dir(home), dataHome.dir(home), cacheHome.dir(home), nil
+}
+
+// resolve returns ~/.aigogo in the legacy layout, otherwise v's directory
+func resolve(v xdgVar) (string, error) {
+	legacy, err := UsesLegacy()
+	if err != nil {
+		return "", err
+	}
+	if legacy {
+		return LegacyDir()
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return v.dir(home), nil
+}
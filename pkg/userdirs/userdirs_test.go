@@ -0,0 +1,91 @@
+package userdirs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setHome points HOME at a new directory, with the XDG variables unset
+func setHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // What os.UserHomeDir reads on Windows
+	for _, v := range []xdgVar{configHome, dataHome, cacheHome} {
+		t.Setenv(v.name, "")
+	}
+	return home
+}
+
+type dirs struct {
+	config, data, cache, packages string
+}
+
+func resolveAll(t *testing.T) dirs {
+	t.Helper()
+	var d dirs
+	var err error
+	if d.config, err = ConfigDir(); err != nil {
+		t.Fatal(err)
+	}
+	if d.data, err = DataDir(); err != nil {
+		t.Fatal(err)
+	}
+	if d.cache, err = CacheDir(); err != nil {
+		t.Fatal(err)
+	}
+	if d.packages, err = PackageCacheDir(); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestXDGLayout(t *testing.T) {
+	if !SupportsXDG() {
+		t.Skip("the XDG layout isn't used on this system")
+	}
+	home := setHome(t)
+
+	want := dirs{
+		config:   filepath.Join(home, ".config", "aigogo"),
+		data:     filepath.Join(home, ".local", "share", "aigogo"),
+		cache:    filepath.Join(home, ".cache", "aigogo"),
+		packages: filepath.Join(home, ".cache", "aigogo", "packages"),
+	}
+	if got := resolveAll(t); got != want {
+		t.Errorf("defaults = %+v, want %+v", got, want)
+	}
+
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(xdg, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(xdg, "data"))
+	t.Setenv("XDG_CACHE_HOME", "relative/cache") // Ignored, as the specification says
+	want = dirs{
+		config:   filepath.Join(xdg, "config", "aigogo"),
+		data:     filepath.Join(xdg, "data", "aigogo"),
+		cache:    filepath.Join(home, ".cache", "aigogo"),
+		packages: filepath.Join(home, ".cache", "aigogo", "packages"),
+	}
+	if got := resolveAll(t); got != want {
+		t.Errorf("with XDG variables = %+v, want %+v", got, want)
+	}
+}
+
+func TestLegacyLayout(t *testing.T) {
+	home := setHome(t)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(t.TempDir(), "config"))
+	legacy := filepath.Join(home, LegacyDirName)
+	if err := os.Mkdir(legacy, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := UsesLegacy(); err != nil || !ok {
+		t.Fatalf("UsesLegacy() = %v, %v with ~/.aigogo", ok, err)
+	}
+	// ~/.aigogo wins over the XDG variables
+	want := dirs{config: legacy, data: legacy, cache: legacy, packages: filepath.Join(legacy, "cache")}
+	if got := resolveAll(t); got != want {
+		t.Errorf("dirs = %+v, want %+v", got, want)
+	}
+}
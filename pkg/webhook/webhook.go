@@ -0,0 +1,218 @@
+// Package webhook notifies URLs of the packages aigg pushes to and deletes
+// from registries, as [[webhooks]] in config.toml configure them, so chat
+// channels and automation learn about new versions
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aupeachmo/aigogo/pkg/errcode"
+	"github.com/aupeachmo/aigogo/pkg/logging"
+)
+
+// Events a webhook can be sent for
+const (
+	EventPush   = "push"
+	EventDelete = "delete"
+)
+
+// Timeout is how long a webhook's URL has to answer
+const Timeout = 10 * time.Second
+
+// Hook is a URL to notify, a [[webhooks]] table of config.toml
+type Hook struct {
+	URL         string            `toml:"url"`                    // $VARS are expanded, so secrets can stay in the environment
+	Events      []string          `toml:"events,omitempty"`       // push and/or delete; both when unset
+	Template    string            `toml:"template,omitempty"`     // text/template of the request body, given the Event; the Event as JSON when unset
+	ContentType string            `toml:"content_type,omitempty"` // of the body; application/json when unset
+	Headers     map[string]string `toml:"headers,omitempty"`      // sent with the request; $VARS are expanded in values
+}
+
+// Validate checks that the hook has an http(s) URL, known events and a
+// template that parses
+func (h *Hook) Validate() error {
+	if !strings.HasPrefix(h.URL, "https://") && !strings.HasPrefix(h.URL, "http://") && !strings.HasPrefix(h.URL, "$") {
+		return fmt.Errorf("invalid webhooks url: %q (expected an http:// or https:// URL, or $VAR)", h.URL)
+	}
+	for _, event := range h.Events {
+		if event != EventPush && event != EventDelete {
+			return fmt.Errorf("invalid webhooks event: %q (expected %s or %s)", event, EventPush, EventDelete)
+		}
+	}
+	if _, err := h.template(); err != nil {
+		return fmt.Errorf("invalid webhooks template: %w", err)
+	}
+	return nil
+}
+
+// Wants reports whether the hook is sent for event
+func (h *Hook) Wants(event string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// templateFuncs are the functions templates can call besides the built-in
+// ones: json quotes a value for a JSON body
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// template returns the hook's template, or nil when it has none
+func (h *Hook) template() (*template.Template, error) {
+	if h.Template == "" {
+		return nil, nil
+	}
+	return template.New("webhook").Funcs(templateFuncs).Option("missingkey=error").Parse(h.Template)
+}
+
+// Event is what a webhook reports, and what its template is given
+type Event struct {
+	Event      string       `json:"event"` // EventPush or EventDelete
+	Ref        string       `json:"ref"`
+	Registry   string       `json:"registry"`
+	Repository string       `json:"repository"`
+	Tag        string       `json:"tag"`
+	Digest     string       `json:"digest,omitempty"` // of the manifest pushed or deleted
+	Name       string       `json:"name,omitempty"`   // from the package's aigogo.json, for pushes
+	Version    string       `json:"version,omitempty"`
+	Previous   string       `json:"previous,omitempty"` // the version tag Diff compares with
+	Diff       *DiffSummary `json:"diff,omitempty"`
+	Summary    string       `json:"summary"` // one line for people, e.g. for a chat message
+	Time       time.Time    `json:"time"`
+	Tool       string       `json:"tool"` // "aigg <version>"
+}
+
+// DiffSummary is how a pushed package differs from the previous version
+type DiffSummary struct {
+	Added      int          `json:"added"`
+	Removed    int          `json:"removed"`
+	Modified   int          `json:"modified"`
+	Insertions int          `json:"insertions"`
+	Deletions  int          `json:"deletions"`
+	Files      []FileChange `json:"files"`
+}
+
+// FileChange is a file that differs from the previous version
+type FileChange struct {
+	Path       string `json:"path"`
+	Status     string `json:"status"` // "added", "removed" or "modified"
+	Insertions int    `json:"insertions"`
+	Deletions  int    `json:"deletions"`
+}
+
+// Body returns the request body of the hook for e, and its content type
+func (h *Hook) Body(e *Event) ([]byte, string, error) {
+	contentType := h.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	tmpl, err := h.template()
+	if err != nil {
+		return nil, "", err
+	}
+	if tmpl == nil {
+		data, err := json.Marshal(e)
+		return data, contentType, err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, e); err != nil {
+		return nil, "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return b.Bytes(), contentType, nil
+}
+
+// Sender posts events to hooks
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender returns a sender whose requests time out after Timeout
+func NewSender() *Sender {
+	return &Sender{client: logging.NewHTTPClient(Timeout)}
+}
+
+// Send posts e to each hook that wants it, and returns the failures, one
+// per hook. A hook's URL is never in an error, as it's often a secret.
+func (s *Sender) Send(ctx context.Context, hooks []Hook, e *Event) []error {
+	var errs []error
+	for i := range hooks {
+		h := &hooks[i]
+		if !h.Wants(e.Event) {
+			continue
+		}
+		if err := s.send(ctx, h, e); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %d (%s): %w", i+1, redactURL(h.URL), err))
+		}
+	}
+	return errs
+}
+
+func (s *Sender) send(ctx context.Context, h *Hook, e *Event) error {
+	body, contentType, err := h.Body(e)
+	if err != nil {
+		return err
+	}
+	url := os.ExpandEnv(h.URL)
+	if url == "" {
+		return errcode.Errorf(errcode.Config, "url %s expands to nothing", h.URL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return errcode.Errorf(errcode.Config, "invalid url")
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", e.Tool)
+	req.Header.Set("X-Aigogo-Event", e.Event)
+	for name, value := range h.Headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		code := errcode.Of(err)
+		// A *url.Error quotes the URL
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return errcode.Errorf(code, "request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return errcode.Errorf(errcode.FromHTTPStatus(resp.StatusCode), "%s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
+
+// redactURL returns the scheme and host of a hook's URL, without the path
+// and query that usually carry its secret
+func redactURL(url string) string {
+	if strings.HasPrefix(url, "$") {
+		return url
+	}
+	scheme, rest, _ := strings.Cut(url, "://")
+	host, _, _ := strings.Cut(rest, "/")
+	return scheme + "://" + host + "/..."
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// received is a request a test server got
+type received struct {
+	path, contentType, auth, event string
+	body                           string
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, func() []received) {
+	t.Helper()
+	var mu sync.Mutex
+	var got []received
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		got = append(got, received{r.URL.Path, r.Header.Get("Content-Type"), r.Header.Get("Authorization"), r.Header.Get("X-Aigogo-Event"), string(body)})
+		mu.Unlock()
+		if r.URL.Path == "/broken/secret-token" {
+			http.Error(w, "no such hook", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []received {
+		mu.Lock()
+		defer mu.Unlock()
+		return got
+	}
+}
+
+func TestSend(t *testing.T) {
+	srv, requests := newTestServer(t)
+	t.Setenv("HOOK_TOKEN", "s3cret")
+	t.Setenv("HOOK_URL", srv.URL+"/from-env")
+	hooks := []Hook{
+		{URL: srv.URL + "/json"},
+		{URL: "$HOOK_URL", Events: []string{EventPush}, Template: `{"text": {{json .Summary}}}`, Headers: map[string]string{"Authorization": "Bearer ${HOOK_TOKEN}"}},
+		{URL: srv.URL + "/deletes", Events: []string{EventDelete}},
+	}
+	e := &Event{Event: EventPush, Ref: "ghcr.io/org/utils:1.1.0", Summary: `Pushed "utils"`, Tool: "aigg/test",
+		Diff: &DiffSummary{Modified: 1, Files: []FileChange{{Path: "utils.py", Status: "modified", Insertions: 2}}}}
+	if errs := NewSender().Send(context.Background(), hooks, e); len(errs) != 0 {
+		t.Fatalf("Send() = %v", errs)
+	}
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("got %d requests, want 2 (the delete hook isn't sent pushes): %+v", len(got), got)
+	}
+	var payload Event
+	if err := json.Unmarshal([]byte(got[0].body), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if got[0].path != "/json" || got[0].contentType != "application/json" || got[0].event != EventPush ||
+		payload.Ref != e.Ref || payload.Diff == nil || payload.Diff.Files[0].Path != "utils.py" {
+		t.Errorf("default payload = %+v, %s", got[0], got[0].body)
+	}
+	if got[1].path != "/from-env" || got[1].auth != "Bearer s3cret" || got[1].body != `{"text": "Pushed \"utils\""}` {
+		t.Errorf("templated request = %+v", got[1])
+	}
+}
+
+func TestSendFailure(t *testing.T) {
+	srv, _ := newTestServer(t)
+	hooks := []Hook{{URL: srv.URL + "/broken/secret-token"}, {URL: "$UNSET_HOOK_URL"}}
+	errs := NewSender().Send(context.Background(), hooks, &Event{Event: EventDelete, Ref: "ghcr.io/org/utils:1.0.0"})
+	if len(errs) != 2 {
+		t.Fatalf("Send() = %v, want two errors", errs)
+	}
+	if msg := errs[0].Error(); !strings.Contains(msg, "404") || strings.Contains(msg, "secret-token") {
+		t.Errorf("error = %q, want the status without the URL's path", msg)
+	}
+	if msg := errs[1].Error(); !strings.Contains(msg, "expands to nothing") {
+		t.Errorf("error = %q", msg)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []Hook{
+		{URL: "https://hooks.example.com/x"},
+		{URL: "$HOOK", Events: []string{EventPush, EventDelete}, Template: `{{.Ref}} {{json .Diff}}`},
+	}
+	for _, h := range valid {
+		if err := h.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", h, err)
+		}
+	}
+	invalid := []Hook{
+		{URL: "ftp://hooks.example.com"},
+		{URL: "https://hooks.example.com", Events: []string{"build"}},
+		{URL: "https://hooks.example.com", Template: "{{.Ref"},
+	}
+	for _, h := range invalid {
+		if err := h.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want an error", h)
+		}
+	}
+
+	// A field the event doesn't have fails when the body is rendered
+	h := Hook{URL: "https://hooks.example.com", Template: "{{.Commit}}"}
+	if _, _, err := h.Body(&Event{}); err == nil {
+		t.Error("Body() with an unknown field = nil, want an error")
+	}
+}
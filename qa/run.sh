@@ -24,6 +24,17 @@ if [[ ! -x "$AIGOGO" ]]; then
     exit 1
 fi
 
+###############################################################################
+# aigg's own directories: ~/.aigogo where it exists (and on macOS), else the
+# XDG ones. The separate HOMEs below get the XDG defaults inside them.
+###############################################################################
+unset XDG_CONFIG_HOME XDG_DATA_HOME XDG_CACHE_HOME
+if [[ -d "$HOME/.aigogo" || "$(uname)" == Darwin ]]; then
+    AIGG_CONFIG_DIR="$HOME/.aigogo"; AIGG_DATA_DIR="$HOME/.aigogo"
+else
+    AIGG_CONFIG_DIR="$HOME/.config/aigogo"; AIGG_DATA_DIR="$HOME/.local/share/aigogo"
+fi
+
 ###############################################################################
 # Colours / formatting
 ###############################################################################
@@ -128,7 +139,7 @@ run_test_grep() {
     local out
     out="$("$@" 2>&1)" || true
     echo "$out" >>"$LOGFILE"
-    if echo "$out" | grep -qE -- "$pattern"; then
+    if grep -qE -- "$pattern" <<<"$out"; then
         PASSED=$((PASSED + 1))
         printf "${GREEN}PASS${RESET}  %s\n" "$desc"
     else
@@ -145,7 +156,7 @@ run_test_fail_grep() {
     local out; local rc=0
     out="$("$@" 2>&1)" || rc=$?
     echo "$out" >>"$LOGFILE"
-    if [[ $rc -ne 0 ]] && echo "$out" | grep -qE -- "$pattern"; then
+    if [[ $rc -ne 0 ]] && grep -qE -- "$pattern" <<<"$out"; then
         PASSED=$((PASSED + 1))
         printf "${GREEN}PASS${RESET}  %s\n" "$desc"
     else
@@ -261,6 +272,101 @@ echo "${BOLD}=== Utilities ===${RESET}"
 run_test_grep "aigg version" "aigg version" \
     "$AIGOGO" version
 
+VERSION_DIR="$WORK/version-bump"
+create_python_project "$VERSION_DIR"
+pushd "$VERSION_DIR" >/dev/null
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+
+run_test_grep "aigg version minor" "0.1.0 -> 0.2.0" \
+    "$AIGOGO" version minor
+
+run_test_grep "aigg version <explicit> — prints build command" "aigg build .*:1.0.0-rc.1" \
+    "$AIGOGO" version 1.0.0-rc.1
+
+run_test_grep "aigg version major — releases the pre-release" "1.0.0-rc.1 -> 1.0.0" \
+    "$AIGOGO" version major
+
+run_test_fail_grep "aigg version <older> -> error" "not greater" \
+    "$AIGOGO" version 0.9.0
+
+run_test_fail_grep "aigg version <not semver> -> error" "invalid semver" \
+    "$AIGOGO" version 1.2
+
+if command -v git >/dev/null 2>&1; then
+    git init -q . && git config user.email qa@example.com && git config user.name qa
+    run_test_grep "aigg version patch --git" "tagged v1.0.1" \
+        "$AIGOGO" version patch --git
+    run_test "aigg version --git — tag exists" \
+        git rev-parse -q --verify refs/tags/v1.0.1
+
+    run_test_grep "aigg hooks install" "Installed pre-push hook" \
+        "$AIGOGO" hooks install
+    run_test "aigg hooks install — hooks are executable and call aigg" \
+        bash -c 'test -x .git/hooks/pre-commit && grep -q "aigg hooks run pre-push" .git/hooks/pre-push'
+
+    printf 'import requests\n' > needs_requests.py
+    git add needs_requests.py
+    run_test_fail_grep "aigg hooks run pre-commit — missing dependency fails" "failed the pre-commit checks" \
+        "$AIGOGO" hooks run pre-commit
+    git rm -q --cached needs_requests.py && rm -f needs_requests.py
+
+    run_test "aigg hooks run pre-push — no aigogo.lock passes" \
+        "$AIGOGO" hooks run pre-push
+
+    printf '#!/bin/sh\nmake lint\n' > .git/hooks/pre-push
+    run_test_fail_grep "aigg hooks install — keeps a hook aigg didn't write" "didn't install" \
+        "$AIGOGO" hooks install
+    run_test_grep "aigg hooks install --force" "Installed pre-push hook" \
+        "$AIGOGO" hooks install --force
+
+    run_test_grep "aigg hooks install --pre-commit-config" "Wrote .*\.pre-commit-config\.yaml" \
+        "$AIGOGO" hooks install --pre-commit-config
+    run_test_grep "aigg hooks install --pre-commit-config — already there" "already runs aigg's hooks" \
+        "$AIGOGO" hooks install --pre-commit-config
+else
+    skip_test "aigg version patch --git (git not installed)"
+    skip_test "aigg hooks (git not installed)"
+fi
+popd >/dev/null
+
+# Plugins: aigg foo runs aigg-foo from PATH
+PLUGIN_DIR="$WORK/plugins"
+mkdir -p "$PLUGIN_DIR"
+cat > "$PLUGIN_DIR/aigg-hello" <<'PLUGIN'
+#!/bin/sh
+echo "hello args: $*"
+echo "level: $AIGOGO_LOG_LEVEL"
+echo "plain: $AIGOGO_PLAIN"
+echo "bin: $AIGOGO_BIN"
+exit 3
+PLUGIN
+chmod +x "$PLUGIN_DIR/aigg-hello"
+
+run_test_grep "aigg <plugin> — runs aigg-<plugin> with its arguments" "hello args: one -v --x" \
+    bash -c 'PATH="$1:$PATH" "$0" hello one -v --x; true' "$AIGOGO" "$PLUGIN_DIR"
+
+run_test_grep "aigg <plugin> — global options before it set AIGOGO_LOG_LEVEL" "level: quiet" \
+    bash -c 'PATH="$1:$PATH" "$0" -q hello; true' "$AIGOGO" "$PLUGIN_DIR"
+
+run_test_grep "aigg --plain <plugin> — sets AIGOGO_PLAIN" "plain: true" \
+    bash -c 'PATH="$1:$PATH" "$0" --plain hello; true' "$AIGOGO" "$PLUGIN_DIR"
+
+run_test "aigg <plugin> — exits with the plugin's exit code" \
+    bash -c 'PATH="$1:$PATH" "$0" hello >/dev/null; test $? -eq 3' "$AIGOGO" "$PLUGIN_DIR"
+
+run_test_grep "aigg help — lists plugins" "^  hello$" \
+    bash -c 'PATH="$1:$PATH" "$0"' "$AIGOGO" "$PLUGIN_DIR"
+
+run_test_fail_grep "aigg <unknown> — neither a command nor a plugin" "unknown command: nosuchplugin" \
+    "$AIGOGO" nosuchplugin
+
+# Self-updates reach GitHub, so only argument checks run here
+run_test_fail_grep "aigg self-update <two versions> -> usage" "usage: aigg self-update" \
+    "$AIGOGO" self-update v1.0.0 v1.0.1
+
+run_test_grep "aigg schema" '"title": "aigogo Manifest"' \
+    "$AIGOGO" schema
+
 run_test_grep "aigg completion bash" "_aigg_completions" \
     "$AIGOGO" completion bash
 
@@ -289,6 +395,96 @@ run_test "aigg init — aigogo.json exists" test -f aigogo.json
 
 popd >/dev/null
 
+# --- init from an existing project manifest ---
+INIT_DETECT_DIR="$WORK/init-detect"
+mkdir -p "$INIT_DETECT_DIR"
+pushd "$INIT_DETECT_DIR" >/dev/null
+cat > pyproject.toml <<'TOMLEOF'
+[project]
+name = "qa-detected"
+version = "2.3.0"
+description = "Detected from pyproject.toml"
+authors = [{name = "QA", email = "qa@example.com"}]
+requires-python = ">=3.10"
+dependencies = ["requests>=2.31"]
+TOMLEOF
+
+run_test_grep "aigg init — detects pyproject.toml" "Found pyproject.toml" \
+    "$AIGOGO" init < /dev/null
+
+run_test "aigg init — pre-fills name and version" \
+    grep -q '"version": "2.3.0"' aigogo.json
+
+run_test_fail "aigg init — doesn't import deps without asking" \
+    grep -q '"requests"' aigogo.json
+
+rm -f aigogo.json
+run_test_grep "aigg init --import-deps — imports deps" "Imported 1 runtime" \
+    "$AIGOGO" init --import-deps
+
+run_test "aigg init --import-deps — dependency in aigogo.json" \
+    grep -q '"requests"' aigogo.json
+
+rm -f aigogo.json
+"$AIGOGO" init --no-detect >>"$LOGFILE" 2>&1
+run_test_fail "aigg init --no-detect — ignores pyproject.toml" \
+    grep -q 'qa-detected' aigogo.json
+
+popd >/dev/null
+
+# --- init wizard (needs util-linux script for a terminal) ---
+INIT_WIZARD_DIR="$WORK/init-wizard"
+mkdir -p "$INIT_WIZARD_DIR"
+pushd "$INIT_WIZARD_DIR" >/dev/null
+echo 'package main' > main.go
+if script --version 2>/dev/null | grep -q util-linux; then
+    run_test_grep "aigg init in a terminal — asks and shows a summary" "Summary:" \
+        bash -c "printf 'qa-wizard\n1.0.0\n\n\n\n\n\n\n' | script -qec '$AIGOGO init' /dev/null"
+    run_test "aigg init in a terminal — writes the answers and the detected language" \
+        bash -c "grep -q '\"name\": \"qa-wizard\"' aigogo.json && grep -q '\"name\": \"go\"' aigogo.json"
+    run_test "aigg init in a terminal — writes .aigogoignore" \
+        grep -q '_test.go' .aigogoignore
+    run_test_grep "aigg init in a terminal — asks before overwriting" "Nothing written" \
+        bash -c "printf 'n\n' | script -qec '$AIGOGO init' /dev/null"
+    run_test_grep "aigg init --yes in a terminal — doesn't ask" "Initialized aigogo package" \
+        bash -c "script -qec '$AIGOGO init --yes' /dev/null < /dev/null"
+else
+    skip_test "aigg init wizard (needs util-linux script)"
+fi
+popd >/dev/null
+
+# --- init --template ---
+TEMPLATE_DIR="$WORK/init-template"
+mkdir -p "$TEMPLATE_DIR"
+pushd "$TEMPLATE_DIR" >/dev/null
+
+run_test_grep "aigg init --template python-lib" "Created __init__.py" \
+    "$AIGOGO" init --template python-lib
+
+run_test "aigg init --template python-lib — .aigogoignore created" test -f .aigogoignore
+
+run_test_grep "aigg init --template python-lib — validate passes" "Validation passed" \
+    "$AIGOGO" validate
+
+run_test_fail_grep "aigg init --template — refuses to overwrite" "would overwrite existing files" \
+    "$AIGOGO" init --template python-lib
+
+run_test_fail_grep "aigg init --template nope — lists templates" "available: .*prompt-pack" \
+    "$AIGOGO" init --template nope
+
+popd >/dev/null
+
+USER_TEMPLATE_DIR="$WORK/init-user-template"
+mkdir -p "$USER_TEMPLATE_DIR" "$AIGG_CONFIG_DIR/templates/qa-template"
+cat > "$AIGG_CONFIG_DIR/templates/qa-template/aigogo.json.tmpl" <<'EOF'
+{"name": "{{.Name}}", "version": "0.1.0", "language": {"name": "python", "version": ">=3.8"}}
+EOF
+pushd "$USER_TEMPLATE_DIR" >/dev/null
+run_test_grep "aigg init --template <user template>" "from template qa-template" \
+    "$AIGOGO" init --template qa-template
+popd >/dev/null
+rm -rf "$AIGG_CONFIG_DIR/templates/qa-template"
+
 # --- add file ---
 PY_DIR="$WORK/author-py"
 create_python_project "$PY_DIR"
@@ -318,6 +514,13 @@ EOF
 run_test_grep "aigg add file <glob>" "Added 1 file" \
     "$AIGOGO" add file "glob*.py"
 
+# nested .aigogoignore: patterns are relative to its own directory
+mkdir -p "$PY_DIR/gen"
+printf '/*.py\n' > "$PY_DIR/gen/.aigogoignore"
+printf 'pass\n' > "$PY_DIR/gen/out.py"
+run_test_grep "aigg add file (nested .aigogoignore)" "ignored by gen/\.aigogoignore:1: /\*\.py" \
+    "$AIGOGO" add file gen/out.py
+
 popd >/dev/null
 
 # --- add dep / add dev ---
@@ -332,6 +535,135 @@ run_test_grep "aigg add dep <pkg> <ver>" "Added requests" \
 run_test_grep "aigg add dev <pkg> <ver>" "Added pytest" \
     "$AIGOGO" add dev pytest ">=7.0.0"
 
+printf 'click>=8.0\n-r base-requirements.txt\ntomli>=2.0; python_version < "3.11"\n' > requirements.txt
+printf 'urllib3>=1.26\n' > base-requirements.txt
+printf 'black>=24.0\n' > requirements-dev.txt
+
+run_test_grep "aigg add dep --from-requirements (-r include)" "Added urllib3" \
+    "$AIGOGO" add dep --from-requirements
+
+run_test_grep "aigg add dep --from-requirements (marker kept)" "tomli>=2.0; python_version < '3.11'" \
+    "$AIGOGO" show-deps . --format requirements
+
+run_test_grep "aigg add dep <pkg[extras]> <ver>" "Added httpx" \
+    "$AIGOGO" add dep "httpx[http2]" ">=0.27"
+
+run_test_grep "aigg show-deps --format requirements (extras)" "httpx\[http2\]>=0.27" \
+    "$AIGOGO" show-deps . --format requirements
+
+run_test_grep "aigg show-deps --format poetry (extras)" 'httpx = \{ version = ">=0.27", extras = \["http2"\] \}' \
+    "$AIGOGO" show-deps . --format poetry
+
+run_test_grep "aigg add dev --from-requirements" "Added black" \
+    "$AIGOGO" add dev --from-requirements
+
+popd >/dev/null
+
+LOCK_DEP_DIR="$WORK/author-dep-lock"
+create_python_project "$LOCK_DEP_DIR"
+pushd "$LOCK_DEP_DIR" >/dev/null
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+cat > pyproject.toml <<'TOMLEOF'
+[project]
+name = "qa-lock"
+version = "0.1.0"
+dependencies = ["requests>=2"]
+TOMLEOF
+cat > uv.lock <<'TOMLEOF'
+version = 1
+
+[[package]]
+name = "requests"
+version = "2.32.3"
+source = { registry = "https://pypi.org/simple" }
+TOMLEOF
+
+run_test_grep "aigg add dep --from-lock --compatible" "Added requests >=2.32.3,<3.0.0" \
+    "$AIGOGO" add dep --from-lock --compatible
+
+run_test_fail_grep "aigg add dep --compatible without --from-lock -> error" "only be used with --from-lock" \
+    "$AIGOGO" add dep --compatible
+
+cat > environment.yml <<'YMLEOF'
+name: qa-conda
+dependencies:
+  - python=3.10
+  - pytorch=2.1
+  - cudatoolkit=11.8
+  - pip:
+      - httpx>=0.27
+YMLEOF
+
+run_test_grep "aigg add dep --from-conda (name translation)" "Added torch ==2.1" \
+    "$AIGOGO" add dep --from-conda environment.yml
+
+run_test_grep "show-deps --format conda" "pytorch=2.1" \
+    "$AIGOGO" show-deps . --format conda
+
+popd >/dev/null
+
+# --- add dep --from-cargo / --from-gomod ---
+RUST_DEP_DIR="$WORK/author-dep-rust"
+mkdir -p "$RUST_DEP_DIR"
+pushd "$RUST_DEP_DIR" >/dev/null
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['language'] = {'name': 'rust', 'version': '1.70'}
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+cat > Cargo.toml <<'TOMLEOF'
+[package]
+name = "qa-agent"
+version = "0.1.0"
+
+[dependencies]
+serde = { version = "1.0", features = ["derive"] }
+helper = { path = "../helper" }
+
+[dev-dependencies]
+pretty_assertions = "1.4"
+TOMLEOF
+
+run_test_grep "aigg add dep --from-cargo" "Added serde 1.0" \
+    "$AIGOGO" add dep --from-cargo
+
+run_test_grep "aigg add dev --from-cargo" "Added pretty_assertions" \
+    "$AIGOGO" add dev --from-cargo
+
+run_test_fail_grep "aigg add dep --from-gomod on Rust project -> error" "only supported for go" \
+    "$AIGOGO" add dep --from-gomod
+
+popd >/dev/null
+
+GO_DEP_DIR="$WORK/author-dep-go"
+mkdir -p "$GO_DEP_DIR"
+pushd "$GO_DEP_DIR" >/dev/null
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['language'] = {'name': 'go', 'version': '1.22'}
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+cat > go.mod <<'GOEOF'
+module example.com/qa-agent
+
+go 1.22
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/sync v0.6.0 // indirect
+)
+GOEOF
+
+run_test_grep "aigg add dep --from-gomod" "Added github.com/pkg/errors" \
+    "$AIGOGO" add dep --from-gomod
+
+run_test_fail_grep "aigg add dev --from-gomod -> error" "no development dependencies" \
+    "$AIGOGO" add dev --from-gomod
+
 popd >/dev/null
 
 # --- rm ---
@@ -355,6 +687,58 @@ run_test_grep "aigg rm dev <pkg>" "Removed pytest" \
 
 popd >/dev/null
 
+# --- files freeze ---
+FREEZE_DIR="$WORK/author-freeze"
+create_python_project "$FREEZE_DIR"
+pushd "$FREEZE_DIR" >/dev/null
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+python3 -c "
+import json
+m = json.load(open('aigogo.json'))
+m['files']['include'] = 'auto'
+json.dump(m, open('aigogo.json', 'w'), indent=2)
+"
+
+run_test_fail_grep "aigg add file (auto include) suggests freeze" "aigg files freeze" \
+    "$AIGOGO" add file utils.py
+
+run_test_grep "aigg files freeze --dry-run" "resolves to 2 file" \
+    "$AIGOGO" files freeze --dry-run
+
+run_test_grep "aigg files freeze --dry-run (unchanged)" '"include": "auto"' \
+    cat aigogo.json
+
+run_test_grep "aigg files freeze (declined)" "Operation cancelled" \
+    bash -c "echo no | '$AIGOGO' files freeze"
+
+run_test_grep "aigg files freeze --force" "Froze files.include to 2 file" \
+    "$AIGOGO" files freeze --force
+
+run_test_grep "aigg rm file (after freeze)" "Removed 1 file" \
+    "$AIGOGO" rm file helpers.py
+
+run_test_grep "aigg files freeze (already explicit)" "already an explicit list" \
+    "$AIGOGO" files freeze --force
+
+# --- check-ignore ---
+printf '*.log\n' > .aigogoignore
+run_test_grep "aigg check-ignore (included)" "utils.py: included" \
+    "$AIGOGO" check-ignore utils.py
+
+run_test_grep "aigg check-ignore (not in files.include)" "helpers.py: not ignored, but files.include" \
+    "$AIGOGO" check-ignore helpers.py
+
+run_test_grep "aigg check-ignore (.aigogoignore file:line)" "app.log: excluded by \.aigogoignore:1: \*\.log" \
+    "$AIGOGO" check-ignore app.log
+
+run_test_grep "aigg check-ignore (built-in default)" "inside directory node_modules/ excluded by built-in default" \
+    "$AIGOGO" check-ignore node_modules/x.js
+
+run_test_fail_grep "aigg check-ignore outside the package -> error" "outside the package directory" \
+    "$AIGOGO" check-ignore ../elsewhere.py
+
+popd >/dev/null
+
 # --- scan ---
 SCAN_DIR="$WORK/author-scan"
 create_python_project "$SCAN_DIR"
@@ -365,6 +749,30 @@ pushd "$SCAN_DIR" >/dev/null
 run_test_grep "aigg scan" "Scanning source files" \
     "$AIGOGO" scan
 
+run_test_grep "aigg scan --offline" "Scanning source files" \
+    "$AIGOGO" scan --offline
+
+cat > analysis.ipynb <<'NBEOF'
+{"cells": [{"cell_type": "code", "metadata": {}, "outputs": [], "source": ["import pandas as pd\n"]}],
+ "metadata": {}, "nbformat": 4, "nbformat_minor": 5}
+NBEOF
+"$AIGOGO" add file analysis.ipynb >>"$LOGFILE" 2>&1
+
+run_test_grep "aigg scan (notebook cell provenance)" "analysis.ipynb cell 1" \
+    "$AIGOGO" scan --offline
+
+run_test "aigg scan — .aigogo/scan-cache.json written" test -f .aigogo/scan-cache.json
+
+run_test_grep "aigg scan --no-cache" "analysis.ipynb cell 1" \
+    "$AIGOGO" scan --offline --no-cache
+
+run_test_grep "aigg licenses --offline (no lock file)" "Resolving licenses" \
+    "$AIGOGO" licenses --offline
+
+"$AIGOGO" add dep requests ">=2.0" >>"$LOGFILE" 2>&1
+run_test_fail_grep "aigg licenses --allow (unknown license fails)" "policy violation" \
+    "$AIGOGO" licenses --offline --allow MIT
+
 popd >/dev/null
 
 # --- validate ---
@@ -377,6 +785,153 @@ pushd "$VAL_DIR" >/dev/null
 run_test_grep "aigg validate" "Validating manifest" \
     "$AIGOGO" validate
 
+run_test_grep "aigg validate --no-cache" "Validation passed" \
+    "$AIGOGO" validate --no-cache
+
+run_test_grep "aigg validate --format sarif" '"version": "2.1.0"' \
+    "$AIGOGO" validate --format sarif
+
+run_test_fail_grep "aigg validate --format bogus -> error" "unsupported format" \
+    "$AIGOGO" validate --format bogus
+
+run_test_grep "aigg validate --schema" "Schema validation passed" \
+    "$AIGOGO" validate --schema
+
+cp aigogo.json aigogo.json.bak
+python3 -c "
+import json
+m = json.load(open('aigogo.json'))
+m['licence'] = 'MIT'
+m['language']['name'] = 'pyton'
+json.dump(m, open('aigogo.json', 'w'), indent=2)
+"
+run_test_fail_grep "aigg validate --schema (unknown field, bad enum)" "aigogo.json:[0-9]+: licence: unknown field" \
+    "$AIGOGO" validate --schema
+mv aigogo.json.bak aigogo.json
+
+# JSONC: comments and trailing commas are accepted, and dropped on save
+cp aigogo.json aigogo.json.bak
+python3 -c "
+text = open('aigogo.json').read().rstrip()
+open('aigogo.json', 'w').write('// QA package\n' + text[:-1].rstrip() + ', /* trailing */\n}\n')
+"
+run_test_grep "aigg validate (JSONC manifest)" "Validation passed" \
+    "$AIGOGO" validate
+
+run_test_grep "aigg validate --schema (JSONC manifest)" "Schema validation passed" \
+    "$AIGOGO" validate --schema
+
+run_test_grep "aigg add dev (JSONC manifest rewritten)" "comments and trailing commas were removed" \
+    "$AIGOGO" add dev pytest ">=7.0"
+
+run_test_fail "aigg add dev (JSONC comments gone)" \
+    grep -q "QA package" aigogo.json
+mv aigogo.json.bak aigogo.json
+
+# aigogoVersion: manifests needing a newer aigogo are refused
+cp aigogo.json aigogo.json.bak
+python3 -c "
+import json
+m = json.load(open('aigogo.json'))
+m['aigogoVersion'] = '>=999'
+json.dump(m, open('aigogo.json', 'w'), indent=2)
+"
+run_test_fail_grep "aigg validate (aigogoVersion too new)" "requires aigogo >=999.*please upgrade aigogo" \
+    "$AIGOGO" validate
+
+run_test_grep "aigg validate --schema (aigogoVersion)" "Schema validation passed" \
+    "$AIGOGO" validate --schema
+mv aigogo.json.bak aigogo.json
+
+echo '{"version": 99, "packages": {}}' > aigogo.lock
+run_test_fail_grep "aigg install (newer lock file format)" "lock file format 99.*please upgrade aigogo" \
+    "$AIGOGO" install
+rm -f aigogo.lock
+
+# An unused dependency is a warning: passes normally, fails with --strict
+"$AIGOGO" add dep requests ">=2.0" >>"$LOGFILE" 2>&1
+
+run_test_grep "aigg validate (warning passes)" "Validation passed" \
+    "$AIGOGO" validate
+
+run_test_fail_grep "aigg validate --strict (warning fails)" "strict treats warnings as errors" \
+    "$AIGOGO" validate --strict
+
+run_test_fail_grep "aigg validate --strict --format sarif" '"ruleId": "unused-dependency"' \
+    "$AIGOGO" validate --strict --format sarif
+
+run_test_grep "aigg --output github validate — annotates the manifest's line" "^::warning file=aigogo\.json,line=[0-9]+,title=aigg validate%3A unused-dependency::" \
+    "$AIGOGO" --output github validate
+
+run_test_fail_grep "aigg --output github validate --strict — annotates the exit code" "^::error title=aigg%3A validation_failure \(exit code 8\)::" \
+    "$AIGOGO" --output github validate --strict
+
+run_test_fail_grep "aigg --output gitlab — usage error" "invalid output: gitlab" \
+    "$AIGOGO" --output gitlab validate
+
+popd >/dev/null
+
+# Included files that nothing imports are warnings once files import each other
+UNUSED_DIR="$WORK/author-unused-file"
+create_python_project "$UNUSED_DIR"
+pushd "$UNUSED_DIR" >/dev/null
+printf 'from .helpers import helper\n' >> utils.py
+echo 'print("scratch")' > scratch.py
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+"$AIGOGO" add file utils.py helpers.py scratch.py >>"$LOGFILE" 2>&1
+
+run_test_grep "aigg validate (unused file warning)" "scratch.py' is not imported" \
+    "$AIGOGO" validate
+
+run_test_fail_grep "aigg validate --strict --format sarif (unused file)" '"ruleId": "unused-file"' \
+    "$AIGOGO" validate --strict --format sarif
+
+popd >/dev/null
+
+# --- lint ---
+LINT_DIR="$WORK/author-lint"
+create_python_project "$LINT_DIR"
+pushd "$LINT_DIR" >/dev/null
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+"$AIGOGO" add file utils.py >>"$LOGFILE" 2>&1
+"$AIGOGO" add dep requests "==2.31.0" >>"$LOGFILE" 2>&1
+
+run_test_grep "aigg lint (warnings pass)" "\[exact-runtime-version\]" \
+    "$AIGOGO" lint
+
+run_test_fail_grep "aigg lint --strict (warnings fail)" "strict treats warnings as errors" \
+    "$AIGOGO" lint --strict
+
+run_test_grep "aigg lint --list-rules" "sensitive-file +error" \
+    "$AIGOGO" lint --list-rules
+
+echo "TOKEN=secret" > .env
+echo "TOKEN=" > .env.example
+"$AIGOGO" add file .env .env.example >>"$LOGFILE" 2>&1
+run_test_fail_grep "aigg lint (sensitive file fails)" "\.env looks like a secret" \
+    "$AIGOGO" lint
+
+run_test_fail_grep "aigg lint --format sarif" '"ruleId": "sensitive-file"' \
+    "$AIGOGO" lint --format sarif
+
+python3 -c "
+import json
+m = json.load(open('aigogo.json'))
+m['lint'] = {'rules': {'sensitive-file': 'off', 'exact-runtime-version': 'off', 'missing-description': 'off'}}
+json.dump(m, open('aigogo.json', 'w'), indent=2)
+"
+run_test_grep "aigg lint (rules turned off)" "Lint passed" \
+    "$AIGOGO" lint --strict
+
+python3 -c "
+import json
+m = json.load(open('aigogo.json'))
+m['lint']['rules']['missing-licence'] = 'error'
+json.dump(m, open('aigogo.json', 'w'), indent=2)
+"
+run_test_fail_grep "aigg lint (unknown rule)" "unknown lint rule" \
+    "$AIGOGO" lint
+
 popd >/dev/null
 
 # --- build ---
@@ -396,9 +951,22 @@ run_test_grep "aigg build (auto-increment)" "Auto-incrementing version" \
 run_test_grep "aigg build --force" "Successfully built" \
     "$AIGOGO" build qa-test:1.0.0 --force
 
+run_test_grep "aigg build — unchanged content reuses the cached build" "qa-test:1\.0\.0 is up to date" \
+    "$AIGOGO" build qa-test:1.0.0
+
+run_test "aigg build — records the content hash" \
+    bash -c 'grep -q "\"content_hash\": \"sha256:" "$("$0" cache path qa-test:1.0.0)/.aigogo-metadata.json"' "$AIGOGO"
+
+echo "# changed" >> utils.py
+run_test_fail_grep "aigg build — changed content needs --force" "different content" \
+    "$AIGOGO" build qa-test:1.0.0
+
 run_test_grep "aigg build --no-validate" "Successfully built" \
     "$AIGOGO" build qa-test:1.0.1 --force --no-validate
 
+run_test_grep "aigg build --no-cache" "Validation passed" \
+    "$AIGOGO" build qa-test:1.0.1 --force --no-cache
+
 popd >/dev/null
 
 echo ""
@@ -439,6 +1007,10 @@ run_test_grep "aigg add <name>:<tag> (local cache)" "Added|local cache" \
 
 run_test "aigg add — aigogo.lock created" test -f aigogo.lock
 
+# The digest info shows for a locked package is the lock's integrity
+run_test "aigg info <locked name> — digest matches aigogo.lock" \
+    bash -c "read -r name integrity < <(python3 -c 'import json; n, p = next(iter(json.load(open(\"aigogo.lock\"))[\"packages\"].items())); print(n, p[\"integrity\"])') && '$AIGOGO' info \"\$name\" | grep -q \"Digest: \$integrity\""
+
 run_test_grep "aigg install" "Installed" \
     "$AIGOGO" install
 
@@ -455,7 +1027,7 @@ pth_file_check() {
         return 1
     fi
     local pth_path
-    pth_path="$(cat "$tracking" | tr -d '[:space:]')"
+    pth_path="$(head -n1 "$tracking" | tr -d '[:space:]')"
     if [ ! -f "$pth_path" ]; then
         echo ".pth file not found at: $pth_path" >>"$LOGFILE"
         return 1
@@ -492,6 +1064,17 @@ for name, pkg in lock['packages'].items():
 run_test "aigg install — Python import works without PYTHONPATH" \
     pth_import_check
 
+# --- Installing a single package ---
+rm -rf "$CONSUMER_DIR/.aigogo/imports/aigogo/consumer_pkg"
+run_test_grep "aigg install <pkg>" "Installed 1 package" \
+    "$AIGOGO" install consumer-pkg
+
+run_test "aigg install <pkg> — link re-created" \
+    test -e "$CONSUMER_DIR/.aigogo/imports/aigogo/consumer_pkg/utils.py"
+
+run_test_fail_grep "aigg install <unknown pkg>" "not in aigogo.lock: no-such-pkg" \
+    "$AIGOGO" install no-such-pkg
+
 # Deactivate the virtualenv
 if [ -n "${VIRTUAL_ENV:-}" ]; then
     deactivate_venv
@@ -499,66 +1082,612 @@ fi
 
 popd >/dev/null
 
-# --- JavaScript consumer tests ---
-# Build a JS package, install it, verify the new structure
+# --- Python environment detection ---
+PYENV_CONSUMER="$WORK/consumer-pyenv"
+mkdir -p "$PYENV_CONSUMER"
+cp "$CONSUMER_DIR/aigogo.lock" "$PYENV_CONSUMER/"
+pushd "$PYENV_CONSUMER" >/dev/null
+if python3 -m venv --without-pip .venv >>"$LOGFILE" 2>&1 && python3 -m venv --without-pip other-venv >>"$LOGFILE" 2>&1; then
+    run_test_grep "aigg install — detects the project .venv" "in the project .venv environment" \
+        env -u VIRTUAL_ENV -u CONDA_PREFIX "$AIGOGO" install
+
+    run_test "aigg install — .pth written into .venv" \
+        grep -q "$PYENV_CONSUMER/.venv" .aigogo/.pth-location
+
+    run_test_grep "aigg install --python <env dir>" "in the selected environment" \
+        env -u VIRTUAL_ENV -u CONDA_PREFIX "$AIGOGO" install --python other-venv
+
+    run_test "aigg install --python — .pth written into that environment" \
+        grep -q "other-venv" .aigogo/.pth-location
+
+    run_test "aigg install --python — the .venv .pth file is still tracked" \
+        grep -q "$PYENV_CONSUMER/.venv" .aigogo/.pth-location
+
+    # Other environments on the machine may hold orphans of their own
+    run_test_grep "aigg doctor — both environments' .pth files are fine" "✓ .*other-venv/lib/python.*/aigogo.pth" \
+        env -u VIRTUAL_ENV -u CONDA_PREFIX "$AIGOGO" doctor --python other-venv
+
+    run_test_grep "aigg uninstall — removes the .pth files of both environments" "from 2 environments" \
+        "$AIGOGO" uninstall
+
+    run_test "aigg uninstall — no aigogo.pth left in either environment" \
+        bash -c "! ls .venv/lib/python*/site-packages/aigogo.pth other-venv/lib/python*/site-packages/aigogo.pth 2>/dev/null"
+
+    # Deleting .aigogo/ by hand orphans the .pth file in .venv
+    env -u VIRTUAL_ENV -u CONDA_PREFIX "$AIGOGO" install >>"$LOGFILE" 2>&1
+    rm -rf .aigogo
+    run_test_fail_grep "aigg doctor — finds the orphaned .pth file" "orphaned: .*consumer-pyenv/.aigogo/imports no longer exists" \
+        env -u VIRTUAL_ENV -u CONDA_PREFIX "$AIGOGO" doctor
+
+    run_test_grep "aigg doctor --fix" "Fixed [0-9]+ problem" \
+        env -u VIRTUAL_ENV -u CONDA_PREFIX "$AIGOGO" doctor --fix
+
+    run_test "aigg doctor --fix — orphaned .pth file removed" \
+        bash -c "! ls .venv/lib/python*/site-packages/aigogo.pth 2>/dev/null"
+else
+    skip_test "aigg install — Python environment detection (python3 -m venv unavailable)"
+fi
+popd >/dev/null
+
+# --- Frozen install ---
+FROZEN_DIR="$WORK/consumer-frozen"
+mkdir -p "$FROZEN_DIR"
+cp "$CONSUMER_DIR/aigogo.lock" "$FROZEN_DIR/"
+pushd "$FROZEN_DIR" >/dev/null
+run_test_grep "aigg install --frozen" "Installing packages from .*(frozen)" \
+    "$AIGOGO" install --frozen
+
+run_test "aigg install --frozen — aigogo.lock unchanged" \
+    cmp -s aigogo.lock "$CONSUMER_DIR/aigogo.lock"
 
-BUILD_JS_CONSUMER="$WORK/consumer-js-build"
-create_js_project "$BUILD_JS_CONSUMER"
-pushd "$BUILD_JS_CONSUMER" >/dev/null
-"$AIGOGO" init >>"$LOGFILE" 2>&1
-# Switch to javascript language and set name to match build tag
 python3 -c "
 import json
-with open('aigogo.json') as f: m = json.load(f)
-m['name'] = 'js-consumer-pkg'
-m['language']['name'] = 'javascript'
-m['language']['version'] = '>=18'
-with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
-" 2>>"$LOGFILE" || true
-"$AIGOGO" add file index.js >>"$LOGFILE" 2>&1
-"$AIGOGO" build js-consumer-pkg:1.0.0 --force >>"$LOGFILE" 2>&1
+lock = json.load(open('aigogo.lock'))
+for pkg in lock['packages'].values():
+    pkg['version'] = '0.9.0'
+json.dump(lock, open('aigogo.lock', 'w'), indent=2)
+" 2>>"$LOGFILE"
+run_test_fail_grep "aigg install --frozen (stale version)" "version is 0.9.0" \
+    "$AIGOGO" install --frozen
+
+run_test "aigg install --frozen (stale version) — exits 9 (diff_found)" \
+    bash -c '"$0" install --frozen >/dev/null 2>&1; test $? -eq 9' "$AIGOGO"
+
+python3 -c "
+import json
+lock = json.load(open('aigogo.lock'))
+for pkg in lock['packages'].values():
+    del pkg['source']
+json.dump(lock, open('aigogo.lock', 'w'), indent=2)
+" 2>>"$LOGFILE"
+run_test_fail_grep "aigg install --frozen (incomplete entry)" "missing source" \
+    "$AIGOGO" install --frozen
+
+run_test "aigg install --frozen (incomplete entry) — exits 8 (validation_failure)" \
+    bash -c '"$0" install --frozen >/dev/null 2>&1; test $? -eq 8' "$AIGOGO"
 popd >/dev/null
 
-JS_CONSUMER_DIR="$WORK/consumer-js"
-mkdir -p "$JS_CONSUMER_DIR"
-pushd "$JS_CONSUMER_DIR" >/dev/null
+# --- Offline install ---
+OFFLINE_DIR="$WORK/consumer-offline"
+mkdir -p "$OFFLINE_DIR"
+cp "$CONSUMER_DIR/aigogo.lock" "$OFFLINE_DIR/"
+pushd "$OFFLINE_DIR" >/dev/null
+run_test_grep "aigg install --offline" "Installed 1 package" \
+    "$AIGOGO" install --offline
 
-"$AIGOGO" add js-consumer-pkg:1.0.0 >>"$LOGFILE" 2>&1
+run_test_grep "aigg pull --all — reports packages already in the store" "Fetched 0 package\(s\), 1 already in the store" \
+    "$AIGOGO" pull --all
+
+run_test_fail_grep "aigg pull --all — takes no reference" "usage: aigg pull --all" \
+    "$AIGOGO" pull --all org/pkg:1.0
+
+python3 -c "
+import json
+lock = json.load(open('aigogo.lock'))
+lock['packages']['remote_pkg'] = {'version': '2.0.0', 'integrity': 'sha256:' + '0' * 64,
+    'source': 'registry.invalid/org/remote-pkg:2.0.0', 'language': 'python', 'files': ['remote.py']}
+json.dump(lock, open('aigogo.lock', 'w'), indent=2)
+" 2>>"$LOGFILE"
+run_test_fail_grep "aigg install --offline (package not available)" "aigg pull registry.invalid/org/remote-pkg:2.0.0" \
+    "$AIGOGO" install --offline
+
+run_test "aigg install --offline — failure leaves .aigogo/ untouched" \
+    test -d .aigogo/imports/aigogo
+
+run_test_fail_grep "aigg pull --all — names the package it couldn't fetch" "failed to fetch remote_pkg" \
+    env AIGOGO_RETRY_ATTEMPTS=1 "$AIGOGO" pull --all
+popd >/dev/null
+
+# --- Self-healing install ---
+# Links left dangling (a pruned store entry, a moved home directory) are
+# found by aigg doctor and repaired by aigg install
+HEAL_DIR="$WORK/consumer-heal"
+mkdir -p "$HEAL_DIR"
+cp "$CONSUMER_DIR/aigogo.lock" "$HEAL_DIR/"
+pushd "$HEAL_DIR" >/dev/null
 "$AIGOGO" install >>"$LOGFILE" 2>&1
+find .aigogo/imports -type l -exec ln -sfn /nonexistent/aigogo-store {} \;
+run_test_fail_grep "aigg doctor — finds a dangling package link" "dangling link to /nonexistent/aigogo-store" \
+    "$AIGOGO" doctor
 
-# Check that JS package is a real directory (not a symlink)
-js_real_dir_check() {
-    local pkg_dir="$JS_CONSUMER_DIR/.aigogo/imports/@aigogo/js_consumer_pkg"
-    # Try both normalized and original name
-    if [ ! -d "$pkg_dir" ]; then
-        # Try with hyphens
-        pkg_dir="$JS_CONSUMER_DIR/.aigogo/imports/@aigogo/js-consumer-pkg"
-    fi
-    if [ ! -d "$pkg_dir" ]; then
-        echo "JS package directory not found" >>"$LOGFILE"
-        ls -la "$JS_CONSUMER_DIR/.aigogo/imports/@aigogo/" >>"$LOGFILE" 2>&1 || true
-        return 1
-    fi
-    # Verify it is NOT a symlink (should be a real directory)
-    if [ -L "$pkg_dir" ]; then
-        echo "JS package should be a real directory, not a symlink" >>"$LOGFILE"
-        return 1
-    fi
-    return 0
-}
+run_test_grep "aigg doctor --fix — reinstalls the package" "Fixed [0-9]+ problem" \
+    "$AIGOGO" doctor --fix
 
-run_test "aigg install — JS package is real directory (not symlink)" \
-    js_real_dir_check
+run_test "aigg doctor --fix — no dangling links left" \
+    bash -c "[ -z \"\$(find .aigogo/imports -xtype l)\" ]"
 
-# Check that generated package.json exists with main field
-js_package_json_check() {
-    local pkg_dir
-    for candidate in "$JS_CONSUMER_DIR/.aigogo/imports/@aigogo/js_consumer_pkg" \
-                     "$JS_CONSUMER_DIR/.aigogo/imports/@aigogo/js-consumer-pkg"; do
-        if [ -d "$candidate" ]; then
-            pkg_dir="$candidate"
-            break
-        fi
+find .aigogo/imports -type l -exec ln -sfn /nonexistent/aigogo-store {} \;
+run_test_grep "aigg install — repairs dangling links" "Repaired 1 package\(s\) with broken links" \
+    "$AIGOGO" install
+
+run_test "aigg install — no dangling links left" \
+    bash -c "[ -z \"\$(find .aigogo/imports -xtype l)\" ]"
+popd >/dev/null
+
+# --- Path variable fallback ---
+# Without a Python environment for the .pth file, install writes the
+# PYTHONPATH it can't configure to .aigogo/path.env and .aigogo/envrc
+ENVFILE_DIR="$WORK/consumer-envfile"
+mkdir -p "$ENVFILE_DIR"
+cp "$CONSUMER_DIR/aigogo.lock" "$ENVFILE_DIR/"
+pushd "$ENVFILE_DIR" >/dev/null
+run_test_grep "aigg install — no Python environment prints the direnv one-liner" "source_env .aigogo/envrc" \
+    "$AIGOGO" install --python /nonexistent/python3
+
+run_test "aigg install — .aigogo/envrc adds the imports dir to PYTHONPATH" \
+    grep -q "^path_add PYTHONPATH '$ENVFILE_DIR/.aigogo/imports'" .aigogo/envrc
+
+run_test_grep "aigg install — .aigogo/path.env makes imports work" "hello from aigogo" \
+    bash -c 'set -a && . .aigogo/path.env && set +a && cd /tmp && python3 -c "from aigogo.consumer_pkg.utils import hello; print(hello())"'
+popd >/dev/null
+
+# --- PEP 582 layout ---
+PEP582_DIR="$WORK/consumer-pep582"
+mkdir -p "$PEP582_DIR"
+cp "$CONSUMER_DIR/aigogo.lock" "$PEP582_DIR/"
+pushd "$PEP582_DIR" >/dev/null
+echo '{"install": {"python_layout": "pypackages"}}' > aigogo.json
+run_test_grep "aigg install — python_layout pypackages" "Installed into __pypackages__/[0-9.]+/lib \(PEP 582\)" \
+    env -u VIRTUAL_ENV -u CONDA_PREFIX "$AIGOGO" install
+
+run_test "aigg install — package linked into __pypackages__/<X.Y>/lib" \
+    bash -c "ls -d __pypackages__/*/lib/aigogo/consumer_pkg"
+
+run_test "aigg install — no .pth file with the pypackages layout" \
+    test ! -e .aigogo/.pth-location
+
+run_test_grep "aigg install — __pypackages__ import works" "hello from aigogo" \
+    bash -c 'PYTHONPATH=$(echo __pypackages__/*/lib) python3 -c "from aigogo.consumer_pkg.utils import hello; print(hello())"'
+
+run_test "aigg install — __pypackages__/ added to .gitignore" \
+    grep -qx "__pypackages__/" .gitignore
+
+run_test_grep "aigg uninstall — removes packages from __pypackages__/" "Removed Python packages from __pypackages__/" \
+    "$AIGOGO" uninstall
+popd >/dev/null
+
+# --- Log levels ---
+LOGLEVEL_DIR="$WORK/consumer-loglevel"
+mkdir -p "$LOGLEVEL_DIR"
+cp "$CONSUMER_DIR/aigogo.lock" "$LOGLEVEL_DIR/"
+pushd "$LOGLEVEL_DIR" >/dev/null
+run_test "aigg --quiet install — prints the result but not the progress" \
+    bash -c '"$0" --quiet install > out.txt && grep -q "Installed 1 package" out.txt && ! grep -qE "Installing packages|To use installed" out.txt' "$AIGOGO"
+
+run_test_grep "aigg install --verbose — prints the package's hash and store path" "consumer_pkg: sha256:[0-9a-f]{64} at .*/store/sha256/" \
+    "$AIGOGO" install --verbose
+
+run_test_fail_grep "aigg --quiet install --debug — can't be combined" "can't be combined" \
+    "$AIGOGO" --quiet install --debug
+
+run_test "aigg --quiet --log-file — quiet output, verbose detail in the log file" \
+    bash -c '"$0" --quiet --log-file logs/aigg.log install > out.txt 2>&1 && ! grep -q "consumer_pkg: sha256:" out.txt && grep -q "\"level\":\"info\",\"msg\":\"aigg .*install" logs/aigg.log && grep -q "\"level\":\"verbose\",\"msg\":\"consumer_pkg: sha256:" logs/aigg.log' "$AIGOGO"
+
+run_test_fail_grep "aigg --log-file= — needs a path" "needs a path" \
+    "$AIGOGO" --log-file= install
+
+# --- History ---
+run_test_grep "aigg history — lists the install just run" "install +ok +[0-9.]+m?s +consumer-pkg:1.0.0" \
+    "$AIGOGO" history --command install -n 1
+
+run_test "aigg --json history — the install's package with its integrity hash" \
+    bash -c '"$0" --json history --command install -n 1 | grep -q "\"digest\": \"sha256:[0-9a-f]\{64\}\""' "$AIGOGO"
+
+run_test "AIGOGO_HISTORY=false — the command isn't recorded" \
+    bash -c 'before=$("$0" --json history -n 0 | grep -c "\"command\":"); AIGOGO_HISTORY=false "$0" install >/dev/null 2>&1 && test "$("$0" --json history -n 0 | grep -c "\"command\":")" -eq "$before"' "$AIGOGO"
+
+run_test_fail_grep "aigg history --command list — not a recorded command" "isn't recorded" \
+    "$AIGOGO" history --command list
+
+# --- Plain output and color ---
+run_test "aigg --plain install — ASCII markers instead of symbols" \
+    bash -c '"$0" --plain install > out.txt 2>&1 && grep -q "^\[ok\] Installed 1 package" out.txt && ! grep -q "✓" out.txt' "$AIGOGO"
+
+run_test_grep "AIGOGO_PLAIN=true — same as --plain" "^\[ok\] Installed 1 package" \
+    env AIGOGO_PLAIN=true "$AIGOGO" install
+
+run_test_fail_grep "aigg --color sometimes — invalid mode" "invalid color: sometimes" \
+    "$AIGOGO" --color sometimes version
+
+# --- Exit codes ---
+run_test "aigg install --nosuchflag — exits 2 (usage_error)" \
+    bash -c '"$0" install --nosuchflag >/dev/null 2>&1; test $? -eq 2' "$AIGOGO"
+
+run_test "aigg <unknown> — exits 2 (usage_error)" \
+    bash -c '"$0" nosuchcommand >/dev/null 2>&1; test $? -eq 2' "$AIGOGO"
+popd >/dev/null
+
+# --- Logins (no registry needed) ---
+# A separate HOME keeps the user's own credentials out of it
+AUTH_HOME="$WORK/auth-home"
+mkdir -p "$AUTH_HOME"
+echo secret | env HOME="$AUTH_HOME" "$AIGOGO" login localhost:1 -u qa-user -p >>"$LOGFILE" 2>&1
+echo secret | env HOME="$AUTH_HOME" "$AIGOGO" login localhost:2 -u qa-other -p >>"$LOGFILE" 2>&1
+
+run_test_grep "aigg whoami --no-check — lists the stored users" "localhost:1: qa-user" \
+    env HOME="$AUTH_HOME" "$AIGOGO" whoami --no-check
+
+run_test "aigg whoami — an unreachable registry exits 5 (network_error)" \
+    bash -c 'env HOME="$1" AIGOGO_RETRY_ATTEMPTS=1 "$0" whoami localhost:1 >/dev/null 2>&1; test $? -eq 5' "$AIGOGO" "$AUTH_HOME"
+
+run_test "aigg whoami <registry> — not logged in exits 4 (auth_error)" \
+    bash -c 'env HOME="$1" "$0" whoami --no-check ghcr.io >/dev/null 2>&1; test $? -eq 4' "$AIGOGO" "$AUTH_HOME"
+
+run_test "aigg logout --all — removes every login" \
+    bash -c 'env HOME="$1" "$0" logout --all | grep -c "Successfully logged out" | grep -qx 2 && env HOME="$1" "$0" whoami | grep -q "Not logged in to any registry"' "$AIGOGO" "$AUTH_HOME"
+
+# --- Encrypted logins ---
+ENC_HOME="$WORK/auth-enc-home"
+mkdir -p "$ENC_HOME"
+echo secret | env HOME="$ENC_HOME" AIGOGO_AUTH_ENCRYPT=true AIGOGO_AUTH_PASSPHRASE=qa-pass "$AIGOGO" login localhost:1 -u qa-user -p >>"$LOGFILE" 2>&1
+
+run_test "aigg login with auth.encrypt — auth.json holds no registry or credentials" \
+    bash -c 'f=$(find "$0" -name auth.json); grep -q "\"ciphertext\"" "$f" && ! grep -qe "localhost:1" -e "$(printf qa-user:secret | base64)" "$f"' "$ENC_HOME"
+
+run_test_grep "aigg whoami — reads encrypted logins with AIGOGO_AUTH_PASSPHRASE" "localhost:1: qa-user" \
+    env HOME="$ENC_HOME" AIGOGO_AUTH_PASSPHRASE=qa-pass "$AIGOGO" whoami --no-check
+
+run_test "aigg whoami — a wrong passphrase exits 4 (auth_error)" \
+    bash -c 'env HOME="$1" AIGOGO_AUTH_PASSPHRASE=wrong "$0" whoami --no-check 2>&1 | grep -q "wrong passphrase" && { env HOME="$1" AIGOGO_AUTH_PASSPHRASE=wrong "$0" whoami --no-check >/dev/null 2>&1; test $? -eq 4; }' "$AIGOGO" "$ENC_HOME"
+
+run_test_fail_grep "aigg whoami — an encrypted auth.json without a passphrase names AIGOGO_AUTH_PASSPHRASE" "AIGOGO_AUTH_PASSPHRASE" \
+    env HOME="$ENC_HOME" "$AIGOGO" whoami --no-check </dev/null
+
+run_test "aigg login --decrypt — stores the logins in the clear" \
+    bash -c 'env HOME="$1" AIGOGO_AUTH_PASSPHRASE=qa-pass "$0" login --decrypt | grep -q "Decrypted the credentials for localhost:1" && grep -q "localhost:1" "$(find "$1" -name auth.json)"' "$AIGOGO" "$ENC_HOME"
+
+run_test "aigg login --encrypt — encrypts the stored logins without logging in" \
+    bash -c 'env HOME="$1" AIGOGO_AUTH_PASSPHRASE=qa-pass "$0" login --encrypt | grep -q "Encrypted the credentials for localhost:1" && ! grep -q "localhost:1" "$(find "$1" -name auth.json)"' "$AIGOGO" "$ENC_HOME"
+
+run_test "aigg config set --project auth.encrypt — refused, exits 2 (usage_error)" \
+    bash -c 'cd "$2" && env HOME="$1" "$0" config set --project auth.encrypt false >/dev/null 2>&1; test $? -eq 2' "$AIGOGO" "$ENC_HOME" "$WORK"
+
+# --- Config command ---
+# A separate HOME keeps the user's own config.toml out of it
+CONFIG_HOME="$WORK/config-home"
+CONFIG_DIR="$WORK/config-project"
+mkdir -p "$CONFIG_HOME" "$CONFIG_DIR/src"
+cp "$CONSUMER_DIR/aigogo.lock" "$CONFIG_DIR/"
+pushd "$CONFIG_DIR/src" >/dev/null
+run_test_grep "aigg config set — writes the user config.toml" "Set registry = ghcr.io/qa in $CONFIG_HOME/(\.config/aigogo|\.aigogo)/config.toml" \
+    env HOME="$CONFIG_HOME" "$AIGOGO" config set registry ghcr.io/qa
+
+run_test_grep "aigg config set --project — writes the project's .aigogo/config.toml" "in $CONFIG_DIR/.aigogo/config.toml" \
+    env HOME="$CONFIG_HOME" "$AIGOGO" config set --project registry localhost:5000
+
+run_test_grep "aigg config get — the project's config file wins" "^localhost:5000$" \
+    env HOME="$CONFIG_HOME" "$AIGOGO" config get registry
+
+run_test_grep "aigg config get — AIGOGO_REGISTRY overrides the files" "^ghcr.io/env$" \
+    env HOME="$CONFIG_HOME" AIGOGO_REGISTRY=ghcr.io/env "$AIGOGO" config get registry
+
+run_test_grep "aigg config get — lists settings and their source" "registry = localhost:5000  \(.*/config-project/.aigogo/config.toml\)" \
+    env HOME="$CONFIG_HOME" "$AIGOGO" config get
+
+# A project's webhooks are ignored: with $VARS expanded they could send
+# the user's secrets anywhere
+printf '\n[[webhooks]]\nurl = "https://hooks.example.com/$QA_WEBHOOK_TOKEN"\n' >> "$CONFIG_DIR/.aigogo/config.toml"
+run_test "aigg config get — ignores the project's [[webhooks]]" \
+    bash -c '! HOME="$1" "$0" config get | grep -q "^webhooks"' "$AIGOGO" "$CONFIG_HOME"
+
+# Webhook URLs are often secret, so config get only counts them
+USER_CONFIG="$CONFIG_HOME/.aigogo/config.toml"
+[ -f "$CONFIG_HOME/.config/aigogo/config.toml" ] && USER_CONFIG="$CONFIG_HOME/.config/aigogo/config.toml"
+printf '\n[[webhooks]]\nurl = "$QA_WEBHOOK_URL"\nevents = ["push"]\n' >> "$USER_CONFIG"
+run_test_grep "aigg config get — counts [[webhooks]] without printing them" "^webhooks = 1 configured  \(.*/config\.toml\)" \
+    env HOME="$CONFIG_HOME" "$AIGOGO" config get
+
+run_test_fail_grep "aigg config set --project — refuses insecure_registries" "set it without --project" \
+    env HOME="$CONFIG_HOME" "$AIGOGO" config set --project insecure_registries localhost:5000
+
+run_test_fail_grep "aigg config set — unknown key" "unknown setting: colour" \
+    env HOME="$CONFIG_HOME" "$AIGOGO" config set colour never
+
+run_test_fail_grep "aigg config set — invalid value" "invalid color: sometimes" \
+    env HOME="$CONFIG_HOME" "$AIGOGO" config set color sometimes
+
+run_test "aigg config set — invalid value exits 3 (config_error)" \
+    bash -c 'HOME="$1" "$0" config set color sometimes >/dev/null 2>&1; test $? -eq 3' "$AIGOGO" "$CONFIG_HOME"
+
+run_test_fail_grep "aigg config get — unset key" "cache is not set" \
+    env HOME="$CONFIG_HOME" "$AIGOGO" config get cache
+
+# The registry setting (localhost:5000, with nothing listening) qualifies
+# references without a registry host; --registry overrides it
+run_test_fail_grep "aigg pull — namespace/name resolves against the registry setting" "Pulling localhost:5000/org/pkg:1.0" \
+    env HOME="$CONFIG_HOME" AIGOGO_RETRY_ATTEMPTS=1 "$AIGOGO" pull org/pkg:1.0
+
+run_test_fail_grep "aigg pull --registry — overrides the registry setting" "Pulling 127.0.0.1:9/team/pkg:1.0" \
+    env HOME="$CONFIG_HOME" AIGOGO_RETRY_ATTEMPTS=1 "$AIGOGO" pull pkg:1.0 --registry 127.0.0.1:9/team
+
+run_test_fail_grep "aigg pull --registry — invalid registry" "--registry: invalid registry" \
+    env HOME="$CONFIG_HOME" "$AIGOGO" pull pkg:1.0 --registry https://ghcr.io
+popd >/dev/null
+
+pushd "$CONFIG_DIR" >/dev/null
+run_test_grep "aigg uninstall — keeps .aigogo/config.toml" "kept config.toml" \
+    "$AIGOGO" uninstall
+popd >/dev/null
+
+# --- Dynamic completion ---
+pushd "$CONSUMER_DIR" >/dev/null
+run_test_grep "aigg __complete packages — names in aigogo.lock" "^consumer_pkg$" \
+    "$AIGOGO" __complete packages
+
+run_test_grep "aigg __complete images — cached packages" "^consumer-pkg:1.0.0$" \
+    "$AIGOGO" __complete images consumer
+
+run_test "aigg __complete files — nothing outside a package, exit 0" \
+    bash -c 'test -z "$("$0" __complete files)"' "$AIGOGO"
+popd >/dev/null
+
+pushd "$BUILD_CONSUMER" >/dev/null
+run_test_grep "aigg __complete files — aigogo.json's include list" "^utils.py$" \
+    "$AIGOGO" __complete files
+
+run_test_fail_grep "aigg __complete <unknown kind>" "unknown completion kind" \
+    "$AIGOGO" __complete tags
+popd >/dev/null
+
+pushd "$BUILD_CONSUMER" >/dev/null
+run_test "aigg build — AIGOGO_CACHE moves the build cache" \
+    bash -c 'AIGOGO_CACHE="$1" "$0" build consumer-pkg:1.0.0 --force && test -d "$1/consumer-pkg_1.0.0"' "$AIGOGO" "$WORK/alt-cache"
+popd >/dev/null
+
+mkdir -p "$WORK/frozen-no-lock"
+pushd "$WORK/frozen-no-lock" >/dev/null
+run_test_fail_grep "aigg install --frozen (no aigogo.lock)" "only from a committed aigogo.lock" \
+    "$AIGOGO" install --frozen
+
+run_test "aigg --json install (no aigogo.lock) — JSON error with its code, exits 6" \
+    bash -c '"$0" --json install 2>err.json >/dev/null; test $? -eq 6 && python3 -c "import json; e = json.load(open(\"err.json\"))[\"error\"]; assert e[\"code\"] == \"not_found\" and e[\"exit_code\"] == 6 and \"aigogo.lock\" in e[\"message\"]"' "$AIGOGO"
+popd >/dev/null
+
+# --- Type stubs and declarations ---
+# Auto-discovery packages .pyi stubs and .d.ts declarations; install marks
+# stubbed Python packages typed and points package.json "types" at the
+# declarations
+TYPED_BUILD="$WORK/typed-build"
+create_python_project "$TYPED_BUILD"
+create_js_project "$TYPED_BUILD"
+printf 'def hello() -> str: ...\n' > "$TYPED_BUILD/utils.pyi"
+printf 'export declare function greet(): string;\n' > "$TYPED_BUILD/index.d.ts"
+pushd "$TYPED_BUILD" >/dev/null
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['name'] = 'typed-pkg'
+m['files'] = {'include': 'auto'}
+m['languages'] = [{'name': 'javascript', 'version': '>=18'}]
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+"$AIGOGO" build typed-pkg:1.0.0 --force >>"$LOGFILE" 2>&1
+popd >/dev/null
+
+TYPED_DIR="$WORK/consumer-typed"
+mkdir -p "$TYPED_DIR"
+pushd "$TYPED_DIR" >/dev/null
+"$AIGOGO" add typed-pkg:1.0.0 >>"$LOGFILE" 2>&1
+"$AIGOGO" install >>"$LOGFILE" 2>&1
+
+run_test "aigg build — auto-discovery packages .pyi stubs" \
+    test -f .aigogo/imports/aigogo/typed_pkg/utils.pyi
+
+run_test "aigg install — stubbed Python package gets py.typed" \
+    test -f .aigogo/imports/aigogo/typed_pkg/py.typed
+
+run_test_grep "aigg install — package.json types points at the .d.ts" '"types": "index.d.ts"' \
+    cat .aigogo/imports/@aigogo/typed_pkg/package.json
+
+# --- Editor settings ---
+run_test_grep "aigg ide setup" "Marked .aigogo/imports as a sources root in .idea/consumer-typed.iml" \
+    "$AIGOGO" ide setup
+
+run_test_grep "aigg ide setup — VS Code extraPaths" '"\.aigogo/imports"' \
+    cat .vscode/settings.json
+
+run_test "aigg ide setup — tsconfig.aigogo.json written" \
+    grep -q '"@aigogo/typed_pkg"' tsconfig.aigogo.json
+
+run_test_grep "aigg ide setup — second run changes nothing" "already lists .aigogo/imports" \
+    "$AIGOGO" ide setup --vscode
+popd >/dev/null
+
+# --- Local path packages ---
+# aigg add <dir> locks a package by its path; install links it to the
+# working copy, so edits show up without reinstalling
+LOCAL_PKG="$WORK/local-lib"
+create_python_project "$LOCAL_PKG"
+pushd "$LOCAL_PKG" >/dev/null
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['name'] = 'local-lib'
+m['files'] = {'include': 'auto'}
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+popd >/dev/null
+
+LOCAL_DIR="$WORK/consumer-local"
+mkdir -p "$LOCAL_DIR"
+pushd "$LOCAL_DIR" >/dev/null
+run_test_grep "aigg add <dir> — local path package" "Path: \.\./local-lib" \
+    "$AIGOGO" add ../local-lib
+
+run_test_grep "aigg add <dir> — locked with source path" '"source": "path"' \
+    cat aigogo.lock
+
+run_test_grep "aigg install — links local package" "Installed local_lib \(linked to \.\./local-lib\)" \
+    "$AIGOGO" install
+
+run_test "aigg install — local package links to the working copy" \
+    test "$(readlink .aigogo/imports/aigogo/local_lib)" = "$LOCAL_PKG"
+
+printf '\ndef edited():\n    return "edited in place"\n' >> "$LOCAL_PKG/utils.py"
+run_test_grep "local package — edits show up without reinstalling" "edited in place" \
+    env PYTHONPATH=.aigogo/imports python3 -c "from aigogo.local_lib.utils import edited; print(edited())"
+
+run_test_fail_grep "aigg install --frozen — refuses local path packages" "is a local path package" \
+    "$AIGOGO" install --frozen
+
+run_test_fail_grep "aigg add <dir> — directory without aigogo.json" "has no aigogo.json" \
+    "$AIGOGO" add "$WORK"
+
+# --- Package groups ---
+# Dev and optional packages are locked with a group; install --production
+# skips them and removes their links
+run_test_grep "aigg add --dev" "Group: dev" \
+    "$AIGOGO" add typed-pkg:1.0.0 --dev
+
+run_test_grep "aigg add --dev — lock entry has the group" '"group": "dev"' \
+    cat aigogo.lock
+
+"$AIGOGO" install >>"$LOGFILE" 2>&1
+run_test "aigg install — links dev packages" \
+    test -e .aigogo/imports/aigogo/typed_pkg
+
+run_test_grep "aigg install --production — skips dev packages" "Skipping 1 dev/optional package\(s\) \(--production\): typed_pkg" \
+    "$AIGOGO" install --production
+
+run_test "aigg install --production — removes dev package links" \
+    test ! -e .aigogo/imports/aigogo/typed_pkg -a ! -e .aigogo/imports/@aigogo/typed_pkg -a -e .aigogo/imports/aigogo/local_lib
+
+"$AIGOGO" install >>"$LOGFILE" 2>&1
+"$AIGOGO" install --production typed-pkg >>"$LOGFILE" 2>&1
+run_test "aigg install --production <dev package> — removes its link" \
+    test ! -e .aigogo/imports/aigogo/typed_pkg -a -e .aigogo/imports/aigogo/local_lib
+
+run_test_fail_grep "aigg add --dev --optional -> error" "can't be combined" \
+    "$AIGOGO" add typed-pkg:1.0.0 --dev --optional
+popd >/dev/null
+
+# --- Batch add ---
+# aigg add --from-file and aigg add - add a list of packages with one
+# aigogo.lock write; any failure leaves aigogo.lock unchanged
+BATCH_DIR="$WORK/batch-add"
+mkdir -p "$BATCH_DIR"
+pushd "$BATCH_DIR" >/dev/null
+printf '# curated packages\ntyped-pkg:1.0.0\n%s  # a local package\n' "$LOCAL_PKG" > packages.txt
+
+run_test_fail_grep "aigg add - — a failure adds nothing" "failed to add ./no-such-dir" \
+    bash -c "printf 'typed-pkg:1.0.0\n./no-such-dir\n' | '$AIGOGO' add -"
+
+run_test "aigg add - — failed batch didn't write aigogo.lock" \
+    test ! -e aigogo.lock
+
+run_test_grep "aigg add --from-file" "Added 2 package\(s\)" \
+    "$AIGOGO" add --from-file packages.txt
+
+run_test_grep "aigg add --from-file — both packages locked" '"local_lib"' \
+    cat aigogo.lock
+
+run_test_grep "aigg add - --dev — reads stdin" "Group: dev" \
+    bash -c "echo typed-pkg:1.0.0 | '$AIGOGO' add - --dev"
+
+run_test_fail_grep "aigg add - --from-file -> error" "not both" \
+    "$AIGOGO" add - --from-file packages.txt
+popd >/dev/null
+
+# --- Concurrent processes ---
+# aigg processes adding packages at the same time take turns with
+# aigogo.lock, the cache and the store instead of losing each other's writes
+CONCURRENT_DIR="$WORK/concurrent-add"
+mkdir -p "$CONCURRENT_DIR"
+pushd "$CONCURRENT_DIR" >/dev/null
+"$AIGOGO" add typed-pkg:1.0.0 >>"$LOGFILE" 2>&1 &
+"$AIGOGO" add "$LOCAL_PKG" >>"$LOGFILE" 2>&1 &
+"$AIGOGO" add typed-pkg:1.0.0 --dev >>"$LOGFILE" 2>&1 &
+wait
+
+run_test "concurrent aigg add — every process's package is locked" \
+    bash -c 'grep -q "\"local_lib\"" aigogo.lock && grep -qE "\"typed[-_]pkg\"" aigogo.lock'
+
+run_test "concurrent aigg add — aigogo.lock is valid" \
+    "$AIGOGO" validate --lock
+popd >/dev/null
+
+# --- JavaScript consumer tests ---
+# Build a JS package, install it, verify the new structure
+
+BUILD_JS_CONSUMER="$WORK/consumer-js-build"
+create_js_project "$BUILD_JS_CONSUMER"
+pushd "$BUILD_JS_CONSUMER" >/dev/null
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+# Switch to javascript language and set name to match build tag
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['name'] = 'js-consumer-pkg'
+m['language']['name'] = 'javascript'
+m['language']['version'] = '>=18'
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+"$AIGOGO" add file index.js >>"$LOGFILE" 2>&1
+"$AIGOGO" build js-consumer-pkg:1.0.0 --force >>"$LOGFILE" 2>&1
+popd >/dev/null
+
+JS_CONSUMER_DIR="$WORK/consumer-js"
+mkdir -p "$JS_CONSUMER_DIR"
+pushd "$JS_CONSUMER_DIR" >/dev/null
+
+"$AIGOGO" add js-consumer-pkg:1.0.0 >>"$LOGFILE" 2>&1
+"$AIGOGO" install >>"$LOGFILE" 2>&1
+
+# Check that JS package is a real directory (not a symlink)
+js_real_dir_check() {
+    local pkg_dir="$JS_CONSUMER_DIR/.aigogo/imports/@aigogo/js_consumer_pkg"
+    # Try both normalized and original name
+    if [ ! -d "$pkg_dir" ]; then
+        # Try with hyphens
+        pkg_dir="$JS_CONSUMER_DIR/.aigogo/imports/@aigogo/js-consumer-pkg"
+    fi
+    if [ ! -d "$pkg_dir" ]; then
+        echo "JS package directory not found" >>"$LOGFILE"
+        ls -la "$JS_CONSUMER_DIR/.aigogo/imports/@aigogo/" >>"$LOGFILE" 2>&1 || true
+        return 1
+    fi
+    # Verify it is NOT a symlink (should be a real directory)
+    if [ -L "$pkg_dir" ]; then
+        echo "JS package should be a real directory, not a symlink" >>"$LOGFILE"
+        return 1
+    fi
+    return 0
+}
+
+run_test "aigg install — JS package is real directory (not symlink)" \
+    js_real_dir_check
+
+# Check that generated package.json exists with main field
+js_package_json_check() {
+    local pkg_dir
+    for candidate in "$JS_CONSUMER_DIR/.aigogo/imports/@aigogo/js_consumer_pkg" \
+                     "$JS_CONSUMER_DIR/.aigogo/imports/@aigogo/js-consumer-pkg"; do
+        if [ -d "$candidate" ]; then
+            pkg_dir="$candidate"
+            break
+        fi
     done
     if [ -z "$pkg_dir" ]; then
         echo "JS package directory not found" >>"$LOGFILE"
@@ -608,11 +1737,225 @@ js_require_check() {
     (cd "$JS_CONSUMER_DIR" && node --require ./.aigogo/register.js -e "require('@aigogo/js-consumer-pkg')" 2>>"$LOGFILE")
 }
 
-run_test "aigg install — JS require works via register script" \
-    js_require_check
+run_test "aigg install — JS require works via register script" \
+    js_require_check
+
+run_test "aigg install — generates ESM register script" \
+    test -f "$JS_CONSUMER_DIR/.aigogo/register.mjs"
+
+# Check that import from '@aigogo/...' works in an ES module via register.mjs
+js_import_check() {
+    if ! command -v node >/dev/null 2>&1; then
+        echo "node not found, skipping" >>"$LOGFILE"
+        return 0
+    fi
+    echo "import pkg from '@aigogo/js-consumer-pkg'; if (!pkg.greet) process.exit(1);" > "$JS_CONSUMER_DIR/app.mjs"
+    (cd "$JS_CONSUMER_DIR" && node --import ./.aigogo/register.mjs app.mjs 2>>"$LOGFILE")
+}
+
+run_test "aigg install — ESM import works via register.mjs" \
+    js_import_check
+
+run_test "aigg install — generates Bun plugin" \
+    test -f "$JS_CONSUMER_DIR/.aigogo/bun-plugin.js"
+
+if command -v bun >/dev/null 2>&1; then
+    run_test "aigg install — Bun require and import work via bun-plugin.js" \
+        bash -c "cd '$JS_CONSUMER_DIR' && bun --preload ./.aigogo/bun-plugin.js -e \"require('@aigogo/js-consumer-pkg')\" && bun --preload ./.aigogo/bun-plugin.js app.mjs"
+else
+    skip_test "aigg install — Bun require and import work via bun-plugin.js (bun not installed)"
+fi
+
+run_test_grep "aigg install — Deno import map" '"@aigogo/js-consumer-pkg": "./imports/@aigogo/js-consumer-pkg/' \
+    cat "$JS_CONSUMER_DIR/.aigogo/import_map.json"
+
+run_test_grep "aigg install --tsconfig" "Extend tsconfig.aigogo.json" \
+    bash -c "cd '$JS_CONSUMER_DIR' && '$AIGOGO' install --tsconfig"
+
+run_test_grep "tsconfig.aigogo.json — paths for installed package" '"@aigogo/js-consumer-pkg/\*"' \
+    cat "$JS_CONSUMER_DIR/tsconfig.aigogo.json"
+
+popd >/dev/null
+
+# --- Go consumer tests ---
+# Build a Go package without a go.mod and install it into a Go module
+GO_BUILD="$WORK/go-strutil-build"
+mkdir -p "$GO_BUILD"
+pushd "$GO_BUILD" >/dev/null
+cat > strutil.go <<'GOEOF'
+package strutil
+
+func Reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+GOEOF
+"$AIGOGO" init --no-detect >>"$LOGFILE" 2>&1
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['name'] = 'strutil'
+m['language'] = {'name': 'go', 'version': '1.21'}
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+"$AIGOGO" add file strutil.go >>"$LOGFILE" 2>&1
+"$AIGOGO" build strutil:1.0.0 --force >>"$LOGFILE" 2>&1
+popd >/dev/null
+
+GO_CONSUMER_DIR="$WORK/consumer-go"
+mkdir -p "$GO_CONSUMER_DIR"
+pushd "$GO_CONSUMER_DIR" >/dev/null
+printf 'module example.com/app\n\ngo 1.21\n' > go.mod
+cat > main.go <<'GOEOF'
+package main
+
+import (
+	"fmt"
+
+	"aigogo/strutil"
+)
+
+func main() { fmt.Println(strutil.Reverse("olleh")) }
+GOEOF
+"$AIGOGO" add strutil:1.0.0 >>"$LOGFILE" 2>&1
+
+run_test_grep "aigg install — Go import hint" 'import "aigogo/strutil"' \
+    "$AIGOGO" install
+
+run_test "aigg install — Go module has a generated go.mod" \
+    grep -q "^module aigogo/strutil$" "$GO_CONSUMER_DIR/.aigogo/imports/go/aigogo/strutil/go.mod"
+
+run_test_grep "aigg install — go.mod replace block" "replace aigogo/strutil => ./.aigogo/imports/go/aigogo/strutil" \
+    cat go.mod
+
+if command -v go >/dev/null 2>&1; then
+    run_test_grep "go run — imports the installed Go package" "hello" \
+        go run .
+
+    go_mod_tidy_check() {
+        cp go.mod "$WORK/go.mod.before"
+        go mod tidy >>"$LOGFILE" 2>&1 && "$AIGOGO" install >>"$LOGFILE" 2>&1 &&
+            diff "$WORK/go.mod.before" go.mod >>"$LOGFILE"
+    }
+    run_test "go mod tidy — leaves the aigogo block unchanged" \
+        go_mod_tidy_check
+else
+    skip_test "go run — imports the installed Go package (go not installed)"
+    skip_test "go mod tidy — leaves the aigogo block unchanged (go not installed)"
+fi
+
+run_test_grep "aigg uninstall — removes the go.mod block" "Removed aigogo require and replace directives" \
+    "$AIGOGO" uninstall
+
+run_test "aigg uninstall — go.mod has no aigogo block" \
+    bash -c "! grep -q aigogo go.mod"
+
+popd >/dev/null
+
+# --- Rust consumer tests ---
+# Build a Rust package without a Cargo.toml and install it into a crate
+RUST_BUILD="$WORK/rust-mathx-build"
+mkdir -p "$RUST_BUILD"
+pushd "$RUST_BUILD" >/dev/null
+printf 'pub fn double(n: i32) -> i32 { n * 2 }\n' > lib.rs
+"$AIGOGO" init --no-detect >>"$LOGFILE" 2>&1
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['name'] = 'mathx'
+m['language'] = {'name': 'rust', 'version': '1.70'}
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+"$AIGOGO" add file lib.rs >>"$LOGFILE" 2>&1
+"$AIGOGO" build mathx:1.0.0 --force >>"$LOGFILE" 2>&1
+popd >/dev/null
+
+RUST_CONSUMER_DIR="$WORK/consumer-rust"
+mkdir -p "$RUST_CONSUMER_DIR/src"
+pushd "$RUST_CONSUMER_DIR" >/dev/null
+printf '[package]\nname = "app"\nversion = "0.1.0"\nedition = "2021"\n\n[dependencies]\n' > Cargo.toml
+printf 'fn main() { println!("{}", mathx::double(21)); }\n' > src/main.rs
+"$AIGOGO" add mathx:1.0.0 >>"$LOGFILE" 2>&1
+
+run_test_grep "aigg install — Rust use hint" "use mathx::" \
+    "$AIGOGO" install
+
+run_test_grep "aigg install — crate has a generated Cargo.toml" 'path = "lib.rs"' \
+    cat "$RUST_CONSUMER_DIR/.aigogo/imports/rust/mathx/Cargo.toml"
+
+run_test_grep "aigg install — Cargo.toml path dependency" "\[dependencies.mathx\]" \
+    cat Cargo.toml
+
+if command -v cargo >/dev/null 2>&1; then
+    run_test_grep "cargo run — uses the installed crate" "^42$" \
+        cargo run --offline -q
+else
+    skip_test "cargo run — uses the installed crate (cargo not installed)"
+fi
+
+run_test_grep "aigg uninstall — removes the Cargo.toml section" "Removed aigogo path dependencies" \
+    "$AIGOGO" uninstall
+
+run_test "aigg uninstall — Cargo.toml has no aigogo section" \
+    bash -c "! grep -q aigogo Cargo.toml"
+
+popd >/dev/null
+
+# --- Cross-package dependency conflicts ---
+# Two packages that need incompatible versions of requests
+for spec in "conflict-a:>=2.0,<2.20" "conflict-b:>=2.28"; do
+    name="${spec%%:*}"
+    CONFLICT_BUILD="$WORK/$name-build"
+    create_python_project "$CONFLICT_BUILD"
+    pushd "$CONFLICT_BUILD" >/dev/null
+    "$AIGOGO" init >>"$LOGFILE" 2>&1
+    python3 -c "
+import json, sys
+with open('aigogo.json') as f: m = json.load(f)
+m['name'] = sys.argv[1]
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" "$name" 2>>"$LOGFILE" || true
+    "$AIGOGO" add file utils.py >>"$LOGFILE" 2>&1
+    "$AIGOGO" add dep requests "${spec#*:}" >>"$LOGFILE" 2>&1
+    "$AIGOGO" build "$name:1.0.0" --force >>"$LOGFILE" 2>&1
+    popd >/dev/null
+done
+
+CONFLICT_DIR="$WORK/conflict-consumer"
+mkdir -p "$CONFLICT_DIR"
+pushd "$CONFLICT_DIR" >/dev/null
+"$AIGOGO" add conflict-a:1.0.0 >>"$LOGFILE" 2>&1
+"$AIGOGO" add conflict-b:1.0.0 >>"$LOGFILE" 2>&1
+
+run_test_fail_grep "aigg validate --lock (conflicting packages)" "requests \(python\): no version satisfies" \
+    "$AIGOGO" validate --lock
+
+run_test_grep "aigg install — warns about dependency conflicts" "Dependency conflicts between installed packages" \
+    "$AIGOGO" install
+
+run_test_grep "aigg tree — packages and their dependencies" "conflict_a@0\.1\.0 \(conflict-a:1\.0\.0\)" \
+    "$AIGOGO" tree
+
+run_test_grep "aigg tree — marks conflicting dependencies" "requests >=2\.28 \(python\) ⚠ conflict" \
+    "$AIGOGO" tree
+
+run_test "aigg --json tree — packages and conflicts" \
+    bash -c "'$AIGOGO' --json tree | python3 -c \"import json, sys; t = json.load(sys.stdin); assert len(t['packages']) == 2 and t['conflicts'][0]['dependency'] == 'requests' and t['packages'][0]['dependencies'][0]['conflict']\""
+
+run_test_grep "aigg --plain tree — ASCII branches" "^\`-- conflict_b@" \
+    "$AIGOGO" --plain tree
 
 popd >/dev/null
 
+run_test_fail_grep "aigg tree — no aigogo.lock" "aigogo.lock not found" \
+    bash -c "cd '$WORK' && mkdir -p tree-empty && cd tree-empty && '$AIGOGO' tree"
+
+run_test_grep "aigg validate --lock (no conflicts)" "No dependency conflicts" \
+    bash -c "cd '$CONSUMER_DIR' && '$AIGOGO' validate --lock"
+
 echo ""
 
 ###############################################################################
@@ -736,7 +2079,7 @@ CAS_HASH=$(echo "$CAS_INFO" | sed -n '1p')
 CAS_PKG_NAME=$(echo "$CAS_INFO" | sed -n '2p')
 CAS_PKG_LANG=$(echo "$CAS_INFO" | sed -n '3p')
 CAS_PREFIX="${CAS_HASH:0:2}"
-STORE_ROOT="$HOME/.aigogo/store"
+STORE_ROOT="$AIGG_DATA_DIR/store"
 STORE_PKG_DIR="$STORE_ROOT/sha256/$CAS_PREFIX/$CAS_HASH"
 
 # Check 1: Store directory structure exists
@@ -761,7 +2104,7 @@ with open('$lock_file') as f:
 for name, pkg in lock['packages'].items():
     h = pkg['integrity'].replace('sha256:', '')
     prefix = h[:2]
-    store_path = os.path.expanduser('~/.aigogo/store/sha256/' + prefix + '/' + h)
+    store_path = os.path.join('$STORE_ROOT', 'sha256', prefix, h)
     if not os.path.isdir(store_path):
         print('MISMATCH: lock hash %s not found at %s' % (h, store_path), file=sys.stderr)
         sys.exit(1)
@@ -852,97 +2195,616 @@ cas_symlink_check() {
     return 0
 }
 
-run_test "CAS: symlink exists at .aigogo/imports/aigogo/<pkg>" \
-    cas_symlink_check
+run_test "CAS: symlink exists at .aigogo/imports/aigogo/<pkg>" \
+    cas_symlink_check
+
+echo ""
+
+# Also test "aigg add <registry>/<name>:<tag>" if registry is available
+if $HAS_REGISTRY; then
+    CONSUMER_REG_DIR="$WORK/consumer-reg"
+    mkdir -p "$CONSUMER_REG_DIR"
+    pushd "$CONSUMER_REG_DIR" >/dev/null
+    run_test_grep "aigg add <registry>/<name>:<tag>" "Added" \
+        "$AIGOGO" add "$REGISTRY/$REG_REPO:1.0.0"
+    popd >/dev/null
+else
+    skip_test "aigg add <registry>/<name>:<tag> (needs registry)"
+fi
+
+echo ""
+
+###############################################################################
+#  SECTION: Exec Command
+###############################################################################
+echo "${BOLD}=== Exec Command ===${RESET}"
+
+# Build a Python agent with scripts
+EXEC_BUILD="$WORK/exec-build"
+create_python_project "$EXEC_BUILD"
+cat > "$EXEC_BUILD/run.py" <<'PYEOF'
+import sys
+print("hello from exec")
+if len(sys.argv) > 1:
+    print("args:", " ".join(sys.argv[1:]))
+PYEOF
+
+pushd "$EXEC_BUILD" >/dev/null
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+"$AIGOGO" add file utils.py >>"$LOGFILE" 2>&1
+"$AIGOGO" add file run.py >>"$LOGFILE" 2>&1
+
+# Add scripts field to manifest
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['name'] = 'exec-test-agent'
+m['scripts'] = {'exec-test-agent': 'run.py'}
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+
+"$AIGOGO" build exec-test-agent:1.0.0 --force >>"$LOGFILE" 2>&1
+popd >/dev/null
+
+EXEC_DIR="$WORK/exec-consumer"
+mkdir -p "$EXEC_DIR"
+pushd "$EXEC_DIR" >/dev/null
+
+"$AIGOGO" add exec-test-agent:1.0.0 >>"$LOGFILE" 2>&1
+"$AIGOGO" install >>"$LOGFILE" 2>&1
+
+run_test_grep "aigg exec <agent>" "hello from exec" \
+    "$AIGOGO" exec exec-test-agent
+
+run_test_grep "aigg exec <agent> with args" "args: foo bar" \
+    "$AIGOGO" exec exec-test-agent foo bar
+
+popd >/dev/null
+
+# Error cases
+EXEC_ERR="$WORK/exec-err"
+mkdir -p "$EXEC_ERR"
+pushd "$EXEC_ERR" >/dev/null
+
+run_test_fail_grep "aigg exec no args -> usage" "[Uu]sage:" \
+    "$AIGOGO" exec
+
+run_test_fail_grep "aigg exec unknown agent -> error" "aigogo.lock" \
+    "$AIGOGO" exec nonexistent-agent
+
+popd >/dev/null
+
+# Agent without scripts field
+EXEC_NO_SCRIPTS="$WORK/exec-no-scripts"
+mkdir -p "$EXEC_NO_SCRIPTS"
+pushd "$EXEC_NO_SCRIPTS" >/dev/null
+
+# Reuse consumer-pkg (built earlier, has no scripts)
+"$AIGOGO" add consumer-pkg:1.0.0 >>"$LOGFILE" 2>&1
+"$AIGOGO" install >>"$LOGFILE" 2>&1
+
+run_test_fail_grep "aigg exec agent without scripts -> error" "no scripts defined" \
+    "$AIGOGO" exec consumer-pkg
+
+popd >/dev/null
+
+# --- lifecycle scripts (prebuild / postbuild / postinstall) ---
+HOOKS_BUILD="$WORK/hooks-build"
+create_python_project "$HOOKS_BUILD"
+pushd "$HOOKS_BUILD" >/dev/null
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+"$AIGOGO" add file utils.py >>"$LOGFILE" 2>&1
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['name'] = 'hooks-pkg'
+m['scripts'] = {
+    'prebuild': 'echo \"prebuild \$AIGOGO_PACKAGE_NAME \$AIGOGO_PACKAGE_VERSION\"',
+    'postbuild': 'echo \"postbuild \$AIGOGO_IMAGE_REF\"',
+    'postinstall': 'echo \"postinstall \$AIGOGO_PACKAGE_NAME\" > postinstall.txt',
+}
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+
+run_test_grep "aigg build — runs prebuild script" "prebuild hooks-pkg 1.0.0" \
+    "$AIGOGO" build hooks-pkg:1.0.0 --force
+
+run_test_grep "aigg build — runs postbuild script" "postbuild hooks-pkg:1.0.0" \
+    "$AIGOGO" build hooks-pkg:1.0.0 --force
+
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['scripts']['prebuild'] = 'exit 3'
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+
+run_test_fail_grep "aigg build — fails when prebuild fails" "prebuild script failed" \
+    "$AIGOGO" build hooks-pkg:1.0.0 --force
+
+run_test_grep "aigg build --ignore-scripts" "Successfully built" \
+    "$AIGOGO" build hooks-pkg:1.0.0 --force --ignore-scripts
+popd >/dev/null
+
+HOOKS_CONSUMER="$WORK/hooks-consumer"
+mkdir -p "$HOOKS_CONSUMER"
+pushd "$HOOKS_CONSUMER" >/dev/null
+"$AIGOGO" add hooks-pkg:1.0.0 >>"$LOGFILE" 2>&1
+
+run_test_grep "aigg install --ignore-scripts — lists postinstall" "Skipped postinstall script for hooks-pkg" \
+    "$AIGOGO" install --ignore-scripts
+
+run_test "aigg install --ignore-scripts — postinstall not run" \
+    test ! -f postinstall.txt
+
+run_test_grep "aigg install — runs postinstall script" "hooks-pkg postinstall" \
+    "$AIGOGO" install
+
+run_test_grep "aigg install — postinstall runs in project dir" "postinstall hooks-pkg" \
+    cat postinstall.txt
+popd >/dev/null
+
+# --- per-file attributes (executable / template / data) ---
+ATTRS_BUILD="$WORK/attrs-build"
+create_python_project "$ATTRS_BUILD"
+pushd "$ATTRS_BUILD" >/dev/null
+cat > cli.py <<'PYEOF'
+#!/usr/bin/env python3
+print("cli")
+PYEOF
+mkdir -p fixtures
+cat > fixtures/sample.py <<'PYEOF'
+import pandas
+PYEOF
+cat > config.yaml.template <<'TPLEOF'
+package: {{.Package.Name}}
+project: {{.Project.Name}}
+TPLEOF
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['name'] = 'attrs-pkg'
+m['files']['include'] = ['utils.py', 'cli.py', 'fixtures/*.py', 'config.yaml.template']
+m['files']['attributes'] = [
+    {'path': 'cli.py', 'executable': True},
+    {'path': 'fixtures/*.py', 'data': True},
+    {'path': 'config.yaml.template', 'template': True},
+]
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+
+run_test_grep "aigg validate — data files not scanned for imports" "Validation passed" \
+    "$AIGOGO" validate
+
+run_test_grep "aigg build — package with file attributes" "Successfully built" \
+    "$AIGOGO" build attrs-pkg:1.0.0 --force
+popd >/dev/null
+
+ATTRS_CONSUMER="$WORK/attrs-consumer"
+mkdir -p "$ATTRS_CONSUMER"
+pushd "$ATTRS_CONSUMER" >/dev/null
+"$AIGOGO" add attrs-pkg:1.0.0 >>"$LOGFILE" 2>&1
+
+run_test_grep "aigg install — lists templates without rendering" "templates: 1" \
+    "$AIGOGO" install
+
+run_test "aigg install — executable attribute sets the x bit" \
+    test -x .aigogo/imports/aigogo/attrs_pkg/cli.py
+
+run_test "aigg install — templates not rendered by default" \
+    test ! -f config.yaml
+
+run_test_grep "aigg install --render-templates" "rendered config.yaml" \
+    "$AIGOGO" install --render-templates
+
+run_test_grep "aigg install --render-templates — fills in package name" "package: attrs-pkg" \
+    cat config.yaml
+
+run_test_grep "aigg install --render-templates — keeps existing file" "kept existing config.yaml" \
+    "$AIGOGO" install --render-templates
+popd >/dev/null
+
+# --- environment constraints ---
+ENV_BUILD="$WORK/env-build"
+create_python_project "$ENV_BUILD"
+pushd "$ENV_BUILD" >/dev/null
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+"$AIGOGO" add file utils.py >>"$LOGFILE" 2>&1
+# Constrain the package to an OS this machine isn't running
+python3 -c "
+import json, sys
+with open('aigogo.json') as f: m = json.load(f)
+m['name'] = 'env-pkg'
+m['environment'] = {'os': ['windows' if sys.platform != 'win32' else 'linux']}
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+
+run_test_grep "aigg build — package with environment constraints" "Successfully built" \
+    "$AIGOGO" build env-pkg:1.0.0 --force
+
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['environment'] = {'os': ['macos']}
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+
+run_test_fail_grep "aigg validate — rejects unknown environment.os" "invalid environment.os" \
+    "$AIGOGO" validate
+popd >/dev/null
+
+ENV_CONSUMER="$WORK/env-consumer"
+mkdir -p "$ENV_CONSUMER"
+pushd "$ENV_CONSUMER" >/dev/null
+
+run_test_fail_grep "aigg add — refuses package for another OS" "can't run on this machine" \
+    "$AIGOGO" add env-pkg:1.0.0
+
+run_test "aigg add — refused package not locked" \
+    test ! -f aigogo.lock
+
+run_test_grep "aigg add --force — adds package for another OS" "may not run on this machine" \
+    "$AIGOGO" add env-pkg:1.0.0 --force
+
+run_test_fail_grep "aigg install — refuses package for another OS" "requires os" \
+    "$AIGOGO" install
+
+run_test_grep "aigg install --force — installs package for another OS" "Installed 1 package" \
+    "$AIGOGO" install --force
+popd >/dev/null
+
+# --- peer dependencies ---
+PEER_BUILD="$WORK/peer-build"
+create_python_project "$PEER_BUILD"
+cat > "$PEER_BUILD/model.py" <<'PYEOF'
+import aigg_qa_missing_peer
+PYEOF
+pushd "$PEER_BUILD" >/dev/null
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['name'] = 'peer-pkg'
+m['files']['include'] = ['utils.py', 'model.py']
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+
+run_test_grep "aigg add peer <pkg> <ver>" "Added aigg_qa_missing_peer >=1.0 to peer dependencies" \
+    "$AIGOGO" add peer aigg_qa_missing_peer ">=1.0"
+
+run_test_fail_grep "aigg add dep — refuses a declared peer dependency" "already declared as a peer dependency" \
+    "$AIGOGO" add dep aigg_qa_missing_peer ">=1.0"
+
+run_test_fail_grep "aigg add peer --from-requirements — rejected" "peer dependencies can't be imported" \
+    "$AIGOGO" add peer --from-requirements
+
+if command -v python3 >/dev/null 2>&1; then
+    run_test_grep "aigg validate — warns about a missing peer dependency" "Peer dependency aigg_qa_missing_peer >=1.0 is not installed" \
+        "$AIGOGO" validate
+else
+    skip_test "aigg validate — warns about a missing peer dependency (python3 not found)"
+fi
+
+run_test_grep "aigg show-deps — lists peer dependencies" "Peer Dependencies \(1" \
+    "$AIGOGO" show-deps .
+
+run_test_grep "aigg build — package with peer dependencies" "Successfully built" \
+    "$AIGOGO" build peer-pkg:1.0.0 --force
+popd >/dev/null
+
+PEER_CONSUMER="$WORK/peer-consumer"
+mkdir -p "$PEER_CONSUMER"
+pushd "$PEER_CONSUMER" >/dev/null
+"$AIGOGO" add peer-pkg:1.0.0 >>"$LOGFILE" 2>&1
+
+if command -v python3 >/dev/null 2>&1; then
+    run_test_grep "aigg install — warns about a missing peer dependency" "peer_pkg: aigg_qa_missing_peer >=1.0 is not installed" \
+        "$AIGOGO" install
+else
+    skip_test "aigg install — warns about a missing peer dependency (python3 not found)"
+fi
+popd >/dev/null
+
+pushd "$PEER_BUILD" >/dev/null
+run_test_grep "aigg rm peer <pkg>" "Removed aigg_qa_missing_peer" \
+    "$AIGOGO" rm peer aigg_qa_missing_peer
+popd >/dev/null
+
+# --- dependency extras ---
+EXTRAS_BUILD="$WORK/extras-build"
+create_python_project "$EXTRAS_BUILD"
+pushd "$EXTRAS_BUILD" >/dev/null
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['name'] = 'extras-pkg'
+m['files']['include'] = ['utils.py']
+m['dependencies'] = {'extras': {'viz': [{'package': 'matplotlib', 'version': '>=3.7'}]}}
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+
+run_test_grep "aigg show-deps --format pyproject — extras get their own group" "aigogo-viz = \\[" \
+    "$AIGOGO" show-deps . --format pyproject
+
+run_test_grep "aigg build — package with extras" "Successfully built" \
+    "$AIGOGO" build extras-pkg:1.0.0 --force
+popd >/dev/null
+
+EXTRAS_CONSUMER="$WORK/extras-consumer"
+mkdir -p "$EXTRAS_CONSUMER"
+pushd "$EXTRAS_CONSUMER" >/dev/null
+run_test_fail_grep "aigg add <ref>[extra] — unknown extra refused" "no extra \"gpu\" \(available: viz\)" \
+    "$AIGOGO" add "extras-pkg:1.0.0[gpu]"
+
+run_test_grep "aigg add <ref>[extra] — selects an extra" "Extras: viz" \
+    "$AIGOGO" add "extras-pkg:1.0.0[viz]"
+
+run_test_grep "aigg add <ref> — keeps the selected extras" "Extras: viz" \
+    "$AIGOGO" add extras-pkg:1.0.0
+
+run_test_grep "aigogo.lock — records the selected extras" '"extras": \[' \
+    cat aigogo.lock
+popd >/dev/null
+
+# --- deprecation ---
+DEP_BUILD="$WORK/deprecated-build"
+create_python_project "$DEP_BUILD"
+pushd "$DEP_BUILD" >/dev/null
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['name'] = 'old-pkg'
+m['files']['include'] = ['utils.py']
+m['deprecated'] = {'message': 'No longer maintained', 'replacement': 'new-pkg:2.0.0'}
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+"$AIGOGO" build old-pkg:1.0.0 --force >>"$LOGFILE" 2>&1
+popd >/dev/null
+
+DEP_CONSUMER="$WORK/deprecated-consumer"
+mkdir -p "$DEP_CONSUMER"
+pushd "$DEP_CONSUMER" >/dev/null
+run_test_fail_grep "aigg add --strict — refuses a deprecated package" "old-pkg@[0-9.]+ is deprecated: No longer maintained" \
+    "$AIGOGO" add old-pkg:1.0.0 --strict
+
+run_test_grep "aigg add — warns about a deprecated package" "DEPRECATED: old-pkg@" \
+    "$AIGOGO" add old-pkg:1.0.0
+
+run_test_grep "aigg install — warns about a deprecated package" "Use instead: new-pkg:2.0.0" \
+    "$AIGOGO" install
+
+run_test_fail_grep "aigg install --strict — fails on a deprecated package" "is deprecated" \
+    "$AIGOGO" install --strict
+popd >/dev/null
+
+run_test_fail_grep "aigg push --deprecate — local reference refused" "is a local reference" \
+    "$AIGOGO" push old-pkg:1.0.0 --deprecate "No longer maintained"
+
+# --- manifest inheritance (extends) ---
+EXT_REPO="$WORK/ext-repo"
+create_python_project "$EXT_REPO/pkg"
+cat > "$EXT_REPO/aigogo.base.json" <<'JSONEOF'
+{
+  "version": "0.1.0",
+  "author": "Shared Team",
+  "language": {"name": "python", "version": ">=3.8"},
+  "files": {"include": ["utils.py"]},
+  "metadata": {"license": "MIT"}
+}
+JSONEOF
+cat > "$EXT_REPO/pkg/aigogo.json" <<'JSONEOF'
+{
+  "extends": "../aigogo.base.json",
+  "name": "ext-pkg",
+  "description": "Inherits from the repository base"
+}
+JSONEOF
+pushd "$EXT_REPO/pkg" >/dev/null
+
+run_test_grep "aigg validate — manifest with extends" "Validation passed" \
+    "$AIGOGO" validate
+
+run_test "aigg validate --schema — extends makes required fields optional" \
+    "$AIGOGO" validate --schema
+
+run_test_grep "aigg build — package with extends" "Successfully built" \
+    "$AIGOGO" build
+
+run_test "aigg build — aigogo.json keeps only its own fields" \
+    python3 -c "
+import json
+m = json.load(open('aigogo.json'))
+assert m['extends'] == '../aigogo.base.json' and m['version'] == '0.1.1', m
+assert 'author' not in m and 'license' not in m.get('metadata', {}), m
+"
+
+run_test_grep "aigg info — packaged manifest is merged" "Author: Shared Team" \
+    "$AIGOGO" info ext-pkg:0.1.1
+popd >/dev/null
+
+EXT_CYCLE="$WORK/ext-cycle"
+mkdir -p "$EXT_CYCLE"
+echo '{"extends": "./b.json"}' > "$EXT_CYCLE/a.json"
+echo '{"extends": "./a.json"}' > "$EXT_CYCLE/b.json"
+echo '{"extends": "./a.json", "name": "cycle-pkg"}' > "$EXT_CYCLE/aigogo.json"
+pushd "$EXT_CYCLE" >/dev/null
+run_test_fail_grep "aigg validate — extends cycle" "extends cycle: .*a.json -> .*b.json -> .*a.json" \
+    "$AIGOGO" validate
+popd >/dev/null
+
+# --- multi-language package (python + javascript) ---
+MULTI_BUILD="$WORK/multi-build"
+create_python_project "$MULTI_BUILD"
+pushd "$MULTI_BUILD" >/dev/null
+cat > client.js <<'JSEOF'
+const axios = require('axios');
+module.exports = { get: (url) => axios.get(url) };
+JSEOF
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['name'] = 'multi-pkg'
+m['language']['version'] = '>=3.8'
+m['files'] = {'include': 'auto'}
+m['languages'] = [{'name': 'javascript', 'version': '>=18'}]
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+
+run_test_fail_grep "aigg add dep --language ruby -> not a package language" "not one of the package's languages" \
+    "$AIGOGO" add dep --language ruby rake "~> 13.0"
+
+run_test_fail_grep "aigg validate (multi-language) — JS import checked" "axios" \
+    "$AIGOGO" validate
+
+run_test_grep "aigg add dep --language javascript" "axios" \
+    "$AIGOGO" add dep --language javascript axios "^1.6.0"
+
+run_test_grep "aigogo.json — dependency records its language" '"language": "javascript"' \
+    cat aigogo.json
+
+run_test_grep "aigg validate (multi-language) — passes" "Validation passed" \
+    "$AIGOGO" validate
+
+run_test_grep "aigg show-deps --format npm — only JS deps" "axios" \
+    "$AIGOGO" show-deps . --format npm
 
-echo ""
+run_test_grep "aigg build (multi-language)" "Successfully built" \
+    "$AIGOGO" build multi-pkg:1.0.0 --force
+popd >/dev/null
 
-# Also test "aigg add <registry>/<name>:<tag>" if registry is available
-if $HAS_REGISTRY; then
-    CONSUMER_REG_DIR="$WORK/consumer-reg"
-    mkdir -p "$CONSUMER_REG_DIR"
-    pushd "$CONSUMER_REG_DIR" >/dev/null
-    run_test_grep "aigg add <registry>/<name>:<tag>" "Added" \
-        "$AIGOGO" add "$REGISTRY/$REG_REPO:1.0.0"
-    popd >/dev/null
-else
-    skip_test "aigg add <registry>/<name>:<tag> (needs registry)"
-fi
+MULTI_CONSUMER="$WORK/multi-consumer"
+mkdir -p "$MULTI_CONSUMER"
+pushd "$MULTI_CONSUMER" >/dev/null
+"$AIGOGO" add multi-pkg:1.0.0 >>"$LOGFILE" 2>&1
 
-echo ""
+run_test_grep "aigogo.lock — records per-language files" '"languages"' \
+    cat aigogo.lock
 
-###############################################################################
-#  SECTION: Exec Command
-###############################################################################
-echo "${BOLD}=== Exec Command ===${RESET}"
+run_test "aigg install (multi-language)" \
+    "$AIGOGO" install
 
-# Build a Python agent with scripts
-EXEC_BUILD="$WORK/exec-build"
-create_python_project "$EXEC_BUILD"
-cat > "$EXEC_BUILD/run.py" <<'PYEOF'
-import sys
-print("hello from exec")
-if len(sys.argv) > 1:
-    print("args:", " ".join(sys.argv[1:]))
-PYEOF
+run_test "aigg install — python link for multi-language package" \
+    test -e .aigogo/imports/aigogo/multi_pkg/utils.py
 
-pushd "$EXEC_BUILD" >/dev/null
+run_test "aigg install — javascript link for multi-language package" \
+    test -e .aigogo/imports/@aigogo/multi_pkg/client.js
+popd >/dev/null
+
+# --- exports (stable import names) ---
+EXPORTS_BUILD="$WORK/exports-build"
+create_python_project "$EXPORTS_BUILD"
+pushd "$EXPORTS_BUILD" >/dev/null
+echo 'module.exports = { run: () => "cli" };' > cli.js
 "$AIGOGO" init >>"$LOGFILE" 2>&1
-"$AIGOGO" add file utils.py >>"$LOGFILE" 2>&1
-"$AIGOGO" add file run.py >>"$LOGFILE" 2>&1
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['name'] = 'exports-pkg'
+m['files'] = {'include': ['utils.py', 'helpers.py', 'cli.js']}
+m['languages'] = [{'name': 'javascript', 'version': '>=18'}]
+m['exports'] = {'.': 'utils.py', 'tools': 'helpers.py', 'cli': 'missing.js'}
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+
+run_test_fail_grep "aigg build — export of a file not packaged" 'exports.cli references file "missing.js"' \
+    "$AIGOGO" build exports-pkg:1.0.0 --force
 
-# Add scripts field to manifest
 python3 -c "
 import json
 with open('aigogo.json') as f: m = json.load(f)
-m['name'] = 'exec-test-agent'
-m['scripts'] = {'exec-test-agent': 'run.py'}
+m['exports']['cli'] = 'cli.js'
 with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
 " 2>>"$LOGFILE" || true
 
-"$AIGOGO" build exec-test-agent:1.0.0 --force >>"$LOGFILE" 2>&1
+run_test_grep "aigg build (exports)" "Successfully built" \
+    "$AIGOGO" build exports-pkg:1.0.0 --force
 popd >/dev/null
 
-EXEC_DIR="$WORK/exec-consumer"
-mkdir -p "$EXEC_DIR"
-pushd "$EXEC_DIR" >/dev/null
+EXPORTS_CONSUMER="$WORK/exports-consumer"
+mkdir -p "$EXPORTS_CONSUMER"
+pushd "$EXPORTS_CONSUMER" >/dev/null
+"$AIGOGO" add exports-pkg:1.0.0 >>"$LOGFILE" 2>&1
 
-"$AIGOGO" add exec-test-agent:1.0.0 >>"$LOGFILE" 2>&1
-"$AIGOGO" install >>"$LOGFILE" 2>&1
+run_test_grep "aigg install — shows exported import names" "from aigogo.exports_pkg.tools import" \
+    "$AIGOGO" install
 
-run_test_grep "aigg exec <agent>" "hello from exec" \
-    "$AIGOGO" exec exec-test-agent
+run_test "aigg install — Python imports exported names" \
+    env PYTHONPATH=.aigogo/imports python3 -c "from aigogo.exports_pkg import hello; from aigogo.exports_pkg.tools import helper; hello(); helper()"
 
-run_test_grep "aigg exec <agent> with args" "args: foo bar" \
-    "$AIGOGO" exec exec-test-agent foo bar
+exports_require_check() {
+    if ! command -v node >/dev/null 2>&1; then
+        echo "node not found, skipping" >>"$LOGFILE"
+        return 0
+    fi
+    NODE_PATH=.aigogo/imports node -e "require('@aigogo/exports_pkg/cli').run()" 2>>"$LOGFILE"
+}
 
-popd >/dev/null
+run_test "aigg install — Node requires exported names" \
+    exports_require_check
 
-# Error cases
-EXEC_ERR="$WORK/exec-err"
-mkdir -p "$EXEC_ERR"
-pushd "$EXEC_ERR" >/dev/null
+# A project namespace replaces aigogo and @aigogo
+echo '{"namespace": {"python": "ourco.snippets", "javascript": "@ourco"}}' > aigogo.json
+run_test_grep "aigg install — configured namespace in import hints" "from ourco.snippets.exports_pkg.tools import" \
+    "$AIGOGO" install
 
-run_test_fail_grep "aigg exec no args -> usage" "[Uu]sage:" \
-    "$AIGOGO" exec
+run_test "aigg install — Python imports from the configured namespace" \
+    env PYTHONPATH=.aigogo/imports python3 -c "from ourco.snippets.exports_pkg.tools import helper; helper()"
 
-run_test_fail_grep "aigg exec unknown agent -> error" "aigogo.lock" \
-    "$AIGOGO" exec nonexistent-agent
+namespace_require_check() {
+    if ! command -v node >/dev/null 2>&1; then
+        echo "node not found, skipping" >>"$LOGFILE"
+        return 0
+    fi
+    node --require ./.aigogo/register.js -e "require('@ourco/exports_pkg/cli').run()" 2>>"$LOGFILE" &&
+        echo "import cli from '@ourco/exports_pkg/cli'; cli.run();" > ns.mjs &&
+        node --import ./.aigogo/register.mjs ns.mjs 2>>"$LOGFILE"
+}
 
-popd >/dev/null
+run_test "aigg install — Node requires and imports from the configured scope" \
+    namespace_require_check
 
-# Agent without scripts field
-EXEC_NO_SCRIPTS="$WORK/exec-no-scripts"
-mkdir -p "$EXEC_NO_SCRIPTS"
-pushd "$EXEC_NO_SCRIPTS" >/dev/null
+run_test_grep "aigg install — Deno import map uses the configured scope" '"@ourco/exports_pkg/cli"' \
+    cat .aigogo/import_map.json
 
-# Reuse consumer-pkg (built earlier, has no scripts)
-"$AIGOGO" add consumer-pkg:1.0.0 >>"$LOGFILE" 2>&1
-"$AIGOGO" install >>"$LOGFILE" 2>&1
+echo '{"namespace": {"javascript": "ourco"}}' > aigogo.json
+run_test_grep "aigg install — invalid namespace falls back to the default" "ignoring project settings: .*invalid namespace.javascript" \
+    "$AIGOGO" install
+rm -f aigogo.json ns.mjs
+
+# [tool.aigogo] in pyproject.toml, with aigogo.json taking precedence
+cat > pyproject.toml <<'TOMLEOF'
+[project]
+name = "exports-consumer"
+
+[tool.aigogo]
+store = ".aigogo-store"
+
+[tool.aigogo.namespace]
+python = "frompyproject"
+
+[tool.aigogo.install]
+mode = "copy"
+TOMLEOF
+echo '{"namespace": {"python": "fromjson"}}' > aigogo.json
+run_test_grep "aigg add — [tool.aigogo] settings, aigogo.json wins" "from fromjson.exports_pkg import" \
+    "$AIGOGO" add exports-pkg:1.0.0
+run_test_grep "aigg install — [tool.aigogo] settings, aigogo.json wins" "from fromjson.exports_pkg import" \
+    "$AIGOGO" install
 
-run_test_fail_grep "aigg exec agent without scripts -> error" "no scripts defined" \
-    "$AIGOGO" exec consumer-pkg
+run_test "aigg install — [tool.aigogo] install.mode copy copies files" \
+    bash -c "test -f .aigogo/imports/fromjson/exports_pkg/utils.py && ! test -L .aigogo/imports/fromjson/exports_pkg/utils.py"
 
+run_test "aigg install — [tool.aigogo] store is used" \
+    test -d .aigogo-store/sha256
+
+run_test "aigg install — Python imports a copied package" \
+    env PYTHONPATH=.aigogo/imports python3 -c "from fromjson.exports_pkg import hello; hello()"
+rm -rf aigogo.json pyproject.toml .aigogo-store
+"$AIGOGO" install >>"$LOGFILE" 2>&1
 popd >/dev/null
 
 echo ""
@@ -957,15 +2819,15 @@ run_test_grep "aigg clean (disk usage)" "aigogo disk usage" \
     "$AIGOGO" clean
 
 # Create an env dir to test cleanup
-mkdir -p "$HOME/.aigogo/envs/test-cleanup-hash"
-echo "test" > "$HOME/.aigogo/envs/test-cleanup-hash/marker"
+mkdir -p "$AIGG_DATA_DIR/envs/test-cleanup-hash"
+echo "test" > "$AIGG_DATA_DIR/envs/test-cleanup-hash/marker"
 
 run_test_grep "aigg clean --envs" "Removed exec environments" \
     "$AIGOGO" clean --envs
 
 # Verify envs dir was removed
 clean_envs_check() {
-    if [ -d "$HOME/.aigogo/envs/test-cleanup-hash" ]; then
+    if [ -d "$AIGG_DATA_DIR/envs/test-cleanup-hash" ]; then
         echo "env dir still exists after clean --envs" >>"$LOGFILE"
         return 1
     fi
@@ -977,6 +2839,53 @@ run_test "aigg clean --envs removes directory" \
 
 echo ""
 
+###############################################################################
+#  SECTION: User Directories
+###############################################################################
+echo "${BOLD}=== User Directories ===${RESET}"
+
+if [[ "$(uname)" == Darwin ]]; then
+    skip_test "XDG directories and aigg migrate" "macOS keeps ~/.aigogo"
+else
+    # A fresh home uses the XDG directories and never creates ~/.aigogo
+    XDG_HOME="$WORK/xdg-home"
+    mkdir -p "$XDG_HOME"
+    echo secret | env HOME="$XDG_HOME" "$AIGOGO" login localhost:1 -u qa-user -p >>"$LOGFILE" 2>&1
+    run_test_grep "aigg config set — a new home writes ~/.config/aigogo/config.toml" "in $XDG_HOME/\.config/aigogo/config.toml" \
+        env HOME="$XDG_HOME" "$AIGOGO" config set color never
+    run_test "aigg login — a new home writes ~/.config/aigogo/auth.json, no ~/.aigogo" \
+        bash -c 'test -f "$0/.config/aigogo/auth.json" && test ! -e "$0/.aigogo"' "$XDG_HOME"
+    run_test_grep "aigg clean — shows the XDG data and cache directories" "$XDG_HOME/\.cache/aigogo/packages" \
+        env HOME="$XDG_HOME" "$AIGOGO" clean
+    run_test_grep "aigg clean — XDG_DATA_HOME moves the store" "$WORK/xdg-data/aigogo/store" \
+        env HOME="$XDG_HOME" XDG_DATA_HOME="$WORK/xdg-data" "$AIGOGO" clean
+    run_test_grep "aigg migrate — nothing to migrate without ~/.aigogo" "Nothing to migrate" \
+        env HOME="$XDG_HOME" "$AIGOGO" migrate
+
+    # An existing ~/.aigogo stays in use until aigg migrate moves it
+    LEGACY_HOME="$WORK/legacy-home"
+    mkdir -p "$LEGACY_HOME/.aigogo/templates/qa-template"
+    echo secret | env HOME="$LEGACY_HOME" "$AIGOGO" login localhost:1 -u qa-legacy -p >>"$LOGFILE" 2>&1
+    run_test_grep "aigg config set — an existing ~/.aigogo keeps being used" "in $LEGACY_HOME/\.aigogo/config.toml" \
+        env HOME="$LEGACY_HOME" "$AIGOGO" config set color never
+    run_test_grep "aigg migrate --dry-run — lists the moves" "Would move $LEGACY_HOME/\.aigogo/auth.json → $LEGACY_HOME/\.config/aigogo/auth.json" \
+        env HOME="$LEGACY_HOME" "$AIGOGO" migrate --dry-run
+    run_test "aigg migrate --dry-run — moves nothing" \
+        test -f "$LEGACY_HOME/.aigogo/auth.json"
+    touch "$LEGACY_HOME/.aigogo/notes.txt"
+    run_test_fail_grep "aigg migrate — refuses with an unknown file" "doesn't know where to put" \
+        env HOME="$LEGACY_HOME" "$AIGOGO" migrate
+    rm "$LEGACY_HOME/.aigogo/notes.txt"
+    run_test_grep "aigg migrate — moves ~/.aigogo" "Removed $LEGACY_HOME/\.aigogo$" \
+        env HOME="$LEGACY_HOME" "$AIGOGO" migrate
+    run_test "aigg migrate — config, logins and templates moved" \
+        bash -c 'test -f "$0/.config/aigogo/config.toml" && test -f "$0/.config/aigogo/auth.json" && test -d "$0/.config/aigogo/templates/qa-template" && test ! -e "$0/.aigogo"' "$LEGACY_HOME"
+    run_test_grep "aigg whoami — finds the migrated login" "localhost:1: qa-legacy" \
+        env HOME="$LEGACY_HOME" "$AIGOGO" whoami --no-check
+fi
+
+echo ""
+
 ###############################################################################
 #  SECTION: show-deps Formats
 ###############################################################################
@@ -1032,6 +2941,37 @@ run_test_grep "show-deps --format pep621" "aigogo = " \
 run_test_grep "show-deps --format poetry" "group.aigogo.dependencies" \
     "$AIGOGO" show-deps "$PY_MANIFEST" --format poetry
 
+run_test_grep "show-deps --format uv" "uv add --optional aigogo " \
+    "$AIGOGO" show-deps "$PY_MANIFEST" --format uv
+
+# PEP 735 dependency groups via generator.python
+SHOWDEPS_PY_GROUPS="$WORK/showdeps-py-groups"
+mkdir -p "$SHOWDEPS_PY_GROUPS"
+python3 -c "
+import json, sys
+with open(sys.argv[1]) as f: m = json.load(f)
+m['generator'] = {'python': sys.argv[3]}
+with open(sys.argv[2], 'w') as f: json.dump(m, f, indent=2)
+" "$PY_MANIFEST" "$SHOWDEPS_PY_GROUPS/aigogo.json" dependency-groups 2>>"$LOGFILE" || true
+
+run_test_grep "show-deps --format pyproject (dependency-groups)" "^\[dependency-groups\]" \
+    "$AIGOGO" show-deps "$SHOWDEPS_PY_GROUPS" --format pyproject
+
+run_test_grep "show-deps --format uv (dependency-groups)" "uv add --group aigogo-dev " \
+    "$AIGOGO" show-deps "$SHOWDEPS_PY_GROUPS" --format uv
+
+SHOWDEPS_PY_BADSTYLE="$WORK/showdeps-py-badstyle"
+mkdir -p "$SHOWDEPS_PY_BADSTYLE"
+python3 -c "
+import json, sys
+with open(sys.argv[1]) as f: m = json.load(f)
+m['generator'] = {'python': sys.argv[3]}
+with open(sys.argv[2], 'w') as f: json.dump(m, f, indent=2)
+" "$PY_MANIFEST" "$SHOWDEPS_PY_BADSTYLE/aigogo.json" extras 2>>"$LOGFILE" || true
+
+run_test_fail_grep "show-deps invalid generator.python -> error" "invalid generator.python" \
+    "$AIGOGO" show-deps "$SHOWDEPS_PY_BADSTYLE" --format pyproject
+
 run_test_grep "show-deps --format npm (JS)" "managedDependencies" \
     "$AIGOGO" show-deps "$JS_MANIFEST" --format npm
 
@@ -1041,6 +2981,27 @@ run_test_grep "show-deps --format package-json (JS)" "managedDependencies" \
 run_test_grep "show-deps --format yarn (JS)" "aigogo-managed" \
     "$AIGOGO" show-deps "$JS_MANIFEST" --format yarn
 
+run_test_grep "show-deps --format yarn-berry (JS)" "nodeLinker: node-modules" \
+    "$AIGOGO" show-deps "$JS_MANIFEST" --format yarn-berry
+
+run_test_grep "show-deps --format pnpm (JS)" "pnpm add --save-dev " \
+    "$AIGOGO" show-deps "$JS_MANIFEST" --format pnpm
+
+run_test_grep "show-deps --format pnpm-workspace (JS)" "^  aigogo:" \
+    "$AIGOGO" show-deps "$JS_MANIFEST" --format pnpm-workspace
+
+SHOWDEPS_JS_BADPM="$WORK/showdeps-js-badpm"
+mkdir -p "$SHOWDEPS_JS_BADPM"
+python3 -c "
+import json, sys
+with open(sys.argv[1]) as f: m = json.load(f)
+m['generator'] = {'javascript': 'bower'}
+with open(sys.argv[2], 'w') as f: json.dump(m, f, indent=2)
+" "$JS_MANIFEST" "$SHOWDEPS_JS_BADPM/aigogo.json" 2>>"$LOGFILE" || true
+
+run_test_fail_grep "show-deps invalid generator.javascript -> error" "invalid generator.javascript" \
+    "$AIGOGO" show-deps "$SHOWDEPS_JS_BADPM" --format pnpm
+
 run_test_grep "show-deps <dir> (directory)" "Package:" \
     "$AIGOGO" show-deps "$SHOWDEPS_PY"
 
@@ -1051,6 +3012,106 @@ run_test_fail_grep "Python format on JS package -> error" "only supported for Py
 run_test_fail_grep "JS format on Python package -> error" "only supported for JavaScript" \
     "$AIGOGO" show-deps "$PY_MANIFEST" --format npm
 
+# Ruby and Java manifests for show-deps
+SHOWDEPS_RB="$WORK/showdeps-rb"
+mkdir -p "$SHOWDEPS_RB"
+cat > "$SHOWDEPS_RB/aigogo.json" <<'RBEOF'
+{
+  "name": "showdeps-rb",
+  "version": "1.0.0",
+  "language": {"name": "ruby", "version": ">= 3.0"},
+  "dependencies": {
+    "runtime": [{"package": "faraday", "version": "~> 2.7"}],
+    "dev": [{"package": "rspec", "version": "~> 3.12"}]
+  },
+  "files": {"include": "auto"}
+}
+RBEOF
+
+SHOWDEPS_JAVA="$WORK/showdeps-java"
+mkdir -p "$SHOWDEPS_JAVA"
+cat > "$SHOWDEPS_JAVA/aigogo.json" <<'JAVAEOF'
+{
+  "name": "showdeps-java",
+  "version": "1.0.0",
+  "language": {"name": "java", "version": ">=17"},
+  "dependencies": {
+    "runtime": [{"package": "com.google.code.gson:gson", "version": "2.10.1"}],
+    "dev": [{"package": "org.junit.jupiter:junit-jupiter", "version": "5.10.0"}]
+  },
+  "files": {"include": "auto"}
+}
+JAVAEOF
+
+run_test_grep "show-deps --format gemfile (Ruby)" "group :aigogo do" \
+    "$AIGOGO" show-deps "$SHOWDEPS_RB" --format gemfile
+
+run_test_grep "show-deps --format bundler (Ruby)" "gem \"faraday\"" \
+    "$AIGOGO" show-deps "$SHOWDEPS_RB" --format bundler
+
+run_test_grep "show-deps --format maven (Java)" "<artifactId>gson</artifactId>" \
+    "$AIGOGO" show-deps "$SHOWDEPS_JAVA" --format maven
+
+run_test_grep "show-deps --format pom (Java)" "<scope>test</scope>" \
+    "$AIGOGO" show-deps "$SHOWDEPS_JAVA" --format pom
+
+run_test_grep "show-deps --format gradle (Java)" "implementation 'com.google.code.gson:gson:2.10.1'" \
+    "$AIGOGO" show-deps "$SHOWDEPS_JAVA" --format gradle
+
+SHOWDEPS_CS="$WORK/showdeps-cs"
+mkdir -p "$SHOWDEPS_CS"
+cat > "$SHOWDEPS_CS/aigogo.json" <<'CSEOF'
+{
+  "name": "showdeps-cs",
+  "version": "1.0.0",
+  "language": {"name": "csharp", "version": "net8.0"},
+  "dependencies": {
+    "runtime": [{"package": "Newtonsoft.Json", "version": "13.0.3"}],
+    "dev": [{"package": "xunit", "version": "2.6.1"}]
+  },
+  "files": {"include": "auto"}
+}
+CSEOF
+
+run_test_grep "show-deps --format nuget (C#)" "PackageReference Include=\"Newtonsoft.Json\"" \
+    "$AIGOGO" show-deps "$SHOWDEPS_CS" --format nuget
+
+run_test_grep "show-deps --format csproj (C#)" "PrivateAssets" \
+    "$AIGOGO" show-deps "$SHOWDEPS_CS" --format csproj
+
+run_test_fail_grep "NuGet format on Java package -> error" "only supported for C#" \
+    "$AIGOGO" show-deps "$SHOWDEPS_JAVA" --format nuget
+
+SHOWDEPS_PHP="$WORK/showdeps-php"
+mkdir -p "$SHOWDEPS_PHP"
+cat > "$SHOWDEPS_PHP/aigogo.json" <<'PHPEOF'
+{
+  "name": "showdeps-php",
+  "version": "1.0.0",
+  "language": {"name": "php", "version": ">=8.1"},
+  "dependencies": {
+    "runtime": [{"package": "guzzlehttp/guzzle", "version": "^7.8"}],
+    "dev": [{"package": "phpunit/phpunit", "version": "^10.5"}]
+  },
+  "files": {"include": "auto"}
+}
+PHPEOF
+
+run_test_grep "show-deps --format composer (PHP)" "managed-dependencies" \
+    "$AIGOGO" show-deps "$SHOWDEPS_PHP" --format composer
+
+run_test_fail_grep "Composer format on Python package -> error" "only supported for PHP" \
+    "$AIGOGO" show-deps "$PY_MANIFEST" --format composer
+
+run_test_fail_grep "Conda format on PHP package -> error" "only supported for Python" \
+    "$AIGOGO" show-deps "$SHOWDEPS_PHP" --format conda
+
+run_test_fail_grep "Ruby format on Python package -> error" "only supported for Ruby" \
+    "$AIGOGO" show-deps "$PY_MANIFEST" --format gemfile
+
+run_test_fail_grep "Java format on Ruby package -> error" "only supported for Java" \
+    "$AIGOGO" show-deps "$SHOWDEPS_RB" --format maven
+
 echo ""
 
 ###############################################################################
@@ -1068,9 +3129,323 @@ pushd "$CACHE_DIR" >/dev/null
 "$AIGOGO" init >>"$LOGFILE" 2>&1
 "$AIGOGO" add file utils.py >>"$LOGFILE" 2>&1
 "$AIGOGO" build cache-remove-me:1.0.0 --force >>"$LOGFILE" 2>&1
+
+# Package metadata shows up in list; invalid metadata is rejected
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['metadata'] = {'license': 'Apache-2.0 OR MIT', 'repository': 'https://github.com/org/cache-meta', 'keywords': ['qa', 'cache']}
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+"$AIGOGO" build cache-meta:1.0.0 --force >>"$LOGFILE" 2>&1
+
+run_test_grep "aigg list --long — shows package metadata" "Repository: https://github.com/org/cache-meta" \
+    "$AIGOGO" list --long
+
+run_test_grep "aigg list — table with a version column" "NAME +VERSION" \
+    "$AIGOGO" list
+
+run_test_grep "aigg list --filter — matches the reference" "cache-meta:1.0.0" \
+    "$AIGOGO" list --filter 'name=cache-meta*'
+
+run_test_fail "aigg list --filter — leaves out other packages" \
+    bash -c "'$AIGOGO' list --filter 'name=cache-meta*' | grep -q cache-remove-me"
+
+run_test_grep "aigg --json list — JSON array" '"name": "cache-meta:1.0.0"' \
+    "$AIGOGO" --json list --filter type=local
+
+run_test_fail_grep "aigg list --sort bad — usage error" "invalid --sort" \
+    "$AIGOGO" list --sort bad
+
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['metadata']['license'] = 'MIT License'
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+
+run_test_fail_grep "aigg validate — rejects non-SPDX license" "not an SPDX license expression" \
+    "$AIGOGO" validate
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['metadata']['license'] = 'MIT'
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+
+run_test_grep "aigg info <name>:<tag>" "Repository: https://github.com/org/cache-meta" \
+    "$AIGOGO" info cache-meta:1.0.0
+
+run_test_grep "aigg info — lists files with sizes" "^     utils.py +[0-9.]+ [KM]?B$" \
+    "$AIGOGO" info cache-meta:1.0.0
+
+run_test_grep "aigg info — shows the digest and install hint" "Install: aigg add cache-meta:1.0.0" \
+    "$AIGOGO" info cache-meta:1.0.0
+
+run_test_fail_grep "aigg info --remote — needs a registry reference" "needs a registry reference" \
+    "$AIGOGO" info cache-meta:1.0.0 --remote
+
+run_test_fail_grep "aigg info --readme — package without README" "has no README" \
+    "$AIGOGO" info cache-meta:1.0.0 --readme
+
+# The readme is bundled even though files.include doesn't list it
+cat > README.md <<'MDEOF'
+# Cache Meta
+
+Call **greet** from your agent:
+
+```python
+from aigogo.cache_meta.utils import greet
+```
+MDEOF
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['readme'] = 'README.md'
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+
+run_test_grep "aigg build — bundles the readme" "\+ README.md" \
+    "$AIGOGO" build cache-readme:1.0.0 --force
+
+run_test_grep "aigg info --readme — renders the README" "^Call greet from your agent" \
+    "$AIGOGO" info cache-readme:1.0.0 --readme
+
+run_test_grep "aigg info --readme — keeps code blocks" "^    from aigogo.cache_meta.utils import greet" \
+    "$AIGOGO" info cache-readme:1.0.0 --readme
+
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['readme'] = '../README.md'
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+
+run_test_fail_grep "aigg validate — rejects a readme outside the package" "readme must be a path inside the package" \
+    "$AIGOGO" validate
+
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+del m['readme']
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+popd >/dev/null
+
+run_test_fail_grep "aigg info — package not in cache" "not found in local cache" \
+    "$AIGOGO" info no-such-package:1.0.0
+
+run_test_grep "aigg cache ls — lists like aigg list" "NAME +VERSION" \
+    "$AIGOGO" cache ls --sort size
+
+run_test_grep "aigg cache path — the cache directory" "\.aigogo/cache|cache" \
+    "$AIGOGO" cache path
+
+run_test_grep "aigg cache path <name>:<tag>" "cache-remove-me_1\.0\.0" \
+    "$AIGOGO" cache path cache-remove-me:1.0.0
+
+run_test_fail_grep "aigg cache path — uncached package exits non-zero" "not in the local cache" \
+    "$AIGOGO" cache path no-such-package:1.0.0
+
+run_test_grep "aigg cache prune --max-size --dry-run" "Would remove" \
+    "$AIGOGO" cache prune --max-size 1B --dry-run
+
+run_test "aigg cache prune --dry-run removes nothing" \
+    "$AIGOGO" cache path cache-remove-me:1.0.0
+
+run_test_grep "aigg cache prune --older-than — keeps new packages" "Nothing to prune" \
+    "$AIGOGO" cache prune --older-than 1h
+
+run_test_fail_grep "aigg cache prune — needs a policy" "Give --older-than, --max-size or both" \
+    "$AIGOGO" cache prune
+
+run_test_fail_grep "aigg cache prune — bad --older-than" "invalid --older-than" \
+    "$AIGOGO" cache prune --older-than 1x
+
+run_test_fail "aigg cache rm — uncached package" \
+    "$AIGOGO" cache rm no-such-package:1.0.0
+
+# Export a cached build and import it back, without a registry
+BUNDLE_DIR="$WORK/bundles"
+mkdir -p "$BUNDLE_DIR"
+run_test_grep "aigg export — writes a tarball" "Exported .* to .*bundle\.tar\.gz" \
+    "$AIGOGO" export cache-remove-me:1.0.0 -o "$BUNDLE_DIR/bundle.tar.gz"
+
+run_test_fail_grep "aigg export — refuses to overwrite without --force" "already exists" \
+    "$AIGOGO" export cache-remove-me:1.0.0 -o "$BUNDLE_DIR/bundle.tar.gz"
+
+run_test_grep "aigg export --format oci — writes an OCI image layout" "Exported .* to .*bundle-oci" \
+    "$AIGOGO" export cache-remove-me:1.0.0 --format oci -o "$BUNDLE_DIR/bundle-oci"
+
+run_test "aigg export --format oci — layout has oci-layout and index.json" \
+    test -f "$BUNDLE_DIR/bundle-oci/oci-layout" -a -f "$BUNDLE_DIR/bundle-oci/index.json"
+
+run_test_grep "aigg pack — writes a local build as an OCI image layout" "Packed cache-remove-me:1\.0\.0 into .*packed as :1\.0\.0" \
+    "$AIGOGO" pack cache-remove-me:1.0.0 -o "$BUNDLE_DIR/packed"
+
+run_test_grep "aigg pack --tag — adds a tag to the layout" "as :latest" \
+    "$AIGOGO" pack cache-remove-me:1.0.0 -o "$BUNDLE_DIR/packed" --tag latest
+
+run_test_fail_grep "aigg pack — a tag already in the layout needs --force" "already has an image tagged 1\.0\.0" \
+    "$AIGOGO" pack cache-remove-me:1.0.0 -o "$BUNDLE_DIR/packed"
+
+run_test_fail_grep "aigg pack — refuses a directory that isn't a layout" "isn't an OCI image layout" \
+    "$AIGOGO" pack cache-remove-me:1.0.0 -o "$CACHE_DIR"
+
+run_test_fail_grep "aigg pack — missing local build" "local build not found" \
+    "$AIGOGO" pack no-such-build:1.0.0
+
+run_test_grep "aigg import <packed layout>:<tag>" "Imported .* as imported-packed:1\.0\.0" \
+    "$AIGOGO" import "$BUNDLE_DIR/packed:latest" --tag imported-packed:1.0.0
+
+run_test_grep "aigg import <tarball> --tag" "Imported .* as imported-tar:1\.0\.0" \
+    "$AIGOGO" import "$BUNDLE_DIR/bundle.tar.gz" --tag imported-tar:1.0.0
+
+run_test_grep "aigg import <oci layout> --tag" "Imported .* as imported-oci:1\.0\.0" \
+    "$AIGOGO" import "$BUNDLE_DIR/bundle-oci" --tag imported-oci:1.0.0
+
+run_test_grep "aigg import — the same bundle again keeps the cached build" "already in the cache with the same content" \
+    "$AIGOGO" import "$BUNDLE_DIR/bundle.tar.gz" --tag imported-tar:1.0.0
+
+run_test_grep "aigg import <package dir>" "Imported .* as imported-dir:1\.0\.0" \
+    "$AIGOGO" import "$CACHE_DIR" --tag imported-dir:1.0.0
+
+"$AIGOGO" export imported-dir:1.0.0 -o "$BUNDLE_DIR/other.tar.gz" >>"$LOGFILE" 2>&1
+run_test_fail_grep "aigg import — an existing cache entry with other content needs --force" "already exists in cache with different content" \
+    "$AIGOGO" import "$BUNDLE_DIR/other.tar.gz" --tag imported-tar:1.0.0
+
+run_test_fail_grep "aigg import — missing bundle" "not found" \
+    "$AIGOGO" import "$BUNDLE_DIR/no-such-bundle.tar.gz"
+
+run_test_fail_grep "aigg import --max-extract-size — refuses a larger bundle" "larger than the extract size limit \(1KB\)" \
+    "$AIGOGO" import "$BUNDLE_DIR/bundle.tar.gz" --tag imported-small:1.0.0 --max-extract-size 1KB
+
+run_test_fail_grep "aigg import --max-extract-size — invalid size" "invalid --max-extract-size" \
+    "$AIGOGO" import "$BUNDLE_DIR/bundle.tar.gz" --max-extract-size nope
+
+# A bundle with an entry leaving the package is refused, writing nothing
+python3 - "$BUNDLE_DIR/evil.tar.gz" <<'PYEOF'
+import io, sys, tarfile
+with tarfile.open(sys.argv[1], "w:gz") as tar:
+    for name in ("aigogo.json", "../../escape.py"):
+        data = b"{}" if name == "aigogo.json" else b"print('escaped')\n"
+        info = tarfile.TarInfo(name)
+        info.size = len(data)
+        tar.addfile(info, io.BytesIO(data))
+PYEOF
+run_test_fail_grep "aigg import — refuses a bundle entry outside the package" "refusing layer entry \"\.\./\.\./escape\.py\"" \
+    "$AIGOGO" import "$BUNDLE_DIR/evil.tar.gz" --tag evil:1.0.0
+
+# The entry would have landed next to the cache directory
+run_test "aigg import — the refused entry wasn't written" \
+    bash -c '[ ! -e "$(dirname "$("$0" cache path)")/escape.py" ]' "$AIGOGO"
+
+# Compare two cached builds: cache-meta only adds metadata to aigogo.json
+run_test_grep "aigg diff — unified diff of a changed file" '^\+.*"repository": "https://github.com/org/cache-meta"' \
+    "$AIGOGO" diff cache-remove-me:1.0.0 cache-meta:1.0.0
+
+run_test_grep "aigg diff --summary — lists the changed files" "modified +aigogo\.json +\+[0-9]+ -[0-9]+" \
+    "$AIGOGO" diff cache-remove-me:1.0.0 cache-meta:1.0.0 --summary
+
+run_test_fail_grep "aigg diff --exit-code — fails when the packages differ" "differ" \
+    "$AIGOGO" diff cache-remove-me:1.0.0 cache-meta:1.0.0 --exit-code --summary
+
+run_test_grep "aigg diff --exit-code — identical packages" "No differences" \
+    "$AIGOGO" diff cache-remove-me:1.0.0 cache-remove-me:1.0.0 --exit-code
+
+run_test "aigg --output github diff — job summary and a group per file" \
+    bash -c 'GITHUB_STEP_SUMMARY=summary.md "$0" --output github diff cache-remove-me:1.0.0 cache-meta:1.0.0 --exit-code > out.txt 2>&1; [ $? -eq 9 ] && grep -q "^::group::modified aigogo.json" out.txt && grep -q "^### aigg diff" summary.md && grep -q "| aigogo.json | modified |" summary.md; rc=$?; rm -f out.txt summary.md; exit $rc' "$AIGOGO"
+
+run_test_grep "aigg --json diff" '"status": "modified"' \
+    "$AIGOGO" --json diff cache-remove-me:1.0.0 cache-meta:1.0.0
+
+run_test_fail_grep "aigg diff — missing package" "not found" \
+    "$AIGOGO" diff cache-remove-me:1.0.0 no-such-build:1.0.0
+
+# A generated file past 5000 lines is compared by digest unless --force-large
+LARGE_DIR="$WORK/diff-large"
+create_python_project "$LARGE_DIR"
+pushd "$LARGE_DIR" >/dev/null
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+python3 -c "print('\\n'.join(f'ROW_{i} = {i}' for i in range(6000)))" > generated.py
+"$AIGOGO" add file generated.py >>"$LOGFILE" 2>&1
+"$AIGOGO" build diff-large:1.0.0 --force >>"$LOGFILE" 2>&1
+echo "ROW_LAST = -1" >> generated.py
+"$AIGOGO" build diff-large:1.1.0 --force >>"$LOGFILE" 2>&1
 popd >/dev/null
 
-run_test_grep "aigg remove <name>:<tag>" "Successfully removed|removed" \
+run_test_grep "aigg diff — large files are compared by digest" "Large files differ \(use --force-large to diff\): generated\.py" \
+    "$AIGOGO" diff diff-large:1.0.0 diff-large:1.1.0
+
+run_test_grep "aigg diff --summary — large file sizes" "modified +generated\.py +large, [0-9]+ -> [0-9]+ bytes" \
+    "$AIGOGO" diff diff-large:1.0.0 diff-large:1.1.0 --summary
+
+run_test_grep "aigg diff --force-large — diffs the large file" "^\+ROW_LAST = -1" \
+    "$AIGOGO" diff diff-large:1.0.0 diff-large:1.1.0 --force-large
+
+# aigg serve answers from the same cache while it runs, until Ctrl-C
+if command -v curl >/dev/null 2>&1; then
+    SERVE_URL="http://127.0.0.1:$((20000 + RANDOM % 20000))"
+    "$AIGOGO" serve --addr "${SERVE_URL#http://}" >>"$LOGFILE" 2>&1 &
+    SERVE_PID=$!
+    for _ in 1 2 3 4 5 6 7 8 9 10; do
+        curl -s "$SERVE_URL/" >/dev/null && break
+        sleep 0.3
+    done
+    run_test_grep "aigg serve — /api/packages lists cached builds" '"name": "cache-meta:1\.0\.0"' \
+        curl -s "$SERVE_URL/api/packages"
+    run_test_grep "aigg serve — /api/diff" '"status": "modified"' \
+        curl -s "$SERVE_URL/api/diff?from=cache-remove-me:1.0.0&to=cache-meta:1.0.0"
+    run_test_grep "aigg serve — a missing package is a 404" '"code": "not_found"' \
+        curl -s "$SERVE_URL/api/files?ref=no-such-build:1.0.0"
+    run_test_grep "aigg serve — refuses another Host (DNS rebinding)" "isn't served" \
+        curl -s -H "Host: attacker.example.com" "$SERVE_URL/api/packages"
+    kill -INT "$SERVE_PID"
+    SERVE_RC=0
+    wait "$SERVE_PID" || SERVE_RC=$?
+    run_test "aigg serve — Ctrl-C stops it with exit code 0" test "$SERVE_RC" -eq 0
+
+    PROXY_URL="http://127.0.0.1:$((20000 + RANDOM % 20000))"
+    "$AIGOGO" proxy --offline --addr "${PROXY_URL#http://}" --dir "$WORK/proxy-cache" >>"$LOGFILE" 2>&1 &
+    PROXY_PID=$!
+    for _ in 1 2 3 4 5 6 7 8 9 10; do
+        curl -s "$PROXY_URL/v2/" >/dev/null && break
+        sleep 0.3
+    done
+    run_test_grep "aigg proxy — answers the registry API" "Docker-Distribution-A[Pp][Ii]-Version: registry/2\.0" \
+        curl -si "$PROXY_URL/v2/"
+    run_test_grep "aigg proxy --offline — uncached manifests are unknown" "MANIFEST_UNKNOWN" \
+        curl -s "$PROXY_URL/v2/ghcr.io/org/utils/manifests/1.0.0"
+    run_test_grep "aigg proxy — pushes are refused" "^HTTP/1.1 405" \
+        curl -si -X PUT "$PROXY_URL/v2/org/utils/manifests/1.0.0"
+    kill -INT "$PROXY_PID"
+    wait "$PROXY_PID" || true
+else
+    skip_test "aigg serve (curl not installed)"
+fi
+
+# mcp_session <aigg mcp flags...> -- <messages...> sends JSON-RPC messages to
+# aigg mcp, one per line
+mcp_session() {
+    local flags=()
+    while [[ $1 != "--" ]]; do flags+=("$1"); shift; done
+    shift
+    printf '%s\n' '{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"qa","version":"1"}}}' \
+        '{"jsonrpc":"2.0","method":"notifications/initialized"}' "$@" | "$AIGOGO" mcp ${flags[@]+"${flags[@]}"}
+}
+run_test_grep "aigg mcp — lists its tools" '"name":"add_package"' \
+    mcp_session -- '{"jsonrpc":"2.0","id":1,"method":"tools/list"}'
+run_test_grep "aigg mcp — search_packages matches keywords" '"ref":"cache-meta:1.0.0"' \
+    mcp_session --offline -- '{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search_packages","arguments":{"query":"qa cache"}}}'
+run_test_grep "aigg mcp — get_package_info lists the files" '"path":"aigogo.json"' \
+    mcp_session --offline -- '{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_package_info","arguments":{"ref":"cache-meta:1.0.0"}}}'
+run_test_grep "aigg mcp --read-only — add_package is not offered" "unknown tool: add_package" \
+    mcp_session --read-only -- '{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"add_package","arguments":{"ref":"cache-meta:1.0.0"}}}'
+
+run_test_grep "aigg cache rm — imported packages" "Successfully removed imported-dir" \
+    "$AIGOGO" cache rm imported-tar:1.0.0 imported-oci:1.0.0 imported-dir:1.0.0 imported-packed:1.0.0
+
+run_test_grep "aigg remove <name>:<tag>" "now aigg cache rm" \
     "$AIGOGO" remove cache-remove-me:1.0.0
 
 # Build two more to test remove-all
@@ -1079,9 +3454,19 @@ pushd "$CACHE_DIR" >/dev/null
 "$AIGOGO" build cache-rm-all-b:1.0.0 --force >>"$LOGFILE" 2>&1
 popd >/dev/null
 
+run_test_grep "aigg cache rm <name>:<tag>" "Successfully removed cache-rm-all-a" \
+    "$AIGOGO" cache rm cache-rm-all-a:1.0.0
+
 run_test_grep "aigg remove-all --force" "Successfully removed|No cached" \
     "$AIGOGO" remove-all --force
 
+pushd "$CACHE_DIR" >/dev/null
+"$AIGOGO" build cache-rm-all-c:1.0.0 --force >>"$LOGFILE" 2>&1
+popd >/dev/null
+
+run_test_grep "aigg cache rm --all --force" "Successfully removed 1 package" \
+    "$AIGOGO" cache rm --all --force
+
 echo ""
 
 ###############################################################################
@@ -1111,10 +3496,16 @@ if $HAS_REGISTRY; then
     run_test_grep "aigg pull" "Successfully pulled|Pulling" \
         "$AIGOGO" pull "$REG_IMAGE"
 
+    run_test_fail_grep "aigg push with tag_policy.overwrite block — an existing version is refused" "already has :1.0.0" \
+        env AIGOGO_TAG_POLICY_OVERWRITE=block "$AIGOGO" push "$REG_IMAGE" --from reg-push-test:1.0.0
+
     # delete (pipe "yes" for confirmation)
     run_test_grep "aigg delete" "Successfully deleted|Delete" \
         bash -c "echo yes | $AIGOGO delete $REG_IMAGE"
 
+    run_test_grep "aigg whoami — the registry accepts the login" "✓ $REGISTRY: " \
+        "$AIGOGO" whoami "$REGISTRY"
+
     # logout
     run_test_grep "aigg logout" "Successfully logged out" \
         "$AIGOGO" logout "$REGISTRY"
@@ -1122,7 +3513,9 @@ else
     skip_test "aigg login <registry> -u <user> -p"
     skip_test "aigg push --from"
     skip_test "aigg pull"
+    skip_test "aigg push with tag_policy.overwrite block — an existing version is refused"
     skip_test "aigg delete"
+    skip_test "aigg whoami — the registry accepts the login"
     skip_test "aigg logout"
 fi
 
@@ -1158,6 +3551,47 @@ popd >/dev/null
 run_test_fail_grep "push without --from -> error" "--from flag is required|--from" \
     "$AIGOGO" push fake.io/org/pkg:1.0.0
 
+# push guardrails are checked before any network access
+PRIVATE_DIR="$WORK/push-private"
+create_python_project "$PRIVATE_DIR"
+pushd "$PRIVATE_DIR" >/dev/null
+"$AIGOGO" init >>"$LOGFILE" 2>&1
+"$AIGOGO" add file utils.py helpers.py >>"$LOGFILE" 2>&1
+python3 -c "
+import json
+with open('aigogo.json') as f: m = json.load(f)
+m['private'] = True
+m['publish'] = {'blocked_registries': ['blocked.example.com']}
+with open('aigogo.json', 'w') as f: json.dump(m, f, indent=2)
+" 2>>"$LOGFILE" || true
+"$AIGOGO" build qa-private:1.0.0 --force --no-validate >>"$LOGFILE" 2>&1
+
+run_test_fail_grep "push private package -> refused" "Run with --allow-private" \
+    "$AIGOGO" push fake.io/org/qa-private:1.0.0 --from qa-private:1.0.0
+
+run_test_fail_grep "push to publish.blocked_registries -> refused even with --allow-private" "blocked by publish.blocked_registries" \
+    "$AIGOGO" push blocked.example.com/org/qa-private:1.0.0 --from qa-private:1.0.0 --allow-private
+
+run_test_grep "push --dry-run -> reports without pushing" "Would push qa-private:1\.0\.0 to fake\.io/org/qa-private:1\.0\.0: .* content sha256:" \
+    "$AIGOGO" push fake.io/org/qa-private:1.0.0 --from qa-private:1.0.0 --allow-private --dry-run
+
+run_test_fail_grep "push --dry-run -> still refuses a private package" "Run with --allow-private" \
+    "$AIGOGO" push fake.io/org/qa-private:1.0.0 --from qa-private:1.0.0 --dry-run
+
+run_test_fail_grep "push to AIGOGO_BLOCKED_REGISTRIES -> refused" "blocked by AIGOGO_BLOCKED_REGISTRIES" \
+    env AIGOGO_BLOCKED_REGISTRIES=fake.io "$AIGOGO" push fake.io/org/qa-private:1.0.0 --from qa-private:1.0.0 --allow-private
+
+run_test_fail_grep "push :latest with tag_policy.mutable block -> refused" "isn't a version tag, and tag_policy.mutable is block" \
+    env AIGOGO_TAG_POLICY_MUTABLE=block "$AIGOGO" push fake.io/org/qa-private:latest --from qa-private:1.0.0 --allow-private
+
+run_test_fail_grep "add :latest with tag_policy.mutable block -> refused before fetching" "refusing to add .*:latest" \
+    env AIGOGO_TAG_POLICY_MUTABLE=block "$AIGOGO" add fake.io/org/qa-private:latest
+
+run_test_fail_grep "tag_policy.mutable warn -> warns, then carries on" "tagged :latest, which can change" \
+    env AIGOGO_TAG_POLICY_MUTABLE=warn AIGOGO_RETRY_ATTEMPTS=1 "$AIGOGO" add localhost:1/org/qa-private:latest
+
+popd >/dev/null
+
 # show-deps with invalid format → error listing valid formats
 FMTERR_DIR="$WORK/fmterr"
 create_python_project "$FMTERR_DIR"